@@ -0,0 +1,82 @@
+//go:build !minimal
+// +build !minimal
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/control"
+	"github.com/kolide/launcher/ee/control/actionqueue"
+	"github.com/kolide/launcher/ee/ipstack"
+	"github.com/kolide/launcher/ee/tuf"
+	osqueryruntime "github.com/kolide/launcher/pkg/osquery/runtime"
+	"github.com/kolide/launcher/pkg/rungroup"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// runAutoupdater adds the autoupdater to the run group, registering it with the control service
+// if there is one, and downloads osqueryd first if it's missing.
+func runAutoupdater(ctx context.Context, k types.Knapsack, slogger *slog.Logger, runGroup *rungroup.Group, osqueryRunner *osqueryruntime.Runner,
+	actionsQueue *actionqueue.ActionQueue, controlService *control.ControlService, startupSpan trace.Span) error {
+	metadataClient := ipstack.NewClient(30 * time.Second)
+	mirrorClient := ipstack.NewClient(8 * time.Minute) // gives us extra time to avoid a timeout on download
+	tufAutoupdater, err := tuf.NewTufAutoupdater(
+		ctx,
+		k,
+		metadataClient,
+		mirrorClient,
+		osqueryRunner,
+		tuf.WithOsqueryRestart(osqueryRunner.Restart),
+		tuf.WithOsqueryInstances(osqueryRunner),
+	)
+	if err != nil {
+		return fmt.Errorf("creating TUF autoupdater updater: %w", err)
+	}
+
+	runGroup.Add("tufAutoupdater", tufAutoupdater.Execute, tufAutoupdater.Interrupt)
+	if actionsQueue != nil {
+		actionsQueue.RegisterActor(tuf.AutoupdateSubsystemName, tufAutoupdater)
+	}
+	if controlService != nil {
+		controlService.RegisterSubscriber(tuf.OsquerydSelectionSubsystemName, tufAutoupdater)
+	}
+
+	// in some cases, (e.g. rolling back a windows installation to a previous osquery version) it is possible that
+	// the installer leaves us in a situation where there is no osqueryd on disk.
+	// we can detect this and attempt to download the correct version into the TUF update library to run from that.
+	// This must be done as a blocking operation before the rungroups start, because the osquery runner will fail to
+	// launch and trigger a restart immediately
+	currentOsquerydBinaryPath := k.LatestOsquerydPath(ctx)
+	if _, err = os.Stat(currentOsquerydBinaryPath); os.IsNotExist(err) {
+		slogger.Log(ctx, slog.LevelInfo,
+			"detected missing osqueryd executable, will attempt to download",
+		)
+
+		startupSpan.AddEvent("osqueryd_startup_download_start")
+		// simulate control server request for immediate update, noting to bypass the initial delay window
+		actionReader := strings.NewReader(`{
+			"bypass_initial_delay": true,
+			"binaries_to_update": [
+				{ "name": "osqueryd" }
+			]
+		}`)
+
+		if err = tufAutoupdater.Do(actionReader); err != nil {
+			slogger.Log(ctx, slog.LevelError,
+				"failure triggering immediate osquery update",
+				"err", err,
+			)
+		}
+
+		startupSpan.AddEvent("osqueryd_startup_download_completed")
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build minimal
+// +build minimal
+
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/control"
+	"github.com/kolide/launcher/ee/control/actionqueue"
+	osqueryruntime "github.com/kolide/launcher/pkg/osquery/runtime"
+	"github.com/kolide/launcher/pkg/rungroup"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// runAutoupdater does nothing, since minimal builds exclude the autoupdater. The knapsack
+// reports autoupdate disabled, so it isn't called.
+func runAutoupdater(_ context.Context, _ types.Knapsack, _ *slog.Logger, _ *rungroup.Group, _ *osqueryruntime.Runner,
+	_ *actionqueue.ActionQueue, _ *control.ControlService, _ trace.Span) error {
+	return nil
+}
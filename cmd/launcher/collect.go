@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/agent/flags"
+	"github.com/kolide/launcher/ee/agent/knapsack"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/tuf"
+	"github.com/kolide/launcher/pkg/launcher"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/kolide/launcher/pkg/osquery/collect"
+	"github.com/peterbourgon/ff/v3"
+)
+
+// runCollect runs a set of named queries against a transient osqueryd, writes the results, and exits.
+// It does not enroll or require an existing launcher installation; it's meant for CI and imaging validation.
+func runCollect(systemMultiSlogger *multislogger.MultiSlogger, args []string) error {
+	attachConsole()
+	defer detachConsole()
+
+	launcher.SetDefaultPaths()
+
+	var (
+		flagset        = flag.NewFlagSet("collect", flag.ExitOnError)
+		flQueries      = flagset.String("queries", "", `path to a JSON file of named queries, e.g. {"queries": {"hostname": "select hostname from system_info"}}`)
+		flOutput       = flagset.String("output", "-", "path to write JSON results to, or - for stdout")
+		flOsquerydPath = flagset.String("osqueryd_path", "", "path to osqueryd binary (defaults to the latest installed osqueryd)")
+		flTimeout      = flagset.Duration("timeout", 5*time.Minute, "maximum time to spend running queries")
+		flDebug        = flagset.Bool("debug", false, "whether or not debug logging is enabled")
+		flRootDir      = flagset.String("root_directory", launcher.DefaultRootDirectoryPath, "the installed launcher's root directory, whose data collection consent is respected")
+		flOsqueryFlags launcher.ArrayFlags
+	)
+	flagset.Var(&flOsqueryFlags, "osquery_flag", "Flags to pass to osquery (possibly overriding Launcher defaults)")
+
+	if err := ff.Parse(flagset, args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	if *flQueries == "" {
+		return errors.New("--queries is required")
+	}
+
+	// Logs go to stderr, so that results can be written to stdout
+	slogLevel := slog.LevelInfo
+	if *flDebug {
+		slogLevel = slog.LevelDebug
+	}
+	systemMultiSlogger.AddHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level:     slogLevel,
+		AddSource: true,
+	}))
+
+	queriesFile, err := os.Open(*flQueries)
+	if err != nil {
+		return fmt.Errorf("opening queries file: %w", err)
+	}
+	queries, err := collect.ReadQueries(queriesFile)
+	queriesFile.Close()
+	if err != nil {
+		return fmt.Errorf("reading queries from %s: %w", *flQueries, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *flTimeout)
+	defer cancel()
+
+	queryCount := len(queries)
+	removed, err := gateConsentQueries(ctx, systemMultiSlogger.Logger, *flRootDir, queries)
+	if err != nil {
+		return err
+	}
+
+	k, collectRootDir, err := newCollectKnapsack(systemMultiSlogger, *flOsquerydPath, flOsqueryFlags, *flDebug)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(collectRootDir)
+
+	results, err := collect.Run(ctx, k, collectRootDir, queries)
+	if err != nil {
+		return fmt.Errorf("collecting results: %w", err)
+	}
+	for name, table := range removed {
+		results.Errors[name] = fmt.Sprintf("uses %s, which is blocked pending data collection consent", table)
+	}
+
+	var out io.Writer = os.Stdout
+	if *flOutput != "-" {
+		outFile, err := os.Create(*flOutput)
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+
+	if err := collect.WriteResults(out, results); err != nil {
+		return fmt.Errorf("writing results: %w", err)
+	}
+
+	// Exit non-zero if any query failed, so that CI notices
+	if len(results.Errors) > 0 {
+		return fmt.Errorf("%d of %d queries failed", len(results.Errors), queryCount)
+	}
+
+	return nil
+}
+
+// newCollectKnapsack creates a knapsack for running osqueryd transiently, as `launcher collect`
+// and `launcher query` do, along with a temporary root directory for its socket, pidfile, and
+// augeas lenses. The caller should remove the root directory when done.
+func newCollectKnapsack(systemMultiSlogger *multislogger.MultiSlogger, osquerydPath string, osqueryFlags []string, debug bool) (types.Knapsack, string, error) {
+	if osquerydPath == "" {
+		if latestOsquerydBinary, err := tuf.CheckOutLatestWithoutConfig("osqueryd", systemMultiSlogger.Logger); err == nil {
+			osquerydPath = latestOsquerydBinary.Path
+		} else if osquerydPath = launcher.FindOsquery(); osquerydPath == "" {
+			return nil, "", fmt.Errorf("could not find osqueryd binary: %w", err)
+		}
+	}
+
+	rootDir, err := agent.MkdirTemp("launcher-collect")
+	if err != nil {
+		return nil, "", fmt.Errorf("creating temp dir for collect mode: %w", err)
+	}
+
+	opts := &launcher.Options{
+		OsquerydPath:  osquerydPath,
+		OsqueryFlags:  osqueryFlags,
+		RootDirectory: rootDir,
+		Debug:         debug,
+	}
+	flagController := flags.NewFlagController(systemMultiSlogger.Logger, inmemory.NewStore(), flags.WithCmdLineOpts(opts))
+
+	return knapsack.New(nil, flagController, nil, systemMultiSlogger, nil), rootDir, nil
+}
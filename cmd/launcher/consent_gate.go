@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	agentbbolt "github.com/kolide/launcher/ee/agent/storage/bbolt"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/osquery"
+)
+
+// gateConsentQueries removes the queries that must be blocked until the user consents to data
+// collection from queries, per the launcher database in rootDirectory, so that `launcher query`
+// and `launcher collect` respect privacy mode like launcher's own queries do. It returns the
+// names of the removed queries, mapped to the table blocking them. If launcher isn't installed
+// in rootDirectory, there's no consent policy, and nothing is removed; if its database can't be
+// read, it returns an error rather than run queries the user may not have consented to.
+func gateConsentQueries(ctx context.Context, slogger *slog.Logger, rootDirectory string, queries map[string]string) (map[string]string, error) {
+	if _, err := os.Stat(agentbbolt.LauncherDbLocation(rootDirectory)); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	db, err := openLauncherDbReadOnly(rootDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("checking data collection consent, which requires running as root or Administrator: %w", err)
+	}
+	defer db.Close()
+
+	policyStore := noBucketGetter{agentbbolt.NewReadOnlyStore(slogger, db, storage.ControlStore.String())}
+	statusStore := noBucketGetter{agentbbolt.NewReadOnlyStore(slogger, db, storage.PersistentHostDataStore.String())}
+
+	return osquery.GateConsentQueries(ctx, slogger, policyStore, statusStore, queries), nil
+}
+
+// noBucketGetter treats a bucket that doesn't exist yet as empty, as in a database from a
+// launcher that has never received a consent policy.
+type noBucketGetter struct {
+	types.Getter
+}
+
+func (g noBucketGetter) Get(key []byte) ([]byte, error) {
+	val, err := g.Getter.Get(key)
+	if isNoBucketError(err) {
+		return nil, nil
+	}
+	return val, err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	agentbbolt "github.com/kolide/launcher/ee/agent/storage/bbolt"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func Test_gateConsentQueries(t *testing.T) {
+	t.Parallel()
+
+	slogger := multislogger.NewNopLogger()
+	newQueries := func() map[string]string {
+		return map[string]string{
+			"time":    "select * from time",
+			"history": "select * from shell_history",
+		}
+	}
+
+	// Launcher isn't installed: nothing to gate
+	queries := newQueries()
+	removed, err := gateConsentQueries(context.TODO(), slogger, t.TempDir(), queries)
+	require.NoError(t, err)
+	require.Empty(t, removed)
+	require.Len(t, queries, 2)
+
+	// Launcher hasn't received a consent policy: nothing to gate
+	rootDir := t.TempDir()
+	db, err := bbolt.Open(agentbbolt.LauncherDbLocation(rootDir), 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	queries = newQueries()
+	removed, err = gateConsentQueries(context.TODO(), slogger, rootDir, queries)
+	require.NoError(t, err)
+	require.Empty(t, removed)
+	require.Len(t, queries, 2)
+
+	// Consent is pending: the gated table is blocked
+	setConsentPolicy(t, rootDir, `{"enabled":true,"policy_version":"1","gated_tables":["shell_history"]}`)
+	queries = newQueries()
+	removed, err = gateConsentQueries(context.TODO(), slogger, rootDir, queries)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"history": "shell_history"}, removed)
+	require.Equal(t, map[string]string{"time": "select * from time"}, queries)
+
+	// The policy can't be read: everything is blocked
+	setConsentPolicy(t, rootDir, `not json`)
+	queries = newQueries()
+	removed, err = gateConsentQueries(context.TODO(), slogger, rootDir, queries)
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+	require.Empty(t, queries)
+}
+
+func setConsentPolicy(t *testing.T, rootDir string, policy string) {
+	db, err := bbolt.Open(agentbbolt.LauncherDbLocation(rootDir), 0600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	controlStore, err := agentbbolt.NewStore(context.TODO(), multislogger.NewNopLogger(), db, storage.ControlStore.String())
+	require.NoError(t, err)
+	require.NoError(t, controlStore.Set([]byte("data_collection_consent_policy"), []byte(policy)))
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net/http"
 
+	"github.com/kolide/krypto/pkg/echelper"
+	"github.com/kolide/launcher/ee/agent"
 	"github.com/kolide/launcher/ee/agent/types"
 	"github.com/kolide/launcher/ee/control"
+	"github.com/kolide/launcher/ee/control/signedpayload"
+	"github.com/kolide/launcher/ee/ipstack"
+	"github.com/kolide/launcher/ee/serverkeys"
 	"github.com/kolide/launcher/pkg/traces"
 )
 
@@ -20,10 +24,13 @@ func createHTTPClient(ctx context.Context, k types.Knapsack) (*control.HTTPClien
 	if k.InsecureControlTLS() {
 		clientOpts = append(clientOpts, control.WithInsecureSkipVerify())
 	}
+	if getClientCertificate := agent.ClientCertificateFunc(k); getClientCertificate != nil {
+		clientOpts = append(clientOpts, control.WithClientCertificate(getClientCertificate))
+	}
 	if k.DisableControlTLS() {
 		clientOpts = append(clientOpts, control.WithDisableTLS())
 	}
-	client, err := control.NewControlHTTPClient(k.ControlServerURL(), http.DefaultClient, clientOpts...)
+	client, err := control.NewControlHTTPClient(k.ControlServerURL(), ipstack.NewClient(0), clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating control http client: %w", err)
 	}
@@ -51,3 +58,14 @@ func createControlService(ctx context.Context, store types.GetterSetter, k types
 
 	return service, nil
 }
+
+// createSignedPayloadVerifier creates the verifier for sensitive control server payloads. It trusts
+// key sets signed by the ECC key of the Kolide server we're enrolled with.
+func createSignedPayloadVerifier(k types.Knapsack) (*signedpayload.Verifier, error) {
+	rootKey, err := echelper.PublicPemToEcdsaKey([]byte(serverkeys.ForServer(k.KolideServerURL()).EccPem))
+	if err != nil {
+		return nil, fmt.Errorf("parsing server ec key: %w", err)
+	}
+
+	return signedpayload.New(k.Slogger(), rootKey, k.ControlSigningKeysStore()), nil
+}
@@ -1,3 +1,6 @@
+//go:build !minimal
+// +build !minimal
+
 package main
 
 import (
@@ -45,6 +48,16 @@ func runDesktop(_ *multislogger.MultiSlogger, args []string) error {
 			"",
 			"path to create socket for user server",
 		)
+		flUserServerPipeSecurityDescriptor = flagset.String(
+			"user_server_pipe_security_descriptor",
+			"",
+			"SDDL security descriptor for the user server named pipe (windows only)",
+		)
+		flParentPid = flagset.Int(
+			"ppid",
+			0,
+			"pid of the launcher process that started this desktop process",
+		)
 		flRunnerServerUrl = flagset.String(
 			"runner_server_url",
 			"",
@@ -145,7 +158,17 @@ func runDesktop(_ *multislogger.MultiSlogger, args []string) error {
 	notifier := notify.NewDesktopNotifier(slogger, *flIconPath)
 	runGroup.Add("desktopNotifier", notifier.Execute, notifier.Interrupt)
 
-	server, err := userserver.New(slogger, *flUserServerAuthToken, *flUserServerSocketPath, shutdownChan, showDesktopChan, notifier)
+	// On Windows, the named pipe's security descriptor restricts who may connect, and only launcher
+	// (our parent) may send requests.
+	var serverOpts []userserver.UserServerOption
+	if runtime.GOOS == "windows" {
+		serverOpts = append(serverOpts, userserver.WithPipeSecurityDescriptor(*flUserServerPipeSecurityDescriptor))
+		if *flParentPid != 0 {
+			serverOpts = append(serverOpts, userserver.WithAllowedClientPid(*flParentPid))
+		}
+	}
+
+	server, err := userserver.New(slogger, *flUserServerAuthToken, *flUserServerSocketPath, shutdownChan, showDesktopChan, notifier, serverOpts...)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,14 @@
+//go:build minimal
+// +build minimal
+
+package main
+
+import (
+	"errors"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+)
+
+func runDesktop(_ *multislogger.MultiSlogger, _ []string) error {
+	return errors.New("desktop is not included in this minimal build of launcher")
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	agentbbolt "github.com/kolide/launcher/ee/agent/storage/bbolt"
+	"github.com/kolide/launcher/ee/agent/storage/encrypted"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/launcher"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/kolide/launcher/pkg/osquery"
+	"github.com/kolide/launcher/pkg/osquery/runtime/history"
+	"github.com/peterbourgon/ff/v3"
+	"go.etcd.io/bbolt"
+)
+
+// enrollStatusDbTimeout is how long we wait for launcher.db. The running launcher holds
+// an exclusive lock on it, so we fall back to the latest backup instead of waiting long.
+const enrollStatusDbTimeout = 2 * time.Second
+
+type enrollStatus struct {
+	RootDirectory  string               `json:"root_directory"`
+	Database       string               `json:"database"`
+	DatabaseBackup bool                 `json:"database_backup"` // true if launcher.db was locked, and we read its latest backup
+	DatabaseTime   string               `json:"database_time"`   // modification time of the database we read
+	Enrolled       bool                 `json:"enrolled"`
+	Munemo         string               `json:"munemo"`
+	OrganizationId string               `json:"organization_id"`
+	DeviceId       string               `json:"device_id"`
+	Registrations  []registrationStatus `json:"registrations"`
+}
+
+type registrationStatus struct {
+	RegistrationId    string                 `json:"registration_id"`
+	NodeKeyPresent    bool                   `json:"node_key_present"`
+	LastConfigCheckin string                 `json:"last_config_checkin"`
+	OsqueryInstance   *osqueryInstanceStatus `json:"osquery_instance"`
+}
+
+type osqueryInstanceStatus struct {
+	State       string `json:"state"` // as last recorded; see osqueryInstanceState
+	RunId       string `json:"run_id"`
+	InstanceId  string `json:"instance_id"`
+	Version     string `json:"version"`
+	StartTime   string `json:"start_time"`
+	ConnectTime string `json:"connect_time"`
+	ExitTime    string `json:"exit_time"`
+	Error       string `json:"error"`
+}
+
+// runEnrollStatus prints the enrollment state recorded in launcher's database. It only reads
+// the database, so it is safe to run alongside launcher, e.g. from MDM inventory scripts.
+func runEnrollStatus(systemMultiSlogger *multislogger.MultiSlogger, args []string) error {
+	attachConsole()
+	defer detachConsole()
+
+	launcher.SetDefaultPaths()
+
+	var (
+		flagset         = flag.NewFlagSet("launcher enroll-status", flag.ExitOnError)
+		flRootDirectory = flagset.String("root_directory", launcher.DefaultRootDirectoryPath, "The location of the local database, pidfiles, etc.")
+		flJson          = flagset.Bool("json", false, "Print the enrollment status as JSON")
+		_               = flagset.String(
+			"config",
+			"",
+			"launcher flags configuration file",
+		)
+	)
+
+	ffOpts := []ff.Option{
+		ff.WithConfigFileFlag("config"),
+		ff.WithConfigFileParser(launcher.ConfigFileParser),
+		ff.WithIgnoreUndefined(true),
+	}
+
+	flagset.Usage = commandUsage(flagset, "launcher enroll-status")
+	if err := ff.Parse(flagset, args, ffOpts...); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	if *flRootDirectory == "" {
+		return errors.New("no root directory specified")
+	}
+
+	status, err := gatherEnrollStatus(systemMultiSlogger, *flRootDirectory)
+	if err != nil {
+		return err
+	}
+
+	if *flJson {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+
+	printEnrollStatus(os.Stdout, status)
+	return nil
+}
+
+func gatherEnrollStatus(systemMultiSlogger *multislogger.MultiSlogger, rootDirectory string) (*enrollStatus, error) {
+	status := &enrollStatus{
+		RootDirectory: rootDirectory,
+		Database:      agentbbolt.LauncherDbLocation(rootDirectory),
+		Registrations: make([]registrationStatus, 0),
+	}
+
+	db, err := openLauncherDbReadOnly(rootDirectory)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	status.Database = db.Path()
+	status.DatabaseBackup = status.Database != agentbbolt.LauncherDbLocation(rootDirectory)
+
+	if info, err := os.Stat(status.Database); err == nil {
+		status.DatabaseTime = info.ModTime().UTC().Format(time.RFC3339)
+	}
+
+	slogger := systemMultiSlogger.Logger
+	var configStore types.KVStore = agentbbolt.NewReadOnlyStore(slogger, db, storage.ConfigStore.String())
+	storageKey, err := encrypted.LoadKey(context.TODO(), rootDirectory)
+	switch {
+	case errors.Is(err, encrypted.ErrNoKey), errors.Is(err, encrypted.ErrKeystoreUnavailable):
+		// This launcher has never encrypted its stores
+	case err != nil:
+		return nil, fmt.Errorf("loading storage encryption key, which requires running as root or Administrator: %w", err)
+	default:
+		configStore, err = encrypted.NewStore(slogger, configStore, storageKey)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting config store: %w", err)
+		}
+	}
+	serverDataStore := agentbbolt.NewReadOnlyStore(slogger, db, storage.ServerProvidedDataStore.String())
+	historyStore := agentbbolt.NewReadOnlyStore(slogger, db, storage.OsqueryHistoryInstanceStore.String())
+
+	for key, value := range map[string]*string{
+		"munemo":          &status.Munemo,
+		"organization_id": &status.OrganizationId,
+		"device_id":       &status.DeviceId,
+	} {
+		val, err := readOnlyGet(serverDataStore, key)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", key, err)
+		}
+		*value = val
+	}
+
+	registrationIds, err := registrationIdsWithNodeKeys(configStore)
+	if err != nil {
+		return nil, fmt.Errorf("finding registrations: %w", err)
+	}
+
+	instances, err := history.LoadInstances(historyStore)
+	if err != nil && !isNoBucketError(err) {
+		return nil, fmt.Errorf("reading osquery instance history: %w", err)
+	}
+
+	for _, registrationId := range registrationIds {
+		registration := registrationStatus{RegistrationId: registrationId}
+
+		nodeKey, err := osquery.NodeKey(configStore, registrationId)
+		if err != nil && !isNoBucketError(err) {
+			return nil, fmt.Errorf("reading node key for %s: %w", registrationId, err)
+		}
+		registration.NodeKeyPresent = nodeKey != ""
+		status.Enrolled = status.Enrolled || registration.NodeKeyPresent
+
+		lastCheckin, err := osquery.LastConfigCheckin(configStore, registrationId)
+		if err != nil && !isNoBucketError(err) {
+			return nil, fmt.Errorf("reading last config checkin for %s: %w", registrationId, err)
+		}
+		if !lastCheckin.IsZero() {
+			registration.LastConfigCheckin = lastCheckin.UTC().Format(time.RFC3339)
+		}
+
+		registration.OsqueryInstance = latestOsqueryInstance(instances, registrationId)
+
+		status.Registrations = append(status.Registrations, registration)
+	}
+
+	return status, nil
+}
+
+// registrationIdsWithNodeKeys returns the default registration ID, and the ID of any other
+// registration with a node key, in order.
+func registrationIdsWithNodeKeys(configStore types.Iterator) ([]string, error) {
+	registrationIds := []string{types.DefaultRegistrationID}
+
+	if err := configStore.ForEach(func(k, _ []byte) error {
+		key, identifierType, identifier := storage.SplitKey(k)
+		if string(key) != "nodeKey" || string(identifierType) != string(storage.IdentifierTypeRegistration) {
+			return nil
+		}
+		if string(identifier) != types.DefaultRegistrationID {
+			registrationIds = append(registrationIds, string(identifier))
+		}
+		return nil
+	}); err != nil && !isNoBucketError(err) {
+		return nil, err
+	}
+
+	sort.Strings(registrationIds[1:])
+	return registrationIds, nil
+}
+
+func latestOsqueryInstance(instances []history.Instance, registrationId string) *osqueryInstanceStatus {
+	for i := len(instances) - 1; i >= 0; i -= 1 {
+		instance := instances[i]
+
+		// Older instances were recorded before there were registrations other than the default
+		instanceRegistrationId := instance.RegistrationId
+		if instanceRegistrationId == "" {
+			instanceRegistrationId = types.DefaultRegistrationID
+		}
+		if instanceRegistrationId != registrationId {
+			continue
+		}
+
+		return &osqueryInstanceStatus{
+			State:       osqueryInstanceState(instance),
+			RunId:       instance.RunId,
+			InstanceId:  instance.InstanceId,
+			Version:     instance.Version,
+			StartTime:   instance.StartTime,
+			ConnectTime: instance.ConnectTime,
+			ExitTime:    instance.ExitTime,
+			Error:       instance.Error,
+		}
+	}
+
+	return nil
+}
+
+// osqueryInstanceState describes the instance as last recorded. If launcher is not running,
+// or stopped without recording the exit, this may no longer be accurate.
+func osqueryInstanceState(instance history.Instance) string {
+	switch {
+	case instance.ExitTime != "":
+		return "exited"
+	case instance.ConnectTime != "":
+		return "running"
+	default:
+		return "starting"
+	}
+}
+
+// openLauncherDbReadOnly opens launcher.db in rootDirectory read-only. If launcher is running
+// and holds the lock on it, the latest backup is opened instead.
+func openLauncherDbReadOnly(rootDirectory string) (*bbolt.DB, error) {
+	dbPath := agentbbolt.LauncherDbLocation(rootDirectory)
+	db, err := bbolt.Open(dbPath, 0600, &bbolt.Options{ReadOnly: true, Timeout: enrollStatusDbTimeout})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		// Launcher is running -- read the latest backup instead
+		backupLocations := agentbbolt.BackupLauncherDbLocations(rootDirectory)
+		if len(backupLocations) == 0 {
+			return nil, fmt.Errorf("%s is in use, and there is no backup to read", dbPath)
+		}
+		dbPath = backupLocations[0]
+		db, err = bbolt.Open(dbPath, 0600, &bbolt.Options{ReadOnly: true, Timeout: enrollStatusDbTimeout})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", dbPath, err)
+	}
+	return db, nil
+}
+
+func readOnlyGet(store types.Getter, key string) (string, error) {
+	val, err := store.Get([]byte(key))
+	if err != nil && !isNoBucketError(err) {
+		return "", err
+	}
+	return string(val), nil
+}
+
+// isNoBucketError reports whether err is because a bucket does not exist yet, as in
+// a database from a launcher that has not yet run the relevant subsystem.
+func isNoBucketError(err error) bool {
+	var noBucketErr agentbbolt.NoBucketError
+	return errors.As(err, &noBucketErr)
+}
+
+func printEnrollStatus(w io.Writer, status *enrollStatus) {
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	defer tw.Flush()
+
+	database := status.Database
+	if status.DatabaseBackup {
+		database += " (launcher.db is in use; read its latest backup)"
+	}
+
+	fmt.Fprintf(tw, "Root directory:\t%s\n", status.RootDirectory)
+	fmt.Fprintf(tw, "Database:\t%s\n", database)
+	fmt.Fprintf(tw, "Database modified:\t%s\n", valueOrNone(status.DatabaseTime))
+	fmt.Fprintf(tw, "Enrolled:\t%t\n", status.Enrolled)
+	fmt.Fprintf(tw, "Munemo:\t%s\n", valueOrNone(status.Munemo))
+	fmt.Fprintf(tw, "Organization ID:\t%s\n", valueOrNone(status.OrganizationId))
+	fmt.Fprintf(tw, "Device ID:\t%s\n", valueOrNone(status.DeviceId))
+
+	for _, registration := range status.Registrations {
+		fmt.Fprintf(tw, "\nRegistration:\t%s\n", registration.RegistrationId)
+		fmt.Fprintf(tw, "  Node key present:\t%t\n", registration.NodeKeyPresent)
+		fmt.Fprintf(tw, "  Last config checkin:\t%s\n", valueOrNone(registration.LastConfigCheckin))
+
+		if registration.OsqueryInstance == nil {
+			fmt.Fprintf(tw, "  Osquery instance:\t(none)\n")
+			continue
+		}
+
+		instance := registration.OsqueryInstance
+		fmt.Fprintf(tw, "  Osquery instance:\t%s\n", instance.State)
+		fmt.Fprintf(tw, "  Osquery version:\t%s\n", valueOrNone(instance.Version))
+		fmt.Fprintf(tw, "  Osquery started:\t%s\n", valueOrNone(instance.StartTime))
+		fmt.Fprintf(tw, "  Osquery connected:\t%s\n", valueOrNone(instance.ConnectTime))
+		if instance.ExitTime != "" {
+			fmt.Fprintf(tw, "  Osquery exited:\t%s\n", instance.ExitTime)
+		}
+		if instance.Error != "" {
+			fmt.Fprintf(tw, "  Osquery error:\t%s\n", instance.Error)
+		}
+	}
+}
+
+func valueOrNone(val string) string {
+	if val == "" {
+		return "(none)"
+	}
+	return val
+}
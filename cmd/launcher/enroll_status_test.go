@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	agentbbolt "github.com/kolide/launcher/ee/agent/storage/bbolt"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/kolide/launcher/pkg/osquery/runtime/history"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func Test_gatherEnrollStatus(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	db := setUpEnrollStatusDb(t, rootDir)
+
+	// Reading launcher.db directly
+	require.NoError(t, db.Close())
+	status, err := gatherEnrollStatus(multislogger.New(), rootDir)
+	require.NoError(t, err)
+	require.Equal(t, agentbbolt.LauncherDbLocation(rootDir), status.Database)
+	require.False(t, status.DatabaseBackup)
+	require.True(t, status.Enrolled)
+	require.Equal(t, "example", status.Munemo)
+	require.Equal(t, "123", status.OrganizationId)
+	require.Equal(t, "", status.DeviceId)
+
+	require.Len(t, status.Registrations, 2)
+	require.Equal(t, registrationStatus{
+		RegistrationId:    "default",
+		NodeKeyPresent:    true,
+		LastConfigCheckin: "2024-01-01T00:00:00Z",
+		OsqueryInstance: &osqueryInstanceStatus{
+			State:       "running",
+			RunId:       "run-2",
+			Version:     "5.12.1",
+			StartTime:   "2024-01-01T00:00:00Z",
+			ConnectTime: "2024-01-01T00:00:05Z",
+		},
+	}, status.Registrations[0])
+	require.Equal(t, registrationStatus{
+		RegistrationId: "other",
+		NodeKeyPresent: true,
+		OsqueryInstance: &osqueryInstanceStatus{
+			State:     "exited",
+			RunId:     "run-1",
+			StartTime: "2023-12-31T00:00:00Z",
+			ExitTime:  "2023-12-31T00:00:01Z",
+			Error:     "exit status 1",
+		},
+	}, status.Registrations[1])
+
+	// Both formats should render
+	var out bytes.Buffer
+	printEnrollStatus(&out, status)
+	require.Contains(t, out.String(), "Munemo:")
+	require.NoError(t, json.NewEncoder(&out).Encode(status))
+}
+
+func Test_gatherEnrollStatus_LauncherRunning(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	db := setUpEnrollStatusDb(t, rootDir)
+
+	// Take a backup, then keep the database open, as the running launcher would
+	require.NoError(t, db.View(func(tx *bbolt.Tx) error {
+		return tx.CopyFile(agentbbolt.LauncherDbLocation(rootDir)+".bak", 0600)
+	}))
+	defer db.Close()
+
+	status, err := gatherEnrollStatus(multislogger.New(), rootDir)
+	require.NoError(t, err)
+	require.True(t, status.DatabaseBackup)
+	require.Equal(t, agentbbolt.LauncherDbLocation(rootDir)+".bak", status.Database)
+	require.Equal(t, "example", status.Munemo)
+}
+
+func Test_gatherEnrollStatus_NewDatabase(t *testing.T) {
+	t.Parallel()
+
+	// A database without any of the stores we read, e.g. from a launcher that never started fully
+	rootDir := t.TempDir()
+	db, err := bbolt.Open(agentbbolt.LauncherDbLocation(rootDir), 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	status, err := gatherEnrollStatus(multislogger.New(), rootDir)
+	require.NoError(t, err)
+	require.False(t, status.Enrolled)
+	require.Equal(t, []registrationStatus{{RegistrationId: "default"}}, status.Registrations)
+}
+
+func Test_gatherEnrollStatus_NoDatabase(t *testing.T) {
+	t.Parallel()
+
+	_, err := gatherEnrollStatus(multislogger.New(), t.TempDir())
+	require.Error(t, err)
+}
+
+func setUpEnrollStatusDb(t *testing.T, rootDir string) *bbolt.DB {
+	db, err := bbolt.Open(agentbbolt.LauncherDbLocation(rootDir), 0600, nil)
+	require.NoError(t, err)
+
+	slogger := multislogger.NewNopLogger()
+	newStore := func(store storage.Store) types.Setter {
+		s, err := agentbbolt.NewStore(context.TODO(), slogger, db, store.String())
+		require.NoError(t, err)
+		return s
+	}
+
+	configStore := newStore(storage.ConfigStore)
+	require.NoError(t, configStore.Set([]byte("nodeKey"), []byte("default-node-key")))
+	require.NoError(t, configStore.Set([]byte("nodeKey:registration:other"), []byte("other-node-key")))
+	require.NoError(t, configStore.Set([]byte("lastConfigCheckin"), []byte("1704067200")))
+
+	serverDataStore := newStore(storage.ServerProvidedDataStore)
+	require.NoError(t, serverDataStore.Set([]byte("munemo"), []byte("example")))
+	require.NoError(t, serverDataStore.Set([]byte("organization_id"), []byte("123")))
+
+	instances, err := json.Marshal([]history.Instance{
+		{RegistrationId: "other", RunId: "run-1", StartTime: "2023-12-31T00:00:00Z", ExitTime: "2023-12-31T00:00:01Z", Error: "exit status 1"},
+		{RunId: "run-2", StartTime: "2024-01-01T00:00:00Z", ConnectTime: "2024-01-01T00:00:05Z", Version: "5.12.1"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, newStore(storage.OsqueryHistoryInstanceStore).Set([]byte("osquery_instance_history"), instances))
+
+	return db
+}
@@ -37,12 +37,31 @@ func runFlare(systemMultiSlogger *multislogger.MultiSlogger, args []string) erro
 		flSave             = flagset.String("save", "upload", "local | upload")
 		flOutputDir        = flagset.String("output_dir", ".", "path to directory to save flare output")
 		flUploadRequestURL = flagset.String("upload_request_url", "https://api.kolide.com/api/agent/flare", "URL to request a signed upload URL")
+		flSince            = flagset.String("since", "", "only collect what changed since: last (the previous flare), an RFC3339 time, or a duration before now, like 1h")
+		flSections         = flagset.String("sections", "", "comma-separated sections to collect, e.g. Logs,Osquery; all if empty")
+		flSectionBudgetMB  = flagset.Int64("section_budget_mb", checkups.DefaultSectionBudget/(1024*1024), "size budget for each section of the flare, in megabytes; 0 for no budget")
+		flRedact           = flagset.String("redact", "", "comma-separated classes of sensitive value to mask in the flare, in addition to those masked in launcher logs: emails, ips, serial_numbers, tokens")
 	)
 
 	if err := ff.Parse(flagset, args); err != nil {
 		return fmt.Errorf("parsing flags: %w", err)
 	}
 
+	flareOpts := []checkups.FlareOption{checkups.WithSectionBudget(*flSectionBudgetMB * 1024 * 1024)}
+	sinceOpt, err := checkups.ParseSince(*flSince)
+	if err != nil {
+		return fmt.Errorf("parsing since: %w", err)
+	}
+	if sinceOpt != nil {
+		flareOpts = append(flareOpts, sinceOpt)
+	}
+	if *flRedact != "" {
+		flareOpts = append(flareOpts, checkups.WithRedactionClasses(strings.Split(*flRedact, ",")))
+	}
+	if *flSections != "" {
+		flareOpts = append(flareOpts, checkups.WithSections(strings.Split(*flSections, ",")))
+	}
+
 	// were passing an empty array here just to get the default options
 	opts, err := launcher.ParseOptions("flareupload", make([]string, 0))
 	if err != nil {
@@ -96,7 +115,7 @@ func runFlare(systemMultiSlogger *multislogger.MultiSlogger, args []string) erro
 		return fmt.Errorf(`invalid save option: %s, expected "local" or "upload"`, *flSave)
 	}
 
-	if err := checkups.RunFlare(ctx, k, flareDest, checkups.StandaloneEnviroment); err != nil {
+	if err := checkups.RunFlare(ctx, k, flareDest, checkups.StandaloneEnviroment, flareOpts...); err != nil {
 		return err
 	}
 
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kolide/kit/version"
+)
+
+const (
+	lastExitFilename = "last_exit.json"
+)
+
+// ExitReason classifies why launcher exited. Each reason maps to a distinct exit code,
+// so that service managers and MDM scripts can tell failure classes apart without
+// having to parse logs.
+type ExitReason string
+
+const (
+	ExitReasonNone             ExitReason = "none"
+	ExitReasonUnknown          ExitReason = "unknown"
+	ExitReasonConfig           ExitReason = "config"
+	ExitReasonRootDirectory    ExitReason = "root_directory"
+	ExitReasonDatabase         ExitReason = "database"
+	ExitReasonUpdateRequested  ExitReason = "update_requested"
+	ExitReasonRestartRequested ExitReason = "restart_requested"
+)
+
+// exitCodes is the exit code contract. These values are relied upon externally,
+// so existing codes must never be changed or reused.
+var exitCodes = map[ExitReason]int{
+	ExitReasonNone:             0,
+	ExitReasonUnknown:          1,
+	ExitReasonConfig:           2,
+	ExitReasonRootDirectory:    3,
+	ExitReasonDatabase:         4,
+	ExitReasonUpdateRequested:  5,
+	ExitReasonRestartRequested: 6,
+}
+
+// ExitCode returns the process exit code for the given reason.
+func (r ExitReason) ExitCode() int {
+	if code, ok := exitCodes[r]; ok {
+		return code
+	}
+	return exitCodes[ExitReasonUnknown]
+}
+
+// ExitError wraps an error with the reason launcher should report when exiting because of it.
+type ExitError struct {
+	reason ExitReason
+	err    error
+}
+
+// NewExitError wraps err, marking it as a fatal error of the given class.
+func NewExitError(reason ExitReason, err error) error {
+	return &ExitError{reason: reason, err: err}
+}
+
+func (e *ExitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.err
+}
+
+// ExitReasonFor determines the exit reason for the given error. A nil error is a clean exit;
+// an error that was not classified via NewExitError is reported as unknown.
+func ExitReasonFor(err error) ExitReason {
+	if err == nil {
+		return ExitReasonNone
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.reason
+	}
+
+	return ExitReasonUnknown
+}
+
+// lastExit is the machine-readable record of launcher's most recent exit.
+type lastExit struct {
+	Reason    ExitReason `json:"reason"`
+	ExitCode  int        `json:"exit_code"`
+	Errors    []string   `json:"errors,omitempty"`
+	Timestamp string     `json:"timestamp"`
+	Version   string     `json:"version"`
+}
+
+// RecordLastExit writes a JSON description of why launcher is exiting to the root directory,
+// overwriting the record from any previous run.
+func RecordLastExit(rootDir string, reason ExitReason, exitErr error) error {
+	if rootDir == "" {
+		return errors.New("no root directory set")
+	}
+
+	raw, err := json.MarshalIndent(lastExit{
+		Reason:    reason,
+		ExitCode:  reason.ExitCode(),
+		Errors:    errorChain(exitErr),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Version:   version.Version().Version,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling last exit: %w", err)
+	}
+
+	// Write to a temporary file and rename, so that readers never see a partial file
+	lastExitPath := LastExitPath(rootDir)
+	tmpPath := lastExitPath + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0644); err != nil {
+		return fmt.Errorf("writing last exit file: %w", err)
+	}
+	if err := os.Rename(tmpPath, lastExitPath); err != nil {
+		return fmt.Errorf("renaming last exit file: %w", err)
+	}
+
+	return nil
+}
+
+// LastExitPath returns the location of the last exit file in the given root directory.
+func LastExitPath(rootDir string) string {
+	return filepath.Join(rootDir, lastExitFilename)
+}
+
+// errorChain returns the message of each error in err's chain, outermost first. Wrappers
+// that don't add anything to the message (like ExitError) are collapsed.
+func errorChain(err error) []string {
+	var chain []string
+	for ; err != nil; err = errors.Unwrap(err) {
+		msg := err.Error()
+		if len(chain) > 0 && chain[len(chain)-1] == msg {
+			continue
+		}
+		chain = append(chain, msg)
+	}
+	return chain
+}
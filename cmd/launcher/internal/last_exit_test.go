@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/kolide/kit/version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitReasonFor(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name           string
+		err            error
+		expectedReason ExitReason
+		expectedCode   int
+	}{
+		{name: "no error", err: nil, expectedReason: ExitReasonNone, expectedCode: 0},
+		{name: "unclassified", err: errors.New("test"), expectedReason: ExitReasonUnknown, expectedCode: 1},
+		{name: "config", err: NewExitError(ExitReasonConfig, errors.New("test")), expectedReason: ExitReasonConfig, expectedCode: 2},
+		{name: "wrapped database", err: fmt.Errorf("outer: %w", NewExitError(ExitReasonDatabase, errors.New("test"))), expectedReason: ExitReasonDatabase, expectedCode: 4},
+		{name: "unrecognized reason", err: NewExitError(ExitReason("not_a_reason"), errors.New("test")), expectedReason: ExitReason("not_a_reason"), expectedCode: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			reason := ExitReasonFor(tt.err)
+			require.Equal(t, tt.expectedReason, reason)
+			require.Equal(t, tt.expectedCode, reason.ExitCode())
+		})
+	}
+}
+
+func TestRecordLastExit(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+
+	err := fmt.Errorf("run service: %w", NewExitError(ExitReasonDatabase, fmt.Errorf("open launcher db: %w", errors.New("timeout"))))
+	require.NoError(t, RecordLastExit(rootDir, ExitReasonFor(err), err))
+
+	raw, err := os.ReadFile(LastExitPath(rootDir))
+	require.NoError(t, err)
+
+	var recorded lastExit
+	require.NoError(t, json.Unmarshal(raw, &recorded))
+	require.Equal(t, ExitReasonDatabase, recorded.Reason)
+	require.Equal(t, 4, recorded.ExitCode)
+	require.Equal(t, []string{"run service: open launcher db: timeout", "open launcher db: timeout", "timeout"}, recorded.Errors)
+	require.Equal(t, version.Version().Version, recorded.Version)
+	require.NotEmpty(t, recorded.Timestamp)
+
+	// A clean exit overwrites the previous record
+	require.NoError(t, RecordLastExit(rootDir, ExitReasonNone, nil))
+
+	raw, err = os.ReadFile(LastExitPath(rootDir))
+	require.NoError(t, err)
+
+	recorded = lastExit{}
+	require.NoError(t, json.Unmarshal(raw, &recorded))
+	require.Equal(t, ExitReasonNone, recorded.Reason)
+	require.Equal(t, 0, recorded.ExitCode)
+	require.Empty(t, recorded.Errors)
+
+	require.Error(t, RecordLastExit("", ExitReasonNone, nil))
+}
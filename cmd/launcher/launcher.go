@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
-	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -29,21 +28,40 @@ import (
 	"github.com/kolide/launcher/ee/agent/startupsettings"
 	"github.com/kolide/launcher/ee/agent/storage"
 	agentbbolt "github.com/kolide/launcher/ee/agent/storage/bbolt"
+	"github.com/kolide/launcher/ee/agent/storage/encrypted"
+	"github.com/kolide/launcher/ee/agent/storage/gc"
 	"github.com/kolide/launcher/ee/agent/timemachine"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/buildprofile"
+	"github.com/kolide/launcher/ee/consent"
 	"github.com/kolide/launcher/ee/control"
+	"github.com/kolide/launcher/ee/control/actionhistory"
 	"github.com/kolide/launcher/ee/control/actionqueue"
 	"github.com/kolide/launcher/ee/control/consumers/acceleratecontrolconsumer"
 	"github.com/kolide/launcher/ee/control/consumers/flareconsumer"
 	"github.com/kolide/launcher/ee/control/consumers/keyvalueconsumer"
 	"github.com/kolide/launcher/ee/control/consumers/notificationconsumer"
+	"github.com/kolide/launcher/ee/control/consumers/osquerydbresetconsumer"
+	"github.com/kolide/launcher/ee/control/consumers/probeconsumer"
 	"github.com/kolide/launcher/ee/control/consumers/remoterestartconsumer"
+	"github.com/kolide/launcher/ee/control/consumers/rootmigrationconsumer"
 	"github.com/kolide/launcher/ee/control/consumers/uninstallconsumer"
+	"github.com/kolide/launcher/ee/control/signedpayload"
 	"github.com/kolide/launcher/ee/debug/checkups"
 	desktopRunner "github.com/kolide/launcher/ee/desktop/runner"
+	"github.com/kolide/launcher/ee/fim"
 	"github.com/kolide/launcher/ee/gowrapper"
+	"github.com/kolide/launcher/ee/hostroot"
+	"github.com/kolide/launcher/ee/hostsfilewatcher"
+	"github.com/kolide/launcher/ee/ipstack"
 	"github.com/kolide/launcher/ee/localserver"
+	"github.com/kolide/launcher/ee/networkchangewatcher"
+	"github.com/kolide/launcher/ee/osquerydbreset"
 	"github.com/kolide/launcher/ee/powereventwatcher"
+	"github.com/kolide/launcher/ee/restartrequired"
+	"github.com/kolide/launcher/ee/supervisor"
+	"github.com/kolide/launcher/ee/tableschema"
 	"github.com/kolide/launcher/ee/tuf"
 	"github.com/kolide/launcher/ee/watchdog"
 	"github.com/kolide/launcher/pkg/augeas"
@@ -53,11 +71,14 @@ import (
 	"github.com/kolide/launcher/pkg/launcher"
 	"github.com/kolide/launcher/pkg/log/logshipper"
 	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/kolide/launcher/pkg/log/platformlog"
 	"github.com/kolide/launcher/pkg/log/teelogger"
 	"github.com/kolide/launcher/pkg/osquery"
+	"github.com/kolide/launcher/pkg/osquery/configmerge"
 	"github.com/kolide/launcher/pkg/osquery/runsimple"
 	osqueryruntime "github.com/kolide/launcher/pkg/osquery/runtime"
 	osqueryInstanceHistory "github.com/kolide/launcher/pkg/osquery/runtime/history"
+	"github.com/kolide/launcher/pkg/osquery/watchdogevents"
 	"github.com/kolide/launcher/pkg/rungroup"
 	"github.com/kolide/launcher/pkg/service"
 	"github.com/kolide/launcher/pkg/traces"
@@ -73,6 +94,12 @@ const (
 	desktopMenuSubsystemName = "kolide_desktop_menu"
 	authTokensSubsystemName  = "auth_tokens"
 	katcSubsystemName        = "katc_config" // Kolide ATC
+	fimSubsystemName         = "fim_config"  // file integrity monitoring
+
+	// Messages launcher sends to the control server
+	osqueryWatchdogEventMethod   = "osquery_watchdog_event"
+	osqueryConfigConflictsMethod = "osquery_config_conflicts"
+	osqueryDbResetMethod         = "osquery_db_reset"
 )
 
 // runLauncher is the entry point into running launcher. It creates a
@@ -102,18 +129,33 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 		"runLauncher starting",
 	)
 
+	// When running in a container to monitor the host, read host files and run host
+	// commands against the host's filesystem
+	if opts.HostRoot != "" {
+		hostroot.Set(opts.HostRoot)
+		slogger.Log(ctx, slog.LevelInfo,
+			"monitoring host through host root",
+			"host_root", opts.HostRoot,
+		)
+	}
+
+	// Run the commands our tables shell out to in a sandbox, limiting what a helper could do
+	// if its input exploited it
+	if currentExecutable, err := os.Executable(); err == nil {
+		allowedcmd.EnableSandbox(currentExecutable)
+	} else {
+		slogger.Log(ctx, slog.LevelWarn,
+			"could not get current executable, running commands without sandbox",
+			"err", err,
+		)
+	}
+
 	// We've seen launcher intermittently be unable to recover from
 	// DNS failures in the past, so this check gives us a little bit
 	// of room to ensure that we are able to resolve DNS requests
 	// before proceeding with starting launcher.
-	//
-	// Note that the SplitN won't work for bare ip6 addresses.
 	if err := backoff.WaitFor(func() error {
-		hostport := strings.SplitN(opts.KolideServerURL, ":", 2)
-		if len(hostport) < 1 {
-			return fmt.Errorf("unable to parse url: %s", opts.KolideServerURL)
-		}
-		_, lookupErr := net.LookupIP(hostport[0])
+		_, lookupErr := net.LookupIP(ipstack.Hostname(opts.KolideServerURL))
 		return lookupErr
 	}, 10*time.Second, 1*time.Second); err != nil {
 		slogger.Log(ctx, slog.LevelInfo,
@@ -130,7 +172,7 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 	if rootDirectory == "" {
 		rootDirectory, err = agent.MkdirTemp(launcher.DefaultRootDirectoryPath)
 		if err != nil {
-			return fmt.Errorf("creating temporary root directory: %w", err)
+			return internal.NewExitError(internal.ExitReasonRootDirectory, fmt.Errorf("creating temporary root directory: %w", err))
 		}
 
 		slogger.Log(ctx, slog.LevelInfo,
@@ -143,19 +185,19 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 	}
 
 	if err := os.MkdirAll(rootDirectory, fsutil.DirMode); err != nil {
-		return fmt.Errorf("creating root directory: %w", err)
+		return internal.NewExitError(internal.ExitReasonRootDirectory, fmt.Errorf("creating root directory: %w", err))
 	}
 	// Ensure permissions are correct, regardless of umask settings -- we use
 	// DirMode (0755) because the desktop processes that run as the user
 	// must be able to access the root directory as well.
 	if err := os.Chmod(rootDirectory, fsutil.DirMode); err != nil {
-		return fmt.Errorf("chmodding root directory: %w", err)
+		return internal.NewExitError(internal.ExitReasonRootDirectory, fmt.Errorf("chmodding root directory: %w", err))
 	}
 	if filepath.Dir(rootDirectory) == "/var/kolide-k2" {
 		// We need to ensure the same for the parent of the root directory, but we only
 		// want to do the same for Kolide-created directories.
 		if err := os.Chmod(filepath.Dir(rootDirectory), fsutil.DirMode); err != nil {
-			return fmt.Errorf("chmodding root directory parent: %w", err)
+			return internal.NewExitError(internal.ExitReasonRootDirectory, fmt.Errorf("chmodding root directory parent: %w", err))
 		}
 	}
 	startupSpan.AddEvent("root_directory_created")
@@ -177,7 +219,7 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 	boltOptions := &bbolt.Options{Timeout: time.Duration(30) * time.Second}
 	db, err := bbolt.Open(agentbbolt.LauncherDbLocation(rootDirectory), 0600, boltOptions)
 	if err != nil {
-		return fmt.Errorf("open launcher db: %w", err)
+		return internal.NewExitError(internal.ExitReasonDatabase, fmt.Errorf("open launcher db: %w", err))
 	}
 	defer db.Close()
 	startupSpan.AddEvent("database_opened")
@@ -188,13 +230,36 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 
 	stores, err := agentbbolt.MakeStores(ctx, slogger, db)
 	if err != nil {
-		return fmt.Errorf("failed to create stores: %w", err)
+		return internal.NewExitError(internal.ExitReasonDatabase, fmt.Errorf("failed to create stores: %w", err))
+	}
+
+	// Encrypt the stores holding secrets at rest. If the OS keystore is unavailable, we carry on
+	// in plaintext rather than fail to start -- unless the stores are already encrypted, in which
+	// case WrapSensitiveStores errors, and we exit rather than run on without our secrets.
+	storageKey, err := encrypted.LoadOrCreateKey(ctx, rootDirectory)
+	switch {
+	case errors.Is(err, encrypted.ErrKeystoreUnavailable):
+		slogger.Log(ctx, slog.LevelInfo,
+			"no OS keystore for the storage encryption key on this platform, keeping secrets in plaintext",
+		)
+	case err != nil:
+		slogger.Log(ctx, slog.LevelError,
+			"could not load storage encryption key",
+			"err", err,
+		)
+	}
+	if err := encrypted.WrapSensitiveStores(ctx, slogger, stores, storageKey); err != nil {
+		return internal.NewExitError(internal.ExitReasonDatabase, fmt.Errorf("encrypting sensitive stores: %w", err))
 	}
+	startupSpan.AddEvent("sensitive_stores_encrypted")
 
 	fcOpts := []flags.Option{flags.WithCmdLineOpts(opts)}
 	flagController := flags.NewFlagController(slogger, stores[storage.AgentFlagsStore], fcOpts...)
 	k := knapsack.New(stores, flagController, db, multiSlogger, systemMultiSlogger)
 
+	// Mask sensitive values in logs, as configured, before they're written or shipped anywhere
+	newLogRedactionUpdater(k, multiSlogger, systemMultiSlogger).update(ctx)
+
 	// Generate a new run ID
 	newRunID := k.GetRunID()
 
@@ -202,6 +267,14 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 	logger = log.With(logger, "run_id", newRunID)
 	slogger = slogger.With("run_id", newRunID)
 
+	if excluded := k.ExcludedSubsystems(); len(excluded) > 0 {
+		slogger.Log(ctx, slog.LevelInfo,
+			"this build of launcher excludes some subsystems, which will report themselves disabled",
+			"build_profile", buildprofile.Name,
+			"excluded_subsystems", excluded,
+		)
+	}
+
 	// start counting uptime
 	processStartTime := time.Now().UTC()
 
@@ -222,6 +295,21 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 		}))
 	}
 
+	// Mirror launcher logs into the platform's logging system, for collectors watching it. System logs
+	// aren't included -- they already reach the event log, or the service manager via stderr.
+	if k.ExportPlatformLogs() {
+		platformLogHandler, err := platformlog.NewHandler(platformlog.CategoryLauncher, slog.LevelInfo)
+		if err != nil {
+			slogger.Log(ctx, slog.LevelWarn,
+				"could not export logs to platform logging system",
+				"err", err,
+			)
+		} else {
+			defer platformLogHandler.Close()
+			multiSlogger.AddHandler(platformLogHandler)
+		}
+	}
+
 	// create a rungroup for all the actors we create to allow for easy start/stop
 	runGroup := rungroup.NewRunGroup()
 
@@ -269,7 +357,7 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 
 	startupSettingsWriter, err := startupsettings.OpenWriter(ctx, k)
 	if err != nil {
-		return fmt.Errorf("creating startup db: %w", err)
+		return internal.NewExitError(internal.ExitReasonDatabase, fmt.Errorf("creating startup db: %w", err))
 	}
 	defer startupSettingsWriter.Close()
 
@@ -288,16 +376,28 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 	dbBackupSaver := agentbbolt.NewDatabaseBackupSaver(k)
 	runGroup.Add("dbBackupSaver", dbBackupSaver.Execute, dbBackupSaver.Interrupt)
 
+	// Watch the hosts file and resolver config for the kolide_hosts_file_watch table
+	hostsFileWatcher := hostsfilewatcher.New(k)
+	runGroup.Add("hostsFileWatcher", hostsFileWatcher.Execute, hostsFileWatcher.Interrupt)
+
+	// Watch for network interface and route changes for the kolide_network_change_events table
+	networkChangeWatcher := networkchangewatcher.New(k)
+	runGroup.Add("networkChangeWatcher", networkChangeWatcher.Execute, networkChangeWatcher.Interrupt)
+
+	// Enforce the retention policies of stores that don't purge their own data
+	storageCollector := gc.New(k)
+	runGroup.Add("storageGc", storageCollector.Execute, storageCollector.Interrupt)
+
 	// create the certificate pool
 	var rootPool *x509.CertPool
 	if k.RootPEM() != "" {
 		rootPool = x509.NewCertPool()
 		pemContents, err := os.ReadFile(k.RootPEM())
 		if err != nil {
-			return fmt.Errorf("reading root certs PEM at path: %s: %w", k.RootPEM(), err)
+			return internal.NewExitError(internal.ExitReasonConfig, fmt.Errorf("reading root certs PEM at path: %s: %w", k.RootPEM(), err))
 		}
 		if ok := rootPool.AppendCertsFromPEM(pemContents); !ok {
-			return fmt.Errorf("found no valid certs in PEM at path: %s", k.RootPEM())
+			return internal.NewExitError(internal.ExitReasonConfig, fmt.Errorf("found no valid certs in PEM at path: %s", k.RootPEM()))
 		}
 	}
 
@@ -357,7 +457,7 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 		case "osquery":
 			client = service.NewNoopClient(logger)
 		default:
-			return errors.New("invalid transport option selected")
+			return internal.NewExitError(internal.ExitReasonConfig, errors.New("invalid transport option selected"))
 		}
 	}
 
@@ -395,32 +495,103 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 		traceExporter.SetOsqueryClient(osqueryRunner)
 	}
 
+	// The process supervisor runs auxiliary processes other than desktop, which the desktop runner manages
+	processSupervisor := supervisor.New(slogger)
+	runGroup.Add("processSupervisor", processSupervisor.Execute, processSupervisor.Interrupt)
+	supervisor.RegisterReporter(processSupervisor)
+
 	// Create the control service and services that depend on it
 	var runner *desktopRunner.DesktopUsersProcessesRunner
 	var actionsQueue *actionqueue.ActionQueue
+	var controlService *control.ControlService
 	if k.ControlServerURL() == "" {
 		slogger.Log(ctx, slog.LevelDebug,
 			"control server URL not set, will not create control service",
 		)
 	} else {
-		controlService, err := createControlService(ctx, k.ControlStore(), k)
+		controlService, err = createControlService(ctx, k.ControlStore(), k)
 		if err != nil {
 			return fmt.Errorf("failed to setup control service: %w", err)
 		}
 		runGroup.Add("controlService", controlService.ExecuteWithContext(ctx), controlService.Interrupt)
 
+		// actionHistory records the actions the control server takes on this device
+		actionHistory := actionhistory.New(slogger, k.ControlActionHistoryStore())
+
+		// signedPayloads verifies the signatures on sensitive control server payloads, and
+		// receives the signing keys to verify them with
+		signedPayloads, err := createSignedPayloadVerifier(k)
+		if err != nil {
+			return fmt.Errorf("failed to set up signed payload verification: %w", err)
+		}
+		controlService.RegisterConsumer(signedpayload.KeySetSubsystem, signedPayloads)
+
 		// serverDataConsumer handles server data table updates
 		controlService.RegisterConsumer(serverDataSubsystemName, keyvalueconsumer.New(k.ServerProvidedDataStore()))
 		// agentFlagConsumer handles agent flags pushed from the control server
-		controlService.RegisterConsumer(agentFlagsSubsystemName, keyvalueconsumer.New(flagController))
+		controlService.RegisterConsumer(agentFlagsSubsystemName, actionhistory.NewRecordingConsumer(actionHistory, agentFlagsSubsystemName, signedpayload.NewConsumer(signedPayloads, agentFlagsSubsystemName, keyvalueconsumer.New(flagController))))
 		// katcConfigConsumer handles updates to Kolide's custom ATC tables
 		controlService.RegisterConsumer(katcSubsystemName, keyvalueconsumer.NewConfigConsumer(k.KatcConfigStore()))
 		controlService.RegisterSubscriber(katcSubsystemName, osqueryRunner)
 		controlService.RegisterSubscriber(katcSubsystemName, startupSettingsWriter)
+		// tableSchemaPublisher sends the schema of our tables to the control server when it changes,
+		// including when the Kolide ATC tables do
+		tableSchemaPublisher := tableschema.New(k, controlService)
+		runGroup.Add("tableSchemaPublisher", tableSchemaPublisher.Execute, tableSchemaPublisher.Interrupt)
+		controlService.RegisterSubscriber(katcSubsystemName, tableSchemaPublisher)
+		// fimConsumer handles updates to the file integrity monitoring spec; osquery must
+		// restart to pick up the changed paths and event flags. The spec decides what files are
+		// watched, so it must be signed.
+		controlService.RegisterConsumer(fimSubsystemName, signedpayload.NewConsumer(signedPayloads, fimSubsystemName, fim.NewConsumer(k.FimConfigStore())))
+		controlService.RegisterSubscriber(fimSubsystemName, osqueryRunner)
+		// osquerydSelectionConsumer handles the osqueryd selected for individual registrations, which
+		// the autoupdater subscribes to below. Selections can run customer-hosted builds, so they must be signed.
+		controlService.RegisterConsumer(tuf.OsquerydSelectionSubsystemName, actionhistory.NewRecordingConsumer(actionHistory, tuf.OsquerydSelectionSubsystemName, signedpayload.NewConsumer(signedPayloads, tuf.OsquerydSelectionSubsystemName, keyvalueconsumer.NewConfigConsumer(k.OsquerydSelectionStore()))))
+		// report osquery watchdog kills to the control server as they happen
+		watchdogevents.Subscribe(func(event watchdogevents.Event) {
+			if err := controlService.SendMessage(osqueryWatchdogEventMethod, event); err != nil {
+				slogger.Log(ctx, slog.LevelWarn,
+					"could not report osquery watchdog event",
+					"event_id", event.ID,
+					"err", err,
+				)
+			}
+		})
+		// report conflicts between osquery config sources to the control server as they change
+		configmerge.Subscribe(func(report configmerge.Report) {
+			if err := controlService.SendMessage(osqueryConfigConflictsMethod, report); err != nil {
+				slogger.Log(ctx, slog.LevelWarn,
+					"could not report osquery config conflicts",
+					"registration_id", report.RegistrationID,
+					"err", err,
+				)
+			}
+		})
+		// report osquery database resets to the control server, including those done by the
+		// reset-osquery-db subcommand while launcher wasn't running
+		osquerydbreset.Subscribe(func(report osquerydbreset.Report) {
+			if err := controlService.SendMessage(osqueryDbResetMethod, report); err != nil {
+				slogger.Log(ctx, slog.LevelWarn,
+					"could not report osquery database reset",
+					"registration_id", report.RegistrationID,
+					"err", err,
+				)
+			}
+		})
+		if err := osquerydbreset.PublishPending(ctx, slogger, k.PersistentHostDataStore()); err != nil {
+			slogger.Log(ctx, slog.LevelWarn,
+				"could not report pending osquery database resets",
+				"err", err,
+			)
+		}
+
+		// consentTracker records the user's data collection consent decisions from the desktop menu,
+		// and passes every other message from desktop on to the control server
+		consentTracker := consent.New(k, controlService)
 
 		runner, err = desktopRunner.New(
 			k,
-			controlService,
+			consentTracker,
 			desktopRunner.WithAuthToken(ulid.New()),
 			desktopRunner.WithUsersFilesRoot(rootDirectory),
 		)
@@ -435,14 +606,21 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 		runGroup.Add("hardwareKeys", execute, interrupt)
 
 		runGroup.Add("desktopRunner", runner.Execute, runner.Interrupt)
+		supervisor.RegisterReporter(runner)
 		controlService.RegisterConsumer(desktopMenuSubsystemName, runner)
 
+		consentTracker.SetNotifier(runner)
+		runGroup.Add("dataCollectionConsent", consentTracker.Execute, consentTracker.Interrupt)
+		// The consent policy decides which tables are kept out of queries, so it must be signed
+		controlService.RegisterConsumer(consent.Subsystem, signedpayload.NewConsumer(signedPayloads, consent.Subsystem, consentTracker))
+
 		// create an action queue for all other action style commands
 		actionsQueue = actionqueue.New(
 			k,
 			actionqueue.WithContext(ctx),
 			actionqueue.WithStore(k.ControlServerActionsStore()),
 			actionqueue.WithOldNotificationsStore(k.SentNotificationsStore()),
+			actionqueue.WithHistory(actionHistory),
 		)
 		runGroup.Add("actionsQueue", actionsQueue.StartCleanup, actionsQueue.StopCleanup)
 		controlService.RegisterConsumer(actionqueue.ActionsSubsystem, actionsQueue)
@@ -450,9 +628,19 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 		// register accelerate control consumer
 		actionsQueue.RegisterActor(acceleratecontrolconsumer.AccelerateControlSubsystem, acceleratecontrolconsumer.New(k))
 		// register uninstall consumer
-		actionsQueue.RegisterActor(uninstallconsumer.UninstallSubsystem, uninstallconsumer.New(k))
-		// register flare consumer
-		actionsQueue.RegisterActor(flareconsumer.FlareSubsystem, flareconsumer.New(k))
+		actionsQueue.RegisterActor(uninstallconsumer.UninstallSubsystem, signedpayload.NewActor(signedPayloads, uninstallconsumer.New(k)))
+		// register flare consumer, and finish any flare uploads interrupted by a restart
+		flareConsumer := flareconsumer.New(ctx, k)
+		actionsQueue.RegisterActor(flareconsumer.FlareSubsystem, signedpayload.NewActor(signedPayloads, flareConsumer))
+		gowrapper.Go(ctx, slogger, func() {
+			flareConsumer.ResumePendingUploads(ctx)
+		})
+		// register connectivity probe consumer
+		actionsQueue.RegisterActor(probeconsumer.ProbeSubsystem, signedpayload.NewActor(signedPayloads, probeconsumer.New(k)))
+		// register root migration consumer
+		actionsQueue.RegisterActor(rootmigrationconsumer.RootMigrationSubsystem, signedpayload.NewActor(signedPayloads, rootmigrationconsumer.New(k)))
+		// register osquery database reset consumer
+		actionsQueue.RegisterActor(osquerydbresetconsumer.OsqueryDbResetSubsystem, signedpayload.NewActor(signedPayloads, osquerydbresetconsumer.New(k, osqueryRunner)))
 		// register force full control data fetch consumer
 		actionsQueue.RegisterActor(control.ForceFullControlDataFetchAction, controlService)
 
@@ -473,6 +661,11 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 		runGroup.Add("remoteRestart", remoteRestartConsumer.Execute, remoteRestartConsumer.Interrupt)
 		actionsQueue.RegisterActor(remoterestartconsumer.RemoteRestartActorType, remoteRestartConsumer)
 
+		// restartRequiredTracker tracks pending device restarts, and coordinates reminding the user about them
+		restartRequiredTracker := restartrequired.New(k, runner, controlService)
+		runGroup.Add("restartRequiredTracker", restartRequiredTracker.Execute, restartRequiredTracker.Interrupt)
+		controlService.RegisterConsumer(restartrequired.Subsystem, restartRequiredTracker)
+
 		// Set up our tracing instrumentation
 		authTokenConsumer := keyvalueconsumer.New(k.TokenStore())
 		if err := controlService.RegisterConsumer(authTokensSubsystemName, authTokenConsumer); err != nil {
@@ -526,57 +719,10 @@ func runLauncher(ctx context.Context, cancel func(), multiSlogger, systemMultiSl
 		runGroup.Add("localserver", ls.Start, ls.Interrupt)
 	}
 
-	// If autoupdating is enabled, run the autoupdater
+	// If autoupdating is enabled, run the autoupdater. Minimal builds exclude it.
 	if k.Autoupdate() {
-		metadataClient := http.DefaultClient
-		metadataClient.Timeout = 30 * time.Second
-		mirrorClient := http.DefaultClient
-		mirrorClient.Timeout = 8 * time.Minute // gives us extra time to avoid a timeout on download
-		tufAutoupdater, err := tuf.NewTufAutoupdater(
-			ctx,
-			k,
-			metadataClient,
-			mirrorClient,
-			osqueryRunner,
-			tuf.WithOsqueryRestart(osqueryRunner.Restart),
-		)
-		if err != nil {
-			return fmt.Errorf("creating TUF autoupdater updater: %w", err)
-		}
-
-		runGroup.Add("tufAutoupdater", tufAutoupdater.Execute, tufAutoupdater.Interrupt)
-		if actionsQueue != nil {
-			actionsQueue.RegisterActor(tuf.AutoupdateSubsystemName, tufAutoupdater)
-		}
-
-		// in some cases, (e.g. rolling back a windows installation to a previous osquery version) it is possible that
-		// the installer leaves us in a situation where there is no osqueryd on disk.
-		// we can detect this and attempt to download the correct version into the TUF update library to run from that.
-		// This must be done as a blocking operation before the rungroups start, because the osquery runner will fail to
-		// launch and trigger a restart immediately
-		currentOsquerydBinaryPath := k.LatestOsquerydPath(ctx)
-		if _, err = os.Stat(currentOsquerydBinaryPath); os.IsNotExist(err) {
-			slogger.Log(ctx, slog.LevelInfo,
-				"detected missing osqueryd executable, will attempt to download",
-			)
-
-			startupSpan.AddEvent("osqueryd_startup_download_start")
-			// simulate control server request for immediate update, noting to bypass the initial delay window
-			actionReader := strings.NewReader(`{
-				"bypass_initial_delay": true,
-				"binaries_to_update": [
-					{ "name": "osqueryd" }
-				]
-			}`)
-
-			if err = tufAutoupdater.Do(actionReader); err != nil {
-				slogger.Log(ctx, slog.LevelError,
-					"failure triggering immediate osquery update",
-					"err", err,
-				)
-			}
-
-			startupSpan.AddEvent("osqueryd_startup_download_completed")
+		if err := runAutoupdater(ctx, k, slogger, runGroup, osqueryRunner, actionsQueue, controlService, startupSpan); err != nil {
+			return err
 		}
 	}
 
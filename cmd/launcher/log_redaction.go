@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/agent/flags/keys"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/kolide/launcher/pkg/log/redact"
+)
+
+// logRedactionUpdater keeps the redaction of launcher's logs in step with the
+// log_redaction_classes flag, which the control server can change.
+type logRedactionUpdater struct {
+	knapsack types.Knapsack
+	sloggers []*multislogger.MultiSlogger
+}
+
+func newLogRedactionUpdater(k types.Knapsack, sloggers ...*multislogger.MultiSlogger) *logRedactionUpdater {
+	u := &logRedactionUpdater{
+		knapsack: k,
+		sloggers: sloggers,
+	}
+	k.RegisterChangeObserver(u, keys.LogRedactionClasses)
+	return u
+}
+
+// FlagsChanged satisfies the types.FlagsChangeObserver interface.
+func (u *logRedactionUpdater) FlagsChanged(ctx context.Context, flagKeys ...keys.FlagKey) {
+	u.update(ctx)
+}
+
+func (u *logRedactionUpdater) update(ctx context.Context) {
+	redactor, errs := redact.New(u.knapsack.LogRedactionClasses())
+	for _, s := range u.sloggers {
+		s.SetRedactor(redactor)
+	}
+
+	for _, err := range errs {
+		u.knapsack.Slogger().Log(ctx, slog.LevelWarn,
+			"ignoring log redaction class",
+			"err", err,
+		)
+	}
+	u.knapsack.Slogger().Log(ctx, slog.LevelInfo,
+		"updated log redaction",
+		"classes", redactor.Classes(),
+	)
+}
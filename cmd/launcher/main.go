@@ -15,7 +15,11 @@ import (
 	"github.com/kolide/kit/env"
 	"github.com/kolide/kit/logutil"
 	"github.com/kolide/kit/version"
+	"github.com/kolide/launcher/cmd/launcher/internal"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/buildprofile"
 	"github.com/kolide/launcher/ee/control/consumers/remoterestartconsumer"
+	"github.com/kolide/launcher/ee/debug/crashreport"
 	"github.com/kolide/launcher/ee/tuf"
 	"github.com/kolide/launcher/ee/watchdog"
 	"github.com/kolide/launcher/pkg/contexts/ctxlog"
@@ -27,6 +31,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// crashReportLogLines is the number of recent log lines to include in a crash report
+const crashReportLogLines = 500
+
 func main() {
 	os.Exit(runMain()) //nolint:forbidigo // Our only allowed usage of os.Exit is in this function
 }
@@ -35,6 +42,14 @@ func main() {
 // or running `runLauncher`. We wrap it so that all our deferred calls will execute before we call
 // `os.Exit` in `main()`.
 func runMain() int {
+	// Sandboxed commands run through launcher on Linux. Handle them before anything else,
+	// since this runs for every command.
+	if len(os.Args) > 1 && os.Args[1] == allowedcmd.SandboxSubcommand {
+		err := allowedcmd.RunSandboxed(os.Args[2:])
+		fmt.Fprintf(os.Stderr, "could not run sandboxed command: %v\n", err)
+		return 126
+	}
+
 	systemSlogger, logCloser, err := multislogger.SystemSlogger()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error creating system logger: %v\n", err)
@@ -110,7 +125,7 @@ func runMain() int {
 			"could not parse options",
 			"err", err,
 		)
-		return 0
+		return internal.ExitReasonConfig.ExitCode()
 	}
 
 	// recreate the logger with  the appropriate level.
@@ -135,6 +150,9 @@ func runMain() int {
 		systemSlogger.AddHandler(localSloggerHandler)
 	}
 
+	// Retain recent logs in memory, to include in a crash report if we panic
+	recentLogs := newCrashReportLogRing(slogger)
+
 	defer func() {
 		if r := recover(); r != nil {
 			level.Info(logger).Log(
@@ -147,6 +165,7 @@ func runMain() int {
 					"stack_trace", fmt.Sprintf("%+v", errors.WithStack(err)),
 				)
 			}
+			writeCrashReport(ctx, systemSlogger.Logger, opts.RootDirectory, r, recentLogs)
 			time.Sleep(time.Second)
 		}
 	}()
@@ -157,14 +176,26 @@ func runMain() int {
 		// launcher exited due to error that does not require further handling -- return now so we can exit
 		if !tuf.IsLauncherReloadNeededErr(err) && !errors.Is(err, remoterestartconsumer.ErrRemoteRestartRequested) {
 			level.Debug(logger).Log("msg", "run launcher", "stack", fmt.Sprintf("%+v", err))
-			return 1
+			return recordLastExit(ctx, slogger.Logger, opts.RootDirectory, internal.ExitReasonFor(err), err)
+		}
+
+		// Record why we're going away before exec'ing. If the exec succeeds, the new process
+		// will overwrite this record when it exits.
+		reason := internal.ExitReasonRestartRequested
+		if tuf.IsLauncherReloadNeededErr(err) {
+			reason = internal.ExitReasonUpdateRequested
 		}
+		recordLastExit(ctx, slogger.Logger, opts.RootDirectory, reason, err)
 
 		// Autoupdate asked for a restart to run the newly-downloaded version of launcher -- run that newer version
 		if tuf.IsLauncherReloadNeededErr(err) {
 			level.Debug(logger).Log("msg", "runLauncher exited to load newer version of launcher after autoupdate", "err", err.Error())
 			if err := runNewerLauncherIfAvailable(ctx, slogger.Logger); err != nil {
-				return 1
+				// We couldn't run the newer version -- fall back to restarting the current one
+				slogger.Log(ctx, slog.LevelError,
+					"could not run newer version of launcher after autoupdate, restarting current version",
+					"err", err,
+				)
 			}
 		}
 
@@ -175,7 +206,7 @@ func runMain() int {
 		currentExecutable, err := os.Executable()
 		if err != nil {
 			level.Debug(logger).Log("msg", "could not get current executable to perform remote restart", "err", err.Error())
-			return 1
+			return recordLastExit(ctx, slogger.Logger, opts.RootDirectory, internal.ExitReasonUnknown, err)
 		}
 		if err := execwrapper.Exec(ctx, currentExecutable, os.Args, os.Environ()); err != nil {
 			slogger.Log(ctx, slog.LevelError,
@@ -183,12 +214,55 @@ func runMain() int {
 				"binary", currentExecutable,
 				"err", err,
 			)
-			return 1
+			return recordLastExit(ctx, slogger.Logger, opts.RootDirectory, internal.ExitReasonUnknown, err)
 		}
 	}
 
 	// launcher exited without error -- nothing to do here
-	return 0
+	return recordLastExit(ctx, slogger.Logger, opts.RootDirectory, internal.ExitReasonNone, nil)
+}
+
+// recordLastExit writes the last exit file to the root directory, and returns the exit code
+// corresponding to the given reason.
+func recordLastExit(ctx context.Context, slogger *slog.Logger, rootDirectory string, reason internal.ExitReason, err error) int {
+	if err := internal.RecordLastExit(rootDirectory, reason, err); err != nil {
+		slogger.Log(ctx, slog.LevelWarn,
+			"could not record last exit",
+			"reason", reason,
+			"err", err,
+		)
+	}
+
+	return reason.ExitCode()
+}
+
+// newCrashReportLogRing returns a log ring that retains the recent logs from slogger.
+func newCrashReportLogRing(slogger *multislogger.MultiSlogger) *crashreport.LogRing {
+	recentLogs := crashreport.NewLogRing(crashReportLogLines)
+	slogger.AddHandler(slog.NewJSONHandler(recentLogs, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelDebug,
+	}))
+	return recentLogs
+}
+
+// writeCrashReport writes a crash report for the recovered panic value r. It must be called
+// from the deferred function that recovered the panic.
+func writeCrashReport(ctx context.Context, slogger *slog.Logger, rootDirectory string, r any, recentLogs *crashreport.LogRing) {
+	reportPath, err := crashreport.Write(rootDirectory, r, recentLogs)
+	if err != nil {
+		slogger.Log(ctx, slog.LevelWarn,
+			"could not write crash report",
+			"path", reportPath,
+			"err", err,
+		)
+		return
+	}
+
+	slogger.Log(ctx, slog.LevelInfo,
+		"wrote crash report",
+		"path", reportPath,
+	)
 }
 
 func runSubcommands(systemMultiSlogger *multislogger.MultiSlogger) error {
@@ -206,14 +280,26 @@ func runSubcommands(systemMultiSlogger *multislogger.MultiSlogger) error {
 		run = runVersion
 	case "compactdb":
 		run = runCompactDb
+	case "enroll-status":
+		run = runEnrollStatus
 	case "interactive":
 		run = runInteractive
+	case "collect":
+		run = runCollect
+	case "query":
+		run = runQuery
 	case "desktop":
 		run = runDesktop
 	case "download-osquery":
 		run = runDownloadOsquery
 	case "uninstall":
 		run = runUninstall
+	case "schema":
+		run = runSchema
+	case "migrate-root":
+		run = runMigrateRoot
+	case "reset-osquery-db":
+		run = runResetOsqueryDb
 	case "watchdog": // note: this is currently only implemented for windows
 		run = watchdog.RunWatchdogTask
 	default:
@@ -230,6 +316,11 @@ func runSubcommands(systemMultiSlogger *multislogger.MultiSlogger) error {
 // runNewerLauncherIfAvailable checks the autoupdate library for a newer version
 // of launcher than the currently-running one. If found, it will exec that version.
 func runNewerLauncherIfAvailable(ctx context.Context, slogger *slog.Logger) error {
+	// Builds without the autoupdater run as built, rather than any version an earlier build left
+	if !buildprofile.Includes(buildprofile.Autoupdate) {
+		return nil
+	}
+
 	newerBinary, err := latestLauncherPath(ctx, slogger)
 	if err != nil {
 		slogger.Log(ctx, slog.LevelError,
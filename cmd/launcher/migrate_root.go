@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kolide/launcher/ee/rootmigration"
+	"github.com/kolide/launcher/pkg/launcher"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+)
+
+// runMigrateRoot moves this launcher installation to a new root directory and/or identifier,
+// stopping and restarting launcher's service around the move.
+func runMigrateRoot(systemMultiSlogger *multislogger.MultiSlogger, args []string) error {
+	var (
+		flagset      = flag.NewFlagSet("launcher migrate-root", flag.ExitOnError)
+		flTo         = flagset.String("to", "", "the new root directory (default: the current root directory)")
+		flIdentifier = flagset.String("identifier", "", "the new identifier (default: the current identifier)")
+		flConfig     = flagset.String("config", launcher.DefaultPath(launcher.ConfigFile), "the installation's launcher flags configuration file")
+	)
+
+	flagset.Usage = commandUsage(flagset, "launcher migrate-root")
+	if err := flagset.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	// Read the installation's current root directory and identifier from its config
+	opts, err := launcher.ParseOptions("migrate-root", []string{"--config", *flConfig})
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", *flConfig, err)
+	}
+	if opts.RootDirectory == "" {
+		return errors.New("no root directory configured")
+	}
+
+	m := rootmigration.Migration{
+		FromRootDirectory: opts.RootDirectory,
+		ToRootDirectory:   *flTo,
+		FromIdentifier:    opts.Identifier,
+		ToIdentifier:      *flIdentifier,
+		ConfigFilePath:    *flConfig,
+	}
+	if m.ToRootDirectory == "" {
+		m.ToRootDirectory = m.FromRootDirectory
+	}
+	if m.ToIdentifier == "" {
+		m.ToIdentifier = m.FromIdentifier
+	}
+
+	systemMultiSlogger.AddHandler(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	return rootmigration.Run(ctx, systemMultiSlogger.Logger, m)
+}
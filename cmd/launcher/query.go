@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/pkg/launcher"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/kolide/launcher/pkg/osquery/collect"
+	"github.com/peterbourgon/ff/v3"
+)
+
+// queryName is the name the query is run under in the transient osqueryd; it's not shown
+const queryName = "query"
+
+// runQuery runs a single query against a transient osqueryd with launcher's tables loaded, prints
+// the rows, and exits. It lets support run launcher tables on a host without enrolling, or
+// without the interactive shell.
+func runQuery(systemMultiSlogger *multislogger.MultiSlogger, args []string) error {
+	attachConsole()
+	defer detachConsole()
+
+	launcher.SetDefaultPaths()
+
+	var (
+		flagset        = flag.NewFlagSet("query", flag.ExitOnError)
+		flFormat       = flagset.String("format", "json", "output format, json or csv")
+		flOsquerydPath = flagset.String("osqueryd_path", "", "path to osqueryd binary (defaults to the latest installed osqueryd)")
+		flTimeout      = flagset.Duration("timeout", 5*time.Minute, "maximum time to spend running the query")
+		flDebug        = flagset.Bool("debug", false, "whether or not debug logging is enabled")
+		flRootDir      = flagset.String("root_directory", launcher.DefaultRootDirectoryPath, "the installed launcher's root directory, whose data collection consent is respected")
+		flOsqueryFlags launcher.ArrayFlags
+	)
+	flagset.Var(&flOsqueryFlags, "osquery_flag", "Flags to pass to osquery (possibly overriding Launcher defaults)")
+	flagset.Usage = func() {
+		fmt.Fprintf(flagset.Output(), "Usage: launcher query [flags] <sql>\n\nWith no SQL, or -, the query is read from stdin.\n\n")
+		flagset.PrintDefaults()
+	}
+
+	if err := ff.Parse(flagset, args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	if *flFormat != "json" && *flFormat != "csv" {
+		return fmt.Errorf("unknown format %s, expected json or csv", *flFormat)
+	}
+
+	sql, err := readQuery(flagset.Args(), os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	// Logs go to stderr, so that results can be written to stdout
+	slogLevel := slog.LevelWarn
+	if *flDebug {
+		slogLevel = slog.LevelDebug
+	}
+	systemMultiSlogger.AddHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level:     slogLevel,
+		AddSource: true,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *flTimeout)
+	defer cancel()
+
+	queries := map[string]string{queryName: sql}
+	removed, err := gateConsentQueries(ctx, systemMultiSlogger.Logger, *flRootDir, queries)
+	if err != nil {
+		return err
+	}
+	if table, blocked := removed[queryName]; blocked {
+		return fmt.Errorf("query uses %s, which is blocked pending data collection consent", table)
+	}
+
+	k, collectRootDir, err := newCollectKnapsack(systemMultiSlogger, *flOsquerydPath, flOsqueryFlags, *flDebug)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(collectRootDir)
+
+	results, err := collect.Run(ctx, k, collectRootDir, queries)
+	if err != nil {
+		return fmt.Errorf("running query: %w", err)
+	}
+	if queryErr, failed := results.Errors[queryName]; failed {
+		return fmt.Errorf("query failed: %s", queryErr)
+	}
+
+	return writeQueryRows(os.Stdout, *flFormat, results.Results[queryName])
+}
+
+// readQuery returns the SQL given as arguments, or read from stdin if there are none, or the
+// only argument is -.
+func readQuery(args []string, stdin io.Reader) (string, error) {
+	sql := strings.Join(args, " ")
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading query from stdin: %w", err)
+		}
+		sql = string(input)
+	}
+
+	sql = strings.TrimSpace(sql)
+	if sql == "" {
+		return "", errors.New("no query provided")
+	}
+	return sql, nil
+}
+
+func writeQueryRows(w io.Writer, format string, rows []map[string]string) error {
+	if format == "csv" {
+		return collect.WriteCSV(w, rows)
+	}
+
+	if rows == nil {
+		rows = []map[string]string{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(rows); err != nil {
+		return fmt.Errorf("encoding rows: %w", err)
+	}
+	return nil
+}
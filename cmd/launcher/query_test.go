@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadQuery(t *testing.T) {
+	t.Parallel()
+
+	sql, err := readQuery([]string{"select * from kolide_chrome_extensions"}, strings.NewReader("ignored"))
+	require.NoError(t, err)
+	require.Equal(t, "select * from kolide_chrome_extensions", sql)
+
+	// Unquoted queries arrive as several arguments
+	sql, err = readQuery([]string{"select", "*", "from", "time"}, strings.NewReader(""))
+	require.NoError(t, err)
+	require.Equal(t, "select * from time", sql)
+
+	sql, err = readQuery(nil, strings.NewReader("select * from time;\n"))
+	require.NoError(t, err)
+	require.Equal(t, "select * from time;", sql)
+
+	sql, err = readQuery([]string{"-"}, strings.NewReader("select 1"))
+	require.NoError(t, err)
+	require.Equal(t, "select 1", sql)
+
+	_, err = readQuery(nil, strings.NewReader("  \n"))
+	require.Error(t, err)
+}
+
+func TestWriteQueryRows(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, writeQueryRows(&buf, "json", nil))
+	require.Equal(t, "[]\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeQueryRows(&buf, "csv", []map[string]string{{"b": "2", "a": "1"}}))
+	require.Equal(t, "a,b\n1,2\n", buf.String())
+}
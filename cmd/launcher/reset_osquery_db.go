@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	agentbbolt "github.com/kolide/launcher/ee/agent/storage/bbolt"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/osquerydbreset"
+	"github.com/kolide/launcher/ee/rootmigration"
+	"github.com/kolide/launcher/pkg/launcher"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"go.etcd.io/bbolt"
+)
+
+// runResetOsqueryDb resets a corrupted osquery database, stopping and restarting launcher's
+// service around the reset.
+func runResetOsqueryDb(systemMultiSlogger *multislogger.MultiSlogger, args []string) error {
+	var (
+		flagset        = flag.NewFlagSet("launcher reset-osquery-db", flag.ExitOnError)
+		flRegistration = flagset.String("registration", types.DefaultRegistrationID, "the registration whose osquery database to reset")
+		flReason       = flagset.String("reason", "", "why the database is being reset, for the report to the control server")
+		flConfig       = flagset.String("config", launcher.DefaultPath(launcher.ConfigFile), "the installation's launcher flags configuration file")
+	)
+
+	flagset.Usage = commandUsage(flagset, "launcher reset-osquery-db")
+	if err := flagset.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	opts, err := launcher.ParseOptions("reset-osquery-db", []string{"--config", *flConfig})
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", *flConfig, err)
+	}
+	if opts.RootDirectory == "" {
+		return errors.New("no root directory configured")
+	}
+
+	systemMultiSlogger.AddHandler(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+	slogger := systemMultiSlogger.Logger
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := rootmigration.StopService(ctx, opts.Identifier); err != nil {
+		return fmt.Errorf("stopping launcher: %w", err)
+	}
+	defer func() {
+		if err := rootmigration.StartService(ctx, opts.Identifier); err != nil {
+			slogger.Log(ctx, slog.LevelError,
+				"could not restart launcher after osquery database reset",
+				"err", err,
+			)
+		}
+	}()
+
+	if err := rootmigration.EnsureNotInUse(opts.RootDirectory); err != nil {
+		return err
+	}
+
+	// Enrollment and the rest of launcher.db are left alone
+	db, err := bbolt.Open(agentbbolt.LauncherDbLocation(opts.RootDirectory), 0600, &bbolt.Options{Timeout: time.Minute})
+	if err != nil {
+		return fmt.Errorf("opening launcher db: %w", err)
+	}
+	defer db.Close()
+
+	snapshotStore, err := agentbbolt.NewStore(ctx, slogger, db, storage.SnapshotDiffStore.String())
+	if err != nil {
+		return fmt.Errorf("opening snapshot store: %w", err)
+	}
+	persistentHostDataStore, err := agentbbolt.NewStore(ctx, slogger, db, storage.PersistentHostDataStore.String())
+	if err != nil {
+		return fmt.Errorf("opening persistent host data store: %w", err)
+	}
+
+	report, err := osquerydbreset.Reset(opts.RootDirectory, snapshotStore, *flRegistration, time.Now())
+	report.Trigger = osquerydbreset.TriggerSubcommand
+	report.Reason = *flReason
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	// launcher sends the report to the control server when it starts
+	if recordErr := osquerydbreset.RecordPending(persistentHostDataStore, report); recordErr != nil {
+		slogger.Log(ctx, slog.LevelWarn,
+			"could not record osquery database reset for reporting",
+			"err", recordErr,
+		)
+	}
+
+	if err != nil {
+		return fmt.Errorf("resetting osquery database: %w", err)
+	}
+
+	slogger.Log(ctx, slog.LevelInfo,
+		"reset osquery database",
+		"registration_id", report.RegistrationID,
+		"archive_path", report.ArchivePath,
+		"cleared_snapshots", report.ClearedSnapshots,
+	)
+
+	return nil
+}
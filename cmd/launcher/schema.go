@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/agent/flags"
+	"github.com/kolide/launcher/ee/agent/knapsack"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/launcher"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/kolide/launcher/pkg/osquery/table"
+)
+
+// runSchema prints the schema of the tables launcher registers with osquery on this platform.
+func runSchema(systemMultiSlogger *multislogger.MultiSlogger, args []string) error {
+	attachConsole()
+	defer detachConsole()
+
+	var (
+		flagset         = flag.NewFlagSet("schema", flag.ExitOnError)
+		flJson          = flagset.Bool("json", false, "print the schema as JSON")
+		flRootDirectory = flagset.String("root_directory", "", "launcher root directory, to include Kolide ATC tables from its startup settings")
+		flDebug         = flagset.Bool("debug", false, "whether or not debug logging is enabled")
+	)
+	if err := flagset.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	// Logs go to stderr, so that the schema can be written to stdout
+	slogLevel := slog.LevelWarn
+	if *flDebug {
+		slogLevel = slog.LevelDebug
+	}
+	systemMultiSlogger.AddHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level:     slogLevel,
+		AddSource: true,
+	}))
+
+	// Without a root directory, use an empty one, rather than creating startup settings in the
+	// working directory while looking for Kolide ATC tables
+	rootDirectory := *flRootDirectory
+	if rootDirectory == "" {
+		tempRootDir, err := agent.MkdirTemp("launcher-schema")
+		if err != nil {
+			return fmt.Errorf("creating temp root directory: %w", err)
+		}
+		defer os.RemoveAll(tempRootDir)
+		rootDirectory = tempRootDir
+	}
+
+	opts := &launcher.Options{
+		RootDirectory: rootDirectory,
+		Debug:         *flDebug,
+	}
+	flagController := flags.NewFlagController(systemMultiSlogger.Logger, inmemory.NewStore(), flags.WithCmdLineOpts(opts))
+	k := knapsack.New(nil, flagController, nil, systemMultiSlogger, nil)
+
+	schema := table.BuildSchema(table.RegisteredTables(k, types.DefaultRegistrationID, systemMultiSlogger.Logger, ""))
+
+	if *flJson {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(schema); err != nil {
+			return fmt.Errorf("writing schema: %w", err)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, t := range schema.Tables {
+		fmt.Fprintf(w, "%s\t%s\n", t.Name, t.Description)
+		for _, c := range t.Columns {
+			fmt.Fprintf(w, "  %s\t%s\n", c.Name, c.Type)
+		}
+	}
+
+	return w.Flush()
+}
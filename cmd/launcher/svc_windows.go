@@ -15,6 +15,8 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/kolide/kit/logutil"
 	"github.com/kolide/kit/version"
+	"github.com/kolide/launcher/cmd/launcher/internal"
+	"github.com/kolide/launcher/ee/debug/crashreport"
 	"github.com/kolide/launcher/ee/gowrapper"
 	"github.com/kolide/launcher/pkg/contexts/ctxlog"
 	"github.com/kolide/launcher/pkg/launcher"
@@ -73,6 +75,9 @@ func runWindowsSvc(systemSlogger *multislogger.MultiSlogger, args []string) erro
 		systemSlogger.AddHandler(localSloggerHandler)
 	}
 
+	// Retain recent logs in memory, to include in a crash report if we panic
+	recentLogs := newCrashReportLogRing(localSlogger)
+
 	systemSlogger.Log(context.TODO(), slog.LevelInfo,
 		"launching service",
 		"version", version.Version().Version,
@@ -91,6 +96,7 @@ func runWindowsSvc(systemSlogger *multislogger.MultiSlogger, args []string) erro
 					"stack_trace", fmt.Sprintf("%+v", errors.WithStack(err)),
 				)
 			}
+			writeCrashReport(context.TODO(), systemSlogger.Logger, opts.RootDirectory, r, recentLogs)
 			time.Sleep(time.Second)
 		}
 	}()
@@ -100,6 +106,7 @@ func runWindowsSvc(systemSlogger *multislogger.MultiSlogger, args []string) erro
 		slogger:       localSlogger,
 		systemSlogger: systemSlogger,
 		opts:          opts,
+		recentLogs:    recentLogs,
 	}); err != nil {
 		// TODO The caller doesn't have the event log configured, so we
 		// need to log here. this implies we need some deeper refactoring
@@ -152,13 +159,20 @@ func runWindowsSvcForeground(systemSlogger *multislogger.MultiSlogger, args []st
 
 	run := debug.Run
 
-	return run(serviceName, &winSvc{logger: logger, slogger: localSlogger, systemSlogger: systemSlogger, opts: opts})
+	return run(serviceName, &winSvc{
+		logger:        logger,
+		slogger:       localSlogger,
+		systemSlogger: systemSlogger,
+		opts:          opts,
+		recentLogs:    newCrashReportLogRing(localSlogger),
+	})
 }
 
 type winSvc struct {
 	logger                 log.Logger
 	slogger, systemSlogger *multislogger.MultiSlogger
 	opts                   *launcher.Options
+	recentLogs             *crashreport.LogRing
 }
 
 func (w *winSvc) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
@@ -195,6 +209,7 @@ func (w *winSvc) Execute(args []string, r <-chan svc.ChangeRequest, changes chan
 				"runLauncher exited cleanly",
 			)
 		}
+		recordLastExit(ctx, w.systemSlogger.Logger, w.opts.RootDirectory, internal.ExitReasonFor(err), err)
 
 		// Since launcher shut down, we must signal to fully exit so that the service manager can restart the service.
 		runLauncherResults <- struct{}{}
@@ -203,6 +218,8 @@ func (w *winSvc) Execute(args []string, r <-chan svc.ChangeRequest, changes chan
 			"exiting after runLauncher panic",
 			"err", r,
 		)
+		writeCrashReport(ctx, w.systemSlogger.Logger, w.opts.RootDirectory, r, w.recentLogs)
+		recordLastExit(ctx, w.systemSlogger.Logger, w.opts.RootDirectory, internal.ExitReasonUnknown, fmt.Errorf("runLauncher panic: %v", r))
 		// Since launcher shut down, we must signal to fully exit so that the service manager can restart the service.
 		runLauncherResults <- struct{}{}
 	})
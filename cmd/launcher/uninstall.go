@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kolide/launcher/pkg/launcher"
 	"github.com/kolide/launcher/pkg/log/multislogger"
 	"github.com/peterbourgon/ff/v3"
 )
@@ -28,7 +29,7 @@ func runUninstall(_ *multislogger.MultiSlogger, args []string) error {
 
 	ffOpts := []ff.Option{
 		ff.WithConfigFileFlag("config"),
-		ff.WithConfigFileParser(ff.PlainParser),
+		ff.WithConfigFileParser(launcher.ConfigFileParser),
 		ff.WithIgnoreUndefined(true),
 		ff.WithEnvVarNoPrefix(),
 	}
@@ -31,6 +31,7 @@ func main() {
 		flStatic       = fs.Bool("static", false, "Build a static binary.")
 		flStampVersion = fs.Bool("linkstamp", false, "Add version info with ldflags.")
 		flFakeData     = fs.Bool("fakedata", false, "Compile with build tags to falsify some data, like serial numbers")
+		flMinimal      = fs.Bool("minimal", false, "Compile the minimal profile, without the desktop UI or autoupdater")
 		flGithubOutput = fs.Bool("github", os.Getenv("GITHUB_ACTIONS") != "", "Include github action output")
 	)
 
@@ -65,6 +66,9 @@ func main() {
 	if *flFakeData {
 		opts = append(opts, make.WithFakeData())
 	}
+	if *flMinimal {
+		opts = append(opts, make.WithMinimal())
+	}
 
 	if *flGoPath != "" {
 		opts = append(opts, make.WithGoPath(*flGoPath))
@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -189,6 +190,11 @@ func runMake(args []string) error {
 			false,
 			"Create persistence service in a disabled state",
 		)
+		flSourceDateEpoch = flagset.Int(
+			"source_date_epoch",
+			env.Int("SOURCE_DATE_EPOCH", 0),
+			"Unix timestamp to use for all times in the packages, for reproducible builds (default: not reproducible)",
+		)
 		flOsqueryFlags arrayFlags // set below with flagset.Var
 	)
 	flagset.Var(&flOsqueryFlags, "osquery_flag", "Flags to pass to osquery (possibly overriding Launcher defaults)")
@@ -270,6 +276,10 @@ func runMake(args []string) error {
 		DisableService:    *flDisableService,
 	}
 
+	if *flSourceDateEpoch != 0 {
+		packageOptions.SourceDateEpoch = time.Unix(int64(*flSourceDateEpoch), 0).UTC()
+	}
+
 	outputDir := *flOutputDir
 
 	// NOTE: if you are using docker-for-mac, you probably need to set the TMPDIR env to /tmp
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/launcher"
+)
+
+// ClientCertificateFunc returns a function for tls.Config.GetClientCertificate that presents
+// the client certificate configured by the mtls_client_cert flag, for mutual TLS. The
+// certificate's private key is the agent key selected by mtls_client_key, so that the private
+// key never needs to exist on disk -- and, for the hardware key, cannot leave the device.
+// It returns nil when no client certificate is configured.
+//
+// The certificate and key are loaded on each handshake, rather than once, since the hardware
+// key may not be available until some time after startup, and so that a renewed certificate
+// is picked up without restarting launcher.
+func ClientCertificateFunc(k types.Knapsack) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if k.MTLSClientCert() == "" {
+		return nil
+	}
+
+	return func(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		signer := LocalDbKeys()
+		if k.MTLSClientKey() == launcher.MTLSClientKeyHardware {
+			signer = HardwareKeys()
+		}
+
+		cert, err := loadClientCertificate(k.MTLSClientCert(), signer)
+		if err != nil {
+			// crypto/tls does not always surface this error to the caller, so log it too
+			k.Slogger().Log(context.TODO(), slog.LevelError,
+				"could not load client certificate for mutual TLS",
+				"cert_path", k.MTLSClientCert(),
+				"key", k.MTLSClientKey(),
+				"err", err,
+			)
+			return nil, err
+		}
+
+		return cert, nil
+	}
+}
+
+// loadClientCertificate reads the PEM-encoded certificate chain at certPath, leaf first, and
+// pairs it with signer, which must hold the leaf certificate's private key.
+func loadClientCertificate(certPath string, signer crypto.Signer) (*tls.Certificate, error) {
+	pemBytes, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading client certificate: %w", err)
+	}
+
+	var chain [][]byte
+	for {
+		var block *pem.Block
+		block, pemBytes = pem.Decode(pemBytes)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			chain = append(chain, block.Bytes)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificates found in %s", certPath)
+	}
+
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, fmt.Errorf("parsing client certificate: %w", err)
+	}
+
+	if signer == nil || signer.Public() == nil {
+		return nil, errors.New("agent key for client certificate is not available")
+	}
+
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(leaf.PublicKey) {
+		return nil, errors.New("client certificate does not match the agent key")
+	}
+
+	return &tls.Certificate{
+		Certificate: chain,
+		PrivateKey:  signer,
+		Leaf:        leaf,
+	}, nil
+}
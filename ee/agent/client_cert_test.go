@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/keys"
+	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCert writes a self-signed client certificate for signer's public key to a temp file
+func writeClientCert(t *testing.T, signer crypto.Signer) string {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "launcher test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(t.TempDir(), "client.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	return certPath
+}
+
+func Test_loadClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	certPath := writeClientCert(t, key)
+
+	notACertPath := filepath.Join(t.TempDir(), "not-a-cert.pem")
+	require.NoError(t, os.WriteFile(notACertPath, []byte("not a cert"), 0600))
+
+	cert, err := loadClientCertificate(certPath, key)
+	require.NoError(t, err)
+	require.Len(t, cert.Certificate, 1)
+	require.Equal(t, "launcher test client", cert.Leaf.Subject.CommonName)
+
+	_, err = loadClientCertificate(certPath, otherKey)
+	require.Error(t, err, "certificate for a different key should not load")
+
+	_, err = loadClientCertificate(certPath, keys.Noop)
+	require.Error(t, err, "certificate should not load without an agent key")
+
+	_, err = loadClientCertificate(notACertPath, key)
+	require.Error(t, err, "file without certificates should not load")
+
+	_, err = loadClientCertificate(filepath.Join(t.TempDir(), "missing.pem"), key)
+	require.Error(t, err, "missing file should not load")
+}
+
+func TestClientCertificateFunc_NotConfigured(t *testing.T) {
+	t.Parallel()
+
+	k := typesmocks.NewKnapsack(t)
+	k.On("MTLSClientCert").Return("")
+
+	require.Nil(t, ClientCertificateFunc(k))
+}
+
+func Test_loadClientCertificate_MutualTLS(t *testing.T) {
+	t.Parallel()
+
+	// A signer, rather than the private key itself, as for the agent keys
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	var signer crypto.Signer = struct{ crypto.Signer }{key}
+	certPath := writeClientCert(t, signer)
+
+	clientCert, err := loadClientCertificate(certPath, signer)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return loadClientCertificate(certPath, signer)
+	}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
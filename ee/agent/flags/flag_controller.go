@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -219,6 +220,10 @@ func (fc *FlagController) EnrollSecretPath() string {
 	).get(nil)
 }
 
+func (fc *FlagController) HardwareKeyProof() bool {
+	return NewBoolFlagValue(WithDefaultBool(fc.cmdLineOpts.HardwareKeyProof)).get(nil)
+}
+
 func (fc *FlagController) RootDirectory() string {
 	return NewStringFlagValue(
 		WithDefaultString(fc.cmdLineOpts.RootDirectory),
@@ -241,6 +246,18 @@ func (fc *FlagController) RootPEM() string {
 	).get(nil)
 }
 
+func (fc *FlagController) MTLSClientCert() string {
+	return NewStringFlagValue(
+		WithDefaultString(fc.cmdLineOpts.MTLSClientCert),
+	).get(nil)
+}
+
+func (fc *FlagController) MTLSClientKey() string {
+	return NewStringFlagValue(
+		WithDefaultString(fc.cmdLineOpts.MTLSClientKey),
+	).get(nil)
+}
+
 func (fc *FlagController) SetLoggingInterval(interval time.Duration) error {
 	return fc.setControlServerValue(keys.LoggingInterval, durationToBytes(interval))
 }
@@ -264,8 +281,33 @@ func (fc *FlagController) Transport() string {
 	).get(nil)
 }
 
+func (fc *FlagController) SetLogMaxBytesPerBatch(max int) error {
+	return fc.setControlServerValue(keys.LogMaxBytesPerBatch, intToBytes(max))
+}
 func (fc *FlagController) LogMaxBytesPerBatch() int {
-	return fc.cmdLineOpts.LogMaxBytesPerBatch
+	return NewIntFlagValue(fc.slogger, keys.LogMaxBytesPerBatch,
+		WithIntValueDefault(fc.cmdLineOpts.LogMaxBytesPerBatch),
+		WithIntValueMin(0),
+		WithIntValueMax(50),
+	).get(fc.getControlServerValue(keys.LogMaxBytesPerBatch))
+}
+
+func (fc *FlagController) SetMaxBufferedLogs(max int) error {
+	return fc.setControlServerValue(keys.MaxBufferedLogs, intToBytes(max))
+}
+func (fc *FlagController) MaxBufferedLogs() int {
+	return NewIntFlagValue(fc.slogger, keys.MaxBufferedLogs,
+		WithIntValueDefault(0),
+		WithIntValueMin(0),
+		WithIntValueMax(5000000),
+	).get(fc.getControlServerValue(keys.MaxBufferedLogs))
+}
+
+func (fc *FlagController) SetDeduplicateSnapshotResults(enabled bool) error {
+	return fc.setControlServerValue(keys.DeduplicateSnapshotResults, boolToBytes(enabled))
+}
+func (fc *FlagController) DeduplicateSnapshotResults() bool {
+	return NewBoolFlagValue(WithDefaultBool(false)).get(fc.getControlServerValue(keys.DeduplicateSnapshotResults))
 }
 
 func (fc *FlagController) SetDesktopEnabled(enabled bool) error {
@@ -297,6 +339,15 @@ func (fc *FlagController) DesktopMenuRefreshInterval() time.Duration {
 	).get(fc.getControlServerValue(keys.DesktopMenuRefreshInterval))
 }
 
+func (fc *FlagController) SetDesktopPipeSecurityDescriptor(sddl string) error {
+	return fc.setControlServerValue(keys.DesktopPipeSecurityDescriptor, []byte(sddl))
+}
+func (fc *FlagController) DesktopPipeSecurityDescriptor() string {
+	return NewStringFlagValue(
+		WithDefaultString(""),
+	).get(fc.getControlServerValue(keys.DesktopPipeSecurityDescriptor))
+}
+
 func (fc *FlagController) SetDebugServerData(debug bool) error {
 	return fc.setControlServerValue(keys.DebugServerData, boolToBytes(debug))
 }
@@ -341,6 +392,13 @@ func (fc *FlagController) ControlRequestInterval() time.Duration {
 	).get(fc.getControlServerValue(keys.ControlRequestInterval))
 }
 
+func (fc *FlagController) SetControlSSEEnabled(enabled bool) error {
+	return fc.setControlServerValue(keys.ControlSSEEnabled, boolToBytes(enabled))
+}
+func (fc *FlagController) ControlSSEEnabled() bool {
+	return NewBoolFlagValue(WithDefaultBool(false)).get(fc.getControlServerValue(keys.ControlSSEEnabled))
+}
+
 func (fc *FlagController) SetDisableControlTLS(disabled bool) error {
 	return fc.setControlServerValue(keys.DisableControlTLS, boolToBytes(disabled))
 }
@@ -433,6 +491,81 @@ func (fc *FlagController) WatchdogUtilizationLimitPercent() int {
 	).get(fc.getControlServerValue(keys.WatchdogUtilizationLimitPercent))
 }
 
+func (fc *FlagController) SetOsqueryHandoverEnabled(enabled bool) error {
+	return fc.setControlServerValue(keys.OsqueryHandoverEnabled, boolToBytes(enabled))
+}
+func (fc *FlagController) OsqueryHandoverEnabled() bool {
+	return NewBoolFlagValue(WithDefaultBool(fc.cmdLineOpts.OsqueryHandoverEnabled)).get(fc.getControlServerValue(keys.OsqueryHandoverEnabled))
+}
+
+func (fc *FlagController) SetExportPlatformLogs(enabled bool) error {
+	return fc.setControlServerValue(keys.ExportPlatformLogs, boolToBytes(enabled))
+}
+func (fc *FlagController) ExportPlatformLogs() bool {
+	return NewBoolFlagValue(WithDefaultBool(fc.cmdLineOpts.ExportPlatformLogs)).get(fc.getControlServerValue(keys.ExportPlatformLogs))
+}
+
+func (fc *FlagController) SetDistributedQueryDenylist(patterns string) error {
+	return fc.setControlServerValue(keys.DistributedQueryDenylist, []byte(patterns))
+}
+func (fc *FlagController) DistributedQueryDenylist() []string {
+	// Locally-configured patterns always apply; the control server can only add to them.
+	patterns := append([]string{}, fc.cmdLineOpts.DistributedQueryDenylist...)
+
+	controlServerPatterns := NewStringFlagValue(WithDefaultString("")).get(fc.getControlServerValue(keys.DistributedQueryDenylist))
+	for _, pattern := range strings.Split(controlServerPatterns, "\n") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+func (fc *FlagController) SetLogRedactionClasses(classes string) error {
+	return fc.setControlServerValue(keys.LogRedactionClasses, []byte(classes))
+}
+func (fc *FlagController) LogRedactionClasses() []string {
+	// Locally-configured classes always apply; the control server can only add to them.
+	classes := append([]string{}, fc.cmdLineOpts.LogRedactionClasses...)
+
+	controlServerClasses := NewStringFlagValue(WithDefaultString("")).get(fc.getControlServerValue(keys.LogRedactionClasses))
+	for _, class := range strings.Split(controlServerClasses, ",") {
+		if class = strings.TrimSpace(class); class != "" && !slices.Contains(classes, class) {
+			classes = append(classes, class)
+		}
+	}
+
+	return classes
+}
+
+func (fc *FlagController) SetSnapshotDiffTables(tables string) error {
+	return fc.setControlServerValue(keys.SnapshotDiffTables, []byte(tables))
+}
+func (fc *FlagController) SnapshotDiffTables() []string {
+	tables := NewStringFlagValue(WithDefaultString("")).get(fc.getControlServerValue(keys.SnapshotDiffTables))
+
+	var result []string
+	for _, table := range strings.Split(tables, ",") {
+		if table = strings.TrimSpace(table); table != "" {
+			result = append(result, table)
+		}
+	}
+
+	return result
+}
+
+func (fc *FlagController) SetSnapshotDiffInterval(interval time.Duration) error {
+	return fc.setControlServerValue(keys.SnapshotDiffInterval, durationToBytes(interval))
+}
+func (fc *FlagController) SnapshotDiffInterval() time.Duration {
+	return NewDurationFlagValue(fc.slogger, keys.SnapshotDiffInterval,
+		WithDefault(1*time.Hour),
+		WithMin(5*time.Minute),
+		WithMax(24*time.Hour),
+	).get(fc.getControlServerValue(keys.SnapshotDiffInterval))
+}
+
 func (fc *FlagController) OsqueryFlags() []string {
 	return fc.cmdLineOpts.OsqueryFlags
 }
@@ -543,6 +676,26 @@ func (fc *FlagController) PinnedOsquerydVersion() string {
 	).get(fc.getControlServerValue(keys.PinnedOsquerydVersion))
 }
 
+func (fc *FlagController) SetMaintenanceWindow(schedule string) error {
+	return fc.setControlServerValue(keys.MaintenanceWindow, []byte(schedule))
+}
+func (fc *FlagController) MaintenanceWindow() string {
+	return NewStringFlagValue(
+		WithDefaultString(fc.cmdLineOpts.MaintenanceWindow),
+	).get(fc.getControlServerValue(keys.MaintenanceWindow))
+}
+
+func (fc *FlagController) SetMaintenanceWindowDeadline(deadline time.Duration) error {
+	return fc.setControlServerValue(keys.MaintenanceWindowDeadline, durationToBytes(deadline))
+}
+func (fc *FlagController) MaintenanceWindowDeadline() time.Duration {
+	return NewDurationFlagValue(fc.slogger, keys.MaintenanceWindowDeadline,
+		WithDefault(fc.cmdLineOpts.MaintenanceWindowDeadline),
+		WithMin(1*time.Hour),
+		WithMax(14*24*time.Hour),
+	).get(fc.getControlServerValue(keys.MaintenanceWindowDeadline))
+}
+
 func (fc *FlagController) SetExportTraces(enabled bool) error {
 	return fc.setControlServerValue(keys.ExportTraces, boolToBytes(enabled))
 }
@@ -60,6 +60,10 @@ func TestControllerBoolFlags(t *testing.T) {
 				assert.Equal(t, expectedValue, value)
 				value = fc.Autoupdate()
 				assert.Equal(t, expectedValue, value)
+				value = fc.DeduplicateSnapshotResults()
+				assert.Equal(t, expectedValue, value)
+				value = fc.ExportPlatformLogs()
+				assert.Equal(t, expectedValue, value)
 			}
 
 			assertValues(false)
@@ -84,6 +88,10 @@ func TestControllerBoolFlags(t *testing.T) {
 			require.NoError(t, err)
 			err = fc.SetAutoupdate(true)
 			require.NoError(t, err)
+			err = fc.SetDeduplicateSnapshotResults(true)
+			require.NoError(t, err)
+			err = fc.SetExportPlatformLogs(true)
+			require.NoError(t, err)
 
 			assertValues(true)
 		})
@@ -126,6 +134,10 @@ func TestControllerStringFlags(t *testing.T) {
 				assert.Equal(t, expectedValue, value)
 				value = fc.RootPEM()
 				assert.Equal(t, expectedValue, value)
+				value = fc.MTLSClientCert()
+				assert.Equal(t, expectedValue, value)
+				value = fc.MTLSClientKey()
+				assert.Equal(t, expectedValue, value)
 				value = fc.Transport()
 				assert.Equal(t, expectedValue, value)
 			}
@@ -217,6 +229,13 @@ func TestControllerDurationFlags(t *testing.T) {
 			valuesToSet: []time.Duration{1 * time.Second, 7 * time.Second, 20 * time.Minute},
 			valuesToGet: []time.Duration{5 * time.Second, 7 * time.Second, 20 * time.Minute},
 		},
+		{
+			name:        "MaintenanceWindowDeadline",
+			getFlag:     func(fc *FlagController) time.Duration { return fc.MaintenanceWindowDeadline() },
+			setFlag:     func(fc *FlagController, d time.Duration) error { return fc.SetMaintenanceWindowDeadline(d) },
+			valuesToSet: []time.Duration{1 * time.Minute, 24 * time.Hour, 30 * 24 * time.Hour},
+			valuesToGet: []time.Duration{1 * time.Hour, 24 * time.Hour, 14 * 24 * time.Hour},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -239,6 +258,44 @@ func TestControllerDurationFlags(t *testing.T) {
 	}
 }
 
+func TestControllerDistributedQueryDenylist(t *testing.T) {
+	t.Parallel()
+
+	store, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.AgentFlagsStore.String())
+	require.NoError(t, err)
+	fc := NewFlagController(multislogger.NewNopLogger(), store, WithCmdLineOpts(&launcher.Options{
+		DistributedQueryDenylist: []string{"shell_history"},
+	}))
+
+	assert.Equal(t, []string{"shell_history"}, fc.DistributedQueryDenylist())
+
+	// The control server can add patterns, but not remove locally-configured ones
+	require.NoError(t, fc.SetDistributedQueryDenylist("keychain_items\n\n  curl  \n"))
+	assert.Equal(t, []string{"shell_history", "keychain_items", "curl"}, fc.DistributedQueryDenylist())
+
+	require.NoError(t, fc.SetDistributedQueryDenylist(""))
+	assert.Equal(t, []string{"shell_history"}, fc.DistributedQueryDenylist())
+}
+
+func TestControllerLogRedactionClasses(t *testing.T) {
+	t.Parallel()
+
+	store, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.AgentFlagsStore.String())
+	require.NoError(t, err)
+	fc := NewFlagController(multislogger.NewNopLogger(), store, WithCmdLineOpts(&launcher.Options{
+		LogRedactionClasses: []string{"tokens"},
+	}))
+
+	assert.Equal(t, []string{"tokens"}, fc.LogRedactionClasses())
+
+	// The control server can add classes, but not remove locally-configured ones
+	require.NoError(t, fc.SetLogRedactionClasses("emails, ,tokens,ips"))
+	assert.Equal(t, []string{"tokens", "emails", "ips"}, fc.LogRedactionClasses())
+
+	require.NoError(t, fc.SetLogRedactionClasses(""))
+	assert.Equal(t, []string{"tokens"}, fc.LogRedactionClasses())
+}
+
 func TestControllerNotify(t *testing.T) {
 	t.Parallel()
 
@@ -14,17 +14,24 @@ const (
 	KolideHosted                    FlagKey = "kolide_hosted"
 	Transport                       FlagKey = "transport"
 	LoggingInterval                 FlagKey = "logging_interval"
+	LogMaxBytesPerBatch             FlagKey = "log_max_bytes_per_batch"
+	MaxBufferedLogs                 FlagKey = "max_buffered_logs"
+	DeduplicateSnapshotResults      FlagKey = "deduplicate_snapshot_results"
 	OsquerydPath                    FlagKey = "osqueryd_path"
 	OsqueryHealthcheckStartupDelay  FlagKey = "osquery_healthcheck_startup_delay"
 	RootDirectory                   FlagKey = "root_directory"
 	RootPEM                         FlagKey = "root_pem"
+	MTLSClientCert                  FlagKey = "mtls_client_cert"
+	MTLSClientKey                   FlagKey = "mtls_client_key"
 	DesktopEnabled                  FlagKey = "desktop_enabled_v1"
 	DesktopUpdateInterval           FlagKey = "desktop_update_interval"
 	DesktopMenuRefreshInterval      FlagKey = "desktop_menu_refresh_interval"
+	DesktopPipeSecurityDescriptor   FlagKey = "desktop_pipe_security_descriptor"
 	DebugServerData                 FlagKey = "debug_server_data"
 	ForceControlSubsystems          FlagKey = "force_control_subsystems"
 	ControlServerURL                FlagKey = "control_server_url"
 	ControlRequestInterval          FlagKey = "control_request_interval"
+	ControlSSEEnabled               FlagKey = "control_sse_enabled"
 	DisableControlTLS               FlagKey = "disable_control_tls"
 	InsecureControlTLS              FlagKey = "insecure_control_tls"
 	InsecureTLS                     FlagKey = "insecure_tls"
@@ -37,6 +44,12 @@ const (
 	WatchdogDelaySec                FlagKey = "watchdog_delay_sec"
 	WatchdogMemoryLimitMB           FlagKey = "watchdog_memory_limit_mb"
 	WatchdogUtilizationLimitPercent FlagKey = "watchdog_utilization_limit_percent"
+	OsqueryHandoverEnabled          FlagKey = "osquery_handover_enabled"
+	ExportPlatformLogs              FlagKey = "export_platform_logs"
+	DistributedQueryDenylist        FlagKey = "distributed_query_denylist"
+	LogRedactionClasses             FlagKey = "log_redaction_classes"
+	SnapshotDiffTables              FlagKey = "snapshot_diff_tables"
+	SnapshotDiffInterval            FlagKey = "snapshot_diff_interval"
 	Autoupdate                      FlagKey = "autoupdate"
 	TufServerURL                    FlagKey = "tuf_url"
 	MirrorServerURL                 FlagKey = "mirror_url"
@@ -46,6 +59,8 @@ const (
 	UpdateDirectory                 FlagKey = "update_directory"
 	PinnedLauncherVersion           FlagKey = "pinned_launcher_version"
 	PinnedOsquerydVersion           FlagKey = "pinned_osqueryd_version"
+	MaintenanceWindow               FlagKey = "maintenance_window"
+	MaintenanceWindowDeadline       FlagKey = "maintenance_window_deadline"
 	ExportTraces                    FlagKey = "export_traces"
 	TraceSamplingRate               FlagKey = "trace_sampling_rate"
 	TraceBatchTimeout               FlagKey = "trace_batch_timeout"
@@ -58,6 +73,7 @@ const (
 	LauncherWatchdogEnabled         FlagKey = "launcher_watchdog_enabled" // note that this will only impact windows deployments for now
 	SystrayRestartEnabled           FlagKey = "systray_restart_enabled"
 	CurrentRunningOsqueryVersion    FlagKey = "osquery_version"
+	HardwareKeyProof                FlagKey = "hardware_key_proof"
 )
 
 func (key FlagKey) String() string {
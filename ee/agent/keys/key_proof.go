@@ -0,0 +1,244 @@
+package keys
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+const (
+	// provenKey is where we store the public half of the hardware key we proved possession
+	// of at enrollment time, per registration.
+	provenKey = "provenHardwareKey"
+
+	keyProofNonceLength = 32
+
+	// KeyProofMaxAge is how far a key proof's timestamp may be from the verifier's clock.
+	KeyProofMaxAge = 5 * time.Minute
+)
+
+// keyProofSigner is the subset of keyInt we need -- a crypto.Signer that knows where it came from.
+type keyProofSigner interface {
+	crypto.Signer
+	Type() string
+}
+
+// KeyProof is a CSR-style statement, signed by the hardware-backed key, that lets the server
+// bind a new enrollment to that key. The signature covers every other field, so the server can
+// verify it using only the included public key.
+//
+// A proof is bound to the enrollment request it's sent with, by the registration and a hash of
+// the enroll secret, and is only good briefly: the server should check it with a
+// KeyProofVerifier, which rejects stale proofs and nonces it has already seen, so that a
+// captured proof can't be replayed to enroll another device with the key.
+//
+// This proves possession of the key, not where the key lives: it is not platform attestation,
+// like a TPM quote, and the server can't tell a hardware key from a software one by it. So it
+// accompanies the enroll secret, rather than replacing it.
+type KeyProof struct {
+	HardwareKey       string `json:"hardware_key"`        // base64 encoded DER PKIX public key
+	HardwareKeySource string `json:"hardware_key_source"` // e.g. tpm, secure_enclave
+	HostIdentifier    string `json:"host_identifier"`
+	RegistrationId    string `json:"registration_id"`
+	EnrollSecretHash  string `json:"enroll_secret_hash"` // base64 encoded SHA256 of the enroll secret
+	Timestamp         int64  `json:"timestamp"`
+	Nonce             string `json:"nonce"`               // base64 encoded random bytes
+	Signature         string `json:"signature,omitempty"` // base64 encoded ASN.1 ECDSA signature
+}
+
+// NewKeyProof creates a KeyProof for an enrollment request with the given host identifier,
+// registration, and enroll secret, signed by signer.
+func NewKeyProof(signer keyProofSigner, hostIdentifier, registrationId, enrollSecret string) (*KeyProof, error) {
+	if signer == nil || signer.Public() == nil {
+		return nil, errors.New("no hardware key available")
+	}
+
+	pubDer, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("marshalling public key: %w", err)
+	}
+
+	nonce := make([]byte, keyProofNonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	a := &KeyProof{
+		HardwareKey:       base64.StdEncoding.EncodeToString(pubDer),
+		HardwareKeySource: signer.Type(),
+		HostIdentifier:    hostIdentifier,
+		RegistrationId:    registrationId,
+		EnrollSecretHash:  enrollSecretHash(enrollSecret),
+		Timestamp:         time.Now().Unix(),
+		Nonce:             base64.StdEncoding.EncodeToString(nonce),
+	}
+
+	digest, err := a.digest()
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := signer.Sign(rand.Reader, digest, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("signing key proof with %s key: %w", signer.Type(), err)
+	}
+	a.Signature = base64.StdEncoding.EncodeToString(sig)
+
+	return a, nil
+}
+
+// Verify checks the key proof signature against the included public key.
+func (a *KeyProof) Verify() error {
+	pubDer, err := base64.StdEncoding.DecodeString(a.HardwareKey)
+	if err != nil {
+		return fmt.Errorf("decoding public key: %w", err)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(pubDer)
+	if err != nil {
+		return fmt.Errorf("parsing public key: %w", err)
+	}
+
+	ecPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+
+	digest, err := a.digest()
+	if err != nil {
+		return err
+	}
+
+	if !ecdsa.VerifyASN1(ecPub, digest, sig) {
+		return errors.New("invalid key proof signature")
+	}
+
+	return nil
+}
+
+// KeyProofVerifier verifies key proofs as the server does on enrollment. It remembers the nonces
+// of the proofs it has accepted for KeyProofMaxAge, after which their timestamps are too old
+// to be accepted again anyway.
+type KeyProofVerifier struct {
+	lock sync.Mutex
+	seen map[string]time.Time // nonce -> when it may be forgotten
+}
+
+func NewKeyProofVerifier() *KeyProofVerifier {
+	return &KeyProofVerifier{
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Verify checks that the key proof is signed, was made for the enrollment request with the
+// given host identifier, registration, and enroll secret, is recent as of now, and hasn't been
+// used before.
+func (v *KeyProofVerifier) Verify(a *KeyProof, hostIdentifier, registrationId, enrollSecret string, now time.Time) error {
+	if err := a.Verify(); err != nil {
+		return err
+	}
+
+	if a.HostIdentifier != hostIdentifier || a.RegistrationId != registrationId || a.EnrollSecretHash != enrollSecretHash(enrollSecret) {
+		return errors.New("key proof was made for a different enrollment request")
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	for nonce, forgetAt := range v.seen {
+		if now.After(forgetAt) {
+			delete(v.seen, nonce)
+		}
+	}
+
+	if age := now.Sub(time.Unix(a.Timestamp, 0)); age > KeyProofMaxAge || age < -KeyProofMaxAge {
+		return fmt.Errorf("key proof timestamp is %s from now, outside of %s", age, KeyProofMaxAge)
+	}
+
+	if _, replayed := v.seen[a.Nonce]; replayed {
+		return errors.New("key proof has already been used")
+	}
+	v.seen[a.Nonce] = time.Unix(a.Timestamp, 0).Add(KeyProofMaxAge)
+
+	return nil
+}
+
+// Encode returns the key proof as base64 encoded JSON, suitable for the enrollment request.
+func (a *KeyProof) Encode() (string, error) {
+	raw, err := json.Marshal(a)
+	if err != nil {
+		return "", fmt.Errorf("marshalling key proof: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// digest is the SHA256 of the JSON encoded key proof, less the signature.
+func (a *KeyProof) digest() ([]byte, error) {
+	unsigned := *a
+	unsigned.Signature = ""
+
+	raw, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling key proof for signing: %w", err)
+	}
+
+	digest := sha256.Sum256(raw)
+	return digest[:], nil
+}
+
+func enrollSecretHash(enrollSecret string) string {
+	hash := sha256.Sum256([]byte(enrollSecret))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// StoreProvenKey records the hardware key we proved possession of when enrolling the given
+// registration, so that we can later detect the hardware key changing out from under it.
+func StoreProvenKey(setter types.Setter, registrationId string, a *KeyProof) error {
+	return setter.Set(provenKeyKey(registrationId), []byte(a.HardwareKey))
+}
+
+// ProvenKeyMatches reports whether signer's public key matches the key proven when enrolling
+// the given registration. If no key was ever proven, it returns true.
+func ProvenKeyMatches(getter types.Getter, registrationId string, signer crypto.Signer) (bool, error) {
+	stored, err := getter.Get(provenKeyKey(registrationId))
+	if err != nil {
+		return false, fmt.Errorf("reading proven key: %w", err)
+	}
+
+	if len(stored) == 0 {
+		return true, nil
+	}
+
+	if signer == nil || signer.Public() == nil {
+		return false, nil
+	}
+
+	pubDer, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return false, fmt.Errorf("marshalling public key: %w", err)
+	}
+
+	return bytes.Equal(stored, []byte(base64.StdEncoding.EncodeToString(pubDer))), nil
+}
+
+func provenKeyKey(registrationId string) []byte {
+	return storage.KeyByIdentifier([]byte(provenKey), storage.IdentifierTypeRegistration, []byte(registrationId))
+}
@@ -0,0 +1,131 @@
+package keys
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kolide/krypto/pkg/echelper"
+	"github.com/kolide/launcher/ee/agent/storage"
+	storageci "github.com/kolide/launcher/ee/agent/storage/ci"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyProof(t *testing.T) {
+	t.Parallel()
+
+	key, err := echelper.GenerateEcdsaKey()
+	require.NoError(t, err)
+	signer := dbKey{key}
+
+	proof, err := NewKeyProof(signer, "some-host-identifier", "default", "some-secret")
+	require.NoError(t, err)
+	require.Equal(t, "local", proof.HardwareKeySource)
+	require.Equal(t, "some-host-identifier", proof.HostIdentifier)
+	require.Equal(t, "default", proof.RegistrationId)
+	require.NoError(t, proof.Verify())
+
+	// Round trip through the encoding used in the enrollment request
+	encoded, err := proof.Encode()
+	require.NoError(t, err)
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	var decoded KeyProof
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	require.NoError(t, decoded.Verify())
+
+	// Tampering with any field should invalidate the signature
+	decoded.HostIdentifier = "some-other-host"
+	require.Error(t, decoded.Verify())
+}
+
+func TestKeyProofNoKey(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewKeyProof(Noop, "some-host-identifier", "default", "some-secret")
+	require.Error(t, err)
+}
+
+func TestProvenKeyMatches(t *testing.T) {
+	t.Parallel()
+
+	store, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String())
+	require.NoError(t, err)
+
+	key, err := echelper.GenerateEcdsaKey()
+	require.NoError(t, err)
+	signer := dbKey{key}
+
+	// Nothing proven yet
+	matches, err := ProvenKeyMatches(store, "default", signer)
+	require.NoError(t, err)
+	require.True(t, matches)
+
+	proof, err := NewKeyProof(signer, "some-host-identifier", "default", "some-secret")
+	require.NoError(t, err)
+	require.NoError(t, StoreProvenKey(store, "default", proof))
+
+	matches, err = ProvenKeyMatches(store, "default", signer)
+	require.NoError(t, err)
+	require.True(t, matches)
+
+	otherKey, err := echelper.GenerateEcdsaKey()
+	require.NoError(t, err)
+	matches, err = ProvenKeyMatches(store, "default", dbKey{otherKey})
+	require.NoError(t, err)
+	require.False(t, matches)
+
+	// Each registration has its own proven key
+	otherProof, err := NewKeyProof(dbKey{otherKey}, "some-host-identifier", "other", "other-secret")
+	require.NoError(t, err)
+	require.NoError(t, StoreProvenKey(store, "other", otherProof))
+
+	matches, err = ProvenKeyMatches(store, "other", dbKey{otherKey})
+	require.NoError(t, err)
+	require.True(t, matches)
+	matches, err = ProvenKeyMatches(store, "default", signer)
+	require.NoError(t, err)
+	require.True(t, matches)
+}
+
+func TestKeyProofVerifier(t *testing.T) {
+	t.Parallel()
+
+	key, err := echelper.GenerateEcdsaKey()
+	require.NoError(t, err)
+	signer := dbKey{key}
+
+	proof, err := NewKeyProof(signer, "some-host-identifier", "default", "some-secret")
+	require.NoError(t, err)
+	now := time.Unix(proof.Timestamp, 0)
+
+	v := NewKeyProofVerifier()
+
+	// The proof is only good for the enrollment request it was made for
+	require.Error(t, v.Verify(proof, "some-other-host", "default", "some-secret", now))
+	require.Error(t, v.Verify(proof, "some-host-identifier", "other", "some-secret", now))
+	require.Error(t, v.Verify(proof, "some-host-identifier", "default", "some-other-secret", now))
+
+	// And only briefly
+	require.Error(t, v.Verify(proof, "some-host-identifier", "default", "some-secret", now.Add(KeyProofMaxAge+time.Second)))
+	require.Error(t, v.Verify(proof, "some-host-identifier", "default", "some-secret", now.Add(-KeyProofMaxAge-time.Second)))
+
+	require.NoError(t, v.Verify(proof, "some-host-identifier", "default", "some-secret", now))
+
+	// Replaying the same proof is rejected
+	require.ErrorContains(t, v.Verify(proof, "some-host-identifier", "default", "some-secret", now.Add(time.Second)), "already been used")
+
+	// A new proof from the same key is accepted
+	newProof, err := NewKeyProof(signer, "some-host-identifier", "default", "some-secret")
+	require.NoError(t, err)
+	require.NoError(t, v.Verify(newProof, "some-host-identifier", "default", "some-secret", now.Add(time.Second)))
+
+	// Once the replayed proof is too old to accept anyway, its nonce is forgotten
+	later := now.Add(KeyProofMaxAge + time.Minute)
+	require.Error(t, v.Verify(proof, "some-host-identifier", "default", "some-secret", later))
+	v.lock.Lock()
+	require.NotContains(t, v.seen, proof.Nonce)
+	v.lock.Unlock()
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/kolide/kit/ulid"
 	"github.com/kolide/launcher/ee/agent/storage"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/buildprofile"
 	"github.com/kolide/launcher/ee/tuf"
 	"github.com/kolide/launcher/pkg/log/multislogger"
 	"go.etcd.io/bbolt"
@@ -73,6 +74,21 @@ func (k *knapsack) GetRunID() string {
 	return runID
 }
 
+// ExcludedSubsystems lists the optional subsystems this build of launcher excludes
+func (k *knapsack) ExcludedSubsystems() []string {
+	return buildprofile.Excluded()
+}
+
+// DesktopEnabled overrides the flag, since builds without desktop can't run it
+func (k *knapsack) DesktopEnabled() bool {
+	return buildprofile.Includes(buildprofile.Desktop) && k.flags.DesktopEnabled()
+}
+
+// Autoupdate overrides the flag, since builds without the autoupdater can't update
+func (k *knapsack) Autoupdate() bool {
+	return buildprofile.Includes(buildprofile.Autoupdate) && k.flags.Autoupdate()
+}
+
 // Logging interface methods
 func (k *knapsack) Slogger() *slog.Logger {
 	return k.slogger.Logger
@@ -106,6 +122,14 @@ func (k *knapsack) InstanceStatuses() map[string]types.InstanceStatus {
 	return k.querier.InstanceStatuses()
 }
 
+// Query runs the given query against the default osquery instance, over its extension socket.
+func (k *knapsack) Query(query string) ([]map[string]string, error) {
+	if k.querier == nil {
+		return nil, errors.New("no instance querier set")
+	}
+	return k.querier.Query(query)
+}
+
 // BboltDB interface methods
 func (k *knapsack) BboltDB() *bbolt.DB {
 	return k.db
@@ -176,6 +200,46 @@ func (k *knapsack) LauncherHistoryStore() types.KVStore {
 	return k.getKVStore(storage.LauncherHistoryStore)
 }
 
+func (k *knapsack) JournaldCursorStore() types.KVStore {
+	return k.getKVStore(storage.JournaldCursorStore)
+}
+
+func (k *knapsack) SnapshotDiffStore() types.KVStore {
+	return k.getKVStore(storage.SnapshotDiffStore)
+}
+
+func (k *knapsack) FimConfigStore() types.KVStore {
+	return k.getKVStore(storage.FimConfigStore)
+}
+
+func (k *knapsack) ListeningServicesStore() types.KVStore {
+	return k.getKVStore(storage.ListeningServicesStore)
+}
+
+func (k *knapsack) HostsFileWatchStore() types.KVStore {
+	return k.getKVStore(storage.HostsFileWatchStore)
+}
+
+func (k *knapsack) EnrollmentAttemptsStore() types.KVStore {
+	return k.getKVStore(storage.EnrollmentAttemptsStore)
+}
+
+func (k *knapsack) ControlActionHistoryStore() types.KVStore {
+	return k.getKVStore(storage.ControlActionHistoryStore)
+}
+
+func (k *knapsack) NetworkChangeEventsStore() types.KVStore {
+	return k.getKVStore(storage.NetworkChangeEventsStore)
+}
+
+func (k *knapsack) ControlSigningKeysStore() types.KVStore {
+	return k.getKVStore(storage.ControlSigningKeysStore)
+}
+
+func (k *knapsack) OsquerydSelectionStore() types.KVStore {
+	return k.getKVStore(storage.OsquerydSelectionStore)
+}
+
 func (k *knapsack) SetLauncherWatchdogEnabled(enabled bool) error {
 	return k.flags.SetLauncherWatchdogEnabled(enabled)
 }
@@ -202,6 +266,24 @@ func (k *knapsack) LatestOsquerydPath(ctx context.Context) string {
 	return latestBin.Path
 }
 
+func (k *knapsack) RegistrationOsquerydPath(ctx context.Context, registrationId string) string {
+	selectedBin, err := tuf.CheckOutRegistrationOsqueryd(ctx, k.OsquerydSelectionStore(), registrationId, k.RootDirectory(), k.UpdateDirectory(), k.UpdateChannel(), k.Slogger())
+	if err != nil {
+		k.Slogger().Log(ctx, slog.LevelWarn,
+			"could not check out osqueryd selected for registration, falling back to latest",
+			"registration_id", registrationId,
+			"err", err,
+		)
+	}
+	if selectedBin == nil {
+		return k.LatestOsquerydPath(ctx)
+	}
+	if registrationId == types.DefaultRegistrationID && selectedBin.Version != "" {
+		k.SetCurrentRunningOsqueryVersion(selectedBin.Version)
+	}
+	return selectedBin.Path
+}
+
 func (k *knapsack) ReadEnrollSecret() (string, error) {
 	if k.EnrollSecret() != "" {
 		return k.EnrollSecret(), nil
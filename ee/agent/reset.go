@@ -76,7 +76,7 @@ func DetectAndRemediateHardwareChange(ctx context.Context, k types.Knapsack) {
 		hardwareUUIDChanged = valueChanged(ctx, k, currentHardwareUUID, hostDataKeyHardwareUuid)
 	}
 
-	currentTenantMunemo, err := currentMunemo(k)
+	currentTenantMunemo, err := CurrentMunemo(k)
 	if err != nil {
 		k.Slogger().Log(ctx, slog.LevelWarn, "could not get current munemo", "err", err)
 	} else {
@@ -245,9 +245,9 @@ func valueChanged(ctx context.Context, k types.Knapsack, currentValue string, da
 	return false
 }
 
-// currentMunemo retrieves the enrollment secret from either the knapsack or the filesystem,
+// CurrentMunemo retrieves the enrollment secret from either the knapsack or the filesystem,
 // depending on launcher configuration, and then parses the tenant munemo from it.
-func currentMunemo(k types.Knapsack) (string, error) {
+func CurrentMunemo(k types.Knapsack) (string, error) {
 	var enrollSecret string
 	if k.EnrollSecret() != "" {
 		enrollSecret = k.EnrollSecret()
@@ -285,6 +285,22 @@ func currentMunemo(k types.Knapsack) (string, error) {
 	return munemoStr, nil
 }
 
+// RecordedHardwareIdentifiers returns the hardware serial and UUID most recently collected and
+// recorded in the host data store by DetectAndRemediateHardwareChange.
+func RecordedHardwareIdentifiers(hostDataStore types.Getter) (string, string, error) {
+	serial, err := hostDataStore.Get(hostDataKeySerial)
+	if err != nil {
+		return "", "", fmt.Errorf("getting recorded serial: %w", err)
+	}
+
+	hardwareUuid, err := hostDataStore.Get(hostDataKeyHardwareUuid)
+	if err != nil {
+		return "", "", fmt.Errorf("getting recorded hardware UUID: %w", err)
+	}
+
+	return string(serial), string(hardwareUuid), nil
+}
+
 // prepareDatabaseResetRecords retrieves the data we want to preserve from various db stores
 // as a record of the current state of this database before reset. It appends this record
 // to previous records if they exist, and returns the collection ready for storage.
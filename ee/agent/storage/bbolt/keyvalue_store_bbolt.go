@@ -61,6 +61,16 @@ func NewStore(ctx context.Context, slogger *slog.Logger, db *bbolt.DB, bucketNam
 	return m, nil
 }
 
+// NewReadOnlyStore returns a store for bucketName without creating the bucket, for use with a
+// database opened read-only. Get and ForEach return NoBucketError if the bucket does not exist.
+func NewReadOnlyStore(slogger *slog.Logger, db *bbolt.DB, bucketName string) *bboltKeyValueStore {
+	return &bboltKeyValueStore{
+		slogger:    slogger.With("bucket", bucketName),
+		db:         db,
+		bucketName: bucketName,
+	}
+}
+
 func (s *bboltKeyValueStore) Get(key []byte) (value []byte, err error) {
 	if s == nil || s.db == nil {
 		return nil, NoDbError{}
@@ -34,6 +34,16 @@ func MakeStores(ctx context.Context, slogger *slog.Logger, db *bbolt.DB) (map[st
 		storage.TokenStore,
 		storage.ControlServerActionsStore,
 		storage.LauncherHistoryStore,
+		storage.JournaldCursorStore,
+		storage.SnapshotDiffStore,
+		storage.FimConfigStore,
+		storage.ListeningServicesStore,
+		storage.HostsFileWatchStore,
+		storage.EnrollmentAttemptsStore,
+		storage.ControlActionHistoryStore,
+		storage.NetworkChangeEventsStore,
+		storage.ControlSigningKeysStore,
+		storage.OsquerydSelectionStore,
 	}
 
 	for _, storeName := range storeNames {
@@ -32,6 +32,16 @@ func MakeStores(t *testing.T, slogger *slog.Logger, db *bbolt.DB) (map[storage.S
 		storage.ServerProvidedDataStore,
 		storage.TokenStore,
 		storage.LauncherHistoryStore,
+		storage.JournaldCursorStore,
+		storage.SnapshotDiffStore,
+		storage.FimConfigStore,
+		storage.ListeningServicesStore,
+		storage.HostsFileWatchStore,
+		storage.EnrollmentAttemptsStore,
+		storage.ControlActionHistoryStore,
+		storage.NetworkChangeEventsStore,
+		storage.ControlSigningKeysStore,
+		storage.OsquerydSelectionStore,
 	}
 
 	if os.Getenv("CI") == "true" {
@@ -0,0 +1,263 @@
+// Package encrypted provides a KVStore wrapper that encrypts values at rest, for the stores
+// holding secrets like node keys and bearer tokens. Values are encrypted with AES-GCM, under a
+// data key held in the OS keystore -- see LoadOrCreateKey.
+//
+// Stores are migrated transparently: plaintext values are still read as-is, and are encrypted
+// by Migrate, or the next time they're set.
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+// SensitiveStores are the stores whose values are encrypted at rest.
+var SensitiveStores = []storage.Store{
+	storage.ConfigStore, // node keys, and launcher's local private key
+	storage.TokenStore,  // bearer tokens
+}
+
+// ciphertextPrefix marks encrypted values, so that they can be told apart from plaintext ones
+// during migration. The version allows for changing the format later.
+var ciphertextPrefix = []byte("kenc1:")
+
+type encryptedStore struct {
+	slogger *slog.Logger
+	store   types.KVStore
+	aead    cipher.AEAD
+}
+
+// NewStore wraps store so that values are encrypted with key. If key is nil -- because the OS
+// keystore was unavailable -- values are written in plaintext, and reading encrypted values is
+// an error.
+func NewStore(slogger *slog.Logger, store types.KVStore, key []byte) (*encryptedStore, error) {
+	s := &encryptedStore{
+		slogger: slogger.With("component", "encrypted_store"),
+		store:   store,
+	}
+
+	if key == nil {
+		return s, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	s.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating GCM: %w", err)
+	}
+
+	return s, nil
+}
+
+// WrapSensitiveStores replaces each of the SensitiveStores in stores with an encrypted store,
+// and encrypts any plaintext values already in them. If key is nil, and any of the stores
+// already holds encrypted values, it returns an error rather than let launcher run on without
+// its secrets -- and replace them with plaintext ones.
+func WrapSensitiveStores(ctx context.Context, slogger *slog.Logger, stores map[storage.Store]types.KVStore, key []byte) error {
+	for _, storeName := range SensitiveStores {
+		store, ok := stores[storeName]
+		if !ok {
+			continue
+		}
+
+		if key == nil {
+			encrypted, err := hasCiphertext(store)
+			if err != nil {
+				return fmt.Errorf("checking %s for encrypted values: %w", storeName, err)
+			}
+			if encrypted {
+				return fmt.Errorf("%s holds encrypted values, but the storage encryption key is unavailable", storeName)
+			}
+		}
+
+		encStore, err := NewStore(slogger.With("store", storeName.String()), store, key)
+		if err != nil {
+			return fmt.Errorf("wrapping %s: %w", storeName, err)
+		}
+
+		migrated, err := encStore.Migrate()
+		if err != nil {
+			return fmt.Errorf("migrating %s: %w", storeName, err)
+		}
+		if migrated > 0 {
+			slogger.Log(ctx, slog.LevelInfo,
+				"encrypted plaintext values in store",
+				"store", storeName.String(),
+				"count", migrated,
+			)
+		}
+
+		stores[storeName] = encStore
+	}
+
+	return nil
+}
+
+// hasCiphertext reports whether any of store's values are encrypted.
+func hasCiphertext(store types.KVStore) (bool, error) {
+	found := false
+	if err := store.ForEach(func(_, v []byte) error {
+		if bytes.HasPrefix(v, ciphertextPrefix) {
+			found = true
+		}
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+func (s *encryptedStore) encrypt(value []byte) ([]byte, error) {
+	if s.aead == nil || value == nil {
+		return value, nil
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(ciphertextPrefix)+len(nonce)+len(value)+s.aead.Overhead())
+	out = append(out, ciphertextPrefix...)
+	out = append(out, nonce...)
+	return s.aead.Seal(out, nonce, value, nil), nil
+}
+
+// decrypt returns the plaintext of value. Values that were never encrypted are returned as-is.
+func (s *encryptedStore) decrypt(value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, ciphertextPrefix) {
+		return value, nil
+	}
+	if s.aead == nil {
+		return nil, errors.New("value is encrypted, but no key is available")
+	}
+
+	sealed := value[len(ciphertextPrefix):]
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, errors.New("encrypted value is truncated")
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting value: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// Get returns the decrypted value for key. Values that can't be decrypted are an error, not
+// missing: treating them as missing would have launcher discard its node key and re-enroll
+// over what may be a transient problem.
+func (s *encryptedStore) Get(key []byte) ([]byte, error) {
+	value, err := s.store.Get(key)
+	if err != nil || value == nil {
+		return value, err
+	}
+
+	plaintext, err := s.decrypt(value)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", string(key), err)
+	}
+
+	return plaintext, nil
+}
+
+func (s *encryptedStore) Set(key, value []byte) error {
+	ciphertext, err := s.encrypt(value)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Set(key, ciphertext)
+}
+
+func (s *encryptedStore) Delete(keys ...[]byte) error {
+	return s.store.Delete(keys...)
+}
+
+func (s *encryptedStore) DeleteAll() error {
+	return s.store.DeleteAll()
+}
+
+// ForEach calls fn with each decrypted value. As with Get, values that can't be decrypted are
+// an error.
+func (s *encryptedStore) ForEach(fn func(k, v []byte) error) error {
+	return s.store.ForEach(func(k, v []byte) error {
+		plaintext, err := s.decrypt(v)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", string(k), err)
+		}
+
+		return fn(k, plaintext)
+	})
+}
+
+func (s *encryptedStore) Update(kvPairs map[string]string) ([]string, error) {
+	encrypted := make(map[string]string, len(kvPairs))
+	for k, v := range kvPairs {
+		ciphertext, err := s.encrypt([]byte(v))
+		if err != nil {
+			return nil, err
+		}
+		encrypted[k] = string(ciphertext)
+	}
+
+	return s.store.Update(encrypted)
+}
+
+func (s *encryptedStore) Count() (int, error) {
+	return s.store.Count()
+}
+
+func (s *encryptedStore) AppendValues(values ...[]byte) error {
+	encrypted := make([][]byte, len(values))
+	for i, v := range values {
+		ciphertext, err := s.encrypt(v)
+		if err != nil {
+			return err
+		}
+		encrypted[i] = ciphertext
+	}
+
+	return s.store.AppendValues(encrypted...)
+}
+
+// Migrate encrypts any plaintext values in the store, returning how many it encrypted.
+func (s *encryptedStore) Migrate() (int, error) {
+	if s.aead == nil {
+		return 0, nil
+	}
+
+	// ForEach doesn't allow modifying the store, so collect the plaintext values first
+	plaintextValues := make(map[string][]byte)
+	if err := s.store.ForEach(func(k, v []byte) error {
+		if !bytes.HasPrefix(v, ciphertextPrefix) {
+			plaintextValues[string(k)] = bytes.Clone(v)
+		}
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("finding plaintext values: %w", err)
+	}
+
+	for k, v := range plaintextValues {
+		if err := s.Set([]byte(k), v); err != nil {
+			return 0, fmt.Errorf("encrypting value for %s: %w", k, err)
+		}
+	}
+
+	return len(plaintextValues), nil
+}
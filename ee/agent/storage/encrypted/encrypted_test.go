@@ -0,0 +1,173 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(t *testing.T) []byte {
+	key := make([]byte, keySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return key
+}
+
+func TestEncryptedStore(t *testing.T) {
+	t.Parallel()
+
+	underlying := inmemory.NewStore()
+	s, err := NewStore(multislogger.NewNopLogger(), underlying, testKey(t))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Set([]byte("nodeKey"), []byte("secret-node-key")))
+
+	// The value is encrypted at rest...
+	raw, err := underlying.Get([]byte("nodeKey"))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(raw, ciphertextPrefix))
+	require.NotContains(t, string(raw), "secret-node-key")
+
+	// ...and decrypted on the way out
+	value, err := s.Get([]byte("nodeKey"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret-node-key"), value)
+
+	missing, err := s.Get([]byte("missing"))
+	require.NoError(t, err)
+	require.Nil(t, missing)
+
+	deleted, err := s.Update(map[string]string{"token": "bearer-token"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"nodeKey"}, deleted)
+
+	require.NoError(t, s.AppendValues([]byte("appended")))
+
+	found := make(map[string]string)
+	require.NoError(t, s.ForEach(func(k, v []byte) error {
+		found[string(k)] = string(v)
+		return nil
+	}))
+	require.Len(t, found, 2)
+	require.Equal(t, "bearer-token", found["token"])
+
+	require.NoError(t, underlying.ForEach(func(k, v []byte) error {
+		require.True(t, bytes.HasPrefix(v, ciphertextPrefix), string(k))
+		return nil
+	}))
+}
+
+func TestEncryptedStore_Migrate(t *testing.T) {
+	t.Parallel()
+
+	underlying := inmemory.NewStore()
+	require.NoError(t, underlying.Set([]byte("nodeKey"), []byte("plaintext-node-key")))
+	require.NoError(t, underlying.Set([]byte("uuid"), []byte("1234")))
+
+	s, err := NewStore(multislogger.NewNopLogger(), underlying, testKey(t))
+	require.NoError(t, err)
+
+	// Plaintext values are readable before migration
+	value, err := s.Get([]byte("nodeKey"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext-node-key"), value)
+
+	migrated, err := s.Migrate()
+	require.NoError(t, err)
+	require.Equal(t, 2, migrated)
+
+	raw, err := underlying.Get([]byte("nodeKey"))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(raw, ciphertextPrefix))
+
+	value, err = s.Get([]byte("nodeKey"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext-node-key"), value)
+
+	// Migrating again is a no-op
+	migrated, err = s.Migrate()
+	require.NoError(t, err)
+	require.Equal(t, 0, migrated)
+}
+
+func TestEncryptedStore_WrongOrMissingKey(t *testing.T) {
+	t.Parallel()
+
+	underlying := inmemory.NewStore()
+	s, err := NewStore(multislogger.NewNopLogger(), underlying, testKey(t))
+	require.NoError(t, err)
+	require.NoError(t, s.Set([]byte("nodeKey"), []byte("secret-node-key")))
+
+	// Values that can't be decrypted are an error, not missing
+	for _, key := range [][]byte{testKey(t), nil} {
+		other, err := NewStore(multislogger.NewNopLogger(), underlying, key)
+		require.NoError(t, err)
+
+		value, err := other.Get([]byte("nodeKey"))
+		require.Error(t, err)
+		require.Nil(t, value)
+
+		require.Error(t, other.ForEach(func(k, v []byte) error { return nil }))
+	}
+
+	// Without a key, values are written in plaintext
+	plain, err := NewStore(multislogger.NewNopLogger(), underlying, nil)
+	require.NoError(t, err)
+	require.NoError(t, plain.Set([]byte("uuid"), []byte("1234")))
+	raw, err := underlying.Get([]byte("uuid"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("1234"), raw)
+}
+
+func TestWrapSensitiveStores(t *testing.T) {
+	t.Parallel()
+
+	configStore := inmemory.NewStore()
+	require.NoError(t, configStore.Set([]byte("nodeKey"), []byte("plaintext-node-key")))
+	flagsStore := inmemory.NewStore()
+	require.NoError(t, flagsStore.Set([]byte("debug"), []byte("true")))
+
+	stores := map[storage.Store]types.KVStore{
+		storage.ConfigStore:     configStore,
+		storage.AgentFlagsStore: flagsStore,
+	}
+	require.NoError(t, WrapSensitiveStores(context.TODO(), multislogger.NewNopLogger(), stores, testKey(t)))
+
+	require.IsType(t, &encryptedStore{}, stores[storage.ConfigStore])
+	require.Equal(t, flagsStore, stores[storage.AgentFlagsStore])
+
+	raw, err := configStore.Get([]byte("nodeKey"))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(raw, ciphertextPrefix))
+
+	value, err := stores[storage.ConfigStore].Get([]byte("nodeKey"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext-node-key"), value)
+}
+
+func TestWrapSensitiveStores_NoKey(t *testing.T) {
+	t.Parallel()
+
+	// Without a key, plaintext stores are left in plaintext
+	configStore := inmemory.NewStore()
+	require.NoError(t, configStore.Set([]byte("nodeKey"), []byte("plaintext-node-key")))
+	stores := map[storage.Store]types.KVStore{storage.ConfigStore: configStore}
+	require.NoError(t, WrapSensitiveStores(context.TODO(), multislogger.NewNopLogger(), stores, nil))
+
+	raw, err := configStore.Get([]byte("nodeKey"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext-node-key"), raw)
+
+	// ...but stores that are already encrypted can't be used without it
+	require.NoError(t, WrapSensitiveStores(context.TODO(), multislogger.NewNopLogger(), stores, testKey(t)))
+	stores = map[storage.Store]types.KVStore{storage.ConfigStore: configStore}
+	require.Error(t, WrapSensitiveStores(context.TODO(), multislogger.NewNopLogger(), stores, nil))
+	require.Equal(t, configStore, stores[storage.ConfigStore], "store should not be wrapped")
+}
@@ -0,0 +1,57 @@
+package encrypted
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// keySize is the size of the data key; 32 bytes selects AES-256
+const keySize = 32
+
+var (
+	// ErrNoKey is returned when no data key has been stored in the OS keystore yet
+	ErrNoKey = errors.New("no storage encryption key")
+
+	// ErrKeystoreUnavailable is returned when there's no OS keystore that can protect the data
+	// key, so the sensitive stores are kept in plaintext
+	ErrKeystoreUnavailable = errors.New("no OS keystore is available for the storage encryption key")
+)
+
+// LoadKey returns the data key from the OS keystore, or an error if there isn't one.
+func LoadKey(ctx context.Context, rootDirectory string) ([]byte, error) {
+	key, err := readKey(ctx, rootDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("storage encryption key is %d bytes, expected %d", len(key), keySize)
+	}
+
+	return key, nil
+}
+
+// LoadOrCreateKey returns the data key from the OS keystore, generating and storing a new one if
+// there isn't one yet.
+func LoadOrCreateKey(ctx context.Context, rootDirectory string) ([]byte, error) {
+	key, err := LoadKey(ctx, rootDirectory)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, ErrNoKey) {
+		// Don't replace a key we couldn't read -- that would orphan everything encrypted with it
+		return nil, fmt.Errorf("reading storage encryption key: %w", err)
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating storage encryption key: %w", err)
+	}
+
+	if err := writeKey(ctx, rootDirectory, key); err != nil {
+		return nil, fmt.Errorf("storing storage encryption key: %w", err)
+	}
+
+	return key, nil
+}
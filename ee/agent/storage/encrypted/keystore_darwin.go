@@ -0,0 +1,90 @@
+//go:build darwin
+// +build darwin
+
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+)
+
+// The data key is kept as a generic password in the System keychain, which only root can modify.
+const (
+	systemKeychain  = "/Library/Keychains/System.keychain"
+	keychainService = "com.kolide.launcher.storage"
+	keychainAccount = "launcher"
+
+	// errSecItemNotFound, as an exit code from security
+	securityItemNotFoundExitCode = 44
+)
+
+func readKey(ctx context.Context, _ string) ([]byte, error) {
+	cmd, err := allowedcmd.Security(ctx, "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w", systemKeychain)
+	if err != nil {
+		return nil, fmt.Errorf("creating security command: %w", err)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFoundExitCode {
+			return nil, ErrNoKey
+		}
+		return nil, fmt.Errorf("reading key from keychain: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, fmt.Errorf("decoding key from keychain: %w", err)
+	}
+
+	return key, nil
+}
+
+func writeKey(ctx context.Context, _ string, key []byte) error {
+	args, input := addKeyCommand(key)
+	cmd, err := allowedcmd.Security(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("creating security command: %w", err)
+	}
+	cmd.Stdin = strings.NewReader(input)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("adding key to keychain: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+
+	// In interactive mode, security doesn't reliably exit non-zero when a command fails, so
+	// check that the key was stored
+	stored, err := readKey(ctx, "")
+	if err != nil {
+		return fmt.Errorf("reading key back from keychain: %w", err)
+	}
+	if !bytes.Equal(stored, key) {
+		return errors.New("key read back from keychain does not match the key added")
+	}
+
+	return nil
+}
+
+// addKeyCommand returns the arguments to run security with to store key in the keychain, and
+// its input. The key mustn't be on the command line, where any user can see it in the process
+// list, so security runs in interactive mode, and the add-generic-password command, with the
+// key, is its input.
+func addKeyCommand(key []byte) ([]string, string) {
+	command := strings.Join([]string{
+		"add-generic-password", "-U",
+		"-s", keychainService,
+		"-a", keychainAccount,
+		"-w", hex.EncodeToString(key),
+		systemKeychain,
+	}, " ")
+
+	return []string{"-i"}, command + "\n"
+}
@@ -0,0 +1,20 @@
+//go:build darwin
+// +build darwin
+
+package encrypted
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_addKeyCommand(t *testing.T) {
+	t.Parallel()
+
+	args, input := addKeyCommand([]byte{0x01, 0xab, 0xff})
+
+	// The key is only in the input, never in the arguments
+	require.Equal(t, []string{"-i"}, args)
+	require.Equal(t, "add-generic-password -U -s com.kolide.launcher.storage -a launcher -w 01abff /Library/Keychains/System.keychain\n", input)
+}
@@ -0,0 +1,23 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package encrypted
+
+import (
+	"context"
+)
+
+// There is no keystore on Linux that can protect the data key for a root daemon: the kernel
+// keyring doesn't survive reboots, the Secret Service needs a user session, and we don't seal
+// keys to a TPM here. A key kept in a file beside the database would be read by anyone who can
+// read the database, so rather than pretend, there's no data key, and the sensitive stores are
+// kept in plaintext -- unless they already hold encrypted values, in which case launcher
+// refuses to run without the key.
+
+func readKey(_ context.Context, _ string) ([]byte, error) {
+	return nil, ErrKeystoreUnavailable
+}
+
+func writeKey(_ context.Context, _ string, _ []byte) error {
+	return ErrKeystoreUnavailable
+}
@@ -0,0 +1,29 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package encrypted
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateKey(t *testing.T) {
+	t.Parallel()
+
+	rootDirectory := t.TempDir()
+
+	_, err := LoadKey(context.TODO(), rootDirectory)
+	require.ErrorIs(t, err, ErrKeystoreUnavailable)
+
+	// No key is created, and nothing is written beside the database
+	_, err = LoadOrCreateKey(context.TODO(), rootDirectory)
+	require.ErrorIs(t, err, ErrKeystoreUnavailable)
+
+	entries, err := os.ReadDir(rootDirectory)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
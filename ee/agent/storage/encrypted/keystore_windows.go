@@ -0,0 +1,62 @@
+//go:build windows
+// +build windows
+
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// The data key is protected with DPAPI, under the account launcher runs as, and the protected
+// blob is kept in the root directory.
+const protectedKeyFilename = "launcher.db.key"
+
+func readKey(_ context.Context, rootDirectory string) ([]byte, error) {
+	protected, err := os.ReadFile(filepath.Join(rootDirectory, protectedKeyFilename))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNoKey
+		}
+		return nil, fmt.Errorf("reading protected key: %w", err)
+	}
+	if len(protected) == 0 {
+		return nil, errors.New("protected key is empty")
+	}
+
+	in := windows.DataBlob{Size: uint32(len(protected)), Data: &protected[0]}
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, fmt.Errorf("unprotecting key: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	return copyBlob(out), nil
+}
+
+func writeKey(_ context.Context, rootDirectory string, key []byte) error {
+	in := windows.DataBlob{Size: uint32(len(key)), Data: &key[0]}
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return fmt.Errorf("protecting key: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	if err := os.WriteFile(filepath.Join(rootDirectory, protectedKeyFilename), copyBlob(out), 0600); err != nil {
+		return fmt.Errorf("writing protected key: %w", err)
+	}
+
+	return nil
+}
+
+// copyBlob copies the contents of a DPAPI output blob, so that it can be freed.
+func copyBlob(blob windows.DataBlob) []byte {
+	return append([]byte(nil), unsafe.Slice(blob.Data, blob.Size)...)
+}
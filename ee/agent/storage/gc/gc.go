@@ -0,0 +1,223 @@
+// Package gc enforces the stores' retention policies, purging entries that are too old or
+// beyond a store's maximum count, so that stores without their own purge logic don't grow
+// without bound.
+package gc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+const (
+	// startupDelay keeps the first collection out of launcher's busy startup
+	startupDelay = 5 * time.Minute
+
+	collectInterval = 1 * time.Hour
+)
+
+// StoreStats describes the garbage collection of a single store.
+type StoreStats struct {
+	Store       storage.Store
+	Policy      storage.RetentionPolicy
+	LastRun     time.Time // zero if the store hasn't been collected yet
+	Entries     int       // how many entries the store held after the last collection
+	LastPurged  int       // how many entries the last collection purged
+	TotalPurged int       // how many entries have been purged since launcher started
+	LastError   string
+}
+
+var stats = &collectionStats{
+	stores: make(map[storage.Store]*StoreStats),
+}
+
+type collectionStats struct {
+	sync.Mutex
+	stores map[storage.Store]*StoreStats
+}
+
+func (c *collectionStats) record(store storage.Store, policy storage.RetentionPolicy, at time.Time, entries int, purged int, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	s, ok := c.stores[store]
+	if !ok {
+		s = &StoreStats{Store: store}
+		c.stores[store] = s
+	}
+
+	s.Policy = policy
+	s.LastRun = at
+	s.Entries = entries
+	s.LastPurged = purged
+	s.TotalPurged += purged
+	s.LastError = ""
+	if err != nil {
+		s.LastError = err.Error()
+	}
+}
+
+// Stats returns the garbage collection stats for each store with a retention policy, ordered
+// by store.
+func Stats() []StoreStats {
+	stats.Lock()
+	defer stats.Unlock()
+
+	results := make([]StoreStats, 0, len(stats.stores))
+	for _, s := range stats.stores {
+		results = append(results, *s)
+	}
+	for store, policy := range storage.RetentionPolicies() {
+		if _, ok := stats.stores[store]; !ok {
+			results = append(results, StoreStats{Store: store, Policy: policy})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Store < results[j].Store })
+	return results
+}
+
+// Collector periodically enforces the stores' retention policies.
+type Collector struct {
+	slogger     *slog.Logger
+	stores      map[storage.Store]types.KVStore
+	policies    map[storage.Store]storage.RetentionPolicy
+	interrupt   chan struct{}
+	interrupted atomic.Bool
+}
+
+func New(k types.Knapsack) *Collector {
+	return &Collector{
+		slogger:   k.Slogger().With("component", "storage_gc"),
+		stores:    k.Stores(),
+		policies:  storage.RetentionPolicies(),
+		interrupt: make(chan struct{}, 1),
+	}
+}
+
+func (c *Collector) Execute() error {
+	timer := time.NewTimer(startupDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			c.collect(time.Now())
+			timer.Reset(collectInterval)
+		case <-c.interrupt:
+			c.slogger.Log(context.TODO(), slog.LevelDebug,
+				"interrupt received, exiting execute loop",
+			)
+			return nil
+		}
+	}
+}
+
+func (c *Collector) Interrupt(_ error) {
+	// Only perform shutdown tasks on first call to interrupt -- no need to repeat on potential extra calls.
+	if c.interrupted.Load() {
+		return
+	}
+	c.interrupted.Store(true)
+
+	c.interrupt <- struct{}{}
+}
+
+// collect enforces each store's retention policy.
+func (c *Collector) collect(now time.Time) {
+	for storeName, policy := range c.policies {
+		store, ok := c.stores[storeName]
+		if !ok || store == nil {
+			continue
+		}
+
+		entries, purged, err := collectStore(store, policy, now)
+		stats.record(storeName, policy, now, entries, purged, err)
+
+		if err != nil {
+			c.slogger.Log(context.TODO(), slog.LevelWarn,
+				"could not enforce store retention policy",
+				"store", storeName.String(),
+				"err", err,
+			)
+			continue
+		}
+
+		if purged > 0 {
+			c.slogger.Log(context.TODO(), slog.LevelInfo,
+				"purged entries beyond store retention policy",
+				"store", storeName.String(),
+				"purged", purged,
+				"remaining", entries,
+			)
+		}
+	}
+}
+
+type entry struct {
+	key   []byte
+	ts    time.Time
+	dated bool
+}
+
+// collectStore purges the entries in store beyond its policy, returning how many entries
+// remain and how many were purged.
+func collectStore(store types.KVStore, policy storage.RetentionPolicy, now time.Time) (int, int, error) {
+	var kept []entry
+	var toDelete [][]byte
+
+	if err := store.ForEach(func(k, v []byte) error {
+		// The key is only valid during iteration
+		e := entry{key: bytes.Clone(k)}
+		if policy.Timestamp != nil {
+			e.ts, e.dated = policy.Timestamp(k, v)
+		}
+
+		if policy.MaxAge > 0 && e.dated && now.Sub(e.ts) > policy.MaxAge {
+			toDelete = append(toDelete, e.key)
+			return nil
+		}
+
+		kept = append(kept, e)
+		return nil
+	}); err != nil {
+		return 0, 0, fmt.Errorf("iterating over store: %w", err)
+	}
+
+	if policy.MaxEntries > 0 && len(kept) > policy.MaxEntries {
+		// Oldest first: undated entries in key order, then dated ones by age
+		sort.SliceStable(kept, func(i, j int) bool {
+			if kept[i].dated != kept[j].dated {
+				return !kept[i].dated
+			}
+			if kept[i].dated {
+				return kept[i].ts.Before(kept[j].ts)
+			}
+			return bytes.Compare(kept[i].key, kept[j].key) < 0
+		})
+
+		excess := len(kept) - policy.MaxEntries
+		for _, e := range kept[:excess] {
+			toDelete = append(toDelete, e.key)
+		}
+		kept = kept[excess:]
+	}
+
+	if len(toDelete) == 0 {
+		return len(kept), 0, nil
+	}
+
+	if err := store.Delete(toDelete...); err != nil {
+		return len(kept) + len(toDelete), 0, fmt.Errorf("deleting entries: %w", err)
+	}
+
+	return len(kept), len(toDelete), nil
+}
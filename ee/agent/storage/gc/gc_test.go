@@ -0,0 +1,172 @@
+package gc
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+// unixValueTimestamp dates entries by their value, a unix timestamp
+func unixValueTimestamp(_, value []byte) (time.Time, bool) {
+	ts, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
+
+func TestCollectStore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+
+	for _, tt := range []struct {
+		name           string
+		policy         storage.RetentionPolicy
+		entries        map[string]string
+		expectedKept   []string
+		expectedPurged int
+	}{
+		{
+			name:   "max age",
+			policy: storage.RetentionPolicy{MaxAge: time.Hour, Timestamp: unixValueTimestamp},
+			entries: map[string]string{
+				"old":     fmt.Sprint(now.Add(-2 * time.Hour).Unix()),
+				"new":     fmt.Sprint(now.Add(-time.Minute).Unix()),
+				"undated": "not a timestamp",
+			},
+			expectedKept:   []string{"new", "undated"},
+			expectedPurged: 1,
+		},
+		{
+			name:   "max entries, oldest first",
+			policy: storage.RetentionPolicy{MaxEntries: 2, Timestamp: unixValueTimestamp},
+			entries: map[string]string{
+				"a": fmt.Sprint(now.Add(-time.Minute).Unix()),
+				"b": fmt.Sprint(now.Add(-3 * time.Minute).Unix()),
+				"c": fmt.Sprint(now.Add(-2 * time.Minute).Unix()),
+			},
+			expectedKept:   []string{"a", "c"},
+			expectedPurged: 1,
+		},
+		{
+			name:   "max entries, undated first in key order",
+			policy: storage.RetentionPolicy{MaxEntries: 2, Timestamp: unixValueTimestamp},
+			entries: map[string]string{
+				"a": fmt.Sprint(now.Add(-time.Hour).Unix()),
+				"y": "undated",
+				"x": "undated",
+			},
+			expectedKept:   []string{"a", "y"},
+			expectedPurged: 1,
+		},
+		{
+			name:   "max entries without timestamps",
+			policy: storage.RetentionPolicy{MaxEntries: 1},
+			entries: map[string]string{
+				"1": "one",
+				"2": "two",
+				"3": "three",
+			},
+			expectedKept:   []string{"3"},
+			expectedPurged: 2,
+		},
+		{
+			name:   "within policy",
+			policy: storage.RetentionPolicy{MaxAge: time.Hour, MaxEntries: 5, Timestamp: unixValueTimestamp},
+			entries: map[string]string{
+				"a": fmt.Sprint(now.Unix()),
+			},
+			expectedKept: []string{"a"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := inmemory.NewStore()
+			for k, v := range tt.entries {
+				require.NoError(t, store.Set([]byte(k), []byte(v)))
+			}
+
+			remaining, purged, err := collectStore(store, tt.policy, now)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedPurged, purged)
+			require.Equal(t, len(tt.expectedKept), remaining)
+
+			var kept []string
+			require.NoError(t, store.ForEach(func(k, _ []byte) error {
+				kept = append(kept, string(k))
+				return nil
+			}))
+			require.ElementsMatch(t, tt.expectedKept, kept)
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	store := inmemory.NewStore()
+	require.NoError(t, store.Set([]byte("stale"), []byte(fmt.Sprintf(`{"last_seen":%d}`, now.Add(-60*24*time.Hour).Unix()))))
+	require.NoError(t, store.Set([]byte("fresh"), []byte(fmt.Sprintf(`{"last_seen":%d}`, now.Unix()))))
+
+	c := &Collector{
+		slogger:  multislogger.NewNopLogger(),
+		stores:   map[storage.Store]types.KVStore{storage.ListeningServicesStore: store},
+		policies: storage.RetentionPolicies(),
+	}
+	c.collect(now)
+
+	count, err := store.Count()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	var found bool
+	for _, s := range Stats() {
+		if s.Store != storage.ListeningServicesStore {
+			continue
+		}
+		found = true
+		require.True(t, now.Equal(s.LastRun))
+		require.Equal(t, 1, s.Entries)
+		require.Equal(t, 1, s.LastPurged)
+		require.GreaterOrEqual(t, s.TotalPurged, 1)
+		require.Empty(t, s.LastError)
+	}
+	require.True(t, found)
+}
+
+func TestInterrupt(t *testing.T) {
+	t.Parallel()
+
+	c := &Collector{
+		slogger:   multislogger.NewNopLogger(),
+		policies:  storage.RetentionPolicies(),
+		interrupt: make(chan struct{}, 1),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- c.Execute()
+	}()
+
+	// Extra interrupts must not block
+	c.Interrupt(nil)
+	c.Interrupt(nil)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector did not exit after interrupt")
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy bounds how much a store keeps; the storage garbage collector purges entries
+// beyond it. Stores without a policy manage their own size, or are small by design.
+type RetentionPolicy struct {
+	// MaxAge purges entries older than this, as dated by Timestamp. Zero means no limit.
+	MaxAge time.Duration
+	// MaxEntries purges the oldest entries beyond this many. Zero means no limit.
+	MaxEntries int
+	// Timestamp dates an entry. Entries it can't date are never too old, and are purged first
+	// when over MaxEntries, in key order.
+	Timestamp func(key, value []byte) (time.Time, bool)
+}
+
+var retentionPolicies = map[Store]RetentionPolicy{
+	// Checkpoints are named by queries, so may be abandoned
+	JournaldCursorStore: {
+		MaxAge:     30 * 24 * time.Hour,
+		MaxEntries: 1000,
+		Timestamp:  journaldCursorTimestamp,
+	},
+	// Services that have gone away are never removed by the table
+	ListeningServicesStore: {
+		MaxAge:     30 * 24 * time.Hour,
+		MaxEntries: 10000,
+		Timestamp:  jsonUnixTimestamp("last_seen"),
+	},
+	// Only read to avoid repeating notifications sent before actions replaced them
+	SentNotificationsStore: {
+		MaxAge:    90 * 24 * time.Hour,
+		Timestamp: jsonTimeTimestamp("sent_at"),
+	},
+}
+
+// RetentionPolicies returns the retention policies of the stores that have one.
+func RetentionPolicies() map[Store]RetentionPolicy {
+	policies := make(map[Store]RetentionPolicy, len(retentionPolicies))
+	for store, policy := range retentionPolicies {
+		policies[store] = policy
+	}
+	return policies
+}
+
+// jsonUnixTimestamp dates entries by a unix timestamp field in their JSON value.
+func jsonUnixTimestamp(field string) func(key, value []byte) (time.Time, bool) {
+	return func(_, value []byte) (time.Time, bool) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(value, &fields); err != nil {
+			return time.Time{}, false
+		}
+
+		ts, err := strconv.ParseInt(string(fields[field]), 10, 64)
+		if err != nil || ts <= 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(ts, 0), true
+	}
+}
+
+// jsonTimeTimestamp dates entries by an RFC 3339 time field in their JSON value.
+func jsonTimeTimestamp(field string) func(key, value []byte) (time.Time, bool) {
+	return func(_, value []byte) (time.Time, bool) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(value, &fields); err != nil {
+			return time.Time{}, false
+		}
+
+		var ts time.Time
+		if err := json.Unmarshal(fields[field], &ts); err != nil || ts.IsZero() {
+			return time.Time{}, false
+		}
+		return ts, true
+	}
+}
+
+// journaldCursorTimestamp dates a journal cursor by the realtime timestamp it includes, which is
+// in hex microseconds, e.g. `s=...;i=...;b=...;m=...;t=5f1e3c2a1b0c9;x=...`
+func journaldCursorTimestamp(_, value []byte) (time.Time, bool) {
+	for _, field := range strings.Split(string(value), ";") {
+		hexUsec, found := strings.CutPrefix(field, "t=")
+		if !found {
+			continue
+		}
+
+		usec, err := strconv.ParseInt(hexUsec, 16, 64)
+		if err != nil || usec <= 0 {
+			return time.Time{}, false
+		}
+		return time.UnixMicro(usec), true
+	}
+
+	return time.Time{}, false
+}
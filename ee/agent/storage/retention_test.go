@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionTimestamps(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name       string
+		timestamp  func(key, value []byte) (time.Time, bool)
+		value      string
+		expected   time.Time
+		expectedOk bool
+	}{
+		{
+			name:       "unix timestamp",
+			timestamp:  jsonUnixTimestamp("last_seen"),
+			value:      `{"first_seen":1700000000,"last_seen":1710000000}`,
+			expected:   time.Unix(1710000000, 0),
+			expectedOk: true,
+		},
+		{
+			name:      "unix timestamp missing",
+			timestamp: jsonUnixTimestamp("last_seen"),
+			value:     `{"first_seen":1700000000}`,
+		},
+		{
+			name:      "unix timestamp not json",
+			timestamp: jsonUnixTimestamp("last_seen"),
+			value:     `last_seen`,
+		},
+		{
+			name:       "time",
+			timestamp:  jsonTimeTimestamp("sent_at"),
+			value:      `{"title":"hello","sent_at":"2023-09-01T12:00:00Z"}`,
+			expected:   time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC),
+			expectedOk: true,
+		},
+		{
+			name:      "time missing",
+			timestamp: jsonTimeTimestamp("sent_at"),
+			value:     `{"title":"hello"}`,
+		},
+		{
+			name:       "journald cursor",
+			timestamp:  journaldCursorTimestamp,
+			value:      "s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece7;b=6c7c6013a8ee4e7eb9b2a3f5d4a1f2e3;m=1d4a1a2;t=5a1b2c3d4e5f6;x=b4d5e6f7a8b9c0d1",
+			expected:   time.UnixMicro(0x5a1b2c3d4e5f6),
+			expectedOk: true,
+		},
+		{
+			name:      "journald cursor without time",
+			timestamp: journaldCursorTimestamp,
+			value:     "s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece7",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts, ok := tt.timestamp([]byte("key"), []byte(tt.value))
+			require.Equal(t, tt.expectedOk, ok)
+			require.True(t, tt.expected.Equal(ts), "expected %s, got %s", tt.expected, ts)
+		})
+	}
+}
+
+func TestRetentionPolicies(t *testing.T) {
+	t.Parallel()
+
+	policies := RetentionPolicies()
+	require.NotEmpty(t, policies)
+
+	for store, policy := range policies {
+		require.True(t, policy.MaxAge > 0 || policy.MaxEntries > 0, "%s policy has no limits", store)
+		if policy.MaxAge > 0 {
+			require.NotNil(t, policy.Timestamp, "%s policy has a max age but can't date entries", store)
+		}
+	}
+
+	// Changing the returned policies doesn't change the stores' policies
+	delete(policies, JournaldCursorStore)
+	require.Contains(t, RetentionPolicies(), JournaldCursorStore)
+}
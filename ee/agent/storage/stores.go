@@ -19,6 +19,16 @@ const (
 	TokenStore                  Store = "token_store"              // The store used for holding bearer auth tokens, e.g. the ones used to authenticate with the observability ingest server.
 	ControlServerActionsStore   Store = "action_store"             // The store used for storing actions sent by control server.
 	LauncherHistoryStore        Store = "launcher_history"         // The store used for storing launcher start time history currently.
+	JournaldCursorStore         Store = "journald_cursors"         // The store used for checkpointing systemd journal cursors between table queries.
+	SnapshotDiffStore           Store = "snapshot_diffs"           // The store used for the previous snapshots of tables with snapshot-diff events.
+	FimConfigStore              Store = "fim_config"               // The store used for the file integrity monitoring spec sent by control server.
+	ListeningServicesStore      Store = "listening_services"       // The store used for tracking when listening services were first seen.
+	HostsFileWatchStore         Store = "hosts_file_watch"         // The store used for hosts file checkpoints and change events.
+	EnrollmentAttemptsStore     Store = "enrollment_attempts"      // The store used for the history of enrollment attempts.
+	ControlActionHistoryStore   Store = "control_action_history"   // The store used for the audit log of actions taken by the control server.
+	NetworkChangeEventsStore    Store = "network_change_events"    // The store used for network interface and route change events.
+	ControlSigningKeysStore     Store = "control_signing_keys"     // The store used for the keys that sign sensitive control server payloads.
+	OsquerydSelectionStore      Store = "osqueryd_selection"       // The store used for the osqueryd builds selected for individual registrations.
 )
 
 func (storeType Store) String() string {
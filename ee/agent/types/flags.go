@@ -25,6 +25,10 @@ type Flags interface {
 	// secret.
 	EnrollSecretPath() string
 
+	// HardwareKeyProof sends proof of possession of the hardware-backed key
+	// along with the enroll secret, so the server can bind the enrollment to it.
+	HardwareKeyProof() bool
+
 	// RootDirectory is the directory that should be used as the osquery
 	// root directory (database files, pidfile, etc.).
 	RootDirectory() string
@@ -40,6 +44,15 @@ type Flags interface {
 	// chain, if necessary for verification.
 	RootPEM() string
 
+	// MTLSClientCert is the path to a PEM file containing a client certificate, and any
+	// intermediates, to present to the Kolide and control servers. The certificate's
+	// private key is one of the agent's keys, as selected by MTLSClientKey.
+	MTLSClientCert() string
+
+	// MTLSClientKey is which agent key the client certificate belongs to, either
+	// "local" or "hardware".
+	MTLSClientKey() string
+
 	// LoggingInterval is the interval at which logs should be flushed to
 	// the server.
 	SetLoggingInterval(interval time.Duration) error
@@ -56,8 +69,19 @@ type Flags interface {
 	// LogMaxBytesPerBatch sets the maximum bytes allowed in a batch
 	// of log. When blank, launcher will pick a value
 	// appropriate for the transport.
+	SetLogMaxBytesPerBatch(max int) error
 	LogMaxBytesPerBatch() int
 
+	// MaxBufferedLogs is the maximum number of logs (per log type) to buffer before
+	// purging the oldest logs. When blank, launcher uses its default.
+	SetMaxBufferedLogs(max int) error
+	MaxBufferedLogs() int
+
+	// DeduplicateSnapshotResults causes identical consecutive snapshot results for the same
+	// query to be sent as a single log, with a count of how many times it repeated.
+	SetDeduplicateSnapshotResults(enabled bool) error
+	DeduplicateSnapshotResults() bool
+
 	// DesktopEnabled causes the launcher desktop process and GUI to be enabled.
 	SetDesktopEnabled(enabled bool) error
 	DesktopEnabled() bool
@@ -70,6 +94,11 @@ type Flags interface {
 	SetDesktopMenuRefreshInterval(interval time.Duration) error
 	DesktopMenuRefreshInterval() time.Duration
 
+	// DesktopPipeSecurityDescriptor is an SDDL string overriding the security descriptor on the named pipe
+	// used to communicate with desktop processes on Windows. When empty, only SYSTEM and the user may connect.
+	SetDesktopPipeSecurityDescriptor(sddl string) error
+	DesktopPipeSecurityDescriptor() string
+
 	// DebugServerData causes logging and diagnostics related to control server error handling to be enabled.
 	SetDebugServerData(debug bool) error
 	DebugServerData() bool
@@ -88,6 +117,11 @@ type Flags interface {
 	SetControlRequestIntervalOverride(value time.Duration, duration time.Duration)
 	ControlRequestInterval() time.Duration
 
+	// ControlSSEEnabled enables receiving near-real-time control updates over a server-sent events stream,
+	// in addition to polling at ControlRequestInterval.
+	SetControlSSEEnabled(enabled bool) error
+	ControlSSEEnabled() bool
+
 	// DisableControlTLS disables TLS transport with the control server.
 	SetDisableControlTLS(disabled bool) error
 	DisableControlTLS() bool
@@ -134,6 +168,36 @@ type Flags interface {
 	SetWatchdogUtilizationLimitPercent(limit int) error
 	WatchdogUtilizationLimitPercent() int
 
+	// OsqueryHandoverEnabled allows a newly-updated launcher to adopt the running osqueryd
+	// process after an autoupdate restart, rather than restarting it.
+	SetOsqueryHandoverEnabled(enabled bool) error
+	OsqueryHandoverEnabled() bool
+
+	// ExportPlatformLogs mirrors launcher logs and osquery status logs into the platform's logging
+	// system: the unified log on macOS, journald on Linux, and the Event Log on Windows.
+	SetExportPlatformLogs(enabled bool) error
+	ExportPlatformLogs() bool
+
+	// DistributedQueryDenylist is the list of regular expressions matching distributed queries that
+	// must not be run. The control server can add patterns, but cannot remove locally-configured ones.
+	SetDistributedQueryDenylist(patterns string) error
+	DistributedQueryDenylist() []string
+
+	// LogRedactionClasses is the list of classes of sensitive value (emails, ips, serial_numbers,
+	// tokens) masked in launcher logs and flares. The control server can add classes, but cannot
+	// remove locally-configured ones.
+	SetLogRedactionClasses(classes string) error
+	LogRedactionClasses() []string
+
+	// SnapshotDiffTables is the list of tables that launcher snapshots periodically, emitting
+	// add/remove events to the result log pipeline for any changes.
+	SetSnapshotDiffTables(tables string) error
+	SnapshotDiffTables() []string
+
+	// SnapshotDiffInterval is how often launcher snapshots the SnapshotDiffTables.
+	SetSnapshotDiffInterval(interval time.Duration) error
+	SnapshotDiffInterval() time.Duration
+
 	// OsqueryFlags defines additional flags to pass to osquery (possibly
 	// overriding Launcher defaults)
 	OsqueryFlags() []string
@@ -178,6 +242,17 @@ type Flags interface {
 	SetPinnedOsquerydVersion(version string) error
 	PinnedOsquerydVersion() string
 
+	// MaintenanceWindow is the schedule, in the device's local time, during which disruptive actions
+	// (restarts after updates, notifications) may happen -- e.g. "mon-fri 01:00-05:00". When empty,
+	// they may happen at any time.
+	SetMaintenanceWindow(schedule string) error
+	MaintenanceWindow() string
+
+	// MaintenanceWindowDeadline is the longest a disruptive action will wait for the maintenance window
+	// before happening anyway.
+	SetMaintenanceWindowDeadline(deadline time.Duration) error
+	MaintenanceWindowDeadline() time.Duration
+
 	// ExportTraces enables exporting our traces
 	SetExportTraces(enabled bool) error
 	SetExportTracesOverride(value bool, duration time.Duration)
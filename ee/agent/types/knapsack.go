@@ -14,10 +14,16 @@ type Knapsack interface {
 	SetInstanceQuerier(q InstanceQuerier)
 	// LatestOsquerydPath finds the path to the latest osqueryd binary, after accounting for updates.
 	LatestOsquerydPath(ctx context.Context) string
+	// RegistrationOsquerydPath finds the path to the osqueryd binary the given registration should run,
+	// which is the latest one unless the control server selected another for the registration.
+	RegistrationOsquerydPath(ctx context.Context, registrationId string) string
 	// ReadEnrollSecret returns the enroll secret value, checking in various locations.
 	ReadEnrollSecret() (string, error)
 	// CurrentEnrollmentStatus returns the current enrollment status of the launcher installation
 	CurrentEnrollmentStatus() (EnrollmentStatus, error)
 	// GetRunID returns the current launcher run ID
 	GetRunID() string
+	// ExcludedSubsystems lists the optional subsystems this build of launcher excludes, which
+	// report themselves disabled regardless of flags.
+	ExcludedSubsystems() []string
 }
@@ -108,6 +108,24 @@ func (_m *Flags) ControlRequestInterval() time.Duration {
 	return r0
 }
 
+// ControlSSEEnabled provides a mock function with given fields:
+func (_m *Flags) ControlSSEEnabled() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ControlSSEEnabled")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // ControlServerURL provides a mock function with given fields:
 func (_m *Flags) ControlServerURL() string {
 	ret := _m.Called()
@@ -198,6 +216,24 @@ func (_m *Flags) DebugServerData() bool {
 	return r0
 }
 
+// DeduplicateSnapshotResults provides a mock function with given fields:
+func (_m *Flags) DeduplicateSnapshotResults() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeduplicateSnapshotResults")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // DesktopEnabled provides a mock function with given fields:
 func (_m *Flags) DesktopEnabled() bool {
 	ret := _m.Called()
@@ -234,6 +270,24 @@ func (_m *Flags) DesktopMenuRefreshInterval() time.Duration {
 	return r0
 }
 
+// DesktopPipeSecurityDescriptor provides a mock function with given fields:
+func (_m *Flags) DesktopPipeSecurityDescriptor() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for DesktopPipeSecurityDescriptor")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // DesktopUpdateInterval provides a mock function with given fields:
 func (_m *Flags) DesktopUpdateInterval() time.Duration {
 	ret := _m.Called()
@@ -288,6 +342,26 @@ func (_m *Flags) DisableTraceIngestTLS() bool {
 	return r0
 }
 
+// DistributedQueryDenylist provides a mock function with given fields:
+func (_m *Flags) DistributedQueryDenylist() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for DistributedQueryDenylist")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
 // EnableInitialRunner provides a mock function with given fields:
 func (_m *Flags) EnableInitialRunner() bool {
 	ret := _m.Called()
@@ -342,6 +416,24 @@ func (_m *Flags) EnrollSecretPath() string {
 	return r0
 }
 
+// ExportPlatformLogs provides a mock function with given fields:
+func (_m *Flags) ExportPlatformLogs() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportPlatformLogs")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // ExportTraces provides a mock function with given fields:
 func (_m *Flags) ExportTraces() bool {
 	ret := _m.Called()
@@ -594,6 +686,26 @@ func (_m *Flags) LogMaxBytesPerBatch() int {
 	return r0
 }
 
+// LogRedactionClasses provides a mock function with given fields:
+func (_m *Flags) LogRedactionClasses() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogRedactionClasses")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
 // LogShippingLevel provides a mock function with given fields:
 func (_m *Flags) LogShippingLevel() string {
 	ret := _m.Called()
@@ -630,6 +742,96 @@ func (_m *Flags) LoggingInterval() time.Duration {
 	return r0
 }
 
+// MTLSClientCert provides a mock function with given fields:
+func (_m *Flags) MTLSClientCert() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MTLSClientCert")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MTLSClientKey provides a mock function with given fields:
+func (_m *Flags) MTLSClientKey() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MTLSClientKey")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MaintenanceWindow provides a mock function with given fields:
+func (_m *Flags) MaintenanceWindow() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaintenanceWindow")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MaintenanceWindowDeadline provides a mock function with given fields:
+func (_m *Flags) MaintenanceWindowDeadline() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaintenanceWindowDeadline")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// MaxBufferedLogs provides a mock function with given fields:
+func (_m *Flags) MaxBufferedLogs() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaxBufferedLogs")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // MirrorServerURL provides a mock function with given fields:
 func (_m *Flags) MirrorServerURL() string {
 	ret := _m.Called()
@@ -668,6 +870,24 @@ func (_m *Flags) OsqueryFlags() []string {
 	return r0
 }
 
+// OsqueryHandoverEnabled provides a mock function with given fields:
+func (_m *Flags) OsqueryHandoverEnabled() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for OsqueryHandoverEnabled")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // OsqueryHealthcheckStartupDelay provides a mock function with given fields:
 func (_m *Flags) OsqueryHealthcheckStartupDelay() time.Duration {
 	ret := _m.Called()
@@ -806,6 +1026,24 @@ func (_m *Flags) RootPEM() string {
 	return r0
 }
 
+// HardwareKeyProof provides a mock function with given fields:
+func (_m *Flags) HardwareKeyProof() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for HardwareKeyProof")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // SetAutoupdate provides a mock function with given fields: enabled
 func (_m *Flags) SetAutoupdate(enabled bool) error {
 	ret := _m.Called(enabled)
@@ -883,6 +1121,24 @@ func (_m *Flags) SetControlRequestIntervalOverride(value time.Duration, duration
 	_m.Called(value, duration)
 }
 
+// SetControlSSEEnabled provides a mock function with given fields: enabled
+func (_m *Flags) SetControlSSEEnabled(enabled bool) error {
+	ret := _m.Called(enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetControlSSEEnabled")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetControlServerURL provides a mock function with given fields: url
 func (_m *Flags) SetControlServerURL(url string) error {
 	ret := _m.Called(url)
@@ -955,6 +1211,24 @@ func (_m *Flags) SetDebugServerData(debug bool) error {
 	return r0
 }
 
+// SetDeduplicateSnapshotResults provides a mock function with given fields: enabled
+func (_m *Flags) SetDeduplicateSnapshotResults(enabled bool) error {
+	ret := _m.Called(enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDeduplicateSnapshotResults")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetDesktopEnabled provides a mock function with given fields: enabled
 func (_m *Flags) SetDesktopEnabled(enabled bool) error {
 	ret := _m.Called(enabled)
@@ -991,6 +1265,24 @@ func (_m *Flags) SetDesktopMenuRefreshInterval(interval time.Duration) error {
 	return r0
 }
 
+// SetDesktopPipeSecurityDescriptor provides a mock function with given fields: sddl
+func (_m *Flags) SetDesktopPipeSecurityDescriptor(sddl string) error {
+	ret := _m.Called(sddl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDesktopPipeSecurityDescriptor")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(sddl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetDesktopUpdateInterval provides a mock function with given fields: interval
 func (_m *Flags) SetDesktopUpdateInterval(interval time.Duration) error {
 	ret := _m.Called(interval)
@@ -1045,6 +1337,42 @@ func (_m *Flags) SetDisableTraceIngestTLS(enabled bool) error {
 	return r0
 }
 
+// SetDistributedQueryDenylist provides a mock function with given fields: patterns
+func (_m *Flags) SetDistributedQueryDenylist(patterns string) error {
+	ret := _m.Called(patterns)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDistributedQueryDenylist")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(patterns)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetExportPlatformLogs provides a mock function with given fields: enabled
+func (_m *Flags) SetExportPlatformLogs(enabled bool) error {
+	ret := _m.Called(enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetExportPlatformLogs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetExportTraces provides a mock function with given fields: enabled
 func (_m *Flags) SetExportTraces(enabled bool) error {
 	ret := _m.Called(enabled)
@@ -1212,6 +1540,42 @@ func (_m *Flags) SetLogIngestServerURL(url string) error {
 	return r0
 }
 
+// SetLogMaxBytesPerBatch provides a mock function with given fields: max
+func (_m *Flags) SetLogMaxBytesPerBatch(max int) error {
+	ret := _m.Called(max)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetLogMaxBytesPerBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(max)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetLogRedactionClasses provides a mock function with given fields: classes
+func (_m *Flags) SetLogRedactionClasses(classes string) error {
+	ret := _m.Called(classes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetLogRedactionClasses")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(classes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetLogShippingLevel provides a mock function with given fields: level
 func (_m *Flags) SetLogShippingLevel(level string) error {
 	ret := _m.Called(level)
@@ -1253,6 +1617,60 @@ func (_m *Flags) SetLoggingInterval(interval time.Duration) error {
 	return r0
 }
 
+// SetMaintenanceWindow provides a mock function with given fields: schedule
+func (_m *Flags) SetMaintenanceWindow(schedule string) error {
+	ret := _m.Called(schedule)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMaintenanceWindow")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(schedule)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetMaintenanceWindowDeadline provides a mock function with given fields: deadline
+func (_m *Flags) SetMaintenanceWindowDeadline(deadline time.Duration) error {
+	ret := _m.Called(deadline)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMaintenanceWindowDeadline")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(time.Duration) error); ok {
+		r0 = rf(deadline)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetMaxBufferedLogs provides a mock function with given fields: max
+func (_m *Flags) SetMaxBufferedLogs(max int) error {
+	ret := _m.Called(max)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMaxBufferedLogs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(max)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetMirrorServerURL provides a mock function with given fields: url
 func (_m *Flags) SetMirrorServerURL(url string) error {
 	ret := _m.Called(url)
@@ -1271,6 +1689,24 @@ func (_m *Flags) SetMirrorServerURL(url string) error {
 	return r0
 }
 
+// SetOsqueryHandoverEnabled provides a mock function with given fields: enabled
+func (_m *Flags) SetOsqueryHandoverEnabled(enabled bool) error {
+	ret := _m.Called(enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetOsqueryHandoverEnabled")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetOsqueryHealthcheckStartupDelay provides a mock function with given fields: delay
 func (_m *Flags) SetOsqueryHealthcheckStartupDelay(delay time.Duration) error {
 	ret := _m.Called(delay)
@@ -1343,6 +1779,42 @@ func (_m *Flags) SetPinnedOsquerydVersion(version string) error {
 	return r0
 }
 
+// SetSnapshotDiffInterval provides a mock function with given fields: interval
+func (_m *Flags) SetSnapshotDiffInterval(interval time.Duration) error {
+	ret := _m.Called(interval)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSnapshotDiffInterval")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(time.Duration) error); ok {
+		r0 = rf(interval)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetSnapshotDiffTables provides a mock function with given fields: tables
+func (_m *Flags) SetSnapshotDiffTables(tables string) error {
+	ret := _m.Called(tables)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSnapshotDiffTables")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(tables)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetSystrayRestartEnabled provides a mock function with given fields: enabled
 func (_m *Flags) SetSystrayRestartEnabled(enabled bool) error {
 	ret := _m.Called(enabled)
@@ -1546,6 +2018,44 @@ func (_m *Flags) SetWatchdogUtilizationLimitPercent(limit int) error {
 	return r0
 }
 
+// SnapshotDiffInterval provides a mock function with given fields:
+func (_m *Flags) SnapshotDiffInterval() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SnapshotDiffInterval")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// SnapshotDiffTables provides a mock function with given fields:
+func (_m *Flags) SnapshotDiffTables() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SnapshotDiffTables")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
 // SystrayRestartEnabled provides a mock function with given fields:
 func (_m *Flags) SystrayRestartEnabled() bool {
 	ret := _m.Called()
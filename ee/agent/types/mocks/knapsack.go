@@ -190,6 +190,26 @@ func (_m *Knapsack) ConfigStore() types.GetterSetterDeleterIteratorUpdaterCounte
 	return r0
 }
 
+// ControlActionHistoryStore provides a mock function with given fields:
+func (_m *Knapsack) ControlActionHistoryStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ControlActionHistoryStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
 // ControlRequestInterval provides a mock function with given fields:
 func (_m *Knapsack) ControlRequestInterval() time.Duration {
 	ret := _m.Called()
@@ -208,6 +228,24 @@ func (_m *Knapsack) ControlRequestInterval() time.Duration {
 	return r0
 }
 
+// ControlSSEEnabled provides a mock function with given fields:
+func (_m *Knapsack) ControlSSEEnabled() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ControlSSEEnabled")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // ControlServerURL provides a mock function with given fields:
 func (_m *Knapsack) ControlServerURL() string {
 	ret := _m.Called()
@@ -226,6 +264,26 @@ func (_m *Knapsack) ControlServerURL() string {
 	return r0
 }
 
+// ControlSigningKeysStore provides a mock function with given fields:
+func (_m *Knapsack) ControlSigningKeysStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ControlSigningKeysStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
 // ControlStore provides a mock function with given fields:
 func (_m *Knapsack) ControlStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
 	ret := _m.Called()
@@ -346,6 +404,24 @@ func (_m *Knapsack) DebugServerData() bool {
 	return r0
 }
 
+// DeduplicateSnapshotResults provides a mock function with given fields:
+func (_m *Knapsack) DeduplicateSnapshotResults() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeduplicateSnapshotResults")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // DesktopEnabled provides a mock function with given fields:
 func (_m *Knapsack) DesktopEnabled() bool {
 	ret := _m.Called()
@@ -382,6 +458,24 @@ func (_m *Knapsack) DesktopMenuRefreshInterval() time.Duration {
 	return r0
 }
 
+// DesktopPipeSecurityDescriptor provides a mock function with given fields:
+func (_m *Knapsack) DesktopPipeSecurityDescriptor() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for DesktopPipeSecurityDescriptor")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // DesktopUpdateInterval provides a mock function with given fields:
 func (_m *Knapsack) DesktopUpdateInterval() time.Duration {
 	ret := _m.Called()
@@ -436,6 +530,26 @@ func (_m *Knapsack) DisableTraceIngestTLS() bool {
 	return r0
 }
 
+// DistributedQueryDenylist provides a mock function with given fields:
+func (_m *Knapsack) DistributedQueryDenylist() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for DistributedQueryDenylist")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
 // EnableInitialRunner provides a mock function with given fields:
 func (_m *Knapsack) EnableInitialRunner() bool {
 	ret := _m.Called()
@@ -490,6 +604,64 @@ func (_m *Knapsack) EnrollSecretPath() string {
 	return r0
 }
 
+// EnrollmentAttemptsStore provides a mock function with given fields:
+func (_m *Knapsack) EnrollmentAttemptsStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnrollmentAttemptsStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
+// ExcludedSubsystems provides a mock function with given fields:
+func (_m *Knapsack) ExcludedSubsystems() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExcludedSubsystems")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// ExportPlatformLogs provides a mock function with given fields:
+func (_m *Knapsack) ExportPlatformLogs() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportPlatformLogs")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // ExportTraces provides a mock function with given fields:
 func (_m *Knapsack) ExportTraces() bool {
 	ret := _m.Called()
@@ -508,6 +680,26 @@ func (_m *Knapsack) ExportTraces() bool {
 	return r0
 }
 
+// FimConfigStore provides a mock function with given fields:
+func (_m *Knapsack) FimConfigStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FimConfigStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
 // ForceControlSubsystems provides a mock function with given fields:
 func (_m *Knapsack) ForceControlSubsystems() bool {
 	ret := _m.Called()
@@ -544,6 +736,26 @@ func (_m *Knapsack) GetRunID() string {
 	return r0
 }
 
+// HostsFileWatchStore provides a mock function with given fields:
+func (_m *Knapsack) HostsFileWatchStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for HostsFileWatchStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
 // IAmBreakingEELicense provides a mock function with given fields:
 func (_m *Knapsack) IAmBreakingEELicense() bool {
 	ret := _m.Called()
@@ -692,6 +904,26 @@ func (_m *Knapsack) InstanceStatuses() map[string]types.InstanceStatus {
 	return r0
 }
 
+// JournaldCursorStore provides a mock function with given fields:
+func (_m *Knapsack) JournaldCursorStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for JournaldCursorStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
 // KatcConfigStore provides a mock function with given fields:
 func (_m *Knapsack) KatcConfigStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
 	ret := _m.Called()
@@ -804,6 +1036,26 @@ func (_m *Knapsack) LauncherWatchdogEnabled() bool {
 	return r0
 }
 
+// ListeningServicesStore provides a mock function with given fields:
+func (_m *Knapsack) ListeningServicesStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListeningServicesStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
 // LocalDevelopmentPath provides a mock function with given fields:
 func (_m *Knapsack) LocalDevelopmentPath() string {
 	ret := _m.Called()
@@ -858,6 +1110,26 @@ func (_m *Knapsack) LogMaxBytesPerBatch() int {
 	return r0
 }
 
+// LogRedactionClasses provides a mock function with given fields:
+func (_m *Knapsack) LogRedactionClasses() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogRedactionClasses")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
 // LogShippingLevel provides a mock function with given fields:
 func (_m *Knapsack) LogShippingLevel() string {
 	ret := _m.Called()
@@ -894,6 +1166,96 @@ func (_m *Knapsack) LoggingInterval() time.Duration {
 	return r0
 }
 
+// MTLSClientCert provides a mock function with given fields:
+func (_m *Knapsack) MTLSClientCert() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MTLSClientCert")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MTLSClientKey provides a mock function with given fields:
+func (_m *Knapsack) MTLSClientKey() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MTLSClientKey")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MaintenanceWindow provides a mock function with given fields:
+func (_m *Knapsack) MaintenanceWindow() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaintenanceWindow")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MaintenanceWindowDeadline provides a mock function with given fields:
+func (_m *Knapsack) MaintenanceWindowDeadline() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaintenanceWindowDeadline")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// MaxBufferedLogs provides a mock function with given fields:
+func (_m *Knapsack) MaxBufferedLogs() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaxBufferedLogs")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // MirrorServerURL provides a mock function with given fields:
 func (_m *Knapsack) MirrorServerURL() string {
 	ret := _m.Called()
@@ -912,6 +1274,26 @@ func (_m *Knapsack) MirrorServerURL() string {
 	return r0
 }
 
+// NetworkChangeEventsStore provides a mock function with given fields:
+func (_m *Knapsack) NetworkChangeEventsStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for NetworkChangeEventsStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
 // OsqueryFlags provides a mock function with given fields:
 func (_m *Knapsack) OsqueryFlags() []string {
 	ret := _m.Called()
@@ -932,6 +1314,24 @@ func (_m *Knapsack) OsqueryFlags() []string {
 	return r0
 }
 
+// OsqueryHandoverEnabled provides a mock function with given fields:
+func (_m *Knapsack) OsqueryHandoverEnabled() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for OsqueryHandoverEnabled")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // OsqueryHealthcheckStartupDelay provides a mock function with given fields:
 func (_m *Knapsack) OsqueryHealthcheckStartupDelay() time.Duration {
 	ret := _m.Called()
@@ -1006,6 +1406,26 @@ func (_m *Knapsack) OsquerydPath() string {
 	return r0
 }
 
+// OsquerydSelectionStore provides a mock function with given fields:
+func (_m *Knapsack) OsquerydSelectionStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for OsquerydSelectionStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
 // PersistentHostDataStore provides a mock function with given fields:
 func (_m *Knapsack) PersistentHostDataStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
 	ret := _m.Called()
@@ -1062,6 +1482,36 @@ func (_m *Knapsack) PinnedOsquerydVersion() string {
 	return r0
 }
 
+// Query provides a mock function with given fields: query
+func (_m *Knapsack) Query(query string) ([]map[string]string, error) {
+	ret := _m.Called(query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Query")
+	}
+
+	var r0 []map[string]string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]map[string]string, error)); ok {
+		return rf(query)
+	}
+	if rf, ok := ret.Get(0).(func(string) []map[string]string); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]map[string]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ReadEnrollSecret provides a mock function with given fields:
 func (_m *Knapsack) ReadEnrollSecret() (string, error) {
 	ret := _m.Called()
@@ -1122,6 +1572,24 @@ func (_m *Knapsack) RegistrationIDs() []string {
 	return r0
 }
 
+// RegistrationOsquerydPath provides a mock function with given fields: ctx, registrationId
+func (_m *Knapsack) RegistrationOsquerydPath(ctx context.Context, registrationId string) string {
+	ret := _m.Called(ctx, registrationId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegistrationOsquerydPath")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, registrationId)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // ResultLogsStore provides a mock function with given fields:
 func (_m *Knapsack) ResultLogsStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
 	ret := _m.Called()
@@ -1178,6 +1646,24 @@ func (_m *Knapsack) RootPEM() string {
 	return r0
 }
 
+// HardwareKeyProof provides a mock function with given fields:
+func (_m *Knapsack) HardwareKeyProof() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for HardwareKeyProof")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // SentNotificationsStore provides a mock function with given fields:
 func (_m *Knapsack) SentNotificationsStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
 	ret := _m.Called()
@@ -1295,6 +1781,24 @@ func (_m *Knapsack) SetControlRequestIntervalOverride(value time.Duration, durat
 	_m.Called(value, duration)
 }
 
+// SetControlSSEEnabled provides a mock function with given fields: enabled
+func (_m *Knapsack) SetControlSSEEnabled(enabled bool) error {
+	ret := _m.Called(enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetControlSSEEnabled")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetControlServerURL provides a mock function with given fields: url
 func (_m *Knapsack) SetControlServerURL(url string) error {
 	ret := _m.Called(url)
@@ -1367,6 +1871,24 @@ func (_m *Knapsack) SetDebugServerData(debug bool) error {
 	return r0
 }
 
+// SetDeduplicateSnapshotResults provides a mock function with given fields: enabled
+func (_m *Knapsack) SetDeduplicateSnapshotResults(enabled bool) error {
+	ret := _m.Called(enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDeduplicateSnapshotResults")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetDesktopEnabled provides a mock function with given fields: enabled
 func (_m *Knapsack) SetDesktopEnabled(enabled bool) error {
 	ret := _m.Called(enabled)
@@ -1403,6 +1925,24 @@ func (_m *Knapsack) SetDesktopMenuRefreshInterval(interval time.Duration) error
 	return r0
 }
 
+// SetDesktopPipeSecurityDescriptor provides a mock function with given fields: sddl
+func (_m *Knapsack) SetDesktopPipeSecurityDescriptor(sddl string) error {
+	ret := _m.Called(sddl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDesktopPipeSecurityDescriptor")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(sddl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetDesktopUpdateInterval provides a mock function with given fields: interval
 func (_m *Knapsack) SetDesktopUpdateInterval(interval time.Duration) error {
 	ret := _m.Called(interval)
@@ -1457,6 +1997,42 @@ func (_m *Knapsack) SetDisableTraceIngestTLS(enabled bool) error {
 	return r0
 }
 
+// SetDistributedQueryDenylist provides a mock function with given fields: patterns
+func (_m *Knapsack) SetDistributedQueryDenylist(patterns string) error {
+	ret := _m.Called(patterns)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDistributedQueryDenylist")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(patterns)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetExportPlatformLogs provides a mock function with given fields: enabled
+func (_m *Knapsack) SetExportPlatformLogs(enabled bool) error {
+	ret := _m.Called(enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetExportPlatformLogs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetExportTraces provides a mock function with given fields: enabled
 func (_m *Knapsack) SetExportTraces(enabled bool) error {
 	ret := _m.Called(enabled)
@@ -1629,6 +2205,42 @@ func (_m *Knapsack) SetLogIngestServerURL(url string) error {
 	return r0
 }
 
+// SetLogMaxBytesPerBatch provides a mock function with given fields: max
+func (_m *Knapsack) SetLogMaxBytesPerBatch(max int) error {
+	ret := _m.Called(max)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetLogMaxBytesPerBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(max)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetLogRedactionClasses provides a mock function with given fields: classes
+func (_m *Knapsack) SetLogRedactionClasses(classes string) error {
+	ret := _m.Called(classes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetLogRedactionClasses")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(classes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetLogShippingLevel provides a mock function with given fields: level
 func (_m *Knapsack) SetLogShippingLevel(level string) error {
 	ret := _m.Called(level)
@@ -1670,6 +2282,60 @@ func (_m *Knapsack) SetLoggingInterval(interval time.Duration) error {
 	return r0
 }
 
+// SetMaintenanceWindow provides a mock function with given fields: schedule
+func (_m *Knapsack) SetMaintenanceWindow(schedule string) error {
+	ret := _m.Called(schedule)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMaintenanceWindow")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(schedule)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetMaintenanceWindowDeadline provides a mock function with given fields: deadline
+func (_m *Knapsack) SetMaintenanceWindowDeadline(deadline time.Duration) error {
+	ret := _m.Called(deadline)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMaintenanceWindowDeadline")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(time.Duration) error); ok {
+		r0 = rf(deadline)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetMaxBufferedLogs provides a mock function with given fields: max
+func (_m *Knapsack) SetMaxBufferedLogs(max int) error {
+	ret := _m.Called(max)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMaxBufferedLogs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(max)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetMirrorServerURL provides a mock function with given fields: url
 func (_m *Knapsack) SetMirrorServerURL(url string) error {
 	ret := _m.Called(url)
@@ -1688,6 +2354,24 @@ func (_m *Knapsack) SetMirrorServerURL(url string) error {
 	return r0
 }
 
+// SetOsqueryHandoverEnabled provides a mock function with given fields: enabled
+func (_m *Knapsack) SetOsqueryHandoverEnabled(enabled bool) error {
+	ret := _m.Called(enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetOsqueryHandoverEnabled")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetOsqueryHealthcheckStartupDelay provides a mock function with given fields: delay
 func (_m *Knapsack) SetOsqueryHealthcheckStartupDelay(delay time.Duration) error {
 	ret := _m.Called(delay)
@@ -1760,6 +2444,42 @@ func (_m *Knapsack) SetPinnedOsquerydVersion(version string) error {
 	return r0
 }
 
+// SetSnapshotDiffInterval provides a mock function with given fields: interval
+func (_m *Knapsack) SetSnapshotDiffInterval(interval time.Duration) error {
+	ret := _m.Called(interval)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSnapshotDiffInterval")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(time.Duration) error); ok {
+		r0 = rf(interval)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetSnapshotDiffTables provides a mock function with given fields: tables
+func (_m *Knapsack) SetSnapshotDiffTables(tables string) error {
+	ret := _m.Called(tables)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSnapshotDiffTables")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(tables)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetSystrayRestartEnabled provides a mock function with given fields: enabled
 func (_m *Knapsack) SetSystrayRestartEnabled(enabled bool) error {
 	ret := _m.Called(enabled)
@@ -1983,6 +2703,64 @@ func (_m *Knapsack) Slogger() *slog.Logger {
 	return r0
 }
 
+// SnapshotDiffInterval provides a mock function with given fields:
+func (_m *Knapsack) SnapshotDiffInterval() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SnapshotDiffInterval")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// SnapshotDiffStore provides a mock function with given fields:
+func (_m *Knapsack) SnapshotDiffStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SnapshotDiffStore")
+	}
+
+	var r0 types.GetterSetterDeleterIteratorUpdaterCounterAppender
+	if rf, ok := ret.Get(0).(func() types.GetterSetterDeleterIteratorUpdaterCounterAppender); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.GetterSetterDeleterIteratorUpdaterCounterAppender)
+		}
+	}
+
+	return r0
+}
+
+// SnapshotDiffTables provides a mock function with given fields:
+func (_m *Knapsack) SnapshotDiffTables() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SnapshotDiffTables")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
 // StatusLogsStore provides a mock function with given fields:
 func (_m *Knapsack) StatusLogsStore() types.GetterSetterDeleterIteratorUpdaterCounterAppender {
 	ret := _m.Called()
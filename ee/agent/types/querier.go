@@ -11,4 +11,5 @@ const (
 // InstanceQuerier is implemented by pkg/osquery/runtime/runner.go
 type InstanceQuerier interface {
 	InstanceStatuses() map[string]InstanceStatus
+	Query(query string) ([]map[string]string, error)
 }
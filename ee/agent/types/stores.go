@@ -18,4 +18,14 @@ type Stores interface {
 	ServerProvidedDataStore() KVStore
 	TokenStore() KVStore
 	LauncherHistoryStore() KVStore
+	JournaldCursorStore() KVStore
+	SnapshotDiffStore() KVStore
+	FimConfigStore() KVStore
+	ListeningServicesStore() KVStore
+	HostsFileWatchStore() KVStore
+	EnrollmentAttemptsStore() KVStore
+	ControlActionHistoryStore() KVStore
+	NetworkChangeEventsStore() KVStore
+	ControlSigningKeysStore() KVStore
+	OsquerydSelectionStore() KVStore
 }
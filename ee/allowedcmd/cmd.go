@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"path/filepath"
 
+	"github.com/kolide/launcher/ee/hostroot"
 	"github.com/kolide/launcher/pkg/traces"
 )
 
@@ -22,6 +23,7 @@ type AllowedCommand func(ctx context.Context, arg ...string) (*TracedCmd, error)
 type TracedCmd struct {
 	Ctx context.Context // nolint:containedctx // This is an approved usage of context for short lived cmd
 	*exec.Cmd
+	sandbox bool // whether to run the command in the sandbox, once it's enabled
 }
 
 // Start overrides the Start method to add tracing before executing the command.
@@ -29,6 +31,7 @@ func (t *TracedCmd) Start() error {
 	_, span := traces.StartSpan(t.Ctx, "path", t.Cmd.Path, "args", fmt.Sprintf("%+v", t.Cmd.Args))
 	defer span.End()
 
+	t.prepare()
 	return t.Cmd.Start() //nolint:forbidigo // This is our approved usage of t.Cmd.Start()
 }
 
@@ -37,6 +40,7 @@ func (t *TracedCmd) Run() error {
 	_, span := traces.StartSpan(t.Ctx, "path", t.Cmd.Path, "args", fmt.Sprintf("%+v", t.Cmd.Args))
 	defer span.End()
 
+	t.prepare()
 	return t.Cmd.Run() //nolint:forbidigo // This is our approved usage of t.Cmd.Run()
 }
 
@@ -45,6 +49,7 @@ func (t *TracedCmd) Output() ([]byte, error) {
 	_, span := traces.StartSpan(t.Ctx, "path", t.Cmd.Path, "args", fmt.Sprintf("%+v", t.Cmd.Args))
 	defer span.End()
 
+	t.prepare()
 	return t.Cmd.Output() //nolint:forbidigo // This is our approved usage of t.Cmd.Output()
 }
 
@@ -53,14 +58,24 @@ func (t *TracedCmd) CombinedOutput() ([]byte, error) {
 	_, span := traces.StartSpan(t.Ctx, "path", t.Cmd.Path, "args", fmt.Sprintf("%+v", t.Cmd.Args))
 	defer span.End()
 
+	t.prepare()
 	return t.Cmd.CombinedOutput() //nolint:forbidigo // This is our approved usage of t.Cmd.CombinedOutput()
 }
 
 func newCmd(ctx context.Context, fullPathToCmd string, arg ...string) *TracedCmd {
-	return &TracedCmd{
-		Ctx: ctx,
-		Cmd: exec.CommandContext(ctx, fullPathToCmd, arg...), //nolint:forbidigo // This is our approved usage of exec.CommandContext
+	tracedCmd := &TracedCmd{
+		Ctx:     ctx,
+		Cmd:     exec.CommandContext(ctx, fullPathToCmd, arg...), //nolint:forbidigo // This is our approved usage of exec.CommandContext
+		sandbox: true,
 	}
+
+	// When monitoring the host from a container, run the host's command against the
+	// host's filesystem, rather than the container's
+	if root := hostroot.Root(); root != "" {
+		runInHostRoot(tracedCmd.Cmd, root)
+	}
+
+	return tracedCmd
 }
 
 var ErrCommandNotFound = errors.New("command not found")
@@ -68,14 +83,15 @@ var ErrCommandNotFound = errors.New("command not found")
 func validatedCommand(ctx context.Context, knownPath string, arg ...string) (*TracedCmd, error) {
 	knownPath = filepath.Clean(knownPath)
 
-	if _, err := os.Stat(knownPath); err == nil {
+	if _, err := os.Stat(hostroot.Path(knownPath)); err == nil {
 		return newCmd(ctx, knownPath, arg...), nil
 	}
 
 	// Not found at known location -- return error for darwin and windows.
 	// We expect to know the exact location for allowlisted commands on all
-	// OSes except for a few Linux distros.
-	if !allowSearchPath() {
+	// OSes except for a few Linux distros. We also don't search when running
+	// against a host root, since our PATH is the container's, not the host's.
+	if !allowSearchPath() || hostroot.Root() != "" {
 		return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, knownPath)
 	}
 
@@ -103,7 +119,7 @@ func IsNixOS() bool {
 		return isNixOS
 	}
 
-	if _, err := os.Stat("/etc/NIXOS"); err == nil {
+	if _, err := os.Stat(hostroot.Path("/etc/NIXOS")); err == nil {
 		isNixOS = true
 	}
 
@@ -6,12 +6,17 @@ package allowedcmd
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 func Airport(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/System/Library/PrivateFrameworks/Apple80211.framework/Versions/Current/Resources/airport", arg...)
 }
 
+func AppSso(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/app-sso", arg...)
+}
+
 func Bioutil(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/bin/bioutil", arg...)
 }
@@ -29,12 +34,36 @@ func Brew(ctx context.Context, arg ...string) (*TracedCmd, error) {
 
 		validatedCmd.Env = append(validatedCmd.Environ(), "HOMEBREW_NO_AUTO_UPDATE=1")
 
-		return validatedCmd, nil
+		// Fetches from the network even without auto-updating
+		return unsandboxed(validatedCmd, nil)
 	}
 
 	return nil, errors.New("homebrew not found")
 }
 
+func CiscoVpn(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// Cisco Secure Client replaced AnyConnect, and installs to a new location
+	for _, p := range []string{"/opt/cisco/secureclient/bin/vpn", "/opt/cisco/anyconnect/bin/vpn"} {
+		validatedCmd, err := validatedCommand(ctx, p, arg...)
+		if err != nil {
+			continue
+		}
+
+		// Talks to the VPN agent over localhost TCP
+		return unsandboxed(validatedCmd, nil)
+	}
+
+	return nil, fmt.Errorf("%w: cisco vpn", ErrCommandNotFound)
+}
+
+func Codesign(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/codesign", arg...)
+}
+
+func Csrutil(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/csrutil", arg...)
+}
+
 func Diskutil(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/sbin/diskutil", arg...)
 }
@@ -63,8 +92,22 @@ func Ioreg(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/sbin/ioreg", arg...)
 }
 
+func Jamf(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// Contacts the Jamf server
+	return unsandboxed(validatedCommand(ctx, "/usr/local/jamf/bin/jamf", arg...))
+}
+
+func Klist(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/klist", arg...)
+}
+
 func Launchctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/bin/launchctl", arg...)
+	// Starts processes in users' sessions, which are sandboxed if the process runs an allowed command
+	return unsandboxed(validatedCommand(ctx, "/bin/launchctl", arg...))
+}
+
+func Log(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/log", arg...)
 }
 
 func Lsof(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -76,7 +119,8 @@ func Mdfind(ctx context.Context, arg ...string) (*TracedCmd, error) {
 }
 
 func Mdmclient(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/libexec/mdmclient", arg...)
+	// Contacts Apple and the MDM server
+	return unsandboxed(validatedCommand(ctx, "/usr/libexec/mdmclient", arg...))
 }
 
 func Netstat(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -88,19 +132,25 @@ func NixEnv(ctx context.Context, arg ...string) (*TracedCmd, error) {
 }
 
 func Open(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/bin/open", arg...)
+	// Opens applications and URLs for the user
+	return unsandboxed(validatedCommand(ctx, "/usr/bin/open", arg...))
 }
 
 func Pkgutil(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/sbin/pkgutil", arg...)
 }
 
+func Pmset(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/pmset", arg...)
+}
+
 func Powermetrics(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/bin/powermetrics", arg...)
 }
 
 func Profiles(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/bin/profiles", arg...)
+	// Checks device enrollment with Apple
+	return unsandboxed(validatedCommand(ctx, "/usr/bin/profiles", arg...))
 }
 
 func Ps(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -123,12 +173,44 @@ func Scutil(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/sbin/scutil", arg...)
 }
 
+func Security(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/security", arg...)
+}
+
+func Smartctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// smartctl isn't part of macOS; it's most often installed with homebrew
+	for _, p := range []string{"/opt/homebrew/bin/smartctl", "/usr/local/bin/smartctl", "/usr/local/sbin/smartctl"} {
+		validatedCmd, err := validatedCommand(ctx, p, arg...)
+		if err != nil {
+			continue
+		}
+
+		return validatedCmd, nil
+	}
+
+	return nil, errors.New("smartctl not found")
+}
+
 func Socketfilterfw(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/libexec/ApplicationFirewall/socketfilterfw", arg...)
 }
 
 func Softwareupdate(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/sbin/softwareupdate", arg...)
+	// Checks Apple's update servers
+	return unsandboxed(validatedCommand(ctx, "/usr/sbin/softwareupdate", arg...))
+}
+
+func Spctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/sbin/spctl", arg...)
+}
+
+func Sudo(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// Runs commands as another user, which are sandboxed if the command is an allowed one
+	return unsandboxed(validatedCommand(ctx, "/usr/bin/sudo", arg...))
+}
+
+func Sysadminctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/sbin/sysadminctl", arg...)
 }
 
 func SystemProfiler(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -139,8 +221,23 @@ func Tmutil(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/bin/tmutil", arg...)
 }
 
+func Wg(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// wg isn't part of macOS; it's most often installed with homebrew, as wireguard-tools
+	for _, p := range []string{"/opt/homebrew/bin/wg", "/usr/local/bin/wg"} {
+		validatedCmd, err := validatedCommand(ctx, p, arg...)
+		if err != nil {
+			continue
+		}
+
+		return validatedCmd, nil
+	}
+
+	return nil, fmt.Errorf("%w: wg", ErrCommandNotFound)
+}
+
 func ZerotierCli(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/local/bin/zerotier-cli", arg...)
+	// Talks to the ZeroTier service over localhost TCP
+	return unsandboxed(validatedCommand(ctx, "/usr/local/bin/zerotier-cli", arg...))
 }
 
 func Zfs(ctx context.Context, arg ...string) (*TracedCmd, error) {
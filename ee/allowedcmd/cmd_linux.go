@@ -6,6 +6,7 @@ package allowedcmd
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 func Apt(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -23,6 +24,20 @@ func Brew(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCmd, nil
 }
 
+func CiscoVpn(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// Cisco Secure Client replaced AnyConnect, and installs to a new location
+	for _, p := range []string{"/opt/cisco/secureclient/bin/vpn", "/opt/cisco/anyconnect/bin/vpn"} {
+		validatedCmd, err := validatedCommand(ctx, p, arg...)
+		if err != nil {
+			continue
+		}
+
+		return validatedCmd, nil
+	}
+
+	return nil, fmt.Errorf("%w: cisco vpn", ErrCommandNotFound)
+}
+
 func Coredumpctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/bin/coredumpctl", arg...)
 }
@@ -61,7 +76,12 @@ func FalconKernelCheck(ctx context.Context, arg ...string) (*TracedCmd, error) {
 }
 
 func Flatpak(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/bin/flatpak", arg...)
+	// Runs bubblewrap, which needs namespaces
+	return unsandboxed(validatedCommand(ctx, "/usr/bin/flatpak", arg...))
+}
+
+func Globalprotect(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/globalprotect", arg...)
 }
 
 func GnomeExtensions(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -84,6 +104,10 @@ func Journalctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/bin/journalctl", arg...)
 }
 
+func Klist(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/klist", arg...)
+}
+
 func Loginctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/bin/loginctl", arg...)
 }
@@ -118,7 +142,8 @@ func Nmcli(ctx context.Context, arg ...string) (*TracedCmd, error) {
 }
 
 func NotifySend(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/bin/notify-send", arg...)
+	// Talks to the user's session, not a data source
+	return unsandboxed(validatedCommand(ctx, "/usr/bin/notify-send", arg...))
 }
 
 func Pacman(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -137,6 +162,10 @@ func Repcli(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/opt/carbonblack/psc/bin/repcli", arg...)
 }
 
+func Resolvectl(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/resolvectl", arg...)
+}
+
 func Rpm(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	for _, p := range []string{"/bin/rpm", "/usr/bin/rpm"} {
 		validatedCmd, err := validatedCommand(ctx, p, arg...)
@@ -150,6 +179,19 @@ func Rpm(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return nil, errors.New("rpm not found")
 }
 
+func Smartctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	for _, p := range []string{"/usr/sbin/smartctl", "/sbin/smartctl"} {
+		validatedCmd, err := validatedCommand(ctx, p, arg...)
+		if err != nil {
+			continue
+		}
+
+		return validatedCmd, nil
+	}
+
+	return nil, errors.New("smartctl not found")
+}
+
 func Snap(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/bin/snap", arg...)
 }
@@ -158,6 +200,15 @@ func Systemctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, "/usr/bin/systemctl", arg...)
 }
 
+func SystemdRun(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// Starts processes as systemd units, which aren't ours to restrict
+	return unsandboxed(validatedCommand(ctx, "/usr/bin/systemd-run", arg...))
+}
+
+func Wg(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, "/usr/bin/wg", arg...)
+}
+
 func Ws1HubUtil(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	for _, p := range []string{"/usr/bin/ws1HubUtil", "/opt/vmware/ws1-hub/bin/ws1HubUtil"} {
 		validatedCmd, err := validatedCommand(ctx, p, arg...)
@@ -172,7 +223,8 @@ func Ws1HubUtil(ctx context.Context, arg ...string) (*TracedCmd, error) {
 }
 
 func XdgOpen(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/bin/xdg-open", arg...)
+	// Opens applications, which may sandbox themselves using namespaces
+	return unsandboxed(validatedCommand(ctx, "/usr/bin/xdg-open", arg...))
 }
 
 func Xrdb(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -180,7 +232,8 @@ func Xrdb(ctx context.Context, arg ...string) (*TracedCmd, error) {
 }
 
 func XWwwBrowser(ctx context.Context, arg ...string) (*TracedCmd, error) {
-	return validatedCommand(ctx, "/usr/bin/x-www-browser", arg...)
+	// Opens the browser, which sandboxes itself using namespaces
+	return unsandboxed(validatedCommand(ctx, "/usr/bin/x-www-browser", arg...))
 }
 
 func ZerotierCli(ctx context.Context, arg ...string) (*TracedCmd, error) {
@@ -5,10 +5,25 @@ package allowedcmd
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
+func CiscoVpn(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// Cisco Secure Client replaced AnyConnect, and installs to a new location
+	for _, dir := range []string{"Cisco Secure Client", "Cisco AnyConnect Secure Mobility Client"} {
+		validatedCmd, err := validatedCommand(ctx, filepath.Join(os.Getenv("PROGRAMFILES(X86)"), "Cisco", dir, "vpncli.exe"), arg...)
+		if err != nil {
+			continue
+		}
+
+		return validatedCmd, nil
+	}
+
+	return nil, fmt.Errorf("%w: vpncli.exe", ErrCommandNotFound)
+}
+
 func CommandPrompt(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, filepath.Join(os.Getenv("WINDIR"), "System32", "cmd.exe"), arg...)
 }
@@ -30,6 +45,10 @@ func Ipconfig(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, filepath.Join(os.Getenv("WINDIR"), "System32", "ipconfig.exe"), arg...)
 }
 
+func Klist(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, filepath.Join(os.Getenv("WINDIR"), "System32", "klist.exe"), arg...)
+}
+
 func Powercfg(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, filepath.Join(os.Getenv("WINDIR"), "System32", "powercfg.exe"), arg...)
 }
@@ -46,10 +65,35 @@ func Secedit(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, filepath.Join(os.Getenv("WINDIR"), "System32", "SecEdit.exe"), arg...)
 }
 
+func Smartctl(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, filepath.Join(os.Getenv("PROGRAMFILES"), "smartmontools", "bin", "smartctl.exe"), arg...)
+}
+
 func Taskkill(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	return validatedCommand(ctx, filepath.Join(os.Getenv("WINDIR"), "System32", "taskkill.exe"), arg...)
 }
 
+func Wevtutil(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, filepath.Join(os.Getenv("WINDIR"), "System32", "wevtutil.exe"), arg...)
+}
+
+func Wg(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	return validatedCommand(ctx, filepath.Join(os.Getenv("PROGRAMFILES"), "WireGuard", "wg.exe"), arg...)
+}
+
+func Winget(ctx context.Context, arg ...string) (*TracedCmd, error) {
+	// winget ships in the App Installer package, which is installed to a directory named for its
+	// version and architecture, e.g. Microsoft.DesktopAppInstaller_1.22.11261.0_x64__8wekyb3d8bbwe
+	pattern := filepath.Join(os.Getenv("PROGRAMFILES"), "WindowsApps", "Microsoft.DesktopAppInstaller_*__8wekyb3d8bbwe", "winget.exe")
+	matches, err := filepath.Glob(pattern)
+	if err != nil || len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, pattern)
+	}
+
+	// Older versions may linger after an update; matches are sorted, so prefer the last
+	return validatedCommand(ctx, matches[len(matches)-1], arg...)
+}
+
 func ZerotierCli(ctx context.Context, arg ...string) (*TracedCmd, error) {
 	// For windows, "-q" should be prepended before all other args
 	return validatedCommand(ctx, filepath.Join(os.Getenv("SYSTEMROOT"), "ProgramData", "ZeroTier", "One", "zerotier-one_x64.exe"), append([]string{"-q"}, arg...)...)
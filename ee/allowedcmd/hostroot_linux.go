@@ -0,0 +1,19 @@
+//go:build linux
+// +build linux
+
+package allowedcmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// runInHostRoot runs cmd with hostRoot as its root directory, so that the command
+// sees the host's filesystem as it would running directly on the host. This requires
+// CAP_SYS_CHROOT.
+func runInHostRoot(cmd *exec.Cmd, hostRoot string) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Chroot = hostRoot
+}
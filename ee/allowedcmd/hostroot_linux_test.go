@@ -0,0 +1,25 @@
+//go:build linux
+// +build linux
+
+package allowedcmd
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runInHostRoot(t *testing.T) {
+	t.Parallel()
+
+	tracedCmd := newCmd(context.TODO(), "/bin/bash")
+	tracedCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	runInHostRoot(tracedCmd.Cmd, "/hostfs")
+
+	require.Equal(t, "/hostfs", tracedCmd.SysProcAttr.Chroot)
+	require.True(t, tracedCmd.SysProcAttr.Setpgid, "existing attributes should be kept")
+	require.Equal(t, "/bin/bash", tracedCmd.Path, "path should be the host's path, inside the host root")
+}
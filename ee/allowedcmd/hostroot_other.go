@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package allowedcmd
+
+import "os/exec"
+
+// runInHostRoot is a no-op -- a host root is only supported on Linux.
+func runInHostRoot(_ *exec.Cmd, _ string) {}
@@ -0,0 +1,102 @@
+package allowedcmd
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// SandboxSubcommand is the launcher subcommand that applies the sandbox on Linux before
+// exec'ing the command given in its arguments. launcher must handle it before doing anything
+// else; see RunSandboxed.
+const SandboxSubcommand = "sandboxed-exec"
+
+var (
+	sandboxLock     sync.RWMutex
+	sandboxLauncher string
+)
+
+// EnableSandbox runs allowed commands in a restrictive sandbox from now on, with a minimal
+// environment. Commands that need more than the sandbox allows opt out; see unsandboxed.
+// launcherPath must be launcher's own executable, since on Linux it applies the sandbox.
+func EnableSandbox(launcherPath string) {
+	sandboxLock.Lock()
+	defer sandboxLock.Unlock()
+
+	sandboxLauncher = launcherPath
+}
+
+func sandboxLauncherPath() string {
+	sandboxLock.RLock()
+	defer sandboxLock.RUnlock()
+
+	return sandboxLauncher
+}
+
+// unsandboxed marks the command to run outside the sandbox, for commands that need the
+// network, start user applications, or otherwise need more than the sandbox allows.
+func unsandboxed(cmd *TracedCmd, err error) (*TracedCmd, error) {
+	if cmd != nil {
+		cmd.sandbox = false
+	}
+	return cmd, err
+}
+
+// prepare applies the sandbox to the command, if it's enabled, before it starts. It's called
+// on start rather than creation, since callers may set the environment or credentials first.
+func (t *TracedCmd) prepare() {
+	if !t.sandbox {
+		return
+	}
+	t.sandbox = false
+
+	launcherPath := sandboxLauncherPath()
+	if launcherPath == "" {
+		return
+	}
+
+	if applySandbox(t.Cmd, launcherPath) {
+		t.Cmd.Env = minimalEnvironment(t.Cmd.Env, os.Environ())
+	}
+}
+
+// inheritedEnvironmentAllowlist is the environment that sandboxed commands inherit from
+// launcher. Anything else inherited is dropped, so that the command doesn't see launcher's
+// configuration, proxies, or credentials.
+var inheritedEnvironmentAllowlist = map[string]bool{
+	"PATH":    true,
+	"HOME":    true,
+	"USER":    true,
+	"LOGNAME": true,
+	"LANG":    true,
+	"LC_ALL":  true,
+	"TZ":      true,
+	"TMPDIR":  true,
+}
+
+// minimalEnvironment returns the environment for a sandboxed command: the allowlisted
+// variables from launcher's environment, plus any the command's caller set. cmdEnv is nil
+// when the caller set nothing, and otherwise usually starts with launcher's environment,
+// since callers add to cmd.Environ().
+func minimalEnvironment(cmdEnv []string, launcherEnv []string) []string {
+	inherited := make(map[string]string, len(launcherEnv))
+	for _, kv := range launcherEnv {
+		k, v, _ := strings.Cut(kv, "=")
+		inherited[k] = v
+	}
+
+	if cmdEnv == nil {
+		cmdEnv = launcherEnv
+	}
+
+	env := make([]string, 0, len(inheritedEnvironmentAllowlist))
+	for _, kv := range cmdEnv {
+		k, v, _ := strings.Cut(kv, "=")
+		if launcherValue, ok := inherited[k]; ok && launcherValue == v && !inheritedEnvironmentAllowlist[k] {
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	return env
+}
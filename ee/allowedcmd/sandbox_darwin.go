@@ -0,0 +1,46 @@
+//go:build darwin
+// +build darwin
+
+package allowedcmd
+
+import (
+	"errors"
+	"os/exec"
+)
+
+const sandboxExecPath = "/usr/bin/sandbox-exec"
+
+// sandboxProfile denies sandboxed commands IP networking, and writes to the system and to
+// the places persistence is installed. Everything else, including the XPC services many
+// commands query, is allowed, since a stricter profile breaks too many of them.
+const sandboxProfile = `(version 1)
+(allow default)
+(deny network-outbound (remote ip))
+(deny network-bind (local ip))
+(deny file-write*
+	(subpath "/System")
+	(subpath "/usr")
+	(subpath "/bin")
+	(subpath "/sbin")
+	(subpath "/etc")
+	(subpath "/private/etc")
+	(subpath "/Applications")
+	(subpath "/Library/LaunchAgents")
+	(subpath "/Library/LaunchDaemons")
+	(subpath "/Library/StartupItems")
+	(regex #"^/Users/[^/]+/Library/LaunchAgents/"))
+`
+
+// applySandbox rewrites cmd to run under sandbox-exec with sandboxProfile. sandbox-exec execs
+// the command, keeping its credentials.
+func applySandbox(cmd *exec.Cmd, _ string) bool {
+	cmd.Args = append([]string{sandboxExecPath, "-p", sandboxProfile, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = sandboxExecPath
+
+	return true
+}
+
+// RunSandboxed is only used on Linux; sandbox-exec applies the sandbox on macOS.
+func RunSandboxed(_ []string) error {
+	return errors.New("not supported on darwin")
+}
@@ -0,0 +1,113 @@
+//go:build linux
+// +build linux
+
+package allowedcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// applySandbox rewrites cmd to run through launcher's SandboxSubcommand, which sets
+// no_new_privs and installs a seccomp filter before exec'ing the command. The command's
+// credentials are applied by the subcommand too, since the user may not be able to run
+// launcher. Commands run against a host root aren't sandboxed, since launcher's executable
+// isn't inside it.
+func applySandbox(cmd *exec.Cmd, launcherPath string) bool {
+	args := []string{launcherPath, SandboxSubcommand}
+
+	if cmd.SysProcAttr != nil {
+		if cmd.SysProcAttr.Chroot != "" {
+			return false
+		}
+		if cred := cmd.SysProcAttr.Credential; cred != nil {
+			args = append(args,
+				"--uid", strconv.FormatUint(uint64(cred.Uid), 10),
+				"--gid", strconv.FormatUint(uint64(cred.Gid), 10),
+			)
+			if cred.NoSetGroups {
+				args = append(args, "--no-setgroups")
+			} else {
+				groups := make([]string, len(cred.Groups))
+				for i, g := range cred.Groups {
+					groups[i] = strconv.FormatUint(uint64(g), 10)
+				}
+				args = append(args, "--groups", strings.Join(groups, ","))
+			}
+			cmd.SysProcAttr.Credential = nil
+		}
+	}
+
+	cmd.Args = append(append(args, "--", cmd.Path), cmd.Args...)
+	cmd.Path = launcherPath
+
+	return true
+}
+
+// RunSandboxed implements launcher's SandboxSubcommand: it switches to the given credentials,
+// sets no_new_privs and installs the seccomp filter, then execs the command. It only returns
+// on error.
+func RunSandboxed(args []string) error {
+	var (
+		flagset       = flag.NewFlagSet(SandboxSubcommand, flag.ContinueOnError)
+		flUid         = flagset.Int64("uid", -1, "the uid to run the command as")
+		flGid         = flagset.Int64("gid", -1, "the gid to run the command as")
+		flGroups      = flagset.String("groups", "", "the supplementary groups to run the command with")
+		flNoSetgroups = flagset.Bool("no-setgroups", false, "keep launcher's supplementary groups")
+	)
+	if err := flagset.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	// The path to the command, then its argv
+	rest := flagset.Args()
+	if len(rest) < 2 {
+		return errors.New("no command given")
+	}
+
+	// no_new_privs and the seccomp filter apply to the thread that execs
+	runtime.LockOSThread()
+
+	if *flUid >= 0 {
+		if !*flNoSetgroups {
+			var groups []int
+			for _, g := range strings.Split(*flGroups, ",") {
+				if g == "" {
+					continue
+				}
+				gid, err := strconv.Atoi(g)
+				if err != nil {
+					return fmt.Errorf("parsing group %s: %w", g, err)
+				}
+				groups = append(groups, gid)
+			}
+			if err := syscall.Setgroups(groups); err != nil {
+				return fmt.Errorf("setting groups: %w", err)
+			}
+		}
+		if err := syscall.Setgid(int(*flGid)); err != nil {
+			return fmt.Errorf("setting gid: %w", err)
+		}
+		if err := syscall.Setuid(int(*flUid)); err != nil {
+			return fmt.Errorf("setting uid: %w", err)
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("setting no_new_privs: %w", err)
+	}
+	if err := installSeccompFilter(); err != nil {
+		return err
+	}
+
+	return syscall.Exec(rest[0], rest[1:], os.Environ())
+}
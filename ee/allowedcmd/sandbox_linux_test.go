@@ -0,0 +1,106 @@
+//go:build linux
+// +build linux
+
+package allowedcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain lets the test binary stand in for launcher when running sandboxed commands.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == SandboxSubcommand {
+		fmt.Fprintf(os.Stderr, "could not run sandboxed command: %v\n", RunSandboxed(os.Args[2:]))
+		os.Exit(126) //nolint:forbidigo // Fine to use os.Exit inside tests
+	}
+
+	os.Exit(m.Run()) //nolint:forbidigo // Fine to use os.Exit inside tests
+}
+
+func sandboxedCommand(t *testing.T, sysProcAttr *syscall.SysProcAttr, path string, arg ...string) *TracedCmd {
+	cmd, err := validatedCommand(context.TODO(), path, arg...)
+	require.NoError(t, err)
+	cmd.SysProcAttr = sysProcAttr
+
+	testExecutable, err := os.Executable()
+	require.NoError(t, err)
+	require.True(t, applySandbox(cmd.Cmd, testExecutable))
+
+	return cmd
+}
+
+func TestSandbox(t *testing.T) {
+	t.Parallel()
+
+	out, err := sandboxedCommand(t, nil, "/bin/sh", "-c", "grep -E '^(NoNewPrivs|Seccomp):' /proc/self/status").Output()
+	require.NoError(t, err)
+	require.Equal(t, "NoNewPrivs:\t1\nSeccomp:\t2\n", string(out))
+}
+
+func TestSandbox_DeniesSyscalls(t *testing.T) {
+	t.Parallel()
+
+	if _, err := os.Stat("/usr/bin/unshare"); err != nil {
+		t.Skip("unshare not available")
+	}
+
+	out, err := sandboxedCommand(t, nil, "/usr/bin/unshare", "--user", "true").CombinedOutput()
+	require.Error(t, err)
+	require.Contains(t, string(out), "Operation not permitted")
+}
+
+func TestApplySandbox_Credential(t *testing.T) {
+	t.Parallel()
+
+	cmd, err := Echo(context.TODO(), "hello")
+	require.NoError(t, err)
+	echoPath := cmd.Path
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: 501, Gid: 20, Groups: []uint32{12, 80}},
+		Setpgid:    true,
+	}
+
+	require.True(t, applySandbox(cmd.Cmd, "/usr/local/bin/launcher"))
+	require.Equal(t, "/usr/local/bin/launcher", cmd.Path)
+	require.Equal(t, []string{
+		"/usr/local/bin/launcher", SandboxSubcommand, "--uid", "501", "--gid", "20", "--groups", "12,80",
+		"--", echoPath, echoPath, "hello",
+	}, cmd.Args)
+	require.Nil(t, cmd.SysProcAttr.Credential)
+	require.True(t, cmd.SysProcAttr.Setpgid)
+}
+
+func TestApplySandbox_HostRoot(t *testing.T) {
+	t.Parallel()
+
+	cmd, err := Echo(context.TODO(), "hello")
+	require.NoError(t, err)
+	echoPath := cmd.Path
+	cmd.SysProcAttr = &syscall.SysProcAttr{Chroot: "/host"}
+
+	require.False(t, applySandbox(cmd.Cmd, "/usr/local/bin/launcher"))
+	require.Equal(t, echoPath, cmd.Path)
+	require.Equal(t, []string{echoPath, "hello"}, cmd.Args)
+}
+
+func TestSandbox_Credential(t *testing.T) {
+	t.Parallel()
+
+	if os.Geteuid() != 0 {
+		t.Skip("running as another user requires root")
+	}
+
+	cmd := sandboxedCommand(t, &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: 65534, Gid: 65534},
+	}, "/bin/sh", "-c", "id -u; id -G")
+
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	require.Equal(t, "65534\n65534\n", string(out))
+}
@@ -0,0 +1,59 @@
+package allowedcmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimalEnvironment(t *testing.T) {
+	t.Parallel()
+
+	launcherEnv := []string{"PATH=/usr/bin:/bin", "HOME=/root", "KOLIDE_LAUNCHER_VERSION_CHAIN=1.2.3", "HTTPS_PROXY=http://proxy:3128"}
+
+	for _, tt := range []struct {
+		name     string
+		cmdEnv   []string
+		expected []string
+	}{
+		{
+			name:     "inherited",
+			cmdEnv:   nil,
+			expected: []string{"PATH=/usr/bin:/bin", "HOME=/root"},
+		},
+		{
+			name:     "added by caller",
+			cmdEnv:   append(append([]string{}, launcherEnv...), "HOMEBREW_NO_AUTO_UPDATE=1", "HOME=/home/alice"),
+			expected: []string{"PATH=/usr/bin:/bin", "HOME=/root", "HOMEBREW_NO_AUTO_UPDATE=1", "HOME=/home/alice"},
+		},
+		{
+			name:     "changed by caller",
+			cmdEnv:   []string{"HTTPS_PROXY=http://other:3128"},
+			expected: []string{"HTTPS_PROXY=http://other:3128"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, minimalEnvironment(tt.cmdEnv, launcherEnv))
+		})
+	}
+}
+
+func TestUnsandboxed(t *testing.T) {
+	t.Parallel()
+
+	cmd, err := Echo(context.TODO(), "hello")
+	require.NoError(t, err)
+	require.True(t, cmd.sandbox)
+
+	cmd, err = unsandboxed(cmd, err)
+	require.NoError(t, err)
+	require.False(t, cmd.sandbox)
+
+	cmd, err = unsandboxed(nil, ErrCommandNotFound)
+	require.ErrorIs(t, err, ErrCommandNotFound)
+	require.Nil(t, cmd)
+}
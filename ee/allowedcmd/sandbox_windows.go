@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package allowedcmd
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// applySandbox does nothing; commands aren't sandboxed on Windows.
+func applySandbox(_ *exec.Cmd, _ string) bool {
+	return false
+}
+
+// RunSandboxed is only used on Linux.
+func RunSandboxed(_ []string) error {
+	return errors.New("not supported on windows")
+}
@@ -0,0 +1,124 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package allowedcmd
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// deniedSyscalls are the syscalls sandboxed commands can't make. None of the commands we
+// run to gather data has a use for them: they load kernel code, change mounts, namespaces,
+// or the clock, trace or write to other processes, or reach the kernel keyring.
+var deniedSyscalls = append([]uintptr{
+	unix.SYS_ACCT,
+	unix.SYS_ADD_KEY,
+	unix.SYS_BPF,
+	unix.SYS_CLOCK_ADJTIME,
+	unix.SYS_CLOCK_SETTIME,
+	unix.SYS_DELETE_MODULE,
+	unix.SYS_FINIT_MODULE,
+	unix.SYS_FSCONFIG,
+	unix.SYS_FSMOUNT,
+	unix.SYS_FSOPEN,
+	unix.SYS_FSPICK,
+	unix.SYS_INIT_MODULE,
+	unix.SYS_KEXEC_FILE_LOAD,
+	unix.SYS_KEXEC_LOAD,
+	unix.SYS_KEYCTL,
+	unix.SYS_MOUNT,
+	unix.SYS_MOVE_MOUNT,
+	unix.SYS_OPEN_BY_HANDLE_AT,
+	unix.SYS_OPEN_TREE,
+	unix.SYS_PERF_EVENT_OPEN,
+	unix.SYS_PIVOT_ROOT,
+	unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_PTRACE,
+	unix.SYS_QUOTACTL,
+	unix.SYS_REBOOT,
+	unix.SYS_REQUEST_KEY,
+	unix.SYS_SETDOMAINNAME,
+	unix.SYS_SETHOSTNAME,
+	unix.SYS_SETNS,
+	unix.SYS_SETTIMEOFDAY,
+	unix.SYS_SWAPOFF,
+	unix.SYS_SWAPON,
+	unix.SYS_UMOUNT2,
+	unix.SYS_UNSHARE,
+	unix.SYS_USERFAULTFD,
+}, archDeniedSyscalls...)
+
+// Offsets into struct seccomp_data, see `man 2 seccomp`
+const (
+	seccompDataNrOffset   = 0
+	seccompDataArchOffset = 4
+)
+
+// seccompFilter returns the BPF program that denies deniedSyscalls with EPERM, as it does any
+// syscall made using another architecture's calling convention.
+func seccompFilter() []unix.SockFilter {
+	var filter []unix.SockFilter
+	// The deny instruction is last, after the 3 instructions checking the architecture and
+	// loading the syscall number, the syscall checks, and the allow. Jumps are relative to
+	// the instruction after the jump.
+	denyIndex := 3 + len(archSyscallChecks) + len(deniedSyscalls) + 1
+	deny := func(jumpIndex int) uint8 {
+		return uint8(denyIndex - jumpIndex - 1)
+	}
+
+	filter = append(filter,
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArchOffset),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, auditArch, 0, 0),
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNrOffset),
+	)
+	// Wrong architecture: jump past the syscall checks to deny
+	filter[1].Jf = deny(1)
+
+	for _, check := range archSyscallChecks {
+		filter = append(filter, bpfJump(check.code, check.k, 0, 0))
+		filter[len(filter)-1].Jt = deny(len(filter) - 1)
+	}
+	for _, nr := range deniedSyscalls {
+		filter = append(filter, bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), 0, 0))
+		filter[len(filter)-1].Jt = deny(len(filter) - 1)
+	}
+
+	return append(filter,
+		bpfStmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW),
+		bpfStmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|uint32(unix.EPERM)),
+	)
+}
+
+// installSeccompFilter installs the filter on the calling thread, which must be locked to
+// its OS thread and have no_new_privs set. The filter is inherited across exec.
+func installSeccompFilter() error {
+	filter := seccompFilter()
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0); err != nil {
+		return fmt.Errorf("installing seccomp filter: %w", err)
+	}
+
+	return nil
+}
+
+// bpfCheck is an architecture-specific jump to deny, checked before deniedSyscalls.
+type bpfCheck struct {
+	code uint16
+	k    uint32
+}
+
+func bpfStmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
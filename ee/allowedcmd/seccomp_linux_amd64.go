@@ -0,0 +1,20 @@
+//go:build linux && amd64
+// +build linux,amd64
+
+package allowedcmd
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_X86_64
+
+// x32SyscallBit marks syscalls made with the x32 ABI, which shares our audit architecture.
+const x32SyscallBit = 0x40000000
+
+var archDeniedSyscalls = []uintptr{
+	unix.SYS_IOPERM,
+	unix.SYS_IOPL,
+}
+
+var archSyscallChecks = []bpfCheck{
+	{code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, k: x32SyscallBit},
+}
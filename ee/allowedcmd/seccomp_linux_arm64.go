@@ -0,0 +1,12 @@
+//go:build linux && arm64
+// +build linux,arm64
+
+package allowedcmd
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_AARCH64
+
+var archDeniedSyscalls = []uintptr{}
+
+var archSyscallChecks = []bpfCheck{}
@@ -0,0 +1,26 @@
+// Package buildprofile reports which of launcher's optional subsystems this build includes.
+// The default build includes everything. The `minimal` build tag selects a profile for server
+// fleets, which excludes the desktop UI and the autoupdater -- the code for them isn't compiled
+// in, and the knapsack reports them disabled, so launcher behaves as if they were turned off.
+package buildprofile
+
+// Optional subsystems
+const (
+	Desktop    = "desktop"
+	Autoupdate = "autoupdate"
+)
+
+// Includes reports whether this build includes the given subsystem.
+func Includes(subsystem string) bool {
+	for _, excluded := range excludedSubsystems {
+		if excluded == subsystem {
+			return false
+		}
+	}
+	return true
+}
+
+// Excluded returns the subsystems this build excludes.
+func Excluded() []string {
+	return append([]string{}, excludedSubsystems...)
+}
@@ -0,0 +1,25 @@
+package buildprofile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludes(t *testing.T) {
+	t.Parallel()
+
+	for _, subsystem := range []string{Desktop, Autoupdate} {
+		switch Name {
+		case "full":
+			require.True(t, Includes(subsystem), subsystem)
+		case "minimal":
+			require.False(t, Includes(subsystem), subsystem)
+		default:
+			t.Fatalf("unknown profile %s", Name)
+		}
+	}
+
+	require.True(t, Includes("osquery"), "subsystems that aren't optional are always included")
+	require.Len(t, Excluded(), len(excludedSubsystems))
+}
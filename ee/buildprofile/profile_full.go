@@ -0,0 +1,9 @@
+//go:build !minimal
+// +build !minimal
+
+package buildprofile
+
+// Name is the name of this build's profile.
+const Name = "full"
+
+var excludedSubsystems = []string{}
@@ -0,0 +1,234 @@
+// Package consent implements privacy mode: the control server names the tables that collect
+// user-behavioral data, and those tables are kept out of distributed and scheduled queries until
+// the end user acknowledges a consent prompt in the desktop app. The user's decision is kept on
+// the device, reported to the control server as it changes, and exposed by the
+// kolide_data_collection_consent table.
+//
+// The prompt itself is part of the desktop menu, which the control server defines: while
+// consent is needed, the menu template data says so, and a menu item with a message-control
+// action sends the user's decision back to launcher with AcknowledgeMethod. The user is also
+// reminded periodically with a notification pointing them to the menu.
+package consent
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+// States of consent
+const (
+	StateNotRequired = "not_required" // privacy mode is off
+	StatePending     = "pending"      // the user hasn't responded to the current policy yet
+	StateGranted     = "granted"
+	StateDeclined    = "declined"
+)
+
+const (
+	// policyKey is the key in the control store for the last policy received
+	policyKey = "data_collection_consent_policy"
+
+	// decisionKey is the key in the persistent host data store for the user's decision
+	decisionKey = "data_collection_consent_decision"
+
+	// lastPromptedKey is the key in the persistent host data store for when the user was last
+	// reminded to respond
+	lastPromptedKey = "data_collection_consent_last_prompted"
+
+	defaultPromptInterval = 24 * time.Hour
+
+	// minPromptInterval keeps a misconfigured policy from reminding the user constantly
+	minPromptInterval = 1 * time.Hour
+)
+
+// tableNameRegex matches valid table names. Gated tables are matched against query text, so
+// anything else is dropped from the policy.
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Policy is set by the control server.
+type Policy struct {
+	// Enabled turns on privacy mode. Without it, no tables are gated.
+	Enabled bool `json:"enabled"`
+
+	// PolicyVersion identifies the consent text the user is asked to acknowledge. When it
+	// changes, earlier decisions no longer apply, and the user is asked again.
+	PolicyVersion string `json:"policy_version"`
+
+	// GatedTables are the tables kept out of queries until the user consents
+	GatedTables []string `json:"gated_tables"`
+
+	// The reminder sent while the user hasn't responded. NoticeUri, if set, is opened when
+	// the user clicks it, and should explain what is collected.
+	PromptTitle           string `json:"prompt_title,omitempty"`
+	PromptBody            string `json:"prompt_body,omitempty"`
+	NoticeUri             string `json:"notice_uri,omitempty"`
+	PromptIntervalSeconds int    `json:"prompt_interval_seconds,omitempty"`
+}
+
+func (p Policy) promptInterval() time.Duration {
+	if p.PromptIntervalSeconds <= 0 {
+		return defaultPromptInterval
+	}
+	if interval := time.Duration(p.PromptIntervalSeconds) * time.Second; interval > minPromptInterval {
+		return interval
+	}
+	return minPromptInterval
+}
+
+// gatedTables returns the valid, distinct gated table names, sorted.
+func (p Policy) gatedTables() []string {
+	seen := make(map[string]bool, len(p.GatedTables))
+	tables := make([]string, 0, len(p.GatedTables))
+	for _, t := range p.GatedTables {
+		if !tableNameRegex.MatchString(t) || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// Decision is the user's response to a version of the policy.
+type Decision struct {
+	PolicyVersion string    `json:"policy_version"`
+	Accepted      bool      `json:"accepted"`
+	DecidedAt     time.Time `json:"decided_at"`
+}
+
+// Status is the current state of consent.
+type Status struct {
+	State          string    `json:"state"`
+	PolicyVersion  string    `json:"policy_version"`
+	GatedTables    []string  `json:"gated_tables"`
+	DecidedAt      time.Time `json:"decided_at"`
+	LastPromptedAt time.Time `json:"last_prompted_at"`
+}
+
+// BlockedTables returns the tables that must be kept out of queries: the gated tables, unless
+// the user has consented or privacy mode is off.
+func (s Status) BlockedTables() []string {
+	if s.State != StatePending && s.State != StateDeclined {
+		return nil
+	}
+	return s.GatedTables
+}
+
+// reportable is the part of the status the control server is told about when it changes.
+func (s Status) reportable() string {
+	return fmt.Sprintf("%s/%s/%d/%v", s.State, s.PolicyVersion, s.DecidedAt.Unix(), s.GatedTables)
+}
+
+// statusFor returns the status, given the policy and the user's latest decision. A decision
+// about an earlier version of the policy doesn't count.
+func statusFor(policy Policy, decision Decision, lastPrompted time.Time) Status {
+	if !policy.Enabled {
+		return Status{State: StateNotRequired}
+	}
+
+	status := Status{
+		State:          StatePending,
+		PolicyVersion:  policy.PolicyVersion,
+		GatedTables:    policy.gatedTables(),
+		LastPromptedAt: lastPrompted,
+	}
+
+	if decision.PolicyVersion == policy.PolicyVersion && !decision.DecidedAt.IsZero() {
+		status.DecidedAt = decision.DecidedAt
+		status.State = StateDeclined
+		if decision.Accepted {
+			status.State = StateGranted
+		}
+	}
+
+	return status
+}
+
+// promptDue reports whether the user should be reminded to respond at now.
+func (s Status) promptDue(policy Policy, now time.Time) bool {
+	return s.State == StatePending && !now.Before(s.LastPromptedAt.Add(policy.promptInterval()))
+}
+
+// LoadStatus returns the current status, from the policy in policyStore and the user's
+// decision in statusStore.
+func LoadStatus(policyStore, statusStore types.Getter) (Status, error) {
+	policy, err := loadPolicy(policyStore)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var decision Decision
+	if err := getJson(statusStore, decisionKey, &decision); err != nil {
+		return Status{}, fmt.Errorf("loading consent decision: %w", err)
+	}
+
+	var lastPrompted time.Time
+	if err := getJson(statusStore, lastPromptedKey, &lastPrompted); err != nil {
+		return Status{}, fmt.Errorf("loading consent prompt time: %w", err)
+	}
+
+	return statusFor(policy, decision, lastPrompted), nil
+}
+
+// CurrentStatus returns the current status, from the knapsack's stores.
+func CurrentStatus(k types.Knapsack) (Status, error) {
+	return LoadStatus(k.ControlStore(), k.PersistentHostDataStore())
+}
+
+// BlockedTables returns the tables that must be kept out of queries, from the policy in
+// policyStore and the user's decision in statusStore. It fails closed: if the decision can't be
+// loaded, the user hasn't consented, so all the gated tables are blocked. If the policy can't be
+// loaded, the gated tables aren't known, and it returns an error -- callers should then block
+// all queries.
+func BlockedTables(policyStore, statusStore types.Getter) ([]string, error) {
+	policy, err := loadPolicy(policyStore)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := LoadStatus(policyStore, statusStore)
+	if err != nil {
+		return statusFor(policy, Decision{}, time.Time{}).BlockedTables(), nil
+	}
+
+	return status.BlockedTables(), nil
+}
+
+func loadPolicy(store types.Getter) (Policy, error) {
+	var policy Policy
+	if err := getJson(store, policyKey, &policy); err != nil {
+		return Policy{}, fmt.Errorf("loading consent policy: %w", err)
+	}
+	return policy, nil
+}
+
+// getJson unmarshals the value at key into v, leaving v untouched if there's no value.
+func getJson(store types.Getter, key string, v any) error {
+	raw, err := store.Get([]byte(key))
+	if err != nil {
+		return fmt.Errorf("getting %s: %w", key, err)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("unmarshalling %s: %w", key, err)
+	}
+	return nil
+}
+
+func setJson(store types.Setter, key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling %s: %w", key, err)
+	}
+	if err := store.Set([]byte(key), raw); err != nil {
+		return fmt.Errorf("setting %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package consent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/desktop/user/notify"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusFor(t *testing.T) {
+	t.Parallel()
+
+	decidedAt := time.Unix(1700000000, 0)
+	policy := Policy{
+		Enabled:       true,
+		PolicyVersion: "2024-06",
+		GatedTables:   []string{"shell_history", "kolide_chrome_history", "shell_history", "bad; table"},
+	}
+
+	// Privacy mode off
+	status := statusFor(Policy{PolicyVersion: "2024-06", GatedTables: []string{"shell_history"}}, Decision{}, time.Time{})
+	require.Equal(t, StateNotRequired, status.State)
+	require.Empty(t, status.BlockedTables())
+
+	// No decision yet; invalid and duplicate table names are dropped
+	status = statusFor(policy, Decision{}, time.Time{})
+	require.Equal(t, StatePending, status.State)
+	require.Equal(t, []string{"kolide_chrome_history", "shell_history"}, status.GatedTables)
+	require.Equal(t, status.GatedTables, status.BlockedTables())
+
+	// Accepted
+	status = statusFor(policy, Decision{PolicyVersion: "2024-06", Accepted: true, DecidedAt: decidedAt}, time.Time{})
+	require.Equal(t, StateGranted, status.State)
+	require.Equal(t, decidedAt, status.DecidedAt)
+	require.Empty(t, status.BlockedTables())
+
+	// Declined
+	status = statusFor(policy, Decision{PolicyVersion: "2024-06", Accepted: false, DecidedAt: decidedAt}, time.Time{})
+	require.Equal(t, StateDeclined, status.State)
+	require.Equal(t, status.GatedTables, status.BlockedTables())
+
+	// Accepted an earlier version of the policy
+	status = statusFor(policy, Decision{PolicyVersion: "2023-01", Accepted: true, DecidedAt: decidedAt}, time.Time{})
+	require.Equal(t, StatePending, status.State)
+	require.True(t, status.DecidedAt.IsZero())
+	require.Equal(t, status.GatedTables, status.BlockedTables())
+}
+
+func TestPromptDue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+	policy := Policy{Enabled: true, PolicyVersion: "1"}
+
+	require.True(t, Status{State: StatePending}.promptDue(policy, now))
+	require.False(t, Status{State: StatePending, LastPromptedAt: now.Add(-time.Hour)}.promptDue(policy, now))
+	require.True(t, Status{State: StatePending, LastPromptedAt: now.Add(-defaultPromptInterval)}.promptDue(policy, now))
+	require.False(t, Status{State: StateDeclined}.promptDue(policy, now))
+	require.False(t, Status{State: StateGranted}.promptDue(policy, now))
+
+	// Prompt intervals are clamped
+	require.Equal(t, minPromptInterval, Policy{PromptIntervalSeconds: 60}.promptInterval())
+	require.Equal(t, 2*time.Hour, Policy{PromptIntervalSeconds: 7200}.promptInterval())
+}
+
+type mockNotifier struct {
+	err  error
+	sent []notify.Notification
+}
+
+func (m *mockNotifier) SendNotification(n notify.Notification) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, n)
+	return nil
+}
+
+type sentMessage struct {
+	method string
+	params interface{}
+}
+
+type mockMessenger struct {
+	messages []sentMessage
+}
+
+func (m *mockMessenger) SendMessage(method string, params interface{}) error {
+	m.messages = append(m.messages, sentMessage{method: method, params: params})
+	return nil
+}
+
+func testTracker(notifier *mockNotifier, messenger *mockMessenger) *Tracker {
+	return &Tracker{
+		slogger:       multislogger.NewNopLogger(),
+		policyStore:   inmemory.NewStore(),
+		statusStore:   inmemory.NewStore(),
+		notifier:      notifier,
+		messenger:     messenger,
+		checkRequests: make(chan struct{}, 1),
+		interrupt:     make(chan struct{}, 1),
+	}
+}
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	notifier := &mockNotifier{}
+	messenger := &mockMessenger{}
+	tracker := testTracker(notifier, messenger)
+	now := time.Unix(1700000000, 0)
+
+	require.NoError(t, tracker.Update(strings.NewReader(`{"enabled":true,"policy_version":"2024-06","gated_tables":["shell_history"],"notice_uri":"https://example.com/privacy"}`)))
+
+	// Pending: the user is reminded, and the status is reported
+	tracker.check(context.TODO(), now)
+	require.Len(t, notifier.sent, 1)
+	require.Equal(t, "https://example.com/privacy", notifier.sent[0].ActionUri)
+	require.Len(t, messenger.messages, 1)
+	require.Equal(t, StatusMethod, messenger.messages[0].method)
+	require.Equal(t, StatePending, messenger.messages[0].params.(Status).State)
+
+	// Not again until the prompt interval has passed, and unchanged status isn't reported again
+	tracker.check(context.TODO(), now.Add(time.Hour))
+	require.Len(t, notifier.sent, 1)
+	require.Len(t, messenger.messages, 1)
+
+	// Other messages from the desktop app are passed on
+	require.NoError(t, tracker.SendMessage("some_other_method", map[string]any{"a": "b"}))
+	require.Len(t, messenger.messages, 2)
+	require.Equal(t, "some_other_method", messenger.messages[1].method)
+
+	// Decisions about a different policy version, or that don't say what the user decided, are rejected
+	require.Error(t, tracker.SendMessage(AcknowledgeMethod, map[string]any{"policy_version": "2023-01", "accepted": true}))
+	require.Error(t, tracker.SendMessage(AcknowledgeMethod, map[string]any{"policy_version": "2024-06"}))
+
+	status, err := LoadStatus(tracker.policyStore, tracker.statusStore)
+	require.NoError(t, err)
+	require.Equal(t, StatePending, status.State)
+
+	// Accepting lifts the gate, and is reported
+	require.NoError(t, tracker.SendMessage(AcknowledgeMethod, map[string]any{"policy_version": "2024-06", "accepted": true}))
+	status, err = LoadStatus(tracker.policyStore, tracker.statusStore)
+	require.NoError(t, err)
+	require.Equal(t, StateGranted, status.State)
+	require.Empty(t, status.BlockedTables())
+
+	tracker.check(context.TODO(), now.Add(48*time.Hour))
+	require.Len(t, notifier.sent, 1, "user should not be reminded after responding")
+	require.Len(t, messenger.messages, 3)
+	require.Equal(t, StateGranted, messenger.messages[2].params.(Status).State)
+
+	// A new policy version needs consent again
+	require.NoError(t, tracker.Update(strings.NewReader(`{"enabled":true,"policy_version":"2024-09","gated_tables":["shell_history"]}`)))
+	status, err = LoadStatus(tracker.policyStore, tracker.statusStore)
+	require.NoError(t, err)
+	require.Equal(t, StatePending, status.State)
+	require.Equal(t, []string{"shell_history"}, status.BlockedTables())
+}
+
+func TestTracker_PromptFailure(t *testing.T) {
+	t.Parallel()
+
+	notifier := &mockNotifier{err: errors.New("no desktop")}
+	tracker := testTracker(notifier, &mockMessenger{})
+	now := time.Unix(1700000000, 0)
+
+	require.NoError(t, tracker.Update(strings.NewReader(`{"enabled":true,"policy_version":"1","gated_tables":["shell_history"]}`)))
+
+	// A reminder that couldn't be sent doesn't count
+	tracker.check(context.TODO(), now)
+	notifier.err = nil
+	tracker.check(context.TODO(), now.Add(10*time.Minute))
+	require.Len(t, notifier.sent, 1)
+}
+
+func TestTrackerUpdate_InvalidPolicy(t *testing.T) {
+	t.Parallel()
+
+	tracker := testTracker(&mockNotifier{}, &mockMessenger{})
+	require.Error(t, tracker.Update(strings.NewReader(`not json`)))
+	require.Error(t, tracker.Update(strings.NewReader(`{"enabled":true,"gated_tables":["shell_history"]}`)))
+}
+
+func TestBlockedTables(t *testing.T) {
+	t.Parallel()
+
+	policyStore := inmemory.NewStore()
+	statusStore := inmemory.NewStore()
+	require.NoError(t, setJson(policyStore, policyKey, Policy{Enabled: true, PolicyVersion: "1", GatedTables: []string{"shell_history"}}))
+	require.NoError(t, setJson(statusStore, decisionKey, Decision{PolicyVersion: "1", Accepted: true, DecidedAt: time.Now()}))
+
+	blocked, err := BlockedTables(policyStore, statusStore)
+	require.NoError(t, err)
+	require.Empty(t, blocked)
+
+	// If the decision can't be read, the gated tables are blocked
+	require.NoError(t, statusStore.Set([]byte(decisionKey), []byte("not json")))
+	blocked, err = BlockedTables(policyStore, statusStore)
+	require.NoError(t, err)
+	require.Equal(t, []string{"shell_history"}, blocked)
+
+	// If the policy can't be read, the gated tables are unknown
+	require.NoError(t, policyStore.Set([]byte(policyKey), []byte("not json")))
+	_, err = BlockedTables(policyStore, statusStore)
+	require.Error(t, err)
+}
@@ -0,0 +1,294 @@
+package consent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/desktop/user/notify"
+)
+
+const (
+	// Subsystem is the control server subsystem the consent policy is sent with
+	Subsystem = "data_collection_consent"
+
+	// StatusMethod is the control server message method status changes are sent with
+	StatusMethod = "data_collection_consent_status"
+
+	// AcknowledgeMethod is the message method the desktop menu sends the user's decision with.
+	// Its params are the policy version the user responded to, and whether they accepted:
+	// {"policy_version": "2024-06", "accepted": true}
+	AcknowledgeMethod = "data_collection_consent_acknowledge"
+
+	// initialDelay gives the control service time to authenticate, and desktop time to start,
+	// before we first check
+	initialDelay = 1 * time.Minute
+
+	checkInterval = 10 * time.Minute
+
+	defaultPromptTitle = "Data collection consent"
+	defaultPromptBody  = "Your organization needs your consent before Kolide collects some information about how you use this device. Open the Kolide menu to review and respond."
+)
+
+type messenger interface {
+	SendMessage(method string, params interface{}) error
+}
+
+// The desktop runner fulfills this interface
+type notifier interface {
+	SendNotification(n notify.Notification) error
+}
+
+// Tracker keeps the consent policy from the control server, records the user's decisions from
+// the desktop app, reminds the user while they haven't responded, and reports changes to the
+// control server. It's a control consumer for the policy, and a run group actor.
+//
+// Tracker is also the messenger for the desktop runner: it handles the user's decisions, and
+// passes every other message from the desktop app on to the control server.
+type Tracker struct {
+	slogger       *slog.Logger
+	policyStore   types.GetterSetter
+	statusStore   types.GetterSetter
+	messenger     messenger
+	notifier      notifier
+	lock          sync.Mutex
+	lastReported  string
+	checkRequests chan struct{}
+	interrupt     chan struct{}
+	interrupted   atomic.Bool
+}
+
+func New(k types.Knapsack, messenger messenger) *Tracker {
+	return &Tracker{
+		slogger:       k.Slogger().With("component", "data_collection_consent"),
+		policyStore:   k.ControlStore(),
+		statusStore:   k.PersistentHostDataStore(),
+		messenger:     messenger,
+		checkRequests: make(chan struct{}, 1),
+		interrupt:     make(chan struct{}, 1),
+	}
+}
+
+// SetNotifier sets the notifier used to remind the user. The desktop runner is created with
+// the tracker as its messenger, so it can't be passed to New.
+func (t *Tracker) SetNotifier(n notifier) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.notifier = n
+}
+
+func (t *Tracker) Execute() error {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	initial := time.After(initialDelay)
+	for {
+		select {
+		case <-initial:
+		case <-ticker.C:
+		case <-t.checkRequests:
+		case <-t.interrupt:
+			t.slogger.Log(context.TODO(), slog.LevelDebug,
+				"received external interrupt, stopping",
+			)
+			return nil
+		}
+
+		t.check(context.TODO(), time.Now())
+	}
+}
+
+func (t *Tracker) Interrupt(_ error) {
+	// Only perform shutdown tasks on first call to interrupt -- no need to repeat on potential extra calls.
+	if t.interrupted.Load() {
+		return
+	}
+	t.interrupted.Store(true)
+
+	t.interrupt <- struct{}{}
+}
+
+// Ping requests a check, without waiting for the next interval.
+func (t *Tracker) Ping() {
+	select {
+	case t.checkRequests <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+}
+
+// Update satisfies the control.consumer interface. It receives the consent policy.
+func (t *Tracker) Update(data io.Reader) error {
+	var policy Policy
+	if err := json.NewDecoder(data).Decode(&policy); err != nil {
+		return fmt.Errorf("decoding consent policy: %w", err)
+	}
+
+	if policy.Enabled && policy.PolicyVersion == "" {
+		return errors.New("consent policy is enabled, but has no policy version")
+	}
+
+	t.lock.Lock()
+	err := setJson(t.policyStore, policyKey, policy)
+	t.lock.Unlock()
+	if err != nil {
+		return fmt.Errorf("saving consent policy: %w", err)
+	}
+
+	t.Ping()
+	return nil
+}
+
+// SendMessage records the user's decision, if the message is one, and otherwise passes the
+// message on to the control server.
+func (t *Tracker) SendMessage(method string, params interface{}) error {
+	if method != AcknowledgeMethod {
+		return t.messenger.SendMessage(method, params)
+	}
+
+	if err := t.acknowledge(params, time.Now()); err != nil {
+		t.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not record data collection consent decision",
+			"err", err,
+		)
+		return err
+	}
+
+	t.Ping()
+	return nil
+}
+
+// acknowledge records the user's decision about the current policy.
+func (t *Tracker) acknowledge(params interface{}, now time.Time) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshalling consent params: %w", err)
+	}
+
+	var ack struct {
+		PolicyVersion string `json:"policy_version"`
+		Accepted      *bool  `json:"accepted"`
+	}
+	if err := json.Unmarshal(raw, &ack); err != nil {
+		return fmt.Errorf("unmarshalling consent params: %w", err)
+	}
+	if ack.Accepted == nil {
+		return errors.New("consent params do not say whether the user accepted")
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	policy, err := loadPolicy(t.policyStore)
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled {
+		return errors.New("privacy mode is not enabled")
+	}
+	// The menu may not have refreshed since the policy changed; the user must respond to
+	// the version they'll actually be held to
+	if ack.PolicyVersion != policy.PolicyVersion {
+		return fmt.Errorf("decision is for policy version %q, but current version is %q", ack.PolicyVersion, policy.PolicyVersion)
+	}
+
+	decision := Decision{
+		PolicyVersion: policy.PolicyVersion,
+		Accepted:      *ack.Accepted,
+		DecidedAt:     now,
+	}
+	if err := setJson(t.statusStore, decisionKey, decision); err != nil {
+		return fmt.Errorf("saving consent decision: %w", err)
+	}
+
+	t.slogger.Log(context.TODO(), slog.LevelInfo,
+		"recorded data collection consent decision",
+		"policy_version", decision.PolicyVersion,
+		"accepted", decision.Accepted,
+	)
+
+	return nil
+}
+
+// check reminds the user if they haven't responded and a reminder is due, and reports the
+// status if it changed.
+func (t *Tracker) check(ctx context.Context, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	policy, err := loadPolicy(t.policyStore)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not load consent policy",
+			"err", err,
+		)
+		return
+	}
+
+	status, err := LoadStatus(t.policyStore, t.statusStore)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not load consent status",
+			"err", err,
+		)
+		return
+	}
+
+	if t.notifier != nil && status.promptDue(policy, now) {
+		if err := t.notifier.SendNotification(promptFor(policy, now)); err != nil {
+			// Try again at the next check; the reminder doesn't count until the user could see it
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not send consent reminder",
+				"err", err,
+			)
+		} else if err := setJson(t.statusStore, lastPromptedKey, now); err != nil {
+			t.slogger.Log(ctx, slog.LevelWarn,
+				"could not save consent reminder time",
+				"err", err,
+			)
+		} else {
+			status.LastPromptedAt = now
+		}
+	}
+
+	if status.reportable() == t.lastReported {
+		return
+	}
+	if err := t.messenger.SendMessage(StatusMethod, status); err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not report consent status, will retry",
+			"err", err,
+		)
+		return
+	}
+	t.lastReported = status.reportable()
+}
+
+// promptFor builds the reminder for the policy.
+func promptFor(policy Policy, now time.Time) notify.Notification {
+	title := policy.PromptTitle
+	if title == "" {
+		title = defaultPromptTitle
+	}
+	body := policy.PromptBody
+	if body == "" {
+		body = defaultPromptBody
+	}
+
+	return notify.Notification{
+		Title:     title,
+		Body:      body,
+		ActionUri: policy.NoticeUri,
+		ID:        fmt.Sprintf("%s_%d", Subsystem, now.Unix()),
+		// Don't let a reminder that couldn't be shown pile up behind the next one
+		ValidUntil: now.Add(policy.promptInterval()).Unix(),
+	}
+}
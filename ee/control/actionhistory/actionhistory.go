@@ -0,0 +1,221 @@
+// Package actionhistory keeps an audit log of the actions the control server has taken on this
+// device -- restarts, uninstalls, notifications, flag changes, and so on -- as endpoint-side
+// evidence of remote management. Each record includes a digest of the action's payload, rather
+// than the payload itself, since payloads may contain data we shouldn't keep around.
+package actionhistory
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+// Outcomes of actions
+const (
+	OutcomeCompleted   = "completed"
+	OutcomeFailed      = "failed"
+	OutcomeUnsupported = "unsupported" // launcher has no actor for the action's type
+	OutcomeInvalid     = "invalid"     // the action is missing its ID, or has expired
+	OutcomeMalformed   = "malformed"   // the action could not be parsed
+)
+
+const (
+	// Retention matches how long the action queue remembers processed actions
+	retentionPeriod = time.Hour * 24 * 30 * 6
+	maxRecords      = 1000
+)
+
+// Record is an entry in the action history.
+type Record struct {
+	Time          int64  `json:"time"` // unix seconds
+	Subsystem     string `json:"subsystem"`
+	Type          string `json:"type"`
+	ActionId      string `json:"action_id,omitempty"`
+	PayloadSha256 string `json:"payload_sha256"`
+	Outcome       string `json:"outcome"`
+	Error         string `json:"error,omitempty"`
+}
+
+type History struct {
+	slogger *slog.Logger
+	store   types.GetterSetterDeleterIterator
+	lock    sync.Mutex
+	lastKey time.Time
+}
+
+func New(slogger *slog.Logger, store types.GetterSetterDeleterIterator) *History {
+	return &History{
+		slogger: slogger.With("component", "control_action_history"),
+		store:   store,
+	}
+}
+
+// PayloadDigest returns the hex-encoded SHA256 digest of an action's payload.
+func PayloadDigest(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Record adds r to the history, and prunes records past the retention period or
+// beyond the maximum count.
+func (h *History) Record(ctx context.Context, r Record) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+	if r.Time == 0 {
+		r.Time = now.Unix()
+	}
+
+	rawRecord, err := json.Marshal(r)
+	if err != nil {
+		h.slogger.Log(ctx, slog.LevelError,
+			"could not marshal action history record",
+			"err", err,
+		)
+		return
+	}
+
+	// The clock may be too coarse to tell apart records made in quick succession, so make
+	// sure each record gets its own key
+	keyTime := now
+	if !keyTime.After(h.lastKey) {
+		keyTime = h.lastKey.Add(time.Nanosecond)
+	}
+	h.lastKey = keyTime
+
+	if err := h.store.Set(recordKey(keyTime), rawRecord); err != nil {
+		h.slogger.Log(ctx, slog.LevelWarn,
+			"could not store action history record",
+			"err", err,
+		)
+		return
+	}
+
+	if err := h.prune(now); err != nil {
+		h.slogger.Log(ctx, slog.LevelWarn,
+			"could not prune action history",
+			"err", err,
+		)
+	}
+}
+
+// recordKey returns the store key for a record made at the given time. Keys sort in the
+// order the records were made.
+func recordKey(t time.Time) []byte {
+	return []byte(fmt.Sprintf("%020d", t.UnixNano()))
+}
+
+func (h *History) prune(now time.Time) error {
+	cutoff := string(recordKey(now.Add(-retentionPeriod)))
+
+	var keys []string
+	if err := h.store.ForEach(func(k, _ []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}); err != nil {
+		return fmt.Errorf("iterating over action history: %w", err)
+	}
+	sort.Strings(keys)
+
+	toDelete := make([][]byte, 0)
+	for i, k := range keys {
+		if k < cutoff || len(keys)-i > maxRecords {
+			toDelete = append(toDelete, []byte(k))
+		}
+	}
+
+	if len(toDelete) == 0 {
+		return nil
+	}
+
+	return h.store.Delete(toDelete...)
+}
+
+// Records returns the records in the history, oldest first.
+func Records(store types.Iterator) ([]Record, error) {
+	type keyedRecord struct {
+		key    string
+		record Record
+	}
+
+	var keyedRecords []keyedRecord
+	if err := store.ForEach(func(k, v []byte) error {
+		var r Record
+		if err := json.Unmarshal(v, &r); err != nil {
+			// Skip anything we can't read, rather than failing the whole history
+			return nil
+		}
+		keyedRecords = append(keyedRecords, keyedRecord{key: string(k), record: r})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("iterating over action history: %w", err)
+	}
+
+	sort.Slice(keyedRecords, func(i, j int) bool {
+		return keyedRecords[i].key < keyedRecords[j].key
+	})
+
+	records := make([]Record, 0, len(keyedRecords))
+	for _, kr := range keyedRecords {
+		records = append(records, kr.record)
+	}
+
+	return records, nil
+}
+
+type consumer interface {
+	Update(data io.Reader) error
+}
+
+// recordingConsumer wraps a control service consumer, recording each update it consumes.
+type recordingConsumer struct {
+	history   *History
+	subsystem string
+	consumer  consumer
+}
+
+// NewRecordingConsumer returns a consumer for the given control subsystem that records each
+// update in the history before passing it on to the wrapped consumer. Use it for subsystems
+// whose updates change how launcher behaves, like agent flags.
+func NewRecordingConsumer(history *History, subsystem string, c consumer) *recordingConsumer {
+	return &recordingConsumer{
+		history:   history,
+		subsystem: subsystem,
+		consumer:  c,
+	}
+}
+
+func (rc *recordingConsumer) Update(data io.Reader) error {
+	payload, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("reading %s update: %w", rc.subsystem, err)
+	}
+
+	r := Record{
+		Subsystem:     rc.subsystem,
+		Type:          rc.subsystem,
+		PayloadSha256: PayloadDigest(payload),
+		Outcome:       OutcomeCompleted,
+	}
+
+	updateErr := rc.consumer.Update(bytes.NewReader(payload))
+	if updateErr != nil {
+		r.Outcome = OutcomeFailed
+		r.Error = updateErr.Error()
+	}
+
+	rc.history.Record(context.TODO(), r)
+
+	return updateErr
+}
@@ -0,0 +1,149 @@
+package actionhistory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	h := New(multislogger.NewNopLogger(), store)
+
+	h.Record(context.TODO(), Record{
+		Subsystem:     "actions",
+		Type:          "uninstall",
+		ActionId:      "abc",
+		PayloadSha256: PayloadDigest([]byte(`{"id":"abc"}`)),
+		Outcome:       OutcomeCompleted,
+	})
+	h.Record(context.TODO(), Record{
+		Subsystem: "actions",
+		Type:      "not_a_real_type",
+		ActionId:  "def",
+		Outcome:   OutcomeUnsupported,
+		Error:     "no actor",
+	})
+
+	records, err := Records(store)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	require.Equal(t, "abc", records[0].ActionId)
+	require.Equal(t, OutcomeCompleted, records[0].Outcome)
+	require.Equal(t, PayloadDigest([]byte(`{"id":"abc"}`)), records[0].PayloadSha256)
+	require.InDelta(t, time.Now().Unix(), records[0].Time, 5)
+
+	require.Equal(t, "def", records[1].ActionId)
+	require.Equal(t, OutcomeUnsupported, records[1].Outcome)
+	require.Equal(t, "no actor", records[1].Error)
+}
+
+func TestRecord_Prunes(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	h := New(multislogger.NewNopLogger(), store)
+
+	// A record past the retention period
+	require.NoError(t, store.Set(recordKey(time.Now().Add(-2*retentionPeriod)), []byte(`{"action_id":"expired"}`)))
+
+	// Fill the store to its maximum
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < maxRecords; i++ {
+		require.NoError(t, store.Set(recordKey(start.Add(time.Duration(i)*time.Millisecond)), []byte(fmt.Sprintf(`{"action_id":"%d"}`, i))))
+	}
+
+	h.Record(context.TODO(), Record{ActionId: "latest", Outcome: OutcomeCompleted})
+
+	records, err := Records(store)
+	require.NoError(t, err)
+	require.Len(t, records, maxRecords)
+	require.Equal(t, "1", records[0].ActionId, "expected the expired record and the oldest record to be pruned")
+	require.Equal(t, "latest", records[len(records)-1].ActionId)
+}
+
+func TestRecords_SkipsUnreadable(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	require.NoError(t, store.Set(recordKey(time.Now()), []byte(`not json`)))
+
+	records, err := Records(store)
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
+
+type testConsumer struct {
+	received []byte
+	err      error
+}
+
+func (c *testConsumer) Update(data io.Reader) error {
+	var err error
+	c.received, err = io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	return c.err
+}
+
+func TestRecordingConsumer(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name            string
+		consumerErr     error
+		expectedOutcome string
+	}{
+		{
+			name:            "update succeeds",
+			expectedOutcome: OutcomeCompleted,
+		},
+		{
+			name:            "update fails",
+			consumerErr:     errors.New("test error"),
+			expectedOutcome: OutcomeFailed,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := inmemory.NewStore()
+			inner := &testConsumer{err: tt.consumerErr}
+			rc := NewRecordingConsumer(New(multislogger.NewNopLogger(), store), "agent_flags", inner)
+
+			payload := []byte(`{"desktop_enabled":"true"}`)
+			err := rc.Update(bytes.NewReader(payload))
+			if tt.consumerErr != nil {
+				require.ErrorIs(t, err, tt.consumerErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// The wrapped consumer still receives the full payload
+			require.Equal(t, payload, inner.received)
+
+			records, err := Records(store)
+			require.NoError(t, err)
+			require.Len(t, records, 1)
+			require.Equal(t, "agent_flags", records[0].Subsystem)
+			require.Equal(t, PayloadDigest(payload), records[0].PayloadSha256)
+			require.Equal(t, tt.expectedOutcome, records[0].Outcome)
+			if tt.consumerErr != nil {
+				require.Equal(t, tt.consumerErr.Error(), records[0].Error)
+			}
+		})
+	}
+}
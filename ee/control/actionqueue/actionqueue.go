@@ -12,6 +12,8 @@ import (
 
 	"github.com/kolide/launcher/ee/agent/storage/inmemory"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/control/actionhistory"
+	"github.com/kolide/launcher/ee/maintenancewindow"
 )
 
 const (
@@ -42,6 +44,7 @@ type ActionQueue struct {
 	actors                map[string]actor
 	store                 types.KVStore
 	oldNotificationsStore types.KVStore
+	history               *actionhistory.History
 	slogger               *slog.Logger
 	actionCleanupInterval time.Duration
 	cancel                context.CancelFunc
@@ -61,6 +64,13 @@ func WithOldNotificationsStore(store types.KVStore) actionqueueOption {
 	}
 }
 
+// WithHistory records every action the queue receives, and its outcome, in the given history.
+func WithHistory(history *actionhistory.History) actionqueueOption {
+	return func(aq *ActionQueue) {
+		aq.history = history
+	}
+}
+
 func WithCleanupInterval(cleanupInterval time.Duration) actionqueueOption {
 	return func(aq *ActionQueue) {
 		aq.actionCleanupInterval = cleanupInterval
@@ -109,10 +119,18 @@ func (aq *ActionQueue) Update(data io.Reader) error {
 				"received action in unexpected format from K2, discarding",
 				"err", err,
 			)
+			aq.recordHistory(action, rawAction, actionhistory.OutcomeMalformed, err)
 			continue
 		}
 
-		if !aq.isActionValid(action) || !aq.isActionNew(action.ID) {
+		if !aq.isActionValid(action) {
+			aq.recordHistory(action, rawAction, actionhistory.OutcomeInvalid, nil)
+			continue
+		}
+
+		// Actions we've already processed are sent again until they expire, so we
+		// don't record them again
+		if !aq.isActionNew(action.ID) {
 			continue
 		}
 
@@ -122,14 +140,25 @@ func (aq *ActionQueue) Update(data io.Reader) error {
 				"getting actor for action",
 				"err", err,
 			)
+			aq.recordHistory(action, rawAction, actionhistory.OutcomeUnsupported, err)
 			continue
 		}
 
 		if err := actor.Do(bytes.NewReader(rawAction)); err != nil {
+			// Deferred actions will be sent down again, so we record them once they're done
+			if errors.Is(err, maintenancewindow.ErrDeferred) {
+				aq.slogger.Log(context.TODO(), slog.LevelDebug,
+					"action deferred until maintenance window, not marking action complete",
+					"action_type", action.Type,
+				)
+				continue
+			}
+
 			aq.slogger.Log(context.TODO(), slog.LevelInfo,
 				"failed to do action with action, not marking action complete",
 				"err", err,
 			)
+			aq.recordHistory(action, rawAction, actionhistory.OutcomeFailed, err)
 			processError = fmt.Errorf("actor.Do, action type: %s, failed: %w", action.Type, err)
 			continue
 		}
@@ -137,6 +166,7 @@ func (aq *ActionQueue) Update(data io.Reader) error {
 		// only mark processed when actor was successful
 		action.ProcessedAt = time.Now().UTC()
 		aq.storeActionRecord(action)
+		aq.recordHistory(action, rawAction, actionhistory.OutcomeCompleted, nil)
 	}
 
 	return processError
@@ -193,6 +223,26 @@ func (aq *ActionQueue) storeActionRecord(actionToStore action) {
 	}
 }
 
+// recordHistory adds the action and its outcome to the action history, if configured.
+func (aq *ActionQueue) recordHistory(a action, rawAction []byte, outcome string, actionErr error) {
+	if aq.history == nil {
+		return
+	}
+
+	r := actionhistory.Record{
+		Subsystem:     ActionsSubsystem,
+		Type:          a.Type,
+		ActionId:      a.ID,
+		PayloadSha256: actionhistory.PayloadDigest(rawAction),
+		Outcome:       outcome,
+	}
+	if actionErr != nil {
+		r.Error = actionErr.Error()
+	}
+
+	aq.history.Record(context.TODO(), r)
+}
+
 func (aq *ActionQueue) isActionNew(id string) bool {
 	completedActionRaw, err := aq.store.Get([]byte(id))
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"testing"
 	"time"
@@ -12,9 +13,12 @@ import (
 	"github.com/kolide/kit/ulid"
 	"github.com/kolide/launcher/ee/agent/storage"
 	storageci "github.com/kolide/launcher/ee/agent/storage/ci"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
 	"github.com/kolide/launcher/ee/agent/types"
 	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/ee/control/actionhistory"
 	"github.com/kolide/launcher/ee/control/actionqueue/mocks"
+	"github.com/kolide/launcher/ee/maintenancewindow"
 	"github.com/kolide/launcher/pkg/log/multislogger"
 	"github.com/kolide/launcher/pkg/threadsafebuffer"
 	"github.com/stretchr/testify/mock"
@@ -357,6 +361,99 @@ func TestActionQueue_HandlesMalformedActions(t *testing.T) {
 	require.NoError(t, actionqueue.Update(testActionsData))
 }
 
+func TestActionQueue_RecordsHistory(t *testing.T) {
+	t.Parallel()
+
+	completedAction := action{ID: ulid.New(), ValidUntil: getValidUntil(), Type: testActorType}
+	failedAction := action{ID: ulid.New(), ValidUntil: getValidUntil(), Type: anotherTestActorType}
+	unsupportedAction := action{ID: ulid.New(), ValidUntil: getValidUntil(), Type: "type-not-found"}
+	invalidAction := action{ID: ulid.New(), Type: testActorType}
+	completedActionRaw := mustJsonMarshal(t, completedAction)
+	testActions := []json.RawMessage{
+		completedActionRaw,
+		mustJsonMarshal(t, failedAction),
+		mustJsonMarshal(t, unsupportedAction),
+		mustJsonMarshal(t, invalidAction),
+		json.RawMessage(`"not an action"`),
+	}
+	testActionsRaw := mustJsonMarshal(t, testActions)
+
+	mockActor := mocks.NewActor(t)
+	mockActor.On("Do", mock.Anything).Return(nil).Once()
+	anotherMockActor := mocks.NewActor(t)
+	anotherMockActor.On("Do", mock.Anything).Return(errors.New("test error")).Twice()
+
+	mockKnapsack := typesmocks.NewKnapsack(t)
+	mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
+
+	historyStore := inmemory.NewStore()
+	actionqueue := New(mockKnapsack, WithHistory(actionhistory.New(multislogger.NewNopLogger(), historyStore)))
+	actionqueue.RegisterActor(testActorType, mockActor)
+	actionqueue.RegisterActor(anotherTestActorType, anotherMockActor)
+
+	require.Error(t, actionqueue.Update(bytes.NewReader(testActionsRaw)))
+
+	records, err := actionhistory.Records(historyStore)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+
+	require.Equal(t, completedAction.ID, records[0].ActionId)
+	require.Equal(t, testActorType, records[0].Type)
+	require.Equal(t, ActionsSubsystem, records[0].Subsystem)
+	require.Equal(t, actionhistory.PayloadDigest(completedActionRaw), records[0].PayloadSha256)
+	require.Equal(t, actionhistory.OutcomeCompleted, records[0].Outcome)
+
+	require.Equal(t, failedAction.ID, records[1].ActionId)
+	require.Equal(t, actionhistory.OutcomeFailed, records[1].Outcome)
+	require.Contains(t, records[1].Error, "test error")
+
+	require.Equal(t, unsupportedAction.ID, records[2].ActionId)
+	require.Equal(t, actionhistory.OutcomeUnsupported, records[2].Outcome)
+
+	require.Equal(t, invalidAction.ID, records[3].ActionId)
+	require.Equal(t, actionhistory.OutcomeInvalid, records[3].Outcome)
+
+	require.Equal(t, actionhistory.OutcomeMalformed, records[4].Outcome)
+
+	// Sending the same actions again records only those that weren't completed
+	require.Error(t, actionqueue.Update(bytes.NewReader(testActionsRaw)))
+	records, err = actionhistory.Records(historyStore)
+	require.NoError(t, err)
+	require.Len(t, records, 9)
+	require.Equal(t, failedAction.ID, records[5].ActionId)
+}
+
+func TestActionQueue_DeferredActions(t *testing.T) {
+	t.Parallel()
+
+	deferredAction := action{ID: ulid.New(), ValidUntil: getValidUntil(), Type: testActorType}
+	testActionsRaw := mustJsonMarshal(t, []json.RawMessage{mustJsonMarshal(t, deferredAction)})
+
+	mockActor := mocks.NewActor(t)
+	mockActor.On("Do", mock.Anything).Return(fmt.Errorf("wrapped: %w", maintenancewindow.ErrDeferred)).Once()
+	mockActor.On("Do", mock.Anything).Return(nil).Once()
+
+	mockKnapsack := typesmocks.NewKnapsack(t)
+	mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
+
+	historyStore := inmemory.NewStore()
+	actionqueue := New(mockKnapsack, WithHistory(actionhistory.New(multislogger.NewNopLogger(), historyStore)))
+	actionqueue.RegisterActor(testActorType, mockActor)
+
+	// A deferred action is not an error, and is neither marked complete nor recorded
+	require.NoError(t, actionqueue.Update(bytes.NewReader(testActionsRaw)))
+	records, err := actionhistory.Records(historyStore)
+	require.NoError(t, err)
+	require.Len(t, records, 0)
+
+	// When it is sent down again, it's performed
+	require.NoError(t, actionqueue.Update(bytes.NewReader(testActionsRaw)))
+	records, err = actionhistory.Records(historyStore)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, actionhistory.OutcomeCompleted, records[0].Outcome)
+}
+
 func setupStorage(t *testing.T) types.KVStore {
 	s, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.ControlServerActionsStore.String())
 	require.NoError(t, err)
@@ -13,10 +13,12 @@ import (
 	"net/http"
 	"net/url"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/kolide/krypto/pkg/echelper"
 	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/ipstack"
 	"github.com/kolide/launcher/pkg/traces"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
@@ -29,6 +31,7 @@ type HTTPClient struct {
 	insecure   bool
 	disableTLS bool
 	token      string
+	tokenLock  sync.RWMutex
 }
 
 const (
@@ -47,7 +50,7 @@ type configResponse struct {
 }
 
 func NewControlHTTPClient(addr string, client *http.Client, opts ...HTTPClientOption) (*HTTPClient, error) {
-	baseURL, err := url.Parse(fmt.Sprintf("https://%s", addr))
+	baseURL, err := url.Parse(fmt.Sprintf("https://%s", ipstack.NormalizeHostPort(addr)))
 	if err != nil {
 		return nil, fmt.Errorf("parsing URL: %w", err)
 	}
@@ -132,7 +135,7 @@ func (c *HTTPClient) GetConfig(ctx context.Context) (io.Reader, error) {
 	}
 
 	// Set the auth token for use when fetching objects by their hashes later
-	c.token = cfgResp.Token
+	c.setToken(cfgResp.Token)
 
 	reader := bytes.NewReader(cfgResp.Config)
 	return reader, nil
@@ -142,7 +145,8 @@ func (c *HTTPClient) GetSubsystemData(ctx context.Context, hash string) (io.Read
 	ctx, span := traces.StartSpan(ctx)
 	defer span.End()
 
-	if c.token == "" {
+	token := c.getToken()
+	if token == "" {
 		return nil, errors.New("token is nil, cannot request subsystem data")
 	}
 
@@ -151,7 +155,7 @@ func (c *HTTPClient) GetSubsystemData(ctx context.Context, hash string) (io.Read
 		return nil, fmt.Errorf("could not create subsystem data request: %w", err)
 	}
 
-	dataReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	dataReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	dataReq.Header.Set("Content-Type", "application/json")
 	dataReq.Header.Set("Accept", "application/json")
 
@@ -169,7 +173,8 @@ func (c *HTTPClient) SendMessage(ctx context.Context, method string, params inte
 	ctx, span := traces.StartSpan(ctx)
 	defer span.End()
 
-	if c.token == "" {
+	token := c.getToken()
+	if token == "" {
 		return errors.New("token is nil, cannot send message to server")
 	}
 
@@ -198,7 +203,7 @@ func (c *HTTPClient) SendMessage(ctx context.Context, method string, params inte
 		return fmt.Errorf("could not create server message: %w", err)
 	}
 
-	dataReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	dataReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	dataReq.Header.Set("Content-Type", "application/json")
 	dataReq.Header.Set("Accept", "application/json")
 
@@ -234,6 +239,18 @@ func (c *HTTPClient) do(ctx context.Context, req *http.Request) ([]byte, error)
 	return respBytes, nil
 }
 
+func (c *HTTPClient) getToken() string {
+	c.tokenLock.RLock()
+	defer c.tokenLock.RUnlock()
+	return c.token
+}
+
+func (c *HTTPClient) setToken(token string) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	c.token = token
+}
+
 func (c *HTTPClient) url(path string) *url.URL {
 	u := *c.baseURL
 	u.Path = path
@@ -18,6 +18,19 @@ func WithInsecureSkipVerify() HTTPClientOption {
 	}
 }
 
+// WithClientCertificate presents the client certificate returned by getClientCertificate, for
+// mutual TLS. It should come after WithInsecureSkipVerify, if both are used.
+func WithClientCertificate(getClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)) HTTPClientOption {
+	return func(c *HTTPClient) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify:   c.insecure,
+			GetClientCertificate: getClientCertificate,
+		}
+		c.client = &http.Client{Transport: transport}
+	}
+}
+
 func WithDisableTLS() HTTPClientOption {
 	return func(c *HTTPClient) {
 		c.disableTLS = true
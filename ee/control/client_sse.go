@@ -0,0 +1,112 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// sseEventControlUpdated is sent by the control server when control data has changed
+	// and should be fetched.
+	sseEventControlUpdated = "control_updated"
+
+	// sseDefaultEvent is the event type when the server doesn't specify one
+	sseDefaultEvent = "message"
+)
+
+// Subscribe opens a server-sent events stream with the control server, and calls notify each time
+// the server indicates that control data has changed. It blocks until the stream ends or ctx is
+// canceled, and always returns a non-nil error describing why the stream ended. It requires the
+// token from a successful GetConfig.
+func (c *HTTPClient) Subscribe(ctx context.Context, notify func()) error {
+	token := c.getToken()
+	if token == "" {
+		return errors.New("token is nil, cannot subscribe to control server events")
+	}
+
+	eventsReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/api/agent/events").String(), nil)
+	if err != nil {
+		return fmt.Errorf("could not create events request: %w", err)
+	}
+
+	eventsReq.Header.Set(HeaderApiVersion, ApiVersion)
+	eventsReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	eventsReq.Header.Set("Accept", "text/event-stream")
+	eventsReq.Header.Set("Cache-Control", "no-cache")
+
+	// We can't use c.do here, since the response body is a stream that we read until it closes
+	resp, err := c.client.Do(eventsReq)
+	if err != nil {
+		return fmt.Errorf("error making events request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got non-200 status code %d from control server at %s", resp.StatusCode, resp.Request.URL)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		return fmt.Errorf("unexpected content type %q from control server at %s", contentType, resp.Request.URL)
+	}
+
+	if err := readEvents(resp.Body, func(event string, _ string) {
+		if event == sseEventControlUpdated {
+			notify()
+		}
+	}); err != nil {
+		return fmt.Errorf("reading events from control server: %w", err)
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return errors.New("control server closed event stream")
+}
+
+// readEvents parses a server-sent events stream, calling dispatch for each complete event. Comments
+// (used by servers as heartbeats), ids, and retry hints are ignored. It returns when the stream ends.
+func readEvents(r io.Reader, dispatch func(event string, data string)) error {
+	scanner := bufio.NewScanner(r)
+
+	var (
+		event string
+		data  []string
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the buffered event
+		if line == "" {
+			if event != "" || len(data) > 0 {
+				if event == "" {
+					event = sseDefaultEvent
+				}
+				dispatch(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	return scanner.Err()
+}
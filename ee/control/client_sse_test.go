@@ -0,0 +1,126 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readEvents(t *testing.T) {
+	t.Parallel()
+
+	stream := strings.Join([]string{
+		": heartbeat",
+		"",
+		"event: control_updated",
+		"data: {}",
+		"",
+		"id: 2",
+		"retry: 10000",
+		"data: first line",
+		"data:second line",
+		"",
+		"event: unterminated",
+	}, "\n")
+
+	type event struct {
+		event string
+		data  string
+	}
+	var events []event
+	require.NoError(t, readEvents(strings.NewReader(stream), func(e string, d string) {
+		events = append(events, event{e, d})
+	}))
+
+	require.Equal(t, []event{
+		{sseEventControlUpdated, "{}"},
+		{sseDefaultEvent, "first line\nsecond line"},
+	}, events)
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/agent/events", r.URL.Path)
+		require.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		require.Equal(t, ApiVersion, r.Header.Get(HeaderApiVersion))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": heartbeat\n\n")
+		fmt.Fprint(w, "event: control_updated\ndata: {}\n\n")
+		fmt.Fprint(w, "event: something_else\ndata: {}\n\n")
+		fmt.Fprint(w, "event: control_updated\ndata: {}\n\n")
+	}))
+	defer server.Close()
+
+	client, err := NewControlHTTPClient(strings.TrimPrefix(server.URL, "http://"), &http.Client{}, WithDisableTLS())
+	require.NoError(t, err)
+
+	// Without a token, we can't subscribe
+	require.Error(t, client.Subscribe(context.TODO(), func() {}))
+
+	client.setToken("test-token")
+
+	notifications := 0
+	err = client.Subscribe(context.TODO(), func() { notifications++ })
+	require.Error(t, err, "closed stream should return an error")
+	require.Equal(t, 2, notifications)
+}
+
+func TestSubscribe_NotEventStream(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "event: control_updated\ndata: {}\n\n")
+	}))
+	defer server.Close()
+
+	client, err := NewControlHTTPClient(strings.TrimPrefix(server.URL, "http://"), &http.Client{}, WithDisableTLS())
+	require.NoError(t, err)
+	client.setToken("test-token")
+
+	notifications := 0
+	require.Error(t, client.Subscribe(context.TODO(), func() { notifications++ }))
+	require.Equal(t, 0, notifications, "should not process events from a response that isn't an event stream")
+}
+
+func TestSubscribe_IPv6Server(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("cannot listen on ipv6 loopback, skipping: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: control_updated\ndata: {}\n\n")
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client, err := NewControlHTTPClient(strings.TrimPrefix(server.URL, "http://"), &http.Client{}, WithDisableTLS())
+	require.NoError(t, err)
+	client.setToken("test-token")
+
+	notifications := 0
+	require.Error(t, client.Subscribe(context.TODO(), func() { notifications++ }), "closed stream should return an error")
+	require.Equal(t, 1, notifications)
+}
+
+func TestNewControlHTTPClient_IPv6Literal(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewControlHTTPClient("2001:db8::10", &http.Client{})
+	require.NoError(t, err)
+	require.Equal(t, "https://[2001:db8::10]/api/agent/config", client.url("/api/agent/config").String())
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/kolide/launcher/ee/agent/types"
 	"github.com/kolide/launcher/ee/debug/checkups"
 	"github.com/kolide/launcher/ee/debug/shipper"
+	"github.com/kolide/launcher/ee/gowrapper"
 )
 
 const (
@@ -20,39 +21,97 @@ const (
 )
 
 type FlareConsumer struct {
+	// ctx bounds the uploads resumed in the background
+	ctx           context.Context
 	lastFlareTime time.Time
 	flarer        flarer
 	knapsack      types.Knapsack
 	// newFlareStream is assigned to a field so it can be mocked in tests
-	newFlareStream func(note, uploadRequestURL string) (io.WriteCloser, error)
-	slogger        *slog.Logger
+	newFlareStream func(note, uploadRequestURL string, chunkSize int64) (io.WriteCloser, error)
+	// resumePendingUploads is assigned to a field so it can be mocked in tests
+	resumePendingUploads func(ctx context.Context) error
+	slogger              *slog.Logger
 }
 
 type flarer interface {
-	RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser) error
+	RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser, opts ...checkups.FlareOption) error
 }
 
 type FlareRunner struct{}
 
-func (f *FlareRunner) RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser) error {
-	return checkups.RunFlare(ctx, k, flareStream, checkups.InSituEnvironment)
+func (f *FlareRunner) RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser, opts ...checkups.FlareOption) error {
+	return checkups.RunFlare(ctx, k, flareStream, checkups.InSituEnvironment, opts...)
 }
 
-func New(knapsack types.Knapsack) *FlareConsumer {
+func New(ctx context.Context, knapsack types.Knapsack) *FlareConsumer {
 	return &FlareConsumer{
+		ctx:      ctx,
 		flarer:   &FlareRunner{},
 		knapsack: knapsack,
-		newFlareStream: func(note, uploadRequestURL string) (io.WriteCloser, error) {
+		newFlareStream: func(note, uploadRequestURL string, chunkSize int64) (io.WriteCloser, error) {
+			// Low-bandwidth hosts upload in chunks, which resume where they left off if interrupted
+			if chunkSize > 0 {
+				return shipper.NewChunked(knapsack, chunkSize, shipper.WithNote(note), shipper.WithUploadRequestURL(uploadRequestURL))
+			}
 			return shipper.New(knapsack, shipper.WithNote(note), shipper.WithUploadRequestURL(uploadRequestURL))
 		},
+		resumePendingUploads: func(ctx context.Context) error {
+			return shipper.ResumePendingUploads(ctx, knapsack)
+		},
 		slogger: knapsack.Slogger().With("component", FlareSubsystem),
 	}
 }
 
+// ResumePendingUploads resumes any chunked flare uploads that were interrupted, e.g. by a restart.
+func (fc *FlareConsumer) ResumePendingUploads(ctx context.Context) {
+	if err := fc.resumePendingUploads(ctx); err != nil {
+		fc.slogger.Log(ctx, slog.LevelWarn,
+			"could not complete pending flare uploads",
+			"err", err,
+		)
+	}
+}
+
 func (fc *FlareConsumer) Do(data io.Reader) error {
 	// slog needs a ctx
 	ctx := context.TODO()
 
+	flareData := struct {
+		Note             string `json:"note"`
+		UploadRequestURL string `json:"upload_request_url"`
+		// Since limits the flare to what changed since the previous flare ("last"), an RFC3339
+		// time, or a duration before now, like "1h"
+		Since string `json:"since"`
+		// Redact lists classes of sensitive value to mask in this flare, in addition to those
+		// masked in launcher logs
+		Redact []string `json:"redact"`
+		// Sections limits the flare to the named sections, e.g. "Logs"
+		Sections []string `json:"sections"`
+		// ChunkSize, when set, uploads the flare in chunks of about that many bytes, resuming
+		// where it left off if interrupted, for hosts on low-bandwidth connections
+		ChunkSize int64 `json:"chunk_size"`
+		// ResumeOnly resumes interrupted uploads, without collecting a new flare
+		ResumeOnly bool `json:"resume_only"`
+	}{}
+
+	if err := json.NewDecoder(data).Decode(&flareData); err != nil {
+		fc.slogger.Log(ctx, slog.LevelError,
+			"failed to decode key-value json, not retrying",
+			"err", err,
+		)
+		return nil
+	}
+
+	// Finish any earlier uploads in the background, so that a slow connection doesn't hold up
+	// the action queue. Uploads are sent one at a time, so they don't compete with this one for
+	// bandwidth.
+	gowrapper.Go(fc.ctx, fc.slogger, func() {
+		fc.ResumePendingUploads(fc.ctx)
+	})
+	if flareData.ResumeOnly {
+		return nil
+	}
+
 	timeSinceLastFlare := time.Since(fc.lastFlareTime)
 
 	if timeSinceLastFlare < minFlareInterval {
@@ -74,24 +133,34 @@ func (fc *FlareConsumer) Do(data io.Reader) error {
 		return nil
 	}
 
-	flareData := struct {
-		Note             string `json:"note"`
-		UploadRequestURL string `json:"upload_request_url"`
-	}{}
+	fc.slogger.Log(ctx, slog.LevelInfo, "received remote flare request",
+		"note", flareData.Note,
+		"since", flareData.Since,
+		"redact", flareData.Redact,
+		"sections", flareData.Sections,
+		"chunk_size", flareData.ChunkSize,
+	)
 
-	if err := json.NewDecoder(data).Decode(&flareData); err != nil {
-		fc.slogger.Log(ctx, slog.LevelError,
-			"failed to decode key-value json, not retrying",
+	var flareOpts []checkups.FlareOption
+	sinceOpt, err := checkups.ParseSince(flareData.Since)
+	if err != nil {
+		// A complete flare is more useful than none
+		fc.slogger.Log(ctx, slog.LevelWarn,
+			"invalid since in flare request, collecting everything",
 			"err", err,
 		)
-		return nil
+	}
+	if sinceOpt != nil {
+		flareOpts = append(flareOpts, sinceOpt)
+	}
+	if len(flareData.Redact) > 0 {
+		flareOpts = append(flareOpts, checkups.WithRedactionClasses(flareData.Redact))
+	}
+	if len(flareData.Sections) > 0 {
+		flareOpts = append(flareOpts, checkups.WithSections(flareData.Sections))
 	}
 
-	fc.slogger.Log(ctx, slog.LevelInfo, "received remote flare request",
-		"note", flareData.Note,
-	)
-
-	flareStream, err := fc.newFlareStream(flareData.Note, flareData.UploadRequestURL)
+	flareStream, err := fc.newFlareStream(flareData.Note, flareData.UploadRequestURL, flareData.ChunkSize)
 	if err != nil {
 		fc.slogger.Log(ctx, slog.LevelError,
 			"failed to create flare stream, not retrying",
@@ -101,7 +170,7 @@ func (fc *FlareConsumer) Do(data io.Reader) error {
 		return nil
 	}
 
-	if err := fc.flarer.RunFlare(context.Background(), fc.knapsack, flareStream); err != nil {
+	if err := fc.flarer.RunFlare(context.Background(), fc.knapsack, flareStream, flareOpts...); err != nil {
 		fc.slogger.Log(ctx, slog.LevelError,
 			"failed to run flare, not retrying",
 			"err", err,
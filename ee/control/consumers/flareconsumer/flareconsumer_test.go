@@ -2,9 +2,11 @@ package flareconsumer
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"log/slog"
 	"testing"
+	"time"
 
 	knapsackMock "github.com/kolide/launcher/ee/agent/types/mocks"
 	"github.com/kolide/launcher/ee/control/consumers/flareconsumer/mocks"
@@ -37,14 +39,82 @@ func TestFlareConsumer(t *testing.T) {
 
 			mockSack := knapsackMock.NewKnapsack(t)
 			mockSack.On("Slogger").Return(slog.New(slog.NewJSONHandler(io.Discard, nil))).Maybe()
-			f := New(mockSack)
+			f := New(context.TODO(), mockSack)
 			f.flarer = tt.flarer(t)
-			f.newFlareStream = func(note, uploadRequestURL string) (io.WriteCloser, error) {
+			f.newFlareStream = func(note, uploadRequestURL string, chunkSize int64) (io.WriteCloser, error) {
 				// whatever, it implements write closer
 				return &io.PipeWriter{}, nil
 			}
+			f.resumePendingUploads = func(ctx context.Context) error { return nil }
 
 			tt.errAssertion(t, f.Do(bytes.NewBuffer([]byte(`{"upload_url":"https://example.com"}`))))
 		})
 	}
 }
+
+func TestFlareConsumer_ChunkedSections(t *testing.T) {
+	t.Parallel()
+
+	mockSack := knapsackMock.NewKnapsack(t)
+	mockSack.On("Slogger").Return(slog.New(slog.NewJSONHandler(io.Discard, nil))).Maybe()
+	f := New(context.TODO(), mockSack)
+
+	flarer := mocks.NewFlarer(t)
+	// the knapsack, stream, and a single option for the sections
+	flarer.On("RunFlare", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	f.flarer = flarer
+
+	var requestedChunkSize int64
+	f.newFlareStream = func(note, uploadRequestURL string, chunkSize int64) (io.WriteCloser, error) {
+		requestedChunkSize = chunkSize
+		return &io.PipeWriter{}, nil
+	}
+	resumed := make(chan struct{}, 2)
+	f.resumePendingUploads = func(ctx context.Context) error {
+		resumed <- struct{}{}
+		return nil
+	}
+
+	require.NoError(t, f.Do(bytes.NewBufferString(`{"sections":["Logs"],"chunk_size":262144}`)))
+	require.Equal(t, int64(262144), requestedChunkSize)
+	requireResumed(t, resumed, "pending uploads should be resumed with a new flare")
+
+	// Resuming alone neither collects a flare, nor counts towards the flare interval
+	require.NoError(t, f.Do(bytes.NewBufferString(`{"resume_only":true}`)))
+	requireResumed(t, resumed, "pending uploads should be resumed")
+}
+
+func TestFlareConsumer_ResumeDoesNotBlock(t *testing.T) {
+	t.Parallel()
+
+	mockSack := knapsackMock.NewKnapsack(t)
+	mockSack.On("Slogger").Return(slog.New(slog.NewJSONHandler(io.Discard, nil))).Maybe()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	f := New(ctx, mockSack)
+
+	// Resuming runs until the consumer's context is done, as a slow upload would
+	stopped := make(chan struct{})
+	f.resumePendingUploads = func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	}
+
+	require.NoError(t, f.Do(bytes.NewBufferString(`{"resume_only":true}`)))
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resuming uploads did not stop with the consumer's context")
+	}
+}
+
+func requireResumed(t *testing.T, resumed chan struct{}, msg string) {
+	select {
+	case <-resumed:
+	case <-time.After(5 * time.Second):
+		t.Fatal(msg)
+	}
+}
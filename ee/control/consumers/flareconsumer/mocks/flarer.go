@@ -7,6 +7,8 @@ import (
 
 	io "io"
 
+	checkups "github.com/kolide/launcher/ee/debug/checkups"
+
 	mock "github.com/stretchr/testify/mock"
 
 	types "github.com/kolide/launcher/ee/agent/types"
@@ -17,13 +19,20 @@ type Flarer struct {
 	mock.Mock
 }
 
-// RunFlare provides a mock function with given fields: ctx, k, flareStream
-func (_m *Flarer) RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser) error {
-	ret := _m.Called(ctx, k, flareStream)
+// RunFlare provides a mock function with given fields: ctx, k, flareStream, opts
+func (_m *Flarer) RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser, opts ...checkups.FlareOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, k, flareStream)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, types.Knapsack, io.WriteCloser) error); ok {
-		r0 = rf(ctx, k, flareStream)
+	if rf, ok := ret.Get(0).(func(context.Context, types.Knapsack, io.WriteCloser, ...checkups.FlareOption) error); ok {
+		r0 = rf(ctx, k, flareStream, opts...)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -7,16 +7,25 @@ import (
 	"io"
 	"log/slog"
 	"net/url"
+	"time"
 
 	"github.com/kolide/launcher/ee/agent/types"
 	desktopRunner "github.com/kolide/launcher/ee/desktop/runner"
 	"github.com/kolide/launcher/ee/desktop/user/notify"
+	"github.com/kolide/launcher/ee/maintenancewindow"
 )
 
 // Consumes notifications from control server
 type NotificationConsumer struct {
-	runner  userProcessesRunner
-	slogger *slog.Logger
+	knapsack types.Knapsack
+	runner   userProcessesRunner
+	slogger  *slog.Logger
+	deferred map[string]deferredNotification // notification ID -> deferral, for notifications waiting for the maintenance window
+}
+
+type deferredNotification struct {
+	since      time.Time
+	validUntil int64
 }
 
 // The desktop runner fullfils this interface -- it exists for testing purposes.
@@ -33,8 +42,10 @@ type notificationConsumerOption func(*NotificationConsumer)
 
 func NewNotifyConsumer(ctx context.Context, k types.Knapsack, runner *desktopRunner.DesktopUsersProcessesRunner, opts ...notificationConsumerOption) (*NotificationConsumer, error) {
 	nc := &NotificationConsumer{
-		runner:  runner,
-		slogger: k.Slogger().With("component", NotificationSubsystem),
+		knapsack: k,
+		runner:   runner,
+		slogger:  k.Slogger().With("component", NotificationSubsystem),
+		deferred: make(map[string]deferredNotification),
 	}
 
 	for _, opt := range opts {
@@ -62,9 +73,52 @@ func (nc *NotificationConsumer) Do(data io.Reader) error {
 		return nil
 	}
 
+	// Returning an error leaves the action incomplete, so that we'll try again when the
+	// control server next sends it down.
+	if !nc.maintenanceWindowPermits(notification) {
+		return maintenancewindow.ErrDeferred
+	}
+
 	return nc.runner.SendNotification(notification)
 }
 
+// maintenanceWindowPermits reports whether the notification may be shown now. Notifications
+// interrupt the user, so they wait for the maintenance window -- unless they would expire first,
+// or have already waited as long as the maintenance window deadline allows.
+func (nc *NotificationConsumer) maintenanceWindowPermits(n notify.Notification) bool {
+	now := time.Now()
+
+	// Forget deferred notifications that expired without being sent down again
+	for id, d := range nc.deferred {
+		if d.validUntil > 0 && now.Unix() > d.validUntil {
+			delete(nc.deferred, id)
+		}
+	}
+
+	d, alreadyDeferred := nc.deferred[n.ID]
+	if !alreadyDeferred {
+		d = deferredNotification{since: now, validUntil: n.ValidUntil}
+	}
+
+	sendAt := maintenancewindow.NextOpportunity(nc.knapsack, d.since, now)
+	if !sendAt.After(now) || (n.ValidUntil > 0 && !sendAt.Before(time.Unix(n.ValidUntil, 0))) {
+		delete(nc.deferred, n.ID)
+		return true
+	}
+
+	if !alreadyDeferred {
+		nc.deferred[n.ID] = d
+		nc.slogger.Log(context.TODO(), slog.LevelInfo,
+			"deferring notification until maintenance window",
+			"notification_id", n.ID,
+			"maintenance_window", nc.knapsack.MaintenanceWindow(),
+			"send_at", sendAt.Format(time.RFC3339),
+		)
+	}
+
+	return false
+}
+
 func (nc *NotificationConsumer) notificationIsValid(notificationToCheck notify.Notification) bool {
 	// If action URI is set, it must be a valid URI
 	if notificationToCheck.ActionUri != "" {
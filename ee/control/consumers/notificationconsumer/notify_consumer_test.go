@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/kolide/kit/ulid"
+	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
 	"github.com/kolide/launcher/ee/desktop/user/notify"
+	"github.com/kolide/launcher/ee/maintenancewindow"
 	"github.com/kolide/launcher/pkg/log/multislogger"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -28,9 +30,14 @@ func TestUpdate_HappyPath(t *testing.T) {
 
 	mockNotifier := newNotifierMock()
 
+	mockKnapsack := typesmocks.NewKnapsack(t)
+	mockKnapsack.On("MaintenanceWindow").Return("")
+
 	testNc := &NotificationConsumer{
-		runner:  mockNotifier,
-		slogger: multislogger.NewNopLogger(),
+		knapsack: mockKnapsack,
+		runner:   mockNotifier,
+		slogger:  multislogger.NewNopLogger(),
+		deferred: make(map[string]deferredNotification),
 	}
 
 	// Send one notification
@@ -63,9 +70,14 @@ func TestUpdate_HappyPath_NoAction(t *testing.T) {
 	t.Parallel()
 
 	mockNotifier := newNotifierMock()
+	mockKnapsack := typesmocks.NewKnapsack(t)
+	mockKnapsack.On("MaintenanceWindow").Return("")
+
 	testNc := &NotificationConsumer{
-		runner:  mockNotifier,
-		slogger: multislogger.NewNopLogger(),
+		knapsack: mockKnapsack,
+		runner:   mockNotifier,
+		slogger:  multislogger.NewNopLogger(),
+		deferred: make(map[string]deferredNotification),
 	}
 
 	// Send one notification that we haven't seen before
@@ -91,6 +103,59 @@ func TestUpdate_HappyPath_NoAction(t *testing.T) {
 	mockNotifier.AssertNumberOfCalls(t, "SendNotification", 1)
 }
 
+func TestUpdate_DefersUntilMaintenanceWindow(t *testing.T) {
+	t.Parallel()
+
+	// A maintenance window that is hours away
+	windowStart := time.Now().Add(6 * time.Hour)
+	windowEnd := windowStart.Add(time.Minute)
+	mockKnapsack := typesmocks.NewKnapsack(t)
+	mockKnapsack.On("MaintenanceWindow").Return(fmt.Sprintf("%02d:%02d-%02d:%02d", windowStart.Hour(), windowStart.Minute(), windowEnd.Hour(), windowEnd.Minute()))
+	mockKnapsack.On("MaintenanceWindowDeadline").Return(72 * time.Hour)
+
+	mockNotifier := newNotifierMock()
+	mockNotifier.On("SendNotification", mock.Anything).Return(nil)
+	testNc := &NotificationConsumer{
+		knapsack: mockKnapsack,
+		runner:   mockNotifier,
+		slogger:  multislogger.NewNopLogger(),
+		deferred: make(map[string]deferredNotification),
+	}
+
+	doNotification := func(n notify.Notification) error {
+		raw, err := json.Marshal(n)
+		require.NoError(t, err)
+		return testNc.Do(bytes.NewReader(raw))
+	}
+
+	// A notification that will still be valid when the window opens waits for it, each time it's sent down
+	deferrable := notify.Notification{
+		Title:      "Test title",
+		Body:       "Test body",
+		ID:         ulid.New(),
+		ValidUntil: time.Now().Add(24 * time.Hour).Unix(),
+	}
+	require.ErrorIs(t, doNotification(deferrable), maintenancewindow.ErrDeferred)
+	require.ErrorIs(t, doNotification(deferrable), maintenancewindow.ErrDeferred)
+	mockNotifier.AssertNumberOfCalls(t, "SendNotification", 0)
+
+	// A notification that would expire before the window opens is sent now
+	expiring := notify.Notification{
+		Title:      "Test title",
+		Body:       "Test body",
+		ID:         ulid.New(),
+		ValidUntil: getValidUntil(),
+	}
+	require.NoError(t, doNotification(expiring))
+	mockNotifier.AssertNumberOfCalls(t, "SendNotification", 1)
+
+	// Once the deadline has passed, the deferred notification is sent too
+	testNc.deferred[deferrable.ID] = deferredNotification{since: time.Now().Add(-73 * time.Hour), validUntil: deferrable.ValidUntil}
+	require.NoError(t, doNotification(deferrable))
+	mockNotifier.AssertNumberOfCalls(t, "SendNotification", 2)
+	require.Empty(t, testNc.deferred)
+}
+
 func TestUpdate_ValidatesNotifications(t *testing.T) {
 	t.Parallel()
 
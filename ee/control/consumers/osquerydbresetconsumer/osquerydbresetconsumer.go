@@ -0,0 +1,102 @@
+package osquerydbresetconsumer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/osquerydbreset"
+)
+
+const (
+	// Identifier for this consumer.
+	OsqueryDbResetSubsystem = "reset_osquery_db"
+)
+
+// databaseResetter is implemented by the osquery runner, which calls reset while the
+// registration's instance is down.
+type databaseResetter interface {
+	ResetDatabase(ctx context.Context, registrationId string, reset func() error) error
+}
+
+type osqueryDbResetAction struct {
+	RegistrationID string `json:"registration_id"` // defaults to the default registration
+	Reason         string `json:"reason"`
+}
+
+type OsqueryDbResetConsumer struct {
+	knapsack types.Knapsack
+	resetter databaseResetter
+	slogger  *slog.Logger
+}
+
+func New(knapsack types.Knapsack, resetter databaseResetter) *OsqueryDbResetConsumer {
+	return &OsqueryDbResetConsumer{
+		knapsack: knapsack,
+		resetter: resetter,
+		slogger:  knapsack.Slogger().With("component", "osquery_db_reset_consumer"),
+	}
+}
+
+// Do implements the `actionqueue.actor` interface. It stops the osquery instance for the
+// action's registration, archives its database, clears its snapshot-diff snapshots, and
+// relaunches it, then reports the outcome to the control server. Failed resets aren't
+// retried, since osquery has been restarted either way; the report says what went wrong.
+func (c *OsqueryDbResetConsumer) Do(data io.Reader) error {
+	var action osqueryDbResetAction
+	if err := json.NewDecoder(data).Decode(&action); err != nil {
+		// Retrying won't make the action decodable
+		c.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not decode osquery database reset action",
+			"err", err,
+		)
+		return nil
+	}
+
+	if action.RegistrationID == "" {
+		action.RegistrationID = types.DefaultRegistrationID
+	}
+	if !slices.Contains(c.knapsack.RegistrationIDs(), action.RegistrationID) {
+		c.slogger.Log(context.TODO(), slog.LevelWarn,
+			"received osquery database reset action for unknown registration, discarding",
+			"registration_id", action.RegistrationID,
+		)
+		return nil
+	}
+
+	report := osquerydbreset.Report{
+		RegistrationID: action.RegistrationID,
+		Timestamp:      time.Now().Unix(),
+	}
+	err := c.resetter.ResetDatabase(context.TODO(), action.RegistrationID, func() error {
+		var resetErr error
+		report, resetErr = osquerydbreset.Reset(c.knapsack.RootDirectory(), c.knapsack.SnapshotDiffStore(), action.RegistrationID, time.Now())
+		return resetErr
+	})
+	report.Trigger = osquerydbreset.TriggerRemoteAction
+	report.Reason = action.Reason
+
+	if err != nil {
+		report.Error = err.Error()
+		c.slogger.Log(context.TODO(), slog.LevelError,
+			"could not reset osquery database",
+			"registration_id", action.RegistrationID,
+			"err", err,
+		)
+	} else {
+		c.slogger.Log(context.TODO(), slog.LevelInfo,
+			"reset osquery database",
+			"registration_id", action.RegistrationID,
+			"archive_path", report.ArchivePath,
+			"cleared_snapshots", report.ClearedSnapshots,
+		)
+	}
+
+	osquerydbreset.Publish(context.TODO(), c.slogger, report)
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package osquerydbresetconsumer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/agent/types"
+	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/ee/osquerydbreset"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+type mockResetter struct {
+	registrationIds []string
+	shutdownErr     error
+	resetErr        error
+}
+
+func (m *mockResetter) ResetDatabase(_ context.Context, registrationId string, reset func() error) error {
+	m.registrationIds = append(m.registrationIds, registrationId)
+	if m.shutdownErr != nil {
+		return m.shutdownErr
+	}
+	m.resetErr = reset()
+	return m.resetErr
+}
+
+func testKnapsack(t *testing.T, rootDir string) *typesmocks.Knapsack {
+	k := typesmocks.NewKnapsack(t)
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID}).Maybe()
+	k.On("RootDirectory").Return(rootDir).Maybe()
+	k.On("SnapshotDiffStore").Return(inmemory.NewStore()).Maybe()
+	return k
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	dbPath := osquerydbreset.DatabasePath(rootDir, types.DefaultRegistrationID)
+	require.NoError(t, os.MkdirAll(dbPath, 0755))
+
+	resetter := &mockResetter{}
+	c := New(testKnapsack(t, rootDir), resetter)
+
+	require.NoError(t, c.Do(strings.NewReader(`{"reason": "corrupted"}`)))
+	require.Equal(t, []string{types.DefaultRegistrationID}, resetter.registrationIds)
+	require.NoError(t, resetter.resetErr)
+	require.NoDirExists(t, dbPath)
+}
+
+func TestDo_Discarded(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		action string
+	}{
+		{name: "undecodable", action: `not json`},
+		{name: "unknown registration", action: `{"registration_id": "other"}`},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resetter := &mockResetter{}
+			c := New(testKnapsack(t, t.TempDir()), resetter)
+
+			require.NoError(t, c.Do(strings.NewReader(tt.action)))
+			require.Empty(t, resetter.registrationIds)
+		})
+	}
+}
+
+func TestDo_ResetFails(t *testing.T) {
+	t.Parallel()
+
+	resetter := &mockResetter{shutdownErr: errors.New("test error")}
+	c := New(testKnapsack(t, t.TempDir()), resetter)
+
+	// Failed resets are reported rather than retried
+	require.NoError(t, c.Do(strings.NewReader(`{"registration_id": "default"}`)))
+	require.Equal(t, []string{types.DefaultRegistrationID}, resetter.registrationIds)
+}
@@ -0,0 +1,386 @@
+// Package probeconsumer checks, on request from the control server, whether this device can reach
+// a set of endpoints -- whether a connection can be made, how long it takes, and, for TLS endpoints,
+// whether the handshake succeeds and what certificate chain is presented. This lets us verify that
+// devices can reach new infrastructure before migrating them to it. Results are kept for the
+// kolide_connectivity_probes table.
+package probeconsumer
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+const (
+	// Identifier for this consumer.
+	ProbeSubsystem = "probe"
+
+	defaultTimeout = 5 * time.Second
+	maxTimeout     = 30 * time.Second
+
+	// maxTargets bounds how long a single action can hold up the action queue
+	maxTargets = 20
+
+	// maxResults is how many of the most recent results are kept for the kolide_connectivity_probes table
+	maxResults = 200
+)
+
+// Probe outcomes
+const (
+	StatusReachable   = "reachable"
+	StatusUnreachable = "unreachable"
+	StatusNoResponse  = "no_response" // UDP only: nothing came back, so the port may be open or filtered
+	StatusTlsFailed   = "tls_failed"
+	StatusInvalid     = "invalid" // the target was malformed
+)
+
+var recent = &history{}
+
+// Target is an endpoint the control server asks us to check.
+type Target struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"` // tcp or udp; defaults to tcp
+	Tls      bool   `json:"tls"`
+
+	// ServerName is used for SNI and certificate verification, and defaults to Host
+	ServerName string `json:"server_name,omitempty"`
+	// ExpectedSha256 are certificate fingerprints, any of which should appear in the presented chain
+	ExpectedSha256 []string `json:"expected_sha256,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+}
+
+type probeAction struct {
+	ID      string   `json:"id"`
+	Targets []Target `json:"targets"`
+}
+
+// Certificate describes a certificate presented during a TLS handshake.
+type Certificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	Sha256    string    `json:"sha256"`
+}
+
+// Result is the outcome of checking a single target.
+type Result struct {
+	ActionId      string    `json:"action_id"`
+	Time          time.Time `json:"time"`
+	Target        Target    `json:"target"`
+	Status        string    `json:"status"`
+	RemoteAddress string    `json:"remote_address,omitempty"`
+	LatencyMs     int64     `json:"latency_ms"` // time to connect, or for UDP, to get a response
+
+	// Set for TLS targets that connected
+	HandshakeMs       int64         `json:"handshake_ms,omitempty"`
+	TlsVersion        string        `json:"tls_version,omitempty"`
+	CipherSuite       string        `json:"cipher_suite,omitempty"`
+	Verified          bool          `json:"verified"`
+	VerifyError       string        `json:"verify_error,omitempty"`
+	CertificateChain  []Certificate `json:"certificate_chain,omitempty"`
+	FingerprintPinned *bool         `json:"fingerprint_pinned,omitempty"` // nil unless the target has expected fingerprints
+
+	Error string `json:"error,omitempty"`
+}
+
+type history struct {
+	sync.Mutex
+	results []Result
+}
+
+func (h *history) add(results ...Result) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.results = append(h.results, results...)
+	if len(h.results) > maxResults {
+		h.results = h.results[len(h.results)-maxResults:]
+	}
+}
+
+// Recent returns the results of the most recent probes, oldest first.
+func Recent() []Result {
+	recent.Lock()
+	defer recent.Unlock()
+
+	results := make([]Result, len(recent.results))
+	copy(results, recent.results)
+	return results
+}
+
+type ProbeConsumer struct {
+	slogger *slog.Logger
+	// rootCAs verifies TLS certificate chains; nil uses the system roots. Assigned to a field
+	// so it can be set in tests.
+	rootCAs *x509.CertPool
+}
+
+func New(knapsack types.Knapsack) *ProbeConsumer {
+	return &ProbeConsumer{
+		slogger: knapsack.Slogger().With("component", "probe_consumer"),
+	}
+}
+
+// Do implements the `actionqueue.actor` interface. It checks each of the action's targets in turn,
+// and records the results. Problems with individual targets are part of the results, so Do only
+// fails if the action can't be read at all.
+func (p *ProbeConsumer) Do(data io.Reader) error {
+	// slog needs a ctx
+	ctx := context.TODO()
+
+	var action probeAction
+	if err := json.NewDecoder(data).Decode(&action); err != nil {
+		p.slogger.Log(ctx, slog.LevelError,
+			"failed to decode probe action, not retrying",
+			"err", err,
+		)
+		return nil
+	}
+
+	targets := action.Targets
+	if len(targets) > maxTargets {
+		p.slogger.Log(ctx, slog.LevelWarn,
+			"probe action has too many targets, only checking some",
+			"action_id", action.ID,
+			"target_count", len(targets),
+			"max_targets", maxTargets,
+		)
+		targets = targets[:maxTargets]
+	}
+
+	results := make([]Result, 0, len(targets))
+	for _, target := range targets {
+		result := p.probe(ctx, target)
+		result.ActionId = action.ID
+
+		p.slogger.Log(ctx, slog.LevelInfo,
+			"probed endpoint",
+			"action_id", action.ID,
+			"host", target.Host,
+			"port", target.Port,
+			"protocol", result.Target.Protocol,
+			"tls", target.Tls,
+			"status", result.Status,
+			"latency_ms", result.LatencyMs,
+			"verified", result.Verified,
+			"err", result.Error,
+		)
+
+		results = append(results, result)
+	}
+
+	recent.add(results...)
+
+	return nil
+}
+
+// probe checks a single target.
+func (p *ProbeConsumer) probe(ctx context.Context, target Target) Result {
+	if target.Protocol == "" {
+		target.Protocol = "tcp"
+	}
+	target.Protocol = strings.ToLower(target.Protocol)
+
+	result := Result{
+		Time:   time.Now().UTC(),
+		Target: target,
+	}
+
+	if err := validateTarget(target); err != nil {
+		result.Status = StatusInvalid
+		result.Error = err.Error()
+		return result
+	}
+
+	timeout := defaultTimeout
+	if target.TimeoutSeconds > 0 {
+		timeout = min(time.Duration(target.TimeoutSeconds)*time.Second, maxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	address := net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+	if target.Protocol == "udp" {
+		probeUdp(ctx, address, &result)
+		return result
+	}
+
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Status = StatusUnreachable
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	result.Status = StatusReachable
+	result.RemoteAddress = conn.RemoteAddr().String()
+
+	if target.Tls {
+		p.handshake(ctx, conn, target, &result)
+	}
+
+	return result
+}
+
+func validateTarget(target Target) error {
+	if target.Host == "" {
+		return errors.New("no host")
+	}
+	if target.Port < 1 || target.Port > 65535 {
+		return fmt.Errorf("invalid port %d", target.Port)
+	}
+	if target.Protocol != "tcp" && target.Protocol != "udp" {
+		return fmt.Errorf("unsupported protocol %s", target.Protocol)
+	}
+	if target.Protocol == "udp" && target.Tls {
+		return errors.New("tls is not supported over udp")
+	}
+	return nil
+}
+
+// probeUdp sends an empty datagram, and waits for anything to come back. UDP is connectionless,
+// so without a response we can't tell an open port from a filtered one -- but an ICMP port
+// unreachable message surfaces as an error on read.
+func probeUdp(ctx context.Context, address string, result *Result) {
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		result.Status = StatusUnreachable
+		result.Error = err.Error()
+		return
+	}
+	defer conn.Close()
+	result.RemoteAddress = conn.RemoteAddr().String()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte{}); err != nil {
+		result.Status = StatusUnreachable
+		result.Error = err.Error()
+		return
+	}
+
+	buf := make([]byte, 1)
+	_, err = conn.Read(buf)
+	result.LatencyMs = time.Since(start).Milliseconds()
+
+	var netErr net.Error
+	switch {
+	case err == nil:
+		result.Status = StatusReachable
+	case errors.As(err, &netErr) && netErr.Timeout():
+		result.Status = StatusNoResponse
+	default:
+		result.Status = StatusUnreachable
+		result.Error = err.Error()
+	}
+}
+
+// handshake performs a TLS handshake over conn, and records the certificate chain presented. We
+// verify the chain ourselves, rather than letting the handshake do it, so that we can still report
+// on chains that don't verify.
+func (p *ProbeConsumer) handshake(ctx context.Context, conn net.Conn, target Target, result *Result) {
+	serverName := target.ServerName
+	if serverName == "" {
+		serverName = target.Host
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true, // nolint:gosec // verified below, so that we can report on failures
+	})
+
+	start := time.Now()
+	err := tlsConn.HandshakeContext(ctx)
+	result.HandshakeMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Status = StatusTlsFailed
+		result.Error = fmt.Sprintf("tls handshake: %s", err)
+		return
+	}
+
+	state := tlsConn.ConnectionState()
+	result.TlsVersion = tls.VersionName(state.Version)
+	result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+
+	fingerprints := make(map[string]struct{}, len(state.PeerCertificates))
+	for _, cert := range state.PeerCertificates {
+		c := describeCertificate(cert)
+		fingerprints[c.Sha256] = struct{}{}
+		result.CertificateChain = append(result.CertificateChain, c)
+	}
+
+	if err := verifyChain(state.PeerCertificates, serverName, p.rootCAs); err != nil {
+		result.VerifyError = err.Error()
+	} else {
+		result.Verified = true
+	}
+
+	if len(target.ExpectedSha256) > 0 {
+		pinned := false
+		for _, expected := range target.ExpectedSha256 {
+			if _, ok := fingerprints[normalizeFingerprint(expected)]; ok {
+				pinned = true
+				break
+			}
+		}
+		result.FingerprintPinned = &pinned
+	}
+}
+
+func verifyChain(certs []*x509.Certificate, serverName string, roots *x509.CertPool) error {
+	if len(certs) == 0 {
+		return errors.New("no certificates presented")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func describeCertificate(cert *x509.Certificate) Certificate {
+	sum := sha256.Sum256(cert.Raw)
+	return Certificate{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		NotBefore: cert.NotBefore.UTC(),
+		NotAfter:  cert.NotAfter.UTC(),
+		Sha256:    hex.EncodeToString(sum[:]),
+	}
+}
+
+// normalizeFingerprint accepts fingerprints in upper or lower case, with or without colons.
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+}
@@ -0,0 +1,241 @@
+package probeconsumer
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func testConsumer(t *testing.T) *ProbeConsumer {
+	mockKnapsack := typesmocks.NewKnapsack(t)
+	mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
+	return New(mockKnapsack)
+}
+
+// hostPort splits a test server's address into a target
+func hostPort(t *testing.T, addr string) (string, int) {
+	host, portStr, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+	return host, port
+}
+
+func TestProbe_Tcp(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	host, port := hostPort(t, listener.Addr().String())
+	result := testConsumer(t).probe(context.TODO(), Target{Host: host, Port: port})
+
+	require.Equal(t, StatusReachable, result.Status, result.Error)
+	require.Equal(t, "tcp", result.Target.Protocol)
+	require.Equal(t, listener.Addr().String(), result.RemoteAddress)
+	require.Empty(t, result.CertificateChain)
+}
+
+func TestProbe_TcpUnreachable(t *testing.T) {
+	t.Parallel()
+
+	// Find a port nothing is listening on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	host, port := hostPort(t, listener.Addr().String())
+	require.NoError(t, listener.Close())
+
+	result := testConsumer(t).probe(context.TODO(), Target{Host: host, Port: port})
+
+	require.Equal(t, StatusUnreachable, result.Status)
+	require.NotEmpty(t, result.Error)
+}
+
+func TestProbe_Tls(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	host, port := hostPort(t, server.Listener.Addr().String())
+	fingerprint := describeCertificate(server.Certificate()).Sha256
+
+	for _, tt := range []struct {
+		name             string
+		trusted          bool
+		expectedSha256   []string
+		expectedVerified bool
+		expectedPinned   *bool
+	}{
+		{
+			name:             "trusted",
+			trusted:          true,
+			expectedVerified: true,
+		},
+		{
+			name:             "untrusted",
+			trusted:          false,
+			expectedVerified: false,
+		},
+		{
+			name:             "pinned",
+			trusted:          true,
+			expectedSha256:   []string{"00", fingerprint},
+			expectedVerified: true,
+			expectedPinned:   boolPtr(true),
+		},
+		{
+			name:             "pin mismatch",
+			trusted:          true,
+			expectedSha256:   []string{"00"},
+			expectedVerified: true,
+			expectedPinned:   boolPtr(false),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := testConsumer(t)
+			if tt.trusted {
+				p.rootCAs = x509.NewCertPool()
+				p.rootCAs.AddCert(server.Certificate())
+			} else {
+				p.rootCAs = x509.NewCertPool()
+			}
+
+			// httptest's certificate is valid for example.com
+			result := p.probe(context.TODO(), Target{
+				Host:           host,
+				Port:           port,
+				Tls:            true,
+				ServerName:     "example.com",
+				ExpectedSha256: tt.expectedSha256,
+			})
+
+			require.Equal(t, StatusReachable, result.Status, result.Error)
+			require.NotEmpty(t, result.TlsVersion)
+			require.NotEmpty(t, result.CipherSuite)
+			require.Len(t, result.CertificateChain, 1)
+			require.Equal(t, fingerprint, result.CertificateChain[0].Sha256)
+			require.Equal(t, tt.expectedVerified, result.Verified, result.VerifyError)
+			if !tt.expectedVerified {
+				require.NotEmpty(t, result.VerifyError)
+			}
+			require.Equal(t, tt.expectedPinned, result.FingerprintPinned)
+		})
+	}
+}
+
+func TestProbe_TlsHandshakeFails(t *testing.T) {
+	t.Parallel()
+
+	// A plain HTTP server won't complete a TLS handshake
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	host, port := hostPort(t, server.Listener.Addr().String())
+	result := testConsumer(t).probe(context.TODO(), Target{Host: host, Port: port, Tls: true})
+
+	require.Equal(t, StatusTlsFailed, result.Status)
+	require.Contains(t, result.Error, "tls handshake")
+	require.False(t, result.Verified)
+}
+
+func TestProbe_Udp(t *testing.T) {
+	t.Parallel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	// Echo back whatever arrives
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(append(buf[:n], 'x'), addr)
+		}
+	}()
+
+	host, port := hostPort(t, conn.LocalAddr().String())
+	result := testConsumer(t).probe(context.TODO(), Target{Host: host, Port: port, Protocol: "UDP"})
+
+	require.Equal(t, StatusReachable, result.Status, result.Error)
+	require.Equal(t, "udp", result.Target.Protocol)
+}
+
+func TestProbe_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, target := range []Target{
+		{Port: 443},
+		{Host: "localhost"},
+		{Host: "localhost", Port: 70000},
+		{Host: "localhost", Port: 443, Protocol: "icmp"},
+		{Host: "localhost", Port: 443, Protocol: "udp", Tls: true},
+	} {
+		result := testConsumer(t).probe(context.TODO(), target)
+		require.Equal(t, StatusInvalid, result.Status, target)
+		require.NotEmpty(t, result.Error)
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	host, port := hostPort(t, listener.Addr().String())
+
+	rawAction, err := json.Marshal(map[string]any{
+		"id":   "probe-test-action",
+		"type": ProbeSubsystem,
+		"targets": []map[string]any{
+			{"host": host, "port": port},
+			{"host": "", "port": port},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, testConsumer(t).Do(bytes.NewReader(rawAction)))
+
+	var found []Result
+	for _, r := range Recent() {
+		if r.ActionId == "probe-test-action" {
+			found = append(found, r)
+		}
+	}
+	require.Len(t, found, 2)
+	require.Equal(t, StatusReachable, found[0].Status)
+	require.Equal(t, StatusInvalid, found[1].Status)
+
+	// Malformed actions aren't retried
+	require.NoError(t, testConsumer(t).Do(bytes.NewReader([]byte("not json"))))
+}
+
+func TestNormalizeFingerprint(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "abcd01", normalizeFingerprint("AB:CD:01"))
+	require.Equal(t, "abcd01", normalizeFingerprint("abcd01"))
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
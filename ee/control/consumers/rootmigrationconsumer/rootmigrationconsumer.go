@@ -0,0 +1,83 @@
+package rootmigrationconsumer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/rootmigration"
+	"github.com/kolide/launcher/pkg/launcher"
+)
+
+const (
+	// Identifier for this consumer.
+	RootMigrationSubsystem = "migrate_root"
+)
+
+type rootMigrationAction struct {
+	ToRootDirectory string `json:"to_root_directory"`
+	Identifier      string `json:"identifier"`
+}
+
+type RootMigrationConsumer struct {
+	knapsack types.Knapsack
+	slogger  *slog.Logger
+}
+
+func New(knapsack types.Knapsack) *RootMigrationConsumer {
+	return &RootMigrationConsumer{
+		knapsack: knapsack,
+		slogger:  knapsack.Slogger().With("component", "root_migration_consumer"),
+	}
+}
+
+// Do implements the `actionqueue.actor` interface. The migration stops launcher, so it is
+// handed off to a detached `launcher migrate-root` process, which restarts launcher from
+// the new root directory once it's done.
+func (c *RootMigrationConsumer) Do(data io.Reader) error {
+	var action rootMigrationAction
+	if err := json.NewDecoder(data).Decode(&action); err != nil {
+		// Retrying won't make the action decodable
+		c.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not decode root migration action",
+			"err", err,
+		)
+		return nil
+	}
+
+	m := rootmigration.Migration{
+		FromRootDirectory: c.knapsack.RootDirectory(),
+		ToRootDirectory:   action.ToRootDirectory,
+		FromIdentifier:    c.knapsack.Identifier(),
+		ToIdentifier:      action.Identifier,
+		ConfigFilePath:    launcher.ConfigFilePath(os.Args),
+	}
+	if m.ToRootDirectory == "" {
+		m.ToRootDirectory = m.FromRootDirectory
+	}
+	if m.ToIdentifier == "" {
+		m.ToIdentifier = m.FromIdentifier
+	}
+
+	if err := rootmigration.StartDetached(context.TODO(), m); err != nil {
+		// Invalid migrations won't become valid on retry, so just log the failure
+		c.slogger.Log(context.TODO(), slog.LevelError,
+			"could not start root migration",
+			"to_root_directory", m.ToRootDirectory,
+			"to_identifier", m.ToIdentifier,
+			"err", err,
+		)
+		return nil
+	}
+
+	c.slogger.Log(context.TODO(), slog.LevelInfo,
+		"started root migration",
+		"to_root_directory", m.ToRootDirectory,
+		"to_identifier", m.ToIdentifier,
+	)
+
+	return nil
+}
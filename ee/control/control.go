@@ -13,12 +13,22 @@ import (
 	"github.com/kolide/kit/version"
 	"github.com/kolide/launcher/ee/agent/flags/keys"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/gowrapper"
 	"github.com/kolide/launcher/pkg/traces"
 	"golang.org/x/exp/slices"
 )
 
 const ForceFullControlDataFetchAction = "force_full_control_data_fetch"
 
+const (
+	// Bounds for the delay between attempts to (re)connect to the control server's event stream
+	minStreamReconnectDelay = 5 * time.Second
+	maxStreamReconnectDelay = 5 * time.Minute
+
+	// An event stream that stays open at least this long resets the reconnect delay
+	healthyStreamDuration = 1 * time.Minute
+)
+
 // ControlService is the main object that manages the control service. It is responsible for fetching
 // and caching control data, and updating consumers and subscribers.
 type ControlService struct {
@@ -29,6 +39,7 @@ type ControlService struct {
 	requestInterval      time.Duration
 	requestTicker        *time.Ticker
 	fetcher              dataProvider
+	fetchRequests        chan struct{}
 	fetchMutex           sync.Mutex
 	fetchFull            bool
 	fetchFullMutex       sync.Mutex
@@ -58,6 +69,12 @@ type dataProvider interface {
 	SendMessage(ctx context.Context, method string, params interface{}) error
 }
 
+// eventSubscriber is an optional interface for a dataProvider that can notify us of control data
+// updates in near-real-time. Subscribe should block until the subscription ends.
+type eventSubscriber interface {
+	Subscribe(ctx context.Context, notify func()) error
+}
+
 func New(k types.Knapsack, fetcher dataProvider, opts ...Option) *ControlService {
 	cs := &ControlService{
 		slogger:              k.Slogger().With("component", "control"),
@@ -65,6 +82,7 @@ func New(k types.Knapsack, fetcher dataProvider, opts ...Option) *ControlService
 		requestInterval:      k.ControlRequestInterval(),
 		requestIntervalMutex: &sync.RWMutex{},
 		fetcher:              fetcher,
+		fetchRequests:        make(chan struct{}, 1),
 		lastFetched:          make(map[string]string),
 		consumers:            make(map[string]consumer),
 		subscribers:          make(map[string][]subscriber),
@@ -98,6 +116,14 @@ func (cs *ControlService) Start(ctx context.Context) {
 		"control service started",
 	)
 
+	// Where supported, listen for update events from the control server, so we don't have to
+	// wait for the next request interval. Polling continues regardless, as the fallback.
+	if subscriber, ok := cs.fetcher.(eventSubscriber); ok {
+		gowrapper.Go(ctx, cs.slogger, func() {
+			cs.streamUpdates(ctx, subscriber)
+		})
+	}
+
 	startUpMessageSuccess := false
 
 	for {
@@ -126,7 +152,69 @@ func (cs *ControlService) Start(ctx context.Context) {
 		case <-cs.requestTicker.C:
 			// Go fetch!
 			continue
+		case <-cs.fetchRequests:
+			// The control server told us there's an update -- go fetch!
+			continue
+		}
+	}
+}
+
+// streamUpdates maintains a subscription to the control server's update events while
+// ControlSSEEnabled is set, reconnecting with backoff whenever the stream drops.
+func (cs *ControlService) streamUpdates(ctx context.Context, subscriber eventSubscriber) {
+	reconnectDelay := minStreamReconnectDelay
+
+	for {
+		// Wait before each attempt -- this also gives the first Fetch time to authenticate
+		// with the control server before we connect.
+		waitDuration := reconnectDelay
+		if !cs.knapsack.ControlSSEEnabled() {
+			waitDuration = cs.readRequestInterval()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(waitDuration):
 		}
+
+		if !cs.knapsack.ControlSSEEnabled() {
+			continue
+		}
+
+		cs.slogger.Log(ctx, slog.LevelDebug,
+			"connecting to control server event stream",
+		)
+
+		connectedAt := time.Now()
+		err := subscriber.Subscribe(ctx, cs.requestFetch)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(connectedAt) >= healthyStreamDuration {
+			reconnectDelay = minStreamReconnectDelay
+		} else {
+			reconnectDelay = min(reconnectDelay*2, maxStreamReconnectDelay)
+		}
+
+		cs.slogger.Log(ctx, slog.LevelInfo,
+			"control server event stream ended, falling back to polling until reconnected",
+			"err", err,
+			"reconnect_delay", reconnectDelay.String(),
+		)
+
+		// We may have missed updates while disconnected
+		cs.requestFetch()
+	}
+}
+
+// requestFetch asks the main loop to fetch as soon as possible. It does not block; if a
+// fetch request is already pending, this one is dropped.
+func (cs *ControlService) requestFetch() {
+	select {
+	case cs.fetchRequests <- struct{}{}:
+	default:
 	}
 }
 
@@ -607,3 +607,45 @@ func TestInterrupt_Multiple(t *testing.T) {
 
 	require.Equal(t, expectedInterrupts, receivedInterrupts)
 }
+
+type subscribingDataProvider struct {
+	nopDataProvider
+	subscriptions chan struct{}
+}
+
+func (dp *subscribingDataProvider) Subscribe(_ context.Context, notify func()) error {
+	dp.subscriptions <- struct{}{}
+	notify()
+	notify()
+	return errors.New("test stream closed")
+}
+
+func TestStreamUpdates(t *testing.T) {
+	t.Parallel()
+
+	mockKnapsack := typesMocks.NewKnapsack(t)
+	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.ControlRequestInterval)
+	mockKnapsack.On("ControlRequestInterval").Return(60 * time.Second)
+	mockKnapsack.On("ControlSSEEnabled").Return(true)
+	mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
+
+	data := &subscribingDataProvider{subscriptions: make(chan struct{}, 1)}
+	cs := New(mockKnapsack, data)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cs.streamUpdates(ctx, data)
+
+	select {
+	case <-data.subscriptions:
+	case <-time.After(minStreamReconnectDelay + 5*time.Second):
+		t.Fatal("streamUpdates did not subscribe")
+	}
+	cancel()
+
+	select {
+	case <-cs.fetchRequests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification did not request fetch")
+	}
+}
@@ -0,0 +1,261 @@
+// Package signedpayload verifies that sensitive control server payloads -- uninstall requests,
+// agent flag overrides, and the like -- were signed by Kolide, rather than trusting whatever
+// arrives over TLS from the control server.
+//
+// Payloads are signed with signing keys that Kolide rotates. The current signing keys are
+// published by the control server as a versioned key set, itself signed by the Kolide server
+// key built into launcher (see ee/serverkeys). Each signing key is valid from its not_before
+// time until its not_after time, plus the key set's grace period, so that payloads signed
+// shortly before a rotation still verify. A key that's dropped from the key set is no longer
+// trusted at all.
+//
+// Nothing is trusted on first use: the root key is pinned in launcher, unsigned payloads are
+// always rejected, and until the control server has delivered a key set, no payload can be
+// verified, so sensitive consumers don't run at all. The key set is persisted once delivered.
+package signedpayload
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/kolide/krypto/pkg/echelper"
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+const (
+	// KeySetSubsystem is the control server subsystem that delivers the signing key set.
+	KeySetSubsystem = "control_signing_keys"
+
+	// keySetPurpose is the purpose the key set is signed for
+	keySetPurpose = "signing_keys"
+
+	keySetStoreKey = "key_set"
+
+	// defaultGracePeriod is how long a signing key remains trusted after it expires, when the
+	// key set doesn't specify
+	defaultGracePeriod = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrUnsigned is returned for unsigned payloads.
+	ErrUnsigned = errors.New("payload is not signed")
+
+	// ErrNoKeySet is returned for signed payloads received before the control server has
+	// delivered the key set to verify them with.
+	ErrNoKeySet = errors.New("no signing keys have been delivered yet")
+)
+
+// Envelope is a signed payload. The signature covers the purpose as well as the payload, so that
+// a payload signed for one subsystem or action can't be replayed as another.
+type Envelope struct {
+	Purpose   string `json:"purpose"`
+	Payload   []byte `json:"payload"`
+	KeyID     string `json:"key_id"`
+	Signature []byte `json:"signature"`
+}
+
+// signedData returns the data an envelope's signature is over.
+func signedData(purpose string, payload []byte) []byte {
+	data := make([]byte, 0, len(purpose)+1+len(payload))
+	data = append(data, purpose...)
+	data = append(data, 0)
+	return append(data, payload...)
+}
+
+// parseEnvelope returns the envelope in data, or nil if data isn't a signed envelope.
+func parseEnvelope(data []byte) *Envelope {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil || len(env.Payload) == 0 || len(env.Signature) == 0 {
+		return nil
+	}
+	return &env
+}
+
+// keySet is the rotation metadata for the signing keys, as delivered by the control server.
+type keySet struct {
+	Version            int64        `json:"version"`
+	GracePeriodSeconds int64        `json:"grace_period_seconds"`
+	Keys               []signingKey `json:"keys"`
+}
+
+type signingKey struct {
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"` // base64-encoded DER
+	NotBefore int64  `json:"not_before"`
+	NotAfter  int64  `json:"not_after"` // 0 if the key doesn't expire
+}
+
+// trustedKey is a parsed signingKey.
+type trustedKey struct {
+	key       *ecdsa.PublicKey
+	notBefore time.Time
+	notAfter  time.Time // zero if the key doesn't expire
+}
+
+// Verifier verifies signed payloads against the current key set. It is also the consumer for
+// KeySetSubsystem.
+type Verifier struct {
+	slogger *slog.Logger
+	rootKey *ecdsa.PublicKey
+	store   types.GetterSetter
+	now     func() time.Time
+
+	lock        sync.RWMutex
+	version     int64
+	gracePeriod time.Duration
+	keys        map[string]trustedKey // nil until a key set has been delivered
+}
+
+// New returns a Verifier that trusts key sets signed by rootKey, and persists the current key
+// set in store.
+func New(slogger *slog.Logger, rootKey *ecdsa.PublicKey, store types.GetterSetter) *Verifier {
+	v := &Verifier{
+		slogger: slogger.With("component", "signed_payload_verifier"),
+		rootKey: rootKey,
+		store:   store,
+		now:     time.Now,
+	}
+
+	storedKeySet, err := store.Get([]byte(keySetStoreKey))
+	switch {
+	case err != nil:
+		v.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not read stored signing key set",
+			"err", err,
+		)
+	case storedKeySet != nil:
+		if err := v.applyKeySet(storedKeySet); err != nil {
+			v.slogger.Log(context.TODO(), slog.LevelWarn,
+				"stored signing key set is invalid, ignoring it",
+				"err", err,
+			)
+		}
+	}
+
+	return v
+}
+
+// Update receives the key set from the control server.
+func (v *Verifier) Update(data io.Reader) error {
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("reading signing key set: %w", err)
+	}
+
+	if err := v.applyKeySet(raw); err != nil {
+		return err
+	}
+
+	if err := v.store.Set([]byte(keySetStoreKey), raw); err != nil {
+		return fmt.Errorf("storing signing key set: %w", err)
+	}
+
+	return nil
+}
+
+// applyKeySet verifies the signed key set in raw, and makes it the current key set.
+func (v *Verifier) applyKeySet(raw []byte) error {
+	env := parseEnvelope(raw)
+	if env == nil {
+		return errors.New("signing key set is not signed")
+	}
+	if env.Purpose != keySetPurpose {
+		return fmt.Errorf("signing key set was signed for %q", env.Purpose)
+	}
+	if err := echelper.VerifySignature(v.rootKey, signedData(env.Purpose, env.Payload), env.Signature); err != nil {
+		return fmt.Errorf("verifying signing key set: %w", err)
+	}
+
+	var ks keySet
+	if err := json.Unmarshal(env.Payload, &ks); err != nil {
+		return fmt.Errorf("unmarshalling signing key set: %w", err)
+	}
+
+	keys := make(map[string]trustedKey, len(ks.Keys))
+	for _, k := range ks.Keys {
+		key, err := echelper.PublicB64DerToEcdsaKey([]byte(k.PublicKey))
+		if err != nil {
+			return fmt.Errorf("parsing signing key %s: %w", k.KeyID, err)
+		}
+
+		tk := trustedKey{key: key, notBefore: time.Unix(k.NotBefore, 0)}
+		if k.NotAfter != 0 {
+			tk.notAfter = time.Unix(k.NotAfter, 0)
+		}
+		keys[k.KeyID] = tk
+	}
+
+	gracePeriod := defaultGracePeriod
+	if ks.GracePeriodSeconds > 0 {
+		gracePeriod = time.Duration(ks.GracePeriodSeconds) * time.Second
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	// Don't let an older key set, which may include since-revoked keys, replace a newer one
+	if v.keys != nil && ks.Version < v.version {
+		return fmt.Errorf("signing key set version %d is older than current version %d", ks.Version, v.version)
+	}
+
+	v.version = ks.Version
+	v.gracePeriod = gracePeriod
+	v.keys = keys
+
+	return nil
+}
+
+// Open returns the payload of data, which must be an Envelope signed for the given purpose.
+func (v *Verifier) Open(purpose string, data []byte) ([]byte, error) {
+	env := parseEnvelope(data)
+	if env == nil {
+		return nil, ErrUnsigned
+	}
+
+	if err := v.verify(purpose, env); err != nil {
+		return nil, err
+	}
+
+	return env.Payload, nil
+}
+
+// verify checks that env was signed for purpose by a currently-trusted signing key.
+func (v *Verifier) verify(purpose string, env *Envelope) error {
+	if env.Purpose != purpose {
+		return fmt.Errorf("payload was signed for %q, not %q", env.Purpose, purpose)
+	}
+
+	v.lock.RLock()
+	haveKeySet := v.keys != nil
+	tk, ok := v.keys[env.KeyID]
+	gracePeriod := v.gracePeriod
+	v.lock.RUnlock()
+
+	if !haveKeySet {
+		return ErrNoKeySet
+	}
+	if !ok {
+		return fmt.Errorf("payload was signed with unknown or revoked key %q", env.KeyID)
+	}
+
+	now := v.now()
+	if now.Before(tk.notBefore) {
+		return fmt.Errorf("payload was signed with key %q, which is not valid until %s", env.KeyID, tk.notBefore.UTC().Format(time.RFC3339))
+	}
+	if !tk.notAfter.IsZero() && now.After(tk.notAfter.Add(gracePeriod)) {
+		return fmt.Errorf("payload was signed with key %q, which expired at %s", env.KeyID, tk.notAfter.UTC().Format(time.RFC3339))
+	}
+
+	if err := echelper.VerifySignature(tk.key, signedData(env.Purpose, env.Payload), env.Signature); err != nil {
+		return fmt.Errorf("verifying payload signed with key %q: %w", env.KeyID, err)
+	}
+
+	return nil
+}
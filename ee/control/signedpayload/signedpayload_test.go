@@ -0,0 +1,218 @@
+package signedpayload
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kolide/krypto/pkg/echelper"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, keyID, purpose string, payload []byte) []byte {
+	sig, err := echelper.Sign(key, signedData(purpose, payload))
+	require.NoError(t, err)
+
+	env, err := json.Marshal(Envelope{Purpose: purpose, Payload: payload, KeyID: keyID, Signature: sig})
+	require.NoError(t, err)
+	return env
+}
+
+func signedKeySet(t *testing.T, rootKey *ecdsa.PrivateKey, ks keySet) []byte {
+	payload, err := json.Marshal(ks)
+	require.NoError(t, err)
+	return sign(t, rootKey, "root", keySetPurpose, payload)
+}
+
+func publicKeyB64(t *testing.T, key *ecdsa.PrivateKey) string {
+	der, err := echelper.PublicEcdsaToB64Der(&key.PublicKey)
+	require.NoError(t, err)
+	return string(der)
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := echelper.GenerateEcdsaKey()
+	require.NoError(t, err)
+	return key
+}
+
+func TestVerifier(t *testing.T) {
+	t.Parallel()
+
+	rootKey := generateKey(t)
+	currentKey := generateKey(t)
+	previousKey := generateKey(t)
+	nextKey := generateKey(t)
+	now := time.Now()
+
+	store := inmemory.NewStore()
+	v := New(multislogger.NewNopLogger(), &rootKey.PublicKey, store)
+	v.now = func() time.Time { return now }
+
+	// Nothing is trusted before a key set is delivered: not unsigned payloads, and not signed
+	// ones, which can't be verified yet
+	_, err := v.Open("agent_flags", []byte(`{"desktop_enabled":"true"}`))
+	require.ErrorIs(t, err, ErrUnsigned)
+	_, err = v.Open("agent_flags", sign(t, currentKey, "current", "agent_flags", []byte(`{"a":"b"}`)))
+	require.ErrorIs(t, err, ErrNoKeySet)
+
+	// Key sets must be signed by the root key
+	ks := keySet{
+		Version:            2,
+		GracePeriodSeconds: int64((24 * time.Hour).Seconds()),
+		Keys: []signingKey{
+			{KeyID: "current", PublicKey: publicKeyB64(t, currentKey), NotBefore: now.Add(-time.Hour).Unix(), NotAfter: now.Add(30 * 24 * time.Hour).Unix()},
+			{KeyID: "previous", PublicKey: publicKeyB64(t, previousKey), NotBefore: now.Add(-60 * 24 * time.Hour).Unix(), NotAfter: now.Add(-time.Hour).Unix()},
+			{KeyID: "next", PublicKey: publicKeyB64(t, nextKey), NotBefore: now.Add(time.Hour).Unix()},
+		},
+	}
+	require.Error(t, v.Update(bytes.NewReader(signedKeySet(t, currentKey, ks))))
+	require.Error(t, v.Update(bytes.NewReader(sign(t, rootKey, "root", "agent_flags", []byte(`{}`)))))
+	require.NoError(t, v.Update(bytes.NewReader(signedKeySet(t, rootKey, ks))))
+
+	// Payloads must still be signed
+	_, err = v.Open("agent_flags", []byte(`{"desktop_enabled":"true"}`))
+	require.ErrorIs(t, err, ErrUnsigned)
+
+	for _, tt := range []struct {
+		name      string
+		envelope  []byte
+		expectErr bool
+	}{
+		{
+			name:     "current key",
+			envelope: sign(t, currentKey, "current", "agent_flags", []byte(`{"a":"b"}`)),
+		},
+		{
+			name:     "expired key within grace period",
+			envelope: sign(t, previousKey, "previous", "agent_flags", []byte(`{"a":"b"}`)),
+		},
+		{
+			name:      "key not valid yet",
+			envelope:  sign(t, nextKey, "next", "agent_flags", []byte(`{"a":"b"}`)),
+			expectErr: true,
+		},
+		{
+			name:      "unknown key",
+			envelope:  sign(t, currentKey, "other", "agent_flags", []byte(`{"a":"b"}`)),
+			expectErr: true,
+		},
+		{
+			name:      "signed with a different key than claimed",
+			envelope:  sign(t, previousKey, "current", "agent_flags", []byte(`{"a":"b"}`)),
+			expectErr: true,
+		},
+		{
+			name:      "signed for a different purpose",
+			envelope:  sign(t, currentKey, "current", "action:uninstall", []byte(`{"a":"b"}`)),
+			expectErr: true,
+		},
+		{
+			name:      "root key can't sign payloads",
+			envelope:  sign(t, rootKey, "root", "agent_flags", []byte(`{"a":"b"}`)),
+			expectErr: true,
+		},
+	} {
+		payload, err := v.Open("agent_flags", tt.envelope)
+		if tt.expectErr {
+			require.Error(t, err, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		require.Equal(t, `{"a":"b"}`, string(payload), tt.name)
+	}
+
+	// Once the grace period is over, the previous key is no longer trusted
+	v.now = func() time.Time { return now.Add(25 * time.Hour) }
+	_, err = v.Open("agent_flags", sign(t, previousKey, "previous", "agent_flags", []byte(`{"a":"b"}`)))
+	require.Error(t, err)
+
+	// An older key set can't replace the current one
+	olderKs := ks
+	olderKs.Version = 1
+	require.Error(t, v.Update(bytes.NewReader(signedKeySet(t, rootKey, olderKs))))
+
+	// The key set persists across restarts
+	restarted := New(multislogger.NewNopLogger(), &rootKey.PublicKey, store)
+	_, err = restarted.Open("agent_flags", []byte(`{"desktop_enabled":"true"}`))
+	require.ErrorIs(t, err, ErrUnsigned)
+	_, err = restarted.Open("agent_flags", sign(t, currentKey, "current", "agent_flags", []byte(`{"a":"b"}`)))
+	require.NoError(t, err)
+
+	// Dropping a key from the key set revokes it immediately
+	rotatedKs := keySet{
+		Version: 3,
+		Keys:    []signingKey{{KeyID: "next", PublicKey: publicKeyB64(t, nextKey), NotBefore: now.Add(-time.Hour).Unix()}},
+	}
+	require.NoError(t, v.Update(bytes.NewReader(signedKeySet(t, rootKey, rotatedKs))))
+	_, err = v.Open("agent_flags", sign(t, currentKey, "current", "agent_flags", []byte(`{"a":"b"}`)))
+	require.Error(t, err)
+	_, err = v.Open("agent_flags", sign(t, nextKey, "next", "agent_flags", []byte(`{"a":"b"}`)))
+	require.NoError(t, err)
+}
+
+type recordingUpdater struct {
+	received [][]byte
+}
+
+func (r *recordingUpdater) Update(data io.Reader) error {
+	b, err := io.ReadAll(data)
+	r.received = append(r.received, b)
+	return err
+}
+
+func (r *recordingUpdater) Do(data io.Reader) error {
+	return r.Update(data)
+}
+
+func TestConsumerAndActor(t *testing.T) {
+	t.Parallel()
+
+	rootKey := generateKey(t)
+	payloadKey := generateKey(t)
+
+	v := New(multislogger.NewNopLogger(), &rootKey.PublicKey, inmemory.NewStore())
+
+	next := &recordingUpdater{}
+	consumer := NewConsumer(v, "agent_flags", next)
+	actor := NewActor(v, next)
+
+	// Nothing passes through before a key set is delivered
+	unsignedAction := []byte(`{"id":"1","type":"uninstall","valid_until":1700000000}`)
+	require.Error(t, consumer.Update(bytes.NewReader([]byte(`{"a":"b"}`))))
+	require.Error(t, consumer.Update(bytes.NewReader(sign(t, payloadKey, "k1", "agent_flags", []byte(`{"a":"c"}`)))))
+	require.Error(t, actor.Do(bytes.NewReader(unsignedAction)))
+	require.Empty(t, next.received)
+
+	require.NoError(t, v.Update(bytes.NewReader(signedKeySet(t, rootKey, keySet{
+		Version: 1,
+		Keys:    []signingKey{{KeyID: "k1", PublicKey: publicKeyB64(t, payloadKey)}},
+	}))))
+
+	require.Error(t, consumer.Update(bytes.NewReader([]byte(`{"a":"b"}`))))
+	require.Error(t, actor.Do(bytes.NewReader(unsignedAction)))
+	require.Empty(t, next.received)
+
+	require.NoError(t, consumer.Update(bytes.NewReader(sign(t, payloadKey, "k1", "agent_flags", []byte(`{"a":"c"}`)))))
+
+	// Signed actions carry the complete action, which is what the actor receives
+	signed := func(outerID string, innerAction []byte) []byte {
+		var env Envelope
+		require.NoError(t, json.Unmarshal(sign(t, payloadKey, "k1", "action:uninstall", innerAction), &env))
+		action, err := json.Marshal(signedAction{ID: outerID, Type: "uninstall", ValidUntil: 1700000000, Signed: &env})
+		require.NoError(t, err)
+		return action
+	}
+	innerAction := []byte(`{"id":"2","type":"uninstall","valid_until":1700000000}`)
+	require.NoError(t, actor.Do(bytes.NewReader(signed("2", innerAction))))
+
+	// A signed action can't be sent under a different ID
+	require.Error(t, actor.Do(bytes.NewReader(signed("3", innerAction))))
+
+	require.Equal(t, [][]byte{[]byte(`{"a":"c"}`), innerAction}, next.received)
+}
@@ -0,0 +1,103 @@
+package signedpayload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type updater interface {
+	Update(data io.Reader) error
+}
+
+type doer interface {
+	Do(data io.Reader) error
+}
+
+// Consumer wraps the consumer for a sensitive control server subsystem, so that it only
+// receives data signed for that subsystem.
+type Consumer struct {
+	verifier  *Verifier
+	subsystem string
+	next      updater
+}
+
+func NewConsumer(verifier *Verifier, subsystem string, next updater) *Consumer {
+	return &Consumer{
+		verifier:  verifier,
+		subsystem: subsystem,
+		next:      next,
+	}
+}
+
+func (c *Consumer) Update(data io.Reader) error {
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("reading %s update: %w", c.subsystem, err)
+	}
+
+	payload, err := c.verifier.Open(c.subsystem, raw)
+	if err != nil {
+		return fmt.Errorf("verifying %s update: %w", c.subsystem, err)
+	}
+
+	return c.next.Update(bytes.NewReader(payload))
+}
+
+// signedAction is an action as the action queue receives it. A signed action carries the
+// complete action, signed for "action:<type>", in its signed field.
+type signedAction struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	ValidUntil int64     `json:"valid_until"`
+	Signed     *Envelope `json:"signed,omitempty"`
+}
+
+// Actor wraps the actor for a sensitive action, so that it only performs actions that were
+// signed for its action type.
+type Actor struct {
+	verifier *Verifier
+	next     doer
+}
+
+func NewActor(verifier *Verifier, next doer) *Actor {
+	return &Actor{
+		verifier: verifier,
+		next:     next,
+	}
+}
+
+func (a *Actor) Do(data io.Reader) error {
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("reading action: %w", err)
+	}
+
+	var outer signedAction
+	if err := json.Unmarshal(raw, &outer); err != nil {
+		return fmt.Errorf("unmarshalling action: %w", err)
+	}
+
+	purpose := "action:" + outer.Type
+
+	if outer.Signed == nil {
+		return fmt.Errorf("verifying %s action: %w", outer.Type, ErrUnsigned)
+	}
+
+	if err := a.verifier.verify(purpose, outer.Signed); err != nil {
+		return fmt.Errorf("verifying %s action: %w", outer.Type, err)
+	}
+
+	// The action queue tracks and expires actions by the unsigned fields, so they must match
+	// what was signed
+	var inner signedAction
+	if err := json.Unmarshal(outer.Signed.Payload, &inner); err != nil {
+		return fmt.Errorf("unmarshalling signed %s action: %w", outer.Type, err)
+	}
+	if inner.ID != outer.ID || inner.Type != outer.Type || inner.ValidUntil != outer.ValidUntil {
+		return fmt.Errorf("signed %s action %s does not match action %s it was sent with", inner.Type, inner.ID, outer.ID)
+	}
+
+	return a.next.Do(bytes.NewReader(outer.Signed.Payload))
+}
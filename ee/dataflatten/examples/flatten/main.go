@@ -29,6 +29,7 @@ func main() {
 		flXml   = flagset.String("xml", "", "Path to xml file")
 		flIni   = flagset.String("ini", "", "Path to ini file")
 		flQuery = flagset.String("q", "", "query")
+		flPath  = flagset.String("jsonpath", "", "JSONPath expression")
 
 		flDebug = flagset.Bool("debug", false, "use a debug logger")
 	)
@@ -45,6 +46,9 @@ func main() {
 		dataflatten.WithNestedPlist(),
 		dataflatten.WithQuery(strings.Split(*flQuery, `/`)),
 	}
+	if *flPath != "" {
+		opts = append(opts, dataflatten.WithJSONPath(*flPath))
+	}
 	if *flDebug {
 		opts = append(opts, dataflatten.WithDebugLogging())
 	}
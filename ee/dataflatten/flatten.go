@@ -34,6 +34,10 @@
 //     *  data/users/name=>A*   Return users whose name starts with "A"
 //     *  data/users/#id        Return the users, and rewrite the users array to be a map with the id as the key
 //
+// Alternatively, a subset of JSONPath can be used via WithJSONPath. This
+// can express things the query syntax can't, such as filtering an array
+// of maps on a key's value. See jsonpath.go for the supported syntax.
+//
 // See the test suite for extensive examples.
 package dataflatten
 
@@ -71,6 +75,7 @@ type Flattener struct {
 	expandNestedPlist bool
 	includeNestedRaw  bool
 	includeNils       bool
+	jsonPath          string
 	slogger           *slog.Logger
 	query             []string
 	queryKeyDenoter   string
@@ -133,6 +138,15 @@ func WithQuery(q []string) FlattenOpts {
 	}
 }
 
+// WithJSONPath specifies a JSONPath expression to select which parts
+// of the data are flattened. If a query is also given, it is applied
+// relative to each node the expression matches.
+func WithJSONPath(expr string) FlattenOpts {
+	return func(fl *Flattener) {
+		fl.jsonPath = expr
+	}
+}
+
 // Flatten is the entry point to the Flattener functionality.
 func Flatten(data interface{}, opts ...FlattenOpts) ([]Row, error) {
 	fl := &Flattener{
@@ -151,6 +165,21 @@ func Flatten(data interface{}, opts ...FlattenOpts) ([]Row, error) {
 		fl.logLevel = slog.LevelDebug
 	}
 
+	if fl.jsonPath != "" {
+		steps, err := parseJSONPath(fl.jsonPath)
+		if err != nil {
+			return nil, fmt.Errorf("parsing jsonpath: %w", err)
+		}
+
+		for _, node := range evaluateJSONPath(steps, data) {
+			if err := fl.descend(node.path, node.value, 0); err != nil {
+				return nil, err
+			}
+		}
+
+		return fl.rows, nil
+	}
+
 	if err := fl.descend([]string{}, data, 0); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,440 @@
+package dataflatten
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath support
+//
+// As an alternative to the slash-separated query syntax, a subset of
+// JSONPath can be used to select which parts of the data are
+// flattened. The supported subset is:
+//
+//	$               the root. Every expression must start with this
+//	.key, ['key']   a map key
+//	.*, [*]         every child of a map or array
+//	[n]             an array index. Negative indexes count from the end
+//	..key, ..*      recursive descent
+//	[?(@.a.b OP v)] a filter on the children of a map or array
+//	[?(@.a.b)]      a filter on the existence of a key
+//
+// Filter operators are ==, !=, <, <=, >, and >=. Values may be
+// quoted strings, numbers, true, false, or null. Children missing
+// the filtered key never match a comparison. For example:
+//
+//	$.users[?(@.name == 'alex')].uuid
+//	$..applications[?(@.version >= 2)]
+//
+// Everything beneath a matched node is flattened as usual. Note that
+// the expression is evaluated before nested plists are expanded, so it
+// cannot select into them.
+
+type jsonPathStepKind int
+
+const (
+	stepChild jsonPathStepKind = iota
+	stepWildcard
+	stepIndex
+	stepRecursive
+	stepFilter
+)
+
+type jsonPathStep struct {
+	kind   jsonPathStepKind
+	key    string // for stepChild, and stepRecursive (where "*" is any key)
+	index  int
+	filter *jsonPathFilter
+}
+
+type jsonPathFilter struct {
+	path     []string
+	operator string // empty for an existence check
+	value    interface{}
+}
+
+// jsonPathNode is a single location in the data, and the value found there.
+type jsonPathNode struct {
+	path  []string
+	value interface{}
+}
+
+var jsonPathOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseJSONPath compiles a JSONPath expression into its steps.
+func parseJSONPath(expr string) ([]jsonPathStep, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("jsonpath %q must start with $", expr)
+	}
+
+	var steps []jsonPathStep
+	for i := 1; i < len(expr); {
+		switch {
+		case strings.HasPrefix(expr[i:], ".."):
+			i += 2
+			name, n := readJSONPathName(expr[i:])
+			if name == "" {
+				return nil, fmt.Errorf("jsonpath %q: expected a key after .. at offset %d", expr, i)
+			}
+			steps = append(steps, jsonPathStep{kind: stepRecursive, key: name})
+			i += n
+
+		case expr[i] == '.':
+			i++
+			name, n := readJSONPathName(expr[i:])
+			switch name {
+			case "":
+				return nil, fmt.Errorf("jsonpath %q: expected a key after . at offset %d", expr, i)
+			case "*":
+				steps = append(steps, jsonPathStep{kind: stepWildcard})
+			default:
+				steps = append(steps, jsonPathStep{kind: stepChild, key: name})
+			}
+			i += n
+
+		case expr[i] == '[':
+			end, err := findJSONPathBracketEnd(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			step, err := parseJSONPathBracket(expr[i+1 : end])
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %q: %w", expr, err)
+			}
+			steps = append(steps, step)
+			i = end + 1
+
+		default:
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q at offset %d", expr, expr[i], i)
+		}
+	}
+
+	return steps, nil
+}
+
+// readJSONPathName reads a dot-notation key, returning it and the number of bytes consumed.
+func readJSONPathName(s string) (string, int) {
+	end := strings.IndexAny(s, ".[")
+	if end == -1 {
+		end = len(s)
+	}
+	return s[:end], end
+}
+
+// findJSONPathBracketEnd returns the index of the bracket closing the one at start,
+// ignoring any brackets inside quotes.
+func findJSONPathBracketEnd(expr string, start int) (int, error) {
+	var quote byte
+	for i := start + 1; i < len(expr); i++ {
+		switch {
+		case quote != 0:
+			if expr[i] == quote {
+				quote = 0
+			}
+		case expr[i] == '\'' || expr[i] == '"':
+			quote = expr[i]
+		case expr[i] == ']':
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("jsonpath %q: unterminated [ at offset %d", expr, start)
+}
+
+func parseJSONPathBracket(inner string) (jsonPathStep, error) {
+	inner = strings.TrimSpace(inner)
+
+	switch {
+	case inner == "*":
+		return jsonPathStep{kind: stepWildcard}, nil
+	case isJSONPathQuoted(inner):
+		return jsonPathStep{kind: stepChild, key: inner[1 : len(inner)-1]}, nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		filter, err := parseJSONPathFilter(inner[2 : len(inner)-1])
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{kind: stepFilter, filter: filter}, nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return jsonPathStep{}, fmt.Errorf("unsupported selector [%s]", inner)
+	}
+	return jsonPathStep{kind: stepIndex, index: index}, nil
+}
+
+func parseJSONPathFilter(expr string) (*jsonPathFilter, error) {
+	expr = strings.TrimSpace(expr)
+
+	lhs, operator, rhs := expr, "", ""
+	for _, op := range jsonPathOperators {
+		if idx := indexOutsideQuotes(expr, op); idx != -1 {
+			lhs, operator, rhs = expr[:idx], op, expr[idx+len(op):]
+			break
+		}
+	}
+
+	lhs = strings.TrimSpace(lhs)
+	if !strings.HasPrefix(lhs, "@") {
+		return nil, fmt.Errorf("filter %q must reference the current element with @", expr)
+	}
+
+	filter := &jsonPathFilter{operator: operator}
+	if path := strings.TrimPrefix(lhs, "@"); path != "" {
+		if !strings.HasPrefix(path, ".") {
+			return nil, fmt.Errorf("filter %q: unsupported path %q", expr, path)
+		}
+		filter.path = strings.Split(strings.TrimPrefix(path, "."), ".")
+	}
+
+	if operator == "" {
+		if len(filter.path) == 0 {
+			return nil, fmt.Errorf("filter %q: existence checks require a key", expr)
+		}
+		return filter, nil
+	}
+
+	value, err := parseJSONPathLiteral(strings.TrimSpace(rhs))
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", expr, err)
+	}
+	filter.value = value
+
+	return filter, nil
+}
+
+func parseJSONPathLiteral(s string) (interface{}, error) {
+	switch {
+	case isJSONPathQuoted(s):
+		return s[1 : len(s)-1], nil
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "null":
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported value %q", s)
+	}
+	return f, nil
+}
+
+func isJSONPathQuoted(s string) bool {
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
+
+// indexOutsideQuotes is strings.Index, skipping over quoted sections.
+func indexOutsideQuotes(s, substr string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case strings.HasPrefix(s[i:], substr):
+			return i
+		}
+	}
+	return -1
+}
+
+// evaluateJSONPath applies the steps to data, returning every matched node.
+func evaluateJSONPath(steps []jsonPathStep, data interface{}) []jsonPathNode {
+	nodes := []jsonPathNode{{path: []string{}, value: data}}
+
+	for _, step := range steps {
+		var next []jsonPathNode
+		for _, node := range nodes {
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+	}
+
+	return nodes
+}
+
+func (step jsonPathStep) apply(node jsonPathNode) []jsonPathNode {
+	var matched []jsonPathNode
+
+	switch step.kind {
+	case stepChild:
+		if m, ok := node.value.(map[string]interface{}); ok {
+			if v, ok := m[step.key]; ok {
+				matched = append(matched, node.child(step.key, v))
+			}
+		}
+
+	case stepWildcard:
+		matched = jsonPathChildren(node)
+
+	case stepIndex:
+		arr := jsonPathArray(node.value)
+		index := step.index
+		if index < 0 {
+			index += len(arr)
+		}
+		if index >= 0 && index < len(arr) {
+			matched = append(matched, node.child(strconv.Itoa(index), arr[index]))
+		}
+
+	case stepFilter:
+		for _, child := range jsonPathChildren(node) {
+			if step.filter.matches(child.value) {
+				matched = append(matched, child)
+			}
+		}
+
+	case stepRecursive:
+		// Check this node, then everything beneath it
+		if step.key == "*" {
+			matched = append(matched, jsonPathChildren(node)...)
+		} else {
+			matched = append(matched, jsonPathStep{kind: stepChild, key: step.key}.apply(node)...)
+		}
+		for _, child := range jsonPathChildren(node) {
+			matched = append(matched, step.apply(child)...)
+		}
+	}
+
+	return matched
+}
+
+func (node jsonPathNode) child(key string, value interface{}) jsonPathNode {
+	path := make([]string, len(node.path), len(node.path)+1)
+	copy(path, node.path)
+	return jsonPathNode{path: append(path, key), value: value}
+}
+
+// jsonPathChildren returns the children of a map or array. Map keys are sorted, so that
+// results are stable.
+func jsonPathChildren(node jsonPathNode) []jsonPathNode {
+	var children []jsonPathNode
+
+	if m, ok := node.value.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			children = append(children, node.child(k, m[k]))
+		}
+		return children
+	}
+
+	for i, v := range jsonPathArray(node.value) {
+		children = append(children, node.child(strconv.Itoa(i), v))
+	}
+	return children
+}
+
+// jsonPathArray normalizes the array types we might encounter.
+func jsonPathArray(data interface{}) []interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		return v
+	case []map[string]interface{}:
+		arr := make([]interface{}, len(v))
+		for i, e := range v {
+			arr[i] = e
+		}
+		return arr
+	}
+	return nil
+}
+
+func (filter *jsonPathFilter) matches(data interface{}) bool {
+	for _, key := range filter.path {
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if data, ok = m[key]; !ok {
+			return false
+		}
+	}
+
+	switch filter.operator {
+	case "":
+		return true
+	case "==":
+		return jsonPathEqual(data, filter.value)
+	case "!=":
+		return !jsonPathEqual(data, filter.value)
+	}
+
+	cmp, err := jsonPathCompareNumbers(data, filter.value)
+	if err != nil {
+		return false
+	}
+
+	switch filter.operator {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return false
+}
+
+func jsonPathEqual(data, value interface{}) bool {
+	if value == nil {
+		return data == nil
+	}
+
+	if _, ok := value.(float64); ok {
+		cmp, err := jsonPathCompareNumbers(data, value)
+		return err == nil && cmp == 0
+	}
+
+	dataStr, err := stringify(data)
+	if err != nil {
+		return false
+	}
+	valueStr, err := stringify(value)
+	if err != nil {
+		return false
+	}
+
+	return dataStr == valueStr
+}
+
+func jsonPathCompareNumbers(data, value interface{}) (int, error) {
+	valueNum, ok := value.(float64)
+	if !ok {
+		return 0, errors.New("comparison value is not a number")
+	}
+
+	dataStr, err := stringify(data)
+	if err != nil {
+		return 0, err
+	}
+	dataNum, err := strconv.ParseFloat(dataStr, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case dataNum < valueNum:
+		return -1, nil
+	case dataNum > valueNum:
+		return 1, nil
+	}
+	return 0, nil
+}
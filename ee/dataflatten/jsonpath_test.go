@@ -0,0 +1,129 @@
+package dataflatten
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const jsonPathTestData = `{
+  "users": [
+    {"name": "alex", "uuid": "abc123", "id": 1, "admin": true, "tags": ["a", "b"]},
+    {"name": "bob", "uuid": "def456", "id": 2, "admin": false},
+    {"name": "carol", "uuid": "ghi789", "id": 3}
+  ],
+  "settings": {"name": "global", "nested": {"name": "inner", "enabled": true}}
+}`
+
+func TestFlatten_JSONPath(t *testing.T) {
+	t.Parallel()
+
+	var dataIn interface{}
+	require.NoError(t, json.Unmarshal([]byte(jsonPathTestData), &dataIn))
+
+	var tests = []flattenTestCase{
+		{
+			options: []FlattenOpts{WithJSONPath("$.users[?(@.name == 'alex')].uuid")},
+			out: []Row{
+				{Path: []string{"users", "0", "uuid"}, Value: "abc123"},
+			},
+			comment: "filter on key equals value",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath(`$.users[?(@.name != "alex")].name`)},
+			out: []Row{
+				{Path: []string{"users", "1", "name"}, Value: "bob"},
+				{Path: []string{"users", "2", "name"}, Value: "carol"},
+			},
+			comment: "filter on key not equals value",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$.users[?(@.id >= 2)].id")},
+			out: []Row{
+				{Path: []string{"users", "1", "id"}, Value: "2"},
+				{Path: []string{"users", "2", "id"}, Value: "3"},
+			},
+			comment: "numeric comparison",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$.users[?(@.admin == true)].name")},
+			out: []Row{
+				{Path: []string{"users", "0", "name"}, Value: "alex"},
+			},
+			comment: "boolean comparison",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$.users[?(@.admin)].name")},
+			out: []Row{
+				{Path: []string{"users", "0", "name"}, Value: "alex"},
+				{Path: []string{"users", "1", "name"}, Value: "bob"},
+			},
+			comment: "existence filter",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$.users[-1]")},
+			out: []Row{
+				{Path: []string{"users", "2", "id"}, Value: "3"},
+				{Path: []string{"users", "2", "name"}, Value: "carol"},
+				{Path: []string{"users", "2", "uuid"}, Value: "ghi789"},
+			},
+			comment: "negative index, flattening beneath the match",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$.users[*].tags[1]")},
+			out: []Row{
+				{Path: []string{"users", "0", "tags", "1"}, Value: "b"},
+			},
+			comment: "wildcard and index",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$..name")},
+			out: []Row{
+				{Path: []string{"settings", "name"}, Value: "global"},
+				{Path: []string{"settings", "nested", "name"}, Value: "inner"},
+				{Path: []string{"users", "0", "name"}, Value: "alex"},
+				{Path: []string{"users", "1", "name"}, Value: "bob"},
+				{Path: []string{"users", "2", "name"}, Value: "carol"},
+			},
+			comment: "recursive descent",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$['settings'].nested"), WithQuery([]string{"enabled"})},
+			out: []Row{
+				{Path: []string{"settings", "nested", "enabled"}, Value: "true"},
+			},
+			comment: "bracket notation, with a relative query",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$.missing")},
+			out:     []Row{},
+			comment: "no match",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("users")},
+			err:     true,
+			comment: "missing root",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$.users[?(@.name == alex)]")},
+			err:     true,
+			comment: "unquoted string",
+		},
+		{
+			options: []FlattenOpts{WithJSONPath("$.users[0")},
+			err:     true,
+			comment: "unterminated bracket",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.comment, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := Flatten(dataIn, tt.options...)
+			testFlattenCase(t, tt, actual, err)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/kolide/launcher/ee/agent/types"
 	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
 	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,6 +19,7 @@ func TestInterrupt_Multiple(t *testing.T) {
 	mockKnapsack := typesmocks.NewKnapsack(t)
 	mockKnapsack.On("UpdateChannel").Return("nightly").Maybe()
 	mockKnapsack.On("TufServerURL").Return("localhost").Maybe()
+	mockKnapsack.On("ExcludedSubsystems").Return([]string{}).Maybe()
 	mockKnapsack.On("BboltDB").Return(storageci.SetupDB(t)).Maybe()
 	mockKnapsack.On("KolideHosted").Return(false).Maybe()
 	mockKnapsack.On("KolideServerURL").Return("localhost").Maybe()
@@ -31,6 +33,7 @@ func TestInterrupt_Multiple(t *testing.T) {
 	mockKnapsack.On("LatestOsquerydPath").Return("").Maybe()
 	mockKnapsack.On("ServerProvidedDataStore").Return(nil).Maybe()
 	mockKnapsack.On("CurrentEnrollmentStatus").Return(types.Enrolled, nil).Maybe()
+	mockKnapsack.On("Query", mock.Anything).Return(nil, errors.New("no instance")).Maybe()
 	checkupLogger := NewCheckupLogger(multislogger.NewNopLogger(), mockKnapsack)
 	mockKnapsack.AssertExpectations(t)
 
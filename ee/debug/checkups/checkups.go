@@ -9,7 +9,8 @@
 // The tricky part is that these get generated at different times. The extra data is generated during a checkup, but
 // the other pieces happen after completion. This has some implications for how method signatures and data buffering work.
 // Namely, it does not make sense to have the checkups comform to interfaces, and let the callers deal. Instead, we define
-// a basic checkup interface, and export wrapper functions.
+// a basic checkup interface, and export wrapper functions. Tools that want individual checkups, rather than all of
+// doctor or flare, can find them in a Registry, which runs them into a structured Result.
 //
 // TODO: The way this enumerates checkups in both Doctor and Flare feels awkward. Needs a rethink. Codegen might help?
 package checkups
@@ -50,8 +51,9 @@ func writeSummary(w io.Writer, s Status, name, msg string) {
 	fmt.Fprintf(w, "%s\t%s: %s\n", s.Emoji(), name, msg)
 }
 
-// checkupInt is the generalized checkup interface. It is not meant to be exported.
-type checkupInt interface {
+// Checkup is the generalized checkup interface. Run must be called before the summary, status, and data
+// are meaningful; each checkup is meant to be run once.
+type Checkup interface {
 	Name() string                                         // Checkup name
 	Run(ctx context.Context, extraWriter io.Writer) error // Run the checkup. Errors here are protocol level
 	ExtraFileName() string                                // If this checkup will have extra data, what name should it use in flare
@@ -66,15 +68,17 @@ const (
 	doctorSupported targetBits = 1 << iota
 	flareSupported
 	logSupported
+
+	allTargets = doctorSupported | flareSupported | logSupported
 )
 
 //const checkupFor iota
 
-func checkupsFor(k types.Knapsack, target targetBits) []checkupInt {
+func checkupsFor(k types.Knapsack, target targetBits) []Checkup {
 	// This encodes what checkups run in which contexts. This could be pushed down into the checkups directly,
 	// but it seems nice to have it here. TBD
 	var potentialCheckups = []struct {
-		c       checkupInt
+		c       Checkup
 		targets targetBits
 	}{
 		{&Platform{}, doctorSupported | flareSupported | logSupported},
@@ -100,18 +104,21 @@ func checkupsFor(k types.Knapsack, target targetBits) []checkupInt {
 		{&quarantine{}, doctorSupported | flareSupported},
 		{&systemTime{}, doctorSupported | flareSupported},
 		{&dnsCheckup{k: k}, doctorSupported | flareSupported | logSupported},
+		{&ipStackCheckup{k: k}, doctorSupported | flareSupported},
 		{&tufCheckup{k: k}, doctorSupported | flareSupported},
 		{&osqConfigConflictCheckup{}, doctorSupported | flareSupported},
 		{&serverDataCheckup{k: k}, flareSupported | logSupported},
 		{&osqDataCollector{k: k}, doctorSupported | flareSupported},
 		{&osqRestartCheckup{k: k}, doctorSupported | flareSupported},
+		{&osqueryStatsCheckup{k: k}, flareSupported | logSupported},
 		{&uninstallHistoryCheckup{k: k}, flareSupported},
 		{&desktopMenu{k: k}, flareSupported},
 		{&coredumpCheckup{}, doctorSupported | flareSupported},
+		{&crashReportsCheckup{k: k}, doctorSupported | flareSupported | logSupported},
 		{&downloadDirectory{}, flareSupported},
 	}
 
-	checkupsToRun := make([]checkupInt, 0)
+	checkupsToRun := make([]Checkup, 0)
 	for _, p := range potentialCheckups {
 		if p.targets&target == 0 {
 			continue
@@ -130,7 +137,7 @@ func checkupsFor(k types.Knapsack, target targetBits) []checkupInt {
 }
 
 // doctorCheckup runs a checkup for the doctor command line. Its a small bit of sugar over the io channels
-func doctorCheckup(ctx context.Context, c checkupInt, w io.Writer) {
+func doctorCheckup(ctx context.Context, c Checkup, w io.Writer) {
 	if err := c.Run(ctx, io.Discard); err != nil {
 		writeSummary(w, Erroring, c.Name(), fmt.Sprintf("failed to run: %s", err))
 		return
@@ -143,7 +150,7 @@ type zipFile interface {
 	Create(name string) (io.Writer, error)
 }
 
-func flareCheckup(ctx context.Context, c checkupInt, combinedSummary io.Writer, flare zipFile) {
+func flareCheckup(ctx context.Context, c Checkup, combinedSummary io.Writer, flare zipFile, scope *flareScope) {
 	// zip can only have a single open file. So defer writing the summary.
 	summary := bytes.Buffer{}
 	defer func() {
@@ -156,10 +163,16 @@ func flareCheckup(ctx context.Context, c checkupInt, combinedSummary io.Writer,
 
 		summaryFH := io.MultiWriter(summaryFlareFH, combinedSummary)
 
-		summaryFH.Write(summary.Bytes())
+		summaryFH.Write(scope.redactor.Bytes(summary.Bytes()))
 	}()
 
+	section := scope.section()
+	if sc, ok := c.(scopedCheckup); ok {
+		sc.setFlareSection(section)
+	}
+
 	fullFH := io.Discard
+	var budgetedFH *headTailWriter
 	if filename := c.ExtraFileName(); filename != "" {
 		var err error
 		fullFH, err = flare.Create(path.Join(c.Name(), filename))
@@ -168,14 +181,34 @@ func flareCheckup(ctx context.Context, c checkupInt, combinedSummary io.Writer,
 			writeSummary(&summary, Erroring, c.Name(), fmt.Sprintf("error creating flare full file: %s", err))
 			return
 		}
+
+		// Zip sections are kept to the budget file by file, as the checkup adds them. Others keep
+		// their start and end.
+		if scope.budget > 0 && path.Ext(filename) != ".zip" {
+			budgetedFH = newHeadTailWriter(fullFH, scope.budget)
+			fullFH = budgetedFH
+		}
 	}
 
-	if err := c.Run(ctx, fullFH); err != nil {
+	if err := runFlareCheckup(ctx, c, fullFH, scope.redactor); err != nil {
 		writeSummary(&summary, Erroring, c.Name(), fmt.Sprintf("failed to run: %s", err))
 		return
 	}
 
+	if budgetedFH != nil {
+		if err := budgetedFH.Flush(); err != nil {
+			writeSummary(&summary, Erroring, c.Name(), fmt.Sprintf("error writing flare full file: %s", err))
+			return
+		}
+		if budgetedFH.truncated() {
+			section.truncated += 1
+		}
+	}
+
 	writeSummary(&summary, c.Status(), c.Name(), c.Summary())
+	if note := section.note(); note != "" {
+		writeSummary(&summary, Informational, c.Name(), note)
+	}
 
 	if data := c.Data(); data != nil {
 		dataFH, err := flare.Create(path.Join(c.Name(), "data.json"))
@@ -184,14 +217,20 @@ func flareCheckup(ctx context.Context, c checkupInt, combinedSummary io.Writer,
 			return
 		}
 
-		if err := json.NewEncoder(dataFH).Encode(data); err != nil {
+		var dataJson bytes.Buffer
+		if err := json.NewEncoder(&dataJson).Encode(data); err != nil {
 			writeSummary(&summary, Erroring, c.Name(), fmt.Sprintf("unable to marshal data: %s", err))
 			return
 		}
+
+		if _, err := dataFH.Write(scope.redactor.Bytes(dataJson.Bytes())); err != nil {
+			writeSummary(&summary, Erroring, c.Name(), fmt.Sprintf("error writing flare data.json file: %s", err))
+			return
+		}
 	}
 }
 
-func logCheckup(ctx context.Context, c checkupInt, slogger *slog.Logger) { // nolint:unused
+func logCheckup(ctx context.Context, c Checkup, slogger *slog.Logger) { // nolint:unused
 	if err := c.Run(ctx, io.Discard); err != nil {
 		slogger.Log(ctx, slog.LevelDebug,
 			"error running checkup",
@@ -255,9 +294,21 @@ const (
 	InSituEnvironment    runtimeEnvironmentType = "in situ"
 )
 
-func RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser, runtimeEnvironment runtimeEnvironmentType) error {
+// RunFlare writes a flare to flareStream. By default, the flare is complete, with each section
+// kept to DefaultSectionBudget; options limit it to some sections, or to what changed since a
+// previous flare, or change the budget. The classes of sensitive value launcher masks in its
+// logs, and any the options add, are masked in the flare's text.
+func RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser, runtimeEnvironment runtimeEnvironmentType, opts ...FlareOption) error {
+	options := &flareOptions{
+		sectionBudget: DefaultSectionBudget,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	flare := zip.NewWriter(flareStream)
 	combinedSummary := bytes.Buffer{}
+	redactor := flareRedactorFor(k, options, &combinedSummary)
 
 	close := func() error {
 		closeFlares := func() error {
@@ -270,7 +321,7 @@ func RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser,
 			return errors.Join(fmt.Errorf("creating doctor.log: %w", err), closeFlares())
 		}
 
-		if _, err := zipSummary.Write(combinedSummary.Bytes()); err != nil {
+		if _, err := zipSummary.Write(redactor.Bytes(combinedSummary.Bytes())); err != nil {
 			return errors.Join(fmt.Errorf("writing doctor.log: %w", err), closeFlares())
 		}
 
@@ -283,19 +334,76 @@ func RunFlare(ctx context.Context, k types.Knapsack, flareStream io.WriteCloser,
 		return errors.Join(fmt.Errorf("writing flare environment: %w", err), close())
 	}
 
+	flareTime := time.Now()
+	scope := flareScopeFor(k.RootDirectory(), options, &combinedSummary)
+	scope.redactor = redactor
+
+	if len(options.sections) > 0 {
+		writeSummary(&combinedSummary, Informational, "flare", fmt.Sprintf("collecting only sections: %s", strings.Join(options.sections, ", ")))
+	}
+
+	collected := make(map[string]bool)
 	for _, c := range checkupsFor(k, flareSupported) {
-		flareCheckup(ctx, c, &combinedSummary, flare)
+		if !options.includesSection(c.Name()) {
+			continue
+		}
+		collected[strings.ToLower(c.Name())] = true
+		flareCheckup(ctx, c, &combinedSummary, flare, scope)
 		if err := flare.Flush(); err != nil {
 			return errors.Join(fmt.Errorf("writing flare zip: %w", err), close())
 		}
 	}
 
+	for _, s := range options.sections {
+		if !collected[strings.ToLower(s)] {
+			writeSummary(&combinedSummary, Warning, "flare", fmt.Sprintf("no section named %s", s))
+		}
+	}
+
 	// note we do not check errors or do anything to complicate the normal flare process
 	// from the multiple installation check. this is not (at this time) an expected production complication
 	noteMultipleInstallations(flare)
 
 	// we could defer this close, but we want to return any errors
-	return close()
+	if err := close(); err != nil {
+		return err
+	}
+
+	// Now that the flare is complete, record what it collected, for the next one
+	if k.RootDirectory() == "" {
+		return nil
+	}
+	if err := writeFlareHistory(k.RootDirectory(), scope.history(flareTime)); err != nil {
+		return fmt.Errorf("flare is complete, but could not record it for the next: %w", err)
+	}
+	return nil
+}
+
+// flareScopeFor sets up what the flare collects from its options and, for a delta flare, what the
+// previous flare collected, noting the scope in the flare's summary.
+func flareScopeFor(rootDirectory string, options *flareOptions, combinedSummary io.Writer) *flareScope {
+	var previous *flareHistory
+	if rootDirectory != "" {
+		var err error
+		previous, err = readFlareHistory(rootDirectory)
+		if err != nil {
+			writeSummary(combinedSummary, Warning, "flare", fmt.Sprintf("could not read what the previous flare collected: %s", err))
+		}
+	}
+
+	since := options.since
+	if options.sinceLast {
+		if previous != nil {
+			since = previous.Time
+		} else {
+			writeSummary(combinedSummary, Informational, "flare", "no previous flare to collect changes since, collecting everything")
+		}
+	}
+	if !since.IsZero() {
+		writeSummary(combinedSummary, Informational, "flare", fmt.Sprintf("collecting changes since %s", since.Format(time.RFC3339)))
+	}
+
+	return newFlareScope(since, previous, options.sectionBudget)
 }
 
 // noteMultipleInstallations checks for whether the results of running flare for this installation may be complicated
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/ipstack"
 	"github.com/pkg/errors"
 )
 
@@ -32,7 +33,7 @@ func (c *Connectivity) Run(ctx context.Context, extraFH io.Writer) error {
 		return nil
 	}
 
-	httpClient := &http.Client{Timeout: requestTimeout}
+	httpClient := ipstack.NewClient(requestTimeout)
 
 	hosts := map[string]string{
 		"device":  c.k.KolideServerURL(),
@@ -0,0 +1,83 @@
+package checkups
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/debug/crashreport"
+)
+
+// recentCrashWindow is how far back a crash is considered recent, for the checkup status
+const recentCrashWindow = 24 * time.Hour
+
+type crashReportsCheckup struct {
+	k       types.Knapsack
+	status  Status
+	summary string
+	data    map[string]any
+	section *flareSection
+}
+
+func (c *crashReportsCheckup) Data() any             { return c.data }
+func (c *crashReportsCheckup) ExtraFileName() string { return "crashes.zip" }
+func (c *crashReportsCheckup) Name() string          { return "Crash Reports" }
+func (c *crashReportsCheckup) Status() Status        { return c.status }
+func (c *crashReportsCheckup) Summary() string       { return c.summary }
+
+func (c *crashReportsCheckup) setFlareSection(section *flareSection) { c.section = section }
+
+func (c *crashReportsCheckup) Run(_ context.Context, extraFH io.Writer) error {
+	c.data = make(map[string]any)
+
+	reports, err := crashreport.List(c.k.RootDirectory())
+	if err != nil {
+		c.status = Erroring
+		c.summary = "unable to list crash reports"
+		c.data["error"] = err.Error()
+		return nil
+	}
+
+	recentCount := 0
+	for _, report := range reports {
+		if time.Since(report.Time) < recentCrashWindow {
+			recentCount += 1
+		}
+	}
+
+	c.data["crash_count"] = len(reports)
+	c.data["recent_crash_count"] = recentCount
+
+	if len(reports) == 0 {
+		c.status = Passing
+		c.summary = "no crash reports"
+		return nil
+	}
+
+	latest := reports[len(reports)-1]
+	c.data["latest_crash"] = latest.Time.Format(time.RFC3339)
+
+	c.status = Informational
+	if recentCount > 0 {
+		c.status = Warning
+	}
+	c.summary = fmt.Sprintf("%d crash reports (%d in the last 24 hours), most recent at %s", len(reports), recentCount, latest.Time.Format(time.RFC3339))
+
+	if extraFH == io.Discard {
+		return nil
+	}
+
+	crashZip := zip.NewWriter(extraFH)
+	defer crashZip.Close()
+
+	for _, report := range reports {
+		if err := c.section.addFile(crashZip, report.Path); err != nil {
+			return fmt.Errorf("adding %s to zip: %w", report.Path, err)
+		}
+	}
+
+	return nil
+}
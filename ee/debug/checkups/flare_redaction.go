@@ -0,0 +1,136 @@
+package checkups
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/log/redact"
+)
+
+// binarySniffBytes is how much of a file we look at to tell whether it's text, which we mask,
+// or binary, like a profile or a core dump, which we leave alone.
+const binarySniffBytes = 8192
+
+// WithRedactionClasses masks the given classes of sensitive value in the flare, in addition to
+// those launcher is configured to mask in its logs.
+func WithRedactionClasses(classes []string) FlareOption {
+	return func(o *flareOptions) {
+		o.redactionClasses = append(o.redactionClasses, classes...)
+	}
+}
+
+// flareRedactorFor returns the redactor for the classes launcher is configured to mask, and the
+// flare's options add, noting them in the flare's summary. It returns nil if there are none.
+func flareRedactorFor(k types.Knapsack, options *flareOptions, combinedSummary io.Writer) *redact.Redactor {
+	redactor, errs := redact.New(append(k.LogRedactionClasses(), options.redactionClasses...))
+	for _, err := range errs {
+		writeSummary(combinedSummary, Warning, "flare", fmt.Sprintf("not redacting: %s", err))
+	}
+	if redactor != nil {
+		writeSummary(combinedSummary, Informational, "flare", fmt.Sprintf("redacting %s", strings.Join(redactor.Classes(), ", ")))
+	}
+	return redactor
+}
+
+// runFlareCheckup runs the checkup, masking what it writes to its extra file with the redactor.
+// Zip files are written to a temporary file first, then copied with each text file in them
+// masked.
+func runFlareCheckup(ctx context.Context, c Checkup, extraFH io.Writer, redactor *redact.Redactor) error {
+	// Checkups skip collecting extra data when writing to io.Discard, so it's passed on as is
+	if redactor == nil || extraFH == io.Discard {
+		return c.Run(ctx, extraFH)
+	}
+
+	if path.Ext(c.ExtraFileName()) != ".zip" {
+		redactedFH := redact.NewWriter(extraFH, redactor)
+		if err := c.Run(ctx, redactedFH); err != nil {
+			return err
+		}
+		return redactedFH.Flush()
+	}
+
+	tmp, err := os.CreateTemp("", "flare-section-*.zip")
+	if err != nil {
+		return fmt.Errorf("creating temporary file to redact %s: %w", c.ExtraFileName(), err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := c.Run(ctx, tmp); err != nil {
+		return err
+	}
+
+	if err := redactZip(tmp, extraFH, redactor); err != nil {
+		return fmt.Errorf("redacting %s: %w", c.ExtraFileName(), err)
+	}
+	return nil
+}
+
+// redactZip copies the zip in src to dst, masking its text files with the redactor. Binary
+// files, including nested zips, are copied as they are.
+func redactZip(src *os.File, dst io.Writer, redactor *redact.Redactor) error {
+	fi, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("stating zip: %w", err)
+	}
+	if fi.Size() == 0 {
+		// The checkup had nothing to add
+		return nil
+	}
+
+	zr, err := zip.NewReader(src, fi.Size())
+	if err != nil {
+		return fmt.Errorf("reading zip: %w", err)
+	}
+
+	zw := zip.NewWriter(dst)
+	for _, f := range zr.File {
+		if err := redactZipEntry(zw, f, redactor); err != nil {
+			return errors.Join(fmt.Errorf("redacting %s: %w", f.Name, err), zw.Close())
+		}
+	}
+
+	return zw.Close()
+}
+
+func redactZipEntry(zw *zip.Writer, f *zip.File, redactor *redact.Redactor) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("opening: %w", err)
+	}
+	defer rc.Close()
+
+	reader := bufio.NewReaderSize(rc, binarySniffBytes)
+	head, _ := reader.Peek(binarySniffBytes)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return zw.Copy(f)
+	}
+
+	header := &zip.FileHeader{
+		Name:     f.Name,
+		Comment:  f.Comment,
+		Method:   f.Method,
+		Modified: f.Modified,
+	}
+	header.SetMode(f.Mode())
+
+	out, err := zw.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("creating: %w", err)
+	}
+
+	redactedOut := redact.NewWriter(out, redactor)
+	if _, err := io.Copy(redactedOut, reader); err != nil {
+		return fmt.Errorf("copying: %w", err)
+	}
+	return redactedOut.Flush()
+}
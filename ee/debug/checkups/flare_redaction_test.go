@@ -0,0 +1,88 @@
+package checkups
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/pkg/log/redact"
+	"github.com/stretchr/testify/require"
+)
+
+// zipCheckup writes a zip of a log and a binary file as its extra file.
+type zipCheckup struct{}
+
+var binaryContents = []byte("\x00\x01alice@example.com\x00")
+
+func (z *zipCheckup) Name() string { return "zipped" }
+func (z *zipCheckup) Run(_ context.Context, extraWriter io.Writer) error {
+	if extraWriter == io.Discard {
+		return nil
+	}
+
+	extraZip := zip.NewWriter(extraWriter)
+	defer extraZip.Close()
+
+	logFH, err := extraZip.Create("debug.json")
+	if err != nil {
+		return err
+	}
+	if _, err := logFH.Write([]byte(`{"msg":"enrolled alice@example.com from 192.0.2.10"}` + "\n")); err != nil {
+		return err
+	}
+
+	binaryFH, err := extraZip.Create("cpu.pprof")
+	if err != nil {
+		return err
+	}
+	_, err = binaryFH.Write(binaryContents)
+	return err
+}
+func (z *zipCheckup) ExtraFileName() string { return "logs.zip" }
+func (z *zipCheckup) Summary() string       { return "owned by alice@example.com" }
+func (z *zipCheckup) Status() Status        { return Informational }
+func (z *zipCheckup) Data() any             { return map[string]string{"owner": "alice@example.com"} }
+
+func TestFlareCheckup_Redacted(t *testing.T) {
+	t.Parallel()
+
+	redactor, errs := redact.New([]string{redact.ClassEmails, redact.ClassIPs})
+	require.Empty(t, errs)
+	scope := newFlareScope(time.Time{}, nil, DefaultSectionBudget)
+	scope.redactor = redactor
+
+	var flareBuf, combinedSummary bytes.Buffer
+	flare := zip.NewWriter(&flareBuf)
+	flareCheckup(context.TODO(), &zipCheckup{}, &combinedSummary, flare, scope)
+	flareCheckup(context.TODO(), &stubCheckup{status: Passing}, &combinedSummary, flare, scope)
+	require.NoError(t, flare.Close())
+
+	require.NotContains(t, combinedSummary.String(), "alice@example.com")
+	require.Contains(t, combinedSummary.String(), "owned by [REDACTED:emails]")
+
+	contents := readZip(t, flareBuf.Bytes())
+
+	require.Equal(t, "extra", contents["stub/extra.txt"])
+	require.Contains(t, contents["zipped/summary.log"], "owned by [REDACTED:emails]")
+	require.Equal(t, `{"owner":"[REDACTED:emails]"}`+"\n", contents["zipped/data.json"])
+
+	section := readZip(t, []byte(contents["zipped/logs.zip"]))
+	require.Equal(t, `{"msg":"enrolled [REDACTED:emails] from [REDACTED:ips]"}`+"\n", section["debug.json"])
+	require.Equal(t, string(binaryContents), section["cpu.pprof"], "binary files should be copied as they are")
+}
+
+func TestFlareCheckup_Discard(t *testing.T) {
+	t.Parallel()
+
+	redactor, errs := redact.New([]string{redact.ClassEmails})
+	require.Empty(t, errs)
+
+	// Checkups rely on seeing io.Discard to skip collecting extra data
+	var extra bytes.Buffer
+	require.NoError(t, runFlareCheckup(context.TODO(), &zipCheckup{}, io.Discard, redactor))
+	require.NoError(t, runFlareCheckup(context.TODO(), &stubCheckup{}, &extra, redactor))
+	require.Equal(t, "extra", extra.String())
+}
@@ -0,0 +1,429 @@
+package checkups
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/pkg/log/redact"
+)
+
+const (
+	// DefaultSectionBudget bounds the size of each flare section -- the extra file a checkup
+	// writes -- so that flares from long-lived hosts stay within upload limits.
+	DefaultSectionBudget = 20 * 1024 * 1024
+
+	// minTruncatedFileBytes is the least of a file worth collecting, once a section's budget
+	// is nearly spent. Below it, the file is skipped instead.
+	minTruncatedFileBytes = 64 * 1024
+
+	// fingerprintBytes is how much of the start of a file we hash to tell whether a file is the
+	// one the previous flare collected, or a replacement, e.g. after log rotation.
+	fingerprintBytes = 4096
+
+	// flareHistoryFilename records what the previous flare collected, in the root directory.
+	flareHistoryFilename = "flare_history.json"
+
+	// sinceLastFlare is the value of the since option that selects the previous flare's time
+	sinceLastFlare = "last"
+)
+
+type FlareOption func(*flareOptions)
+
+type flareOptions struct {
+	since            time.Time
+	sinceLast        bool
+	sectionBudget    int64
+	redactionClasses []string
+	sections         []string
+}
+
+// WithSince limits the flare to files changed since the given time.
+func WithSince(since time.Time) FlareOption {
+	return func(o *flareOptions) {
+		o.since = since
+	}
+}
+
+// WithSinceLastFlare limits the flare to what changed since the previous flare from this
+// installation. If there was none, the flare is complete.
+func WithSinceLastFlare() FlareOption {
+	return func(o *flareOptions) {
+		o.sinceLast = true
+	}
+}
+
+// WithSectionBudget sets the size budget, in bytes, for each section of the flare. Zero removes
+// the budget.
+func WithSectionBudget(budget int64) FlareOption {
+	return func(o *flareOptions) {
+		o.sectionBudget = budget
+	}
+}
+
+// WithSections limits the flare to the named sections, matched case-insensitively against
+// the checkup names that head each section, e.g. `Logs` or `Osquery`. The summary of what the
+// flare collected is always included.
+func WithSections(sections []string) FlareOption {
+	return func(o *flareOptions) {
+		for _, s := range sections {
+			if s = strings.TrimSpace(s); s != "" {
+				o.sections = append(o.sections, s)
+			}
+		}
+	}
+}
+
+// includesSection reports whether the flare collects the named section.
+func (o *flareOptions) includesSection(name string) bool {
+	if len(o.sections) == 0 {
+		return true
+	}
+	for _, s := range o.sections {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSince parses the value of flare's since option: `last`, for the previous flare, an
+// RFC3339 time, or a duration before now, like `1h`. An empty value selects a complete flare,
+// and returns no option.
+func ParseSince(value string) (FlareOption, error) {
+	switch value {
+	case "":
+		return nil, nil
+	case sinceLastFlare:
+		return WithSinceLastFlare(), nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return WithSince(time.Now().Add(-d)), nil
+	}
+
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf(`since must be "%s", an RFC3339 time, or a duration: %w`, sinceLastFlare, err)
+	}
+	return WithSince(since), nil
+}
+
+// flareHistory is what a flare collected, so that the next can collect only what changed.
+type flareHistory struct {
+	Time  time.Time                `json:"time"`
+	Files map[string]collectedFile `json:"files"`
+}
+
+type collectedFile struct {
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	Fingerprint string    `json:"fingerprint"`
+}
+
+// readFlareHistory returns the history the previous flare left, or nil if there was none.
+func readFlareHistory(rootDirectory string) (*flareHistory, error) {
+	raw, err := os.ReadFile(filepath.Join(rootDirectory, flareHistoryFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading flare history: %w", err)
+	}
+
+	var history flareHistory
+	if err := json.Unmarshal(raw, &history); err != nil {
+		return nil, fmt.Errorf("unmarshalling flare history: %w", err)
+	}
+	return &history, nil
+}
+
+func writeFlareHistory(rootDirectory string, history flareHistory) error {
+	raw, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("marshalling flare history: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(rootDirectory, flareHistoryFilename), raw, 0600); err != nil {
+		return fmt.Errorf("writing flare history: %w", err)
+	}
+	return nil
+}
+
+// flareScope is what a flare collects: everything, or only what changed since a previous flare,
+// within a size budget for each section, with sensitive values masked by the redactor.
+type flareScope struct {
+	since     time.Time
+	previous  map[string]collectedFile
+	budget    int64
+	collected map[string]collectedFile
+	redactor  *redact.Redactor
+}
+
+func newFlareScope(since time.Time, previous *flareHistory, budget int64) *flareScope {
+	s := &flareScope{
+		since:     since,
+		budget:    budget,
+		collected: make(map[string]collectedFile),
+	}
+	if previous != nil {
+		s.previous = previous.Files
+	}
+	return s
+}
+
+// delta reports whether the flare collects only what changed since a previous flare.
+func (s *flareScope) delta() bool {
+	return !s.since.IsZero()
+}
+
+// history records the files this flare saw, for the next. Files the previous flare saw, and
+// this one didn't look at, are carried forward.
+func (s *flareScope) history(flareTime time.Time) flareHistory {
+	files := make(map[string]collectedFile, len(s.previous)+len(s.collected))
+	for location, f := range s.previous {
+		files[location] = f
+	}
+	for location, f := range s.collected {
+		files[location] = f
+	}
+	return flareHistory{Time: flareTime, Files: files}
+}
+
+func (s *flareScope) section() *flareSection {
+	return &flareSection{
+		scope:     s,
+		remaining: s.budget,
+	}
+}
+
+// scopedCheckup is implemented by checkups that collect files for flare, so that they collect
+// only what changed since the previous flare, within their section's budget.
+type scopedCheckup interface {
+	setFlareSection(section *flareSection)
+}
+
+// flareSection tracks what a checkup collects against the flare's scope and its budget.
+type flareSection struct {
+	scope     *flareScope
+	remaining int64
+	unchanged int
+	truncated int
+	omitted   int
+}
+
+// since returns the time the flare collects changes since, or the zero time for a complete
+// flare. It's safe to call on a nil section.
+func (s *flareSection) since() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	return s.scope.since
+}
+
+// note describes what the section left out, for its summary.
+func (s *flareSection) note() string {
+	var notes []string
+	if s.unchanged > 0 {
+		notes = append(notes, fmt.Sprintf("skipped %d files unchanged since %s", s.unchanged, s.scope.since.Format(time.RFC3339)))
+	}
+	if s.truncated > 0 {
+		notes = append(notes, fmt.Sprintf("kept the start and end of %d files to fit the %d byte budget", s.truncated, s.scope.budget))
+	}
+	if s.omitted > 0 {
+		notes = append(notes, fmt.Sprintf("omitted %d files over the %d byte budget", s.omitted, s.scope.budget))
+	}
+	return strings.Join(notes, "; ")
+}
+
+// limit returns the most of a file or stream of the given length the section can take, or -1
+// for all of it. It returns 0 when what's left of the budget isn't worth spending.
+func (s *flareSection) limit(length int64) int64 {
+	if s.scope.budget <= 0 || length <= s.remaining {
+		return -1
+	}
+	if s.remaining < minTruncatedFileBytes {
+		return 0
+	}
+	return s.remaining
+}
+
+// addFile adds the file to the zip, as addFileToZip does. In a delta flare, files unchanged since
+// the previous flare are skipped, and of files that have only been appended to, like logs, only
+// what was appended is added. Files over the remaining budget keep their start and end. It's safe
+// to call on a nil section, which adds the whole file.
+func (s *flareSection) addFile(z *zip.Writer, location string) error {
+	if s == nil {
+		return addFileToZip(z, location)
+	}
+
+	fi, err := os.Stat(location)
+	if err != nil || fi.IsDir() {
+		// addFileToZip notes the error in the file's metadata
+		return addFileToZip(z, location)
+	}
+
+	current := collectedFile{
+		Size:        fi.Size(),
+		ModTime:     fi.ModTime(),
+		Fingerprint: fingerprint(location),
+	}
+
+	var offset int64
+	if s.scope.delta() {
+		if !fi.ModTime().After(s.scope.since) {
+			s.unchanged += 1
+			s.scope.collected[location] = current
+			return nil
+		}
+		if previous, ok := s.scope.previous[location]; ok && current.appendedTo(previous) {
+			offset = previous.Size
+		}
+	}
+
+	// Files we omit aren't recorded as collected, so that the next flare picks them up
+	limit := s.limit(fi.Size() - offset)
+	switch {
+	case limit == 0:
+		s.omitted += 1
+		return nil
+	case limit > 0:
+		s.truncated += 1
+	}
+	s.scope.collected[location] = current
+
+	written, err := addFileRangeToZip(z, location, offset, limit)
+	s.remaining -= written
+	return err
+}
+
+// addStream adds the stream to the zip, as addStreamToZip does, keeping its start and end if it's
+// over the remaining budget. It's safe to call on a nil section, which adds the whole stream.
+func (s *flareSection) addStream(z *zip.Writer, name string, modTime time.Time, contents []byte) error {
+	if s == nil {
+		return addStreamToZip(z, name, modTime, bytes.NewReader(contents))
+	}
+
+	limit := s.limit(int64(len(contents)))
+	switch {
+	case limit == 0:
+		s.omitted += 1
+		return nil
+	case limit > 0:
+		s.truncated += 1
+		head, tail := contents[:limit/2], contents[int64(len(contents))-(limit-limit/2):]
+		contents = bytes.Join([][]byte{head, truncationMarker(int64(len(contents)) - limit), tail}, nil)
+	}
+
+	s.remaining -= int64(len(contents))
+	return addStreamToZip(z, name, modTime, bytes.NewReader(contents))
+}
+
+// appendedTo reports whether the file is the previously collected one, with more appended to it.
+func (f collectedFile) appendedTo(previous collectedFile) bool {
+	return f.Fingerprint != "" &&
+		f.Size >= previous.Size &&
+		previous.Size >= fingerprintBytes &&
+		f.Fingerprint == previous.Fingerprint
+}
+
+// fingerprint hashes the start of the file, or returns an empty string if the file is too short
+// to tell apart from its replacement.
+func fingerprint(location string) string {
+	fh, err := os.Open(location)
+	if err != nil {
+		return ""
+	}
+	defer fh.Close()
+
+	hasher := sha256.New()
+	if n, err := io.CopyN(hasher, fh, fingerprintBytes); err != nil || n < fingerprintBytes {
+		return ""
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func truncationMarker(truncatedBytes int64) []byte {
+	return []byte(fmt.Sprintf("\n\n... flare truncated %d bytes ...\n\n", truncatedBytes))
+}
+
+// headTailWriter keeps the start and end of what's written to it, within a budget, and drops
+// the middle. The end is buffered until Flush.
+type headTailWriter struct {
+	w         io.Writer
+	headLimit int64
+	tailLimit int64
+	written   int64
+	tail      []byte
+	err       error
+}
+
+func newHeadTailWriter(w io.Writer, budget int64) *headTailWriter {
+	return &headTailWriter{
+		w:         w,
+		headLimit: budget / 2,
+		tailLimit: budget - budget/2,
+	}
+}
+
+func (h *headTailWriter) Write(p []byte) (int, error) {
+	if h.err != nil {
+		return 0, h.err
+	}
+
+	n := len(p)
+	if remainingHead := h.headLimit - h.written; remainingHead > 0 {
+		head := p
+		if int64(len(head)) > remainingHead {
+			head = head[:remainingHead]
+		}
+		if _, err := h.w.Write(head); err != nil {
+			h.err = err
+			return 0, err
+		}
+		h.written += int64(len(head))
+		p = p[len(head):]
+	}
+
+	h.written += int64(len(p))
+	h.tail = append(h.tail, p...)
+	// Trim occasionally, rather than on every write
+	if int64(len(h.tail)) > 2*h.tailLimit {
+		h.tail = append(h.tail[:0], h.tail[int64(len(h.tail))-h.tailLimit:]...)
+	}
+
+	return n, nil
+}
+
+// Flush writes the end of what was written, after a marker noting how much was dropped.
+func (h *headTailWriter) Flush() error {
+	if h.err != nil {
+		return h.err
+	}
+
+	if int64(len(h.tail)) > h.tailLimit {
+		h.tail = h.tail[int64(len(h.tail))-h.tailLimit:]
+	}
+	if dropped := h.written - h.headLimit - int64(len(h.tail)); dropped > 0 {
+		if _, err := h.w.Write(truncationMarker(dropped)); err != nil {
+			return err
+		}
+	}
+	if _, err := h.w.Write(h.tail); err != nil {
+		return err
+	}
+	h.tail = nil
+	return nil
+}
+
+// truncated reports whether the writer dropped anything.
+func (h *headTailWriter) truncated() bool {
+	return h.written > h.headLimit+h.tailLimit
+}
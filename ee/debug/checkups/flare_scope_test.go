@@ -0,0 +1,206 @@
+package checkups
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince(t *testing.T) {
+	t.Parallel()
+
+	opt, err := ParseSince("")
+	require.NoError(t, err)
+	require.Nil(t, opt)
+
+	var options flareOptions
+	opt, err = ParseSince("last")
+	require.NoError(t, err)
+	opt(&options)
+	require.True(t, options.sinceLast)
+
+	options = flareOptions{}
+	opt, err = ParseSince("2024-03-01T12:00:00Z")
+	require.NoError(t, err)
+	opt(&options)
+	require.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), options.since)
+
+	options = flareOptions{}
+	opt, err = ParseSince("1h")
+	require.NoError(t, err)
+	opt(&options)
+	require.WithinDuration(t, time.Now().Add(-time.Hour), options.since, time.Minute)
+
+	_, err = ParseSince("yesterday")
+	require.Error(t, err)
+
+	_, err = ParseSince("-1h")
+	require.Error(t, err)
+}
+
+func TestWithSections(t *testing.T) {
+	t.Parallel()
+
+	var options flareOptions
+	require.True(t, options.includesSection("Logs"), "every section is included by default")
+
+	WithSections([]string{"logs", " Osquery ", ""})(&options)
+	require.Equal(t, []string{"logs", "Osquery"}, options.sections)
+	require.True(t, options.includesSection("Logs"))
+	require.True(t, options.includesSection("Osquery"))
+	require.False(t, options.includesSection("Osquery Data"))
+}
+
+func TestHeadTailWriter(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testCaseName string
+		writes       []string
+		budget       int64
+		expected     string
+	}{
+		{
+			testCaseName: "under budget",
+			writes:       []string{"abc", "def"},
+			budget:       10,
+			expected:     "abcdef",
+		},
+		{
+			testCaseName: "over budget",
+			writes:       []string{"0123", "4567", "89"},
+			budget:       4,
+			expected:     "01" + string(truncationMarker(6)) + "89",
+		},
+		{
+			testCaseName: "many small writes",
+			writes:       strings.Split("abcdefghijklmnopqrstuvwxyz", ""),
+			budget:       6,
+			expected:     "abc" + string(truncationMarker(20)) + "xyz",
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w := newHeadTailWriter(&buf, tt.budget)
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				require.NoError(t, err)
+				require.Equal(t, len(s), n)
+			}
+			require.NoError(t, w.Flush())
+			require.Equal(t, tt.expected, buf.String())
+			require.Equal(t, tt.expected != strings.Join(tt.writes, ""), w.truncated())
+		})
+	}
+}
+
+func TestFlareSection_addFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	unchanged := filepath.Join(dir, "unchanged.log")
+	appended := filepath.Join(dir, "appended.log")
+	rotated := filepath.Join(dir, "rotated.log")
+	large := filepath.Join(dir, "large.log")
+
+	previousFlare := time.Now().Add(-time.Hour)
+	original := bytes.Repeat([]byte("a"), fingerprintBytes)
+	for _, location := range []string{unchanged, appended, rotated} {
+		require.NoError(t, os.WriteFile(location, original, 0644))
+	}
+	previous := &flareHistory{Time: previousFlare, Files: make(map[string]collectedFile)}
+	for _, location := range []string{unchanged, appended, rotated} {
+		previous.Files[location] = collectedFile{Size: int64(len(original)), ModTime: previousFlare, Fingerprint: fingerprint(location)}
+	}
+	require.NoError(t, os.Chtimes(unchanged, previousFlare.Add(-time.Minute), previousFlare.Add(-time.Minute)))
+
+	require.NoError(t, os.WriteFile(appended, append(original, []byte("appended")...), 0644))
+	require.NoError(t, os.WriteFile(rotated, bytes.Repeat([]byte("b"), fingerprintBytes), 0644))
+	require.NoError(t, os.WriteFile(large, bytes.Repeat([]byte("c"), 3*minTruncatedFileBytes), 0644))
+
+	scope := newFlareScope(previousFlare, previous, 2*minTruncatedFileBytes)
+	section := scope.section()
+
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for _, location := range []string{unchanged, appended, rotated, large} {
+		require.NoError(t, section.addFile(z, location))
+	}
+	require.NoError(t, z.Close())
+
+	contents := readZip(t, buf.Bytes())
+	require.NotContains(t, contents, filepath.Join(".", unchanged))
+	require.Equal(t, "appended", contents[filepath.Join(".", appended)])
+	require.Equal(t, strings.Repeat("b", fingerprintBytes), contents[filepath.Join(".", rotated)])
+	require.Contains(t, contents[filepath.Join(".", large)], "... flare truncated")
+	require.True(t, strings.HasPrefix(contents[filepath.Join(".", large)], "ccc"))
+	require.True(t, strings.HasSuffix(contents[filepath.Join(".", large)], "ccc"))
+
+	require.Equal(t, 1, section.unchanged)
+	require.Equal(t, 1, section.truncated)
+	require.Equal(t, 0, section.omitted)
+	require.NotEmpty(t, section.note())
+
+	// The section's budget is spent, so further files are omitted, and left for the next flare
+	another := filepath.Join(dir, "another.log")
+	require.NoError(t, os.WriteFile(another, bytes.Repeat([]byte("d"), minTruncatedFileBytes), 0644))
+	require.NoError(t, section.addFile(zip.NewWriter(io.Discard), another))
+	require.Equal(t, 1, section.omitted)
+
+	history := scope.history(time.Now())
+	require.Contains(t, history.Files, unchanged)
+	require.Contains(t, history.Files, large)
+	require.NotContains(t, history.Files, another)
+}
+
+func TestFlareHistory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	history, err := readFlareHistory(dir)
+	require.NoError(t, err)
+	require.Nil(t, history)
+
+	expected := flareHistory{
+		Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Files: map[string]collectedFile{
+			"/var/log/launcher.log": {Size: 10, ModTime: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), Fingerprint: "abc"},
+		},
+	}
+	require.NoError(t, writeFlareHistory(dir, expected))
+
+	history, err = readFlareHistory(dir)
+	require.NoError(t, err)
+	require.Equal(t, &expected, history)
+}
+
+// readZip returns the contents of each file in the zip, other than flare metadata
+func readZip(t *testing.T, raw []byte) map[string]string {
+	r, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	require.NoError(t, err)
+
+	contents := make(map[string]string)
+	for _, f := range r.File {
+		if strings.HasSuffix(f.Name, ".flaremeta") {
+			continue
+		}
+		fh, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(fh)
+		require.NoError(t, err)
+		fh.Close()
+		contents[f.Name] = string(b)
+	}
+	return contents
+}
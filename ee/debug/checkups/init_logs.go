@@ -9,6 +9,7 @@ import (
 type InitLogs struct {
 	status  Status
 	summary string
+	section *flareSection
 }
 
 func (c *InitLogs) Name() string {
@@ -26,7 +27,11 @@ func (c *InitLogs) Run(ctx context.Context, fullFH io.Writer) error {
 	logZip := zip.NewWriter(fullFH)
 	defer logZip.Close()
 
-	return writeInitLogs(ctx, logZip)
+	return writeInitLogs(ctx, logZip, c.section)
+}
+
+func (c *InitLogs) setFlareSection(section *flareSection) {
+	c.section = section
 }
 
 func (c *InitLogs) Status() Status {
@@ -7,7 +7,7 @@ import (
 	"path/filepath"
 )
 
-func writeInitLogs(_ context.Context, logZip *zip.Writer) error {
+func writeInitLogs(_ context.Context, logZip *zip.Writer, section *flareSection) error {
 	stdMatches, err := filepath.Glob("/var/log/kolide-k2/*")
 	if err != nil {
 		return fmt.Errorf("globbing /var/log/kolide-k2/*: %w", err)
@@ -15,7 +15,7 @@ func writeInitLogs(_ context.Context, logZip *zip.Writer) error {
 
 	var lastErr error
 	for _, f := range stdMatches {
-		if err := addFileToZip(logZip, f); err != nil {
+		if err := section.addFile(logZip, f); err != nil {
 			lastErr = err
 		}
 	}
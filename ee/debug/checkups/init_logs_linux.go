@@ -2,7 +2,6 @@ package checkups
 
 import (
 	"archive/zip"
-	"bytes"
 	"context"
 	"fmt"
 	"time"
@@ -10,8 +9,13 @@ import (
 	"github.com/kolide/launcher/ee/allowedcmd"
 )
 
-func writeInitLogs(ctx context.Context, logZip *zip.Writer) error {
-	cmd, err := allowedcmd.Journalctl(ctx, "-u", "launcher.kolide-k2.service")
+func writeInitLogs(ctx context.Context, logZip *zip.Writer, section *flareSection) error {
+	args := []string{"-u", "launcher.kolide-k2.service"}
+	if since := section.since(); !since.IsZero() {
+		args = append(args, "--since", fmt.Sprintf("@%d", since.Unix()))
+	}
+
+	cmd, err := allowedcmd.Journalctl(ctx, args...)
 	if err != nil {
 		return fmt.Errorf("creating journalctl command: %w", err)
 	}
@@ -21,5 +25,5 @@ func writeInitLogs(ctx context.Context, logZip *zip.Writer) error {
 		return fmt.Errorf("creating linux_journalctl_launcher_logs.json: %w", err)
 	}
 
-	return addStreamToZip(logZip, "linux_journalctl_launcher_logs.json", time.Now(), bytes.NewReader(output))
+	return section.addStream(logZip, "linux_journalctl_launcher_logs.json", time.Now(), output)
 }
@@ -2,7 +2,6 @@ package checkups
 
 import (
 	"archive/zip"
-	"bytes"
 	"context"
 	"fmt"
 	"time"
@@ -10,8 +9,13 @@ import (
 	"github.com/kolide/launcher/ee/allowedcmd"
 )
 
-func writeInitLogs(ctx context.Context, logZip *zip.Writer) error {
-	cmdStr := `Get-WinEvent -FilterHashtable @{LogName='Application'; ProviderName='launcher'} | ConvertTo-Json`
+func writeInitLogs(ctx context.Context, logZip *zip.Writer, section *flareSection) error {
+	filter := `LogName='Application'; ProviderName='launcher'`
+	if since := section.since(); !since.IsZero() {
+		filter += fmt.Sprintf(`; StartTime=[DateTime]'%s'`, since.UTC().Format(time.RFC3339))
+	}
+
+	cmdStr := fmt.Sprintf(`Get-WinEvent -FilterHashtable @{%s} | ConvertTo-Json`, filter)
 	cmd, err := allowedcmd.Powershell(ctx, cmdStr)
 	if err != nil {
 		return fmt.Errorf("creating powershell command: %w", err)
@@ -23,5 +27,5 @@ func writeInitLogs(ctx context.Context, logZip *zip.Writer) error {
 		return fmt.Errorf("creating windows_launcher_events.json: %w", err)
 	}
 
-	return addStreamToZip(logZip, "windows_launcher_events.json", time.Now(), bytes.NewReader(output))
+	return section.addStream(logZip, "windows_launcher_events.json", time.Now(), output)
 }
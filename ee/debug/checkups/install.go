@@ -11,7 +11,8 @@ import (
 )
 
 type installCheckup struct {
-	k types.Knapsack
+	k       types.Knapsack
+	section *flareSection
 }
 
 func (i *installCheckup) Name() string {
@@ -22,7 +23,7 @@ func (i *installCheckup) Run(ctx context.Context, extraWriter io.Writer) error {
 	extraZip := zip.NewWriter(extraWriter)
 	defer extraZip.Close()
 
-	if err := gatherInstallationLogs(extraZip); err != nil {
+	if err := gatherInstallationLogs(extraZip, i.section); err != nil {
 		return fmt.Errorf("gathering installation logs: %w", err)
 	}
 
@@ -34,6 +35,10 @@ func (i *installCheckup) Run(ctx context.Context, extraWriter io.Writer) error {
 
 }
 
+func (i *installCheckup) setFlareSection(section *flareSection) {
+	i.section = section
+}
+
 func (i *installCheckup) ExtraFileName() string {
 	return "install.zip"
 }
@@ -50,10 +55,10 @@ func (i *installCheckup) Data() any {
 	return nil
 }
 
-func gatherInstallationLogs(z *zip.Writer) error {
+func gatherInstallationLogs(z *zip.Writer, section *flareSection) error {
 	if runtime.GOOS == "windows" || runtime.GOOS == "linux" {
 		return nil
 	}
 
-	return addFileToZip(z, "/var/log/install.log")
+	return section.addFile(z, "/var/log/install.log")
 }
@@ -0,0 +1,153 @@
+package checkups
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/ipstack"
+)
+
+// ipStackCheckup checks that launcher can reach its servers over the address families the host
+// has: that on an IPv6-only host, the servers have IPv6 addresses, or the network has NAT64.
+type ipStackCheckup struct {
+	k              types.Knapsack
+	status         Status
+	summary        string
+	data           map[string]any
+	interfaceAddrs func() ([]net.Addr, error)
+	resolver       ipstack.Resolver
+}
+
+func (ic *ipStackCheckup) Data() any             { return ic.data }
+func (ic *ipStackCheckup) ExtraFileName() string { return "" }
+func (ic *ipStackCheckup) Name() string          { return "IP Stack" }
+func (ic *ipStackCheckup) Status() Status        { return ic.status }
+func (ic *ipStackCheckup) Summary() string       { return ic.summary }
+
+func (ic *ipStackCheckup) Run(ctx context.Context, extraFH io.Writer) error {
+	if ic.interfaceAddrs == nil {
+		ic.interfaceAddrs = net.InterfaceAddrs
+	}
+	if ic.resolver == nil {
+		ic.resolver = &net.Resolver{}
+	}
+
+	ic.data = make(map[string]any)
+
+	addrs, err := ic.interfaceAddrs()
+	if err != nil {
+		return fmt.Errorf("listing interface addresses: %w", err)
+	}
+	stack := ipstack.StackOf(addrs)
+	ic.data["stack"] = stack.String()
+
+	nat64Ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	prefixes, err := ipstack.DetectNAT64(nat64Ctx, ic.resolver)
+	cancel()
+	if err != nil {
+		ic.data["nat64"] = fmt.Sprintf("LOOKUP ERROR: %s", err.Error())
+	} else {
+		nat64Prefixes := make([]string, len(prefixes))
+		for i, p := range prefixes {
+			nat64Prefixes[i] = p.String()
+		}
+		ic.data["nat64"] = nat64Prefixes
+	}
+
+	if !stack.IPv4 && !stack.IPv6 {
+		ic.status = Failing
+		ic.summary = "no routable IPv4 or IPv6 addresses"
+		return nil
+	}
+
+	servers := map[string]string{
+		"device":  ic.k.KolideServerURL(),
+		"control": ic.k.ControlServerURL(),
+		"tuf":     ic.k.TufServerURL(),
+		"mirror":  ic.k.MirrorServerURL(),
+	}
+
+	var unreachable, unresolved []string
+	for name, server := range servers {
+		if strings.TrimSpace(server) == "" {
+			continue
+		}
+
+		serverUrl, err := parseUrl(ic.k, server)
+		if err != nil {
+			ic.data[name] = fmt.Sprintf("PARSE ERROR: %s", err.Error())
+			continue
+		}
+
+		serverAddrs, err := ic.lookup(ctx, serverUrl.Hostname())
+		if err != nil {
+			ic.data[name] = fmt.Sprintf("RESOLVE ERROR: %s", err.Error())
+			unresolved = append(unresolved, name)
+			continue
+		}
+
+		var ipv4, ipv6 int
+		for _, a := range serverAddrs {
+			if a.Unmap().Is4() {
+				ipv4 += 1
+			} else {
+				ipv6 += 1
+			}
+		}
+		ic.data[name] = map[string]any{
+			"host": serverUrl.Hostname(),
+			"ipv4": ipv4,
+			"ipv6": ipv6,
+		}
+
+		// With NAT64, an IPv6-only host reaches IPv4 addresses through the NAT64 prefix
+		reachable := (stack.IPv4 && ipv4 > 0) || (stack.IPv6 && (ipv6 > 0 || (ipv4 > 0 && len(prefixes) > 0)))
+		if !reachable {
+			unreachable = append(unreachable, name)
+		}
+	}
+	sort.Strings(unreachable)
+	sort.Strings(unresolved)
+
+	switch {
+	case len(unreachable) > 0:
+		ic.status = Failing
+		ic.summary = fmt.Sprintf("%s host has no route to: %s", stack, strings.Join(unreachable, ", "))
+	case len(unresolved) > 0:
+		ic.status = Warning
+		ic.summary = fmt.Sprintf("%s host could not resolve: %s", stack, strings.Join(unresolved, ", "))
+	case !stack.IPv4 && len(prefixes) > 0:
+		ic.status = Passing
+		ic.summary = fmt.Sprintf("%s host, with NAT64 through %s, can reach all servers", stack, prefixes[0])
+	default:
+		ic.status = Passing
+		ic.summary = fmt.Sprintf("%s host can reach all servers", stack)
+	}
+
+	return nil
+}
+
+// lookup resolves host, unless it's already an address literal.
+func (ic *ipStackCheckup) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	addrs, err := ic.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("host was valid but did not resolve")
+	}
+	return addrs, nil
+}
@@ -0,0 +1,164 @@
+package checkups
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+
+	typesMocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver resolves names from a map; names it doesn't have don't exist
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupNetIP(_ context.Context, network, host string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for _, a := range f[host] {
+		addr := netip.MustParseAddr(a)
+		if network == "ip6" && !addr.Is6() {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func Test_ipStackCheckup_Run(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ipv4Addrs = []net.Addr{&net.IPNet{IP: net.ParseIP("127.0.0.1")}, &net.IPNet{IP: net.ParseIP("192.168.1.20")}, &net.IPNet{IP: net.ParseIP("fe80::1")}}
+		ipv6Addrs = []net.Addr{&net.IPNet{IP: net.ParseIP("::1")}, &net.IPNet{IP: net.ParseIP("2001:db8::20")}, &net.IPNet{IP: net.ParseIP("fe80::1")}}
+		dualAddrs = append(append([]net.Addr{}, ipv4Addrs...), ipv6Addrs...)
+
+		// Servers with only IPv4 addresses, and with both
+		ipv4Servers = fakeResolver{
+			"device.example.com":  {"192.0.2.10"},
+			"control.example.com": {"192.0.2.11"},
+			"tuf.example.com":     {"192.0.2.12"},
+		}
+		dualServers = fakeResolver{
+			"device.example.com":  {"192.0.2.10", "2001:db8:1::10"},
+			"control.example.com": {"192.0.2.11", "2001:db8:1::11"},
+			"tuf.example.com":     {"192.0.2.12", "2001:db8:1::12"},
+		}
+		// DNS64 synthesizes IPv6 addresses for IPv4-only names
+		dns64Servers = fakeResolver{
+			"ipv4only.arpa":       {"64:ff9b::c000:aa", "64:ff9b::c000:ab"},
+			"device.example.com":  {"192.0.2.10", "64:ff9b::c000:20a"},
+			"control.example.com": {"192.0.2.11", "64:ff9b::c000:20b"},
+			"tuf.example.com":     {"192.0.2.12", "64:ff9b::c000:20c"},
+		}
+	)
+
+	for _, tt := range []struct {
+		testCaseName    string
+		interfaceAddrs  []net.Addr
+		resolver        fakeResolver
+		kolideServerURL string
+		expectedStatus  Status
+		expectedStack   string
+		expectedSummary string
+	}{
+		{
+			testCaseName:    "ipv4-only host, ipv4-only servers",
+			interfaceAddrs:  ipv4Addrs,
+			resolver:        ipv4Servers,
+			expectedStatus:  Passing,
+			expectedStack:   "ipv4-only",
+			expectedSummary: "ipv4-only host can reach all servers",
+		},
+		{
+			testCaseName:    "ipv6-only host, dual-stack servers",
+			interfaceAddrs:  ipv6Addrs,
+			resolver:        dualServers,
+			expectedStatus:  Passing,
+			expectedStack:   "ipv6-only",
+			expectedSummary: "ipv6-only host can reach all servers",
+		},
+		{
+			testCaseName:    "ipv6-only host, ipv4-only servers, no nat64",
+			interfaceAddrs:  ipv6Addrs,
+			resolver:        ipv4Servers,
+			expectedStatus:  Failing,
+			expectedStack:   "ipv6-only",
+			expectedSummary: "ipv6-only host has no route to: control, device, tuf",
+		},
+		{
+			testCaseName:    "ipv6-only host, ipv4-only servers, nat64",
+			interfaceAddrs:  ipv6Addrs,
+			resolver:        dns64Servers,
+			expectedStatus:  Passing,
+			expectedStack:   "ipv6-only",
+			expectedSummary: "ipv6-only host, with NAT64 through 64:ff9b::/96, can reach all servers",
+		},
+		{
+			testCaseName:    "ipv6-only host, ipv4 literal server, nat64",
+			interfaceAddrs:  ipv6Addrs,
+			resolver:        dns64Servers,
+			kolideServerURL: "192.0.2.10:443",
+			expectedStatus:  Passing,
+			expectedStack:   "ipv6-only",
+			expectedSummary: "ipv6-only host, with NAT64 through 64:ff9b::/96, can reach all servers",
+		},
+		{
+			testCaseName:    "ipv4-only host, ipv6 literal server",
+			interfaceAddrs:  ipv4Addrs,
+			resolver:        ipv4Servers,
+			kolideServerURL: "2001:db8:1::10",
+			expectedStatus:  Failing,
+			expectedStack:   "ipv4-only",
+			expectedSummary: "ipv4-only host has no route to: device",
+		},
+		{
+			testCaseName:    "dual-stack host, unresolvable server",
+			interfaceAddrs:  dualAddrs,
+			resolver:        fakeResolver{"control.example.com": {"192.0.2.11"}, "tuf.example.com": {"2001:db8:1::12"}},
+			expectedStatus:  Warning,
+			expectedStack:   "dual-stack",
+			expectedSummary: "dual-stack host could not resolve: device",
+		},
+		{
+			testCaseName:    "no network",
+			interfaceAddrs:  []net.Addr{&net.IPNet{IP: net.ParseIP("127.0.0.1")}, &net.IPNet{IP: net.ParseIP("::1")}},
+			resolver:        dualServers,
+			expectedStatus:  Failing,
+			expectedStack:   "none",
+			expectedSummary: "no routable IPv4 or IPv6 addresses",
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			kolideServerURL := tt.kolideServerURL
+			if kolideServerURL == "" {
+				kolideServerURL = "device.example.com"
+			}
+
+			k := typesMocks.NewKnapsack(t)
+			k.On("KolideServerURL").Return(kolideServerURL).Maybe()
+			k.On("ControlServerURL").Return("control.example.com").Maybe()
+			k.On("TufServerURL").Return("https://tuf.example.com").Maybe()
+			k.On("MirrorServerURL").Return("").Maybe()
+			k.On("InsecureTransportTLS").Return(false).Maybe()
+
+			ic := &ipStackCheckup{
+				k:              k,
+				interfaceAddrs: func() ([]net.Addr, error) { return tt.interfaceAddrs, nil },
+				resolver:       tt.resolver,
+			}
+			require.NoError(t, ic.Run(context.TODO(), io.Discard))
+
+			require.Equal(t, tt.expectedStatus, ic.Status())
+			require.Equal(t, tt.expectedSummary, ic.Summary())
+			require.Equal(t, tt.expectedStack, ic.data["stack"])
+		})
+	}
+}
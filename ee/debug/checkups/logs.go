@@ -15,6 +15,7 @@ type Logs struct {
 	k       types.Knapsack
 	status  Status
 	summary string
+	section *flareSection
 }
 
 func (c *Logs) Name() string {
@@ -48,7 +49,7 @@ func (c *Logs) Run(_ context.Context, fullFH io.Writer) error {
 	matches, _ := filepath.Glob(filepath.Join(c.k.RootDirectory(), "debug*"))
 
 	for _, f := range matches {
-		if err := addFileToZip(logZip, f); err != nil {
+		if err := c.section.addFile(logZip, f); err != nil {
 			return fmt.Errorf("adding %s to zip: %w", f, err)
 		}
 	}
@@ -57,6 +58,10 @@ func (c *Logs) Run(_ context.Context, fullFH io.Writer) error {
 
 }
 
+func (c *Logs) setFlareSection(section *flareSection) {
+	c.section = section
+}
+
 func (c *Logs) Status() Status {
 	return c.status
 }
@@ -0,0 +1,210 @@
+package checkups
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+const (
+	osqueryScheduleStatsQuery = `SELECT name, interval, executions, last_executed, denylisted, output_size, wall_time_ms, user_time, system_time, average_memory FROM osquery_schedule;`
+	osqueryEventsStatsQuery   = `SELECT name, publisher, type, subscriptions, events, refreshes, active FROM osquery_events;`
+
+	// worstOffendersCount is how many scheduled queries and event tables we report in each category
+	worstOffendersCount = 5
+)
+
+// osqueryStatsCheckup queries the running osquery instance's own performance tables, and
+// summarizes the scheduled queries and event tables that cost the most. This lets us spot a
+// bad pack from the log checkpoint, without running a live query.
+type osqueryStatsCheckup struct {
+	k       types.Knapsack
+	status  Status
+	summary string
+	data    map[string]any
+}
+
+type scheduledQueryStats struct {
+	Name               string `json:"name"`
+	Interval           int64  `json:"interval"`
+	Executions         int64  `json:"executions"`
+	LastExecuted       int64  `json:"last_executed"`
+	Denylisted         bool   `json:"denylisted"`
+	OutputSize         int64  `json:"output_size"`
+	WallTimeMs         int64  `json:"wall_time_ms"`
+	AverageWallTimeMs  int64  `json:"average_wall_time_ms"`
+	UserTime           int64  `json:"user_time"`
+	SystemTime         int64  `json:"system_time"`
+	AverageMemoryBytes int64  `json:"average_memory"`
+}
+
+type eventTableStats struct {
+	Name          string `json:"name"`
+	Publisher     string `json:"publisher"`
+	Subscriptions int64  `json:"subscriptions"`
+	Events        int64  `json:"events"`
+	Refreshes     int64  `json:"refreshes"`
+	Active        bool   `json:"active"`
+}
+
+func (osc *osqueryStatsCheckup) Data() any             { return osc.data }
+func (osc *osqueryStatsCheckup) ExtraFileName() string { return "" }
+func (osc *osqueryStatsCheckup) Name() string          { return "Osquery Performance" }
+func (osc *osqueryStatsCheckup) Status() Status        { return osc.status }
+func (osc *osqueryStatsCheckup) Summary() string       { return osc.summary }
+
+func (osc *osqueryStatsCheckup) Run(_ context.Context, _ io.Writer) error {
+	osc.data = make(map[string]any)
+
+	// These go through the running instance's extension socket -- outside of launcher, e.g. during
+	// doctor, there isn't one.
+	scheduleRows, err := osc.k.Query(osqueryScheduleStatsQuery)
+	if err != nil {
+		osc.status = Erroring
+		osc.summary = fmt.Sprintf("could not query osquery_schedule: %s", err)
+		osc.data["error"] = err.Error()
+		return nil
+	}
+
+	schedule := parseScheduleStats(scheduleRows)
+	osc.data["scheduled_queries"] = len(schedule)
+	osc.data["worst_by_wall_time"] = worstScheduledQueries(schedule, func(s scheduledQueryStats) int64 { return s.AverageWallTimeMs })
+	osc.data["worst_by_memory"] = worstScheduledQueries(schedule, func(s scheduledQueryStats) int64 { return s.AverageMemoryBytes })
+	osc.data["worst_by_output_size"] = worstScheduledQueries(schedule, func(s scheduledQueryStats) int64 { return s.OutputSize })
+
+	denylisted := make([]string, 0)
+	for _, s := range schedule {
+		if s.Denylisted {
+			denylisted = append(denylisted, s.Name)
+		}
+	}
+	osc.data["denylisted"] = denylisted
+
+	if eventRows, err := osc.k.Query(osqueryEventsStatsQuery); err != nil {
+		osc.data["events_error"] = err.Error()
+	} else {
+		osc.data["worst_by_events"] = worstEventTables(parseEventStats(eventRows))
+	}
+
+	if len(denylisted) > 0 {
+		osc.status = Warning
+		osc.summary = fmt.Sprintf("%d of %d scheduled queries denylisted by the watchdog: %s", len(denylisted), len(schedule), strings.Join(denylisted, ", "))
+		return nil
+	}
+
+	osc.status = Informational
+	osc.summary = fmt.Sprintf("%d scheduled queries, none denylisted", len(schedule))
+	if slowest := osc.data["worst_by_wall_time"].([]scheduledQueryStats); len(slowest) > 0 {
+		osc.summary += fmt.Sprintf("; slowest is %s at %d ms per execution", slowest[0].Name, slowest[0].AverageWallTimeMs)
+	}
+
+	return nil
+}
+
+func parseScheduleStats(rows []map[string]string) []scheduledQueryStats {
+	stats := make([]scheduledQueryStats, 0, len(rows))
+	for _, row := range rows {
+		s := scheduledQueryStats{
+			Name:               row["name"],
+			Interval:           parseStat(row["interval"]),
+			Executions:         parseStat(row["executions"]),
+			LastExecuted:       parseStat(row["last_executed"]),
+			Denylisted:         row["denylisted"] == "1",
+			OutputSize:         parseStat(row["output_size"]),
+			WallTimeMs:         parseStat(row["wall_time_ms"]),
+			UserTime:           parseStat(row["user_time"]),
+			SystemTime:         parseStat(row["system_time"]),
+			AverageMemoryBytes: parseStat(row["average_memory"]),
+		}
+
+		if s.Executions > 0 {
+			s.AverageWallTimeMs = s.WallTimeMs / s.Executions
+		}
+
+		stats = append(stats, s)
+	}
+
+	return stats
+}
+
+func parseEventStats(rows []map[string]string) []eventTableStats {
+	stats := make([]eventTableStats, 0, len(rows))
+	for _, row := range rows {
+		// Publishers are listed too, but the subscribers are the tables queries read from
+		if row["type"] != "subscriber" {
+			continue
+		}
+
+		stats = append(stats, eventTableStats{
+			Name:          row["name"],
+			Publisher:     row["publisher"],
+			Subscriptions: parseStat(row["subscriptions"]),
+			Events:        parseStat(row["events"]),
+			Refreshes:     parseStat(row["refreshes"]),
+			Active:        row["active"] == "1",
+		})
+	}
+
+	return stats
+}
+
+// worstScheduledQueries returns the scheduled queries with the highest nonzero value for the
+// given stat, highest first.
+func worstScheduledQueries(stats []scheduledQueryStats, stat func(scheduledQueryStats) int64) []scheduledQueryStats {
+	worst := make([]scheduledQueryStats, 0, worstOffendersCount)
+	for _, s := range stats {
+		if stat(s) > 0 {
+			worst = append(worst, s)
+		}
+	}
+
+	sort.SliceStable(worst, func(i, j int) bool {
+		if stat(worst[i]) != stat(worst[j]) {
+			return stat(worst[i]) > stat(worst[j])
+		}
+		return worst[i].Name < worst[j].Name
+	})
+
+	if len(worst) > worstOffendersCount {
+		worst = worst[:worstOffendersCount]
+	}
+
+	return worst
+}
+
+// worstEventTables returns the event tables that have buffered the most events, highest first.
+func worstEventTables(stats []eventTableStats) []eventTableStats {
+	worst := make([]eventTableStats, 0, worstOffendersCount)
+	for _, s := range stats {
+		if s.Events > 0 {
+			worst = append(worst, s)
+		}
+	}
+
+	sort.SliceStable(worst, func(i, j int) bool {
+		if worst[i].Events != worst[j].Events {
+			return worst[i].Events > worst[j].Events
+		}
+		return worst[i].Name < worst[j].Name
+	})
+
+	if len(worst) > worstOffendersCount {
+		worst = worst[:worstOffendersCount]
+	}
+
+	return worst
+}
+
+// parseStat parses one of osquery's integer columns, treating anything unparseable as zero.
+func parseStat(val string) int64 {
+	i, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return i
+}
@@ -0,0 +1,98 @@
+package checkups
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOsqueryStatsCheckup(t *testing.T) {
+	t.Parallel()
+
+	k := typesmocks.NewKnapsack(t)
+	k.On("Query", osqueryScheduleStatsQuery).Return([]map[string]string{
+		{"name": "pack:default:fast", "executions": "10", "wall_time_ms": "50", "average_memory": "1000", "output_size": "10", "denylisted": "0"},
+		{"name": "pack:default:slow", "executions": "2", "wall_time_ms": "9000", "average_memory": "500", "output_size": "4096", "denylisted": "0"},
+		{"name": "pack:default:hungry", "executions": "4", "wall_time_ms": "400", "average_memory": "900000000", "output_size": "0", "denylisted": "1"},
+		{"name": "pack:default:never_ran", "executions": "0", "wall_time_ms": "0", "average_memory": "0", "denylisted": "0"},
+	}, nil)
+	k.On("Query", osqueryEventsStatsQuery).Return([]map[string]string{
+		{"name": "inotify", "type": "publisher", "events": "100000"},
+		{"name": "file_events", "publisher": "inotify", "type": "subscriber", "events": "20000", "subscriptions": "3", "refreshes": "12", "active": "1"},
+		{"name": "process_events", "publisher": "auditeventpublisher", "type": "subscriber", "events": "0", "active": "0"},
+	}, nil)
+
+	c := &osqueryStatsCheckup{k: k}
+	require.NoError(t, c.Run(context.TODO(), io.Discard))
+
+	require.Equal(t, Warning, c.Status())
+	require.Equal(t, "1 of 4 scheduled queries denylisted by the watchdog: pack:default:hungry", c.Summary())
+
+	data := c.Data().(map[string]any)
+	require.Equal(t, 4, data["scheduled_queries"])
+	require.Equal(t, []string{"pack:default:hungry"}, data["denylisted"])
+
+	byWallTime := data["worst_by_wall_time"].([]scheduledQueryStats)
+	require.Len(t, byWallTime, 3)
+	require.Equal(t, "pack:default:slow", byWallTime[0].Name)
+	require.Equal(t, int64(4500), byWallTime[0].AverageWallTimeMs)
+	require.Equal(t, "pack:default:hungry", byWallTime[1].Name)
+	require.Equal(t, "pack:default:fast", byWallTime[2].Name)
+
+	byMemory := data["worst_by_memory"].([]scheduledQueryStats)
+	require.Equal(t, "pack:default:hungry", byMemory[0].Name)
+
+	byOutput := data["worst_by_output_size"].([]scheduledQueryStats)
+	require.Len(t, byOutput, 2)
+
+	require.Equal(t, []eventTableStats{
+		{Name: "file_events", Publisher: "inotify", Subscriptions: 3, Events: 20000, Refreshes: 12, Active: true},
+	}, data["worst_by_events"])
+}
+
+func TestOsqueryStatsCheckup_NoDenylisted(t *testing.T) {
+	t.Parallel()
+
+	k := typesmocks.NewKnapsack(t)
+	k.On("Query", osqueryScheduleStatsQuery).Return([]map[string]string{
+		{"name": "pack:default:slow", "executions": "3", "wall_time_ms": "300"},
+	}, nil)
+	k.On("Query", osqueryEventsStatsQuery).Return(nil, errors.New("no such table"))
+
+	c := &osqueryStatsCheckup{k: k}
+	require.NoError(t, c.Run(context.TODO(), io.Discard))
+
+	require.Equal(t, Informational, c.Status())
+	require.Equal(t, "1 scheduled queries, none denylisted; slowest is pack:default:slow at 100 ms per execution", c.Summary())
+	require.Equal(t, "no such table", c.Data().(map[string]any)["events_error"])
+}
+
+func TestOsqueryStatsCheckup_NoInstance(t *testing.T) {
+	t.Parallel()
+
+	k := typesmocks.NewKnapsack(t)
+	k.On("Query", osqueryScheduleStatsQuery).Return(nil, errors.New("no default instance exists, cannot query"))
+
+	c := &osqueryStatsCheckup{k: k}
+	require.NoError(t, c.Run(context.TODO(), io.Discard))
+
+	require.Equal(t, Erroring, c.Status())
+	require.Contains(t, c.Data(), "error")
+}
+
+func TestWorstScheduledQueries_Limit(t *testing.T) {
+	t.Parallel()
+
+	stats := make([]scheduledQueryStats, 0)
+	for i := int64(1); i <= worstOffendersCount+3; i++ {
+		stats = append(stats, scheduledQueryStats{Name: string(rune('a' + i)), AverageMemoryBytes: i})
+	}
+
+	worst := worstScheduledQueries(stats, func(s scheduledQueryStats) int64 { return s.AverageMemoryBytes })
+	require.Len(t, worst, worstOffendersCount)
+	require.Equal(t, int64(worstOffendersCount+3), worst[0].AverageMemoryBytes)
+}
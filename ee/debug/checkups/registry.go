@@ -0,0 +1,106 @@
+package checkups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+// ErrUnknownCheckup is returned when asking a Registry for a checkup it doesn't have
+var ErrUnknownCheckup = errors.New("unknown checkup")
+
+// Result is the structured outcome of running a single checkup.
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Summary  string        `json:"summary"`
+	Data     any           `json:"data,omitempty"`
+	Error    string        `json:"error,omitempty"` // set if the checkup was unable to run, in which case Status is Erroring
+	Duration time.Duration `json:"duration"`
+}
+
+// Registry gives access to the individual checkups supported on this platform, so that they
+// can be embedded in other tools without running the whole of doctor or flare.
+type Registry struct {
+	k types.Knapsack
+}
+
+func NewRegistry(k types.Knapsack) *Registry {
+	return &Registry{k: k}
+}
+
+// Names returns the names of the available checkups, in the order doctor and flare run them.
+func (r *Registry) Names() []string {
+	checkups := checkupsFor(r.k, allTargets)
+	names := make([]string, len(checkups))
+	for i, c := range checkups {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// Get returns a new instance of the named checkup, ignoring case. Checkups hold their results,
+// so each call returns a fresh one.
+func (r *Registry) Get(name string) (Checkup, error) {
+	for _, c := range checkupsFor(r.k, allTargets) {
+		if strings.EqualFold(c.Name(), name) {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCheckup, name)
+}
+
+// Run runs the named checkup. Any extra data the checkup produces for flare is written to
+// extraWriter, which may be nil to skip it.
+func (r *Registry) Run(ctx context.Context, name string, extraWriter io.Writer) (Result, error) {
+	c, err := r.Get(name)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return RunCheckup(ctx, c, extraWriter), nil
+}
+
+// RunAll runs every available checkup, without collecting extra data.
+func (r *Registry) RunAll(ctx context.Context) []Result {
+	checkups := checkupsFor(r.k, allTargets)
+	results := make([]Result, len(checkups))
+	for i, c := range checkups {
+		results[i] = RunCheckup(ctx, c, nil)
+	}
+	return results
+}
+
+// RunCheckup runs c, and collects its result. Extra data is written to extraWriter, which may be
+// nil to skip it.
+func RunCheckup(ctx context.Context, c Checkup, extraWriter io.Writer) Result {
+	if extraWriter == nil {
+		extraWriter = io.Discard
+	}
+
+	start := time.Now()
+	err := c.Run(ctx, extraWriter)
+	result := Result{
+		Name:     c.Name(),
+		Duration: time.Since(start),
+	}
+
+	if err != nil {
+		result.Status = Erroring
+		result.Summary = fmt.Sprintf("failed to run: %s", err)
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = c.Status()
+	result.Summary = c.Summary()
+	result.Data = c.Data()
+
+	return result
+}
@@ -0,0 +1,76 @@
+package checkups
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+
+	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry(typesmocks.NewKnapsack(t))
+
+	names := r.Names()
+	require.Contains(t, names, "Platform")
+
+	c, err := r.Get("platform")
+	require.NoError(t, err)
+	require.Equal(t, "Platform", c.Name())
+
+	_, err = r.Get("not a checkup")
+	require.ErrorIs(t, err, ErrUnknownCheckup)
+
+	_, err = r.Run(context.TODO(), "not a checkup", nil)
+	require.ErrorIs(t, err, ErrUnknownCheckup)
+
+	result, err := r.Run(context.TODO(), "Platform", nil)
+	require.NoError(t, err)
+	require.Equal(t, "Platform", result.Name)
+	require.Equal(t, Informational, result.Status)
+	require.Empty(t, result.Error)
+	require.Equal(t, runtime.GOOS, result.Data.(map[string]any)["platform"])
+}
+
+type stubCheckup struct {
+	err    error
+	status Status
+}
+
+func (s *stubCheckup) Name() string { return "stub" }
+func (s *stubCheckup) Run(_ context.Context, extraWriter io.Writer) error {
+	extraWriter.Write([]byte("extra"))
+	return s.err
+}
+func (s *stubCheckup) ExtraFileName() string { return "extra.txt" }
+func (s *stubCheckup) Summary() string       { return "stub summary" }
+func (s *stubCheckup) Status() Status        { return s.status }
+func (s *stubCheckup) Data() any             { return "stub data" }
+
+func TestRunCheckup(t *testing.T) {
+	t.Parallel()
+
+	var extra bytes.Buffer
+	result := RunCheckup(context.TODO(), &stubCheckup{status: Warning}, &extra)
+	require.Equal(t, Result{
+		Name:     "stub",
+		Status:   Warning,
+		Summary:  "stub summary",
+		Data:     "stub data",
+		Duration: result.Duration,
+	}, result)
+	require.Equal(t, "extra", extra.String())
+
+	// A nil writer discards extra data
+	result = RunCheckup(context.TODO(), &stubCheckup{err: errors.New("boom"), status: Passing}, nil)
+	require.Equal(t, Erroring, result.Status)
+	require.Equal(t, "boom", result.Error)
+	require.Equal(t, "failed to run: boom", result.Summary)
+	require.Nil(t, result.Data)
+}
@@ -12,6 +12,7 @@ import (
 	"runtime"
 
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/ipstack"
 	"github.com/kolide/launcher/ee/tuf"
 	"github.com/kolide/launcher/pkg/log/multislogger"
 )
@@ -58,7 +59,7 @@ func (tc *tufCheckup) Run(ctx context.Context, extraFH io.Writer) error {
 		return nil
 	}
 
-	httpClient := &http.Client{Timeout: requestTimeout}
+	httpClient := ipstack.NewClient(requestTimeout)
 
 	tufEndpoint := fmt.Sprintf("%s/repository/targets.json", tc.k.TufServerURL())
 	tufUrl, err := parseUrl(tc.k, tufEndpoint)
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/ipstack"
 	"golang.org/x/exp/maps"
 )
 
@@ -48,44 +49,63 @@ type fileInfo struct {
 	Mode    string    // file mode bits
 	ModTime time.Time // modification time
 	IsDir   bool      // abbreviation for Mode().IsDir()
+
+	Offset         int64 `json:",omitempty"` // where the collected contents start, if not at the beginning
+	TruncatedBytes int64 `json:",omitempty"` // how much was dropped from the middle to fit the flare's budget
 }
 
 // addFileToZip takes a file path, and a zip writer, and adds the file and some metadata.
 func addFileToZip(z *zip.Writer, location string) error {
+	_, err := addFileRangeToZip(z, location, 0, -1)
+	return err
+}
+
+// addFileRangeToZip adds the file and some metadata to the zip, as addFileToZip does, starting
+// from offset. If limit isn't negative, and the file is longer, only the first and last limit/2
+// bytes are added. It returns how many bytes of the file it added.
+func addFileRangeToZip(z *zip.Writer, location string, offset int64, limit int64) (int64, error) {
 	// Create metadata file first, keeping existing pattern
 	metaout, err := z.Create(filepath.Join(".", location+".flaremeta"))
 	if err != nil {
-		return fmt.Errorf("creating %s in zip: %w", location+".flaremeta", err)
+		return 0, fmt.Errorf("creating %s in zip: %w", location+".flaremeta", err)
 	}
 
 	// Get file info
 	fi, err := os.Stat(location)
 	if os.IsNotExist(err) || os.IsPermission(err) {
 		fmt.Fprintf(metaout, `{ "error stating file": "%s" }`, err)
-		return nil
+		return 0, nil
+	}
+
+	length := fi.Size() - offset
+	var truncatedBytes int64
+	if limit >= 0 && length > limit {
+		truncatedBytes = length - limit
 	}
 
 	// Marshal metadata
 	b, err := json.Marshal(fileInfo{
-		Name:    fi.Name(),
-		Size:    fi.Size(),
-		Mode:    fi.Mode().String(),
-		ModTime: fi.ModTime(),
-		IsDir:   fi.IsDir(),
+		Name:           fi.Name(),
+		Size:           fi.Size(),
+		Mode:           fi.Mode().String(),
+		ModTime:        fi.ModTime(),
+		IsDir:          fi.IsDir(),
+		Offset:         offset,
+		TruncatedBytes: truncatedBytes,
 	})
 	if err != nil {
 		// Structural error. Abort
-		return fmt.Errorf("marshalling json: %w", err)
+		return 0, fmt.Errorf("marshalling json: %w", err)
 	}
 
 	var buf bytes.Buffer
 	if err := json.Indent(&buf, b, "", "  "); err != nil {
 		// Structural error. Abort
-		return fmt.Errorf("indenting json: %w", err)
+		return 0, fmt.Errorf("indenting json: %w", err)
 	}
 
 	if _, err := metaout.Write(buf.Bytes()); err != nil {
-		return fmt.Errorf("writing metadata: %w", err)
+		return 0, fmt.Errorf("writing metadata: %w", err)
 	}
 
 	//
@@ -95,28 +115,41 @@ func addFileToZip(z *zip.Writer, location string) error {
 	fh, err := os.Open(location)
 	if err != nil {
 		fmt.Fprintf(metaout, `{ "error opening file": "%s" }`, err)
-		return nil
+		return 0, nil
 	}
 	defer fh.Close()
 
 	// Create zip header with metadata
 	header, err := zip.FileInfoHeader(fi)
 	if err != nil {
-		return fmt.Errorf("creating file header: %w", err)
+		return 0, fmt.Errorf("creating file header: %w", err)
 	}
 	header.Name = filepath.Join(".", location)
 
 	// Create file in zip with metadata
 	dataout, err := z.CreateHeader(header)
 	if err != nil {
-		return fmt.Errorf("creating %s in zip: %w", location, err)
+		return 0, fmt.Errorf("creating %s in zip: %w", location, err)
 	}
 
-	if _, err := io.Copy(dataout, fh); err != nil {
-		return fmt.Errorf("copy data into zip file %s: %w", location, err)
+	var reader io.Reader = io.NewSectionReader(fh, offset, length)
+	if truncatedBytes > 0 {
+		headLength := limit / 2
+		tailLength := limit - headLength
+		reader = io.MultiReader(
+			io.NewSectionReader(fh, offset, headLength),
+			bytes.NewReader(truncationMarker(truncatedBytes)),
+			io.NewSectionReader(fh, fi.Size()-tailLength, tailLength),
+		)
 	}
 
-	return nil
+	// The file may still be growing, as logs do, so copy no more than we measured
+	written, err := io.Copy(dataout, reader)
+	if err != nil {
+		return written, fmt.Errorf("copy data into zip file %s: %w", location, err)
+	}
+
+	return written, nil
 }
 
 func addStreamToZip(z *zip.Writer, name string, modTime time.Time, reader io.Reader) error {
@@ -221,7 +254,7 @@ func parseUrl(k types.Knapsack, addr string) (*url.URL, error) {
 		if k.InsecureTransportTLS() {
 			scheme = "http"
 		}
-		addr = fmt.Sprintf("%s://%s", scheme, addr)
+		addr = fmt.Sprintf("%s://%s", scheme, ipstack.NormalizeHostPort(addr))
 	}
 
 	u, err := url.Parse(addr)
@@ -235,7 +268,7 @@ func parseUrl(k types.Knapsack, addr string) (*url.URL, error) {
 		if k.InsecureTransportTLS() {
 			port = "80"
 		}
-		u.Host = net.JoinHostPort(u.Host, port)
+		u.Host = net.JoinHostPort(u.Hostname(), port)
 	}
 
 	return u, nil
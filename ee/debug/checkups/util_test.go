@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	typesMocks "github.com/kolide/launcher/ee/agent/types/mocks"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,3 +44,33 @@ func Test_recursiveDirectoryContents(t *testing.T) {
 	filesFound2 := strings.Split(strings.ReplaceAll(strings.TrimSpace(contents2.String()), "\r\n", "\n"), "\n")
 	require.Equal(t, expectedTotalFileCount, len(filesFound2))
 }
+
+func Test_parseUrl(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "k2device.kolide.com", expected: "https://k2device.kolide.com:443"},
+		{addr: "k2device.kolide.com:8443", expected: "https://k2device.kolide.com:8443"},
+		{addr: "https://tuf.kolide.com/repository/targets.json", expected: "https://tuf.kolide.com:443/repository/targets.json"},
+		{addr: "192.0.2.10", expected: "https://192.0.2.10:443"},
+		{addr: "2001:db8::10", expected: "https://[2001:db8::10]:443"},
+		{addr: "[2001:db8::10]", expected: "https://[2001:db8::10]:443"},
+		{addr: "[2001:db8::10]:8443", expected: "https://[2001:db8::10]:8443"},
+		{addr: "https://[2001:db8::10]/version", expected: "https://[2001:db8::10]:443/version"},
+	} {
+		tt := tt
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			k := typesMocks.NewKnapsack(t)
+			k.On("InsecureTransportTLS").Return(false).Maybe()
+
+			u, err := parseUrl(k, tt.addr)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, u.String())
+		})
+	}
+}
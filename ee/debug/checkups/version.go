@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/kolide/kit/version"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/buildprofile"
 )
 
 type Version struct {
@@ -30,13 +32,18 @@ func (c *Version) Status() Status {
 }
 
 func (c *Version) Summary() string {
+	if excluded := c.k.ExcludedSubsystems(); len(excluded) > 0 {
+		return fmt.Sprintf("launcher_version %s (%s build, without %s)", version.Version().Version, buildprofile.Name, strings.Join(excluded, ", "))
+	}
 	return fmt.Sprintf("launcher_version %s", version.Version().Version)
 }
 
 func (c *Version) Data() any {
 	return map[string]any{
-		"update_channel":   c.k.UpdateChannel(),
-		"tufServer":        c.k.TufServerURL(),
-		"launcher_version": version.Version().Version,
+		"update_channel":      c.k.UpdateChannel(),
+		"tufServer":           c.k.TufServerURL(),
+		"launcher_version":    version.Version().Version,
+		"build_profile":       buildprofile.Name,
+		"excluded_subsystems": c.k.ExcludedSubsystems(),
 	}
 }
@@ -0,0 +1,242 @@
+// Package crashreport writes a report when launcher panics, to allow for post-hoc analysis of
+// crashes beyond what makes it into the logs. Reports are written to the crashes directory
+// under launcher's root directory, and only the most recent reports are retained.
+package crashreport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kolide/kit/version"
+)
+
+const (
+	crashDirName      = "crashes"
+	crashReportPrefix = "crash-"
+	crashReportSuffix = ".json"
+
+	// crashReportTimeFormat sorts lexically, so the filenames sort oldest first
+	crashReportTimeFormat = "20060102T150405.000000000Z"
+
+	// maxCrashReports is the number of reports retained in the crashes directory
+	maxCrashReports = 10
+
+	// maxGoroutineDumpBytes caps the size of the dump of all goroutines' stacks
+	maxGoroutineDumpBytes = 16 << 20
+)
+
+// Report is a crash report, written when launcher panics.
+type Report struct {
+	Timestamp  string        `json:"timestamp"`
+	Panic      string        `json:"panic"`
+	PanicType  string        `json:"panic_type"`
+	Stack      string        `json:"stack"`
+	Goroutines string        `json:"goroutines"`
+	Build      BuildMetadata `json:"build"`
+	RecentLogs []string      `json:"recent_logs,omitempty"`
+}
+
+// BuildMetadata identifies the exact binary that crashed, so that stack traces from the
+// report can be symbolicated against the matching build.
+type BuildMetadata struct {
+	Version          string            `json:"version"`
+	Revision         string            `json:"revision"`
+	Branch           string            `json:"branch"`
+	BuildDate        string            `json:"build_date"`
+	GoVersion        string            `json:"go_version"`
+	Goos             string            `json:"goos"`
+	Goarch           string            `json:"goarch"`
+	Executable       string            `json:"executable,omitempty"`
+	ExecutableSha256 string            `json:"executable_sha256,omitempty"`
+	MainModule       string            `json:"main_module,omitempty"`
+	Settings         map[string]string `json:"settings,omitempty"`
+	Dependencies     map[string]string `json:"dependencies,omitempty"`
+}
+
+// ReportInfo describes a crash report on disk.
+type ReportInfo struct {
+	Path string
+	Time time.Time
+}
+
+// CrashDir returns the location of the crashes directory in the given root directory.
+func CrashDir(rootDir string) string {
+	return filepath.Join(rootDir, crashDirName)
+}
+
+// Write writes a crash report for the recovered panic value r to the crashes directory, then
+// removes the oldest reports beyond the retention limit. It must be called from the deferred
+// function that recovered the panic, so that the stack still includes the panicking frames.
+// recentLogs may be nil. It returns the path to the new report.
+func Write(rootDir string, r any, recentLogs *LogRing) (string, error) {
+	if rootDir == "" {
+		return "", errors.New("no root directory set")
+	}
+
+	now := time.Now().UTC()
+	report := Report{
+		Timestamp:  now.Format(time.RFC3339Nano),
+		Panic:      fmt.Sprintf("%v", r),
+		PanicType:  fmt.Sprintf("%T", r),
+		Stack:      string(debug.Stack()),
+		Goroutines: goroutineDump(),
+		Build:      buildMetadata(),
+	}
+	if recentLogs != nil {
+		report.RecentLogs = recentLogs.Lines()
+	}
+
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshalling crash report: %w", err)
+	}
+
+	crashDir := CrashDir(rootDir)
+	if err := os.MkdirAll(crashDir, 0755); err != nil {
+		return "", fmt.Errorf("creating crash directory: %w", err)
+	}
+
+	// Write to a temporary file and rename, so that readers never see a partial report
+	reportPath := filepath.Join(crashDir, crashReportPrefix+now.Format(crashReportTimeFormat)+crashReportSuffix)
+	tmpPath := reportPath + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0644); err != nil {
+		return "", fmt.Errorf("writing crash report: %w", err)
+	}
+	if err := os.Rename(tmpPath, reportPath); err != nil {
+		return "", fmt.Errorf("renaming crash report: %w", err)
+	}
+
+	if err := rotate(rootDir); err != nil {
+		return reportPath, fmt.Errorf("rotating crash reports: %w", err)
+	}
+
+	return reportPath, nil
+}
+
+// List returns the crash reports in the crashes directory, oldest first. It returns no
+// reports, rather than an error, if the crashes directory does not exist.
+func List(rootDir string) ([]ReportInfo, error) {
+	entries, err := os.ReadDir(CrashDir(rootDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading crash directory: %w", err)
+	}
+
+	var reports []ReportInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, crashReportPrefix) || !strings.HasSuffix(name, crashReportSuffix) {
+			continue
+		}
+
+		ts, err := time.Parse(crashReportTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, crashReportPrefix), crashReportSuffix))
+		if err != nil {
+			continue
+		}
+
+		reports = append(reports, ReportInfo{
+			Path: filepath.Join(CrashDir(rootDir), name),
+			Time: ts,
+		})
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Time.Before(reports[j].Time)
+	})
+
+	return reports, nil
+}
+
+// rotate removes the oldest crash reports beyond maxCrashReports.
+func rotate(rootDir string) error {
+	reports, err := List(rootDir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := 0; i < len(reports)-maxCrashReports; i++ {
+		if err := os.Remove(reports[i].Path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// goroutineDump returns the stacks of all goroutines, growing the buffer as needed up to
+// maxGoroutineDumpBytes.
+func goroutineDump() string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxGoroutineDumpBytes {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func buildMetadata() BuildMetadata {
+	v := version.Version()
+	metadata := BuildMetadata{
+		Version:   v.Version,
+		Revision:  v.Revision,
+		Branch:    v.Branch,
+		BuildDate: v.BuildDate,
+		GoVersion: runtime.Version(),
+		Goos:      runtime.GOOS,
+		Goarch:    runtime.GOARCH,
+	}
+
+	if executable, err := os.Executable(); err == nil {
+		metadata.Executable = executable
+		if sum, err := fileSha256(executable); err == nil {
+			metadata.ExecutableSha256 = sum
+		}
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		metadata.MainModule = buildInfo.Main.Path + "@" + buildInfo.Main.Version
+
+		metadata.Settings = make(map[string]string)
+		for _, setting := range buildInfo.Settings {
+			metadata.Settings[setting.Key] = setting.Value
+		}
+
+		metadata.Dependencies = make(map[string]string)
+		for _, dep := range buildInfo.Deps {
+			metadata.Dependencies[dep.Path] = dep.Version
+		}
+	}
+
+	return metadata
+}
+
+func fileSha256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,114 @@
+package crashreport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kolide/kit/version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRing(t *testing.T) {
+	t.Parallel()
+
+	r := NewLogRing(3)
+	require.Empty(t, r.Lines())
+
+	fmt.Fprint(r, "one\n")
+	fmt.Fprint(r, "two\n")
+	require.Equal(t, []string{"one", "two"}, r.Lines())
+
+	fmt.Fprint(r, "three\n")
+	fmt.Fprint(r, "four\n")
+	fmt.Fprint(r, "five\n")
+	require.Equal(t, []string{"three", "four", "five"}, r.Lines())
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+
+	recentLogs := NewLogRing(10)
+	fmt.Fprint(recentLogs, `{"msg":"about to panic"}`+"\n")
+
+	var reportPath string
+	func() {
+		defer func() {
+			r := recover()
+			require.NotNil(t, r)
+
+			var err error
+			reportPath, err = Write(rootDir, r, recentLogs)
+			require.NoError(t, err)
+		}()
+
+		panic(errors.New("test panic"))
+	}()
+
+	raw, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(raw, &report))
+	require.Equal(t, "test panic", report.Panic)
+	require.Equal(t, "*errors.errorString", report.PanicType)
+	require.Contains(t, report.Stack, "TestWrite", "stack should include the panicking frames")
+	require.Contains(t, report.Goroutines, "goroutine ")
+	require.Equal(t, []string{`{"msg":"about to panic"}`}, report.RecentLogs)
+	require.Equal(t, version.Version().Version, report.Build.Version)
+	require.Equal(t, runtime.GOOS, report.Build.Goos)
+	require.Equal(t, runtime.Version(), report.Build.GoVersion)
+
+	reports, err := List(rootDir)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, reportPath, reports[0].Path)
+}
+
+func TestWrite_Rotation(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+
+	var paths []string
+	for i := 0; i < maxCrashReports+3; i += 1 {
+		reportPath, err := Write(rootDir, fmt.Sprintf("panic %d", i), nil)
+		require.NoError(t, err)
+		paths = append(paths, reportPath)
+	}
+
+	reports, err := List(rootDir)
+	require.NoError(t, err)
+	require.Len(t, reports, maxCrashReports)
+
+	// The oldest reports should have been removed
+	require.Equal(t, paths[3], reports[0].Path)
+	require.Equal(t, paths[len(paths)-1], reports[len(reports)-1].Path)
+}
+
+func TestList_NoCrashDir(t *testing.T) {
+	t.Parallel()
+
+	reports, err := List(t.TempDir())
+	require.NoError(t, err)
+	require.Empty(t, reports)
+}
+
+func TestList_IgnoresOtherFiles(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(CrashDir(rootDir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(CrashDir(rootDir), "notes.txt"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(CrashDir(rootDir), "crash-not-a-time.json"), nil, 0644))
+
+	reports, err := List(rootDir)
+	require.NoError(t, err)
+	require.Empty(t, reports)
+}
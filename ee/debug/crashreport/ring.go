@@ -0,0 +1,63 @@
+package crashreport
+
+import (
+	"bytes"
+	"sync"
+)
+
+// LogRing is an io.Writer that retains the most recent log lines in memory, so that they
+// can be included in a crash report. It expects each call to Write to contain one log
+// record, as written by slog's handlers.
+type LogRing struct {
+	lock  sync.Mutex
+	lines [][]byte
+	next  int
+	full  bool
+}
+
+// NewLogRing returns a LogRing that retains the last size log lines.
+func NewLogRing(size int) *LogRing {
+	if size < 1 {
+		size = 1
+	}
+
+	return &LogRing{
+		lines: make([][]byte, size),
+	}
+}
+
+func (r *LogRing) Write(p []byte) (int, error) {
+	// The caller may reuse p, so we must copy it
+	line := bytes.TrimRight(p, "\n")
+	lineCopy := make([]byte, len(line))
+	copy(lineCopy, line)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.lines[r.next] = lineCopy
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+
+	return len(p), nil
+}
+
+// Lines returns the retained log lines, oldest first.
+func (r *LogRing) Lines() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var lines []string
+	if r.full {
+		for _, line := range r.lines[r.next:] {
+			lines = append(lines, string(line))
+		}
+	}
+	for _, line := range r.lines[:r.next] {
+		lines = append(lines, string(line))
+	}
+
+	return lines
+}
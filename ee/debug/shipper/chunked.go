@@ -0,0 +1,417 @@
+package shipper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+const (
+	// chunkAlignment is the unit of chunk sizes: resumable uploads to cloud storage require
+	// every chunk but the last to be a multiple of 256 KiB.
+	chunkAlignment = 256 * 1024
+
+	// DefaultChunkSize is the size of each chunk of a chunked upload, unless another is requested.
+	DefaultChunkSize = 4 * chunkAlignment
+
+	// pendingUploadsDirectory holds chunked uploads, and their progress, in the root directory
+	// until they complete.
+	pendingUploadsDirectory = "flare_uploads"
+
+	// maxPendingUploadAge is how long we keep trying to finish a chunked upload. Signed urls
+	// will have expired well before then.
+	maxPendingUploadAge = 7 * 24 * time.Hour
+
+	// maxChunkAttempts is how many times we try an upload without progress before leaving it
+	// to resume later.
+	maxChunkAttempts = 3
+
+	// chunkTimeout bounds each chunk's request, allowing for slow connections.
+	chunkTimeout = 10 * time.Minute
+
+	// statusResumeIncomplete is the status the upload server responds with until it has the
+	// whole upload, with a Range header saying how much it has.
+	statusResumeIncomplete = 308
+)
+
+// errUploadExpired is returned when the upload server no longer accepts the upload, usually
+// because its url expired. The upload can't be resumed, so it's abandoned.
+var errUploadExpired = errors.New("upload expired")
+
+// pendingUploadsLock keeps a chunked upload from being resumed by more than one caller at once.
+var pendingUploadsLock sync.Mutex
+
+// spooling holds the paths of the spools still being written, which have no recorded progress
+// yet. Any other spool without recorded progress was left by an interrupted flare, and can't be
+// uploaded, since we don't know where to.
+var (
+	spooling     = make(map[string]struct{})
+	spoolingLock sync.Mutex
+)
+
+// pendingUpload is the progress of a chunked upload, recorded beside the spooled upload so
+// that it can resume after a failure, or a restart.
+type pendingUpload struct {
+	URL       string    `json:"url"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	ChunkSize int64     `json:"chunk_size"`
+	Offset    int64     `json:"offset"`
+	Created   time.Time `json:"created"`
+}
+
+type chunkedShipper struct {
+	knapsack  types.Knapsack
+	spool     *os.File
+	upload    *pendingUpload
+	statePath string
+}
+
+// NewChunked returns a shipper for low-bandwidth hosts. Rather than streaming the whole upload
+// in a single request, it spools it in the root directory, and on Close uploads it in chunks of
+// chunkSize bytes, or DefaultChunkSize if that's zero. Its progress is recorded as it goes, so
+// that an upload that's interrupted resumes where the server left off, rather than starting
+// over; see ResumePendingUploads.
+func NewChunked(knapsack types.Knapsack, chunkSize int64, opts ...shipperOption) (*chunkedShipper, error) {
+	if knapsack.RootDirectory() == "" {
+		return nil, errors.New("chunked uploads need a root directory to spool to")
+	}
+
+	s, uploadURL, err := newSigned(knapsack, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(knapsack.RootDirectory(), pendingUploadsDirectory)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating pending uploads directory: %w", err)
+	}
+
+	spool, err := os.CreateTemp(dir, "upload-*.spool")
+	if err != nil {
+		return nil, fmt.Errorf("creating spool file: %w", err)
+	}
+	setSpooling(spool.Name(), true)
+
+	return &chunkedShipper{
+		knapsack: knapsack,
+		spool:    spool,
+		upload: &pendingUpload{
+			URL:       uploadURL,
+			Name:      s.uploadName,
+			ChunkSize: alignChunkSize(chunkSize),
+			Created:   time.Now(),
+		},
+		statePath: statePathFor(spool.Name()),
+	}, nil
+}
+
+func (c *chunkedShipper) Name() string {
+	return c.upload.Name
+}
+
+func (c *chunkedShipper) Write(p []byte) (int, error) {
+	return c.spool.Write(p)
+}
+
+// Close finishes spooling, and uploads the spool. If the upload doesn't complete, it's left
+// to resume later.
+func (c *chunkedShipper) Close() error {
+	defer setSpooling(c.spool.Name(), false)
+
+	size, err := c.spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errors.Join(fmt.Errorf("checking spool size: %w", err), c.spool.Close(), removePendingUpload(c.spool.Name()))
+	}
+	if err := c.spool.Close(); err != nil {
+		return errors.Join(fmt.Errorf("closing spool: %w", err), removePendingUpload(c.spool.Name()))
+	}
+
+	// Nothing was written, so there's nothing to send
+	if size == 0 {
+		return removePendingUpload(c.spool.Name())
+	}
+
+	c.upload.Size = size
+	if err := writePendingUpload(c.statePath, c.upload); err != nil {
+		return errors.Join(fmt.Errorf("recording upload: %w", err), removePendingUpload(c.spool.Name()))
+	}
+
+	pendingUploadsLock.Lock()
+	defer pendingUploadsLock.Unlock()
+
+	return resumeUpload(context.TODO(), c.spool.Name(), c.upload, false)
+}
+
+// ResumePendingUploads resumes any chunked uploads that didn't complete, abandoning those whose
+// urls have expired, or that are too old to try any longer. Spools left by flares interrupted
+// while spooling are removed.
+func ResumePendingUploads(ctx context.Context, knapsack types.Knapsack) error {
+	if knapsack.RootDirectory() == "" {
+		return nil
+	}
+
+	statePaths, err := filepath.Glob(filepath.Join(knapsack.RootDirectory(), pendingUploadsDirectory, "*.json"))
+	if err != nil {
+		return fmt.Errorf("listing pending uploads: %w", err)
+	}
+
+	pendingUploadsLock.Lock()
+	defer pendingUploadsLock.Unlock()
+
+	errs := []error{removeOrphanedSpools(knapsack.RootDirectory())}
+	for _, statePath := range statePaths {
+		spoolPath := strings.TrimSuffix(statePath, ".json")
+
+		upload, err := readPendingUpload(statePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading pending upload %s: %w", statePath, err), removePendingUpload(spoolPath))
+			continue
+		}
+
+		if time.Since(upload.Created) > maxPendingUploadAge {
+			errs = append(errs, removePendingUpload(spoolPath))
+			continue
+		}
+
+		if err := resumeUpload(ctx, spoolPath, upload, true); err != nil {
+			errs = append(errs, fmt.Errorf("resuming upload %s: %w", upload.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// removeOrphanedSpools removes the spools left by flares interrupted before they finished
+// spooling.
+func removeOrphanedSpools(rootDirectory string) error {
+	spoolPaths, err := filepath.Glob(filepath.Join(rootDirectory, pendingUploadsDirectory, "*.spool"))
+	if err != nil {
+		return fmt.Errorf("listing spools: %w", err)
+	}
+
+	spoolingLock.Lock()
+	defer spoolingLock.Unlock()
+
+	var errs []error
+	for _, spoolPath := range spoolPaths {
+		if _, inProgress := spooling[spoolPath]; inProgress {
+			continue
+		}
+		if _, err := os.Stat(statePathFor(spoolPath)); !os.IsNotExist(err) {
+			continue
+		}
+		errs = append(errs, removePendingUpload(spoolPath))
+	}
+
+	return errors.Join(errs...)
+}
+
+func setSpooling(spoolPath string, inProgress bool) {
+	spoolingLock.Lock()
+	defer spoolingLock.Unlock()
+
+	if inProgress {
+		spooling[spoolPath] = struct{}{}
+	} else {
+		delete(spooling, spoolPath)
+	}
+}
+
+// resumeUpload uploads what the server doesn't have yet of the spooled upload, recording its
+// progress as it goes. When resuming, the server is first asked how much it has, in case its
+// response to the last chunk was lost. The spool is removed once the upload completes, or
+// once the server won't take it.
+func resumeUpload(ctx context.Context, spoolPath string, upload *pendingUpload, askOffset bool) error {
+	spool, err := os.Open(spoolPath)
+	if err != nil {
+		return errors.Join(fmt.Errorf("opening spool: %w", err), removePendingUpload(spoolPath))
+	}
+	defer spool.Close()
+
+	statePath := statePathFor(spoolPath)
+	attempts := 0
+	for {
+		var offset int64
+		var complete bool
+		if askOffset {
+			offset, complete, err = sendChunk(ctx, upload, nil, 0)
+		} else {
+			offset, complete, err = uploadChunk(ctx, spool, upload)
+		}
+
+		switch {
+		case errors.Is(err, errUploadExpired):
+			spool.Close()
+			return errors.Join(err, removePendingUpload(spoolPath))
+		case err == nil && complete:
+			spool.Close()
+			return removePendingUpload(spoolPath)
+		case err == nil && (offset > upload.Offset || askOffset):
+			if offset > upload.Offset {
+				attempts = 0
+			}
+			askOffset = false
+			upload.Offset = offset
+			if err := writePendingUpload(statePath, upload); err != nil {
+				return fmt.Errorf("recording upload progress: %w", err)
+			}
+			continue
+		case err == nil:
+			err = fmt.Errorf("server acknowledged nothing past offset %d", upload.Offset)
+		}
+
+		attempts++
+		if attempts >= maxChunkAttempts {
+			return fmt.Errorf("upload incomplete after %d attempts at offset %d of %d, will resume later: %w", attempts, upload.Offset, upload.Size, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempts) * 5 * time.Second):
+		}
+		// We don't know what the server made of the failed request
+		askOffset = true
+	}
+}
+
+// uploadChunk sends the chunk starting at the upload's offset.
+func uploadChunk(ctx context.Context, spool io.ReaderAt, upload *pendingUpload) (int64, bool, error) {
+	length := upload.ChunkSize
+	if remaining := upload.Size - upload.Offset; remaining < length {
+		length = remaining
+	}
+	// The server has everything, but hasn't said the upload is complete
+	if length <= 0 {
+		return sendChunk(ctx, upload, nil, 0)
+	}
+
+	chunk := make([]byte, length)
+	if _, err := spool.ReadAt(chunk, upload.Offset); err != nil {
+		return 0, false, fmt.Errorf("reading chunk at offset %d: %w", upload.Offset, err)
+	}
+
+	return sendChunk(ctx, upload, chunk, upload.Offset)
+}
+
+// sendChunk PUTs the chunk at the given offset, returning how much of the upload the server now
+// has, and whether that's all of it. A nil chunk asks the server how much it has, without
+// sending anything.
+func sendChunk(ctx context.Context, upload *pendingUpload, chunk []byte, offset int64) (int64, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, chunkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, upload.URL, bytes.NewReader(chunk))
+	if err != nil {
+		return 0, false, fmt.Errorf("creating chunk request: %w", err)
+	}
+
+	if chunk == nil {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", upload.Size))
+	} else {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, upload.Size))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("sending chunk request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return upload.Size, true, nil
+	case statusResumeIncomplete:
+		received, err := parseReceivedRange(resp.Header.Get("Range"))
+		if err != nil {
+			return 0, false, err
+		}
+		return received, false, nil
+	case http.StatusNotFound, http.StatusGone:
+		return 0, false, fmt.Errorf("%w: %s", errUploadExpired, resp.Status)
+	default:
+		return 0, false, fmt.Errorf("got %s status in chunk response: %s", resp.Status, string(body))
+	}
+}
+
+// parseReceivedRange parses the Range header of an incomplete upload's response, returning how
+// many bytes the server has. No header means it has none.
+func parseReceivedRange(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+
+	start, end, found := strings.Cut(strings.TrimPrefix(header, "bytes="), "-")
+	if !found || start != "0" {
+		return 0, fmt.Errorf("unexpected range `%s` in chunk response", header)
+	}
+
+	last, err := strconv.ParseInt(end, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected range `%s` in chunk response: %w", header, err)
+	}
+	return last + 1, nil
+}
+
+// alignChunkSize rounds the chunk size up to a multiple of chunkAlignment.
+func alignChunkSize(chunkSize int64) int64 {
+	if chunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return (chunkSize + chunkAlignment - 1) / chunkAlignment * chunkAlignment
+}
+
+func statePathFor(spoolPath string) string {
+	return spoolPath + ".json"
+}
+
+func readPendingUpload(statePath string) (*pendingUpload, error) {
+	b, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var upload pendingUpload
+	if err := json.Unmarshal(b, &upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func writePendingUpload(statePath string, upload *pendingUpload) error {
+	b, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	// Write and rename, so that an interruption can't leave the progress half-written
+	tmpPath := statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, statePath)
+}
+
+func removePendingUpload(spoolPath string) error {
+	var errs []error
+	for _, p := range []string{spoolPath, statePathFor(spoolPath)} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,201 @@
+package shipper
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	typesMocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+// resumableServer accepts chunked uploads the way resumable uploads to cloud storage do.
+type resumableServer struct {
+	sync.Mutex
+	received []byte
+	chunks   int
+}
+
+func (rs *resumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rs.Lock()
+	defer rs.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	contentRange := strings.TrimPrefix(r.Header.Get("Content-Range"), "bytes ")
+	byteRange, totalStr, _ := strings.Cut(contentRange, "/")
+	total, _ := strconv.Atoi(totalStr)
+
+	if byteRange != "*" {
+		rs.chunks++
+		startStr, _, _ := strings.Cut(byteRange, "-")
+		start, _ := strconv.Atoi(startStr)
+		if start > len(rs.received) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rs.received = append(rs.received[:start], body...)
+	}
+
+	if len(rs.received) == total {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(rs.received) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(rs.received)-1))
+	}
+	w.WriteHeader(statusResumeIncomplete)
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func TestChunkedShipper(t *testing.T) {
+	t.Parallel()
+
+	uploads := &resumableServer{}
+	mux := http.NewServeMux()
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	mux.Handle("/upload", uploads)
+	mux.HandleFunc("/api/agent/flare", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"URL":  testServer.URL + "/upload",
+			"name": "chunked-flare",
+		})
+	})
+
+	rootDir := t.TempDir()
+	k := typesMocks.NewKnapsack(t)
+	k.On("RootDirectory").Return(rootDir)
+	k.On("EnrollSecret").Return("enroll_secret_value")
+	k.On("Slogger").Return(multislogger.NewNopLogger()).Maybe()
+
+	s, err := NewChunked(k, 1, WithNote("chunked"), WithUploadRequestURL(testServer.URL+"/api/agent/flare"))
+	require.NoError(t, err)
+	require.Equal(t, "chunked-flare", s.Name())
+
+	data := randomBytes(t, 3*chunkAlignment+100)
+	_, err = io.Copy(s, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	require.Equal(t, data, uploads.received)
+	require.Equal(t, 4, uploads.chunks, "chunk size should be rounded up to the alignment")
+
+	pending, err := os.ReadDir(filepath.Join(rootDir, pendingUploadsDirectory))
+	require.NoError(t, err)
+	require.Empty(t, pending, "completed upload should be removed")
+}
+
+func TestResumePendingUploads(t *testing.T) {
+	t.Parallel()
+
+	data := randomBytes(t, 3*chunkAlignment)
+
+	// The server has the first two chunks, though the recorded progress only has the first,
+	// as if the response to the second was lost.
+	uploads := &resumableServer{received: append([]byte{}, data[:2*chunkAlignment]...)}
+	mux := http.NewServeMux()
+	mux.Handle("/upload", uploads)
+	mux.HandleFunc("/expired", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	rootDir := t.TempDir()
+	dir := filepath.Join(rootDir, pendingUploadsDirectory)
+	require.NoError(t, os.MkdirAll(dir, 0700))
+
+	pend := func(name, url string, created time.Time) string {
+		spoolPath := filepath.Join(dir, name+".spool")
+		require.NoError(t, os.WriteFile(spoolPath, data, 0600))
+		require.NoError(t, writePendingUpload(statePathFor(spoolPath), &pendingUpload{
+			URL:       url,
+			Name:      name,
+			Size:      int64(len(data)),
+			ChunkSize: chunkAlignment,
+			Offset:    chunkAlignment,
+			Created:   created,
+		}))
+		return spoolPath
+	}
+	pend("resumable", testServer.URL+"/upload", time.Now())
+	pend("expired", testServer.URL+"/expired", time.Now())
+	pend("stale", testServer.URL+"/upload", time.Now().Add(-2*maxPendingUploadAge))
+
+	// A flare interrupted while spooling leaves a spool without recorded progress
+	orphanedSpool := filepath.Join(dir, "upload-orphaned.spool")
+	require.NoError(t, os.WriteFile(orphanedSpool, data, 0600))
+
+	// A flare still spooling has no recorded progress yet either, but must be left alone
+	spoolingPath := filepath.Join(dir, "upload-spooling.spool")
+	require.NoError(t, os.WriteFile(spoolingPath, data, 0600))
+	setSpooling(spoolingPath, true)
+
+	k := typesMocks.NewKnapsack(t)
+	k.On("RootDirectory").Return(rootDir)
+
+	err := ResumePendingUploads(context.TODO(), k)
+	require.ErrorIs(t, err, errUploadExpired)
+
+	require.Equal(t, data, uploads.received)
+	require.Equal(t, 1, uploads.chunks, "only the chunk the server was missing should be sent")
+
+	pending, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "completed, expired, stale, and orphaned uploads should all be removed")
+	require.Equal(t, filepath.Base(spoolingPath), pending[0].Name())
+
+	setSpooling(spoolingPath, false)
+}
+
+func TestParseReceivedRange(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		header    string
+		expected  int64
+		expectErr bool
+	}{
+		{header: "", expected: 0},
+		{header: "bytes=0-262143", expected: 262144},
+		{header: "bytes=100-200", expectErr: true},
+		{header: "bytes=0-", expectErr: true},
+	} {
+		received, err := parseReceivedRange(tt.header)
+		if tt.expectErr {
+			require.Error(t, err, tt.header)
+			continue
+		}
+		require.NoError(t, err, tt.header)
+		require.Equal(t, tt.expected, received, tt.header)
+	}
+}
+
+func TestAlignChunkSize(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, int64(DefaultChunkSize), alignChunkSize(0))
+	require.Equal(t, int64(chunkAlignment), alignChunkSize(1))
+	require.Equal(t, int64(chunkAlignment), alignChunkSize(chunkAlignment))
+	require.Equal(t, int64(2*chunkAlignment), alignChunkSize(chunkAlignment+1))
+}
@@ -60,6 +60,25 @@ type shipper struct {
 }
 
 func New(knapsack types.Knapsack, opts ...shipperOption) (*shipper, error) {
+	s, uploadURL, err := newSigned(knapsack, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	s.writer = writer
+
+	req, err := http.NewRequest(http.MethodPut, uploadURL, reader)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for http upload: %w", err)
+	}
+	s.uploadRequest = req
+
+	return s, nil
+}
+
+// newSigned sets up a shipper from its options, and requests the signed url to upload to.
+func newSigned(knapsack types.Knapsack, opts ...shipperOption) (*shipper, string, error) {
 	s := &shipper{
 		knapsack:        knapsack,
 		uploadRequestWg: &sync.WaitGroup{},
@@ -72,26 +91,17 @@ func New(knapsack types.Knapsack, opts ...shipperOption) (*shipper, error) {
 	if s.uploadRequestURL == "" {
 		uploadRequestURL, err := url.JoinPath(knapsack.KolideServerURL(), "api/agent/flare")
 		if err != nil {
-			return nil, fmt.Errorf("joining url: %w", err)
+			return nil, "", fmt.Errorf("joining url: %w", err)
 		}
 		s.uploadRequestURL = uploadRequestURL
 	}
 
 	uploadURL, err := s.signedUrl()
 	if err != nil {
-		return nil, fmt.Errorf("getting signed url: %w", err)
-	}
-
-	reader, writer := io.Pipe()
-	s.writer = writer
-
-	req, err := http.NewRequest(http.MethodPut, uploadURL, reader)
-	if err != nil {
-		return nil, fmt.Errorf("creating request for http upload: %w", err)
+		return nil, "", fmt.Errorf("getting signed url: %w", err)
 	}
-	s.uploadRequest = req
 
-	return s, nil
+	return s, uploadURL, nil
 }
 
 func (s *shipper) Name() string {
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// DesktopCapability describes whether this device can run the desktop process at all. Headless
+// Linux servers and Windows Server Core have no graphical session for the desktop process to
+// run in; there, the runner stays in a headless mode rather than repeatedly trying to find one.
+type DesktopCapability struct {
+	Supported   bool
+	Reason      string // what the decision was based on
+	LastChecked time.Time
+}
+
+// DetectDesktopCapability checks whether this device can run the desktop process.
+func DetectDesktopCapability(ctx context.Context) DesktopCapability {
+	supported, reason := detectDesktopCapability(ctx)
+	return DesktopCapability{
+		Supported:   supported,
+		Reason:      reason,
+		LastChecked: time.Now(),
+	}
+}
+
+// InstanceDesktopCapability returns the desktop capability as last seen by the runner. If the
+// runner hasn't been created, or hasn't checked yet, it checks now.
+func InstanceDesktopCapability() DesktopCapability {
+	if instance == nil {
+		return DetectDesktopCapability(context.TODO())
+	}
+
+	instance.capabilityLock.RLock()
+	defer instance.capabilityLock.RUnlock()
+
+	if instance.capability.LastChecked.IsZero() {
+		return DetectDesktopCapability(context.TODO())
+	}
+
+	return instance.capability
+}
+
+// updateDesktopCapability re-checks whether this device can run the desktop process, logging
+// when the runner enters or leaves headless mode. Graphical environments can be installed or
+// removed while launcher runs, so this is checked on each update interval; the checks are
+// cheap.
+func (r *DesktopUsersProcessesRunner) updateDesktopCapability(ctx context.Context) DesktopCapability {
+	capability := DetectDesktopCapability(ctx)
+
+	r.capabilityLock.Lock()
+	previous := r.capability
+	r.capability = capability
+	r.capabilityLock.Unlock()
+
+	firstCheck := previous.LastChecked.IsZero()
+	switch {
+	case !capability.Supported && (firstCheck || previous.Supported):
+		r.slogger.Log(ctx, slog.LevelInfo,
+			"no graphical session support on this device, desktop runner is in headless mode",
+			"reason", capability.Reason,
+		)
+	case capability.Supported && !firstCheck && !previous.Supported:
+		r.slogger.Log(ctx, slog.LevelInfo,
+			"graphical session support detected, desktop runner is leaving headless mode",
+			"reason", capability.Reason,
+		)
+	}
+
+	return capability
+}
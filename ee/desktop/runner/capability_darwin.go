@@ -0,0 +1,10 @@
+//go:build darwin
+// +build darwin
+
+package runner
+
+import "context"
+
+func detectDesktopCapability(_ context.Context) (bool, string) {
+	return true, "macOS always has a graphical environment"
+}
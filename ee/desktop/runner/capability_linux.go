@@ -0,0 +1,58 @@
+//go:build linux
+// +build linux
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// displayManagers are the display managers we look for when there's no systemd display
+// manager service, relative to the root.
+var displayManagers = []string{
+	"usr/sbin/gdm",
+	"usr/sbin/gdm3",
+	"usr/sbin/lightdm",
+	"usr/bin/sddm",
+	"usr/bin/xdm",
+	"usr/bin/lxdm",
+}
+
+func detectDesktopCapability(_ context.Context) (bool, string) {
+	return linuxDesktopCapability("/")
+}
+
+// linuxDesktopCapability looks for signs of a graphical environment under root: a running X11
+// or Wayland display server, an installed display manager, or a graphical default systemd target.
+// A server without any of them has no graphical session for the desktop process to run in.
+func linuxDesktopCapability(root string) (bool, string) {
+	if matches, _ := filepath.Glob(filepath.Join(root, "tmp", ".X11-unix", "X*")); len(matches) > 0 {
+		return true, "X11 display server running"
+	}
+
+	waylandSockets, _ := filepath.Glob(filepath.Join(root, "run", "user", "*", "wayland-*"))
+	for _, s := range waylandSockets {
+		if !strings.HasSuffix(s, ".lock") {
+			return true, "Wayland display server running"
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "etc", "systemd", "system", "display-manager.service")); err == nil {
+		return true, "display manager service installed"
+	}
+
+	if target, err := os.Readlink(filepath.Join(root, "etc", "systemd", "system", "default.target")); err == nil && filepath.Base(target) == "graphical.target" {
+		return true, "default systemd target is graphical"
+	}
+
+	for _, dm := range displayManagers {
+		if _, err := os.Stat(filepath.Join(root, dm)); err == nil {
+			return true, "display manager " + filepath.Base(dm) + " installed"
+		}
+	}
+
+	return false, "no display server, display manager, or graphical systemd target found"
+}
@@ -0,0 +1,100 @@
+//go:build linux
+// +build linux
+
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_linuxDesktopCapability(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name              string
+		setup             func(t *testing.T, root string)
+		expectedSupported bool
+	}{
+		{
+			name:              "headless server",
+			setup:             func(t *testing.T, root string) {},
+			expectedSupported: false,
+		},
+		{
+			name: "headless server with multi-user target",
+			setup: func(t *testing.T, root string) {
+				mkdir(t, root, "etc", "systemd", "system")
+				require.NoError(t, os.Symlink("/lib/systemd/system/multi-user.target", filepath.Join(root, "etc", "systemd", "system", "default.target")))
+			},
+			expectedSupported: false,
+		},
+		{
+			name: "wayland lock file only",
+			setup: func(t *testing.T, root string) {
+				touch(t, root, "run", "user", "1000", "wayland-0.lock")
+			},
+			expectedSupported: false,
+		},
+		{
+			name: "x11 running",
+			setup: func(t *testing.T, root string) {
+				touch(t, root, "tmp", ".X11-unix", "X0")
+			},
+			expectedSupported: true,
+		},
+		{
+			name: "wayland running",
+			setup: func(t *testing.T, root string) {
+				touch(t, root, "run", "user", "1000", "wayland-0")
+			},
+			expectedSupported: true,
+		},
+		{
+			name: "display manager service",
+			setup: func(t *testing.T, root string) {
+				touch(t, root, "etc", "systemd", "system", "display-manager.service")
+			},
+			expectedSupported: true,
+		},
+		{
+			name: "graphical target",
+			setup: func(t *testing.T, root string) {
+				mkdir(t, root, "etc", "systemd", "system")
+				require.NoError(t, os.Symlink("/lib/systemd/system/graphical.target", filepath.Join(root, "etc", "systemd", "system", "default.target")))
+			},
+			expectedSupported: true,
+		},
+		{
+			name: "display manager binary",
+			setup: func(t *testing.T, root string) {
+				touch(t, root, "usr", "sbin", "lightdm")
+			},
+			expectedSupported: true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			tt.setup(t, root)
+
+			supported, reason := linuxDesktopCapability(root)
+			require.Equal(t, tt.expectedSupported, supported, reason)
+			require.NotEmpty(t, reason)
+		})
+	}
+}
+
+func mkdir(t *testing.T, root string, elem ...string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(append([]string{root}, elem...)...), 0755))
+}
+
+func touch(t *testing.T, root string, elem ...string) {
+	mkdir(t, root, elem[:len(elem)-1]...)
+	require.NoError(t, os.WriteFile(filepath.Join(append([]string{root}, elem...)...), nil, 0644))
+}
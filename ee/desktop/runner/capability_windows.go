@@ -0,0 +1,34 @@
+//go:build windows
+// +build windows
+
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// detectDesktopCapability checks the installation type: Server Core and Nano Server have no
+// desktop shell, so no explorer process to run the desktop process alongside.
+func detectDesktopCapability(_ context.Context) (bool, string) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE)
+	if err != nil {
+		// Assume a desktop, as we always did before checking
+		return true, fmt.Sprintf("could not open CurrentVersion key: %v", err)
+	}
+	defer key.Close()
+
+	installationType, _, err := key.GetStringValue("InstallationType")
+	if err != nil {
+		return true, fmt.Sprintf("could not read installation type: %v", err)
+	}
+
+	switch installationType {
+	case "Server Core", "Nano Server":
+		return false, fmt.Sprintf("installation type %s has no desktop shell", installationType)
+	default:
+		return true, fmt.Sprintf("installation type %s", installationType)
+	}
+}
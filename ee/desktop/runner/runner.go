@@ -27,6 +27,7 @@ import (
 	"github.com/kolide/launcher/ee/agent"
 	"github.com/kolide/launcher/ee/agent/flags/keys"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/consent"
 	"github.com/kolide/launcher/ee/consoleuser"
 	runnerserver "github.com/kolide/launcher/ee/desktop/runner/server"
 	"github.com/kolide/launcher/ee/desktop/user/client"
@@ -34,6 +35,7 @@ import (
 	"github.com/kolide/launcher/ee/desktop/user/notify"
 	"github.com/kolide/launcher/ee/gowrapper"
 	"github.com/kolide/launcher/ee/presencedetection"
+	"github.com/kolide/launcher/ee/supervisor"
 	"github.com/kolide/launcher/ee/ui/assets"
 	"github.com/kolide/launcher/pkg/backoff"
 	"github.com/kolide/launcher/pkg/rungroup"
@@ -45,6 +47,14 @@ import (
 
 const nonWindowsDesktopSocketPrefix = "desktop.sock"
 
+// Desktop processes that die soon after starting are respawned with increasing delays,
+// rather than on every update interval.
+const (
+	desktopMinRestartDelay = 5 * time.Second
+	desktopMaxRestartDelay = 5 * time.Minute
+	desktopStableAfter     = 1 * time.Minute
+)
+
 type desktopUsersProcessesRunnerOption func(*DesktopUsersProcessesRunner)
 
 // WithExecutablePath sets the path to the executable that will be run for each desktop.
@@ -107,6 +117,9 @@ type DesktopUsersProcessesRunner struct {
 	interrupted         atomic.Bool
 	// uidProcs is a map of uid to desktop process
 	uidProcs map[string]processRecord
+	// restartBackoffs is a map of uid to the backoff for restarting that user's desktop process
+	restartBackoffs     map[string]*supervisor.Backoff
+	restartBackoffsLock sync.Mutex
 	// procsWg is a WaitGroup to wait for all desktop processes to finish during an interrupt
 	procsWg *sync.WaitGroup
 	// interruptTimeout how long to wait for desktop proccesses to finish on interrupt
@@ -131,6 +144,10 @@ type DesktopUsersProcessesRunner struct {
 	osVersion string
 	// cachedMenuData is the cached label values of the currently displayed menu data, used for detecting changes
 	cachedMenuData *menuItemCache
+	// capability is whether this device can run desktop processes, as of the last check; if not,
+	// the runner is in headless mode, and doesn't try to spawn them
+	capability     DesktopCapability
+	capabilityLock sync.RWMutex
 }
 
 // processRecord is used to track spawned desktop processes.
@@ -159,6 +176,7 @@ func New(k types.Knapsack, messenger runnerserver.Messenger, opts ...desktopUser
 	runner := &DesktopUsersProcessesRunner{
 		interrupt:           make(chan struct{}),
 		uidProcs:            make(map[string]processRecord),
+		restartBackoffs:     make(map[string]*supervisor.Backoff),
 		updateInterval:      k.DesktopUpdateInterval(),
 		menuRefreshInterval: k.DesktopMenuRefreshInterval(),
 		procsWg:             &sync.WaitGroup{},
@@ -295,7 +313,7 @@ func (r *DesktopUsersProcessesRunner) DetectPresence(reason string, interval tim
 	var lastErr error
 
 	for _, proc := range r.uidProcs {
-		client := client.New(r.userServerAuthToken, proc.socketPath)
+		client := client.New(r.userServerAuthToken, proc.socketPath, userServerClientOpts(proc.Process)...)
 
 		durationSinceLastDetection, err := client.DetectPresence(reason, interval)
 		if err != nil {
@@ -319,7 +337,7 @@ func (r *DesktopUsersProcessesRunner) CreateSecureEnclaveKey(uid string) (*ecdsa
 		return nil, fmt.Errorf("no desktop process for uid: %s", uid)
 	}
 
-	client := client.New(r.userServerAuthToken, proc.socketPath)
+	client := client.New(r.userServerAuthToken, proc.socketPath, userServerClientOpts(proc.Process)...)
 	keyBytes, err := client.CreateSecureEnclaveKey()
 	if err != nil {
 		return nil, fmt.Errorf("creating secure enclave key: %w", err)
@@ -349,7 +367,7 @@ func (r *DesktopUsersProcessesRunner) killDesktopProcesses(ctx context.Context)
 		// unregistering client from runner server so server will not respond to its requests
 		r.runnerServer.DeRegisterClient(uid)
 
-		client := client.New(r.userServerAuthToken, proc.socketPath)
+		client := client.New(r.userServerAuthToken, proc.socketPath, userServerClientOpts(proc.Process)...)
 		if err := client.Shutdown(ctx); err != nil {
 			r.slogger.Log(ctx, slog.LevelError,
 				"sending shutdown command to user desktop process",
@@ -410,7 +428,7 @@ func (r *DesktopUsersProcessesRunner) killDesktopProcess(ctx context.Context, ui
 	// unregistering client from runner server so server will not respond to its requests
 	r.runnerServer.DeRegisterClient(uid)
 
-	client := client.New(r.userServerAuthToken, proc.socketPath)
+	client := client.New(r.userServerAuthToken, proc.socketPath, userServerClientOpts(proc.Process)...)
 	err := client.Shutdown(ctx)
 	if err == nil {
 		r.slogger.Log(ctx, slog.LevelInfo,
@@ -468,7 +486,7 @@ func (r *DesktopUsersProcessesRunner) SendNotification(n notify.Notification) er
 	atLeastOneSuccess := false
 	errs := make([]error, 0)
 	for uid, proc := range r.uidProcs {
-		client := client.New(r.userServerAuthToken, proc.socketPath)
+		client := client.New(r.userServerAuthToken, proc.socketPath, userServerClientOpts(proc.Process)...)
 		if err := client.Notify(n); err != nil {
 			errs = append(errs, err)
 			continue
@@ -537,7 +555,7 @@ func (r *DesktopUsersProcessesRunner) FlagsChanged(ctx context.Context, flagKeys
 	// DesktopEnabled() == true
 	// Tell any running desktop user processes that they should show the menu
 	for uid, proc := range r.uidProcs {
-		client := client.New(r.userServerAuthToken, proc.socketPath)
+		client := client.New(r.userServerAuthToken, proc.socketPath, userServerClientOpts(proc.Process)...)
 		if err := client.ShowDesktop(); err != nil {
 			r.slogger.Log(ctx, slog.LevelError,
 				"sending refresh command to user desktop process",
@@ -590,7 +608,7 @@ func (r *DesktopUsersProcessesRunner) refreshMenu() {
 
 	// Tell any running desktop user processes that they should refresh the latest menu data
 	for uid, proc := range r.uidProcs {
-		client := client.New(r.userServerAuthToken, proc.socketPath)
+		client := client.New(r.userServerAuthToken, proc.socketPath, userServerClientOpts(proc.Process)...)
 		if err := client.Refresh(); err != nil {
 			r.slogger.Log(context.TODO(), slog.LevelError,
 				"sending refresh command to user desktop process",
@@ -622,6 +640,17 @@ func (r *DesktopUsersProcessesRunner) generateMenuFile() error {
 		menu.MenuVersion:        menu.CurrentMenuVersion,
 	}
 
+	// The menu asks for data collection consent while it's needed
+	if consentStatus, err := consent.CurrentStatus(r.knapsack); err != nil {
+		r.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not load data collection consent status for menu",
+			"err", err,
+		)
+	} else {
+		(*td)[menu.ConsentState] = consentStatus.State
+		(*td)[menu.ConsentPolicyVersion] = consentStatus.PolicyVersion
+	}
+
 	menuTemplateFileBytes, err := os.ReadFile(r.menuTemplatePath())
 	if err != nil {
 		return fmt.Errorf("failed to read menu template file: %w", err)
@@ -697,6 +726,11 @@ func (r *DesktopUsersProcessesRunner) runConsoleUserDesktop() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// On headless devices, there are no console users to look for
+	if !r.updateDesktopCapability(ctx).Supported {
+		return nil
+	}
+
 	consoleUsers, err := consoleuser.CurrentUids(ctx)
 	if err != nil {
 		return fmt.Errorf("getting console users: %w", err)
@@ -707,8 +741,19 @@ func (r *DesktopUsersProcessesRunner) runConsoleUserDesktop() error {
 			continue
 		}
 
+		restartBackoff := r.restartBackoff(uid)
+		if !restartBackoff.Ready() {
+			r.slogger.Log(ctx, slog.LevelDebug,
+				"waiting to respawn desktop process for console user",
+				"uid", uid,
+				"next_start", restartBackoff.NextStart(),
+			)
+			continue
+		}
+
 		// we've decided to spawn a new desktop user process for this user
 		if err := r.spawnForUser(ctx, uid, executablePath); err != nil {
+			restartBackoff.Exited(0)
 			return fmt.Errorf("spawning new desktop user process for %s: %w", uid, err)
 		}
 	}
@@ -750,7 +795,7 @@ func (r *DesktopUsersProcessesRunner) spawnForUser(ctx context.Context, uid stri
 
 	r.waitOnProcessAsync(uid, cmd.Process)
 
-	client := client.New(r.userServerAuthToken, socketPath)
+	client := client.New(r.userServerAuthToken, socketPath, userServerClientOpts(cmd.Process)...)
 
 	pingFunc := client.Ping
 
@@ -796,6 +841,73 @@ func (r *DesktopUsersProcessesRunner) spawnForUser(ctx context.Context, uid stri
 	return nil
 }
 
+// restartBackoff returns the backoff for restarting the given user's desktop process.
+func (r *DesktopUsersProcessesRunner) restartBackoff(uid string) *supervisor.Backoff {
+	r.restartBackoffsLock.Lock()
+	defer r.restartBackoffsLock.Unlock()
+
+	if b, ok := r.restartBackoffs[uid]; ok {
+		return b
+	}
+	b := supervisor.NewBackoff(desktopMinRestartDelay, desktopMaxRestartDelay, desktopStableAfter)
+	r.restartBackoffs[uid] = b
+	return b
+}
+
+// ProcessStatuses implements supervisor.Reporter, reporting on each user's desktop process.
+func (r *DesktopUsersProcessesRunner) ProcessStatuses() []supervisor.Status {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r.restartBackoffsLock.Lock()
+	defer r.restartBackoffsLock.Unlock()
+
+	var statuses []supervisor.Status
+	for uid, proc := range r.uidProcs {
+		status := supervisor.Status{
+			Name:            "desktop",
+			Instance:        uid,
+			State:           supervisor.StateExited,
+			StartTime:       proc.StartTime,
+			LastHealthCheck: proc.LastHealthCheck,
+		}
+		if b, ok := r.restartBackoffs[uid]; ok {
+			status.Restarts = b.Restarts()
+		}
+		if running, _ := supervisor.ProcessMatches(ctx, proc.Process.Pid, proc.path); running {
+			status.State = supervisor.StateRunning
+			status.Pid = proc.Process.Pid
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Users whose desktop process died, and is waiting to be respawned
+	for uid, b := range r.restartBackoffs {
+		if _, ok := r.uidProcs[uid]; ok || b.Ready() {
+			continue
+		}
+		statuses = append(statuses, supervisor.Status{
+			Name:      "desktop",
+			Instance:  uid,
+			State:     supervisor.StateBackingOff,
+			Restarts:  b.Restarts(),
+			NextStart: b.NextStart(),
+		})
+	}
+
+	return statuses
+}
+
+// userServerClientOpts returns the options for a client of the given desktop process's user server.
+// On Windows, we start the desktop process directly, so we can verify that it is the process serving
+// the named pipe. Elsewhere, the desktop process is started via a wrapper, so its pid isn't known.
+func userServerClientOpts(proc *os.Process) []client.ClientOption {
+	if runtime.GOOS != "windows" || proc == nil {
+		return nil
+	}
+	return []client.ClientOption{client.WithExpectedServerPid(proc.Pid)}
+}
+
 // addProcessTrackingRecordForUser adds process information to the internal tracking state
 func (r *DesktopUsersProcessesRunner) addProcessTrackingRecordForUser(uid string, socketPath string, osProcess *os.Process) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
@@ -865,7 +977,7 @@ func (r *DesktopUsersProcessesRunner) userHasDesktopProcess(uid string) bool {
 		return false
 	}
 
-	// have a record of process, but it died for some reason, log it
+	// have a record of process, but it died for some reason, log it and schedule its restart
 	if !r.processExists(proc) {
 		r.slogger.Log(context.TODO(), slog.LevelInfo,
 			"found existing desktop process dead for console user",
@@ -874,6 +986,8 @@ func (r *DesktopUsersProcessesRunner) userHasDesktopProcess(uid string) bool {
 			"uid", uid,
 		)
 
+		r.restartBackoff(uid).Exited(time.Since(proc.StartTime))
+		delete(r.uidProcs, uid)
 		return false
 	}
 
@@ -888,26 +1002,13 @@ func (r *DesktopUsersProcessesRunner) processExists(processRecord processRecord)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
 
-	// the call to process.NewProcessWithContext ensures process exists
-	proc, err := process.NewProcessWithContext(ctx, int32(processRecord.Process.Pid))
-	if err != nil {
-		r.slogger.Log(ctx, slog.LevelInfo,
-			"error checking existing desktop process",
-			"pid", processRecord.Process.Pid,
-			"process_path", processRecord.path,
-			"err", err,
-		)
-		return false
-	}
-
-	path, err := proc.ExeWithContext(ctx)
-	if err != nil || path != processRecord.path {
+	matches, err := supervisor.ProcessMatches(ctx, processRecord.Process.Pid, processRecord.path)
+	if err != nil || !matches {
 		r.slogger.Log(ctx, slog.LevelInfo,
-			"error or path mismatch checking existing desktop process path",
+			"error or path mismatch checking existing desktop process",
 			"pid", processRecord.Process.Pid,
 			"process_record_path", processRecord.path,
 			"err", err,
-			"found_path", path,
 		)
 		return false
 	}
@@ -915,13 +1016,21 @@ func (r *DesktopUsersProcessesRunner) processExists(processRecord processRecord)
 	return true
 }
 
-// setupSocketPath returns standard pipe path for windows.
+// setupSocketPath returns a per-user pipe path for windows.
 // On posix systems, it creates a directory and changes owner to the user,
 // deletes any existing desktop sockets in the directory,
 // then provides a path to the socket in that folder.
 func (r *DesktopUsersProcessesRunner) setupSocketPath(uid string) (string, error) {
 	if runtime.GOOS == "windows" {
-		return fmt.Sprintf(`\\.\pipe\kolide_desktop_%s`, ulid.New()), nil
+		// Include the user, so that each user's pipe is distinct and identifiable. On Windows, the
+		// uid is a username, possibly including a domain; backslashes are not allowed in pipe names.
+		pipeUser := strings.Map(func(c rune) rune {
+			if c == '\\' || c == '/' || c == ' ' {
+				return '_'
+			}
+			return c
+		}, uid)
+		return fmt.Sprintf(`\\.\pipe\kolide_desktop_%s_%s`, pipeUser, ulid.New()), nil
 	}
 
 	userFolderPath := filepath.Join(r.usersFilesRoot, fmt.Sprintf("desktop_%s", uid))
@@ -982,6 +1091,7 @@ func (r *DesktopUsersProcessesRunner) desktopCommand(executablePath, uid, socket
 		fmt.Sprintf("HOSTNAME=%s", r.hostname),
 		fmt.Sprintf("USER_SERVER_AUTH_TOKEN=%s", r.userServerAuthToken),
 		fmt.Sprintf("USER_SERVER_SOCKET_PATH=%s", socketPath),
+		fmt.Sprintf("USER_SERVER_PIPE_SECURITY_DESCRIPTOR=%s", r.knapsack.DesktopPipeSecurityDescriptor()),
 		fmt.Sprintf("ICON_PATH=%s", r.iconFileLocation()),
 		fmt.Sprintf("MENU_PATH=%s", menuPath),
 		fmt.Sprintf("PPID=%d", os.Getpid()),
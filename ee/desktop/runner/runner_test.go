@@ -16,6 +16,7 @@ import (
 
 	"github.com/kolide/kit/ulid"
 	"github.com/kolide/launcher/ee/agent/flags/keys"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
 	"github.com/kolide/launcher/ee/agent/types/mocks"
 	"github.com/kolide/launcher/ee/desktop/user/notify"
 	"github.com/kolide/launcher/ee/presencedetection"
@@ -129,6 +130,8 @@ func TestDesktopUserProcessRunner_Execute(t *testing.T) {
 			mockKnapsack.On("DesktopUpdateInterval").Return(time.Millisecond * 250)
 			mockKnapsack.On("DesktopMenuRefreshInterval").Return(time.Millisecond * 250)
 			mockKnapsack.On("KolideServerURL").Return("somewhere-over-the-rainbow.example.com")
+			mockKnapsack.On("ControlStore").Return(inmemory.NewStore()).Maybe()
+			mockKnapsack.On("PersistentHostDataStore").Return(inmemory.NewStore()).Maybe()
 
 			// if were not in CI, always exepect desktop enabled call
 			// if we are in CI only expect desktop enabled on windows and darwin
@@ -140,6 +143,7 @@ func TestDesktopUserProcessRunner_Execute(t *testing.T) {
 			mockKnapsack.On("Slogger").Return(slogger)
 			mockKnapsack.On("InModernStandby").Return(false)
 			mockKnapsack.On("SystrayRestartEnabled").Return(false).Maybe()
+			mockKnapsack.On("DesktopPipeSecurityDescriptor").Return("").Maybe()
 
 			if os.Getenv("CI") != "true" || runtime.GOOS != "linux" {
 				// Only expect that we call Debug (to set the DEBUG flag on the process) if we actually expect
@@ -372,6 +376,8 @@ func TestUpdate(t *testing.T) {
 			mockKnapsack.On("DesktopUpdateInterval").Return(time.Millisecond * 250)
 			mockKnapsack.On("DesktopMenuRefreshInterval").Return(time.Millisecond * 250)
 			mockKnapsack.On("KolideServerURL").Return("somewhere-over-the-rainbow.example.com")
+			mockKnapsack.On("ControlStore").Return(inmemory.NewStore()).Maybe()
+			mockKnapsack.On("PersistentHostDataStore").Return(inmemory.NewStore()).Maybe()
 			mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
 			mockKnapsack.On("InModernStandby").Return(false)
 
@@ -406,6 +412,8 @@ func TestSendNotification_NoProcessesYet(t *testing.T) {
 	mockKnapsack.On("DesktopUpdateInterval").Return(time.Millisecond * 250)
 	mockKnapsack.On("DesktopMenuRefreshInterval").Return(time.Millisecond * 250)
 	mockKnapsack.On("KolideServerURL").Return("somewhere-over-the-rainbow.example.com")
+	mockKnapsack.On("ControlStore").Return(inmemory.NewStore()).Maybe()
+	mockKnapsack.On("PersistentHostDataStore").Return(inmemory.NewStore()).Maybe()
 	mockKnapsack.On("DesktopEnabled").Return(true)
 	mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
 	mockKnapsack.On("InModernStandby").Return(false)
@@ -32,11 +32,30 @@ type client struct {
 	base http.Client
 }
 
-func New(authToken, socketPath string) client {
+type clientOptions struct {
+	expectedServerPid int
+}
+
+type ClientOption func(*clientOptions)
+
+// WithExpectedServerPid requires that the server be the desktop process with the given pid,
+// refusing to send requests (and our auth token) to any other process.
+func WithExpectedServerPid(pid int) ClientOption {
+	return func(o *clientOptions) {
+		o.expectedServerPid = pid
+	}
+}
+
+func New(authToken, socketPath string, opts ...ClientOption) client {
+	options := &clientOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	transport := &transport{
 		authToken: authToken,
 		base: http.Transport{
-			DialContext: dialContext(socketPath),
+			DialContext: verifyingDialContext(socketPath, options.expectedServerPid),
 		},
 	}
 
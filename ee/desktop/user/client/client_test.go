@@ -69,6 +69,77 @@ func TestClient_GetAndShutdown(t *testing.T) {
 	}
 }
 
+func TestClient_VerifiesProcesses(t *testing.T) {
+	t.Parallel()
+
+	const validAuthToken = "test-auth-header"
+
+	// This process is both client and server
+	tests := []struct {
+		name              string
+		expectedServerPid int
+		allowedClientPid  int
+		expectedError     bool
+	}{
+		{
+			name:              "verified",
+			expectedServerPid: os.Getpid(),
+			allowedClientPid:  os.Getpid(),
+		},
+		{
+			name:              "unexpected_server",
+			expectedServerPid: os.Getpid() + 1,
+			allowedClientPid:  os.Getpid(),
+			expectedError:     true,
+		},
+		{
+			name:              "unexpected_client",
+			expectedServerPid: os.Getpid(),
+			allowedClientPid:  os.Getpid() + 1,
+			expectedError:     true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			socketPath := testSocketPath(t)
+			server, err := server.New(multislogger.NewNopLogger(), validAuthToken, socketPath, make(chan struct{}), make(chan<- struct{}), nil,
+				server.WithAllowedClientPid(tt.allowedClientPid),
+			)
+			require.NoError(t, err)
+
+			go func() {
+				server.Serve()
+			}()
+
+			client := New(validAuthToken, socketPath, WithExpectedServerPid(tt.expectedServerPid))
+			err = client.Ping()
+			if tt.expectedError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// The connection should have been recorded
+			var found bool
+			for _, r := range RecentConnections() {
+				if r.SocketPath != socketPath {
+					continue
+				}
+				found = true
+				require.Equal(t, tt.expectedServerPid, r.ExpectedPid)
+				require.Equal(t, os.Getpid(), r.ServerPid)
+				require.Equal(t, tt.expectedServerPid == os.Getpid(), r.Verified)
+			}
+			require.True(t, found, "connection was not recorded")
+
+			assert.NoError(t, server.Shutdown(context.Background()))
+		})
+	}
+}
+
 func testSocketPath(t *testing.T) string {
 	socketFileName := strings.Replace(t.Name(), "/", "_", -1)
 
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/ee/desktop/user/server"
+)
+
+// maxConnectionRecords is how many recent connections we keep for auditing.
+const maxConnectionRecords = 100
+
+// ConnectionRecord describes a connection made to a desktop user server.
+type ConnectionRecord struct {
+	Time        time.Time
+	SocketPath  string
+	ExpectedPid int // the desktop process we expected to be serving the socket; 0 if unverified
+	ServerPid   int // the process actually serving the socket; 0 if unknown
+	Verified    bool
+	Err         string
+}
+
+var (
+	connectionRecords     []ConnectionRecord
+	connectionRecordsLock sync.Mutex
+)
+
+func recordConnection(r ConnectionRecord) {
+	connectionRecordsLock.Lock()
+	defer connectionRecordsLock.Unlock()
+
+	connectionRecords = append(connectionRecords, r)
+	if len(connectionRecords) > maxConnectionRecords {
+		connectionRecords = connectionRecords[len(connectionRecords)-maxConnectionRecords:]
+	}
+}
+
+// RecentConnections returns the most recent connections made to desktop user servers, oldest first.
+func RecentConnections() []ConnectionRecord {
+	connectionRecordsLock.Lock()
+	defer connectionRecordsLock.Unlock()
+
+	records := make([]ConnectionRecord, len(connectionRecords))
+	copy(records, connectionRecords)
+	return records
+}
+
+// verifyingDialContext wraps dialContext, checking that the server on the other end of each
+// connection is the expected desktop process -- otherwise, another local user could create the
+// socket or pipe first and receive our auth token. Every connection is recorded for auditing.
+func verifyingDialContext(socketPath string, expectedServerPid int) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := dialContext(socketPath)
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		record := ConnectionRecord{
+			Time:        time.Now().UTC(),
+			SocketPath:  socketPath,
+			ExpectedPid: expectedServerPid,
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			record.Err = err.Error()
+			recordConnection(record)
+			return nil, err
+		}
+
+		serverPid, pidErr := server.ServerPid(conn)
+		if pidErr == nil {
+			record.ServerPid = serverPid
+		}
+
+		if expectedServerPid != 0 {
+			switch {
+			case pidErr != nil:
+				err = fmt.Errorf("verifying desktop server process: %w", pidErr)
+			case serverPid != expectedServerPid:
+				err = fmt.Errorf("desktop server process is %d, expected %d", serverPid, expectedServerPid)
+			default:
+				record.Verified = true
+			}
+		}
+
+		if err != nil {
+			record.Err = err.Error()
+			recordConnection(record)
+			conn.Close()
+			return nil, err
+		}
+
+		recordConnection(record)
+		return conn, nil
+	}
+}
@@ -1,3 +1,6 @@
+//go:build !minimal
+// +build !minimal
+
 package menu
 
 import (
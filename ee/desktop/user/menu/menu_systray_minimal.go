@@ -0,0 +1,29 @@
+//go:build minimal
+// +build minimal
+
+package menu
+
+// Minimal builds exclude desktop, so there's no menu bar to draw the menu in. The menu's
+// templates are still used to prepare menu data.
+
+// systrayDarkMode is never set, without systray to report the appearance
+var systrayDarkMode bool // nolint:unused
+
+// Init returns immediately, since there's no menu bar.
+func (m *menu) Init() {}
+
+// Build does nothing, since there's no menu bar.
+func (m *menu) Build() {}
+
+func (m *menu) setIcon(icon menuIcon) {}
+
+func (m *menu) setTooltip(tooltip string) {}
+
+func (m *menu) addMenuItem(label, tooltip string, disabled bool, ap ActionPerformer, parent any) any {
+	return nil
+}
+
+func (m *menu) addSeparator() {}
+
+// Shutdown does nothing, since Init doesn't block.
+func (m *menu) Shutdown() {}
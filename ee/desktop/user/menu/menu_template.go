@@ -16,6 +16,7 @@ const (
 	errorlessTemplateVars = "errorlessTemplateVars" // capability to evaluate undefined template vars without failing
 	errorlessActions      = "errorlessActions"      // capability to evaluate undefined menu item actions without failing
 	circleDot             = "circleDot"             // capability to use circle-dot icon
+	dataCollectionConsent = "dataCollectionConsent" // capability to show data collection consent state, and send the user's decision
 
 	// TemplateData keys
 	LauncherVersion    string = "LauncherVersion"
@@ -24,6 +25,10 @@ const (
 	ServerHostname     string = "ServerHostname"
 	LastMenuUpdateTime string = "LastMenuUpdateTime"
 	MenuVersion        string = "MenuVersion"
+
+	// Data collection consent under privacy mode; see the consent package
+	ConsentState         string = "ConsentState"
+	ConsentPolicyVersion string = "ConsentPolicyVersion"
 )
 
 type TemplateData map[string]interface{}
@@ -59,6 +64,8 @@ func (tp *templateParser) Parse(text string) (string, error) {
 				return true
 			case circleDot:
 				return true
+			case dataCollectionConsent:
+				return true
 			}
 			return false
 		},
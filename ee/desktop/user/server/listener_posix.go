@@ -4,9 +4,48 @@
 package server
 
 import (
+	"fmt"
 	"net"
+	"syscall"
 )
 
-func listener(socketPath string) (net.Listener, error) {
+// listener creates the unix socket for the user server. Access is controlled by the permissions
+// on the socket's directory, so the security descriptor is ignored.
+func listener(socketPath string, _ string) (net.Listener, error) {
 	return net.Listen("unix", socketPath)
 }
+
+// ClientPid returns the process ID of the client on the other end of the socket connection.
+func ClientPid(conn net.Conn) (int, error) {
+	return peerPid(conn)
+}
+
+// ServerPid returns the process ID of the server on the other end of the socket connection.
+func ServerPid(conn net.Conn) (int, error) {
+	return peerPid(conn)
+}
+
+func peerPid(conn net.Conn) (int, error) {
+	syscallConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return 0, fmt.Errorf("connection of type %T does not support peer credentials", conn)
+	}
+
+	rawConn, err := syscallConn.SyscallConn()
+	if err != nil {
+		return 0, fmt.Errorf("getting raw connection: %w", err)
+	}
+
+	var pid int
+	var pidErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		pid, pidErr = socketPeerPid(fd)
+	}); err != nil {
+		return 0, fmt.Errorf("accessing socket: %w", err)
+	}
+	if pidErr != nil {
+		return 0, fmt.Errorf("getting socket peer pid: %w", pidErr)
+	}
+
+	return pid, nil
+}
@@ -4,11 +4,73 @@
 package server
 
 import (
+	"fmt"
 	"net"
 
 	"github.com/Microsoft/go-winio"
+	"golang.org/x/sys/windows"
 )
 
-func listener(socketPath string) (net.Listener, error) {
-	return winio.ListenPipe(socketPath, nil)
+// listener creates the named pipe for the user server. Unless a security descriptor is provided,
+// only SYSTEM (i.e. root launcher) and the current user may connect to it -- by default, named
+// pipes may be read by everyone.
+func listener(socketPath string, securityDescriptor string) (net.Listener, error) {
+	if securityDescriptor == "" {
+		var err error
+		securityDescriptor, err = defaultSecurityDescriptor()
+		if err != nil {
+			return nil, fmt.Errorf("building default security descriptor: %w", err)
+		}
+	}
+
+	return winio.ListenPipe(socketPath, &winio.PipeConfig{
+		SecurityDescriptor: securityDescriptor,
+	})
+}
+
+// defaultSecurityDescriptor returns an SDDL string granting full access to SYSTEM and the
+// current user, and denying everyone else.
+func defaultSecurityDescriptor() (string, error) {
+	tokenUser, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return "", fmt.Errorf("getting current user: %w", err)
+	}
+
+	return fmt.Sprintf("D:P(A;;GA;;;SY)(A;;GA;;;%s)", tokenUser.User.Sid.String()), nil
+}
+
+// ClientPid returns the process ID of the client on the other end of the named pipe connection.
+func ClientPid(conn net.Conn) (int, error) {
+	handle, err := pipeHandle(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	var pid uint32
+	if err := windows.GetNamedPipeClientProcessId(handle, &pid); err != nil {
+		return 0, fmt.Errorf("getting named pipe client process id: %w", err)
+	}
+	return int(pid), nil
+}
+
+// ServerPid returns the process ID of the server on the other end of the named pipe connection.
+func ServerPid(conn net.Conn) (int, error) {
+	handle, err := pipeHandle(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	var pid uint32
+	if err := windows.GetNamedPipeServerProcessId(handle, &pid); err != nil {
+		return 0, fmt.Errorf("getting named pipe server process id: %w", err)
+	}
+	return int(pid), nil
+}
+
+func pipeHandle(conn net.Conn) (windows.Handle, error) {
+	fdConn, ok := conn.(interface{ Fd() uintptr })
+	if !ok {
+		return 0, fmt.Errorf("connection of type %T is not a named pipe", conn)
+	}
+	return windows.Handle(fdConn.Fd()), nil
 }
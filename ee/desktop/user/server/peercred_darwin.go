@@ -0,0 +1,10 @@
+//go:build darwin
+// +build darwin
+
+package server
+
+import "golang.org/x/sys/unix"
+
+func socketPeerPid(fd uintptr) (int, error) {
+	return unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+}
@@ -0,0 +1,14 @@
+//go:build linux
+// +build linux
+
+package server
+
+import "golang.org/x/sys/unix"
+
+func socketPeerPid(fd uintptr) (int, error) {
+	ucred, err := unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return 0, err
+	}
+	return int(ucred.Pid), nil
+}
@@ -38,6 +38,27 @@ type UserServer struct {
 	refreshListeners    []func()
 	presenceDetector    presencedetection.PresenceDetector
 	showDesktopOnceFunc func()
+	securityDescriptor  string
+	allowedClientPid    int
+}
+
+type UserServerOption func(*UserServer)
+
+// WithPipeSecurityDescriptor sets the SDDL security descriptor for the server's named pipe,
+// overriding the default. It has no effect on non-Windows platforms.
+func WithPipeSecurityDescriptor(sddl string) UserServerOption {
+	return func(s *UserServer) {
+		s.securityDescriptor = sddl
+	}
+}
+
+// WithAllowedClientPid restricts the server to connections from the given process, i.e. the
+// root launcher process that started this desktop process. Connections from other processes
+// are rejected before they can attempt to authenticate.
+func WithAllowedClientPid(pid int) UserServerOption {
+	return func(s *UserServer) {
+		s.allowedClientPid = pid
+	}
 }
 
 func New(slogger *slog.Logger,
@@ -45,7 +66,8 @@ func New(slogger *slog.Logger,
 	socketPath string,
 	shutdownChan chan<- struct{},
 	showDesktopChan chan<- struct{},
-	notifier notificationSender) (*UserServer, error) {
+	notifier notificationSender,
+	opts ...UserServerOption) (*UserServer, error) {
 	userServer := &UserServer{
 		shutdownChan: shutdownChan,
 		authToken:    authToken,
@@ -60,6 +82,10 @@ func New(slogger *slog.Logger,
 		}),
 	}
 
+	for _, opt := range opts {
+		opt(userServer)
+	}
+
 	authedMux := http.NewServeMux()
 	authedMux.HandleFunc("/shutdown", userServer.shutdownHandler)
 	authedMux.HandleFunc("/ping", userServer.pingHandler)
@@ -82,12 +108,20 @@ func New(slogger *slog.Logger,
 		return nil, err
 	}
 
-	listener, err := listener(socketPath)
+	listener, err := listener(socketPath, userServer.securityDescriptor)
 	if err != nil {
 		return nil, err
 	}
 	userServer.listener = listener
 
+	if userServer.allowedClientPid != 0 {
+		userServer.listener = &clientVerifyingListener{
+			Listener:         listener,
+			slogger:          userServer.slogger,
+			allowedClientPid: userServer.allowedClientPid,
+		}
+	}
+
 	userServer.server.RegisterOnShutdown(func() {
 		// remove socket on shutdown
 		if err := userServer.removeSocket(); err != nil {
@@ -277,6 +311,35 @@ func (s *UserServer) authMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// clientVerifyingListener only accepts connections from the allowed client process.
+type clientVerifyingListener struct {
+	net.Listener
+	slogger          *slog.Logger
+	allowedClientPid int
+}
+
+func (l *clientVerifyingListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		clientPid, err := ClientPid(conn)
+		if err == nil && clientPid == l.allowedClientPid {
+			return conn, nil
+		}
+
+		l.slogger.Log(context.TODO(), slog.LevelWarn,
+			"rejecting connection from unexpected client",
+			"client_pid", clientPid,
+			"allowed_client_pid", l.allowedClientPid,
+			"err", err,
+		)
+		conn.Close()
+	}
+}
+
 // removeSocket is a helper function to remove the socket file. The reason it exists is that
 // on windows, you can't delete a file that is opened by another resource. When the server
 // shuts down, there is some lag time before the file is release, this can cause errors
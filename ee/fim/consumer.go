@@ -0,0 +1,43 @@
+package fim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+// Consumer receives the FIM spec from the control server and stores it. Specs that
+// can't be parsed are rejected; invalid paths within a valid spec are stored as-is,
+// and are reported via the kolide_fim_config table when the spec is applied.
+type Consumer struct {
+	store types.Setter
+}
+
+func NewConsumer(store types.Setter) *Consumer {
+	return &Consumer{
+		store: store,
+	}
+}
+
+func (c *Consumer) Update(data io.Reader) error {
+	if c == nil || c.store == nil {
+		return errors.New("FIM consumer is nil")
+	}
+
+	specBytes, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("reading FIM spec: %w", err)
+	}
+
+	if _, err := ParseSpec(specBytes); err != nil {
+		return err
+	}
+
+	if err := c.store.Set(specKey, specBytes); err != nil {
+		return fmt.Errorf("storing FIM spec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+// Package fim translates a high-level file integrity monitoring spec, sent by the control
+// server, into the osquery configuration and flags needed to implement it on the current
+// platform. osquery's FIM configuration differs by platform and fails silently on bad paths,
+// so launcher validates the spec and reports what it actually applied.
+package fim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+// specKey is the key the spec is stored under in the FIM config store
+var specKey = []byte("spec")
+
+var categoryRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+// Windows paths must start with a drive letter, e.g. C:\
+var windowsAbsPathRegexp = regexp.MustCompile(`^[a-zA-Z]:\\`)
+
+// Spec is the high-level FIM spec sent by the control server.
+type Spec struct {
+	// Categories maps osquery FIM categories to the paths to monitor in each
+	Categories map[string]CategorySpec `json:"categories"`
+	// Hashing requests that file events include hashes. Unset means the platform default.
+	Hashing *bool `json:"hashing,omitempty"`
+}
+
+type CategorySpec struct {
+	Paths        []string `json:"paths"`
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+}
+
+// The parts of a spec that a Problem can refer to
+const (
+	KindCategory    = "category"
+	KindFilePath    = "file_path"
+	KindExcludePath = "exclude_path"
+	KindHashing     = "hashing"
+)
+
+// Problem describes part of the spec that could not be applied as written.
+type Problem struct {
+	Category string
+	Kind     string
+	Value    string
+	Message  string
+}
+
+// Effective is the FIM configuration launcher applies on a platform, after validation.
+type Effective struct {
+	FilePaths    map[string][]string
+	ExcludePaths map[string][]string
+	Hashing      bool
+	Flags        []string
+	Problems     []Problem
+}
+
+// ParseSpec parses a FIM spec.
+func ParseSpec(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("unmarshalling FIM spec: %w", err)
+	}
+
+	return &spec, nil
+}
+
+// LoadSpec loads the FIM spec from the store. It returns nil if no spec has been set.
+func LoadSpec(store types.Getter) (*Spec, error) {
+	if store == nil {
+		return nil, errors.New("no FIM config store")
+	}
+
+	data, err := store.Get(specKey)
+	if err != nil {
+		return nil, fmt.Errorf("getting FIM spec from store: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	return ParseSpec(data)
+}
+
+// LoadEffective loads the FIM spec from the store and translates it for goos. If no spec
+// has been set, FIM is disabled, and the returned configuration is empty.
+func LoadEffective(store types.Getter, goos string) (*Effective, error) {
+	spec, err := LoadSpec(store)
+	if err != nil {
+		return nil, err
+	}
+
+	return Translate(spec, goos), nil
+}
+
+// Translate validates the spec and translates it into the osquery configuration for goos.
+// Invalid categories and paths are dropped, and reported in Problems.
+func Translate(spec *Spec, goos string) *Effective {
+	e := &Effective{
+		FilePaths:    make(map[string][]string),
+		ExcludePaths: make(map[string][]string),
+		Hashing:      platformHashes(goos),
+	}
+
+	if spec == nil {
+		return e
+	}
+
+	categories := make([]string, 0, len(spec.Categories))
+	for category := range spec.Categories {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for _, category := range categories {
+		if !categoryRegexp.MatchString(category) {
+			e.problem(category, KindCategory, category, "invalid category name: must contain only letters, numbers, underscores, and hyphens")
+			continue
+		}
+
+		categorySpec := spec.Categories[category]
+
+		var paths []string
+		for _, path := range categorySpec.Paths {
+			normalized, err := normalizePath(path, goos)
+			if err != nil {
+				e.problem(category, KindFilePath, path, err.Error())
+				continue
+			}
+			paths = append(paths, normalized)
+		}
+
+		if len(paths) == 0 {
+			e.problem(category, KindCategory, category, "category has no valid paths to monitor, ignoring it")
+			continue
+		}
+		e.FilePaths[category] = paths
+
+		for _, path := range categorySpec.ExcludePaths {
+			normalized, err := normalizePath(path, goos)
+			if err != nil {
+				e.problem(category, KindExcludePath, path, err.Error())
+				continue
+			}
+			e.ExcludePaths[category] = append(e.ExcludePaths[category], normalized)
+		}
+	}
+
+	if spec.Hashing != nil && *spec.Hashing != e.Hashing {
+		requested := strconv.FormatBool(*spec.Hashing)
+		if e.Hashing {
+			e.problem("", KindHashing, requested, "osquery always hashes created and updated files on this platform")
+		} else {
+			e.problem("", KindHashing, requested, "osquery does not hash files on this platform")
+		}
+	}
+
+	if len(e.FilePaths) > 0 {
+		e.Flags = platformFlags(goos)
+	}
+
+	return e
+}
+
+func (e *Effective) problem(category, kind, value, message string) {
+	e.Problems = append(e.Problems, Problem{Category: category, Kind: kind, Value: value, Message: message})
+}
+
+// Enabled returns true if there are any paths to monitor.
+func (e *Effective) Enabled() bool {
+	return len(e.FilePaths) > 0
+}
+
+// OsqueryConfig returns the osquery config containing the FIM paths, suitable for merging
+// with the other configs returned by our config plugin.
+func (e *Effective) OsqueryConfig() (string, error) {
+	cfg := map[string]map[string][]string{
+		"file_paths": e.FilePaths,
+	}
+	if len(e.ExcludePaths) > 0 {
+		cfg["exclude_paths"] = e.ExcludePaths
+	}
+
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("marshalling FIM config: %w", err)
+	}
+
+	return string(cfgBytes), nil
+}
+
+// platformFlags returns the osqueryd flags that enable the FIM event publisher for goos.
+func platformFlags(goos string) []string {
+	switch goos {
+	case "windows":
+		return []string{"--disable_events=false", "--enable_ntfs_event_publisher=true"}
+	default:
+		return []string{"--disable_events=false", "--enable_file_events=true"}
+	}
+}
+
+// platformHashes returns whether osquery hashes files for file events on goos. The
+// inotify and FSEvents publishers hash created and updated files; ntfs_journal_events
+// never does.
+func platformHashes(goos string) bool {
+	return goos != "windows"
+}
+
+// normalizePath validates a FIM path for goos, returning it in the form osquery expects.
+// osquery supports two wildcards: % matches within a single path component, and %%, which
+// may only end a path, matches everything below it recursively.
+func normalizePath(path string, goos string) (string, error) {
+	if path == "" || strings.TrimSpace(path) != path {
+		return "", errors.New("path must be non-empty, without leading or trailing whitespace")
+	}
+
+	separator := "/"
+	if goos == "windows" {
+		separator = `\`
+		path = strings.ReplaceAll(path, "/", `\`)
+		if !windowsAbsPathRegexp.MatchString(path) {
+			return "", errors.New(`path must be absolute, starting with a drive letter, e.g. C:\`)
+		}
+	} else if !strings.HasPrefix(path, "/") {
+		return "", errors.New("path must be absolute")
+	}
+
+	if strings.ContainsAny(path, "*?[") {
+		return "", errors.New("glob wildcards are not supported: use % to match within a directory, or %% to match recursively")
+	}
+
+	components := strings.Split(path, separator)
+	for i, component := range components {
+		if component == ".." {
+			return "", errors.New("path must not contain .. components")
+		}
+		if strings.Contains(component, "%%") && (component != "%%" || i != len(components)-1) {
+			return "", errors.New("%% may only be used as the final path component")
+		}
+	}
+
+	return path, nil
+}
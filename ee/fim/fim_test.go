@@ -0,0 +1,156 @@
+package fim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	storageci "github.com/kolide/launcher/ee/agent/storage/ci"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_normalizePath(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name          string
+		path          string
+		goos          string
+		expected      string
+		expectedError bool
+	}{
+		{name: "posix file", path: "/etc/passwd", goos: "linux", expected: "/etc/passwd"},
+		{name: "posix directory wildcard", path: "/etc/%", goos: "darwin", expected: "/etc/%"},
+		{name: "posix partial wildcard", path: "/Users/%/Library/LaunchAgents/%", goos: "darwin", expected: "/Users/%/Library/LaunchAgents/%"},
+		{name: "posix recursive wildcard", path: "/usr/bin/%%", goos: "linux", expected: "/usr/bin/%%"},
+		{name: "posix relative", path: "etc/passwd", goos: "linux", expectedError: true},
+		{name: "posix glob", path: "/etc/*.conf", goos: "linux", expectedError: true},
+		{name: "posix recursive wildcard in middle", path: "/home/%%/.ssh", goos: "linux", expectedError: true},
+		{name: "posix recursive wildcard with suffix", path: "/home/foo%%", goos: "linux", expectedError: true},
+		{name: "posix parent directory", path: "/etc/../root", goos: "linux", expectedError: true},
+		{name: "empty", path: "", goos: "linux", expectedError: true},
+		{name: "whitespace", path: " /etc/passwd", goos: "linux", expectedError: true},
+		{name: "windows file", path: `C:\Windows\System32\drivers\etc\hosts`, goos: "windows", expected: `C:\Windows\System32\drivers\etc\hosts`},
+		{name: "windows forward slashes", path: "C:/Windows/System32/%%", goos: "windows", expected: `C:\Windows\System32\%%`},
+		{name: "windows no drive letter", path: `\Windows\System32`, goos: "windows", expectedError: true},
+		{name: "windows posix path", path: "/etc/passwd", goos: "windows", expectedError: true},
+		{name: "windows glob", path: `C:\Users\?\Desktop`, goos: "windows", expectedError: true},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			normalized, err := normalizePath(tt.path, tt.goos)
+			if tt.expectedError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, normalized)
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+
+	hashing := false
+	spec := &Spec{
+		Categories: map[string]CategorySpec{
+			"etc": {
+				Paths:        []string{"/etc/%%", "etc/relative"},
+				ExcludePaths: []string{"/etc/mtab", "/etc/*.swp"},
+			},
+			"bad category!": {
+				Paths: []string{"/tmp/%"},
+			},
+			"all_invalid": {
+				Paths: []string{"/tmp/*"},
+			},
+		},
+		Hashing: &hashing,
+	}
+
+	linux := Translate(spec, "linux")
+	require.True(t, linux.Enabled())
+	require.Equal(t, map[string][]string{"etc": {"/etc/%%"}}, linux.FilePaths)
+	require.Equal(t, map[string][]string{"etc": {"/etc/mtab"}}, linux.ExcludePaths)
+	require.Equal(t, []string{"--disable_events=false", "--enable_file_events=true"}, linux.Flags)
+	require.True(t, linux.Hashing, "osquery always hashes on linux")
+
+	problemKinds := make(map[string]int)
+	for _, p := range linux.Problems {
+		problemKinds[p.Kind] += 1
+	}
+	require.Equal(t, map[string]int{
+		KindCategory:    2, // invalid name, and no valid paths
+		KindFilePath:    2,
+		KindExcludePath: 1,
+		KindHashing:     1,
+	}, problemKinds)
+
+	windows := Translate(spec, "windows")
+	require.False(t, windows.Enabled(), "no posix paths are valid on windows")
+	require.Empty(t, windows.Flags)
+	require.False(t, windows.Hashing)
+	for _, p := range windows.Problems {
+		require.NotEqual(t, KindHashing, p.Kind, "hashing off is honored on windows")
+	}
+}
+
+func TestTranslate_NoSpec(t *testing.T) {
+	t.Parallel()
+
+	e := Translate(nil, "darwin")
+	require.False(t, e.Enabled())
+	require.Empty(t, e.Flags)
+	require.Empty(t, e.Problems)
+}
+
+func TestOsqueryConfig(t *testing.T) {
+	t.Parallel()
+
+	e := Translate(&Spec{
+		Categories: map[string]CategorySpec{
+			"ssh": {Paths: []string{"/root/.ssh/%"}, ExcludePaths: []string{"/root/.ssh/known_hosts"}},
+		},
+	}, "linux")
+
+	cfg, err := e.OsqueryConfig()
+	require.NoError(t, err)
+
+	var parsed map[string]map[string][]string
+	require.NoError(t, json.Unmarshal([]byte(cfg), &parsed))
+	require.Equal(t, []string{"/root/.ssh/%"}, parsed["file_paths"]["ssh"])
+	require.Equal(t, []string{"/root/.ssh/known_hosts"}, parsed["exclude_paths"]["ssh"])
+}
+
+func TestConsumer(t *testing.T) {
+	t.Parallel()
+
+	store, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.FimConfigStore.String())
+	require.NoError(t, err)
+
+	// Nothing stored yet
+	spec, err := LoadSpec(store)
+	require.NoError(t, err)
+	require.Nil(t, spec)
+
+	c := NewConsumer(store)
+	require.Error(t, c.Update(strings.NewReader("not json")))
+
+	require.NoError(t, c.Update(strings.NewReader(`{"categories":{"etc":{"paths":["/etc/%%"]}},"hashing":true}`)))
+
+	spec, err = LoadSpec(store)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/etc/%%"}, spec.Categories["etc"].Paths)
+	require.NotNil(t, spec.Hashing)
+	require.True(t, *spec.Hashing)
+
+	e, err := LoadEffective(store, "linux")
+	require.NoError(t, err)
+	require.True(t, e.Enabled())
+}
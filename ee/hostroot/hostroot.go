@@ -0,0 +1,51 @@
+// Package hostroot supports running launcher in a container, monitoring the host the
+// container runs on rather than the container itself. The host's filesystem is mounted
+// into the container, e.g. at /hostfs, and launcher is started with --host_root pointing
+// at it. Code that reads well-known host files, or runs host commands, resolves them
+// against the host root.
+package hostroot
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+var (
+	hostRoot     string
+	hostRootLock sync.RWMutex
+)
+
+// Set sets the location the host's filesystem is mounted at. It should be called once
+// at startup, before any tables are created. An empty host root, or /, means launcher
+// is running directly on the host.
+func Set(root string) {
+	hostRootLock.Lock()
+	defer hostRootLock.Unlock()
+
+	if root != "" {
+		root = filepath.Clean(root)
+	}
+	if root == string(filepath.Separator) {
+		root = ""
+	}
+	hostRoot = root
+}
+
+// Root returns the location the host's filesystem is mounted at, or an empty
+// string if launcher is running directly on the host.
+func Root() string {
+	hostRootLock.RLock()
+	defer hostRootLock.RUnlock()
+
+	return hostRoot
+}
+
+// Path returns the location of the given absolute host path, as launcher sees it.
+func Path(path string) string {
+	root := Root()
+	if root == "" {
+		return path
+	}
+
+	return filepath.Join(root, path)
+}
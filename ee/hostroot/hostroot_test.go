@@ -0,0 +1,58 @@
+package hostroot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// These tests share the package-level host root, so they do not run in parallel.
+
+func TestPath(t *testing.T) {
+	t.Cleanup(func() { Set("") })
+
+	for _, tt := range []struct {
+		name         string
+		root         string
+		expectedRoot string
+		path         string
+		expectedPath string
+	}{
+		{
+			name:         "not set",
+			root:         "",
+			expectedRoot: "",
+			path:         "/etc/hosts",
+			expectedPath: "/etc/hosts",
+		},
+		{
+			name:         "filesystem root",
+			root:         "/",
+			expectedRoot: "",
+			path:         "/etc/hosts",
+			expectedPath: "/etc/hosts",
+		},
+		{
+			name:         "host root",
+			root:         "/hostfs",
+			expectedRoot: filepath.Clean("/hostfs"),
+			path:         "/etc/hosts",
+			expectedPath: filepath.Join("/hostfs", "etc", "hosts"),
+		},
+		{
+			name:         "host root with trailing slash",
+			root:         "/hostfs/",
+			expectedRoot: filepath.Clean("/hostfs"),
+			path:         "/home",
+			expectedPath: filepath.Join("/hostfs", "home"),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			Set(tt.root)
+			require.Equal(t, tt.expectedRoot, Root())
+			require.Equal(t, tt.expectedPath, Path(tt.path))
+		})
+	}
+}
@@ -0,0 +1,532 @@
+// Package hostsfilewatcher watches the hosts file and resolver configuration for changes,
+// recording each change as an event for the kolide_hosts_file_watch table. The last-seen state
+// of each file is checkpointed to launcher's database, so that changes made while launcher
+// (or osquery) was not running are still detected when launcher next starts.
+package hostsfilewatcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+const (
+	// debounceDelay gives editors time to finish writing before we examine a changed file
+	debounceDelay = 1 * time.Second
+
+	// pollInterval is how often we check the files regardless of fsnotify, in case we
+	// miss a notification or cannot watch a directory at all
+	pollInterval = 5 * time.Minute
+
+	// maxDiffSize is the largest file we will store the contents of, to report added and removed lines
+	maxDiffSize = 256 * 1024
+
+	// eventRetention and maxEvents bound how many events we keep
+	eventRetention = 30 * 24 * time.Hour
+	maxEvents      = 500
+
+	checkpointKeyPrefix = "checkpoint:"
+	eventKeyPrefix      = "event:"
+)
+
+// Actions
+const (
+	ActionCreated  = "created"
+	ActionModified = "modified"
+	ActionDeleted  = "deleted"
+)
+
+// Sources describe how a change was detected
+const (
+	SourceStartup  = "startup"  // the file changed while launcher was not running
+	SourceFsnotify = "fsnotify" // launcher was notified of the change
+	SourcePoll     = "poll"     // launcher noticed the change during a periodic check
+)
+
+// Event is a single change to a watched file.
+type Event struct {
+	Time           int64    `json:"time"`
+	Path           string   `json:"path"`
+	Action         string   `json:"action"`
+	Source         string   `json:"source"`
+	Sha256         string   `json:"sha256,omitempty"`
+	PreviousSha256 string   `json:"previous_sha256,omitempty"`
+	Size           int64    `json:"size"`
+	Added          []string `json:"added,omitempty"`
+	Removed        []string `json:"removed,omitempty"`
+}
+
+// checkpoint is the last-seen state of a watched file.
+type checkpoint struct {
+	Exists  bool     `json:"exists"`
+	Sha256  string   `json:"sha256,omitempty"`
+	Size    int64    `json:"size"`
+	ModTime int64    `json:"mod_time"`
+	Lines   []string `json:"lines"` // nil when the file is larger than maxDiffSize
+}
+
+type HostsFileWatcher struct {
+	slogger     *slog.Logger
+	store       types.GetterSetterDeleterIterator
+	paths       []string
+	interrupt   chan struct{}
+	interrupted atomic.Bool
+}
+
+func New(k types.Knapsack) *HostsFileWatcher {
+	return &HostsFileWatcher{
+		slogger:   k.Slogger().With("component", "hosts_file_watcher"),
+		store:     k.HostsFileWatchStore(),
+		paths:     watchedPaths(),
+		interrupt: make(chan struct{}, 1),
+	}
+}
+
+func (h *HostsFileWatcher) Execute() error {
+	// Catch anything that changed while we weren't running
+	h.checkAll(SourceStartup)
+
+	var fsEvents <-chan fsnotify.Event
+	var fsErrors <-chan error
+	watcher, watchedNames, err := h.watch()
+	if err != nil {
+		h.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not watch files, will rely on polling",
+			"err", err,
+		)
+	} else {
+		defer watcher.Close()
+		fsEvents = watcher.Events
+		fsErrors = watcher.Errors
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	pending := make(map[string]struct{})
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case event, ok := <-fsEvents:
+			if !ok {
+				fsEvents = nil
+				continue
+			}
+			path, watched := watchedNames[filepath.Clean(event.Name)]
+			if !watched {
+				continue
+			}
+			pending[path] = struct{}{}
+			if debounce == nil {
+				debounce = time.After(debounceDelay)
+			}
+		case err, ok := <-fsErrors:
+			if !ok {
+				fsErrors = nil
+				continue
+			}
+			h.slogger.Log(context.TODO(), slog.LevelWarn,
+				"error from file watcher",
+				"err", err,
+			)
+		case <-debounce:
+			debounce = nil
+			for path := range pending {
+				h.check(path, SourceFsnotify)
+			}
+			clear(pending)
+		case <-ticker.C:
+			h.checkAll(SourcePoll)
+		case <-h.interrupt:
+			h.slogger.Log(context.TODO(), slog.LevelDebug,
+				"interrupt received, exiting execute loop",
+			)
+			return nil
+		}
+	}
+}
+
+func (h *HostsFileWatcher) Interrupt(_ error) {
+	// Only perform shutdown tasks on first call to interrupt -- no need to repeat on potential extra calls.
+	if h.interrupted.Load() {
+		return
+	}
+	h.interrupted.Store(true)
+
+	h.interrupt <- struct{}{}
+}
+
+// watch sets up fsnotify watches. We watch the directories rather than the files themselves,
+// since editors commonly replace a file rather than writing to it. The returned map takes
+// the names fsnotify may report to the watched path they correspond to -- this includes
+// the targets of symlinks, like /etc/resolv.conf often is.
+func (h *HostsFileWatcher) watch() (*fsnotify.Watcher, map[string]string, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating watcher: %w", err)
+	}
+
+	watchedNames := make(map[string]string)
+	watchedDirs := make(map[string]struct{})
+	for _, path := range h.paths {
+		for _, name := range notificationNames(path) {
+			watchedNames[name] = path
+
+			dir := filepath.Dir(name)
+			if _, ok := watchedDirs[dir]; ok {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				h.slogger.Log(context.TODO(), slog.LevelWarn,
+					"could not watch directory",
+					"dir", dir,
+					"err", err,
+				)
+				continue
+			}
+			watchedDirs[dir] = struct{}{}
+		}
+	}
+
+	if len(watchedDirs) == 0 {
+		watcher.Close()
+		return nil, nil, errors.New("no directories could be watched")
+	}
+
+	return watcher, watchedNames, nil
+}
+
+// notificationNames returns the names fsnotify may use for changes to path
+func notificationNames(path string) []string {
+	names := []string{filepath.Clean(path)}
+
+	// The directory itself may be a symlink, e.g. /etc on macOS
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		names = append(names, filepath.Join(dir, filepath.Base(path)))
+	}
+
+	// As may the file
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		names = append(names, target)
+	}
+
+	return names
+}
+
+func (h *HostsFileWatcher) checkAll(source string) {
+	for _, path := range h.paths {
+		h.check(path, source)
+	}
+}
+
+// check compares path against its checkpoint, recording an event and updating the checkpoint
+// if it has changed.
+func (h *HostsFileWatcher) check(path string, source string) {
+	current, err := readCheckpoint(path)
+	if err != nil {
+		h.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not read watched file",
+			"path", path,
+			"err", err,
+		)
+		return
+	}
+
+	previous, err := h.loadCheckpoint(path)
+	if err != nil {
+		h.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not load checkpoint, resetting",
+			"path", path,
+			"err", err,
+		)
+	}
+
+	// Without a previous checkpoint, this is our baseline -- there's no change to report
+	if previous != nil {
+		event, changed := diff(path, previous, current)
+		if !changed {
+			return
+		}
+
+		event.Time = time.Now().Unix()
+		event.Source = source
+		if err := h.recordEvent(event); err != nil {
+			h.slogger.Log(context.TODO(), slog.LevelWarn,
+				"could not record event",
+				"path", path,
+				"err", err,
+			)
+			return
+		}
+
+		h.slogger.Log(context.TODO(), slog.LevelInfo,
+			"watched file changed",
+			"path", path,
+			"action", event.Action,
+			"source", source,
+		)
+	}
+
+	if err := h.saveCheckpoint(path, current); err != nil {
+		h.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not save checkpoint",
+			"path", path,
+			"err", err,
+		)
+	}
+}
+
+// readCheckpoint returns the current state of the file at path
+func readCheckpoint(path string) (*checkpoint, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &checkpoint{Exists: false}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("opening: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+
+	var buf bytes.Buffer
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(hash, &limitedBuffer{buf: &buf, limit: maxDiffSize}), f); err != nil {
+		return nil, fmt.Errorf("reading: %w", err)
+	}
+
+	cp := &checkpoint{
+		Exists:  true,
+		Sha256:  hex.EncodeToString(hash.Sum(nil)),
+		Size:    info.Size(),
+		ModTime: info.ModTime().Unix(),
+	}
+
+	if buf.Len() <= maxDiffSize {
+		cp.Lines = splitLines(buf.Bytes())
+	}
+
+	return cp, nil
+}
+
+// limitedBuffer writes up to limit+1 bytes to buf, so that the caller can tell whether
+// the limit was exceeded, and discards the rest
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := l.limit + 1 - l.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			l.buf.Write(p[:remaining])
+		} else {
+			l.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// splitLines returns the non-blank lines in contents, with surrounding whitespace trimmed
+func splitLines(contents []byte) []string {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxDiffSize+1)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// diff returns the event describing the change from previous to current, if any
+func diff(path string, previous, current *checkpoint) (Event, bool) {
+	event := Event{
+		Path:           path,
+		Sha256:         current.Sha256,
+		PreviousSha256: previous.Sha256,
+		Size:           current.Size,
+	}
+
+	switch {
+	case !previous.Exists && !current.Exists:
+		return event, false
+	case !previous.Exists:
+		event.Action = ActionCreated
+	case !current.Exists:
+		event.Action = ActionDeleted
+	case previous.Sha256 == current.Sha256:
+		return event, false
+	default:
+		event.Action = ActionModified
+	}
+
+	// We can only report which lines changed when we have both versions
+	if (previous.Lines != nil || !previous.Exists) && (current.Lines != nil || !current.Exists) {
+		event.Added, event.Removed = diffLines(previous.Lines, current.Lines)
+	}
+
+	return event, true
+}
+
+// diffLines returns the lines in current but not previous, and those in previous but not current.
+// Order is not considered, since it rarely matters in hosts files or resolver configuration.
+func diffLines(previous, current []string) ([]string, []string) {
+	counts := make(map[string]int)
+	for _, line := range previous {
+		counts[line]++
+	}
+
+	var added []string
+	for _, line := range current {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+		added = append(added, line)
+	}
+
+	var removed []string
+	for _, line := range previous {
+		if counts[line] > 0 {
+			counts[line]--
+			removed = append(removed, line)
+		}
+	}
+
+	return added, removed
+}
+
+func (h *HostsFileWatcher) loadCheckpoint(path string) (*checkpoint, error) {
+	raw, err := h.store.Get([]byte(checkpointKeyPrefix + path))
+	if err != nil {
+		return nil, fmt.Errorf("getting checkpoint: %w", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(raw, &cp); err != nil {
+		return nil, fmt.Errorf("unmarshalling checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+func (h *HostsFileWatcher) saveCheckpoint(path string, cp *checkpoint) error {
+	raw, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("marshalling checkpoint: %w", err)
+	}
+	return h.store.Set([]byte(checkpointKeyPrefix+path), raw)
+}
+
+func (h *HostsFileWatcher) recordEvent(event Event) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling event: %w", err)
+	}
+
+	if err := h.store.Set([]byte(eventKey(time.Now())), raw); err != nil {
+		return fmt.Errorf("storing event: %w", err)
+	}
+
+	h.pruneEvents(time.Now())
+	return nil
+}
+
+// eventKey returns the key for an event recorded at t. Zero-padded nanoseconds keep keys
+// unique and in order.
+func eventKey(t time.Time) string {
+	return fmt.Sprintf("%s%020d", eventKeyPrefix, t.UnixNano())
+}
+
+// pruneEvents deletes events older than eventRetention, and the oldest events beyond maxEvents
+func (h *HostsFileWatcher) pruneEvents(now time.Time) {
+	var keys []string
+	if err := h.store.ForEach(func(k, _ []byte) error {
+		if strings.HasPrefix(string(k), eventKeyPrefix) {
+			keys = append(keys, string(k))
+		}
+		return nil
+	}); err != nil {
+		h.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not iterate over events to prune",
+			"err", err,
+		)
+		return
+	}
+	sort.Strings(keys)
+
+	cutoff := eventKey(now.Add(-eventRetention))
+	var toDelete [][]byte
+	for i, key := range keys {
+		if key < cutoff || len(keys)-i > maxEvents {
+			toDelete = append(toDelete, []byte(key))
+		}
+	}
+
+	if len(toDelete) == 0 {
+		return
+	}
+	if err := h.store.Delete(toDelete...); err != nil {
+		h.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not prune events",
+			"err", err,
+		)
+	}
+}
+
+// Events returns the recorded events in store, oldest first.
+func Events(store types.Iterator) ([]Event, error) {
+	eventsByKey := make(map[string]Event)
+	if err := store.ForEach(func(k, v []byte) error {
+		if !strings.HasPrefix(string(k), eventKeyPrefix) {
+			return nil
+		}
+
+		var event Event
+		if err := json.Unmarshal(v, &event); err != nil {
+			// Skip anything corrupt rather than failing the whole table
+			return nil
+		}
+		eventsByKey[string(k)] = event
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("iterating over events: %w", err)
+	}
+
+	keys := make([]string, 0, len(eventsByKey))
+	for k := range eventsByKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	events := make([]Event, 0, len(keys))
+	for _, k := range keys {
+		events = append(events, eventsByKey[k])
+	}
+
+	return events, nil
+}
@@ -0,0 +1,149 @@
+package hostsfilewatcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWatcher(t *testing.T, paths ...string) *HostsFileWatcher {
+	return &HostsFileWatcher{
+		slogger:   multislogger.NewNopLogger(),
+		store:     inmemory.NewStore(),
+		paths:     paths,
+		interrupt: make(chan struct{}, 1),
+	}
+}
+
+func TestCheck_ChangedWhileNotRunning(t *testing.T) {
+	t.Parallel()
+
+	hostsPath := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1 localhost\n::1 localhost\n"), 0644))
+
+	h := newTestWatcher(t, hostsPath)
+
+	// The first check is the baseline, and records nothing
+	h.checkAll(SourceStartup)
+	events, err := Events(h.store)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// Unchanged, so still nothing
+	h.checkAll(SourceStartup)
+	events, err = Events(h.store)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// Simulate an edit between launcher runs
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1 localhost\n\n10.0.0.66 login.example.com\n"), 0644))
+	h.checkAll(SourceStartup)
+
+	events, err = Events(h.store)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, hostsPath, events[0].Path)
+	require.Equal(t, ActionModified, events[0].Action)
+	require.Equal(t, SourceStartup, events[0].Source)
+	require.NotEqual(t, events[0].PreviousSha256, events[0].Sha256)
+	require.Equal(t, []string{"10.0.0.66 login.example.com"}, events[0].Added)
+	require.Equal(t, []string{"::1 localhost"}, events[0].Removed)
+
+	// Deletion and recreation
+	require.NoError(t, os.Remove(hostsPath))
+	h.checkAll(SourcePoll)
+	require.NoError(t, os.WriteFile(hostsPath, []byte(""), 0644))
+	h.checkAll(SourcePoll)
+
+	events, err = Events(h.store)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, ActionDeleted, events[1].Action)
+	require.Equal(t, []string{"127.0.0.1 localhost", "10.0.0.66 login.example.com"}, events[1].Removed)
+	require.Equal(t, ActionCreated, events[2].Action)
+	require.Empty(t, events[2].Added)
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	hostsPath := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1 localhost\n"), 0644))
+
+	h := newTestWatcher(t, hostsPath)
+
+	done := make(chan error)
+	go func() {
+		done <- h.Execute()
+	}()
+
+	// Wait for the baseline checkpoint before changing the file
+	require.Eventually(t, func() bool {
+		cp, err := h.loadCheckpoint(hostsPath)
+		return err == nil && cp != nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Replace the file, the way many editors do
+	tmpPath := hostsPath + ".tmp"
+	require.NoError(t, os.WriteFile(tmpPath, []byte("127.0.0.1 localhost\n10.0.0.66 login.example.com\n"), 0644))
+	require.NoError(t, os.Rename(tmpPath, hostsPath))
+
+	require.Eventually(t, func() bool {
+		events, err := Events(h.store)
+		return err == nil && len(events) == 1
+	}, 10*time.Second, 100*time.Millisecond)
+
+	events, err := Events(h.store)
+	require.NoError(t, err)
+	require.Equal(t, SourceFsnotify, events[0].Source)
+	require.Equal(t, []string{"10.0.0.66 login.example.com"}, events[0].Added)
+
+	h.Interrupt(nil)
+	h.Interrupt(nil)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Error("watcher did not exit after interrupt")
+	}
+}
+
+func TestPruneEvents(t *testing.T) {
+	t.Parallel()
+
+	h := newTestWatcher(t)
+	now := time.Now()
+
+	for i := 0; i < maxEvents+5; i++ {
+		require.NoError(t, h.store.Set([]byte(eventKey(now.Add(time.Duration(i)*time.Second))), []byte(`{}`)))
+	}
+	require.NoError(t, h.store.Set([]byte(eventKey(now.Add(-eventRetention-time.Hour))), []byte(`{}`)))
+	require.NoError(t, h.store.Set([]byte(checkpointKeyPrefix+"/etc/hosts"), []byte(`{}`)))
+
+	h.pruneEvents(now)
+
+	events, err := Events(h.store)
+	require.NoError(t, err)
+	require.Len(t, events, maxEvents)
+
+	// Checkpoints are left alone
+	cp, err := h.store.Get([]byte(checkpointKeyPrefix + "/etc/hosts"))
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+}
+
+func Test_diffLines(t *testing.T) {
+	t.Parallel()
+
+	added, removed := diffLines(
+		[]string{"127.0.0.1 localhost", "127.0.0.1 localhost", "::1 localhost"},
+		[]string{"::1 localhost", "127.0.0.1 localhost", "10.0.0.66 login.example.com"},
+	)
+	require.Equal(t, []string{"10.0.0.66 login.example.com"}, added)
+	require.Equal(t, []string{"127.0.0.1 localhost"}, removed)
+}
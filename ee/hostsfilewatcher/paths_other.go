@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package hostsfilewatcher
+
+import "github.com/kolide/launcher/ee/hostroot"
+
+func watchedPaths() []string {
+	return []string{hostroot.Path("/etc/hosts"), hostroot.Path("/etc/resolv.conf")}
+}
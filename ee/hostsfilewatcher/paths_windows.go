@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package hostsfilewatcher
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// watchedPaths returns the hosts file. Resolver configuration lives in the registry on
+// Windows, so there is no file to watch.
+func watchedPaths() []string {
+	systemRoot := os.Getenv("SystemRoot")
+	if systemRoot == "" {
+		systemRoot = `C:\Windows`
+	}
+
+	return []string{filepath.Join(systemRoot, "System32", "drivers", "etc", "hosts")}
+}
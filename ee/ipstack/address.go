@@ -0,0 +1,29 @@
+package ipstack
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// NormalizeHostPort brackets a bare IPv6 literal, like `2001:db8::1`, so that it can be used as
+// the host of a URL or as a dial address. Other addresses -- hostnames and IPv4 literals, with or
+// without a port, and IPv6 literals already in brackets -- are returned unchanged.
+func NormalizeHostPort(addr string) string {
+	if strings.HasPrefix(addr, "[") {
+		return addr
+	}
+	if ip, err := netip.ParseAddr(addr); err == nil && ip.Is6() {
+		return "[" + addr + "]"
+	}
+	return addr
+}
+
+// Hostname returns the host of addr, without its port, if any, or the brackets around an IPv6
+// literal. It's what TLS verifies certificates against, and what DNS resolves.
+func Hostname(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
@@ -0,0 +1,57 @@
+package ipstack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeHostPort(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "k2device.kolide.com", expected: "k2device.kolide.com"},
+		{addr: "k2device.kolide.com:443", expected: "k2device.kolide.com:443"},
+		{addr: "192.0.2.1", expected: "192.0.2.1"},
+		{addr: "192.0.2.1:443", expected: "192.0.2.1:443"},
+		{addr: "2001:db8::1", expected: "[2001:db8::1]"},
+		{addr: "::1", expected: "[::1]"},
+		{addr: "[2001:db8::1]", expected: "[2001:db8::1]"},
+		{addr: "[2001:db8::1]:443", expected: "[2001:db8::1]:443"},
+		{addr: "64:ff9b::c000:201", expected: "[64:ff9b::c000:201]"},
+		{addr: "", expected: ""},
+	} {
+		tt := tt
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, NormalizeHostPort(tt.addr))
+		})
+	}
+}
+
+func TestHostname(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "k2device.kolide.com", expected: "k2device.kolide.com"},
+		{addr: "k2device.kolide.com:443", expected: "k2device.kolide.com"},
+		{addr: "192.0.2.1:443", expected: "192.0.2.1"},
+		{addr: "2001:db8::1", expected: "2001:db8::1"},
+		{addr: "[2001:db8::1]", expected: "2001:db8::1"},
+		{addr: "[2001:db8::1]:443", expected: "2001:db8::1"},
+	} {
+		tt := tt
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, Hostname(tt.addr))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+// Package ipstack helps launcher's clients work the same on IPv4-only, IPv6-only, and dual-stack
+// networks: it provides helpers for server addresses that may be IPv6 literals, HTTP clients that
+// don't share http.DefaultClient, and NAT64 detection for diagnostics. Go's dialer already races
+// IPv6 and IPv4 connections when a name resolves to both, so there's no dialer here.
+package ipstack
+
+import (
+	"net/http"
+	"time"
+)
+
+// NewClient returns a client with http.DefaultTransport's settings and the given timeout. Use it
+// rather than http.DefaultClient, which is shared, so that one client's settings don't leak into
+// another's.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		Timeout:   timeout,
+	}
+}
@@ -0,0 +1,97 @@
+package ipstack
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewClient_NetworkMatrix requests from servers listening on one address family, or both,
+// by each form of address the server could be configured with.
+func TestNewClient_NetworkMatrix(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testCaseName string
+		listen       []string
+		addrs        []string
+	}{
+		{
+			testCaseName: "ipv4-only server",
+			listen:       []string{"127.0.0.1"},
+			addrs:        []string{"127.0.0.1"},
+		},
+		{
+			testCaseName: "ipv6-only server",
+			listen:       []string{"::1"},
+			addrs:        []string{"::1", "[::1]", "0:0:0:0:0:0:0:1"},
+		},
+		{
+			testCaseName: "dual-stack server",
+			listen:       []string{"127.0.0.1", "::1"},
+			addrs:        []string{"127.0.0.1", "::1", "[::1]"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			port := serve(t, tt.listen)
+			client := NewClient(5 * time.Second)
+
+			for _, addr := range tt.addrs {
+				hostPort := net.JoinHostPort(Hostname(NormalizeHostPort(addr)), port)
+				resp, err := client.Get(fmt.Sprintf("http://%s/version", hostPort))
+				require.NoError(t, err, addr)
+
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				require.NoError(t, err)
+				require.Equal(t, "ok", string(body))
+			}
+		})
+	}
+}
+
+func TestNewClient_Independent(t *testing.T) {
+	t.Parallel()
+
+	first := NewClient(time.Second)
+	second := NewClient(time.Minute)
+	require.Equal(t, time.Second, first.Timeout)
+	require.NotSame(t, first.Transport, second.Transport)
+	require.NotSame(t, http.DefaultTransport, first.Transport)
+}
+
+// serve serves on the same port of each of the given loopback addresses, skipping the test if
+// the host doesn't have them.
+func serve(t *testing.T, loopbacks []string) string {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	port := "0"
+	for _, loopback := range loopbacks {
+		listener, err := net.Listen("tcp", net.JoinHostPort(loopback, port))
+		if err != nil {
+			t.Skipf("cannot listen on %s, skipping: %v", loopback, err)
+		}
+
+		server := httptest.NewUnstartedServer(handler)
+		server.Listener.Close()
+		server.Listener = listener
+		server.Start()
+		t.Cleanup(server.Close)
+
+		_, port, err = net.SplitHostPort(listener.Addr().String())
+		require.NoError(t, err)
+	}
+
+	return port
+}
@@ -0,0 +1,85 @@
+package ipstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+)
+
+// ipv4OnlyArpa only has A records, so on a network with DNS64, any AAAA records it resolves
+// to were synthesized, and embed one of its IPv4 addresses in the network's NAT64 prefix.
+// See RFC 7050.
+const ipv4OnlyArpa = "ipv4only.arpa"
+
+var ipv4OnlyArpaAddrs = []netip.Addr{
+	netip.AddrFrom4([4]byte{192, 0, 0, 170}),
+	netip.AddrFrom4([4]byte{192, 0, 0, 171}),
+}
+
+// nat64Prefixes are the prefix lengths RFC 6052 allows, and the bytes of the IPv6 address that
+// hold the embedded IPv4 address for each. Byte 8 is reserved, so addresses with prefixes
+// shorter than 64 bits skip it.
+var nat64Prefixes = []struct {
+	bits      int
+	positions [4]int
+}{
+	{bits: 96, positions: [4]int{12, 13, 14, 15}},
+	{bits: 64, positions: [4]int{9, 10, 11, 12}},
+	{bits: 56, positions: [4]int{7, 9, 10, 11}},
+	{bits: 48, positions: [4]int{6, 7, 9, 10}},
+	{bits: 40, positions: [4]int{5, 6, 7, 9}},
+	{bits: 32, positions: [4]int{4, 5, 6, 7}},
+}
+
+// Resolver looks up addresses; *net.Resolver implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// DetectNAT64 returns the NAT64 prefixes of the network's DNS64 resolver, if it has one. On
+// an IPv6-only network, hosts with only IPv4 addresses are reachable through these prefixes.
+func DetectNAT64(ctx context.Context, resolver Resolver) ([]netip.Prefix, error) {
+	addrs, err := resolver.LookupNetIP(ctx, "ip6", ipv4OnlyArpa)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("looking up %s: %w", ipv4OnlyArpa, err)
+	}
+
+	var prefixes []netip.Prefix
+	for _, addr := range addrs {
+		prefix, ok := nat64Prefix(addr)
+		if ok && !slices.Contains(prefixes, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return prefixes, nil
+}
+
+// nat64Prefix returns the NAT64 prefix of an address synthesized for ipv4only.arpa. It checks
+// the longest prefix first, since the well-known prefix, 64:ff9b::/96, is the most common.
+func nat64Prefix(addr netip.Addr) (netip.Prefix, bool) {
+	if !addr.Is6() || addr.Is4In6() {
+		return netip.Prefix{}, false
+	}
+
+	raw := addr.As16()
+	for _, p := range nat64Prefixes {
+		var embedded [4]byte
+		for i, position := range p.positions {
+			embedded[i] = raw[position]
+		}
+
+		if slices.Contains(ipv4OnlyArpaAddrs, netip.AddrFrom4(embedded)) {
+			return netip.PrefixFrom(addr, p.bits).Masked(), true
+		}
+	}
+
+	return netip.Prefix{}, false
+}
@@ -0,0 +1,89 @@
+package ipstack
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver struct {
+	addrs []netip.Addr
+	err   error
+}
+
+func (f fakeResolver) LookupNetIP(_ context.Context, _, _ string) ([]netip.Addr, error) {
+	return f.addrs, f.err
+}
+
+func TestDetectNAT64(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testCaseName string
+		resolver     fakeResolver
+		expected     []netip.Prefix
+		expectErr    bool
+	}{
+		{
+			testCaseName: "well-known prefix",
+			resolver: fakeResolver{addrs: []netip.Addr{
+				netip.MustParseAddr("64:ff9b::c000:aa"),
+				netip.MustParseAddr("64:ff9b::c000:ab"),
+			}},
+			expected: []netip.Prefix{netip.MustParsePrefix("64:ff9b::/96")},
+		},
+		{
+			testCaseName: "network-specific /64 prefix",
+			resolver: fakeResolver{addrs: []netip.Addr{
+				netip.MustParseAddr("2001:db8:122:344:c0:0:aa00:0"),
+			}},
+			expected: []netip.Prefix{netip.MustParsePrefix("2001:db8:122:344::/64")},
+		},
+		{
+			testCaseName: "network-specific /48 prefix",
+			resolver: fakeResolver{addrs: []netip.Addr{
+				netip.MustParseAddr("2001:db8:122:c000:0:aa00::"),
+			}},
+			expected: []netip.Prefix{netip.MustParsePrefix("2001:db8:122::/48")},
+		},
+		{
+			testCaseName: "network-specific /32 prefix",
+			resolver: fakeResolver{addrs: []netip.Addr{
+				netip.MustParseAddr("2001:db8:c000:aa::"),
+			}},
+			expected: []netip.Prefix{netip.MustParsePrefix("2001:db8::/32")},
+		},
+		{
+			testCaseName: "no dns64",
+			resolver:     fakeResolver{err: &net.DNSError{Err: "no such host", Name: ipv4OnlyArpa, IsNotFound: true}},
+			expected:     nil,
+		},
+		{
+			testCaseName: "unrelated addresses",
+			resolver:     fakeResolver{addrs: []netip.Addr{netip.MustParseAddr("2001:db8::1")}},
+			expected:     nil,
+		},
+		{
+			testCaseName: "lookup failure",
+			resolver:     fakeResolver{err: errors.New("i/o timeout")},
+			expectErr:    true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			prefixes, err := DetectNAT64(context.TODO(), tt.resolver)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, prefixes)
+		})
+	}
+}
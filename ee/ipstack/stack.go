@@ -0,0 +1,58 @@
+package ipstack
+
+import (
+	"net"
+	"net/netip"
+)
+
+// Stack is the address families a host has routable addresses in.
+type Stack struct {
+	IPv4 bool
+	IPv6 bool
+}
+
+func (s Stack) String() string {
+	switch {
+	case s.IPv4 && s.IPv6:
+		return "dual-stack"
+	case s.IPv4:
+		return "ipv4-only"
+	case s.IPv6:
+		return "ipv6-only"
+	default:
+		return "none"
+	}
+}
+
+// StackOf returns the stack of a host with the given interface addresses, as from
+// net.InterfaceAddrs. Loopback and link-local addresses aren't routable, so don't count.
+func StackOf(addrs []net.Addr) Stack {
+	var s Stack
+	for _, a := range addrs {
+		var ip net.IP
+		switch v := a.(type) {
+		case *net.IPNet:
+			ip = v.IP
+		case *net.IPAddr:
+			ip = v.IP
+		default:
+			continue
+		}
+
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		if !addr.IsGlobalUnicast() {
+			continue
+		}
+
+		if addr.Is4() {
+			s.IPv4 = true
+		} else {
+			s.IPv6 = true
+		}
+	}
+	return s
+}
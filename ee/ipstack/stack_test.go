@@ -0,0 +1,43 @@
+package ipstack
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackOf(t *testing.T) {
+	t.Parallel()
+
+	loopback := []net.Addr{ipNet("127.0.0.1/8"), ipNet("::1/128")}
+	linkLocal := []net.Addr{ipNet("169.254.10.1/16"), ipNet("fe80::1/64")}
+
+	for _, tt := range []struct {
+		testCaseName string
+		addrs        []net.Addr
+		expected     string
+	}{
+		{testCaseName: "no network", addrs: append(loopback, linkLocal...), expected: "none"},
+		{testCaseName: "ipv4-only", addrs: append(loopback, ipNet("192.168.1.20/24"), ipNet("fe80::1/64")), expected: "ipv4-only"},
+		{testCaseName: "ipv6-only", addrs: append(loopback, ipNet("2001:db8::20/64"), ipNet("fe80::1/64")), expected: "ipv6-only"},
+		{testCaseName: "dual-stack", addrs: append(loopback, ipNet("192.168.1.20/24"), ipNet("2001:db8::20/64")), expected: "dual-stack"},
+		{testCaseName: "ip addrs", addrs: []net.Addr{&net.IPAddr{IP: net.ParseIP("2001:db8::20")}}, expected: "ipv6-only"},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, StackOf(tt.addrs).String())
+		})
+	}
+}
+
+func ipNet(cidr string) *net.IPNet {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	ipNet.IP = ip
+	return ipNet
+}
@@ -2,11 +2,14 @@ package keyidentifier
 
 import (
 	"bytes"
+	"crypto"
 	"encoding/binary"
 	"encoding/pem"
 	"fmt"
 	"io"
 	"strings"
+
+	"golang.org/x/crypto/ssh"
 )
 
 // boolPtr makes a pointer from a boolean. We use it to fake a ternary unknown/true/false
@@ -41,3 +44,25 @@ func readSizedString(r *bytes.Reader) (string, error) {
 func pemEncryptedBlock(block *pem.Block) bool {
 	return strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED")
 }
+
+// FingerprintSHA256 returns the SHA256 fingerprint of a public key, such as a certificate's, in
+// the same form as KeyInfo. It's empty if ssh doesn't support the key type.
+func FingerprintSHA256(pub crypto.PublicKey) string {
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(ssh.FingerprintSHA256(sshPub), "SHA256:")
+}
+
+// setFingerprints sets the key's fingerprints from its public key, in the same form as ssh-keygen
+// reports them. They're left empty if ssh doesn't support the key type.
+func setFingerprints(ki *KeyInfo, pub crypto.PublicKey) {
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return
+	}
+
+	ki.FingerprintSHA256 = strings.TrimPrefix(ssh.FingerprintSHA256(sshPub), "SHA256:")
+	ki.FingerprintMD5 = strings.TrimPrefix(ssh.FingerprintLegacyMD5(sshPub), "MD5:")
+}
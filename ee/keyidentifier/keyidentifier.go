@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -18,15 +19,16 @@ import (
 )
 
 type KeyInfo struct {
-	Type              string // Key type. rsa/dsa/etc
-	Format            string // file format
-	Bits              int    // number of bits in the key
-	Encryption        string // key encryption algorythem
-	Encrypted         *bool  // is the key encrypted
-	Comment           string // comments attached to the key
-	Parser            string // what parser we used to determine information
-	FingerprintSHA256 string // the fingerprint of the key, as a SHA256 hash
-	FingerprintMD5    string // the fingerprint of the key, as an MD5 hash
+	Type              string           // Key type. rsa/dsa/etc
+	Format            string           // file format
+	Bits              int              // number of bits in the key
+	Encryption        string           // key encryption algorythem
+	Encrypted         *bool            // is the key encrypted
+	Comment           string           // comments attached to the key
+	Parser            string           // what parser we used to determine information
+	FingerprintSHA256 string           // the fingerprint of the key, as a SHA256 hash
+	FingerprintMD5    string           // the fingerprint of the key, as an MD5 hash
+	Certificate       *CertificateInfo // details of the certificate, if this is an ssh certificate
 }
 
 // keyidentifier attempts to identify a key. It uses a set of
@@ -92,6 +94,8 @@ func (kIdentifier *KeyIdentifier) Identify(keyBytes []byte) (*KeyInfo, error) {
 		return ParseSshComPrivateKey(keyBytes)
 	case bytes.HasPrefix(keyBytes, []byte(ssh1LegacyBegin)):
 		return ParseSsh1PrivateKey(keyBytes)
+	case isSshCertificate(keyBytes):
+		return ParseSshCertificate(keyBytes)
 	}
 
 	// If nothing else fits. treat it like a pem
@@ -131,6 +135,7 @@ func (kIdentifier *KeyIdentifier) attemptPem(keyBytes []byte) (*KeyInfo, error)
 
 		if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
 			ki.Bits = len(key.PublicKey.N.Bytes()) * 8
+			setFingerprints(ki, &key.PublicKey)
 		}
 
 		return ki, nil
@@ -143,19 +148,32 @@ func (kIdentifier *KeyIdentifier) attemptPem(keyBytes []byte) (*KeyInfo, error)
 			case *rsa.PrivateKey:
 				ki.Bits = assertedKey.PublicKey.Size() * 8
 				ki.Type = "rsa"
+				setFingerprints(ki, &assertedKey.PublicKey)
 			case *ecdsa.PrivateKey:
 				ki.Bits = assertedKey.PublicKey.Curve.Params().BitSize
 				ki.Type = "ecdsa"
+				setFingerprints(ki, &assertedKey.PublicKey)
+			case ed25519.PrivateKey:
+				ki.Bits = ed25519.PublicKeySize * 8
+				ki.Type = "ed25519"
+				setFingerprints(ki, assertedKey.Public())
 			}
 		}
 		return ki, nil
 
+	case "ENCRYPTED PRIVATE KEY":
+		// RFC5958 - the key, type included, is in the encrypted data
+		ki.Format = "pkcs8"
+		ki.Encrypted = boolPtr(true)
+		return ki, nil
+
 	case "EC PRIVATE KEY":
 		// set the Type here, since parsing fails on encrypted keys
 		ki.Type = "ecdsa"
 
 		if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
 			ki.Bits = key.PublicKey.Curve.Params().BitSize
+			setFingerprints(ki, &key.PublicKey)
 		} else {
 			kIdentifier.slogger.Log(context.TODO(), slog.LevelDebug,
 				"x509.ParseECPrivateKey failed to parse",
@@ -168,6 +186,7 @@ func (kIdentifier *KeyIdentifier) attemptPem(keyBytes []byte) (*KeyInfo, error)
 	case "DSA PRIVATE KEY":
 		if key, err := ssh.ParseDSAPrivateKey(block.Bytes); err == nil {
 			ki.Bits = len(key.PublicKey.Y.Bytes()) * 8
+			setFingerprints(ki, &key.PublicKey)
 		}
 		ki.Type = ssh.KeyAlgoDSA
 		ki.Format = "openssh"
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kolide/launcher/pkg/log/multislogger"
 	"github.com/stretchr/testify/require"
@@ -88,8 +89,9 @@ func testIdentifyFile(t *testing.T, kIdentifer *KeyIdentifier, specFilePath stri
 		expected.Bits = 0
 	}
 
-	// test correct fingerprint reporting. limited support for now
-	if actual.Format != "openssh-new" {
+	// test correct fingerprint reporting. limited support for now: openssh-new keys, and
+	// unencrypted pem keys
+	if actual.Format != "openssh-new" && actual.FingerprintSHA256 == "" {
 		expected.FingerprintSHA256 = ""
 		expected.FingerprintMD5 = ""
 	}
@@ -102,3 +104,57 @@ func testIdentifyFile(t *testing.T, kIdentifer *KeyIdentifier, specFilePath stri
 
 	require.EqualValues(t, &expected.KeyInfo, actual)
 }
+
+func TestIdentifySshCertificates(t *testing.T) {
+	t.Parallel()
+
+	kIdentifier, err := New(WithSlogger(multislogger.NewNopLogger()))
+	require.NoError(t, err)
+
+	// Generated by ssh-keygen, signed by the same ed25519 CA:
+	//   ssh-keygen -s ca -I alice-laptop -n alice,deploy -z 42 -V 20240101000000:20350101000000 \
+	//     -O force-command=/usr/bin/true -O source-address=10.0.0.0/8,192.168.0.0/16 -O no-port-forwarding id_ecdsa.pub
+	//   ssh-keygen -s ca -h -I host.example.com -n host.example.com host_rsa.pub
+	userCert, err := kIdentifier.IdentifyFile(filepath.Join("testdata", "certs", "user_ecdsa-cert.pub"))
+	require.NoError(t, err)
+	require.Equal(t, &KeyInfo{
+		Type:              "ecdsa-sha2-nistp384",
+		Format:            "openssh-cert",
+		Bits:              384,
+		Comment:           "alice@example.com",
+		Parser:            "ParseSshCertificate",
+		FingerprintSHA256: "pWw1+vurLM4ApwD+3ZRQfjR3CaESBHrTC32u2o9q70k",
+		FingerprintMD5:    userCert.FingerprintMD5,
+		Certificate: &CertificateInfo{
+			Type:                "user",
+			KeyId:               "alice-laptop",
+			Serial:              42,
+			Principals:          []string{"alice", "deploy"},
+			ValidAfter:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			ValidBefore:         time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC),
+			CAType:              "ssh-ed25519",
+			CAFingerprintSHA256: "5jQu3YJKsncWSMSisaz+ILC6vnk43u06Nu/3KMV2/7U",
+			CriticalOptions: map[string]string{
+				"force-command":  "/usr/bin/true",
+				"source-address": "10.0.0.0/8,192.168.0.0/16",
+			},
+			Extensions: []string{"permit-X11-forwarding", "permit-agent-forwarding", "permit-pty", "permit-user-rc"},
+		},
+	}, userCert)
+	require.NotEmpty(t, userCert.FingerprintMD5)
+
+	hostCert, err := kIdentifier.IdentifyFile(filepath.Join("testdata", "certs", "host_rsa-cert.pub"))
+	require.NoError(t, err)
+	require.Equal(t, "ssh-rsa", hostCert.Type)
+	require.Equal(t, 2048, hostCert.Bits)
+	require.Equal(t, "+w+fxgXHVzecX1+zgiqgEyyJb9nxNf13WzcltplhTzo", hostCert.FingerprintSHA256)
+	require.Equal(t, "host", hostCert.Certificate.Type)
+	require.Equal(t, []string{"host.example.com"}, hostCert.Certificate.Principals)
+	require.True(t, hostCert.Certificate.ValidAfter.IsZero(), "valid forever")
+	require.True(t, hostCert.Certificate.ValidBefore.IsZero(), "valid forever")
+	require.Equal(t, userCert.Certificate.CAFingerprintSHA256, hostCert.Certificate.CAFingerprintSHA256)
+
+	// Plain public keys are still not identified
+	_, err = kIdentifier.Identify([]byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMzoVCEVKbGJtKSK0jNl5WVUsNUhScpz9VBV1CYkbU1f comment"))
+	require.Error(t, err)
+}
@@ -13,7 +13,6 @@ import (
 	"net"
 	"net/http"
 	"runtime"
-	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +22,7 @@ import (
 	"github.com/kolide/launcher/ee/agent/types"
 	"github.com/kolide/launcher/ee/gowrapper"
 	"github.com/kolide/launcher/ee/presencedetection"
+	"github.com/kolide/launcher/ee/serverkeys"
 	"github.com/kolide/launcher/pkg/osquery"
 	"github.com/kolide/launcher/pkg/traces"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -163,34 +163,14 @@ func (ls *localServer) LoadDefaultKeyIfNotSet() error {
 		return nil
 	}
 
-	serverRsaCertPem := k2RsaServerCert
-	serverEccCertPem := k2EccServerCert
+	certs := serverkeys.ForServer(ls.kolideServer)
 
-	ctx := context.TODO()
-	slogLevel := slog.LevelDebug
-
-	switch {
-	case strings.HasPrefix(ls.kolideServer, "localhost"), strings.HasPrefix(ls.kolideServer, "127.0.0.1"), strings.Contains(ls.kolideServer, ".ngrok."):
-		ls.slogger.Log(ctx, slogLevel,
-			"using developer certificates",
-		)
-
-		serverRsaCertPem = localhostRsaServerCert
-		serverEccCertPem = localhostEccServerCert
-	case strings.HasSuffix(ls.kolideServer, ".herokuapp.com"):
-		ls.slogger.Log(ctx, slogLevel,
-			"using review app certificates",
-		)
-
-		serverRsaCertPem = reviewRsaServerCert
-		serverEccCertPem = reviewEccServerCert
-	default:
-		ls.slogger.Log(ctx, slogLevel,
-			"using default/production certificates",
-		)
-	}
+	ls.slogger.Log(context.TODO(), slog.LevelDebug,
+		"using server certificates",
+		"environment", certs.Environment,
+	)
 
-	serverKeyRaw, err := krypto.KeyFromPem([]byte(serverRsaCertPem))
+	serverKeyRaw, err := krypto.KeyFromPem([]byte(certs.RsaPem))
 	if err != nil {
 		return fmt.Errorf("parsing default public key: %w", err)
 	}
@@ -202,7 +182,7 @@ func (ls *localServer) LoadDefaultKeyIfNotSet() error {
 
 	ls.serverKey = serverKey
 
-	ls.serverEcKey, err = echelper.PublicPemToEcdsaKey([]byte(serverEccCertPem))
+	ls.serverEcKey, err = echelper.PublicPemToEcdsaKey([]byte(certs.EccPem))
 	if err != nil {
 		return fmt.Errorf("parsing default server ec key: %w", err)
 	}
@@ -23,6 +23,7 @@ type OsqueryLogAdapter struct {
 	level               slog.Level
 	rootDirectory       string
 	lastLockfileLogTime time.Time
+	observers           []func(msg string)
 }
 
 type Option func(*OsqueryLogAdapter)
@@ -33,6 +34,14 @@ func WithLevel(level slog.Level) Option {
 	}
 }
 
+// WithObserver registers f to be called with each message osquery writes, e.g. to watch
+// for particular events.
+func WithObserver(f func(msg string)) Option {
+	return func(l *OsqueryLogAdapter) {
+		l.observers = append(l.observers, f)
+	}
+}
+
 var (
 	callerRegexp  = regexp.MustCompile(`[\w.]+:\d+]`)
 	pidRegex      = regexp.MustCompile(`Refusing to kill non-osqueryd process (\d+)`)
@@ -104,6 +113,10 @@ func (l *OsqueryLogAdapter) Write(p []byte) (int, error) {
 	}
 
 	msg := strings.TrimSpace(string(p))
+	for _, observe := range l.observers {
+		observe(msg)
+	}
+
 	caller := extractOsqueryCaller(msg)
 	l.slogger.Log(context.TODO(), level, // nolint:sloglint // it's fine to not have a constant or literal here
 		msg,
@@ -0,0 +1,272 @@
+// Package maintenancewindow decides when launcher may perform disruptive actions -- restarting
+// launcher or osquery after an update, or showing a notification -- so that they happen during
+// the maintenance window configured by the control server, instead of in the middle of the
+// user's day.
+//
+// A maintenance window is a weekly schedule in the device's local time, made up of one or more
+// semicolon-separated entries of the form "[days] HH:MM-HH:MM". Days may be "daily" (the
+// default), "weekdays", "weekends", or a comma-separated list of days and day ranges, e.g.
+// "mon,wed,fri" or "mon-fri". A window whose end is before its start runs past midnight into
+// the next day. For example:
+//
+//	mon-fri 01:00-05:00; sat,sun 00:00-23:59
+//	daily 22:00-06:00
+package maintenancewindow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+// ErrDeferred is returned by disruptive actions that were postponed until the maintenance window.
+var ErrDeferred = errors.New("deferred until maintenance window")
+
+const minutesPerDay = 24 * 60
+
+// Schedule is a set of weekly maintenance windows.
+type Schedule struct {
+	windows []window
+}
+
+type window struct {
+	days  [7]bool // indexed by time.Weekday; the day the window starts on
+	start int     // minutes after midnight
+	end   int     // minutes after midnight; before start when the window runs past midnight
+}
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Parse parses a maintenance window schedule. An empty schedule has no windows.
+func Parse(spec string) (*Schedule, error) {
+	s := &Schedule{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		w, err := parseWindow(entry)
+		if err != nil {
+			return nil, fmt.Errorf("parsing maintenance window %q: %w", entry, err)
+		}
+		s.windows = append(s.windows, w)
+	}
+
+	return s, nil
+}
+
+func parseWindow(entry string) (window, error) {
+	var w window
+
+	fields := strings.Fields(strings.ToLower(entry))
+	var daysSpec, timesSpec string
+	switch len(fields) {
+	case 1:
+		daysSpec, timesSpec = "daily", fields[0]
+	case 2:
+		daysSpec, timesSpec = fields[0], fields[1]
+	default:
+		return w, errors.New("expected [days] HH:MM-HH:MM")
+	}
+
+	days, err := parseDays(daysSpec)
+	if err != nil {
+		return w, err
+	}
+	w.days = days
+
+	startSpec, endSpec, ok := strings.Cut(timesSpec, "-")
+	if !ok {
+		return w, fmt.Errorf("time range %s is not of the form HH:MM-HH:MM", timesSpec)
+	}
+	if w.start, err = parseTimeOfDay(startSpec); err != nil {
+		return w, err
+	}
+	if w.end, err = parseTimeOfDay(endSpec); err != nil {
+		return w, err
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("time range %s is empty", timesSpec)
+	}
+
+	return w, nil
+}
+
+func parseDays(spec string) ([7]bool, error) {
+	var days [7]bool
+
+	switch spec {
+	case "daily":
+		for d := range days {
+			days[d] = true
+		}
+		return days, nil
+	case "weekdays":
+		for d := time.Monday; d <= time.Friday; d++ {
+			days[d] = true
+		}
+		return days, nil
+	case "weekends":
+		days[time.Saturday], days[time.Sunday] = true, true
+		return days, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		firstName, lastName, isRange := strings.Cut(part, "-")
+		first, ok := dayNames[firstName]
+		if !ok {
+			return days, fmt.Errorf("unknown day %s", firstName)
+		}
+		if !isRange {
+			days[first] = true
+			continue
+		}
+
+		last, ok := dayNames[lastName]
+		if !ok {
+			return days, fmt.Errorf("unknown day %s", lastName)
+		}
+		// Ranges may wrap around the end of the week, e.g. fri-mon
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+
+	return days, nil
+}
+
+func parseTimeOfDay(spec string) (int, error) {
+	hourSpec, minuteSpec, ok := strings.Cut(spec, ":")
+	if !ok {
+		return 0, fmt.Errorf("time %s is not of the form HH:MM", spec)
+	}
+	hour, err := strconv.Atoi(hourSpec)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, fmt.Errorf("invalid hour in %s", spec)
+	}
+	minute, err := strconv.Atoi(minuteSpec)
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid minute in %s", spec)
+	}
+
+	return hour*60 + minute, nil
+}
+
+// Empty reports whether the schedule has no windows.
+func (s *Schedule) Empty() bool {
+	return len(s.windows) == 0
+}
+
+// Contains reports whether t falls within one of the schedule's windows, in t's location.
+func (s *Schedule) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, w := range s.windows {
+		if w.start < w.end {
+			if w.days[today] && minute >= w.start && minute < w.end {
+				return true
+			}
+			continue
+		}
+
+		// The window runs past midnight: we may be in the part that started today, or
+		// in the part that started yesterday.
+		if w.days[today] && minute >= w.start {
+			return true
+		}
+		if w.days[yesterday] && minute < w.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Next returns the start of the next window after t, or the zero time if the schedule is empty.
+func (s *Schedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range s.windows {
+		// Every window starts at least once a week, so a week and a day is far enough to look
+		for day := 0; day <= 7; day++ {
+			date := t.AddDate(0, 0, day)
+			if !w.days[date.Weekday()] {
+				continue
+			}
+			start := time.Date(date.Year(), date.Month(), date.Day(), w.start/60, w.start%60, 0, 0, t.Location())
+			if !start.After(t) {
+				continue
+			}
+			if next.IsZero() || start.Before(next) {
+				next = start
+			}
+			break
+		}
+	}
+
+	return next
+}
+
+// Permits reports whether a disruptive action, pending since pendingSince, may happen at now:
+// that is, if no maintenance window is configured, if now is within the window, or if the
+// action has waited as long as the maintenance window deadline allows. An unparseable
+// schedule is logged and ignored, so that a bad setting cannot hold back updates forever.
+func Permits(ctx context.Context, k types.Knapsack, pendingSince, now time.Time) bool {
+	spec := k.MaintenanceWindow()
+	if spec == "" {
+		return true
+	}
+
+	schedule, err := Parse(spec)
+	if err != nil {
+		k.Slogger().Log(ctx, slog.LevelWarn,
+			"could not parse maintenance window, ignoring it",
+			"maintenance_window", spec,
+			"err", err,
+		)
+		return true
+	}
+
+	if schedule.Empty() || schedule.Contains(now) {
+		return true
+	}
+
+	return now.Sub(pendingSince) >= k.MaintenanceWindowDeadline()
+}
+
+// NextOpportunity returns the soonest time at or after now when a disruptive action pending
+// since pendingSince would be permitted, for logging and for deciding whether an action will
+// still be relevant by then.
+func NextOpportunity(k types.Knapsack, pendingSince, now time.Time) time.Time {
+	if Permits(context.TODO(), k, pendingSince, now) {
+		return now
+	}
+
+	deadline := pendingSince.Add(k.MaintenanceWindowDeadline())
+	// Permits already handled a missing or unparseable schedule
+	schedule, _ := Parse(k.MaintenanceWindow())
+	if next := schedule.Next(now); !next.IsZero() && next.Before(deadline) {
+		return next
+	}
+
+	return deadline
+}
@@ -0,0 +1,123 @@
+package maintenancewindow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+// 2024-01-01 was a Monday
+func at(day int, hour int, minute int) time.Time {
+	return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{
+		"01:00",
+		"01:00-01:00",
+		"25:00-02:00",
+		"01:60-02:00",
+		"someday 01:00-02:00",
+		"mon-someday 01:00-02:00",
+		"mon 01:00-02:00 extra",
+		"mon 1-2",
+	} {
+		_, err := Parse(spec)
+		require.Error(t, err, spec)
+	}
+
+	s, err := Parse(" ; ")
+	require.NoError(t, err)
+	require.True(t, s.Empty())
+}
+
+func TestContains(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		spec     string
+		in       []time.Time
+		notIn    []time.Time
+		nextFrom time.Time
+		next     time.Time
+	}{
+		{
+			spec:     "mon-fri 01:00-05:00",
+			in:       []time.Time{at(1, 1, 0), at(5, 4, 59)},
+			notIn:    []time.Time{at(1, 0, 59), at(1, 5, 0), at(6, 2, 0), at(7, 2, 0)},
+			nextFrom: at(5, 12, 0), // Friday afternoon: next window is Monday
+			next:     at(8, 1, 0),
+		},
+		{
+			spec:     "daily 22:00-06:00",
+			in:       []time.Time{at(1, 22, 0), at(2, 0, 30), at(2, 5, 59)},
+			notIn:    []time.Time{at(1, 21, 59), at(2, 6, 0), at(2, 12, 0)},
+			nextFrom: at(2, 12, 0),
+			next:     at(2, 22, 0),
+		},
+		{
+			// A window starting Saturday night runs into Sunday, but not Monday
+			spec:     "sat 22:00-06:00; weekdays 12:00-13:00",
+			in:       []time.Time{at(6, 23, 0), at(7, 5, 0), at(3, 12, 30)},
+			notIn:    []time.Time{at(7, 23, 0), at(8, 5, 0), at(6, 12, 30)},
+			nextFrom: at(6, 13, 0),
+			next:     at(6, 22, 0),
+		},
+		{
+			spec:     "fri-mon 03:00-04:00",
+			in:       []time.Time{at(5, 3, 0), at(7, 3, 30), at(8, 3, 59)},
+			notIn:    []time.Time{at(2, 3, 0), at(4, 3, 30)},
+			nextFrom: at(1, 3, 30), // Monday, during the window: next is Friday's
+			next:     at(5, 3, 0),
+		},
+	} {
+		s, err := Parse(tt.spec)
+		require.NoError(t, err, tt.spec)
+		require.False(t, s.Empty())
+
+		for _, in := range tt.in {
+			require.True(t, s.Contains(in), "%s should contain %s", tt.spec, in.Format(time.RFC1123))
+		}
+		for _, notIn := range tt.notIn {
+			require.False(t, s.Contains(notIn), "%s should not contain %s", tt.spec, notIn.Format(time.RFC1123))
+		}
+		require.Equal(t, tt.next, s.Next(tt.nextFrom), tt.spec)
+	}
+}
+
+func TestPermits(t *testing.T) {
+	t.Parallel()
+
+	k := typesmocks.NewKnapsack(t)
+	k.On("Slogger").Return(multislogger.NewNopLogger()).Maybe()
+	k.On("MaintenanceWindowDeadline").Return(72 * time.Hour).Maybe()
+
+	schedule := "mon-fri 01:00-05:00"
+	k.On("MaintenanceWindow").Return(func() string { return schedule })
+
+	pendingSince := at(1, 12, 0) // Monday midday
+
+	// Outside the window
+	require.False(t, Permits(context.TODO(), k, pendingSince, pendingSince))
+	require.Equal(t, at(2, 1, 0), NextOpportunity(k, pendingSince, pendingSince))
+
+	// Inside the window
+	require.True(t, Permits(context.TODO(), k, pendingSince, at(2, 1, 30)))
+
+	// Outside the window, but past the deadline
+	require.False(t, Permits(context.TODO(), k, at(5, 12, 0), at(8, 11, 59)))
+	require.True(t, Permits(context.TODO(), k, at(5, 12, 0), at(8, 12, 0)))
+	require.Equal(t, at(8, 0, 0), NextOpportunity(k, at(5, 0, 0), at(6, 0, 0)))
+
+	// No window, or an invalid one, is not held to a schedule
+	schedule = ""
+	require.True(t, Permits(context.TODO(), k, pendingSince, pendingSince))
+	schedule = "whenever"
+	require.True(t, Permits(context.TODO(), k, pendingSince, pendingSince))
+}
@@ -0,0 +1,432 @@
+// Package networkchangewatcher records changes to the device's network interfaces, addresses,
+// and default routes as events for the kolide_network_change_events table. It subscribes to
+// the platform's network change notifications -- netlink on Linux, a routing socket on macOS,
+// and NotifyIpInterfaceChange on Windows -- so that short-lived changes, like a VPN connection
+// flapping, are caught even though they would be missed by osquery's polling.
+package networkchangewatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+const (
+	// debounceDelay coalesces the burst of notifications a single change usually produces,
+	// while staying short enough to catch changes that are quickly reverted
+	debounceDelay = 250 * time.Millisecond
+
+	// pollInterval is how often we check the network state regardless of notifications, in
+	// case we miss a notification or cannot subscribe to them at all
+	pollInterval = 1 * time.Minute
+
+	// eventRetention and maxEvents bound how many events we keep
+	eventRetention = 7 * 24 * time.Hour
+	maxEvents      = 2000
+)
+
+// Actions
+const (
+	ActionInterfaceAdded      = "interface_added"
+	ActionInterfaceRemoved    = "interface_removed"
+	ActionInterfaceUp         = "interface_up"
+	ActionInterfaceDown       = "interface_down"
+	ActionAddressAdded        = "address_added"
+	ActionAddressRemoved      = "address_removed"
+	ActionDefaultRouteChanged = "default_route_changed"
+)
+
+// Sources describe how a change was detected
+const (
+	SourceNotification = "notification" // the platform notified launcher of the change
+	SourcePoll         = "poll"         // launcher noticed the change during a periodic check
+)
+
+// Event is a single change to the network configuration. For default route changes, the
+// interface and address are those the default route now uses, and the previous interface
+// and address those it used before; both are empty when there is no default route.
+type Event struct {
+	Time              int64  `json:"time"`
+	Action            string `json:"action"`
+	Source            string `json:"source"`
+	Interface         string `json:"interface,omitempty"`
+	Address           string `json:"address,omitempty"`
+	PreviousInterface string `json:"previous_interface,omitempty"`
+	PreviousAddress   string `json:"previous_address,omitempty"`
+}
+
+// interfaceState is the state of a single network interface.
+type interfaceState struct {
+	up    bool
+	addrs map[string]struct{}
+}
+
+// route is the interface and source address used to reach the default route.
+type route struct {
+	iface string
+	addr  string
+}
+
+// networkState is the network configuration at a point in time.
+type networkState struct {
+	interfaces    map[string]interfaceState
+	defaultRoutes map[string]route // by address family, "ipv4" or "ipv6"
+}
+
+type NetworkChangeWatcher struct {
+	slogger     *slog.Logger
+	store       types.GetterSetterDeleterIterator
+	readState   func() (*networkState, error)
+	interrupt   chan struct{}
+	interrupted atomic.Bool
+}
+
+func New(k types.Knapsack) *NetworkChangeWatcher {
+	return &NetworkChangeWatcher{
+		slogger:   k.Slogger().With("component", "network_change_watcher"),
+		store:     k.NetworkChangeEventsStore(),
+		readState: readNetworkState,
+		interrupt: make(chan struct{}, 1),
+	}
+}
+
+func (n *NetworkChangeWatcher) Execute() error {
+	// The state at startup is our baseline. Unlike file changes, changes to the network made
+	// while launcher wasn't running aren't worth reporting -- the device has likely moved
+	// between networks many times.
+	previous, err := n.readState()
+	if err != nil {
+		n.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not read initial network state",
+			"err", err,
+		)
+	}
+
+	notifications, stop, err := subscribe()
+	if err != nil {
+		n.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not subscribe to network change notifications, will rely on polling",
+			"err", err,
+		)
+	} else {
+		defer stop()
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case _, ok := <-notifications:
+			if !ok {
+				notifications = nil
+				continue
+			}
+			if debounce == nil {
+				debounce = time.After(debounceDelay)
+			}
+		case <-debounce:
+			debounce = nil
+			previous = n.check(previous, SourceNotification)
+		case <-ticker.C:
+			previous = n.check(previous, SourcePoll)
+		case <-n.interrupt:
+			n.slogger.Log(context.TODO(), slog.LevelDebug,
+				"interrupt received, exiting execute loop",
+			)
+			return nil
+		}
+	}
+}
+
+func (n *NetworkChangeWatcher) Interrupt(_ error) {
+	// Only perform shutdown tasks on first call to interrupt -- no need to repeat on potential extra calls.
+	if n.interrupted.Load() {
+		return
+	}
+	n.interrupted.Store(true)
+
+	n.interrupt <- struct{}{}
+}
+
+// check compares the current network state against previous, recording an event for each
+// change. It returns the state to compare against next time.
+func (n *NetworkChangeWatcher) check(previous *networkState, source string) *networkState {
+	current, err := n.readState()
+	if err != nil {
+		n.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not read network state",
+			"err", err,
+		)
+		return previous
+	}
+
+	// Without a previous state, this is our baseline -- there's no change to report
+	if previous == nil {
+		return current
+	}
+
+	events := diff(previous, current)
+	if len(events) == 0 {
+		return current
+	}
+
+	now := time.Now()
+	for i := range events {
+		events[i].Time = now.Unix()
+		events[i].Source = source
+	}
+
+	if err := n.recordEvents(now, events); err != nil {
+		n.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not record network change events",
+			"err", err,
+		)
+		return current
+	}
+
+	n.slogger.Log(context.TODO(), slog.LevelDebug,
+		"network changed",
+		"events", len(events),
+		"source", source,
+	)
+
+	return current
+}
+
+// diff returns the events describing the changes from previous to current, in a stable order.
+func diff(previous, current *networkState) []Event {
+	events := make([]Event, 0)
+
+	for _, name := range sortedKeys(current.interfaces) {
+		cur := current.interfaces[name]
+		prev, existed := previous.interfaces[name]
+		if !existed {
+			events = append(events, Event{Action: ActionInterfaceAdded, Interface: name})
+			if cur.up {
+				events = append(events, Event{Action: ActionInterfaceUp, Interface: name})
+			}
+			for _, addr := range sortedKeys(cur.addrs) {
+				events = append(events, Event{Action: ActionAddressAdded, Interface: name, Address: addr})
+			}
+			continue
+		}
+
+		if cur.up && !prev.up {
+			events = append(events, Event{Action: ActionInterfaceUp, Interface: name})
+		} else if !cur.up && prev.up {
+			events = append(events, Event{Action: ActionInterfaceDown, Interface: name})
+		}
+
+		for _, addr := range sortedKeys(cur.addrs) {
+			if _, ok := prev.addrs[addr]; !ok {
+				events = append(events, Event{Action: ActionAddressAdded, Interface: name, Address: addr})
+			}
+		}
+		for _, addr := range sortedKeys(prev.addrs) {
+			if _, ok := cur.addrs[addr]; !ok {
+				events = append(events, Event{Action: ActionAddressRemoved, Interface: name, Address: addr})
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(previous.interfaces) {
+		prev := previous.interfaces[name]
+		if _, ok := current.interfaces[name]; ok {
+			continue
+		}
+		for _, addr := range sortedKeys(prev.addrs) {
+			events = append(events, Event{Action: ActionAddressRemoved, Interface: name, Address: addr})
+		}
+		events = append(events, Event{Action: ActionInterfaceRemoved, Interface: name})
+	}
+
+	for _, family := range []string{"ipv4", "ipv6"} {
+		prev, cur := previous.defaultRoutes[family], current.defaultRoutes[family]
+		if prev == cur {
+			continue
+		}
+		events = append(events, Event{
+			Action:            ActionDefaultRouteChanged,
+			Interface:         cur.iface,
+			Address:           cur.addr,
+			PreviousInterface: prev.iface,
+			PreviousAddress:   prev.addr,
+		})
+	}
+
+	return events
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readNetworkState reads the current interfaces, their addresses, and the default routes.
+func readNetworkState() (*networkState, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("listing interfaces: %w", err)
+	}
+
+	state := &networkState{
+		interfaces:    make(map[string]interfaceState, len(ifaces)),
+		defaultRoutes: make(map[string]route),
+	}
+
+	// addrInterfaces lets us find the interface the default route uses from its source address
+	addrInterfaces := make(map[string]string)
+
+	for _, iface := range ifaces {
+		is := interfaceState{
+			up:    iface.Flags&net.FlagUp != 0,
+			addrs: make(map[string]struct{}),
+		}
+
+		// An interface can disappear between listing it and reading its addresses; we'll
+		// treat it as having none, and catch up on the next check
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			is.addrs[addr.String()] = struct{}{}
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				addrInterfaces[ipNet.IP.String()] = iface.Name
+			}
+		}
+
+		state.interfaces[iface.Name] = is
+	}
+
+	for family, probe := range defaultRouteProbes {
+		source := defaultRouteSource(family, probe)
+		if source == "" {
+			continue
+		}
+		state.defaultRoutes[family] = route{iface: addrInterfaces[source], addr: source}
+	}
+
+	return state, nil
+}
+
+// defaultRouteProbes are documentation addresses (RFC 5737, RFC 3849), which are not routed
+// anywhere and so will not have more specific routes than the default.
+var defaultRouteProbes = map[string]string{
+	"ipv4": "203.0.113.1:53",
+	"ipv6": "[2001:db8::1]:53",
+}
+
+// defaultRouteSource returns the source address the OS would use to send to probe, which
+// is an address on the interface the default route uses. Connecting a UDP socket selects
+// a route without sending anything. It returns an empty string when there is no route.
+func defaultRouteSource(family, probe string) string {
+	network := "udp4"
+	if family == "ipv6" {
+		network = "udp6"
+	}
+
+	conn, err := net.Dial(network, probe)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	localAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return ""
+	}
+	return localAddr.IP.String()
+}
+
+func (n *NetworkChangeWatcher) recordEvents(now time.Time, events []Event) error {
+	for i, event := range events {
+		raw, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("marshalling event: %w", err)
+		}
+
+		// Offset each event in the batch so that they keep their own keys, in order
+		if err := n.store.Set([]byte(eventKey(now.Add(time.Duration(i)))), raw); err != nil {
+			return fmt.Errorf("storing event: %w", err)
+		}
+	}
+
+	n.pruneEvents(now)
+	return nil
+}
+
+// eventKey returns the key for an event recorded at t. Zero-padded nanoseconds keep keys
+// unique and in order.
+func eventKey(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
+
+// pruneEvents deletes events older than eventRetention, and the oldest events beyond maxEvents
+func (n *NetworkChangeWatcher) pruneEvents(now time.Time) {
+	var keys []string
+	if err := n.store.ForEach(func(k, _ []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}); err != nil {
+		n.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not iterate over events to prune",
+			"err", err,
+		)
+		return
+	}
+	sort.Strings(keys)
+
+	cutoff := eventKey(now.Add(-eventRetention))
+	var toDelete [][]byte
+	for i, key := range keys {
+		if key < cutoff || len(keys)-i > maxEvents {
+			toDelete = append(toDelete, []byte(key))
+		}
+	}
+
+	if len(toDelete) == 0 {
+		return
+	}
+	if err := n.store.Delete(toDelete...); err != nil {
+		n.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not prune events",
+			"err", err,
+		)
+	}
+}
+
+// Events returns the recorded events in store, oldest first.
+func Events(store types.Iterator) ([]Event, error) {
+	eventsByKey := make(map[string]Event)
+	if err := store.ForEach(func(k, v []byte) error {
+		var event Event
+		if err := json.Unmarshal(v, &event); err != nil {
+			// Skip anything corrupt rather than failing the whole table
+			return nil
+		}
+		eventsByKey[string(k)] = event
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("iterating over events: %w", err)
+	}
+
+	keys := sortedKeys(eventsByKey)
+	events := make([]Event, 0, len(keys))
+	for _, k := range keys {
+		events = append(events, eventsByKey[k])
+	}
+
+	return events, nil
+}
@@ -0,0 +1,172 @@
+package networkchangewatcher
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func addrs(a ...string) map[string]struct{} {
+	m := make(map[string]struct{}, len(a))
+	for _, addr := range a {
+		m[addr] = struct{}{}
+	}
+	return m
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	previous := &networkState{
+		interfaces: map[string]interfaceState{
+			"en0":   {up: true, addrs: addrs("192.168.1.10/24")},
+			"en1":   {up: true, addrs: addrs("10.0.0.5/8")},
+			"utun3": {up: true, addrs: addrs("100.64.0.2/32")},
+		},
+		defaultRoutes: map[string]route{
+			"ipv4": {iface: "utun3", addr: "100.64.0.2"},
+		},
+	}
+	current := &networkState{
+		interfaces: map[string]interfaceState{
+			"en0":   {up: true, addrs: addrs("192.168.1.11/24")},
+			"en1":   {up: false, addrs: addrs("10.0.0.5/8")},
+			"utun4": {up: true, addrs: addrs("100.64.0.3/32")},
+		},
+		defaultRoutes: map[string]route{
+			"ipv4": {iface: "en0", addr: "192.168.1.11"},
+		},
+	}
+
+	require.Equal(t, []Event{
+		{Action: ActionAddressAdded, Interface: "en0", Address: "192.168.1.11/24"},
+		{Action: ActionAddressRemoved, Interface: "en0", Address: "192.168.1.10/24"},
+		{Action: ActionInterfaceDown, Interface: "en1"},
+		{Action: ActionInterfaceAdded, Interface: "utun4"},
+		{Action: ActionInterfaceUp, Interface: "utun4"},
+		{Action: ActionAddressAdded, Interface: "utun4", Address: "100.64.0.3/32"},
+		{Action: ActionAddressRemoved, Interface: "utun3", Address: "100.64.0.2/32"},
+		{Action: ActionInterfaceRemoved, Interface: "utun3"},
+		{Action: ActionDefaultRouteChanged, Interface: "en0", Address: "192.168.1.11", PreviousInterface: "utun3", PreviousAddress: "100.64.0.2"},
+	}, diff(previous, current))
+
+	require.Empty(t, diff(current, current))
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	states := []*networkState{
+		{
+			interfaces:    map[string]interfaceState{"eth0": {up: true, addrs: addrs("10.1.1.1/24")}},
+			defaultRoutes: map[string]route{"ipv4": {iface: "eth0", addr: "10.1.1.1"}},
+		},
+		{
+			interfaces: map[string]interfaceState{"eth0": {up: false, addrs: addrs()}},
+		},
+	}
+	next := 0
+
+	store := inmemory.NewStore()
+	n := &NetworkChangeWatcher{
+		slogger: multislogger.NewNopLogger(),
+		store:   store,
+		readState: func() (*networkState, error) {
+			s := states[next]
+			next += 1
+			return s, nil
+		},
+	}
+
+	// The first check is a baseline, with nothing to record
+	previous := n.check(nil, SourcePoll)
+	events, err := Events(store)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	n.check(previous, SourceNotification)
+	events, err = Events(store)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, ActionInterfaceDown, events[0].Action)
+	require.Equal(t, ActionAddressRemoved, events[1].Action)
+	require.Equal(t, ActionDefaultRouteChanged, events[2].Action)
+	require.Equal(t, "eth0", events[2].PreviousInterface)
+	require.Equal(t, "", events[2].Interface)
+	for _, event := range events {
+		require.Equal(t, SourceNotification, event.Source)
+		require.InDelta(t, time.Now().Unix(), event.Time, 5)
+	}
+}
+
+func TestPruneEvents(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	n := &NetworkChangeWatcher{
+		slogger: multislogger.NewNopLogger(),
+		store:   store,
+	}
+
+	now := time.Now()
+	require.NoError(t, store.Set([]byte(eventKey(now.Add(-2*eventRetention))), []byte(`{"action":"expired"}`)))
+	for i := 0; i < maxEvents+1; i++ {
+		require.NoError(t, store.Set([]byte(eventKey(now.Add(-time.Hour+time.Duration(i)*time.Millisecond))), []byte(fmt.Sprintf(`{"action":"%d"}`, i))))
+	}
+
+	n.pruneEvents(now)
+
+	events, err := Events(store)
+	require.NoError(t, err)
+	require.Len(t, events, maxEvents)
+	require.Equal(t, "1", events[0].Action, "expected the expired event and the oldest event to be pruned")
+}
+
+func TestReadNetworkState(t *testing.T) {
+	t.Parallel()
+
+	state, err := readNetworkState()
+	require.NoError(t, err)
+
+	// Every platform we run tests on has at least a loopback interface
+	require.NotEmpty(t, state.interfaces)
+
+	// Any default route should use one of the interfaces we found
+	for _, r := range state.defaultRoutes {
+		if r.iface != "" {
+			require.Contains(t, state.interfaces, r.iface)
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	n := &NetworkChangeWatcher{
+		slogger:   multislogger.NewNopLogger(),
+		store:     inmemory.NewStore(),
+		readState: readNetworkState,
+		interrupt: make(chan struct{}, 1),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- n.Execute()
+	}()
+
+	// Give the watcher time to subscribe before interrupting it
+	time.Sleep(500 * time.Millisecond)
+
+	n.Interrupt(nil)
+	n.Interrupt(nil)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Error("watcher did not exit after interrupt")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build darwin
+// +build darwin
+
+package networkchangewatcher
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// openChangeSocket opens a routing socket, which receives a message for each change to the
+// routing table and to interfaces and their addresses. This is the same source of changes
+// that SCNetworkReachability relies on, without needing cgo.
+func openChangeSocket() (int, error) {
+	fd, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, unix.AF_UNSPEC)
+	if err != nil {
+		return -1, fmt.Errorf("opening routing socket: %w", err)
+	}
+	unix.CloseOnExec(fd)
+
+	return fd, nil
+}
@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package networkchangewatcher
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// openChangeSocket opens a netlink socket subscribed to link, address, and route changes.
+func openChangeSocket() (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return -1, fmt.Errorf("opening netlink socket: %w", err)
+	}
+
+	addr := &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK |
+			unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR |
+			unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE,
+	}
+	if err := unix.Bind(fd, addr); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("binding netlink socket: %w", err)
+	}
+
+	return fd, nil
+}
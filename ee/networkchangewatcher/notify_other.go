@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package networkchangewatcher
+
+import "errors"
+
+// subscribe is not supported on this platform; the watcher relies on polling instead.
+func subscribe() (<-chan struct{}, func(), error) {
+	return nil, nil, errors.New("network change notifications not supported on this platform")
+}
@@ -0,0 +1,52 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package networkchangewatcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// subscribe returns a channel that receives whenever the OS reports a change to the network
+// configuration, and a function to stop the subscription. We don't parse the messages we
+// receive -- they only tell us when to compare the network state against the last we saw.
+func subscribe() (<-chan struct{}, func(), error) {
+	fd, err := openChangeSocket()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A non-blocking socket lets the os.File use the runtime's poller, so that closing it
+	// interrupts a pending read
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return nil, nil, fmt.Errorf("setting socket non-blocking: %w", err)
+	}
+	socket := os.NewFile(uintptr(fd), "network change socket")
+
+	notifications := make(chan struct{}, 1)
+	go func() {
+		defer close(notifications)
+
+		buf := make([]byte, os.Getpagesize()*4)
+		for {
+			if _, err := socket.Read(buf); err != nil && !errors.Is(err, unix.ENOBUFS) {
+				// The socket was closed, or is unusable; either way, we're done
+				return
+			}
+
+			// ENOBUFS means the kernel dropped messages because we were slow to read them --
+			// there were changes, so notify as usual
+			select {
+			case notifications <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return notifications, func() { socket.Close() }, nil
+}
@@ -0,0 +1,56 @@
+//go:build windows
+// +build windows
+
+package networkchangewatcher
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	// Callbacks created with windows.NewCallback are never released, so we create ours once
+	changeCallback     uintptr
+	changeCallbackOnce sync.Once
+
+	// windowsNotifications receives from the callback
+	windowsNotifications = make(chan struct{}, 1)
+)
+
+// onNetworkChange is called by Windows, on its own thread, for each interface or address change.
+func onNetworkChange(callerContext, row uintptr, notificationType uint32) uintptr {
+	select {
+	case windowsNotifications <- struct{}{}:
+	default:
+	}
+	return 0
+}
+
+// subscribe returns a channel that receives whenever Windows reports a change to an interface
+// or a unicast address, and a function to stop the subscription. Changes to routes alone are caught by
+// polling, since golang.org/x/sys/windows does not wrap NotifyRouteChange2.
+func subscribe() (<-chan struct{}, func(), error) {
+	changeCallbackOnce.Do(func() {
+		changeCallback = windows.NewCallback(onNetworkChange)
+	})
+
+	var interfaceHandle windows.Handle
+	if err := windows.NotifyIpInterfaceChange(windows.AF_UNSPEC, changeCallback, nil, false, &interfaceHandle); err != nil {
+		return nil, nil, fmt.Errorf("subscribing to interface changes: %w", err)
+	}
+
+	var addressHandle windows.Handle
+	if err := windows.NotifyUnicastIpAddressChange(windows.AF_UNSPEC, changeCallback, nil, false, &addressHandle); err != nil {
+		_ = windows.CancelMibChangeNotify2(interfaceHandle)
+		return nil, nil, fmt.Errorf("subscribing to address changes: %w", err)
+	}
+
+	stop := func() {
+		_ = windows.CancelMibChangeNotify2(interfaceHandle)
+		_ = windows.CancelMibChangeNotify2(addressHandle)
+	}
+
+	return windowsNotifications, stop, nil
+}
@@ -0,0 +1,226 @@
+// Package osquerydbreset resets an osquery instance's RocksDB database, for when it has become
+// corrupted. The old database is archived rather than deleted, so that it can be inspected,
+// and launcher's own state -- including enrollment -- is left alone, apart from the snapshots
+// behind snapshot-diff tables, which are cleared so that they re-baseline along with osquery's
+// own differential queries and event tables.
+package osquerydbreset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/gowrapper"
+)
+
+const (
+	// archiveInfix separates the database path from the reset time in the archive's name
+	archiveInfix = ".corrupt-"
+
+	// archiveTimeLayout sorts lexically in time order
+	archiveTimeLayout = "20060102T150405Z"
+
+	// maxArchives is how many archived databases we keep for each registration, including the
+	// newest; archived databases can be large.
+	maxArchives = 2
+
+	TriggerRemoteAction = "remote_action"
+	TriggerSubcommand   = "subcommand"
+)
+
+// pendingReportsKey holds the reports of resets that haven't yet been sent to the control
+// server, because they happened while launcher wasn't running.
+var pendingReportsKey = []byte("osquery_db_reset_pending_reports")
+
+// Report describes a reset, for the control server.
+type Report struct {
+	RegistrationID   string `json:"registration_id"`
+	Trigger          string `json:"trigger"` // TriggerRemoteAction or TriggerSubcommand
+	Reason           string `json:"reason,omitempty"`
+	Timestamp        int64  `json:"timestamp"`
+	ArchivePath      string `json:"archive_path,omitempty"` // empty if there was no database to archive
+	ClearedSnapshots int    `json:"cleared_snapshots"`
+	Error            string `json:"error,omitempty"`
+}
+
+// DatabasePath returns the path to the database of the osquery instance for the given
+// registration, as the osquery runtime sets it.
+func DatabasePath(rootDirectory, registrationId string) string {
+	if registrationId == types.DefaultRegistrationID {
+		return filepath.Join(rootDirectory, "osquery.db")
+	}
+	return filepath.Join(rootDirectory, fmt.Sprintf("osquery-%s.db", registrationId))
+}
+
+// Reset archives the database of the osquery instance for the given registration, and clears
+// the registration's snapshot-diff snapshots. osquery must not be running. The returned report
+// has the trigger and reason unset, for the caller to fill in.
+func Reset(rootDirectory string, snapshotStore types.KVStore, registrationId string, now time.Time) (Report, error) {
+	report := Report{
+		RegistrationID: registrationId,
+		Timestamp:      now.Unix(),
+	}
+
+	archivePath, err := archiveDatabase(DatabasePath(rootDirectory, registrationId), now)
+	if err != nil {
+		return report, fmt.Errorf("archiving database: %w", err)
+	}
+	report.ArchivePath = archivePath
+
+	cleared, err := clearSnapshots(snapshotStore, registrationId)
+	report.ClearedSnapshots = cleared
+	if err != nil {
+		return report, fmt.Errorf("clearing snapshots: %w", err)
+	}
+
+	return report, nil
+}
+
+// archiveDatabase moves the database aside, and removes all but the newest archives. It returns
+// the archive's path, or an empty string if there was no database.
+func archiveDatabase(dbPath string, now time.Time) (string, error) {
+	if _, err := os.Stat(dbPath); errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("checking %s: %w", dbPath, err)
+	}
+
+	archivePath := dbPath + archiveInfix + now.UTC().Format(archiveTimeLayout)
+	if err := os.Rename(dbPath, archivePath); err != nil {
+		return "", fmt.Errorf("moving %s aside: %w", dbPath, err)
+	}
+
+	archives, err := filepath.Glob(dbPath + archiveInfix + "*")
+	if err != nil {
+		return archivePath, fmt.Errorf("listing archives: %w", err)
+	}
+	sort.Strings(archives)
+
+	var errs []error
+	for len(archives) > maxArchives {
+		if err := os.RemoveAll(archives[0]); err != nil {
+			errs = append(errs, fmt.Errorf("removing old archive %s: %w", archives[0], err))
+		}
+		archives = archives[1:]
+	}
+
+	return archivePath, errors.Join(errs...)
+}
+
+// clearSnapshots deletes the registration's snapshots, so that each snapshot-diff table emits
+// its rows afresh, with a counter of 0. It returns the number of snapshots deleted.
+func clearSnapshots(store types.KVStore, registrationId string) (int, error) {
+	if store == nil {
+		return 0, nil
+	}
+
+	var keys [][]byte
+	if err := store.ForEach(func(k, _ []byte) error {
+		_, _, identifier := storage.SplitKey(k)
+		if bytes.Equal(identifier, []byte(registrationId)) {
+			keys = append(keys, bytes.Clone(k))
+		}
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("iterating over snapshots: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	if err := store.Delete(keys...); err != nil {
+		return 0, fmt.Errorf("deleting snapshots: %w", err)
+	}
+
+	return len(keys), nil
+}
+
+// RecordPending saves the report, to send to the control server once launcher is running.
+func RecordPending(store types.GetterSetter, report Report) error {
+	reports, err := pendingReports(store)
+	if err != nil {
+		return err
+	}
+
+	reportsRaw, err := json.Marshal(append(reports, report))
+	if err != nil {
+		return fmt.Errorf("marshalling pending reports: %w", err)
+	}
+
+	return store.Set(pendingReportsKey, reportsRaw)
+}
+
+// PublishPending publishes the reports saved by RecordPending, and clears them.
+func PublishPending(ctx context.Context, slogger *slog.Logger, store types.GetterSetterDeleter) error {
+	reports, err := pendingReports(store)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return nil
+	}
+
+	if err := store.Delete(pendingReportsKey); err != nil {
+		return fmt.Errorf("clearing pending reports: %w", err)
+	}
+
+	for _, report := range reports {
+		Publish(ctx, slogger, report)
+	}
+
+	return nil
+}
+
+func pendingReports(store types.Getter) ([]Report, error) {
+	reportsRaw, err := store.Get(pendingReportsKey)
+	if err != nil {
+		return nil, fmt.Errorf("getting pending reports: %w", err)
+	}
+	if len(reportsRaw) == 0 {
+		return nil, nil
+	}
+
+	var reports []Report
+	if err := json.Unmarshal(reportsRaw, &reports); err != nil {
+		return nil, fmt.Errorf("unmarshalling pending reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+var subscribers = struct {
+	sync.Mutex
+	funcs []func(Report)
+}{}
+
+// Subscribe registers f to be called with each reset report. f is called in its own goroutine.
+func Subscribe(f func(Report)) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	subscribers.funcs = append(subscribers.funcs, f)
+}
+
+// Publish sends the report to subscribers.
+func Publish(ctx context.Context, slogger *slog.Logger, report Report) {
+	subscribers.Lock()
+	funcs := make([]func(Report), len(subscribers.funcs))
+	copy(funcs, subscribers.funcs)
+	subscribers.Unlock()
+
+	for _, f := range funcs {
+		gowrapper.Go(ctx, slogger, func() {
+			f(report)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package osquerydbreset
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabasePath(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, filepath.Join("root", "osquery.db"), DatabasePath("root", types.DefaultRegistrationID))
+	require.Equal(t, filepath.Join("root", "osquery-other.db"), DatabasePath("root", "other"))
+}
+
+func TestReset(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	dbPath := DatabasePath(rootDir, types.DefaultRegistrationID)
+	require.NoError(t, os.MkdirAll(dbPath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dbPath, "CURRENT"), []byte("MANIFEST-000001\n"), 0644))
+
+	// The other registration's database and snapshots are left alone
+	otherDbPath := DatabasePath(rootDir, "other")
+	require.NoError(t, os.MkdirAll(otherDbPath, 0755))
+
+	snapshotStore := inmemory.NewStore()
+	require.NoError(t, snapshotStore.Set(storage.KeyByIdentifier([]byte("kolide_listening_ports"), storage.IdentifierTypeRegistration, []byte(types.DefaultRegistrationID)), []byte("{}")))
+	require.NoError(t, snapshotStore.Set(storage.KeyByIdentifier([]byte("kolide_hosts"), storage.IdentifierTypeRegistration, []byte(types.DefaultRegistrationID)), []byte("{}")))
+	otherKey := storage.KeyByIdentifier([]byte("kolide_hosts"), storage.IdentifierTypeRegistration, []byte("other"))
+	require.NoError(t, snapshotStore.Set(otherKey, []byte("{}")))
+
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	report, err := Reset(rootDir, snapshotStore, types.DefaultRegistrationID, now)
+	require.NoError(t, err)
+
+	require.Equal(t, Report{
+		RegistrationID:   types.DefaultRegistrationID,
+		Timestamp:        now.Unix(),
+		ArchivePath:      dbPath + ".corrupt-20240301T123000Z",
+		ClearedSnapshots: 2,
+	}, report)
+
+	require.NoDirExists(t, dbPath)
+	require.FileExists(t, filepath.Join(report.ArchivePath, "CURRENT"))
+	require.DirExists(t, otherDbPath)
+
+	remaining := 0
+	require.NoError(t, snapshotStore.ForEach(func(k, _ []byte) error {
+		require.Equal(t, otherKey, k)
+		remaining += 1
+		return nil
+	}))
+	require.Equal(t, 1, remaining)
+
+	// Resetting again without a database is fine
+	report, err = Reset(rootDir, snapshotStore, types.DefaultRegistrationID, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, report.ArchivePath)
+	require.Zero(t, report.ClearedSnapshots)
+}
+
+func TestReset_PrunesOldArchives(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	dbPath := DatabasePath(rootDir, types.DefaultRegistrationID)
+	start := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	var archives []string
+	for i := 0; i < maxArchives+2; i++ {
+		require.NoError(t, os.MkdirAll(dbPath, 0755))
+		report, err := Reset(rootDir, nil, types.DefaultRegistrationID, start.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+		archives = append(archives, report.ArchivePath)
+	}
+
+	for _, archive := range archives[:2] {
+		require.NoDirExists(t, archive)
+	}
+	for _, archive := range archives[2:] {
+		require.DirExists(t, archive)
+	}
+}
+
+func TestPendingReports(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+
+	// Nothing to publish yet
+	require.NoError(t, PublishPending(context.TODO(), multislogger.NewNopLogger(), store))
+
+	reports := []Report{
+		{RegistrationID: types.DefaultRegistrationID, Trigger: TriggerSubcommand, Reason: "corrupted", Timestamp: 1},
+		{RegistrationID: "other", Trigger: TriggerSubcommand, Timestamp: 2, Error: "archiving database: test error"},
+	}
+	for _, r := range reports {
+		require.NoError(t, RecordPending(store, r))
+	}
+
+	pending, err := pendingReports(store)
+	require.NoError(t, err)
+	require.Equal(t, reports, pending)
+
+	require.NoError(t, PublishPending(context.TODO(), multislogger.NewNopLogger(), store))
+
+	pending, err = pendingReports(store)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
@@ -0,0 +1,71 @@
+package restartrequired
+
+import (
+	"bufio"
+	"strings"
+)
+
+// parseRebootRequiredPkgs parses /var/run/reboot-required.pkgs, written by Debian and Ubuntu
+// package scripts, into the packages that require a restart.
+func parseRebootRequiredPkgs(contents string) []string {
+	seen := make(map[string]bool)
+	var packages []string
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		pkg := strings.TrimSpace(scanner.Text())
+		if pkg == "" || seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+		packages = append(packages, pkg)
+	}
+
+	return packages
+}
+
+// parseSoftwareUpdateRestarts returns the titles of the updates in `softwareupdate --list`
+// output that restart the device when installed. Each update has a line like
+//
+//	Title: macOS Sonoma 14.5, Version: 14.5, Size: 3717349K, Recommended: YES, Action: restart,
+func parseSoftwareUpdateRestarts(output string) []string {
+	var titles []string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "Title:") {
+			continue
+		}
+
+		var title string
+		restart := false
+		for _, field := range strings.Split(line, ",") {
+			key, value, found := strings.Cut(field, ":")
+			if !found {
+				continue
+			}
+			switch strings.TrimSpace(key) {
+			case "Title":
+				title = strings.TrimSpace(value)
+			case "Action":
+				restart = strings.EqualFold(strings.TrimSpace(value), "restart")
+			}
+		}
+
+		if restart && title != "" {
+			titles = append(titles, title)
+		}
+	}
+
+	return titles
+}
+
+// parseFdesetupDeferred reports whether `fdesetup status` output shows FileVault enablement
+// deferred until the next login or restart, e.g.
+//
+//	FileVault is Off.
+//	Deferred enablement appears to be active for user 'alice'.
+func parseFdesetupDeferred(output string) bool {
+	return strings.Contains(strings.ToLower(output), "deferred enablement appears to be active")
+}
@@ -0,0 +1,44 @@
+//go:build darwin
+// +build darwin
+
+package restartrequired
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+// detectRequirements checks for available updates that restart the device when installed,
+// using softwareupdate's cached results rather than scanning, and for FileVault enablement
+// waiting on the next restart.
+func detectRequirements(ctx context.Context, slogger *slog.Logger) []Requirement {
+	var requirements []Requirement
+
+	var stdout bytes.Buffer
+	if err := tablehelpers.Run(ctx, slogger, 30, allowedcmd.Softwareupdate, []string{"--list", "--no-scan"}, &stdout, &stdout); err != nil {
+		slogger.Log(ctx, slog.LevelDebug,
+			"could not list software updates",
+			"err", err,
+		)
+	} else {
+		for _, title := range parseSoftwareUpdateRestarts(stdout.String()) {
+			requirements = append(requirements, Requirement{Source: SourceOsUpdate, Reason: title})
+		}
+	}
+
+	stdout.Reset()
+	if err := tablehelpers.Run(ctx, slogger, 10, allowedcmd.Fdesetup, []string{"status"}, &stdout, &stdout); err != nil {
+		slogger.Log(ctx, slog.LevelDebug,
+			"could not get FileVault status",
+			"err", err,
+		)
+	} else if parseFdesetupDeferred(stdout.String()) {
+		requirements = append(requirements, Requirement{Source: SourceEncryption, Reason: "FileVault deferred enablement"})
+	}
+
+	return requirements
+}
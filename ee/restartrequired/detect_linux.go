@@ -0,0 +1,39 @@
+//go:build linux
+// +build linux
+
+package restartrequired
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	rebootRequiredFile     = "/var/run/reboot-required"
+	rebootRequiredPkgsFile = "/var/run/reboot-required.pkgs"
+)
+
+// detectRequirements checks for the flag file Debian and Ubuntu package scripts leave when an
+// update needs a restart to take effect.
+func detectRequirements(ctx context.Context, slogger *slog.Logger) []Requirement {
+	if _, err := os.Stat(rebootRequiredFile); err != nil {
+		if !os.IsNotExist(err) {
+			slogger.Log(ctx, slog.LevelDebug,
+				"could not check for reboot-required file",
+				"err", err,
+			)
+		}
+		return nil
+	}
+
+	reason := "packages updated"
+	if contents, err := os.ReadFile(rebootRequiredPkgsFile); err == nil {
+		if packages := parseRebootRequiredPkgs(string(contents)); len(packages) > 0 {
+			reason = "packages updated: " + strings.Join(packages, ", ")
+		}
+	}
+
+	return []Requirement{{Source: SourceOsUpdate, Reason: reason}}
+}
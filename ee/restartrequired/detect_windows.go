@@ -0,0 +1,46 @@
+//go:build windows
+// +build windows
+
+package restartrequired
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// rebootPendingKeys are registry keys that exist only while an update is waiting for a
+// restart. We don't consider PendingFileRenameOperations, which many installers set without
+// needing the restart to be prompt.
+var rebootPendingKeys = map[string]string{
+	`SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update\RebootRequired`: "Windows Update",
+	`SOFTWARE\Microsoft\Windows\CurrentVersion\Component Based Servicing\RebootPending`:  "Windows component servicing",
+}
+
+// detectRequirements checks for updates installed by Windows Update or component servicing
+// that are waiting for a restart.
+func detectRequirements(ctx context.Context, slogger *slog.Logger) []Requirement {
+	var requirements []Requirement
+
+	for path, reason := range rebootPendingKeys {
+		key, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+		if err != nil {
+			if !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+				slogger.Log(ctx, slog.LevelDebug,
+					"could not check for pending reboot",
+					"key", path,
+					"err", err,
+				)
+			}
+			continue
+		}
+		key.Close()
+
+		requirements = append(requirements, Requirement{Source: SourceOsUpdate, Reason: reason})
+	}
+
+	return requirements
+}
@@ -0,0 +1,235 @@
+// Package restartrequired tracks whether this device needs to restart -- to finish installing OS
+// updates, to pick up an agent update, or to complete enabling disk encryption -- and coordinates
+// reminding the user about it. Rather than each subsystem nagging independently, pending restarts
+// from every source are combined into a single reminder schedule, with a limited number of
+// snoozes before the restart is considered overdue. The state is reported to the control server
+// as it changes, and kept for the kolide_restart_required table.
+package restartrequired
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+)
+
+// Sources of restart requirements
+const (
+	SourceOsUpdate    = "os_update"
+	SourceAgentUpdate = "agent_update"
+	SourceEncryption  = "encryption"
+)
+
+// States of the restart requirement
+const (
+	StateNone     = "none"     // no restart is required
+	StatePending  = "pending"  // a restart is required, but the user hasn't been reminded yet
+	StateNotified = "notified" // the user has been reminded, and has snoozes remaining
+	StateOverdue  = "overdue"  // the user has used up their snoozes
+)
+
+const (
+	// statusKey is the key in the persistent host data store for the current status
+	statusKey = "restart_required_status"
+
+	defaultSnoozeInterval  = 4 * time.Hour
+	defaultMaxSnoozes      = 3
+	defaultOverdueInterval = 24 * time.Hour
+
+	// minReminderInterval keeps a misconfigured policy from reminding the user constantly
+	minReminderInterval = 15 * time.Minute
+)
+
+// Requirement is a single reason this device needs to restart.
+type Requirement struct {
+	Source     string    `json:"source"`
+	Reason     string    `json:"reason"`
+	DetectedAt time.Time `json:"detected_at"`
+
+	// FromServer is set for requirements sent by the control server, rather than detected
+	// locally. They're satisfied by the next restart, which we notice by the boot time changing.
+	FromServer bool   `json:"from_server,omitempty"`
+	BootTime   uint64 `json:"boot_time,omitempty"`
+}
+
+func (r Requirement) key() string {
+	return r.Source + "\x00" + r.Reason
+}
+
+// Status is the current state of the restart requirement.
+type Status struct {
+	State              string        `json:"state"`
+	Requirements       []Requirement `json:"requirements"`
+	SnoozesUsed        int           `json:"snoozes_used"`
+	MaxSnoozes         int           `json:"max_snoozes"`
+	LastNotifiedAt     time.Time     `json:"last_notified_at"`
+	NextNotificationAt time.Time     `json:"next_notification_at"`
+	UpdatedAt          time.Time     `json:"updated_at"`
+}
+
+// Policy is set by the control server, and controls how the user is reminded.
+type Policy struct {
+	// NotificationsEnabled turns on reminders. Without it, restart requirements are still
+	// tracked and reported, but the user isn't reminded.
+	NotificationsEnabled   bool `json:"notifications_enabled"`
+	SnoozeIntervalSeconds  int  `json:"snooze_interval_seconds,omitempty"`
+	MaxSnoozes             int  `json:"max_snoozes,omitempty"`
+	OverdueIntervalSeconds int  `json:"overdue_interval_seconds,omitempty"`
+
+	// ActionUri, if set, is opened when the user clicks a reminder
+	ActionUri string `json:"action_uri,omitempty"`
+
+	// Requirements are restarts the control server knows are needed, e.g. to finish enabling
+	// encryption or apply an agent update
+	Requirements []Requirement `json:"requirements,omitempty"`
+}
+
+func (p Policy) snoozeInterval() time.Duration {
+	return intervalOrDefault(p.SnoozeIntervalSeconds, defaultSnoozeInterval)
+}
+
+func (p Policy) overdueInterval() time.Duration {
+	return intervalOrDefault(p.OverdueIntervalSeconds, defaultOverdueInterval)
+}
+
+func (p Policy) maxSnoozes() int {
+	if p.MaxSnoozes <= 0 {
+		return defaultMaxSnoozes
+	}
+	return p.MaxSnoozes
+}
+
+func intervalOrDefault(seconds int, defaultInterval time.Duration) time.Duration {
+	if seconds <= 0 {
+		return defaultInterval
+	}
+	if interval := time.Duration(seconds) * time.Second; interval > minReminderInterval {
+		return interval
+	}
+	return minReminderInterval
+}
+
+// LoadStatus returns the status last saved to store, or a status with no restart required if
+// none has been saved yet.
+func LoadStatus(store types.Getter) (Status, error) {
+	raw, err := store.Get([]byte(statusKey))
+	if err != nil {
+		return Status{}, fmt.Errorf("getting restart required status: %w", err)
+	}
+	if len(raw) == 0 {
+		return Status{State: StateNone}, nil
+	}
+
+	var status Status
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return Status{}, fmt.Errorf("unmarshalling restart required status: %w", err)
+	}
+	return status, nil
+}
+
+func saveStatus(store types.Setter, status Status) error {
+	raw, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("marshalling restart required status: %w", err)
+	}
+	if err := store.Set([]byte(statusKey), raw); err != nil {
+		return fmt.Errorf("saving restart required status: %w", err)
+	}
+	return nil
+}
+
+// nextStatus returns the status given the restarts currently required. Requirements that were
+// already pending keep the time they were first detected, and the reminder schedule carries on
+// where it left off; once nothing requires a restart, the status resets.
+func nextStatus(prev Status, current []Requirement, policy Policy, now time.Time) Status {
+	detectedAt := make(map[string]time.Time, len(prev.Requirements))
+	for _, r := range prev.Requirements {
+		detectedAt[r.key()] = r.DetectedAt
+	}
+
+	seen := make(map[string]bool, len(current))
+	requirements := make([]Requirement, 0, len(current))
+	for _, r := range current {
+		if seen[r.key()] {
+			continue
+		}
+		seen[r.key()] = true
+
+		if previouslyDetected, ok := detectedAt[r.key()]; ok {
+			r.DetectedAt = previouslyDetected
+		} else if r.DetectedAt.IsZero() {
+			r.DetectedAt = now
+		}
+		requirements = append(requirements, r)
+	}
+	sort.Slice(requirements, func(i, j int) bool {
+		if requirements[i].Source != requirements[j].Source {
+			return requirements[i].Source < requirements[j].Source
+		}
+		return requirements[i].Reason < requirements[j].Reason
+	})
+
+	if len(requirements) == 0 {
+		return Status{
+			State:        StateNone,
+			Requirements: requirements,
+			MaxSnoozes:   policy.maxSnoozes(),
+			UpdatedAt:    now,
+		}
+	}
+
+	next := prev
+	next.Requirements = requirements
+	next.MaxSnoozes = policy.maxSnoozes()
+	next.UpdatedAt = now
+	if prev.State == "" || prev.State == StateNone {
+		next.State = StatePending
+		next.SnoozesUsed = 0
+		next.LastNotifiedAt = time.Time{}
+		next.NextNotificationAt = now
+	}
+
+	return next
+}
+
+// notificationDue reports whether the user should be reminded at now.
+func (s Status) notificationDue(now time.Time) bool {
+	return s.State != StateNone && !now.Before(s.NextNotificationAt)
+}
+
+// notified returns the status after the user was reminded at now. Each reminder after the
+// first means the previous one was snoozed; once the snoozes are used up, the restart is
+// overdue, and the user is reminded less often but without end.
+func (s Status) notified(policy Policy, now time.Time) Status {
+	switch s.State {
+	case StatePending:
+		s.State = StateNotified
+	case StateNotified:
+		s.SnoozesUsed += 1
+		if s.SnoozesUsed >= policy.maxSnoozes() {
+			s.State = StateOverdue
+		}
+	}
+
+	s.LastNotifiedAt = now
+	if s.State == StateOverdue {
+		s.NextNotificationAt = now.Add(policy.overdueInterval())
+	} else {
+		s.NextNotificationAt = now.Add(policy.snoozeInterval())
+	}
+	s.UpdatedAt = now
+
+	return s
+}
+
+// reportable is the part of the status the control server is told about when it changes --
+// the state and what requires the restart, but not every change of reminder time.
+func (s Status) reportable() string {
+	reportable := fmt.Sprintf("%s/%d", s.State, s.SnoozesUsed)
+	for _, r := range s.Requirements {
+		reportable += "/" + r.key()
+	}
+	return reportable
+}
@@ -0,0 +1,245 @@
+package restartrequired
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/desktop/user/notify"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextStatus(t *testing.T) {
+	t.Parallel()
+
+	start := time.Unix(1700000000, 0)
+	update := Requirement{Source: SourceOsUpdate, Reason: "macOS Sonoma 14.5"}
+	encryption := Requirement{Source: SourceEncryption, Reason: "FileVault deferred enablement"}
+
+	// A new requirement starts the reminder schedule
+	status := nextStatus(Status{State: StateNone}, []Requirement{update}, Policy{}, start)
+	require.Equal(t, StatePending, status.State)
+	require.Equal(t, defaultMaxSnoozes, status.MaxSnoozes)
+	require.Equal(t, start, status.NextNotificationAt)
+	require.Len(t, status.Requirements, 1)
+	require.Equal(t, start, status.Requirements[0].DetectedAt)
+
+	// Requirements keep when they were first detected, and the schedule carries on
+	status = status.notified(Policy{}, start)
+	later := start.Add(time.Hour)
+	status = nextStatus(status, []Requirement{encryption, update, update}, Policy{}, later)
+	require.Equal(t, StateNotified, status.State)
+	require.Len(t, status.Requirements, 2)
+	require.Equal(t, SourceEncryption, status.Requirements[0].Source)
+	require.Equal(t, later, status.Requirements[0].DetectedAt)
+	require.Equal(t, start, status.Requirements[1].DetectedAt)
+	require.Equal(t, start.Add(defaultSnoozeInterval), status.NextNotificationAt)
+
+	// Once nothing requires a restart, the status resets
+	status = nextStatus(status, nil, Policy{}, later)
+	require.Equal(t, StateNone, status.State)
+	require.Empty(t, status.Requirements)
+	require.Zero(t, status.SnoozesUsed)
+	require.True(t, status.NextNotificationAt.IsZero())
+}
+
+func TestNotified(t *testing.T) {
+	t.Parallel()
+
+	policy := Policy{MaxSnoozes: 2, SnoozeIntervalSeconds: 3600, OverdueIntervalSeconds: 7200}
+	now := time.Unix(1700000000, 0)
+	status := nextStatus(Status{}, []Requirement{{Source: SourceAgentUpdate, Reason: "1.2.3"}}, policy, now)
+
+	require.True(t, status.notificationDue(now))
+	status = status.notified(policy, now)
+	require.Equal(t, StateNotified, status.State)
+	require.Zero(t, status.SnoozesUsed)
+	require.False(t, status.notificationDue(now.Add(59*time.Minute)))
+	require.True(t, status.notificationDue(now.Add(time.Hour)))
+
+	now = now.Add(time.Hour)
+	status = status.notified(policy, now)
+	require.Equal(t, StateNotified, status.State)
+	require.Equal(t, 1, status.SnoozesUsed)
+	require.Equal(t, now.Add(time.Hour), status.NextNotificationAt)
+
+	now = now.Add(time.Hour)
+	status = status.notified(policy, now)
+	require.Equal(t, StateOverdue, status.State)
+	require.Equal(t, 2, status.SnoozesUsed)
+	require.Equal(t, now.Add(2*time.Hour), status.NextNotificationAt)
+
+	now = now.Add(2 * time.Hour)
+	status = status.notified(policy, now)
+	require.Equal(t, StateOverdue, status.State)
+	require.Equal(t, 2, status.SnoozesUsed)
+	require.Equal(t, now, status.LastNotifiedAt)
+}
+
+func TestIntervalOrDefault(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, defaultSnoozeInterval, Policy{}.snoozeInterval())
+	require.Equal(t, minReminderInterval, Policy{SnoozeIntervalSeconds: 1}.snoozeInterval())
+	require.Equal(t, 2*time.Hour, Policy{OverdueIntervalSeconds: 7200}.overdueInterval())
+}
+
+func TestDescribeSources(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "to finish installing updates", describeSources([]Requirement{
+		{Source: SourceOsUpdate, Reason: "a"},
+		{Source: SourceOsUpdate, Reason: "b"},
+	}))
+	require.Equal(t, "to finish enabling disk encryption, finish installing updates and finish updating Kolide", describeSources([]Requirement{
+		{Source: SourceEncryption},
+		{Source: SourceOsUpdate},
+		{Source: SourceAgentUpdate},
+	}))
+}
+
+type mockNotifier struct {
+	err  error
+	sent []notify.Notification
+}
+
+func (m *mockNotifier) SendNotification(n notify.Notification) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, n)
+	return nil
+}
+
+type mockMessenger struct {
+	messages []Status
+}
+
+func (m *mockMessenger) SendMessage(method string, params interface{}) error {
+	m.messages = append(m.messages, params.(Status))
+	return nil
+}
+
+func testTracker(notifier *mockNotifier, messenger *mockMessenger, detected *[]Requirement, bootTime *uint64) *Tracker {
+	return &Tracker{
+		slogger:     multislogger.NewNopLogger(),
+		statusStore: inmemory.NewStore(),
+		policyStore: inmemory.NewStore(),
+		notifier:    notifier,
+		messenger:   messenger,
+		detect: func(context.Context, *slog.Logger) []Requirement {
+			return *detected
+		},
+		bootTime: func() (uint64, error) {
+			return *bootTime, nil
+		},
+		permits: func(context.Context, time.Time, time.Time) bool {
+			return true
+		},
+		checkRequests: make(chan struct{}, 1),
+		interrupt:     make(chan struct{}, 1),
+	}
+}
+
+func TestTrackerCheck(t *testing.T) {
+	t.Parallel()
+
+	notifier := &mockNotifier{}
+	messenger := &mockMessenger{}
+	detected := []Requirement{}
+	bootTime := uint64(1000)
+	tracker := testTracker(notifier, messenger, &detected, &bootTime)
+
+	now := time.Unix(1700000000, 0)
+
+	// Nothing required: reported once, nothing sent
+	tracker.check(context.TODO(), now)
+	tracker.check(context.TODO(), now)
+	require.Len(t, messenger.messages, 1)
+	require.Equal(t, StateNone, messenger.messages[0].State)
+	require.Empty(t, notifier.sent)
+
+	// Server requirement, but reminders are off
+	require.NoError(t, tracker.Update(strings.NewReader(`{"requirements":[{"source":"agent_update","reason":"launcher 1.2.3"}]}`)))
+	tracker.check(context.TODO(), now)
+	require.Len(t, messenger.messages, 2)
+	require.Equal(t, StatePending, messenger.messages[1].State)
+	require.True(t, messenger.messages[1].Requirements[0].FromServer)
+	require.Empty(t, notifier.sent)
+
+	// Reminders on, and a local requirement too: one combined reminder
+	detected = []Requirement{{Source: SourceOsUpdate, Reason: "packages updated"}}
+	require.NoError(t, tracker.Update(strings.NewReader(`{"notifications_enabled":true,"requirements":[{"source":"agent_update","reason":"launcher 1.2.3"}]}`)))
+	tracker.check(context.TODO(), now)
+	require.Len(t, notifier.sent, 1)
+	require.Contains(t, notifier.sent[0].Body, "finish updating Kolide and finish installing updates")
+
+	status, err := LoadStatus(tracker.statusStore)
+	require.NoError(t, err)
+	require.Equal(t, StateNotified, status.State)
+	require.Len(t, status.Requirements, 2)
+
+	// A reminder that can't be shown doesn't use a snooze
+	notifier.err = errors.New("no desktop processes")
+	now = now.Add(defaultSnoozeInterval)
+	tracker.check(context.TODO(), now)
+	status, err = LoadStatus(tracker.statusStore)
+	require.NoError(t, err)
+	require.Zero(t, status.SnoozesUsed)
+
+	// After a restart, the server requirement is satisfied
+	notifier.err = nil
+	bootTime = 2000
+	detected = nil
+	tracker.check(context.TODO(), now)
+	status, err = LoadStatus(tracker.statusStore)
+	require.NoError(t, err)
+	require.Equal(t, StateNone, status.State)
+	require.Equal(t, StateNone, messenger.messages[len(messenger.messages)-1].State)
+}
+
+func TestTrackerUpdate_InvalidPolicy(t *testing.T) {
+	t.Parallel()
+
+	detected := []Requirement{}
+	bootTime := uint64(1000)
+	tracker := testTracker(&mockNotifier{}, &mockMessenger{}, &detected, &bootTime)
+
+	require.Error(t, tracker.Update(strings.NewReader(`not json`)))
+	require.Error(t, tracker.Update(strings.NewReader("")))
+}
+
+func TestParseRebootRequiredPkgs(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"linux-image-6.8.0-45-generic", "libc6"}, parseRebootRequiredPkgs("linux-image-6.8.0-45-generic\nlibc6\n\nlibc6\n"))
+	require.Empty(t, parseRebootRequiredPkgs(""))
+}
+
+func TestParseSoftwareUpdateRestarts(t *testing.T) {
+	t.Parallel()
+
+	output := `Software Update Tool
+
+Finding available software
+Software Update found the following new or updated software:
+* Label: macOS Sonoma 14.5-23F79
+	Title: macOS Sonoma 14.5, Version: 14.5, Size: 3717349K, Recommended: YES, Action: restart,
+* Label: Safari17.5SonomaAuto-17.5
+	Title: Safari, Version: 17.5, Size: 166292K, Recommended: YES,
+`
+	require.Equal(t, []string{"macOS Sonoma 14.5"}, parseSoftwareUpdateRestarts(output))
+	require.Empty(t, parseSoftwareUpdateRestarts("No new software available.\n"))
+}
+
+func TestParseFdesetupDeferred(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, parseFdesetupDeferred("FileVault is Off.\nDeferred enablement appears to be active for user 'alice'.\n"))
+	require.False(t, parseFdesetupDeferred("FileVault is On.\n"))
+}
@@ -0,0 +1,315 @@
+package restartrequired
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/desktop/user/notify"
+	"github.com/kolide/launcher/ee/maintenancewindow"
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+const (
+	// Subsystem is the control server subsystem the reminder policy, and any restart
+	// requirements known to the server, are sent with
+	Subsystem = "restart_required"
+
+	// StatusMethod is the control server message method status changes are sent with
+	StatusMethod = "restart_required_status"
+
+	// policyKey is the key in the control store for the last policy received
+	policyKey = "restart_required_policy"
+
+	// initialDelay gives the control service time to authenticate, and desktop time to start,
+	// before we first check
+	initialDelay = 1 * time.Minute
+
+	checkInterval = 10 * time.Minute
+)
+
+type messenger interface {
+	SendMessage(method string, params interface{}) error
+}
+
+// The desktop runner fulfills this interface
+type notifier interface {
+	SendNotification(n notify.Notification) error
+}
+
+// Tracker periodically checks whether this device needs to restart, reminds the user according
+// to the control server's policy, and reports changes to the control server. It's a control
+// consumer for the policy, and a run group actor.
+type Tracker struct {
+	slogger       *slog.Logger
+	statusStore   types.GetterSetter
+	policyStore   types.GetterSetter
+	notifier      notifier
+	messenger     messenger
+	detect        func(context.Context, *slog.Logger) []Requirement
+	bootTime      func() (uint64, error)
+	permits       func(ctx context.Context, pendingSince, now time.Time) bool
+	lock          sync.Mutex
+	lastReported  string
+	checkRequests chan struct{}
+	interrupt     chan struct{}
+	interrupted   atomic.Bool
+}
+
+func New(k types.Knapsack, notifier notifier, messenger messenger) *Tracker {
+	return &Tracker{
+		slogger:     k.Slogger().With("component", "restart_required"),
+		statusStore: k.PersistentHostDataStore(),
+		policyStore: k.ControlStore(),
+		notifier:    notifier,
+		messenger:   messenger,
+		detect:      detectRequirements,
+		bootTime:    host.BootTime,
+		permits: func(ctx context.Context, pendingSince, now time.Time) bool {
+			return maintenancewindow.Permits(ctx, k, pendingSince, now)
+		},
+		checkRequests: make(chan struct{}, 1),
+		interrupt:     make(chan struct{}, 1),
+	}
+}
+
+func (t *Tracker) Execute() error {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	initial := time.After(initialDelay)
+	for {
+		select {
+		case <-initial:
+		case <-ticker.C:
+		case <-t.checkRequests:
+		case <-t.interrupt:
+			t.slogger.Log(context.TODO(), slog.LevelDebug,
+				"received external interrupt, stopping",
+			)
+			return nil
+		}
+
+		t.check(context.TODO(), time.Now())
+	}
+}
+
+func (t *Tracker) Interrupt(_ error) {
+	// Only perform shutdown tasks on first call to interrupt -- no need to repeat on potential extra calls.
+	if t.interrupted.Load() {
+		return
+	}
+	t.interrupted.Store(true)
+
+	t.interrupt <- struct{}{}
+}
+
+// Ping requests a check, without waiting for the next interval.
+func (t *Tracker) Ping() {
+	select {
+	case t.checkRequests <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+}
+
+// Update satisfies the control.consumer interface. It receives the reminder policy, and any
+// restarts the control server knows are needed.
+func (t *Tracker) Update(data io.Reader) error {
+	var policy Policy
+	if err := json.NewDecoder(data).Decode(&policy); err != nil {
+		return fmt.Errorf("decoding restart required policy: %w", err)
+	}
+
+	// Server requirements hold until the next restart, so note when they arrived
+	bootTime, err := t.bootTime()
+	if err != nil {
+		t.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not get boot time, server restart requirements will not clear until the server removes them",
+			"err", err,
+		)
+	}
+	for i := range policy.Requirements {
+		policy.Requirements[i].FromServer = true
+		policy.Requirements[i].BootTime = bootTime
+	}
+
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("marshalling restart required policy: %w", err)
+	}
+
+	t.lock.Lock()
+	err = t.policyStore.Set([]byte(policyKey), raw)
+	t.lock.Unlock()
+	if err != nil {
+		return fmt.Errorf("saving restart required policy: %w", err)
+	}
+
+	t.Ping()
+	return nil
+}
+
+func (t *Tracker) loadPolicy() Policy {
+	var policy Policy
+
+	raw, err := t.policyStore.Get([]byte(policyKey))
+	if err != nil || len(raw) == 0 {
+		return policy
+	}
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		t.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not unmarshal restart required policy, using defaults",
+			"err", err,
+		)
+		return Policy{}
+	}
+
+	return policy
+}
+
+// check updates the status from the restarts currently required, reminds the user if a
+// reminder is due, and reports the status if it changed.
+func (t *Tracker) check(ctx context.Context, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	policy := t.loadPolicy()
+
+	prev, err := LoadStatus(t.statusStore)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not load restart required status, starting over",
+			"err", err,
+		)
+		prev = Status{State: StateNone}
+	}
+
+	requirements := t.detect(ctx, t.slogger)
+	requirements = append(requirements, t.serverRequirements(ctx, policy)...)
+
+	status := nextStatus(prev, requirements, policy, now)
+	if policy.NotificationsEnabled && status.notificationDue(now) && t.permits(ctx, status.NextNotificationAt, now) {
+		reminded := status.notified(policy, now)
+		if err := t.notifier.SendNotification(reminderFor(reminded, policy, now)); err != nil {
+			// Try again at the next check; the reminder doesn't count until the user could see it
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not send restart reminder",
+				"err", err,
+			)
+		} else {
+			status = reminded
+		}
+	}
+
+	if err := saveStatus(t.statusStore, status); err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not save restart required status",
+			"err", err,
+		)
+	}
+
+	if status.reportable() == t.lastReported {
+		return
+	}
+	if err := t.messenger.SendMessage(StatusMethod, status); err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not report restart required status, will retry",
+			"err", err,
+		)
+		return
+	}
+	t.lastReported = status.reportable()
+}
+
+// serverRequirements returns the requirements sent by the control server that haven't been
+// satisfied by a restart since.
+func (t *Tracker) serverRequirements(ctx context.Context, policy Policy) []Requirement {
+	bootTime, err := t.bootTime()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"could not get boot time",
+			"err", err,
+		)
+		return policy.Requirements
+	}
+
+	requirements := make([]Requirement, 0, len(policy.Requirements))
+	for _, r := range policy.Requirements {
+		if r.BootTime != 0 && r.BootTime != bootTime {
+			continue
+		}
+		requirements = append(requirements, r)
+	}
+	return requirements
+}
+
+// reminderFor builds the reminder for status, which already reflects the reminder being sent.
+func reminderFor(status Status, policy Policy, now time.Time) notify.Notification {
+	body := fmt.Sprintf("Your device needs to restart %s. Please save your work and restart when you can.", describeSources(status.Requirements))
+	if status.State == StateOverdue {
+		body = fmt.Sprintf("Your device has needed to restart %s since %s. Please save your work and restart now.",
+			describeSources(status.Requirements), earliestDetection(status.Requirements).Format("January 2"))
+	} else if remaining := status.MaxSnoozes - status.SnoozesUsed; status.SnoozesUsed > 0 && remaining > 0 {
+		body += fmt.Sprintf(" You can postpone this %d more time(s).", remaining)
+	}
+
+	return notify.Notification{
+		Title:     "Restart required",
+		Body:      body,
+		ActionUri: policy.ActionUri,
+		ID:        fmt.Sprintf("%s_%d", Subsystem, now.Unix()),
+		// Don't let a reminder that couldn't be shown pile up behind the next one
+		ValidUntil: status.NextNotificationAt.Unix(),
+	}
+}
+
+// describeSources describes why the device needs to restart, e.g. "to finish installing
+// updates and enable encryption".
+func describeSources(requirements []Requirement) string {
+	seen := make(map[string]bool)
+	var reasons []string
+	for _, r := range requirements {
+		if seen[r.Source] {
+			continue
+		}
+		seen[r.Source] = true
+
+		switch r.Source {
+		case SourceOsUpdate:
+			reasons = append(reasons, "finish installing updates")
+		case SourceAgentUpdate:
+			reasons = append(reasons, "finish updating Kolide")
+		case SourceEncryption:
+			reasons = append(reasons, "finish enabling disk encryption")
+		default:
+			reasons = append(reasons, "apply changes")
+		}
+	}
+
+	switch len(reasons) {
+	case 0:
+		return "to apply changes"
+	case 1:
+		return "to " + reasons[0]
+	default:
+		return "to " + strings.Join(reasons[:len(reasons)-1], ", ") + " and " + reasons[len(reasons)-1]
+	}
+}
+
+func earliestDetection(requirements []Requirement) time.Time {
+	var earliest time.Time
+	for _, r := range requirements {
+		if earliest.IsZero() || r.DetectedAt.Before(earliest) {
+			earliest = r.DetectedAt
+		}
+	}
+	return earliest
+}
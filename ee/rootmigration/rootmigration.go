@@ -0,0 +1,440 @@
+// Package rootmigration moves a launcher installation to a new root directory and identifier:
+// the root directory, holding launcher.db, osquery's database, and the TUF library; the
+// installation directory holding launcher's configuration and binaries; and the service that
+// runs launcher. This lets an installation be rebranded without re-enrolling.
+//
+// Everything is copied before anything is switched over, and the old root directory is kept
+// as a backup, so a migration that fails part way leaves the original installation in place.
+package rootmigration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	agentbbolt "github.com/kolide/launcher/ee/agent/storage/bbolt"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	// backupSuffix is appended to the old root directory once the migration succeeds
+	backupSuffix = ".pre-migration"
+
+	dbLockTimeout = 2 * time.Second
+)
+
+var (
+	identifierRegexp = regexp.MustCompile(`^[a-zA-Z0-9][-a-zA-Z0-9]*$`)
+
+	// ErrLauncherRunning is returned when launcher still holds its database open
+	ErrLauncherRunning = errors.New("launcher is running")
+)
+
+// Migration describes moving an installation from one root directory and identifier to another.
+type Migration struct {
+	FromRootDirectory string
+	ToRootDirectory   string
+	FromIdentifier    string
+	ToIdentifier      string
+	ConfigFilePath    string // the installation's launcher.flags
+}
+
+// Validate checks that the migration is possible.
+func (m Migration) Validate() error {
+	if !identifierRegexp.MatchString(m.ToIdentifier) {
+		return fmt.Errorf("invalid identifier %q", m.ToIdentifier)
+	}
+	if m.FromRootDirectory == "" || m.ConfigFilePath == "" {
+		return errors.New("current root directory and config file are required")
+	}
+	if !filepath.IsAbs(m.ToRootDirectory) {
+		return fmt.Errorf("root directory %q is not an absolute path", m.ToRootDirectory)
+	}
+
+	from, to := filepath.Clean(m.FromRootDirectory), filepath.Clean(m.ToRootDirectory)
+	if from == to && m.FromIdentifier == m.ToIdentifier {
+		return errors.New("nothing to migrate: root directory and identifier are unchanged")
+	}
+	if from != to && (isWithin(to, from) || isWithin(from, to)) {
+		return fmt.Errorf("root directories %s and %s may not contain one another", from, to)
+	}
+
+	if from != to {
+		entries, err := os.ReadDir(to)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("checking %s: %w", to, err)
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("root directory %s already exists and is not empty", to)
+		}
+	}
+
+	return nil
+}
+
+// Run performs the migration. launcher must not be running: the old service is stopped first,
+// and the new one started once everything has been moved. If the migration fails before the new
+// service is in place, the old service is restarted.
+func Run(ctx context.Context, slogger *slog.Logger, m Migration) (err error) {
+	if err := m.Validate(); err != nil {
+		return fmt.Errorf("validating migration: %w", err)
+	}
+
+	slogger.Log(ctx, slog.LevelInfo,
+		"starting root migration",
+		"from_root_directory", m.FromRootDirectory,
+		"to_root_directory", m.ToRootDirectory,
+		"from_identifier", m.FromIdentifier,
+		"to_identifier", m.ToIdentifier,
+	)
+
+	if err := StopService(ctx, m.FromIdentifier); err != nil {
+		return fmt.Errorf("stopping launcher: %w", err)
+	}
+
+	var created []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, path := range created {
+			if removeErr := os.RemoveAll(path); removeErr != nil {
+				slogger.Log(ctx, slog.LevelWarn,
+					"could not clean up after failed migration",
+					"path", path,
+					"err", removeErr,
+				)
+			}
+		}
+		if startErr := StartService(ctx, m.FromIdentifier); startErr != nil {
+			slogger.Log(ctx, slog.LevelError,
+				"could not restart launcher after failed migration",
+				"err", startErr,
+			)
+		}
+	}()
+
+	if err := EnsureNotInUse(m.FromRootDirectory); err != nil {
+		return err
+	}
+
+	migratesRoot := filepath.Clean(m.FromRootDirectory) != filepath.Clean(m.ToRootDirectory)
+	if migratesRoot {
+		created = append(created, m.ToRootDirectory)
+		if err := CopyRootDirectory(m.FromRootDirectory, m.ToRootDirectory); err != nil {
+			return fmt.Errorf("copying root directory: %w", err)
+		}
+	}
+
+	configFilePath := m.ConfigFilePath
+	if m.FromIdentifier != m.ToIdentifier {
+		newInstallDirs, err := copyInstallDirectories(m.FromIdentifier, m.ToIdentifier)
+		created = append(created, newInstallDirs...)
+		if err != nil {
+			return fmt.Errorf("copying installation: %w", err)
+		}
+		configFilePath = ReplaceIdentifier(configFilePath, m.FromIdentifier, m.ToIdentifier)
+	}
+
+	originalConfig, err := rewriteConfigFileAt(configFilePath, m)
+	if err != nil {
+		return fmt.Errorf("rewriting config file: %w", err)
+	}
+	if configFilePath == m.ConfigFilePath {
+		// The old installation's config was rewritten in place, so put it back if we fail
+		defer func() {
+			if err != nil && created != nil {
+				_ = os.WriteFile(configFilePath, originalConfig, 0644)
+			}
+		}()
+	}
+
+	if err := migrateService(ctx, m); err != nil {
+		return fmt.Errorf("migrating service: %w", err)
+	}
+
+	// The new installation is in place, so failures from here on leave it running
+	created = nil
+
+	if err := StartService(ctx, m.ToIdentifier); err != nil {
+		return fmt.Errorf("starting migrated launcher: %w", err)
+	}
+
+	if migratesRoot {
+		backup := filepath.Clean(m.FromRootDirectory) + backupSuffix
+		if err := os.Rename(m.FromRootDirectory, backup); err != nil {
+			slogger.Log(ctx, slog.LevelWarn,
+				"could not move old root directory aside",
+				"err", err,
+			)
+		}
+	}
+
+	slogger.Log(ctx, slog.LevelInfo,
+		"completed root migration",
+		"to_root_directory", m.ToRootDirectory,
+		"to_identifier", m.ToIdentifier,
+	)
+
+	return nil
+}
+
+// StartDetached starts the migration in a separate launcher process, running the migrate-root
+// subcommand, since the migration stops the launcher that requests it.
+func StartDetached(ctx context.Context, m Migration) error {
+	if err := m.Validate(); err != nil {
+		return fmt.Errorf("validating migration: %w", err)
+	}
+
+	launcherPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("getting path to launcher: %w", err)
+	}
+
+	cmd, err := detachedCommand(ctx, launcherPath, []string{
+		"migrate-root",
+		"--to", m.ToRootDirectory,
+		"--identifier", m.ToIdentifier,
+		"--config", m.ConfigFilePath,
+	})
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting migrate-root: %w", err)
+	}
+
+	// The migration outlives us, so there's nothing to wait for
+	return cmd.Process.Release()
+}
+
+// EnsureNotInUse checks that no launcher has the database in rootDirectory open.
+func EnsureNotInUse(rootDirectory string) error {
+	dbPath := agentbbolt.LauncherDbLocation(rootDirectory)
+	if _, err := os.Stat(dbPath); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	db, err := bbolt.Open(dbPath, 0600, &bbolt.Options{Timeout: dbLockTimeout})
+	if err != nil {
+		if errors.Is(err, bbolt.ErrTimeout) {
+			return fmt.Errorf("%w: %s is locked", ErrLauncherRunning, dbPath)
+		}
+		return fmt.Errorf("opening %s: %w", dbPath, err)
+	}
+	return db.Close()
+}
+
+// CopyRootDirectory copies the contents of the root directory from to the directory to,
+// skipping files that only make sense for a running launcher, such as pidfiles and sockets.
+func CopyRootDirectory(from, to string) error {
+	return copyTree(from, to, func(path string, d fs.DirEntry) bool {
+		return d.Type()&fs.ModeSocket == 0 && !isRuntimeFile(d.Name())
+	})
+}
+
+// isRuntimeFile reports whether a file in the root directory belongs to a running launcher
+// or osquery, rather than holding state.
+func isRuntimeFile(name string) bool {
+	return strings.HasSuffix(name, ".pid") ||
+		strings.HasSuffix(name, ".sock") ||
+		strings.Contains(name, ".sock.") ||
+		strings.HasSuffix(name, ".lock")
+}
+
+// copyTree copies the directory from, and everything under it that include accepts, to to.
+// Symlinks are recreated rather than followed.
+func copyTree(from, to string, include func(path string, d fs.DirEntry) bool) error {
+	return filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+
+		if rel != "." && !include(path, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Devices, pipes, and the like aren't state
+			return nil
+		}
+	})
+}
+
+func copyFile(from, to string, perm fs.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("copying %s: %w", from, err)
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// ReplaceIdentifier replaces whole occurrences of the identifier from in s with to -- so that
+// kolide-k2 is replaced in /etc/kolide-k2/, Launcher-kolide-k2, and com.kolide-k2.launcher, but
+// not in kolide-k2-beta.
+func ReplaceIdentifier(s, from, to string) string {
+	if from == "" || from == to {
+		return s
+	}
+
+	isAlphanumeric := func(b byte) bool {
+		return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+	}
+
+	var out strings.Builder
+	for {
+		i := strings.Index(s, from)
+		if i < 0 {
+			out.WriteString(s)
+			return out.String()
+		}
+
+		end := i + len(from)
+		// Identifiers are embedded after a dash, as in Launcher-kolide-k2, but may themselves
+		// be extended with one
+		whole := (i == 0 || !isAlphanumeric(s[i-1])) && (end == len(s) || (!isAlphanumeric(s[end]) && s[end] != '-'))
+
+		out.WriteString(s[:i])
+		if whole {
+			out.WriteString(to)
+		} else {
+			out.WriteString(from)
+		}
+		s = s[end:]
+	}
+}
+
+// RewriteConfig rewrites the contents of a launcher.flags file for the migration: the root
+// directory and identifier are updated, and any paths within the old installation are
+// pointed at the new one.
+func RewriteConfig(config []byte, m Migration) []byte {
+	var out bytes.Buffer
+	wroteIdentifier := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(config))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		name, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch name {
+		case "root_directory":
+			line = "root_directory " + m.ToRootDirectory
+		case "identifier":
+			line = "identifier " + m.ToIdentifier
+			wroteIdentifier = true
+		default:
+			if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+				line = strings.ReplaceAll(line, m.FromRootDirectory, m.ToRootDirectory)
+				line = ReplaceIdentifier(line, m.FromIdentifier, m.ToIdentifier)
+			}
+		}
+
+		out.WriteString(line)
+		out.WriteString("\n")
+	}
+
+	if !wroteIdentifier && m.FromIdentifier != m.ToIdentifier {
+		out.WriteString("identifier " + m.ToIdentifier + "\n")
+	}
+
+	return out.Bytes()
+}
+
+// rewriteConfigFileAt rewrites the config file at path for the migration, returning its
+// original contents.
+func rewriteConfigFileAt(path string, m Migration) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the new config alongside, then swap it in, so a failure doesn't leave it truncated
+	tmp := path + ".migrating"
+	if err := os.WriteFile(tmp, RewriteConfig(config, m), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	return config, os.Rename(tmp, path)
+}
+
+// copyInstallDirectories copies each of the installation directories for the identifier from,
+// holding launcher's configuration and binaries, to the corresponding directory for the identifier
+// to. It returns the directories it created.
+func copyInstallDirectories(from, to string) ([]string, error) {
+	var created []string
+	for _, dir := range installDirectories(from) {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		newDir := ReplaceIdentifier(dir, from, to)
+		if _, err := os.Stat(newDir); err == nil {
+			return created, fmt.Errorf("%s already exists", newDir)
+		}
+
+		created = append(created, newDir)
+		if err := copyTree(dir, newDir, func(string, fs.DirEntry) bool { return true }); err != nil {
+			return created, fmt.Errorf("copying %s: %w", dir, err)
+		}
+	}
+
+	return created, nil
+}
+
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
@@ -0,0 +1,167 @@
+package rootmigration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agentbbolt "github.com/kolide/launcher/ee/agent/storage/bbolt"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	nonEmpty := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(nonEmpty, "launcher.db"), []byte("db"), 0600))
+	newRoot := filepath.Join(t.TempDir(), "new-root")
+
+	for _, tt := range []struct {
+		name          string
+		m             Migration
+		expectedValid bool
+	}{
+		{
+			name:          "new root directory",
+			m:             Migration{FromRootDirectory: root, ToRootDirectory: newRoot, FromIdentifier: "kolide-k2", ToIdentifier: "kolide-k2", ConfigFilePath: "launcher.flags"},
+			expectedValid: true,
+		},
+		{
+			name:          "new identifier",
+			m:             Migration{FromRootDirectory: root, ToRootDirectory: root, FromIdentifier: "kolide-k2", ToIdentifier: "acme", ConfigFilePath: "launcher.flags"},
+			expectedValid: true,
+		},
+		{
+			name: "nothing changes",
+			m:    Migration{FromRootDirectory: root, ToRootDirectory: root, FromIdentifier: "kolide-k2", ToIdentifier: "kolide-k2", ConfigFilePath: "launcher.flags"},
+		},
+		{
+			name: "invalid identifier",
+			m:    Migration{FromRootDirectory: root, ToRootDirectory: newRoot, FromIdentifier: "kolide-k2", ToIdentifier: "../acme", ConfigFilePath: "launcher.flags"},
+		},
+		{
+			name: "relative root directory",
+			m:    Migration{FromRootDirectory: root, ToRootDirectory: "new-root", FromIdentifier: "kolide-k2", ToIdentifier: "kolide-k2", ConfigFilePath: "launcher.flags"},
+		},
+		{
+			name: "root directory within the old one",
+			m:    Migration{FromRootDirectory: root, ToRootDirectory: filepath.Join(root, "nested"), FromIdentifier: "kolide-k2", ToIdentifier: "kolide-k2", ConfigFilePath: "launcher.flags"},
+		},
+		{
+			name: "root directory not empty",
+			m:    Migration{FromRootDirectory: root, ToRootDirectory: nonEmpty, FromIdentifier: "kolide-k2", ToIdentifier: "kolide-k2", ConfigFilePath: "launcher.flags"},
+		},
+		{
+			name: "no config file",
+			m:    Migration{FromRootDirectory: root, ToRootDirectory: newRoot, FromIdentifier: "kolide-k2", ToIdentifier: "kolide-k2"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.m.Validate()
+			if tt.expectedValid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestReplaceIdentifier(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		in       string
+		expected string
+	}{
+		{in: "/etc/kolide-k2/launcher.flags", expected: "/etc/acme/launcher.flags"},
+		{in: "com.kolide-k2.launcher", expected: "com.acme.launcher"},
+		{in: "launcher.kolide-k2.service", expected: "launcher.acme.service"},
+		{in: `C:\Program Files\Kolide\Launcher-kolide-k2\conf`, expected: `C:\Program Files\Kolide\Launcher-acme\conf`},
+		{in: "kolide-k2", expected: "acme"},
+		{in: "/etc/kolide-k2-beta/launcher.flags", expected: "/etc/kolide-k2-beta/launcher.flags"},
+		{in: "/etc/mykolide-k2/launcher.flags", expected: "/etc/mykolide-k2/launcher.flags"},
+		{in: "kolide-k2 and kolide-k2.service", expected: "acme and acme.service"},
+	} {
+		require.Equal(t, tt.expected, ReplaceIdentifier(tt.in, "kolide-k2", "acme"), tt.in)
+	}
+}
+
+func TestRewriteConfig(t *testing.T) {
+	t.Parallel()
+
+	m := Migration{
+		FromRootDirectory: "/var/kolide-k2/k2device.kolide.com",
+		ToRootDirectory:   "/var/acme/device.acme.example",
+		FromIdentifier:    "kolide-k2",
+		ToIdentifier:      "acme",
+	}
+
+	config := []byte(`# installed by kolide-k2
+root_directory /var/kolide-k2/k2device.kolide.com
+enroll_secret_path /etc/kolide-k2/secret
+osqueryd_path /usr/local/kolide-k2/bin/osqueryd
+hostname k2device.kolide.com
+`)
+
+	expected := `# installed by kolide-k2
+root_directory /var/acme/device.acme.example
+enroll_secret_path /etc/acme/secret
+osqueryd_path /usr/local/acme/bin/osqueryd
+hostname k2device.kolide.com
+identifier acme
+`
+
+	require.Equal(t, expected, string(RewriteConfig(config, m)))
+}
+
+func TestCopyRootDirectory(t *testing.T) {
+	t.Parallel()
+
+	from := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(from, "launcher.db"), []byte("db"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "launcher.pid"), []byte("123"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "osquery.sock"), nil, 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(from, "osquery.db"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "osquery.db", "CURRENT"), []byte("MANIFEST"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(from, "tuf"), 0755))
+
+	to := filepath.Join(t.TempDir(), "new-root")
+	require.NoError(t, CopyRootDirectory(from, to))
+
+	contents, err := os.ReadFile(filepath.Join(to, "launcher.db"))
+	require.NoError(t, err)
+	require.Equal(t, "db", string(contents))
+
+	contents, err = os.ReadFile(filepath.Join(to, "osquery.db", "CURRENT"))
+	require.NoError(t, err)
+	require.Equal(t, "MANIFEST", string(contents))
+
+	require.DirExists(t, filepath.Join(to, "tuf"))
+	require.NoFileExists(t, filepath.Join(to, "launcher.pid"))
+	require.NoFileExists(t, filepath.Join(to, "osquery.sock"))
+}
+
+func TestEnsureNotInUse(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+
+	// No database yet
+	require.NoError(t, EnsureNotInUse(root))
+
+	db, err := bbolt.Open(agentbbolt.LauncherDbLocation(root), 0600, nil)
+	require.NoError(t, err)
+
+	err = EnsureNotInUse(root)
+	require.True(t, errors.Is(err, ErrLauncherRunning), "expected launcher to be detected as running, got %v", err)
+
+	require.NoError(t, db.Close())
+	require.NoError(t, EnsureNotInUse(root))
+}
@@ -0,0 +1,88 @@
+//go:build darwin
+// +build darwin
+
+package rootmigration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+)
+
+const launchDaemonsDir = "/Library/LaunchDaemons"
+
+func installDirectories(identifier string) []string {
+	return []string{
+		filepath.Join("/etc", identifier),
+		filepath.Join("/usr/local", identifier),
+	}
+}
+
+func serviceLabel(identifier string) string {
+	return fmt.Sprintf("com.%s.launcher", identifier)
+}
+
+func plistPath(identifier string) string {
+	return filepath.Join(launchDaemonsDir, serviceLabel(identifier)+".plist")
+}
+
+// StopService stops launcher's service for the given identifier.
+func StopService(ctx context.Context, identifier string) error {
+	return launchctl(ctx, "bootout", "system/"+serviceLabel(identifier))
+}
+
+// StartService starts launcher's service for the given identifier.
+func StartService(ctx context.Context, identifier string) error {
+	return launchctl(ctx, "bootstrap", "system", plistPath(identifier))
+}
+
+// migrateService writes a launch daemon for the new identifier, pointing at the new installation,
+// and disables the old one, so that it isn't loaded again at boot.
+func migrateService(ctx context.Context, m Migration) error {
+	if m.FromIdentifier == m.ToIdentifier {
+		// The launch daemon only refers to the config file, which hasn't moved
+		return nil
+	}
+
+	contents, err := os.ReadFile(plistPath(m.FromIdentifier))
+	if err != nil {
+		return fmt.Errorf("reading launch daemon: %w", err)
+	}
+
+	newPlist := plistPath(m.ToIdentifier)
+	rewritten := ReplaceIdentifier(string(contents), m.FromIdentifier, m.ToIdentifier)
+	if err := os.WriteFile(newPlist, []byte(rewritten), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", newPlist, err)
+	}
+
+	if err := launchctl(ctx, "disable", "system/"+serviceLabel(m.FromIdentifier)); err != nil {
+		os.Remove(newPlist)
+		return err
+	}
+
+	return nil
+}
+
+func launchctl(ctx context.Context, args ...string) error {
+	cmd, err := allowedcmd.Launchctl(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("creating launchctl cmd: %w", err)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("running launchctl %v: %w: %s", args, err, out)
+	}
+	return nil
+}
+
+// detachedCommand runs launcher's migrate-root subcommand in its own session, so that it
+// outlives launcher's launch daemon being booted out.
+func detachedCommand(ctx context.Context, launcherPath string, args []string) (*allowedcmd.TracedCmd, error) {
+	cmd := exec.Command(launcherPath, args...) //nolint:forbidigo // It is safe to exec the current running executable, and it must outlive ctx
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	return &allowedcmd.TracedCmd{Ctx: ctx, Cmd: cmd}, nil
+}
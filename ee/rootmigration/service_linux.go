@@ -0,0 +1,110 @@
+//go:build linux
+// +build linux
+
+package rootmigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+)
+
+// systemdUnitDirs are where packages install launcher's unit, in the order systemd prefers them
+var systemdUnitDirs = []string{"/etc/systemd/system", "/usr/lib/systemd/system", "/lib/systemd/system"}
+
+func installDirectories(identifier string) []string {
+	return []string{
+		filepath.Join("/etc", identifier),
+		filepath.Join("/usr/local", identifier),
+	}
+}
+
+func serviceName(identifier string) string {
+	return fmt.Sprintf("launcher.%s.service", identifier)
+}
+
+// StopService stops launcher's service for the given identifier.
+func StopService(ctx context.Context, identifier string) error {
+	return systemctl(ctx, "stop", serviceName(identifier))
+}
+
+// StartService starts launcher's service for the given identifier.
+func StartService(ctx context.Context, identifier string) error {
+	return systemctl(ctx, "start", serviceName(identifier))
+}
+
+// migrateService writes a unit for the new identifier, pointing at the new installation, and
+// enables it in place of the old one. The unit is written to /etc/systemd/system, since the
+// package manager owns the old one.
+func migrateService(ctx context.Context, m Migration) error {
+	if m.FromIdentifier == m.ToIdentifier {
+		// The unit only refers to the config file, which hasn't moved
+		return nil
+	}
+
+	oldUnit, err := findUnit(m.FromIdentifier)
+	if err != nil {
+		return err
+	}
+	contents, err := os.ReadFile(oldUnit)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", oldUnit, err)
+	}
+
+	newUnit := filepath.Join(systemdUnitDirs[0], serviceName(m.ToIdentifier))
+	rewritten := ReplaceIdentifier(string(contents), m.FromIdentifier, m.ToIdentifier)
+	if err := os.WriteFile(newUnit, []byte(rewritten), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", newUnit, err)
+	}
+
+	if err := systemctl(ctx, "daemon-reload"); err != nil {
+		os.Remove(newUnit)
+		return err
+	}
+	if err := systemctl(ctx, "enable", serviceName(m.ToIdentifier)); err != nil {
+		os.Remove(newUnit)
+		return err
+	}
+
+	// The new service is enabled, so the old one must not start again alongside it
+	return systemctl(ctx, "disable", serviceName(m.FromIdentifier))
+}
+
+func findUnit(identifier string) (string, error) {
+	for _, dir := range systemdUnitDirs {
+		path := filepath.Join(dir, serviceName(identifier))
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("checking for %s: %w", path, err)
+		}
+	}
+	return "", fmt.Errorf("no systemd unit found for %s", serviceName(identifier))
+}
+
+func systemctl(ctx context.Context, args ...string) error {
+	cmd, err := allowedcmd.Systemctl(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("creating systemctl cmd: %w", err)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("running systemctl %v: %w: %s", args, err, out)
+	}
+	return nil
+}
+
+// detachedCommand runs launcher's migrate-root subcommand in its own transient unit, since
+// stopping launcher's service stops every process in it.
+func detachedCommand(ctx context.Context, launcherPath string, args []string) (*allowedcmd.TracedCmd, error) {
+	unitArgs := append([]string{"--collect", "--unit", "launcher-root-migration", launcherPath}, args...)
+	cmd, err := allowedcmd.SystemdRun(ctx, unitArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("creating systemd-run cmd: %w", err)
+	}
+	return cmd, nil
+}
@@ -0,0 +1,147 @@
+//go:build windows
+// +build windows
+
+package rootmigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/pkg/launcher"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const serviceStopTimeout = 30 * time.Second
+
+func installDirectories(identifier string) []string {
+	return []string{
+		fmt.Sprintf(`C:\Program Files\Kolide\Launcher-%s`, identifier),
+	}
+}
+
+// StopService stops launcher's service for the given identifier.
+func StopService(ctx context.Context, identifier string) error {
+	return withService(identifier, func(s *mgr.Service) error {
+		status, err := s.Query()
+		if err != nil {
+			return fmt.Errorf("querying service: %w", err)
+		}
+		if status.State == svc.Stopped {
+			return nil
+		}
+		if status.State != svc.StopPending {
+			if _, err := s.Control(svc.Stop); err != nil {
+				return fmt.Errorf("stopping service: %w", err)
+			}
+		}
+
+		deadline := time.Now().Add(serviceStopTimeout)
+		for time.Now().Before(deadline) {
+			if status, err := s.Query(); err == nil && status.State == svc.Stopped {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return errors.New("timed out waiting for service to stop")
+	})
+}
+
+// StartService starts launcher's service for the given identifier.
+func StartService(_ context.Context, identifier string) error {
+	return withService(identifier, func(s *mgr.Service) error {
+		return s.Start()
+	})
+}
+
+// migrateService creates a service for the new identifier, configured like the old one but
+// pointing at the new installation, and disables the old one. The old service is kept, so
+// that uninstalling the old package still finds it.
+func migrateService(_ context.Context, m Migration) error {
+	if m.FromIdentifier == m.ToIdentifier {
+		// The service only refers to the config file, which hasn't moved
+		return nil
+	}
+
+	sman, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to service manager: %w", err)
+	}
+	defer sman.Disconnect()
+
+	oldService, err := sman.OpenService(launcher.ServiceName(m.FromIdentifier))
+	if err != nil {
+		return fmt.Errorf("opening service: %w", err)
+	}
+	defer oldService.Close()
+
+	oldConfig, err := oldService.Config()
+	if err != nil {
+		return fmt.Errorf("reading service config: %w", err)
+	}
+
+	newConfig := oldConfig
+	newConfig.DisplayName = ReplaceIdentifier(oldConfig.DisplayName, m.FromIdentifier, m.ToIdentifier)
+	newConfig.BinaryPathName = ReplaceIdentifier(oldConfig.BinaryPathName, m.FromIdentifier, m.ToIdentifier)
+
+	// CreateService builds the command line from an executable and arguments, so set the
+	// rewritten command line afterwards
+	newService, err := sman.CreateService(launcher.ServiceName(m.ToIdentifier), newConfig.BinaryPathName, newConfig)
+	if err != nil {
+		return fmt.Errorf("creating service: %w", err)
+	}
+	defer newService.Close()
+
+	if err := newService.UpdateConfig(newConfig); err != nil {
+		newService.Delete()
+		return fmt.Errorf("configuring service: %w", err)
+	}
+
+	if actions, err := oldService.RecoveryActions(); err == nil && len(actions) > 0 {
+		resetPeriod, _ := oldService.ResetPeriod()
+		_ = newService.SetRecoveryActions(actions, resetPeriod)
+	}
+
+	oldConfig.StartType = mgr.StartDisabled
+	if err := oldService.UpdateConfig(oldConfig); err != nil {
+		newService.Delete()
+		return fmt.Errorf("disabling old service: %w", err)
+	}
+
+	return nil
+}
+
+func withService(identifier string, f func(*mgr.Service) error) error {
+	sman, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to service manager: %w", err)
+	}
+	defer sman.Disconnect()
+
+	s, err := sman.OpenService(launcher.ServiceName(identifier))
+	if err != nil {
+		return fmt.Errorf("opening service: %w", err)
+	}
+	defer s.Close()
+
+	return f(s)
+}
+
+// detachedCommand runs launcher's migrate-root subcommand detached from launcher, so that it
+// outlives launcher's service being stopped.
+func detachedCommand(ctx context.Context, launcherPath string, args []string) (*allowedcmd.TracedCmd, error) {
+	cmd := exec.Command(launcherPath, args...) //nolint:forbidigo // It is safe to exec the current running executable, and it must outlive ctx
+	cmd.SysProcAttr = &windows.SysProcAttr{
+		CreationFlags: windows.DETACHED_PROCESS | windows.CREATE_NEW_PROCESS_GROUP,
+	}
+	return &allowedcmd.TracedCmd{Ctx: ctx, Cmd: cmd}, nil
+}
@@ -1,4 +1,4 @@
-package localserver
+package serverkeys
 
 // These are the hardcoded certificates
 const (
@@ -0,0 +1,44 @@
+// Package serverkeys holds the public keys of Kolide's servers, which launcher uses to verify
+// data that came from Kolide regardless of how it got to us.
+package serverkeys
+
+import "strings"
+
+const (
+	EnvironmentProduction = "production"
+	EnvironmentReview     = "review"
+	EnvironmentDeveloper  = "developer"
+)
+
+// Certs are the PEM-encoded public keys for a Kolide server environment.
+type Certs struct {
+	Environment string
+	RsaPem      string
+	EccPem      string
+}
+
+// ForServer returns the keys for the environment the given Kolide server belongs to:
+// developer certificates for local and tunnelled servers, review app certificates for
+// review apps, and production certificates otherwise.
+func ForServer(kolideServer string) Certs {
+	switch {
+	case strings.HasPrefix(kolideServer, "localhost"), strings.HasPrefix(kolideServer, "127.0.0.1"), strings.Contains(kolideServer, ".ngrok."):
+		return Certs{
+			Environment: EnvironmentDeveloper,
+			RsaPem:      localhostRsaServerCert,
+			EccPem:      localhostEccServerCert,
+		}
+	case strings.HasSuffix(kolideServer, ".herokuapp.com"):
+		return Certs{
+			Environment: EnvironmentReview,
+			RsaPem:      reviewRsaServerCert,
+			EccPem:      reviewEccServerCert,
+		}
+	default:
+		return Certs{
+			Environment: EnvironmentProduction,
+			RsaPem:      k2RsaServerCert,
+			EccPem:      k2EccServerCert,
+		}
+	}
+}
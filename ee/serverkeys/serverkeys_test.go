@@ -0,0 +1,35 @@
+package serverkeys
+
+import (
+	"testing"
+
+	"github.com/kolide/krypto/pkg/echelper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForServer(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		kolideServer string
+		environment  string
+	}{
+		{kolideServer: "k.kolide.com", environment: EnvironmentProduction},
+		{kolideServer: "k.kolide.com:443", environment: EnvironmentProduction},
+		{kolideServer: "localhost:3443", environment: EnvironmentDeveloper},
+		{kolideServer: "127.0.0.1:8080", environment: EnvironmentDeveloper},
+		{kolideServer: "abc123.ngrok.io", environment: EnvironmentDeveloper},
+		{kolideServer: "kolide-pr-1234.herokuapp.com", environment: EnvironmentReview},
+	} {
+		tt := tt
+		t.Run(tt.kolideServer, func(t *testing.T) {
+			t.Parallel()
+
+			certs := ForServer(tt.kolideServer)
+			require.Equal(t, tt.environment, certs.Environment)
+
+			_, err := echelper.PublicPemToEcdsaKey([]byte(certs.EccPem))
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package supervisor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/pkg/backoff"
+)
+
+type durationCounter interface {
+	Next() time.Duration
+	Reset()
+}
+
+// Backoff tracks how long to wait before restarting a process that keeps exiting. A process
+// that ran for a while before exiting is restarted immediately; one that keeps exiting soon
+// after starting is restarted after a delay that doubles with each consecutive restart.
+type Backoff struct {
+	lock        sync.Mutex
+	delays      durationCounter
+	stableAfter time.Duration
+	notBefore   time.Time
+	restarts    int
+}
+
+// NewBackoff returns a Backoff whose delays start at minDelay and double up to maxDelay. A
+// process that runs for stableAfter is considered stable.
+func NewBackoff(minDelay, maxDelay, stableAfter time.Duration) *Backoff {
+	return &Backoff{
+		delays:      backoff.NewExponentialDurationCounter(minDelay, maxDelay),
+		stableAfter: stableAfter,
+	}
+}
+
+// Exited records that the process exited after running for uptime, and returns how long to
+// wait before restarting it.
+func (b *Backoff) Exited(uptime time.Duration) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.restarts++
+
+	if uptime >= b.stableAfter {
+		b.delays.Reset()
+		b.notBefore = time.Now()
+		return 0
+	}
+
+	delay := b.delays.Next()
+	b.notBefore = time.Now().Add(delay)
+	return delay
+}
+
+// Ready reports whether the process may be restarted now.
+func (b *Backoff) Ready() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return !time.Now().Before(b.notBefore)
+}
+
+// NextStart returns the earliest time the process may be restarted.
+func (b *Backoff) NextStart() time.Time {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.notBefore
+}
+
+// Restarts returns how many times the process has exited and been scheduled for restart.
+func (b *Backoff) Restarts() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.restarts
+}
@@ -0,0 +1,30 @@
+package supervisor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	b := NewBackoff(time.Second, 5*time.Second, time.Minute)
+	require.True(t, b.Ready())
+
+	// Quick exits back off, doubling up to the max
+	require.Equal(t, time.Second, b.Exited(time.Second))
+	require.False(t, b.Ready())
+	require.Equal(t, 2*time.Second, b.Exited(time.Second))
+	require.Equal(t, 4*time.Second, b.Exited(time.Second))
+	require.Equal(t, 5*time.Second, b.Exited(time.Second))
+	require.Equal(t, 5*time.Second, b.Exited(time.Second))
+
+	// A process that stayed up is restarted immediately, and the delays start over
+	require.Equal(t, time.Duration(0), b.Exited(time.Hour))
+	require.True(t, b.Ready())
+	require.Equal(t, time.Second, b.Exited(time.Second))
+
+	require.Equal(t, 7, b.Restarts())
+}
@@ -0,0 +1,270 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/ee/gowrapper"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// supervisedProcess runs a single Spec, restarting it as needed.
+type supervisedProcess struct {
+	slogger *slog.Logger
+	spec    Spec
+	backoff *Backoff
+
+	lock sync.Mutex
+	st   Status
+}
+
+func newSupervisedProcess(slogger *slog.Logger, spec Spec) *supervisedProcess {
+	return &supervisedProcess{
+		slogger: slogger.With("process", spec.Name),
+		spec:    spec,
+		backoff: NewBackoff(spec.MinRestartDelay, spec.MaxRestartDelay, spec.StableAfter),
+		st: Status{
+			Name:  spec.Name,
+			State: StateStarting,
+		},
+	}
+}
+
+func (p *supervisedProcess) status() Status {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.st
+}
+
+func (p *supervisedProcess) updateStatus(update func(*Status)) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	update(&p.st)
+}
+
+// run starts the process, and restarts it according to its restart policy, until ctx is done.
+func (p *supervisedProcess) run(ctx context.Context) {
+	for {
+		startTime := time.Now()
+		exitErr := p.runOnce(ctx)
+		uptime := time.Since(startTime)
+
+		if ctx.Err() != nil {
+			p.updateStatus(func(st *Status) {
+				st.State = StateStopped
+				st.Pid = 0
+			})
+			return
+		}
+
+		p.slogger.Log(ctx, slog.LevelInfo,
+			"supervised process exited",
+			"uptime", uptime.String(),
+			"err", exitErr,
+		)
+
+		if p.spec.RestartPolicy == RestartNever || (p.spec.RestartPolicy == RestartOnFailure && exitErr == nil) {
+			p.updateStatus(func(st *Status) {
+				st.State = StateExited
+				st.Pid = 0
+			})
+			return
+		}
+
+		delay := p.backoff.Exited(uptime)
+		p.updateStatus(func(st *Status) {
+			st.State = StateBackingOff
+			st.Pid = 0
+			st.NextStart = time.Now().Add(delay)
+		})
+
+		select {
+		case <-ctx.Done():
+			p.updateStatus(func(st *Status) { st.State = StateStopped })
+			return
+		case <-time.After(delay):
+		}
+
+		p.updateStatus(func(st *Status) {
+			st.Restarts++
+			st.State = StateStarting
+			st.NextStart = time.Time{}
+		})
+	}
+}
+
+// runOnce starts the process, and supervises it until it exits, it must be restarted, or
+// ctx is done. It returns why the process stopped.
+func (p *supervisedProcess) runOnce(ctx context.Context) (err error) {
+	defer func() {
+		p.updateStatus(func(st *Status) {
+			st.LastExitTime = time.Now()
+			st.LastExit = "exited successfully"
+			if err != nil {
+				st.LastExit = err.Error()
+			}
+		})
+	}()
+
+	cmd, err := p.spec.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("starting: %w", err)
+	}
+	if cmd.Process == nil {
+		return errors.New("starting: process was not started")
+	}
+
+	p.updateStatus(func(st *Status) {
+		st.State = StateRunning
+		st.Pid = cmd.Process.Pid
+		st.StartTime = time.Now()
+		st.HealthCheckError = ""
+		st.RssBytes = 0
+		st.CpuPercent = 0
+	})
+	p.slogger.Log(ctx, slog.LevelInfo,
+		"started supervised process",
+		"pid", cmd.Process.Pid,
+	)
+
+	// Wait on the process, so that it doesn't linger as a zombie once it exits
+	exited := make(chan error, 1)
+	gowrapper.Go(ctx, p.slogger, func() {
+		exited <- cmd.Wait()
+	})
+
+	// Resource usage is measured with the same handle throughout, so that CPU usage
+	// is measured across each check interval
+	proc, _ := process.NewProcessWithContext(ctx, int32(cmd.Process.Pid))
+
+	checkTicker := time.NewTicker(p.spec.CheckInterval)
+	defer checkTicker.Stop()
+
+	healthCheckFailures := 0
+	for {
+		select {
+		case err := <-exited:
+			return err
+		case <-ctx.Done():
+			p.stop(cmd, exited)
+			return ctx.Err()
+		case <-checkTicker.C:
+		}
+
+		if err := p.checkLimits(ctx, proc); err != nil {
+			p.slogger.Log(ctx, slog.LevelWarn,
+				"supervised process exceeded its resource limits, restarting",
+				"pid", cmd.Process.Pid,
+				"err", err,
+			)
+			p.stop(cmd, exited)
+			return err
+		}
+
+		if p.spec.HealthCheck == nil {
+			continue
+		}
+
+		healthCheckErr := p.healthCheck(ctx, cmd)
+		p.updateStatus(func(st *Status) {
+			st.LastHealthCheck = time.Now()
+			st.HealthCheckError = ""
+			if healthCheckErr != nil {
+				st.HealthCheckError = healthCheckErr.Error()
+			}
+		})
+		if healthCheckErr == nil {
+			healthCheckFailures = 0
+			continue
+		}
+
+		healthCheckFailures++
+		if healthCheckFailures >= p.spec.HealthCheckFailures {
+			p.slogger.Log(ctx, slog.LevelWarn,
+				"supervised process failed its health checks, restarting",
+				"pid", cmd.Process.Pid,
+				"failures", healthCheckFailures,
+				"err", healthCheckErr,
+			)
+			p.stop(cmd, exited)
+			return fmt.Errorf("failed %d health checks: %w", healthCheckFailures, healthCheckErr)
+		}
+	}
+}
+
+func (p *supervisedProcess) healthCheck(ctx context.Context, cmd *exec.Cmd) error {
+	ctx, cancel := context.WithTimeout(ctx, p.spec.CheckInterval)
+	defer cancel()
+
+	return p.spec.HealthCheck(ctx, cmd)
+}
+
+// checkLimits records the process's resource usage, and returns an error if it exceeds its limits.
+func (p *supervisedProcess) checkLimits(ctx context.Context, proc *process.Process) error {
+	if proc == nil {
+		return nil
+	}
+
+	var rssBytes uint64
+	if memInfo, err := proc.MemoryInfoWithContext(ctx); err == nil {
+		rssBytes = memInfo.RSS
+	}
+	// Percent with no interval reports usage since the previous call
+	cpuPercent, _ := proc.PercentWithContext(ctx, 0)
+
+	p.updateStatus(func(st *Status) {
+		st.RssBytes = rssBytes
+		st.CpuPercent = cpuPercent
+	})
+
+	limits := p.spec.Limits
+	if limits.MaxRssBytes > 0 && rssBytes > limits.MaxRssBytes {
+		return fmt.Errorf("memory usage %d bytes exceeds limit of %d bytes", rssBytes, limits.MaxRssBytes)
+	}
+	if limits.MaxCpuPercent > 0 && cpuPercent > limits.MaxCpuPercent {
+		return fmt.Errorf("cpu usage %.1f%% exceeds limit of %.1f%%", cpuPercent, limits.MaxCpuPercent)
+	}
+
+	return nil
+}
+
+// stop asks the process to stop, killing it if it doesn't, and waits for it to exit.
+func (p *supervisedProcess) stop(cmd *exec.Cmd, exited <-chan error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.spec.StopTimeout)
+	defer cancel()
+
+	var stopErr error
+	switch {
+	case p.spec.Stop != nil:
+		stopErr = p.spec.Stop(ctx, cmd)
+	case runtime.GOOS != "windows":
+		stopErr = cmd.Process.Signal(os.Interrupt)
+	default:
+		// Windows can't interrupt another process
+		stopErr = errors.New("no graceful shutdown available")
+	}
+
+	if stopErr == nil {
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+		}
+	}
+
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		p.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not kill supervised process",
+			"pid", cmd.Process.Pid,
+			"err", err,
+		)
+	}
+	<-exited
+}
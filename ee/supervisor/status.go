@@ -0,0 +1,87 @@
+package supervisor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// State is the lifecycle state of a supervised process.
+type State string
+
+const (
+	StateStarting   State = "starting"
+	StateRunning    State = "running"
+	StateBackingOff State = "backing_off" // exited, and waiting to be restarted
+	StateExited     State = "exited"      // exited, and won't be restarted
+	StateStopped    State = "stopped"     // stopped because launcher is shutting down
+)
+
+// Status describes a supervised process, for the kolide_launcher_processes table.
+type Status struct {
+	Name             string
+	Instance         string // distinguishes processes of the same name, e.g. one desktop process per user
+	State            State
+	Pid              int
+	StartTime        time.Time
+	Restarts         int
+	NextStart        time.Time // set while backing off
+	LastExit         string
+	LastExitTime     time.Time
+	LastHealthCheck  time.Time
+	HealthCheckError string
+	RssBytes         uint64
+	CpuPercent       float64
+}
+
+// Reporter is anything that supervises processes and can report on them -- the Supervisor
+// itself, and the desktop runner, which manages a process per console user.
+type Reporter interface {
+	ProcessStatuses() []Status
+}
+
+var reporters = &reporterRegistry{}
+
+type reporterRegistry struct {
+	sync.Mutex
+	reporters []Reporter
+}
+
+// RegisterReporter adds r to the reporters whose processes are returned by Statuses.
+func RegisterReporter(r Reporter) {
+	reporters.Lock()
+	defer reporters.Unlock()
+
+	reporters.reporters = append(reporters.reporters, r)
+}
+
+// Statuses returns the status of every process reported by a registered Reporter.
+func Statuses() []Status {
+	reporters.Lock()
+	defer reporters.Unlock()
+
+	var statuses []Status
+	for _, r := range reporters.reporters {
+		statuses = append(statuses, r.ProcessStatuses()...)
+	}
+	return statuses
+}
+
+// ProcessMatches reports whether the process with the given pid is still running the
+// executable at path. Pids are reused, so a process that has exited may have been replaced by
+// an unrelated one; checking the path guards against mistaking that process for ours.
+func ProcessMatches(ctx context.Context, pid int, path string) (bool, error) {
+	proc, err := process.NewProcessWithContext(ctx, int32(pid))
+	if err != nil {
+		return false, err
+	}
+
+	exePath, err := proc.ExeWithContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return exePath == path, nil
+}
@@ -0,0 +1,223 @@
+// Package supervisor runs and supervises launcher's auxiliary processes -- processes launcher
+// starts and keeps running alongside itself. Each process is described by a Spec: how to start
+// it, when to restart it, how to check that it's healthy, and how much memory and CPU it may
+// use. The supervisor restarts processes that exit, fail their health checks, or exceed their
+// limits, backing off when they keep failing, and reports their state for the
+// kolide_launcher_processes table.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/kolide/launcher/ee/gowrapper"
+	"github.com/kolide/launcher/pkg/rungroup"
+)
+
+// RestartPolicy determines whether a process is restarted when it exits.
+type RestartPolicy string
+
+const (
+	RestartAlways    RestartPolicy = "always"     // restart whenever the process exits
+	RestartOnFailure RestartPolicy = "on_failure" // restart unless the process exits successfully
+	RestartNever     RestartPolicy = "never"
+)
+
+const (
+	defaultMinRestartDelay     = 1 * time.Second
+	defaultMaxRestartDelay     = 5 * time.Minute
+	defaultStableAfter         = 10 * time.Minute
+	defaultCheckInterval       = 30 * time.Second
+	defaultHealthCheckFailures = 3
+	defaultStopTimeout         = 5 * time.Second
+)
+
+// Spec describes a process to supervise. Only Name and Start are required.
+type Spec struct {
+	Name string
+
+	// Start starts the process, returning its command; the supervisor waits on it. It is
+	// called again each time the process is restarted, so that it can e.g. pick up a new
+	// executable after an update.
+	Start func(ctx context.Context) (*exec.Cmd, error)
+
+	// Stop asks the process to shut down gracefully. If it's unset, or the process hasn't
+	// exited StopTimeout after it's called, the process is killed.
+	Stop        func(ctx context.Context, cmd *exec.Cmd) error
+	StopTimeout time.Duration
+
+	RestartPolicy RestartPolicy // defaults to RestartAlways
+	// A process that exits after running for StableAfter is restarted immediately. Otherwise,
+	// restarts are delayed by MinRestartDelay, doubling each consecutive restart up to
+	// MaxRestartDelay.
+	MinRestartDelay time.Duration
+	MaxRestartDelay time.Duration
+	StableAfter     time.Duration
+
+	// HealthCheck, if set, is called every CheckInterval while the process is running. After
+	// HealthCheckFailures consecutive failures, the process is restarted.
+	HealthCheck         func(ctx context.Context, cmd *exec.Cmd) error
+	HealthCheckFailures int
+
+	// Limits, if set, are checked every CheckInterval; a process that exceeds them is restarted.
+	Limits        Limits
+	CheckInterval time.Duration
+}
+
+// Limits are the resources a process may use. Zero values are unlimited.
+type Limits struct {
+	MaxRssBytes   uint64
+	MaxCpuPercent float64 // averaged over CheckInterval; may exceed 100 on multi-core machines
+}
+
+func (s *Spec) setDefaults() {
+	if s.RestartPolicy == "" {
+		s.RestartPolicy = RestartAlways
+	}
+	if s.MinRestartDelay <= 0 {
+		s.MinRestartDelay = defaultMinRestartDelay
+	}
+	if s.MaxRestartDelay < s.MinRestartDelay {
+		s.MaxRestartDelay = max(defaultMaxRestartDelay, s.MinRestartDelay)
+	}
+	if s.StableAfter <= 0 {
+		s.StableAfter = defaultStableAfter
+	}
+	if s.CheckInterval <= 0 {
+		s.CheckInterval = defaultCheckInterval
+	}
+	if s.HealthCheckFailures <= 0 {
+		s.HealthCheckFailures = defaultHealthCheckFailures
+	}
+	if s.StopTimeout <= 0 {
+		s.StopTimeout = defaultStopTimeout
+	}
+}
+
+// Supervisor runs a set of supervised processes. Add processes with Add, then run it
+// in launcher's rungroup.
+type Supervisor struct {
+	slogger *slog.Logger
+
+	lock      sync.Mutex
+	processes []*supervisedProcess
+	ctx       context.Context // nolint:containedctx // cancelled on interrupt to stop every process
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	started     bool
+	interrupt   chan struct{}
+	interrupted bool
+}
+
+func New(slogger *slog.Logger) *Supervisor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Supervisor{
+		slogger:   slogger.With("component", "supervisor"),
+		ctx:       ctx,
+		cancel:    cancel,
+		interrupt: make(chan struct{}, 1),
+	}
+}
+
+// Add adds a process to supervise. Processes added before Execute are started when it runs;
+// processes added afterwards are started immediately.
+func (s *Supervisor) Add(spec Spec) error {
+	if spec.Name == "" || spec.Start == nil {
+		return errors.New("supervised process requires a name and start function")
+	}
+	switch spec.RestartPolicy {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return fmt.Errorf("unknown restart policy %q", spec.RestartPolicy)
+	}
+	spec.setDefaults()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.interrupted {
+		return errors.New("supervisor is shut down")
+	}
+	for _, p := range s.processes {
+		if p.spec.Name == spec.Name {
+			return fmt.Errorf("process %s is already supervised", spec.Name)
+		}
+	}
+
+	p := newSupervisedProcess(s.slogger, spec)
+	s.processes = append(s.processes, p)
+	if s.started {
+		s.start(p)
+	}
+
+	return nil
+}
+
+// start runs p until the supervisor is interrupted. Callers must hold s.lock.
+func (s *Supervisor) start(p *supervisedProcess) {
+	s.wg.Add(1)
+	gowrapper.Go(s.ctx, s.slogger, func() {
+		defer s.wg.Done()
+		p.run(s.ctx)
+	})
+}
+
+// Execute starts the supervised processes, and supervises them until interrupted.
+func (s *Supervisor) Execute() error {
+	s.lock.Lock()
+	s.started = true
+	for _, p := range s.processes {
+		s.start(p)
+	}
+	s.lock.Unlock()
+
+	<-s.interrupt
+	return nil
+}
+
+// Interrupt stops the supervised processes.
+func (s *Supervisor) Interrupt(_ error) {
+	s.lock.Lock()
+	if s.interrupted {
+		s.lock.Unlock()
+		return
+	}
+	s.interrupted = true
+	s.lock.Unlock()
+
+	s.cancel()
+
+	done := make(chan struct{})
+	gowrapper.Go(context.TODO(), s.slogger, func() {
+		s.wg.Wait()
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(rungroup.InterruptTimeout):
+		s.slogger.Log(context.TODO(), slog.LevelWarn,
+			"timed out waiting for supervised processes to stop",
+		)
+	}
+
+	s.interrupt <- struct{}{}
+}
+
+// ProcessStatuses implements Reporter.
+func (s *Supervisor) ProcessStatuses() []Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	statuses := make([]Status, len(s.processes))
+	for i, p := range s.processes {
+		statuses[i] = p.status()
+	}
+	return statuses
+}
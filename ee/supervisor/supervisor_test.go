@@ -0,0 +1,182 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+// helperStart returns a Start function running TestHelperProcess in the given mode.
+func helperStart(mode string) func(ctx context.Context) (*exec.Cmd, error) {
+	return func(_ context.Context) (*exec.Cmd, error) {
+		cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess", "--", mode) //nolint:forbidigo // Fine to use exec.Command in tests
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd, cmd.Start()
+	}
+}
+
+// TestHelperProcess isn't a real test; it's the process the tests supervise.
+func TestHelperProcess(t *testing.T) { //nolint:paralleltest
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	switch os.Args[len(os.Args)-1] {
+	case "fail":
+		os.Exit(1) //nolint:forbidigo // Fine to use os.Exit in tests
+	case "succeed":
+		os.Exit(0) //nolint:forbidigo // Fine to use os.Exit in tests
+	case "sleep":
+		time.Sleep(time.Minute)
+		os.Exit(0) //nolint:forbidigo // Fine to use os.Exit in tests
+	}
+}
+
+func runSupervisor(t *testing.T, specs ...Spec) *Supervisor {
+	s := New(multislogger.NewNopLogger())
+	for _, spec := range specs {
+		require.NoError(t, s.Add(spec))
+	}
+
+	go s.Execute()
+	t.Cleanup(func() { s.Interrupt(nil) })
+
+	return s
+}
+
+func statusOf(s *Supervisor, name string) Status {
+	for _, st := range s.ProcessStatuses() {
+		if st.Name == name {
+			return st
+		}
+	}
+	return Status{}
+}
+
+func TestSupervisor_RestartsFailedProcess(t *testing.T) {
+	t.Parallel()
+
+	s := runSupervisor(t, Spec{
+		Name:            "failing",
+		Start:           helperStart("fail"),
+		MinRestartDelay: 10 * time.Millisecond,
+		MaxRestartDelay: 20 * time.Millisecond,
+	})
+
+	require.Eventually(t, func() bool {
+		return statusOf(s, "failing").Restarts >= 2
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Contains(t, statusOf(s, "failing").LastExit, "exit status 1")
+}
+
+func TestSupervisor_RestartOnFailure_SuccessfulExit(t *testing.T) {
+	t.Parallel()
+
+	s := runSupervisor(t, Spec{
+		Name:            "succeeding",
+		Start:           helperStart("succeed"),
+		RestartPolicy:   RestartOnFailure,
+		MinRestartDelay: 10 * time.Millisecond,
+	})
+
+	require.Eventually(t, func() bool {
+		return statusOf(s, "succeeding").State == StateExited
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, 0, statusOf(s, "succeeding").Restarts)
+}
+
+func TestSupervisor_RestartsUnhealthyProcess(t *testing.T) {
+	t.Parallel()
+
+	s := runSupervisor(t, Spec{
+		Name:                "unhealthy",
+		Start:               helperStart("sleep"),
+		MinRestartDelay:     10 * time.Millisecond,
+		CheckInterval:       20 * time.Millisecond,
+		HealthCheckFailures: 2,
+		HealthCheck: func(_ context.Context, _ *exec.Cmd) error {
+			return errors.New("not responding")
+		},
+	})
+
+	require.Eventually(t, func() bool {
+		return statusOf(s, "unhealthy").Restarts >= 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	status := statusOf(s, "unhealthy")
+	require.Contains(t, status.LastExit, "failed 2 health checks")
+	require.False(t, status.LastHealthCheck.IsZero())
+}
+
+func TestSupervisor_RestartsProcessExceedingLimits(t *testing.T) {
+	t.Parallel()
+
+	s := runSupervisor(t, Spec{
+		Name:            "hungry",
+		Start:           helperStart("sleep"),
+		MinRestartDelay: 10 * time.Millisecond,
+		CheckInterval:   20 * time.Millisecond,
+		Limits:          Limits{MaxRssBytes: 1},
+	})
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(statusOf(s, "hungry").LastExit, "memory usage")
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestSupervisor_StopsProcessesOnInterrupt(t *testing.T) {
+	t.Parallel()
+
+	var cmd *exec.Cmd
+	started := make(chan struct{})
+	s := New(multislogger.NewNopLogger())
+	require.NoError(t, s.Add(Spec{
+		Name: "sleeping",
+		Start: func(ctx context.Context) (*exec.Cmd, error) {
+			c, err := helperStart("sleep")(ctx)
+			cmd = c
+			close(started)
+			return c, err
+		},
+	}))
+
+	executeDone := make(chan struct{})
+	go func() {
+		require.NoError(t, s.Execute())
+		close(executeDone)
+	}()
+
+	<-started
+	require.Eventually(t, func() bool {
+		return statusOf(s, "sleeping").State == StateRunning
+	}, 10*time.Second, 10*time.Millisecond)
+
+	s.Interrupt(nil)
+	<-executeDone
+
+	require.Equal(t, StateStopped, statusOf(s, "sleeping").State)
+	require.NotNil(t, cmd.ProcessState, "process should have been waited on")
+
+	// Interrupting again doesn't block
+	s.Interrupt(nil)
+}
+
+func TestSupervisor_Add(t *testing.T) {
+	t.Parallel()
+
+	s := New(multislogger.NewNopLogger())
+	require.Error(t, s.Add(Spec{Name: "no-start"}))
+	require.Error(t, s.Add(Spec{Name: "bad-policy", Start: helperStart("sleep"), RestartPolicy: "sometimes"}))
+	require.NoError(t, s.Add(Spec{Name: "process", Start: helperStart("sleep"), RestartPolicy: RestartNever}))
+	require.Error(t, s.Add(Spec{Name: "process", Start: helperStart("sleep")}), "names must be unique")
+
+	s.Interrupt(nil)
+	require.Error(t, s.Add(Spec{Name: "late", Start: helperStart("sleep")}))
+}
@@ -0,0 +1,311 @@
+package analyticssettings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// location is one place a setting may be stored. Apple moves these settings between domains
+// and keys from one macOS version to the next, so each setting has several candidates.
+type location struct {
+	domain string
+	key    string
+
+	// path, if set, is the one plist the key is read from, relative to the root. Otherwise
+	// the key is read from the domain, wherever preferences for it may be. Diagnostics settings
+	// chosen in System Settings are kept in a plist outside of any preference domain.
+	path string
+
+	// enabled interprets the value. It returns false for ok if the value doesn't tell us.
+	enabled func(val interface{}) (enabled bool, ok bool)
+}
+
+// settings are the settings we report on, with the places each may be stored, in order of
+// preference when a setting is found in more than one place with the same precedence.
+var settings = map[string][]location{
+	// "Share Mac Analytics"
+	"diagnostics_submission": {
+		{domain: "com.apple.SubmitDiagInfo", key: "AutoSubmit", enabled: isTrue},
+		{key: "AutoSubmit", path: "Library/Application Support/CrashReporter/DiagnosticMessagesHistory.plist", enabled: isTrue},
+	},
+	// "Share with app developers"
+	"third_party_diagnostics_submission": {
+		{domain: "com.apple.SubmitDiagInfo", key: "ThirdPartyDataSubmit", enabled: isTrue},
+		{key: "ThirdPartyDataSubmit", path: "Library/Application Support/CrashReporter/DiagnosticMessagesHistory.plist", enabled: isTrue},
+	},
+	"siri": {
+		{domain: "com.apple.applicationaccess", key: "allowAssistant", enabled: isTrue},
+		{domain: "com.apple.assistant.support", key: "Assistant Enabled", enabled: isTrue},
+		{domain: "com.apple.Siri", key: "SiriEnabled", enabled: isTrue},
+	},
+	// "Improve Siri & Dictation"; the opt-in status is 1 for opted in, and 2 for opted out
+	"siri_data_sharing": {
+		{domain: "com.apple.assistant.support", key: "Siri Data Sharing Opt-In Status", enabled: isInt(1, 2)},
+	},
+	"dictation": {
+		{domain: "com.apple.applicationaccess", key: "allowDictation", enabled: isTrue},
+		{domain: "com.apple.assistant.support", key: "Dictation Enabled", enabled: isTrue},
+		{domain: "com.apple.HIToolbox", key: "AppleDictationAutoEnable", enabled: isTrue},
+	},
+	"personalized_ads": {
+		{domain: "com.apple.AdLib", key: "forceLimitAdTracking", enabled: isFalse},
+		{domain: "com.apple.AdLib", key: "allowApplePersonalizedAdvertising", enabled: isTrue},
+	},
+}
+
+// Sources, from highest to lowest precedence. This mirrors how cfprefsd resolves values:
+// managed (per-user, then per-computer), then the user's by-host and any-host
+// preferences, then the system-wide preferences.
+const (
+	sourceManagedUser = "managed_user"
+	sourceManaged     = "managed"
+	sourceUserByHost  = "user_byhost"
+	sourceUser        = "user"
+	sourceSystem      = "system"
+)
+
+type prefPath struct {
+	source string
+	path   string
+}
+
+type setting struct {
+	username string
+	name     string
+	enabled  string
+	value    string
+	domain   string
+	key      string
+	source   string
+	path     string
+}
+
+// settingsCollector reads preference plists relative to rootDir, which is / outside of tests.
+type settingsCollector struct {
+	rootDir string
+}
+
+// users returns the usernames with home directories under /Users.
+func (sc *settingsCollector) users() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(sc.rootDir, "Users"))
+	if err != nil {
+		return nil, fmt.Errorf("reading user directories: %w", err)
+	}
+
+	var users []string
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "Shared" || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		users = append(users, e.Name())
+	}
+
+	return users, nil
+}
+
+// sourcePaths returns the candidate plist paths for a location, in precedence order. The
+// by-host plist is suffixed with the hardware UUID, so it's found by glob.
+func (sc *settingsCollector) sourcePaths(username string, loc location) []prefPath {
+	if loc.path != "" {
+		return []prefPath{{sourceSystem, filepath.Join(sc.rootDir, filepath.FromSlash(loc.path))}}
+	}
+
+	plistName := loc.domain + ".plist"
+	managedDir := filepath.Join(sc.rootDir, "Library", "Managed Preferences")
+
+	var paths []prefPath
+	if username != "" {
+		homePrefs := filepath.Join(sc.rootDir, "Users", username, "Library", "Preferences")
+
+		paths = append(paths, prefPath{sourceManagedUser, filepath.Join(managedDir, username, plistName)})
+		paths = append(paths, prefPath{sourceManaged, filepath.Join(managedDir, plistName)})
+
+		byHost, _ := filepath.Glob(filepath.Join(homePrefs, "ByHost", loc.domain+".*.plist"))
+		sort.Strings(byHost)
+		for _, p := range byHost {
+			paths = append(paths, prefPath{sourceUserByHost, p})
+		}
+
+		paths = append(paths, prefPath{sourceUser, filepath.Join(homePrefs, plistName)})
+	} else {
+		paths = append(paths, prefPath{sourceManaged, filepath.Join(managedDir, plistName)})
+	}
+
+	paths = append(paths, prefPath{sourceSystem, filepath.Join(sc.rootDir, "Library", "Preferences", plistName)})
+
+	return paths
+}
+
+// collect returns each setting for the given user, from the highest precedence place it's
+// set. Settings that aren't set anywhere are returned with an empty source. An empty username
+// returns only the computer-level settings.
+func (sc *settingsCollector) collect(username string) []setting {
+	plists := make(map[string]map[string]interface{})
+	read := func(path string) map[string]interface{} {
+		if prefs, ok := plists[path]; ok {
+			return prefs
+		}
+		prefs, _ := readPlist(path)
+		plists[path] = prefs
+		return prefs
+	}
+
+	results := make([]setting, 0, len(settings))
+	for _, name := range sortedSettings() {
+		result := setting{username: username, name: name}
+		bestRank := len(sourceRanks)
+
+		for _, loc := range settings[name] {
+			for _, src := range sc.sourcePaths(username, loc) {
+				// Earlier locations win ties, and each location's paths are in precedence order
+				if sourceRanks[src.source] >= bestRank {
+					break
+				}
+
+				val, ok := read(src.path)[loc.key]
+				if !ok {
+					continue
+				}
+
+				enabled := ""
+				if e, ok := loc.enabled(val); ok {
+					enabled = boolToIntString(e)
+				}
+
+				result.enabled = enabled
+				result.value = stringifyValue(val)
+				result.domain = loc.domain
+				result.key = loc.key
+				result.source = src.source
+				result.path = sc.displayPath(src.path)
+				bestRank = sourceRanks[src.source]
+				break
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+var sourceRanks = map[string]int{
+	sourceManagedUser: 0,
+	sourceManaged:     1,
+	sourceUserByHost:  2,
+	sourceUser:        3,
+	sourceSystem:      4,
+}
+
+// displayPath returns the path as it would be on the host, without rootDir.
+func (sc *settingsCollector) displayPath(path string) string {
+	rel, err := filepath.Rel(sc.rootDir, path)
+	if err != nil {
+		return path
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+func readPlist(path string) (map[string]interface{}, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefs map[string]interface{}
+	if _, err := plist.Unmarshal(raw, &prefs); err != nil {
+		return nil, fmt.Errorf("unmarshalling %s: %w", path, err)
+	}
+
+	return prefs, nil
+}
+
+// isTrue interprets booleans, and the integers preferences sometimes store them as.
+func isTrue(val interface{}) (bool, bool) {
+	switch v := val.(type) {
+	case bool:
+		return v, true
+	case uint64:
+		return v != 0, true
+	case int64:
+		return v != 0, true
+	case string:
+		switch strings.ToLower(v) {
+		case "1", "true", "yes":
+			return true, true
+		case "0", "false", "no":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// isFalse is isTrue, inverted, for settings that disable something when set.
+func isFalse(val interface{}) (bool, bool) {
+	b, ok := isTrue(val)
+	return !b, ok
+}
+
+// isInt interprets integer statuses: on means enabled, off means disabled, and anything else
+// is unknown.
+func isInt(on, off int64) func(interface{}) (bool, bool) {
+	return func(val interface{}) (bool, bool) {
+		var n int64
+		switch v := val.(type) {
+		case uint64:
+			n = int64(v)
+		case int64:
+			n = v
+		default:
+			return false, false
+		}
+
+		switch n {
+		case on:
+			return true, true
+		case off:
+			return false, true
+		}
+		return false, false
+	}
+}
+
+// stringifyValue renders scalars directly, and anything more complex as JSON.
+func stringifyValue(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case bool:
+		return boolToIntString(v)
+	case uint64, int64, float64, int, uint:
+		return fmt.Sprintf("%v", v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(raw)
+	}
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func sortedSettings() []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
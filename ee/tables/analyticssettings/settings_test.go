@@ -0,0 +1,108 @@
+package analyticssettings
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	sc := &settingsCollector{rootDir: filepath.Join("testdata", "root")}
+
+	users, err := sc.users()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"alice", "bob"}, users)
+
+	const (
+		diagnosticsPath = "/Library/Application Support/CrashReporter/DiagnosticMessagesHistory.plist"
+		managedDiagPath = "/Library/Managed Preferences/com.apple.SubmitDiagInfo.plist"
+	)
+
+	var tests = []struct {
+		username string
+		expected []setting
+	}{
+		{
+			username: "alice",
+			expected: []setting{
+				{name: "diagnostics_submission", enabled: "0", value: "0", domain: "com.apple.SubmitDiagInfo", key: "AutoSubmit", source: sourceManaged, path: managedDiagPath},
+				{name: "dictation", enabled: "1", value: "1", domain: "com.apple.assistant.support", key: "Dictation Enabled", source: sourceUser, path: "/Users/alice/Library/Preferences/com.apple.assistant.support.plist"},
+				{name: "personalized_ads", enabled: "1", value: "1", domain: "com.apple.AdLib", key: "allowApplePersonalizedAdvertising", source: sourceUser, path: "/Users/alice/Library/Preferences/com.apple.AdLib.plist"},
+				{name: "siri", enabled: "0", value: "0", domain: "com.apple.applicationaccess", key: "allowAssistant", source: sourceManagedUser, path: "/Library/Managed Preferences/alice/com.apple.applicationaccess.plist"},
+				{name: "siri_data_sharing", enabled: "0", value: "2", domain: "com.apple.assistant.support", key: "Siri Data Sharing Opt-In Status", source: sourceUser, path: "/Users/alice/Library/Preferences/com.apple.assistant.support.plist"},
+				{name: "third_party_diagnostics_submission", enabled: "0", value: "0", key: "ThirdPartyDataSubmit", source: sourceSystem, path: diagnosticsPath},
+			},
+		},
+		{
+			username: "bob",
+			expected: []setting{
+				{name: "diagnostics_submission", enabled: "0", value: "0", domain: "com.apple.SubmitDiagInfo", key: "AutoSubmit", source: sourceManaged, path: managedDiagPath},
+				{name: "dictation", enabled: "1", value: "1", domain: "com.apple.HIToolbox", key: "AppleDictationAutoEnable", source: sourceUser, path: "/Users/bob/Library/Preferences/com.apple.HIToolbox.plist"},
+				{name: "personalized_ads", enabled: "0", value: "1", domain: "com.apple.AdLib", key: "forceLimitAdTracking", source: sourceSystem, path: "/Library/Preferences/com.apple.AdLib.plist"},
+				{name: "siri", enabled: "0", value: "0", domain: "com.apple.Siri", key: "SiriEnabled", source: sourceUserByHost, path: "/Users/bob/Library/Preferences/ByHost/com.apple.Siri.0A1B2C3D-0000-0000-0000-000000000000.plist"},
+				{name: "siri_data_sharing", enabled: "", value: "3", domain: "com.apple.assistant.support", key: "Siri Data Sharing Opt-In Status", source: sourceUser, path: "/Users/bob/Library/Preferences/com.apple.assistant.support.plist"},
+				{name: "third_party_diagnostics_submission", enabled: "0", value: "0", key: "ThirdPartyDataSubmit", source: sourceSystem, path: diagnosticsPath},
+			},
+		},
+		{
+			username: "",
+			expected: []setting{
+				{name: "diagnostics_submission", enabled: "0", value: "0", domain: "com.apple.SubmitDiagInfo", key: "AutoSubmit", source: sourceManaged, path: managedDiagPath},
+				{name: "dictation"},
+				{name: "personalized_ads", enabled: "0", value: "1", domain: "com.apple.AdLib", key: "forceLimitAdTracking", source: sourceSystem, path: "/Library/Preferences/com.apple.AdLib.plist"},
+				{name: "siri"},
+				{name: "siri_data_sharing"},
+				{name: "third_party_diagnostics_submission", enabled: "0", value: "0", key: "ThirdPartyDataSubmit", source: sourceSystem, path: diagnosticsPath},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.username, func(t *testing.T) {
+			t.Parallel()
+
+			for i := range tt.expected {
+				tt.expected[i].username = tt.username
+			}
+
+			require.Equal(t, tt.expected, sc.collect(tt.username))
+		})
+	}
+}
+
+func TestInterpretValues(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name            string
+		interpret       func(interface{}) (bool, bool)
+		val             interface{}
+		expectedEnabled bool
+		expectedOk      bool
+	}{
+		{name: "true", interpret: isTrue, val: true, expectedEnabled: true, expectedOk: true},
+		{name: "integer", interpret: isTrue, val: uint64(0), expectedEnabled: false, expectedOk: true},
+		{name: "string", interpret: isTrue, val: "YES", expectedEnabled: true, expectedOk: true},
+		{name: "unknown string", interpret: isTrue, val: "maybe"},
+		{name: "inverted", interpret: isFalse, val: true, expectedEnabled: false, expectedOk: true},
+		{name: "inverted unknown", interpret: isFalse, val: []interface{}{}},
+		{name: "status on", interpret: isInt(1, 2), val: uint64(1), expectedEnabled: true, expectedOk: true},
+		{name: "status off", interpret: isInt(1, 2), val: int64(2), expectedEnabled: false, expectedOk: true},
+		{name: "status unknown", interpret: isInt(1, 2), val: uint64(0)},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			enabled, ok := tt.interpret(tt.val)
+			require.Equal(t, tt.expectedOk, ok)
+			if ok {
+				require.Equal(t, tt.expectedEnabled, enabled)
+			}
+		})
+	}
+}
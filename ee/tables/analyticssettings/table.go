@@ -0,0 +1,88 @@
+//go:build darwin
+// +build darwin
+
+package analyticssettings
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName                 = "kolide_macos_analytics_and_siri_settings"
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+)
+
+type Table struct {
+	slogger   *slog.Logger
+	collector *settingsCollector
+}
+
+// TablePlugin provides an osquery table of diagnostics submission, Siri, dictation, and
+// personalized ads settings. There's one row per user and setting, with the value from
+// wherever it takes effect; enabled is empty if the setting isn't set, or its value isn't
+// understood.
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("setting"),
+		table.IntegerColumn("enabled"),
+		table.TextColumn("value"),
+		table.TextColumn("domain"),
+		table.TextColumn("key"),
+		table.TextColumn("source"),
+		table.TextColumn("path"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", tableName),
+		collector: &settingsCollector{rootDir: "/"},
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	if len(usernames) == 0 {
+		var err error
+		usernames, err = t.collector.users()
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list users, only returning computer-level settings",
+				"err", err,
+			)
+		}
+	}
+
+	// With no users at all, still report what's set for the computer
+	if len(usernames) == 0 {
+		usernames = []string{""}
+	}
+
+	for _, username := range usernames {
+		for _, s := range t.collector.collect(username) {
+			results = append(results, map[string]string{
+				"username": s.username,
+				"setting":  s.name,
+				"enabled":  s.enabled,
+				"value":    s.value,
+				"domain":   s.domain,
+				"key":      s.key,
+				"source":   s.source,
+				"path":     s.path,
+			})
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,256 @@
+// Package appconfig provides tables reporting the local configuration of conferencing and
+// chat apps -- Zoom, Microsoft Teams, and Slack. osquery can see the app versions, but not
+// whether they are set to update themselves, or locked down to the organization's tenant.
+// These tables report the settings an admin or MDM can set for each app, along with the
+// installed version.
+package appconfig
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/ee/hostroot"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+// Categories of settings
+const (
+	categoryUpdate  = "update"
+	categoryTenant  = "tenant"
+	categoryVersion = "version"
+	categoryOther   = "other"
+)
+
+// Sources of settings, per platform. On macOS and Linux, sources are listed in precedence order.
+const (
+	sourceManagedUser = "managed_user" // macOS: /Library/Managed Preferences/<user>/<domain>.plist
+	sourceManaged     = "managed"      // macOS: /Library/Managed Preferences/<domain>.plist
+	sourceUser        = "user"         // the user's own configuration
+	sourceSystem      = "system"       // system-wide configuration
+	sourcePolicy      = "policy"       // Windows: HKLM\SOFTWARE\Policies
+	sourceApp         = "app"          // the installed app, for its version
+)
+
+// app describes where to find an app's configuration and version on each platform.
+type app struct {
+	tableName string
+
+	// macOS
+	preferenceDomains []string // preference domains, e.g. us.zoom.config
+	bundles           []string // app bundle names, looked for in /Applications and ~/Applications
+
+	// Windows
+	policyKeys           []string // keys under HKLM, read recursively
+	uninstallDisplayName string   // prefix of the DisplayName of the app's uninstall entry
+
+	// Linux
+	userConfigFiles   []string // INI files, relative to the user's home directory
+	systemConfigFiles []string // INI files
+}
+
+var apps = []app{
+	{
+		tableName:            "kolide_zoom_config",
+		preferenceDomains:    []string{"us.zoom.config"},
+		bundles:              []string{"zoom.us.app"},
+		policyKeys:           []string{`SOFTWARE\Policies\Zoom`},
+		uninstallDisplayName: "Zoom",
+		userConfigFiles:      []string{".config/zoomus.conf"},
+		systemConfigFiles:    []string{"/etc/zoomus.conf"},
+	},
+	{
+		tableName: "kolide_teams_config",
+		// Teams updates through Microsoft AutoUpdate on macOS, so its settings are reported too
+		preferenceDomains:    []string{"com.microsoft.teams2", "com.microsoft.teams", "com.microsoft.autoupdate2"},
+		bundles:              []string{"Microsoft Teams.app", "Microsoft Teams classic.app"},
+		policyKeys:           []string{`SOFTWARE\Policies\Microsoft\Teams`, `SOFTWARE\Policies\Microsoft\Office\16.0\Teams`},
+		uninstallDisplayName: "Microsoft Teams",
+	},
+	{
+		// kolide_slack_config already reports the signed-in Slack workspaces
+		tableName:            "kolide_slack_app_config",
+		preferenceDomains:    []string{"com.tinyspeck.slackmacgap"},
+		bundles:              []string{"Slack.app"},
+		policyKeys:           []string{`SOFTWARE\Policies\Slack`, `SOFTWARE\Policies\Slack Technologies\Slack`},
+		uninstallDisplayName: "Slack",
+	},
+}
+
+type setting struct {
+	username  string
+	group     string // settings only take precedence over others in the same group, e.g. preference domain
+	key       string
+	value     string
+	source    string
+	path      string
+	effective bool
+}
+
+type Table struct {
+	slogger   *slog.Logger
+	app       app
+	collector *settingsCollector
+}
+
+// TablePlugins returns the tables for all supported apps.
+func TablePlugins(slogger *slog.Logger) []osquery.OsqueryPlugin {
+	plugins := make([]osquery.OsqueryPlugin, 0, len(apps))
+	for _, a := range apps {
+		plugins = append(plugins, tablePlugin(slogger, a))
+	}
+	return plugins
+}
+
+func tablePlugin(slogger *slog.Logger, a app) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("key"),
+		table.TextColumn("value"),
+		table.TextColumn("category"),
+		table.TextColumn("source"),
+		table.TextColumn("path"),
+		table.IntegerColumn("effective"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", a.tableName),
+		app:       a,
+		collector: &settingsCollector{rootDir: hostroot.Path("/")},
+	}
+
+	return table.NewPlugin(a.tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	if len(usernames) == 0 {
+		var err error
+		usernames, err = t.collector.users()
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list users, only returning computer-level settings",
+				"err", err,
+			)
+		}
+	}
+
+	// With no users at all, still report what's set for the computer
+	if len(usernames) == 0 {
+		usernames = []string{""}
+	}
+
+	for _, username := range usernames {
+		for _, s := range t.collector.collect(ctx, t.slogger, t.app, username) {
+			results = append(results, row(s))
+		}
+	}
+
+	return results, nil
+}
+
+func row(s setting) map[string]string {
+	effective := 0
+	if s.effective {
+		effective = 1
+	}
+
+	settingCategory := category(s.key)
+	if s.source == sourceApp {
+		settingCategory = categoryVersion
+	}
+
+	return map[string]string{
+		"username":  s.username,
+		"key":       s.key,
+		"value":     s.value,
+		"category":  settingCategory,
+		"source":    s.source,
+		"path":      s.path,
+		"effective": strconv.Itoa(effective),
+	}
+}
+
+// category classifies a setting by its name, so that queries can find the update and tenant
+// lock-down settings without knowing each app's key names, which vary across platforms and
+// app versions.
+func category(key string) string {
+	// Only consider the setting's own name, not the path to it
+	name := strings.ToLower(key[strings.LastIndex(key, "/")+1:])
+
+	switch {
+	case strings.Contains(name, "update"):
+		return categoryUpdate
+	case strings.Contains(name, "tenant"),
+		strings.Contains(name, "sso"),
+		strings.Contains(name, "domain"),
+		strings.Contains(name, "signin"),
+		strings.Contains(name, "login"),
+		strings.Contains(name, "workspace"),
+		strings.Contains(name, "organization"):
+		return categoryTenant
+	default:
+		return categoryOther
+	}
+}
+
+// settingsCollector reads configuration relative to rootDir, which is the host root outside of tests.
+type settingsCollector struct {
+	rootDir string
+}
+
+// usersFrom returns the usernames with home directories under homeRoot, relative to rootDir.
+func (sc *settingsCollector) usersFrom(homeRoot string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(sc.rootDir, homeRoot))
+	if err != nil {
+		return nil, fmt.Errorf("reading user directories: %w", err)
+	}
+
+	var users []string
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "Shared" || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		users = append(users, e.Name())
+	}
+
+	return users, nil
+}
+
+// displayPath returns the path as it would be on the host, without rootDir.
+func (sc *settingsCollector) displayPath(path string) string {
+	rel, err := filepath.Rel(sc.rootDir, path)
+	if err != nil {
+		return path
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// markEffective sets effective on the first setting for each key in each group. settings must
+// be in precedence order.
+func markEffective(settings []setting) {
+	seen := make(map[string]bool)
+	for i := range settings {
+		if settings[i].source == sourceApp {
+			settings[i].effective = true
+			continue
+		}
+		groupKey := settings[i].group + "\x00" + settings[i].key
+		settings[i].effective = !seen[groupKey]
+		seen[groupKey] = true
+	}
+}
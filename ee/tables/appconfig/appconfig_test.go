@@ -0,0 +1,59 @@
+package appconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_category(t *testing.T) {
+	t.Parallel()
+
+	for key, expected := range map[string]string{
+		"EnableSilentAutoUpdate":               categoryUpdate,
+		"Zoom Meetings/General/AutoUpdate":     categoryUpdate,
+		"SlackNoAutoUpdates":                   categoryUpdate,
+		"SetSSOURL":                            categoryTenant,
+		"ForceLoginWithSSO":                    categoryTenant,
+		"DefaultSignInTeam":                    categoryTenant,
+		"AllowedTenantIds":                     categoryTenant,
+		"General/ZAutoFullScreenWhenViewShare": categoryOther,
+		"Teams/DisableFullscreen":              categoryOther,
+	} {
+		require.Equal(t, expected, category(key), key)
+	}
+}
+
+func Test_markEffective(t *testing.T) {
+	t.Parallel()
+
+	settings := []setting{
+		{group: "com.microsoft.teams2", key: "a", source: sourceManaged},
+		{group: "com.microsoft.autoupdate2", key: "a", source: sourceManaged},
+		{group: "com.microsoft.teams2", key: "a", source: sourceUser},
+		{group: "com.microsoft.teams2", key: "b", source: sourceUser},
+		{key: "version", source: sourceApp},
+		{key: "version", source: sourceApp},
+	}
+	markEffective(settings)
+
+	var effective []bool
+	for _, s := range settings {
+		effective = append(effective, s.effective)
+	}
+	require.Equal(t, []bool{true, true, false, true, true, true}, effective)
+}
+
+func Test_row(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, map[string]string{
+		"username":  "alice",
+		"key":       "version",
+		"value":     "4.41.105",
+		"category":  categoryVersion,
+		"source":    sourceApp,
+		"path":      "/Applications/Slack.app",
+		"effective": "1",
+	}, row(setting{username: "alice", key: "version", value: "4.41.105", source: sourceApp, path: "/Applications/Slack.app", effective: true}))
+}
@@ -0,0 +1,126 @@
+//go:build darwin
+// +build darwin
+
+package appconfig
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/kolide/launcher/ee/dataflatten"
+	"howett.net/plist"
+)
+
+type prefPath struct {
+	source string
+	path   string
+}
+
+func (sc *settingsCollector) users() ([]string, error) {
+	return sc.usersFrom("Users")
+}
+
+// sourcePaths returns the candidate plist paths for a domain, in the order cfprefsd resolves
+// them: managed (per-user, then per-computer), then the user's preferences, then the system's.
+func (sc *settingsCollector) sourcePaths(username, domain string) []prefPath {
+	plistName := domain + ".plist"
+	managedDir := filepath.Join(sc.rootDir, "Library", "Managed Preferences")
+
+	var paths []prefPath
+	if username != "" {
+		paths = append(paths, prefPath{sourceManagedUser, filepath.Join(managedDir, username, plistName)})
+	}
+	paths = append(paths, prefPath{sourceManaged, filepath.Join(managedDir, plistName)})
+	if username != "" {
+		paths = append(paths, prefPath{sourceUser, filepath.Join(sc.rootDir, "Users", username, "Library", "Preferences", plistName)})
+	}
+	paths = append(paths, prefPath{sourceSystem, filepath.Join(sc.rootDir, "Library", "Preferences", plistName)})
+
+	return paths
+}
+
+// collect returns the app's preferences for the given user, followed by the versions of the
+// app installed for the computer and for the user. An empty username returns only the
+// computer-level settings.
+func (sc *settingsCollector) collect(ctx context.Context, slogger *slog.Logger, a app, username string) []setting {
+	var results []setting
+
+	for _, domain := range a.preferenceDomains {
+		for _, src := range sc.sourcePaths(username, domain) {
+			if _, err := os.Stat(src.path); err != nil {
+				continue
+			}
+
+			rows, err := dataflatten.PlistFile(src.path, dataflatten.WithSlogger(slogger))
+			if err != nil {
+				slogger.Log(ctx, slog.LevelInfo,
+					"could not read preferences",
+					"path", src.path,
+					"err", err,
+				)
+				continue
+			}
+
+			// Plist dictionaries are unordered, so sort for stable results
+			sort.Slice(rows, func(i, j int) bool {
+				return rows[i].StringPath("/") < rows[j].StringPath("/")
+			})
+
+			for _, r := range rows {
+				results = append(results, setting{
+					username: username,
+					group:    domain,
+					key:      r.StringPath("/"),
+					value:    r.Value,
+					source:   src.source,
+					path:     sc.displayPath(src.path),
+				})
+			}
+		}
+	}
+
+	applicationDirs := []string{filepath.Join(sc.rootDir, "Applications")}
+	if username != "" {
+		applicationDirs = append(applicationDirs, filepath.Join(sc.rootDir, "Users", username, "Applications"))
+	}
+	for _, applicationDir := range applicationDirs {
+		for _, bundle := range a.bundles {
+			bundlePath := filepath.Join(applicationDir, bundle)
+			version, err := bundleVersion(bundlePath)
+			if err != nil {
+				continue
+			}
+
+			results = append(results, setting{
+				username: username,
+				key:      "version",
+				value:    version,
+				source:   sourceApp,
+				path:     sc.displayPath(bundlePath),
+			})
+		}
+	}
+
+	markEffective(results)
+	return results
+}
+
+func bundleVersion(bundlePath string) (string, error) {
+	raw, err := os.ReadFile(filepath.Join(bundlePath, "Contents", "Info.plist"))
+	if err != nil {
+		return "", err
+	}
+
+	var info struct {
+		ShortVersion string `plist:"CFBundleShortVersionString"`
+	}
+	if _, err := plist.Unmarshal(raw, &info); err != nil {
+		return "", fmt.Errorf("unmarshalling Info.plist: %w", err)
+	}
+
+	return info.ShortVersion, nil
+}
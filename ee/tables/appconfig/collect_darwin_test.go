@@ -0,0 +1,40 @@
+//go:build darwin
+// +build darwin
+
+package appconfig
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	sc := &settingsCollector{rootDir: filepath.Join("testdata", "root")}
+
+	users, err := sc.users()
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, users)
+
+	zoom := apps[0]
+	require.Equal(t, "kolide_zoom_config", zoom.tableName)
+
+	require.Equal(t, []setting{
+		{username: "alice", group: "us.zoom.config", key: "EnableSilentAutoUpdate", value: "true", source: sourceManaged, path: "/Library/Managed Preferences/us.zoom.config.plist", effective: true},
+		{username: "alice", group: "us.zoom.config", key: "SetSSOURL", value: "example", source: sourceManaged, path: "/Library/Managed Preferences/us.zoom.config.plist", effective: true},
+		{username: "alice", group: "us.zoom.config", key: "EnableSilentAutoUpdate", value: "false", source: sourceUser, path: "/Users/alice/Library/Preferences/us.zoom.config.plist", effective: false},
+		{username: "alice", group: "us.zoom.config", key: "ZAutoFullScreenWhenViewShare", value: "true", source: sourceUser, path: "/Users/alice/Library/Preferences/us.zoom.config.plist", effective: true},
+		{username: "alice", key: "version", value: "6.2.5 (41698)", source: sourceApp, path: "/Applications/zoom.us.app", effective: true},
+	}, sc.collect(context.TODO(), multislogger.NewNopLogger(), zoom, "alice"))
+
+	// Without a user, only the computer-level settings
+	require.Len(t, sc.collect(context.TODO(), multislogger.NewNopLogger(), zoom, ""), 3)
+
+	// Nothing for an app that isn't configured or installed
+	require.Empty(t, sc.collect(context.TODO(), multislogger.NewNopLogger(), apps[2], "alice"))
+}
@@ -0,0 +1,73 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package appconfig
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/kolide/launcher/ee/dataflatten"
+)
+
+func (sc *settingsCollector) users() ([]string, error) {
+	return sc.usersFrom("home")
+}
+
+// collect returns the app's configuration for the given user, followed by the system-wide
+// configuration. An empty username returns only the system-wide configuration.
+func (sc *settingsCollector) collect(ctx context.Context, slogger *slog.Logger, a app, username string) []setting {
+	var results []setting
+
+	type configFile struct {
+		source string
+		path   string
+	}
+
+	var configFiles []configFile
+	if username != "" {
+		for _, f := range a.userConfigFiles {
+			configFiles = append(configFiles, configFile{sourceUser, filepath.Join(sc.rootDir, "home", username, f)})
+		}
+	}
+	for _, f := range a.systemConfigFiles {
+		configFiles = append(configFiles, configFile{sourceSystem, filepath.Join(sc.rootDir, f)})
+	}
+
+	for _, f := range configFiles {
+		if _, err := os.Stat(f.path); err != nil {
+			continue
+		}
+
+		rows, err := dataflatten.IniFile(f.path, dataflatten.WithSlogger(slogger))
+		if err != nil {
+			slogger.Log(ctx, slog.LevelInfo,
+				"could not read config file",
+				"path", f.path,
+				"err", err,
+			)
+			continue
+		}
+
+		// INI sections are read into maps, so sort for stable results
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i].StringPath("/") < rows[j].StringPath("/")
+		})
+
+		for _, r := range rows {
+			results = append(results, setting{
+				username: username,
+				key:      r.StringPath("/"),
+				value:    r.Value,
+				source:   f.source,
+				path:     sc.displayPath(f.path),
+			})
+		}
+	}
+
+	markEffective(results)
+	return results
+}
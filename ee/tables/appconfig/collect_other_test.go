@@ -0,0 +1,39 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package appconfig
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	sc := &settingsCollector{rootDir: filepath.Join("testdata", "root")}
+
+	users, err := sc.users()
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, users)
+
+	zoom := apps[0]
+	require.Equal(t, "kolide_zoom_config", zoom.tableName)
+
+	require.Equal(t, []setting{
+		{username: "alice", key: "General/autoUpdate", value: "false", source: sourceUser, path: "/home/alice/.config/zoomus.conf", effective: true},
+		{username: "alice", key: "General/enableMiniWindow", value: "true", source: sourceUser, path: "/home/alice/.config/zoomus.conf", effective: true},
+		{username: "alice", key: "General/SetSSOURL", value: "example", source: sourceSystem, path: "/etc/zoomus.conf", effective: true},
+		{username: "alice", key: "General/autoUpdate", value: "true", source: sourceSystem, path: "/etc/zoomus.conf", effective: false},
+	}, sc.collect(context.TODO(), multislogger.NewNopLogger(), zoom, "alice"))
+
+	// Without a user, only the system-wide settings
+	require.Len(t, sc.collect(context.TODO(), multislogger.NewNopLogger(), zoom, ""), 2)
+
+	// Nothing for an app without configuration on this platform
+	require.Empty(t, sc.collect(context.TODO(), multislogger.NewNopLogger(), apps[1], "alice"))
+}
@@ -0,0 +1,198 @@
+//go:build windows
+// +build windows
+
+package appconfig
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// maxPolicyKeyDepth bounds how far we descend into a policy key's subkeys
+	maxPolicyKeyDepth = 4
+
+	uninstallKey      = `SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall`
+	uninstallKeyWow64 = `SOFTWARE\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall`
+)
+
+// users returns no users: app policy is set per-computer, under HKLM.
+func (sc *settingsCollector) users() ([]string, error) {
+	return nil, nil
+}
+
+// collect returns the app's policy settings, followed by the versions of the app installed
+// for the computer. username is unused.
+func (sc *settingsCollector) collect(ctx context.Context, slogger *slog.Logger, a app, username string) []setting {
+	var results []setting
+
+	for _, policyKey := range a.policyKeys {
+		settings, err := readPolicyKey(policyKey, "", 0)
+		if err != nil {
+			slogger.Log(ctx, slog.LevelInfo,
+				"could not read policy key",
+				"key", policyKey,
+				"err", err,
+			)
+			continue
+		}
+		for i := range settings {
+			settings[i].username = username
+			settings[i].group = policyKey
+		}
+		results = append(results, settings...)
+	}
+
+	for _, k := range []string{uninstallKey, uninstallKeyWow64} {
+		versions, err := uninstallVersions(k, a.uninstallDisplayName)
+		if err != nil {
+			slogger.Log(ctx, slog.LevelInfo,
+				"could not read uninstall entries",
+				"key", k,
+				"err", err,
+			)
+			continue
+		}
+		for i := range versions {
+			versions[i].username = username
+		}
+		results = append(results, versions...)
+	}
+
+	markEffective(results)
+	return results
+}
+
+// readPolicyKey returns the values under HKLM\<keyPath>, and its subkeys, as settings. Keys are
+// the value's path relative to the policy key, e.g. `Zoom Meetings/General/EnableSilentAutoUpdate`.
+func readPolicyKey(keyPath string, relativePath string, depth int) ([]setting, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.QUERY_VALUE|registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening key: %w", err)
+	}
+	defer key.Close()
+
+	var results []setting
+
+	valueNames, err := key.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("reading value names: %w", err)
+	}
+	for _, valueName := range valueNames {
+		value, err := stringValue(key, valueName)
+		if err != nil {
+			continue
+		}
+
+		settingKey := valueName
+		if relativePath != "" {
+			settingKey = relativePath + "/" + valueName
+		}
+
+		results = append(results, setting{
+			key:    settingKey,
+			value:  value,
+			source: sourcePolicy,
+			path:   `HKEY_LOCAL_MACHINE\` + keyPath,
+		})
+	}
+
+	if depth >= maxPolicyKeyDepth {
+		return results, nil
+	}
+
+	subkeyNames, err := key.ReadSubKeyNames(0)
+	if err != nil {
+		return results, nil
+	}
+	for _, subkeyName := range subkeyNames {
+		subRelativePath := subkeyName
+		if relativePath != "" {
+			subRelativePath = relativePath + "/" + subkeyName
+		}
+
+		subResults, err := readPolicyKey(keyPath+`\`+subkeyName, subRelativePath, depth+1)
+		if err != nil {
+			continue
+		}
+		results = append(results, subResults...)
+	}
+
+	return results, nil
+}
+
+// stringValue renders a registry value as a string, whatever its type.
+func stringValue(key registry.Key, valueName string) (string, error) {
+	_, valType, err := key.GetValue(valueName, nil)
+	if err != nil {
+		return "", err
+	}
+
+	switch valType {
+	case registry.SZ, registry.EXPAND_SZ:
+		val, _, err := key.GetStringValue(valueName)
+		return val, err
+	case registry.DWORD, registry.QWORD:
+		val, _, err := key.GetIntegerValue(valueName)
+		return strconv.FormatUint(val, 10), err
+	case registry.MULTI_SZ:
+		val, _, err := key.GetStringsValue(valueName)
+		return strings.Join(val, "\n"), err
+	default:
+		val, _, err := key.GetBinaryValue(valueName)
+		return hex.EncodeToString(val), err
+	}
+}
+
+// uninstallVersions returns the DisplayVersion of each uninstall entry under HKLM\<keyPath>
+// whose DisplayName starts with displayName.
+func uninstallVersions(keyPath string, displayName string) ([]setting, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening key: %w", err)
+	}
+	defer key.Close()
+
+	subkeyNames, err := key.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("reading subkeys: %w", err)
+	}
+
+	var results []setting
+	for _, subkeyName := range subkeyNames {
+		entry, err := registry.OpenKey(key, subkeyName, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+
+		name, _, nameErr := entry.GetStringValue("DisplayName")
+		version, _, versionErr := entry.GetStringValue("DisplayVersion")
+		entry.Close()
+
+		if nameErr != nil || versionErr != nil || !strings.HasPrefix(name, displayName) {
+			continue
+		}
+
+		results = append(results, setting{
+			key:    "version",
+			value:  version,
+			source: sourceApp,
+			path:   `HKEY_LOCAL_MACHINE\` + keyPath + `\` + subkeyName,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,118 @@
+// Package batteryhealth provides a table reporting the health of the device's batteries -- cycle
+// count, and how much charge they hold now compared to when they were new -- for hardware
+// lifecycle planning. On macOS, this comes from IOKit's AppleSmartBattery, via ioreg. On Windows,
+// it comes from `powercfg /batteryreport`. On Linux, it comes from sysfs.
+package batteryhealth
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_battery_health"
+
+// serviceThresholdPercent is the health below which a battery should be replaced; Apple
+// recommends service once a battery holds less than 80% of its design capacity.
+const serviceThresholdPercent = 80
+
+// Conditions
+const (
+	conditionNormal             = "normal"
+	conditionServiceRecommended = "service_recommended"
+	conditionUnknown            = "unknown"
+)
+
+// Capacity units
+const (
+	unitMah      = "mAh"
+	unitMwh      = "mWh"
+	unitRelative = "relative" // the battery doesn't report real units
+)
+
+var columns = []table.ColumnDefinition{
+	table.TextColumn("name"),
+	table.TextColumn("manufacturer"),
+	table.TextColumn("model"),
+	table.TextColumn("serial_number"),
+	table.TextColumn("chemistry"),
+	table.IntegerColumn("cycle_count"),
+	table.IntegerColumn("design_capacity"),
+	table.IntegerColumn("full_charge_capacity"),
+	table.TextColumn("capacity_unit"),
+	table.DoubleColumn("health_percent"),
+	table.TextColumn("condition"),
+	table.TextColumn("source"),
+}
+
+// battery is a row of the table. Counts and capacities are -1 where the battery doesn't
+// report them.
+type battery struct {
+	name               string
+	manufacturer       string
+	model              string
+	serialNumber       string
+	chemistry          string
+	cycleCount         int64
+	designCapacity     int64
+	fullChargeCapacity int64
+	capacityUnit       string
+	failed             bool // the battery reports a failure, regardless of its capacity
+	source             string
+}
+
+// healthPercent is the full charge capacity as a percentage of the design capacity. It returns
+// false if either is unknown.
+func (b battery) healthPercent() (float64, bool) {
+	if b.designCapacity <= 0 || b.fullChargeCapacity < 0 {
+		return 0, false
+	}
+	return float64(b.fullChargeCapacity) * 100 / float64(b.designCapacity), true
+}
+
+func (b battery) condition() string {
+	if b.failed {
+		return conditionServiceRecommended
+	}
+
+	health, ok := b.healthPercent()
+	switch {
+	case !ok:
+		return conditionUnknown
+	case health < serviceThresholdPercent:
+		return conditionServiceRecommended
+	default:
+		return conditionNormal
+	}
+}
+
+func (b battery) toRow() map[string]string {
+	row := map[string]string{
+		"name":                 b.name,
+		"manufacturer":         b.manufacturer,
+		"model":                b.model,
+		"serial_number":        b.serialNumber,
+		"chemistry":            b.chemistry,
+		"cycle_count":          optionalInt(b.cycleCount),
+		"design_capacity":      optionalInt(b.designCapacity),
+		"full_charge_capacity": optionalInt(b.fullChargeCapacity),
+		"capacity_unit":        b.capacityUnit,
+		"health_percent":       "",
+		"condition":            b.condition(),
+		"source":               b.source,
+	}
+
+	if health, ok := b.healthPercent(); ok {
+		row["health_percent"] = fmt.Sprintf("%.1f", health)
+	}
+
+	return row
+}
+
+func optionalInt(i int64) string {
+	if i < 0 {
+		return ""
+	}
+	return strconv.FormatInt(i, 10)
+}
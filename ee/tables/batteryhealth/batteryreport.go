@@ -0,0 +1,59 @@
+package batteryhealth
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// batteryReport is the part of `powercfg /batteryreport /xml` we're interested in
+type batteryReport struct {
+	Batteries []struct {
+		Id                 string `xml:"Id"`
+		Manufacturer       string `xml:"Manufacturer"`
+		SerialNumber       string `xml:"SerialNumber"`
+		Chemistry          string `xml:"Chemistry"`
+		RelativeCapacity   int    `xml:"RelativeCapacity"`
+		DesignCapacity     int64  `xml:"DesignCapacity"`
+		FullChargeCapacity int64  `xml:"FullChargeCapacity"`
+		CycleCount         int64  `xml:"CycleCount"`
+	} `xml:"Batteries>Battery"`
+}
+
+// batteriesFromBatteryReport reads batteries from the XML battery report written by
+// `powercfg /batteryreport /xml`. Capacities are in mWh, unless the battery only reports
+// relative capacities.
+func batteriesFromBatteryReport(report []byte) ([]battery, error) {
+	var parsed batteryReport
+	if err := xml.Unmarshal(report, &parsed); err != nil {
+		return nil, fmt.Errorf("unmarshalling battery report: %w", err)
+	}
+
+	batteries := make([]battery, len(parsed.Batteries))
+	for i, b := range parsed.Batteries {
+		unit := unitMwh
+		if b.RelativeCapacity != 0 {
+			unit = unitRelative
+		}
+
+		cycleCount := b.CycleCount
+		if cycleCount == 0 {
+			// Many batteries don't report a cycle count to Windows, which then reports 0
+			cycleCount = -1
+		}
+
+		batteries[i] = battery{
+			name:               strings.TrimSpace(b.Id),
+			manufacturer:       strings.TrimSpace(b.Manufacturer),
+			serialNumber:       strings.TrimSpace(b.SerialNumber),
+			chemistry:          strings.TrimSpace(b.Chemistry),
+			cycleCount:         cycleCount,
+			designCapacity:     b.DesignCapacity,
+			fullChargeCapacity: b.FullChargeCapacity,
+			capacityUnit:       unit,
+			source:             "battery_report",
+		}
+	}
+
+	return batteries, nil
+}
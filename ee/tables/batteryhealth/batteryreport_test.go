@@ -0,0 +1,41 @@
+package batteryhealth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatteriesFromBatteryReport(t *testing.T) {
+	t.Parallel()
+
+	report, err := os.ReadFile(filepath.Join("testdata", "batteryreport.xml"))
+	require.NoError(t, err)
+
+	batteries, err := batteriesFromBatteryReport(report)
+	require.NoError(t, err)
+	require.Len(t, batteries, 2)
+
+	require.Equal(t, map[string]string{
+		"name":                 "DELL 7FMXV2A",
+		"manufacturer":         "BYD",
+		"model":                "",
+		"serial_number":        "1234",
+		"chemistry":            "LiP",
+		"cycle_count":          "488",
+		"design_capacity":      "63001",
+		"full_charge_capacity": "47215",
+		"capacity_unit":        "mWh",
+		"health_percent":       "74.9",
+		"condition":            "service_recommended",
+		"source":               "battery_report",
+	}, batteries[0].toRow())
+
+	virtual := batteries[1].toRow()
+	require.Equal(t, "relative", virtual["capacity_unit"])
+	require.Equal(t, "", virtual["cycle_count"])
+	require.Equal(t, "100.0", virtual["health_percent"])
+	require.Equal(t, "normal", virtual["condition"])
+}
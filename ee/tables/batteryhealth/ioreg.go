@@ -0,0 +1,77 @@
+package batteryhealth
+
+import (
+	"bytes"
+	"fmt"
+
+	"howett.net/plist"
+)
+
+// batteriesFromIoreg reads batteries from the output of `ioreg -r -a -c AppleSmartBattery`.
+// Capacities are in mAh.
+func batteriesFromIoreg(output []byte) ([]battery, error) {
+	// ioreg prints nothing at all when there's no battery
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil, nil
+	}
+
+	var entries []map[string]any
+	if _, err := plist.Unmarshal(output, &entries); err != nil {
+		return nil, fmt.Errorf("unmarshalling ioreg output: %w", err)
+	}
+
+	batteries := make([]battery, len(entries))
+	for i, entry := range entries {
+		batteries[i] = battery{
+			name:               stringValue(entry, "DeviceName"),
+			manufacturer:       stringValue(entry, "Manufacturer"),
+			serialNumber:       stringValue(entry, "Serial", "BatterySerialNumber"),
+			cycleCount:         intValue(entry, "CycleCount"),
+			designCapacity:     intValue(entry, "DesignCapacity"),
+			fullChargeCapacity: fullChargeCapacity(entry),
+			capacityUnit:       unitMah,
+			failed:             intValue(entry, "PermanentFailureStatus") > 0,
+			source:             "ioreg",
+		}
+	}
+
+	return batteries, nil
+}
+
+// fullChargeCapacity reads the battery's full charge capacity in mAh. On Apple Silicon,
+// MaxCapacity is a percentage, so the raw capacity is preferred.
+func fullChargeCapacity(entry map[string]any) int64 {
+	if capacity := intValue(entry, "AppleRawMaxCapacity", "NominalChargeCapacity"); capacity >= 0 {
+		return capacity
+	}
+
+	// Only on older, Intel, Macs, where MaxCapacity is in mAh
+	if capacity := intValue(entry, "MaxCapacity"); capacity > 100 {
+		return capacity
+	}
+
+	return -1
+}
+
+// stringValue returns the first of keys present in entry as a string.
+func stringValue(entry map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := entry[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// intValue returns the first of keys present in entry as an integer, or -1 if none are.
+func intValue(entry map[string]any, keys ...string) int64 {
+	for _, key := range keys {
+		switch v := entry[key].(type) {
+		case int64:
+			return v
+		case uint64:
+			return int64(v)
+		}
+	}
+	return -1
+}
@@ -0,0 +1,74 @@
+package batteryhealth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatteriesFromIoreg(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testdata    string
+		expectedRow map[string]string
+	}{
+		{
+			testdata: "ioreg_apple_silicon.plist",
+			expectedRow: map[string]string{
+				"name":                 "bq40z651",
+				"manufacturer":         "",
+				"model":                "",
+				"serial_number":        "F8Y2345ABCD1234XY",
+				"chemistry":            "",
+				"cycle_count":          "312",
+				"design_capacity":      "4382",
+				"full_charge_capacity": "4121",
+				"capacity_unit":        "mAh",
+				"health_percent":       "94.0",
+				"condition":            "normal",
+				"source":               "ioreg",
+			},
+		},
+		{
+			testdata: "ioreg_intel.plist",
+			expectedRow: map[string]string{
+				"name":                 "bq20z451",
+				"manufacturer":         "SMP",
+				"model":                "",
+				"serial_number":        "D86123456789ABCDE",
+				"chemistry":            "",
+				"cycle_count":          "1021",
+				"design_capacity":      "6669",
+				"full_charge_capacity": "4380",
+				"capacity_unit":        "mAh",
+				"health_percent":       "65.7",
+				"condition":            "service_recommended",
+				"source":               "ioreg",
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.testdata, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := os.ReadFile(filepath.Join("testdata", tt.testdata))
+			require.NoError(t, err)
+
+			batteries, err := batteriesFromIoreg(output)
+			require.NoError(t, err)
+			require.Len(t, batteries, 1)
+			require.Equal(t, tt.expectedRow, batteries[0].toRow())
+		})
+	}
+}
+
+func TestBatteriesFromIoreg_NoBattery(t *testing.T) {
+	t.Parallel()
+
+	batteries, err := batteriesFromIoreg([]byte("\n"))
+	require.NoError(t, err)
+	require.Empty(t, batteries)
+}
@@ -0,0 +1,96 @@
+package batteryhealth
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const powerSupplyDir = "sys/class/power_supply"
+
+// batteriesFromSysfs reads the system's batteries from the power supply class in sysfs, in
+// fsys rooted at /. Batteries in peripherals, such as wireless mice, are skipped. Capacities
+// are converted from the µWh or µAh the kernel reports.
+func batteriesFromSysfs(fsys fs.FS) ([]battery, error) {
+	entries, err := fs.ReadDir(fsys, powerSupplyDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", powerSupplyDir, err)
+	}
+
+	var batteries []battery
+	for _, entry := range entries {
+		supply := powerSupply{fsys: fsys, dir: path.Join(powerSupplyDir, entry.Name())}
+		if supply.text("type") != "Battery" || supply.text("scope") == "Device" {
+			continue
+		}
+
+		b := battery{
+			name:               entry.Name(),
+			manufacturer:       supply.text("manufacturer"),
+			model:              supply.text("model_name"),
+			serialNumber:       supply.text("serial_number"),
+			chemistry:          supply.text("technology"),
+			cycleCount:         supply.int("cycle_count"),
+			designCapacity:     supply.int("energy_full_design"),
+			fullChargeCapacity: supply.int("energy_full"),
+			capacityUnit:       unitMwh,
+			source:             "sysfs",
+		}
+		if b.designCapacity < 0 {
+			b.designCapacity = supply.int("charge_full_design")
+			b.fullChargeCapacity = supply.int("charge_full")
+			b.capacityUnit = unitMah
+		}
+		if b.designCapacity >= 0 {
+			b.designCapacity /= 1000
+		}
+		if b.fullChargeCapacity >= 0 {
+			b.fullChargeCapacity /= 1000
+		}
+		if b.cycleCount == 0 {
+			// Drivers that don't track cycles report 0
+			b.cycleCount = -1
+		}
+
+		switch supply.text("health") {
+		case "", "Good", "Unknown":
+		default:
+			// Dead, Overheat, Unspecified failure, and so on
+			b.failed = true
+		}
+
+		batteries = append(batteries, b)
+	}
+
+	return batteries, nil
+}
+
+type powerSupply struct {
+	fsys fs.FS
+	dir  string
+}
+
+// text returns the contents of one of the power supply's attributes, or "" if it doesn't
+// have that attribute.
+func (p powerSupply) text(attribute string) string {
+	contents, err := fs.ReadFile(p.fsys, path.Join(p.dir, attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+// int returns one of the power supply's numeric attributes, or -1 if it doesn't have that attribute.
+func (p powerSupply) int(attribute string) int64 {
+	i, err := strconv.ParseInt(p.text(attribute), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return i
+}
@@ -0,0 +1,67 @@
+package batteryhealth
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatteriesFromSysfs(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"sys/class/power_supply/AC/type":                     {Data: []byte("Mains\n")},
+		"sys/class/power_supply/AC/online":                   {Data: []byte("1\n")},
+		"sys/class/power_supply/BAT0/type":                   {Data: []byte("Battery\n")},
+		"sys/class/power_supply/BAT0/manufacturer":           {Data: []byte("SMP\n")},
+		"sys/class/power_supply/BAT0/model_name":             {Data: []byte("5B10W51867\n")},
+		"sys/class/power_supply/BAT0/serial_number":          {Data: []byte("  512\n")},
+		"sys/class/power_supply/BAT0/technology":             {Data: []byte("Li-poly\n")},
+		"sys/class/power_supply/BAT0/cycle_count":            {Data: []byte("87\n")},
+		"sys/class/power_supply/BAT0/energy_full_design":     {Data: []byte("57000000\n")},
+		"sys/class/power_supply/BAT0/energy_full":            {Data: []byte("53580000\n")},
+		"sys/class/power_supply/BAT1/type":                   {Data: []byte("Battery\n")},
+		"sys/class/power_supply/BAT1/cycle_count":            {Data: []byte("0\n")},
+		"sys/class/power_supply/BAT1/charge_full_design":     {Data: []byte("4000000\n")},
+		"sys/class/power_supply/BAT1/charge_full":            {Data: []byte("3900000\n")},
+		"sys/class/power_supply/BAT1/health":                 {Data: []byte("Dead\n")},
+		"sys/class/power_supply/hidpp_battery_0/type":        {Data: []byte("Battery\n")},
+		"sys/class/power_supply/hidpp_battery_0/scope":       {Data: []byte("Device\n")},
+		"sys/class/power_supply/hidpp_battery_0/model_name":  {Data: []byte("MX Master 3\n")},
+		"sys/class/power_supply/hidpp_battery_0/cycle_count": {Data: []byte("4\n")},
+	}
+
+	batteries, err := batteriesFromSysfs(fsys)
+	require.NoError(t, err)
+	require.Len(t, batteries, 2)
+
+	require.Equal(t, map[string]string{
+		"name":                 "BAT0",
+		"manufacturer":         "SMP",
+		"model":                "5B10W51867",
+		"serial_number":        "512",
+		"chemistry":            "Li-poly",
+		"cycle_count":          "87",
+		"design_capacity":      "57000",
+		"full_charge_capacity": "53580",
+		"capacity_unit":        "mWh",
+		"health_percent":       "94.0",
+		"condition":            "normal",
+		"source":               "sysfs",
+	}, batteries[0].toRow())
+
+	bat1 := batteries[1].toRow()
+	require.Equal(t, "4000", bat1["design_capacity"])
+	require.Equal(t, "mAh", bat1["capacity_unit"])
+	require.Equal(t, "", bat1["cycle_count"])
+	require.Equal(t, "service_recommended", bat1["condition"], "a dead battery should need service regardless of capacity")
+}
+
+func TestBatteriesFromSysfs_NoPowerSupplies(t *testing.T) {
+	t.Parallel()
+
+	batteries, err := batteriesFromSysfs(fstest.MapFS{})
+	require.NoError(t, err)
+	require.Empty(t, batteries)
+}
@@ -0,0 +1,52 @@
+//go:build darwin
+// +build darwin
+
+package batteryhealth
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	output, err := tablehelpers.RunSimple(ctx, t.slogger, 30, allowedcmd.Ioreg, []string{"-r", "-a", "-c", "AppleSmartBattery"})
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure running ioreg",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	batteries, err := batteriesFromIoreg(output)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure parsing ioreg output",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	results := make([]map[string]string, len(batteries))
+	for i, b := range batteries {
+		results[i] = b.toRow()
+	}
+
+	return results, nil
+}
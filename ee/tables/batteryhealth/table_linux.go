@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package batteryhealth
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	batteries, err := batteriesFromSysfs(os.DirFS("/"))
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure reading batteries from sysfs",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	results := make([]map[string]string, len(batteries))
+	for i, b := range batteries {
+		results[i] = b.toRow()
+	}
+
+	return results, nil
+}
@@ -0,0 +1,75 @@
+//go:build windows
+// +build windows
+
+package batteryhealth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	report, err := t.batteryReport(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure generating battery report",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	batteries, err := batteriesFromBatteryReport(report)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure parsing battery report",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	results := make([]map[string]string, len(batteries))
+	for i, b := range batteries {
+		results[i] = b.toRow()
+	}
+
+	return results, nil
+}
+
+// batteryReport generates the battery report, which powercfg can only write to a file.
+// See: https://learn.microsoft.com/en-us/windows-hardware/design/device-experiences/powercfg-command-line-options#option_batteryreport
+func (t *Table) batteryReport(ctx context.Context) ([]byte, error) {
+	dir, err := agent.MkdirTemp("launcher-battery-report")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	reportPath := filepath.Join(dir, "battery-report.xml")
+
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 30, allowedcmd.Powercfg, []string{"/batteryreport", "/xml", "/output", reportPath}, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("running powercfg: %w: %s", err, stderr.String())
+	}
+
+	return os.ReadFile(reportPath)
+}
@@ -0,0 +1,69 @@
+package cloudsync
+
+import (
+	"strings"
+)
+
+// parseCloudStorageName returns the account a sync root in ~/Library/CloudStorage belongs to,
+// from its name. Since macOS 12.3, sync clients use File Provider, which names sync roots after
+// the client and account:
+//
+//	OneDrive-Personal            a personal OneDrive account
+//	OneDrive-<organization>      a business OneDrive account, by the organization's name
+//	GoogleDrive-<email>          a Google account
+//	Dropbox, Dropbox-<team>      a Dropbox account
+//
+// It returns false for sync roots of other clients.
+func parseCloudStorageName(name string) (syncAccount, bool) {
+	client, account, _ := strings.Cut(name, "-")
+
+	switch client {
+	case "OneDrive":
+		if account == "" {
+			return syncAccount{}, false
+		}
+		accountType := accountTypeBusiness
+		if account == "Personal" {
+			accountType = accountTypePersonal
+		}
+		return syncAccount{
+			client:      clientOneDrive,
+			account:     account,
+			accountType: accountType,
+			source:      sourceCloudStorage,
+		}, true
+
+	case "GoogleDrive":
+		domain := emailDomain(account)
+		if domain == "" {
+			return syncAccount{}, false
+		}
+		return syncAccount{
+			client:      clientGoogleDrive,
+			account:     account,
+			accountType: accountTypeForDomain(domain),
+			domain:      domain,
+			source:      sourceCloudStorage,
+		}, true
+
+	case "Dropbox":
+		accountType := accountTypeUnknown
+		switch {
+		case account == "Personal":
+			accountType = accountTypePersonal
+		case account != "":
+			accountType = accountTypeBusiness
+		}
+		if account == "" {
+			account = "Dropbox"
+		}
+		return syncAccount{
+			client:      clientDropbox,
+			account:     account,
+			accountType: accountType,
+			source:      sourceCloudStorage,
+		}, true
+	}
+
+	return syncAccount{}, false
+}
@@ -0,0 +1,60 @@
+package cloudsync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCloudStorageName(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		expected syncAccount
+		ok       bool
+	}{
+		{
+			name:     "OneDrive-Personal",
+			expected: syncAccount{client: clientOneDrive, account: "Personal", accountType: accountTypePersonal, source: sourceCloudStorage},
+			ok:       true,
+		},
+		{
+			name:     "OneDrive-Contoso Ltd",
+			expected: syncAccount{client: clientOneDrive, account: "Contoso Ltd", accountType: accountTypeBusiness, source: sourceCloudStorage},
+			ok:       true,
+		},
+		{
+			name:     "GoogleDrive-alice@corp.example.com",
+			expected: syncAccount{client: clientGoogleDrive, account: "alice@corp.example.com", accountType: accountTypeBusiness, domain: "corp.example.com", source: sourceCloudStorage},
+			ok:       true,
+		},
+		{
+			name:     "GoogleDrive-alice@gmail.com",
+			expected: syncAccount{client: clientGoogleDrive, account: "alice@gmail.com", accountType: accountTypePersonal, domain: "gmail.com", source: sourceCloudStorage},
+			ok:       true,
+		},
+		{
+			name:     "Dropbox",
+			expected: syncAccount{client: clientDropbox, account: "Dropbox", accountType: accountTypeUnknown, source: sourceCloudStorage},
+			ok:       true,
+		},
+		{
+			name:     "Dropbox-Contoso",
+			expected: syncAccount{client: clientDropbox, account: "Contoso", accountType: accountTypeBusiness, source: sourceCloudStorage},
+			ok:       true,
+		},
+		{name: "Box-Box"},
+		{name: "OneDrive"},
+		{name: "GoogleDrive-notanemail"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			account, ok := parseCloudStorageName(tt.name)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, account)
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Package cloudsync provides tables reporting the cloud file sync clients -- OneDrive, Dropbox,
+// and Google Drive -- each user has set up: the kind of account they're signed in to and its
+// domain, where it syncs to, and which of the user's folders, like Desktop and Documents, it
+// backs up. Data governance uses these to find machines syncing corporate folders to personal
+// accounts.
+package cloudsync
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/kolide/launcher/ee/hostroot"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+// Clients
+const (
+	clientOneDrive    = "onedrive"
+	clientDropbox     = "dropbox"
+	clientGoogleDrive = "google_drive"
+)
+
+var tableNames = map[string]string{
+	clientOneDrive:    "kolide_onedrive",
+	clientDropbox:     "kolide_dropbox",
+	clientGoogleDrive: "kolide_google_drive",
+}
+
+// Account types
+const (
+	accountTypeBusiness = "business"
+	accountTypePersonal = "personal"
+	accountTypeUnknown  = "unknown"
+)
+
+// Sources of accounts
+const (
+	sourceRegistry     = "registry"      // Windows: the client's settings in the user's registry hive
+	sourceDropboxInfo  = "info_json"     // Dropbox's info.json
+	sourceCloudStorage = "cloud_storage" // macOS: the File Provider sync roots in ~/Library/CloudStorage
+	sourceDriveFS      = "drivefs"       // Google Drive's DriveFS data directory
+)
+
+// Known folders, which clients can back up by moving or redirecting them into their sync root
+const (
+	folderDesktop   = "desktop"
+	folderDocuments = "documents"
+	folderDownloads = "downloads"
+	folderPictures  = "pictures"
+	folderMusic     = "music"
+	folderVideos    = "videos"
+)
+
+// personalDomains are the domains of consumer accounts. Accounts in other domains are assumed to
+// belong to an organization.
+var personalDomains = []string{
+	"gmail.com",
+	"googlemail.com",
+	"outlook.com",
+	"hotmail.com",
+	"live.com",
+	"msn.com",
+	"icloud.com",
+	"me.com",
+	"yahoo.com",
+}
+
+// syncAccount is an account a user has set up in a sync client. An account without a name means
+// the client is set up for the user, but not signed in.
+type syncAccount struct {
+	client        string
+	account       string
+	accountType   string
+	domain        string
+	syncRoot      string
+	backupFolders []string
+	source        string
+}
+
+// user is a user whose sync clients we look for.
+type user struct {
+	name string
+	home string
+	sid  string // Windows only
+}
+
+type Table struct {
+	slogger   *slog.Logger
+	client    string
+	collector *collector
+}
+
+// collector finds sync client accounts on the filesystem under rootDir.
+type collector struct {
+	rootDir string
+}
+
+// TablePlugins returns the tables for all supported sync clients.
+func TablePlugins(slogger *slog.Logger) []osquery.OsqueryPlugin {
+	clients := []string{clientOneDrive, clientDropbox, clientGoogleDrive}
+	plugins := make([]osquery.OsqueryPlugin, 0, len(clients))
+	for _, c := range clients {
+		plugins = append(plugins, tablePlugin(slogger, c))
+	}
+	return plugins
+}
+
+func tablePlugin(slogger *slog.Logger, client string) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.IntegerColumn("signed_in"),
+		table.TextColumn("account"),
+		table.TextColumn("account_type"),
+		table.TextColumn("account_domain"),
+		table.TextColumn("sync_root"),
+		table.TextColumn("backup_folders"),
+		table.TextColumn("source"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", tableNames[client]),
+		client:    client,
+		collector: &collector{rootDir: hostroot.Path("/")},
+	}
+
+	return table.NewPlugin(tableNames[client], columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	users, err := t.collector.users(usernames)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list users",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	for _, u := range users {
+		accounts := t.collector.accounts(ctx, t.slogger, u)
+		withBackupFolders(accounts, t.collector.knownFolders(ctx, t.slogger, u))
+
+		for _, a := range accounts {
+			if a.client != t.client {
+				continue
+			}
+
+			signedIn := "0"
+			if a.account != "" {
+				signedIn = "1"
+			}
+
+			results = append(results, map[string]string{
+				"username":       u.name,
+				"signed_in":      signedIn,
+				"account":        a.account,
+				"account_type":   a.accountType,
+				"account_domain": a.domain,
+				"sync_root":      a.syncRoot,
+				"backup_folders": strings.Join(a.backupFolders, ","),
+				"source":         a.source,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// emailDomain returns the domain of the email address, or an empty string if it isn't one.
+func emailDomain(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 || i == len(email)-1 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[i+1:]))
+}
+
+// accountTypeForDomain guesses whether an account in the domain is a personal or business one.
+func accountTypeForDomain(domain string) string {
+	switch {
+	case domain == "":
+		return accountTypeUnknown
+	case slices.Contains(personalDomains, domain):
+		return accountTypePersonal
+	default:
+		return accountTypeBusiness
+	}
+}
+
+// withBackupFolders sets the known folders each account backs up: those that have been moved or
+// redirected into its sync root.
+func withBackupFolders(accounts []syncAccount, knownFolders map[string]string) {
+	for i := range accounts {
+		if accounts[i].syncRoot == "" {
+			continue
+		}
+		for name, location := range knownFolders {
+			if pathWithin(location, accounts[i].syncRoot) {
+				accounts[i].backupFolders = append(accounts[i].backupFolders, name)
+			}
+		}
+		slices.Sort(accounts[i].backupFolders)
+	}
+}
+
+// pathWithin reports whether location is root, or inside it.
+func pathWithin(location, root string) bool {
+	if location == "" || root == "" {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		location, root = strings.ToLower(location), strings.ToLower(root)
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(location))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func samePath(a, b string) bool {
+	return pathWithin(a, b) && pathWithin(b, a)
+}
+
+// dedupeAccounts drops accounts whose client and sync root an earlier account already has, so
+// that an account found in more than one place is reported once, from the more detailed source.
+func dedupeAccounts(accounts []syncAccount) []syncAccount {
+	var results []syncAccount
+	for _, a := range accounts {
+		if a.syncRoot != "" && slices.ContainsFunc(results, func(r syncAccount) bool {
+			return r.client == a.client && samePath(a.syncRoot, r.syncRoot)
+		}) {
+			continue
+		}
+		results = append(results, a)
+	}
+	return results
+}
+
+// withPresence adds an account without a name for each client that's set up for the user, but
+// has no signed-in accounts.
+func withPresence(accounts []syncAccount, present map[string]string) []syncAccount {
+	for _, client := range []string{clientOneDrive, clientDropbox, clientGoogleDrive} {
+		source, ok := present[client]
+		if !ok {
+			continue
+		}
+		if hasClient(accounts, client) {
+			continue
+		}
+		accounts = append(accounts, syncAccount{client: client, source: source})
+	}
+	return accounts
+}
+
+func hasClient(accounts []syncAccount, client string) bool {
+	return slices.ContainsFunc(accounts, func(a syncAccount) bool { return a.client == client })
+}
@@ -0,0 +1,71 @@
+package cloudsync
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountTypeForDomain(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "example.com", emailDomain("alice@Example.com"))
+	require.Equal(t, "", emailDomain("alice"))
+	require.Equal(t, "", emailDomain("alice@"))
+
+	require.Equal(t, accountTypePersonal, accountTypeForDomain(emailDomain("alice@gmail.com")))
+	require.Equal(t, accountTypeBusiness, accountTypeForDomain(emailDomain("alice@corp.example.com")))
+	require.Equal(t, accountTypeUnknown, accountTypeForDomain(""))
+}
+
+func TestWithBackupFolders(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/Users/alice/Library/CloudStorage/OneDrive-Contoso")
+	accounts := []syncAccount{
+		{client: clientOneDrive, account: "Contoso", syncRoot: root},
+		{client: clientDropbox, account: "personal", syncRoot: filepath.FromSlash("/Users/alice/Dropbox")},
+		{client: clientGoogleDrive, account: "123"},
+	}
+
+	withBackupFolders(accounts, map[string]string{
+		folderDesktop:   filepath.Join(root, "Desktop"),
+		folderDocuments: filepath.Join(root, "Documents"),
+		folderDownloads: filepath.FromSlash("/Users/alice/Downloads"),
+		// Shares a prefix with the sync root, but isn't in it
+		folderPictures: root + " (Archive)",
+	})
+
+	require.Equal(t, []string{folderDesktop, folderDocuments}, accounts[0].backupFolders)
+	require.Empty(t, accounts[1].backupFolders)
+	require.Empty(t, accounts[2].backupFolders)
+}
+
+func TestDedupeAccounts(t *testing.T) {
+	t.Parallel()
+
+	accounts := dedupeAccounts([]syncAccount{
+		{client: clientDropbox, account: "personal", syncRoot: "/Users/alice/Library/CloudStorage/Dropbox", source: sourceDropboxInfo},
+		{client: clientDropbox, account: "Dropbox", syncRoot: "/Users/alice/Library/CloudStorage/Dropbox/", source: sourceCloudStorage},
+		{client: clientGoogleDrive, account: "1"},
+		{client: clientGoogleDrive, account: "2"},
+	})
+
+	require.Len(t, accounts, 3)
+	require.Equal(t, sourceDropboxInfo, accounts[0].source, "the first source should be kept")
+}
+
+func TestWithPresence(t *testing.T) {
+	t.Parallel()
+
+	accounts := withPresence(
+		[]syncAccount{{client: clientDropbox, account: "personal"}},
+		map[string]string{clientDropbox: sourceDropboxInfo, clientOneDrive: sourceRegistry},
+	)
+
+	require.Equal(t, []syncAccount{
+		{client: clientDropbox, account: "personal"},
+		{client: clientOneDrive, source: sourceRegistry},
+	}, accounts)
+}
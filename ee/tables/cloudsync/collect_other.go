@@ -0,0 +1,165 @@
+//go:build !windows
+// +build !windows
+
+package cloudsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// knownFolderPaths returns where the known folders are, relative to the user's home directory
+func knownFolderPaths() map[string]string {
+	videos := "Videos"
+	if runtime.GOOS == "darwin" {
+		videos = "Movies"
+	}
+
+	return map[string]string{
+		folderDesktop:   "Desktop",
+		folderDocuments: "Documents",
+		folderDownloads: "Downloads",
+		folderPictures:  "Pictures",
+		folderMusic:     "Music",
+		folderVideos:    videos,
+	}
+}
+
+// homeRoot is the directory user home directories are in.
+func homeRoot() string {
+	if runtime.GOOS == "darwin" {
+		return "Users"
+	}
+	return "home"
+}
+
+// users returns the users with home directories, or the given users.
+func (c *collector) users(usernames []string) ([]user, error) {
+	if len(usernames) == 0 {
+		entries, err := os.ReadDir(filepath.Join(c.rootDir, homeRoot()))
+		if err != nil {
+			return nil, fmt.Errorf("reading user directories: %w", err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() || e.Name() == "Shared" || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			usernames = append(usernames, e.Name())
+		}
+	}
+
+	users := make([]user, len(usernames))
+	for i, username := range usernames {
+		users[i] = user{name: username, home: filepath.Join(c.rootDir, homeRoot(), username)}
+	}
+	return users, nil
+}
+
+// accounts returns the user's sync client accounts. Dropbox's info.json is read first, as it
+// says more about each account than the File Provider sync roots in ~/Library/CloudStorage do.
+func (c *collector) accounts(ctx context.Context, slogger *slog.Logger, u user) []syncAccount {
+	var accounts []syncAccount
+	present := make(map[string]string)
+
+	dropboxAccounts, err := c.dropboxAccounts(u)
+	switch {
+	case err == nil:
+		accounts = append(accounts, dropboxAccounts...)
+	case !errors.Is(err, fs.ErrNotExist):
+		slogger.Log(ctx, slog.LevelInfo,
+			"could not read dropbox info",
+			"username", u.name,
+			"err", err,
+		)
+	}
+	if _, err := os.Stat(filepath.Join(u.home, ".dropbox")); err == nil {
+		present[clientDropbox] = sourceDropboxInfo
+	}
+
+	cloudStorageDir := filepath.Join(u.home, "Library", "CloudStorage")
+	if entries, err := os.ReadDir(cloudStorageDir); err == nil {
+		for _, e := range entries {
+			account, ok := parseCloudStorageName(e.Name())
+			if !ok {
+				continue
+			}
+			account.syncRoot = c.displayPath(filepath.Join(cloudStorageDir, e.Name()))
+			accounts = append(accounts, account)
+		}
+	}
+
+	// Without a File Provider sync root, Google Drive may still be signed in, e.g. when it mounts
+	// its drive as a volume instead. We can only tell which accounts by their ids.
+	driveFSDir := filepath.Join(u.home, "Library", "Application Support", "Google", "DriveFS")
+	if ids, err := driveFSAccountIds(driveFSDir); err == nil {
+		present[clientGoogleDrive] = sourceDriveFS
+		if !hasClient(accounts, clientGoogleDrive) {
+			for _, id := range ids {
+				accounts = append(accounts, syncAccount{
+					client:      clientGoogleDrive,
+					account:     id,
+					accountType: accountTypeUnknown,
+					source:      sourceDriveFS,
+				})
+			}
+		}
+	}
+
+	return withPresence(dedupeAccounts(accounts), present)
+}
+
+// dropboxAccounts reads the user's accounts from Dropbox's info.json.
+func (c *collector) dropboxAccounts(u user) ([]syncAccount, error) {
+	fh, err := os.Open(filepath.Join(u.home, ".dropbox", "info.json"))
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	return parseDropboxInfo(fh)
+}
+
+// knownFolders returns where each of the user's known folders is. Clients that back them up move
+// them into their sync root, and leave a symlink behind, so symlinks are followed. Paths are as
+// they are on the host, to compare with sync roots.
+func (c *collector) knownFolders(_ context.Context, _ *slog.Logger, u user) map[string]string {
+	folders := make(map[string]string)
+	for name, rel := range knownFolderPaths() {
+		location := filepath.Join(u.home, rel)
+		fi, err := os.Lstat(location)
+		if err != nil {
+			continue
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			folders[name] = c.displayPath(location)
+			continue
+		}
+
+		// The link's target is a path on the host, which may not be where rootDir is
+		target, err := os.Readlink(location)
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(c.displayPath(filepath.Dir(location)), target)
+		}
+		folders[name] = filepath.Clean(target)
+	}
+	return folders
+}
+
+// displayPath returns the path as it is on the host, without rootDir.
+func (c *collector) displayPath(path string) string {
+	rel, err := filepath.Rel(c.rootDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return "/" + filepath.ToSlash(rel)
+}
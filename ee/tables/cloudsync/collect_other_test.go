@@ -0,0 +1,105 @@
+//go:build !windows
+// +build !windows
+
+package cloudsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	home := filepath.Join(rootDir, homeRoot(), "alice")
+	cloudStorage := filepath.Join(home, "Library", "CloudStorage")
+	oneDriveRoot := filepath.Join(cloudStorage, "OneDrive-Personal")
+
+	for _, dir := range []string{
+		filepath.Join(home, ".dropbox"),
+		filepath.Join(oneDriveRoot, "Desktop"),
+		filepath.Join(cloudStorage, "GoogleDrive-alice@corp.example.com"),
+		filepath.Join(home, "Documents"),
+		filepath.Join(rootDir, homeRoot(), "bob"),
+	} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".dropbox", "info.json"),
+		[]byte(`{"business": {"path": "/`+homeRoot()+`/alice/Dropbox (Contoso)", "is_team": true}}`), 0644))
+
+	// Desktop has been moved into OneDrive, leaving a symlink behind, with the target as it is on the host
+	require.NoError(t, os.Symlink("/"+homeRoot()+"/alice/Library/CloudStorage/OneDrive-Personal/Desktop", filepath.Join(home, "Desktop")))
+
+	for _, tt := range []struct {
+		client   string
+		expected []map[string]string
+	}{
+		{
+			client: clientOneDrive,
+			expected: []map[string]string{{
+				"username":       "alice",
+				"signed_in":      "1",
+				"account":        "Personal",
+				"account_type":   accountTypePersonal,
+				"account_domain": "",
+				"sync_root":      "/" + homeRoot() + "/alice/Library/CloudStorage/OneDrive-Personal",
+				"backup_folders": folderDesktop,
+				"source":         sourceCloudStorage,
+			}},
+		},
+		{
+			client: clientDropbox,
+			expected: []map[string]string{{
+				"username":       "alice",
+				"signed_in":      "1",
+				"account":        "business",
+				"account_type":   accountTypeBusiness,
+				"account_domain": "",
+				"sync_root":      "/" + homeRoot() + "/alice/Dropbox (Contoso)",
+				"backup_folders": "",
+				"source":         sourceDropboxInfo,
+			}},
+		},
+		{
+			client: clientGoogleDrive,
+			expected: []map[string]string{{
+				"username":       "alice",
+				"signed_in":      "1",
+				"account":        "alice@corp.example.com",
+				"account_type":   accountTypeBusiness,
+				"account_domain": "corp.example.com",
+				"sync_root":      "/" + homeRoot() + "/alice/Library/CloudStorage/GoogleDrive-alice@corp.example.com",
+				"backup_folders": "",
+				"source":         sourceCloudStorage,
+			}},
+		},
+	} {
+		tt := tt
+		t.Run(tt.client, func(t *testing.T) {
+			t.Parallel()
+
+			table := &Table{
+				slogger:   multislogger.NewNopLogger(),
+				client:    tt.client,
+				collector: &collector{rootDir: rootDir},
+			}
+
+			results, err := table.generate(context.TODO(), tablehelpers.MockQueryContext(nil))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, results)
+
+			results, err = table.generate(context.TODO(), tablehelpers.MockQueryContext(map[string][]string{
+				"username": {"bob"},
+			}))
+			require.NoError(t, err)
+			require.Empty(t, results)
+		})
+	}
+}
@@ -0,0 +1,302 @@
+//go:build windows
+// +build windows
+
+package cloudsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	profileListKey      = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList`
+	oneDriveKey         = `Software\Microsoft\OneDrive`
+	oneDriveAccountsKey = `Software\Microsoft\OneDrive\Accounts`
+	driveFSKey          = `Software\Google\DriveFS`
+	userShellFoldersKey = `Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`
+)
+
+// knownFolderValues are the User Shell Folders values that say where each known folder is. Known
+// Folder Move, and Dropbox's backup, redirect them into the sync root.
+var knownFolderValues = map[string]string{
+	folderDesktop:   "Desktop",
+	folderDocuments: "Personal",
+	folderDownloads: "{374DE290-123F-4565-9164-39C4925E467B}",
+	folderPictures:  "My Pictures",
+	folderMusic:     "My Music",
+	folderVideos:    "My Video",
+}
+
+// users returns the users with a loaded registry hive -- that is, each logged-in user -- as their
+// clients' settings are in it. If usernames is non-empty, only those users are returned.
+func (c *collector) users(usernames []string) ([]user, error) {
+	usersKey, err := registry.OpenKey(registry.USERS, "", registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil, fmt.Errorf("opening HKEY_USERS: %w", err)
+	}
+	defer usersKey.Close()
+
+	sids, err := usersKey.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("listing HKEY_USERS: %w", err)
+	}
+
+	var users []user
+	for _, sid := range sids {
+		// Only local and domain user accounts
+		if !strings.HasPrefix(sid, "S-1-5-21-") || strings.HasSuffix(sid, "_Classes") {
+			continue
+		}
+
+		username, err := accountName(sid)
+		if err != nil {
+			continue
+		}
+		if len(usernames) > 0 && !slices.Contains(usernames, username) {
+			continue
+		}
+
+		home, err := profilePath(sid)
+		if err != nil {
+			continue
+		}
+
+		users = append(users, user{name: username, home: home, sid: sid})
+	}
+
+	return users, nil
+}
+
+// accounts returns the user's sync client accounts.
+func (c *collector) accounts(ctx context.Context, slogger *slog.Logger, u user) []syncAccount {
+	var accounts []syncAccount
+	present := make(map[string]string)
+
+	if key, err := registry.OpenKey(registry.USERS, u.sid+`\`+oneDriveKey, registry.QUERY_VALUE); err == nil {
+		key.Close()
+		present[clientOneDrive] = sourceRegistry
+	}
+	oneDriveAccounts, err := oneDriveAccounts(u.sid)
+	if err != nil {
+		slogger.Log(ctx, slog.LevelInfo,
+			"could not read onedrive accounts",
+			"username", u.name,
+			"err", err,
+		)
+	}
+	accounts = append(accounts, oneDriveAccounts...)
+
+	for _, dir := range []string{
+		filepath.Join(u.home, "AppData", "Roaming", "Dropbox"),
+		filepath.Join(u.home, "AppData", "Local", "Dropbox"),
+	} {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		present[clientDropbox] = sourceDropboxInfo
+
+		dropboxAccounts, err := readDropboxInfo(filepath.Join(dir, "info.json"))
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				slogger.Log(ctx, slog.LevelInfo,
+					"could not read dropbox info",
+					"username", u.name,
+					"err", err,
+				)
+			}
+			continue
+		}
+		accounts = append(accounts, dropboxAccounts...)
+		break
+	}
+
+	// Google Drive mounts each account's drive as a drive letter; the DriveFS directory has a
+	// subdirectory for each account, named by its id. Neither says which Google account it is.
+	if ids, err := driveFSAccountIds(filepath.Join(u.home, "AppData", "Local", "Google", "DriveFS")); err == nil {
+		present[clientGoogleDrive] = sourceDriveFS
+
+		mountPoints := driveFSMountPoints(u.sid)
+		for _, id := range ids {
+			accounts = append(accounts, syncAccount{
+				client:      clientGoogleDrive,
+				account:     id,
+				accountType: accountTypeUnknown,
+				syncRoot:    mountPoints[id],
+				source:      sourceDriveFS,
+			})
+		}
+	}
+
+	return withPresence(dedupeAccounts(accounts), present)
+}
+
+// oneDriveAccounts reads the user's OneDrive accounts. Each is a subkey of the Accounts key, named
+// `Personal` for a personal account, and `Business1`, `Business2`, etc. for business accounts.
+func oneDriveAccounts(sid string) ([]syncAccount, error) {
+	accountsKey, err := registry.OpenKey(registry.USERS, sid+`\`+oneDriveAccountsKey, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening onedrive accounts key: %w", err)
+	}
+	defer accountsKey.Close()
+
+	names, err := accountsKey.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("listing onedrive accounts: %w", err)
+	}
+	slices.Sort(names)
+
+	var accounts []syncAccount
+	for _, name := range names {
+		key, err := registry.OpenKey(accountsKey, name, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+
+		email, _, _ := key.GetStringValue("UserEmail")
+		syncRoot, _, _ := key.GetStringValue("UserFolder")
+		business, _, _ := key.GetIntegerValue("Business")
+		key.Close()
+
+		// Accounts that were signed out leave their key behind, without these
+		if email == "" && syncRoot == "" {
+			continue
+		}
+
+		domain := emailDomain(email)
+		accountType := accountTypeForDomain(domain)
+		switch {
+		case business == 1:
+			accountType = accountTypeBusiness
+		case name == "Personal":
+			accountType = accountTypePersonal
+		}
+
+		accounts = append(accounts, syncAccount{
+			client:      clientOneDrive,
+			account:     name,
+			accountType: accountType,
+			domain:      domain,
+			syncRoot:    syncRoot,
+			source:      sourceRegistry,
+		})
+	}
+
+	return accounts, nil
+}
+
+func readDropboxInfo(path string) ([]syncAccount, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	return parseDropboxInfo(fh)
+}
+
+// driveFSMountPoints returns where Google Drive mounts each account's drive, by account id.
+func driveFSMountPoints(sid string) map[string]string {
+	key, err := registry.OpenKey(registry.USERS, sid+`\`+driveFSKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil
+	}
+	defer key.Close()
+
+	raw, _, err := key.GetStringValue("PerAccountPreferences")
+	if err != nil {
+		return nil
+	}
+
+	mountPoints, err := parseDriveFSMountPoints(raw)
+	if err != nil {
+		return nil
+	}
+	return mountPoints
+}
+
+// knownFolders returns where each of the user's known folders is, from User Shell Folders.
+func (c *collector) knownFolders(ctx context.Context, slogger *slog.Logger, u user) map[string]string {
+	key, err := registry.OpenKey(registry.USERS, u.sid+`\`+userShellFoldersKey, registry.QUERY_VALUE)
+	if err != nil {
+		slogger.Log(ctx, slog.LevelInfo,
+			"could not open user shell folders",
+			"username", u.name,
+			"err", err,
+		)
+		return nil
+	}
+	defer key.Close()
+
+	folders := make(map[string]string)
+	for name, valueName := range knownFolderValues {
+		location, _, err := key.GetStringValue(valueName)
+		if err != nil || location == "" {
+			continue
+		}
+		folders[name] = expandProfilePath(location, u.home)
+	}
+	return folders
+}
+
+// expandProfilePath expands %USERPROFILE% in a User Shell Folders path to the user's profile
+// directory. launcher's own environment is not the user's, so that's done before expanding the
+// rest, like %SystemDrive%.
+func expandProfilePath(location string, home string) string {
+	const userProfile = "%USERPROFILE%"
+	if len(location) >= len(userProfile) && strings.EqualFold(location[:len(userProfile)], userProfile) {
+		location = home + location[len(userProfile):]
+	}
+
+	expanded, err := registry.ExpandString(location)
+	if err != nil {
+		return location
+	}
+	return expanded
+}
+
+// profilePath returns the user's profile directory.
+func profilePath(sid string) (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, profileListKey+`\`+sid, registry.QUERY_VALUE)
+	if err != nil {
+		return "", fmt.Errorf("opening profile list key: %w", err)
+	}
+	defer key.Close()
+
+	path, _, err := key.GetStringValue("ProfileImagePath")
+	if err != nil {
+		return "", fmt.Errorf("reading profile image path: %w", err)
+	}
+
+	expanded, err := registry.ExpandString(path)
+	if err != nil {
+		return path, nil
+	}
+	return expanded, nil
+}
+
+func accountName(sid string) (string, error) {
+	s, err := windows.StringToSid(sid)
+	if err != nil {
+		return "", fmt.Errorf("parsing sid: %w", err)
+	}
+
+	account, _, _, err := s.LookupAccount("")
+	if err != nil {
+		return "", fmt.Errorf("looking up account: %w", err)
+	}
+
+	return account, nil
+}
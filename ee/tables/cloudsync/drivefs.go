@@ -0,0 +1,62 @@
+package cloudsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// driveFSAccountIds returns the ids of the Google accounts signed in to Google Drive, from the
+// DriveFS data directory, where each has a subdirectory named by its numeric id.
+func driveFSAccountIds(driveFSDir string) ([]string, error) {
+	entries, err := os.ReadDir(driveFSDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading DriveFS directory: %w", err)
+	}
+
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "" && strings.Trim(e.Name(), "0123456789") == "" {
+			ids = append(ids, e.Name())
+		}
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// driveFSPreferences is Google Drive's PerAccountPreferences registry value on Windows, e.g.
+// `{"per_account_preferences":[{"key":"1234567890","value":{"mount_point_path":"G"}}]}`.
+type driveFSPreferences struct {
+	PerAccountPreferences []struct {
+		Key   string `json:"key"`
+		Value struct {
+			MountPointPath string `json:"mount_point_path"`
+		} `json:"value"`
+	} `json:"per_account_preferences"`
+}
+
+// parseDriveFSMountPoints returns where each account's drive is mounted, by account id. Mount
+// points are drive letters, or paths.
+func parseDriveFSMountPoints(raw string) (map[string]string, error) {
+	var prefs driveFSPreferences
+	if err := json.Unmarshal([]byte(raw), &prefs); err != nil {
+		return nil, fmt.Errorf("decoding DriveFS preferences: %w", err)
+	}
+
+	mountPoints := make(map[string]string)
+	for _, p := range prefs.PerAccountPreferences {
+		mountPoint := strings.TrimSpace(p.Value.MountPointPath)
+		if p.Key == "" || mountPoint == "" {
+			continue
+		}
+		if len(mountPoint) == 1 {
+			mountPoint += `:\`
+		}
+		mountPoints[p.Key] = mountPoint
+	}
+
+	return mountPoints, nil
+}
@@ -0,0 +1,43 @@
+package cloudsync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriveFSAccountIds(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, d := range []string{"118234567890123456789", "cef_cache", "Logs", "104567890123456789012"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, d), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1234"), nil, 0644))
+
+	ids, err := driveFSAccountIds(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"104567890123456789012", "118234567890123456789"}, ids)
+
+	_, err = driveFSAccountIds(filepath.Join(dir, "missing"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestParseDriveFSMountPoints(t *testing.T) {
+	t.Parallel()
+
+	mountPoints, err := parseDriveFSMountPoints(`{"per_account_preferences":[` +
+		`{"key":"118234567890123456789","value":{"mount_point_path":"G"}},` +
+		`{"key":"104567890123456789012","value":{"mount_point_path":"D:\\Google Drive"}},` +
+		`{"key":"100000000000000000000","value":{}}]}`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"118234567890123456789": `G:\`,
+		"104567890123456789012": `D:\Google Drive`,
+	}, mountPoints)
+
+	_, err = parseDriveFSMountPoints(`{`)
+	require.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package cloudsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// dropboxInfo is an account in Dropbox's info.json, which Dropbox writes for integrations to find
+// its sync roots. It's keyed by account type, `personal` or `business`, e.g.
+// `{"personal": {"path": "/Users/alice/Dropbox", "host": 123, "is_team": false, "subscription_type": "Basic"}}`.
+// Dropbox doesn't record the account's email there, so we can't report its domain.
+type dropboxInfo struct {
+	Path             string `json:"path"`
+	IsTeam           bool   `json:"is_team"`
+	SubscriptionType string `json:"subscription_type"`
+}
+
+// parseDropboxInfo reads the accounts in info.json.
+func parseDropboxInfo(r io.Reader) ([]syncAccount, error) {
+	var info map[string]dropboxInfo
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		return nil, fmt.Errorf("decoding dropbox info: %w", err)
+	}
+
+	accounts := make([]syncAccount, 0, len(info))
+	for name, account := range info {
+		accountType := accountTypeUnknown
+		switch {
+		case name == "business" || account.IsTeam:
+			accountType = accountTypeBusiness
+		case name == "personal":
+			accountType = accountTypePersonal
+		}
+
+		accounts = append(accounts, syncAccount{
+			client:      clientDropbox,
+			account:     name,
+			accountType: accountType,
+			syncRoot:    account.Path,
+			source:      sourceDropboxInfo,
+		})
+	}
+
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].account < accounts[j].account })
+	return accounts, nil
+}
@@ -0,0 +1,26 @@
+package cloudsync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDropboxInfo(t *testing.T) {
+	t.Parallel()
+
+	accounts, err := parseDropboxInfo(strings.NewReader(`{
+		"personal": {"path": "/Users/alice/Dropbox (Personal)", "host": 1234, "is_team": false, "subscription_type": "Basic"},
+		"business": {"path": "/Users/alice/Dropbox (Contoso)", "host": 5678, "is_team": true, "subscription_type": "Business"}
+	}`))
+	require.NoError(t, err)
+
+	require.Equal(t, []syncAccount{
+		{client: clientDropbox, account: "business", accountType: accountTypeBusiness, syncRoot: "/Users/alice/Dropbox (Contoso)", source: sourceDropboxInfo},
+		{client: clientDropbox, account: "personal", accountType: accountTypePersonal, syncRoot: "/Users/alice/Dropbox (Personal)", source: sourceDropboxInfo},
+	}, accounts)
+
+	_, err = parseDropboxInfo(strings.NewReader(`not json`))
+	require.Error(t, err)
+}
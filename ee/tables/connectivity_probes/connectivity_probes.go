@@ -0,0 +1,86 @@
+package connectivity_probes
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/control/consumers/probeconsumer"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// TablePlugin reports the results of the connectivity probes the control server has requested
+// since launcher started.
+func TablePlugin() *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("action_id"),
+		table.BigIntColumn("time"),
+		table.TextColumn("host"),
+		table.IntegerColumn("port"),
+		table.TextColumn("protocol"),
+		table.IntegerColumn("tls"),
+		table.TextColumn("status"),
+		table.TextColumn("remote_address"),
+		table.BigIntColumn("latency_ms"),
+		table.BigIntColumn("handshake_ms"),
+		table.TextColumn("tls_version"),
+		table.TextColumn("cipher_suite"),
+		table.IntegerColumn("verified"),
+		table.TextColumn("verify_error"),
+		table.IntegerColumn("fingerprint_pinned"),
+		table.TextColumn("certificate_chain"),
+		table.TextColumn("error"),
+	}
+	return table.NewPlugin("kolide_connectivity_probes", columns, generate)
+}
+
+func generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := []map[string]string{}
+
+	for _, r := range probeconsumer.Recent() {
+		row := map[string]string{
+			"action_id":          r.ActionId,
+			"time":               strconv.FormatInt(r.Time.Unix(), 10),
+			"host":               r.Target.Host,
+			"port":               strconv.Itoa(r.Target.Port),
+			"protocol":           r.Target.Protocol,
+			"tls":                boolToIntString(r.Target.Tls),
+			"status":             r.Status,
+			"remote_address":     r.RemoteAddress,
+			"latency_ms":         strconv.FormatInt(r.LatencyMs, 10),
+			"handshake_ms":       "",
+			"tls_version":        r.TlsVersion,
+			"cipher_suite":       r.CipherSuite,
+			"verified":           "",
+			"verify_error":       r.VerifyError,
+			"fingerprint_pinned": "",
+			"certificate_chain":  "",
+			"error":              r.Error,
+		}
+
+		// TLS details are only meaningful once a handshake has been attempted
+		if r.Target.Tls && (r.Status == probeconsumer.StatusReachable || r.Status == probeconsumer.StatusTlsFailed) {
+			row["handshake_ms"] = strconv.FormatInt(r.HandshakeMs, 10)
+			row["verified"] = boolToIntString(r.Verified)
+		}
+		if r.FingerprintPinned != nil {
+			row["fingerprint_pinned"] = boolToIntString(*r.FingerprintPinned)
+		}
+		if len(r.CertificateChain) > 0 {
+			if chain, err := json.Marshal(r.CertificateChain); err == nil {
+				row["certificate_chain"] = string(chain)
+			}
+		}
+
+		results = append(results, row)
+	}
+
+	return results, nil
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,62 @@
+package consentstatus
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/consent"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_data_collection_consent"
+
+// TablePlugin provides an osquery table of the user's data collection consent under privacy
+// mode: whether consent is needed, the policy version the user was asked about, and the tables
+// kept out of queries until they consent.
+func TablePlugin(slogger *slog.Logger, policyStore, statusStore types.Getter) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("state"),
+		table.TextColumn("policy_version"),
+		table.TextColumn("gated_tables"),
+		table.TextColumn("blocked_tables"),
+		table.BigIntColumn("decided_at"),
+		table.BigIntColumn("last_prompted_at"),
+	}
+
+	return table.NewPlugin(tableName, columns, generate(slogger.With("table", tableName), policyStore, statusStore))
+}
+
+func generate(slogger *slog.Logger, policyStore, statusStore types.Getter) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		status, err := consent.LoadStatus(policyStore, statusStore)
+		if err != nil {
+			slogger.Log(ctx, slog.LevelInfo,
+				"could not load data collection consent status",
+				"err", err,
+			)
+			return nil, nil
+		}
+
+		return []map[string]string{
+			{
+				"state":            status.State,
+				"policy_version":   status.PolicyVersion,
+				"gated_tables":     strings.Join(status.GatedTables, ","),
+				"blocked_tables":   strings.Join(status.BlockedTables(), ","),
+				"decided_at":       unixOrEmpty(status.DecidedAt),
+				"last_prompted_at": unixOrEmpty(status.LastPromptedAt),
+			},
+		}, nil
+	}
+}
+
+func unixOrEmpty(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
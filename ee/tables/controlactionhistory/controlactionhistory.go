@@ -0,0 +1,57 @@
+package controlactionhistory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/control/actionhistory"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_control_action_history"
+
+// TablePlugin provides an osquery table of the actions the control server has taken on this
+// device, and their outcomes, as recorded in launcher's control action history.
+func TablePlugin(store types.Iterator) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.BigIntColumn("time"),
+		table.TextColumn("subsystem"),
+		table.TextColumn("type"),
+		table.TextColumn("action_id"),
+		table.TextColumn("payload_sha256"),
+		table.TextColumn("outcome"),
+		table.TextColumn("error"),
+	}
+
+	return table.NewPlugin(tableName, columns, generate(store))
+}
+
+func generate(store types.Iterator) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		results := make([]map[string]string, 0)
+
+		if store == nil {
+			return results, nil
+		}
+
+		records, err := actionhistory.Records(store)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range records {
+			results = append(results, map[string]string{
+				"time":           strconv.FormatInt(r.Time, 10),
+				"subsystem":      r.Subsystem,
+				"type":           r.Type,
+				"action_id":      r.ActionId,
+				"payload_sha256": r.PayloadSha256,
+				"outcome":        r.Outcome,
+				"error":          r.Error,
+			})
+		}
+
+		return results, nil
+	}
+}
@@ -0,0 +1,50 @@
+package controlactionhistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	require.NoError(t, store.Set([]byte("00000000000000000002"), []byte(`{"time":2,"subsystem":"agent_flags","type":"agent_flags","payload_sha256":"b","outcome":"failed","error":"test error"}`)))
+	require.NoError(t, store.Set([]byte("00000000000000000001"), []byte(`{"time":1,"subsystem":"actions","type":"uninstall","action_id":"abc","payload_sha256":"a","outcome":"completed"}`)))
+	require.NoError(t, store.Set([]byte("00000000000000000003"), []byte(`not json`)))
+
+	rows, err := generate(store)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{
+			"time":           "1",
+			"subsystem":      "actions",
+			"type":           "uninstall",
+			"action_id":      "abc",
+			"payload_sha256": "a",
+			"outcome":        "completed",
+			"error":          "",
+		},
+		{
+			"time":           "2",
+			"subsystem":      "agent_flags",
+			"type":           "agent_flags",
+			"action_id":      "",
+			"payload_sha256": "b",
+			"outcome":        "failed",
+			"error":          "test error",
+		},
+	}, rows)
+}
+
+func TestGenerate_NoStore(t *testing.T) {
+	t.Parallel()
+
+	rows, err := generate(nil)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
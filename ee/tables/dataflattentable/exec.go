@@ -5,7 +5,6 @@ import (
 	"log/slog"
 
 	"os"
-	"strings"
 
 	"github.com/kolide/launcher/ee/allowedcmd"
 	"github.com/kolide/launcher/ee/dataflatten"
@@ -66,7 +65,7 @@ func (t *Table) generateExec(ctx context.Context, queryContext table.QueryContex
 	for _, dataQuery := range tablehelpers.GetConstraints(queryContext, "query", tablehelpers.WithDefaults("*")) {
 		flattenOpts := []dataflatten.FlattenOpts{
 			dataflatten.WithSlogger(t.slogger),
-			dataflatten.WithQuery(strings.Split(dataQuery, "/")),
+			queryFlattenOpt(dataQuery),
 		}
 
 		flattened, err := t.flattenBytesFunc(execBytes, flattenOpts...)
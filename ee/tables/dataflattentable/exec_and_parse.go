@@ -6,7 +6,6 @@ import (
 	"io"
 	"log/slog"
 	"os"
-	"strings"
 
 	"github.com/kolide/launcher/ee/allowedcmd"
 	"github.com/kolide/launcher/ee/dataflatten"
@@ -97,7 +96,7 @@ func (t *execTableV2) generate(ctx context.Context, queryContext table.QueryCont
 	for _, dataQuery := range tablehelpers.GetConstraints(queryContext, "query", tablehelpers.WithDefaults("*")) {
 		flattenOpts := []dataflatten.FlattenOpts{
 			dataflatten.WithSlogger(t.slogger),
-			dataflatten.WithQuery(strings.Split(dataQuery, "/")),
+			queryFlattenOpt(dataQuery),
 		}
 		if t.tabledebug {
 			flattenOpts = append(flattenOpts, dataflatten.WithDebugLogging())
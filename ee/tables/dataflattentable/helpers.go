@@ -1,6 +1,8 @@
 package dataflattentable
 
 import (
+	"strings"
+
 	"github.com/kolide/launcher/ee/dataflatten"
 	"github.com/osquery/osquery-go/plugin/table"
 )
@@ -44,3 +46,14 @@ func Columns(additional ...table.ColumnDefinition) []table.ColumnDefinition {
 
 	return append(columns, additional...)
 }
+
+// queryFlattenOpt returns the flatten option for a `query` constraint. Queries
+// starting with `$` are JSONPath expressions, everything else uses the
+// dataflatten query syntax.
+func queryFlattenOpt(dataQuery string) dataflatten.FlattenOpts {
+	if strings.HasPrefix(dataQuery, "$") {
+		return dataflatten.WithJSONPath(dataQuery)
+	}
+
+	return dataflatten.WithQuery(strings.Split(dataQuery, "/"))
+}
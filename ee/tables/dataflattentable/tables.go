@@ -153,7 +153,7 @@ func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) (
 
 		for _, filePath := range filePaths {
 			for _, dataQuery := range tablehelpers.GetConstraints(queryContext, "query", tablehelpers.WithDefaults("*")) {
-				subresults, err := t.generatePath(ctx, filePath, dataQuery, append(flattenOpts, dataflatten.WithQuery(strings.Split(dataQuery, "/")))...)
+				subresults, err := t.generatePath(ctx, filePath, dataQuery, append(flattenOpts, queryFlattenOpt(dataQuery))...)
 				if err != nil {
 					t.slogger.Log(ctx, slog.LevelInfo,
 						"failed to get data for path",
@@ -170,7 +170,7 @@ func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) (
 
 	for _, rawdata := range requestedRawDatas {
 		for _, dataQuery := range tablehelpers.GetConstraints(queryContext, "query", tablehelpers.WithDefaults("*")) {
-			subresults, err := t.generateRawData(ctx, rawdata, dataQuery, append(flattenOpts, dataflatten.WithQuery(strings.Split(dataQuery, "/")))...)
+			subresults, err := t.generateRawData(ctx, rawdata, dataQuery, append(flattenOpts, queryFlattenOpt(dataQuery))...)
 			if err != nil {
 				t.slogger.Log(ctx, slog.LevelInfo,
 					"failed to generate for raw_data",
@@ -53,6 +53,17 @@ func TestDataFlattenTablePlist_Animals(t *testing.T) {
 				{"fullkey": "users/2/id", "key": "id", "parent": "users/2", "value": "3"},
 			},
 		},
+		{
+			queries: []string{
+				"$.users[?(@.name == 'Bailey Bobcat')].favorites",
+				"$.users[?(@.id > 2)].uuid",
+			},
+			expected: []map[string]string{
+				{"fullkey": "users/1/favorites/0", "key": "0", "parent": "users/1/favorites", "value": "mice"},
+				{"fullkey": "users/1/favorites/1", "key": "1", "parent": "users/1/favorites", "value": "birds"},
+				{"fullkey": "users/2/uuid", "key": "uuid", "parent": "users/2", "value": "ghi789"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,35 @@
+package desktopcapability
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kolide/launcher/ee/desktop/runner"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+func TablePlugin() *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.IntegerColumn("supported"),
+		table.TextColumn("reason"),
+		table.BigIntColumn("last_checked"),
+	}
+	return table.NewPlugin("kolide_desktop_capability", columns, generate)
+}
+
+func generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	capability := runner.InstanceDesktopCapability()
+
+	supported := "0"
+	if capability.Supported {
+		supported = "1"
+	}
+
+	return []map[string]string{
+		{
+			"supported":    supported,
+			"reason":       capability.Reason,
+			"last_checked": fmt.Sprint(capability.LastChecked.Unix()),
+		},
+	}, nil
+}
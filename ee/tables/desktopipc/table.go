@@ -0,0 +1,47 @@
+package desktopipc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/desktop/user/client"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// TablePlugin returns a table of recent connections from launcher to the desktop processes'
+// user servers, for auditing the desktop IPC.
+func TablePlugin() *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.BigIntColumn("time"),
+		table.TextColumn("socket_path"),
+		table.IntegerColumn("expected_pid"),
+		table.IntegerColumn("server_pid"),
+		table.IntegerColumn("verified"),
+		table.TextColumn("error"),
+	}
+	return table.NewPlugin("kolide_desktop_ipc_connections", columns, generate)
+}
+
+func generate(_ context.Context, _ table.QueryContext) ([]map[string]string, error) {
+	records := client.RecentConnections()
+	results := make([]map[string]string, 0, len(records))
+
+	for _, r := range records {
+		verified := "0"
+		if r.Verified {
+			verified = "1"
+		}
+
+		results = append(results, map[string]string{
+			"time":         strconv.FormatInt(r.Time.Unix(), 10),
+			"socket_path":  r.SocketPath,
+			"expected_pid": fmt.Sprint(r.ExpectedPid),
+			"server_pid":   fmt.Sprint(r.ServerPid),
+			"verified":     verified,
+			"error":        r.Err,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,312 @@
+// Package dhcpleases provides a table of the DHCP leases this device currently holds, one per
+// interface, with the options the DHCP server sent -- including DNS servers and the WPAD URL
+// browsers use to discover a proxy -- so that rogue DHCP servers and WPAD attacks can be
+// investigated.
+package dhcpleases
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_dhcp_leases"
+
+// Options we pull out into their own columns
+const (
+	optionSubnetMask    = 1
+	optionRouters       = 3
+	optionDnsServers    = 6
+	optionDomainName    = 15
+	optionLeaseTime     = 51
+	optionServer        = 54
+	optionWpad          = 252
+	optionPad           = 0
+	optionEnd           = 255
+	dhcpOptionsOffset   = 240 // BOOTP header, then the magic cookie
+	bootpYiaddrOffset   = 16
+	bootpHeaderLength   = 236
+	maxOptionValueBytes = 1024
+
+	// infiniteLease is the lease time of a lease that never expires
+	infiniteLease = 0xffffffff
+)
+
+var dhcpMagicCookie = []byte{99, 130, 83, 99}
+
+type optionType int
+
+const (
+	optionTypeHex optionType = iota
+	optionTypeIp
+	optionTypeIps
+	optionTypeUint32
+	optionTypeUint16
+	optionTypeUint8
+	optionTypeText
+)
+
+type optionDefinition struct {
+	name       string
+	optionType optionType
+}
+
+// optionDefinitions names options the way ISC dhclient does, with underscores. Options we
+// don't know are named option_<code>, with their value hex-encoded.
+var optionDefinitions = map[int]optionDefinition{
+	1:   {"subnet_mask", optionTypeIp},
+	2:   {"time_offset", optionTypeUint32},
+	3:   {"routers", optionTypeIps},
+	6:   {"domain_name_servers", optionTypeIps},
+	12:  {"host_name", optionTypeText},
+	15:  {"domain_name", optionTypeText},
+	26:  {"interface_mtu", optionTypeUint16},
+	28:  {"broadcast_address", optionTypeIp},
+	42:  {"ntp_servers", optionTypeIps},
+	44:  {"netbios_name_servers", optionTypeIps},
+	46:  {"netbios_node_type", optionTypeUint8},
+	51:  {"dhcp_lease_time", optionTypeUint32},
+	53:  {"dhcp_message_type", optionTypeUint8},
+	54:  {"dhcp_server_identifier", optionTypeIp},
+	58:  {"dhcp_renewal_time", optionTypeUint32},
+	59:  {"dhcp_rebinding_time", optionTypeUint32},
+	66:  {"tftp_server_name", optionTypeText},
+	67:  {"bootfile_name", optionTypeText},
+	114: {"captive_portal", optionTypeText},
+	252: {"wpad", optionTypeText},
+}
+
+// optionName returns the name of the option with the given code.
+func optionName(code int) string {
+	if definition, ok := optionDefinitions[code]; ok {
+		return definition.name
+	}
+	return fmt.Sprintf("option_%d", code)
+}
+
+// decodeOption formats the raw value of an option for display: addresses are comma-separated,
+// numbers are decimal, and text is as sent. Values that don't fit the option's type are
+// hex-encoded.
+func decodeOption(code int, value []byte) string {
+	if len(value) > maxOptionValueBytes {
+		value = value[:maxOptionValueBytes]
+	}
+
+	switch optionDefinitions[code].optionType {
+	case optionTypeIp, optionTypeIps:
+		if len(value) == 0 || len(value)%4 != 0 {
+			break
+		}
+		ips := make([]string, 0, len(value)/4)
+		for i := 0; i < len(value); i += 4 {
+			ips = append(ips, net.IP(value[i:i+4]).String())
+		}
+		return strings.Join(ips, ",")
+	case optionTypeUint32:
+		if len(value) == 4 {
+			return strconv.FormatUint(uint64(binary.BigEndian.Uint32(value)), 10)
+		}
+	case optionTypeUint16:
+		if len(value) == 2 {
+			return strconv.FormatUint(uint64(binary.BigEndian.Uint16(value)), 10)
+		}
+	case optionTypeUint8:
+		if len(value) == 1 {
+			return strconv.FormatUint(uint64(value[0]), 10)
+		}
+	case optionTypeText:
+		if text := strings.TrimRight(string(value), "\x00"); isPrintable(text) {
+			return text
+		}
+	}
+
+	return hex.EncodeToString(value)
+}
+
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// lease is a DHCP lease held by an interface.
+type lease struct {
+	iface    string
+	address  string
+	obtained time.Time
+	expires  time.Time
+	source   string
+
+	// options are the options the server sent, by name
+	options map[string]string
+}
+
+// parseDhcpPacket decodes a DHCP packet -- the ACK that granted a lease -- into the address it
+// offered and the options it carried.
+func parseDhcpPacket(packet []byte) (string, map[string]string, error) {
+	if len(packet) < dhcpOptionsOffset {
+		return "", nil, fmt.Errorf("packet is %d bytes, too short to be DHCP", len(packet))
+	}
+	if string(packet[bootpHeaderLength:dhcpOptionsOffset]) != string(dhcpMagicCookie) {
+		return "", nil, errors.New("packet is missing the DHCP magic cookie")
+	}
+
+	address := net.IP(packet[bootpYiaddrOffset : bootpYiaddrOffset+4]).String()
+
+	// Options may be split across several instances of the same code, which are concatenated
+	raw := make(map[int][]byte)
+	var order []int
+	for i := dhcpOptionsOffset; i < len(packet); {
+		code := int(packet[i])
+		if code == optionEnd {
+			break
+		}
+		if code == optionPad {
+			i += 1
+			continue
+		}
+		if i+1 >= len(packet) {
+			break
+		}
+		length := int(packet[i+1])
+		if i+2+length > len(packet) {
+			return address, nil, fmt.Errorf("option %d overruns the packet", code)
+		}
+		if _, seen := raw[code]; !seen {
+			order = append(order, code)
+		}
+		raw[code] = append(raw[code], packet[i+2:i+2+length]...)
+		i += 2 + length
+	}
+
+	options := make(map[string]string, len(order))
+	for _, code := range order {
+		options[optionName(code)] = decodeOption(code, raw[code])
+	}
+
+	return address, options, nil
+}
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("interface"),
+		table.TextColumn("address"),
+		table.TextColumn("subnet_mask"),
+		table.TextColumn("routers"),
+		table.TextColumn("server"),
+		table.BigIntColumn("obtained"),
+		table.BigIntColumn("expires"),
+		table.BigIntColumn("lease_time"),
+		table.TextColumn("dns_servers"),
+		table.TextColumn("domain"),
+		table.TextColumn("wpad_url"),
+		table.TextColumn("options"),
+		table.TextColumn("source"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := make([]map[string]string, 0)
+	for _, l := range currentLeases(t.leases(ctx), time.Now()) {
+		results = append(results, l.row())
+	}
+	return results, nil
+}
+
+// currentLeases drops expired leases, which DHCP clients often leave behind for networks the
+// device was on before, and keeps the most recently obtained lease for each interface.
+func currentLeases(leases []lease, now time.Time) []lease {
+	var current []lease
+	byInterface := make(map[string]int)
+	for _, l := range leases {
+		if !l.expires.IsZero() && l.expires.Before(now) {
+			continue
+		}
+
+		i, seen := byInterface[l.iface]
+		if !seen {
+			byInterface[l.iface] = len(current)
+			current = append(current, l)
+			continue
+		}
+		if !l.obtained.Before(current[i].obtained) {
+			current[i] = l
+		}
+	}
+	return current
+}
+
+func (l lease) row() map[string]string {
+	row := map[string]string{
+		"interface":   l.iface,
+		"address":     l.address,
+		"subnet_mask": l.options[optionName(optionSubnetMask)],
+		"routers":     l.options[optionName(optionRouters)],
+		"server":      l.options[optionName(optionServer)],
+		"obtained":    unixOrEmpty(l.obtained),
+		"expires":     unixOrEmpty(l.expires),
+		"lease_time":  l.options[optionName(optionLeaseTime)],
+		"dns_servers": l.options[optionName(optionDnsServers)],
+		"domain":      l.options[optionName(optionDomainName)],
+		"wpad_url":    l.options[optionName(optionWpad)],
+		"options":     "",
+		"source":      l.source,
+	}
+
+	if len(l.options) > 0 {
+		if options, err := json.Marshal(l.options); err == nil {
+			row["options"] = string(options)
+		}
+	}
+
+	return row
+}
+
+// withTimes fills in whichever of the lease's obtained and expiry times is missing, from the
+// other and the lease time.
+func (l lease) withTimes() lease {
+	leaseSeconds, err := strconv.ParseInt(l.options[optionName(optionLeaseTime)], 10, 64)
+	if err != nil || leaseSeconds <= 0 || leaseSeconds == infiniteLease {
+		return l
+	}
+	leaseTime := time.Duration(leaseSeconds) * time.Second
+
+	switch {
+	case l.obtained.IsZero() && !l.expires.IsZero():
+		l.obtained = l.expires.Add(-leaseTime)
+	case l.expires.IsZero() && !l.obtained.IsZero():
+		l.expires = l.obtained.Add(leaseTime)
+	}
+	return l
+}
+
+func unixOrEmpty(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
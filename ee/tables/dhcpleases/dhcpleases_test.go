@@ -0,0 +1,196 @@
+package dhcpleases
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPacket(options ...[]byte) []byte {
+	packet := make([]byte, bootpHeaderLength)
+	copy(packet[bootpYiaddrOffset:], []byte{192, 168, 1, 50})
+	packet = append(packet, dhcpMagicCookie...)
+	for _, option := range options {
+		packet = append(packet, option...)
+	}
+	return append(packet, optionEnd)
+}
+
+func TestParseDhcpPacket(t *testing.T) {
+	t.Parallel()
+
+	wpad := "http://wpad.example.com/wpad.dat"
+	packet := testPacket(
+		[]byte{53, 1, 5},
+		[]byte{54, 4, 192, 168, 1, 1},
+		[]byte{51, 4, 0, 1, 0x51, 0x80},
+		[]byte{0, 0}, // padding
+		[]byte{6, 8, 192, 168, 1, 1, 8, 8, 8, 8},
+		[]byte{15, 11, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'},
+		append([]byte{252, byte(len(wpad))}, wpad...),
+		[]byte{200, 2, 0xde, 0xad},
+	)
+
+	address, options, err := parseDhcpPacket(packet)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.1.50", address)
+	require.Equal(t, map[string]string{
+		"dhcp_message_type":      "5",
+		"dhcp_server_identifier": "192.168.1.1",
+		"dhcp_lease_time":        "86400",
+		"domain_name_servers":    "192.168.1.1,8.8.8.8",
+		"domain_name":            "example.com",
+		"wpad":                   wpad,
+		"option_200":             "dead",
+	}, options)
+
+	_, _, err = parseDhcpPacket(packet[:100])
+	require.Error(t, err)
+
+	_, _, err = parseDhcpPacket(testPacket([]byte{6, 8, 192, 168}))
+	require.Error(t, err, "option overrunning the packet")
+}
+
+func TestDecodeOption(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "0a0b", decodeOption(optionServer, []byte{10, 11}), "wrong length for an address")
+	require.Equal(t, "00ff", decodeOption(optionWpad, []byte{0, 255}), "unprintable text")
+	require.Equal(t, "1500", decodeOption(26, []byte{0x05, 0xdc}))
+}
+
+func TestParseDhclientLeases(t *testing.T) {
+	t.Parallel()
+
+	contents := `default-duid "\000\001\000\001";
+lease {
+  interface "eth0";
+  fixed-address 10.0.0.5;
+  option dhcp-lease-time 3600;
+  expire 1 2024/05/13 00:00:00;
+}
+lease {
+  interface "eth0";
+  fixed-address 192.168.1.50;
+  option subnet-mask 255.255.255.0;
+  option routers 192.168.1.1;
+  option dhcp-lease-time 86400;
+  option domain-name-servers 192.168.1.1,8.8.8.8;
+  option dhcp-server-identifier 192.168.1.1;
+  option domain-name "example.com";
+  option unknown-252 68:74:74:70:3a:2f:2f:77:70:61:64:2f;
+  renew 2 2024/05/14 10:00:00;
+  rebind 2 2024/05/14 20:00:00;
+  expire 3 2024/05/15 00:00:00;
+}
+lease {
+  interface "wlan0";
+  fixed-address 172.16.0.9;
+  expire epoch 1715731200; # Wed May 15 00:00:00 2024
+}
+`
+	leases := parseDhclientLeases(contents, "/var/lib/dhcp/dhclient.leases")
+	require.Len(t, leases, 2)
+
+	eth0 := leases[0]
+	require.Equal(t, "eth0", eth0.iface)
+	require.Equal(t, "192.168.1.50", eth0.address)
+	require.Equal(t, time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), eth0.expires)
+	require.Equal(t, time.Date(2024, 5, 14, 0, 0, 0, 0, time.UTC), eth0.obtained)
+	require.Equal(t, "example.com", eth0.options["domain_name"])
+	require.Equal(t, "http://wpad/", eth0.options["wpad"])
+
+	row := eth0.row()
+	require.Equal(t, "192.168.1.1", row["server"])
+	require.Equal(t, "192.168.1.1,8.8.8.8", row["dns_servers"])
+	require.Equal(t, "255.255.255.0", row["subnet_mask"])
+	require.Equal(t, "86400", row["lease_time"])
+	require.Equal(t, "http://wpad/", row["wpad_url"])
+	require.Equal(t, "1715731200", row["expires"])
+
+	require.Equal(t, "wlan0", leases[1].iface)
+	require.Equal(t, time.Unix(1715731200, 0), leases[1].expires)
+	require.True(t, leases[1].obtained.IsZero())
+}
+
+func TestParseSdLease(t *testing.T) {
+	t.Parallel()
+
+	contents := `# This is private data. Do not parse.
+ADDRESS=192.168.1.50
+NETMASK=255.255.255.0
+ROUTER=192.168.1.1
+SERVER_ADDRESS=192.168.1.1
+LIFETIME=86400
+DNS=192.168.1.1 8.8.8.8
+DOMAINNAME=example.com
+CLIENTID=ff0a2b3c4d
+OPTION_252=687474703a2f2f777061642f777061642e646174
+`
+	modified := time.Unix(1715644800, 0)
+	l := parseSdLease(contents, "eth0", modified, "/run/systemd/netif/leases/2")
+
+	require.Equal(t, "eth0", l.iface)
+	require.Equal(t, "192.168.1.50", l.address)
+	require.Equal(t, modified, l.obtained)
+	require.Equal(t, modified.Add(24*time.Hour), l.expires)
+	require.Equal(t, map[string]string{
+		"subnet_mask":            "255.255.255.0",
+		"routers":                "192.168.1.1",
+		"dhcp_server_identifier": "192.168.1.1",
+		"dhcp_lease_time":        "86400",
+		"domain_name_servers":    "192.168.1.1,8.8.8.8",
+		"domain_name":            "example.com",
+		"clientid":               "ff0a2b3c4d",
+		"wpad":                   "http://wpad/wpad.dat",
+	}, l.options)
+}
+
+func TestParseDhcpInterfaceOptions(t *testing.T) {
+	t.Parallel()
+
+	record := func(code uint32, isVendor uint32, value []byte) []byte {
+		header := make([]byte, 16)
+		binary.LittleEndian.PutUint32(header[0:], code)
+		binary.LittleEndian.PutUint32(header[4:], isVendor)
+		binary.LittleEndian.PutUint32(header[8:], uint32(len(value)))
+		binary.LittleEndian.PutUint32(header[12:], 1715644800)
+		padded := append(header, value...)
+		for len(padded)%4 != 0 {
+			padded = append(padded, 0)
+		}
+		return padded
+	}
+
+	var value []byte
+	value = append(value, record(54, 0, []byte{10, 0, 0, 1})...)
+	value = append(value, record(15, 0, []byte("corp"))...)
+	value = append(value, record(1, 1, []byte{1, 2, 3})...)
+	value = append(value, record(252, 0, []byte("http://wpad/wpad.dat\x00"))...)
+	value = append(value, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0xff, 0xff, 0, 0, 0, 0, 0, 0) // garbage
+
+	require.Equal(t, map[string]string{
+		"dhcp_server_identifier": "10.0.0.1",
+		"domain_name":            "corp",
+		"wpad":                   "http://wpad/wpad.dat",
+	}, parseDhcpInterfaceOptions(value))
+}
+
+func TestCurrentLeases(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1715644800, 0)
+	leases := []lease{
+		{iface: "en0", address: "10.0.0.2", obtained: now.Add(-48 * time.Hour), expires: now.Add(-24 * time.Hour)},
+		{iface: "en0", address: "10.0.0.3", obtained: now.Add(-1 * time.Hour), expires: now.Add(time.Hour)},
+		{iface: "en0", address: "10.0.0.4", obtained: now.Add(-2 * time.Hour), expires: now.Add(time.Hour)},
+		{iface: "en1", address: "10.0.1.2"},
+	}
+
+	current := currentLeases(leases, now)
+	require.Len(t, current, 2)
+	require.Equal(t, "10.0.0.3", current[0].address)
+	require.Equal(t, "10.0.1.2", current[1].address)
+}
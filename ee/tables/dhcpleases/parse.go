@@ -0,0 +1,212 @@
+package dhcpleases
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDhclientLeases parses an ISC dhclient lease file. dhclient appends each lease it's
+// granted, so only the last lease for each interface is returned, e.g.
+//
+//	lease {
+//	  interface "eth0";
+//	  fixed-address 192.168.1.50;
+//	  option subnet-mask 255.255.255.0;
+//	  option dhcp-lease-time 86400;
+//	  option domain-name-servers 192.168.1.1,8.8.8.8;
+//	  expire 3 2024/05/15 00:00:00;
+//	}
+func parseDhclientLeases(contents string, source string) []lease {
+	var leases []lease
+	byInterface := make(map[string]int)
+
+	var current *lease
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if comment := strings.Index(line, "#"); comment >= 0 && !strings.Contains(line[:comment], `"`) {
+			line = strings.TrimSpace(line[:comment])
+		}
+
+		switch {
+		case strings.HasPrefix(line, "lease") && strings.HasSuffix(line, "{"):
+			current = &lease{source: source, options: make(map[string]string)}
+			continue
+		case line == "}":
+			if current != nil {
+				l := current.withTimes()
+				if i, seen := byInterface[l.iface]; seen {
+					leases[i] = l
+				} else {
+					byInterface[l.iface] = len(leases)
+					leases = append(leases, l)
+				}
+			}
+			current = nil
+			continue
+		case current == nil:
+			continue
+		}
+
+		statement := strings.TrimSpace(strings.TrimSuffix(line, ";"))
+		keyword, rest, _ := strings.Cut(statement, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch keyword {
+		case "interface":
+			current.iface = unquote(rest)
+		case "fixed-address":
+			current.address = rest
+		case "expire":
+			current.expires = parseDhclientTime(rest)
+		case "option":
+			name, value, _ := strings.Cut(rest, " ")
+			name, value = dhclientOption(name, strings.TrimSpace(value))
+			current.options[name] = value
+		}
+	}
+
+	return leases
+}
+
+// dhclientOption normalizes an option from a dhclient lease file to the names and formats we
+// use for options decoded from packets. Options dhclient doesn't know are written as
+// unknown-<code>, with colon-separated hex values.
+func dhclientOption(name string, value string) (string, string) {
+	if codeStr, found := strings.CutPrefix(name, "unknown-"); found {
+		if code, err := strconv.Atoi(codeStr); err == nil {
+			if raw, err := hex.DecodeString(strings.ReplaceAll(value, ":", "")); err == nil {
+				return optionName(code), decodeOption(code, raw)
+			}
+			return optionName(code), unquote(value)
+		}
+	}
+
+	return strings.ReplaceAll(name, "-", "_"), unquote(value)
+}
+
+// parseDhclientTime parses a dhclient lease time, either as weekday and UTC date and time, e.g.
+// `3 2024/05/15 00:00:00`, or as `epoch 1715731200`.
+func parseDhclientTime(value string) time.Time {
+	fields := strings.Fields(value)
+	if len(fields) == 2 && fields[0] == "epoch" {
+		if seconds, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			return time.Unix(seconds, 0)
+		}
+		return time.Time{}
+	}
+	if len(fields) == 3 {
+		if t, err := time.Parse("2006/01/02 15:04:05", fields[1]+" "+fields[2]); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func unquote(value string) string {
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return strings.Trim(value, `"`)
+}
+
+// sdLeaseOptions maps the keys in systemd's DHCP lease files to option names.
+var sdLeaseOptions = map[string]string{
+	"NETMASK":            "subnet_mask",
+	"ROUTER":             "routers",
+	"SERVER_ADDRESS":     "dhcp_server_identifier",
+	"DNS":                "domain_name_servers",
+	"NTP":                "ntp_servers",
+	"DOMAINNAME":         "domain_name",
+	"HOSTNAME":           "host_name",
+	"DOMAIN_SEARCH_LIST": "domain_search",
+	"LIFETIME":           "dhcp_lease_time",
+	"T1":                 "dhcp_renewal_time",
+	"T2":                 "dhcp_rebinding_time",
+	"MTU":                "interface_mtu",
+	"BROADCAST":          "broadcast_address",
+	"CAPTIVE_PORTAL":     "captive_portal",
+}
+
+// parseSdLease parses a DHCP lease file written by systemd's DHCP client, as used by
+// systemd-networkd and NetworkManager's internal client, e.g.
+//
+//	ADDRESS=192.168.1.50
+//	NETMASK=255.255.255.0
+//	SERVER_ADDRESS=192.168.1.1
+//	LIFETIME=86400
+//	DNS=192.168.1.1 8.8.8.8
+//	OPTION_252=687474703a2f2f777061642f777061642e646174
+//
+// The file doesn't record when the lease was obtained, but it's rewritten whenever the lease
+// is renewed, so its modification time is used.
+func parseSdLease(contents string, iface string, modified time.Time, source string) lease {
+	l := lease{iface: iface, obtained: modified, source: source, options: make(map[string]string)}
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+
+		if key == "ADDRESS" {
+			l.address = value
+			continue
+		}
+
+		// Private options are stored hex-encoded
+		if codeStr, found := strings.CutPrefix(key, "OPTION_"); found {
+			code, err := strconv.Atoi(codeStr)
+			raw, hexErr := hex.DecodeString(value)
+			if err == nil && hexErr == nil {
+				l.options[optionName(code)] = decodeOption(code, raw)
+				continue
+			}
+		}
+
+		name, known := sdLeaseOptions[key]
+		if !known {
+			name = strings.ToLower(key)
+		}
+		l.options[name] = strings.Join(strings.Fields(value), ",")
+	}
+
+	return l.withTimes()
+}
+
+// parseDhcpInterfaceOptions decodes the DhcpInterfaceOptions registry value, where Windows
+// keeps the options it received with the lease. Each option is a record of little-endian
+// uint32s -- the option code, whether it's vendor-specific, the length of its value, and a
+// timestamp -- followed by the value, padded to a multiple of four bytes. The format isn't
+// documented, so decoding stops at the first record that doesn't make sense.
+func parseDhcpInterfaceOptions(value []byte) map[string]string {
+	const recordHeaderLength = 16
+
+	options := make(map[string]string)
+	for i := 0; i+recordHeaderLength <= len(value); {
+		code := binary.LittleEndian.Uint32(value[i:])
+		isVendor := binary.LittleEndian.Uint32(value[i+4:])
+		length := int(binary.LittleEndian.Uint32(value[i+8:]))
+		start := i + recordHeaderLength
+		if code > optionEnd || length < 0 || start+length > len(value) {
+			break
+		}
+
+		if isVendor == 0 {
+			options[optionName(int(code))] = decodeOption(int(code), value[start:start+length])
+		}
+
+		i = start + (length+3)/4*4
+	}
+
+	return options
+}
@@ -0,0 +1,91 @@
+//go:build darwin
+// +build darwin
+
+package dhcpleases
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"howett.net/plist"
+)
+
+// leaseDir has the leases kept by configd's DHCP client, one per interface and network, named
+// <interface>-<router hardware address>.plist
+const leaseDir = "/var/db/dhcpclient/leases"
+
+type darwinLease struct {
+	IPAddress      string    `plist:"IPAddress"`
+	LeaseLength    int64     `plist:"LeaseLength"`
+	LeaseStartDate time.Time `plist:"LeaseStartDate"`
+	PacketData     []byte    `plist:"PacketData"`
+}
+
+func (t *Table) leases(ctx context.Context) []lease {
+	paths, err := filepath.Glob(filepath.Join(leaseDir, "*.plist"))
+	if err != nil {
+		return nil
+	}
+
+	var leases []lease
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read lease file",
+				"path", path,
+				"err", err,
+			)
+			continue
+		}
+
+		var dl darwinLease
+		if _, err := plist.Unmarshal(contents, &dl); err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not unmarshal lease file",
+				"path", path,
+				"err", err,
+			)
+			continue
+		}
+
+		iface, _, _ := strings.Cut(filepath.Base(path), "-")
+		l := lease{
+			iface:    iface,
+			address:  dl.IPAddress,
+			obtained: dl.LeaseStartDate,
+			source:   path,
+			options:  make(map[string]string),
+		}
+
+		// The lease keeps the ACK that granted it, which has all the options the server sent
+		if len(dl.PacketData) > 0 {
+			address, options, err := parseDhcpPacket(dl.PacketData)
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not parse DHCP packet from lease",
+					"path", path,
+					"err", err,
+				)
+			}
+			if options != nil {
+				l.options = options
+			}
+			if l.address == "" {
+				l.address = address
+			}
+		}
+
+		if !dl.LeaseStartDate.IsZero() && dl.LeaseLength > 0 && dl.LeaseLength != infiniteLease {
+			l.expires = dl.LeaseStartDate.Add(time.Duration(dl.LeaseLength) * time.Second)
+		}
+
+		leases = append(leases, l.withTimes())
+	}
+
+	return leases
+}
@@ -0,0 +1,99 @@
+//go:build linux
+// +build linux
+
+package dhcpleases
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// dhclientLeaseGlobs are where ISC dhclient keeps its leases, on Debian-based and
+	// RedHat-based distributions, and when run by NetworkManager
+	dhclientLeaseGlobs = []string{
+		"/var/lib/dhcp/dhclient*.leases",
+		"/var/lib/dhclient/*.lease*",
+		"/var/lib/NetworkManager/dhclient-*.lease",
+	}
+
+	// networkdLeaseDir has a lease file for each interface, named by interface index
+	networkdLeaseDir = "/run/systemd/netif/leases"
+
+	// networkManagerInternalLeaseGlob matches the lease files of NetworkManager's internal DHCP
+	// client, named internal-<connection uuid>-<interface>.lease
+	networkManagerInternalLeaseGlob = "/var/lib/NetworkManager/internal-*.lease"
+)
+
+func (t *Table) leases(ctx context.Context) []lease {
+	var leases []lease
+
+	for _, pattern := range dhclientLeaseGlobs {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not read dhclient lease file",
+					"path", path,
+					"err", err,
+				)
+				continue
+			}
+			leases = append(leases, parseDhclientLeases(string(contents), path)...)
+		}
+	}
+
+	if entries, err := os.ReadDir(networkdLeaseDir); err == nil {
+		for _, entry := range entries {
+			iface := entry.Name()
+			if index, err := strconv.Atoi(entry.Name()); err == nil {
+				if netIface, err := net.InterfaceByIndex(index); err == nil {
+					iface = netIface.Name
+				}
+			}
+			if l, ok := t.readSdLease(ctx, filepath.Join(networkdLeaseDir, entry.Name()), iface); ok {
+				leases = append(leases, l)
+			}
+		}
+	}
+
+	if paths, err := filepath.Glob(networkManagerInternalLeaseGlob); err == nil {
+		for _, path := range paths {
+			// The connection UUID contains dashes, but interface names rarely do
+			name := strings.TrimSuffix(filepath.Base(path), ".lease")
+			iface := name[strings.LastIndex(name, "-")+1:]
+			if l, ok := t.readSdLease(ctx, path, iface); ok {
+				leases = append(leases, l)
+			}
+		}
+	}
+
+	return leases
+}
+
+func (t *Table) readSdLease(ctx context.Context, path string, iface string) (lease, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return lease{}, false
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read lease file",
+			"path", path,
+			"err", err,
+		)
+		return lease{}, false
+	}
+
+	return parseSdLease(string(contents), iface, info.ModTime(), path), true
+}
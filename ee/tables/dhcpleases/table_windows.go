@@ -0,0 +1,146 @@
+//go:build windows
+// +build windows
+
+package dhcpleases
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// interfacesKey has a subkey for each interface, named by its GUID, with its DHCP lease
+const interfacesKey = `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces`
+
+func (t *Table) leases(ctx context.Context) []lease {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, interfacesKey, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not open interfaces key",
+			"err", err,
+		)
+		return nil
+	}
+	defer key.Close()
+
+	guids, err := key.ReadSubKeyNames(0)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list interfaces",
+			"err", err,
+		)
+		return nil
+	}
+
+	names := adapterNames()
+
+	var leases []lease
+	for _, guid := range guids {
+		l, ok := t.interfaceLease(ctx, guid)
+		if !ok {
+			continue
+		}
+		if name, found := names[strings.ToLower(guid)]; found {
+			l.iface = name
+		}
+		leases = append(leases, l)
+	}
+
+	return leases
+}
+
+// interfaceLease reads the lease for the interface with the given GUID, if it has one.
+func (t *Table) interfaceLease(ctx context.Context, guid string) (lease, bool) {
+	path := interfacesKey + `\` + guid
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not open interface key",
+			"key", path,
+			"err", err,
+		)
+		return lease{}, false
+	}
+	defer key.Close()
+
+	if enabled, _, err := key.GetIntegerValue("EnableDHCP"); err != nil || enabled == 0 {
+		return lease{}, false
+	}
+	address, _, err := key.GetStringValue("DhcpIPAddress")
+	if err != nil || address == "" || address == "0.0.0.0" {
+		return lease{}, false
+	}
+
+	l := lease{
+		iface:   guid,
+		address: address,
+		source:  `HKEY_LOCAL_MACHINE\` + path,
+		options: make(map[string]string),
+	}
+
+	if raw, _, err := key.GetBinaryValue("DhcpInterfaceOptions"); err == nil {
+		l.options = parseDhcpInterfaceOptions(raw)
+	}
+
+	// The values Windows applied take precedence over the raw options
+	for valueName, option := range map[string]int{
+		"DhcpSubnetMask": optionSubnetMask,
+		"DhcpServer":     optionServer,
+		"DhcpNameServer": optionDnsServers,
+		"DhcpDomain":     optionDomainName,
+	} {
+		if value, _, err := key.GetStringValue(valueName); err == nil && value != "" {
+			l.options[optionName(option)] = strings.Join(strings.Fields(value), ",")
+		}
+	}
+	if routers, _, err := key.GetStringsValue("DhcpDefaultGateway"); err == nil && len(routers) > 0 {
+		l.options[optionName(optionRouters)] = strings.Join(routers, ",")
+	}
+	if leaseSeconds, _, err := key.GetIntegerValue("Lease"); err == nil && leaseSeconds > 0 {
+		l.options[optionName(optionLeaseTime)] = strconv.FormatUint(leaseSeconds, 10)
+	}
+	if obtained, _, err := key.GetIntegerValue("LeaseObtainedTime"); err == nil && obtained > 0 {
+		l.obtained = time.Unix(int64(obtained), 0)
+	}
+	if expires, _, err := key.GetIntegerValue("LeaseTerminatesTime"); err == nil && expires > 0 && expires != infiniteLease {
+		l.expires = time.Unix(int64(expires), 0)
+	}
+
+	return l.withTimes(), true
+}
+
+// adapterNames maps the lowercased GUIDs of network adapters to their friendly names, e.g.
+// "Wi-Fi".
+func adapterNames() map[string]string {
+	names := make(map[string]string)
+
+	size := uint32(15 * 1024)
+	var buf []byte
+	for attempt := 0; attempt < 3; attempt++ {
+		buf = make([]byte, size)
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC, windows.GAA_FLAG_SKIP_UNICAST|windows.GAA_FLAG_SKIP_ANYCAST|windows.GAA_FLAG_SKIP_MULTICAST|windows.GAA_FLAG_SKIP_DNS_SERVER, 0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])), &size)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, windows.ERROR_BUFFER_OVERFLOW) {
+			return names
+		}
+		buf = nil
+	}
+	if buf == nil {
+		return names
+	}
+
+	for adapter := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])); adapter != nil; adapter = adapter.Next {
+		names[strings.ToLower(windows.BytePtrToString(adapter.AdapterName))] = windows.UTF16PtrToString(adapter.FriendlyName)
+	}
+
+	return names
+}
@@ -0,0 +1,393 @@
+// Package disksmart provides kolide_disk_smart_info, a table of SMART health data for the
+// device's disks. It uses smartctl, from smartmontools, where it is installed. On Linux,
+// NVMe drives are read directly when smartctl is not available.
+package disksmart
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName = "kolide_disk_smart_info"
+
+	smartctlTimeoutSeconds = 30
+
+	// Sources of a row's data
+	sourceSmartctl = "smartctl"
+	sourceNVMe     = "nvme_ioctl"
+
+	// Values for the health column
+	healthPassed = "passed"
+	healthFailed = "failed"
+)
+
+// smartctl's exit status is a bitmask. Bits 0 and 1 mean it could not read the device at all
+// (or, with --nocheck, that the device is asleep); the higher bits report problems with the
+// disk itself, alongside complete output.
+const smartctlFatalExitBits = 0x3
+
+// diskInfo is the SMART data for a single disk. Values that could not be read are nil.
+type diskInfo struct {
+	device          string
+	deviceType      string
+	source          string
+	model           string
+	serial          string
+	firmwareVersion string
+	capacityBytes   *uint64
+	health          string
+	temperature     *int64 // celsius
+	powerOnHours    *uint64
+	powerCycles     *uint64
+
+	// ATA
+	reallocatedSectors   *uint64
+	pendingSectors       *uint64
+	offlineUncorrectable *uint64
+
+	// SSD wear. For NVMe drives, this is reported directly; for ATA SSDs, it is derived from
+	// the vendor's wear attribute.
+	percentageUsed *uint64
+
+	// NVMe
+	availableSpare  *uint64
+	mediaErrors     *uint64
+	criticalWarning *uint64
+	unsafeShutdowns *uint64
+}
+
+type Table struct {
+	slogger *slog.Logger
+
+	// smartctl and readNVMe are overridden in tests
+	smartctl allowedcmd.AllowedCommand
+	readNVMe func(ctx context.Context, slogger *slog.Logger) ([]diskInfo, error)
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("device"),
+		table.TextColumn("type"),
+		table.TextColumn("source"),
+		table.TextColumn("model"),
+		table.TextColumn("serial"),
+		table.TextColumn("firmware_version"),
+		table.BigIntColumn("capacity_bytes"),
+		table.TextColumn("health"),
+		table.IntegerColumn("temperature_celsius"),
+		table.BigIntColumn("power_on_hours"),
+		table.BigIntColumn("power_cycles"),
+		table.BigIntColumn("reallocated_sectors"),
+		table.BigIntColumn("pending_sectors"),
+		table.BigIntColumn("offline_uncorrectable"),
+		table.IntegerColumn("percentage_used"),
+		table.IntegerColumn("available_spare"),
+		table.BigIntColumn("media_errors"),
+		table.IntegerColumn("critical_warning"),
+		table.BigIntColumn("unsafe_shutdowns"),
+	}
+
+	t := &Table{
+		slogger:  slogger.With("table", tableName),
+		smartctl: allowedcmd.Smartctl,
+		readNVMe: readNVMeDisks,
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	disks, err := t.smartctlDisks(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"could not read disks with smartctl, falling back to native NVMe",
+			"err", err,
+		)
+
+		disks, err = t.readNVMe(ctx, t.slogger)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not read NVMe disks",
+				"err", err,
+			)
+			return []map[string]string{}, nil
+		}
+	}
+
+	requestedDevices := tablehelpers.GetConstraints(queryContext, "device")
+
+	results := make([]map[string]string, 0, len(disks))
+	for _, disk := range disks {
+		if !matchesConstraints(disk.device, requestedDevices) {
+			continue
+		}
+		results = append(results, disk.row())
+	}
+
+	return results, nil
+}
+
+func matchesConstraints(device string, requestedDevices []string) bool {
+	if len(requestedDevices) == 0 {
+		return true
+	}
+	for _, requested := range requestedDevices {
+		if requested == device {
+			return true
+		}
+	}
+	return false
+}
+
+// smartctlDisks reads each device smartctl can find. It returns an error only when smartctl
+// cannot be used at all.
+func (t *Table) smartctlDisks(ctx context.Context) ([]diskInfo, error) {
+	scanOutput, err := t.runSmartctl(ctx, "--scan", "--json")
+	if err != nil {
+		return nil, fmt.Errorf("scanning for devices: %w", err)
+	}
+
+	devices, err := parseScan(scanOutput)
+	if err != nil {
+		return nil, fmt.Errorf("parsing scan: %w", err)
+	}
+
+	disks := make([]diskInfo, 0, len(devices))
+	for _, device := range devices {
+		// Don't wake sleeping disks just to read their health; a device that's asleep will fail
+		// with a fatal exit status, and be skipped
+		output, err := t.runSmartctl(ctx, "--json", "--all", "--nocheck=standby", "--device", device.Type, device.Name)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not read device with smartctl",
+				"device", device.Name,
+				"err", err,
+			)
+			continue
+		}
+
+		disk, err := parseSmartctlOutput(output)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not parse smartctl output",
+				"device", device.Name,
+				"err", err,
+			)
+			continue
+		}
+
+		disks = append(disks, disk)
+	}
+
+	return disks, nil
+}
+
+// runSmartctl runs smartctl, returning its output when it ran successfully -- including when
+// its exit status reports problems with the disk, rather than with smartctl itself.
+func (t *Table) runSmartctl(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, smartctlTimeoutSeconds, t.smartctl, args, &stdout, io.Discard); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode()&smartctlFatalExitBits != 0 {
+			return nil, err
+		}
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// smartctlDevice is a device found by `smartctl --scan`
+type smartctlDevice struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func parseScan(output []byte) ([]smartctlDevice, error) {
+	var scan struct {
+		Devices []smartctlDevice `json:"devices"`
+	}
+	if err := json.Unmarshal(output, &scan); err != nil {
+		return nil, fmt.Errorf("unmarshalling scan: %w", err)
+	}
+	return scan.Devices, nil
+}
+
+// smartctlOutput is the subset of `smartctl --json --all` output we report
+type smartctlOutput struct {
+	Device struct {
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName       string `json:"model_name"`
+	SerialNumber    string `json:"serial_number"`
+	FirmwareVersion string `json:"firmware_version"`
+	UserCapacity    *struct {
+		Bytes uint64 `json:"bytes"`
+	} `json:"user_capacity"`
+	NVMeTotalCapacity *uint64 `json:"nvme_total_capacity"`
+	SmartStatus       *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature *struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime *struct {
+		Hours uint64 `json:"hours"`
+	} `json:"power_on_time"`
+	PowerCycleCount    *uint64 `json:"power_cycle_count"`
+	ATASmartAttributes *struct {
+		Table []ataAttribute `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeSmartHealthInformationLog *struct {
+		CriticalWarning uint64 `json:"critical_warning"`
+		AvailableSpare  uint64 `json:"available_spare"`
+		PercentageUsed  uint64 `json:"percentage_used"`
+		MediaErrors     uint64 `json:"media_errors"`
+		UnsafeShutdowns uint64 `json:"unsafe_shutdowns"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+type ataAttribute struct {
+	ID    int    `json:"id"`
+	Value uint64 `json:"value"` // normalized, usually counting down from 100 as the disk wears
+	Raw   struct {
+		Value uint64 `json:"value"`
+	} `json:"raw"`
+}
+
+// ATA attribute IDs
+const (
+	ataReallocatedSectors   = 5
+	ataPendingSectors       = 197
+	ataOfflineUncorrectable = 198
+)
+
+// ataWearAttributes are the vendor-specific attributes whose normalized value reports an SSD's
+// remaining life, in order of preference: Wear_Leveling_Count, SSD_Life_Left, and
+// Media_Wearout_Indicator
+var ataWearAttributes = []int{177, 231, 233}
+
+func parseSmartctlOutput(output []byte) (diskInfo, error) {
+	var out smartctlOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return diskInfo{}, fmt.Errorf("unmarshalling smartctl output: %w", err)
+	}
+
+	disk := diskInfo{
+		device:          out.Device.Name,
+		deviceType:      out.Device.Type,
+		source:          sourceSmartctl,
+		model:           out.ModelName,
+		serial:          out.SerialNumber,
+		firmwareVersion: out.FirmwareVersion,
+	}
+
+	if out.UserCapacity != nil {
+		disk.capacityBytes = &out.UserCapacity.Bytes
+	} else if out.NVMeTotalCapacity != nil {
+		disk.capacityBytes = out.NVMeTotalCapacity
+	}
+
+	if out.SmartStatus != nil {
+		disk.health = healthFailed
+		if out.SmartStatus.Passed {
+			disk.health = healthPassed
+		}
+	}
+
+	if out.Temperature != nil {
+		disk.temperature = &out.Temperature.Current
+	}
+	if out.PowerOnTime != nil {
+		disk.powerOnHours = &out.PowerOnTime.Hours
+	}
+	disk.powerCycles = out.PowerCycleCount
+
+	if out.ATASmartAttributes != nil {
+		attributes := make(map[int]ataAttribute, len(out.ATASmartAttributes.Table))
+		for _, attr := range out.ATASmartAttributes.Table {
+			attributes[attr.ID] = attr
+		}
+
+		disk.reallocatedSectors = rawAttribute(attributes, ataReallocatedSectors)
+		disk.pendingSectors = rawAttribute(attributes, ataPendingSectors)
+		disk.offlineUncorrectable = rawAttribute(attributes, ataOfflineUncorrectable)
+
+		for _, id := range ataWearAttributes {
+			if attr, ok := attributes[id]; ok && attr.Value <= 100 {
+				used := 100 - attr.Value
+				disk.percentageUsed = &used
+				break
+			}
+		}
+	}
+
+	if log := out.NVMeSmartHealthInformationLog; log != nil {
+		disk.criticalWarning = &log.CriticalWarning
+		disk.availableSpare = &log.AvailableSpare
+		disk.percentageUsed = &log.PercentageUsed
+		disk.mediaErrors = &log.MediaErrors
+		disk.unsafeShutdowns = &log.UnsafeShutdowns
+	}
+
+	return disk, nil
+}
+
+func rawAttribute(attributes map[int]ataAttribute, id int) *uint64 {
+	attr, ok := attributes[id]
+	if !ok {
+		return nil
+	}
+	return &attr.Raw.Value
+}
+
+func (d diskInfo) row() map[string]string {
+	return map[string]string{
+		"device":                d.device,
+		"type":                  d.deviceType,
+		"source":                d.source,
+		"model":                 d.model,
+		"serial":                d.serial,
+		"firmware_version":      d.firmwareVersion,
+		"capacity_bytes":        formatUint(d.capacityBytes),
+		"health":                d.health,
+		"temperature_celsius":   formatInt(d.temperature),
+		"power_on_hours":        formatUint(d.powerOnHours),
+		"power_cycles":          formatUint(d.powerCycles),
+		"reallocated_sectors":   formatUint(d.reallocatedSectors),
+		"pending_sectors":       formatUint(d.pendingSectors),
+		"offline_uncorrectable": formatUint(d.offlineUncorrectable),
+		"percentage_used":       formatUint(d.percentageUsed),
+		"available_spare":       formatUint(d.availableSpare),
+		"media_errors":          formatUint(d.mediaErrors),
+		"critical_warning":      formatUint(d.criticalWarning),
+		"unsafe_shutdowns":      formatUint(d.unsafeShutdowns),
+	}
+}
+
+func formatUint(v *uint64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatUint(*v, 10)
+}
+
+func formatInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
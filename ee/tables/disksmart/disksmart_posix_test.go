@@ -0,0 +1,116 @@
+//go:build !windows
+// +build !windows
+
+package disksmart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+// smartctlFaker returns the scan output for `--scan`, and otherwise the output for the requested
+// device, exiting with that device's exit status.
+func smartctlFaker(outputs map[string]string, exitStatuses map[string]int) allowedcmd.AllowedCommand {
+	return func(ctx context.Context, args ...string) (*allowedcmd.TracedCmd, error) {
+		file, exitStatus := "scan.json", 0
+		if args[0] != "--scan" {
+			device := args[len(args)-1]
+			file, exitStatus = outputs[device], exitStatuses[device]
+		}
+
+		script := fmt.Sprintf("cat %s; exit %d", filepath.Join("testdata", file), exitStatus)
+		return &allowedcmd.TracedCmd{
+			Ctx: ctx,
+			Cmd: exec.CommandContext(ctx, "/bin/sh", "-c", script), //nolint:forbidigo // Fine to use exec.CommandContext in test
+		}, nil
+	}
+}
+
+func noNVMe(_ context.Context, _ *slog.Logger) ([]diskInfo, error) {
+	return nil, errors.New("not expected in this test")
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name            string
+		exitStatuses    map[string]int
+		constraints     map[string][]string
+		expectedDevices []string
+	}{
+		{
+			name:            "all disks read",
+			expectedDevices: []string{"/dev/sda", "/dev/nvme0"},
+		},
+		{
+			name:            "disk problems reported in exit status",
+			exitStatuses:    map[string]int{"/dev/sda": 4, "/dev/nvme0": 8 | 16},
+			expectedDevices: []string{"/dev/sda", "/dev/nvme0"},
+		},
+		{
+			name:            "disk asleep",
+			exitStatuses:    map[string]int{"/dev/sda": 2},
+			expectedDevices: []string{"/dev/nvme0"},
+		},
+		{
+			name:            "device constraint",
+			constraints:     map[string][]string{"device": {"/dev/nvme0"}},
+			expectedDevices: []string{"/dev/nvme0"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			testTable := &Table{
+				slogger: multislogger.NewNopLogger(),
+				smartctl: smartctlFaker(
+					map[string]string{"/dev/sda": "ata.json", "/dev/nvme0": "nvme.json"},
+					tt.exitStatuses,
+				),
+				readNVMe: noNVMe,
+			}
+
+			rows, err := testTable.generate(context.TODO(), tablehelpers.MockQueryContext(tt.constraints))
+			require.NoError(t, err)
+
+			devices := make([]string, 0, len(rows))
+			for _, row := range rows {
+				devices = append(devices, row["device"])
+				require.Equal(t, sourceSmartctl, row["source"])
+			}
+			require.Equal(t, tt.expectedDevices, devices)
+		})
+	}
+}
+
+func TestGenerate_FallsBackToNVMe(t *testing.T) {
+	t.Parallel()
+
+	testTable := &Table{
+		slogger: multislogger.NewNopLogger(),
+		smartctl: func(_ context.Context, _ ...string) (*allowedcmd.TracedCmd, error) {
+			return nil, errors.New("smartctl not found")
+		},
+		readNVMe: func(_ context.Context, _ *slog.Logger) ([]diskInfo, error) {
+			return []diskInfo{{device: "/dev/nvme0", deviceType: "nvme", source: sourceNVMe}}, nil
+		},
+	}
+
+	rows, err := testTable.generate(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, "/dev/nvme0", rows[0]["device"])
+	require.Equal(t, sourceNVMe, rows[0]["source"])
+}
@@ -0,0 +1,150 @@
+package disksmart
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseScan(t *testing.T) {
+	t.Parallel()
+
+	output, err := os.ReadFile(filepath.Join("testdata", "scan.json"))
+	require.NoError(t, err)
+
+	devices, err := parseScan(output)
+	require.NoError(t, err)
+	require.Equal(t, []smartctlDevice{
+		{Name: "/dev/sda", Type: "sat"},
+		{Name: "/dev/nvme0", Type: "nvme"},
+	}, devices)
+
+	_, err = parseScan([]byte("not json"))
+	require.Error(t, err)
+}
+
+func Test_parseSmartctlOutput(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		file        string
+		expectedRow map[string]string
+	}{
+		{
+			file: "ata.json",
+			expectedRow: map[string]string{
+				"device":                "/dev/sda",
+				"type":                  "sat",
+				"source":                "smartctl",
+				"model":                 "Samsung SSD 860 EVO 500GB",
+				"serial":                "S3Z1NB0K123456X",
+				"firmware_version":      "RVT04B6Q",
+				"capacity_bytes":        "500107862016",
+				"health":                "passed",
+				"temperature_celsius":   "34",
+				"power_on_hours":        "31502",
+				"power_cycles":          "1215",
+				"reallocated_sectors":   "3",
+				"pending_sectors":       "0",
+				"offline_uncorrectable": "",
+				"percentage_used":       "12",
+				"available_spare":       "",
+				"media_errors":          "",
+				"critical_warning":      "",
+				"unsafe_shutdowns":      "",
+			},
+		},
+		{
+			file: "nvme.json",
+			expectedRow: map[string]string{
+				"device":                "/dev/nvme0",
+				"type":                  "nvme",
+				"source":                "smartctl",
+				"model":                 "WD_BLACK SN850X 1000GB",
+				"serial":                "23011K800123",
+				"firmware_version":      "620311WD",
+				"capacity_bytes":        "1000204886016",
+				"health":                "failed",
+				"temperature_celsius":   "41",
+				"power_on_hours":        "8123",
+				"power_cycles":          "512",
+				"reallocated_sectors":   "",
+				"pending_sectors":       "",
+				"offline_uncorrectable": "",
+				"percentage_used":       "7",
+				"available_spare":       "100",
+				"media_errors":          "2",
+				"critical_warning":      "4",
+				"unsafe_shutdowns":      "37",
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.file, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			require.NoError(t, err)
+
+			disk, err := parseSmartctlOutput(output)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedRow, disk.row())
+		})
+	}
+}
+
+func Test_parseNVMe(t *testing.T) {
+	t.Parallel()
+
+	identify := make([]byte, nvmeIdentifyControllerSize)
+	copy(identify[4:24], "S64ANS0T512345      ")
+	copy(identify[24:64], "Samsung SSD 980 PRO 1TB                 ")
+	copy(identify[64:72], "5B2QGXA7")
+
+	smartLog := make([]byte, nvmeSmartLogSize)
+	smartLog[0] = 0 // critical warning
+	binary.LittleEndian.PutUint16(smartLog[1:3], 310)
+	smartLog[3] = 98 // available spare
+	smartLog[5] = 3  // percentage used
+	binary.LittleEndian.PutUint64(smartLog[112:120], 1024)
+	binary.LittleEndian.PutUint64(smartLog[128:136], 4567)
+	binary.LittleEndian.PutUint64(smartLog[144:152], 12)
+	binary.LittleEndian.PutUint64(smartLog[160:168], 0)
+
+	disk := diskInfo{device: "/dev/nvme0", deviceType: "nvme", source: sourceNVMe}
+	require.NoError(t, parseNVMeIdentifyController(identify, &disk))
+	require.NoError(t, parseNVMeSmartLog(smartLog, &disk))
+
+	require.Equal(t, map[string]string{
+		"device":                "/dev/nvme0",
+		"type":                  "nvme",
+		"source":                "nvme_ioctl",
+		"model":                 "Samsung SSD 980 PRO 1TB",
+		"serial":                "S64ANS0T512345",
+		"firmware_version":      "5B2QGXA7",
+		"capacity_bytes":        "",
+		"health":                "passed",
+		"temperature_celsius":   "37",
+		"power_on_hours":        "4567",
+		"power_cycles":          "1024",
+		"reallocated_sectors":   "",
+		"pending_sectors":       "",
+		"offline_uncorrectable": "",
+		"percentage_used":       "3",
+		"available_spare":       "98",
+		"media_errors":          "0",
+		"critical_warning":      "0",
+		"unsafe_shutdowns":      "12",
+	}, disk.row())
+
+	// A critical warning means the disk is failing
+	smartLog[0] = 0x1
+	require.NoError(t, parseNVMeSmartLog(smartLog, &disk))
+	require.Equal(t, healthFailed, disk.health)
+
+	require.Error(t, parseNVMeSmartLog(smartLog[:100], &disk))
+	require.Error(t, parseNVMeIdentifyController(identify[:100], &disk))
+}
@@ -0,0 +1,66 @@
+package disksmart
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	// Sizes of the NVMe admin command responses we read
+	nvmeSmartLogSize           = 512
+	nvmeIdentifyControllerSize = 4096
+
+	// NVMe reports temperatures in kelvin
+	kelvinOffset = 273
+)
+
+// parseNVMeSmartLog reads the SMART / Health Information log page (log identifier 02h),
+// as laid out in the NVMe base specification.
+func parseNVMeSmartLog(log []byte, disk *diskInfo) error {
+	if len(log) < nvmeSmartLogSize {
+		return errors.New("SMART log too short")
+	}
+
+	criticalWarning := uint64(log[0])
+	temperature := int64(binary.LittleEndian.Uint16(log[1:3])) - kelvinOffset
+	availableSpare := uint64(log[3])
+	percentageUsed := uint64(log[5])
+
+	// The counters are 128-bit; the upper half will not be set in practice
+	powerCycles := binary.LittleEndian.Uint64(log[112:120])
+	powerOnHours := binary.LittleEndian.Uint64(log[128:136])
+	unsafeShutdowns := binary.LittleEndian.Uint64(log[144:152])
+	mediaErrors := binary.LittleEndian.Uint64(log[160:168])
+
+	disk.criticalWarning = &criticalWarning
+	disk.temperature = &temperature
+	disk.availableSpare = &availableSpare
+	disk.percentageUsed = &percentageUsed
+	disk.powerCycles = &powerCycles
+	disk.powerOnHours = &powerOnHours
+	disk.unsafeShutdowns = &unsafeShutdowns
+	disk.mediaErrors = &mediaErrors
+
+	// As smartctl does, consider any critical warning a failure
+	disk.health = healthPassed
+	if criticalWarning != 0 {
+		disk.health = healthFailed
+	}
+
+	return nil
+}
+
+// parseNVMeIdentifyController reads the serial number, model, and firmware revision from the
+// Identify Controller data structure (CNS 01h).
+func parseNVMeIdentifyController(identify []byte, disk *diskInfo) error {
+	if len(identify) < nvmeIdentifyControllerSize {
+		return errors.New("identify controller data too short")
+	}
+
+	disk.serial = strings.TrimSpace(string(identify[4:24]))
+	disk.model = strings.TrimSpace(string(identify[24:64]))
+	disk.firmwareVersion = strings.TrimSpace(string(identify[64:72]))
+
+	return nil
+}
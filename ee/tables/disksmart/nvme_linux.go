@@ -0,0 +1,140 @@
+//go:build linux
+// +build linux
+
+package disksmart
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"unsafe"
+
+	"github.com/kolide/launcher/ee/hostroot"
+	"golang.org/x/sys/unix"
+)
+
+// nvmeAdminCmd is struct nvme_admin_cmd, from linux/nvme_ioctl.h
+type nvmeAdminCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+const (
+	// NVME_IOCTL_ADMIN_CMD, _IOWR('N', 0x41, struct nvme_admin_cmd)
+	nvmeIoctlAdminCmd = 0xC0484E41
+
+	nvmeAdminGetLogPage = 0x02
+	nvmeAdminIdentify   = 0x06
+
+	nvmeLogSmart              = 0x02
+	nvmeIdentifyCnsController = 0x01
+	nvmeNamespaceAll          = 0xFFFFFFFF
+)
+
+// nvmeControllerPattern matches NVMe controller character devices, like /dev/nvme0, but not
+// namespaces, like /dev/nvme0n1
+var nvmeControllerPattern = regexp.MustCompile(`^nvme[0-9]+$`)
+
+// readNVMeDisks reads SMART data from each NVMe controller, using the admin command ioctl.
+func readNVMeDisks(ctx context.Context, slogger *slog.Logger) ([]diskInfo, error) {
+	entries, err := os.ReadDir(hostroot.Path("/dev"))
+	if err != nil {
+		return nil, fmt.Errorf("reading /dev: %w", err)
+	}
+
+	disks := make([]diskInfo, 0)
+	for _, entry := range entries {
+		if !nvmeControllerPattern.MatchString(entry.Name()) {
+			continue
+		}
+
+		device := filepath.Join("/dev", entry.Name())
+		disk, err := readNVMeDisk(hostroot.Path(device))
+		if err != nil {
+			slogger.Log(ctx, slog.LevelDebug,
+				"could not read NVMe device",
+				"device", device,
+				"err", err,
+			)
+			continue
+		}
+		disk.device = device
+
+		disks = append(disks, disk)
+	}
+
+	return disks, nil
+}
+
+func readNVMeDisk(path string) (diskInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return diskInfo{}, fmt.Errorf("opening device: %w", err)
+	}
+	defer f.Close()
+
+	disk := diskInfo{
+		deviceType: "nvme",
+		source:     sourceNVMe,
+	}
+
+	identify := make([]byte, nvmeIdentifyControllerSize)
+	if err := nvmeAdminCommand(f, nvmeAdminCmd{
+		opcode: nvmeAdminIdentify,
+		cdw10:  nvmeIdentifyCnsController,
+	}, identify); err != nil {
+		return diskInfo{}, fmt.Errorf("identifying controller: %w", err)
+	}
+	if err := parseNVMeIdentifyController(identify, &disk); err != nil {
+		return diskInfo{}, err
+	}
+
+	smartLog := make([]byte, nvmeSmartLogSize)
+	if err := nvmeAdminCommand(f, nvmeAdminCmd{
+		opcode: nvmeAdminGetLogPage,
+		nsid:   nvmeNamespaceAll,
+		// The number of dwords to read, less one, in the upper half; the log page in the lower
+		cdw10: uint32(nvmeSmartLogSize/4-1)<<16 | nvmeLogSmart,
+	}, smartLog); err != nil {
+		return diskInfo{}, fmt.Errorf("reading SMART log: %w", err)
+	}
+	if err := parseNVMeSmartLog(smartLog, &disk); err != nil {
+		return diskInfo{}, err
+	}
+
+	return disk, nil
+}
+
+// nvmeAdminCommand sends cmd to the controller, reading the response into data.
+func nvmeAdminCommand(f *os.File, cmd nvmeAdminCmd, data []byte) error {
+	cmd.addr = uint64(uintptr(unsafe.Pointer(&data[0])))
+	cmd.dataLen = uint32(len(data))
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&cmd)))
+	runtime.KeepAlive(data)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
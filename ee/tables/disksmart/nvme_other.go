@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package disksmart
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// readNVMeDisks is only implemented on Linux; elsewhere, SMART data requires smartctl.
+func readNVMeDisks(_ context.Context, _ *slog.Logger) ([]diskInfo, error) {
+	return nil, errors.New("reading NVMe devices directly is not supported on this platform")
+}
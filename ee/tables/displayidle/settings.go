@@ -0,0 +1,277 @@
+// Package displayidle provides a table normalizing the display sleep, screen lock timeout, and
+// "require password after sleep" settings across platforms. Each platform keeps these in
+// different places -- pmset and the screensaver preferences on macOS, the power scheme and
+// screen saver registry settings on Windows, and GNOME's gsettings on Linux -- so baselines
+// otherwise need a separate query for each.
+package displayidle
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"howett.net/plist"
+)
+
+const tableName = "kolide_display_and_idle_settings"
+
+// The normalized settings. Durations are in seconds, with 0 meaning never; booleans are 0 or 1.
+const (
+	settingDisplaySleep              = "display_sleep"                // idle time before the display sleeps
+	settingScreenLockTimeout         = "screen_lock_timeout"          // idle time before the screen saver or lock starts
+	settingRequirePasswordAfterSleep = "require_password_after_sleep" // whether waking requires a password
+	settingRequirePasswordDelay      = "require_password_delay"       // grace period before a password is required
+)
+
+const (
+	powerSourceAc      = "ac"
+	powerSourceBattery = "battery"
+	powerSourceUps     = "ups"
+)
+
+var columns = []table.ColumnDefinition{
+	table.TextColumn("username"),
+	table.TextColumn("power_source"),
+	table.TextColumn("setting"),
+	table.TextColumn("value"),
+	table.TextColumn("source"),
+}
+
+// settingRow returns a row for a setting. username is empty for system-wide settings, and
+// powerSource is empty for settings that don't depend on the power source.
+func settingRow(username, powerSource, setting, value, source string) map[string]string {
+	return map[string]string{
+		"username":     username,
+		"power_source": powerSource,
+		"setting":      setting,
+		"value":        value,
+		"source":       source,
+	}
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// pmsetPowerSources maps the section headers of `pmset -g custom` to power sources
+var pmsetPowerSources = map[string]string{
+	"AC Power":      powerSourceAc,
+	"Battery Power": powerSourceBattery,
+	"UPS Power":     powerSourceUps,
+}
+
+// parsePmsetDisplaySleep returns the displaysleep setting, in seconds, for each power source in
+// the output of `pmset -g custom`, e.g.
+//
+//	Battery Power:
+//	 displaysleep         2
+//	 Sleep On Power Button 1
+//	AC Power:
+//	 displaysleep         10
+func parsePmsetDisplaySleep(output []byte) map[string]string {
+	results := make(map[string]string)
+
+	powerSource := ""
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if header, found := strings.CutSuffix(line, ":"); found {
+			powerSource = pmsetPowerSources[header]
+			continue
+		}
+		if powerSource == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "displaysleep" {
+			continue
+		}
+
+		minutes, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		results[powerSource] = strconv.Itoa(minutes * 60)
+	}
+
+	return results
+}
+
+var screenLockDelayRegex = regexp.MustCompile(`screenLock delay is (\d+) seconds`)
+
+// parseScreenLockStatus parses the output of `sysadminctl -screenLock status`, returning whether
+// a password is required after sleep or the screen saver starts, and after how many seconds. ok
+// is false if the output couldn't be parsed.
+func parseScreenLockStatus(output string) (enabled bool, delay string, ok bool) {
+	switch {
+	case strings.Contains(output, "screenLock is off"):
+		return false, "", true
+	case strings.Contains(output, "screenLock delay is immediate"):
+		return true, "0", true
+	}
+
+	if m := screenLockDelayRegex.FindStringSubmatch(output); m != nil {
+		return true, m[1], true
+	}
+
+	return false, "", false
+}
+
+// parseGsettings parses the output of `gsettings list-recursively`, returning the values keyed by
+// "schema key". GVariant type annotations, as in "uint32 300", are dropped.
+func parseGsettings(output []byte) map[string]string {
+	results := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 3)
+		if len(parts) < 3 {
+			continue
+		}
+
+		value := parts[2]
+		for _, prefix := range []string{"uint32 ", "int32 ", "uint64 ", "int64 "} {
+			value = strings.TrimPrefix(value, prefix)
+		}
+		results[parts[0]+" "+parts[1]] = value
+	}
+
+	return results
+}
+
+const (
+	gnomeIdleDelay      = "org.gnome.desktop.session idle-delay"
+	gnomeLockEnabled    = "org.gnome.desktop.screensaver lock-enabled"
+	gnomeLockDelay      = "org.gnome.desktop.screensaver lock-delay"
+	ubuntuLockOnSuspend = "org.gnome.desktop.screensaver ubuntu-lock-on-suspend"
+	gsettingsSource     = "gsettings"
+)
+
+// gnomeRows normalizes a user's GNOME settings. GNOME blanks the screen after idle-delay, and
+// locks it lock-delay later, if locking is enabled.
+func gnomeRows(username string, values map[string]string) []map[string]string {
+	var results []map[string]string
+
+	idleDelay, hasIdleDelay := values[gnomeIdleDelay]
+	lockEnabled, hasLockEnabled := values[gnomeLockEnabled]
+	lockDelay, hasLockDelay := values[gnomeLockDelay]
+
+	if hasIdleDelay {
+		results = append(results, settingRow(username, "", settingDisplaySleep, idleDelay, gsettingsSource))
+	}
+
+	if hasIdleDelay && hasLockEnabled {
+		timeout := "0"
+		if lockEnabled == "true" && idleDelay != "0" {
+			idle, idleErr := strconv.Atoi(idleDelay)
+			delay, delayErr := strconv.Atoi(lockDelay)
+			if idleErr == nil && delayErr == nil {
+				timeout = strconv.Itoa(idle + delay)
+			} else {
+				timeout = idleDelay
+			}
+		}
+		results = append(results, settingRow(username, "", settingScreenLockTimeout, timeout, gsettingsSource))
+	}
+
+	// Ubuntu patches GNOME to lock on suspend separately from locking when idle
+	if lockOnSuspend, ok := values[ubuntuLockOnSuspend]; ok {
+		results = append(results, settingRow(username, "", settingRequirePasswordAfterSleep, boolToIntString(lockOnSuspend == "true"), gsettingsSource))
+	} else if hasLockEnabled {
+		results = append(results, settingRow(username, "", settingRequirePasswordAfterSleep, boolToIntString(lockEnabled == "true"), gsettingsSource))
+	}
+
+	if hasLockDelay {
+		results = append(results, settingRow(username, "", settingRequirePasswordDelay, lockDelay, gsettingsSource))
+	}
+
+	return results
+}
+
+// screensaverPrefs holds the com.apple.screensaver keys we normalize. Each is nil if unset.
+type screensaverPrefs struct {
+	idleTime            *string
+	askForPassword      *bool
+	askForPasswordDelay *string
+}
+
+// parseScreensaverPrefs decodes a com.apple.screensaver plist. Managed profiles aren't consistent
+// about types -- askForPassword may be a boolean or an integer, and askForPasswordDelay an integer
+// or a real -- so the values are decoded loosely.
+func parseScreensaverPrefs(raw []byte) (screensaverPrefs, error) {
+	var decoded map[string]interface{}
+	if _, err := plist.Unmarshal(raw, &decoded); err != nil {
+		return screensaverPrefs{}, err
+	}
+
+	var prefs screensaverPrefs
+	if v, ok := plistSeconds(decoded["idleTime"]); ok {
+		prefs.idleTime = &v
+	}
+	if v, ok := plistSeconds(decoded["askForPasswordDelay"]); ok {
+		prefs.askForPasswordDelay = &v
+	}
+	switch v := decoded["askForPassword"].(type) {
+	case bool:
+		prefs.askForPassword = &v
+	case uint64, int64, float64:
+		enabled := v != uint64(0) && v != int64(0) && v != float64(0)
+		prefs.askForPassword = &enabled
+	}
+
+	return prefs, nil
+}
+
+// plistSeconds formats a numeric plist value as whole seconds.
+func plistSeconds(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatInt(int64(v), 10), true
+	default:
+		return "", false
+	}
+}
+
+// screenSaverValues are the Windows screen saver settings from a user's Control Panel\Desktop
+// key, or its policy equivalent. Each is empty if unset.
+type screenSaverValues struct {
+	active  string // ScreenSaveActive
+	secure  string // ScreenSaverIsSecure
+	timeout string // ScreenSaveTimeOut, in seconds
+}
+
+// screenSaverLockTimeout returns how long, in seconds, the screen saver waits before locking the
+// session, with policy values taking precedence over the user's own. The screen saver only locks
+// the session if it's both active and secure; otherwise, the timeout is 0, for never. ok is false
+// if there's nothing to report.
+func screenSaverLockTimeout(user, policy screenSaverValues) (string, bool) {
+	effective := user
+	if policy.active != "" {
+		effective.active = policy.active
+	}
+	if policy.secure != "" {
+		effective.secure = policy.secure
+	}
+	if policy.timeout != "" {
+		effective.timeout = policy.timeout
+	}
+
+	if effective == (screenSaverValues{}) {
+		return "", false
+	}
+	if effective.active != "1" || effective.secure != "1" || effective.timeout == "" {
+		return "0", true
+	}
+	return effective.timeout, true
+}
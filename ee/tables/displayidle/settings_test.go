@@ -0,0 +1,132 @@
+package displayidle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePmsetDisplaySleep(t *testing.T) {
+	t.Parallel()
+
+	output := []byte(`Battery Power:
+ lidwake              1
+ displaysleep         2
+ Sleep On Power Button 1
+AC Power:
+ displaysleep         0
+ sleep                1
+`)
+
+	require.Equal(t, map[string]string{
+		powerSourceBattery: "120",
+		powerSourceAc:      "0",
+	}, parsePmsetDisplaySleep(output))
+
+	require.Empty(t, parsePmsetDisplaySleep([]byte("displaysleep 10\n")), "settings outside a power source section are ignored")
+}
+
+func TestParseScreenLockStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		output          string
+		expectedEnabled bool
+		expectedDelay   string
+		expectedOk      bool
+	}{
+		{output: "2024-05-01 10:00:00.000 sysadminctl[1234:5678] screenLock delay is immediate", expectedEnabled: true, expectedDelay: "0", expectedOk: true},
+		{output: "2024-05-01 10:00:00.000 sysadminctl[1234:5678] screenLock delay is 300 seconds", expectedEnabled: true, expectedDelay: "300", expectedOk: true},
+		{output: "2024-05-01 10:00:00.000 sysadminctl[1234:5678] screenLock is off", expectedOk: true},
+		{output: "usage: sysadminctl"},
+	} {
+		enabled, delay, ok := parseScreenLockStatus(tt.output)
+		require.Equal(t, tt.expectedEnabled, enabled, tt.output)
+		require.Equal(t, tt.expectedDelay, delay, tt.output)
+		require.Equal(t, tt.expectedOk, ok, tt.output)
+	}
+}
+
+func TestGnomeRows(t *testing.T) {
+	t.Parallel()
+
+	values := parseGsettings([]byte(`org.gnome.desktop.session idle-delay uint32 300
+org.gnome.desktop.screensaver lock-enabled true
+org.gnome.desktop.screensaver lock-delay uint32 30
+org.gnome.desktop.screensaver picture-uri 'file:///usr/share/backgrounds/warty-final-ubuntu.png'
+`))
+
+	require.Equal(t, []map[string]string{
+		settingRow("alice", "", settingDisplaySleep, "300", gsettingsSource),
+		settingRow("alice", "", settingScreenLockTimeout, "330", gsettingsSource),
+		settingRow("alice", "", settingRequirePasswordAfterSleep, "1", gsettingsSource),
+		settingRow("alice", "", settingRequirePasswordDelay, "30", gsettingsSource),
+	}, gnomeRows("alice", values))
+
+	// The screen never locks when locking is disabled, or the screen never blanks
+	values[gnomeLockEnabled] = "false"
+	values[ubuntuLockOnSuspend] = "true"
+	rows := gnomeRows("alice", values)
+	require.Contains(t, rows, settingRow("alice", "", settingScreenLockTimeout, "0", gsettingsSource))
+	require.Contains(t, rows, settingRow("alice", "", settingRequirePasswordAfterSleep, "1", gsettingsSource))
+
+	values[gnomeLockEnabled] = "true"
+	values[gnomeIdleDelay] = "0"
+	require.Contains(t, gnomeRows("alice", values), settingRow("alice", "", settingScreenLockTimeout, "0", gsettingsSource))
+
+	require.Empty(t, gnomeRows("alice", map[string]string{}))
+}
+
+func TestParseScreensaverPrefs(t *testing.T) {
+	t.Parallel()
+
+	prefs, err := parseScreensaverPrefs([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>idleTime</key>
+	<integer>600</integer>
+	<key>askForPassword</key>
+	<integer>1</integer>
+	<key>askForPasswordDelay</key>
+	<real>5</real>
+</dict>
+</plist>`))
+	require.NoError(t, err)
+	require.Equal(t, "600", *prefs.idleTime)
+	require.True(t, *prefs.askForPassword)
+	require.Equal(t, "5", *prefs.askForPasswordDelay)
+
+	prefs, err = parseScreensaverPrefs([]byte(`<plist version="1.0"><dict><key>askForPassword</key><false/></dict></plist>`))
+	require.NoError(t, err)
+	require.Nil(t, prefs.idleTime)
+	require.False(t, *prefs.askForPassword)
+	require.Nil(t, prefs.askForPasswordDelay)
+}
+
+func TestScreenSaverLockTimeout(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name            string
+		user            screenSaverValues
+		policy          screenSaverValues
+		expectedTimeout string
+		expectedOk      bool
+	}{
+		{name: "unset"},
+		{name: "secure screen saver", user: screenSaverValues{active: "1", secure: "1", timeout: "600"}, expectedTimeout: "600", expectedOk: true},
+		{name: "screen saver doesn't lock", user: screenSaverValues{active: "1", secure: "0", timeout: "600"}, expectedTimeout: "0", expectedOk: true},
+		{name: "screen saver inactive", user: screenSaverValues{active: "0", secure: "1", timeout: "600"}, expectedTimeout: "0", expectedOk: true},
+		{
+			name:            "policy takes precedence",
+			user:            screenSaverValues{active: "1", secure: "0", timeout: "1800"},
+			policy:          screenSaverValues{secure: "1", timeout: "900"},
+			expectedTimeout: "900",
+			expectedOk:      true,
+		},
+	} {
+		timeout, ok := screenSaverLockTimeout(tt.user, tt.policy)
+		require.Equal(t, tt.expectedTimeout, timeout, tt.name)
+		require.Equal(t, tt.expectedOk, ok, tt.name)
+	}
+}
@@ -0,0 +1,251 @@
+//go:build darwin
+// +build darwin
+
+package displayidle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/consoleuser"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+	managedPreferencesDir = "/Library/Managed Preferences"
+	screensaverDomain     = "com.apple.screensaver"
+
+	managedPreferencesSource = "managed_preferences"
+	userPreferencesSource    = "user_preferences"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	if len(usernames) == 0 {
+		results = append(results, t.displaySleepRows(ctx)...)
+	}
+
+	consoleUsernames := t.consoleUsernames(ctx)
+
+	entries, err := os.ReadDir("/Users")
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list users",
+			"err", err,
+		)
+		return results, nil
+	}
+
+	for _, entry := range entries {
+		username := entry.Name()
+		if !entry.IsDir() || username == "Shared" || strings.HasPrefix(username, ".") {
+			continue
+		}
+		if len(usernames) > 0 && !slices.Contains(usernames, username) {
+			continue
+		}
+
+		results = append(results, t.userRows(ctx, username, slices.Contains(consoleUsernames, username))...)
+	}
+
+	return results, nil
+}
+
+// displaySleepRows returns the display sleep setting for each power source, from pmset.
+func (t *Table) displaySleepRows(ctx context.Context) []map[string]string {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 5, allowedcmd.Pmset, []string{"-g", "custom"}, &stdout, &stderr); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not run pmset",
+			"stderr", stderr.String(),
+			"err", err,
+		)
+		return nil
+	}
+
+	var results []map[string]string
+	for powerSource, seconds := range parsePmsetDisplaySleep(stdout.Bytes()) {
+		results = append(results, settingRow("", powerSource, settingDisplaySleep, seconds, "pmset"))
+	}
+	return results
+}
+
+// userRows returns the given user's screen saver and screen lock settings. Managed preferences
+// take precedence over the user's own. Whether a password is required is only available from the
+// user's preferences while they're logged in.
+func (t *Table) userRows(ctx context.Context, username string, loggedIn bool) []map[string]string {
+	var results []map[string]string
+
+	managed := t.managedScreensaverPrefs(ctx, username)
+	own := t.userScreensaverPrefs(ctx, username)
+
+	switch {
+	case managed.idleTime != nil:
+		results = append(results, settingRow(username, "", settingScreenLockTimeout, *managed.idleTime, managedPreferencesSource))
+	case own.idleTime != nil:
+		results = append(results, settingRow(username, "", settingScreenLockTimeout, *own.idleTime, userPreferencesSource))
+	}
+
+	if managed.askForPassword != nil {
+		results = append(results, settingRow(username, "", settingRequirePasswordAfterSleep, boolToIntString(*managed.askForPassword), managedPreferencesSource))
+		if managed.askForPasswordDelay != nil {
+			results = append(results, settingRow(username, "", settingRequirePasswordDelay, *managed.askForPasswordDelay, managedPreferencesSource))
+		}
+		return results
+	}
+
+	if !loggedIn {
+		return results
+	}
+
+	enabled, delay, err := t.screenLockStatus(ctx, username)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get screen lock status",
+			"username", username,
+			"err", err,
+		)
+		return results
+	}
+
+	results = append(results, settingRow(username, "", settingRequirePasswordAfterSleep, boolToIntString(enabled), "sysadminctl"))
+	if enabled {
+		results = append(results, settingRow(username, "", settingRequirePasswordDelay, delay, "sysadminctl"))
+	}
+
+	return results
+}
+
+// managedScreensaverPrefs reads the screensaver preferences set by configuration profiles,
+// for the given user or the whole computer.
+func (t *Table) managedScreensaverPrefs(ctx context.Context, username string) screensaverPrefs {
+	for _, path := range []string{
+		filepath.Join(managedPreferencesDir, username, screensaverDomain+".plist"),
+		filepath.Join(managedPreferencesDir, screensaverDomain+".plist"),
+	} {
+		prefs, err := t.readScreensaverPrefs(ctx, path)
+		if err != nil {
+			continue
+		}
+		if prefs.idleTime != nil || prefs.askForPassword != nil {
+			return prefs
+		}
+	}
+
+	return screensaverPrefs{}
+}
+
+// userScreensaverPrefs reads the screensaver preferences the user set themselves, which are
+// kept per host.
+func (t *Table) userScreensaverPrefs(ctx context.Context, username string) screensaverPrefs {
+	paths, err := filepath.Glob(filepath.Join("/Users", username, "Library", "Preferences", "ByHost", screensaverDomain+".*.plist"))
+	if err != nil || len(paths) == 0 {
+		return screensaverPrefs{}
+	}
+
+	prefs, err := t.readScreensaverPrefs(ctx, paths[0])
+	if err != nil {
+		return screensaverPrefs{}
+	}
+	return prefs
+}
+
+func (t *Table) readScreensaverPrefs(ctx context.Context, path string) (screensaverPrefs, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read screensaver preferences",
+				"path", path,
+				"err", err,
+			)
+		}
+		return screensaverPrefs{}, err
+	}
+
+	prefs, err := parseScreensaverPrefs(raw)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not parse screensaver preferences",
+			"path", path,
+			"err", err,
+		)
+		return screensaverPrefs{}, err
+	}
+
+	return prefs, nil
+}
+
+// screenLockStatus runs `sysadminctl -screenLock status` in the user's login session.
+func (t *Table) screenLockStatus(ctx context.Context, username string) (bool, string, error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return false, "", fmt.Errorf("looking up user: %w", err)
+	}
+
+	// sysadminctl writes its status to stderr
+	var output bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.Sysadminctl, []string{"-screenLock", "status"}, &output, &output,
+		tablehelpers.WithUserContext(u.Uid),
+	); err != nil {
+		return false, "", fmt.Errorf("running sysadminctl: %w", err)
+	}
+
+	enabled, delay, ok := parseScreenLockStatus(output.String())
+	if !ok {
+		return false, "", fmt.Errorf("unexpected sysadminctl output: %s", output.String())
+	}
+
+	return enabled, delay, nil
+}
+
+// consoleUsernames returns the usernames of the users logged in at the console.
+func (t *Table) consoleUsernames(ctx context.Context) []string {
+	uids, err := consoleuser.CurrentUids(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get console users",
+			"err", err,
+		)
+		return nil
+	}
+
+	var usernames []string
+	for _, uid := range uids {
+		if u, err := user.LookupId(uid); err == nil {
+			usernames = append(usernames, u.Username)
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,118 @@
+//go:build linux
+// +build linux
+
+package displayidle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"slices"
+
+	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/consoleuser"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+// gnomeSchemas are the gsettings schemas holding the settings we normalize
+var gnomeSchemas = []string{"org.gnome.desktop.session", "org.gnome.desktop.screensaver"}
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	// These settings live in each user's dconf database, so only check the users logged in
+	uids, err := consoleuser.CurrentUids(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get console users",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	for _, uid := range uids {
+		u, err := user.LookupId(uid)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not look up console user",
+				"uid", uid,
+				"err", err,
+			)
+			continue
+		}
+		if len(usernames) > 0 && !slices.Contains(usernames, u.Username) {
+			continue
+		}
+
+		values := make(map[string]string)
+		for _, schema := range gnomeSchemas {
+			output, err := t.gsettings(ctx, u, schema)
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not read gsettings",
+					"username", u.Username,
+					"schema", schema,
+					"err", err,
+				)
+				continue
+			}
+			for k, v := range parseGsettings(output) {
+				values[k] = v
+			}
+		}
+
+		results = append(results, gnomeRows(u.Username, values)...)
+	}
+
+	return results, nil
+}
+
+// gsettings lists the given schema's settings, as the given user.
+func (t *Table) gsettings(ctx context.Context, u *user.User, schema string) ([]byte, error) {
+	dir, err := agent.MkdirTemp("osq-displayidle")
+	if err != nil {
+		return nil, fmt.Errorf("mktemp: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// gsettings needs to be able to run from the working directory, as the user
+	if err := os.Chmod(dir, 0755); err != nil {
+		return nil, fmt.Errorf("chmod: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 5,
+		allowedcmd.Gsettings, []string{"list-recursively", schema}, &stdout, &stderr,
+		tablehelpers.WithUid(u.Uid),
+		tablehelpers.WithAppendEnv("HOME", u.HomeDir),
+		tablehelpers.WithDir(dir),
+	); err != nil {
+		return nil, fmt.Errorf("running gsettings: %w", err)
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,260 @@
+//go:build windows
+// +build windows
+
+package displayidle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_. "
+
+	systemPolicyKey  = `SOFTWARE\Microsoft\Windows\CurrentVersion\Policies\System`
+	winlogonKey      = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\Winlogon`
+	desktopKey       = `Control Panel\Desktop`
+	desktopPolicyKey = `Software\Policies\Microsoft\Windows\Control Panel\Desktop`
+
+	powerSchemeSource      = "power_scheme"
+	screenSaverSource      = "screen_saver"
+	inactivityPolicySource = "inactivity_timeout_policy"
+	winlogonSource         = "winlogon"
+)
+
+var (
+	powrprof                  = windows.NewLazySystemDLL("powrprof.dll")
+	procPowerGetActiveScheme  = powrprof.NewProc("PowerGetActiveScheme")
+	procPowerReadACValueIndex = powrprof.NewProc("PowerReadACValueIndex")
+	procPowerReadDCValueIndex = powrprof.NewProc("PowerReadDCValueIndex")
+
+	// Power setting GUIDs, see `powercfg /aliases`
+	subVideoGuid    = windows.GUID{Data1: 0x7516b95f, Data2: 0xf776, Data3: 0x4464, Data4: [8]byte{0x8c, 0x53, 0x06, 0x16, 0x7f, 0x40, 0xcc, 0x99}}
+	videoIdleGuid   = windows.GUID{Data1: 0x3c0bc021, Data2: 0xc8a8, Data3: 0x4e07, Data4: [8]byte{0xa9, 0x73, 0x6b, 0x14, 0xcb, 0xcb, 0x2b, 0x7e}}
+	subNoneGuid     = windows.GUID{Data1: 0xfea3413e, Data2: 0x7e05, Data3: 0x4911, Data4: [8]byte{0x9a, 0x71, 0x70, 0x03, 0x31, 0xf1, 0xc2, 0x94}}
+	consoleLockGuid = windows.GUID{Data1: 0x0e796bdb, Data2: 0x100d, Data3: 0x47d6, Data4: [8]byte{0xa2, 0xd5, 0xf7, 0xd2, 0xda, 0xa5, 0x1f, 0x51}}
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	if len(usernames) == 0 {
+		results = append(results, t.powerSchemeRows(ctx)...)
+		results = append(results, t.machinePolicyRows(ctx)...)
+	}
+
+	results = append(results, t.userRows(ctx, usernames)...)
+
+	return results, nil
+}
+
+// powerSchemeRows returns the display timeout, and whether waking requires a password, from the
+// active power scheme, for each power source.
+func (t *Table) powerSchemeRows(ctx context.Context) []map[string]string {
+	var scheme *windows.GUID
+	if ret, _, _ := procPowerGetActiveScheme.Call(0, uintptr(unsafe.Pointer(&scheme))); ret != 0 {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get active power scheme",
+			"err", windows.Errno(ret),
+		)
+		return nil
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(scheme)))
+
+	var results []map[string]string
+	for _, powerSource := range []string{powerSourceAc, powerSourceBattery} {
+		if seconds, err := readPowerSetting(scheme, &subVideoGuid, &videoIdleGuid, powerSource); err == nil {
+			results = append(results, settingRow("", powerSource, settingDisplaySleep, strconv.FormatUint(uint64(seconds), 10), powerSchemeSource))
+		} else {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read display timeout",
+				"power_source", powerSource,
+				"err", err,
+			)
+		}
+
+		if consoleLock, err := readPowerSetting(scheme, &subNoneGuid, &consoleLockGuid, powerSource); err == nil {
+			results = append(results, settingRow("", powerSource, settingRequirePasswordAfterSleep, boolToIntString(consoleLock != 0), powerSchemeSource))
+		} else {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read console lock setting",
+				"power_source", powerSource,
+				"err", err,
+			)
+		}
+	}
+
+	return results
+}
+
+// readPowerSetting reads a setting's value from the given power scheme, for the given power source.
+func readPowerSetting(scheme, subgroup, setting *windows.GUID, powerSource string) (uint32, error) {
+	proc := procPowerReadACValueIndex
+	if powerSource == powerSourceBattery {
+		proc = procPowerReadDCValueIndex
+	}
+
+	var value uint32
+	ret, _, _ := proc.Call(0,
+		uintptr(unsafe.Pointer(scheme)),
+		uintptr(unsafe.Pointer(subgroup)),
+		uintptr(unsafe.Pointer(setting)),
+		uintptr(unsafe.Pointer(&value)),
+	)
+	if ret != 0 {
+		return 0, fmt.Errorf("%s: %w", proc.Name, windows.Errno(ret))
+	}
+
+	return value, nil
+}
+
+// machinePolicyRows returns the machine inactivity limit, which locks the session regardless of
+// the screen saver, and the screen saver grace period.
+func (t *Table) machinePolicyRows(ctx context.Context) []map[string]string {
+	var results []map[string]string
+
+	if key, err := registry.OpenKey(registry.LOCAL_MACHINE, systemPolicyKey, registry.QUERY_VALUE); err == nil {
+		if timeout, _, err := key.GetIntegerValue("InactivityTimeoutSecs"); err == nil {
+			results = append(results, settingRow("", "", settingScreenLockTimeout, strconv.FormatUint(timeout, 10), inactivityPolicySource))
+		}
+		key.Close()
+	}
+
+	if key, err := registry.OpenKey(registry.LOCAL_MACHINE, winlogonKey, registry.QUERY_VALUE); err == nil {
+		if gracePeriod, _, err := key.GetStringValue("ScreenSaverGracePeriod"); err == nil {
+			results = append(results, settingRow("", "", settingRequirePasswordDelay, strings.TrimSpace(gracePeriod), winlogonSource))
+		}
+		key.Close()
+	}
+
+	return results
+}
+
+// userRows returns the screen saver lock timeout of each user with a loaded registry hive -- that
+// is, each logged-in user.
+func (t *Table) userRows(ctx context.Context, usernames []string) []map[string]string {
+	users, err := registry.OpenKey(registry.USERS, "", registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not open HKEY_USERS",
+			"err", err,
+		)
+		return nil
+	}
+	defer users.Close()
+
+	sids, err := users.ReadSubKeyNames(0)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list HKEY_USERS",
+			"err", err,
+		)
+		return nil
+	}
+
+	var results []map[string]string
+	for _, sid := range sids {
+		// Only local and domain user accounts
+		if !strings.HasPrefix(sid, "S-1-5-21-") || strings.HasSuffix(sid, "_Classes") {
+			continue
+		}
+
+		username, err := accountName(sid)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not look up account",
+				"sid", sid,
+				"err", err,
+			)
+			continue
+		}
+		if len(usernames) > 0 && !slices.Contains(usernames, username) {
+			continue
+		}
+
+		own := readScreenSaverValues(sid + `\` + desktopKey)
+		policy := readScreenSaverValues(sid + `\` + desktopPolicyKey)
+		timeout, ok := screenSaverLockTimeout(own, policy)
+		if !ok {
+			continue
+		}
+
+		results = append(results, settingRow(username, "", settingScreenLockTimeout, timeout, screenSaverSource))
+	}
+
+	return results
+}
+
+// readScreenSaverValues reads the screen saver settings from the given key under HKEY_USERS.
+func readScreenSaverValues(keyPath string) screenSaverValues {
+	key, err := registry.OpenKey(registry.USERS, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return screenSaverValues{}
+	}
+	defer key.Close()
+
+	var values screenSaverValues
+	values.active, _ = registryValueString(key, "ScreenSaveActive")
+	values.secure, _ = registryValueString(key, "ScreenSaverIsSecure")
+	values.timeout, _ = registryValueString(key, "ScreenSaveTimeOut")
+	return values
+}
+
+// registryValueString reads a value that may be stored as a string or an integer, as these
+// settings are by different tools.
+func registryValueString(key registry.Key, name string) (string, error) {
+	if val, _, err := key.GetStringValue(name); err == nil {
+		return strings.TrimSpace(val), nil
+	} else if !errors.Is(err, registry.ErrUnexpectedType) {
+		return "", err
+	}
+
+	val, _, err := key.GetIntegerValue(name)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(val, 10), nil
+}
+
+// accountName returns the username of the account with the given SID.
+func accountName(sid string) (string, error) {
+	s, err := windows.StringToSid(sid)
+	if err != nil {
+		return "", fmt.Errorf("parsing sid: %w", err)
+	}
+
+	account, _, _, err := s.LookupAccount("")
+	if err != nil {
+		return "", fmt.Errorf("looking up account: %w", err)
+	}
+
+	return account, nil
+}
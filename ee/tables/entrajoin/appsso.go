@@ -0,0 +1,114 @@
+package entrajoin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// appSsoSectionRegex matches the section titles in `app-sso platform -s` output, like
+// `Device Configuration:`. Each is followed by a JSON object, or `none`.
+var appSsoSectionRegex = regexp.MustCompile(`^([A-Z][A-Za-z ]+):\s*$`)
+
+// entraEndpointRegex captures the tenant from Entra ID endpoints, like
+// https://login.microsoftonline.com/<tenant>/oauth2/v2.0/token
+var entraEndpointRegex = regexp.MustCompile(`^https://login\.microsoftonline\.(?:com|us)/([^/]+)/`)
+
+var guidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// parseAppSso parses the output of `app-sso platform -s` into its sections. Sections
+// that aren't configured have no value.
+func parseAppSso(reader io.Reader) (map[string]map[string]any, error) {
+	sections := make(map[string]map[string]any)
+
+	var currentSection string
+	var body strings.Builder
+	finishSection := func() error {
+		defer body.Reset()
+
+		if currentSection == "" {
+			return nil
+		}
+		sections[currentSection] = nil
+
+		trimmed := strings.TrimSpace(body.String())
+		if !strings.HasPrefix(trimmed, "{") {
+			return nil
+		}
+
+		var config map[string]any
+		if err := json.Unmarshal([]byte(trimmed), &config); err != nil {
+			return fmt.Errorf("parsing %s: %w", currentSection, err)
+		}
+		sections[currentSection] = config
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := appSsoSectionRegex.FindStringSubmatch(line); m != nil {
+			if err := finishSection(); err != nil {
+				return nil, err
+			}
+			currentSection = m[1]
+			continue
+		}
+
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading app-sso output: %w", err)
+	}
+
+	if err := finishSection(); err != nil {
+		return nil, err
+	}
+
+	return sections, nil
+}
+
+// statusFromPlatformSso summarizes Platform SSO's device registration. It returns false if
+// Platform SSO isn't configured.
+func statusFromPlatformSso(sections map[string]map[string]any) (joinStatus, bool) {
+	device := sections["Device Configuration"]
+	if device == nil {
+		return joinStatus{}, false
+	}
+
+	status := joinStatus{
+		source:   "platform_sso",
+		joinType: joinTypePlatformSso,
+	}
+	status.joined, _ = device["registrationCompleted"].(bool)
+	status.ssoExtension, _ = device["extensionIdentifier"].(string)
+
+	// The tenant is part of the Entra ID endpoints, though the device configuration
+	// may only use the common ones.
+	for _, section := range []map[string]any{sections["Login Configuration"], device} {
+		for _, key := range []string{"tokenEndpointURL", "jwksEndpointURL", "nonceEndpointURL"} {
+			endpoint, _ := section[key].(string)
+			m := entraEndpointRegex.FindStringSubmatch(endpoint)
+			if m == nil {
+				continue
+			}
+
+			switch tenant := m[1]; {
+			case tenant == "common" || tenant == "organizations":
+				continue
+			case guidRegex.MatchString(tenant):
+				status.tenantId = tenant
+			default:
+				status.tenantName = tenant
+			}
+			return status, true
+		}
+	}
+
+	return status, true
+}
@@ -0,0 +1,61 @@
+package entrajoin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusFromPlatformSso(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filepath.Join("test-data", "app_sso_platform.txt"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	sections, err := parseAppSso(f)
+	require.NoError(t, err)
+	require.Contains(t, sections, "User Configuration")
+	require.Nil(t, sections["User Configuration"])
+
+	status, ok := statusFromPlatformSso(sections)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{
+		"source":             "platform_sso",
+		"username":           "",
+		"join_type":          "platform_sso",
+		"joined":             "1",
+		"device_id":          "",
+		"tenant_id":          "72f988bf-86f1-41af-91ab-2d7cd011db47",
+		"tenant_name":        "",
+		"device_auth_status": "",
+		"prt_present":        "",
+		"prt_update_time":    "",
+		"sso_extension":      "com.microsoft.CompanyPortalMac.ssoextension",
+	}, status.toRow())
+}
+
+func TestStatusFromPlatformSsoNotConfigured(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filepath.Join("test-data", "app_sso_not_configured.txt"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	sections, err := parseAppSso(f)
+	require.NoError(t, err)
+	require.Len(t, sections, 3)
+
+	_, ok := statusFromPlatformSso(sections)
+	require.False(t, ok)
+}
+
+func TestParseAppSsoMalformed(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseAppSso(strings.NewReader("Device Configuration:\n {\n  \"registrationCompleted\" : \n"))
+	require.Error(t, err)
+}
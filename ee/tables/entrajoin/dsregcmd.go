@@ -0,0 +1,67 @@
+package entrajoin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// statusFromDsregcmd summarizes the sections parsed from `dsregcmd /status` output. The user
+// and SSO state describe the account dsregcmd ran as.
+func statusFromDsregcmd(parsed any) (joinStatus, error) {
+	sections, ok := parsed.(map[string]map[string]interface{})
+	if !ok {
+		return joinStatus{}, fmt.Errorf("unexpected dsregcmd output type %T", parsed)
+	}
+	if _, ok := sections["Device State"]; !ok {
+		return joinStatus{}, fmt.Errorf("no device state in dsregcmd output")
+	}
+
+	value := func(section, key string) string {
+		v, _ := sections[section][key].(string)
+		return v
+	}
+	yes := func(section, key string) bool {
+		return strings.EqualFold(value(section, key), "YES")
+	}
+
+	status := joinStatus{
+		source:           "dsregcmd",
+		deviceId:         value("Device Details", "DeviceId"),
+		tenantId:         value("Tenant Details", "TenantId"),
+		tenantName:       value("Tenant Details", "TenantName"),
+		deviceAuthStatus: value("Device Details", "DeviceAuthStatus"),
+		prtUpdateTime:    value("SSO State", "AzureAdPrtUpdateTime"),
+	}
+
+	if _, ok := sections["SSO State"]["AzureAdPrt"]; ok {
+		prtPresent := yes("SSO State", "AzureAdPrt")
+		status.prtPresent = &prtPresent
+	}
+
+	switch {
+	case yes("Device State", "AzureAdJoined") && yes("Device State", "DomainJoined"):
+		status.joinType = joinTypeHybrid
+	case yes("Device State", "AzureAdJoined"):
+		status.joinType = joinTypeEntra
+	case yes("Device State", "EnterpriseJoined"):
+		status.joinType = joinTypeEnterprise
+	case yes("User State", "WorkplaceJoined"):
+		status.joinType = joinTypeWorkplace
+	default:
+		status.joinType = joinTypeNone
+	}
+	status.joined = status.joinType != joinTypeNone
+
+	// Workplace joined devices report their tenant in the user's registration
+	if status.tenantName == "" {
+		status.tenantName = value("Work Account 1", "WorkplaceTenantName")
+	}
+	if status.tenantId == "" {
+		status.tenantId = value("Work Account 1", "WorkplaceTenantId")
+	}
+	if status.deviceId == "" {
+		status.deviceId = value("Work Account 1", "WorkplaceDeviceId")
+	}
+
+	return status, nil
+}
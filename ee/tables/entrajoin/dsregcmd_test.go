@@ -0,0 +1,81 @@
+package entrajoin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/ee/tables/execparsers/dsregcmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusFromDsregcmd(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{
+			name:  "hybrid joined",
+			input: filepath.Join("test-data", "dsregcmd_hybrid_joined.txt"),
+			expected: map[string]string{
+				"source":             "dsregcmd",
+				"username":           "",
+				"join_type":          "hybrid_joined",
+				"joined":             "1",
+				"device_id":          "4f1c1b1e-7d2a-4c3b-9d8e-2a1b3c4d5e6f",
+				"tenant_id":          "72f988bf-86f1-41af-91ab-2d7cd011db47",
+				"tenant_name":        "Contoso",
+				"device_auth_status": "SUCCESS",
+				"prt_present":        "1",
+				"prt_update_time":    "2024-05-06 07:08:09.000 UTC",
+				"sso_extension":      "",
+			},
+		},
+		{
+			name:  "not configured",
+			input: filepath.Join("..", "execparsers", "dsregcmd", "test-data", "not_configured.txt"),
+			expected: map[string]string{
+				"source":             "dsregcmd",
+				"username":           "",
+				"join_type":          "not_joined",
+				"joined":             "0",
+				"device_id":          "",
+				"tenant_id":          "",
+				"tenant_name":        "",
+				"device_auth_status": "",
+				"prt_present":        "0",
+				"prt_update_time":    "",
+				"sso_extension":      "",
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			input, err := os.ReadFile(tt.input)
+			require.NoError(t, err)
+
+			parsed, err := dsregcmd.Parser.Parse(bytes.NewReader(input))
+			require.NoError(t, err)
+
+			status, err := statusFromDsregcmd(parsed)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, status.toRow())
+		})
+	}
+}
+
+func TestStatusFromDsregcmdErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := statusFromDsregcmd(map[string]map[string]interface{}{})
+	require.Error(t, err, "no device state")
+
+	_, err = statusFromDsregcmd("unexpected")
+	require.Error(t, err, "unexpected type")
+}
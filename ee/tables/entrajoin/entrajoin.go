@@ -0,0 +1,80 @@
+// Package entrajoin provides a table reporting whether the device is joined or registered to
+// Microsoft Entra ID (formerly Azure AD), for troubleshooting conditional access from the device
+// side. On Windows, this comes from `dsregcmd /status`. On macOS, it comes from Platform SSO's
+// device registration, and from the Workplace Join certificates Company Portal installs into
+// users' login keychains.
+package entrajoin
+
+import (
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_entra_join_status"
+
+// Join types
+const (
+	joinTypeEntra       = "entra_joined"
+	joinTypeHybrid      = "hybrid_joined"
+	joinTypeWorkplace   = "workplace_joined"
+	joinTypeEnterprise  = "enterprise_joined"
+	joinTypePlatformSso = "platform_sso"
+	joinTypeNone        = "not_joined"
+)
+
+var columns = []table.ColumnDefinition{
+	table.TextColumn("source"),
+	table.TextColumn("username"),
+	table.TextColumn("join_type"),
+	table.IntegerColumn("joined"),
+	table.TextColumn("device_id"),
+	table.TextColumn("tenant_id"),
+	table.TextColumn("tenant_name"),
+	table.TextColumn("device_auth_status"),
+	table.IntegerColumn("prt_present"),
+	table.TextColumn("prt_update_time"),
+	table.TextColumn("sso_extension"),
+}
+
+// joinStatus is a row of the table
+type joinStatus struct {
+	source           string
+	username         string
+	joinType         string
+	joined           bool
+	deviceId         string
+	tenantId         string
+	tenantName       string
+	deviceAuthStatus string
+	prtPresent       *bool // nil where the source doesn't report it
+	prtUpdateTime    string
+	ssoExtension     string
+}
+
+func (j joinStatus) toRow() map[string]string {
+	row := map[string]string{
+		"source":             j.source,
+		"username":           j.username,
+		"join_type":          j.joinType,
+		"joined":             boolToIntString(j.joined),
+		"device_id":          j.deviceId,
+		"tenant_id":          j.tenantId,
+		"tenant_name":        j.tenantName,
+		"device_auth_status": j.deviceAuthStatus,
+		"prt_present":        "",
+		"prt_update_time":    j.prtUpdateTime,
+		"sso_extension":      j.ssoExtension,
+	}
+
+	if j.prtPresent != nil {
+		row["prt_present"] = boolToIntString(*j.prtPresent)
+	}
+
+	return row
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
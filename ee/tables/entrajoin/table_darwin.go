@@ -0,0 +1,137 @@
+//go:build darwin
+// +build darwin
+
+package entrajoin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+	loginKeychainPath         = "Library/Keychains/login.keychain-db"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	if status, ok := t.platformSsoStatus(ctx); ok {
+		results = append(results, status.toRow())
+	}
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	keychains, err := loginKeychains(usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, keychain := range keychains {
+		var stdout, stderr bytes.Buffer
+		if err := tablehelpers.Run(ctx, t.slogger, 30, allowedcmd.Security, []string{"find-certificate", "-a", "-p", keychain.path}, &stdout, &stderr); err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"failure reading certificates from login keychain",
+				"username", keychain.username,
+				"err", err,
+				"stderr", stderr.String(),
+			)
+			continue
+		}
+
+		for _, status := range statusFromWorkplaceJoinCerts(stdout.Bytes()) {
+			status.username = keychain.username
+			results = append(results, status.toRow())
+		}
+	}
+
+	return results, nil
+}
+
+// platformSsoStatus reports Platform SSO's device registration. It returns false if Platform
+// SSO isn't available or configured.
+func (t *Table) platformSsoStatus(ctx context.Context) (joinStatus, bool) {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 30, allowedcmd.AppSso, []string{"platform", "-s"}, &stdout, &stderr); err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"failure running app-sso",
+			"err", err,
+			"stderr", stderr.String(),
+		)
+		return joinStatus{}, false
+	}
+
+	sections, err := parseAppSso(&stdout)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure parsing app-sso output",
+			"err", err,
+		)
+		return joinStatus{}, false
+	}
+
+	return statusFromPlatformSso(sections)
+}
+
+type loginKeychain struct {
+	username string
+	path     string
+}
+
+// loginKeychains returns the login keychains of the given users, or of all users if none are given.
+func loginKeychains(usernames []string) ([]loginKeychain, error) {
+	var keychains []loginKeychain
+
+	if len(usernames) == 0 {
+		matches, err := filepath.Glob(filepath.Join("/Users", "*", loginKeychainPath))
+		if err != nil {
+			return nil, fmt.Errorf("globbing for login keychains: %w", err)
+		}
+		sort.Strings(matches)
+
+		for _, m := range matches {
+			rel, err := filepath.Rel("/Users", m)
+			if err != nil {
+				continue
+			}
+			keychains = append(keychains, loginKeychain{username: strings.Split(rel, "/")[0], path: m})
+		}
+
+		return keychains, nil
+	}
+
+	for _, username := range usernames {
+		keychain := filepath.Join("/Users", username, loginKeychainPath)
+		if _, err := os.Stat(keychain); err != nil {
+			continue
+		}
+		keychains = append(keychains, loginKeychain{username: username, path: keychain})
+	}
+
+	return keychains, nil
+}
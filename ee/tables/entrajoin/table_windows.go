@@ -0,0 +1,61 @@
+//go:build windows
+// +build windows
+
+package entrajoin
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/execparsers/dsregcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+// generate reports the device's join state. Since launcher runs as SYSTEM, the user and
+// PRT state are SYSTEM's, not the logged in user's.
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 30, allowedcmd.Dsregcmd, []string{"/status"}, &stdout, &stderr); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure running dsregcmd",
+			"err", err,
+			"stderr", stderr.String(),
+		)
+		return nil, nil
+	}
+
+	parsed, err := dsregcmd.Parser.Parse(&stdout)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure parsing dsregcmd output",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	status, err := statusFromDsregcmd(parsed)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure reading join status from dsregcmd output",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	return []map[string]string{status.toRow()}, nil
+}
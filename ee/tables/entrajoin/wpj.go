@@ -0,0 +1,85 @@
+package entrajoin
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"sort"
+)
+
+// workplaceJoinIssuer issues the device certificates Entra ID hands out when registering a
+// device, whose subject is the device ID.
+const workplaceJoinIssuer = "MS-Organization-Access"
+
+// tenantIdOid is the certificate extension holding the registration's tenant ID
+var tenantIdOid = asn1.ObjectIdentifier{1, 2, 840, 113556, 1, 5, 284, 5}
+
+// statusFromWorkplaceJoinCerts finds the Workplace Join registrations among the given
+// PEM-encoded certificates, such as those in a user's login keychain.
+func statusFromWorkplaceJoinCerts(pemData []byte) []joinStatus {
+	var statuses []joinStatus
+	seen := make(map[string]bool)
+
+	for {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || cert.Issuer.CommonName != workplaceJoinIssuer {
+			continue
+		}
+
+		deviceId := cert.Subject.CommonName
+		if seen[deviceId] {
+			continue
+		}
+		seen[deviceId] = true
+
+		status := joinStatus{
+			source:   "workplace_join",
+			joinType: joinTypeWorkplace,
+			joined:   true,
+			deviceId: deviceId,
+		}
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(tenantIdOid) {
+				status.tenantId = decodeGuid(ext.Value)
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].deviceId < statuses[j].deviceId })
+	return statuses
+}
+
+// decodeGuid formats a GUID stored in Microsoft's mixed-endian binary layout. Values
+// that aren't binary GUIDs are returned as strings.
+func decodeGuid(b []byte) string {
+	// The value may be wrapped in an OCTET STRING
+	var inner []byte
+	if rest, err := asn1.Unmarshal(b, &inner); err == nil && len(rest) == 0 {
+		b = inner
+	}
+
+	if len(b) != 16 {
+		return string(b)
+	}
+
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10],
+		b[10:16],
+	)
+}
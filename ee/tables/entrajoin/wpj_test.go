@@ -0,0 +1,65 @@
+package entrajoin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusFromWorkplaceJoinCerts(t *testing.T) {
+	t.Parallel()
+
+	// 72f988bf-86f1-41af-91ab-2d7cd011db47, in Microsoft's binary layout
+	tenantGuid := []byte{0xbf, 0x88, 0xf9, 0x72, 0xf1, 0x86, 0xaf, 0x41, 0x91, 0xab, 0x2d, 0x7c, 0xd0, 0x11, 0xdb, 0x47}
+	wrappedTenantGuid, err := asn1.Marshal(tenantGuid)
+	require.NoError(t, err)
+
+	var pemData []byte
+	pemData = append(pemData, testCert(t, "MS-Organization-Access", "9e1b2c3d-4e5f-4a6b-8c7d-1e2f3a4b5c6d", wrappedTenantGuid)...)
+	pemData = append(pemData, testCert(t, "Some Other CA", "not-a-device", nil)...)
+	pemData = append(pemData, testCert(t, "MS-Organization-Access", "1a2b3c4d-0000-4000-8000-000000000001", nil)...)
+
+	statuses := statusFromWorkplaceJoinCerts(pemData)
+	require.Len(t, statuses, 2)
+
+	require.Equal(t, "1a2b3c4d-0000-4000-8000-000000000001", statuses[0].deviceId)
+	require.Equal(t, "", statuses[0].tenantId)
+
+	require.Equal(t, "9e1b2c3d-4e5f-4a6b-8c7d-1e2f3a4b5c6d", statuses[1].deviceId)
+	require.Equal(t, "72f988bf-86f1-41af-91ab-2d7cd011db47", statuses[1].tenantId)
+	require.Equal(t, "workplace_joined", statuses[1].joinType)
+	require.True(t, statuses[1].joined)
+
+	require.Empty(t, statusFromWorkplaceJoinCerts(nil))
+}
+
+func testCert(t *testing.T, issuer, subject string, tenantExtension []byte) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Issuer:       pkix.Name{CommonName: issuer},
+		Subject:      pkix.Name{CommonName: subject},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if tenantExtension != nil {
+		template.ExtraExtensions = []pkix.Extension{{Id: tenantIdOid, Value: tenantExtension}}
+	}
+
+	parent := &x509.Certificate{Subject: pkix.Name{CommonName: issuer}}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
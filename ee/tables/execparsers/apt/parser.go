@@ -36,9 +36,24 @@ func aptParse(reader io.Reader) (any, error) {
 		row["sources"] = strings.TrimSpace(values[0])
 		row["update_version"] = strings.TrimSpace(values[1])
 		row["current_version"] = strings.TrimRight(values[5], "]")
+		row["security"] = "0"
+		if isSecuritySource(row["sources"]) {
+			row["security"] = "1"
+		}
 
 		results = append(results, row)
 	}
 
 	return results, nil
 }
+
+// isSecuritySource reports whether any of the comma-separated sources an update is available
+// from is a security pocket, e.g. `jammy-security` or Debian's `bookworm-security`.
+func isSecuritySource(sources string) bool {
+	for _, source := range strings.Split(sources, ",") {
+		if source == "security" || strings.HasSuffix(source, "-security") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package dnf_updateinfo
+
+import (
+	"io"
+)
+
+type parser struct{}
+
+var Parser = New()
+
+func New() parser {
+	return parser{}
+}
+
+func (p parser) Parse(reader io.Reader) (any, error) {
+	return dnfUpdateinfoParse(reader)
+}
@@ -0,0 +1,91 @@
+package dnf_updateinfo
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// dnfUpdateinfoParse parses the output of `dnf updateinfo list --updates`, which lists the advisories
+// for updates available to installed packages, one package per line. dnf4 prints
+// `<advisory> <type> <package>`, where the type of a security advisory may include its severity:
+//
+//	RHSA-2024:2394 Important/Sec. kernel-5.14.0-427.16.1.el9_4.x86_64
+//	RHBA-2024:2417 bugfix         NetworkManager-1:1.46.0-8.el9_4.x86_64
+//
+// dnf5 prints a header, then `<advisory> <type> <severity> <package> <issued date> <issued time>`.
+func dnfUpdateinfoParse(reader io.Reader) (any, error) {
+	results := make([]map[string]string, 0)
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		row := make(map[string]string)
+		switch len(fields) {
+		case 3:
+			row["advisory"] = fields[0]
+			row["type"], row["severity"] = advisoryType(fields[1])
+			row["nevra"] = fields[2]
+		case 6:
+			row["advisory"] = fields[0]
+			row["type"] = fields[1]
+			row["severity"] = fields[2]
+			row["nevra"] = fields[3]
+			row["issued"] = fields[4] + " " + fields[5]
+		default:
+			continue
+		}
+
+		name, version, arch, ok := splitNevra(row["nevra"])
+		if !ok {
+			continue
+		}
+		row["package"] = name
+		row["version"] = version
+		row["arch"] = arch
+
+		if row["severity"] == "None" {
+			row["severity"] = ""
+		}
+
+		row["security"] = "0"
+		if row["type"] == "security" {
+			row["security"] = "1"
+		}
+
+		results = append(results, row)
+	}
+
+	return results, scanner.Err()
+}
+
+// advisoryType splits dnf4's advisory type, e.g. `Important/Sec.`, into the type and severity.
+func advisoryType(t string) (string, string) {
+	severity, found := strings.CutSuffix(t, "/Sec.")
+	if !found {
+		return t, ""
+	}
+	return "security", severity
+}
+
+// splitNevra splits a package's `<name>-[<epoch>:]<version>-<release>.<arch>` into its name,
+// `[<epoch>:]<version>-<release>`, and arch.
+func splitNevra(nevra string) (string, string, string, bool) {
+	archIndex := strings.LastIndex(nevra, ".")
+	if archIndex < 0 {
+		return "", "", "", false
+	}
+	nevr, arch := nevra[:archIndex], nevra[archIndex+1:]
+
+	releaseIndex := strings.LastIndex(nevr, "-")
+	if releaseIndex < 0 {
+		return "", "", "", false
+	}
+	versionIndex := strings.LastIndex(nevr[:releaseIndex], "-")
+	if versionIndex <= 0 {
+		return "", "", "", false
+	}
+
+	return nevr[:versionIndex], nevr[versionIndex+1:], arch, true
+}
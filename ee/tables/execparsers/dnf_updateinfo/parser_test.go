@@ -0,0 +1,142 @@
+package dnf_updateinfo
+
+import (
+	"bytes"
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test-data/dnf4_updateinfo.txt
+var dnf4_updateinfo []byte
+
+//go:embed test-data/dnf5_updateinfo.txt
+var dnf5_updateinfo []byte
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name     string
+		input    []byte
+		expected []map[string]string
+	}{
+		{
+			name:     "empty input",
+			expected: make([]map[string]string, 0),
+		},
+		{
+			name:     "malformed input",
+			input:    []byte("\nNo updates.\nRHSA-2024:1 security nodots\nRHSA-2024:1 security no-release.x86_64\n"),
+			expected: make([]map[string]string, 0),
+		},
+		{
+			name:  "dnf4",
+			input: dnf4_updateinfo,
+			expected: []map[string]string{
+				{
+					"advisory": "RHSA-2024:2394",
+					"type":     "security",
+					"severity": "Important",
+					"nevra":    "kernel-5.14.0-427.16.1.el9_4.x86_64",
+					"package":  "kernel",
+					"version":  "5.14.0-427.16.1.el9_4",
+					"arch":     "x86_64",
+					"security": "1",
+				},
+				{
+					"advisory": "RHSA-2024:2394",
+					"type":     "security",
+					"severity": "Important",
+					"nevra":    "kernel-core-5.14.0-427.16.1.el9_4.x86_64",
+					"package":  "kernel-core",
+					"version":  "5.14.0-427.16.1.el9_4",
+					"arch":     "x86_64",
+					"security": "1",
+				},
+				{
+					"advisory": "RHSA-2024:2562",
+					"type":     "security",
+					"severity": "Moderate",
+					"nevra":    "golang-1.21.9-2.el9_4.x86_64",
+					"package":  "golang",
+					"version":  "1.21.9-2.el9_4",
+					"arch":     "x86_64",
+					"security": "1",
+				},
+				{
+					"advisory": "RHBA-2024:2417",
+					"type":     "bugfix",
+					"severity": "",
+					"nevra":    "NetworkManager-1:1.46.0-8.el9_4.x86_64",
+					"package":  "NetworkManager",
+					"version":  "1:1.46.0-8.el9_4",
+					"arch":     "x86_64",
+					"security": "0",
+				},
+				{
+					"advisory": "RHEA-2024:2405",
+					"type":     "enhancement",
+					"severity": "",
+					"nevra":    "python3-dnf-plugins-core-4.3.0-13.el9.noarch",
+					"package":  "python3-dnf-plugins-core",
+					"version":  "4.3.0-13.el9",
+					"arch":     "noarch",
+					"security": "0",
+				},
+				{
+					"advisory": "FEDORA-2024-6f7e8d9c0b",
+					"type":     "security",
+					"severity": "",
+					"nevra":    "firefox-125.0.3-1.fc40.x86_64",
+					"package":  "firefox",
+					"version":  "125.0.3-1.fc40",
+					"arch":     "x86_64",
+					"security": "1",
+				},
+			},
+		},
+		{
+			name:  "dnf5",
+			input: dnf5_updateinfo,
+			expected: []map[string]string{
+				{
+					"advisory": "FEDORA-2024-1a2b3c4d5e",
+					"type":     "bugfix",
+					"severity": "",
+					"nevra":    "NetworkManager-1:1.46.0-2.fc40.x86_64",
+					"package":  "NetworkManager",
+					"version":  "1:1.46.0-2.fc40",
+					"arch":     "x86_64",
+					"issued":   "2024-05-02 01:23:45",
+					"security": "0",
+				},
+				{
+					"advisory": "FEDORA-2024-6f7e8d9c0b",
+					"type":     "security",
+					"severity": "Important",
+					"nevra":    "firefox-125.0.3-1.fc40.x86_64",
+					"package":  "firefox",
+					"version":  "125.0.3-1.fc40",
+					"arch":     "x86_64",
+					"issued":   "2024-05-10 02:11:09",
+					"security": "1",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := New()
+			result, err := p.Parse(bytes.NewReader(tt.input))
+			require.NoError(t, err, "unexpected error parsing input")
+
+			require.ElementsMatch(t, tt.expected, result)
+		})
+	}
+}
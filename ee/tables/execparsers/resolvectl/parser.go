@@ -0,0 +1,133 @@
+package resolvectl
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	linkRegex    = regexp.MustCompile(`^Link (\d+) \((.+)\)$`)
+	nonWordRegex = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// listKeys are the settings whose values are space-separated lists, which may continue onto
+// following lines.
+var listKeys = map[string]bool{
+	"current_scopes":       true,
+	"protocols":            true,
+	"dns_servers":          true,
+	"fallback_dns_servers": true,
+	"dns_domain":           true,
+	"dnssec_nta":           true,
+}
+
+// resolvectlParse parses the output of `resolvectl status`. This describes systemd-resolved's
+// global settings, and then each link's (i.e. interface's), like:
+//
+//	Global
+//	       Protocols: +LLMNR +mDNS -DNSOverTLS DNSSEC=no/unsupported
+//	resolv.conf mode: stub
+//
+//	Link 2 (eth0)
+//	    Current Scopes: DNS LLMNR/IPv4 LLMNR/IPv6
+//	Current DNS Server: 192.168.1.1
+//	       DNS Servers: 192.168.1.1 192.168.1.2
+//	        DNS Domain: ~.
+//	                    example.com
+//
+// The setting names are right-aligned, so they may or may not be indented; values that continue
+// onto more lines are indented to line up with the first. Each section is returned as a row.
+func resolvectlParse(reader io.Reader) (any, error) {
+	results := make([]map[string]any, 0)
+	var section map[string]any
+	lastKey := ""
+	valueColumn := 0
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		// Section headings, like `Global` or `Link 2 (eth0)`, aren't indented and have no value
+		if indent == 0 && !strings.Contains(line, ":") {
+			section = sectionFor(trimmed)
+			results = append(results, section)
+			lastKey = ""
+			continue
+		}
+
+		if section == nil {
+			continue
+		}
+
+		// Lines indented as far as the previous setting's value continue it
+		if lastKey != "" && indent >= valueColumn {
+			appendValue(section, lastKey, strings.Fields(trimmed)...)
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		lastKey = normalizeKey(key)
+		valueColumn = len(key) + 2
+		value = strings.TrimSpace(value)
+
+		if listKeys[lastKey] {
+			section[lastKey] = []string{}
+			appendValue(section, lastKey, strings.Fields(value)...)
+			continue
+		}
+		section[lastKey] = value
+	}
+
+	return results, scanner.Err()
+}
+
+// sectionFor starts the row for a section heading
+func sectionFor(heading string) map[string]any {
+	if matches := linkRegex.FindStringSubmatch(heading); matches != nil {
+		return map[string]any{
+			"scope":      "link",
+			"link_index": matches[1],
+			"interface":  matches[2],
+		}
+	}
+
+	// `Global`, or newer sections like `Delegate <name>`
+	scope, name, _ := strings.Cut(heading, " ")
+	section := map[string]any{
+		"scope": strings.ToLower(scope),
+	}
+	if name != "" {
+		section["name"] = name
+	}
+	return section
+}
+
+// appendValue adds values to the setting key, turning it into a list if it isn't one already
+func appendValue(section map[string]any, key string, values ...string) {
+	switch existing := section[key].(type) {
+	case []string:
+		section[key] = append(existing, values...)
+	case string:
+		if existing == "" {
+			section[key] = values
+		} else {
+			section[key] = append([]string{existing}, values...)
+		}
+	default:
+		section[key] = values
+	}
+}
+
+func normalizeKey(key string) string {
+	return strings.Trim(nonWordRegex.ReplaceAllString(strings.ToLower(key), "_"), "_")
+}
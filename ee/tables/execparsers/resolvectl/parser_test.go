@@ -0,0 +1,78 @@
+package resolvectl
+
+import (
+	"bytes"
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test-data/resolvectl_status.txt
+var resolvectlStatus []byte
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name     string
+		input    []byte
+		expected []map[string]any
+	}{
+		{
+			name:     "empty input",
+			expected: []map[string]any{},
+		},
+		{
+			name:  "resolvectl status",
+			input: resolvectlStatus,
+			expected: []map[string]any{
+				{
+					"scope":                "global",
+					"protocols":            []string{"-LLMNR", "-mDNS", "-DNSOverTLS", "DNSSEC=no/unsupported"},
+					"resolv_conf_mode":     "stub",
+					"fallback_dns_servers": []string{"1.1.1.1#cloudflare-dns.com", "2606:4700:4700::1111#cloudflare-dns.com"},
+				},
+				{
+					"scope":              "link",
+					"link_index":         "2",
+					"interface":          "enp0s31f6",
+					"current_scopes":     []string{"DNS"},
+					"protocols":          []string{"+DefaultRoute", "-LLMNR", "-mDNS", "-DNSOverTLS", "DNSSEC=no/unsupported"},
+					"current_dns_server": "192.168.1.1",
+					"dns_servers":        []string{"192.168.1.1", "fe80::1%enp0s31f6"},
+					"dns_domain":         []string{"home.example"},
+				},
+				{
+					"scope":              "link",
+					"link_index":         "5",
+					"interface":          "tun0",
+					"current_scopes":     []string{"DNS"},
+					"protocols":          []string{"-DefaultRoute", "-LLMNR", "-mDNS", "-DNSOverTLS", "DNSSEC=no/unsupported"},
+					"current_dns_server": "10.8.0.1",
+					"dns_servers":        []string{"10.8.0.1"},
+					"dns_domain":         []string{"corp.example.com", "~internal.example.com"},
+				},
+				{
+					"scope":          "link",
+					"link_index":     "7",
+					"interface":      "docker0",
+					"current_scopes": []string{"none"},
+					"protocols":      []string{"-DefaultRoute", "-LLMNR", "-mDNS", "-DNSOverTLS", "DNSSEC=no/unsupported"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := New()
+			result, err := p.Parse(bytes.NewReader(tt.input))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package scutil_dns
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	indexedKeyRegex = regexp.MustCompile(`^(.+)\[\d+\]$`)
+	ifIndexRegex    = regexp.MustCompile(`^(\d+) \((.+)\)$`)
+	nonWordRegex    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// scutilDnsParse parses the output of `scutil --dns`. This lists the resolvers macOS uses:
+// first those for unscoped queries -- including per-domain resolvers, from /etc/resolver or
+// pushed by VPNs -- and then those scoped to an interface. It looks like:
+//
+//	DNS configuration
+//
+//	resolver #1
+//	  search domain[0] : example.com
+//	  nameserver[0] : 192.168.1.1
+//	  if_index : 6 (en0)
+//	  flags    : Request A records
+//	  reach    : 0x00020002 (Reachable,Directly Reachable Address)
+//
+//	DNS configuration (for scoped queries)
+//
+//	resolver #1
+//	  ...
+//
+// Each resolver is returned as a row, noting which configuration it's part of. Indexed values,
+// like nameserver[0] and nameserver[1], are collected into a list.
+func scutilDnsParse(reader io.Reader) (any, error) {
+	results := make([]map[string]any, 0)
+	configuration := ""
+	var resolver map[string]any
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "DNS configuration"):
+			configuration = configurationName(strings.TrimPrefix(line, "DNS configuration"))
+			resolver = nil
+		case strings.HasPrefix(line, "resolver #"):
+			resolver = map[string]any{
+				"configuration": configuration,
+				"resolver":      strings.TrimPrefix(line, "resolver #"),
+			}
+			results = append(results, resolver)
+		case resolver != nil:
+			key, value, found := strings.Cut(line, ":")
+			if !found {
+				continue
+			}
+			addValue(resolver, strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+	}
+
+	return results, scanner.Err()
+}
+
+// configurationName turns the suffix of a configuration's heading, e.g. `(for scoped queries)`,
+// into a name, e.g. `scoped_queries`. The first configuration has no suffix, and is the default.
+func configurationName(suffix string) string {
+	suffix = strings.Trim(strings.TrimSpace(suffix), "()")
+	suffix = strings.TrimPrefix(suffix, "for ")
+	if suffix == "" {
+		return "default"
+	}
+	return normalizeKey(suffix)
+}
+
+// addValue sets key to value in resolver, appending to a list for indexed keys.
+func addValue(resolver map[string]any, key string, value string) {
+	if matches := indexedKeyRegex.FindStringSubmatch(key); matches != nil {
+		listKey := normalizeKey(matches[1])
+		list, _ := resolver[listKey].([]string)
+		resolver[listKey] = append(list, value)
+		return
+	}
+
+	key = normalizeKey(key)
+
+	// The interface index is followed by the interface's name, e.g. `6 (en0)`
+	if key == "if_index" {
+		if matches := ifIndexRegex.FindStringSubmatch(value); matches != nil {
+			resolver["if_index"] = matches[1]
+			resolver["interface"] = matches[2]
+			return
+		}
+	}
+
+	resolver[key] = value
+}
+
+func normalizeKey(key string) string {
+	return strings.Trim(nonWordRegex.ReplaceAllString(strings.ToLower(key), "_"), "_")
+}
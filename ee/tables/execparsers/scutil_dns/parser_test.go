@@ -0,0 +1,86 @@
+package scutil_dns
+
+import (
+	"bytes"
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test-data/scutil_dns.txt
+var scutilDns []byte
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name     string
+		input    []byte
+		expected []map[string]any
+	}{
+		{
+			name:     "empty input",
+			expected: []map[string]any{},
+		},
+		{
+			name:  "scutil --dns",
+			input: scutilDns,
+			expected: []map[string]any{
+				{
+					"configuration": "default",
+					"resolver":      "1",
+					"search_domain": []string{"home.example"},
+					"nameserver":    []string{"192.168.1.1", "fe80::1%en0"},
+					"if_index":      "6",
+					"interface":     "en0",
+					"flags":         "Request A records, Request AAAA records",
+					"reach":         "0x00020002 (Reachable,Directly Reachable Address)",
+				},
+				{
+					"configuration": "default",
+					"resolver":      "2",
+					"domain":        "corp.example.com",
+					"nameserver":    []string{"10.8.0.1"},
+					"if_index":      "21",
+					"interface":     "utun3",
+					"flags":         "Supplemental, Request A records",
+					"reach":         "0x00000003 (Reachable,Transient Connection)",
+					"order":         "102200",
+				},
+				{
+					"configuration": "default",
+					"resolver":      "3",
+					"domain":        "local",
+					"options":       "mdns",
+					"timeout":       "5",
+					"flags":         "Request A records, Request AAAA records",
+					"reach":         "0x00000000 (Not Reachable)",
+					"order":         "300000",
+				},
+				{
+					"configuration": "scoped_queries",
+					"resolver":      "1",
+					"search_domain": []string{"home.example"},
+					"nameserver":    []string{"192.168.1.1"},
+					"if_index":      "6",
+					"interface":     "en0",
+					"flags":         "Scoped, Request A records",
+					"reach":         "0x00020002 (Reachable,Directly Reachable Address)",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := New()
+			result, err := p.Parse(bytes.NewReader(tt.input))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
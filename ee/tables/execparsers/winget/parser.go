@@ -0,0 +1,89 @@
+package winget
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// columnNames are the keys for winget's columns, in order. The headings themselves are localized.
+var columnNames = []string{"name", "id", "version", "available", "source"}
+
+// wingetParse parses the output of `winget upgrade`, which lists the packages with available upgrades
+// in one or more fixed-width tables, like:
+//
+//	Name                               Id                          Version      Available    Source
+//	---------------------------------------------------------------------------------------------------
+//	Mozilla Firefox (x64 en-US)        Mozilla.Firefox             124.0.2      125.0.3      winget
+//	Microsoft Visual Studio Code (Us…  Microsoft.VisualStudioCode  1.88.1       1.89.0       winget
+//	2 upgrades available.
+//
+// Columns are found from the positions of the headings, above the line of dashes. Long names are
+// truncated, and the output may be preceded by a progress spinner, drawn with carriage returns.
+func wingetParse(reader io.Reader) (any, error) {
+	results := make([]map[string]string, 0)
+
+	var previousLine []rune
+	var columnStarts []int
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Only the text after the spinner's last carriage return is visible
+		line = strings.TrimRight(line, "\r")
+		if i := strings.LastIndex(line, "\r"); i >= 0 {
+			line = line[i+1:]
+		}
+		runes := []rune(strings.TrimRightFunc(line, unicode.IsSpace))
+
+		switch {
+		case isDashes(runes):
+			columnStarts = headingStarts(previousLine)
+		case len(columnStarts) < 2 || len(runes) <= columnStarts[len(columnStarts)-1]:
+			// Outside a table, or a line too short to be a row, which ends the table
+			columnStarts = nil
+		default:
+			row := make(map[string]string)
+			for i, start := range columnStarts {
+				if i >= len(columnNames) {
+					break
+				}
+				end := len(runes)
+				if i+1 < len(columnStarts) {
+					end = columnStarts[i+1]
+				}
+				row[columnNames[i]] = strings.TrimSpace(string(runes[start:end]))
+			}
+			results = append(results, row)
+		}
+
+		previousLine = runes
+	}
+
+	return results, scanner.Err()
+}
+
+func isDashes(runes []rune) bool {
+	if len(runes) == 0 {
+		return false
+	}
+	for _, r := range runes {
+		if r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// headingStarts returns the position of each heading in the heading line.
+func headingStarts(heading []rune) []int {
+	var starts []int
+	for i, r := range heading {
+		if !unicode.IsSpace(r) && (i == 0 || unicode.IsSpace(heading[i-1])) {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
@@ -0,0 +1,63 @@
+package winget
+
+import (
+	"bytes"
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test-data/winget_upgrade.txt
+var winget_upgrade []byte
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name     string
+		input    []byte
+		expected []map[string]string
+	}{
+		{
+			name:     "empty input",
+			expected: make([]map[string]string, 0),
+		},
+		{
+			name:     "no upgrades",
+			input:    []byte("No installed package found matching input criteria.\r\n"),
+			expected: make([]map[string]string, 0),
+		},
+		{
+			name:  "windows line endings",
+			input: []byte("Name  Id      Version Available Source\r\n--------------------------------------\r\nFoo   Foo.Foo 1.0     2.0       winget\r\n1 upgrades available.\r\n"),
+			expected: []map[string]string{
+				{"name": "Foo", "id": "Foo.Foo", "version": "1.0", "available": "2.0", "source": "winget"},
+			},
+		},
+		{
+			name:  "winget_upgrade",
+			input: winget_upgrade,
+			expected: []map[string]string{
+				{"name": "Mozilla Firefox (x64 en-US)", "id": "Mozilla.Firefox", "version": "124.0.2", "available": "125.0.3", "source": "winget"},
+				{"name": "Microsoft Visual Studio Code (Us…", "id": "Microsoft.VisualStudioCode", "version": "1.88.1", "available": "1.89.0", "source": "winget"},
+				{"name": "7-Zip 23.01 (x64)", "id": "7zip.7zip", "version": "23.01", "available": "24.05", "source": "winget"},
+				{"name": "Zoom Workplace", "id": "Zoom.Zoom", "version": "Unknown", "available": "6.0.10", "source": "winget"},
+				{"name": "Discord", "id": "Discord.Discord", "version": "1.0.9035", "available": "1.0.9042", "source": "winget"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := New()
+			result, err := p.Parse(bytes.NewReader(tt.input))
+			require.NoError(t, err, "unexpected error parsing input")
+
+			require.ElementsMatch(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package fimconfig
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/fim"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName = "kolide_fim_config"
+
+	kindFlag = "flag"
+
+	statusApplied  = "applied"
+	statusRejected = "rejected"
+)
+
+// TablePlugin provides an osquery table reporting the effective file integrity monitoring
+// configuration: the paths and flags launcher applied from the control server's FIM spec, and
+// any parts of the spec that could not be applied on this platform.
+func TablePlugin(store types.Getter) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("category"),
+		table.TextColumn("kind"),
+		table.TextColumn("value"),
+		table.TextColumn("status"),
+		table.TextColumn("error"),
+	}
+
+	return table.NewPlugin(tableName, columns, generate(store))
+}
+
+func generate(store types.Getter) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		results := make([]map[string]string, 0)
+
+		// Without a store, there is no FIM config to report
+		if store == nil {
+			return results, nil
+		}
+
+		effective, err := fim.LoadEffective(store, runtime.GOOS)
+		if err != nil {
+			return nil, err
+		}
+
+		return effectiveRows(effective), nil
+	}
+}
+
+func effectiveRows(effective *fim.Effective) []map[string]string {
+	results := make([]map[string]string, 0)
+
+	// Without any paths to monitor, launcher applies nothing, so there's nothing to report
+	// beyond why the spec was rejected
+	if effective.Enabled() {
+		for _, category := range sortedKeys(effective.FilePaths) {
+			for _, path := range effective.FilePaths[category] {
+				results = append(results, row(category, fim.KindFilePath, path, statusApplied, ""))
+			}
+			for _, path := range effective.ExcludePaths[category] {
+				results = append(results, row(category, fim.KindExcludePath, path, statusApplied, ""))
+			}
+		}
+
+		for _, flag := range effective.Flags {
+			results = append(results, row("", kindFlag, flag, statusApplied, ""))
+		}
+
+		results = append(results, row("", fim.KindHashing, strconv.FormatBool(effective.Hashing), statusApplied, ""))
+	}
+
+	for _, problem := range effective.Problems {
+		results = append(results, row(problem.Category, problem.Kind, problem.Value, statusRejected, problem.Message))
+	}
+
+	return results
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func row(category, kind, value, status, errMsg string) map[string]string {
+	return map[string]string{
+		"category": category,
+		"kind":     kind,
+		"value":    value,
+		"status":   status,
+		"error":    errMsg,
+	}
+}
@@ -0,0 +1,45 @@
+package fimconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kolide/launcher/ee/fim"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_effectiveRows(t *testing.T) {
+	t.Parallel()
+
+	hashing := true
+	effective := fim.Translate(&fim.Spec{
+		Categories: map[string]fim.CategorySpec{
+			"etc": {Paths: []string{"/etc/%%", "relative"}, ExcludePaths: []string{"/etc/mtab"}},
+		},
+		Hashing: &hashing,
+	}, "linux")
+
+	require.Equal(t, []map[string]string{
+		row("etc", fim.KindFilePath, "/etc/%%", statusApplied, ""),
+		row("etc", fim.KindExcludePath, "/etc/mtab", statusApplied, ""),
+		row("", kindFlag, "--disable_events=false", statusApplied, ""),
+		row("", kindFlag, "--enable_file_events=true", statusApplied, ""),
+		row("", fim.KindHashing, "true", statusApplied, ""),
+		row("etc", fim.KindFilePath, "relative", statusRejected, "path must be absolute"),
+	}, effectiveRows(effective))
+}
+
+func Test_effectiveRows_Disabled(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, effectiveRows(fim.Translate(nil, "linux")))
+}
+
+func TestTablePlugin_NilStore(t *testing.T) {
+	t.Parallel()
+
+	rows, err := generate(nil)(context.TODO(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
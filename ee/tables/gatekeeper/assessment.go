@@ -0,0 +1,121 @@
+//go:build darwin
+// +build darwin
+
+package gatekeeper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const assessmentTableName = "kolide_macos_gatekeeper_assessment"
+
+// assessmentTypes are the kinds of assessment spctl makes, and the arguments selecting them
+var assessmentTypes = map[string][]string{
+	"execute": {"--type", "execute"},
+	"install": {"--type", "install"},
+	"open":    {"--type", "open", "--context", "context:primary-signature"},
+}
+
+type AssessmentTable struct {
+	slogger *slog.Logger
+}
+
+func AssessmentTablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("path"),
+		table.TextColumn("type"),
+		table.IntegerColumn("accepted"),
+		table.TextColumn("assessment"),
+		table.TextColumn("detail"),
+		table.TextColumn("source"),
+		table.TextColumn("origin"),
+	}
+
+	t := &AssessmentTable{
+		slogger: slogger.With("table", assessmentTableName),
+	}
+
+	return table.NewPlugin(assessmentTableName, columns, t.generate)
+}
+
+func (t *AssessmentTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	requestedPaths := tablehelpers.GetConstraints(queryContext, "path")
+	if len(requestedPaths) == 0 {
+		return results, errors.New("the kolide_macos_gatekeeper_assessment table requires that you specify a constraint for path")
+	}
+
+	assessmentTypeNames := tablehelpers.GetConstraints(queryContext, "type", tablehelpers.WithDefaults("execute"))
+
+	for _, requestedPath := range requestedPaths {
+		// We take globs in via the sql %, but glob needs *. So convert.
+		paths, err := filepath.Glob(strings.ReplaceAll(requestedPath, `%`, `*`))
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"bad file glob",
+				"err", err,
+			)
+			continue
+		}
+
+		for _, path := range paths {
+			for _, assessmentType := range assessmentTypeNames {
+				typeArgs, ok := assessmentTypes[assessmentType]
+				if !ok {
+					t.slogger.Log(ctx, slog.LevelInfo,
+						"unknown gatekeeper assessment type",
+						"type", assessmentType,
+					)
+					continue
+				}
+
+				if row, ok := t.assess(ctx, path, assessmentType, typeArgs); ok {
+					results = append(results, row)
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func (t *AssessmentTable) assess(ctx context.Context, path string, assessmentType string, typeArgs []string) (map[string]string, bool) {
+	args := append([]string{"--assess", "-vv"}, typeArgs...)
+	args = append(args, path)
+
+	// spctl reports on stderr, and exits non-zero when it rejects the app, so the error alone
+	// doesn't mean the assessment failed
+	var stdout, stderr bytes.Buffer
+	err := tablehelpers.Run(ctx, t.slogger, 30, allowedcmd.Spctl, args, &stdout, &stderr)
+
+	a, ok := parseSpctlAssessment(path, stderr.String())
+	if !ok {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not assess app with gatekeeper",
+			"path", path,
+			"stderr", stderr.String(),
+			"err", err,
+		)
+		return nil, false
+	}
+
+	return map[string]string{
+		"path":       path,
+		"type":       assessmentType,
+		"accepted":   boolToInt(a.verdict == "accepted"),
+		"assessment": a.verdict,
+		"detail":     a.detail,
+		"source":     a.source,
+		"origin":     a.origin,
+	}, true
+}
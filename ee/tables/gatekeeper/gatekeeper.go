@@ -0,0 +1,308 @@
+// Package gatekeeper reports on Gatekeeper's policy rules -- in particular the overrides users
+// add for apps Gatekeeper would otherwise block -- and on how Gatekeeper assesses individual apps.
+package gatekeeper
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// systemPolicyDbPath is syspolicyd's rule database. Reading it requires Full Disk Access.
+	systemPolicyDbPath = "/var/db/SystemPolicy"
+
+	// Rule timestamps are julian days
+	julianDayUnixEpoch = 2440587.5
+
+	// neverExpires is the expiry syspolicyd gives rules that don't expire
+	neverExpires = 5000000
+
+	// authority flags, see policydb.h
+	authorityFlagVirtual = 0x0001
+	authorityFlagDefault = 0x0002
+)
+
+// ruleTypes decodes the authority table's type, see policydb.h
+var ruleTypes = map[int64]string{
+	1: "execute",
+	2: "install",
+	3: "open",
+}
+
+// spctlRuleTypes maps the rule types spctl lists to ruleTypes' names
+var spctlRuleTypes = map[string]string{
+	"execute": "execute",
+	"install": "install",
+	"lsopen":  "open",
+}
+
+// defaultRuleLabels are the labels of the rules macOS ships with. Rules with other labels were
+// added to the policy, by a user or a management tool. GKE rules are Apple's Gatekeeper
+// exceptions for known apps.
+var defaultRuleLabels = map[string]bool{
+	"Apple System":             true,
+	"Apple Installer":          true,
+	"Mac App Store":            true,
+	"Developer ID":             true,
+	"Notarized Developer ID":   true,
+	"Unnotarized Developer ID": true,
+	"Testflight":               true,
+	"GKE":                      true,
+}
+
+// rule is a Gatekeeper policy rule, from the database or spctl.
+type rule struct {
+	id          string
+	label       string
+	ruleType    string
+	allow       bool
+	disabled    bool
+	priority    string
+	requirement string
+	remarks     string
+	override    bool
+	expires     int64
+	created     int64
+	modified    int64
+	user        string
+	source      string
+}
+
+func (r rule) row() map[string]string {
+	row := map[string]string{
+		"id":            r.id,
+		"label":         r.label,
+		"type":          r.ruleType,
+		"allow":         boolToInt(r.allow),
+		"disabled":      boolToInt(r.disabled),
+		"priority":      r.priority,
+		"requirement":   r.requirement,
+		"path":          r.path(),
+		"remarks":       r.remarks,
+		"user_override": boolToInt(r.override),
+		"user":          r.user,
+		"source":        r.source,
+	}
+
+	for column, ts := range map[string]int64{"expires": r.expires, "created": r.created, "modified": r.modified} {
+		row[column] = ""
+		if ts != 0 {
+			row[column] = strconv.FormatInt(ts, 10)
+		}
+	}
+
+	return row
+}
+
+// path returns the path of the app the rule was added for. When a rule is added for an app,
+// its remarks are the app's path.
+func (r rule) path() string {
+	if strings.HasPrefix(r.remarks, "/") {
+		return r.remarks
+	}
+	return ""
+}
+
+// readRules reads the policy rules from syspolicyd's database. Virtual rules, which only anchor
+// cached assessments, are skipped.
+func readRules(ctx context.Context, slogger *slog.Logger, dbPath string) ([]rule, error) {
+	// sqlite's errors for a missing or unreadable database aren't very useful, so check first
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("checking system policy database: %w", err)
+	}
+
+	// read-only, so that we don't contend with syspolicyd for locks
+	conn, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro", dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite db: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slogger.Log(ctx, slog.LevelWarn,
+				"closing sqlite db after query",
+				"err", err,
+			)
+		}
+	}()
+
+	rows, err := conn.QueryContext(ctx, `SELECT
+		id, type, requirement, allow, disabled, expires, priority, label, flags, ctime, mtime, user, remarks
+		FROM authority
+		WHERE flags & ? = 0
+		ORDER BY priority DESC, id`, authorityFlagVirtual)
+	if err != nil {
+		return nil, fmt.Errorf("running query: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []rule
+	for rows.Next() {
+		var (
+			id, ruleType, flags int64
+			allow, disabled     bool
+			expires, priority   float64
+			created, modified   sql.NullFloat64
+			requirement, label  sql.NullString
+			user, remarks       sql.NullString
+		)
+		if err := rows.Scan(&id, &ruleType, &requirement, &allow, &disabled, &expires, &priority, &label, &flags, &created, &modified, &user, &remarks); err != nil {
+			return nil, fmt.Errorf("scanning query results: %w", err)
+		}
+
+		r := rule{
+			id:          strconv.FormatInt(id, 10),
+			label:       label.String,
+			ruleType:    strconv.FormatInt(ruleType, 10),
+			allow:       allow,
+			disabled:    disabled,
+			priority:    strconv.FormatFloat(priority, 'f', -1, 64),
+			requirement: requirement.String,
+			remarks:     remarks.String,
+			override:    flags&authorityFlagDefault == 0 && !defaultRuleLabels[label.String],
+			user:        user.String,
+			source:      "database",
+		}
+		if name, ok := ruleTypes[ruleType]; ok {
+			r.ruleType = name
+		}
+		if expires < neverExpires {
+			r.expires = julianDayToUnix(expires)
+		}
+		if created.Valid {
+			r.created = julianDayToUnix(created.Float64)
+		}
+		if modified.Valid {
+			r.modified = julianDayToUnix(modified.Float64)
+		}
+
+		rules = append(rules, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating query results: %w", err)
+	}
+
+	return rules, nil
+}
+
+func julianDayToUnix(julianDay float64) int64 {
+	return int64((julianDay - julianDayUnixEpoch) * 86400)
+}
+
+// spctlRuleLine matches the first line of each rule `spctl --list` prints, e.g.
+// `1021[Slack] P0 allow execute [/Applications/Slack.app]`. The rule's requirement follows,
+// indented, on the next line.
+var spctlRuleLine = regexp.MustCompile(`^(\d+)\[(.*?)\] P(\S+) (allow|deny) (\S+)(.*)$`)
+
+// parseSpctlList parses the rules `spctl --list` prints. It has less detail than the database.
+func parseSpctlList(output string) []rule {
+	var rules []rule
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// The requirement belongs to the rule above it
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ") {
+			if len(rules) > 0 && rules[len(rules)-1].requirement == "" {
+				rules[len(rules)-1].requirement = strings.TrimSpace(line)
+			}
+			continue
+		}
+
+		m := spctlRuleLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		r := rule{
+			id:       m[1],
+			label:    m[2],
+			priority: m[3],
+			allow:    m[4] == "allow",
+			ruleType: m[5],
+			override: !defaultRuleLabels[m[2]],
+			source:   "spctl",
+		}
+		if name, ok := spctlRuleTypes[m[5]]; ok {
+			r.ruleType = name
+		}
+
+		rest := strings.TrimSpace(m[6])
+		if strings.HasPrefix(rest, "(disabled)") {
+			r.disabled = true
+			rest = strings.TrimSpace(strings.TrimPrefix(rest, "(disabled)"))
+		}
+		if strings.HasPrefix(rest, "[") && strings.HasSuffix(rest, "]") {
+			r.remarks = rest[1 : len(rest)-1]
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+// assessment is how Gatekeeper assessed an app, from `spctl --assess -vv`.
+type assessment struct {
+	verdict string
+	detail  string
+	source  string
+	origin  string
+}
+
+// parseSpctlAssessment parses the output of `spctl --assess -vv`, e.g.
+//
+//	/Applications/Slack.app: accepted
+//	source=Notarized Developer ID
+//	origin=Developer ID Application: Slack Technologies, Inc. (BQR82RBBHL)
+func parseSpctlAssessment(path string, output string) (assessment, bool) {
+	var a assessment
+	found := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if result, ok := strings.CutPrefix(line, path+": "); ok {
+			found = true
+			verdict, detail, _ := strings.Cut(result, " ")
+			switch verdict {
+			case "accepted", "rejected":
+				a.verdict = verdict
+				a.detail = strings.Trim(detail, "()")
+			default:
+				// e.g. `code object is not signed at all`, or the file doesn't exist
+				a.detail = result
+			}
+			continue
+		}
+
+		if key, value, ok := strings.Cut(line, "="); ok {
+			switch key {
+			case "source":
+				a.source = value
+			case "origin":
+				a.origin = value
+			}
+		}
+	}
+
+	return a, found
+}
+
+func boolToInt(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
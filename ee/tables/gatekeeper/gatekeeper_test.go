@@ -0,0 +1,189 @@
+package gatekeeper
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+const schema = `CREATE TABLE authority (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	version INTEGER NOT NULL DEFAULT (1),
+	type INTEGER NOT NULL,
+	requirement TEXT,
+	allow INTEGER NOT NULL DEFAULT (1),
+	disabled INTEGER NOT NULL DEFAULT (0),
+	expires FLOAT NOT NULL DEFAULT (5000000),
+	priority REAL NOT NULL DEFAULT (0),
+	label TEXT,
+	filter_unsigned TEXT,
+	flags INTEGER NOT NULL DEFAULT (0),
+	ctime FLOAT,
+	mtime FLOAT,
+	user TEXT,
+	remarks TEXT
+);`
+
+func TestReadRules(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "SystemPolicy")
+	createTestDb(t, dbPath,
+		// A rule macOS ships with
+		`INSERT INTO authority (id, type, requirement, priority, label, flags) VALUES (1, 1, 'anchor apple', 10, 'Apple System', 2);`,
+		// A virtual rule, anchoring a cached assessment
+		`INSERT INTO authority (id, type, requirement, priority, label, flags) VALUES (2, 1, 'cdhash H"00"', 0, NULL, 1);`,
+		// An app the user allowed
+		`INSERT INTO authority (id, type, requirement, priority, label, flags, ctime, mtime, user, remarks)
+			VALUES (3, 3, 'cdhash H"ab"', 0, 'Unsigned Thing', 0, 2460000.5, 2460001.5, 'alice', '/Applications/Thing.app');`,
+		// A disabled rule that expires
+		`INSERT INTO authority (id, type, requirement, allow, disabled, expires, priority, label, flags) VALUES (4, 2, 'anchor apple generic', 0, 1, 2460002.5, 5, 'Blocked', 0);`,
+	)
+
+	rules, err := readRules(context.TODO(), multislogger.NewNopLogger(), dbPath)
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+
+	// Ordered by priority
+	require.Equal(t, "1", rules[0].id)
+	require.Equal(t, "execute", rules[0].ruleType)
+	require.False(t, rules[0].override)
+	require.Equal(t, "", rules[0].row()["expires"])
+
+	require.Equal(t, "4", rules[1].id)
+	require.Equal(t, "install", rules[1].ruleType)
+	require.False(t, rules[1].allow)
+	require.True(t, rules[1].disabled)
+	require.True(t, rules[1].override)
+	require.Equal(t, int64(1677456000), rules[1].expires)
+
+	row := rules[2].row()
+	require.Equal(t, "3", row["id"])
+	require.Equal(t, "open", row["type"])
+	require.Equal(t, "1", row["allow"])
+	require.Equal(t, "1", row["user_override"])
+	require.Equal(t, "/Applications/Thing.app", row["path"])
+	require.Equal(t, "alice", row["user"])
+	require.Equal(t, "1677283200", row["created"])
+	require.Equal(t, "1677369600", row["modified"])
+	require.Equal(t, "database", row["source"])
+}
+
+func TestReadRules_MissingDb(t *testing.T) {
+	t.Parallel()
+
+	_, err := readRules(context.TODO(), multislogger.NewNopLogger(), filepath.Join(t.TempDir(), "SystemPolicy"))
+	require.Error(t, err)
+}
+
+func TestParseSpctlList(t *testing.T) {
+	t.Parallel()
+
+	output := `12[Apple System] P20 allow lsopen
+        anchor apple
+1021[Thing] P0 allow execute [/Applications/Thing.app]
+        cdhash H"abcdef"
+1022[Blocked Installer] P5 deny install (disabled)
+        identifier "com.example.installer"
+`
+
+	rules := parseSpctlList(output)
+	require.Len(t, rules, 3)
+
+	require.Equal(t, "12", rules[0].id)
+	require.Equal(t, "open", rules[0].ruleType)
+	require.Equal(t, "anchor apple", rules[0].requirement)
+	require.False(t, rules[0].override)
+
+	require.Equal(t, "Thing", rules[1].label)
+	require.True(t, rules[1].allow)
+	require.True(t, rules[1].override)
+	require.Equal(t, "/Applications/Thing.app", rules[1].path())
+	require.Equal(t, `cdhash H"abcdef"`, rules[1].requirement)
+
+	require.Equal(t, "5", rules[2].priority)
+	require.False(t, rules[2].allow)
+	require.True(t, rules[2].disabled)
+	require.Equal(t, "", rules[2].remarks)
+	require.Equal(t, "spctl", rules[2].source)
+}
+
+func TestParseSpctlAssessment(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testCaseName string
+		path         string
+		output       string
+		expected     assessment
+		expectFound  bool
+	}{
+		{
+			testCaseName: "accepted",
+			path:         "/Applications/Slack.app",
+			output: `/Applications/Slack.app: accepted
+source=Notarized Developer ID
+origin=Developer ID Application: Slack Technologies, Inc. (BQR82RBBHL)
+`,
+			expected: assessment{
+				verdict: "accepted",
+				source:  "Notarized Developer ID",
+				origin:  "Developer ID Application: Slack Technologies, Inc. (BQR82RBBHL)",
+			},
+			expectFound: true,
+		},
+		{
+			testCaseName: "rejected",
+			path:         "/Applications/Thing.app",
+			output: `/Applications/Thing.app: rejected (the code is valid but does not seem to be an app)
+origin=Developer ID Application: Example (ABCDE12345)
+`,
+			expected: assessment{
+				verdict: "rejected",
+				detail:  "the code is valid but does not seem to be an app",
+				origin:  "Developer ID Application: Example (ABCDE12345)",
+			},
+			expectFound: true,
+		},
+		{
+			testCaseName: "unsigned",
+			path:         "/tmp/thing",
+			output:       "/tmp/thing: code object is not signed at all\n",
+			expected:     assessment{detail: "code object is not signed at all"},
+			expectFound:  true,
+		},
+		{
+			testCaseName: "no result",
+			path:         "/tmp/thing",
+			output:       "spctl: unexpected error\n",
+			expectFound:  false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			a, found := parseSpctlAssessment(tt.path, tt.output)
+			require.Equal(t, tt.expectFound, found)
+			require.Equal(t, tt.expected, a)
+		})
+	}
+}
+
+func createTestDb(t *testing.T, path string, inserts ...string) {
+	conn, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec(schema)
+	require.NoError(t, err)
+
+	for _, insert := range inserts {
+		_, err = conn.Exec(insert)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build darwin
+// +build darwin
+
+package gatekeeper
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const overridesTableName = "kolide_macos_gatekeeper_overrides"
+
+type OverridesTable struct {
+	slogger *slog.Logger
+	dbPath  string
+}
+
+func OverridesTablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("id"),
+		table.TextColumn("label"),
+		table.TextColumn("type"),
+		table.IntegerColumn("allow"),
+		table.IntegerColumn("disabled"),
+		table.DoubleColumn("priority"),
+		table.TextColumn("requirement"),
+		table.TextColumn("path"),
+		table.TextColumn("remarks"),
+		table.IntegerColumn("user_override"),
+		table.BigIntColumn("expires"),
+		table.BigIntColumn("created"),
+		table.BigIntColumn("modified"),
+		table.TextColumn("user"),
+		table.TextColumn("source"),
+	}
+
+	t := &OverridesTable{
+		slogger: slogger.With("table", overridesTableName),
+		dbPath:  systemPolicyDbPath,
+	}
+
+	return table.NewPlugin(overridesTableName, columns, t.generate)
+}
+
+// generate returns Gatekeeper's policy rules. They're read from syspolicyd's database when
+// launcher has access to it, for the most detail, and otherwise from spctl.
+func (t *OverridesTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	rules, err := readRules(ctx, t.slogger, t.dbPath)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"could not read system policy database, falling back to spctl",
+			"err", err,
+		)
+
+		var stdout, stderr bytes.Buffer
+		if err := tablehelpers.Run(ctx, t.slogger, 30, allowedcmd.Spctl, []string{"--list"}, &stdout, &stderr); err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list gatekeeper rules",
+				"stderr", stderr.String(),
+				"err", err,
+			)
+			return nil, nil
+		}
+		rules = parseSpctlList(stdout.String())
+	}
+
+	results := make([]map[string]string, 0, len(rules))
+	for _, r := range rules {
+		results = append(results, r.row())
+	}
+
+	return results, nil
+}
@@ -0,0 +1,94 @@
+package hardwaresecurity
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parseLockdown parses the contents of /sys/kernel/security/lockdown, which lists the
+// available lockdown modes with the current one in brackets, e.g. `none [integrity] confidentiality`.
+func parseLockdown(contents string) (string, error) {
+	for _, mode := range strings.Fields(contents) {
+		if strings.HasPrefix(mode, "[") && strings.HasSuffix(mode, "]") {
+			return strings.Trim(mode, "[]"), nil
+		}
+	}
+
+	return "", fmt.Errorf("no current lockdown mode in %q", strings.TrimSpace(contents))
+}
+
+// parseCsrutilStatus parses the output of `csrutil status` or `csrutil authenticated-root status`,
+// returning whether the protection is enabled and its status string. Example output:
+//
+//	System Integrity Protection status: enabled.
+//	Authenticated Root status: enabled
+//	System Integrity Protection status: enabled (Custom Configuration).
+func parseCsrutilStatus(output []byte) (bool, string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		_, status, found := strings.Cut(line, "status:")
+		if !found {
+			continue
+		}
+
+		status = strings.TrimSuffix(strings.TrimSpace(status), ".")
+		return strings.HasPrefix(status, "enabled"), status, nil
+	}
+
+	return false, "", fmt.Errorf("no status in csrutil output %q", strings.TrimSpace(string(output)))
+}
+
+// This regexp matches the security mode line in `bputil --display-policy` output, e.g.
+// `Security Mode:               Full       (smb0): absent`
+var securityModeRegexp = regexp.MustCompile(`^Security Mode:\s+(\S+)`)
+
+// parseBootPolicySecurityMode parses the secure boot security mode (Full, Reduced, or Permissive)
+// from the output of `bputil --display-policy`.
+func parseBootPolicySecurityMode(output []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if m := securityModeRegexp.FindStringSubmatch(strings.TrimSpace(scanner.Text())); m != nil {
+			return m[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("no security mode in bputil output")
+}
+
+// intsFromWmi converts a WMI integer or integer array property into a slice of ints.
+// WMI returns uint32 properties as int32, and arrays as []interface{}.
+func intsFromWmi(val interface{}) []int {
+	switch v := val.(type) {
+	case []interface{}:
+		var ints []int
+		for _, item := range v {
+			ints = append(ints, intsFromWmi(item)...)
+		}
+		return ints
+	case int32:
+		return []int{int(v)}
+	case uint32:
+		return []int{int(v)}
+	case int64:
+		return []int{int(v)}
+	case uint8:
+		return []int{int(v)}
+	case int:
+		return []int{v}
+	}
+
+	return nil
+}
+
+func containsInt(haystack []int, needle int) bool {
+	for _, i := range haystack {
+		if i == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package hardwaresecurity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLockdown(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		contents    string
+		expected    string
+		expectedErr bool
+	}{
+		{contents: "[none] integrity confidentiality\n", expected: "none"},
+		{contents: "none [integrity] confidentiality\n", expected: "integrity"},
+		{contents: "none integrity [confidentiality]", expected: "confidentiality"},
+		{contents: "none integrity confidentiality", expectedErr: true},
+		{contents: "", expectedErr: true},
+	} {
+		tt := tt
+		t.Run(tt.contents, func(t *testing.T) {
+			t.Parallel()
+
+			mode, err := parseLockdown(tt.contents)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, mode)
+		})
+	}
+}
+
+func TestParseCsrutilStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name            string
+		output          string
+		expectedEnabled bool
+		expectedStatus  string
+		expectedErr     bool
+	}{
+		{
+			name:            "sip enabled",
+			output:          "System Integrity Protection status: enabled.\n",
+			expectedEnabled: true,
+			expectedStatus:  "enabled",
+		},
+		{
+			name:            "sip disabled",
+			output:          "System Integrity Protection status: disabled.\n",
+			expectedEnabled: false,
+			expectedStatus:  "disabled",
+		},
+		{
+			name:            "sip custom configuration",
+			output:          "System Integrity Protection status: enabled (Custom Configuration).\n\nConfiguration:\n\tApple Internal: disabled\n\tKext Signing: disabled\n",
+			expectedEnabled: true,
+			expectedStatus:  "enabled (Custom Configuration)",
+		},
+		{
+			name:            "authenticated root enabled",
+			output:          "Authenticated Root status: enabled\n",
+			expectedEnabled: true,
+			expectedStatus:  "enabled",
+		},
+		{
+			name:        "unexpected output",
+			output:      "csrutil: unrecognized command\n",
+			expectedErr: true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			enabled, status, err := parseCsrutilStatus([]byte(tt.output))
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedEnabled, enabled)
+			require.Equal(t, tt.expectedStatus, status)
+		})
+	}
+}
+
+func TestParseBootPolicySecurityMode(t *testing.T) {
+	t.Parallel()
+
+	output := `Local policy:
+Pairing Integrity                             : Valid
+Security Mode                           (CSEC): 1
+Security Mode:               Reduced    (smb0): present
+SIP Status:                  Enabled    (sip0): absent
+`
+	mode, err := parseBootPolicySecurityMode([]byte(output))
+	require.NoError(t, err)
+	require.Equal(t, "Reduced", mode)
+
+	_, err = parseBootPolicySecurityMode([]byte("This utility is not meant for normal users or even sysadmins.\n"))
+	require.Error(t, err)
+}
+
+func TestIntsFromWmi(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []int{2}, intsFromWmi(int32(2)))
+	require.Equal(t, []int{1, 2}, intsFromWmi([]interface{}{int32(1), int32(2)}))
+	require.Nil(t, intsFromWmi(nil))
+	require.Nil(t, intsFromWmi("2"))
+}
+
+func TestSecurityCheckToRow(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t,
+		map[string]string{"check": "tpm", "enabled": "1", "value": "tpm0", "error": ""},
+		securityCheck{name: "tpm", enabled: true, value: "tpm0"}.toRow(),
+	)
+	require.Equal(t,
+		map[string]string{"check": "kernel_lockdown", "enabled": "0", "value": "none", "error": ""},
+		securityCheck{name: "kernel_lockdown", value: "none"}.toRow(),
+	)
+	require.Equal(t,
+		map[string]string{"check": "secure_boot", "enabled": "", "value": "", "error": "no efi"},
+		securityCheck{name: "secure_boot", enabled: true, err: errors.New("no efi")}.toRow(),
+	)
+}
@@ -0,0 +1,75 @@
+// Package hardwaresecurity provides a single table summarizing the hardware-backed
+// security posture of the device. Each platform reports the checks that apply to it:
+// virtualization-based security and Credential Guard on Windows, SIP, the signed system
+// volume, and secure boot policy on macOS, and kernel lockdown, IOMMU, secure boot, and
+// TPM presence on Linux.
+package hardwaresecurity
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_hardware_security"
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+// securityCheck is the result of a single hardware security check. If the check could
+// not be performed, err is set, and enabled and value are meaningless.
+type securityCheck struct {
+	name    string
+	enabled bool
+	value   string
+	err     error
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("check"),
+		table.IntegerColumn("enabled"),
+		table.TextColumn("value"),
+		table.TextColumn("error"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	for _, c := range t.checks(ctx) {
+		results = append(results, c.toRow())
+	}
+
+	return results, nil
+}
+
+func (c securityCheck) toRow() map[string]string {
+	row := map[string]string{
+		"check":   c.name,
+		"enabled": "",
+		"value":   c.value,
+		"error":   "",
+	}
+
+	if c.err != nil {
+		row["error"] = c.err.Error()
+		return row
+	}
+
+	if c.enabled {
+		row["enabled"] = "1"
+	} else {
+		row["enabled"] = "0"
+	}
+
+	return row
+}
@@ -0,0 +1,70 @@
+//go:build darwin
+// +build darwin
+
+package hardwaresecurity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+func (t *Table) checks(ctx context.Context) []securityCheck {
+	checks := []securityCheck{
+		t.csrutilCheck(ctx, "system_integrity_protection", []string{"status"}),
+		t.csrutilCheck(ctx, "signed_system_volume", []string{"authenticated-root", "status"}),
+	}
+
+	// The boot policy is only available on Apple Silicon; Intel Macs report secure boot
+	// through the T2 chip, which has no equivalent command line interface.
+	if runtime.GOARCH == "arm64" {
+		checks = append(checks, t.secureBootPolicyCheck(ctx))
+	}
+
+	for _, c := range checks {
+		if c.err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not perform hardware security check",
+				"check", c.name,
+				"err", c.err,
+			)
+		}
+	}
+
+	return checks
+}
+
+func (t *Table) csrutilCheck(ctx context.Context, name string, args []string) securityCheck {
+	c := securityCheck{name: name}
+
+	output, err := tablehelpers.RunSimple(ctx, t.slogger, 30, allowedcmd.Csrutil, args)
+	if err != nil {
+		c.err = fmt.Errorf("running csrutil: %w", err)
+		return c
+	}
+
+	c.enabled, c.value, c.err = parseCsrutilStatus(output)
+
+	return c
+}
+
+// secureBootPolicyCheck reports the secure boot security mode. Only Full security is
+// considered enabled.
+func (t *Table) secureBootPolicyCheck(ctx context.Context) securityCheck {
+	c := securityCheck{name: "secure_boot"}
+
+	output, err := tablehelpers.RunSimple(ctx, t.slogger, 30, allowedcmd.Bputil, []string{"--display-policy"})
+	if err != nil {
+		c.err = fmt.Errorf("running bputil: %w", err)
+		return c
+	}
+
+	c.value, c.err = parseBootPolicySecurityMode(output)
+	c.enabled = c.err == nil && c.value == "Full"
+
+	return c
+}
@@ -0,0 +1,109 @@
+//go:build linux
+// +build linux
+
+package hardwaresecurity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kolide/launcher/pkg/efi"
+)
+
+const (
+	lockdownPath = "/sys/kernel/security/lockdown"
+	iommuDir     = "/sys/class/iommu"
+	tpmDir       = "/sys/class/tpm"
+)
+
+func (t *Table) checks(ctx context.Context) []securityCheck {
+	checks := []securityCheck{
+		secureBootCheck(),
+		lockdownCheck(),
+		sysfsClassCheck("iommu", iommuDir),
+		tpmCheck(),
+	}
+
+	for _, c := range checks {
+		if c.err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not perform hardware security check",
+				"check", c.name,
+				"err", c.err,
+			)
+		}
+	}
+
+	return checks
+}
+
+func secureBootCheck() securityCheck {
+	c := securityCheck{name: "secure_boot"}
+
+	c.enabled, c.err = efi.ReadSecureBoot()
+	if c.err != nil {
+		c.err = fmt.Errorf("reading secure boot from efi: %w", c.err)
+	}
+
+	return c
+}
+
+func lockdownCheck() securityCheck {
+	c := securityCheck{name: "kernel_lockdown"}
+
+	contents, err := os.ReadFile(lockdownPath)
+	if err != nil {
+		c.err = fmt.Errorf("reading %s: %w", lockdownPath, err)
+		return c
+	}
+
+	c.value, c.err = parseLockdown(string(contents))
+	c.enabled = c.err == nil && c.value != "none"
+
+	return c
+}
+
+// sysfsClassCheck reports whether any devices are registered in the given sysfs class directory,
+// with the device names as the value.
+func sysfsClassCheck(name string, dir string) securityCheck {
+	c := securityCheck{name: name}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		c.err = fmt.Errorf("reading %s: %w", dir, err)
+		return c
+	}
+
+	devices := make([]string, len(entries))
+	for i, entry := range entries {
+		devices[i] = entry.Name()
+	}
+
+	c.enabled = len(devices) > 0
+	c.value = strings.Join(devices, ",")
+
+	return c
+}
+
+// tpmCheck reports whether a TPM is present, with its major version as the value.
+func tpmCheck() securityCheck {
+	c := sysfsClassCheck("tpm", tpmDir)
+	if c.err != nil || !c.enabled {
+		return c
+	}
+
+	firstTpm := strings.Split(c.value, ",")[0]
+	version, err := os.ReadFile(filepath.Join(tpmDir, firstTpm, "tpm_version_major"))
+	if err != nil {
+		// Older kernels don't expose the version, so just report the device
+		return c
+	}
+
+	c.value = fmt.Sprintf("%s (version %s)", firstTpm, strings.TrimSpace(string(version)))
+
+	return c
+}
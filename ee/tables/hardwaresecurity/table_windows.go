@@ -0,0 +1,151 @@
+//go:build windows
+// +build windows
+
+package hardwaresecurity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/wmi"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	deviceGuardNamespace = `root\Microsoft\Windows\DeviceGuard`
+	tpmNamespace         = `root\CIMV2\Security\MicrosoftTpm`
+	secureBootStateKey   = `SYSTEM\CurrentControlSet\Control\SecureBoot\State`
+)
+
+// Values for Win32_DeviceGuard, see
+// https://learn.microsoft.com/en-us/windows/security/hardware-security/enable-virtualization-based-protection-of-code-integrity
+const (
+	vbsStatusRunning = 2
+
+	securityServiceCredentialGuard = 1
+	securityServiceHvci            = 2
+)
+
+var vbsStatusNames = map[int]string{
+	0: "not enabled",
+	1: "enabled but not running",
+	2: "running",
+}
+
+func (t *Table) checks(ctx context.Context) []securityCheck {
+	checks := append(t.deviceGuardChecks(ctx), secureBootCheck(), t.tpmCheck(ctx))
+
+	for _, c := range checks {
+		if c.err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not perform hardware security check",
+				"check", c.name,
+				"err", c.err,
+			)
+		}
+	}
+
+	return checks
+}
+
+// deviceGuardChecks reports the status of virtualization-based security, and of the
+// security services that depend on it.
+func (t *Table) deviceGuardChecks(ctx context.Context) []securityCheck {
+	vbs := securityCheck{name: "virtualization_based_security"}
+	credentialGuard := securityCheck{name: "credential_guard"}
+	hvci := securityCheck{name: "hypervisor_enforced_code_integrity"}
+
+	properties := []string{"VirtualizationBasedSecurityStatus", "SecurityServicesConfigured", "SecurityServicesRunning"}
+	results, err := wmi.Query(ctx, t.slogger, "Win32_DeviceGuard", properties, wmi.ConnectNamespace(deviceGuardNamespace))
+	if err == nil && len(results) == 0 {
+		err = fmt.Errorf("no Win32_DeviceGuard results")
+	}
+	if err != nil {
+		err = fmt.Errorf("querying device guard: %w", err)
+		vbs.err, credentialGuard.err, hvci.err = err, err, err
+		return []securityCheck{vbs, credentialGuard, hvci}
+	}
+
+	result := results[0]
+
+	if status := intsFromWmi(result["VirtualizationBasedSecurityStatus"]); len(status) > 0 {
+		vbs.enabled = status[0] == vbsStatusRunning
+		vbs.value = vbsStatusNames[status[0]]
+	}
+
+	configured := intsFromWmi(result["SecurityServicesConfigured"])
+	running := intsFromWmi(result["SecurityServicesRunning"])
+	credentialGuard = securityServiceCheck(credentialGuard.name, securityServiceCredentialGuard, configured, running)
+	hvci = securityServiceCheck(hvci.name, securityServiceHvci, configured, running)
+
+	return []securityCheck{vbs, credentialGuard, hvci}
+}
+
+func securityServiceCheck(name string, service int, configured []int, running []int) securityCheck {
+	c := securityCheck{
+		name:    name,
+		enabled: containsInt(running, service),
+	}
+
+	switch {
+	case c.enabled:
+		c.value = "running"
+	case containsInt(configured, service):
+		c.value = "configured but not running"
+	default:
+		c.value = "not configured"
+	}
+
+	return c
+}
+
+func secureBootCheck() securityCheck {
+	c := securityCheck{name: "secure_boot"}
+
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, secureBootStateKey, registry.QUERY_VALUE)
+	if err != nil {
+		// Legacy BIOS systems don't have this key at all
+		if err == registry.ErrNotExist {
+			c.value = "unsupported"
+			return c
+		}
+		c.err = fmt.Errorf("opening secure boot state key: %w", err)
+		return c
+	}
+	defer key.Close()
+
+	enabled, _, err := key.GetIntegerValue("UEFISecureBootEnabled")
+	if err != nil {
+		c.err = fmt.Errorf("reading UEFISecureBootEnabled: %w", err)
+		return c
+	}
+
+	c.enabled = enabled == 1
+
+	return c
+}
+
+// tpmCheck reports whether a TPM is present and enabled, with its spec version as the value.
+func (t *Table) tpmCheck(ctx context.Context) securityCheck {
+	c := securityCheck{name: "tpm"}
+
+	properties := []string{"IsEnabled_InitialValue", "IsActivated_InitialValue", "SpecVersion"}
+	results, err := wmi.Query(ctx, t.slogger, "Win32_Tpm", properties, wmi.ConnectNamespace(tpmNamespace))
+	if err != nil {
+		c.err = fmt.Errorf("querying tpm: %w", err)
+		return c
+	}
+
+	// No results means there's no TPM
+	if len(results) == 0 {
+		return c
+	}
+
+	enabled, _ := results[0]["IsEnabled_InitialValue"].(bool)
+	activated, _ := results[0]["IsActivated_InitialValue"].(bool)
+	c.enabled = enabled && activated
+	c.value, _ = results[0]["SpecVersion"].(string)
+
+	return c
+}
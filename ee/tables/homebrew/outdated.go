@@ -0,0 +1,79 @@
+//go:build !windows
+// +build !windows
+
+package brew_upgradeable
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const outdatedTableName = "kolide_brew_outdated"
+
+type OutdatedTable struct {
+	slogger *slog.Logger
+}
+
+// OutdatedTablePlugin lists outdated Homebrew formulae and casks, with the installed and
+// available versions. Unlike kolide_brew_upgradeable, it has a fixed schema.
+func OutdatedTablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("type"),
+		table.TextColumn("installed_versions"),
+		table.TextColumn("current_version"),
+		table.TextColumn("candidate_version"),
+		table.IntegerColumn("pinned"),
+		table.TextColumn("pinned_version"),
+		table.TextColumn("uid"),
+	}
+
+	t := &OutdatedTable{
+		slogger: slogger.With("table", outdatedTableName),
+	}
+
+	return table.NewPlugin(outdatedTableName, columns, t.generate)
+}
+
+func (t *OutdatedTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	uid, err := brewOwnerUid(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uid == "" {
+		// No data, no error
+		return nil, nil
+	}
+
+	// Brew can take a while to load the first time the command is ran, so leaving 60 seconds for the timeout here.
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 60, allowedcmd.Brew, []string{"outdated", "--json=v2"}, &stdout, &stderr, tablehelpers.WithUid(uid)); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure querying brew outdated packages",
+			"err", err,
+			"target_uid", uid,
+			"stderr", stderr.String(),
+		)
+		return nil, nil
+	}
+
+	results, err := parseOutdated(stdout.Bytes())
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"failure parsing brew outdated packages",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	for _, row := range results {
+		row["uid"] = uid
+	}
+
+	return results, nil
+}
@@ -0,0 +1,66 @@
+package brew_upgradeable
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// brewOutdated is the output of `brew outdated --json=v2`. Brew calls the version an outdated
+// package can be upgraded to its current_version.
+type brewOutdated struct {
+	Formulae []brewOutdatedPackage `json:"formulae"`
+	Casks    []brewOutdatedPackage `json:"casks"`
+}
+
+type brewOutdatedPackage struct {
+	Name              string   `json:"name"`
+	InstalledVersions []string `json:"installed_versions"`
+	CurrentVersion    string   `json:"current_version"`
+	Pinned            bool     `json:"pinned"`
+	PinnedVersion     *string  `json:"pinned_version"`
+}
+
+// parseOutdated turns the output of `brew outdated --json=v2` into a row per outdated formula or cask.
+func parseOutdated(output []byte) ([]map[string]string, error) {
+	var outdated brewOutdated
+	if err := json.Unmarshal(output, &outdated); err != nil {
+		return nil, fmt.Errorf("unmarshalling brew outdated output: %w", err)
+	}
+
+	results := make([]map[string]string, 0, len(outdated.Formulae)+len(outdated.Casks))
+	for _, f := range outdated.Formulae {
+		results = append(results, f.toRow("formula"))
+	}
+	for _, c := range outdated.Casks {
+		results = append(results, c.toRow("cask"))
+	}
+
+	return results, nil
+}
+
+func (p brewOutdatedPackage) toRow(packageType string) map[string]string {
+	row := map[string]string{
+		"name":               p.Name,
+		"type":               packageType,
+		"installed_versions": strings.Join(p.InstalledVersions, ","),
+		"current_version":    "",
+		"candidate_version":  p.CurrentVersion,
+		"pinned":             "0",
+		"pinned_version":     "",
+	}
+
+	// Several versions of a formula may be installed; the last is the newest
+	if len(p.InstalledVersions) > 0 {
+		row["current_version"] = p.InstalledVersions[len(p.InstalledVersions)-1]
+	}
+
+	if p.Pinned {
+		row["pinned"] = "1"
+	}
+	if p.PinnedVersion != nil {
+		row["pinned_version"] = *p.PinnedVersion
+	}
+
+	return row
+}
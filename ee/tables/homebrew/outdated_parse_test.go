@@ -0,0 +1,54 @@
+package brew_upgradeable
+
+import (
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/brew_outdated.json
+var brewOutdatedJson []byte
+
+func TestParseOutdated(t *testing.T) {
+	t.Parallel()
+
+	results, err := parseOutdated(brewOutdatedJson)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{
+			"name":               "openssl@3",
+			"type":               "formula",
+			"installed_versions": "3.2.1",
+			"current_version":    "3.2.1",
+			"candidate_version":  "3.3.0",
+			"pinned":             "0",
+			"pinned_version":     "",
+		},
+		{
+			"name":               "node",
+			"type":               "formula",
+			"installed_versions": "21.6.2,21.7.1",
+			"current_version":    "21.7.1",
+			"candidate_version":  "22.1.0",
+			"pinned":             "1",
+			"pinned_version":     "21.7.1",
+		},
+		{
+			"name":               "firefox",
+			"type":               "cask",
+			"installed_versions": "124.0.2",
+			"current_version":    "124.0.2",
+			"candidate_version":  "125.0.3",
+			"pinned":             "0",
+			"pinned_version":     "",
+		},
+	}, results)
+
+	results, err = parseOutdated([]byte(`{"formulae":[],"casks":[]}`))
+	require.NoError(t, err)
+	require.Empty(t, results)
+
+	_, err = parseOutdated([]byte("Error: brew is broken"))
+	require.Error(t, err)
+}
@@ -40,31 +40,15 @@ func TablePlugin(slogger *slog.Logger) *table.Plugin {
 func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	var results []map[string]string
 
-	// Brew is owned by a single user on a system. Brew is only intended to run with the context of
-	// that user. To reduce duplicating the WithUid table helper, we can find the owner of the binary,
-	// and pass the said owner to the WIthUid method to handle setting the appropriate env vars.
-	cmd, err := allowedcmd.Brew(ctx)
-
-	if err != nil {
-		if errors.Is(err, allowedcmd.ErrCommandNotFound) {
-			// No data, no error
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failure allocating allowedcmd.Brew: %w", err)
-	}
-
-	info, err := os.Stat(cmd.Path)
+	uid, err := brewOwnerUid(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failure getting FileInfo: %s. err: %w", cmd.Path, err)
+		return nil, err
 	}
-
-	stat, ok := info.Sys().(*syscall.Stat_t)
-	if !ok {
-		return nil, fmt.Errorf("failure getting Sys data source: %s", cmd.Path)
+	if uid == "" {
+		// No data, no error
+		return nil, nil
 	}
 
-	uid := strconv.FormatUint(uint64(stat.Uid), 10)
-
 	for _, dataQuery := range tablehelpers.GetConstraints(queryContext, "query", tablehelpers.WithDefaults("*")) {
 		// Brew can take a while to load the first time the command is ran, so leaving 60 seconds for the timeout here.
 		var output bytes.Buffer
@@ -98,3 +82,30 @@ func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) (
 
 	return results, nil
 }
+
+// brewOwnerUid returns the uid of the user who owns brew, or an empty string if brew isn't installed.
+//
+// Brew is owned by a single user on a system. Brew is only intended to run with the context of
+// that user. To reduce duplicating the WithUid table helper, we can find the owner of the binary,
+// and pass the said owner to the WIthUid method to handle setting the appropriate env vars.
+func brewOwnerUid(ctx context.Context) (string, error) {
+	cmd, err := allowedcmd.Brew(ctx)
+	if err != nil {
+		if errors.Is(err, allowedcmd.ErrCommandNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failure allocating allowedcmd.Brew: %w", err)
+	}
+
+	info, err := os.Stat(cmd.Path)
+	if err != nil {
+		return "", fmt.Errorf("failure getting FileInfo: %s. err: %w", cmd.Path, err)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("failure getting Sys data source: %s", cmd.Path)
+	}
+
+	return strconv.FormatUint(uint64(stat.Uid), 10), nil
+}
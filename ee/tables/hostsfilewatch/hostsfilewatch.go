@@ -0,0 +1,64 @@
+package hostsfilewatch
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/hostsfilewatcher"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_hosts_file_watch"
+
+// TablePlugin provides an osquery table of changes to the hosts file and resolver configuration,
+// as recorded by launcher's hosts file watcher. Unlike osquery's own evented tables, the events
+// persist across osquery and launcher restarts, and changes made while launcher was not running
+// are reported with source `startup`.
+func TablePlugin(store types.Iterator) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.BigIntColumn("time"),
+		table.TextColumn("path"),
+		table.TextColumn("action"),
+		table.TextColumn("source"),
+		table.TextColumn("sha256"),
+		table.TextColumn("previous_sha256"),
+		table.BigIntColumn("size"),
+		table.TextColumn("added"),
+		table.TextColumn("removed"),
+	}
+
+	return table.NewPlugin(tableName, columns, generate(store))
+}
+
+func generate(store types.Iterator) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		results := make([]map[string]string, 0)
+
+		if store == nil {
+			return results, nil
+		}
+
+		events, err := hostsfilewatcher.Events(store)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			results = append(results, map[string]string{
+				"time":            strconv.FormatInt(event.Time, 10),
+				"path":            event.Path,
+				"action":          event.Action,
+				"source":          event.Source,
+				"sha256":          event.Sha256,
+				"previous_sha256": event.PreviousSha256,
+				"size":            strconv.FormatInt(event.Size, 10),
+				"added":           strings.Join(event.Added, "\n"),
+				"removed":         strings.Join(event.Removed, "\n"),
+			})
+		}
+
+		return results, nil
+	}
+}
@@ -0,0 +1,55 @@
+package hostsfilewatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	require.NoError(t, store.Set([]byte("checkpoint:/etc/hosts"), []byte(`{"exists":true}`)))
+	require.NoError(t, store.Set([]byte("event:00000000000000000002"), []byte(`{"time":2,"path":"/etc/hosts","action":"modified","source":"fsnotify","sha256":"b","previous_sha256":"a","size":40,"added":["10.0.0.66 login.example.com","10.0.0.66 mail.example.com"]}`)))
+	require.NoError(t, store.Set([]byte("event:00000000000000000001"), []byte(`{"time":1,"path":"/etc/resolv.conf","action":"deleted","source":"startup","previous_sha256":"c","removed":["nameserver 1.1.1.1"]}`)))
+	require.NoError(t, store.Set([]byte("event:00000000000000000003"), []byte(`not json`)))
+
+	rows, err := generate(store)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{
+			"time":            "1",
+			"path":            "/etc/resolv.conf",
+			"action":          "deleted",
+			"source":          "startup",
+			"sha256":          "",
+			"previous_sha256": "c",
+			"size":            "0",
+			"added":           "",
+			"removed":         "nameserver 1.1.1.1",
+		},
+		{
+			"time":            "2",
+			"path":            "/etc/hosts",
+			"action":          "modified",
+			"source":          "fsnotify",
+			"sha256":          "b",
+			"previous_sha256": "a",
+			"size":            "40",
+			"added":           "10.0.0.66 login.example.com\n10.0.0.66 mail.example.com",
+			"removed":         "",
+		},
+	}, rows)
+}
+
+func TestGenerate_NoStore(t *testing.T) {
+	t.Parallel()
+
+	rows, err := generate(nil)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
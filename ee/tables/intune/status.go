@@ -0,0 +1,52 @@
+package intune
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The OMA-DM client records sync times like `20241016T101530Z`
+const omadmTimeLayout = "20060102T150405Z"
+
+func parseOmadmTime(s string) (time.Time, error) {
+	return time.Parse(omadmTimeLayout, s)
+}
+
+// enrollmentStateNames describes the EnrollmentState registry values for MDM enrollments.
+var enrollmentStateNames = map[uint64]string{
+	0: "not enrolled",
+	1: "enrolled",
+	2: "enrollment in progress",
+	3: "unenrollment in progress",
+}
+
+func enrollmentStateName(state uint64) string {
+	if name, ok := enrollmentStateNames[state]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// newestModTime returns the most recent modification time of the files in dir matching
+// pattern. Agents write their logs continuously, so this tells us when they were last active.
+func newestModTime(dir string, pattern string) time.Time {
+	var newest time.Time
+
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return newest
+	}
+
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+
+	return newest
+}
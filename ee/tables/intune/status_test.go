@@ -0,0 +1,51 @@
+package intune
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOmadmTime(t *testing.T) {
+	t.Parallel()
+
+	ts, err := parseOmadmTime("20241016T101530Z")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.October, 16, 10, 15, 30, 0, time.UTC), ts)
+
+	_, err = parseOmadmTime("10/16/2024 10:15:30")
+	require.Error(t, err)
+}
+
+func TestEnrollmentStateName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "enrolled", enrollmentStateName(1))
+	require.Equal(t, "unknown", enrollmentStateName(42))
+}
+
+func TestNewestModTime(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.True(t, newestModTime(dir, "*.log").IsZero())
+
+	older := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	newer := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+	ignored := time.Now().Truncate(time.Second)
+
+	for name, modTime := range map[string]time.Time{
+		"IntuneMDMDaemon 2024-10-15.log": older,
+		"IntuneMDMDaemon 2024-10-16.log": newer,
+		"other.txt":                      ignored,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("log"), 0600))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	require.True(t, newer.Equal(newestModTime(dir, "IntuneMDMDaemon*.log")))
+}
@@ -0,0 +1,65 @@
+//go:build darwin || windows
+// +build darwin windows
+
+// Package intune provides a table reporting the health of the Microsoft Intune management
+// agents on the device, to help triage whether a policy gap is launcher-side or MDM-side.
+package intune
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_intune_status"
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+// component is the status of a single Intune agent component.
+type component struct {
+	name         string
+	installed    bool
+	version      string
+	state        string
+	lastActivity time.Time
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("component"),
+		table.IntegerColumn("installed"),
+		table.TextColumn("version"),
+		table.TextColumn("state"),
+		table.IntegerColumn("last_activity"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (c component) toRow() map[string]string {
+	row := map[string]string{
+		"component":     c.name,
+		"installed":     "0",
+		"version":       c.version,
+		"state":         c.state,
+		"last_activity": "",
+	}
+
+	if c.installed {
+		row["installed"] = "1"
+	}
+
+	if !c.lastActivity.IsZero() {
+		row["last_activity"] = strconv.FormatInt(c.lastActivity.Unix(), 10)
+	}
+
+	return row
+}
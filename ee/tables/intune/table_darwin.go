@@ -0,0 +1,64 @@
+//go:build darwin
+// +build darwin
+
+package intune
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/groob/plist"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	companyPortalPath = "/Applications/Company Portal.app"
+	intuneAgentPath   = "/Library/Intune/Microsoft Intune Agent.app"
+	intuneLogDir      = "/Library/Logs/Microsoft/Intune"
+)
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	companyPortal := appComponent("company_portal", companyPortalPath)
+
+	agent := appComponent("intune_agent", intuneAgentPath)
+	if agent.installed {
+		agent.lastActivity = newestModTime(intuneLogDir, "IntuneMDMDaemon*.log")
+	}
+
+	return []map[string]string{companyPortal.toRow(), agent.toRow()}, nil
+}
+
+// appComponent reports whether the given app bundle is installed, and its version.
+func appComponent(name string, appPath string) component {
+	c := component{name: name}
+
+	if _, err := os.Stat(appPath); err != nil {
+		return c
+	}
+	c.installed = true
+
+	if version, err := bundleVersion(appPath); err == nil {
+		c.version = version
+	}
+
+	return c
+}
+
+func bundleVersion(appPath string) (string, error) {
+	infoPlistPath := appPath + "/Contents/Info.plist"
+	f, err := os.Open(infoPlistPath)
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", infoPlistPath, err)
+	}
+	defer f.Close()
+
+	var info struct {
+		ShortVersion string `plist:"CFBundleShortVersionString"`
+	}
+	if err := plist.NewDecoder(f).Decode(&info); err != nil {
+		return "", fmt.Errorf("decoding %s: %w", infoPlistPath, err)
+	}
+
+	return info.ShortVersion, nil
+}
@@ -0,0 +1,162 @@
+//go:build windows
+// +build windows
+
+package intune
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	imeServiceName = "IntuneManagementExtension"
+	imeLogDir      = `C:\ProgramData\Microsoft\IntuneManagementExtension\Logs`
+
+	enrollmentsKey = `SOFTWARE\Microsoft\Enrollments`
+	omadmKeyFmt    = `SOFTWARE\Microsoft\Provisioning\OMADM\Accounts\%s\Protected\ConnInfo`
+
+	// intuneProviderId is the ProviderID of enrollments managed by Intune
+	intuneProviderId = "MS DM Server"
+)
+
+var serviceStateNames = map[svc.State]string{
+	svc.Stopped:         "stopped",
+	svc.StartPending:    "start pending",
+	svc.StopPending:     "stop pending",
+	svc.Running:         "running",
+	svc.ContinuePending: "continue pending",
+	svc.PausePending:    "pause pending",
+	svc.Paused:          "paused",
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := []map[string]string{t.managementExtension(ctx).toRow()}
+
+	enrollments, err := t.mdmEnrollments(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read mdm enrollments",
+			"err", err,
+		)
+	}
+	for _, enrollment := range enrollments {
+		results = append(results, enrollment.toRow())
+	}
+
+	return results, nil
+}
+
+// managementExtension reports the state of the Intune Management Extension service, which runs
+// Win32 app installs, scripts, and remediations.
+func (t *Table) managementExtension(ctx context.Context) component {
+	c := component{name: "intune_management_extension"}
+
+	serviceManager, err := mgr.Connect()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not connect to service manager",
+			"err", err,
+		)
+		return c
+	}
+	defer serviceManager.Disconnect()
+
+	service, err := serviceManager.OpenService(imeServiceName)
+	if err != nil {
+		// Not installed
+		return c
+	}
+	defer service.Close()
+	c.installed = true
+
+	status, err := service.Query()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not query service status",
+			"service", imeServiceName,
+			"err", err,
+		)
+	} else {
+		c.state = serviceStateNames[status.State]
+	}
+
+	c.lastActivity = newestModTime(imeLogDir, "*.log")
+
+	return c
+}
+
+// mdmEnrollments reports each Intune MDM enrollment, with its last successful sync.
+func (t *Table) mdmEnrollments(ctx context.Context) ([]component, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, enrollmentsKey, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		if err == registry.ErrNotExist {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening enrollments key: %w", err)
+	}
+	defer key.Close()
+
+	enrollmentIds, err := key.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("reading enrollments: %w", err)
+	}
+
+	var enrollments []component
+	for _, enrollmentId := range enrollmentIds {
+		enrollmentKey, err := registry.OpenKey(key, enrollmentId, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+
+		providerId, _, err := enrollmentKey.GetStringValue("ProviderID")
+		if err != nil || providerId != intuneProviderId {
+			enrollmentKey.Close()
+			continue
+		}
+
+		c := component{
+			name:      "mdm_enrollment",
+			installed: true,
+		}
+		if state, _, err := enrollmentKey.GetIntegerValue("EnrollmentState"); err == nil {
+			c.state = enrollmentStateName(state)
+		}
+		enrollmentKey.Close()
+
+		c.lastActivity = t.lastSuccessfulSync(ctx, enrollmentId)
+		enrollments = append(enrollments, c)
+	}
+
+	return enrollments, nil
+}
+
+func (t *Table) lastSuccessfulSync(ctx context.Context, enrollmentId string) (lastSync time.Time) {
+	connInfoKey, err := registry.OpenKey(registry.LOCAL_MACHINE, fmt.Sprintf(omadmKeyFmt, enrollmentId), registry.QUERY_VALUE)
+	if err != nil {
+		return lastSync
+	}
+	defer connInfoKey.Close()
+
+	lastSuccess, _, err := connInfoKey.GetStringValue("ServerLastSuccessTime")
+	if err != nil {
+		return lastSync
+	}
+
+	lastSync, err = parseOmadmTime(lastSuccess)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"could not parse last sync time",
+			"value", lastSuccess,
+			"err", err,
+		)
+	}
+
+	return lastSync
+}
@@ -0,0 +1,359 @@
+package iosdevices
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"howett.net/plist"
+)
+
+// lockdownDir holds a pairing record for each device that has trusted the Mac, named for its UDID.
+const lockdownDir = "private/var/db/lockdown"
+
+// deviceCacheDomains are the preference domains where Finder (and iTunes, before it) cache
+// the devices that have connected. Each has a Devices dictionary, keyed by an internal ID.
+var deviceCacheDomains = []string{"com.apple.iPod", "com.apple.AMPDevicesAgent"}
+
+type device struct {
+	username        string
+	udid            string
+	name            string
+	productType     string
+	productVersion  string
+	serialNumber    string
+	paired          bool
+	lastConnected   time.Time
+	backupPath      string
+	lastBackup      time.Time
+	backupEncrypted *bool // nil when there's no backup, or its manifest couldn't be read
+}
+
+func (d device) row() map[string]string {
+	row := map[string]string{
+		"username":         d.username,
+		"udid":             d.udid,
+		"device_name":      d.name,
+		"product_type":     d.productType,
+		"product_version":  d.productVersion,
+		"serial_number":    d.serialNumber,
+		"paired":           boolToString(d.paired),
+		"last_connected":   unixString(d.lastConnected),
+		"backup_path":      d.backupPath,
+		"last_backup_time": unixString(d.lastBackup),
+		"backup_encrypted": "",
+	}
+	if d.backupEncrypted != nil {
+		row["backup_encrypted"] = boolToString(*d.backupEncrypted)
+	}
+	return row
+}
+
+// backupInfo is the part of a backup's Info.plist we need.
+type backupInfo struct {
+	DeviceName       string    `plist:"Device Name"`
+	DisplayName      string    `plist:"Display Name"`
+	LastBackupDate   time.Time `plist:"Last Backup Date"`
+	ProductType      string    `plist:"Product Type"`
+	ProductVersion   string    `plist:"Product Version"`
+	SerialNumber     string    `plist:"Serial Number"`
+	UniqueIdentifier string    `plist:"Unique Identifier"`
+	TargetIdentifier string    `plist:"Target Identifier"`
+}
+
+// backupManifest is the part of a backup's Manifest.plist we need. Manifests are not encrypted,
+// even when the backup is.
+type backupManifest struct {
+	IsEncrypted *bool     `plist:"IsEncrypted"`
+	Date        time.Time `plist:"Date"`
+	Lockdown    struct {
+		DeviceName     string `plist:"DeviceName"`
+		ProductType    string `plist:"ProductType"`
+		ProductVersion string `plist:"ProductVersion"`
+		SerialNumber   string `plist:"SerialNumber"`
+		UniqueDeviceID string `plist:"UniqueDeviceID"`
+	} `plist:"Lockdown"`
+}
+
+type cachedDevice struct {
+	DeviceClass     string    `plist:"Device Class"`
+	FirmwareVersion string    `plist:"Firmware Version String"`
+	SerialNumber    string    `plist:"Serial Number"`
+	Connected       time.Time `plist:"Connected"`
+}
+
+type deviceCache struct {
+	Devices map[string]cachedDevice `plist:"Devices"`
+}
+
+type deviceCollector struct {
+	rootDir string
+}
+
+func (dc *deviceCollector) users() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dc.rootDir, "Users"))
+	if err != nil {
+		return nil, fmt.Errorf("reading user directories: %w", err)
+	}
+
+	var users []string
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "Shared" || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		users = append(users, e.Name())
+	}
+
+	return users, nil
+}
+
+// pairedUdids returns the UDIDs of the devices with pairing records.
+func (dc *deviceCollector) pairedUdids() (map[string]bool, error) {
+	entries, err := os.ReadDir(filepath.Join(dc.rootDir, filepath.FromSlash(lockdownDir)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading pairing records: %w", err)
+	}
+
+	udids := make(map[string]bool)
+	for _, e := range entries {
+		name := e.Name()
+		// SystemConfiguration.plist holds the Mac's own pairing identity
+		if e.IsDir() || filepath.Ext(name) != ".plist" || name == "SystemConfiguration.plist" {
+			continue
+		}
+		udids[strings.ToUpper(strings.TrimSuffix(name, ".plist"))] = true
+	}
+
+	return udids, nil
+}
+
+// collect returns the devices known to each of the given users, and the paired devices no user
+// has backed up or synced. Errors reading individual sources are returned alongside the devices
+// that could be found.
+func (dc *deviceCollector) collect(usernames []string, includeUnowned bool) ([]device, error) {
+	var errs []error
+
+	paired, err := dc.pairedUdids()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	var devices []device
+	seenUdids := make(map[string]bool)
+	for _, username := range usernames {
+		userDevices, err := dc.userDevices(username)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		for _, d := range userDevices {
+			if d.udid != "" {
+				d.paired = paired[d.udid]
+				seenUdids[d.udid] = true
+			}
+			devices = append(devices, d)
+		}
+	}
+
+	if includeUnowned {
+		var unowned []string
+		for udid := range paired {
+			if !seenUdids[udid] {
+				unowned = append(unowned, udid)
+			}
+		}
+		sort.Strings(unowned)
+
+		for _, udid := range unowned {
+			devices = append(devices, device{udid: udid, paired: true})
+		}
+	}
+
+	return devices, errors.Join(errs...)
+}
+
+// userDevices returns the devices the user has backed up locally, or that have connected while
+// they were logged in.
+func (dc *deviceCollector) userDevices(username string) ([]device, error) {
+	var errs []error
+	byKey := make(map[string]*device)
+	var keys []string
+
+	add := func(key string, d *device) {
+		byKey[key] = d
+		keys = append(keys, key)
+	}
+
+	homeDir := filepath.Join(dc.rootDir, "Users", username)
+	backupDir := filepath.Join(homeDir, "Library", "Application Support", "MobileSync", "Backup")
+	backups, err := os.ReadDir(backupDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("reading %s: %w", backupDir, err))
+	}
+
+	for _, b := range backups {
+		if !b.IsDir() {
+			continue
+		}
+
+		d, err := readBackup(filepath.Join(backupDir, b.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		d.username = username
+		// The backup's path, as it is on the device rather than under our root
+		d.backupPath = "/" + filepath.ToSlash(strings.TrimPrefix(filepath.Join(backupDir, b.Name()), filepath.Clean(dc.rootDir)+string(filepath.Separator)))
+
+		// Older backups are kept alongside the current one, in directories suffixed with their date
+		if existing, ok := byKey[d.udid]; ok {
+			if d.lastBackup.After(existing.lastBackup) {
+				*existing = d
+			}
+			continue
+		}
+		add(d.udid, &d)
+	}
+
+	for _, domain := range deviceCacheDomains {
+		cachePath := filepath.Join(homeDir, "Library", "Preferences", domain+".plist")
+		cache, err := readDeviceCache(cachePath)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		ids := make([]string, 0, len(cache.Devices))
+		for id := range cache.Devices {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			cached := cache.Devices[id]
+
+			// The cache doesn't know the UDID, so match it to a backup by serial number
+			var d *device
+			for _, key := range keys {
+				if cached.SerialNumber != "" && strings.EqualFold(byKey[key].serialNumber, cached.SerialNumber) {
+					d = byKey[key]
+					break
+				}
+			}
+			if d == nil {
+				key := "serial:" + cached.SerialNumber
+				if cached.SerialNumber == "" {
+					key = "id:" + id
+				}
+				if existing, ok := byKey[key]; ok {
+					d = existing
+				} else {
+					d = &device{username: username, serialNumber: cached.SerialNumber}
+					add(key, d)
+				}
+			}
+
+			if d.productType == "" {
+				d.productType = cached.DeviceClass
+			}
+			if d.productVersion == "" {
+				d.productVersion = cached.FirmwareVersion
+			}
+			if cached.Connected.After(d.lastConnected) {
+				d.lastConnected = cached.Connected
+			}
+		}
+	}
+
+	devices := make([]device, 0, len(keys))
+	for _, key := range keys {
+		devices = append(devices, *byKey[key])
+	}
+
+	return devices, errors.Join(errs...)
+}
+
+// readBackup reads a local backup's Info.plist and Manifest.plist. Either is enough to identify
+// the device; only the manifest says whether the backup is encrypted.
+func readBackup(dir string) (device, error) {
+	var info backupInfo
+	infoErr := readPlist(filepath.Join(dir, "Info.plist"), &info)
+
+	var manifest backupManifest
+	manifestErr := readPlist(filepath.Join(dir, "Manifest.plist"), &manifest)
+
+	if infoErr != nil && manifestErr != nil {
+		return device{}, fmt.Errorf("reading backup %s: %w", dir, errors.Join(infoErr, manifestErr))
+	}
+
+	d := device{
+		udid:            firstNonEmpty(info.TargetIdentifier, info.UniqueIdentifier, manifest.Lockdown.UniqueDeviceID, filepath.Base(dir)),
+		name:            firstNonEmpty(info.DeviceName, info.DisplayName, manifest.Lockdown.DeviceName),
+		productType:     firstNonEmpty(info.ProductType, manifest.Lockdown.ProductType),
+		productVersion:  firstNonEmpty(info.ProductVersion, manifest.Lockdown.ProductVersion),
+		serialNumber:    firstNonEmpty(info.SerialNumber, manifest.Lockdown.SerialNumber),
+		lastBackup:      info.LastBackupDate,
+		backupEncrypted: manifest.IsEncrypted,
+	}
+	d.udid = strings.ToUpper(d.udid)
+	if d.lastBackup.IsZero() {
+		d.lastBackup = manifest.Date
+	}
+
+	return d, nil
+}
+
+func readDeviceCache(path string) (deviceCache, error) {
+	var cache deviceCache
+	if err := readPlist(path, &cache); err != nil {
+		return deviceCache{}, err
+	}
+	return cache, nil
+}
+
+func readPlist(path string, v any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := plist.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func unixString(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func boolToString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,105 @@
+package iosdevices
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	dc := &deviceCollector{rootDir: filepath.Join("testdata", "root")}
+
+	users, err := dc.users()
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "bob"}, users)
+
+	devices, err := dc.collect(users, true)
+	require.NoError(t, err)
+
+	rows := make([]map[string]string, len(devices))
+	for i, d := range devices {
+		rows[i] = d.row()
+	}
+
+	require.Equal(t, []map[string]string{
+		{
+			"username":         "alice",
+			"udid":             "00008030-001A2B3C4D5E6F70",
+			"device_name":      "Alice’s iPhone",
+			"product_type":     "iPhone12,1",
+			"product_version":  "17.4.1",
+			"serial_number":    "F2LXK0ABCDEF",
+			"paired":           "1",
+			"last_connected":   "1717317000",
+			"backup_path":      "/Users/alice/Library/Application Support/MobileSync/Backup/00008030-001A2B3C4D5E6F70",
+			"last_backup_time": "1714557600",
+			"backup_encrypted": "0",
+		},
+		{
+			// Only seen in the device cache
+			"username":         "alice",
+			"udid":             "",
+			"device_name":      "",
+			"product_type":     "iPad",
+			"product_version":  "16.7",
+			"serial_number":    "DMPXYZ123456",
+			"paired":           "0",
+			"last_connected":   "1700499600",
+			"backup_path":      "",
+			"last_backup_time": "",
+			"backup_encrypted": "",
+		},
+		{
+			// Only a manifest
+			"username":         "bob",
+			"udid":             "00008110-000B0B0B0B0B0B0B",
+			"device_name":      "Bob’s iPhone",
+			"product_type":     "iPhone14,5",
+			"product_version":  "17.3",
+			"serial_number":    "HX9BOB000000",
+			"paired":           "0",
+			"last_connected":   "",
+			"backup_path":      "/Users/bob/Library/Application Support/MobileSync/Backup/00008110-000B0B0B0B0B0B0B",
+			"last_backup_time": "1707556500",
+			"backup_encrypted": "1",
+		},
+		{
+			// Paired, but never backed up or synced
+			"username":         "",
+			"udid":             "00008101-000A1B2C3D4E5F60",
+			"device_name":      "",
+			"product_type":     "",
+			"product_version":  "",
+			"serial_number":    "",
+			"paired":           "1",
+			"last_connected":   "",
+			"backup_path":      "",
+			"last_backup_time": "",
+			"backup_encrypted": "",
+		},
+	}, rows)
+}
+
+func TestCollect_ConstrainedUser(t *testing.T) {
+	t.Parallel()
+
+	dc := &deviceCollector{rootDir: filepath.Join("testdata", "root")}
+
+	devices, err := dc.collect([]string{"bob", "nobody"}, false)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	require.Equal(t, "bob", devices[0].username)
+}
+
+func TestCollect_NoLockdown(t *testing.T) {
+	t.Parallel()
+
+	dc := &deviceCollector{rootDir: t.TempDir()}
+
+	devices, err := dc.collect(nil, true)
+	require.NoError(t, err)
+	require.Empty(t, devices)
+}
@@ -0,0 +1,84 @@
+//go:build darwin
+// +build darwin
+
+package iosdevices
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName                 = "kolide_ios_paired_devices"
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+)
+
+type Table struct {
+	slogger   *slog.Logger
+	collector *deviceCollector
+}
+
+// TablePlugin provides an osquery table of the iPhones and iPads paired with, backed up to, or
+// synced with the Mac. There's a row per user and device; devices that are paired but that no
+// user has backed up or synced have an empty username. backup_encrypted is empty when the user
+// has no local backup of the device.
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("udid"),
+		table.TextColumn("device_name"),
+		table.TextColumn("product_type"),
+		table.TextColumn("product_version"),
+		table.TextColumn("serial_number"),
+		table.IntegerColumn("paired"),
+		table.BigIntColumn("last_connected"),
+		table.TextColumn("backup_path"),
+		table.BigIntColumn("last_backup_time"),
+		table.IntegerColumn("backup_encrypted"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", tableName),
+		collector: &deviceCollector{rootDir: "/"},
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	// Paired devices no one has backed up only belong in unconstrained queries
+	includeUnowned := len(usernames) == 0
+	if len(usernames) == 0 {
+		var err error
+		usernames, err = t.collector.users()
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list users",
+				"err", err,
+			)
+		}
+	}
+
+	devices, err := t.collector.collect(usernames, includeUnowned)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read all device information",
+			"err", err,
+		)
+	}
+
+	results := make([]map[string]string, 0, len(devices))
+	for _, d := range devices {
+		results = append(results, d.row())
+	}
+
+	return results, nil
+}
@@ -0,0 +1,87 @@
+package jamf
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// jamf.log lines look like:
+//
+//	Wed Oct 16 09:15:01 Janes-MacBook-Pro jamf[12345]: Checking for policies triggered by "recurring check-in" for user "jane"...
+var logLineRegexp = regexp.MustCompile(`^(\w{3} \w{3}\s+\d{1,2} \d{2}:\d{2}:\d{2}) \S+ jamf\[\d+\]: (.*)$`)
+
+var (
+	checkInRegexp = regexp.MustCompile(`(?i)triggered by "recurring check-in"`)
+	errorRegexp   = regexp.MustCompile(`(?i)\b(error|could not|unable to|failed)\b`)
+)
+
+// jamf.log timestamps don't include a year
+const logTimestampLayout = "Mon Jan 2 15:04:05"
+
+// logSummary is what we learn about the jamf agent's recent activity from jamf.log.
+type logSummary struct {
+	lastCheckIn   time.Time
+	lastError     string
+	lastErrorTime time.Time
+}
+
+// parseJamfLog reads jamf.log, returning the time of the most recent recurring check-in, and the
+// most recent error. now is used to determine the year of the log timestamps.
+func parseJamfLog(r io.Reader, now time.Time) logSummary {
+	var summary logSummary
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := logLineRegexp.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+
+		ts, err := parseLogTimestamp(m[1], now)
+		if err != nil {
+			continue
+		}
+
+		message := m[2]
+		switch {
+		case checkInRegexp.MatchString(message):
+			summary.lastCheckIn = ts
+		case errorRegexp.MatchString(message):
+			summary.lastError = message
+			summary.lastErrorTime = ts
+		}
+	}
+
+	return summary
+}
+
+// parseLogTimestamp parses a jamf.log timestamp, assuming it's from the most recent year that
+// doesn't put it in the future.
+func parseLogTimestamp(s string, now time.Time) (time.Time, error) {
+	// Single-digit days are space-padded, so normalize whitespace before parsing
+	ts, err := time.ParseInLocation(logTimestampLayout, strings.Join(strings.Fields(s), " "), now.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ts = ts.AddDate(now.Year()-ts.Year(), 0, 0)
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+
+	return ts, nil
+}
+
+// parseJamfVersion parses the output of `jamf version`, e.g. `version=11.4.1-t1712591696`.
+func parseJamfVersion(output []byte) string {
+	for _, line := range strings.Split(string(output), "\n") {
+		if version, found := strings.CutPrefix(strings.TrimSpace(line), "version="); found {
+			return version
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,60 @@
+package jamf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJamfLog(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filepath.Join("testdata", "jamf.log"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	now := time.Date(2025, time.October, 17, 12, 0, 0, 0, time.UTC)
+	summary := parseJamfLog(f, now)
+
+	require.Equal(t, time.Date(2025, time.October, 16, 10, 22, 45, 0, time.UTC), summary.lastCheckIn)
+	require.Equal(t, "Could not connect to the JSS. Looking for cached policies...", summary.lastError)
+	require.Equal(t, time.Date(2025, time.October, 15, 9, 17, 2, 0, time.UTC), summary.lastErrorTime)
+}
+
+func TestParseJamfLog_Empty(t *testing.T) {
+	t.Parallel()
+
+	summary := parseJamfLog(strings.NewReader(""), time.Now())
+	require.True(t, summary.lastCheckIn.IsZero())
+	require.True(t, summary.lastErrorTime.IsZero())
+	require.Empty(t, summary.lastError)
+}
+
+func TestParseLogTimestamp(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.January, 2, 12, 0, 0, 0, time.UTC)
+
+	// A timestamp from late December must be from the previous year
+	ts, err := parseLogTimestamp("Wed Dec 31 23:59:59", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.December, 31, 23, 59, 59, 0, time.UTC), ts)
+
+	ts, err = parseLogTimestamp("Thu Jan  2 08:00:00", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2025, time.January, 2, 8, 0, 0, 0, time.UTC), ts)
+
+	_, err = parseLogTimestamp("not a timestamp", now)
+	require.Error(t, err)
+}
+
+func TestParseJamfVersion(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "11.4.1-t1712591696", parseJamfVersion([]byte("version=11.4.1-t1712591696\n")))
+	require.Equal(t, "", parseJamfVersion([]byte("jamf: command not found\n")))
+}
@@ -0,0 +1,143 @@
+//go:build darwin
+// +build darwin
+
+package jamf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/groob/plist"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName = "kolide_jamf_status"
+
+	jamfBinaryPath = "/usr/local/jamf/bin/jamf"
+	jamfPlistPath  = "/Library/Preferences/com.jamfsoftware.jamf.plist"
+	jamfLogPath    = "/private/var/log/jamf.log"
+
+	// maxLogBytes is how much of the end of jamf.log we read, since it can grow large
+	maxLogBytes = 1 << 20
+)
+
+type Table struct {
+	slogger   *slog.Logger
+	plistPath string
+	logPath   string
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.IntegerColumn("installed"),
+		table.TextColumn("version"),
+		table.TextColumn("jss_url"),
+		table.IntegerColumn("last_check_in"),
+		table.TextColumn("last_error"),
+		table.IntegerColumn("last_error_time"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", tableName),
+		plistPath: jamfPlistPath,
+		logPath:   jamfLogPath,
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	row := map[string]string{
+		"installed":       "0",
+		"version":         "",
+		"jss_url":         "",
+		"last_check_in":   "",
+		"last_error":      "",
+		"last_error_time": "",
+	}
+
+	if _, err := os.Stat(jamfBinaryPath); err != nil {
+		return []map[string]string{row}, nil
+	}
+	row["installed"] = "1"
+
+	if output, err := tablehelpers.RunSimple(ctx, t.slogger, 10, allowedcmd.Jamf, []string{"version"}); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get jamf version",
+			"err", err,
+		)
+	} else {
+		row["version"] = parseJamfVersion(output)
+	}
+
+	if jssUrl, err := t.readJssUrl(); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read jamf plist",
+			"err", err,
+		)
+	} else {
+		row["jss_url"] = jssUrl
+	}
+
+	summary, err := t.readLog()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read jamf log",
+			"err", err,
+		)
+		return []map[string]string{row}, nil
+	}
+
+	if !summary.lastCheckIn.IsZero() {
+		row["last_check_in"] = strconv.FormatInt(summary.lastCheckIn.Unix(), 10)
+	}
+	if !summary.lastErrorTime.IsZero() {
+		row["last_error"] = summary.lastError
+		row["last_error_time"] = strconv.FormatInt(summary.lastErrorTime.Unix(), 10)
+	}
+
+	return []map[string]string{row}, nil
+}
+
+func (t *Table) readJssUrl() (string, error) {
+	f, err := os.Open(t.plistPath)
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", t.plistPath, err)
+	}
+	defer f.Close()
+
+	var prefs struct {
+		JssUrl string `plist:"jss_url"`
+	}
+	if err := plist.NewDecoder(f).Decode(&prefs); err != nil {
+		return "", fmt.Errorf("decoding %s: %w", t.plistPath, err)
+	}
+
+	return prefs.JssUrl, nil
+}
+
+func (t *Table) readLog() (logSummary, error) {
+	f, err := os.Open(t.logPath)
+	if err != nil {
+		return logSummary{}, fmt.Errorf("opening %s: %w", t.logPath, err)
+	}
+	defer f.Close()
+
+	// Only read the end of the log. We may start partway through a line, but the parser
+	// skips lines it doesn't recognize.
+	if info, err := f.Stat(); err == nil && info.Size() > maxLogBytes {
+		if _, err := f.Seek(-maxLogBytes, io.SeekEnd); err != nil {
+			return logSummary{}, fmt.Errorf("seeking in %s: %w", t.logPath, err)
+		}
+	}
+
+	return parseJamfLog(f, time.Now()), nil
+}
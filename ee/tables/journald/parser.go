@@ -0,0 +1,112 @@
+//go:build linux
+// +build linux
+
+package journald
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// maxLineSize is the largest single journal entry we're willing to buffer. Entries
+// larger than this are quite unusual, and almost certainly binary payloads.
+const maxLineSize = 1024 * 1024
+
+// entry is a single record from `journalctl --output=json`. journalctl emits most
+// fields as strings, but may emit MESSAGE as an array of bytes when it contains
+// non-printable data, so it is decoded separately.
+type entry struct {
+	Cursor           string          `json:"__CURSOR"`
+	RealtimeUsec     string          `json:"__REALTIME_TIMESTAMP"`
+	BootID           string          `json:"_BOOT_ID"`
+	Hostname         string          `json:"_HOSTNAME"`
+	SystemdUnit      string          `json:"_SYSTEMD_UNIT"`
+	SyslogIdentifier string          `json:"SYSLOG_IDENTIFIER"`
+	Pid              string          `json:"_PID"`
+	Priority         string          `json:"PRIORITY"`
+	RawMessage       json.RawMessage `json:"MESSAGE"`
+}
+
+// message returns the MESSAGE field as a string, regardless of whether journalctl
+// encoded it as a string or as a byte array.
+func (e entry) message() string {
+	if len(e.RawMessage) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(e.RawMessage, &s); err == nil {
+		return s
+	}
+
+	var ints []int
+	if err := json.Unmarshal(e.RawMessage, &ints); err == nil {
+		b := make([]byte, len(ints))
+		for i, v := range ints {
+			b[i] = byte(v)
+		}
+		return string(b)
+	}
+
+	return ""
+}
+
+// toRow converts the entry into an osquery row.
+func (e entry) toRow() map[string]string {
+	row := map[string]string{
+		"cursor":            e.Cursor,
+		"boot_id":           e.BootID,
+		"hostname":          e.Hostname,
+		"unit":              e.SystemdUnit,
+		"syslog_identifier": e.SyslogIdentifier,
+		"pid":               e.Pid,
+		"priority":          e.Priority,
+		"message":           e.message(),
+	}
+
+	if usec, err := strconv.ParseInt(e.RealtimeUsec, 10, 64); err == nil {
+		row["timestamp"] = strconv.FormatInt(time.UnixMicro(usec).Unix(), 10)
+	}
+
+	return row
+}
+
+// parseEntries reads newline-delimited JSON from journalctl, calling fn with each entry as it's
+// read. It stops at the first error from fn, returning it.
+func parseEntries(r io.Reader, fn func(entry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("unmarshalling journal entry: %w", err)
+		}
+
+		// An entry without a cursor can't be checkpointed, and isn't something journalctl
+		// should ever produce. Skip it rather than risk losing our place.
+		if e.Cursor == "" {
+			continue
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanning journal output: %w", err)
+	}
+
+	return nil
+}
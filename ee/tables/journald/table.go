@@ -0,0 +1,243 @@
+//go:build linux
+// +build linux
+
+package journald
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/ee/tables/tablewrapper"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName = "kolide_journald"
+
+	allowedUnitCharacters       = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.@:\\"
+	allowedPriorityCharacters   = "abcdefghijklmnopqrstuvwxyz01234567."
+	allowedSinceCharacters      = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-+: "
+	allowedCheckpointCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+
+	// defaultSince bounds how far back we look when there is neither a `since` constraint nor a
+	// stored cursor. Without it, a first query would scan the entire journal.
+	defaultSince = "-1h"
+
+	// maxEntries caps the number of rows returned by a single query. When checkpointing, the
+	// cursor is stored at the last returned entry, so the remainder is returned by the next query.
+	// The output is also bounded in size, by tablewrapper.DefaultMaxBytes.
+	maxEntries = 10000
+
+	execTimeoutSeconds = 60
+)
+
+type Table struct {
+	slogger     *slog.Logger
+	cursorStore types.GetterSetter
+	execer      func(ctx context.Context, args []string, stdout io.Writer) error
+
+	// checkpointLock serializes checkpointed reads, so that overlapping queries do not race
+	// on reading and updating the same cursor.
+	checkpointLock sync.Mutex
+}
+
+// TablePlugin returns the kolide_journald table. cursorStore is used to persist journal
+// cursors for queries that set a `checkpoint` constraint; it may be nil, in which case
+// checkpointing is unavailable.
+func TablePlugin(slogger *slog.Logger, cursorStore types.GetterSetter) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		// Filters, pushed down to journalctl
+		table.TextColumn("unit"),
+		table.TextColumn("priority"),
+		table.TextColumn("since"),
+		table.TextColumn("checkpoint"),
+
+		// Entry data
+		table.TextColumn("cursor"),
+		table.BigIntColumn("timestamp"),
+		table.TextColumn("boot_id"),
+		table.TextColumn("hostname"),
+		table.TextColumn("syslog_identifier"),
+		table.TextColumn("pid"),
+		table.TextColumn("message"),
+	}
+
+	t := &Table{
+		slogger:     slogger.With("table", tableName),
+		cursorStore: cursorStore,
+	}
+	t.execer = t.execJournalctl
+
+	return tablewrapper.NewStreaming(slogger, tableName, columns, t.generate, tablewrapper.WithMaxRows(maxEntries))
+}
+
+// generate streams journal entries to w, as journalctl outputs them, so that we never hold the
+// whole of journalctl's output.
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext, w *tablewrapper.RowWriter) error {
+	checkpoints := tablehelpers.GetConstraints(queryContext, "checkpoint",
+		tablehelpers.WithAllowedCharacters(allowedCheckpointCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+		tablehelpers.WithDefaults(""),
+	)
+
+	if len(checkpoints) > 1 {
+		return fmt.Errorf("%s supports at most one checkpoint per query", tableName)
+	}
+	checkpoint := checkpoints[0]
+
+	if checkpoint != "" && t.cursorStore == nil {
+		return fmt.Errorf("%s checkpointing is not available in this context", tableName)
+	}
+
+	units := tablehelpers.GetConstraints(queryContext, "unit",
+		tablehelpers.WithAllowedCharacters(allowedUnitCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+		tablehelpers.WithDefaults(""),
+	)
+
+	priorities := tablehelpers.GetConstraints(queryContext, "priority",
+		tablehelpers.WithAllowedCharacters(allowedPriorityCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+		tablehelpers.WithDefaults(""),
+	)
+
+	sinces := tablehelpers.GetConstraints(queryContext, "since",
+		tablehelpers.WithAllowedCharacters(allowedSinceCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+		tablehelpers.WithDefaults(""),
+	)
+
+	if checkpoint != "" {
+		t.checkpointLock.Lock()
+		defer t.checkpointLock.Unlock()
+	}
+
+	for _, unit := range units {
+		for _, priority := range priorities {
+			for _, since := range sinces {
+				err := t.query(ctx, w, checkpoint, unit, priority, since)
+				if errors.Is(err, tablewrapper.ErrOutputLimit) {
+					return err
+				}
+				if err != nil {
+					t.slogger.Log(ctx, slog.LevelInfo,
+						"error querying journal",
+						"unit", unit,
+						"priority", priority,
+						"since", since,
+						"checkpoint", checkpoint,
+						"err", err,
+					)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// query runs journalctl for a single combination of filters, writing the resulting rows to w.
+// If checkpoint is set, the read resumes after the stored cursor, and the cursor is advanced
+// to the last written entry -- so that entries past the output limit are returned next time.
+func (t *Table) query(ctx context.Context, w *tablewrapper.RowWriter, checkpoint, unit, priority, since string) error {
+	args := []string{"--output=json", "--no-pager", "--quiet"}
+
+	if unit != "" {
+		args = append(args, "--unit="+unit)
+	}
+
+	if priority != "" {
+		args = append(args, "--priority="+priority)
+	}
+
+	var storedCursor string
+	if checkpoint != "" {
+		cursor, err := t.cursorStore.Get(cursorKey(checkpoint, unit, priority))
+		if err != nil {
+			return fmt.Errorf("reading stored cursor: %w", err)
+		}
+		storedCursor = string(cursor)
+	}
+
+	// A stored cursor takes precedence over since -- since is only used to bound the first read.
+	switch {
+	case storedCursor != "":
+		args = append(args, "--after-cursor="+storedCursor)
+	case since != "":
+		args = append(args, "--since="+since)
+	default:
+		args = append(args, "--since="+defaultSince)
+	}
+
+	// Read journalctl's output as it's written. If we stop reading early, canceling the context
+	// stops journalctl.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stdout, stdoutWriter := io.Pipe()
+	defer stdout.Close()
+	go func() {
+		if err := t.execer(ctx, args, stdoutWriter); err != nil {
+			stdoutWriter.CloseWithError(fmt.Errorf("running journalctl: %w", err))
+			return
+		}
+		stdoutWriter.Close()
+	}()
+
+	var lastCursor string
+	err := parseEntries(stdout, func(e entry) error {
+		row := e.toRow()
+
+		// Echo the filters back, so osquery doesn't filter out our rows. journalctl matches them
+		// more loosely than osquery would -- e.g. --priority=err includes crit -- so a filter
+		// replaces the entry's own value. Without one, the entry's value comes through.
+		if unit != "" {
+			row["unit"] = unit
+		}
+		if priority != "" {
+			row["priority"] = priority
+		}
+		row["since"] = since
+		row["checkpoint"] = checkpoint
+
+		if err := w.Write(row); err != nil {
+			return err
+		}
+		lastCursor = e.Cursor
+		return nil
+	})
+
+	// Keep whatever we wrote before any error -- the cursor only advances as far as the entries
+	// we actually return.
+	if checkpoint != "" && lastCursor != "" {
+		if err := t.cursorStore.Set(cursorKey(checkpoint, unit, priority), []byte(lastCursor)); err != nil {
+			return fmt.Errorf("storing cursor: %w", err)
+		}
+	}
+
+	return err
+}
+
+func (t *Table) execJournalctl(ctx context.Context, args []string, stdout io.Writer) error {
+	var stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, execTimeoutSeconds, allowedcmd.Journalctl, args, stdout, &stderr); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// cursorKey returns the store key for a checkpoint. The filters are part of the key, because a
+// cursor is only meaningful for the filter set it was recorded with.
+func cursorKey(checkpoint, unit, priority string) []byte {
+	return []byte(strings.Join([]string{checkpoint, unit, priority}, ":"))
+}
@@ -0,0 +1,184 @@
+//go:build linux
+// +build linux
+
+package journald
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/ee/tables/tablewrapper"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEntries(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filepath.Join("testdata", "journal.json"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []entry
+	require.NoError(t, parseEntries(f, func(e entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	require.Len(t, entries, 3)
+
+	row := entries[0].toRow()
+	require.Equal(t, "sshd.service", row["unit"])
+	require.Equal(t, "1700000000", row["timestamp"])
+	require.Equal(t, "Server listening on 0.0.0.0 port 22.", row["message"])
+
+	// Byte-array encoded messages should be decoded
+	require.Equal(t, "hi\x00!", entries[1].toRow()["message"])
+}
+
+// generate runs the table's stream, returning the rows it wrote.
+func generate(jTable *Table, queryContext table.QueryContext, maxRows int) ([]map[string]string, error) {
+	w := tablewrapper.NewRowWriter(maxRows, 0)
+	err := jTable.generate(context.TODO(), queryContext, w)
+	return w.Rows(), err
+}
+
+func TestCheckpointing(t *testing.T) {
+	t.Parallel()
+
+	testdata, err := os.ReadFile(filepath.Join("testdata", "journal.json"))
+	require.NoError(t, err)
+
+	store := inmemory.NewStore()
+	jTable := &Table{
+		slogger:     multislogger.NewNopLogger(),
+		cursorStore: store,
+	}
+
+	var lastArgs []string
+	output := testdata
+	jTable.execer = func(_ context.Context, args []string, stdout io.Writer) error {
+		lastArgs = args
+		_, err := stdout.Write(output)
+		return err
+	}
+
+	queryContext := tablehelpers.MockQueryContext(map[string][]string{
+		"checkpoint": {"test"},
+		"unit":       {"sshd.service"},
+	})
+
+	// First query has no stored cursor, so should be bounded by the default since
+	rows, err := generate(jTable, queryContext, 0)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Contains(t, lastArgs, "--since="+defaultSince)
+	require.Equal(t, "test", rows[0]["checkpoint"])
+
+	storedCursor, err := store.Get(cursorKey("test", "sshd.service", ""))
+	require.NoError(t, err)
+	require.Equal(t, "s=abc;i=3;b=boot1;m=3;t=5f0000000003;x=3", string(storedCursor))
+
+	// Second query should resume from the stored cursor. With no new entries, the cursor stays put.
+	output = nil
+	rows, err = generate(jTable, queryContext, 0)
+	require.NoError(t, err)
+	require.Len(t, rows, 0)
+	require.Contains(t, lastArgs, "--after-cursor="+string(storedCursor))
+
+	storedCursor, err = store.Get(cursorKey("test", "sshd.service", ""))
+	require.NoError(t, err)
+	require.Equal(t, "s=abc;i=3;b=boot1;m=3;t=5f0000000003;x=3", string(storedCursor))
+}
+
+func TestUnfilteredQuery(t *testing.T) {
+	t.Parallel()
+
+	testdata, err := os.ReadFile(filepath.Join("testdata", "journal.json"))
+	require.NoError(t, err)
+
+	jTable := &Table{
+		slogger: multislogger.NewNopLogger(),
+		execer: func(_ context.Context, _ []string, stdout io.Writer) error {
+			_, err := stdout.Write(testdata)
+			return err
+		},
+	}
+
+	// Without filters, each entry's own unit and priority come through
+	rows, err := generate(jTable, tablehelpers.MockQueryContext(map[string][]string{}), 0)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	for i, priority := range []string{"6", "4", "3"} {
+		require.Equal(t, "sshd.service", rows[i]["unit"])
+		require.Equal(t, priority, rows[i]["priority"])
+	}
+
+	// With filters, they're echoed back, so osquery keeps the rows journalctl matched
+	rows, err = generate(jTable, tablehelpers.MockQueryContext(map[string][]string{
+		"unit":     {"sshd"},
+		"priority": {"warning"},
+	}), 0)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, "sshd", rows[0]["unit"])
+	require.Equal(t, "warning", rows[0]["priority"])
+}
+
+func TestCheckpointingWithoutStore(t *testing.T) {
+	t.Parallel()
+
+	jTable := &Table{
+		slogger: multislogger.NewNopLogger(),
+	}
+
+	_, err := generate(jTable, tablehelpers.MockQueryContext(map[string][]string{
+		"checkpoint": {"test"},
+	}), 0)
+	require.Error(t, err)
+}
+
+func TestOutputLimit(t *testing.T) {
+	t.Parallel()
+
+	testdata, err := os.ReadFile(filepath.Join("testdata", "journal.json"))
+	require.NoError(t, err)
+
+	store := inmemory.NewStore()
+	jTable := &Table{
+		slogger:     multislogger.NewNopLogger(),
+		cursorStore: store,
+	}
+
+	stopped := make(chan struct{})
+	jTable.execer = func(ctx context.Context, _ []string, stdout io.Writer) error {
+		_, err := stdout.Write(testdata)
+		if err == nil {
+			// Keep "running", as journalctl --follow would, until we're stopped
+			<-ctx.Done()
+			err = ctx.Err()
+		}
+		close(stopped)
+		return err
+	}
+
+	queryContext := tablehelpers.MockQueryContext(map[string][]string{
+		"checkpoint": {"test"},
+		"unit":       {"sshd.service"},
+	})
+
+	rows, err := generate(jTable, queryContext, 2)
+	require.ErrorIs(t, err, tablewrapper.ErrOutputLimit)
+	require.Len(t, rows, 2)
+	<-stopped
+
+	// The cursor should only advance as far as the rows we returned
+	storedCursor, err := store.Get(cursorKey("test", "sshd.service", ""))
+	require.NoError(t, err)
+	require.Equal(t, rows[1]["cursor"], string(storedCursor))
+}
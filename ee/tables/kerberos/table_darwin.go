@@ -0,0 +1,33 @@
+//go:build darwin
+// +build darwin
+
+package kerberos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/user"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+// klist lists the tickets in all of the user's credential caches. macOS keeps credential caches
+// per login session, so klist has to run inside the user's session to find them.
+func (t *Table) klist(ctx context.Context, u *user.User) ([]ticket, error) {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 5,
+		allowedcmd.Klist, []string{"-A", "-v"}, &stdout, &stderr,
+		tablehelpers.WithUserContext(u.Uid),
+	); err != nil {
+		// klist exits non-zero when the user has no credential caches
+		if stdout.Len() == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("running klist: %w: %s", err, stderr.String())
+	}
+
+	return parseHeimdalKlist(&stdout, time.Local), nil
+}
@@ -0,0 +1,35 @@
+//go:build linux
+// +build linux
+
+package kerberos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/user"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+// klist lists the tickets in all of the user's credential caches. The default cache -- a file,
+// keyring, or KCM -- is derived from the user's uid, so klist runs as the user.
+func (t *Table) klist(ctx context.Context, u *user.User) ([]ticket, error) {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 5,
+		allowedcmd.Klist, []string{"-A", "-f", "-e"}, &stdout, &stderr,
+		tablehelpers.WithUid(u.Uid),
+		// The format of klist's times depends on the locale
+		tablehelpers.WithAppendEnv("LC_ALL", "C"),
+	); err != nil {
+		// klist exits non-zero when the user has no credential caches
+		if stdout.Len() == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("running klist: %w: %s", err, stderr.String())
+	}
+
+	return parseMitKlist(&stdout, time.Local), nil
+}
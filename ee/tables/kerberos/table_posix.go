@@ -0,0 +1,87 @@
+//go:build !windows
+// +build !windows
+
+package kerberos
+
+import (
+	"context"
+	"log/slog"
+	"os/user"
+	"time"
+
+	"github.com/kolide/launcher/ee/consoleuser"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	now := time.Now()
+	for _, u := range t.users(ctx, queryContext) {
+		tickets, err := t.klist(ctx, u)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list kerberos tickets",
+				"username", u.Username,
+				"err", err,
+			)
+			continue
+		}
+
+		for _, tk := range tickets {
+			results = append(results, ticketRow(u.Username, tk, now))
+		}
+	}
+
+	return results, nil
+}
+
+// users returns the users whose credential caches to list: the ones in the username
+// constraint if there is one, and otherwise the users logged in.
+func (t *Table) users(ctx context.Context, queryContext table.QueryContext) []*user.User {
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	if len(usernames) == 0 {
+		users, err := consoleuser.CurrentUsers(ctx)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not get console users",
+				"err", err,
+			)
+			return nil
+		}
+		return users
+	}
+
+	var users []*user.User
+	for _, username := range usernames {
+		u, err := user.Lookup(username)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not look up user",
+				"username", username,
+				"err", err,
+			)
+			continue
+		}
+		users = append(users, u)
+	}
+
+	return users
+}
@@ -0,0 +1,91 @@
+//go:build windows
+// +build windows
+
+package kerberos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	// Windows caches tickets per logon session, rather than per user, so list the sessions
+	// and then each session's tickets
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 10,
+		allowedcmd.Klist, []string{"sessions"}, &stdout, &stderr,
+	); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list logon sessions",
+			"err", err,
+			"stderr", stderr.String(),
+		)
+		return results, nil
+	}
+
+	now := time.Now()
+	for _, session := range parseWindowsSessions(&stdout) {
+		if len(usernames) > 0 && !slices.ContainsFunc(usernames, func(username string) bool {
+			return strings.EqualFold(username, session.username)
+		}) {
+			continue
+		}
+
+		tickets, err := t.klist(ctx, session)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list kerberos tickets",
+				"username", session.username,
+				"logon_id", session.logonId,
+				"err", err,
+			)
+			continue
+		}
+
+		for _, tk := range tickets {
+			results = append(results, ticketRow(session.username, tk, now))
+		}
+	}
+
+	return results, nil
+}
+
+// klist lists the tickets cached for the logon session.
+func (t *Table) klist(ctx context.Context, session logonSession) ([]ticket, error) {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 5,
+		allowedcmd.Klist, []string{"-li", session.logonId, "tickets"}, &stdout, &stderr,
+	); err != nil {
+		return nil, fmt.Errorf("running klist: %w: %s", err, stderr.String())
+	}
+
+	return parseWindowsKlist(&stdout, session.logonId, time.Local), nil
+}
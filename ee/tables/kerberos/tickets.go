@@ -0,0 +1,446 @@
+// Package kerberos provides a table of the tickets in users' Kerberos credential caches, as
+// reported by klist. macOS ships Heimdal's klist, most Linux distributions MIT's, and Windows
+// its own, which lists the tickets cached for each logon session; each prints its tickets
+// differently, so each has its own parser here.
+package kerberos
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName = "kolide_kerberos_tickets"
+
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+)
+
+var columns = []table.ColumnDefinition{
+	table.TextColumn("username"),
+	table.TextColumn("cache"),
+	table.TextColumn("principal"),
+	table.TextColumn("realm"),
+	table.TextColumn("service_principal"),
+	table.TextColumn("service_realm"),
+	table.TextColumn("flags"),
+	table.TextColumn("encryption_type"),
+	table.BigIntColumn("start_time"),
+	table.BigIntColumn("end_time"),
+	table.BigIntColumn("renew_until"),
+	table.IntegerColumn("expired"),
+}
+
+// ticket is a single ticket from a credential cache.
+type ticket struct {
+	cache          string
+	principal      string // the client
+	service        string
+	flags          []string
+	encryptionType string
+	start          time.Time
+	end            time.Time
+	renewUntil     time.Time
+}
+
+// mitFlags maps the single-letter ticket flags MIT's klist prints to their names.
+var mitFlags = map[rune]string{
+	'F': "forwardable",
+	'f': "forwarded",
+	'P': "proxiable",
+	'p': "proxy",
+	'D': "may_postdate",
+	'd': "postdated",
+	'R': "renewable",
+	'I': "initial",
+	'i': "invalid",
+	'H': "hw_authent",
+	'A': "pre_authent",
+	'T': "transited_policy_checked",
+	'O': "ok_as_delegate",
+	'a': "anonymous",
+}
+
+// ticketRow returns the row for the ticket. expired is relative to now.
+func ticketRow(username string, tk ticket, now time.Time) map[string]string {
+	expired := ""
+	if !tk.end.IsZero() {
+		expired = "0"
+		if tk.end.Before(now) {
+			expired = "1"
+		}
+	}
+
+	return map[string]string{
+		"username":          username,
+		"cache":             tk.cache,
+		"principal":         tk.principal,
+		"realm":             realm(tk.principal),
+		"service_principal": tk.service,
+		"service_realm":     realm(tk.service),
+		"flags":             strings.Join(tk.flags, ","),
+		"encryption_type":   tk.encryptionType,
+		"start_time":        unixString(tk.start),
+		"end_time":          unixString(tk.end),
+		"renew_until":       unixString(tk.renewUntil),
+		"expired":           expired,
+	}
+}
+
+// parseMitKlist parses the output of MIT's `klist -A -f -e`. MIT's klist prints the tickets
+// of each cache as a table, with the flags and encryption types on the lines below each
+// ticket, and only prints the client when it isn't the cache's default principal:
+//
+//	Ticket cache: KCM:1000
+//	Default principal: alice@EXAMPLE.COM
+//
+//	Valid starting       Expires              Service principal
+//	10/18/26 10:00:00  10/18/26 20:00:00  krbtgt/EXAMPLE.COM@EXAMPLE.COM
+//		renew until 10/25/26 10:00:00, Flags: FRIA
+//		Etype (skey, tkt): aes256-cts-hmac-sha1-96, aes256-cts-hmac-sha1-96
+func parseMitKlist(r io.Reader, loc *time.Location) []ticket {
+	var tickets []ticket
+	var cache, defaultPrincipal string
+	var current *ticket
+
+	flush := func() {
+		if current != nil {
+			tickets = append(tickets, *current)
+			current = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(line, "Ticket cache:"):
+			flush()
+			cache = strings.TrimSpace(strings.TrimPrefix(line, "Ticket cache:"))
+			defaultPrincipal = ""
+		case strings.HasPrefix(line, "Default principal:"):
+			defaultPrincipal = strings.TrimSpace(strings.TrimPrefix(line, "Default principal:"))
+		case strings.HasPrefix(line, "Valid starting"):
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			if current != nil {
+				parseMitDetails(current, trimmed, loc)
+			}
+		default:
+			// Each date is a date and a time, so a ticket line has five fields
+			fields := strings.Fields(line)
+			if len(fields) < 5 {
+				continue
+			}
+			flush()
+			current = &ticket{
+				cache:     cache,
+				principal: defaultPrincipal,
+				service:   fields[4],
+				start:     parseTime(fields[0]+" "+fields[1], loc, mitTimeLayouts...),
+				end:       parseTime(fields[2]+" "+fields[3], loc, mitTimeLayouts...),
+			}
+		}
+	}
+	flush()
+
+	return tickets
+}
+
+// mitTimeLayouts are the time formats MIT's klist prints, which depend on the locale.
+var mitTimeLayouts = []string{
+	"01/02/06 15:04:05",
+	"01/02/2006 15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// parseMitDetails parses one of the indented lines below a ticket in MIT's klist output.
+func parseMitDetails(tk *ticket, line string, loc *time.Location) {
+	switch {
+	case strings.HasPrefix(line, "Etype"):
+		// The session key's encryption type, then the ticket's
+		_, etypes, _ := strings.Cut(line, ":")
+		parts := strings.Split(etypes, ",")
+		tk.encryptionType = strings.TrimSpace(parts[len(parts)-1])
+	default:
+		for _, part := range strings.Split(line, ", ") {
+			switch {
+			case strings.HasPrefix(part, "for client"):
+				tk.principal = strings.TrimSpace(strings.TrimPrefix(part, "for client"))
+			case strings.HasPrefix(part, "renew until"):
+				tk.renewUntil = parseTime(strings.TrimSpace(strings.TrimPrefix(part, "renew until")), loc, mitTimeLayouts...)
+			case strings.HasPrefix(part, "Flags:"):
+				for _, f := range strings.TrimSpace(strings.TrimPrefix(part, "Flags:")) {
+					if name, ok := mitFlags[f]; ok {
+						tk.flags = append(tk.flags, name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// heimdalTimeLayout is the time format Heimdal's klist prints.
+const heimdalTimeLayout = "Jan _2 15:04:05 2006"
+
+// parseHeimdalKlist parses the output of Heimdal's `klist -A -v`, which prints each ticket
+// as a block of fields:
+//
+//	Credentials cache: API:8C1F2E4A-...
+//	        Principal: alice@EXAMPLE.COM
+//	    Cache version: 0
+//
+//	Server: krbtgt/EXAMPLE.COM@EXAMPLE.COM
+//	Client: alice@EXAMPLE.COM
+//	Ticket etype: aes256-cts-hmac-sha1-96, kvno 2
+//	Auth time:  Oct 18 10:00:00 2026
+//	End time:   Oct 18 20:00:00 2026
+//	Renew till: Oct 25 10:00:00 2026
+//	Ticket flags: pre-authent, initial, renewable, forwardable
+//
+// The start time is only printed when it differs from the auth time.
+func parseHeimdalKlist(r io.Reader, loc *time.Location) []ticket {
+	var tickets []ticket
+	var cache string
+	var current *ticket
+
+	flush := func() {
+		if current != nil {
+			tickets = append(tickets, *current)
+			current = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "Credentials cache":
+			flush()
+			cache = value
+		case "Server":
+			flush()
+			current = &ticket{cache: cache, service: value}
+		}
+
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "Client":
+			current.principal = value
+		case "Ticket etype":
+			current.encryptionType, _, _ = strings.Cut(value, ",")
+		case "Auth time":
+			if current.start.IsZero() {
+				current.start = parseHeimdalTime(value, loc)
+			}
+		case "Start time":
+			current.start = parseHeimdalTime(value, loc)
+		case "End time":
+			current.end = parseHeimdalTime(value, loc)
+		case "Renew till":
+			current.renewUntil = parseHeimdalTime(value, loc)
+		case "Ticket flags":
+			for _, f := range strings.Split(value, ",") {
+				if f = strings.TrimSpace(f); f != "" {
+					current.flags = append(current.flags, strings.ReplaceAll(f, "-", "_"))
+				}
+			}
+		}
+	}
+	flush()
+
+	return tickets
+}
+
+// parseHeimdalTime parses a time from Heimdal's klist, which marks expired times.
+func parseHeimdalTime(value string, loc *time.Location) time.Time {
+	value = strings.TrimSpace(strings.TrimSuffix(value, "(expired)"))
+	return parseTime(value, loc, heimdalTimeLayout)
+}
+
+// logonSession is a logon session, as listed by Windows' `klist sessions`.
+type logonSession struct {
+	logonId  string // in the form klist's -li flag takes
+	username string
+}
+
+// parseWindowsSessions parses the output of Windows' `klist sessions`:
+//
+//	Current LogonId is 0:0x3e7
+//	[0] Session 1 0:0x1a2b3c EXAMPLE\alice Kerberos:Interactive
+//	[1] Session 0 0:0x3e7 WORKGROUP\HOST$ Negotiate:(0)
+func parseWindowsSessions(r io.Reader) []logonSession {
+	var sessions []logonSession
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// The account name may include spaces, so it's everything between the logon id and
+		// the authentication package
+		if len(fields) < 6 || !strings.HasPrefix(fields[0], "[") || fields[1] != "Session" {
+			continue
+		}
+
+		logonId, err := windowsLogonId(fields[3])
+		if err != nil || seen[logonId] {
+			continue
+		}
+		seen[logonId] = true
+
+		account := strings.Join(fields[4:len(fields)-1], " ")
+		if i := strings.LastIndex(account, `\`); i >= 0 {
+			account = account[i+1:]
+		}
+
+		sessions = append(sessions, logonSession{logonId: logonId, username: account})
+	}
+
+	return sessions
+}
+
+// windowsLogonId converts a logon id as klist prints it, high and low parts, e.g. 0:0x1a2b3c,
+// into the single number its -li flag takes.
+func windowsLogonId(s string) (string, error) {
+	high, low, found := strings.Cut(s, ":")
+	if !found {
+		return "", fmt.Errorf("malformed logon id %s", s)
+	}
+
+	highVal, err := strconv.ParseUint(strings.TrimPrefix(high, "0x"), 16, 32)
+	if err != nil {
+		return "", fmt.Errorf("parsing logon id %s: %w", s, err)
+	}
+	lowVal, err := strconv.ParseUint(strings.TrimPrefix(low, "0x"), 16, 32)
+	if err != nil {
+		return "", fmt.Errorf("parsing logon id %s: %w", s, err)
+	}
+
+	return fmt.Sprintf("0x%x", highVal<<32|lowVal), nil
+}
+
+// windowsTimeLayouts are the time formats Windows' klist prints, which depend on the
+// system's regional settings.
+var windowsTimeLayouts = []string{
+	"1/2/2006 15:04:05",
+	"1/2/2006 3:04:05 PM",
+	"2006-01-02 15:04:05",
+}
+
+// parseWindowsKlist parses the output of Windows' `klist -li <logon id> tickets`:
+//
+//	#0>	Client: alice @ EXAMPLE.COM
+//		Server: krbtgt/EXAMPLE.COM @ EXAMPLE.COM
+//		KerbTicket Encryption Type: AES-256-CTS-HMAC-SHA1-96
+//		Ticket Flags 0x40e10000 -> forwardable renewable initial pre_authent name_canonicalize
+//		Start Time: 10/18/2026 10:00:00 (local)
+//		End Time:   10/18/2026 20:00:00 (local)
+//		Renew Time: 10/25/2026 10:00:00 (local)
+func parseWindowsKlist(r io.Reader, cache string, loc *time.Location) []ticket {
+	var tickets []ticket
+	var current *ticket
+
+	flush := func() {
+		if current != nil {
+			tickets = append(tickets, *current)
+			current = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Each ticket begins with its index, e.g. #0>
+		if strings.HasPrefix(line, "#") {
+			if _, rest, found := strings.Cut(line, ">"); found {
+				flush()
+				current = &ticket{cache: cache}
+				line = strings.TrimSpace(rest)
+			}
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if strings.HasPrefix(line, "Ticket Flags") {
+			if _, names, found := strings.Cut(line, "->"); found {
+				current.flags = append(current.flags, strings.Fields(names)...)
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "(local)"))
+
+		switch key {
+		case "Client":
+			current.principal = windowsPrincipal(value)
+		case "Server":
+			current.service = windowsPrincipal(value)
+		case "KerbTicket Encryption Type":
+			current.encryptionType = value
+		case "Start Time":
+			current.start = parseTime(value, loc, windowsTimeLayouts...)
+		case "End Time":
+			current.end = parseTime(value, loc, windowsTimeLayouts...)
+		case "Renew Time":
+			current.renewUntil = parseTime(value, loc, windowsTimeLayouts...)
+		}
+	}
+	flush()
+
+	return tickets
+}
+
+// windowsPrincipal converts a principal as Windows' klist prints it, alice @ EXAMPLE.COM, to
+// the usual form.
+func windowsPrincipal(s string) string {
+	return strings.Replace(s, " @ ", "@", 1)
+}
+
+// realm returns the realm of a principal: everything after its last @.
+func realm(principal string) string {
+	if i := strings.LastIndex(principal, "@"); i >= 0 {
+		return principal[i+1:]
+	}
+	return ""
+}
+
+// parseTime parses value with the first matching layout, returning the zero time if none do.
+func parseTime(value string, loc *time.Location, layouts ...string) time.Time {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func unixString(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
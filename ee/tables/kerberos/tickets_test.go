@@ -0,0 +1,217 @@
+package kerberos
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMitKlist(t *testing.T) {
+	t.Parallel()
+
+	output := `Ticket cache: KCM:1000
+Default principal: alice@EXAMPLE.COM
+
+Valid starting     Expires            Service principal
+10/18/26 10:00:00  10/18/26 20:00:00  krbtgt/EXAMPLE.COM@EXAMPLE.COM
+	renew until 10/25/26 10:00:00, Flags: FRIA
+	Etype (skey, tkt): aes128-cts-hmac-sha1-96, aes256-cts-hmac-sha1-96
+10/18/26 10:05:00  10/18/26 20:00:00  HTTP/intranet.example.com@EXAMPLE.COM
+	Flags: FAT
+	Etype (skey, tkt): aes256-cts-hmac-sha1-96, aes256-cts-hmac-sha1-96
+
+Ticket cache: KCM:1000:58213
+Default principal: alice@CORP.EXAMPLE.ORG
+
+Valid starting       Expires              Service principal
+10/17/2026 08:00:00  10/17/2026 18:00:00  krbtgt/CORP.EXAMPLE.ORG@CORP.EXAMPLE.ORG
+	for client alice-admin@CORP.EXAMPLE.ORG, renew until 10/24/2026 08:00:00, Flags: FRIA
+`
+
+	tickets := parseMitKlist(strings.NewReader(output), time.UTC)
+	require.Len(t, tickets, 3)
+
+	require.Equal(t, ticket{
+		cache:          "KCM:1000",
+		principal:      "alice@EXAMPLE.COM",
+		service:        "krbtgt/EXAMPLE.COM@EXAMPLE.COM",
+		flags:          []string{"forwardable", "renewable", "initial", "pre_authent"},
+		encryptionType: "aes256-cts-hmac-sha1-96",
+		start:          time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC),
+		end:            time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+		renewUntil:     time.Date(2026, 10, 25, 10, 0, 0, 0, time.UTC),
+	}, tickets[0])
+
+	require.Equal(t, "HTTP/intranet.example.com@EXAMPLE.COM", tickets[1].service)
+	require.Equal(t, []string{"forwardable", "pre_authent", "transited_policy_checked"}, tickets[1].flags)
+	require.True(t, tickets[1].renewUntil.IsZero())
+
+	require.Equal(t, "KCM:1000:58213", tickets[2].cache)
+	require.Equal(t, "alice-admin@CORP.EXAMPLE.ORG", tickets[2].principal)
+	require.Equal(t, time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC), tickets[2].start)
+	require.Equal(t, time.Date(2026, 10, 24, 8, 0, 0, 0, time.UTC), tickets[2].renewUntil)
+}
+
+func TestParseHeimdalKlist(t *testing.T) {
+	t.Parallel()
+
+	output := `Credentials cache: API:8C1F2E4A-1B2C-4D5E-8F90-123456789ABC
+        Principal: alice@EXAMPLE.COM
+    Cache version: 0
+
+Server: krbtgt/EXAMPLE.COM@EXAMPLE.COM
+Client: alice@EXAMPLE.COM
+Ticket etype: aes256-cts-hmac-sha1-96, kvno 2
+Ticket length: 1432
+Auth time:  Oct 18 10:00:00 2026
+End time:   Oct 18 20:00:00 2026
+Renew till: Oct 25 10:00:00 2026
+Ticket flags: pre-authent, initial, renewable, forwardable
+Addresses: addressless
+
+Server: cifs/fileserver.example.com@EXAMPLE.COM
+Client: alice@EXAMPLE.COM
+Ticket etype: aes256-cts-hmac-sha1-96, kvno 5
+Ticket length: 1510
+Auth time:  Oct  8 10:00:00 2026
+Start time: Oct  8 10:30:00 2026
+End time:   Oct  8 20:00:00 2026 (expired)
+Ticket flags: pre-authent, ok-as-delegate, forwardable
+Addresses: addressless
+`
+
+	tickets := parseHeimdalKlist(strings.NewReader(output), time.UTC)
+	require.Len(t, tickets, 2)
+
+	require.Equal(t, ticket{
+		cache:          "API:8C1F2E4A-1B2C-4D5E-8F90-123456789ABC",
+		principal:      "alice@EXAMPLE.COM",
+		service:        "krbtgt/EXAMPLE.COM@EXAMPLE.COM",
+		flags:          []string{"pre_authent", "initial", "renewable", "forwardable"},
+		encryptionType: "aes256-cts-hmac-sha1-96",
+		start:          time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC),
+		end:            time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+		renewUntil:     time.Date(2026, 10, 25, 10, 0, 0, 0, time.UTC),
+	}, tickets[0])
+
+	require.Equal(t, time.Date(2026, 10, 8, 10, 30, 0, 0, time.UTC), tickets[1].start, "start time should win over auth time")
+	require.Equal(t, time.Date(2026, 10, 8, 20, 0, 0, 0, time.UTC), tickets[1].end)
+	require.Equal(t, []string{"pre_authent", "ok_as_delegate", "forwardable"}, tickets[1].flags)
+}
+
+func TestParseWindowsSessions(t *testing.T) {
+	t.Parallel()
+
+	output := `
+Current LogonId is 0:0x3e7
+[0] Session 1 0:0x1a2b3c EXAMPLE\alice Kerberos:Interactive
+[1] Session 1 0:0x1a2b3c EXAMPLE\alice Kerberos:Interactive
+[2] Session 2 0:0x4d5e6f DESKTOP-1\Bob Smith NTLM:RemoteInteractive
+[3] Session 0 0:0x3e7 WORKGROUP\HOST$ Negotiate:(0)
+`
+
+	require.Equal(t, []logonSession{
+		{logonId: "0x1a2b3c", username: "alice"},
+		{logonId: "0x4d5e6f", username: "Bob Smith"},
+		{logonId: "0x3e7", username: "HOST$"},
+	}, parseWindowsSessions(strings.NewReader(output)))
+}
+
+func TestWindowsLogonId(t *testing.T) {
+	t.Parallel()
+
+	id, err := windowsLogonId("0:0x3e7")
+	require.NoError(t, err)
+	require.Equal(t, "0x3e7", id)
+
+	id, err = windowsLogonId("0x1:0x2")
+	require.NoError(t, err)
+	require.Equal(t, "0x100000002", id)
+
+	_, err = windowsLogonId("0x3e7")
+	require.Error(t, err)
+}
+
+func TestParseWindowsKlist(t *testing.T) {
+	t.Parallel()
+
+	output := `
+Current LogonId is 0:0x3e7
+Targeted LogonId is 0:0x1a2b3c
+
+Cached Tickets: (2)
+
+#0>	Client: alice @ EXAMPLE.COM
+	Server: krbtgt/EXAMPLE.COM @ EXAMPLE.COM
+	KerbTicket Encryption Type: AES-256-CTS-HMAC-SHA1-96
+	Ticket Flags 0x40e10000 -> forwardable renewable initial pre_authent name_canonicalize
+	Start Time: 10/18/2026 10:00:00 (local)
+	End Time:   10/18/2026 20:00:00 (local)
+	Renew Time: 10/25/2026 10:00:00 (local)
+	Session Key Type: AES-256-CTS-HMAC-SHA1-96
+	Cache Flags: 0x1 -> PRIMARY
+	Kdc Called: dc1.example.com
+
+#1>	Client: alice @ EXAMPLE.COM
+	Server: HTTP/intranet.example.com @ EXAMPLE.COM
+	KerbTicket Encryption Type: RSADSI RC4-HMAC(NT)
+	Ticket Flags 0x40a10000 -> forwardable renewable pre_authent name_canonicalize
+	Start Time: 10/18/2026 1:05:00 PM (local)
+	End Time:   10/18/2026 8:00:00 PM (local)
+	Renew Time: 10/25/2026 10:00:00 AM (local)
+	Session Key Type: RSADSI RC4-HMAC(NT)
+	Cache Flags: 0
+	Kdc Called: dc1.example.com
+`
+
+	tickets := parseWindowsKlist(strings.NewReader(output), "0x1a2b3c", time.UTC)
+	require.Len(t, tickets, 2)
+
+	require.Equal(t, ticket{
+		cache:          "0x1a2b3c",
+		principal:      "alice@EXAMPLE.COM",
+		service:        "krbtgt/EXAMPLE.COM@EXAMPLE.COM",
+		flags:          []string{"forwardable", "renewable", "initial", "pre_authent", "name_canonicalize"},
+		encryptionType: "AES-256-CTS-HMAC-SHA1-96",
+		start:          time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC),
+		end:            time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+		renewUntil:     time.Date(2026, 10, 25, 10, 0, 0, 0, time.UTC),
+	}, tickets[0])
+
+	require.Equal(t, "RSADSI RC4-HMAC(NT)", tickets[1].encryptionType)
+	require.Equal(t, time.Date(2026, 10, 18, 13, 5, 0, 0, time.UTC), tickets[1].start)
+	require.Equal(t, time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC), tickets[1].end)
+}
+
+func TestTicketRow(t *testing.T) {
+	t.Parallel()
+
+	tk := ticket{
+		cache:          "KCM:1000",
+		principal:      "alice@EXAMPLE.COM",
+		service:        "HTTP/intranet.example.com@CORP.EXAMPLE.COM",
+		flags:          []string{"forwardable", "renewable"},
+		encryptionType: "aes256-cts-hmac-sha1-96",
+		start:          time.Unix(1790000000, 0),
+		end:            time.Unix(1790036000, 0),
+	}
+
+	require.Equal(t, map[string]string{
+		"username":          "alice",
+		"cache":             "KCM:1000",
+		"principal":         "alice@EXAMPLE.COM",
+		"realm":             "EXAMPLE.COM",
+		"service_principal": "HTTP/intranet.example.com@CORP.EXAMPLE.COM",
+		"service_realm":     "CORP.EXAMPLE.COM",
+		"flags":             "forwardable,renewable",
+		"encryption_type":   "aes256-cts-hmac-sha1-96",
+		"start_time":        "1790000000",
+		"end_time":          "1790036000",
+		"renew_until":       "",
+		"expired":           "0",
+	}, ticketRow("alice", tk, time.Unix(1790000100, 0)))
+
+	require.Equal(t, "1", ticketRow("alice", tk, time.Unix(1790036001, 0))["expired"])
+}
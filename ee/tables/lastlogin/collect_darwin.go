@@ -0,0 +1,32 @@
+//go:build darwin
+// +build darwin
+
+package lastlogin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+func (t *Table) collectEvents(ctx context.Context, since time.Time) ([]loginEvent, error) {
+	args := []string{
+		"show",
+		"--style", "ndjson",
+		"--info",
+		"--start", since.Format("2006-01-02 15:04:05"),
+		"--predicate", unifiedLogPredicate,
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, execTimeoutSeconds, allowedcmd.Log, args, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("running log show: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseUnifiedLog(&stdout)
+}
@@ -0,0 +1,34 @@
+//go:build linux
+// +build linux
+
+package lastlogin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+func (t *Table) collectEvents(ctx context.Context, since time.Time) ([]loginEvent, error) {
+	args := []string{
+		"--output=json",
+		"--no-pager",
+		"--quiet",
+		fmt.Sprintf("--since=@%d", since.Unix()),
+		// PAM records to the authpriv facility, and sshd to auth. Matches on the same field are ORed.
+		"SYSLOG_FACILITY=4",
+		"SYSLOG_FACILITY=10",
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, execTimeoutSeconds, allowedcmd.Journalctl, args, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("running journalctl: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parsePamJournal(&stdout)
+}
@@ -0,0 +1,83 @@
+//go:build windows
+// +build windows
+
+package lastlogin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	logonUIKey = `SOFTWARE\Microsoft\Windows\CurrentVersion\Authentication\LogonUI`
+
+	// maxSecurityEvents bounds how many logon events we read. Busy servers log many.
+	maxSecurityEvents = 20000
+)
+
+func (t *Table) collectEvents(ctx context.Context, since time.Time) ([]loginEvent, error) {
+	query := fmt.Sprintf("*[System[(EventID=4624) and TimeCreated[timediff(@SystemTime) <= %d]]]", time.Since(since).Milliseconds())
+	args := []string{
+		"qe", "Security",
+		"/q:" + query,
+		"/f:xml",
+		"/rd:true",
+		fmt.Sprintf("/c:%d", maxSecurityEvents),
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, execTimeoutSeconds, allowedcmd.Wevtutil, args, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("running wevtutil: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	events, err := parseSecurityEvents(&stdout)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"error parsing security events",
+			"parsed_count", len(events),
+			"err", err,
+		)
+	}
+
+	lastUser, provider, err := lastCredentialProvider()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"could not read last credential provider",
+			"err", err,
+		)
+		return events, nil
+	}
+	applyLastCredentialProvider(events, lastUser, provider)
+
+	return events, nil
+}
+
+// lastCredentialProvider returns the user who last logged on at the console, and the credential
+// provider they used, as LogonUI records them.
+func lastCredentialProvider() (string, string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, logonUIKey, registry.QUERY_VALUE)
+	if err != nil {
+		return "", "", fmt.Errorf("opening LogonUI key: %w", err)
+	}
+	defer key.Close()
+
+	provider, _, err := key.GetStringValue("LastLoggedOnProvider")
+	if err != nil {
+		return "", "", fmt.Errorf("reading LastLoggedOnProvider: %w", err)
+	}
+
+	lastUser, _, err := key.GetStringValue("LastLoggedOnSAMUser")
+	if err != nil {
+		return "", "", fmt.Errorf("reading LastLoggedOnSAMUser: %w", err)
+	}
+
+	return lastUser, provider, nil
+}
@@ -0,0 +1,204 @@
+// Package lastlogin reports when each user last logged in to, or unlocked, the device, and how
+// they authenticated. osquery's `last` table only reads utmpx, which misses screen unlocks, and
+// biometric unlocks entirely. Instead, we read each platform's authentication records -- the
+// unified log on macOS, the Security event log on Windows, and PAM's records in the journal on
+// Linux -- and normalize them.
+package lastlogin
+
+import (
+	"context"
+	"log/slog"
+	"os/user"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName = "kolide_loginwindow_users"
+
+	// defaultDays and maxDays bound how far back we read. Reading the logs is slow, so a query
+	// looks back a week, unless it asks for more.
+	defaultDays = 7
+	maxDays     = 90
+
+	execTimeoutSeconds = 120
+)
+
+// Events
+const (
+	eventLogin       = "login"
+	eventUnlock      = "unlock"
+	eventRemoteLogin = "remote_login"
+)
+
+// Authentication methods
+const (
+	methodPassword                = "password"
+	methodTouchID                 = "touch_id"
+	methodAppleWatch              = "apple_watch"
+	methodWindowsHelloPin         = "windows_hello_pin"
+	methodWindowsHelloFace        = "windows_hello_face"
+	methodWindowsHelloFingerprint = "windows_hello_fingerprint"
+	methodPicturePassword         = "picture_password"
+	methodFingerprint             = "fingerprint"
+	methodSecurityKey             = "security_key"
+	methodSmartcard               = "smartcard"
+	methodPublicKey               = "public_key"
+	methodKerberos                = "kerberos"
+	methodAutologin               = "autologin"
+	methodUnknown                 = "unknown"
+)
+
+// loginEvent is a single login or unlock. Platforms identify the user by name, or by uid.
+type loginEvent struct {
+	username   string
+	uid        string
+	event      string
+	method     string
+	time       time.Time
+	source     string
+	remoteHost string
+}
+
+type Table struct {
+	slogger *slog.Logger
+	collect func(ctx context.Context, since time.Time) ([]loginEvent, error)
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("event"),
+		table.TextColumn("method"),
+		table.BigIntColumn("last_time"),
+		table.IntegerColumn("count"),
+		table.TextColumn("source"),
+		table.TextColumn("remote_host"),
+		table.IntegerColumn("days"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+	t.collect = t.collectEvents
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	for _, daysStr := range tablehelpers.GetConstraints(queryContext, "days",
+		tablehelpers.WithAllowedCharacters("0123456789"),
+		tablehelpers.WithSlogger(t.slogger),
+		tablehelpers.WithDefaults(strconv.Itoa(defaultDays)),
+	) {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > maxDays {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"days must be between 1 and the maximum",
+				"days", daysStr,
+				"max_days", maxDays,
+			)
+			continue
+		}
+
+		events, err := t.collect(ctx, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not collect login events",
+				"err", err,
+			)
+			continue
+		}
+
+		for _, row := range summarize(events) {
+			// Echo the constraint back, so osquery doesn't filter out our rows
+			row["days"] = daysStr
+			results = append(results, row)
+		}
+	}
+
+	return results, nil
+}
+
+// summarize reduces events to a row for each user, event, and method, with the time of the
+// latest, and how many there were.
+func summarize(events []loginEvent) []map[string]string {
+	type key struct {
+		username, event, method string
+	}
+	type summary struct {
+		latest loginEvent
+		count  int
+	}
+
+	usernames := make(map[string]string)
+	summaries := make(map[key]*summary)
+	for _, e := range events {
+		if e.username == "" && e.uid != "" {
+			if _, ok := usernames[e.uid]; !ok {
+				usernames[e.uid] = usernameForUid(e.uid)
+			}
+			e.username = usernames[e.uid]
+		}
+		if e.username == "" {
+			continue
+		}
+
+		k := key{username: e.username, event: e.event, method: e.method}
+		s, ok := summaries[k]
+		if !ok {
+			s = &summary{}
+			summaries[k] = s
+		}
+		s.count += 1
+		if e.time.After(s.latest.time) {
+			s.latest = e
+		}
+	}
+
+	keys := make([]key, 0, len(summaries))
+	for k := range summaries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].username != keys[j].username {
+			return keys[i].username < keys[j].username
+		}
+		if keys[i].event != keys[j].event {
+			return keys[i].event < keys[j].event
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	rows := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		s := summaries[k]
+		rows = append(rows, map[string]string{
+			"username":    k.username,
+			"event":       k.event,
+			"method":      k.method,
+			"last_time":   strconv.FormatInt(s.latest.time.Unix(), 10),
+			"count":       strconv.Itoa(s.count),
+			"source":      s.latest.source,
+			"remote_host": s.latest.remoteHost,
+		})
+	}
+
+	return rows
+}
+
+// usernameForUid returns the name of the user with the given uid, or the uid itself if the
+// user no longer exists.
+func usernameForUid(uid string) string {
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return uid
+	}
+	return u.Username
+}
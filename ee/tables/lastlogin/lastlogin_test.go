@@ -0,0 +1,77 @@
+package lastlogin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	events := []loginEvent{
+		{username: "alice", event: eventLogin, method: methodPassword, time: base, source: sourceJournald},
+		{username: "alice", event: eventUnlock, method: methodTouchID, time: base.Add(time.Hour), source: sourceUnifiedLog},
+		{username: "alice", event: eventUnlock, method: methodTouchID, time: base.Add(3 * time.Hour), source: sourceUnifiedLog},
+		{username: "alice", event: eventUnlock, method: methodTouchID, time: base.Add(2 * time.Hour), source: sourceUnifiedLog},
+		{username: "alice", event: eventUnlock, method: methodPassword, time: base.Add(4 * time.Hour), source: sourceUnifiedLog},
+		{username: "bob", event: eventRemoteLogin, method: methodPublicKey, time: base, source: sourceJournald, remoteHost: "192.0.2.10"},
+		// Neither a name nor a uid to look it up by
+		{event: eventLogin, method: methodPassword, time: base},
+	}
+
+	require.Equal(t, []map[string]string{
+		{"username": "alice", "event": "login", "method": "password", "last_time": "1714554000", "count": "1", "source": "journald", "remote_host": ""},
+		{"username": "alice", "event": "unlock", "method": "password", "last_time": "1714568400", "count": "1", "source": "unified_log", "remote_host": ""},
+		{"username": "alice", "event": "unlock", "method": "touch_id", "last_time": "1714564800", "count": "3", "source": "unified_log", "remote_host": ""},
+		{"username": "bob", "event": "remote_login", "method": "public_key", "last_time": "1714554000", "count": "1", "source": "journald", "remote_host": "192.0.2.10"},
+	}, summarize(events))
+}
+
+func TestSummarize_UnknownUid(t *testing.T) {
+	t.Parallel()
+
+	rows := summarize([]loginEvent{{uid: "987654", event: eventUnlock, method: methodAppleWatch, time: time.Now()}})
+	require.Len(t, rows, 1)
+	require.Equal(t, "987654", rows[0]["username"], "users that no longer exist should be reported by uid")
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	var requestedSince []time.Time
+	lastLoginTable := &Table{
+		slogger: multislogger.NewNopLogger(),
+		collect: func(_ context.Context, since time.Time) ([]loginEvent, error) {
+			requestedSince = append(requestedSince, since)
+			if len(requestedSince) > 1 {
+				return nil, errors.New("log unavailable")
+			}
+			return []loginEvent{{username: "alice", event: eventLogin, method: methodPassword, time: time.Now()}}, nil
+		},
+	}
+
+	// Invalid values are skipped, and errors don't fail the query
+	rows, err := lastLoginTable.generate(context.TODO(), tablehelpers.MockQueryContext(map[string][]string{
+		"days": {"30", "0", "365", "2"},
+	}))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, "30", rows[0]["days"])
+	require.Len(t, requestedSince, 2)
+	require.WithinDuration(t, time.Now().AddDate(0, 0, -30), requestedSince[0], time.Minute)
+
+	// Without a constraint, we look back the default number of days
+	requestedSince = nil
+	rows, err = lastLoginTable.generate(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, "7", rows[0]["days"])
+	require.WithinDuration(t, time.Now().AddDate(0, 0, -defaultDays), requestedSince[0], time.Minute)
+}
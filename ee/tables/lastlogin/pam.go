@@ -0,0 +1,117 @@
+package lastlogin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const sourceJournald = "journald"
+
+var (
+	// pamSessionPattern matches pam_unix's record of a session, e.g.
+	// `pam_unix(gdm-password:session): session opened for user alice(uid=1000) by (uid=0)`
+	pamSessionPattern = regexp.MustCompile(`pam_unix\(([^:]+):session\): session opened for user ([^\s(]+)`)
+
+	// sshdAcceptedPattern matches sshd's record of an authentication, e.g.
+	// `Accepted publickey for alice from 192.0.2.10 port 52514 ssh2: ED25519 SHA256:...`
+	sshdAcceptedPattern = regexp.MustCompile(`^Accepted (\S+) for (\S+) from (\S+) port \d+`)
+)
+
+// pamServiceMethods maps the PAM services of display managers and consoles to how their users
+// authenticate. Services that aren't logins, like sudo and cron, aren't here; sshd's sessions
+// are read from sshd's own records, which say how the user authenticated.
+var pamServiceMethods = map[string]string{
+	"gdm-password":      methodPassword,
+	"gdm-fingerprint":   methodFingerprint,
+	"gdm-smartcard":     methodSmartcard,
+	"gdm-autologin":     methodAutologin,
+	"lightdm":           methodPassword,
+	"lightdm-autologin": methodAutologin,
+	"sddm":              methodPassword,
+	"sddm-autologin":    methodAutologin,
+	"login":             methodPassword,
+}
+
+// sshdMethods maps sshd's authentication methods to ours
+var sshdMethods = map[string]string{
+	"password":                 methodPassword,
+	"keyboard-interactive/pam": methodPassword,
+	"publickey":                methodPublicKey,
+	"gssapi-with-mic":          methodKerberos,
+	"gssapi-keyex":             methodKerberos,
+}
+
+// journalEntry is a line of `journalctl --output=json`. journalctl emits MESSAGE as an array
+// of bytes when it has non-printable characters; we don't need those messages.
+type journalEntry struct {
+	RealtimeUsec string          `json:"__REALTIME_TIMESTAMP"`
+	Message      json.RawMessage `json:"MESSAGE"`
+}
+
+// parsePamJournal reads logins from PAM's and sshd's records in the journal. Unlocking the
+// screen doesn't open a session, and display managers don't otherwise record it, so Linux
+// only has logins.
+func parsePamJournal(r io.Reader) ([]loginEvent, error) {
+	var events []loginEvent
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+
+		var message string
+		if err := json.Unmarshal(entry.Message, &message); err != nil {
+			continue
+		}
+
+		usec, err := strconv.ParseInt(entry.RealtimeUsec, 10, 64)
+		if err != nil {
+			continue
+		}
+		ts := time.UnixMicro(usec)
+
+		if m := sshdAcceptedPattern.FindStringSubmatch(message); m != nil {
+			method, ok := sshdMethods[m[1]]
+			if !ok {
+				method = methodUnknown
+			}
+			events = append(events, loginEvent{
+				username:   m[2],
+				event:      eventRemoteLogin,
+				method:     method,
+				time:       ts,
+				source:     sourceJournald,
+				remoteHost: m[3],
+			})
+			continue
+		}
+
+		if m := pamSessionPattern.FindStringSubmatch(message); m != nil {
+			method, ok := pamServiceMethods[m[1]]
+			if !ok {
+				continue
+			}
+			events = append(events, loginEvent{
+				username: m[2],
+				event:    eventLogin,
+				method:   method,
+				time:     ts,
+				source:   sourceJournald,
+			})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return events, fmt.Errorf("scanning journal: %w", err)
+	}
+
+	return events, nil
+}
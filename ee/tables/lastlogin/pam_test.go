@@ -0,0 +1,37 @@
+package lastlogin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePamJournal(t *testing.T) {
+	t.Parallel()
+
+	output := strings.Join([]string{
+		`{"__REALTIME_TIMESTAMP":"1714554000000000","SYSLOG_IDENTIFIER":"gdm-password]","MESSAGE":"pam_unix(gdm-password:session): session opened for user alice(uid=1000) by (uid=0)"}`,
+		`{"__REALTIME_TIMESTAMP":"1714557600000000","SYSLOG_IDENTIFIER":"gdm-fingerprint]","MESSAGE":"pam_unix(gdm-fingerprint:session): session opened for user alice(uid=1000) by (uid=0)"}`,
+		`{"__REALTIME_TIMESTAMP":"1714561200000000","SYSLOG_IDENTIFIER":"sshd","MESSAGE":"Accepted publickey for bob from 2001:db8::10 port 52514 ssh2: ED25519 SHA256:abc"}`,
+		`{"__REALTIME_TIMESTAMP":"1714561200100000","SYSLOG_IDENTIFIER":"sshd","MESSAGE":"pam_unix(sshd:session): session opened for user bob(uid=1001) by (uid=0)"}`,
+		`{"__REALTIME_TIMESTAMP":"1714564800000000","SYSLOG_IDENTIFIER":"sudo","MESSAGE":"pam_unix(sudo:session): session opened for user root(uid=0) by alice(uid=1000)"}`,
+		`{"__REALTIME_TIMESTAMP":"1714568400000000","SYSLOG_IDENTIFIER":"login","MESSAGE":"pam_unix(login:session): session opened for user carol by LOGIN(uid=0)"}`,
+		`{"__REALTIME_TIMESTAMP":"1714572000000000","SYSLOG_IDENTIFIER":"sshd","MESSAGE":"Accepted password for dave from 192.0.2.10 port 40000 ssh2"}`,
+		`{"__REALTIME_TIMESTAMP":"1714572001000000","SYSLOG_IDENTIFIER":"sshd","MESSAGE":[1,2,3]}`,
+		`not json`,
+	}, "\n")
+
+	events, err := parsePamJournal(strings.NewReader(output))
+	require.NoError(t, err)
+
+	require.Equal(t, []loginEvent{
+		{username: "alice", event: eventLogin, method: methodPassword, time: events[0].time, source: sourceJournald},
+		{username: "alice", event: eventLogin, method: methodFingerprint, time: events[1].time, source: sourceJournald},
+		{username: "bob", event: eventRemoteLogin, method: methodPublicKey, time: events[2].time, source: sourceJournald, remoteHost: "2001:db8::10"},
+		{username: "carol", event: eventLogin, method: methodPassword, time: events[3].time, source: sourceJournald},
+		{username: "dave", event: eventRemoteLogin, method: methodPassword, time: events[4].time, source: sourceJournald, remoteHost: "192.0.2.10"},
+	}, events)
+	require.Equal(t, int64(1714554000), events[0].time.Unix())
+	require.Equal(t, int64(1714572000), events[4].time.Unix())
+}
@@ -0,0 +1,161 @@
+package lastlogin
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const sourceSecurityEventLog = "security_event_log"
+
+// logonTypeEvents maps the logon types of event 4624 that are a person at the device, or at a
+// remote desktop, to our events. Network, service, and batch logons aren't logins.
+var logonTypeEvents = map[string]string{
+	"2":  eventLogin,       // Interactive
+	"7":  eventUnlock,      // Unlock
+	"10": eventRemoteLogin, // RemoteInteractive
+	"11": eventLogin,       // CachedInteractive
+	"12": eventRemoteLogin, // CachedRemoteInteractive
+	"13": eventUnlock,      // CachedUnlock
+}
+
+// credentialProviderMethods maps the credential providers that ship with Windows, by CLSID,
+// to the authentication method they provide.
+var credentialProviderMethods = map[string]string{
+	"60B78E88-EAD8-445C-9CFD-0B87F74EA6CD": methodPassword,
+	"CB82EA12-9F71-446D-89E1-8D0924E1256E": methodWindowsHelloPin,
+	"D6886603-9D2F-4EB2-B667-1971041FA96B": methodWindowsHelloPin,
+	"8AF662BF-65A0-4D0A-A540-A338A999D36F": methodWindowsHelloFace,
+	"BEC09223-B018-416D-A0AC-523971B639F5": methodWindowsHelloFingerprint,
+	"2135F72A-90B5-4ED3-A7F1-8BB705AC276A": methodPicturePassword,
+	"F8A1793B-7873-4046-B2A7-1F318747F427": methodSecurityKey,
+	"8FD7E19C-3BF7-489B-A72C-846AB3678C96": methodSmartcard,
+	"94596C7E-3744-41CE-893E-BBF09122F76A": methodSmartcard,
+}
+
+// securityEvent is an event from `wevtutil qe Security /f:xml`
+type securityEvent struct {
+	System struct {
+		EventID     string `xml:"EventID"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+	} `xml:"System"`
+	Data []struct {
+		Name  string `xml:"Name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"EventData>Data"`
+}
+
+func (e securityEvent) data(name string) string {
+	for _, d := range e.Data {
+		if d.Name == name {
+			return strings.TrimSpace(d.Value)
+		}
+	}
+	return ""
+}
+
+// parseSecurityEvents reads logins and unlocks from logon events (4624). Event 4624 doesn't
+// record the credential provider, so the method is unknown; see applyLastCredentialProvider.
+func parseSecurityEvents(r io.Reader) ([]loginEvent, error) {
+	var events []loginEvent
+
+	// An administrator's logon creates two linked logon sessions, one with the elevated token
+	// and one with the filtered token, and an event for each. Count them once.
+	seenLogonIds := make(map[string]bool)
+
+	decoder := xml.NewDecoder(r)
+	for {
+		var e securityEvent
+		if err := decoder.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return events, fmt.Errorf("decoding security event: %w", err)
+		}
+
+		if e.System.EventID != "4624" {
+			continue
+		}
+
+		event, ok := logonTypeEvents[e.data("LogonType")]
+		if !ok || !isPersonLogon(e) {
+			continue
+		}
+
+		if linked := e.data("TargetLinkedLogonId"); linked != "" && linked != "0x0" && seenLogonIds[linked] {
+			continue
+		}
+		seenLogonIds[e.data("TargetLogonId")] = true
+
+		ts, err := time.Parse(time.RFC3339Nano, e.System.TimeCreated.SystemTime)
+		if err != nil {
+			continue
+		}
+
+		remoteHost := ""
+		if event == eventRemoteLogin {
+			remoteHost = e.data("IpAddress")
+		}
+
+		events = append(events, loginEvent{
+			username:   e.data("TargetUserName"),
+			event:      event,
+			method:     methodUnknown,
+			time:       ts,
+			source:     sourceSecurityEventLog,
+			remoteHost: remoteHost,
+		})
+	}
+
+	return events, nil
+}
+
+// isPersonLogon filters out the interactive logons Windows makes for its own accounts, like
+// the Desktop Window Manager's and the font driver host's.
+func isPersonLogon(e securityEvent) bool {
+	switch e.data("TargetUserSid") {
+	case "S-1-5-18", "S-1-5-19", "S-1-5-20":
+		return false
+	}
+
+	switch e.data("TargetDomainName") {
+	case "Window Manager", "Font Driver Host":
+		return false
+	}
+
+	return e.data("TargetUserName") != ""
+}
+
+// applyLastCredentialProvider sets the method of the user's latest local login or unlock, from
+// the credential provider LogonUI records for the last logon. lastUser is the recorded user,
+// as DOMAIN\name or .\name.
+func applyLastCredentialProvider(events []loginEvent, lastUser, providerClsid string) {
+	method, ok := credentialProviderMethods[strings.ToUpper(strings.Trim(providerClsid, "{}"))]
+	if !ok {
+		return
+	}
+
+	username := lastUser
+	if i := strings.LastIndex(lastUser, `\`); i >= 0 {
+		username = lastUser[i+1:]
+	}
+
+	latest := -1
+	for i, e := range events {
+		if e.event == eventRemoteLogin || !strings.EqualFold(e.username, username) {
+			continue
+		}
+		if latest == -1 || e.time.After(events[latest].time) {
+			latest = i
+		}
+	}
+
+	if latest >= 0 {
+		events[latest].method = method
+	}
+}
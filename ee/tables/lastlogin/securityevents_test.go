@@ -0,0 +1,82 @@
+package lastlogin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func logonEvent(systemTime, logonType, sid, domain, username, logonId, linkedLogonId, ipAddress string) string {
+	return fmt.Sprintf(`<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><Provider Name='Microsoft-Windows-Security-Auditing' Guid='{54849625-5478-4994-a5ba-3e3b0328c30d}'/><EventID>4624</EventID><Version>2</Version><TimeCreated SystemTime='%s'/><Channel>Security</Channel></System><EventData><Data Name='SubjectUserSid'>S-1-5-18</Data><Data Name='TargetUserSid'>%s</Data><Data Name='TargetUserName'>%s</Data><Data Name='TargetDomainName'>%s</Data><Data Name='TargetLogonId'>%s</Data><Data Name='LogonType'>%s</Data><Data Name='LogonProcessName'>User32 </Data><Data Name='AuthenticationPackageName'>Negotiate</Data><Data Name='IpAddress'>%s</Data><Data Name='TargetLinkedLogonId'>%s</Data></EventData></Event>`,
+		systemTime, sid, username, domain, logonId, logonType, ipAddress, linkedLogonId)
+}
+
+func TestParseSecurityEvents(t *testing.T) {
+	t.Parallel()
+
+	output := strings.Join([]string{
+		// Newest first, as /rd:true returns them
+		logonEvent("2024-05-01T18:00:00.1234567Z", "10", "S-1-5-21-1-1001", "CORP", "bob", "0x3000", "0x0", "192.0.2.10"),
+		logonEvent("2024-05-01T17:00:00.1234567Z", "7", "S-1-5-21-1-1000", "CORP", "alice", "0x2001", "0x2000", "127.0.0.1"),
+		logonEvent("2024-05-01T17:00:00.1234567Z", "7", "S-1-5-21-1-1000", "CORP", "alice", "0x2000", "0x2001", "127.0.0.1"),
+		logonEvent("2024-05-01T16:59:59.0000000Z", "2", "S-1-5-90-0-2", "Window Manager", "DWM-2", "0x1500", "0x0", "-"),
+		logonEvent("2024-05-01T16:00:00.0000000Z", "3", "S-1-5-21-1-1000", "CORP", "alice", "0x1200", "0x0", "192.0.2.20"),
+		logonEvent("2024-05-01T15:00:00.0000000Z", "5", "S-1-5-18", "NT AUTHORITY", "SYSTEM", "0x3e7", "0x0", "-"),
+		logonEvent("2024-05-01T09:00:00.0000000Z", "11", "S-1-5-21-1-1000", "CORP", "alice", "0x1000", "0x0", "127.0.0.1"),
+	}, "\r\n")
+
+	events, err := parseSecurityEvents(strings.NewReader(output))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	require.Equal(t, "bob", events[0].username)
+	require.Equal(t, eventRemoteLogin, events[0].event)
+	require.Equal(t, "192.0.2.10", events[0].remoteHost)
+	require.Equal(t, int64(1714586400), events[0].time.Unix())
+
+	// The linked logons of an administrator's unlock are one unlock
+	require.Equal(t, "alice", events[1].username)
+	require.Equal(t, eventUnlock, events[1].event)
+	require.Equal(t, "", events[1].remoteHost)
+
+	require.Equal(t, eventLogin, events[2].event)
+	for _, e := range events {
+		require.Equal(t, methodUnknown, e.method)
+		require.Equal(t, sourceSecurityEventLog, e.source)
+	}
+}
+
+func TestParseSecurityEvents_Malformed(t *testing.T) {
+	t.Parallel()
+
+	output := logonEvent("2024-05-01T09:00:00.0000000Z", "2", "S-1-5-21-1-1000", "CORP", "alice", "0x1000", "0x0", "-") + "<Event><System>"
+
+	events, err := parseSecurityEvents(strings.NewReader(output))
+	require.Error(t, err)
+	require.Len(t, events, 1, "events before the malformed one should be kept")
+}
+
+func TestApplyLastCredentialProvider(t *testing.T) {
+	t.Parallel()
+
+	events, err := parseSecurityEvents(strings.NewReader(strings.Join([]string{
+		logonEvent("2024-05-01T18:00:00.0000000Z", "10", "S-1-5-21-1-1000", "CORP", "alice", "0x3000", "0x0", "192.0.2.10"),
+		logonEvent("2024-05-01T17:00:00.0000000Z", "7", "S-1-5-21-1-1000", "CORP", "Alice", "0x2000", "0x0", "127.0.0.1"),
+		logonEvent("2024-05-01T09:00:00.0000000Z", "2", "S-1-5-21-1-1000", "CORP", "alice", "0x1000", "0x0", "127.0.0.1"),
+	}, "")))
+	require.NoError(t, err)
+
+	// Unknown providers don't change anything
+	applyLastCredentialProvider(events, `CORP\alice`, "{00000000-0000-0000-0000-000000000000}")
+	for _, e := range events {
+		require.Equal(t, methodUnknown, e.method)
+	}
+
+	// The latest local logon is the one the provider was used for; remote logons don't use LogonUI
+	applyLastCredentialProvider(events, `CORP\alice`, "{8AF662BF-65A0-4D0A-A540-A338A999D36F}")
+	require.Equal(t, methodUnknown, events[0].method)
+	require.Equal(t, methodWindowsHelloFace, events[1].method)
+	require.Equal(t, methodUnknown, events[2].method)
+}
@@ -0,0 +1,135 @@
+package lastlogin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sourceUnifiedLog = "unified_log"
+
+	// unifiedLogTimeLayout is the format of timestamps in `log show --style ndjson`
+	unifiedLogTimeLayout = "2006-01-02 15:04:05.000000-0700"
+
+	// authenticationWindow is how long before an unlock we look for the biometric match, or
+	// Apple Watch unlock, that authenticated it. Unlocks without one were by password.
+	authenticationWindow = 10 * time.Second
+
+	// loginwindow posts these notifications when a user logs in, or unlocks the screen
+	sessionDidLoginMarker  = "sessionDidLogin"
+	screenIsUnlockedMarker = "screenIsUnlocked"
+)
+
+// unifiedLogPredicate selects the messages parseUnifiedLog reads, so that `log show` doesn't
+// have to format the rest.
+const unifiedLogPredicate = `(process == "loginwindow" AND (eventMessage CONTAINS "` + sessionDidLoginMarker + `" OR eventMessage CONTAINS "` + screenIsUnlockedMarker + `")) ` +
+	`OR (subsystem == "com.apple.BiometricKit" AND eventMessage CONTAINS[c] "match") ` +
+	`OR (subsystem == "com.apple.sharing" AND category == "AutoUnlock")`
+
+// forUserPattern matches the user that loginwindow's notifications are for, when it's in
+// the message
+var forUserPattern = regexp.MustCompile(`(?i)for ?user:? (\d+)`)
+
+// unifiedLogEntry is a line of `log show --style ndjson`. The last line is a summary, which
+// has none of these fields.
+type unifiedLogEntry struct {
+	Timestamp    string `json:"timestamp"`
+	EventMessage string `json:"eventMessage"`
+	Subsystem    string `json:"subsystem"`
+	Category     string `json:"category"`
+	UserID       *int64 `json:"userID"`
+}
+
+// parseUnifiedLog reads logins and unlocks from `log show --style ndjson`. The unified log
+// doesn't say how an unlock was authenticated, so we take the Touch ID match or Apple Watch
+// unlock just before it, if any.
+func parseUnifiedLog(r io.Reader) ([]loginEvent, error) {
+	var events []loginEvent
+
+	var pendingMethod string
+	var pendingTime time.Time
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry unifiedLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Timestamp == "" {
+			continue
+		}
+
+		ts, err := time.Parse(unifiedLogTimeLayout, entry.Timestamp)
+		if err != nil {
+			continue
+		}
+
+		if method := authenticationMethod(entry); method != "" {
+			pendingMethod, pendingTime = method, ts
+			continue
+		}
+
+		var event string
+		switch {
+		case strings.Contains(entry.EventMessage, sessionDidLoginMarker):
+			event = eventLogin
+		case strings.Contains(entry.EventMessage, screenIsUnlockedMarker):
+			event = eventUnlock
+		default:
+			continue
+		}
+
+		uid := ""
+		if m := forUserPattern.FindStringSubmatch(entry.EventMessage); m != nil {
+			uid = m[1]
+		} else if entry.UserID != nil {
+			uid = strconv.FormatInt(*entry.UserID, 10)
+		}
+		if uid == "" || uid == "0" {
+			continue
+		}
+
+		method := methodPassword
+		if pendingMethod != "" && ts.Sub(pendingTime) <= authenticationWindow {
+			method = pendingMethod
+		}
+		pendingMethod = ""
+
+		events = append(events, loginEvent{
+			uid:    uid,
+			event:  event,
+			method: method,
+			time:   ts,
+			source: sourceUnifiedLog,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return events, fmt.Errorf("scanning unified log: %w", err)
+	}
+
+	return events, nil
+}
+
+// authenticationMethod returns the method if the entry records a successful biometric match,
+// or Apple Watch unlock.
+func authenticationMethod(entry unifiedLogEntry) string {
+	message := strings.ToLower(entry.EventMessage)
+
+	switch {
+	case entry.Subsystem == "com.apple.BiometricKit":
+		if strings.Contains(message, "match") && !strings.Contains(message, "no match") && !strings.Contains(message, "nomatch") {
+			return methodTouchID
+		}
+	case entry.Subsystem == "com.apple.sharing" && entry.Category == "AutoUnlock":
+		if strings.Contains(message, "success") || strings.Contains(message, "succeeded") {
+			return methodAppleWatch
+		}
+	}
+
+	return ""
+}
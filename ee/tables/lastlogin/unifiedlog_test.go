@@ -0,0 +1,59 @@
+package lastlogin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnifiedLog(t *testing.T) {
+	t.Parallel()
+
+	output := strings.Join([]string{
+		// A login, by password
+		`{"timestamp":"2024-05-01 09:00:00.000000-0700","eventMessage":"-[SessionAgentNotificationCenter sendBSDNotification:forUser:] | sending BSD notification: com.apple.sessionagent.sessionDidLogin forUser: 501","subsystem":"com.apple.loginwindow.logging","category":"Standard","userID":0}`,
+		// A Touch ID unlock
+		`{"timestamp":"2024-05-01 10:00:00.000000-0700","eventMessage":"Match result: match found for identity","subsystem":"com.apple.BiometricKit","category":"Match","userID":0}`,
+		`{"timestamp":"2024-05-01 10:00:01.500000-0700","eventMessage":"sending BSD notification: com.apple.sessionagent.screenIsUnlocked","subsystem":"com.apple.loginwindow.logging","category":"Standard","userID":501}`,
+		// A failed Touch ID attempt, then a password unlock
+		`{"timestamp":"2024-05-01 11:00:00.000000-0700","eventMessage":"Match result: no match","subsystem":"com.apple.BiometricKit","category":"Match","userID":0}`,
+		`{"timestamp":"2024-05-01 11:00:05.000000-0700","eventMessage":"sending BSD notification: com.apple.sessionagent.screenIsUnlocked","subsystem":"com.apple.loginwindow.logging","category":"Standard","userID":501}`,
+		// An Apple Watch unlock
+		`{"timestamp":"2024-05-01 12:00:00.000000-0700","eventMessage":"Auto Unlock succeeded","subsystem":"com.apple.sharing","category":"AutoUnlock","userID":501}`,
+		`{"timestamp":"2024-05-01 12:00:02.000000-0700","eventMessage":"sending BSD notification: com.apple.sessionagent.screenIsUnlocked","subsystem":"com.apple.loginwindow.logging","category":"Standard","userID":501}`,
+		// A Touch ID match too long before an unlock to have been for it
+		`{"timestamp":"2024-05-01 13:00:00.000000-0700","eventMessage":"Match result: match found for identity","subsystem":"com.apple.BiometricKit","category":"Match","userID":0}`,
+		`{"timestamp":"2024-05-01 13:05:00.000000-0700","eventMessage":"sending BSD notification: com.apple.sessionagent.screenIsUnlocked","subsystem":"com.apple.loginwindow.logging","category":"Standard","userID":502}`,
+		// Unlocks at the login window, before a user is known, are skipped
+		`{"timestamp":"2024-05-01 14:00:00.000000-0700","eventMessage":"sending BSD notification: com.apple.sessionagent.screenIsUnlocked","subsystem":"com.apple.loginwindow.logging","category":"Standard","userID":0}`,
+		`{"count":11,"finished":1}`,
+	}, "\n")
+
+	events, err := parseUnifiedLog(strings.NewReader(output))
+	require.NoError(t, err)
+
+	type simplified struct {
+		uid, event, method string
+		time               int64
+	}
+	var actual []simplified
+	for _, e := range events {
+		require.Equal(t, sourceUnifiedLog, e.source)
+		actual = append(actual, simplified{uid: e.uid, event: e.event, method: e.method, time: e.time.Unix()})
+	}
+
+	at := func(s string) int64 {
+		ts, err := time.Parse(unifiedLogTimeLayout, s)
+		require.NoError(t, err)
+		return ts.Unix()
+	}
+	require.Equal(t, []simplified{
+		{uid: "501", event: eventLogin, method: methodPassword, time: at("2024-05-01 09:00:00.000000-0700")},
+		{uid: "501", event: eventUnlock, method: methodTouchID, time: at("2024-05-01 10:00:01.500000-0700")},
+		{uid: "501", event: eventUnlock, method: methodPassword, time: at("2024-05-01 11:00:05.000000-0700")},
+		{uid: "501", event: eventUnlock, method: methodAppleWatch, time: at("2024-05-01 12:00:02.000000-0700")},
+		{uid: "502", event: eventUnlock, method: methodPassword, time: at("2024-05-01 13:05:00.000000-0700")},
+	}, actual)
+}
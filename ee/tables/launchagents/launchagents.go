@@ -0,0 +1,102 @@
+// Package launchagents provides a table listing the LaunchAgents in every local user's home
+// directory. osquery's launchd table only sees the agents of users with active sessions, so
+// persistence installed for logged-out users goes unnoticed.
+package launchagents
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"howett.net/plist"
+)
+
+const userLaunchAgentsDir = "Library/LaunchAgents"
+
+// userHome is a local user's home directory.
+type userHome struct {
+	username string
+	uid      string
+	dir      string
+}
+
+// launchAgent holds the launchd.plist keys we report, see `man launchd.plist`.
+type launchAgent struct {
+	Label             string      `plist:"Label"`
+	Program           string      `plist:"Program"`
+	ProgramArguments  []string    `plist:"ProgramArguments"`
+	RunAtLoad         bool        `plist:"RunAtLoad"`
+	KeepAlive         interface{} `plist:"KeepAlive"`
+	Disabled          bool        `plist:"Disabled"`
+	StartInterval     int64       `plist:"StartInterval"`
+	WorkingDirectory  string      `plist:"WorkingDirectory"`
+	StandardOutPath   string      `plist:"StandardOutPath"`
+	StandardErrorPath string      `plist:"StandardErrorPath"`
+}
+
+// agentPaths returns the LaunchAgent plists in the given user's home directory.
+func agentPaths(home userHome) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(home.dir, userLaunchAgentsDir, "*.plist"))
+	if err != nil {
+		return nil, fmt.Errorf("globbing for launch agents: %w", err)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// agentRow reads the LaunchAgent plist at path, belonging to the given user.
+func agentRow(home userHome, path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading launch agent: %w", err)
+	}
+
+	var agent launchAgent
+	if _, err := plist.Unmarshal(raw, &agent); err != nil {
+		return nil, fmt.Errorf("unmarshalling launch agent: %w", err)
+	}
+
+	return map[string]string{
+		"uid":               home.uid,
+		"username":          home.username,
+		"path":              path,
+		"name":              filepath.Base(path),
+		"label":             agent.Label,
+		"program":           agent.Program,
+		"program_arguments": strings.Join(agent.ProgramArguments, " "),
+		"run_at_load":       boolToIntString(agent.RunAtLoad),
+		"keep_alive":        keepAlive(agent.KeepAlive),
+		"disabled":          boolToIntString(agent.Disabled),
+		"start_interval":    strconv.FormatInt(agent.StartInterval, 10),
+		"working_directory": agent.WorkingDirectory,
+		"stdout_path":       agent.StandardOutPath,
+		"stderr_path":       agent.StandardErrorPath,
+	}, nil
+}
+
+// keepAlive flattens KeepAlive, which is either a boolean or a dictionary of conditions under
+// which the agent is kept running. Conditions are reported as "1", since the agent may be
+// restarted.
+func keepAlive(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "0"
+	case bool:
+		return boolToIntString(v)
+	case map[string]interface{}:
+		return boolToIntString(len(v) > 0)
+	default:
+		return "1"
+	}
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,119 @@
+package launchagents
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const updaterPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.example.updater</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>/Users/alice/.local/bin/updater</string>
+		<string>--quiet</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>StartInterval</key>
+	<integer>3600</integer>
+</dict>
+</plist>`
+
+const disabledPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.example.helper</string>
+	<key>Program</key>
+	<string>/Applications/Helper.app/Contents/MacOS/helper</string>
+	<key>Disabled</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>/tmp/helper.log</string>
+</dict>
+</plist>`
+
+func TestAgentRows(t *testing.T) {
+	t.Parallel()
+
+	home := userHome{username: "alice", uid: "501", dir: t.TempDir()}
+	agentsDir := filepath.Join(home.dir, userLaunchAgentsDir)
+	require.NoError(t, os.MkdirAll(agentsDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(agentsDir, "com.example.updater.plist"), []byte(updaterPlist), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(agentsDir, "com.example.helper.plist"), []byte(disabledPlist), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(agentsDir, "notes.txt"), []byte("not an agent"), 0644))
+
+	paths, err := agentPaths(home)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(agentsDir, "com.example.helper.plist"),
+		filepath.Join(agentsDir, "com.example.updater.plist"),
+	}, paths)
+
+	helper, err := agentRow(home, paths[0])
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"uid":               "501",
+		"username":          "alice",
+		"path":              paths[0],
+		"name":              "com.example.helper.plist",
+		"label":             "com.example.helper",
+		"program":           "/Applications/Helper.app/Contents/MacOS/helper",
+		"program_arguments": "",
+		"run_at_load":       "0",
+		"keep_alive":        "0",
+		"disabled":          "1",
+		"start_interval":    "0",
+		"working_directory": "",
+		"stdout_path":       "/tmp/helper.log",
+		"stderr_path":       "",
+	}, helper)
+
+	updater, err := agentRow(home, paths[1])
+	require.NoError(t, err)
+	require.Equal(t, "com.example.updater", updater["label"])
+	require.Equal(t, "/Users/alice/.local/bin/updater --quiet", updater["program_arguments"])
+	require.Equal(t, "1", updater["run_at_load"])
+	require.Equal(t, "1", updater["keep_alive"])
+	require.Equal(t, "3600", updater["start_interval"])
+}
+
+func TestAgentPaths_NoLaunchAgents(t *testing.T) {
+	t.Parallel()
+
+	paths, err := agentPaths(userHome{username: "bob", uid: "502", dir: t.TempDir()})
+	require.NoError(t, err)
+	require.Empty(t, paths)
+}
+
+func TestAgentRow_Malformed(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "broken.plist")
+	require.NoError(t, os.WriteFile(path, []byte("<plist><dict><key>Label"), 0644))
+
+	_, err := agentRow(userHome{username: "alice", uid: "501"}, path)
+	require.Error(t, err)
+}
+
+func TestKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "0", keepAlive(nil))
+	require.Equal(t, "0", keepAlive(false))
+	require.Equal(t, "1", keepAlive(true))
+	require.Equal(t, "0", keepAlive(map[string]interface{}{}))
+	require.Equal(t, "1", keepAlive(map[string]interface{}{"NetworkState": true}))
+}
@@ -0,0 +1,147 @@
+//go:build darwin
+// +build darwin
+
+package launchagents
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName                 = "kolide_launchagents_per_user"
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+	allowedUidCharacters      = "0123456789"
+)
+
+type Table struct {
+	slogger *slog.Logger
+	rootDir string
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.BigIntColumn("uid"),
+		table.TextColumn("username"),
+		table.TextColumn("path"),
+		table.TextColumn("name"),
+		table.TextColumn("label"),
+		table.TextColumn("program"),
+		table.TextColumn("program_arguments"),
+		table.IntegerColumn("run_at_load"),
+		table.IntegerColumn("keep_alive"),
+		table.IntegerColumn("disabled"),
+		table.BigIntColumn("start_interval"),
+		table.TextColumn("working_directory"),
+		table.TextColumn("stdout_path"),
+		table.TextColumn("stderr_path"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+		rootDir: "/",
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+	uids := tablehelpers.GetConstraints(queryContext, "uid",
+		tablehelpers.WithAllowedCharacters(allowedUidCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	homes, err := userHomes(t.rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, home := range homes {
+		if len(usernames) > 0 && !slices.Contains(usernames, home.username) {
+			continue
+		}
+		if len(uids) > 0 && !slices.Contains(uids, home.uid) {
+			continue
+		}
+
+		paths, err := agentPaths(home)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list launch agents",
+				"home", home.dir,
+				"err", err,
+			)
+			continue
+		}
+
+		for _, path := range paths {
+			row, err := agentRow(home, path)
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not read launch agent",
+					"path", path,
+					"err", err,
+				)
+				continue
+			}
+			results = append(results, row)
+		}
+	}
+
+	return results, nil
+}
+
+// userHomes returns the home directories under /Users, whether or not their users are logged
+// in. Each home's uid is that of its owner, since the directory name can outlive a renamed account.
+func userHomes(rootDir string) ([]userHome, error) {
+	usersDir := filepath.Join(rootDir, "Users")
+	entries, err := os.ReadDir(usersDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", usersDir, err)
+	}
+
+	var homes []userHome
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "Shared" || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		home := userHome{
+			username: entry.Name(),
+			dir:      filepath.Join(usersDir, entry.Name()),
+		}
+
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			home.uid = strconv.FormatUint(uint64(stat.Uid), 10)
+			if u, err := user.LookupId(home.uid); err == nil {
+				home.username = u.Username
+			}
+		}
+
+		homes = append(homes, home)
+	}
+
+	return homes, nil
+}
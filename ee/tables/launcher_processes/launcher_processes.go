@@ -0,0 +1,73 @@
+package launcher_processes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kolide/launcher/ee/supervisor"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// TablePlugin reports the auxiliary processes launcher runs and supervises, such as the
+// desktop processes, with their state, restarts, health, and resource usage.
+func TablePlugin() *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("instance"),
+		table.TextColumn("state"),
+		table.BigIntColumn("pid"),
+		table.BigIntColumn("start_time"),
+		table.IntegerColumn("restarts"),
+		table.BigIntColumn("next_start"),
+		table.TextColumn("last_exit"),
+		table.BigIntColumn("last_exit_time"),
+		table.BigIntColumn("last_health_check"),
+		table.TextColumn("health_check_error"),
+		table.BigIntColumn("rss_bytes"),
+		table.DoubleColumn("cpu_percent"),
+	}
+	return table.NewPlugin("kolide_launcher_processes", columns, generate)
+}
+
+func generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := []map[string]string{}
+
+	for _, s := range supervisor.Statuses() {
+		row := map[string]string{
+			"name":               s.Name,
+			"instance":           s.Instance,
+			"state":              string(s.State),
+			"pid":                "",
+			"start_time":         unixOrEmpty(s.StartTime),
+			"restarts":           strconv.Itoa(s.Restarts),
+			"next_start":         unixOrEmpty(s.NextStart),
+			"last_exit":          s.LastExit,
+			"last_exit_time":     unixOrEmpty(s.LastExitTime),
+			"last_health_check":  unixOrEmpty(s.LastHealthCheck),
+			"health_check_error": s.HealthCheckError,
+			"rss_bytes":          "",
+			"cpu_percent":        "",
+		}
+
+		if s.State == supervisor.StateRunning {
+			row["pid"] = strconv.Itoa(s.Pid)
+			if s.RssBytes > 0 {
+				row["rss_bytes"] = strconv.FormatUint(s.RssBytes, 10)
+				row["cpu_percent"] = fmt.Sprintf("%.1f", s.CpuPercent)
+			}
+		}
+
+		results = append(results, row)
+	}
+
+	return results, nil
+}
+
+func unixOrEmpty(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
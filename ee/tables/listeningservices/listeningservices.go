@@ -0,0 +1,350 @@
+// Package listeningservices provides the kolide_listening_services table, which enriches
+// listening sockets with the owning process's binary, its code signature, and when launcher
+// first saw the service.
+package listeningservices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+const (
+	tableName = "kolide_listening_services"
+
+	// seenRetention is how long we remember a service after it was last seen listening
+	seenRetention = 30 * 24 * time.Hour
+)
+
+// Signature statuses
+const (
+	signatureValid       = "valid"
+	signatureInvalid     = "invalid"
+	signatureUnsigned    = "unsigned"
+	signatureUnknown     = "unknown"
+	signatureUnsupported = "unsupported"
+)
+
+type signature struct {
+	status    string
+	identity  string
+	publisher string
+}
+
+// signatureCacheEntry caches a binary's signature for as long as the binary is unchanged
+type signatureCacheEntry struct {
+	modTime time.Time
+	size    int64
+	sig     signature
+}
+
+// seenRecord is stored per service, keyed by serviceKey
+type seenRecord struct {
+	FirstSeen int64 `json:"first_seen"`
+	LastSeen  int64 `json:"last_seen"`
+}
+
+type listener struct {
+	pid      int32
+	protocol string
+	family   string
+	address  string
+	port     uint32
+}
+
+type Table struct {
+	slogger *slog.Logger
+	store   types.GetterSetterDeleterIterator
+
+	signatureCacheLock sync.Mutex
+	signatureCache     map[string]signatureCacheEntry
+}
+
+// TablePlugin returns the kolide_listening_services table. store holds the first-seen
+// timestamps; if it is nil, first_seen and last_seen are left blank.
+func TablePlugin(slogger *slog.Logger, store types.GetterSetterDeleterIterator) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.BigIntColumn("pid"),
+		table.TextColumn("name"),
+		table.TextColumn("path"),
+		table.TextColumn("protocol"),
+		table.TextColumn("family"),
+		table.TextColumn("address"),
+		table.IntegerColumn("port"),
+		table.IntegerColumn("signed"),
+		table.TextColumn("signature_status"),
+		table.TextColumn("signing_identity"),
+		table.TextColumn("publisher"),
+		table.BigIntColumn("first_seen"),
+		table.BigIntColumn("last_seen"),
+	}
+
+	t := &Table{
+		slogger:        slogger.With("table", tableName),
+		store:          store,
+		signatureCache: make(map[string]signatureCacheEntry),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	listeners, err := listListeners(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing listening sockets: %w", err)
+	}
+
+	protocols := tablehelpers.GetConstraints(queryContext, "protocol", tablehelpers.WithAllowedValues([]string{"tcp", "udp"}))
+
+	now := time.Now().Unix()
+	results := make([]map[string]string, 0, len(listeners))
+	processes := make(map[int32]processInfo)
+
+	for _, l := range listeners {
+		if len(protocols) > 0 && !slices.Contains(protocols, l.protocol) {
+			continue
+		}
+
+		p, ok := processes[l.pid]
+		if !ok {
+			p = t.processInfo(ctx, l.pid)
+			processes[l.pid] = p
+		}
+
+		sig := signature{status: signatureUnknown}
+		if p.path != "" {
+			sig = t.signatureFor(ctx, p.path)
+		}
+
+		row := map[string]string{
+			"pid":              strconv.Itoa(int(l.pid)),
+			"name":             p.name,
+			"path":             p.path,
+			"protocol":         l.protocol,
+			"family":           l.family,
+			"address":          l.address,
+			"port":             strconv.Itoa(int(l.port)),
+			"signed":           signedColumn(sig.status),
+			"signature_status": sig.status,
+			"signing_identity": sig.identity,
+			"publisher":        sig.publisher,
+			"first_seen":       "",
+			"last_seen":        "",
+		}
+
+		if record, ok := t.recordSeen(ctx, serviceKey(l, p.path), now); ok {
+			row["first_seen"] = strconv.FormatInt(record.FirstSeen, 10)
+			row["last_seen"] = strconv.FormatInt(record.LastSeen, 10)
+		}
+
+		results = append(results, row)
+	}
+
+	t.pruneSeen(ctx, now)
+
+	return results, nil
+}
+
+// listListeners returns the TCP sockets in the LISTEN state, and the UDP sockets that are
+// bound but not connected.
+func listListeners(ctx context.Context) ([]listener, error) {
+	conns, err := net.ConnectionsWithContext(ctx, "inet")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[listener]struct{})
+	var listeners []listener
+	for _, conn := range conns {
+		var protocol string
+		switch {
+		case conn.Type == syscall.SOCK_STREAM && conn.Status == "LISTEN":
+			protocol = "tcp"
+		case conn.Type == syscall.SOCK_DGRAM && conn.Raddr.Port == 0:
+			protocol = "udp"
+		default:
+			continue
+		}
+
+		family := "ipv4"
+		if conn.Family == syscall.AF_INET6 {
+			family = "ipv6"
+		}
+
+		l := listener{
+			pid:      conn.Pid,
+			protocol: protocol,
+			family:   family,
+			address:  conn.Laddr.IP,
+			port:     conn.Laddr.Port,
+		}
+
+		// Processes may have several sockets bound to the same address, e.g. with SO_REUSEPORT
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+type processInfo struct {
+	name string
+	path string
+}
+
+func (t *Table) processInfo(ctx context.Context, pid int32) processInfo {
+	var info processInfo
+	if pid <= 0 {
+		return info
+	}
+
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"could not get process for listening socket",
+			"pid", pid,
+			"err", err,
+		)
+		return info
+	}
+
+	if name, err := p.NameWithContext(ctx); err == nil {
+		info.name = name
+	}
+	if path, err := p.ExeWithContext(ctx); err == nil {
+		info.path = path
+	}
+
+	return info
+}
+
+// signatureFor returns the signature of the binary at path, from the cache if the binary
+// hasn't changed since we last checked it.
+func (t *Table) signatureFor(ctx context.Context, path string) signature {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return signature{status: signatureUnknown}
+	}
+
+	t.signatureCacheLock.Lock()
+	cached, ok := t.signatureCache[path]
+	t.signatureCacheLock.Unlock()
+	if ok && cached.modTime.Equal(fi.ModTime()) && cached.size == fi.Size() {
+		return cached.sig
+	}
+
+	sig, err := checkSignature(ctx, t.slogger, path)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"could not check signature",
+			"path", path,
+			"err", err,
+		)
+		// Don't cache failures, so that we retry next time
+		return signature{status: signatureUnknown}
+	}
+
+	t.signatureCacheLock.Lock()
+	t.signatureCache[path] = signatureCacheEntry{modTime: fi.ModTime(), size: fi.Size(), sig: sig}
+	t.signatureCacheLock.Unlock()
+
+	return sig
+}
+
+func signedColumn(status string) string {
+	switch status {
+	case signatureValid, signatureInvalid:
+		return "1"
+	case signatureUnsigned:
+		return "0"
+	default:
+		return ""
+	}
+}
+
+// serviceKey identifies a service across process restarts: the pid is deliberately excluded.
+func serviceKey(l listener, path string) string {
+	return strings.Join([]string{l.protocol, l.address, strconv.Itoa(int(l.port)), path}, "|")
+}
+
+// recordSeen updates the service's last-seen time to now, setting its first-seen time if this
+// is the first time we've seen it. It returns false if there is no store to record to.
+func (t *Table) recordSeen(ctx context.Context, key string, now int64) (seenRecord, bool) {
+	if t.store == nil {
+		return seenRecord{}, false
+	}
+
+	record := seenRecord{FirstSeen: now}
+	if raw, err := t.store.Get([]byte(key)); err == nil && raw != nil {
+		if err := json.Unmarshal(raw, &record); err != nil {
+			record = seenRecord{FirstSeen: now}
+		}
+	}
+	record.LastSeen = now
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return record, true
+	}
+	if err := t.store.Set([]byte(key), raw); err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not record listening service",
+			"key", key,
+			"err", err,
+		)
+	}
+
+	return record, true
+}
+
+// pruneSeen forgets services that haven't been seen listening for longer than seenRetention.
+func (t *Table) pruneSeen(ctx context.Context, now int64) {
+	if t.store == nil {
+		return
+	}
+
+	cutoff := now - int64(seenRetention.Seconds())
+	var expired [][]byte
+	if err := t.store.ForEach(func(k, v []byte) error {
+		var record seenRecord
+		if err := json.Unmarshal(v, &record); err != nil || record.LastSeen < cutoff {
+			// k is only valid during iteration, so copy it
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	}); err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not iterate over listening services",
+			"err", err,
+		)
+		return
+	}
+
+	if len(expired) == 0 {
+		return
+	}
+
+	if err := t.store.Delete(expired...); err != nil {
+		t.slogger.Log(ctx, slog.LevelWarn,
+			"could not prune listening services",
+			"err", err,
+		)
+	}
+}
@@ -0,0 +1,107 @@
+package listeningservices
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	// Listen on a port, so that we know at least one listener exists
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+
+	store := inmemory.NewStore()
+	tbl := &Table{
+		slogger:        multislogger.NewNopLogger(),
+		store:          store,
+		signatureCache: make(map[string]signatureCacheEntry),
+	}
+
+	rows, err := tbl.generate(context.TODO(), tablehelpers.MockQueryContext(map[string][]string{
+		"protocol": {"tcp"},
+	}))
+	require.NoError(t, err)
+
+	var found map[string]string
+	for _, row := range rows {
+		require.Equal(t, "tcp", row["protocol"])
+		if row["port"] == port && row["address"] == "127.0.0.1" {
+			found = row
+		}
+	}
+	require.NotNil(t, found, "expected to find our listener")
+	require.NotEmpty(t, found["path"])
+	require.NotEmpty(t, found["signature_status"])
+	require.NotEmpty(t, found["first_seen"])
+	firstSeen := found["first_seen"]
+
+	// First seen should be stable across queries
+	rows, err = tbl.generate(context.TODO(), tablehelpers.MockQueryContext(map[string][]string{
+		"protocol": {"tcp"},
+	}))
+	require.NoError(t, err)
+	for _, row := range rows {
+		if row["port"] == port && row["address"] == "127.0.0.1" {
+			require.Equal(t, firstSeen, row["first_seen"])
+		}
+	}
+}
+
+func TestRecordSeen_NoStore(t *testing.T) {
+	t.Parallel()
+
+	tbl := &Table{slogger: multislogger.NewNopLogger()}
+	_, ok := tbl.recordSeen(context.TODO(), "tcp|0.0.0.0|22|/usr/sbin/sshd", time.Now().Unix())
+	require.False(t, ok)
+}
+
+func TestPruneSeen(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	tbl := &Table{slogger: multislogger.NewNopLogger(), store: store}
+
+	now := time.Now().Unix()
+	old := now - int64((seenRetention + time.Hour).Seconds())
+
+	staleRecord, err := json.Marshal(seenRecord{FirstSeen: old, LastSeen: old})
+	require.NoError(t, err)
+	require.NoError(t, store.Set([]byte("stale"), staleRecord))
+	require.NoError(t, store.Set([]byte("corrupt"), []byte("not json")))
+
+	record, ok := tbl.recordSeen(context.TODO(), "current", now)
+	require.True(t, ok)
+	require.Equal(t, now, record.FirstSeen)
+
+	tbl.pruneSeen(context.TODO(), now)
+
+	var remaining []string
+	require.NoError(t, store.ForEach(func(k, v []byte) error {
+		remaining = append(remaining, string(k))
+		return nil
+	}))
+	require.Equal(t, []string{"current"}, remaining)
+}
+
+func Test_signedColumn(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "1", signedColumn(signatureValid))
+	require.Equal(t, "1", signedColumn(signatureInvalid))
+	require.Equal(t, "0", signedColumn(signatureUnsigned))
+	require.Equal(t, "", signedColumn(signatureUnknown))
+	require.Equal(t, "", signedColumn(signatureUnsupported))
+}
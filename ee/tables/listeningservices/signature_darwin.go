@@ -0,0 +1,83 @@
+//go:build darwin
+// +build darwin
+
+package listeningservices
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+// signatureAdhoc is for binaries with an ad-hoc signature, which does not identify a signer
+const signatureAdhoc = "adhoc"
+
+// checkSignature describes the binary's signature with `codesign --display`, then checks
+// its validity with `codesign --verify`.
+func checkSignature(ctx context.Context, slogger *slog.Logger, path string) (signature, error) {
+	var stderr bytes.Buffer
+	// codesign writes its description of the signature to stderr
+	displayErr := tablehelpers.Run(ctx, slogger, 10, allowedcmd.Codesign, []string{"--display", "--verbose=2", path}, io.Discard, &stderr)
+	if strings.Contains(stderr.String(), "code object is not signed at all") {
+		return signature{status: signatureUnsigned}, nil
+	}
+	if displayErr != nil {
+		return signature{}, fmt.Errorf("describing signature: %s: %w", stderr.String(), displayErr)
+	}
+
+	sig := parseCodesignDisplay(stderr.Bytes())
+	if sig.status == signatureAdhoc {
+		return sig, nil
+	}
+
+	if err := tablehelpers.Run(ctx, slogger, 30, allowedcmd.Codesign, []string{"--verify", "--strict", path}, io.Discard, io.Discard); err != nil {
+		sig.status = signatureInvalid
+	} else {
+		sig.status = signatureValid
+	}
+
+	return sig, nil
+}
+
+// parseCodesignDisplay parses the output of `codesign --display --verbose=2`. The signing
+// identity is the leaf certificate, the first Authority listed. The publisher is the team
+// identifier, which Apple's own binaries don't have.
+func parseCodesignDisplay(output []byte) signature {
+	var sig signature
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "Authority":
+			if sig.identity == "" {
+				sig.identity = value
+			}
+		case "TeamIdentifier":
+			if value != "not set" {
+				sig.publisher = value
+			}
+		case "Signature":
+			if value == "adhoc" {
+				sig.status = signatureAdhoc
+			}
+		}
+	}
+
+	if sig.publisher == "" && sig.identity == "Software Signing" {
+		sig.publisher = "Apple"
+	}
+
+	return sig
+}
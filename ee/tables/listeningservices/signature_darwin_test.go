@@ -0,0 +1,63 @@
+//go:build darwin
+// +build darwin
+
+package listeningservices
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCodesignDisplay(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		output   string
+		expected signature
+	}{
+		{
+			name: "developer id",
+			output: `Executable=/Applications/Example.app/Contents/MacOS/Example
+Identifier=com.example.app
+Format=app bundle with Mach-O universal (x86_64 arm64)
+CodeDirectory v=20500 size=1234 flags=0x10000(runtime) hashes=28+7 location=embedded
+Signature size=8987
+Authority=Developer ID Application: Example, Inc. (ABCDE12345)
+Authority=Developer ID Certification Authority
+Authority=Apple Root CA
+Timestamp=Jan 1, 2024 at 12:00:00 PM
+TeamIdentifier=ABCDE12345
+`,
+			expected: signature{identity: "Developer ID Application: Example, Inc. (ABCDE12345)", publisher: "ABCDE12345"},
+		},
+		{
+			name: "apple platform binary",
+			output: `Executable=/usr/sbin/sshd
+Identifier=com.apple.sshd
+Authority=Software Signing
+Authority=Apple Code Signing Certification Authority
+Authority=Apple Root CA
+TeamIdentifier=not set
+`,
+			expected: signature{identity: "Software Signing", publisher: "Apple"},
+		},
+		{
+			name: "adhoc",
+			output: `Executable=/usr/local/bin/example
+Identifier=example
+Signature=adhoc
+TeamIdentifier=not set
+`,
+			expected: signature{status: signatureAdhoc},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, parseCodesignDisplay([]byte(tt.output)))
+		})
+	}
+}
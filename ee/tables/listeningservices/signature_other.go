@@ -0,0 +1,15 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package listeningservices
+
+import (
+	"context"
+	"log/slog"
+)
+
+// checkSignature reports that code signatures are unsupported: Linux has no platform
+// code signing for binaries.
+func checkSignature(_ context.Context, _ *slog.Logger, _ string) (signature, error) {
+	return signature{status: signatureUnsupported}, nil
+}
@@ -0,0 +1,84 @@
+//go:build windows
+// +build windows
+
+package listeningservices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+type authenticodeSignature struct {
+	Status  string `json:"status"`
+	Subject string `json:"subject"`
+}
+
+// checkSignature checks the binary's Authenticode signature, including catalog signatures
+// for OS binaries, with Get-AuthenticodeSignature.
+func checkSignature(ctx context.Context, slogger *slog.Logger, path string) (signature, error) {
+	// Single quotes are escaped by doubling them in a single-quoted powershell string
+	script := fmt.Sprintf(
+		`$s = Get-AuthenticodeSignature -LiteralPath '%s'; [pscustomobject]@{status=[string]$s.Status; subject=[string]$s.SignerCertificate.Subject} | ConvertTo-Json -Compress`,
+		strings.ReplaceAll(path, "'", "''"),
+	)
+
+	out, err := tablehelpers.RunSimple(ctx, slogger, 30, allowedcmd.Powershell, []string{"-NoProfile", "-NonInteractive", "-Command", script})
+	if err != nil {
+		return signature{}, fmt.Errorf("getting authenticode signature: %w", err)
+	}
+
+	var authenticode authenticodeSignature
+	if err := json.Unmarshal(out, &authenticode); err != nil {
+		return signature{}, fmt.Errorf("unmarshalling authenticode signature `%s`: %w", string(out), err)
+	}
+
+	return parseAuthenticodeSignature(authenticode), nil
+}
+
+func parseAuthenticodeSignature(authenticode authenticodeSignature) signature {
+	sig := signature{
+		identity:  authenticode.Subject,
+		publisher: commonName(authenticode.Subject),
+	}
+
+	switch authenticode.Status {
+	case "Valid":
+		sig.status = signatureValid
+	case "NotSigned":
+		sig.status = signatureUnsigned
+	case "NotSupportedFileFormat":
+		sig.status = signatureUnsupported
+	default:
+		// HashMismatch, NotTrusted, UnknownError, and so on
+		sig.status = signatureInvalid
+	}
+
+	return sig
+}
+
+// commonName extracts the CN from a certificate subject, e.g. `CN=Microsoft Corporation, O=...`.
+// Values containing commas are quoted, e.g. `CN="Example, Inc.", O=...`.
+func commonName(subject string) string {
+	for rest := subject; rest != ""; {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		if key == "CN" {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,48 @@
+//go:build windows
+// +build windows
+
+package listeningservices
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseAuthenticodeSignature(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name         string
+		authenticode authenticodeSignature
+		expected     signature
+	}{
+		{
+			name:         "valid",
+			authenticode: authenticodeSignature{Status: "Valid", Subject: "CN=Microsoft Windows, O=Microsoft Corporation, L=Redmond, S=Washington, C=US"},
+			expected:     signature{status: signatureValid, identity: "CN=Microsoft Windows, O=Microsoft Corporation, L=Redmond, S=Washington, C=US", publisher: "Microsoft Windows"},
+		},
+		{
+			name:         "quoted common name",
+			authenticode: authenticodeSignature{Status: "Valid", Subject: `O="Example, Inc.", CN="Example, Inc.", C=US`},
+			expected:     signature{status: signatureValid, identity: `O="Example, Inc.", CN="Example, Inc.", C=US`, publisher: "Example, Inc."},
+		},
+		{
+			name:         "unsigned",
+			authenticode: authenticodeSignature{Status: "NotSigned"},
+			expected:     signature{status: signatureUnsigned},
+		},
+		{
+			name:         "hash mismatch",
+			authenticode: authenticodeSignature{Status: "HashMismatch", Subject: "CN=Example"},
+			expected:     signature{status: signatureInvalid, identity: "CN=Example", publisher: "Example"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, parseAuthenticodeSignature(tt.authenticode))
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build darwin
+// +build darwin
+
+package loginbanner
+
+import (
+	"context"
+	"os"
+)
+
+func (t *Table) collectBanners(_ context.Context) ([]banner, error) {
+	return darwinBanners(os.DirFS("/"))
+}
@@ -0,0 +1,117 @@
+//go:build windows
+// +build windows
+
+package loginbanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The legal notice is shown before sign-in when its text is set. Group policy sets it under the
+// policies key, which takes precedence over the older Winlogon values.
+var legalNoticeKeys = []struct {
+	source  string
+	key     string
+	caption string
+	text    string
+}{
+	{
+		source:  sourceLegalNoticePolicy,
+		key:     `SOFTWARE\Microsoft\Windows\CurrentVersion\Policies\System`,
+		caption: "legalnoticecaption",
+		text:    "legalnoticetext",
+	},
+	{
+		source:  sourceLegalNoticeWinlogon,
+		key:     `SOFTWARE\Microsoft\Windows NT\CurrentVersion\Winlogon`,
+		caption: "LegalNoticeCaption",
+		text:    "LegalNoticeText",
+	},
+}
+
+func (t *Table) collectBanners(_ context.Context) ([]banner, error) {
+	var banners []banner
+	var errs []error
+
+	for _, n := range legalNoticeKeys {
+		b, err := legalNotice(n.key, n.caption, n.text)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if b == nil {
+			continue
+		}
+		b.source = n.source
+		banners = append(banners, *b)
+	}
+
+	return banners, errors.Join(errs...)
+}
+
+// legalNotice returns the legal notice set under the given key, or nil if neither its caption
+// nor its text is set.
+func legalNotice(keyPath, captionName, textName string) (*banner, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", keyPath, err)
+	}
+	defer key.Close()
+
+	caption, captionErr := stringValue(key, captionName)
+	text, textErr := stringValue(key, textName)
+	if err := errors.Join(captionErr, textErr); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", keyPath, err)
+	}
+	if caption == nil && text == nil {
+		return nil, nil
+	}
+
+	b := &banner{path: `HKEY_LOCAL_MACHINE\` + keyPath}
+	if caption != nil {
+		b.caption = *caption
+	}
+	if text != nil {
+		b.text = *text
+	}
+	b.enabled = strings.TrimSpace(b.text) != ""
+
+	return b, nil
+}
+
+// stringValue returns the value, or nil if it isn't set. The legal notice text may be a
+// multi-string, one line per string, when set by some policy tools.
+func stringValue(key registry.Key, name string) (*string, error) {
+	_, valueType, err := key.GetValue(name, nil)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var value string
+	switch valueType {
+	case registry.MULTI_SZ:
+		values, _, err := key.GetStringsValue(name)
+		if err != nil {
+			return nil, err
+		}
+		value = strings.Join(values, "\r\n")
+	default:
+		value, _, err = key.GetStringValue(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &value, nil
+}
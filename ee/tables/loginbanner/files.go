@@ -0,0 +1,465 @@
+package loginbanner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// maxBannerBytes bounds how much of a banner file we read. Banners are a few paragraphs at most.
+const maxBannerBytes = 64 * 1024
+
+// The paths below are relative to the root of the filesystem they're read from, as io/fs requires.
+
+// darwinBanners returns the policy banner, login window text, and sshd banner.
+func darwinBanners(fsys fs.FS) ([]banner, error) {
+	var banners []banner
+	var errs []error
+
+	// The login window shows the first policy banner it finds
+	for _, p := range []string{
+		"Library/Security/PolicyBanner.txt",
+		"Library/Security/PolicyBanner.rtf",
+		"Library/Security/PolicyBanner.rtfd/TXT.rtf",
+	} {
+		text, err := readBannerFile(fsys, p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if strings.HasSuffix(p, ".rtf") {
+			text = rtfToText(text)
+		}
+		banners = append(banners, banner{
+			source:  sourcePolicyBanner,
+			path:    "/" + p,
+			enabled: true,
+			text:    text,
+		})
+		break
+	}
+
+	// Managed preferences, from a configuration profile, take precedence
+	for _, p := range []string{
+		"Library/Managed Preferences/com.apple.loginwindow.plist",
+		"Library/Preferences/com.apple.loginwindow.plist",
+	} {
+		text, ok, err := loginwindowText(fsys, p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		banners = append(banners, banner{
+			source:  sourceLoginwindowText,
+			path:    "/" + p,
+			enabled: strings.TrimSpace(text) != "",
+			text:    text,
+		})
+	}
+
+	b, err := sshBanner(fsys)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if b != nil {
+		banners = append(banners, *b)
+	}
+
+	return banners, errors.Join(errs...)
+}
+
+// linuxBanners returns the console and network issue files, the GDM banner, and the sshd banner.
+func linuxBanners(fsys fs.FS) ([]banner, error) {
+	var banners []banner
+	var errs []error
+
+	for _, f := range []struct {
+		source string
+		path   string
+	}{
+		{sourceIssue, "etc/issue"},
+		{sourceIssueNet, "etc/issue.net"},
+	} {
+		text, err := readBannerFile(fsys, f.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		banners = append(banners, banner{
+			source:  f.source,
+			path:    "/" + f.path,
+			enabled: strings.TrimSpace(text) != "",
+			text:    text,
+		})
+	}
+
+	gdm, err := gdmBanners(fsys)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	banners = append(banners, gdm...)
+
+	b, err := sshBanner(fsys)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if b != nil {
+		banners = append(banners, *b)
+	}
+
+	return banners, errors.Join(errs...)
+}
+
+func readBannerFile(fsys fs.FS, p string) (string, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(io.LimitReader(f, maxBannerBytes))
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", p, err)
+	}
+	return string(b), nil
+}
+
+// loginwindowText returns the LoginwindowText set in the given loginwindow preferences, and
+// whether it's set at all.
+func loginwindowText(fsys fs.FS, p string) (string, bool, error) {
+	raw, err := fs.ReadFile(fsys, p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("reading %s: %w", p, err)
+	}
+
+	var prefs struct {
+		LoginwindowText *string `plist:"LoginwindowText"`
+	}
+	if _, err := plist.Unmarshal(raw, &prefs); err != nil {
+		return "", false, fmt.Errorf("parsing %s: %w", p, err)
+	}
+	if prefs.LoginwindowText == nil {
+		return "", false, nil
+	}
+	return *prefs.LoginwindowText, true, nil
+}
+
+const gdmLoginScreenSection = "org/gnome/login-screen"
+
+// gdmBanners returns the banner from each GDM configuration file that sets it: the distribution's
+// greeter defaults, and the dconf keyfiles for the gdm database, which override them in order.
+func gdmBanners(fsys fs.FS) ([]banner, error) {
+	paths := []string{"etc/gdm3/greeter.dconf-defaults"}
+	keyfiles, err := fs.Glob(fsys, "etc/dconf/db/gdm.d/*")
+	if err != nil {
+		return nil, fmt.Errorf("listing gdm dconf keyfiles: %w", err)
+	}
+	sort.Strings(keyfiles)
+	paths = append(paths, keyfiles...)
+
+	var banners []banner
+	var errs []error
+	for _, p := range paths {
+		raw, err := fs.ReadFile(fsys, p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading %s: %w", p, err))
+			continue
+		}
+
+		section := parseKeyfile(raw)[gdmLoginScreenSection]
+		enable, hasEnable := section["banner-message-enable"]
+		text, hasText := section["banner-message-text"]
+		if !hasEnable && !hasText {
+			continue
+		}
+
+		banners = append(banners, banner{
+			source:  sourceGdm,
+			path:    "/" + p,
+			enabled: enable == "true",
+			text:    gvariantString(text),
+		})
+	}
+
+	return banners, errors.Join(errs...)
+}
+
+// parseKeyfile parses a GLib keyfile, as dconf uses, into its sections' keys and values.
+func parseKeyfile(raw []byte) map[string]map[string]string {
+	sections := make(map[string]map[string]string)
+	var current map[string]string
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if sections[name] == nil {
+				sections[name] = make(map[string]string)
+			}
+			current = sections[name]
+		default:
+			key, value, found := strings.Cut(line, "=")
+			if !found || current == nil {
+				continue
+			}
+			current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return sections
+}
+
+// gvariantString returns the string a GVariant text-format string literal holds. Anything
+// else is returned as is.
+func gvariantString(value string) string {
+	if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+		return value
+	}
+
+	var b strings.Builder
+	inner := value[1 : len(value)-1]
+	for i := 0; i < len(inner); i++ {
+		if inner[i] != '\\' || i == len(inner)-1 {
+			b.WriteByte(inner[i])
+			continue
+		}
+
+		i++
+		switch inner[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(inner[i])
+		}
+	}
+	return b.String()
+}
+
+// sshBanner returns the banner sshd sends before authentication, or nil if none is configured.
+func sshBanner(fsys fs.FS) (*banner, error) {
+	bannerPath, configPath, err := sshdBannerPath(fsys, "etc/ssh/sshd_config", 0)
+	if err != nil || bannerPath == "" {
+		return nil, err
+	}
+
+	if strings.EqualFold(bannerPath, "none") {
+		return &banner{source: sourceSsh, path: "/" + configPath}, nil
+	}
+
+	text, err := readBannerFile(fsys, strings.TrimPrefix(path.Clean(bannerPath), "/"))
+	if err != nil {
+		return &banner{source: sourceSsh, path: bannerPath}, fmt.Errorf("reading sshd banner: %w", err)
+	}
+	return &banner{
+		source:  sourceSsh,
+		path:    bannerPath,
+		enabled: true,
+		text:    text,
+	}, nil
+}
+
+// maxSshdIncludeDepth bounds Include recursion, as sshd does.
+const maxSshdIncludeDepth = 16
+
+// sshdBannerPath returns the value of the first Banner directive in the given sshd config, which
+// is the one sshd uses, and the config file it's in. Includes are followed; directives inside
+// Match blocks only apply to some connections, and are ignored.
+func sshdBannerPath(fsys fs.FS, configPath string, depth int) (string, string, error) {
+	if depth > maxSshdIncludeDepth {
+		return "", "", fmt.Errorf("sshd config includes nested too deeply at %s", configPath)
+	}
+
+	raw, err := fs.ReadFile(fsys, configPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("reading %s: %w", configPath, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		keyword, args := strings.TrimSuffix(fields[0], "="), fields[1:]
+
+		switch strings.ToLower(keyword) {
+		case "match":
+			return "", "", nil
+		case "banner":
+			return unquote(strings.Join(args, " ")), configPath, nil
+		case "include":
+			for _, pattern := range args {
+				pattern = unquote(pattern)
+				// Relative includes are relative to /etc/ssh
+				if !path.IsAbs(pattern) {
+					pattern = path.Join("/etc/ssh", pattern)
+				}
+				matches, err := fs.Glob(fsys, strings.TrimPrefix(pattern, "/"))
+				if err != nil {
+					return "", "", fmt.Errorf("expanding include %s: %w", pattern, err)
+				}
+				sort.Strings(matches)
+				for _, m := range matches {
+					bannerPath, bannerConfigPath, err := sshdBannerPath(fsys, m, depth+1)
+					if err != nil || bannerPath != "" {
+						return bannerPath, bannerConfigPath, err
+					}
+				}
+			}
+		}
+	}
+
+	return "", "", nil
+}
+
+func unquote(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// rtfToText returns the text of an RTF document, as the login window shows it, dropping its
+// formatting, and tables of fonts and colors.
+func rtfToText(rtf string) string {
+	var b strings.Builder
+
+	// Whether we're skipping the current group, and the enclosing groups
+	skip := false
+	var skips []bool
+
+	for i := 0; i < len(rtf); {
+		c := rtf[i]
+		switch c {
+		case '{':
+			skips = append(skips, skip)
+			i++
+		case '}':
+			if len(skips) > 0 {
+				skip = skips[len(skips)-1]
+				skips = skips[:len(skips)-1]
+			}
+			i++
+		case '\r', '\n':
+			// Line breaks in the source aren't in the text
+			i++
+		case '\\':
+			word, param, hasParam, next := rtfControl(rtf, i+1)
+			i = next
+
+			switch word {
+			case "\\", "{", "}":
+				if !skip {
+					b.WriteString(word)
+				}
+			case "par", "line", "\n", "\r":
+				if !skip {
+					b.WriteByte('\n')
+				}
+			case "tab":
+				if !skip {
+					b.WriteByte('\t')
+				}
+			case "'":
+				// An escaped character, as two hex digits in the document's code page
+				if i+2 <= len(rtf) {
+					if v, err := strconv.ParseUint(rtf[i:i+2], 16, 8); err == nil && !skip {
+						b.WriteRune(rune(v))
+					}
+					i += 2
+				}
+			case "u":
+				if hasParam && !skip {
+					if param < 0 {
+						param += 65536
+					}
+					b.WriteRune(rune(param))
+				}
+				// Skip the fallback character for readers without unicode
+				if i < len(rtf) && rtf[i] != '\\' && rtf[i] != '{' && rtf[i] != '}' {
+					i++
+				}
+			case "*", "fonttbl", "colortbl", "expandedcolortbl", "stylesheet", "info", "pict", "listtable", "listoverridetable":
+				skip = true
+			}
+		default:
+			if !skip {
+				b.WriteByte(c)
+			}
+			i++
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// rtfControl reads the control word or symbol starting at i, just after its backslash,
+// returning it, its numeric parameter if it has one, and where what follows it starts.
+func rtfControl(rtf string, i int) (string, int, bool, int) {
+	if i >= len(rtf) {
+		return "", 0, false, i
+	}
+
+	start := i
+	for i < len(rtf) && (rtf[i] >= 'a' && rtf[i] <= 'z' || rtf[i] >= 'A' && rtf[i] <= 'Z') {
+		i++
+	}
+	// A control symbol, a single non-letter
+	if i == start {
+		return rtf[i : i+1], 0, false, i + 1
+	}
+	word := rtf[start:i]
+
+	paramStart := i
+	if i < len(rtf) && rtf[i] == '-' {
+		i++
+	}
+	for i < len(rtf) && rtf[i] >= '0' && rtf[i] <= '9' {
+		i++
+	}
+	param, err := strconv.Atoi(rtf[paramStart:i])
+	hasParam := err == nil
+
+	// A single space delimits the control word, and isn't part of the text
+	if i < len(rtf) && rtf[i] == ' ' {
+		i++
+	}
+
+	return word, param, hasParam, i
+}
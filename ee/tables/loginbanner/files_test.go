@@ -0,0 +1,111 @@
+package loginbanner
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinuxBanners(t *testing.T) {
+	t.Parallel()
+
+	banners, err := linuxBanners(os.DirFS("testdata/linux"))
+	require.NoError(t, err)
+
+	require.Equal(t, []banner{
+		{
+			source:  sourceIssue,
+			path:    "/etc/issue",
+			enabled: true,
+			text:    "Authorized use only. \\S kernel \\r on \\l\n\n",
+		},
+		{
+			source: sourceIssueNet,
+			path:   "/etc/issue.net",
+		},
+		{
+			source:  sourceGdm,
+			path:    "/etc/dconf/db/gdm.d/01-banner-message",
+			enabled: true,
+			text:    "Authorized users only.\nAll activity may be monitored.",
+		},
+		{
+			source:  sourceSsh,
+			path:    "/etc/ssh/banner.txt",
+			enabled: true,
+			text:    "WARNING: monitored system\n",
+		},
+	}, banners)
+}
+
+func TestDarwinBanners(t *testing.T) {
+	t.Parallel()
+
+	banners, err := darwinBanners(os.DirFS("testdata/darwin"))
+	require.NoError(t, err)
+
+	require.Equal(t, []banner{
+		{
+			source:  sourcePolicyBanner,
+			path:    "/Library/Security/PolicyBanner.txt",
+			enabled: true,
+			text:    "Use of this system is monitored.\n",
+		},
+		{
+			source:  sourceLoginwindowText,
+			path:    "/Library/Managed Preferences/com.apple.loginwindow.plist",
+			enabled: true,
+			text:    "Property of Example Corp",
+		},
+		{
+			source: sourceSsh,
+			path:   "/etc/ssh/sshd_config",
+		},
+	}, banners)
+}
+
+func TestRtfToText(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testCaseName string
+		rtf          string
+		expected     string
+	}{
+		{
+			testCaseName: "textedit document",
+			rtf: `{\rtf1\ansi\ansicpg1252\cocoartf2709
+\cocoatextscaling0\cocoaplatform0{\fonttbl\f0\fswiss\fcharset0 Helvetica;}
+{\colortbl;\red255\green255\blue255;}
+{\*\expandedcolortbl;;}
+\margl1440\margr1440\vieww11520\viewh8400\viewkind0
+\pard\tx720\tx1440\pardirnatural\qc\partightenfactor0
+
+\f0\b\fs24 \cf0 NOTICE\
+\b0 This system is for authorized use only.\par
+Caf\'e9 \{policy\} \u8212? see IT}`,
+			expected: "NOTICE\nThis system is for authorized use only.\nCafé {policy} — see IT",
+		},
+		{
+			testCaseName: "plain",
+			rtf:          `{\rtf1 Hello\tab world}`,
+			expected:     "Hello\tworld",
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, rtfToText(tt.rtf))
+		})
+	}
+}
+
+func TestGvariantString(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "it's\tmonitored\n", gvariantString(`'it\'s\tmonitored\n'`))
+	require.Equal(t, `say "hi"`, gvariantString(`"say \"hi\""`))
+	require.Equal(t, "unquoted", gvariantString("unquoted"))
+}
@@ -0,0 +1,90 @@
+// Package loginbanner reports the banner, or legal notice, shown before login -- which several
+// compliance frameworks require, and which is otherwise checked by hand. Each platform has its
+// own places for it: the policy banner and login window text on macOS, the legal notice policy
+// on Windows, and /etc/issue, the display manager, and sshd on Linux. There's a row for each
+// place that's configured.
+package loginbanner
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_login_banner"
+
+// Sources
+const (
+	sourcePolicyBanner        = "policy_banner"
+	sourceLoginwindowText     = "loginwindow_text"
+	sourceLegalNoticePolicy   = "legal_notice_policy"
+	sourceLegalNoticeWinlogon = "legal_notice_winlogon"
+	sourceIssue               = "issue"
+	sourceIssueNet            = "issue_net"
+	sourceGdm                 = "gdm"
+	sourceSsh                 = "sshd"
+)
+
+// banner is the banner configured in one place. Only Windows gives banners a caption.
+type banner struct {
+	source  string
+	path    string
+	enabled bool
+	caption string
+	text    string
+}
+
+func (b banner) row() map[string]string {
+	enabled := "0"
+	if b.enabled {
+		enabled = "1"
+	}
+
+	return map[string]string{
+		"source":  b.source,
+		"path":    b.path,
+		"enabled": enabled,
+		"caption": b.caption,
+		"text":    b.text,
+	}
+}
+
+type Table struct {
+	slogger *slog.Logger
+	collect func(ctx context.Context) ([]banner, error)
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("source"),
+		table.TextColumn("path"),
+		table.IntegerColumn("enabled"),
+		table.TextColumn("caption"),
+		table.TextColumn("text"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+	t.collect = t.collectBanners
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	banners, err := t.collect(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read all login banners",
+			"err", err,
+		)
+	}
+
+	results := make([]map[string]string, 0, len(banners))
+	for _, b := range banners {
+		results = append(results, b.row())
+	}
+
+	return results, nil
+}
@@ -0,0 +1,209 @@
+package loginwindow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// domainKeys are the preference keys we report on, by domain. Screen lock policy is spread
+// across the loginwindow and screensaver domains, and may be set by the user, the admin,
+// or an MDM profile. Collecting them here saves a query author from knowing all of that.
+var domainKeys = map[string][]string{
+	"com.apple.loginwindow": {
+		"AdminHostInfo",
+		"DisableConsoleAccess",
+		"DisableFDEAutoLogin",
+		"GuestEnabled",
+		"LoginwindowText",
+		"PowerOffDisabledWhileLoggedIn",
+		"RestartDisabled",
+		"RetriesUntilHint",
+		"SHOWFULLNAME",
+		"SHOWOTHERUSERS_MANAGED",
+		"ShutDownDisabled",
+		"SleepDisabled",
+		"autoLoginUser",
+	},
+	"com.apple.screensaver": {
+		"askForPassword",
+		"askForPasswordDelay",
+		"idleTime",
+		"loginWindowIdleTime",
+		"loginWindowModulePath",
+		"moduleName",
+	},
+}
+
+// Sources, from highest to lowest precedence. This mirrors how cfprefsd resolves values:
+// managed (per-user, then per-computer), then the user's by-host and any-host
+// preferences, then the system-wide preferences.
+const (
+	sourceManagedUser = "managed_user"
+	sourceManaged     = "managed"
+	sourceUserByHost  = "user_byhost"
+	sourceUser        = "user"
+	sourceSystem      = "system"
+)
+
+type prefPath struct {
+	source string
+	path   string
+}
+
+type setting struct {
+	username  string
+	domain    string
+	key       string
+	value     string
+	source    string
+	path      string
+	effective bool
+}
+
+// settingsCollector reads preference plists relative to rootDir, which is / outside of tests.
+type settingsCollector struct {
+	rootDir string
+}
+
+// users returns the usernames with home directories under /Users.
+func (sc *settingsCollector) users() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(sc.rootDir, "Users"))
+	if err != nil {
+		return nil, fmt.Errorf("reading user directories: %w", err)
+	}
+
+	var users []string
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "Shared" || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		users = append(users, e.Name())
+	}
+
+	return users, nil
+}
+
+// sourcePaths returns the candidate plist paths for a domain, in precedence order. The
+// by-host plist is suffixed with the hardware UUID, so it's found by glob.
+func (sc *settingsCollector) sourcePaths(username, domain string) []prefPath {
+	plistName := domain + ".plist"
+	managedDir := filepath.Join(sc.rootDir, "Library", "Managed Preferences")
+
+	var paths []prefPath
+	if username != "" {
+		homePrefs := filepath.Join(sc.rootDir, "Users", username, "Library", "Preferences")
+
+		paths = append(paths, prefPath{sourceManagedUser, filepath.Join(managedDir, username, plistName)})
+		paths = append(paths, prefPath{sourceManaged, filepath.Join(managedDir, plistName)})
+
+		byHost, _ := filepath.Glob(filepath.Join(homePrefs, "ByHost", domain+".*.plist"))
+		sort.Strings(byHost)
+		for _, p := range byHost {
+			paths = append(paths, prefPath{sourceUserByHost, p})
+		}
+
+		paths = append(paths, prefPath{sourceUser, filepath.Join(homePrefs, plistName)})
+	} else {
+		paths = append(paths, prefPath{sourceManaged, filepath.Join(managedDir, plistName)})
+	}
+
+	paths = append(paths, prefPath{sourceSystem, filepath.Join(sc.rootDir, "Library", "Preferences", plistName)})
+
+	return paths
+}
+
+// collect returns the settings found for the given user. An empty username returns only
+// the computer-level settings.
+func (sc *settingsCollector) collect(username string) []setting {
+	var results []setting
+
+	for _, domain := range sortedDomains() {
+		seen := make(map[string]bool)
+
+		for _, src := range sc.sourcePaths(username, domain) {
+			prefs, err := readPlist(src.path)
+			if err != nil {
+				continue
+			}
+
+			for _, key := range domainKeys[domain] {
+				val, ok := prefs[key]
+				if !ok {
+					continue
+				}
+
+				results = append(results, setting{
+					username:  username,
+					domain:    domain,
+					key:       key,
+					value:     stringifyValue(val),
+					source:    src.source,
+					path:      sc.displayPath(src.path),
+					effective: !seen[key],
+				})
+				seen[key] = true
+			}
+		}
+	}
+
+	return results
+}
+
+// displayPath returns the path as it would be on the host, without rootDir.
+func (sc *settingsCollector) displayPath(path string) string {
+	rel, err := filepath.Rel(sc.rootDir, path)
+	if err != nil {
+		return path
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+func readPlist(path string) (map[string]interface{}, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefs map[string]interface{}
+	if _, err := plist.Unmarshal(raw, &prefs); err != nil {
+		return nil, fmt.Errorf("unmarshalling %s: %w", path, err)
+	}
+
+	return prefs, nil
+}
+
+// stringifyValue renders scalars directly, and anything more complex as JSON.
+func stringifyValue(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case uint64, int64, float64, int, uint:
+		return fmt.Sprintf("%v", v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(raw)
+	}
+}
+
+func sortedDomains() []string {
+	domains := make([]string, 0, len(domainKeys))
+	for d := range domainKeys {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+	return domains
+}
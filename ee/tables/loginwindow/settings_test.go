@@ -0,0 +1,70 @@
+package loginwindow
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	sc := &settingsCollector{rootDir: filepath.Join("testdata", "root")}
+
+	users, err := sc.users()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"alice", "bob"}, users)
+
+	var tests = []struct {
+		username string
+		expected []setting
+	}{
+		{
+			username: "alice",
+			expected: []setting{
+				{domain: "com.apple.loginwindow", key: "GuestEnabled", value: "0", source: sourceManaged, path: "/Library/Managed Preferences/com.apple.loginwindow.plist", effective: true},
+				{domain: "com.apple.loginwindow", key: "LoginwindowText", value: "Property of Example Corp", source: sourceManaged, path: "/Library/Managed Preferences/com.apple.loginwindow.plist", effective: true},
+				{domain: "com.apple.loginwindow", key: "GuestEnabled", value: "1", source: sourceSystem, path: "/Library/Preferences/com.apple.loginwindow.plist", effective: false},
+				{domain: "com.apple.loginwindow", key: "SHOWFULLNAME", value: "0", source: sourceSystem, path: "/Library/Preferences/com.apple.loginwindow.plist", effective: true},
+				{domain: "com.apple.screensaver", key: "askForPassword", value: "1", source: sourceManagedUser, path: "/Library/Managed Preferences/alice/com.apple.screensaver.plist", effective: true},
+				{domain: "com.apple.screensaver", key: "askForPasswordDelay", value: "5", source: sourceManagedUser, path: "/Library/Managed Preferences/alice/com.apple.screensaver.plist", effective: true},
+				{domain: "com.apple.screensaver", key: "askForPasswordDelay", value: "60", source: sourceUserByHost, path: "/Users/alice/Library/Preferences/ByHost/com.apple.screensaver.0A1B2C3D-0000-0000-0000-000000000000.plist", effective: false},
+				{domain: "com.apple.screensaver", key: "idleTime", value: "600", source: sourceUserByHost, path: "/Users/alice/Library/Preferences/ByHost/com.apple.screensaver.0A1B2C3D-0000-0000-0000-000000000000.plist", effective: true},
+				{domain: "com.apple.screensaver", key: "moduleName", value: `{"moduleName":"Flurry"}`, source: sourceUserByHost, path: "/Users/alice/Library/Preferences/ByHost/com.apple.screensaver.0A1B2C3D-0000-0000-0000-000000000000.plist", effective: true},
+			},
+		},
+		{
+			username: "bob",
+			expected: []setting{
+				{domain: "com.apple.loginwindow", key: "GuestEnabled", value: "0", source: sourceManaged, path: "/Library/Managed Preferences/com.apple.loginwindow.plist", effective: true},
+				{domain: "com.apple.loginwindow", key: "LoginwindowText", value: "Property of Example Corp", source: sourceManaged, path: "/Library/Managed Preferences/com.apple.loginwindow.plist", effective: true},
+				{domain: "com.apple.loginwindow", key: "GuestEnabled", value: "1", source: sourceSystem, path: "/Library/Preferences/com.apple.loginwindow.plist", effective: false},
+				{domain: "com.apple.loginwindow", key: "SHOWFULLNAME", value: "0", source: sourceSystem, path: "/Library/Preferences/com.apple.loginwindow.plist", effective: true},
+				{domain: "com.apple.screensaver", key: "idleTime", value: "1200", source: sourceUser, path: "/Users/bob/Library/Preferences/com.apple.screensaver.plist", effective: true},
+			},
+		},
+		{
+			username: "",
+			expected: []setting{
+				{domain: "com.apple.loginwindow", key: "GuestEnabled", value: "0", source: sourceManaged, path: "/Library/Managed Preferences/com.apple.loginwindow.plist", effective: true},
+				{domain: "com.apple.loginwindow", key: "LoginwindowText", value: "Property of Example Corp", source: sourceManaged, path: "/Library/Managed Preferences/com.apple.loginwindow.plist", effective: true},
+				{domain: "com.apple.loginwindow", key: "GuestEnabled", value: "1", source: sourceSystem, path: "/Library/Preferences/com.apple.loginwindow.plist", effective: false},
+				{domain: "com.apple.loginwindow", key: "SHOWFULLNAME", value: "0", source: sourceSystem, path: "/Library/Preferences/com.apple.loginwindow.plist", effective: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.username, func(t *testing.T) {
+			t.Parallel()
+
+			for i := range tt.expected {
+				tt.expected[i].username = tt.username
+			}
+
+			require.Equal(t, tt.expected, sc.collect(tt.username))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+//go:build darwin
+// +build darwin
+
+package loginwindow
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName                 = "kolide_login_window_settings"
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+)
+
+type Table struct {
+	slogger   *slog.Logger
+	collector *settingsCollector
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("domain"),
+		table.TextColumn("key"),
+		table.TextColumn("value"),
+		table.TextColumn("source"),
+		table.TextColumn("path"),
+		table.IntegerColumn("effective"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", tableName),
+		collector: &settingsCollector{rootDir: "/"},
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	if len(usernames) == 0 {
+		var err error
+		usernames, err = t.collector.users()
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list users, only returning computer-level settings",
+				"err", err,
+			)
+		}
+	}
+
+	// With no users at all, still report what's set for the computer
+	if len(usernames) == 0 {
+		usernames = []string{""}
+	}
+
+	for _, username := range usernames {
+		for _, s := range t.collector.collect(username) {
+			effective := 0
+			if s.effective {
+				effective = 1
+			}
+
+			results = append(results, map[string]string{
+				"username":  s.username,
+				"domain":    s.domain,
+				"key":       s.key,
+				"value":     s.value,
+				"source":    s.source,
+				"path":      s.path,
+				"effective": strconv.Itoa(effective),
+			})
+		}
+	}
+
+	return results, nil
+}
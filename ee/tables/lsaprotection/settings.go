@@ -0,0 +1,303 @@
+// Package lsaprotection provides a table describing how Windows protects credentials held by
+// LSASS: whether LSASS runs as a protected process (RunAsPPL), whether Credential Guard and the
+// rest of Device Guard are configured and running, and how NTLM authentication is restricted.
+package lsaprotection
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	lsaKey         = `SYSTEM\CurrentControlSet\Control\Lsa`
+	msv10Key       = `SYSTEM\CurrentControlSet\Control\Lsa\MSV1_0`
+	netlogonKey    = `SYSTEM\CurrentControlSet\Services\Netlogon\Parameters`
+	deviceGuardKey = `SYSTEM\CurrentControlSet\Control\DeviceGuard`
+	policyKey      = `SOFTWARE\Policies\Microsoft\Windows\DeviceGuard`
+)
+
+// registrySetting is a DWORD registry value under HKEY_LOCAL_MACHINE, and how to interpret it.
+// Values are either enumerations, described by meanings, or bitmasks, described by flags.
+type registrySetting struct {
+	name     string
+	key      string
+	value    string
+	meanings map[uint64]string
+	flags    map[uint64]string
+	unset    string // what Windows does when the value isn't set
+}
+
+var uefiLockMeanings = map[uint64]string{
+	0: "disabled",
+	1: "enabled with UEFI lock",
+	2: "enabled without UEFI lock",
+}
+
+// ntlmMinSecFlags are the bits of NtlmMinClientSec and NtlmMinServerSec
+var ntlmMinSecFlags = map[uint64]string{
+	0x00080000: "require NTLMv2 session security",
+	0x20000000: "require 128-bit encryption",
+}
+
+// registrySettings are documented in
+// https://learn.microsoft.com/en-us/windows-server/security/credentials-protection-and-management/configuring-additional-lsa-protection,
+// https://learn.microsoft.com/en-us/windows/security/identity-protection/credential-guard/configure,
+// and under "Network security" in
+// https://learn.microsoft.com/en-us/windows/security/threat-protection/security-policy-settings/security-options
+var registrySettings = []registrySetting{
+	{
+		name:     "run_as_ppl",
+		key:      lsaKey,
+		value:    "RunAsPPL",
+		meanings: uefiLockMeanings,
+		unset:    "disabled",
+	},
+	{
+		name:     "credential_guard_configuration",
+		key:      lsaKey,
+		value:    "LsaCfgFlags",
+		meanings: uefiLockMeanings,
+		unset:    "not configured",
+	},
+	{
+		name:     "credential_guard_policy",
+		key:      policyKey,
+		value:    "LsaCfgFlags",
+		meanings: uefiLockMeanings,
+		unset:    "not configured",
+	},
+	{
+		name:  "virtualization_based_security_configuration",
+		key:   deviceGuardKey,
+		value: "EnableVirtualizationBasedSecurity",
+		meanings: map[uint64]string{
+			0: "disabled",
+			1: "enabled",
+		},
+		unset: "not configured",
+	},
+	{
+		name:  "lm_compatibility_level",
+		key:   lsaKey,
+		value: "LmCompatibilityLevel",
+		meanings: map[uint64]string{
+			0: "send LM and NTLM responses",
+			1: "send LM and NTLM responses, use NTLMv2 session security if negotiated",
+			2: "send NTLM response only",
+			3: "send NTLMv2 response only",
+			4: "send NTLMv2 response only, refuse LM",
+			5: "send NTLMv2 response only, refuse LM and NTLM",
+		},
+		unset: "send NTLMv2 response only",
+	},
+	{
+		name:  "no_lm_hash",
+		key:   lsaKey,
+		value: "NoLMHash",
+		meanings: map[uint64]string{
+			0: "LM hashes stored",
+			1: "LM hashes not stored",
+		},
+		unset: "LM hashes not stored",
+	},
+	{
+		name:  "restrict_sending_ntlm_traffic",
+		key:   msv10Key,
+		value: "RestrictSendingNTLMTraffic",
+		meanings: map[uint64]string{
+			0: "allow all",
+			1: "audit all",
+			2: "deny all",
+		},
+		unset: "allow all",
+	},
+	{
+		name:  "restrict_receiving_ntlm_traffic",
+		key:   msv10Key,
+		value: "RestrictReceivingNTLMTraffic",
+		meanings: map[uint64]string{
+			0: "allow all",
+			1: "deny all domain accounts",
+			2: "deny all accounts",
+		},
+		unset: "allow all",
+	},
+	{
+		name:  "audit_receiving_ntlm_traffic",
+		key:   msv10Key,
+		value: "AuditReceivingNTLMTraffic",
+		meanings: map[uint64]string{
+			0: "disabled",
+			1: "enabled for domain accounts",
+			2: "enabled for all accounts",
+		},
+		unset: "disabled",
+	},
+	{
+		name:  "ntlm_min_client_sec",
+		key:   msv10Key,
+		value: "NtlmMinClientSec",
+		flags: ntlmMinSecFlags,
+		unset: "require 128-bit encryption",
+	},
+	{
+		name:  "ntlm_min_server_sec",
+		key:   msv10Key,
+		value: "NtlmMinServerSec",
+		flags: ntlmMinSecFlags,
+		unset: "require 128-bit encryption",
+	},
+	{
+		// Only meaningful on domain controllers
+		name:  "restrict_ntlm_in_domain",
+		key:   netlogonKey,
+		value: "RestrictNTLMInDomain",
+		meanings: map[uint64]string{
+			0: "disabled",
+			1: "deny for domain accounts to domain servers",
+			3: "deny for domain accounts",
+			5: "deny for domain servers",
+			7: "deny all",
+		},
+		unset: "disabled",
+	},
+}
+
+// source names where the setting's value is read from
+func (r registrySetting) source() string {
+	return `HKEY_LOCAL_MACHINE\` + r.key + `\` + r.value
+}
+
+// describe interprets the setting's value
+func (r registrySetting) describe(val uint64) string {
+	if r.flags != nil {
+		return describeFlags(r.flags, val)
+	}
+
+	if meaning, ok := r.meanings[val]; ok {
+		return meaning
+	}
+	return "unknown"
+}
+
+// describeFlags lists the flags set in val, in order, noting any unknown bits.
+func describeFlags(flags map[uint64]string, val uint64) string {
+	bits := make([]uint64, 0, len(flags))
+	for bit := range flags {
+		bits = append(bits, bit)
+	}
+	sort.Slice(bits, func(i, j int) bool { return bits[i] < bits[j] })
+
+	var descriptions []string
+	for _, bit := range bits {
+		if val&bit != 0 {
+			descriptions = append(descriptions, flags[bit])
+			val &^= bit
+		}
+	}
+	if val != 0 {
+		descriptions = append(descriptions, "unknown flags 0x"+strconv.FormatUint(val, 16))
+	}
+	if len(descriptions) == 0 {
+		return "none"
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// Values for Win32_DeviceGuard, see
+// https://learn.microsoft.com/en-us/windows/security/hardware-security/enable-virtualization-based-protection-of-code-integrity
+var vbsStatusNames = map[int]string{
+	0: "not enabled",
+	1: "enabled but not running",
+	2: "running",
+}
+
+var securityServiceNames = map[int]string{
+	1: "credential_guard",
+	2: "hypervisor_enforced_code_integrity",
+	3: "system_guard_secure_launch",
+	4: "smm_firmware_measurement",
+	5: "kernel_mode_hardware_enforced_stack_protection",
+	6: "kernel_mode_hardware_enforced_stack_protection_audit",
+	7: "hypervisor_enforced_paging_translation",
+}
+
+// securityServiceStatus describes whether a Device Guard security service is running.
+func securityServiceStatus(service int, configured []int, running []int) string {
+	switch {
+	case containsInt(running, service):
+		return "running"
+	case containsInt(configured, service):
+		return "configured but not running"
+	default:
+		return "not configured"
+	}
+}
+
+// intsFromWmi converts a WMI integer or integer array property into a slice of ints.
+// WMI returns uint32 properties as int32, and arrays as []interface{}.
+func intsFromWmi(val interface{}) []int {
+	switch v := val.(type) {
+	case []interface{}:
+		var ints []int
+		for _, item := range v {
+			ints = append(ints, intsFromWmi(item)...)
+		}
+		return ints
+	case int32:
+		return []int{int(v)}
+	case uint32:
+		return []int{int(v)}
+	case int64:
+		return []int{int(v)}
+	case uint8:
+		return []int{int(v)}
+	case int:
+		return []int{v}
+	}
+
+	return nil
+}
+
+func containsInt(haystack []int, needle int) bool {
+	for _, i := range haystack {
+		if i == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// setting is a row of the table. If the setting could not be read, err is set.
+type setting struct {
+	name        string
+	source      string
+	configured  bool // whether the value is set, rather than left to Windows' default, or the service enabled
+	value       string
+	description string
+	err         error
+}
+
+func (s setting) toRow() map[string]string {
+	row := map[string]string{
+		"setting":     s.name,
+		"source":      s.source,
+		"configured":  "0",
+		"value":       s.value,
+		"description": s.description,
+		"error":       "",
+	}
+
+	if s.configured {
+		row["configured"] = "1"
+	}
+
+	if s.err != nil {
+		row["configured"] = ""
+		row["error"] = s.err.Error()
+	}
+
+	return row
+}
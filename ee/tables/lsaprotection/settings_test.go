@@ -0,0 +1,86 @@
+package lsaprotection
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistrySettingDescribe(t *testing.T) {
+	t.Parallel()
+
+	settings := make(map[string]registrySetting)
+	for _, r := range registrySettings {
+		settings[r.name] = r
+	}
+
+	for _, tt := range []struct {
+		setting  string
+		val      uint64
+		expected string
+	}{
+		{setting: "run_as_ppl", val: 0, expected: "disabled"},
+		{setting: "run_as_ppl", val: 1, expected: "enabled with UEFI lock"},
+		{setting: "run_as_ppl", val: 2, expected: "enabled without UEFI lock"},
+		{setting: "run_as_ppl", val: 9, expected: "unknown"},
+		{setting: "lm_compatibility_level", val: 5, expected: "send NTLMv2 response only, refuse LM and NTLM"},
+		{setting: "restrict_sending_ntlm_traffic", val: 2, expected: "deny all"},
+		{setting: "ntlm_min_client_sec", val: 0, expected: "none"},
+		{setting: "ntlm_min_client_sec", val: 0x20080000, expected: "require NTLMv2 session security, require 128-bit encryption"},
+		{setting: "ntlm_min_server_sec", val: 0x20000010, expected: "require 128-bit encryption, unknown flags 0x10"},
+	} {
+		tt := tt
+		t.Run(tt.setting, func(t *testing.T) {
+			t.Parallel()
+
+			r, ok := settings[tt.setting]
+			require.True(t, ok, "setting %s not found", tt.setting)
+			require.Equal(t, tt.expected, r.describe(tt.val))
+		})
+	}
+}
+
+func TestRegistrySettingSource(t *testing.T) {
+	t.Parallel()
+
+	for _, r := range registrySettings {
+		require.NotEmpty(t, r.unset, "%s should describe its default", r.name)
+		require.True(t, (r.meanings == nil) != (r.flags == nil), "%s should have either meanings or flags", r.name)
+	}
+
+	require.Equal(t, `HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Control\Lsa\RunAsPPL`, registrySettings[0].source())
+}
+
+func TestSecurityServiceStatus(t *testing.T) {
+	t.Parallel()
+
+	configured := intsFromWmi([]interface{}{int32(1), int32(2)})
+	running := intsFromWmi([]interface{}{int32(2)})
+
+	require.Equal(t, "configured but not running", securityServiceStatus(1, configured, running))
+	require.Equal(t, "running", securityServiceStatus(2, configured, running))
+	require.Equal(t, "not configured", securityServiceStatus(3, configured, running))
+}
+
+func TestSettingToRow(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, map[string]string{
+		"setting":     "run_as_ppl",
+		"source":      "src",
+		"configured":  "1",
+		"value":       "2",
+		"description": "enabled without UEFI lock",
+		"error":       "",
+	}, setting{name: "run_as_ppl", source: "src", configured: true, value: "2", description: "enabled without UEFI lock"}.toRow())
+
+	require.Equal(t, map[string]string{
+		"setting":     "run_as_ppl",
+		"source":      "src",
+		"configured":  "",
+		"value":       "",
+		"description": "",
+		"error":       "access denied",
+	}, setting{name: "run_as_ppl", source: "src", err: errors.New("access denied")}.toRow())
+}
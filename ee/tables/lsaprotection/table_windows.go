@@ -0,0 +1,150 @@
+//go:build windows
+// +build windows
+
+package lsaprotection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/wmi"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	tableName            = "kolide_lsa_protection"
+	deviceGuardNamespace = `root\Microsoft\Windows\DeviceGuard`
+	deviceGuardSource    = "Win32_DeviceGuard"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("setting"),
+		table.TextColumn("source"),
+		table.IntegerColumn("configured"),
+		table.TextColumn("value"),
+		table.TextColumn("description"),
+		table.TextColumn("error"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	settings := make([]setting, 0, len(registrySettings))
+	for _, r := range registrySettings {
+		settings = append(settings, readRegistrySetting(r))
+	}
+	settings = append(settings, t.deviceGuardSettings(ctx)...)
+
+	for _, s := range settings {
+		if s.err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not read lsa protection setting",
+				"setting", s.name,
+				"err", s.err,
+			)
+		}
+		results = append(results, s.toRow())
+	}
+
+	return results, nil
+}
+
+// readRegistrySetting reads a DWORD setting. Missing keys and values aren't errors -- they
+// leave the setting to Windows' default.
+func readRegistrySetting(r registrySetting) setting {
+	s := setting{
+		name:        r.name,
+		source:      r.source(),
+		description: r.unset,
+	}
+
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, r.key, registry.QUERY_VALUE)
+	if err != nil {
+		if !errors.Is(err, registry.ErrNotExist) {
+			s.err = fmt.Errorf("opening %s: %w", r.key, err)
+		}
+		return s
+	}
+	defer key.Close()
+
+	val, _, err := key.GetIntegerValue(r.value)
+	if err != nil {
+		if !errors.Is(err, registry.ErrNotExist) {
+			s.err = fmt.Errorf("reading %s: %w", r.value, err)
+		}
+		return s
+	}
+
+	s.configured = true
+	s.value = strconv.FormatUint(val, 10)
+	s.description = r.describe(val)
+
+	return s
+}
+
+// deviceGuardSettings reports whether virtualization-based security, and the security services
+// that depend on it, such as Credential Guard, are actually running. For these, configured notes
+// whether they're enabled, and value whether they're running.
+func (t *Table) deviceGuardSettings(ctx context.Context) []setting {
+	properties := []string{"VirtualizationBasedSecurityStatus", "SecurityServicesConfigured", "SecurityServicesRunning"}
+	results, err := wmi.Query(ctx, t.slogger, "Win32_DeviceGuard", properties, wmi.ConnectNamespace(deviceGuardNamespace))
+	if err == nil && len(results) == 0 {
+		err = errors.New("no Win32_DeviceGuard results")
+	}
+	if err != nil {
+		return []setting{{
+			name:   "virtualization_based_security_status",
+			source: deviceGuardSource + ".VirtualizationBasedSecurityStatus",
+			err:    fmt.Errorf("querying device guard: %w", err),
+		}}
+	}
+
+	result := results[0]
+	vbs := setting{
+		name:   "virtualization_based_security_status",
+		source: deviceGuardSource + ".VirtualizationBasedSecurityStatus",
+	}
+	if status := intsFromWmi(result["VirtualizationBasedSecurityStatus"]); len(status) > 0 {
+		vbs.configured = status[0] != 0
+		vbs.value = strconv.Itoa(status[0])
+		vbs.description = vbsStatusNames[status[0]]
+	}
+	settings := []setting{vbs}
+
+	configured := intsFromWmi(result["SecurityServicesConfigured"])
+	running := intsFromWmi(result["SecurityServicesRunning"])
+	for service := 1; service <= len(securityServiceNames); service++ {
+		settings = append(settings, setting{
+			name:        securityServiceNames[service],
+			source:      deviceGuardSource + ".SecurityServicesRunning",
+			configured:  containsInt(configured, service),
+			value:       boolToIntString(containsInt(running, service)),
+			description: securityServiceStatus(service, configured, running),
+		})
+	}
+
+	return settings
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
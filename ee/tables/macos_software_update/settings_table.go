@@ -0,0 +1,179 @@
+//go:build darwin
+// +build darwin
+
+package macos_software_update
+
+/*
+#cgo darwin LDFLAGS: -framework CoreFoundation
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+*/
+import (
+	"C"
+)
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"unsafe"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	softwareUpdateDomain = "com.apple.SoftwareUpdate"
+	appStoreDomain       = "com.apple.commerce"
+	assetCacheDomain     = "com.apple.AssetCache"
+)
+
+// softwareUpdateSettings are the preferences reported by kolide_software_update_settings, by their
+// exact key names, so that checks can match them against what an MDM profile sets.
+var softwareUpdateSettings = []struct {
+	domain string
+	key    string
+}{
+	{softwareUpdateDomain, "AutomaticCheckEnabled"},
+	{softwareUpdateDomain, "AutomaticDownload"},
+	{softwareUpdateDomain, "AutomaticallyInstallMacOSUpdates"},
+	{softwareUpdateDomain, "CriticalUpdateInstall"},
+	{softwareUpdateDomain, "ConfigDataInstall"},
+	{softwareUpdateDomain, "AllowPreReleaseInstallation"},
+	{softwareUpdateDomain, "LastSuccessfulDate"},
+	{softwareUpdateDomain, "LastFullSuccessfulDate"},
+	{softwareUpdateDomain, "LastAttemptSystemVersion"},
+	{softwareUpdateDomain, "LastRecommendedUpdatesAvailable"},
+	{appStoreDomain, "AutoUpdate"},
+	{assetCacheDomain, "Activated"},
+	{assetCacheDomain, "CacheLimit"},
+	{assetCacheDomain, "AllowSharedCaching"},
+	{assetCacheDomain, "AllowPersonalCaching"},
+}
+
+// preferenceValue is a preference as read for the table. Booleans are 1 or 0, and dates are
+// unix timestamps.
+type preferenceValue struct {
+	value      string
+	configured bool // whether the preference is set at all, as opposed to using the OS default
+	managed    bool // whether the preference is forced by a configuration profile
+}
+
+type preferenceReader func(domain, key string) preferenceValue
+
+type settingsTable struct {
+	slogger        *slog.Logger
+	readPreference preferenceReader
+}
+
+// SoftwareUpdateSettings returns a table of the Software Update, App Store, and content caching
+// preferences that control how the device updates itself. Unlike kolide_macos_software_update,
+// it reports the preferences by name, including when they were last successfully checked.
+func SoftwareUpdateSettings(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("domain"),
+		table.TextColumn("key"),
+		table.TextColumn("value"),
+		table.IntegerColumn("configured"),
+		table.IntegerColumn("managed"),
+	}
+
+	t := &settingsTable{
+		slogger:        slogger.With("table", "kolide_software_update_settings"),
+		readPreference: copyAppPreference,
+	}
+
+	return table.NewPlugin("kolide_software_update_settings", columns, t.generate)
+}
+
+func (t *settingsTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := make([]map[string]string, 0, len(softwareUpdateSettings))
+
+	for _, setting := range softwareUpdateSettings {
+		pref := t.readPreference(setting.domain, setting.key)
+
+		results = append(results, map[string]string{
+			"domain":     setting.domain,
+			"key":        setting.key,
+			"value":      pref.value,
+			"configured": boolToIntString(pref.configured),
+			"managed":    boolToIntString(pref.managed),
+		})
+	}
+
+	return results, nil
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// copyAppPreference reads the effective value of the preference, as the system sees it --
+// including values forced by configuration profiles.
+func copyAppPreference(domain, key string) preferenceValue {
+	domainRef := cfStringRef(domain)
+	defer C.CFRelease(C.CFTypeRef(domainRef))
+	keyRef := cfStringRef(key)
+	defer C.CFRelease(C.CFTypeRef(keyRef))
+
+	// Pick up changes made by other processes since we last read the domain
+	C.CFPreferencesAppSynchronize(domainRef)
+
+	val := C.CFPreferencesCopyAppValue(keyRef, domainRef)
+	if C.CFTypeRef(val) == 0 {
+		return preferenceValue{}
+	}
+	defer C.CFRelease(C.CFTypeRef(val))
+
+	return preferenceValue{
+		value:      cfValueString(val),
+		configured: true,
+		managed:    C.CFPreferencesAppValueIsForced(keyRef, domainRef) != 0,
+	}
+}
+
+// cfStringRef returns a C.CFStringRef which must be released with C.CFRelease
+func cfStringRef(s string) C.CFStringRef {
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+	return C.CFStringCreateWithCString(C.kCFAllocatorDefault, cs, C.kCFStringEncodingUTF8)
+}
+
+// cfValueString formats the scalar preference types. Other types, like arrays, are reported
+// as configured, without a value.
+func cfValueString(ref C.CFPropertyListRef) string {
+	switch C.CFGetTypeID(C.CFTypeRef(ref)) {
+	case C.CFBooleanGetTypeID():
+		return boolToIntString(C.CFBooleanGetValue(C.CFBooleanRef(ref)) != 0)
+	case C.CFNumberGetTypeID():
+		if C.CFNumberIsFloatType(C.CFNumberRef(ref)) != 0 {
+			var f C.double
+			C.CFNumberGetValue(C.CFNumberRef(ref), C.kCFNumberDoubleType, unsafe.Pointer(&f))
+			return strconv.FormatFloat(float64(f), 'f', -1, 64)
+		}
+		var n C.longlong
+		C.CFNumberGetValue(C.CFNumberRef(ref), C.kCFNumberLongLongType, unsafe.Pointer(&n))
+		return strconv.FormatInt(int64(n), 10)
+	case C.CFDateGetTypeID():
+		absoluteTime := C.CFDateGetAbsoluteTime(C.CFDateRef(ref))
+		return strconv.FormatInt(int64(absoluteTime+C.kCFAbsoluteTimeIntervalSince1970), 10)
+	case C.CFStringGetTypeID():
+		return goStringFromCFString(C.CFStringRef(ref))
+	default:
+		return ""
+	}
+}
+
+func goStringFromCFString(ref C.CFStringRef) string {
+	if cs := C.CFStringGetCStringPtr(ref, C.kCFStringEncodingUTF8); cs != nil {
+		return C.GoString(cs)
+	}
+
+	bufSize := C.CFStringGetMaximumSizeForEncoding(C.CFStringGetLength(ref), C.kCFStringEncodingUTF8) + 1
+	buf := make([]byte, int(bufSize))
+	if C.CFStringGetCString(ref, (*C.char)(unsafe.Pointer(&buf[0])), bufSize, C.kCFStringEncodingUTF8) == 0 {
+		return ""
+	}
+	return C.GoString((*C.char)(unsafe.Pointer(&buf[0])))
+}
@@ -0,0 +1,69 @@
+//go:build darwin
+// +build darwin
+
+package macos_software_update
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateSoftwareUpdateSettings(t *testing.T) {
+	t.Parallel()
+
+	st := &settingsTable{
+		slogger: multislogger.NewNopLogger(),
+		readPreference: func(domain, key string) preferenceValue {
+			switch {
+			case domain == softwareUpdateDomain && key == "AutomaticCheckEnabled":
+				return preferenceValue{value: "1", configured: true, managed: true}
+			case domain == softwareUpdateDomain && key == "LastSuccessfulDate":
+				return preferenceValue{value: "1700000000", configured: true}
+			default:
+				return preferenceValue{}
+			}
+		},
+	}
+
+	rows, err := st.generate(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Len(t, rows, len(softwareUpdateSettings), "every setting should have a row, even if unset")
+
+	require.Contains(t, rows, map[string]string{
+		"domain":     softwareUpdateDomain,
+		"key":        "AutomaticCheckEnabled",
+		"value":      "1",
+		"configured": "1",
+		"managed":    "1",
+	})
+	require.Contains(t, rows, map[string]string{
+		"domain":     softwareUpdateDomain,
+		"key":        "LastSuccessfulDate",
+		"value":      "1700000000",
+		"configured": "1",
+		"managed":    "0",
+	})
+	require.Contains(t, rows, map[string]string{
+		"domain":     appStoreDomain,
+		"key":        "AutoUpdate",
+		"value":      "",
+		"configured": "0",
+		"managed":    "0",
+	})
+}
+
+func Test_copyAppPreference(t *testing.T) {
+	t.Parallel()
+
+	// The value depends on the machine, so just check that reading it works
+	pref := copyAppPreference(softwareUpdateDomain, "LastSuccessfulDate")
+	if pref.configured {
+		require.NotEmpty(t, pref.value)
+	}
+
+	require.False(t, copyAppPreference(softwareUpdateDomain, "NotARealKolideTestKey").configured)
+}
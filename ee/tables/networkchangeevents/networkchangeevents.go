@@ -0,0 +1,59 @@
+package networkchangeevents
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/networkchangewatcher"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_network_change_events"
+
+// TablePlugin provides an osquery table of changes to network interfaces, addresses, and
+// default routes, as recorded by launcher's network change watcher. Because the watcher is
+// notified of changes as they happen, this includes changes that are quickly reverted, like
+// a VPN connection dropping and reconnecting, which polling the interface tables would miss.
+func TablePlugin(store types.Iterator) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.BigIntColumn("time"),
+		table.TextColumn("action"),
+		table.TextColumn("source"),
+		table.TextColumn("interface"),
+		table.TextColumn("address"),
+		table.TextColumn("previous_interface"),
+		table.TextColumn("previous_address"),
+	}
+
+	return table.NewPlugin(tableName, columns, generate(store))
+}
+
+func generate(store types.Iterator) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		results := make([]map[string]string, 0)
+
+		if store == nil {
+			return results, nil
+		}
+
+		events, err := networkchangewatcher.Events(store)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			results = append(results, map[string]string{
+				"time":               strconv.FormatInt(event.Time, 10),
+				"action":             event.Action,
+				"source":             event.Source,
+				"interface":          event.Interface,
+				"address":            event.Address,
+				"previous_interface": event.PreviousInterface,
+				"previous_address":   event.PreviousAddress,
+			})
+		}
+
+		return results, nil
+	}
+}
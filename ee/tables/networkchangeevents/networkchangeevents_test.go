@@ -0,0 +1,50 @@
+package networkchangeevents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	require.NoError(t, store.Set([]byte("00000000000000000002"), []byte(`{"time":2,"action":"default_route_changed","source":"notification","interface":"en0","address":"192.168.1.11","previous_interface":"utun3","previous_address":"100.64.0.2"}`)))
+	require.NoError(t, store.Set([]byte("00000000000000000001"), []byte(`{"time":1,"action":"interface_down","source":"poll","interface":"utun3"}`)))
+	require.NoError(t, store.Set([]byte("00000000000000000003"), []byte(`not json`)))
+
+	rows, err := generate(store)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{
+			"time":               "1",
+			"action":             "interface_down",
+			"source":             "poll",
+			"interface":          "utun3",
+			"address":            "",
+			"previous_interface": "",
+			"previous_address":   "",
+		},
+		{
+			"time":               "2",
+			"action":             "default_route_changed",
+			"source":             "notification",
+			"interface":          "en0",
+			"address":            "192.168.1.11",
+			"previous_interface": "utun3",
+			"previous_address":   "100.64.0.2",
+		},
+	}, rows)
+}
+
+func TestGenerate_NoStore(t *testing.T) {
+	t.Parallel()
+
+	rows, err := generate(nil)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
@@ -0,0 +1,348 @@
+package networkshares
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_network_shares_mounted"
+
+const (
+	protocolSmb    = "smb"
+	protocolNfs    = "nfs"
+	protocolAfp    = "afp"
+	protocolWebdav = "webdav"
+)
+
+// Where the credentials for the mount came from. Which of these we can tell apart depends on
+// the platform; credentialsSourceUnknown is used when we can't tell.
+const (
+	credentialsSourceKerberos          = "kerberos"           // a Kerberos ticket
+	credentialsSourceKeychain          = "keychain"           // a password saved in the user's macOS keychain
+	credentialsSourceCredentialManager = "credential_manager" // credentials saved in Windows Credential Manager
+	credentialsSourceLogonSession      = "logon_session"      // the Windows user's own logon credentials
+	credentialsSourcePassword          = "password"           // a username and password given when mounting
+	credentialsSourceGuest             = "guest"              // guest or anonymous access
+	credentialsSourceHost              = "host"               // NFS AUTH_SYS, which trusts the client's uid
+	credentialsSourceUnknown           = ""
+)
+
+var columns = []table.ColumnDefinition{
+	table.TextColumn("username"),
+	table.TextColumn("protocol"),
+	table.TextColumn("server"),
+	table.TextColumn("share"),
+	table.TextColumn("mount_point"),
+	table.TextColumn("fs_type"),
+	table.TextColumn("options"),
+	table.TextColumn("remote_username"),
+	table.TextColumn("credentials_source"),
+}
+
+type mount struct {
+	username          string // the local user that mounted the share, if known
+	protocol          string
+	server            string
+	share             string
+	mountPoint        string
+	fsType            string
+	options           []string
+	remoteUsername    string // the user the server knows us as, if known
+	credentialsSource string
+}
+
+func (m mount) row() map[string]string {
+	return map[string]string{
+		"username":           m.username,
+		"protocol":           m.protocol,
+		"server":             m.server,
+		"share":              m.share,
+		"mount_point":        m.mountPoint,
+		"fs_type":            m.fsType,
+		"options":            strings.Join(m.options, ","),
+		"remote_username":    m.remoteUsername,
+		"credentials_source": m.credentialsSource,
+	}
+}
+
+// protocolForFsType returns the network filesystem protocol behind the filesystem type, or an
+// empty string if it isn't a network filesystem we report on.
+func protocolForFsType(fsType string) string {
+	switch strings.ToLower(fsType) {
+	case "cifs", "smb3", "smbfs":
+		return protocolSmb
+	case "nfs", "nfs4":
+		return protocolNfs
+	case "afpfs", "fuse.afpfs":
+		return protocolAfp
+	case "webdav", "davfs", "fuse.davfs2":
+		return protocolWebdav
+	default:
+		return ""
+	}
+}
+
+// parseSource splits the mount's source -- `//[user@]server/share` for SMB and AFP,
+// `server:/export` for NFS, and a URL for WebDAV -- into the server, share, and remote user.
+func parseSource(protocol, source string) (server, share, remoteUsername string) {
+	switch protocol {
+	case protocolNfs:
+		// IPv6 servers are bracketed, so split on the last colon before the path
+		sep := strings.LastIndex(source, ":/")
+		if sep < 0 {
+			return "", source, ""
+		}
+		return strings.Trim(source[:sep], "[]"), source[sep+1:], ""
+
+	case protocolWebdav:
+		u, err := url.Parse(source)
+		if err != nil || u.Host == "" {
+			return "", source, ""
+		}
+		return u.Hostname(), u.Path, u.User.Username()
+
+	default:
+		// smbfs and afpfs on macOS may include the scheme
+		source = strings.TrimPrefix(strings.TrimPrefix(source, "smb:"), "afp:")
+		source = strings.TrimPrefix(source, "//")
+
+		authority, share, _ := strings.Cut(source, "/")
+		if at := strings.LastIndex(authority, "@"); at >= 0 {
+			remoteUsername = authority[:at]
+			authority = authority[at+1:]
+			// The user may carry a password (`user:password`) or domain (`domain;user`)
+			remoteUsername, _, _ = strings.Cut(remoteUsername, ":")
+			if domain, user, found := strings.Cut(remoteUsername, ";"); found {
+				remoteUsername = domain + `\` + user
+			}
+		}
+		server = authority
+		if unescaped, err := url.PathUnescape(server); err == nil {
+			server = unescaped
+		}
+		if unescaped, err := url.PathUnescape(share); err == nil {
+			share = unescaped
+		}
+		if unescaped, err := url.PathUnescape(remoteUsername); err == nil {
+			remoteUsername = unescaped
+		}
+		return server, share, remoteUsername
+	}
+}
+
+// isGuestUser reports whether the remote user is the SMB guest account.
+func isGuestUser(remoteUsername string) bool {
+	return strings.EqualFold(remoteUsername, "guest")
+}
+
+// parseProcMounts parses /proc/self/mounts into the network filesystems mounted.
+func parseProcMounts(r io.Reader) []mount {
+	var mounts []mount
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		source, mountPoint, fsType := unescapeMountField(fields[0]), unescapeMountField(fields[1]), fields[2]
+		protocol := protocolForFsType(fsType)
+		if protocol == "" {
+			continue
+		}
+
+		options := strings.Split(unescapeMountField(fields[3]), ",")
+		m := mount{
+			protocol:   protocol,
+			mountPoint: mountPoint,
+			fsType:     fsType,
+			options:    options,
+		}
+		m.server, m.share, m.remoteUsername = parseSource(protocol, source)
+
+		optionValues := make(map[string]string, len(options))
+		for _, o := range options {
+			key, value, _ := strings.Cut(o, "=")
+			optionValues[key] = value
+		}
+
+		if protocol == protocolSmb {
+			if user, ok := optionValues["username"]; ok && user != "" {
+				m.remoteUsername = user
+				if domain := optionValues["domain"]; domain != "" {
+					m.remoteUsername = domain + `\` + user
+				}
+			}
+		}
+		m.credentialsSource = linuxCredentialsSource(protocol, optionValues)
+
+		mounts = append(mounts, m)
+	}
+
+	return mounts
+}
+
+// linuxCredentialsSource tells where the mount's credentials came from, based on its security
+// mode. cifs doesn't show whether a password came from a credentials file or the command line.
+func linuxCredentialsSource(protocol string, options map[string]string) string {
+	sec := strings.ToLower(options["sec"])
+
+	switch protocol {
+	case protocolSmb:
+		switch {
+		case strings.HasPrefix(sec, "krb5"):
+			return credentialsSourceKerberos
+		case sec == "none", isGuestUser(options["username"]):
+			return credentialsSourceGuest
+		default:
+			return credentialsSourcePassword
+		}
+
+	case protocolNfs:
+		switch {
+		case strings.HasPrefix(sec, "krb5"):
+			return credentialsSourceKerberos
+		case sec == "none":
+			return credentialsSourceGuest
+		default:
+			// NFS defaults to sec=sys
+			return credentialsSourceHost
+		}
+
+	case protocolWebdav:
+		// davfs2 reads credentials from its secrets file, or prompts for them
+		return credentialsSourcePassword
+
+	default:
+		return credentialsSourceUnknown
+	}
+}
+
+// unescapeMountField undoes the octal escaping of spaces, tabs, newlines, and backslashes
+// in /proc/self/mounts.
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if c, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+
+	return b.String()
+}
+
+// smbConnection is an SMB client connection, as reported by Get-SmbConnection on Windows.
+type smbConnection struct {
+	ServerName string `json:"server"`
+	ShareName  string `json:"share"`
+	UserName   string `json:"username"`   // the Windows user the connection belongs to
+	Credential string `json:"credential"` // the account the server authenticated
+	Dialect    string `json:"dialect"`
+	Signed     bool   `json:"signed"`
+	Encrypted  bool   `json:"encrypted"`
+}
+
+// driveMapping is a Windows user's persistent network drive mapping.
+type driveMapping struct {
+	username   string // DOMAIN\user
+	drive      string
+	remotePath string
+	remoteUser string // set when the mapping uses other credentials than the user's own
+}
+
+func parseSmbConnections(out []byte) ([]smbConnection, error) {
+	var connections []smbConnection
+	if err := json.Unmarshal(out, &connections); err != nil {
+		return nil, fmt.Errorf("unmarshalling Get-SmbConnection output `%s`: %w", string(out), err)
+	}
+	return connections, nil
+}
+
+// windowsMounts combines the SMB connections with the users' drive mappings. Windows restores
+// persistent mappings at logon, so a mapping made with other credentials than the user's own
+// has them saved in Credential Manager.
+func windowsMounts(connections []smbConnection, mappings []driveMapping) []mount {
+	mounts := make([]mount, 0, len(connections))
+	for _, c := range connections {
+		m := mount{
+			username:       c.UserName,
+			protocol:       protocolSmb,
+			server:         c.ServerName,
+			share:          c.ShareName,
+			fsType:         "smb",
+			remoteUsername: c.Credential,
+		}
+		if c.Dialect != "" {
+			m.options = append(m.options, "dialect="+c.Dialect)
+		}
+		if c.Signed {
+			m.options = append(m.options, "signed")
+		}
+		if c.Encrypted {
+			m.options = append(m.options, "encrypted")
+		}
+
+		remotePath := `\\` + c.ServerName + `\` + c.ShareName
+		var mapping *driveMapping
+		for i := range mappings {
+			if strings.EqualFold(mappings[i].username, c.UserName) && strings.EqualFold(mappings[i].remotePath, remotePath) {
+				mapping = &mappings[i]
+				break
+			}
+		}
+		if mapping != nil {
+			m.mountPoint = mapping.drive + ":"
+		}
+
+		switch {
+		case isGuestUser(accountName(c.Credential)):
+			m.credentialsSource = credentialsSourceGuest
+		case c.Credential == "" || strings.EqualFold(c.Credential, c.UserName):
+			// Windows negotiates Kerberos or NTLM with the logon session's credentials
+			m.credentialsSource = credentialsSourceLogonSession
+		case mapping != nil && mapping.remoteUser != "":
+			m.credentialsSource = credentialsSourceCredentialManager
+		default:
+			m.credentialsSource = credentialsSourcePassword
+		}
+
+		mounts = append(mounts, m)
+	}
+
+	return mounts
+}
+
+// accountName strips the domain from a DOMAIN\user or user@domain account.
+func accountName(account string) string {
+	if _, user, found := strings.Cut(account, `\`); found {
+		return user
+	}
+	user, _, _ := strings.Cut(account, "@")
+	return user
+}
+
+// hasServiceTicket reports whether the klist output includes a ticket for the SMB server,
+// meaning the share was mounted with Kerberos.
+func hasServiceTicket(klistOutput []byte, server string) bool {
+	if server == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(klistOutput)), "cifs/"+strings.ToLower(server))
+}
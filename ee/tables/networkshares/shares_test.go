@@ -0,0 +1,142 @@
+package networkshares
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSource(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name                   string
+		protocol               string
+		source                 string
+		expectedServer         string
+		expectedShare          string
+		expectedRemoteUsername string
+	}{
+		{name: "smb", protocol: protocolSmb, source: "//fileserver/Finance", expectedServer: "fileserver", expectedShare: "Finance"},
+		{name: "smb with user", protocol: protocolSmb, source: "//jdoe@fileserver.corp.example.com/Home%20Dirs", expectedServer: "fileserver.corp.example.com", expectedShare: "Home Dirs", expectedRemoteUsername: "jdoe"},
+		{name: "smb with domain", protocol: protocolSmb, source: "//CORP;jdoe@fileserver/Finance", expectedServer: "fileserver", expectedShare: "Finance", expectedRemoteUsername: `CORP\jdoe`},
+		{name: "smb guest", protocol: protocolSmb, source: "//GUEST:@nas._smb._tcp.local/public", expectedServer: "nas._smb._tcp.local", expectedShare: "public", expectedRemoteUsername: "GUEST"},
+		{name: "afp", protocol: protocolAfp, source: "afp://jdoe@oldmac.local/Shared", expectedServer: "oldmac.local", expectedShare: "Shared", expectedRemoteUsername: "jdoe"},
+		{name: "nfs", protocol: protocolNfs, source: "nfs.example.com:/export/data", expectedServer: "nfs.example.com", expectedShare: "/export/data"},
+		{name: "nfs ipv6", protocol: protocolNfs, source: "[fd00::5]:/export/scratch", expectedServer: "fd00::5", expectedShare: "/export/scratch"},
+		{name: "webdav", protocol: protocolWebdav, source: "https://jdoe@dav.example.com/remote.php/webdav/", expectedServer: "dav.example.com", expectedShare: "/remote.php/webdav/", expectedRemoteUsername: "jdoe"},
+		{name: "webdav not a url", protocol: protocolWebdav, source: "dav", expectedShare: "dav"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, share, remoteUsername := parseSource(tt.protocol, tt.source)
+			require.Equal(t, tt.expectedServer, server)
+			require.Equal(t, tt.expectedShare, share)
+			require.Equal(t, tt.expectedRemoteUsername, remoteUsername)
+		})
+	}
+}
+
+func TestParseProcMounts(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filepath.Join("testdata", "mounts"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	mounts := parseProcMounts(f)
+	require.Len(t, mounts, 6)
+
+	expected := []struct {
+		protocol          string
+		server            string
+		share             string
+		mountPoint        string
+		remoteUsername    string
+		credentialsSource string
+	}{
+		{protocolSmb, "fileserver.corp.example.com", "Finance", "/mnt/finance", `CORP\jdoe`, credentialsSourcePassword},
+		{protocolSmb, "fileserver.corp.example.com", "Home", "/mnt/home dir", "", credentialsSourceKerberos},
+		{protocolSmb, "nas.local", "public", "/mnt/public", "", credentialsSourceGuest},
+		{protocolNfs, "nfs.example.com", "/export/data", "/data", "", credentialsSourceKerberos},
+		{protocolNfs, "fd00::5", "/export/scratch", "/scratch", "", credentialsSourceHost},
+		{protocolWebdav, "dav.example.com", "/remote.php/webdav/", "/mnt/dav", "", credentialsSourcePassword},
+	}
+	for i, e := range expected {
+		require.Equal(t, e.protocol, mounts[i].protocol, "mount %d", i)
+		require.Equal(t, e.server, mounts[i].server, "mount %d", i)
+		require.Equal(t, e.share, mounts[i].share, "mount %d", i)
+		require.Equal(t, e.mountPoint, mounts[i].mountPoint, "mount %d", i)
+		require.Equal(t, e.remoteUsername, mounts[i].remoteUsername, "mount %d", i)
+		require.Equal(t, e.credentialsSource, mounts[i].credentialsSource, "mount %d", i)
+	}
+
+	require.Equal(t, "cifs", mounts[0].row()["fs_type"])
+	require.Contains(t, mounts[0].row()["options"], "sec=ntlmssp")
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "/mnt/no escapes", unescapeMountField("/mnt/no escapes"))
+	require.Equal(t, "/mnt/a b\tc\\d", unescapeMountField(`/mnt/a\040b\011c\134d`))
+	require.Equal(t, `/mnt/trailing\04`, unescapeMountField(`/mnt/trailing\04`))
+}
+
+func TestWindowsMounts(t *testing.T) {
+	t.Parallel()
+
+	connections, err := parseSmbConnections([]byte(`[
+		{"server":"fileserver","share":"Finance","username":"CORP\\jdoe","credential":"CORP\\jdoe","dialect":"3.1.1","signed":true,"encrypted":false},
+		{"server":"nas","share":"backup","username":"CORP\\jdoe","credential":"NAS\\admin","dialect":"3.0.2","signed":false,"encrypted":true},
+		{"server":"partner","share":"drop","username":"CORP\\jdoe","credential":"PARTNER\\upload","dialect":"2.1","signed":false,"encrypted":false},
+		{"server":"printserver","share":"IPC$","username":"CORP\\jdoe","credential":"printserver\\Guest","dialect":"3.1.1","signed":false,"encrypted":false}
+	]`))
+	require.NoError(t, err)
+
+	mounts := windowsMounts(connections, []driveMapping{
+		{username: `CORP\jdoe`, drive: "F", remotePath: `\\fileserver\Finance`},
+		{username: `CORP\jdoe`, drive: "N", remotePath: `\\NAS\backup`, remoteUser: `NAS\admin`},
+		{username: `CORP\other`, drive: "P", remotePath: `\\partner\drop`, remoteUser: `PARTNER\upload`},
+	})
+	require.Len(t, mounts, 4)
+
+	require.Equal(t, "F:", mounts[0].mountPoint)
+	require.Equal(t, credentialsSourceLogonSession, mounts[0].credentialsSource)
+	require.Equal(t, "dialect=3.1.1,signed", mounts[0].row()["options"])
+
+	require.Equal(t, "N:", mounts[1].mountPoint)
+	require.Equal(t, credentialsSourceCredentialManager, mounts[1].credentialsSource)
+	require.Equal(t, `NAS\admin`, mounts[1].remoteUsername)
+	require.Equal(t, "dialect=3.0.2,encrypted", mounts[1].row()["options"])
+
+	// Another user's mapping of the same share doesn't count
+	require.Empty(t, mounts[2].mountPoint)
+	require.Equal(t, credentialsSourcePassword, mounts[2].credentialsSource)
+
+	require.Equal(t, credentialsSourceGuest, mounts[3].credentialsSource)
+
+	_, err = parseSmbConnections([]byte("not json"))
+	require.Error(t, err)
+}
+
+func TestHasServiceTicket(t *testing.T) {
+	t.Parallel()
+
+	klistOutput := []byte(`Credentials cache: API:6F1E0A6B-1234
+        Principal: jdoe@CORP.EXAMPLE.COM
+
+  Issued                Expires               Principal
+Mar  1 09:00:00 2024  Mar  1 19:00:00 2024  krbtgt/CORP.EXAMPLE.COM@CORP.EXAMPLE.COM
+Mar  1 09:05:00 2024  Mar  1 19:00:00 2024  cifs/FileServer.corp.example.com@CORP.EXAMPLE.COM
+`)
+
+	require.True(t, hasServiceTicket(klistOutput, "fileserver.corp.example.com"))
+	require.False(t, hasServiceTicket(klistOutput, "nas.local"))
+	require.False(t, hasServiceTicket(klistOutput, ""))
+	require.False(t, hasServiceTicket(nil, "fileserver.corp.example.com"))
+}
@@ -0,0 +1,172 @@
+//go:build darwin
+// +build darwin
+
+package networkshares
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/user"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/unix"
+)
+
+// keychainProtocols are the keychain's four-character protocol codes for internet passwords
+var keychainProtocols = map[string][]string{
+	protocolSmb:    {"smb "},
+	protocolAfp:    {"afp "},
+	protocolWebdav: {"http", "htps"},
+}
+
+// mountFlags are the mount flags we report as options
+var mountFlags = []struct {
+	flag   uint32
+	option string
+}{
+	{unix.MNT_RDONLY, "ro"},
+	{unix.MNT_NOSUID, "nosuid"},
+	{unix.MNT_NODEV, "nodev"},
+	{unix.MNT_NOEXEC, "noexec"},
+	{unix.MNT_DONTBROWSE, "nobrowse"},
+	{unix.MNT_AUTOMOUNTED, "automounted"},
+	{unix.MNT_QUARANTINE, "quarantine"},
+}
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+// TablePlugin provides an osquery table of the network filesystems currently mounted, and where
+// the credentials used to mount them came from.
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	mounts, err := networkMounts()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list mounts",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	// klist output for each mount owner, so we run it once per user
+	klistOutputs := make(map[uint32][]byte)
+
+	var results []map[string]string
+	for _, nm := range mounts {
+		m := nm.mount
+		if u, err := user.LookupId(strconv.FormatUint(uint64(nm.owner), 10)); err == nil {
+			m.username = u.Username
+		}
+		m.credentialsSource = t.credentialsSource(ctx, nm, klistOutputs)
+		results = append(results, m.row())
+	}
+
+	return results, nil
+}
+
+type networkMount struct {
+	mount
+	owner uint32
+}
+
+// networkMounts lists the mounted network filesystems.
+func networkMounts() ([]networkMount, error) {
+	n, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, fmt.Errorf("counting mounts: %w", err)
+	}
+
+	// Leave room for mounts made in the meantime
+	stats := make([]unix.Statfs_t, n+8)
+	n, err = unix.Getfsstat(stats, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, fmt.Errorf("listing mounts: %w", err)
+	}
+
+	var mounts []networkMount
+	for _, stat := range stats[:n] {
+		fsType := unix.ByteSliceToString(stat.Fstypename[:])
+		protocol := protocolForFsType(fsType)
+		if protocol == "" {
+			continue
+		}
+
+		m := mount{
+			protocol:   protocol,
+			mountPoint: unix.ByteSliceToString(stat.Mntonname[:]),
+			fsType:     fsType,
+		}
+		m.server, m.share, m.remoteUsername = parseSource(protocol, unix.ByteSliceToString(stat.Mntfromname[:]))
+		for _, f := range mountFlags {
+			if stat.Flags&f.flag != 0 {
+				m.options = append(m.options, f.option)
+			}
+		}
+
+		mounts = append(mounts, networkMount{mount: m, owner: stat.Owner})
+	}
+
+	return mounts, nil
+}
+
+// credentialsSource tells where the credentials for the mount came from: a Kerberos ticket
+// for the server in the owner's credential caches, or a password saved in their keychain.
+// Otherwise, the owner entered a password when mounting.
+func (t *Table) credentialsSource(ctx context.Context, nm networkMount, klistOutputs map[uint32][]byte) string {
+	switch nm.protocol {
+	case protocolNfs:
+		// macOS doesn't expose the NFS security flavor through statfs
+		return credentialsSourceUnknown
+	case protocolSmb, protocolAfp:
+		if isGuestUser(nm.remoteUsername) {
+			return credentialsSourceGuest
+		}
+	}
+
+	uid := strconv.FormatUint(uint64(nm.owner), 10)
+
+	if nm.protocol == protocolSmb {
+		klistOutput, ok := klistOutputs[nm.owner]
+		if !ok {
+			var stdout, stderr bytes.Buffer
+			// klist exits non-zero when the user has no credential caches, which is fine
+			_ = tablehelpers.Run(ctx, t.slogger, 5,
+				allowedcmd.Klist, []string{"-A"}, &stdout, &stderr,
+				tablehelpers.WithUserContext(uid),
+			)
+			klistOutput = stdout.Bytes()
+			klistOutputs[nm.owner] = klistOutput
+		}
+		if hasServiceTicket(klistOutput, nm.server) {
+			return credentialsSourceKerberos
+		}
+	}
+
+	for _, keychainProtocol := range keychainProtocols[nm.protocol] {
+		var stdout, stderr bytes.Buffer
+		// security exits non-zero when there's no matching password; it doesn't print the
+		// password itself without -g or -w
+		if err := tablehelpers.Run(ctx, t.slogger, 5,
+			allowedcmd.Security, []string{"find-internet-password", "-s", nm.server, "-r", keychainProtocol}, &stdout, &stderr,
+			tablehelpers.WithUserContext(uid),
+		); err == nil {
+			return credentialsSourceKeychain
+		}
+	}
+
+	return credentialsSourcePassword
+}
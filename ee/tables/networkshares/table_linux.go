@@ -0,0 +1,74 @@
+//go:build linux
+// +build linux
+
+package networkshares
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger    *slog.Logger
+	mountsPath string
+}
+
+// TablePlugin provides an osquery table of the network filesystems currently mounted, and where
+// the credentials used to mount them came from.
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger:    slogger.With("table", tableName),
+		mountsPath: "/proc/self/mounts",
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	f, err := os.Open(t.mountsPath)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read mounts",
+			"err", err,
+		)
+		return nil, nil
+	}
+	defer f.Close()
+
+	var results []map[string]string
+	for _, m := range parseProcMounts(f) {
+		m.username = mountOwner(m.options)
+		results = append(results, m.row())
+	}
+
+	return results, nil
+}
+
+// mountOwner returns the user that cifs uses the credentials of (cruid), or that owns the
+// mount's files (uid), which is usually the user that mounted it.
+func mountOwner(options []string) string {
+	var uid string
+	for _, o := range options {
+		if value, found := strings.CutPrefix(o, "cruid="); found {
+			uid = value
+			break
+		}
+		if value, found := strings.CutPrefix(o, "uid="); found {
+			uid = value
+		}
+	}
+	if uid == "" {
+		return ""
+	}
+
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
@@ -0,0 +1,38 @@
+//go:build linux
+// +build linux
+
+package networkshares
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/ee/tables/tabletest"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTablePlugin(t *testing.T) {
+	t.Parallel()
+
+	tbl := &Table{
+		slogger:    multislogger.NewNopLogger(),
+		mountsPath: filepath.Join("testdata", "mounts"),
+	}
+	plugin := table.NewPlugin(tableName, columns, tbl.generate)
+
+	rows := tabletest.Generate(t, plugin, tabletest.NewQueryContext().Build())
+	require.Len(t, rows, 6)
+	for _, row := range rows {
+		if row["mount_point"] == "/mnt/finance" {
+			// uid=0
+			require.Equal(t, "root", row["username"])
+			require.Equal(t, credentialsSourcePassword, row["credentials_source"])
+		}
+	}
+
+	// Missing mounts file is not an error
+	tbl.mountsPath = filepath.Join(t.TempDir(), "mounts")
+	require.Empty(t, tabletest.Generate(t, plugin, tabletest.NewQueryContext().Build()))
+}
@@ -0,0 +1,163 @@
+//go:build windows
+// +build windows
+
+package networkshares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Get-SmbConnection, run elevated, lists every user's SMB connections. It's missing when the
+// SMB client PowerShell module isn't installed, which isn't an error.
+const smbConnectionScript = `if (-not (Get-Command Get-SmbConnection -ErrorAction SilentlyContinue)) { '[]'; exit }
+ConvertTo-Json -Compress -InputObject @(Get-SmbConnection | ForEach-Object { [pscustomobject]@{server=$_.ServerName; share=$_.ShareName; username=$_.UserName; credential=$_.Credential; dialect=[string]$_.Dialect; signed=[bool]$_.Signed; encrypted=[bool]$_.Encrypted} })`
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+// TablePlugin provides an osquery table of the network filesystems currently mounted, and where
+// the credentials used to mount them came from.
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	out, err := tablehelpers.RunSimple(ctx, t.slogger, 30, allowedcmd.Powershell, []string{"-NoProfile", "-NonInteractive", "-Command", smbConnectionScript})
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not run Get-SmbConnection",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	connections, err := parseSmbConnections(out)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not parse SMB connections",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	mappings, err := driveMappings()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read all drive mappings",
+			"err", err,
+		)
+	}
+
+	var results []map[string]string
+	for _, m := range windowsMounts(connections, mappings) {
+		results = append(results, m.row())
+	}
+
+	return results, nil
+}
+
+// driveMappings reads the persistent network drive mappings of the users whose registry hives
+// are loaded -- that is, the users logged in.
+func driveMappings() ([]driveMapping, error) {
+	usersKey, err := registry.OpenKey(registry.USERS, "", registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil, fmt.Errorf("opening HKEY_USERS: %w", err)
+	}
+	defer usersKey.Close()
+
+	sids, err := usersKey.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("listing HKEY_USERS: %w", err)
+	}
+
+	var mappings []driveMapping
+	var errs []error
+	for _, sid := range sids {
+		// Only local and domain user accounts
+		if !strings.HasPrefix(sid, "S-1-5-21-") || strings.HasSuffix(sid, "_Classes") {
+			continue
+		}
+
+		username, err := sidAccount(sid)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("looking up %s: %w", sid, err))
+			continue
+		}
+
+		userMappings, err := userDriveMappings(sid, username)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading drive mappings for %s: %w", username, err))
+			continue
+		}
+		mappings = append(mappings, userMappings...)
+	}
+
+	return mappings, errors.Join(errs...)
+}
+
+func userDriveMappings(sid, username string) ([]driveMapping, error) {
+	networkKey, err := registry.OpenKey(registry.USERS, sid+`\Network`, registry.ENUMERATE_SUB_KEYS)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening Network key: %w", err)
+	}
+	defer networkKey.Close()
+
+	drives, err := networkKey.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("listing drives: %w", err)
+	}
+
+	mappings := make([]driveMapping, 0, len(drives))
+	for _, drive := range drives {
+		driveKey, err := registry.OpenKey(networkKey, drive, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+
+		remotePath, _, _ := driveKey.GetStringValue("RemotePath")
+		remoteUser, _, _ := driveKey.GetStringValue("UserName")
+		driveKey.Close()
+
+		mappings = append(mappings, driveMapping{
+			username:   username,
+			drive:      strings.ToUpper(drive),
+			remotePath: remotePath,
+			remoteUser: remoteUser,
+		})
+	}
+
+	return mappings, nil
+}
+
+// sidAccount returns the DOMAIN\user account for the SID, as Get-SmbConnection reports users.
+func sidAccount(sid string) (string, error) {
+	s, err := windows.StringToSid(sid)
+	if err != nil {
+		return "", fmt.Errorf("parsing sid: %w", err)
+	}
+
+	account, domain, _, err := s.LookupAccount("")
+	if err != nil {
+		return "", fmt.Errorf("looking up account: %w", err)
+	}
+
+	return domain + `\` + account, nil
+}
@@ -0,0 +1,205 @@
+//go:build windows
+// +build windows
+
+package ntfsads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/ee/tables/tablewrapper"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows"
+)
+
+const tableName = "kolide_ntfs_ads"
+
+var (
+	kernel32            = windows.NewLazySystemDLL("kernel32.dll")
+	procFindFirstStream = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStream  = kernel32.NewProc("FindNextStreamW")
+)
+
+// findStreamInfoStandard is the FindStreamInfoStandard STREAM_INFO_LEVELS value
+const findStreamInfoStandard = 0
+
+// win32FindStreamData is WIN32_FIND_STREAM_DATA, see
+// https://learn.microsoft.com/en-us/windows/win32/api/fileapi/ns-fileapi-win32_find_stream_data
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [windows.MAX_PATH + 36]uint16
+}
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("path"),
+		table.TextColumn("stream"),
+		table.TextColumn("type"),
+		table.BigIntColumn("size"),
+		table.TextColumn("zone_id"),
+		table.TextColumn("zone"),
+		table.TextColumn("referrer_url"),
+		table.TextColumn("host_url"),
+		table.TextColumn("zone_identifier"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return tablewrapper.NewStreaming(slogger, tableName, columns, t.generate)
+}
+
+// generate streams rows to w as it goes, since path globs can match a great many files.
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext, w *tablewrapper.RowWriter) error {
+	requestedPaths := tablehelpers.GetConstraints(queryContext, "path")
+	if len(requestedPaths) == 0 {
+		return fmt.Errorf("the %s table requires that you specify a constraint for path", tableName)
+	}
+
+	for _, requestedPath := range requestedPaths {
+		// We take globs in via the sql %, but glob needs *. So convert.
+		filePaths, err := filepath.Glob(strings.ReplaceAll(requestedPath, `%`, `*`))
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"bad file glob",
+				"path", requestedPath,
+				"err", err,
+			)
+			continue
+		}
+
+		for _, filePath := range filePaths {
+			streams, err := alternateStreams(filePath)
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not list alternate data streams",
+					"path", filePath,
+					"err", err,
+				)
+				continue
+			}
+
+			for _, s := range streams {
+				if err := w.Write(t.streamRow(ctx, filePath, s)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// stream is an alternate data stream.
+type stream struct {
+	name       string
+	streamType string
+	size       int64
+}
+
+// alternateStreams lists the named streams of the file at path, skipping its default data stream.
+func alternateStreams(path string) ([]stream, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, fmt.Errorf("converting path: %w", err)
+	}
+
+	var data win32FindStreamData
+	handle, _, err := procFindFirstStream.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		findStreamInfoStandard,
+		uintptr(unsafe.Pointer(&data)),
+		0,
+	)
+	if windows.Handle(handle) == windows.InvalidHandle {
+		// Files with no streams at all, like most directories, report ERROR_HANDLE_EOF
+		if errors.Is(err, windows.ERROR_HANDLE_EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("FindFirstStreamW: %w", err)
+	}
+	defer windows.FindClose(windows.Handle(handle))
+
+	var streams []stream
+	for {
+		name, streamType := parseStreamName(windows.UTF16ToString(data.StreamName[:]))
+		if name != "" {
+			streams = append(streams, stream{name: name, streamType: streamType, size: data.StreamSize})
+		}
+
+		ret, _, err := procFindNextStream.Call(handle, uintptr(unsafe.Pointer(&data)))
+		if ret == 0 {
+			if errors.Is(err, windows.ERROR_HANDLE_EOF) {
+				return streams, nil
+			}
+			return streams, fmt.Errorf("FindNextStreamW: %w", err)
+		}
+	}
+}
+
+func (t *Table) streamRow(ctx context.Context, path string, s stream) map[string]string {
+	row := map[string]string{
+		"path":            path,
+		"stream":          s.name,
+		"type":            s.streamType,
+		"size":            strconv.FormatInt(s.size, 10),
+		"zone_id":         "",
+		"zone":            "",
+		"referrer_url":    "",
+		"host_url":        "",
+		"zone_identifier": "",
+	}
+
+	if !strings.EqualFold(s.name, zoneIdentifierStream) {
+		return row
+	}
+
+	contents, err := readStream(path, s.name)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read zone identifier",
+			"path", path,
+			"err", err,
+		)
+		return row
+	}
+
+	zi := parseZoneIdentifier(contents)
+	row["zone_id"] = zi.zoneId
+	row["zone"] = zoneName(zi.zoneId)
+	row["referrer_url"] = zi.referrerUrl
+	row["host_url"] = zi.hostUrl
+	row["zone_identifier"] = contents
+
+	return row
+}
+
+// readStream reads the named stream of the file at path.
+func readStream(path string, name string) (string, error) {
+	f, err := os.Open(path + ":" + name)
+	if err != nil {
+		return "", fmt.Errorf("opening stream: %w", err)
+	}
+	defer f.Close()
+
+	contents, err := io.ReadAll(io.LimitReader(f, maxZoneIdentifierSize))
+	if err != nil {
+		return "", fmt.Errorf("reading stream: %w", err)
+	}
+
+	return string(contents), nil
+}
@@ -0,0 +1,96 @@
+// Package ntfsads provides a table listing the NTFS alternate data streams on files, decoding
+// the Zone.Identifier stream Windows attaches to downloaded files -- the "mark of the web" --
+// which records the security zone and the URL a file was downloaded from.
+package ntfsads
+
+import (
+	"bufio"
+	"strings"
+)
+
+const (
+	zoneIdentifierStream = "Zone.Identifier"
+
+	// maxZoneIdentifierSize bounds how much of a Zone.Identifier stream we'll read; they're
+	// normally a few hundred bytes
+	maxZoneIdentifierSize = 64 * 1024
+)
+
+// zoneNames are the URL security zones, see
+// https://learn.microsoft.com/en-us/previous-versions/windows/internet-explorer/ie-developer/platform-apis/ms537183(v=vs.85)
+var zoneNames = map[string]string{
+	"0": "local_machine",
+	"1": "local_intranet",
+	"2": "trusted_sites",
+	"3": "internet",
+	"4": "restricted_sites",
+}
+
+// zoneIdentifier is the decoded contents of a Zone.Identifier stream.
+type zoneIdentifier struct {
+	zoneId      string
+	referrerUrl string
+	hostUrl     string
+}
+
+// parseZoneIdentifier decodes the [ZoneTransfer] section of a Zone.Identifier stream, e.g.
+//
+//	[ZoneTransfer]
+//	ZoneId=3
+//	ReferrerUrl=https://example.com/downloads
+//	HostUrl=https://example.com/downloads/setup.exe
+func parseZoneIdentifier(contents string) zoneIdentifier {
+	var zi zoneIdentifier
+
+	inZoneTransfer := false
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inZoneTransfer = strings.EqualFold(line, "[ZoneTransfer]")
+			continue
+		}
+		if !inZoneTransfer {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "zoneid":
+			zi.zoneId = strings.TrimSpace(value)
+		case "referrerurl":
+			zi.referrerUrl = strings.TrimSpace(value)
+		case "hosturl":
+			zi.hostUrl = strings.TrimSpace(value)
+		}
+	}
+
+	return zi
+}
+
+// zoneName returns the name of the security zone with the given ID.
+func zoneName(zoneId string) string {
+	if name, ok := zoneNames[zoneId]; ok {
+		return name
+	}
+	if zoneId == "" {
+		return ""
+	}
+	return "unknown"
+}
+
+// parseStreamName splits a stream name as returned by FindFirstStreamW, e.g.
+// ":Zone.Identifier:$DATA", into the stream's name and type. The default, unnamed data stream
+// has an empty name.
+func parseStreamName(raw string) (string, string) {
+	raw = strings.TrimPrefix(raw, ":")
+	name, streamType, found := strings.Cut(raw, ":")
+	if !found {
+		return name, ""
+	}
+	return name, streamType
+}
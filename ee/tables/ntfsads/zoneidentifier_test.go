@@ -0,0 +1,60 @@
+package ntfsads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseZoneIdentifier(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		contents string
+		expected zoneIdentifier
+	}{
+		{
+			name:     "browser download",
+			contents: "[ZoneTransfer]\r\nZoneId=3\r\nReferrerUrl=https://example.com/downloads\r\nHostUrl=https://cdn.example.com/setup.exe\r\n",
+			expected: zoneIdentifier{zoneId: "3", referrerUrl: "https://example.com/downloads", hostUrl: "https://cdn.example.com/setup.exe"},
+		},
+		{
+			name:     "zone only, with BOM",
+			contents: "\ufeff[ZoneTransfer]\nZoneId=2\n",
+			expected: zoneIdentifier{zoneId: "2"},
+		},
+		{
+			name:     "keys outside ZoneTransfer are ignored",
+			contents: "[Other]\nZoneId=4\n[zonetransfer]\nzoneid = 3\nHostUrl=about:internet\n",
+			expected: zoneIdentifier{zoneId: "3", hostUrl: "about:internet"},
+		},
+		{
+			name:     "empty",
+			contents: "",
+		},
+	} {
+		require.Equal(t, tt.expected, parseZoneIdentifier(tt.contents), tt.name)
+	}
+}
+
+func TestZoneName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "internet", zoneName("3"))
+	require.Equal(t, "local_machine", zoneName("0"))
+	require.Equal(t, "unknown", zoneName("7"))
+	require.Equal(t, "", zoneName(""))
+}
+
+func TestParseStreamName(t *testing.T) {
+	t.Parallel()
+
+	name, streamType := parseStreamName(":Zone.Identifier:$DATA")
+	require.Equal(t, "Zone.Identifier", name)
+	require.Equal(t, "$DATA", streamType)
+
+	name, streamType = parseStreamName("::$DATA")
+	require.Equal(t, "", name)
+	require.Equal(t, "$DATA", streamType)
+}
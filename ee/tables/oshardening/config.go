@@ -0,0 +1,155 @@
+package oshardening
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// configValue finds key in a simple configuration file: INI-style files with optional
+// [section] headings and key=value lines, or files of whitespace-separated key value lines,
+// such as login.defs. Keys are matched ignoring case, and later values override earlier ones.
+// If sections are given, only keys within them are considered.
+func configValue(data []byte, key string, sections ...string) (string, bool) {
+	var value string
+	found := false
+	currentSection := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			currentSection = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		if len(sections) > 0 && !containsFold(sections, currentSection) {
+			continue
+		}
+
+		var k, v string
+		if i := strings.Index(line, "="); i >= 0 {
+			k, v = line[:i], line[i+1:]
+		} else if fields := strings.Fields(line); len(fields) > 1 {
+			k, v = fields[0], strings.Join(fields[1:], " ")
+		} else {
+			continue
+		}
+
+		if strings.EqualFold(strings.TrimSpace(k), key) {
+			value = strings.Trim(strings.TrimSpace(v), `"'`)
+			found = true
+		}
+	}
+
+	return value, found
+}
+
+// configFileValue looks for key in each of the files at paths within fsys, in order, returning
+// the last value found and the file it was found in. Missing files are skipped.
+func configFileValue(fsys fs.FS, paths []string, key string, sections ...string) (string, string, error) {
+	var value, source string
+	for _, p := range paths {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return "", "", fmt.Errorf("reading %s: %w", p, err)
+		}
+
+		if v, ok := configValue(data, key, sections...); ok {
+			value, source = v, "/"+p
+		}
+	}
+
+	return value, source, nil
+}
+
+// withDropIns returns path followed by the files in dropInDir matching *.conf, as read by
+// programs that support drop-in configuration directories.
+func withDropIns(fsys fs.FS, path string, dropInDir string) []string {
+	paths := []string{path}
+	if matches, err := fs.Glob(fsys, dropInDir+"/*.conf"); err == nil {
+		paths = append(paths, matches...)
+	}
+	return paths
+}
+
+// maxSshdIncludeDepth guards against Include loops
+const maxSshdIncludeDepth = 8
+
+// sshdConfigValue returns the global value of keyword in sshd's configuration within fsys,
+// which is rooted at /, following Include directives. As with sshd, the first value found
+// wins. Settings in Match blocks are conditional, so they're ignored.
+func sshdConfigValue(fsys fs.FS, keyword string) (string, string, bool, error) {
+	data, err := fs.ReadFile(fsys, "etc/ssh/sshd_config")
+	if err != nil {
+		return "", "", false, fmt.Errorf("reading sshd_config: %w", err)
+	}
+
+	return sshdConfigValueIn(fsys, data, "/etc/ssh/sshd_config", keyword, 0)
+}
+
+func sshdConfigValueIn(fsys fs.FS, data []byte, source string, keyword string, depth int) (string, string, bool, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Keywords are separated from their arguments by whitespace or an optional =
+		fields := strings.Fields(strings.Replace(line, "=", " ", 1))
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch {
+		case strings.EqualFold(fields[0], "Match"):
+			// Everything after a Match line, to the end of the file, is conditional
+			return "", "", false, nil
+		case strings.EqualFold(fields[0], "Include") && depth < maxSshdIncludeDepth:
+			for _, pattern := range fields[1:] {
+				if !path.IsAbs(pattern) {
+					pattern = path.Join("/etc/ssh", pattern)
+				}
+				matches, err := fs.Glob(fsys, strings.TrimPrefix(pattern, "/"))
+				if err != nil {
+					continue
+				}
+				for _, match := range matches {
+					included, err := fs.ReadFile(fsys, match)
+					if err != nil {
+						continue
+					}
+					value, includedSource, found, err := sshdConfigValueIn(fsys, included, "/"+match, keyword, depth+1)
+					if err != nil || found {
+						return value, includedSource, found, err
+					}
+				}
+			}
+		case strings.EqualFold(fields[0], keyword):
+			return strings.Trim(fields[1], `"`), source, true, nil
+		}
+	}
+
+	return "", "", false, nil
+}
+
+func containsFold(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if strings.EqualFold(s, needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package oshardening
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValue(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`
+# comment
+PASS_MAX_DAYS	99999
+PASS_MIN_DAYS 0
+; another comment
+ENABLED="yes"
+
+[daemon]
+AutomaticLoginEnable = true
+AutomaticLogin=alice
+
+[Seat:*]
+autologin-user=
+autologin-user=bob
+`)
+
+	for _, tt := range []struct {
+		key           string
+		sections      []string
+		expectedValue string
+		expectedFound bool
+	}{
+		{key: "PASS_MAX_DAYS", expectedValue: "99999", expectedFound: true},
+		{key: "pass_min_days", expectedValue: "0", expectedFound: true},
+		{key: "ENABLED", expectedValue: "yes", expectedFound: true},
+		{key: "AutomaticLoginEnable", sections: []string{"daemon"}, expectedValue: "true", expectedFound: true},
+		{key: "AutomaticLogin", sections: []string{"security"}, expectedFound: false},
+		{key: "autologin-user", sections: []string{"Seat:*", "SeatDefaults"}, expectedValue: "bob", expectedFound: true},
+		{key: "missing", expectedFound: false},
+	} {
+		value, found := configValue(data, tt.key, tt.sections...)
+		require.Equal(t, tt.expectedFound, found, tt.key)
+		require.Equal(t, tt.expectedValue, value, tt.key)
+	}
+}
+
+func TestConfigFileValue(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"etc/security/pwquality.conf":                 {Data: []byte("minlen = 8\n")},
+		"etc/security/pwquality.conf.d/50-cis.conf":   {Data: []byte("minlen = 14\n")},
+		"etc/security/pwquality.conf.d/60-other.conf": {Data: []byte("dcredit = -1\n")},
+	}
+
+	paths := withDropIns(fsys, "etc/security/pwquality.conf", "etc/security/pwquality.conf.d")
+	value, source, err := configFileValue(fsys, paths, "minlen")
+	require.NoError(t, err)
+	require.Equal(t, "14", value)
+	require.Equal(t, "/etc/security/pwquality.conf.d/50-cis.conf", source)
+
+	value, source, err = configFileValue(fsys, []string{"etc/missing.conf"}, "minlen")
+	require.NoError(t, err)
+	require.Empty(t, value)
+	require.Empty(t, source)
+}
+
+func TestSshdConfigValue(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"etc/ssh/sshd_config": {Data: []byte(`Include /etc/ssh/sshd_config.d/*.conf
+PermitRootLogin yes
+PasswordAuthentication=no
+
+Match User backup
+	PermitEmptyPasswords yes
+`)},
+		"etc/ssh/sshd_config.d/10-cis.conf": {Data: []byte("# hardened\npermitrootlogin no\n")},
+		"etc/ssh/sshd_config.d/20-match.conf": {Data: []byte(`Match Address 10.0.0.0/8
+	X11Forwarding yes
+`)},
+		"etc/ssh/sshd_config.d/30-x11.conf": {Data: []byte("X11Forwarding no\n")},
+	}
+
+	for _, tt := range []struct {
+		keyword        string
+		expectedValue  string
+		expectedSource string
+		expectedFound  bool
+	}{
+		// The included file comes first, so wins
+		{keyword: "PermitRootLogin", expectedValue: "no", expectedSource: "/etc/ssh/sshd_config.d/10-cis.conf", expectedFound: true},
+		{keyword: "PasswordAuthentication", expectedValue: "no", expectedSource: "/etc/ssh/sshd_config", expectedFound: true},
+		// Match blocks in included files end with the file
+		{keyword: "X11Forwarding", expectedValue: "no", expectedSource: "/etc/ssh/sshd_config.d/30-x11.conf", expectedFound: true},
+		// Match blocks are conditional
+		{keyword: "PermitEmptyPasswords", expectedFound: false},
+	} {
+		value, source, found, err := sshdConfigValue(fsys, tt.keyword)
+		require.NoError(t, err)
+		require.Equal(t, tt.expectedFound, found, tt.keyword)
+		require.Equal(t, tt.expectedValue, value, tt.keyword)
+		require.Equal(t, tt.expectedSource, source, tt.keyword)
+	}
+
+	_, _, _, err := sshdConfigValue(fstest.MapFS{}, "PermitRootLogin")
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+// Package oshardening provides a table that evaluates a curated set of CIS benchmark-style
+// hardening checks locally -- password policy, firewall, guest account, automatic login,
+// SSH root login, and so on -- emitting a row per check with its status and the evidence
+// it was judged on. This saves running a separate query per setting on every device.
+package oshardening
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_os_hardening"
+
+// Check statuses
+const (
+	statusPass    = "pass"
+	statusFail    = "fail"
+	statusUnknown = "unknown" // the check could not be evaluated
+)
+
+// Password policy thresholds we consider hardened, across platforms
+const (
+	minPasswordLength   = 14
+	maxPasswordAgeDays  = 365
+	maxLockoutThreshold = 5 // failed logins before an account is locked
+)
+
+var columns = []table.ColumnDefinition{
+	table.TextColumn("check_name"),
+	table.TextColumn("category"),
+	table.TextColumn("description"),
+	table.TextColumn("status"),
+	table.TextColumn("evidence"),
+	table.TextColumn("error"),
+}
+
+// check is a single hardening check. evaluate reports whether the check passes, and the
+// evidence it was judged on; an error means the check could not be evaluated.
+type check struct {
+	name        string
+	category    string
+	description string // what passing means
+	evaluate    func(ctx context.Context) (bool, string, error)
+}
+
+// runChecks evaluates each check, returning a row for each.
+func runChecks(ctx context.Context, slogger *slog.Logger, checks []check) []map[string]string {
+	results := make([]map[string]string, 0, len(checks))
+
+	for _, c := range checks {
+		row := map[string]string{
+			"check_name":  c.name,
+			"category":    c.category,
+			"description": c.description,
+			"status":      statusFail,
+			"evidence":    "",
+			"error":       "",
+		}
+
+		passed, evidence, err := c.evaluate(ctx)
+		row["evidence"] = evidence
+		switch {
+		case err != nil:
+			slogger.Log(ctx, slog.LevelDebug,
+				"could not evaluate hardening check",
+				"check_name", c.name,
+				"err", err,
+			)
+			row["status"] = statusUnknown
+			row["error"] = err.Error()
+		case passed:
+			row["status"] = statusPass
+		}
+
+		results = append(results, row)
+	}
+
+	return results
+}
+
+// atLeast reports whether the integer value is at least min.
+func atLeast(value string, min int) (bool, error) {
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("parsing %q as an integer: %w", value, err)
+	}
+	return i >= min, nil
+}
+
+// inRange reports whether the integer value is between min and max, inclusive.
+func inRange(value string, min, max int) (bool, error) {
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("parsing %q as an integer: %w", value, err)
+	}
+	return i >= min && i <= max, nil
+}
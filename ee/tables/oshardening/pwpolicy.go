@@ -0,0 +1,77 @@
+package oshardening
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"howett.net/plist"
+)
+
+// minLengthPattern matches the content of a pwpolicy minimum length policy, e.g.
+// policyAttributePassword matches '.{8,}+'
+var minLengthPattern = regexp.MustCompile(`policyAttributePassword\s+matches\s+'\.\{(\d+),`)
+
+type accountPolicy struct {
+	Content    string         `plist:"policyContent"`
+	Identifier string         `plist:"policyIdentifier"`
+	Parameters map[string]any `plist:"policyParameters"`
+}
+
+// accountPolicies are the policies reported by `pwpolicy getaccountpolicies`
+type accountPolicies struct {
+	Authentication  []accountPolicy `plist:"policyCategoryAuthentication"`
+	PasswordContent []accountPolicy `plist:"policyCategoryPasswordContent"`
+}
+
+// parseAccountPolicies parses the output of `pwpolicy getaccountpolicies`, which precedes
+// the plist with a line of text.
+func parseAccountPolicies(output []byte) (accountPolicies, error) {
+	var policies accountPolicies
+
+	if i := bytes.Index(output, []byte("<?xml")); i > 0 {
+		output = output[i:]
+	}
+	if _, err := plist.Unmarshal(output, &policies); err != nil {
+		return policies, fmt.Errorf("unmarshalling account policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// minLength returns the minimum password length the policies require. Every policy applies,
+// so it's the longest minimum of any of them.
+func (p accountPolicies) minLength() (int, bool) {
+	minLength, found := 0, false
+	for _, policy := range p.PasswordContent {
+		matches := minLengthPattern.FindStringSubmatch(policy.Content)
+		if matches == nil {
+			continue
+		}
+		if length, err := strconv.Atoi(matches[1]); err == nil && length > minLength {
+			minLength, found = length, true
+		}
+	}
+	return minLength, found
+}
+
+// maxFailedAuthentications returns the number of failed logins after which the policies lock
+// an account. Every policy applies, so it's the fewest of any of them.
+func (p accountPolicies) maxFailedAuthentications() (int, bool) {
+	maxFailed, found := 0, false
+	for _, policy := range p.Authentication {
+		raw, ok := policy.Parameters["policyAttributeMaximumFailedAuthentications"]
+		if !ok {
+			continue
+		}
+		failed, err := strconv.Atoi(fmt.Sprintf("%v", raw))
+		if err != nil || failed <= 0 {
+			continue
+		}
+		if !found || failed < maxFailed {
+			maxFailed, found = failed, true
+		}
+	}
+	return maxFailed, found
+}
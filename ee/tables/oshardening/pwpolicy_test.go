@@ -0,0 +1,57 @@
+package oshardening
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountPolicies(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		file              string
+		expectedMinLength int
+		expectedMaxFailed int
+		expectMaxFailed   bool
+	}{
+		{
+			file:              "pwpolicy_default.output",
+			expectedMinLength: 4,
+		},
+		{
+			file:              "pwpolicy_profile.output",
+			expectedMinLength: 8,
+			expectedMaxFailed: 5,
+			expectMaxFailed:   true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.file, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			require.NoError(t, err)
+
+			policies, err := parseAccountPolicies(output)
+			require.NoError(t, err)
+
+			minLength, found := policies.minLength()
+			require.True(t, found)
+			require.Equal(t, tt.expectedMinLength, minLength)
+
+			maxFailed, found := policies.maxFailedAuthentications()
+			require.Equal(t, tt.expectMaxFailed, found)
+			require.Equal(t, tt.expectedMaxFailed, maxFailed)
+		})
+	}
+}
+
+func TestParseAccountPolicies_Malformed(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseAccountPolicies([]byte("Getting global account policies\nnot a plist"))
+	require.Error(t, err)
+}
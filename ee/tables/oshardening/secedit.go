@@ -0,0 +1,94 @@
+package oshardening
+
+import (
+	"context"
+	"fmt"
+)
+
+// seceditSetting is a setting in the [System Access] section of a secedit export, and the
+// range of values we consider hardened.
+type seceditSetting struct {
+	name        string
+	category    string
+	description string
+	key         string
+	min, max    int
+}
+
+var seceditSettings = []seceditSetting{
+	{
+		name:        "password_min_length",
+		category:    "password_policy",
+		description: fmt.Sprintf("password policy requires passwords of at least %d characters", minPasswordLength),
+		key:         "MinimumPasswordLength",
+		min:         minPasswordLength,
+		max:         128,
+	},
+	{
+		name:        "password_complexity_enabled",
+		category:    "password_policy",
+		description: "password policy requires complex passwords",
+		key:         "PasswordComplexity",
+		min:         1,
+		max:         1,
+	},
+	{
+		name:        "password_max_age",
+		category:    "password_policy",
+		description: fmt.Sprintf("passwords expire within %d days", maxPasswordAgeDays),
+		key:         "MaximumPasswordAge",
+		min:         1,
+		max:         maxPasswordAgeDays,
+	},
+	{
+		name:        "account_lockout_enabled",
+		category:    "password_policy",
+		description: fmt.Sprintf("accounts are locked after at most %d failed logins", maxLockoutThreshold),
+		key:         "LockoutBadCount",
+		min:         1,
+		max:         maxLockoutThreshold,
+	},
+	{
+		name:        "guest_account_disabled",
+		category:    "authentication",
+		description: "the Guest account is disabled",
+		key:         "EnableGuestAccount",
+		min:         0,
+		max:         0,
+	},
+	{
+		name:        "reversible_password_encryption_disabled",
+		category:    "password_policy",
+		description: "passwords are not stored using reversible encryption",
+		key:         "ClearTextPassword",
+		min:         0,
+		max:         0,
+	},
+}
+
+// seceditChecks returns the checks of the local security policy. exportPolicy returns the policy
+// as exported by secedit, decoded to UTF-8.
+func seceditChecks(exportPolicy func(context.Context) ([]byte, error)) []check {
+	checks := make([]check, 0, len(seceditSettings))
+	for _, s := range seceditSettings {
+		s := s
+		checks = append(checks, check{
+			name:        s.name,
+			category:    s.category,
+			description: s.description,
+			evaluate: func(ctx context.Context) (bool, string, error) {
+				policy, err := exportPolicy(ctx)
+				if err != nil {
+					return false, "", err
+				}
+				value, found := configValue(policy, s.key, "System Access")
+				if !found {
+					return false, s.key + " is not set", nil
+				}
+				passed, err := inRange(value, s.min, s.max)
+				return passed, fmt.Sprintf("%s = %s", s.key, value), err
+			},
+		})
+	}
+	return checks
+}
@@ -0,0 +1,56 @@
+package oshardening
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeceditChecks(t *testing.T) {
+	t.Parallel()
+
+	policy, err := os.ReadFile(filepath.Join("testdata", "secedit.ini"))
+	require.NoError(t, err)
+
+	exports := 0
+	checks := seceditChecks(func(context.Context) ([]byte, error) {
+		exports += 1
+		return policy, nil
+	})
+
+	rows := runChecks(context.TODO(), multislogger.NewNopLogger(), checks)
+	require.Len(t, rows, len(seceditSettings))
+	require.Equal(t, len(seceditSettings), exports)
+
+	expected := map[string]string{
+		"password_min_length":                     statusFail,
+		"password_complexity_enabled":             statusPass,
+		"password_max_age":                        statusPass,
+		"account_lockout_enabled":                 statusFail,
+		"guest_account_disabled":                  statusPass,
+		"reversible_password_encryption_disabled": statusPass,
+	}
+	for _, row := range rows {
+		require.Equal(t, expected[row["check_name"]], row["status"], row["check_name"])
+		require.NotEmpty(t, row["evidence"], row["check_name"])
+		require.Empty(t, row["error"], row["check_name"])
+	}
+}
+
+func TestSeceditChecks_ExportFails(t *testing.T) {
+	t.Parallel()
+
+	checks := seceditChecks(func(context.Context) ([]byte, error) {
+		return nil, errors.New("secedit failed")
+	})
+
+	for _, row := range runChecks(context.TODO(), multislogger.NewNopLogger(), checks) {
+		require.Equal(t, statusUnknown, row["status"])
+		require.Equal(t, "secedit failed", row["error"])
+	}
+}
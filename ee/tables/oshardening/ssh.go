@@ -0,0 +1,49 @@
+package oshardening
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// sshChecks are the checks of sshd's configuration, shared by macOS and Linux. fsys is rooted at /.
+func sshChecks(fsys fs.FS) []check {
+	return []check{
+		{
+			name:        "ssh_root_login_disabled",
+			category:    "remote_access",
+			description: "sshd does not allow root to log in",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				value, source, found, err := sshdConfigValue(fsys, "PermitRootLogin")
+				switch {
+				case errors.Is(err, fs.ErrNotExist):
+					return true, "sshd is not configured", nil
+				case err != nil:
+					return false, "", err
+				case !found:
+					return false, "PermitRootLogin is not set, so sshd defaults to prohibit-password", nil
+				}
+				return strings.EqualFold(value, "no"), fmt.Sprintf("PermitRootLogin %s in %s", value, source), nil
+			},
+		},
+		{
+			name:        "ssh_empty_passwords_disabled",
+			category:    "remote_access",
+			description: "sshd does not allow logging in to accounts with empty passwords",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				value, source, found, err := sshdConfigValue(fsys, "PermitEmptyPasswords")
+				switch {
+				case errors.Is(err, fs.ErrNotExist):
+					return true, "sshd is not configured", nil
+				case err != nil:
+					return false, "", err
+				case !found:
+					return true, "PermitEmptyPasswords is not set, so sshd defaults to no", nil
+				}
+				return strings.EqualFold(value, "no"), fmt.Sprintf("PermitEmptyPasswords %s in %s", value, source), nil
+			},
+		},
+	}
+}
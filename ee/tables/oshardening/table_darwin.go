@@ -0,0 +1,217 @@
+//go:build darwin
+// +build darwin
+
+package oshardening
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"howett.net/plist"
+)
+
+type Table struct {
+	slogger *slog.Logger
+	checks  []check
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+	t.checks = t.darwinChecks()
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return runChecks(ctx, t.slogger, t.checks), nil
+}
+
+func (t *Table) darwinChecks() []check {
+	checks := []check{
+		{
+			name:        "firewall_enabled",
+			category:    "network",
+			description: "the application firewall is enabled",
+			evaluate: func(ctx context.Context) (bool, string, error) {
+				output, err := t.run(ctx, allowedcmd.Socketfilterfw, "--getglobalstate")
+				if err != nil {
+					return false, "", err
+				}
+				// e.g. "Firewall is enabled. (State = 1)"
+				return strings.Contains(output, "enabled"), output, nil
+			},
+		},
+		{
+			name:        "firewall_stealth_mode_enabled",
+			category:    "network",
+			description: "the application firewall does not respond to probes",
+			evaluate: func(ctx context.Context) (bool, string, error) {
+				output, err := t.run(ctx, allowedcmd.Socketfilterfw, "--getstealthmode")
+				if err != nil {
+					return false, "", err
+				}
+				// Older versions say "Stealth mode enabled", newer "Firewall stealth mode is on"
+				return strings.Contains(output, "enabled") || strings.HasSuffix(output, " on"), output, nil
+			},
+		},
+		{
+			name:        "guest_account_disabled",
+			category:    "authentication",
+			description: "the guest account is disabled",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				value, source, err := preferenceValue("com.apple.loginwindow", "GuestEnabled")
+				if err != nil {
+					return false, "", err
+				}
+				if source == "" {
+					return true, "GuestEnabled is not set", nil
+				}
+				return value != true, fmt.Sprintf("GuestEnabled = %v in %s", value, source), nil
+			},
+		},
+		{
+			name:        "auto_login_disabled",
+			category:    "authentication",
+			description: "no user is logged in automatically",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				value, source, err := preferenceValue("com.apple.loginwindow", "autoLoginUser")
+				if err != nil {
+					return false, "", err
+				}
+				if source == "" {
+					return true, "autoLoginUser is not set", nil
+				}
+				return false, fmt.Sprintf("autoLoginUser = %v in %s", value, source), nil
+			},
+		},
+		{
+			name:        "automatic_update_check_enabled",
+			category:    "updates",
+			description: "macOS automatically checks for software updates",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				value, source, err := preferenceValue("com.apple.SoftwareUpdate", "AutomaticCheckEnabled")
+				if err != nil {
+					return false, "", err
+				}
+				if source == "" {
+					return true, "AutomaticCheckEnabled is not set, so macOS defaults to checking", nil
+				}
+				return value == true, fmt.Sprintf("AutomaticCheckEnabled = %v in %s", value, source), nil
+			},
+		},
+		{
+			name:        "sip_enabled",
+			category:    "system_integrity",
+			description: "System Integrity Protection is enabled",
+			evaluate: func(ctx context.Context) (bool, string, error) {
+				output, err := t.run(ctx, allowedcmd.Csrutil, "status")
+				if err != nil {
+					return false, "", err
+				}
+				// e.g. "System Integrity Protection status: enabled."
+				return strings.Contains(output, "status: enabled"), output, nil
+			},
+		},
+		{
+			name:        "filevault_enabled",
+			category:    "encryption",
+			description: "FileVault disk encryption is on",
+			evaluate: func(ctx context.Context) (bool, string, error) {
+				output, err := t.run(ctx, allowedcmd.Fdesetup, "status")
+				if err != nil {
+					return false, "", err
+				}
+				return strings.HasPrefix(output, "FileVault is On"), output, nil
+			},
+		},
+		{
+			name:        "password_min_length",
+			category:    "password_policy",
+			description: fmt.Sprintf("account policy requires passwords of at least %d characters", minPasswordLength),
+			evaluate: func(ctx context.Context) (bool, string, error) {
+				policies, err := t.accountPolicies(ctx)
+				if err != nil {
+					return false, "", err
+				}
+				minLength, found := policies.minLength()
+				if !found {
+					return false, "no minimum password length policy", nil
+				}
+				return minLength >= minPasswordLength, fmt.Sprintf("minimum password length %d", minLength), nil
+			},
+		},
+		{
+			name:        "account_lockout_enabled",
+			category:    "password_policy",
+			description: fmt.Sprintf("account policy locks accounts after at most %d failed logins", maxLockoutThreshold),
+			evaluate: func(ctx context.Context) (bool, string, error) {
+				policies, err := t.accountPolicies(ctx)
+				if err != nil {
+					return false, "", err
+				}
+				maxFailed, found := policies.maxFailedAuthentications()
+				if !found {
+					return false, "no account lockout policy", nil
+				}
+				return maxFailed <= maxLockoutThreshold, fmt.Sprintf("accounts lock after %d failed logins", maxFailed), nil
+			},
+		},
+	}
+
+	return append(checks, sshChecks(os.DirFS("/"))...)
+}
+
+// run runs cmd, returning its trimmed output.
+func (t *Table) run(ctx context.Context, cmd allowedcmd.AllowedCommand, args ...string) (string, error) {
+	output, err := tablehelpers.RunSimple(ctx, t.slogger, 10, cmd, args)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (t *Table) accountPolicies(ctx context.Context) (accountPolicies, error) {
+	output, err := tablehelpers.RunSimple(ctx, t.slogger, 30, allowedcmd.Pwpolicy, []string{"getaccountpolicies"})
+	if err != nil {
+		return accountPolicies{}, err
+	}
+	return parseAccountPolicies(output)
+}
+
+// preferenceValue reads a system-wide preference, preferring any value set by an MDM
+// profile. It returns the path the value was read from, or an empty path if it isn't set.
+func preferenceValue(domain, key string) (any, string, error) {
+	for _, dir := range []string{"/Library/Managed Preferences", "/Library/Preferences"} {
+		path := filepath.Join(dir, domain+".plist")
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, "", fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		var prefs map[string]any
+		if _, err := plist.Unmarshal(raw, &prefs); err != nil {
+			return nil, "", fmt.Errorf("unmarshalling %s: %w", path, err)
+		}
+
+		if value, ok := prefs[key]; ok {
+			return value, path, nil
+		}
+	}
+
+	return nil, "", nil
+}
@@ -0,0 +1,197 @@
+//go:build linux
+// +build linux
+
+package oshardening
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// firewallUnits are the services that manage a host firewall
+var firewallUnits = []string{"firewalld.service", "nftables.service", "ufw.service"}
+
+type Table struct {
+	slogger *slog.Logger
+	checks  []check
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+	t.checks = linuxChecks(os.DirFS("/"), t.activeUnits)
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return runChecks(ctx, t.slogger, t.checks), nil
+}
+
+// activeUnits returns the active state of each of the given systemd units.
+func (t *Table) activeUnits(ctx context.Context, units []string) (map[string]string, error) {
+	var stdout, stderr bytes.Buffer
+	// is-active exits non-zero unless every unit is active, so its output matters more than its exit code
+	err := tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.Systemctl, append([]string{"is-active"}, units...), &stdout, &stderr)
+	if err != nil && stdout.Len() == 0 {
+		return nil, fmt.Errorf("running systemctl is-active: %s: %w", stderr.String(), err)
+	}
+
+	states := make(map[string]string, len(units))
+	scanner := bufio.NewScanner(&stdout)
+	for i := 0; scanner.Scan() && i < len(units); i++ {
+		states[units[i]] = strings.TrimSpace(scanner.Text())
+	}
+	return states, nil
+}
+
+// linuxChecks returns the checks for Linux. fsys is rooted at /, and activeUnits reports the
+// state of systemd units.
+func linuxChecks(fsys fs.FS, activeUnits func(context.Context, []string) (map[string]string, error)) []check {
+	checks := []check{
+		{
+			name:        "password_max_age",
+			category:    "password_policy",
+			description: fmt.Sprintf("new passwords expire within %d days", maxPasswordAgeDays),
+			evaluate: func(_ context.Context) (bool, string, error) {
+				value, source, err := configFileValue(fsys, []string{"etc/login.defs"}, "PASS_MAX_DAYS")
+				if err != nil {
+					return false, "", err
+				}
+				if source == "" {
+					return false, "PASS_MAX_DAYS is not set", nil
+				}
+				passed, err := inRange(value, 1, maxPasswordAgeDays)
+				return passed, fmt.Sprintf("PASS_MAX_DAYS %s in %s", value, source), err
+			},
+		},
+		{
+			name:        "password_min_length",
+			category:    "password_policy",
+			description: fmt.Sprintf("pam_pwquality requires passwords of at least %d characters", minPasswordLength),
+			evaluate: func(_ context.Context) (bool, string, error) {
+				paths := withDropIns(fsys, "etc/security/pwquality.conf", "etc/security/pwquality.conf.d")
+				value, source, err := configFileValue(fsys, paths, "minlen")
+				if err != nil {
+					return false, "", err
+				}
+				if source == "" {
+					return false, "minlen is not set in pwquality.conf", nil
+				}
+				passed, err := atLeast(value, minPasswordLength)
+				return passed, fmt.Sprintf("minlen = %s in %s", value, source), err
+			},
+		},
+		{
+			name:        "firewall_enabled",
+			category:    "network",
+			description: "a host firewall is enabled",
+			evaluate: func(ctx context.Context) (bool, string, error) {
+				if value, source, err := configFileValue(fsys, []string{"etc/ufw/ufw.conf"}, "ENABLED"); err == nil && strings.EqualFold(value, "yes") {
+					return true, fmt.Sprintf("ENABLED=yes in %s", source), nil
+				}
+
+				states, err := activeUnits(ctx, firewallUnits)
+				if err != nil {
+					return false, "", err
+				}
+				var evidence []string
+				for _, unit := range firewallUnits {
+					if states[unit] == "active" {
+						return true, unit + " is active", nil
+					}
+					evidence = append(evidence, fmt.Sprintf("%s is %s", unit, states[unit]))
+				}
+				return false, strings.Join(evidence, ", "), nil
+			},
+		},
+		{
+			name:        "auto_login_disabled",
+			category:    "authentication",
+			description: "the display manager does not log a user in automatically",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				for _, setting := range autoLoginSettings(fsys) {
+					value, source, err := configFileValue(fsys, setting.paths, setting.key, setting.sections...)
+					if err != nil {
+						return false, "", err
+					}
+					if setting.enabled(value) {
+						return false, fmt.Sprintf("%s = %s in %s", setting.key, value, source), nil
+					}
+				}
+				return true, "no display manager is configured to log in automatically", nil
+			},
+		},
+		{
+			name:        "guest_account_disabled",
+			category:    "authentication",
+			description: "the display manager does not offer a guest session",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				paths := withDropIns(fsys, "etc/lightdm/lightdm.conf", "etc/lightdm/lightdm.conf.d")
+				value, source, err := configFileValue(fsys, paths, "allow-guest", lightdmSections...)
+				if err != nil {
+					return false, "", err
+				}
+				if strings.EqualFold(value, "true") {
+					return false, fmt.Sprintf("allow-guest = %s in %s", value, source), nil
+				}
+				return true, "no display manager is configured to allow guest sessions", nil
+			},
+		},
+		sysctlCheck(fsys, "aslr_enabled", "kernel", "address space layout randomization is fully enabled", "kernel/randomize_va_space", "2"),
+		sysctlCheck(fsys, "suid_core_dumps_disabled", "kernel", "setuid programs do not dump core", "fs/suid_dumpable", "0"),
+		sysctlCheck(fsys, "ip_forwarding_disabled", "network", "the host does not forward IPv4 packets", "net/ipv4/ip_forward", "0"),
+	}
+
+	return append(checks, sshChecks(fsys)...)
+}
+
+var lightdmSections = []string{"Seat:*", "SeatDefaults"}
+
+type autoLoginSetting struct {
+	paths    []string
+	sections []string
+	key      string
+	enabled  func(value string) bool
+}
+
+// autoLoginSettings are the automatic login settings of GDM, LightDM, and SDDM
+func autoLoginSettings(fsys fs.FS) []autoLoginSetting {
+	isTrue := func(value string) bool { return strings.EqualFold(value, "true") }
+	isSet := func(value string) bool { return value != "" }
+
+	return []autoLoginSetting{
+		{paths: []string{"etc/gdm3/custom.conf", "etc/gdm/custom.conf"}, sections: []string{"daemon"}, key: "AutomaticLoginEnable", enabled: isTrue},
+		{paths: []string{"etc/gdm3/custom.conf", "etc/gdm/custom.conf"}, sections: []string{"daemon"}, key: "TimedLoginEnable", enabled: isTrue},
+		{paths: withDropIns(fsys, "etc/lightdm/lightdm.conf", "etc/lightdm/lightdm.conf.d"), sections: lightdmSections, key: "autologin-user", enabled: isSet},
+		{paths: withDropIns(fsys, "etc/sddm.conf", "etc/sddm.conf.d"), sections: []string{"Autologin"}, key: "User", enabled: isSet},
+	}
+}
+
+// sysctlCheck checks that the kernel parameter at path, under /proc/sys, has the wanted value
+func sysctlCheck(fsys fs.FS, name, category, description, path, want string) check {
+	return check{
+		name:        name,
+		category:    category,
+		description: description,
+		evaluate: func(_ context.Context) (bool, string, error) {
+			data, err := fs.ReadFile(fsys, "proc/sys/"+path)
+			if err != nil {
+				return false, "", fmt.Errorf("reading %s: %w", path, err)
+			}
+			value := strings.TrimSpace(string(data))
+			return value == want, fmt.Sprintf("%s = %s", strings.ReplaceAll(path, "/", "."), value), nil
+		},
+	}
+}
@@ -0,0 +1,119 @@
+//go:build linux
+// +build linux
+
+package oshardening
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinuxChecks(t *testing.T) {
+	t.Parallel()
+
+	hardened := fstest.MapFS{
+		"etc/login.defs":                     {Data: []byte("PASS_MAX_DAYS 365\nPASS_MIN_DAYS 1\n")},
+		"etc/security/pwquality.conf":        {Data: []byte("minlen = 14\n")},
+		"etc/gdm3/custom.conf":               {Data: []byte("[daemon]\nAutomaticLoginEnable=false\n")},
+		"etc/ssh/sshd_config":                {Data: []byte("PermitRootLogin no\n")},
+		"proc/sys/kernel/randomize_va_space": {Data: []byte("2\n")},
+		"proc/sys/fs/suid_dumpable":          {Data: []byte("0\n")},
+		"proc/sys/net/ipv4/ip_forward":       {Data: []byte("0\n")},
+	}
+
+	weak := fstest.MapFS{
+		"etc/login.defs":                          {Data: []byte("PASS_MAX_DAYS 99999\n")},
+		"etc/lightdm/lightdm.conf":                {Data: []byte("[Seat:*]\nallow-guest=true\n")},
+		"etc/lightdm/lightdm.conf.d/50-auto.conf": {Data: []byte("[Seat:*]\nautologin-user=alice\n")},
+		"etc/ssh/sshd_config":                     {Data: []byte("PermitEmptyPasswords yes\n")},
+		"proc/sys/kernel/randomize_va_space":      {Data: []byte("1\n")},
+		"proc/sys/fs/suid_dumpable":               {Data: []byte("2\n")},
+		"proc/sys/net/ipv4/ip_forward":            {Data: []byte("1\n")},
+	}
+
+	for _, tt := range []struct {
+		name             string
+		fsys             fstest.MapFS
+		unitState        string
+		expectedStatuses map[string]string
+	}{
+		{
+			name:      "hardened",
+			fsys:      hardened,
+			unitState: "active",
+			expectedStatuses: map[string]string{
+				"password_max_age":             statusPass,
+				"password_min_length":          statusPass,
+				"firewall_enabled":             statusPass,
+				"auto_login_disabled":          statusPass,
+				"guest_account_disabled":       statusPass,
+				"aslr_enabled":                 statusPass,
+				"suid_core_dumps_disabled":     statusPass,
+				"ip_forwarding_disabled":       statusPass,
+				"ssh_root_login_disabled":      statusPass,
+				"ssh_empty_passwords_disabled": statusPass,
+			},
+		},
+		{
+			name:      "weak",
+			fsys:      weak,
+			unitState: "inactive",
+			expectedStatuses: map[string]string{
+				"password_max_age":             statusFail,
+				"password_min_length":          statusFail,
+				"firewall_enabled":             statusFail,
+				"auto_login_disabled":          statusFail,
+				"guest_account_disabled":       statusFail,
+				"aslr_enabled":                 statusFail,
+				"suid_core_dumps_disabled":     statusFail,
+				"ip_forwarding_disabled":       statusFail,
+				"ssh_root_login_disabled":      statusFail,
+				"ssh_empty_passwords_disabled": statusFail,
+			},
+		},
+		{
+			name: "nothing configured",
+			fsys: fstest.MapFS{},
+			expectedStatuses: map[string]string{
+				"password_max_age":             statusFail,
+				"password_min_length":          statusFail,
+				"firewall_enabled":             statusUnknown,
+				"auto_login_disabled":          statusPass,
+				"guest_account_disabled":       statusPass,
+				"aslr_enabled":                 statusUnknown,
+				"suid_core_dumps_disabled":     statusUnknown,
+				"ip_forwarding_disabled":       statusUnknown,
+				"ssh_root_login_disabled":      statusPass,
+				"ssh_empty_passwords_disabled": statusPass,
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			activeUnits := func(_ context.Context, units []string) (map[string]string, error) {
+				if tt.unitState == "" {
+					return nil, context.DeadlineExceeded
+				}
+				states := make(map[string]string)
+				for _, unit := range units {
+					states[unit] = tt.unitState
+				}
+				return states, nil
+			}
+
+			rows := runChecks(context.TODO(), multislogger.NewNopLogger(), linuxChecks(tt.fsys, activeUnits))
+			require.Len(t, rows, len(tt.expectedStatuses))
+			for _, row := range rows {
+				expected, ok := tt.expectedStatuses[row["check_name"]]
+				require.True(t, ok, "unexpected check %s", row["check_name"])
+				require.Equal(t, expected, row["status"], "%s: %s %s", row["check_name"], row["evidence"], row["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+//go:build windows
+// +build windows
+
+package oshardening
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+const (
+	firewallPolicyKey      = `SOFTWARE\Policies\Microsoft\WindowsFirewall`
+	firewallLocalPolicyKey = `SYSTEM\CurrentControlSet\Services\SharedAccess\Parameters\FirewallPolicy`
+	winlogonKey            = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\Winlogon`
+	systemPolicyKey        = `SOFTWARE\Microsoft\Windows\CurrentVersion\Policies\System`
+	lanmanServerKey        = `SYSTEM\CurrentControlSet\Services\LanmanServer\Parameters`
+	smb1ClientServiceKey   = `SYSTEM\CurrentControlSet\Services\mrxsmb10`
+)
+
+// firewallProfile is a Windows Firewall profile, which is named differently in group policy
+// than in the local firewall configuration
+type firewallProfile struct {
+	name        string
+	policyName  string
+	settingName string
+}
+
+var firewallProfiles = []firewallProfile{
+	{name: "domain", policyName: "DomainProfile", settingName: "DomainProfile"},
+	{name: "private", policyName: "PrivateProfile", settingName: "StandardProfile"},
+	{name: "public", policyName: "PublicProfile", settingName: "PublicProfile"},
+}
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	// Several checks read the security policy, so only export it once per query
+	var once sync.Once
+	var policy []byte
+	var policyErr error
+	exportPolicy := func(ctx context.Context) ([]byte, error) {
+		once.Do(func() {
+			policy, policyErr = t.exportSecurityPolicy(ctx)
+		})
+		return policy, policyErr
+	}
+
+	return runChecks(ctx, t.slogger, t.windowsChecks(exportPolicy)), nil
+}
+
+func (t *Table) windowsChecks(exportPolicy func(context.Context) ([]byte, error)) []check {
+	checks := make([]check, 0)
+	for _, profile := range firewallProfiles {
+		profile := profile
+		checks = append(checks, check{
+			name:        fmt.Sprintf("firewall_%s_profile_enabled", profile.name),
+			category:    "network",
+			description: fmt.Sprintf("Windows Firewall is enabled for the %s profile", profile.name),
+			evaluate: func(_ context.Context) (bool, string, error) {
+				return firewallEnabled(profile)
+			},
+		})
+	}
+
+	checks = append(checks, seceditChecks(exportPolicy)...)
+
+	return append(checks,
+		check{
+			name:        "auto_login_disabled",
+			category:    "authentication",
+			description: "no user is logged in automatically",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				value, found, err := registryString(winlogonKey, "AutoAdminLogon")
+				if err != nil {
+					return false, "", err
+				}
+				if !found {
+					return true, "AutoAdminLogon is not set", nil
+				}
+				return value != "1", fmt.Sprintf(`AutoAdminLogon = %s in HKEY_LOCAL_MACHINE\%s`, value, winlogonKey), nil
+			},
+		},
+		check{
+			name:        "uac_enabled",
+			category:    "system_integrity",
+			description: "User Account Control is enabled",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				value, found, err := registryInteger(systemPolicyKey, "EnableLUA")
+				if err != nil {
+					return false, "", err
+				}
+				if !found {
+					return true, "EnableLUA is not set, so Windows defaults to enabling UAC", nil
+				}
+				return value == 1, fmt.Sprintf(`EnableLUA = %d in HKEY_LOCAL_MACHINE\%s`, value, systemPolicyKey), nil
+			},
+		},
+		check{
+			name:        "smb1_disabled",
+			category:    "network",
+			description: "the SMBv1 server and client are disabled",
+			evaluate: func(_ context.Context) (bool, string, error) {
+				return smb1Disabled()
+			},
+		},
+	)
+}
+
+// firewallEnabled reports whether the firewall is enabled for the profile. Group policy takes
+// precedence over the local setting.
+func firewallEnabled(profile firewallProfile) (bool, string, error) {
+	for _, key := range []string{
+		firewallPolicyKey + `\` + profile.policyName,
+		firewallLocalPolicyKey + `\` + profile.settingName,
+	} {
+		value, found, err := registryInteger(key, "EnableFirewall")
+		if err != nil {
+			return false, "", err
+		}
+		if found {
+			return value == 1, fmt.Sprintf(`EnableFirewall = %d in HKEY_LOCAL_MACHINE\%s`, value, key), nil
+		}
+	}
+
+	return false, "EnableFirewall is not set", nil
+}
+
+// smb1Disabled reports whether both the SMBv1 server and client are disabled. Recent versions
+// of Windows don't install the SMBv1 client at all.
+func smb1Disabled() (bool, string, error) {
+	server, serverFound, err := registryInteger(lanmanServerKey, "SMB1")
+	if err != nil {
+		return false, "", err
+	}
+	serverDisabled := serverFound && server == 0
+	evidence := []string{"SMB1 is not set for the server"}
+	if serverFound {
+		evidence = []string{fmt.Sprintf(`SMB1 = %d in HKEY_LOCAL_MACHINE\%s`, server, lanmanServerKey)}
+	}
+
+	clientStart, clientFound, err := registryInteger(smb1ClientServiceKey, "Start")
+	if err != nil {
+		return false, "", err
+	}
+	// A Start value of 4 means the service is disabled
+	clientDisabled := !clientFound || clientStart == 4
+	if clientFound {
+		evidence = append(evidence, fmt.Sprintf(`Start = %d in HKEY_LOCAL_MACHINE\%s`, clientStart, smb1ClientServiceKey))
+	} else {
+		evidence = append(evidence, "the SMBv1 client is not installed")
+	}
+
+	return serverDisabled && clientDisabled, strings.Join(evidence, ", "), nil
+}
+
+// registryInteger reads a DWORD value under HKEY_LOCAL_MACHINE. Missing keys and values aren't
+// errors -- they're reported as not found.
+func registryInteger(keyPath, name string) (uint64, bool, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("opening %s: %w", keyPath, err)
+	}
+	defer key.Close()
+
+	val, _, err := key.GetIntegerValue(name)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	return val, true, nil
+}
+
+// registryString reads a string value under HKEY_LOCAL_MACHINE, as registryInteger does.
+func registryString(keyPath, name string) (string, bool, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("opening %s: %w", keyPath, err)
+	}
+	defer key.Close()
+
+	val, _, err := key.GetStringValue(name)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	return val, true, nil
+}
+
+// exportSecurityPolicy exports the effective security policy with secedit, which can only
+// write it to a file, UTF-16 encoded.
+func (t *Table) exportSecurityPolicy(ctx context.Context) ([]byte, error) {
+	dir, err := agent.MkdirTemp("kolide_os_hardening")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "secpol.ini")
+
+	var out bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 30, allowedcmd.Secedit, []string{"/export", "/cfg", dst, "/mergedpolicy"}, &out, &out); err != nil {
+		return nil, fmt.Errorf("calling secedit. Got: %s: %w", out.String(), err)
+	}
+
+	file, err := os.Open(dst)
+	if err != nil {
+		return nil, fmt.Errorf("opening secedit output: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(transform.NewReader(file, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()))
+	if err != nil {
+		return nil, fmt.Errorf("reading secedit output: %w", err)
+	}
+
+	return data, nil
+}
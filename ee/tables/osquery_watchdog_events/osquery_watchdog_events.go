@@ -0,0 +1,41 @@
+package osquery_watchdog_events
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/pkg/osquery/watchdogevents"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+func TablePlugin() *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("id"),
+		table.BigIntColumn("time"),
+		table.IntegerColumn("worker_pid"),
+		table.TextColumn("limit"),
+		table.TextColumn("message"),
+		table.TextColumn("scheduled_query"),
+		table.TextColumn("pending_distributed_queries"),
+	}
+	return table.NewPlugin("kolide_osquery_watchdog_events", columns, generate)
+}
+
+func generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := []map[string]string{}
+
+	for _, event := range watchdogevents.Recent() {
+		results = append(results, map[string]string{
+			"id":                          event.ID,
+			"time":                        strconv.FormatInt(event.Time.Unix(), 10),
+			"worker_pid":                  strconv.Itoa(event.WorkerPid),
+			"limit":                       event.Limit,
+			"message":                     event.Message,
+			"scheduled_query":             event.ScheduledQuery,
+			"pending_distributed_queries": strings.Join(event.PendingDistributedQueries, ","),
+		})
+	}
+
+	return results, nil
+}
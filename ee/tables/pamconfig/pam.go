@@ -0,0 +1,339 @@
+// Package pamconfig provides the kolide_linux_pam_config table, which reports the effective PAM
+// module stack for each service. Includes are resolved the way libpam resolves them, so each
+// service's stack is what actually runs, rather than what its own file says.
+package pamconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// pamDirs are where libpam looks for service files, in order. Files in the vendor directories
+// apply only when /etc/pam.d doesn't have a file of the same name. Paths are relative to the
+// root of the filesystem, as fs.FS requires.
+var pamDirs = []string{"etc/pam.d", "usr/lib/pam.d", "usr/share/pam.d"}
+
+// maxIncludeDepth guards against include loops
+const maxIncludeDepth = 16
+
+// Module types
+const (
+	typeAuth     = "auth"
+	typeAccount  = "account"
+	typePassword = "password"
+	typeSession  = "session"
+)
+
+var moduleTypes = []string{typeAuth, typeAccount, typePassword, typeSession}
+
+// rule is one line of a service's effective stack.
+type rule struct {
+	service    string
+	moduleType string
+	position   int // position within the service's stack for this type, starting at 1
+	control    string
+	module     string
+	arguments  string
+	optional   bool   // the type was prefixed with -, so a missing module is ignored
+	source     string // the file the rule comes from
+	risk       string // why the rule is risky, if it is
+}
+
+// moduleName returns the module's name without its directory, e.g. pam_unix.so.
+func (r rule) moduleName() string {
+	return path.Base(r.module)
+}
+
+// stack is a service's effective configuration.
+type stack struct {
+	service             string
+	rules               []rule
+	faillockConfigured  bool // accounts lock after repeated failed logins
+	pwqualityConfigured bool // new passwords are checked for strength
+}
+
+type parser struct {
+	fsys fs.FS
+}
+
+// services returns the names of the services with configuration.
+func (p *parser) services() ([]string, error) {
+	seen := make(map[string]bool)
+	var errs []error
+
+	for _, dir := range pamDirs {
+		entries, err := fs.ReadDir(p.fsys, dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading %s: %w", dir, err))
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			seen[entry.Name()] = true
+		}
+	}
+
+	services := make([]string, 0, len(seen))
+	for service := range seen {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	return services, errors.Join(errs...)
+}
+
+// stack returns the service's effective configuration. Errors in included files are returned
+// alongside whatever could be resolved.
+func (p *parser) stack(service string) (stack, error) {
+	filename, err := p.resolve(service)
+	if err != nil {
+		return stack{}, err
+	}
+
+	var errs []error
+	rules := p.rules(filename, "", 0, &errs)
+
+	s := stack{service: service}
+	positions := make(map[string]int)
+	for _, r := range rules {
+		positions[r.moduleType] += 1
+		r.service = service
+		r.position = positions[r.moduleType]
+		s.rules = append(s.rules, r)
+
+		switch {
+		case r.moduleType == typeAuth && (r.moduleName() == "pam_faillock.so" || r.moduleName() == "pam_tally2.so"):
+			s.faillockConfigured = true
+		case r.moduleType == typePassword && (r.moduleName() == "pam_pwquality.so" || r.moduleName() == "pam_cracklib.so"):
+			s.pwqualityConfigured = true
+		}
+	}
+
+	flagRisks(s.rules)
+
+	return s, errors.Join(errs...)
+}
+
+// resolve returns the path of the named service file, which may instead be a path itself.
+func (p *parser) resolve(name string) (string, error) {
+	if strings.HasPrefix(name, "/") {
+		return strings.TrimPrefix(path.Clean(name), "/"), nil
+	}
+	if name == "" || strings.Contains(name, "/") || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid service name %q", name)
+	}
+
+	for _, dir := range pamDirs {
+		filename := path.Join(dir, name)
+		if _, err := fs.Stat(p.fsys, filename); err == nil {
+			return filename, nil
+		}
+	}
+
+	return "", fmt.Errorf("no configuration for %s: %w", name, fs.ErrNotExist)
+}
+
+// rules returns the rules in filename, with includes expanded. If onlyType is set, only rules of
+// that type are returned, as with `auth include system-auth`.
+func (p *parser) rules(filename string, onlyType string, depth int, errs *[]error) []rule {
+	if depth > maxIncludeDepth {
+		*errs = append(*errs, fmt.Errorf("includes nested too deeply at /%s", filename))
+		return nil
+	}
+
+	f, err := p.fsys.Open(filename)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("opening /%s: %w", filename, err))
+		return nil
+	}
+	defer f.Close()
+
+	var rules []rule
+	for _, fields := range lines(f) {
+		// Debian's @include pulls in every type from the named file
+		if fields[0] == "@include" {
+			if len(fields) < 2 {
+				continue
+			}
+			included, err := p.resolve(fields[1])
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("resolving @include in /%s: %w", filename, err))
+				continue
+			}
+			rules = append(rules, p.rules(included, onlyType, depth+1, errs)...)
+			continue
+		}
+
+		r, ok := parseRule(fields)
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("malformed line in /%s: %s", filename, strings.Join(fields, " ")))
+			continue
+		}
+		if onlyType != "" && r.moduleType != onlyType {
+			continue
+		}
+
+		// include and substack name a file in place of the module
+		if r.control == "include" || r.control == "substack" {
+			included, err := p.resolve(r.module)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("resolving %s in /%s: %w", r.control, filename, err))
+				continue
+			}
+			rules = append(rules, p.rules(included, r.moduleType, depth+1, errs)...)
+			continue
+		}
+
+		r.source = "/" + filename
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+// lines returns the fields of each line in the file, dropping comments and joining lines
+// continued with a trailing backslash.
+func lines(f fs.File) [][]string {
+	var result [][]string
+	var continued string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+
+		if strings.HasSuffix(line, `\`) {
+			continued += strings.TrimSuffix(line, `\`) + " "
+			continue
+		}
+		line = continued + line
+		continued = ""
+
+		if fields := strings.Fields(line); len(fields) > 0 {
+			result = append(result, fields)
+		}
+	}
+
+	return result
+}
+
+// parseRule parses the fields of a line: type, control, module, and arguments. The control may
+// be a bracketed list of actions, e.g. `[success=1 default=ignore]`, which contains spaces.
+func parseRule(fields []string) (rule, bool) {
+	if len(fields) < 3 {
+		return rule{}, false
+	}
+
+	r := rule{moduleType: strings.ToLower(fields[0])}
+	if strings.HasPrefix(r.moduleType, "-") {
+		r.optional = true
+		r.moduleType = strings.TrimPrefix(r.moduleType, "-")
+	}
+	if !isModuleType(r.moduleType) {
+		return rule{}, false
+	}
+
+	rest := fields[1:]
+	if strings.HasPrefix(rest[0], "[") {
+		end := -1
+		for i, field := range rest {
+			if strings.HasSuffix(field, "]") {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return rule{}, false
+		}
+		r.control = strings.Join(rest[:end+1], " ")
+		rest = rest[end+1:]
+	} else {
+		r.control = rest[0]
+		rest = rest[1:]
+	}
+
+	if len(rest) == 0 {
+		return rule{}, false
+	}
+	r.module = rest[0]
+	r.arguments = strings.Join(rest[1:], " ")
+
+	return r, true
+}
+
+func isModuleType(t string) bool {
+	for _, moduleType := range moduleTypes {
+		if t == moduleType {
+			return true
+		}
+	}
+	return false
+}
+
+// flagRisks sets the risk of each rule in a service's stack that weakens authentication.
+func flagRisks(rules []rule) {
+	denied := false
+	for i := range rules {
+		r := &rules[i]
+		if r.moduleType != typeAuth {
+			continue
+		}
+
+		switch r.moduleName() {
+		case "pam_deny.so":
+			// Debian's common-auth jumps over `requisite pam_deny.so` on success, then ends
+			// with `required pam_permit.so`, which is safe: no one reaches it without passing
+			// an earlier module.
+			if r.control == "requisite" || r.control == "required" {
+				denied = true
+			}
+		case "pam_permit.so":
+			if !denied || r.control == "sufficient" {
+				r.risk = "pam_permit.so in the auth stack allows authentication without credentials"
+			}
+		case "pam_unix.so", "pam_unix2.so":
+			for _, arg := range strings.Fields(r.arguments) {
+				if arg == "nullok" || arg == "nullok_secure" {
+					r.risk = "nullok allows authentication to accounts with empty passwords"
+				}
+			}
+		}
+	}
+}
+
+func (r rule) row(s stack) map[string]string {
+	return map[string]string{
+		"service":              s.service,
+		"type":                 r.moduleType,
+		"position":             fmt.Sprintf("%d", r.position),
+		"control":              r.control,
+		"module":               r.module,
+		"arguments":            r.arguments,
+		"optional":             boolToString(r.optional),
+		"source":               r.source,
+		"risky":                boolToString(r.risk != ""),
+		"risk_reason":          r.risk,
+		"faillock_configured":  boolToString(s.faillockConfigured),
+		"pwquality_configured": boolToString(s.pwqualityConfigured),
+	}
+}
+
+func boolToString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,132 @@
+package pamconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testParser() *parser {
+	return &parser{fsys: os.DirFS(filepath.Join("testdata", "root"))}
+}
+
+func TestServices(t *testing.T) {
+	t.Parallel()
+
+	services, err := testParser().services()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"common-account", "common-auth", "common-password", "login", "loop", "malformed", "sshd", "system-auth", "vendoronly",
+	}, services)
+}
+
+func TestStack_DebianIncludes(t *testing.T) {
+	t.Parallel()
+
+	s, err := testParser().stack("sshd")
+	require.NoError(t, err)
+
+	const (
+		commonAuth     = "/etc/pam.d/common-auth"
+		commonAccount  = "/etc/pam.d/common-account"
+		commonPassword = "/etc/pam.d/common-password"
+		sshd           = "/etc/pam.d/sshd"
+	)
+
+	require.Equal(t, []rule{
+		{service: "sshd", moduleType: typeAuth, position: 1, control: "[success=1 default=ignore]", module: "pam_unix.so", arguments: "nullok", source: commonAuth, risk: "nullok allows authentication to accounts with empty passwords"},
+		{service: "sshd", moduleType: typeAuth, position: 2, control: "requisite", module: "pam_deny.so", source: commonAuth},
+		{service: "sshd", moduleType: typeAuth, position: 3, control: "required", module: "pam_permit.so", source: commonAuth},
+		{service: "sshd", moduleType: typeAuth, position: 4, control: "optional", module: "pam_cap.so", source: commonAuth},
+		{service: "sshd", moduleType: typeAccount, position: 1, control: "required", module: "pam_nologin.so", source: sshd},
+		{service: "sshd", moduleType: typeAccount, position: 2, control: "[success=1 new_authtok_reqd=done default=ignore]", module: "pam_unix.so", source: commonAccount},
+		{service: "sshd", moduleType: typeAccount, position: 3, control: "requisite", module: "pam_deny.so", source: commonAccount},
+		{service: "sshd", moduleType: typeAccount, position: 4, control: "required", module: "pam_permit.so", source: commonAccount},
+		{service: "sshd", moduleType: typeSession, position: 1, control: "optional", module: "pam_systemd.so", optional: true, source: sshd},
+		{service: "sshd", moduleType: typePassword, position: 1, control: "requisite", module: "pam_pwquality.so", arguments: "retry=3", source: commonPassword},
+		{service: "sshd", moduleType: typePassword, position: 2, control: "[success=1 default=ignore]", module: "pam_unix.so", arguments: "obscure use_authtok try_first_pass yescrypt", source: commonPassword},
+		{service: "sshd", moduleType: typePassword, position: 3, control: "requisite", module: "pam_deny.so", source: commonPassword},
+		{service: "sshd", moduleType: typePassword, position: 4, control: "required", module: "pam_permit.so", source: commonPassword},
+	}, s.rules)
+	require.False(t, s.faillockConfigured)
+	require.True(t, s.pwqualityConfigured)
+}
+
+func TestStack_TypedIncludes(t *testing.T) {
+	t.Parallel()
+
+	s, err := testParser().stack("login")
+	require.Error(t, err, "missing-file can't be included")
+
+	const systemAuth = "/etc/pam.d/system-auth"
+	require.Equal(t, []rule{
+		{service: "login", moduleType: typeAuth, position: 1, control: "required", module: "pam_env.so", source: systemAuth},
+		{service: "login", moduleType: typeAuth, position: 2, control: "required", module: "pam_faillock.so", arguments: "preauth silent audit deny=5 unlock_time=900", source: systemAuth},
+		{service: "login", moduleType: typeAuth, position: 3, control: "sufficient", module: "pam_unix.so", arguments: "try_first_pass", source: systemAuth},
+		{service: "login", moduleType: typeAuth, position: 4, control: "sufficient", module: "/usr/lib64/security/pam_permit.so", source: systemAuth, risk: "pam_permit.so in the auth stack allows authentication without credentials"},
+		{service: "login", moduleType: typeAuth, position: 5, control: "required", module: "pam_deny.so", source: systemAuth},
+		{service: "login", moduleType: typeAccount, position: 1, control: "required", module: "pam_unix.so", source: systemAuth},
+		{service: "login", moduleType: typeSession, position: 1, control: "required", module: "pam_loginuid.so", source: "/etc/pam.d/login"},
+	}, s.rules)
+	require.True(t, s.faillockConfigured)
+	require.False(t, s.pwqualityConfigured)
+}
+
+func TestStack_VendorDirectory(t *testing.T) {
+	t.Parallel()
+
+	s, err := testParser().stack("vendoronly")
+	require.NoError(t, err)
+	require.Equal(t, []rule{
+		{service: "vendoronly", moduleType: typeAuth, position: 1, control: "required", module: "pam_permit.so", source: "/usr/lib/pam.d/vendoronly", risk: "pam_permit.so in the auth stack allows authentication without credentials"},
+	}, s.rules)
+
+	// /etc/pam.d takes precedence over the vendor directory
+	s, err = testParser().stack("sshd")
+	require.NoError(t, err)
+	require.Equal(t, "/etc/pam.d/common-auth", s.rules[0].source)
+}
+
+func TestStack_Errors(t *testing.T) {
+	t.Parallel()
+
+	s, err := testParser().stack("loop")
+	require.ErrorContains(t, err, "nested too deeply")
+	require.Empty(t, s.rules)
+
+	s, err = testParser().stack("malformed")
+	require.ErrorContains(t, err, "malformed line")
+	require.Equal(t, []rule{
+		{service: "malformed", moduleType: typeSession, position: 1, control: "optional", module: "pam_motd.so", arguments: "motd=/run/motd.dynamic", source: "/etc/pam.d/malformed"},
+	}, s.rules)
+
+	_, err = testParser().stack("nonexistent")
+	require.Error(t, err)
+
+	_, err = testParser().stack("../shadow")
+	require.Error(t, err)
+}
+
+func TestRow(t *testing.T) {
+	t.Parallel()
+
+	s, err := testParser().stack("sshd")
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"service":              "sshd",
+		"type":                 "auth",
+		"position":             "1",
+		"control":              "[success=1 default=ignore]",
+		"module":               "pam_unix.so",
+		"arguments":            "nullok",
+		"optional":             "0",
+		"source":               "/etc/pam.d/common-auth",
+		"risky":                "1",
+		"risk_reason":          "nullok allows authentication to accounts with empty passwords",
+		"faillock_configured":  "0",
+		"pwquality_configured": "1",
+	}, s.rules[0].row(s))
+}
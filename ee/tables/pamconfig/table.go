@@ -0,0 +1,79 @@
+//go:build linux
+// +build linux
+
+package pamconfig
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const allowedServiceCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.@+"
+
+type Table struct {
+	slogger *slog.Logger
+	parser  *parser
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("service"),
+		table.TextColumn("type"),
+		table.IntegerColumn("position"),
+		table.TextColumn("control"),
+		table.TextColumn("module"),
+		table.TextColumn("arguments"),
+		table.IntegerColumn("optional"),
+		table.TextColumn("source"),
+		table.IntegerColumn("risky"),
+		table.TextColumn("risk_reason"),
+		table.IntegerColumn("faillock_configured"),
+		table.IntegerColumn("pwquality_configured"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", "kolide_linux_pam_config"),
+		parser:  &parser{fsys: os.DirFS("/")},
+	}
+
+	return table.NewPlugin("kolide_linux_pam_config", columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	services := tablehelpers.GetConstraints(queryContext, "service",
+		tablehelpers.WithAllowedCharacters(allowedServiceCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+	if len(services) == 0 {
+		var err error
+		services, err = t.parser.services()
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list all pam services",
+				"err", err,
+			)
+		}
+	}
+
+	var results []map[string]string
+	for _, service := range services {
+		s, err := t.parser.stack(service)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not fully resolve pam configuration",
+				"service", service,
+				"err", err,
+			)
+		}
+
+		for _, r := range s.rules {
+			results = append(results, r.row(s))
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,157 @@
+// Package quarantineevents reads the LaunchServices quarantine events database, in which macOS
+// records each file a user downloads -- where it came from, and which app downloaded it.
+package quarantineevents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	userDbPath = "Library/Preferences/com.apple.LaunchServices.QuarantineEventsV2"
+
+	// Quarantine timestamps are Core Foundation absolute times, in seconds since 2001-01-01
+	cfAbsoluteTimeEpoch = 978307200
+)
+
+// quarantineTypes decodes LSQuarantineTypeNumber, see LSQuarantine.h
+var quarantineTypes = map[int64]string{
+	0: "web_download",
+	1: "other_download",
+	2: "email_attachment",
+	3: "instant_message_attachment",
+	4: "calendar_event_attachment",
+	5: "other_attachment",
+}
+
+// quarantineDb is a user's quarantine events database.
+type quarantineDb struct {
+	username string
+	path     string
+}
+
+// quarantineDbs returns each user's quarantine events database under rootDir. When usernames
+// is non-empty, only those users' databases are returned, without checking they exist.
+func quarantineDbs(rootDir string, usernames []string) ([]quarantineDb, error) {
+	var dbs []quarantineDb
+
+	if len(usernames) > 0 {
+		for _, username := range usernames {
+			dbs = append(dbs, quarantineDb{username: username, path: filepath.Join(rootDir, "Users", username, userDbPath)})
+		}
+		return dbs, nil
+	}
+
+	userDbs, err := filepath.Glob(filepath.Join(rootDir, "Users", "*", userDbPath))
+	if err != nil {
+		return nil, fmt.Errorf("globbing for quarantine events databases: %w", err)
+	}
+	sort.Strings(userDbs)
+
+	for _, p := range userDbs {
+		rel, err := filepath.Rel(filepath.Join(rootDir, "Users"), p)
+		if err != nil {
+			continue
+		}
+		dbs = append(dbs, quarantineDb{username: strings.Split(filepath.ToSlash(rel), "/")[0], path: p})
+	}
+
+	return dbs, nil
+}
+
+// readEvents reads the quarantine events from the given database, oldest first.
+func readEvents(ctx context.Context, slogger *slog.Logger, db quarantineDb) ([]map[string]string, error) {
+	// sqlite's errors for a missing database aren't very useful, so check first
+	if _, err := os.Stat(db.path); err != nil {
+		return nil, fmt.Errorf("checking quarantine events database: %w", err)
+	}
+
+	// read-only, so that we don't contend with LaunchServices for locks
+	conn, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro", db.path))
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite db: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slogger.Log(ctx, slog.LevelWarn,
+				"closing sqlite db after query",
+				"err", err,
+			)
+		}
+	}()
+
+	rows, err := conn.QueryContext(ctx, `SELECT
+		LSQuarantineEventIdentifier,
+		LSQuarantineTimeStamp,
+		LSQuarantineAgentBundleIdentifier,
+		LSQuarantineAgentName,
+		LSQuarantineDataURLString,
+		LSQuarantineOriginURLString,
+		LSQuarantineOriginTitle,
+		LSQuarantineSenderName,
+		LSQuarantineSenderAddress,
+		LSQuarantineTypeNumber
+		FROM LSQuarantineEvent
+		ORDER BY LSQuarantineTimeStamp`)
+	if err != nil {
+		return nil, fmt.Errorf("running query: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]map[string]string, 0)
+	for rows.Next() {
+		var (
+			eventId                                                   string
+			timestamp                                                 sql.NullFloat64
+			agentBundleId, agentName, dataUrl, originUrl, originTitle sql.NullString
+			senderName, senderAddress                                 sql.NullString
+			typeNumber                                                sql.NullInt64
+		)
+		if err := rows.Scan(&eventId, &timestamp, &agentBundleId, &agentName, &dataUrl, &originUrl, &originTitle, &senderName, &senderAddress, &typeNumber); err != nil {
+			return nil, fmt.Errorf("scanning query results: %w", err)
+		}
+
+		row := map[string]string{
+			"username":        db.username,
+			"path":            db.path,
+			"event_id":        eventId,
+			"timestamp":       "",
+			"agent_bundle_id": agentBundleId.String,
+			"agent_name":      agentName.String,
+			"data_url":        dataUrl.String,
+			"origin_url":      originUrl.String,
+			"origin_title":    originTitle.String,
+			"sender_name":     senderName.String,
+			"sender_address":  senderAddress.String,
+			"type":            "",
+		}
+
+		if timestamp.Valid {
+			row["timestamp"] = strconv.FormatInt(int64(timestamp.Float64)+cfAbsoluteTimeEpoch, 10)
+		}
+
+		if typeNumber.Valid {
+			row["type"] = strconv.FormatInt(typeNumber.Int64, 10)
+			if t, ok := quarantineTypes[typeNumber.Int64]; ok {
+				row["type"] = t
+			}
+		}
+
+		results = append(results, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating query results: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,105 @@
+package quarantineevents
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+const schema = `CREATE TABLE LSQuarantineEvent (LSQuarantineEventIdentifier TEXT PRIMARY KEY NOT NULL, LSQuarantineTimeStamp REAL, LSQuarantineAgentBundleIdentifier TEXT, LSQuarantineAgentName TEXT, LSQuarantineDataURLString TEXT, LSQuarantineSenderName TEXT, LSQuarantineSenderAddress TEXT, LSQuarantineTypeNumber INTEGER, LSQuarantineOriginTitle TEXT, LSQuarantineOriginURLString TEXT, LSQuarantineOriginAlias BLOB)`
+
+func TestReadEvents(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+
+	aliceDb := filepath.Join(rootDir, "Users", "alice", userDbPath)
+	createTestDb(t, aliceDb,
+		`INSERT INTO LSQuarantineEvent VALUES ('A1B2C3D4-0000-0000-0000-000000000002', 737000100.5, 'com.google.Chrome', 'Chrome', 'https://dl.example.com/tool.dmg', NULL, NULL, 0, NULL, 'https://example.com/download', NULL)`,
+		`INSERT INTO LSQuarantineEvent VALUES ('A1B2C3D4-0000-0000-0000-000000000001', 737000000, 'com.apple.mail', 'Mail', NULL, 'Bob', 'bob@example.com', 2, 'Invoice', NULL, NULL)`,
+	)
+
+	bobDb := filepath.Join(rootDir, "Users", "bob", userDbPath)
+	createTestDb(t, bobDb,
+		`INSERT INTO LSQuarantineEvent (LSQuarantineEventIdentifier, LSQuarantineTypeNumber) VALUES ('B0000000-0000-0000-0000-000000000001', 9)`,
+	)
+
+	// A user without a quarantine events database
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "Users", "carol"), 0755))
+
+	dbs, err := quarantineDbs(rootDir, nil)
+	require.NoError(t, err)
+	require.Equal(t, []quarantineDb{
+		{username: "alice", path: aliceDb},
+		{username: "bob", path: bobDb},
+	}, dbs)
+
+	aliceEvents, err := readEvents(context.TODO(), multislogger.NewNopLogger(), dbs[0])
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{
+			"username":        "alice",
+			"path":            aliceDb,
+			"event_id":        "A1B2C3D4-0000-0000-0000-000000000001",
+			"timestamp":       "1715307200",
+			"agent_bundle_id": "com.apple.mail",
+			"agent_name":      "Mail",
+			"data_url":        "",
+			"origin_url":      "",
+			"origin_title":    "Invoice",
+			"sender_name":     "Bob",
+			"sender_address":  "bob@example.com",
+			"type":            "email_attachment",
+		},
+		{
+			"username":        "alice",
+			"path":            aliceDb,
+			"event_id":        "A1B2C3D4-0000-0000-0000-000000000002",
+			"timestamp":       "1715307300",
+			"agent_bundle_id": "com.google.Chrome",
+			"agent_name":      "Chrome",
+			"data_url":        "https://dl.example.com/tool.dmg",
+			"origin_url":      "https://example.com/download",
+			"origin_title":    "",
+			"sender_name":     "",
+			"sender_address":  "",
+			"type":            "web_download",
+		},
+	}, aliceEvents)
+
+	bobEvents, err := readEvents(context.TODO(), multislogger.NewNopLogger(), dbs[1])
+	require.NoError(t, err)
+	require.Len(t, bobEvents, 1)
+	require.Equal(t, "", bobEvents[0]["timestamp"])
+	require.Equal(t, "9", bobEvents[0]["type"])
+
+	// Asking for a specific user returns their database, whether or not it exists
+	dbs, err = quarantineDbs(rootDir, []string{"carol"})
+	require.NoError(t, err)
+	require.Len(t, dbs, 1)
+
+	_, err = readEvents(context.TODO(), multislogger.NewNopLogger(), dbs[0])
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func createTestDb(t *testing.T, path string, inserts ...string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+
+	conn, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec(schema)
+	require.NoError(t, err)
+
+	for _, insert := range inserts {
+		_, err = conn.Exec(insert)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build darwin
+// +build darwin
+
+package quarantineevents
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName                 = "kolide_quarantine_events"
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+)
+
+type Table struct {
+	slogger *slog.Logger
+	rootDir string
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("path"),
+		table.TextColumn("event_id"),
+		table.BigIntColumn("timestamp"),
+		table.TextColumn("agent_bundle_id"),
+		table.TextColumn("agent_name"),
+		table.TextColumn("data_url"),
+		table.TextColumn("origin_url"),
+		table.TextColumn("origin_title"),
+		table.TextColumn("sender_name"),
+		table.TextColumn("sender_address"),
+		table.TextColumn("type"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+		rootDir: "/",
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	dbs, err := quarantineDbs(t.rootDir, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, db := range dbs {
+		rows, err := readEvents(ctx, t.slogger, db)
+		if err != nil {
+			// Users without a quarantine events database are common, don't bother logging those
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not read quarantine events database",
+					"path", db.path,
+					"err", err,
+				)
+			}
+			continue
+		}
+
+		results = append(results, rows...)
+	}
+
+	return results, nil
+}
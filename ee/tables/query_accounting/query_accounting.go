@@ -0,0 +1,51 @@
+package query_accounting
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kolide/launcher/pkg/osquery/queryaccounting"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+func TablePlugin() *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("query_name"),
+		table.BigIntColumn("start_time"),
+		table.BigIntColumn("duration_ms"),
+		table.IntegerColumn("row_count"),
+		table.IntegerColumn("status"),
+		table.TextColumn("error"),
+		table.BigIntColumn("osquery_wall_time_ms"),
+		table.BigIntColumn("osquery_user_time"),
+		table.BigIntColumn("osquery_system_time"),
+		table.BigIntColumn("osquery_memory"),
+	}
+	return table.NewPlugin("kolide_query_accounting", columns, generate)
+}
+
+func generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := []map[string]string{}
+
+	for _, entry := range queryaccounting.Recent() {
+		startTime := ""
+		if !entry.StartTime.IsZero() {
+			startTime = strconv.FormatInt(entry.StartTime.Unix(), 10)
+		}
+
+		results = append(results, map[string]string{
+			"query_name":           entry.QueryName,
+			"start_time":           startTime,
+			"duration_ms":          strconv.FormatInt(entry.DurationMs, 10),
+			"row_count":            strconv.Itoa(entry.RowCount),
+			"status":               strconv.Itoa(entry.Status),
+			"error":                entry.Error,
+			"osquery_wall_time_ms": strconv.FormatInt(entry.OsqueryWallTimeMs, 10),
+			"osquery_user_time":    strconv.FormatInt(entry.OsqueryUserTime, 10),
+			"osquery_system_time":  strconv.FormatInt(entry.OsquerySystemTime, 10),
+			"osquery_memory":       strconv.FormatInt(entry.OsqueryMemory, 10),
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,102 @@
+package restartstatus
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/restartrequired"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_restart_required"
+
+// TablePlugin provides an osquery table of the restarts this device is waiting on, and where
+// launcher is in reminding the user about them. There's one row per reason a restart is
+// required, or a single row with the state "none" if no restart is required.
+func TablePlugin(slogger *slog.Logger, store types.Getter) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("state"),
+		table.TextColumn("source"),
+		table.TextColumn("reason"),
+		table.IntegerColumn("from_server"),
+		table.BigIntColumn("detected_at"),
+		table.IntegerColumn("snoozes_used"),
+		table.IntegerColumn("max_snoozes"),
+		table.BigIntColumn("last_notified_at"),
+		table.BigIntColumn("next_notification_at"),
+		table.BigIntColumn("updated_at"),
+	}
+
+	return table.NewPlugin(tableName, columns, generate(slogger.With("table", tableName), store))
+}
+
+func generate(slogger *slog.Logger, store types.Getter) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		status, err := restartrequired.LoadStatus(store)
+		if err != nil {
+			slogger.Log(ctx, slog.LevelInfo,
+				"could not load restart required status",
+				"err", err,
+			)
+			return nil, nil
+		}
+
+		base := map[string]string{
+			"state":                status.State,
+			"snoozes_used":         strconv.Itoa(status.SnoozesUsed),
+			"max_snoozes":          strconv.Itoa(status.MaxSnoozes),
+			"last_notified_at":     unixOrEmpty(status.LastNotifiedAt),
+			"next_notification_at": "",
+			"updated_at":           unixOrEmpty(status.UpdatedAt),
+		}
+		if status.State != restartrequired.StateNone {
+			base["next_notification_at"] = unixOrEmpty(status.NextNotificationAt)
+		}
+
+		if len(status.Requirements) == 0 {
+			row := copyRow(base)
+			row["source"] = ""
+			row["reason"] = ""
+			row["from_server"] = ""
+			row["detected_at"] = ""
+			return []map[string]string{row}, nil
+		}
+
+		results := make([]map[string]string, 0, len(status.Requirements))
+		for _, r := range status.Requirements {
+			row := copyRow(base)
+			row["source"] = r.Source
+			row["reason"] = r.Reason
+			row["from_server"] = boolToIntString(r.FromServer)
+			row["detected_at"] = unixOrEmpty(r.DetectedAt)
+			results = append(results, row)
+		}
+
+		return results, nil
+	}
+}
+
+func copyRow(row map[string]string) map[string]string {
+	copied := make(map[string]string, len(row)+4)
+	for k, v := range row {
+		copied[k] = v
+	}
+	return copied
+}
+
+func unixOrEmpty(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,191 @@
+package scheduledjobs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronShortcuts are the `@` schedules cron accepts in place of the five time fields, other
+// than @reboot, which has no next run.
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+const rebootSchedule = "@reboot"
+
+var (
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// bitset holds the values a cron field matches, which are all below 64.
+type bitset uint64
+
+func (b bitset) has(v int) bool {
+	return b&(1<<uint(v)) != 0
+}
+
+// cronSchedule is a parsed cron schedule, see `man 5 crontab`.
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek bitset
+	// Set when the day-of-month or day-of-week field starts with `*`. Unless one of them
+	// does, a day matches when either field does, rather than both.
+	dayOfMonthStar, dayOfWeekStar bool
+}
+
+// parseCronSchedule parses the five time fields of a crontab entry, or one of the `@`
+// shortcuts. @reboot has no schedule to parse, and is an error.
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	if expanded, ok := cronShortcuts[strings.ToLower(expr)]; ok {
+		expr = expanded
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule `%s` has %d fields, expected 5", expr, len(fields))
+	}
+
+	s := &cronSchedule{
+		dayOfMonthStar: strings.HasPrefix(fields[2], "*"),
+		dayOfWeekStar:  strings.HasPrefix(fields[4], "*"),
+	}
+
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("parsing minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("parsing hour: %w", err)
+	}
+	if s.dayOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("parsing day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("parsing month: %w", err)
+	}
+	// Sunday may be 0 or 7
+	if s.dayOfWeek, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("parsing day of week: %w", err)
+	}
+	if s.dayOfWeek.has(7) {
+		s.dayOfWeek |= 1
+	}
+
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of `*`, values, and ranges, each optionally
+// with a `/step`. names, when given, are accepted for the values starting at min.
+func parseCronField(field string, min, max int, names []string) (bitset, error) {
+	var b bitset
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step `%s`", stepPart)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = min, max
+		case strings.Contains(rangePart, "-"):
+			lo, hi, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(lo, min, max, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(hi, min, max, names); err != nil {
+				return 0, err
+			}
+			if end < start {
+				return 0, fmt.Errorf("invalid range `%s`", rangePart)
+			}
+		default:
+			var err error
+			if start, err = parseCronValue(rangePart, min, max, names); err != nil {
+				return 0, err
+			}
+			end = start
+			// A step on a single value runs from it to the end of the range
+			if hasStep {
+				end = max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			b |= 1 << uint(v)
+		}
+	}
+
+	return b, nil
+}
+
+func parseCronValue(value string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(value, name) {
+			return min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value `%s`", value)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth.has(t.Day())
+	dayOfWeek := s.dayOfWeek.has(int(t.Weekday()))
+	if s.dayOfMonthStar || s.dayOfWeekStar {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// errNoNextRun is returned for schedules that can't match, like February 30th.
+var errNoNextRun = errors.New("schedule never matches")
+
+// nextRunSearchYears bounds the search for a schedule's next run. Any schedule that can match
+// does so within a leap-year cycle.
+const nextRunSearchYears = 5
+
+// next returns the first time after the given one that the schedule matches, in its location.
+func (s *cronSchedule) next(after time.Time) (time.Time, error) {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(nextRunSearchYears, 0, 0)
+	loc := t.Location()
+
+	for t.Before(limit) {
+		switch {
+		case !s.month.has(int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !s.hour.has(t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !s.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errNoNextRun
+}
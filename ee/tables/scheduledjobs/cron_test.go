@@ -0,0 +1,78 @@
+package scheduledjobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	// A Wednesday
+	now := time.Date(2024, time.January, 10, 14, 37, 20, 0, time.UTC)
+
+	for _, tt := range []struct {
+		schedule string
+		expected time.Time
+	}{
+		{schedule: "* * * * *", expected: time.Date(2024, time.January, 10, 14, 38, 0, 0, time.UTC)},
+		{schedule: "17 * * * *", expected: time.Date(2024, time.January, 10, 15, 17, 0, 0, time.UTC)},
+		{schedule: "*/15 9-17 * * mon-fri", expected: time.Date(2024, time.January, 10, 14, 45, 0, 0, time.UTC)},
+		{schedule: "0 12 1 * *", expected: time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{schedule: "0 0 * * 7", expected: time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		{schedule: "30 5 1,15 jun *", expected: time.Date(2024, time.June, 1, 5, 30, 0, 0, time.UTC)},
+		{schedule: "5/20 * * * *", expected: time.Date(2024, time.January, 10, 14, 45, 0, 0, time.UTC)},
+		// Restricting both days matches either
+		{schedule: "0 0 13 * fri", expected: time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)},
+		// ...unless one starts with *
+		{schedule: "0 0 */2 * fri", expected: time.Date(2024, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{schedule: "0 0 29 2 *", expected: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{schedule: "@yearly", expected: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{schedule: "@weekly", expected: time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		{schedule: "@HOURLY", expected: time.Date(2024, time.January, 10, 15, 0, 0, 0, time.UTC)},
+	} {
+		tt := tt
+		t.Run(tt.schedule, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := parseCronSchedule(tt.schedule)
+			require.NoError(t, err)
+
+			next, err := s.next(now)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, next)
+		})
+	}
+}
+
+func TestCronScheduleNext_NeverMatches(t *testing.T) {
+	t.Parallel()
+
+	s, err := parseCronSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+
+	_, err = s.next(time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC))
+	require.ErrorIs(t, err, errNoNextRun)
+}
+
+func TestParseCronSchedule_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, schedule := range []string{
+		"@reboot",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * smarch *",
+	} {
+		_, err := parseCronSchedule(schedule)
+		require.Error(t, err, schedule)
+	}
+}
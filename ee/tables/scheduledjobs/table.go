@@ -0,0 +1,57 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package scheduledjobs
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("source"),
+		table.TextColumn("path"),
+		table.TextColumn("username"),
+		table.TextColumn("label"),
+		table.TextColumn("schedule"),
+		table.TextColumn("command"),
+		table.BigIntColumn("next_run"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	c := &collector{
+		fsys: os.DirFS("/"),
+		now:  time.Now(),
+	}
+
+	jobs, err := c.jobs()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read all scheduled jobs",
+			"err", err,
+		)
+	}
+
+	results := make([]map[string]string, 0, len(jobs))
+	for _, j := range jobs {
+		results = append(results, j.row())
+	}
+
+	return results, nil
+}
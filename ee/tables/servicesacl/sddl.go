@@ -0,0 +1,443 @@
+// Package servicesacl provides a table describing who may start, stop, reconfigure, or take
+// over each Windows service. Service permissions aren't visible in osquery's services table, and
+// a service that ordinary users may reconfigure is a well-known path to running code as
+// LocalSystem.
+//
+// Security descriptors are read as SDDL, and decoded here. The SDDL format is documented at
+// https://learn.microsoft.com/en-us/windows/win32/secauthz/security-descriptor-string-format
+package servicesacl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Service access rights, see
+// https://learn.microsoft.com/en-us/windows/win32/services/service-security-and-access-rights
+const (
+	serviceQueryConfig         uint32 = 0x0001
+	serviceChangeConfig        uint32 = 0x0002
+	serviceQueryStatus         uint32 = 0x0004
+	serviceEnumerateDependents uint32 = 0x0008
+	serviceStart               uint32 = 0x0010
+	serviceStop                uint32 = 0x0020
+	servicePauseContinue       uint32 = 0x0040
+	serviceInterrogate         uint32 = 0x0080
+	serviceUserDefinedControl  uint32 = 0x0100
+
+	accessDelete      uint32 = 0x00010000
+	accessReadControl uint32 = 0x00020000
+	accessWriteDac    uint32 = 0x00040000
+	accessWriteOwner  uint32 = 0x00080000
+
+	genericAll     uint32 = 0x10000000
+	genericExecute uint32 = 0x20000000
+	genericWrite   uint32 = 0x40000000
+	genericRead    uint32 = 0x80000000
+
+	serviceAllAccess uint32 = 0x000F01FF
+)
+
+// sddlRights maps SDDL access right codes to access masks. The directory service codes are the
+// ones Windows uses for the service-specific rights.
+var sddlRights = map[string]uint32{
+	"CC": serviceQueryConfig,
+	"DC": serviceChangeConfig,
+	"LC": serviceQueryStatus,
+	"SW": serviceEnumerateDependents,
+	"RP": serviceStart,
+	"WP": serviceStop,
+	"DT": servicePauseContinue,
+	"LO": serviceInterrogate,
+	"CR": serviceUserDefinedControl,
+	"SD": accessDelete,
+	"RC": accessReadControl,
+	"WD": accessWriteDac,
+	"WO": accessWriteOwner,
+	"GA": genericAll,
+	"GX": genericExecute,
+	"GW": genericWrite,
+	"GR": genericRead,
+	"FA": 0x001F01FF,
+	"FR": 0x00120089,
+	"FW": 0x00120116,
+	"FX": 0x001200A0,
+	"KA": 0x000F003F,
+	"KR": 0x00020019,
+	"KW": 0x00020006,
+	"KX": 0x00020019,
+}
+
+// serviceRightNames are the names reported for each access right, in the order they're reported.
+var serviceRightNames = []struct {
+	mask uint32
+	name string
+}{
+	{serviceQueryConfig, "query_config"},
+	{serviceChangeConfig, "change_config"},
+	{serviceQueryStatus, "query_status"},
+	{serviceEnumerateDependents, "enumerate_dependents"},
+	{serviceStart, "start"},
+	{serviceStop, "stop"},
+	{servicePauseContinue, "pause_continue"},
+	{serviceInterrogate, "interrogate"},
+	{serviceUserDefinedControl, "user_defined_control"},
+	{accessDelete, "delete"},
+	{accessReadControl, "read_control"},
+	{accessWriteDac, "write_dac"},
+	{accessWriteOwner, "write_owner"},
+}
+
+// modifyRights are the rights that let a trustee change what a service runs, or grant
+// themselves the right to.
+const modifyRights = serviceChangeConfig | accessWriteDac | accessWriteOwner
+
+// mapGenericRights replaces generic rights in mask with the service rights they stand for.
+func mapGenericRights(mask uint32) uint32 {
+	if mask&genericRead != 0 {
+		mask |= accessReadControl | serviceQueryConfig | serviceQueryStatus | serviceInterrogate | serviceEnumerateDependents
+	}
+	if mask&genericWrite != 0 {
+		mask |= accessReadControl | serviceChangeConfig
+	}
+	if mask&genericExecute != 0 {
+		mask |= accessReadControl | serviceStart | serviceStop | servicePauseContinue | serviceUserDefinedControl
+	}
+	if mask&genericAll != 0 {
+		mask |= serviceAllAccess
+	}
+	return mask &^ (genericAll | genericExecute | genericWrite | genericRead)
+}
+
+// serviceRights lists the service rights in mask, noting any other bits.
+func serviceRights(mask uint32) []string {
+	mask = mapGenericRights(mask)
+
+	var rights []string
+	for _, r := range serviceRightNames {
+		if mask&r.mask != 0 {
+			rights = append(rights, r.name)
+			mask &^= r.mask
+		}
+	}
+	if mask != 0 {
+		rights = append(rights, "0x"+strconv.FormatUint(uint64(mask), 16))
+	}
+
+	return rights
+}
+
+// sidAliases maps SDDL SID strings to the well-known SIDs they stand for. Aliases for domain
+// groups depend on the domain, and are left as they are.
+var sidAliases = map[string]string{
+	"AC": "S-1-15-2-1",
+	"AN": "S-1-5-7",
+	"AO": "S-1-5-32-548",
+	"AU": "S-1-5-11",
+	"BA": "S-1-5-32-544",
+	"BG": "S-1-5-32-546",
+	"BO": "S-1-5-32-551",
+	"BU": "S-1-5-32-545",
+	"CO": "S-1-3-0",
+	"CG": "S-1-3-1",
+	"IU": "S-1-5-4",
+	"LS": "S-1-5-19",
+	"NS": "S-1-5-20",
+	"NU": "S-1-5-2",
+	"OW": "S-1-3-4",
+	"PO": "S-1-5-32-550",
+	"PU": "S-1-5-32-547",
+	"RD": "S-1-5-32-555",
+	"RU": "S-1-5-32-554",
+	"SO": "S-1-5-32-549",
+	"SU": "S-1-5-6",
+	"SY": "S-1-5-18",
+	"WD": "S-1-1-0",
+	"WR": "S-1-5-33",
+}
+
+// broadTrustees are the SIDs of groups that include ordinary, unprivileged users.
+var broadTrustees = map[string]bool{
+	"S-1-1-0":      true, // Everyone
+	"S-1-5-2":      true, // Network
+	"S-1-5-4":      true, // Interactive
+	"S-1-5-6":      true, // Service
+	"S-1-5-7":      true, // Anonymous
+	"S-1-5-11":     true, // Authenticated Users
+	"S-1-5-32-545": true, // Users
+	"S-1-5-32-546": true, // Guests
+	"S-1-5-32-547": true, // Power Users
+	"S-1-5-32-555": true, // Remote Desktop Users
+	"S-1-15-2-1":   true, // All application packages
+	"DU":           true, // Domain Users
+	"DG":           true, // Domain Guests
+	"DC":           true, // Domain Computers
+}
+
+// isBroadTrustee reports whether sid is a group that includes ordinary users.
+func isBroadTrustee(sid string) bool {
+	if broadTrustees[sid] {
+		return true
+	}
+
+	// Domain Users, Domain Guests, and Domain Computers in domains other than the machine's
+	if strings.HasPrefix(sid, "S-1-5-21-") {
+		for _, rid := range []string{"-513", "-514", "-515"} {
+			if strings.HasSuffix(sid, rid) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// aceTypeNames are the names reported for SDDL ACE types.
+var aceTypeNames = map[string]string{
+	"A":  "allow",
+	"D":  "deny",
+	"OA": "object_allow",
+	"OD": "object_deny",
+	"XA": "callback_allow",
+	"XD": "callback_deny",
+	"ZA": "callback_object_allow",
+	"AU": "audit",
+	"AL": "alarm",
+	"OU": "object_audit",
+	"OL": "object_alarm",
+	"XU": "callback_audit",
+	"ML": "mandatory_label",
+	"RA": "resource_attribute",
+	"SP": "scoped_policy_id",
+}
+
+// securityDescriptor is a decoded SDDL security descriptor. Only the DACL is kept -- reading a
+// service's SACL needs the security privilege, and the SACL doesn't grant access anyway.
+type securityDescriptor struct {
+	owner     string
+	group     string
+	daclFlags string
+	dacl      []ace
+}
+
+// ace is an access control entry.
+type ace struct {
+	aceType string
+	flags   string
+	mask    uint32
+	trustee string
+}
+
+func (a ace) isAllow() bool {
+	return a.aceType == "A" || a.aceType == "OA" || a.aceType == "XA" || a.aceType == "ZA"
+}
+
+func (a ace) isInherited() bool {
+	for i := 0; i+1 < len(a.flags); i += 2 {
+		if a.flags[i:i+2] == "ID" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSddl decodes an SDDL security descriptor string.
+func parseSddl(sddl string) (*securityDescriptor, error) {
+	components, err := splitComponents(sddl)
+	if err != nil {
+		return nil, err
+	}
+
+	sd := &securityDescriptor{
+		owner: resolveSid(components['O']),
+		group: resolveSid(components['G']),
+	}
+
+	if dacl, ok := components['D']; ok {
+		sd.daclFlags, sd.dacl, err = parseAcl(dacl)
+		if err != nil {
+			return nil, fmt.Errorf("parsing DACL: %w", err)
+		}
+	}
+
+	return sd, nil
+}
+
+// splitComponents splits an SDDL string into its owner (O), group (G), DACL (D), and SACL (S)
+// components. Components only start outside of ACEs, since conditional ACEs may contain colons.
+func splitComponents(sddl string) (map[byte]string, error) {
+	components := make(map[byte]string)
+
+	var current byte
+	start := 0
+	depth := 0
+	for i := 0; i < len(sddl); i++ {
+		switch sddl[i] {
+		case '(':
+			depth++
+			continue
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, errors.New("unbalanced parentheses")
+			}
+			continue
+		}
+
+		if depth > 0 || i+1 >= len(sddl) || sddl[i+1] != ':' || !strings.ContainsRune("OGDS", rune(sddl[i])) {
+			continue
+		}
+
+		if current != 0 {
+			components[current] = sddl[start:i]
+		}
+		current = sddl[i]
+		start = i + 2
+		i++
+	}
+
+	if depth != 0 {
+		return nil, errors.New("unbalanced parentheses")
+	}
+	if current == 0 {
+		if sddl != "" {
+			return nil, fmt.Errorf("no security descriptor components in %q", sddl)
+		}
+		return components, nil
+	}
+	components[current] = sddl[start:]
+
+	return components, nil
+}
+
+// parseAcl decodes an ACL component: its flags, followed by its ACEs, each in parentheses.
+func parseAcl(acl string) (string, []ace, error) {
+	flagsEnd := strings.IndexByte(acl, '(')
+	if flagsEnd < 0 {
+		return acl, nil, nil
+	}
+	flags := acl[:flagsEnd]
+
+	var aces []ace
+	depth := 0
+	start := 0
+	for i := flagsEnd; i < len(acl); i++ {
+		switch acl[i] {
+		case '(':
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				a, err := parseAce(acl[start:i])
+				if err != nil {
+					return "", nil, err
+				}
+				aces = append(aces, a)
+			}
+		default:
+			if depth == 0 {
+				return "", nil, fmt.Errorf("unexpected %q between ACEs", acl[i])
+			}
+		}
+	}
+
+	return flags, aces, nil
+}
+
+// parseAce decodes an ACE string: type;flags;rights;object_guid;inherit_object_guid;sid, with
+// an optional trailing condition or resource attribute.
+func parseAce(s string) (ace, error) {
+	fields := strings.SplitN(s, ";", 7)
+	if len(fields) < 6 {
+		return ace{}, fmt.Errorf("ACE %q has %d fields, expected at least 6", s, len(fields))
+	}
+
+	mask, err := parseRights(fields[2])
+	if err != nil {
+		return ace{}, fmt.Errorf("ACE %q: %w", s, err)
+	}
+
+	return ace{
+		aceType: fields[0],
+		flags:   fields[1],
+		mask:    mask,
+		trustee: resolveSid(fields[5]),
+	}, nil
+}
+
+// parseRights decodes ACE rights, given either as a number or as a string of two-letter codes.
+func parseRights(rights string) (uint32, error) {
+	if rights == "" {
+		return 0, nil
+	}
+
+	if rights[0] >= '0' && rights[0] <= '9' {
+		mask, err := strconv.ParseUint(rights, 0, 32)
+		if err != nil {
+			return 0, fmt.Errorf("parsing rights %q: %w", rights, err)
+		}
+		return uint32(mask), nil
+	}
+
+	if len(rights)%2 != 0 {
+		return 0, fmt.Errorf("rights %q are not a list of two-letter codes", rights)
+	}
+
+	var mask uint32
+	for i := 0; i < len(rights); i += 2 {
+		right, ok := sddlRights[rights[i:i+2]]
+		if !ok {
+			return 0, fmt.Errorf("unknown right %q", rights[i:i+2])
+		}
+		mask |= right
+	}
+
+	return mask, nil
+}
+
+// resolveSid returns the SID an SDDL SID string stands for.
+func resolveSid(sid string) string {
+	if resolved, ok := sidAliases[sid]; ok {
+		return resolved
+	}
+	return sid
+}
+
+// aceRow returns a table row describing a, for the given service.
+func aceRow(serviceName string, sd *securityDescriptor, a ace, sddl string) map[string]string {
+	aceType, ok := aceTypeNames[a.aceType]
+	if !ok {
+		aceType = a.aceType
+	}
+
+	mask := mapGenericRights(a.mask)
+	canModify := mask&modifyRights != 0
+	broad := isBroadTrustee(a.trustee)
+
+	return map[string]string{
+		"name":                      serviceName,
+		"owner":                     sd.owner,
+		"ace_type":                  aceType,
+		"ace_flags":                 a.flags,
+		"inherited":                 boolToIntString(a.isInherited()),
+		"trustee_sid":               a.trustee,
+		"access_mask":               "0x" + strconv.FormatUint(uint64(a.mask), 16),
+		"permissions":               strings.Join(serviceRights(a.mask), ","),
+		"can_modify":                boolToIntString(canModify),
+		"broad_trustee":             boolToIntString(broad),
+		"privilege_escalation_risk": boolToIntString(a.isAllow() && canModify && broad),
+		"sddl":                      sddl,
+	}
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
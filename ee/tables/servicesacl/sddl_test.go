@@ -0,0 +1,127 @@
+package servicesacl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSddl(t *testing.T) {
+	t.Parallel()
+
+	// A typical service, with a misconfigured ACE granting Authenticated Users full control
+	sddl := "O:SYG:SYD:PAI(A;;CCLCSWRPWPDTLOCRRC;;;SY)(A;;CCDCLCSWRPWPDTLOCRSDRCWDWO;;;BA)(A;ID;CCLCSWLOCRRC;;;IU)(A;;GA;;;AU)(A;;CCLCSWLORC;;;S-1-5-21-1004336348-1177238915-682003330-513)S:(AU;FA;CCDCLCSWRPWPDTLOCRSDRCWDWO;;;WD)"
+
+	sd, err := parseSddl(sddl)
+	require.NoError(t, err)
+
+	require.Equal(t, "S-1-5-18", sd.owner)
+	require.Equal(t, "S-1-5-18", sd.group)
+	require.Equal(t, "PAI", sd.daclFlags)
+	require.Equal(t, []ace{
+		{aceType: "A", mask: 0x201fd, trustee: "S-1-5-18"},
+		{aceType: "A", mask: 0xf01ff, trustee: "S-1-5-32-544"},
+		{aceType: "A", flags: "ID", mask: 0x2018d, trustee: "S-1-5-4"},
+		{aceType: "A", mask: genericAll, trustee: "S-1-5-11"},
+		{aceType: "A", mask: 0x2008d, trustee: "S-1-5-21-1004336348-1177238915-682003330-513"},
+	}, sd.dacl)
+
+	rows := make([]map[string]string, 0, len(sd.dacl))
+	for _, a := range sd.dacl {
+		rows = append(rows, aceRow("ExampleSvc", sd, a, sddl))
+	}
+
+	require.Equal(t, "query_config,query_status,enumerate_dependents,start,stop,pause_continue,interrogate,user_defined_control,read_control", rows[0]["permissions"])
+	require.Equal(t, "0", rows[0]["can_modify"])
+	require.Equal(t, "0", rows[0]["broad_trustee"])
+
+	require.Equal(t, "1", rows[1]["can_modify"])
+	require.Equal(t, "0", rows[1]["privilege_escalation_risk"])
+
+	require.Equal(t, "1", rows[2]["inherited"])
+	require.Equal(t, "1", rows[2]["broad_trustee"])
+	require.Equal(t, "0", rows[2]["privilege_escalation_risk"])
+
+	require.Equal(t, map[string]string{
+		"name":                      "ExampleSvc",
+		"owner":                     "S-1-5-18",
+		"ace_type":                  "allow",
+		"ace_flags":                 "",
+		"inherited":                 "0",
+		"trustee_sid":               "S-1-5-11",
+		"access_mask":               "0x10000000",
+		"permissions":               "query_config,change_config,query_status,enumerate_dependents,start,stop,pause_continue,interrogate,user_defined_control,delete,read_control,write_dac,write_owner",
+		"can_modify":                "1",
+		"broad_trustee":             "1",
+		"privilege_escalation_risk": "1",
+		"sddl":                      sddl,
+	}, rows[3])
+
+	// Domain Users, from a domain other than the machine's
+	require.Equal(t, "1", rows[4]["broad_trustee"])
+	require.Equal(t, "0", rows[4]["privilege_escalation_risk"])
+}
+
+func TestParseSddl_Variants(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name         string
+		sddl         string
+		expectedDacl []ace
+		expectErr    bool
+	}{
+		{
+			name:         "hex rights and deny",
+			sddl:         "O:BAD:(D;;0x2;;;WD)(A;;0x000F01FF;;;BA)",
+			expectedDacl: []ace{{aceType: "D", mask: 0x2, trustee: "S-1-1-0"}, {aceType: "A", mask: 0xf01ff, trustee: "S-1-5-32-544"}},
+		},
+		{
+			name:         "conditional ACE",
+			sddl:         `D:(XA;;RP;;;WD;(@User.Title=="PM" && (@User.Division=="D:1")))`,
+			expectedDacl: []ace{{aceType: "XA", mask: serviceStart, trustee: "S-1-1-0"}},
+		},
+		{
+			name: "empty DACL",
+			sddl: "O:SYD:P",
+		},
+		{
+			name:      "unknown right",
+			sddl:      "D:(A;;QQ;;;WD)",
+			expectErr: true,
+		},
+		{
+			name:      "too few fields",
+			sddl:      "D:(A;;RP;;WD)",
+			expectErr: true,
+		},
+		{
+			name:      "unbalanced",
+			sddl:      "D:(A;;RP;;;WD",
+			expectErr: true,
+		},
+		{
+			name:      "not sddl",
+			sddl:      "garbage",
+			expectErr: true,
+		},
+	} {
+		sd, err := parseSddl(tt.sddl)
+		if tt.expectErr {
+			require.Error(t, err, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.expectedDacl, sd.dacl, tt.name)
+	}
+}
+
+func TestServiceRights(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"start", "stop"}, serviceRights(serviceStart|serviceStop))
+	require.Equal(t, []string{"query_config", "query_status", "enumerate_dependents", "interrogate", "read_control"}, serviceRights(genericRead))
+	require.Equal(t, []string{"change_config", "read_control"}, serviceRights(genericWrite))
+	require.Equal(t, []string{"start", "0x200000"}, serviceRights(serviceStart|0x200000))
+	require.Empty(t, serviceRights(0))
+}
@@ -0,0 +1,154 @@
+//go:build windows
+// +build windows
+
+package servicesacl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const tableName = "kolide_windows_services_acl"
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("start_name"),
+		table.TextColumn("owner"),
+		table.TextColumn("ace_type"),
+		table.TextColumn("ace_flags"),
+		table.IntegerColumn("inherited"),
+		table.TextColumn("trustee_sid"),
+		table.TextColumn("trustee"),
+		table.TextColumn("access_mask"),
+		table.TextColumn("permissions"),
+		table.IntegerColumn("can_modify"),
+		table.IntegerColumn("broad_trustee"),
+		table.IntegerColumn("privilege_escalation_risk"),
+		table.TextColumn("sddl"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	serviceManager, err := mgr.Connect()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not connect to service manager",
+			"err", err,
+		)
+		return nil, nil
+	}
+	defer serviceManager.Disconnect()
+
+	serviceNames, err := serviceManager.ListServices()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list services",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	// Many services share the same trustees, so only look each one up once
+	accountNames := make(map[string]string)
+
+	var results []map[string]string
+	for _, serviceName := range serviceNames {
+		sddl, startName, err := serviceSecurity(serviceManager, serviceName)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not read service security descriptor",
+				"service", serviceName,
+				"err", err,
+			)
+			continue
+		}
+
+		sd, err := parseSddl(sddl)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not parse service security descriptor",
+				"service", serviceName,
+				"sddl", sddl,
+				"err", err,
+			)
+			continue
+		}
+
+		for _, a := range sd.dacl {
+			row := aceRow(serviceName, sd, a, sddl)
+			row["start_name"] = startName
+
+			if _, ok := accountNames[a.trustee]; !ok {
+				accountNames[a.trustee] = accountName(a.trustee)
+			}
+			row["trustee"] = accountNames[a.trustee]
+
+			results = append(results, row)
+		}
+	}
+
+	return results, nil
+}
+
+// serviceSecurity returns the SDDL for the owner and DACL of the named service, along with the
+// account the service runs as.
+func serviceSecurity(serviceManager *mgr.Mgr, serviceName string) (string, string, error) {
+	namePtr, err := windows.UTF16PtrFromString(serviceName)
+	if err != nil {
+		return "", "", fmt.Errorf("converting service name: %w", err)
+	}
+
+	// Open with just the access we need, rather than mgr.OpenService's SERVICE_ALL_ACCESS
+	handle, err := windows.OpenService(serviceManager.Handle, namePtr, windows.READ_CONTROL|windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return "", "", fmt.Errorf("opening service: %w", err)
+	}
+	service := &mgr.Service{Name: serviceName, Handle: handle}
+	defer service.Close()
+
+	sd, err := windows.GetSecurityInfo(handle, windows.SE_SERVICE, windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return "", "", fmt.Errorf("getting security info: %w", err)
+	}
+
+	// The start name is informational, so don't fail the service over it
+	var startName string
+	if config, err := service.Config(); err == nil {
+		startName = config.ServiceStartName
+	}
+
+	return sd.String(), startName, nil
+}
+
+// accountName returns the DOMAIN\name for sid, or an empty string if it can't be looked up.
+func accountName(sid string) string {
+	s, err := windows.StringToSid(sid)
+	if err != nil {
+		return ""
+	}
+
+	account, domain, _, err := s.LookupAccount("")
+	if err != nil {
+		return ""
+	}
+	if domain == "" {
+		return account
+	}
+	return domain + `\` + account
+}
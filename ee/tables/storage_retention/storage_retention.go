@@ -0,0 +1,53 @@
+package storage_retention
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/agent/storage/gc"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// TablePlugin reports each store's retention policy, and how much its garbage collection
+// has purged since launcher started.
+func TablePlugin() *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("store"),
+		table.BigIntColumn("max_age_seconds"),
+		table.IntegerColumn("max_entries"),
+		table.BigIntColumn("last_run"),
+		table.IntegerColumn("entries"),
+		table.IntegerColumn("last_purged"),
+		table.IntegerColumn("total_purged"),
+		table.TextColumn("last_error"),
+	}
+	return table.NewPlugin("kolide_storage_retention", columns, generate)
+}
+
+func generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := []map[string]string{}
+
+	for _, s := range gc.Stats() {
+		row := map[string]string{
+			"store":           s.Store.String(),
+			"max_age_seconds": strconv.FormatInt(int64(s.Policy.MaxAge.Seconds()), 10),
+			"max_entries":     strconv.Itoa(s.Policy.MaxEntries),
+			"last_run":        "",
+			"entries":         "",
+			"last_purged":     "",
+			"total_purged":    strconv.Itoa(s.TotalPurged),
+			"last_error":      s.LastError,
+		}
+
+		// Stores that haven't been collected yet have no counts
+		if !s.LastRun.IsZero() {
+			row["last_run"] = strconv.FormatInt(s.LastRun.Unix(), 10)
+			row["entries"] = strconv.Itoa(s.Entries)
+			row["last_purged"] = strconv.Itoa(s.LastPurged)
+		}
+
+		results = append(results, row)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,476 @@
+// Package systemproxy provides a table of the effective proxy configuration: per-protocol
+// proxies, PAC URLs, auto-discovery, and bypass lists. Each platform keeps these somewhere
+// different -- the SystemConfiguration dynamic store on macOS, WinHTTP and each user's WinINET
+// settings on Windows, and the environment, GNOME, and KDE on Linux -- and launcher itself also
+// honors the proxy environment variables it was started with.
+package systemproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const tableName = "kolide_system_proxy_settings"
+
+// Types of proxy settings
+const (
+	typeHttp          = "http"
+	typeHttps         = "https"
+	typeFtp           = "ftp"
+	typeSocks         = "socks"
+	typeAll           = "all"            // a single proxy for all protocols
+	typePac           = "pac"            // proxy auto-config; the proxy is the PAC URL
+	typeAutoDiscovery = "auto_discovery" // WPAD
+)
+
+// Sources of proxy settings
+const (
+	sourceEnvironment    = "environment" // launcher's own environment
+	sourceEtcEnvironment = "etc_environment"
+	sourceScutil         = "scutil"
+	sourceWinHttp        = "winhttp"
+	sourceWinInet        = "wininet"
+	sourceGsettings      = "gsettings"
+	sourceKioslaverc     = "kioslaverc"
+)
+
+var columns = []table.ColumnDefinition{
+	table.TextColumn("username"),
+	table.TextColumn("type"),
+	table.IntegerColumn("enabled"),
+	table.TextColumn("proxy"),
+	table.TextColumn("bypass_list"),
+	table.TextColumn("source"),
+}
+
+// proxyRow returns a row for a proxy setting. username is empty for system-wide settings.
+func proxyRow(username, proxyType string, enabled bool, proxy string, bypass []string, source string) map[string]string {
+	enabledStr := "0"
+	if enabled {
+		enabledStr = "1"
+	}
+
+	return map[string]string{
+		"username":    username,
+		"type":        proxyType,
+		"enabled":     enabledStr,
+		"proxy":       proxy,
+		"bypass_list": strings.Join(bypass, ","),
+		"source":      source,
+	}
+}
+
+// proxyEnvVars are the environment variables that configure proxies, by type. The lowercase
+// forms take precedence, as they do for curl and Go's net/http.
+var proxyEnvVars = []struct {
+	proxyType string
+	names     []string
+}{
+	{typeHttp, []string{"http_proxy", "HTTP_PROXY"}},
+	{typeHttps, []string{"https_proxy", "HTTPS_PROXY"}},
+	{typeFtp, []string{"ftp_proxy", "FTP_PROXY"}},
+	{typeAll, []string{"all_proxy", "ALL_PROXY"}},
+}
+
+var noProxyEnvVars = []string{"no_proxy", "NO_PROXY"}
+
+// envRows returns the proxies configured in an environment, as looked up by getenv.
+func envRows(username string, getenv func(string) string, source string) []map[string]string {
+	lookup := func(names []string) string {
+		for _, name := range names {
+			if v := strings.TrimSpace(getenv(name)); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	bypass := splitList(lookup(noProxyEnvVars), ",")
+
+	var results []map[string]string
+	for _, v := range proxyEnvVars {
+		if proxy := lookup(v.names); proxy != "" {
+			results = append(results, proxyRow(username, v.proxyType, true, proxy, bypass, source))
+		}
+	}
+	return results
+}
+
+// parseEnvironmentFile parses an environment file, like /etc/environment, of KEY=value lines.
+func parseEnvironmentFile(r io.Reader) map[string]string {
+	results := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		results[strings.TrimSpace(key)] = unquote(strings.TrimSpace(value))
+	}
+
+	return results
+}
+
+// parseScutilProxy parses the output of `scutil --proxy`, the effective proxy configuration
+// from the SystemConfiguration dynamic store, e.g.
+//
+//	<dictionary> {
+//	  ExceptionsList : <array> {
+//	    0 : *.local
+//	    1 : 169.254/16
+//	  }
+//	  HTTPEnable : 1
+//	  HTTPPort : 8080
+//	  HTTPProxy : proxy.example.com
+//	  ProxyAutoConfigEnable : 1
+//	  ProxyAutoConfigURLString : http://wpad.example.com/wpad.dat
+//	  ProxyAutoDiscoveryEnable : 0
+//	}
+//
+// Per-interface overrides, under __SCOPED__, are ignored.
+func parseScutilProxy(output []byte) []map[string]string {
+	values := make(map[string]string)
+	var exceptions []string
+
+	depth := 0
+	nested := ""
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case line == "}":
+			depth -= 1
+			continue
+		}
+
+		key, value, found := strings.Cut(line, " : ")
+		if strings.HasSuffix(line, "{") {
+			depth += 1
+			if depth == 2 && found {
+				nested = key
+			}
+			continue
+		}
+		if !found {
+			continue
+		}
+
+		switch {
+		case depth == 1:
+			values[key] = value
+		case depth == 2 && nested == "ExceptionsList":
+			exceptions = append(exceptions, value)
+		}
+	}
+
+	var results []map[string]string
+	for _, p := range []struct {
+		proxyType string
+		prefix    string
+	}{
+		{typeHttp, "HTTP"},
+		{typeHttps, "HTTPS"},
+		{typeFtp, "FTP"},
+		{typeSocks, "SOCKS"},
+	} {
+		host := values[p.prefix+"Proxy"]
+		if host == "" {
+			continue
+		}
+		results = append(results, proxyRow("", p.proxyType, values[p.prefix+"Enable"] == "1", hostPort(host, values[p.prefix+"Port"]), exceptions, sourceScutil))
+	}
+
+	if url := values["ProxyAutoConfigURLString"]; url != "" {
+		results = append(results, proxyRow("", typePac, values["ProxyAutoConfigEnable"] == "1", url, exceptions, sourceScutil))
+	}
+	if enabled, ok := values["ProxyAutoDiscoveryEnable"]; ok {
+		results = append(results, proxyRow("", typeAutoDiscovery, enabled == "1", "", exceptions, sourceScutil))
+	}
+
+	return results
+}
+
+// Flags in a Windows connection settings blob
+const (
+	connectionFlagProxy        = 0x2
+	connectionFlagAutoProxyUrl = 0x4
+	connectionFlagAutoDetect   = 0x8
+)
+
+// connectionSettings is a Windows connection settings blob: WinHTTP's WinHttpSettings, or a
+// WinINET connection's settings, like DefaultConnectionSettings.
+type connectionSettings struct {
+	flags         uint32
+	proxyServer   string
+	proxyBypass   string
+	autoConfigUrl string
+}
+
+// parseConnectionSettings decodes a Windows connection settings blob. It's little-endian: a
+// version, a change counter, the flags, then length-prefixed strings for the proxy server, the
+// bypass list, and -- for WinINET only -- the PAC URL.
+func parseConnectionSettings(raw []byte) (connectionSettings, error) {
+	r := bytes.NewReader(raw)
+
+	var header struct {
+		Version uint32
+		Counter uint32
+		Flags   uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return connectionSettings{}, fmt.Errorf("reading header: %w", err)
+	}
+
+	settings := connectionSettings{flags: header.Flags}
+
+	var err error
+	if settings.proxyServer, err = readLengthPrefixedString(r); err != nil {
+		return connectionSettings{}, fmt.Errorf("reading proxy server: %w", err)
+	}
+	if settings.proxyBypass, err = readLengthPrefixedString(r); err != nil {
+		return connectionSettings{}, fmt.Errorf("reading proxy bypass: %w", err)
+	}
+
+	// WinHTTP's settings end here
+	settings.autoConfigUrl, err = readLengthPrefixedString(r)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return connectionSettings{}, fmt.Errorf("reading auto config url: %w", err)
+	}
+
+	return settings, nil
+}
+
+func readLengthPrefixedString(r *bytes.Reader) (string, error) {
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	if int64(length) > int64(r.Len()) {
+		return "", fmt.Errorf("length %d exceeds remaining %d bytes", length, r.Len())
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// parseProxyServer parses a Windows proxy server string. It's either a single proxy for all
+// protocols, like `proxy.example.com:8080`, or per-protocol proxies, like
+// `http=proxy.example.com:8080;https=proxy.example.com:8443;socks=socks.example.com:1080`.
+// The returned proxies are keyed by type.
+func parseProxyServer(proxyServer string) map[string]string {
+	results := make(map[string]string)
+
+	for _, entry := range splitList(proxyServer, ";") {
+		protocol, proxy, found := strings.Cut(entry, "=")
+		if !found {
+			results[typeAll] = entry
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(protocol)) {
+		case "http":
+			results[typeHttp] = strings.TrimSpace(proxy)
+		case "https":
+			results[typeHttps] = strings.TrimSpace(proxy)
+		case "ftp":
+			results[typeFtp] = strings.TrimSpace(proxy)
+		case "socks":
+			results[typeSocks] = strings.TrimSpace(proxy)
+		}
+	}
+
+	return results
+}
+
+// windowsProxyRows returns the rows for a Windows proxy server string and bypass list.
+func windowsProxyRows(username string, enabled bool, proxyServer string, bypass []string, source string) []map[string]string {
+	var results []map[string]string
+
+	proxies := parseProxyServer(proxyServer)
+	for _, proxyType := range []string{typeAll, typeHttp, typeHttps, typeFtp, typeSocks} {
+		if proxy, ok := proxies[proxyType]; ok {
+			results = append(results, proxyRow(username, proxyType, enabled, proxy, bypass, source))
+		}
+	}
+
+	return results
+}
+
+// parseGsettings parses the output of `gsettings list-recursively`, returning the values keyed by
+// "schema key". Strings are unquoted.
+func parseGsettings(output []byte) map[string]string {
+	results := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 3)
+		if len(parts) < 3 {
+			continue
+		}
+
+		value := parts[2]
+		for _, prefix := range []string{"uint32 ", "int32 ", "@as "} {
+			value = strings.TrimPrefix(value, prefix)
+		}
+		results[parts[0]+" "+parts[1]] = unquote(value)
+	}
+
+	return results
+}
+
+// parseGvariantStringArray parses a GVariant string array, like `['localhost', '127.0.0.0/8']`.
+func parseGvariantStringArray(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]")
+
+	var results []string
+	for _, item := range splitList(value, ",") {
+		results = append(results, unquote(item))
+	}
+	return results
+}
+
+const gnomeProxySchema = "org.gnome.system.proxy"
+
+// gnomeRows normalizes a user's GNOME proxy settings. The mode says which settings are in
+// effect: `none`, `manual` for the per-protocol proxies, or `auto` for the PAC URL -- or, without
+// one, auto-discovery.
+func gnomeRows(username string, values map[string]string) []map[string]string {
+	var results []map[string]string
+
+	mode := values[gnomeProxySchema+" mode"]
+	bypass := parseGvariantStringArray(values[gnomeProxySchema+" ignore-hosts"])
+
+	for _, proxyType := range []string{typeHttp, typeHttps, typeFtp, typeSocks} {
+		schema := gnomeProxySchema + "." + proxyType
+		host := values[schema+" host"]
+		if host == "" {
+			continue
+		}
+		results = append(results, proxyRow(username, proxyType, mode == "manual", hostPort(host, values[schema+" port"]), bypass, sourceGsettings))
+	}
+
+	if url := values[gnomeProxySchema+" autoconfig-url"]; url != "" {
+		results = append(results, proxyRow(username, typePac, mode == "auto", url, bypass, sourceGsettings))
+	} else if mode == "auto" {
+		results = append(results, proxyRow(username, typeAutoDiscovery, true, "", bypass, sourceGsettings))
+	}
+
+	return results
+}
+
+// KDE's proxy types, from the ProxyType setting in kioslaverc
+const (
+	kdeProxyTypeManual        = "1"
+	kdeProxyTypePac           = "2"
+	kdeProxyTypeAutoDiscovery = "3"
+)
+
+// parseKioslaverc returns KDE's proxy settings from the `[Proxy Settings]` section of the user's
+// kioslaverc, e.g.
+//
+//	[Proxy Settings]
+//	NoProxyFor=localhost,127.0.0.1
+//	Proxy Config Script=http://wpad.example.com/wpad.dat
+//	ProxyType=1
+//	httpProxy=http://proxy.example.com 8080
+func parseKioslaverc(username string, r io.Reader) []map[string]string {
+	values := make(map[string]string)
+
+	inSection := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = line == "[Proxy Settings]"
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	proxyType := values["ProxyType"]
+	bypass := splitList(values["NoProxyFor"], ",")
+
+	var results []map[string]string
+	for _, p := range []struct {
+		proxyType string
+		key       string
+	}{
+		{typeHttp, "httpProxy"},
+		{typeHttps, "httpsProxy"},
+		{typeFtp, "ftpProxy"},
+		{typeSocks, "socksProxy"},
+	} {
+		proxy := values[p.key]
+		if proxy == "" {
+			continue
+		}
+		// Older versions of KDE separate the port with a space
+		if host, port, found := strings.Cut(proxy, " "); found {
+			proxy = hostPort(host, port)
+		}
+		results = append(results, proxyRow(username, p.proxyType, proxyType == kdeProxyTypeManual, proxy, bypass, sourceKioslaverc))
+	}
+
+	if url := values["Proxy Config Script"]; url != "" {
+		results = append(results, proxyRow(username, typePac, proxyType == kdeProxyTypePac, url, bypass, sourceKioslaverc))
+	}
+	if proxyType == kdeProxyTypeAutoDiscovery {
+		results = append(results, proxyRow(username, typeAutoDiscovery, true, "", bypass, sourceKioslaverc))
+	}
+
+	return results
+}
+
+// hostPort joins a host and port, leaving out ports that aren't set.
+func hostPort(host, port string) string {
+	if p, err := strconv.Atoi(strings.TrimSpace(port)); err != nil || p == 0 {
+		return host
+	}
+	return host + ":" + strings.TrimSpace(port)
+}
+
+// splitList splits a list on sep, trimming space and dropping empty items.
+func splitList(list, sep string) []string {
+	var results []string
+	for _, item := range strings.Split(list, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			results = append(results, item)
+		}
+	}
+	return results
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,183 @@
+package systemproxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvRows(t *testing.T) {
+	t.Parallel()
+
+	env := parseEnvironmentFile(strings.NewReader(`# proxies
+PATH="/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin"
+http_proxy="http://proxy.example.com:3128"
+HTTP_PROXY=http://ignored.example.com:3128
+export HTTPS_PROXY='http://proxy.example.com:3128'
+no_proxy=localhost, 127.0.0.1,.example.com
+`))
+
+	require.Equal(t, []map[string]string{
+		proxyRow("", typeHttp, true, "http://proxy.example.com:3128", []string{"localhost", "127.0.0.1", ".example.com"}, sourceEtcEnvironment),
+		proxyRow("", typeHttps, true, "http://proxy.example.com:3128", []string{"localhost", "127.0.0.1", ".example.com"}, sourceEtcEnvironment),
+	}, envRows("", func(name string) string { return env[name] }, sourceEtcEnvironment))
+
+	require.Empty(t, envRows("", func(string) string { return "" }, sourceEnvironment))
+}
+
+func TestParseScutilProxy(t *testing.T) {
+	t.Parallel()
+
+	output := []byte(`<dictionary> {
+  ExceptionsList : <array> {
+    0 : *.local
+    1 : 169.254/16
+  }
+  FTPPassive : 1
+  HTTPEnable : 1
+  HTTPPort : 8080
+  HTTPProxy : proxy.example.com
+  HTTPSEnable : 0
+  HTTPSPort : 8443
+  HTTPSProxy : secure.example.com
+  ProxyAutoConfigEnable : 1
+  ProxyAutoConfigURLString : http://wpad.example.com/wpad.dat
+  ProxyAutoDiscoveryEnable : 0
+  __SCOPED__ : <dictionary> {
+    en0 : <dictionary> {
+      ExceptionsList : <array> {
+        0 : ignored.example.com
+      }
+      SOCKSEnable : 1
+      SOCKSProxy : socks.example.com
+    }
+  }
+}
+`)
+
+	exceptions := []string{"*.local", "169.254/16"}
+	require.Equal(t, []map[string]string{
+		proxyRow("", typeHttp, true, "proxy.example.com:8080", exceptions, sourceScutil),
+		proxyRow("", typeHttps, false, "secure.example.com:8443", exceptions, sourceScutil),
+		proxyRow("", typePac, true, "http://wpad.example.com/wpad.dat", exceptions, sourceScutil),
+		proxyRow("", typeAutoDiscovery, false, "", exceptions, sourceScutil),
+	}, parseScutilProxy(output))
+
+	require.Empty(t, parseScutilProxy([]byte("<dictionary> {\n}\n")))
+}
+
+// connectionSettingsBlob builds a Windows connection settings blob from its strings.
+func connectionSettingsBlob(t *testing.T, flags uint32, strs ...string) []byte {
+	var buf bytes.Buffer
+	for _, v := range []uint32{0x46, 1, flags} {
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, v))
+	}
+	for _, s := range strs {
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint32(len(s))))
+		buf.WriteString(s)
+	}
+	return buf.Bytes()
+}
+
+func TestParseConnectionSettings(t *testing.T) {
+	t.Parallel()
+
+	// WinHTTP's settings have no PAC URL
+	settings, err := parseConnectionSettings(connectionSettingsBlob(t, 0x3, "proxy.example.com:8080", "<local>;*.example.com"))
+	require.NoError(t, err)
+	require.Equal(t, connectionSettings{flags: 0x3, proxyServer: "proxy.example.com:8080", proxyBypass: "<local>;*.example.com"}, settings)
+
+	settings, err = parseConnectionSettings(connectionSettingsBlob(t, 0xd, "", "", "http://wpad.example.com/wpad.dat"))
+	require.NoError(t, err)
+	require.Equal(t, "http://wpad.example.com/wpad.dat", settings.autoConfigUrl)
+	require.NotZero(t, settings.flags&connectionFlagAutoDetect)
+	require.Zero(t, settings.flags&connectionFlagProxy)
+
+	_, err = parseConnectionSettings([]byte{0x46, 0, 0})
+	require.Error(t, err)
+
+	// A length longer than the blob
+	raw := connectionSettingsBlob(t, 0x3, "proxy.example.com:8080", "")
+	raw[12] = 0xff
+	_, err = parseConnectionSettings(raw)
+	require.Error(t, err)
+}
+
+func TestWindowsProxyRows(t *testing.T) {
+	t.Parallel()
+
+	bypass := []string{"<local>"}
+
+	require.Equal(t, []map[string]string{
+		proxyRow("alice", typeAll, true, "proxy.example.com:8080", bypass, sourceWinInet),
+	}, windowsProxyRows("alice", true, "proxy.example.com:8080", bypass, sourceWinInet))
+
+	require.Equal(t, []map[string]string{
+		proxyRow("", typeHttp, false, "proxy.example.com:8080", bypass, sourceWinHttp),
+		proxyRow("", typeHttps, false, "proxy.example.com:8443", bypass, sourceWinHttp),
+		proxyRow("", typeSocks, false, "socks.example.com:1080", bypass, sourceWinHttp),
+	}, windowsProxyRows("", false, "http=proxy.example.com:8080; https=proxy.example.com:8443;socks=socks.example.com:1080;", bypass, sourceWinHttp))
+
+	require.Empty(t, windowsProxyRows("", true, "", bypass, sourceWinHttp))
+}
+
+func TestGnomeRows(t *testing.T) {
+	t.Parallel()
+
+	output := []byte(`org.gnome.system.proxy use-same-proxy true
+org.gnome.system.proxy mode 'manual'
+org.gnome.system.proxy autoconfig-url ''
+org.gnome.system.proxy ignore-hosts ['localhost', '127.0.0.0/8', '::1']
+org.gnome.system.proxy.http host 'proxy.example.com'
+org.gnome.system.proxy.http port 3128
+org.gnome.system.proxy.https host ''
+org.gnome.system.proxy.https port 0
+org.gnome.system.proxy.socks host 'socks.example.com'
+org.gnome.system.proxy.socks port 0
+`)
+
+	bypass := []string{"localhost", "127.0.0.0/8", "::1"}
+	require.Equal(t, []map[string]string{
+		proxyRow("alice", typeHttp, true, "proxy.example.com:3128", bypass, sourceGsettings),
+		proxyRow("alice", typeSocks, true, "socks.example.com", bypass, sourceGsettings),
+	}, gnomeRows("alice", parseGsettings(output)))
+
+	require.Equal(t, []map[string]string{
+		proxyRow("alice", typeAutoDiscovery, true, "", nil, sourceGsettings),
+	}, gnomeRows("alice", parseGsettings([]byte("org.gnome.system.proxy mode 'auto'\norg.gnome.system.proxy ignore-hosts @as []\n"))))
+
+	require.Empty(t, gnomeRows("alice", parseGsettings([]byte("org.gnome.system.proxy mode 'none'\n"))))
+}
+
+func TestParseKioslaverc(t *testing.T) {
+	t.Parallel()
+
+	rows := parseKioslaverc("alice", strings.NewReader(`[$Version]
+update_info=kioslave.upd:kde4.2
+
+[Proxy Settings]
+NoProxyFor=localhost,.example.com
+Proxy Config Script=http://wpad.example.com/wpad.dat
+ProxyType=1
+httpProxy=http://proxy.example.com 3128
+httpsProxy=http://proxy.example.com:3129
+socksProxy=
+
+[Other]
+httpProxy=http://ignored.example.com 3128
+`))
+
+	bypass := []string{"localhost", ".example.com"}
+	require.Equal(t, []map[string]string{
+		proxyRow("alice", typeHttp, true, "http://proxy.example.com:3128", bypass, sourceKioslaverc),
+		proxyRow("alice", typeHttps, true, "http://proxy.example.com:3129", bypass, sourceKioslaverc),
+		proxyRow("alice", typePac, false, "http://wpad.example.com/wpad.dat", bypass, sourceKioslaverc),
+	}, rows)
+
+	require.Equal(t, []map[string]string{
+		proxyRow("alice", typeAutoDiscovery, true, "", nil, sourceKioslaverc),
+	}, parseKioslaverc("alice", strings.NewReader("[Proxy Settings]\nProxyType=3\n")))
+}
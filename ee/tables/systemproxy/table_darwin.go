@@ -0,0 +1,45 @@
+//go:build darwin
+// +build darwin
+
+package systemproxy
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results := envRows("", os.Getenv, sourceEnvironment)
+
+	// Proxies are configured per network service; scutil reports the effective configuration,
+	// for the primary service.
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.Scutil, []string{"--proxy"}, &stdout, &stderr); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not run scutil",
+			"stderr", stderr.String(),
+			"err", err,
+		)
+		return results, nil
+	}
+
+	return append(results, parseScutilProxy(stdout.Bytes())...), nil
+}
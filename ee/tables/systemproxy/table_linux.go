@@ -0,0 +1,159 @@
+//go:build linux
+// +build linux
+
+package systemproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+
+	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/consoleuser"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	if len(usernames) == 0 {
+		results = append(results, envRows("", os.Getenv, sourceEnvironment)...)
+		results = append(results, t.etcEnvironmentRows(ctx)...)
+	}
+
+	// Desktop proxy settings are per user, and GNOME's live in each user's dconf database, so
+	// only check the users logged in
+	uids, err := consoleuser.CurrentUids(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get console users",
+			"err", err,
+		)
+		return results, nil
+	}
+
+	for _, uid := range uids {
+		u, err := user.LookupId(uid)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not look up console user",
+				"uid", uid,
+				"err", err,
+			)
+			continue
+		}
+		if len(usernames) > 0 && !slices.Contains(usernames, u.Username) {
+			continue
+		}
+
+		results = append(results, t.gnomeRows(ctx, u)...)
+		results = append(results, t.kdeRows(ctx, u)...)
+	}
+
+	return results, nil
+}
+
+// etcEnvironmentRows returns the proxies set system-wide in /etc/environment.
+func (t *Table) etcEnvironmentRows(ctx context.Context) []map[string]string {
+	fh, err := os.Open("/etc/environment")
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read /etc/environment",
+				"err", err,
+			)
+		}
+		return nil
+	}
+	defer fh.Close()
+
+	env := parseEnvironmentFile(fh)
+	return envRows("", func(name string) string { return env[name] }, sourceEtcEnvironment)
+}
+
+// gnomeRows returns the user's GNOME proxy settings.
+func (t *Table) gnomeRows(ctx context.Context, u *user.User) []map[string]string {
+	output, err := t.gsettings(ctx, u)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read gsettings",
+			"username", u.Username,
+			"err", err,
+		)
+		return nil
+	}
+
+	return gnomeRows(u.Username, parseGsettings(output))
+}
+
+// gsettings lists the proxy settings, as the given user.
+func (t *Table) gsettings(ctx context.Context, u *user.User) ([]byte, error) {
+	dir, err := agent.MkdirTemp("osq-systemproxy")
+	if err != nil {
+		return nil, fmt.Errorf("mktemp: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// gsettings needs to be able to run from the working directory, as the user
+	if err := os.Chmod(dir, 0755); err != nil {
+		return nil, fmt.Errorf("chmod: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 5,
+		allowedcmd.Gsettings, []string{"list-recursively", gnomeProxySchema}, &stdout, &stderr,
+		tablehelpers.WithUid(u.Uid),
+		tablehelpers.WithAppendEnv("HOME", u.HomeDir),
+		tablehelpers.WithDir(dir),
+	); err != nil {
+		return nil, fmt.Errorf("running gsettings: %w", err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// kdeRows returns the user's KDE proxy settings.
+func (t *Table) kdeRows(ctx context.Context, u *user.User) []map[string]string {
+	fh, err := os.Open(filepath.Join(u.HomeDir, ".config", "kioslaverc"))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read kioslaverc",
+				"username", u.Username,
+				"err", err,
+			)
+		}
+		return nil
+	}
+	defer fh.Close()
+
+	return parseKioslaverc(u.Username, fh)
+}
@@ -0,0 +1,202 @@
+//go:build windows
+// +build windows
+
+package systemproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+	internetSettingsKey = `Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+	connectionsKey      = internetSettingsKey + `\Connections`
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	if len(usernames) == 0 {
+		results = append(results, envRows("", os.Getenv, sourceEnvironment)...)
+		results = append(results, t.winHttpRows(ctx)...)
+	}
+
+	// WinINET settings are per user, in their registry hive, so only check the users whose
+	// hives are loaded -- that is, the users logged in
+	usersKey, err := registry.OpenKey(registry.USERS, "", registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not open HKEY_USERS",
+			"err", err,
+		)
+		return results, nil
+	}
+	defer usersKey.Close()
+
+	sids, err := usersKey.ReadSubKeyNames(0)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list HKEY_USERS",
+			"err", err,
+		)
+		return results, nil
+	}
+
+	for _, sid := range sids {
+		// Only local and domain user accounts
+		if !strings.HasPrefix(sid, "S-1-5-21-") || strings.HasSuffix(sid, "_Classes") {
+			continue
+		}
+
+		username, err := accountName(sid)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not look up account",
+				"sid", sid,
+				"err", err,
+			)
+			continue
+		}
+		if len(usernames) > 0 && !slices.Contains(usernames, username) {
+			continue
+		}
+
+		results = append(results, t.winInetRows(ctx, sid, username)...)
+	}
+
+	return results, nil
+}
+
+// winHttpRows returns the system-wide WinHTTP proxy, as set by `netsh winhttp set proxy`. Services
+// using WinHTTP, like Windows Update, use it; WinHTTP doesn't support PAC URLs or auto-discovery
+// through its settings.
+func (t *Table) winHttpRows(ctx context.Context) []map[string]string {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, connectionsKey, registry.QUERY_VALUE)
+	if err != nil {
+		if !errors.Is(err, registry.ErrNotExist) {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not open connections key",
+				"err", err,
+			)
+		}
+		return nil
+	}
+	defer key.Close()
+
+	raw, _, err := key.GetBinaryValue("WinHttpSettings")
+	if err != nil {
+		// Without settings, WinHTTP connects directly
+		return nil
+	}
+
+	settings, err := parseConnectionSettings(raw)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not parse WinHTTP settings",
+			"err", err,
+		)
+		return nil
+	}
+
+	return windowsProxyRows("", settings.flags&connectionFlagProxy != 0, settings.proxyServer, splitList(settings.proxyBypass, ";"), sourceWinHttp)
+}
+
+// winInetRows returns the user's WinINET proxy settings, as set in Internet Options or the
+// Settings app. Browsers and most applications use these.
+func (t *Table) winInetRows(ctx context.Context, sid string, username string) []map[string]string {
+	key, err := registry.OpenKey(registry.USERS, sid+`\`+internetSettingsKey, registry.QUERY_VALUE)
+	if err != nil {
+		if !errors.Is(err, registry.ErrNotExist) {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not open internet settings key",
+				"username", username,
+				"err", err,
+			)
+		}
+		return nil
+	}
+	defer key.Close()
+
+	proxyEnable, _, _ := key.GetIntegerValue("ProxyEnable")
+	proxyServer, _, _ := key.GetStringValue("ProxyServer")
+	proxyOverride, _, _ := key.GetStringValue("ProxyOverride")
+	autoConfigUrl, _, _ := key.GetStringValue("AutoConfigURL")
+
+	bypass := splitList(proxyOverride, ";")
+	results := windowsProxyRows(username, proxyEnable == 1, proxyServer, bypass, sourceWinInet)
+
+	// Whether to use the PAC URL, and whether to automatically detect settings, are only in the
+	// connection settings blob. Without it, a PAC URL is in use if it's set.
+	settings, err := t.defaultConnectionSettings(sid)
+	if err != nil {
+		if autoConfigUrl != "" {
+			results = append(results, proxyRow(username, typePac, true, autoConfigUrl, bypass, sourceWinInet))
+		}
+		return results
+	}
+
+	if autoConfigUrl != "" {
+		results = append(results, proxyRow(username, typePac, settings.flags&connectionFlagAutoProxyUrl != 0, autoConfigUrl, bypass, sourceWinInet))
+	}
+	results = append(results, proxyRow(username, typeAutoDiscovery, settings.flags&connectionFlagAutoDetect != 0, "", bypass, sourceWinInet))
+
+	return results
+}
+
+func (t *Table) defaultConnectionSettings(sid string) (connectionSettings, error) {
+	key, err := registry.OpenKey(registry.USERS, sid+`\`+connectionsKey, registry.QUERY_VALUE)
+	if err != nil {
+		return connectionSettings{}, fmt.Errorf("opening connections key: %w", err)
+	}
+	defer key.Close()
+
+	raw, _, err := key.GetBinaryValue("DefaultConnectionSettings")
+	if err != nil {
+		return connectionSettings{}, fmt.Errorf("reading default connection settings: %w", err)
+	}
+
+	return parseConnectionSettings(raw)
+}
+
+func accountName(sid string) (string, error) {
+	s, err := windows.StringToSid(sid)
+	if err != nil {
+		return "", fmt.Errorf("parsing sid: %w", err)
+	}
+
+	account, _, _, err := s.LookupAccount("")
+	if err != nil {
+		return "", fmt.Errorf("looking up account: %w", err)
+	}
+
+	return account, nil
+}
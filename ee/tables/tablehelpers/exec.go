@@ -50,11 +50,27 @@ func RunSimple(ctx context.Context, slogger *slog.Logger, timeoutSeconds int, cm
 }
 
 // Run is a wrapper over allowedcmd.AllowedCommand. It enforces a timeout, logs, traces.
+// Only a few commands run at once, launcher-wide; the rest wait their turn, by priority (see
+// WithExecPriority), before their timeout starts.
 // Use RunSimple() for a simpler interface.
 func Run(ctx context.Context, slogger *slog.Logger, timeoutSeconds int, execCmd allowedcmd.AllowedCommand, args []string, stdout io.Writer, stderr io.Writer, opts ...ExecOps) error {
 	ctx, span := traces.StartSpan(ctx)
 	defer span.End()
 
+	priority := execPriority(ctx)
+	waitStart := time.Now()
+	waitCtx, cancelWait := context.WithTimeout(ctx, maxExecQueueWait)
+	err := execs.acquire(waitCtx, priority)
+	cancelWait()
+	if err != nil {
+		traces.SetError(span, err)
+		return fmt.Errorf("waiting for a turn to run command: %w", err)
+	}
+	defer execs.release()
+
+	span.SetAttributes(attribute.Int("exec.priority", int(priority)))
+	span.SetAttributes(attribute.Int64("exec.queue_wait_ms", time.Since(waitStart).Milliseconds()))
+
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
 	defer cancel()
 
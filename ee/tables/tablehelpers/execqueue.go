@@ -0,0 +1,183 @@
+package tablehelpers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ExecPriority orders the commands waiting to run when too many are running already.
+type ExecPriority int
+
+const (
+	// ExecPriorityScheduled is for commands that no one is waiting on, such as those run by
+	// scheduled queries
+	ExecPriorityScheduled ExecPriority = iota
+	// ExecPriorityInteractive is for commands someone is waiting on, such as those run by
+	// distributed queries
+	ExecPriorityInteractive
+
+	execPriorityCount
+)
+
+const (
+	// maxConcurrentExecs is how many commands tables may run at once, launcher-wide. Many tables
+	// shell out to expensive commands, like system_profiler or powershell, and a burst of queries
+	// shouldn't run dozens of them at once.
+	maxConcurrentExecs = 4
+
+	// maxExecQueueWait is how long a command waits to run before giving up
+	maxExecQueueWait = 2 * time.Minute
+
+	// interactiveQueriesWindow bounds how long distributed queries are considered pending, in
+	// case their results never come back
+	interactiveQueriesWindow = 10 * time.Minute
+)
+
+// execs limits the commands run by Run.
+var execs = newExecQueue(maxConcurrentExecs)
+
+type execPriorityKey struct{}
+
+// WithExecPriority returns a context under which commands run by Run wait at the given
+// priority, for callers that know who's waiting on them.
+func WithExecPriority(ctx context.Context, priority ExecPriority) context.Context {
+	return context.WithValue(ctx, execPriorityKey{}, priority)
+}
+
+// execPriority returns the priority to run a command at. osquery doesn't tell tables which
+// query they're generating rows for, so unless the caller set a priority, commands run while
+// distributed queries are pending are considered interactive.
+func execPriority(ctx context.Context) ExecPriority {
+	if priority, ok := ctx.Value(execPriorityKey{}).(ExecPriority); ok && priority >= 0 && priority < execPriorityCount {
+		return priority
+	}
+	if interactiveQueries.pending(time.Now()) {
+		return ExecPriorityInteractive
+	}
+	return ExecPriorityScheduled
+}
+
+var interactiveQueries = &pendingInteractiveQueries{
+	since: make(map[string]time.Time),
+}
+
+type pendingInteractiveQueries struct {
+	lock  sync.Mutex
+	since map[string]time.Time // source -> when it last had queries pending
+}
+
+// SetInteractiveQueriesPending notes whether the given source -- an osquery instance's
+// registration -- has distributed queries pending, which raises the priority of the commands
+// tables run until it doesn't.
+func SetInteractiveQueriesPending(source string, pending bool) {
+	interactiveQueries.set(source, pending, time.Now())
+}
+
+func (p *pendingInteractiveQueries) set(source string, pending bool, now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if pending {
+		p.since[source] = now
+	} else {
+		delete(p.since, source)
+	}
+}
+
+func (p *pendingInteractiveQueries) pending(now time.Time) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, since := range p.since {
+		if now.Sub(since) < interactiveQueriesWindow {
+			return true
+		}
+	}
+	return false
+}
+
+// execQueue limits how many commands run at once. Commands that can't run yet wait their turn,
+// higher priorities first, and in order of arrival within a priority.
+type execQueue struct {
+	lock    sync.Mutex
+	limit   int
+	running int
+	waiting [execPriorityCount][]chan struct{}
+}
+
+func newExecQueue(limit int) *execQueue {
+	return &execQueue{limit: limit}
+}
+
+// acquire waits until a command may run, or ctx is done. On success, the caller must call
+// release when the command has finished.
+func (q *execQueue) acquire(ctx context.Context, priority ExecPriority) error {
+	q.lock.Lock()
+	if q.running < q.limit && q.waitingCount() == 0 {
+		q.running += 1
+		q.lock.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	q.waiting[priority] = append(q.waiting[priority], ready)
+	q.lock.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		q.lock.Lock()
+		defer q.lock.Unlock()
+
+		select {
+		case <-ready:
+			// Our turn came just as we gave up, so pass it on
+			q.releaseLocked()
+		default:
+			q.remove(priority, ready)
+		}
+		return ctx.Err()
+	}
+}
+
+func (q *execQueue) release() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.releaseLocked()
+}
+
+func (q *execQueue) releaseLocked() {
+	q.running -= 1
+
+	for priority := len(q.waiting) - 1; priority >= 0; priority-- {
+		if len(q.waiting[priority]) == 0 {
+			continue
+		}
+
+		next := q.waiting[priority][0]
+		q.waiting[priority] = q.waiting[priority][1:]
+		q.running += 1
+		close(next)
+		return
+	}
+}
+
+func (q *execQueue) remove(priority ExecPriority, ready chan struct{}) {
+	for i, waiting := range q.waiting[priority] {
+		if waiting == ready {
+			q.waiting[priority] = append(q.waiting[priority][:i], q.waiting[priority][i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *execQueue) waitingCount() int {
+	count := 0
+	for _, waiting := range q.waiting {
+		count += len(waiting)
+	}
+	return count
+}
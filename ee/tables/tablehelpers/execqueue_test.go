@@ -0,0 +1,88 @@
+package tablehelpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecQueue_Limit(t *testing.T) {
+	t.Parallel()
+
+	q := newExecQueue(2)
+	require.NoError(t, q.acquire(context.TODO(), ExecPriorityScheduled))
+	require.NoError(t, q.acquire(context.TODO(), ExecPriorityScheduled))
+
+	// The third has to wait for one of the first two to finish
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, q.acquire(ctx, ExecPriorityInteractive), context.DeadlineExceeded)
+	require.Zero(t, q.waitingCount(), "a waiter that gave up should leave the queue")
+
+	q.release()
+	require.NoError(t, q.acquire(context.TODO(), ExecPriorityScheduled))
+}
+
+func TestExecQueue_Priority(t *testing.T) {
+	t.Parallel()
+
+	q := newExecQueue(1)
+	require.NoError(t, q.acquire(context.TODO(), ExecPriorityScheduled))
+
+	order := make(chan string, 3)
+	wait := func(name string, priority ExecPriority) {
+		if err := q.acquire(context.TODO(), priority); err != nil {
+			order <- err.Error()
+			return
+		}
+		order <- name
+		q.release()
+	}
+
+	go wait("scheduled", ExecPriorityScheduled)
+	require.Eventually(t, func() bool { return waiting(q) == 1 }, time.Second, time.Millisecond)
+	go wait("interactive 1", ExecPriorityInteractive)
+	require.Eventually(t, func() bool { return waiting(q) == 2 }, time.Second, time.Millisecond)
+	go wait("interactive 2", ExecPriorityInteractive)
+	require.Eventually(t, func() bool { return waiting(q) == 3 }, time.Second, time.Millisecond)
+
+	q.release()
+	require.Equal(t, "interactive 1", <-order)
+	require.Equal(t, "interactive 2", <-order)
+	require.Equal(t, "scheduled", <-order)
+}
+
+func waiting(q *execQueue) int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.waitingCount()
+}
+
+func TestExecPriority(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, ExecPriorityInteractive, execPriority(WithExecPriority(context.TODO(), ExecPriorityInteractive)))
+	require.Equal(t, ExecPriorityScheduled, execPriority(WithExecPriority(context.TODO(), ExecPriorityScheduled)))
+}
+
+func TestPendingInteractiveQueries(t *testing.T) {
+	t.Parallel()
+
+	p := &pendingInteractiveQueries{since: make(map[string]time.Time)}
+	now := time.Now()
+	require.False(t, p.pending(now))
+
+	p.set("default", true, now)
+	p.set("secondary", true, now)
+	require.True(t, p.pending(now))
+
+	p.set("default", false, now)
+	require.True(t, p.pending(now), "another source still has queries pending")
+
+	require.False(t, p.pending(now.Add(interactiveQueriesWindow)), "results that never come back shouldn't count forever")
+
+	p.set("secondary", false, now)
+	require.False(t, p.pending(now))
+}
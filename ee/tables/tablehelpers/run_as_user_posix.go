@@ -41,11 +41,13 @@ func WithUid(uid string) ExecOps {
 			return fmt.Errorf("converting gid %s to int: %w", runningUser.Gid, err)
 		}
 
-		cmd.SysProcAttr = &syscall.SysProcAttr{
-			Credential: &syscall.Credential{
-				Uid: uint32(runningUserUid),
-				Gid: uint32(runningUserGid),
-			},
+		// Keep any existing attributes, e.g. the chroot into the host root
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid: uint32(runningUserUid),
+			Gid: uint32(runningUserGid),
 		}
 
 		// Set PWD and HOME to the running user's home directory for supporting executes that use them as the prefix to create temp files.
@@ -0,0 +1,86 @@
+//go:build darwin
+// +build darwin
+
+package tablehelpers
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"os/user"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+)
+
+// WithUserContext is a functional argument which modifies the input exec command to run as a specific
+// user, inside that user's login session. Some user-scoped data -- defaults domains, browser profiles,
+// anything backed by the user's keychain -- is only visible from within the session; WithUid changes
+// the uid the command runs as, but leaves it in launcher's (root's) session.
+//
+// The command is run as `launchctl asuser $UID sudo -u $USER <command>`. sudo resets the environment
+// apart from HOME, which is set to the user's home directory.
+func WithUserContext(uid string) ExecOps {
+	return func(cmd *exec.Cmd) error {
+		return runInUserContext(cmd, uid, true)
+	}
+}
+
+// WithUserSessionContext is like WithUserContext, except the command keeps running as the current
+// user (i.e. root) inside the user's login session, as `launchctl asuser $UID <command>`. Some tools --
+// osquery, for example -- need the session, but don't return the user's data when run under sudo.
+func WithUserSessionContext(uid string) ExecOps {
+	return func(cmd *exec.Cmd) error {
+		return runInUserContext(cmd, uid, false)
+	}
+}
+
+func runInUserContext(cmd *exec.Cmd, uid string, switchUser bool) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("getting current user: %w", err)
+	}
+
+	runningUser, err := user.LookupId(uid)
+	if err != nil {
+		return fmt.Errorf("looking up user with uid %s: %w", uid, err)
+	}
+
+	// If the current user is the user to run as, we're already able to run in their context
+	if currentUser.Uid == runningUser.Uid {
+		return nil
+	} else if currentUser.Uid != "0" {
+		return fmt.Errorf("current user %s is not root and can't start process in context of other user %s", currentUser.Uid, uid)
+	}
+
+	// We only want the validated paths of these commands, to prefix cmd with
+	launchctl, err := allowedcmd.Launchctl(context.Background())
+	if err != nil {
+		return fmt.Errorf("finding launchctl: %w", err)
+	}
+	prefix := []string{launchctl.Path, "asuser", runningUser.Uid}
+
+	if switchUser {
+		sudo, err := allowedcmd.Sudo(context.Background())
+		if err != nil {
+			return fmt.Errorf("finding sudo: %w", err)
+		}
+		prefix = append(prefix, sudo.Path, "--non-interactive", "--set-home", "-u", runningUser.Username)
+	}
+
+	prefixCommand(cmd, prefix)
+
+	return nil
+}
+
+// prefixCommand updates cmd to run the given command, with cmd's own command and arguments as its arguments.
+func prefixCommand(cmd *exec.Cmd, prefix []string) {
+	args := make([]string, 0, len(prefix)+len(cmd.Args))
+	args = append(args, prefix...)
+	args = append(args, cmd.Path)
+	if len(cmd.Args) > 1 {
+		args = append(args, cmd.Args[1:]...)
+	}
+
+	cmd.Path = prefix[0]
+	cmd.Args = args
+}
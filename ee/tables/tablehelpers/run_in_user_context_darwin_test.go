@@ -0,0 +1,42 @@
+//go:build darwin
+// +build darwin
+
+package tablehelpers
+
+import (
+	"os/exec"
+	"os/user"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithUserContext_CurrentUser(t *testing.T) {
+	t.Parallel()
+
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+
+	for _, opt := range []ExecOps{WithUserContext(currentUser.Uid), WithUserSessionContext(currentUser.Uid)} {
+		cmd := &exec.Cmd{Path: "/usr/bin/defaults", Args: []string{"/usr/bin/defaults", "read", "com.apple.dock"}}
+		require.NoError(t, opt(cmd))
+
+		// We're already in our own context, so the command should be unchanged
+		require.Equal(t, "/usr/bin/defaults", cmd.Path)
+		require.Equal(t, []string{"/usr/bin/defaults", "read", "com.apple.dock"}, cmd.Args)
+	}
+}
+
+func TestPrefixCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := &exec.Cmd{Path: "/usr/bin/defaults", Args: []string{"defaults", "read", "com.apple.dock"}}
+	prefixCommand(cmd, []string{"/bin/launchctl", "asuser", "501", "/usr/bin/sudo", "--non-interactive", "--set-home", "-u", "alice"})
+
+	require.Equal(t, "/bin/launchctl", cmd.Path)
+	require.Equal(t, []string{
+		"/bin/launchctl", "asuser", "501",
+		"/usr/bin/sudo", "--non-interactive", "--set-home", "-u", "alice",
+		"/usr/bin/defaults", "read", "com.apple.dock",
+	}, cmd.Args)
+}
@@ -0,0 +1,205 @@
+package tabletest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/stretchr/testify/require"
+)
+
+// execResponseEnvVar tells the re-executed test binary where to find the response it should give
+const execResponseEnvVar = "TABLETEST_EXEC_RESPONSE"
+
+// init stands in for the command when the recorder re-executes the test binary: it writes the
+// canned response and exits, before any tests run.
+func init() {
+	responsePath := os.Getenv(execResponseEnvVar)
+	if responsePath == "" {
+		return
+	}
+
+	raw, err := os.ReadFile(responsePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tabletest: reading exec response: %s\n", err)
+		os.Exit(127) //nolint:forbidigo // Fine to use os.Exit in the re-executed test binary
+	}
+
+	var resp ExecResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		fmt.Fprintf(os.Stderr, "tabletest: parsing exec response: %s\n", err)
+		os.Exit(127) //nolint:forbidigo // Fine to use os.Exit in the re-executed test binary
+	}
+
+	os.Stdout.WriteString(resp.Stdout)
+	os.Stderr.WriteString(resp.Stderr)
+	os.Exit(resp.ExitCode) //nolint:forbidigo // Fine to use os.Exit in the re-executed test binary
+}
+
+// ExecResponse is what a recorded command outputs.
+type ExecResponse struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// ExecCall is a command the table ran.
+type ExecCall struct {
+	Name string
+	Args []string
+	Dir  string   // the working directory, if the table set one
+	Env  []string // environment variables the table added
+}
+
+type execResponder struct {
+	name     string
+	args     []string // nil matches any arguments
+	response ExecResponse
+}
+
+type recordedCall struct {
+	name string
+	args []string
+	cmd  *exec.Cmd
+}
+
+// ExecRecorder stands in for allowedcmd commands. It records each command a table runs, and
+// responds with canned output, so tables can be tested without the real binaries. The commands
+// it returns run the test binary itself, which outputs the response, so they behave like real
+// commands to tablehelpers.Run -- including timeouts, stdout and stderr, and exit codes.
+type ExecRecorder struct {
+	t          *testing.T
+	dir        string
+	executable string
+	lock       sync.Mutex
+	responders []execResponder
+	calls      []recordedCall
+}
+
+func NewExecRecorder(t *testing.T) *ExecRecorder {
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	return &ExecRecorder{
+		t:          t,
+		dir:        t.TempDir(),
+		executable: executable,
+	}
+}
+
+// Respond sets the response to the named command when run with exactly these arguments, or with
+// any arguments if args is nil. Exact matches take precedence. Commands without a response fail
+// as though the binary doesn't exist.
+func (r *ExecRecorder) Respond(name string, args []string, response ExecResponse) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.responders = append(r.responders, execResponder{name: name, args: args, response: response})
+}
+
+// Command returns an allowedcmd.AllowedCommand for the named command, to pass to the table in
+// place of the real one.
+func (r *ExecRecorder) Command(name string) allowedcmd.AllowedCommand {
+	return func(ctx context.Context, args ...string) (*allowedcmd.TracedCmd, error) {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		call := recordedCall{name: name, args: slices.Clone(args)}
+
+		response, ok := r.response(name, args)
+		if !ok {
+			r.calls = append(r.calls, call)
+			return nil, fmt.Errorf("%w: no response set for %s %s", allowedcmd.ErrCommandNotFound, name, strings.Join(args, " "))
+		}
+
+		raw, err := json.Marshal(response)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling response: %w", err)
+		}
+		responsePath := filepath.Join(r.dir, fmt.Sprintf("response-%d.json", len(r.calls)))
+		if err := os.WriteFile(responsePath, raw, 0600); err != nil {
+			return nil, fmt.Errorf("writing response: %w", err)
+		}
+
+		cmd := exec.CommandContext(ctx, r.executable, args...) //nolint:forbidigo // The recorder runs the test binary in place of the command
+		cmd.Env = []string{fmt.Sprintf("%s=%s", execResponseEnvVar, responsePath)}
+		call.cmd = cmd
+		r.calls = append(r.calls, call)
+
+		return &allowedcmd.TracedCmd{Ctx: ctx, Cmd: cmd}, nil
+	}
+}
+
+func (r *ExecRecorder) response(name string, args []string) (ExecResponse, bool) {
+	var fallback *ExecResponse
+	for i, responder := range r.responders {
+		if responder.name != name {
+			continue
+		}
+		if responder.args == nil {
+			if fallback == nil {
+				fallback = &r.responders[i].response
+			}
+			continue
+		}
+		if slices.Equal(responder.args, args) {
+			return responder.response, true
+		}
+	}
+
+	if fallback != nil {
+		return *fallback, true
+	}
+	return ExecResponse{}, false
+}
+
+// Calls returns the commands the table ran, in order, including those without a response.
+func (r *ExecRecorder) Calls() []ExecCall {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	calls := make([]ExecCall, len(r.calls))
+	for i, c := range r.calls {
+		calls[i] = ExecCall{Name: c.name, Args: c.args}
+		if c.cmd == nil {
+			continue
+		}
+
+		// Options like tablehelpers.WithDir are applied to the command after it's created
+		calls[i].Dir = c.cmd.Dir
+		for _, env := range c.cmd.Env {
+			if !strings.HasPrefix(env, execResponseEnvVar+"=") {
+				calls[i].Env = append(calls[i].Env, env)
+			}
+		}
+	}
+
+	return calls
+}
+
+// RequireCalls asserts that the table ran exactly these commands, in order. Only the names and
+// arguments are compared.
+func (r *ExecRecorder) RequireCalls(expected ...ExecCall) {
+	r.t.Helper()
+
+	require.Equal(r.t, namesAndArgs(expected), namesAndArgs(r.Calls()))
+}
+
+func namesAndArgs(calls []ExecCall) []ExecCall {
+	stripped := make([]ExecCall, len(calls))
+	for i, c := range calls {
+		stripped[i] = ExecCall{Name: c.Name}
+		if len(c.Args) > 0 {
+			stripped[i].Args = c.Args
+		}
+	}
+	return stripped
+}
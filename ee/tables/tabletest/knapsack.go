@@ -0,0 +1,113 @@
+package tabletest
+
+import (
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/flags"
+	"github.com/kolide/launcher/ee/agent/knapsack"
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/launcher"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+// storeNames are the stores the fake knapsack provides
+var storeNames = []storage.Store{
+	storage.AgentFlagsStore,
+	storage.KatcConfigStore,
+	storage.AutoupdateErrorsStore,
+	storage.ConfigStore,
+	storage.ControlStore,
+	storage.PersistentHostDataStore,
+	storage.InitialResultsStore,
+	storage.ResultLogsStore,
+	storage.OsqueryHistoryInstanceStore,
+	storage.SentNotificationsStore,
+	storage.StatusLogsStore,
+	storage.ServerProvidedDataStore,
+	storage.TokenStore,
+	storage.ControlServerActionsStore,
+	storage.LauncherHistoryStore,
+	storage.JournaldCursorStore,
+	storage.SnapshotDiffStore,
+	storage.FimConfigStore,
+	storage.ListeningServicesStore,
+	storage.HostsFileWatchStore,
+	storage.EnrollmentAttemptsStore,
+	storage.ControlActionHistoryStore,
+	storage.NetworkChangeEventsStore,
+	storage.ControlSigningKeysStore,
+	storage.OsquerydSelectionStore,
+}
+
+type knapsackConfig struct {
+	opts        *launcher.Options
+	querier     types.InstanceQuerier
+	storeValues map[storage.Store]map[string][]byte
+}
+
+// KnapsackOption configures the knapsack returned by NewKnapsack.
+type KnapsackOption func(*knapsackConfig)
+
+// WithLauncherOptions sets the command-line options the knapsack's flags are based on. If the
+// options don't set a root directory, a temporary one is used.
+func WithLauncherOptions(opts *launcher.Options) KnapsackOption {
+	return func(kc *knapsackConfig) {
+		kc.opts = opts
+	}
+}
+
+// WithInstanceQuerier sets the querier the knapsack uses to query osquery.
+func WithInstanceQuerier(querier types.InstanceQuerier) KnapsackOption {
+	return func(kc *knapsackConfig) {
+		kc.querier = querier
+	}
+}
+
+// WithStoreValue seeds the given store with a value before the test starts.
+func WithStoreValue(store storage.Store, key string, value []byte) KnapsackOption {
+	return func(kc *knapsackConfig) {
+		if kc.storeValues[store] == nil {
+			kc.storeValues[store] = make(map[string][]byte)
+		}
+		kc.storeValues[store][key] = value
+	}
+}
+
+// NewKnapsack returns a real knapsack, backed by in-memory stores, for tables that need one.
+// Unlike a mock, it doesn't need to be told about every call the table makes, so tests don't
+// break when a table starts reading another flag.
+func NewKnapsack(t *testing.T, opts ...KnapsackOption) types.Knapsack {
+	kc := &knapsackConfig{
+		opts:        &launcher.Options{},
+		storeValues: make(map[storage.Store]map[string][]byte),
+	}
+	for _, opt := range opts {
+		opt(kc)
+	}
+
+	if kc.opts.RootDirectory == "" {
+		kc.opts.RootDirectory = t.TempDir()
+	}
+
+	stores := make(map[storage.Store]types.KVStore, len(storeNames))
+	for _, storeName := range storeNames {
+		store := inmemory.NewStore()
+		for key, value := range kc.storeValues[storeName] {
+			require.NoError(t, store.Set([]byte(key), value))
+		}
+		stores[storeName] = store
+	}
+
+	slogger := multislogger.New()
+	flagController := flags.NewFlagController(slogger.Logger, stores[storage.AgentFlagsStore], flags.WithCmdLineOpts(kc.opts))
+
+	k := knapsack.New(stores, flagController, nil, slogger, slogger)
+	if kc.querier != nil {
+		k.SetInstanceQuerier(kc.querier)
+	}
+
+	return k
+}
@@ -0,0 +1,47 @@
+package tabletest
+
+import (
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// QueryContextBuilder builds the query context osquery would pass a table for a query's WHERE
+// clause, e.g.
+//
+//	tabletest.NewQueryContext().Equals("name", "a", "b").Where("path", table.OperatorLike, "/tmp/%").Build()
+//
+// is the context for `WHERE (name = 'a' OR name = 'b') AND path LIKE '/tmp/%'`.
+type QueryContextBuilder struct {
+	queryContext table.QueryContext
+}
+
+func NewQueryContext() *QueryContextBuilder {
+	return &QueryContextBuilder{
+		queryContext: table.QueryContext{
+			Constraints: make(map[string]table.ConstraintList),
+		},
+	}
+}
+
+// Equals adds an equality constraint on the column for each value.
+func (b *QueryContextBuilder) Equals(column string, values ...string) *QueryContextBuilder {
+	for _, value := range values {
+		b.Where(column, table.OperatorEquals, value)
+	}
+	return b
+}
+
+// Where adds a constraint on the column.
+func (b *QueryContextBuilder) Where(column string, operator table.Operator, expression string) *QueryContextBuilder {
+	constraintList := b.queryContext.Constraints[column]
+	constraintList.Constraints = append(constraintList.Constraints, table.Constraint{
+		Operator:   operator,
+		Expression: expression,
+	})
+	b.queryContext.Constraints[column] = constraintList
+	return b
+}
+
+// Build returns the query context.
+func (b *QueryContextBuilder) Build() table.QueryContext {
+	return b.queryContext
+}
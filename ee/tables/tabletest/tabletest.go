@@ -0,0 +1,145 @@
+// Package tabletest is a harness for testing launcher tables. It provides a knapsack backed by
+// in-memory stores, a builder for query contexts, a way to run a table plugin the way osquery
+// does, assertions against expected or golden rows, and a recorder that stands in for
+// allowedcmd commands, so tables that exec can be tested with canned output.
+//
+// A typical test looks like:
+//
+//	execs := tabletest.NewExecRecorder(t)
+//	execs.Respond("example", []string{"--json"}, tabletest.ExecResponse{Stdout: `{"enabled": true}`})
+//
+//	plugin := TablePlugin(multislogger.NewNopLogger(), execs.Command("example"))
+//	rows := tabletest.Generate(t, plugin, tabletest.NewQueryContext().Equals("name", "a").Build())
+//	tabletest.RequireGoldenRows(t, filepath.Join("testdata", "example.golden.json"), rows)
+package tabletest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+// UpdateGoldenEnvVar is the environment variable that, when set to 1, makes RequireGoldenRows
+// write the rows it's given to the golden file instead of comparing them.
+const UpdateGoldenEnvVar = "TABLETEST_UPDATE_GOLDEN"
+
+// Generate runs the table plugin with the given query context, as osquery would, and returns the
+// rows it generates. It fails the test if the table returns an error, if the query context
+// constrains a column the table doesn't have, or if a row has a column the table doesn't
+// declare -- osquery would silently drop it.
+func Generate(t *testing.T, plugin *table.Plugin, queryContext table.QueryContext) []map[string]string {
+	t.Helper()
+
+	columnTypes := make(map[string]string)
+	for _, column := range plugin.Routes() {
+		columnTypes[column["name"]] = column["type"]
+	}
+
+	type constraintJSON struct {
+		Op   table.Operator `json:"op"`
+		Expr string         `json:"expr"`
+	}
+	type constraintListJSON struct {
+		Name     string           `json:"name"`
+		Affinity string           `json:"affinity"`
+		List     []constraintJSON `json:"list"`
+	}
+
+	var constraintLists []constraintListJSON
+	for column, constraintList := range queryContext.Constraints {
+		affinity, ok := columnTypes[column]
+		require.True(t, ok, "query context constrains %s, which is not a column of %s", column, plugin.Name())
+
+		list := make([]constraintJSON, len(constraintList.Constraints))
+		for i, c := range constraintList.Constraints {
+			list[i] = constraintJSON{Op: c.Operator, Expr: c.Expression}
+		}
+		constraintLists = append(constraintLists, constraintListJSON{Name: column, Affinity: affinity, List: list})
+	}
+	sort.Slice(constraintLists, func(i, j int) bool { return constraintLists[i].Name < constraintLists[j].Name })
+
+	contextJSON, err := json.Marshal(map[string]any{"constraints": constraintLists})
+	require.NoError(t, err)
+
+	resp := plugin.Call(context.TODO(), map[string]string{
+		"action":  "generate",
+		"context": string(contextJSON),
+	})
+	require.Equal(t, int32(0), resp.Status.Code, "generating %s: %s", plugin.Name(), resp.Status.Message)
+
+	rows := make([]map[string]string, len(resp.Response))
+	for i, row := range resp.Response {
+		for column := range row {
+			_, ok := columnTypes[column]
+			require.True(t, ok, "row %d has column %s, which %s does not declare", i, column, plugin.Name())
+		}
+		rows[i] = row
+	}
+
+	return rows
+}
+
+// RequireRows asserts that the rows are the expected ones, in any order.
+func RequireRows(t *testing.T, expected, actual []map[string]string) {
+	t.Helper()
+
+	require.Equal(t, sortedRows(t, expected), sortedRows(t, actual))
+}
+
+// RequireGoldenRows asserts that the rows match those in the golden file, a JSON array of rows,
+// in any order. Run the test with TABLETEST_UPDATE_GOLDEN=1 to create or update the file.
+func RequireGoldenRows(t *testing.T, goldenPath string, rows []map[string]string) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnvVar) == "1" {
+		golden, err := json.MarshalIndent(sortedRows(t, rows), "", "  ")
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(goldenPath), 0755))
+		require.NoError(t, os.WriteFile(goldenPath, append(golden, '\n'), 0644))
+		return
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		require.FailNow(t, "golden file does not exist", "run with %s=1 to create %s", UpdateGoldenEnvVar, goldenPath)
+	}
+	require.NoError(t, err)
+
+	var expected []map[string]string
+	require.NoError(t, json.Unmarshal(golden, &expected), "parsing golden file %s", goldenPath)
+
+	RequireRows(t, expected, rows)
+}
+
+// sortedRows returns the rows in a canonical order, so that comparisons ignore the order the
+// table generated them in. An empty result and a nil result are the same.
+func sortedRows(t *testing.T, rows []map[string]string) []map[string]string {
+	type keyedRow struct {
+		key string
+		row map[string]string
+	}
+
+	keyed := make([]keyedRow, len(rows))
+	for i, row := range rows {
+		// Maps marshal with sorted keys, so this is a stable key for the row's contents
+		key, err := json.Marshal(row)
+		require.NoError(t, err)
+		keyed[i] = keyedRow{key: string(key), row: row}
+	}
+	sort.SliceStable(keyed, func(i, j int) bool { return keyed[i].key < keyed[j].key })
+
+	sorted := make([]map[string]string, len(keyed))
+	for i, k := range keyed {
+		sorted[i] = k.row
+	}
+
+	return sorted
+}
@@ -0,0 +1,175 @@
+package tabletest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/launcher"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+// exampleTable execs a command for each name it's constrained on, and reports its output along
+// with a value from the knapsack.
+func exampleTable(k types.Knapsack, cmd allowedcmd.AllowedCommand) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("output"),
+		table.TextColumn("error"),
+		table.TextColumn("root_directory"),
+		table.TextColumn("hardware_uuid"),
+	}
+
+	return table.NewPlugin("kolide_example", columns, func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		uuid, err := k.PersistentHostDataStore().Get([]byte("hardware_uuid"))
+		if err != nil {
+			return nil, err
+		}
+
+		// GetConstraints doesn't preserve the constraints' order, so sort them to exec in a known order
+		names := tablehelpers.GetConstraints(queryContext, "name", tablehelpers.WithDefaults("default"))
+		sort.Strings(names)
+
+		var results []map[string]string
+		for _, name := range names {
+			row := map[string]string{"name": name, "root_directory": k.RootDirectory(), "hardware_uuid": string(uuid)}
+
+			output, err := tablehelpers.RunSimple(ctx, multislogger.NewNopLogger(), 10, cmd, []string{"show", name}, tablehelpers.WithAppendEnv("EXAMPLE_NAME", name))
+			if err != nil {
+				row["error"] = err.Error()
+			}
+			row["output"] = strings.TrimSpace(string(output))
+
+			results = append(results, row)
+		}
+
+		return results, nil
+	})
+}
+
+func TestExampleTable(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	k := NewKnapsack(t,
+		WithLauncherOptions(&launcher.Options{RootDirectory: rootDir}),
+		WithStoreValue(storage.PersistentHostDataStore, "hardware_uuid", []byte("abc-123")),
+	)
+
+	execs := NewExecRecorder(t)
+	execs.Respond("example", []string{"show", "alpha"}, ExecResponse{Stdout: "alpha output\n"})
+	execs.Respond("example", []string{"show", "beta"}, ExecResponse{Stdout: "partial", Stderr: "failed", ExitCode: 2})
+
+	rows := Generate(t, exampleTable(k, execs.Command("example")), NewQueryContext().Equals("name", "alpha", "beta", "gamma").Build())
+	require.Len(t, rows, 3)
+
+	for _, row := range rows {
+		require.Equal(t, rootDir, row["root_directory"])
+		require.Equal(t, "abc-123", row["hardware_uuid"])
+
+		switch row["name"] {
+		case "alpha":
+			require.Equal(t, "alpha output", row["output"])
+			require.Empty(t, row["error"])
+		case "beta":
+			require.Empty(t, row["output"], "RunSimple discards output on failure")
+			require.Contains(t, row["error"], "exit status 2")
+		case "gamma":
+			require.Contains(t, row["error"], allowedcmd.ErrCommandNotFound.Error())
+		}
+	}
+
+	execs.RequireCalls(
+		ExecCall{Name: "example", Args: []string{"show", "alpha"}},
+		ExecCall{Name: "example", Args: []string{"show", "beta"}},
+		ExecCall{Name: "example", Args: []string{"show", "gamma"}},
+	)
+	require.Equal(t, []string{"EXAMPLE_NAME=alpha"}, execs.Calls()[0].Env)
+}
+
+func TestExecRecorder_AnyArgs(t *testing.T) {
+	t.Parallel()
+
+	execs := NewExecRecorder(t)
+	execs.Respond("example", nil, ExecResponse{Stdout: "anything"})
+	execs.Respond("example", []string{"--version"}, ExecResponse{Stdout: "1.0.0"})
+
+	out, err := tablehelpers.RunSimple(context.TODO(), multislogger.NewNopLogger(), 10, execs.Command("example"), []string{"--list"})
+	require.NoError(t, err)
+	require.Equal(t, "anything", string(out))
+
+	out, err = tablehelpers.RunSimple(context.TODO(), multislogger.NewNopLogger(), 10, execs.Command("example"), []string{"--version"})
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0", string(out))
+
+	_, err = tablehelpers.RunSimple(context.TODO(), multislogger.NewNopLogger(), 10, execs.Command("other"), nil)
+	require.True(t, errors.Is(err, allowedcmd.ErrCommandNotFound))
+
+	execs.RequireCalls(
+		ExecCall{Name: "example", Args: []string{"--list"}},
+		ExecCall{Name: "example", Args: []string{"--version"}},
+		ExecCall{Name: "other"},
+	)
+}
+
+func TestQueryContextBuilder(t *testing.T) {
+	t.Parallel()
+
+	qc := NewQueryContext().
+		Equals("name", "a", "b").
+		Where("path", table.OperatorLike, "/tmp/%").
+		Build()
+
+	require.ElementsMatch(t, []string{"a", "b"}, tablehelpers.GetConstraints(qc, "name"))
+	require.Equal(t, table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"name": {Constraints: []table.Constraint{
+				{Operator: table.OperatorEquals, Expression: "a"},
+				{Operator: table.OperatorEquals, Expression: "b"},
+			}},
+			"path": {Constraints: []table.Constraint{
+				{Operator: table.OperatorLike, Expression: "/tmp/%"},
+			}},
+		},
+	}, qc)
+
+	// Round trips through the plugin, as osquery would send it
+	var received table.QueryContext
+	plugin := table.NewPlugin("kolide_example", []table.ColumnDefinition{table.TextColumn("name"), table.TextColumn("path")},
+		func(_ context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+			received = queryContext
+			return nil, nil
+		})
+	require.Empty(t, Generate(t, plugin, qc))
+	require.Equal(t, []string{"a", "b"}, tablehelpers.GetConstraints(received, "name"))
+	require.Equal(t, table.ColumnType(table.ColumnTypeText), received.Constraints["path"].Affinity)
+	require.Equal(t, qc.Constraints["path"].Constraints, received.Constraints["path"].Constraints)
+}
+
+func TestRequireRows(t *testing.T) {
+	t.Parallel()
+
+	RequireRows(t,
+		[]map[string]string{{"name": "b"}, {"name": "a", "value": "1"}},
+		[]map[string]string{{"name": "a", "value": "1"}, {"name": "b"}},
+	)
+	RequireRows(t, nil, []map[string]string{})
+}
+
+func TestRequireGoldenRows(t *testing.T) {
+	t.Parallel()
+
+	RequireGoldenRows(t, filepath.Join("testdata", "example.golden.json"), []map[string]string{
+		{"name": "beta", "output": "", "error": "exit status 2"},
+		{"name": "alpha", "output": "alpha output", "error": ""},
+	})
+}
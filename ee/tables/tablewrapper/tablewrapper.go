@@ -0,0 +1,137 @@
+// Package tablewrapper provides a streaming way to write tables that can produce very large
+// outputs, like file walks and log reads. Rather than build the whole []map[string]string, a
+// streaming table writes rows as it generates them, so that it doesn't also hold everything it
+// read to generate them. The rows are bounded: once a table reaches its output limit, further
+// writes fail, and the table stops early, returning what it has so far.
+//
+// The rows still go to osquery in a single response -- the extension protocol has no way to
+// send them in parts -- so the output limit is what bounds memory use.
+package tablewrapper
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	// DefaultMaxBytes is the default limit on the size of a table's rows, counting the lengths
+	// of their column names and values.
+	DefaultMaxBytes = 64 * 1024 * 1024
+)
+
+// ErrOutputLimit is returned by RowWriter.Write once the table has reached its output limit.
+var ErrOutputLimit = errors.New("table output limit reached")
+
+// StreamFunc generates a table's rows, writing them to w. It should stop, returning the error,
+// when Write fails.
+type StreamFunc func(ctx context.Context, queryContext table.QueryContext, w *RowWriter) error
+
+// RowWriter collects the rows a streaming table generates, up to the table's output limit.
+type RowWriter struct {
+	rows     []map[string]string
+	size     int
+	maxRows  int
+	maxBytes int
+}
+
+// NewRowWriter returns a RowWriter limited to maxRows rows, and maxBytes bytes. Zero means no
+// limit. Tables get theirs from NewStreaming; this is for testing their StreamFuncs.
+func NewRowWriter(maxRows, maxBytes int) *RowWriter {
+	return &RowWriter{
+		maxRows:  maxRows,
+		maxBytes: maxBytes,
+	}
+}
+
+// Write adds rows to the table's results. If adding a row would take the table past its
+// output limit, the row is dropped, and Write returns ErrOutputLimit.
+func (w *RowWriter) Write(rows ...map[string]string) error {
+	for _, row := range rows {
+		size := rowSize(row)
+		if w.maxRows > 0 && len(w.rows) >= w.maxRows {
+			return ErrOutputLimit
+		}
+		if w.maxBytes > 0 && w.size+size > w.maxBytes {
+			return ErrOutputLimit
+		}
+
+		w.rows = append(w.rows, row)
+		w.size += size
+	}
+
+	return nil
+}
+
+// Rows returns the rows written so far.
+func (w *RowWriter) Rows() []map[string]string {
+	return w.rows
+}
+
+func rowSize(row map[string]string) int {
+	size := 0
+	for k, v := range row {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+type streamingTable struct {
+	slogger  *slog.Logger
+	stream   StreamFunc
+	maxRows  int
+	maxBytes int
+}
+
+type Option func(*streamingTable)
+
+// WithMaxRows limits the number of rows the table returns. By default, the number of rows isn't
+// limited.
+func WithMaxRows(maxRows int) Option {
+	return func(t *streamingTable) {
+		t.maxRows = maxRows
+	}
+}
+
+// WithMaxBytes limits the size of the rows the table returns, instead of DefaultMaxBytes. Zero
+// removes the limit.
+func WithMaxBytes(maxBytes int) Option {
+	return func(t *streamingTable) {
+		t.maxBytes = maxBytes
+	}
+}
+
+// NewStreaming returns a table plugin that generates its rows with stream.
+func NewStreaming(slogger *slog.Logger, name string, columns []table.ColumnDefinition, stream StreamFunc, opts ...Option) *table.Plugin {
+	t := &streamingTable{
+		slogger:  slogger.With("table", name),
+		stream:   stream,
+		maxBytes: DefaultMaxBytes,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return table.NewPlugin(name, columns, t.generate)
+}
+
+func (t *streamingTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	w := NewRowWriter(t.maxRows, t.maxBytes)
+
+	if err := t.stream(ctx, queryContext, w); err != nil {
+		if !errors.Is(err, ErrOutputLimit) {
+			return nil, err
+		}
+
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"table output limit reached, returning partial results",
+			"row_count", len(w.rows),
+			"size", w.size,
+		)
+	}
+
+	return w.rows, nil
+}
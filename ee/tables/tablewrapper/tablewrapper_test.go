@@ -0,0 +1,98 @@
+package tablewrapper
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStream writes rows numbered 0 to n-1, one at a time, and records how many it wrote.
+func countingStream(n int, written *int) StreamFunc {
+	return func(_ context.Context, _ table.QueryContext, w *RowWriter) error {
+		for i := 0; i < n; i++ {
+			if err := w.Write(map[string]string{"n": strconv.Itoa(i)}); err != nil {
+				return err
+			}
+			*written++
+		}
+		return nil
+	}
+}
+
+func TestStreamingTable(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testCaseName    string
+		opts            []Option
+		expectedRows    int
+		expectedWritten int
+	}{
+		{
+			testCaseName:    "no limit reached",
+			expectedRows:    10,
+			expectedWritten: 10,
+		},
+		{
+			testCaseName:    "row limit",
+			opts:            []Option{WithMaxRows(4)},
+			expectedRows:    4,
+			expectedWritten: 4,
+		},
+		{
+			// Each row is 2 bytes: the column name, and a single digit
+			testCaseName:    "byte limit",
+			opts:            []Option{WithMaxBytes(7)},
+			expectedRows:    3,
+			expectedWritten: 3,
+		},
+		{
+			testCaseName:    "byte limit removed",
+			opts:            []Option{WithMaxBytes(0)},
+			expectedRows:    10,
+			expectedWritten: 10,
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			written := 0
+			st := &streamingTable{
+				slogger:  multislogger.NewNopLogger(),
+				stream:   countingStream(10, &written),
+				maxBytes: DefaultMaxBytes,
+			}
+			for _, opt := range tt.opts {
+				opt(st)
+			}
+
+			rows, err := st.generate(context.TODO(), table.QueryContext{})
+			require.NoError(t, err)
+			require.Len(t, rows, tt.expectedRows)
+			require.Equal(t, "0", rows[0]["n"])
+			require.Equal(t, tt.expectedWritten, written, "table should stop generating at the limit")
+		})
+	}
+}
+
+func TestStreamingTable_Error(t *testing.T) {
+	t.Parallel()
+
+	st := &streamingTable{
+		slogger: multislogger.NewNopLogger(),
+		stream: func(_ context.Context, _ table.QueryContext, w *RowWriter) error {
+			require.NoError(t, w.Write(map[string]string{"n": "0"}))
+			return errors.New("bad constraint")
+		},
+	}
+
+	rows, err := st.generate(context.TODO(), table.QueryContext{})
+	require.Error(t, err)
+	require.Nil(t, rows)
+}
@@ -0,0 +1,422 @@
+package tcc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TCC stores the code requirement for each client as a compiled blob. decodeCsreq
+// decompiles it back into the requirement language, approximating the output of
+// `csreq -t`. See Security.framework's requirement.h and reqdumper.cpp for the format.
+
+const (
+	requirementMagic     = 0xfade0c00
+	requirementKindExpr  = 1
+	requirementHeaderLen = 12
+
+	// The high byte of an opcode holds flags; we don't need any of them.
+	opFlagMask = 0xff000000
+)
+
+const (
+	opFalse = iota
+	opTrue
+	opIdent
+	opAppleAnchor
+	opAnchorHash
+	opInfoKeyValue
+	opAnd
+	opOr
+	opCDHash
+	opNot
+	opInfoKeyField
+	opCertField
+	opTrustedCert
+	opTrustedCerts
+	opCertGeneric
+	opAppleGenericAnchor
+	opEntitlementField
+	opCertPolicy
+	opNamedAnchor
+	opNamedCode
+	opPlatform
+	opNotarized
+	opCertFieldDate
+	opLegacyDevID
+)
+
+const (
+	matchExists = iota
+	matchEqual
+	matchContains
+	matchBeginsWith
+	matchEndsWith
+	matchLessThan
+	matchGreaterThan
+	matchLessEqual
+	matchGreaterEqual
+	matchOn
+	matchBefore
+	matchAfter
+	matchOnOrBefore
+	matchOnOrAfter
+	matchAbsent
+)
+
+// Operator precedence, used to decide when to parenthesize
+const (
+	precOr = iota + 1
+	precAnd
+	precPrimary
+)
+
+// decodeCsreq decompiles a code requirement blob into its text form.
+func decodeCsreq(blob []byte) (string, error) {
+	if len(blob) < requirementHeaderLen {
+		return "", errors.New("csreq too short")
+	}
+
+	if magic := binary.BigEndian.Uint32(blob[0:4]); magic != requirementMagic {
+		return "", fmt.Errorf("unexpected csreq magic 0x%x", magic)
+	}
+
+	length := binary.BigEndian.Uint32(blob[4:8])
+	if int(length) > len(blob) || length < requirementHeaderLen {
+		return "", fmt.Errorf("csreq length %d does not match blob length %d", length, len(blob))
+	}
+
+	if kind := binary.BigEndian.Uint32(blob[8:12]); kind != requirementKindExpr {
+		return "", fmt.Errorf("unsupported csreq kind %d", kind)
+	}
+
+	r := &requirementReader{data: blob[requirementHeaderLen:length]}
+	expr, _, err := r.expression()
+	if err != nil {
+		return "", err
+	}
+
+	return expr, nil
+}
+
+type requirementReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *requirementReader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, errors.New("csreq truncated")
+	}
+	v := binary.BigEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+// bytes reads length-prefixed data, which is padded to a 4 byte boundary.
+func (r *requirementReader) bytes() ([]byte, error) {
+	length, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.pos+int(length) > len(r.data) {
+		return nil, errors.New("csreq truncated")
+	}
+	b := r.data[r.pos : r.pos+int(length)]
+
+	r.pos += int(length)
+	if pad := r.pos % 4; pad != 0 {
+		r.pos += 4 - pad
+	}
+
+	return b, nil
+}
+
+func (r *requirementReader) string() (string, error) {
+	b, err := r.bytes()
+	if err != nil {
+		return "", err
+	}
+	return quoteRequirementString(string(b)), nil
+}
+
+func (r *requirementReader) certSlot() (string, error) {
+	slot, err := r.uint32()
+	if err != nil {
+		return "", err
+	}
+
+	switch int32(slot) {
+	case 0:
+		return "leaf", nil
+	case -1:
+		return "root", nil
+	}
+	return strconv.Itoa(int(int32(slot))), nil
+}
+
+func (r *requirementReader) oid() (string, error) {
+	b, err := r.bytes()
+	if err != nil {
+		return "", err
+	}
+	return decodeOID(b)
+}
+
+// expression reads a single expression, returning it and its precedence.
+func (r *requirementReader) expression() (string, int, error) {
+	op, err := r.uint32()
+	if err != nil {
+		return "", 0, err
+	}
+
+	switch op &^ opFlagMask {
+	case opFalse:
+		return "never", precPrimary, nil
+	case opTrue:
+		return "always", precPrimary, nil
+	case opAppleAnchor:
+		return "anchor apple", precPrimary, nil
+	case opAppleGenericAnchor:
+		return "anchor apple generic", precPrimary, nil
+	case opTrustedCerts:
+		return "anchor trusted", precPrimary, nil
+	case opNotarized:
+		return "notarized", precPrimary, nil
+	case opLegacyDevID:
+		return "legacy", precPrimary, nil
+
+	case opIdent:
+		s, err := r.string()
+		return "identifier " + s, precPrimary, err
+
+	case opNamedAnchor:
+		s, err := r.string()
+		return "anchor apple " + s, precPrimary, err
+
+	case opNamedCode:
+		s, err := r.string()
+		return "(" + s + ")", precPrimary, err
+
+	case opCDHash:
+		b, err := r.bytes()
+		return fmt.Sprintf(`cdhash H"%s"`, hex.EncodeToString(b)), precPrimary, err
+
+	case opPlatform:
+		platform, err := r.uint32()
+		return fmt.Sprintf("platform = %d", platform), precPrimary, err
+
+	case opAnchorHash:
+		slot, err := r.certSlot()
+		if err != nil {
+			return "", 0, err
+		}
+		b, err := r.bytes()
+		return fmt.Sprintf(`certificate %s = H"%s"`, slot, hex.EncodeToString(b)), precPrimary, err
+
+	case opTrustedCert:
+		slot, err := r.certSlot()
+		return fmt.Sprintf("certificate %s trusted", slot), precPrimary, err
+
+	case opInfoKeyValue:
+		key, err := r.string()
+		if err != nil {
+			return "", 0, err
+		}
+		value, err := r.string()
+		return fmt.Sprintf("info[%s] = %s", key, value), precPrimary, err
+
+	case opInfoKeyField, opEntitlementField:
+		prefix := "info"
+		if op&^opFlagMask == opEntitlementField {
+			prefix = "entitlement"
+		}
+		key, err := r.string()
+		if err != nil {
+			return "", 0, err
+		}
+		match, err := r.match()
+		return fmt.Sprintf("%s[%s]%s", prefix, key, match), precPrimary, err
+
+	case opCertField:
+		slot, err := r.certSlot()
+		if err != nil {
+			return "", 0, err
+		}
+		// Field names like subject.OU are printed bare
+		field, err := r.bytes()
+		if err != nil {
+			return "", 0, err
+		}
+		match, err := r.match()
+		return fmt.Sprintf("certificate %s[%s]%s", slot, field, match), precPrimary, err
+
+	case opCertGeneric, opCertPolicy, opCertFieldDate:
+		prefix := map[uint32]string{opCertGeneric: "field", opCertPolicy: "policy", opCertFieldDate: "timestamp"}[op&^opFlagMask]
+		slot, err := r.certSlot()
+		if err != nil {
+			return "", 0, err
+		}
+		oid, err := r.oid()
+		if err != nil {
+			return "", 0, err
+		}
+		match, err := r.match()
+		return fmt.Sprintf("certificate %s[%s.%s]%s", slot, prefix, oid, match), precPrimary, err
+
+	case opNot:
+		expr, prec, err := r.expression()
+		if err != nil {
+			return "", 0, err
+		}
+		if prec < precPrimary {
+			expr = "(" + expr + ")"
+		}
+		return "! " + expr, precPrimary, nil
+
+	case opAnd, opOr:
+		prec, joiner := precAnd, " and "
+		if op&^opFlagMask == opOr {
+			prec, joiner = precOr, " or "
+		}
+
+		left, leftPrec, err := r.expression()
+		if err != nil {
+			return "", 0, err
+		}
+		right, rightPrec, err := r.expression()
+		if err != nil {
+			return "", 0, err
+		}
+
+		if leftPrec < prec {
+			left = "(" + left + ")"
+		}
+		if rightPrec < prec {
+			right = "(" + right + ")"
+		}
+		return left + joiner + right, prec, nil
+	}
+
+	return "", 0, fmt.Errorf("unknown csreq opcode %d", op)
+}
+
+// match reads a match suffix, such as ` = "value"` or ` /* exists */`.
+func (r *requirementReader) match() (string, error) {
+	op, err := r.uint32()
+	if err != nil {
+		return "", err
+	}
+
+	switch op {
+	case matchExists:
+		return " /* exists */", nil
+	case matchAbsent:
+		return " absent", nil
+	}
+
+	var value string
+	switch op {
+	case matchOn, matchBefore, matchAfter, matchOnOrBefore, matchOnOrAfter:
+		// Timestamps are stored as a big endian int64 of seconds since 2001-01-01
+		ts, err := r.uint32()
+		if err != nil {
+			return "", err
+		}
+		tsLow, err := r.uint32()
+		if err != nil {
+			return "", err
+		}
+		value = fmt.Sprintf("<%d>", int64(ts)<<32|int64(tsLow))
+	default:
+		value, err = r.string()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch op {
+	case matchEqual, matchOn:
+		return " = " + value, nil
+	case matchContains:
+		return " ~ " + value, nil
+	case matchBeginsWith:
+		return " = " + wildcardSuffix(value), nil
+	case matchEndsWith:
+		return " = " + wildcardPrefix(value), nil
+	case matchLessThan, matchBefore:
+		return " < " + value, nil
+	case matchGreaterThan, matchAfter:
+		return " > " + value, nil
+	case matchLessEqual, matchOnOrBefore:
+		return " <= " + value, nil
+	case matchGreaterEqual, matchOnOrAfter:
+		return " >= " + value, nil
+	}
+
+	return "", fmt.Errorf("unknown csreq match operation %d", op)
+}
+
+// wildcardSuffix and wildcardPrefix add a wildcard to a possibly quoted string,
+// keeping it inside the quotes.
+func wildcardSuffix(s string) string {
+	if strings.HasSuffix(s, `"`) {
+		return strings.TrimSuffix(s, `"`) + `*"`
+	}
+	return s + "*"
+}
+
+func wildcardPrefix(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		return `"*` + strings.TrimPrefix(s, `"`)
+	}
+	return "*" + s
+}
+
+// quoteRequirementString quotes s, unless it's a plain alphanumeric token.
+func quoteRequirementString(s string) string {
+	if s != "" && strings.IndexFunc(s, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9')
+	}) == -1 {
+		return s
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// decodeOID decodes the content octets of a DER encoded object identifier.
+func decodeOID(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", errors.New("empty oid")
+	}
+
+	var parts []string
+	var value uint64
+	for i, c := range b {
+		value = value<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return "", errors.New("truncated oid")
+			}
+			continue
+		}
+
+		if len(parts) == 0 {
+			// The first value encodes the first two arcs
+			first := value / 40
+			if first > 2 {
+				first = 2
+			}
+			parts = append(parts, strconv.FormatUint(first, 10), strconv.FormatUint(value-first*40, 10))
+		} else {
+			parts = append(parts, strconv.FormatUint(value, 10))
+		}
+		value = 0
+	}
+
+	return strings.Join(parts, "."), nil
+}
@@ -0,0 +1,130 @@
+package tcc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requirementBuilder assembles a compiled requirement, for testing the decoder.
+type requirementBuilder []byte
+
+func (b requirementBuilder) op(v uint32) requirementBuilder {
+	return binary.BigEndian.AppendUint32(b, v)
+}
+
+func (b requirementBuilder) data(d []byte) requirementBuilder {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(d)))
+	b = append(b, d...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func (b requirementBuilder) str(s string) requirementBuilder {
+	return b.data([]byte(s))
+}
+
+func (b requirementBuilder) blob() []byte {
+	out := binary.BigEndian.AppendUint32(nil, requirementMagic)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(b)+requirementHeaderLen))
+	out = binary.BigEndian.AppendUint32(out, requirementKindExpr)
+	return append(out, b...)
+}
+
+func TestDecodeCsreq(t *testing.T) {
+	t.Parallel()
+
+	// OID 1.2.840.113635.100.6.2.6 (Developer ID CA) and 1.2.840.113635.100.6.1.13 (Developer ID app)
+	devIdCA := mustDecodeHex(t, "2a864886f76364060206")
+	devIdApp := mustDecodeHex(t, "2a864886f7636406010d")
+
+	var tests = []struct {
+		name     string
+		blob     []byte
+		expected string
+	}{
+		{
+			name:     "identifier and anchor apple, as produced by macOS",
+			blob:     mustDecodeHex(t, "fade0c000000003000000001000000060000000200000012636f6d2e6170706c652e5465726d696e616c000000000003"),
+			expected: `identifier "com.apple.Terminal" and anchor apple`,
+		},
+		{
+			name: "developer id",
+			blob: requirementBuilder{}.
+				op(opAnd).op(opIdent).str("com.example.App").
+				op(opAnd).op(opAppleGenericAnchor).
+				op(opAnd).op(opCertGeneric).op(1).data(devIdCA).op(matchExists).
+				op(opAnd).op(opCertGeneric).op(0).data(devIdApp).op(matchExists).
+				op(opCertField).op(0).str("subject.OU").op(matchEqual).str("ABCDE12345").
+				blob(),
+			expected: `identifier "com.example.App" and anchor apple generic and certificate 1[field.1.2.840.113635.100.6.2.6] /* exists */ and certificate leaf[field.1.2.840.113635.100.6.1.13] /* exists */ and certificate leaf[subject.OU] = ABCDE12345`,
+		},
+		{
+			name: "or nested in and is parenthesized",
+			blob: requirementBuilder{}.
+				op(opAnd).op(opIdent).str("tool").
+				op(opOr).op(opAppleAnchor).op(opCDHash).data([]byte{0xde, 0xad, 0xbe, 0xef}).
+				blob(),
+			expected: `identifier tool and (anchor apple or cdhash H"deadbeef")`,
+		},
+		{
+			name: "not, info and entitlement matches",
+			blob: requirementBuilder{}.
+				op(opAnd).op(opNot).op(opInfoKeyField).str("CFBundleName").op(matchBeginsWith).str("Beta").
+				op(opEntitlementField).str("com.apple.security.app-sandbox").op(matchEqual).str("true").
+				blob(),
+			expected: `! info[CFBundleName] = Beta* and entitlement["com.apple.security.app-sandbox"] = true`,
+		},
+		{
+			name:     "flags in the opcode are ignored",
+			blob:     requirementBuilder{}.op(opFlagMask&0x80000000 | opNotarized).blob(),
+			expected: `notarized`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := decodeCsreq(tt.blob)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestDecodeCsreq_Invalid(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name string
+		blob []byte
+	}{
+		{name: "empty", blob: []byte{}},
+		{name: "bad magic", blob: mustDecodeHex(t, "deadbeef0000000c00000001")},
+		{name: "truncated", blob: mustDecodeHex(t, "fade0c000000003000000001000000060000000200000012636f6d2e6170706c65")},
+		{name: "unknown opcode", blob: requirementBuilder{}.op(0xff).blob()},
+		{name: "missing operand", blob: requirementBuilder{}.op(opAnd).op(opTrue).blob()},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := decodeCsreq(tt.blob)
+			require.Error(t, err)
+		})
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
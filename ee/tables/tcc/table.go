@@ -0,0 +1,82 @@
+//go:build darwin
+// +build darwin
+
+package tcc
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	tableName                 = "kolide_macos_tcc_permissions"
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+)
+
+type Table struct {
+	slogger *slog.Logger
+	rootDir string
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("source"),
+		table.TextColumn("path"),
+		table.TextColumn("service"),
+		table.TextColumn("client"),
+		table.TextColumn("client_type"),
+		table.TextColumn("auth_value"),
+		table.TextColumn("auth_reason"),
+		table.IntegerColumn("auth_version"),
+		table.TextColumn("csreq"),
+		table.IntegerColumn("policy_id"),
+		table.TextColumn("indirect_object_identifier"),
+		table.BigIntColumn("last_modified"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+		rootDir: "/",
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+
+	dbs, err := tccDbs(t.rootDir, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, db := range dbs {
+		rows, err := readAccess(ctx, t.slogger, db)
+		if err != nil {
+			// Users without a TCC database are common, don't bother logging those
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not read tcc database",
+					"path", db.path,
+					"err", err,
+				)
+			}
+			continue
+		}
+
+		results = append(results, rows...)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,235 @@
+package tcc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	systemDbPath = "/Library/Application Support/com.apple.TCC/TCC.db"
+	userDbPath   = "Library/Application Support/com.apple.TCC/TCC.db"
+)
+
+// authValues and authReasons decode the access table's auth_value and auth_reason
+// columns. These aren't documented, but are stable across macOS releases.
+var authValues = map[string]string{
+	"0": "denied",
+	"1": "unknown",
+	"2": "allowed",
+	"3": "limited",
+}
+
+var authReasons = map[string]string{
+	"0":  "none",
+	"1":  "error",
+	"2":  "user_consent",
+	"3":  "user_set",
+	"4":  "system_set",
+	"5":  "service_policy",
+	"6":  "mdm_policy",
+	"7":  "override_policy",
+	"8":  "missing_usage_string",
+	"9":  "prompt_timeout",
+	"10": "preflight_unknown",
+	"11": "entitled",
+	"12": "app_type_policy",
+}
+
+var clientTypes = map[string]string{
+	"0": "bundle_id",
+	"1": "absolute_path",
+}
+
+// tccDb is a TCC database, and whose permissions it holds.
+type tccDb struct {
+	username string // empty for the system database
+	path     string
+}
+
+// tccDbs returns the system TCC database, and that of each user, under rootDir.
+// When usernames is non-empty, only those users' databases are returned. The
+// databases are not checked for existence.
+func tccDbs(rootDir string, usernames []string) ([]tccDb, error) {
+	dbs := []tccDb{{path: filepath.Join(rootDir, systemDbPath)}}
+
+	if len(usernames) > 0 {
+		for _, username := range usernames {
+			dbs = append(dbs, tccDb{username: username, path: filepath.Join(rootDir, "Users", username, userDbPath)})
+		}
+		return dbs, nil
+	}
+
+	userDbs, err := filepath.Glob(filepath.Join(rootDir, "Users", "*", userDbPath))
+	if err != nil {
+		return nil, fmt.Errorf("globbing for user tcc databases: %w", err)
+	}
+	sort.Strings(userDbs)
+
+	for _, p := range userDbs {
+		rel, err := filepath.Rel(filepath.Join(rootDir, "Users"), p)
+		if err != nil {
+			continue
+		}
+		dbs = append(dbs, tccDb{username: strings.Split(filepath.ToSlash(rel), "/")[0], path: p})
+	}
+
+	return dbs, nil
+}
+
+// readAccess reads the access table of the given database, returning one row per client
+// and service, with values decoded.
+func readAccess(ctx context.Context, slogger *slog.Logger, db tccDb) ([]map[string]string, error) {
+	// sqlite's errors for a missing database aren't very useful, so check first
+	if _, err := os.Stat(db.path); err != nil {
+		return nil, fmt.Errorf("checking tcc database: %w", err)
+	}
+
+	rawRows, err := querySqliteDb(ctx, slogger, db.path, "SELECT * FROM access")
+	if err != nil {
+		return nil, err
+	}
+
+	source := "user"
+	if db.username == "" {
+		source = "system"
+	}
+
+	results := make([]map[string]string, 0, len(rawRows))
+	for _, raw := range rawRows {
+		row := map[string]string{
+			"username":                   db.username,
+			"source":                     source,
+			"path":                       db.path,
+			"service":                    string(raw["service"]),
+			"client":                     string(raw["client"]),
+			"client_type":                decode(clientTypes, raw["client_type"]),
+			"auth_value":                 decode(authValues, raw["auth_value"]),
+			"auth_reason":                decode(authReasons, raw["auth_reason"]),
+			"auth_version":               string(raw["auth_version"]),
+			"policy_id":                  string(raw["policy_id"]),
+			"indirect_object_identifier": string(raw["indirect_object_identifier"]),
+			"last_modified":              string(raw["last_modified"]),
+			"csreq":                      "",
+		}
+
+		// Before Big Sur, there was a boolean `allowed` column instead of auth_value
+		if _, ok := raw["auth_value"]; !ok {
+			if allowed, ok := raw["allowed"]; ok {
+				row["auth_value"] = "denied"
+				if string(allowed) == "1" {
+					row["auth_value"] = "allowed"
+				}
+			}
+		}
+
+		if csreq := raw["csreq"]; len(csreq) > 0 {
+			requirement, err := decodeCsreq(csreq)
+			if err != nil {
+				slogger.Log(ctx, slog.LevelDebug,
+					"could not decode csreq",
+					"path", db.path,
+					"client", row["client"],
+					"err", err,
+				)
+			}
+			row["csreq"] = requirement
+		}
+
+		results = append(results, row)
+	}
+
+	return results, nil
+}
+
+// decode looks up a raw integer column value in lookup, falling back to the raw value.
+func decode(lookup map[string]string, raw []byte) string {
+	if v, ok := lookup[string(raw)]; ok {
+		return v
+	}
+	return string(raw)
+}
+
+// querySqliteDb runs query against the database at path, returning each row as a map of
+// column name to raw value.
+func querySqliteDb(ctx context.Context, slogger *slog.Logger, path string, query string) ([]map[string][]byte, error) {
+	// immutable, so that we don't contend with tccd for locks
+	conn, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro&immutable=1", path))
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite db: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slogger.Log(ctx, slog.LevelWarn,
+				"closing sqlite db after query",
+				"err", err,
+			)
+		}
+	}()
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("running query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("getting columns from query result: %w", err)
+	}
+
+	rawResult := make([]any, len(columns))
+	scanDest := make([]any, len(columns))
+	for i := range columns {
+		scanDest[i] = &rawResult[i]
+	}
+
+	results := make([]map[string][]byte, 0)
+	for rows.Next() {
+		if err := rows.Scan(scanDest...); err != nil {
+			return nil, fmt.Errorf("scanning query results: %w", err)
+		}
+
+		row := make(map[string][]byte, len(columns))
+		for i, column := range columns {
+			row[column] = toBytes(rawResult[i])
+		}
+		results = append(results, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating query results: %w", err)
+	}
+
+	return results, nil
+}
+
+// toBytes converts a scanned sqlite value into bytes, formatting numbers as text.
+func toBytes(v any) []byte {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return append([]byte(nil), val...)
+	case string:
+		return []byte(val)
+	case int64:
+		return []byte(strconv.FormatInt(val, 10))
+	case float64:
+		return []byte(strconv.FormatFloat(val, 'f', -1, 64))
+	case bool:
+		if val {
+			return []byte("1")
+		}
+		return []byte("0")
+	}
+	return []byte(fmt.Sprintf("%v", v))
+}
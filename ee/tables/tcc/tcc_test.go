@@ -0,0 +1,128 @@
+package tcc
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	modernSchema = `CREATE TABLE access (service TEXT NOT NULL, client TEXT NOT NULL, client_type INTEGER NOT NULL, auth_value INTEGER NOT NULL, auth_reason INTEGER NOT NULL, auth_version INTEGER NOT NULL, csreq BLOB, policy_id INTEGER, indirect_object_identifier_type INTEGER, indirect_object_identifier TEXT NOT NULL DEFAULT 'UNUSED', indirect_object_code_identity BLOB, flags INTEGER, last_modified INTEGER NOT NULL DEFAULT (CAST(strftime('%s','now') AS INTEGER)))`
+	legacySchema = `CREATE TABLE access (service TEXT NOT NULL, client TEXT NOT NULL, client_type INTEGER NOT NULL, allowed INTEGER NOT NULL, prompt_count INTEGER NOT NULL, csreq BLOB, policy_id INTEGER, indirect_object_identifier_type INTEGER, indirect_object_identifier TEXT NOT NULL DEFAULT 'UNUSED', indirect_object_code_identity BLOB, flags INTEGER, last_modified INTEGER NOT NULL DEFAULT (CAST(strftime('%s','now') AS INTEGER)))`
+)
+
+func TestReadAccess(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	terminalCsreq := mustDecodeHex(t, "fade0c000000003000000001000000060000000200000012636f6d2e6170706c652e5465726d696e616c000000000003")
+
+	systemDb := filepath.Join(rootDir, systemDbPath)
+	createTestDb(t, systemDb, modernSchema,
+		`INSERT INTO access (service, client, client_type, auth_value, auth_reason, auth_version, csreq, policy_id, last_modified) VALUES ('kTCCServiceSystemPolicyAllFiles', 'com.apple.Terminal', 0, 2, 3, 1, ?, NULL, 1700000000)`, terminalCsreq,
+	)
+
+	aliceDb := filepath.Join(rootDir, "Users", "alice", userDbPath)
+	createTestDb(t, aliceDb, modernSchema,
+		`INSERT INTO access (service, client, client_type, auth_value, auth_reason, auth_version, csreq, policy_id, last_modified) VALUES ('kTCCServiceCamera', '/usr/local/bin/tool', 1, 0, 6, 1, ?, 7, 1700000001)`, []byte("garbage"),
+	)
+
+	bobDb := filepath.Join(rootDir, "Users", "bob", userDbPath)
+	createTestDb(t, bobDb, legacySchema,
+		`INSERT INTO access (service, client, client_type, allowed, prompt_count, last_modified) VALUES ('kTCCServiceMicrophone', 'us.zoom.xos', 0, 1, 1, 1700000002)`,
+	)
+
+	// A user without a TCC database
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "Users", "carol"), 0755))
+
+	dbs, err := tccDbs(rootDir, nil)
+	require.NoError(t, err)
+	require.Equal(t, []tccDb{
+		{username: "", path: systemDb},
+		{username: "alice", path: aliceDb},
+		{username: "bob", path: bobDb},
+	}, dbs)
+
+	var results []map[string]string
+	for _, db := range dbs {
+		rows, err := readAccess(context.TODO(), multislogger.NewNopLogger(), db)
+		require.NoError(t, err)
+		results = append(results, rows...)
+	}
+
+	require.Equal(t, []map[string]string{
+		{
+			"username":                   "",
+			"source":                     "system",
+			"path":                       systemDb,
+			"service":                    "kTCCServiceSystemPolicyAllFiles",
+			"client":                     "com.apple.Terminal",
+			"client_type":                "bundle_id",
+			"auth_value":                 "allowed",
+			"auth_reason":                "user_set",
+			"auth_version":               "1",
+			"csreq":                      `identifier "com.apple.Terminal" and anchor apple`,
+			"policy_id":                  "",
+			"indirect_object_identifier": "UNUSED",
+			"last_modified":              "1700000000",
+		},
+		{
+			"username":                   "alice",
+			"source":                     "user",
+			"path":                       aliceDb,
+			"service":                    "kTCCServiceCamera",
+			"client":                     "/usr/local/bin/tool",
+			"client_type":                "absolute_path",
+			"auth_value":                 "denied",
+			"auth_reason":                "mdm_policy",
+			"auth_version":               "1",
+			"csreq":                      "",
+			"policy_id":                  "7",
+			"indirect_object_identifier": "UNUSED",
+			"last_modified":              "1700000001",
+		},
+		{
+			"username":                   "bob",
+			"source":                     "user",
+			"path":                       bobDb,
+			"service":                    "kTCCServiceMicrophone",
+			"client":                     "us.zoom.xos",
+			"client_type":                "bundle_id",
+			"auth_value":                 "allowed",
+			"auth_reason":                "",
+			"auth_version":               "",
+			"csreq":                      "",
+			"policy_id":                  "",
+			"indirect_object_identifier": "UNUSED",
+			"last_modified":              "1700000002",
+		},
+	}, results)
+
+	// Requesting a specific user skips the glob, and missing databases are reported as such
+	dbs, err = tccDbs(rootDir, []string{"carol"})
+	require.NoError(t, err)
+	require.Len(t, dbs, 2)
+
+	_, err = readAccess(context.TODO(), multislogger.NewNopLogger(), dbs[1])
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func createTestDb(t *testing.T, path string, schema string, insert string, args ...any) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+
+	conn, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec(schema)
+	require.NoError(t, err)
+
+	_, err = conn.Exec(insert, args...)
+	require.NoError(t, err)
+}
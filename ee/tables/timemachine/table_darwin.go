@@ -0,0 +1,169 @@
+//go:build darwin
+// +build darwin
+
+package timemachine
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const (
+	exclusionsTableName       = "kolide_time_machine_exclusions"
+	coverageTableName         = "kolide_time_machine_backup_coverage"
+	allowedUsernameCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+	// Sticky exclusions are found through Spotlight, which can be slow on a large disk
+	mdfindTimeoutSeconds = 30
+)
+
+type Table struct {
+	slogger   *slog.Logger
+	collector *collector
+}
+
+func ExclusionsTablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("path"),
+		table.TextColumn("exclusion_type"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", exclusionsTableName),
+		collector: &collector{rootDir: "/"},
+	}
+
+	return table.NewPlugin(exclusionsTableName, columns, t.generateExclusions)
+}
+
+func CoverageTablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("path"),
+		table.IntegerColumn("excluded"),
+		table.TextColumn("excluded_by"),
+		table.IntegerColumn("auto_backup"),
+		table.TextColumn("destination_id"),
+		table.TextColumn("destination_name"),
+		table.TextColumn("destination_kind"),
+		table.BigIntColumn("last_backup_time"),
+		table.BigIntColumn("last_backup_age_seconds"),
+		table.IntegerColumn("covered"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", coverageTableName),
+		collector: &collector{rootDir: "/"},
+	}
+
+	return table.NewPlugin(coverageTableName, columns, t.generateCoverage)
+}
+
+func (t *Table) generateExclusions(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	for _, e := range exclusions(t.preferences(ctx), t.stickyExclusions(ctx)) {
+		results = append(results, map[string]string{
+			"path":           e.path,
+			"exclusion_type": e.exclusionType,
+		})
+	}
+
+	return results, nil
+}
+
+func (t *Table) generateCoverage(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	usernames := tablehelpers.GetConstraints(queryContext, "username",
+		tablehelpers.WithAllowedCharacters(allowedUsernameCharacters),
+		tablehelpers.WithSlogger(t.slogger),
+	)
+	if len(usernames) == 0 {
+		var err error
+		usernames, err = t.collector.users()
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not list users",
+				"err", err,
+			)
+			return nil, nil
+		}
+	}
+
+	prefs := t.preferences(ctx)
+	excl := exclusions(prefs, t.stickyExclusions(ctx))
+	autoBackup := "0"
+	if prefs != nil && prefs.AutoBackup {
+		autoBackup = "1"
+	}
+
+	for _, username := range usernames {
+		for _, c := range coverageFor(prefs, excl, username) {
+			row := map[string]string{
+				"username":    c.username,
+				"path":        c.path,
+				"excluded":    "0",
+				"excluded_by": c.excludedBy,
+				"auto_backup": autoBackup,
+				"covered":     "0",
+			}
+			if c.excludedBy != "" {
+				row["excluded"] = "1"
+			}
+
+			if c.destination != nil {
+				row["destination_id"] = c.destination.DestinationID
+				row["destination_name"] = c.destination.LastKnownVolumeName
+				row["destination_kind"] = c.destination.kind()
+
+				if lastBackup := c.destination.lastBackup(); !lastBackup.IsZero() {
+					row["last_backup_time"] = strconv.FormatInt(lastBackup.Unix(), 10)
+					row["last_backup_age_seconds"] = strconv.FormatInt(int64(time.Since(lastBackup).Seconds()), 10)
+
+					if c.excludedBy == "" {
+						row["covered"] = "1"
+					}
+				}
+			}
+
+			results = append(results, row)
+		}
+	}
+
+	return results, nil
+}
+
+// preferences returns Time Machine's preferences, or nil if it has never been configured.
+func (t *Table) preferences(ctx context.Context) *preferences {
+	prefs, err := t.collector.readPreferences()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read Time Machine preferences",
+			"err", err,
+		)
+		return nil
+	}
+	return prefs
+}
+
+func (t *Table) stickyExclusions(ctx context.Context) []string {
+	output, err := tablehelpers.RunSimple(ctx, t.slogger, mdfindTimeoutSeconds, allowedcmd.Mdfind,
+		[]string{"com_apple_backup_excludeItem = 'com.apple.backupd'"},
+	)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not find sticky Time Machine exclusions",
+			"err", err,
+		)
+		return nil
+	}
+
+	return parseMdfindOutput(output)
+}
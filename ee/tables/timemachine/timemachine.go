@@ -0,0 +1,212 @@
+// Package timemachine reports on Time Machine's exclusions, and on whether each user's key
+// directories are actually being backed up -- i.e. not excluded, and with a recent backup
+// on at least one destination.
+package timemachine
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"howett.net/plist"
+)
+
+const (
+	// Fixed exclusions are recorded in Time Machine's preferences by path, and are what's set
+	// in System Settings or with `tmutil addexclusion -p`.
+	exclusionTypeFixed = "fixed"
+	// Sticky exclusions are an extended attribute on the item itself, set with
+	// `tmutil addexclusion`, so they follow the item if it's moved.
+	exclusionTypeSticky = "sticky"
+)
+
+// keyDirectories are the directories, relative to each user's home directory, that we expect
+// to be backed up. The empty string is the home directory itself.
+var keyDirectories = []string{"", "Desktop", "Documents", "Pictures"}
+
+// preferences is the subset of /Library/Preferences/com.apple.TimeMachine.plist we report on.
+type preferences struct {
+	AutoBackup    bool          `plist:"AutoBackup"`
+	SkipPaths     []string      `plist:"SkipPaths"`
+	ExcludeByPath []string      `plist:"ExcludeByPath"`
+	Destinations  []destination `plist:"Destinations"`
+}
+
+type destination struct {
+	DestinationID              string      `plist:"DestinationID"`
+	LastKnownVolumeName        string      `plist:"LastKnownVolumeName"`
+	NetworkURL                 string      `plist:"NetworkURL"`
+	SnapshotDates              []time.Time `plist:"SnapshotDates"`
+	ReferenceLocalSnapshotDate time.Time   `plist:"ReferenceLocalSnapshotDate"`
+}
+
+func (d destination) kind() string {
+	if d.NetworkURL != "" {
+		return "network"
+	}
+	return "local"
+}
+
+// lastBackup returns the time of the most recent backup to this destination, or the zero time
+// if it has none.
+func (d destination) lastBackup() time.Time {
+	last := d.ReferenceLocalSnapshotDate
+	for _, snapshot := range d.SnapshotDates {
+		if snapshot.After(last) {
+			last = snapshot
+		}
+	}
+	return last
+}
+
+type exclusion struct {
+	path          string
+	exclusionType string
+}
+
+// coverage is whether a user's key directory is backed up to a destination.
+type coverage struct {
+	username    string
+	path        string
+	excludedBy  string // the exclusion that covers path, if any
+	destination *destination
+}
+
+// collector reads Time Machine's state relative to rootDir, which is / outside of tests.
+type collector struct {
+	rootDir string
+}
+
+func (c *collector) readPreferences() (*preferences, error) {
+	raw, err := os.ReadFile(filepath.Join(c.rootDir, "Library", "Preferences", "com.apple.TimeMachine.plist"))
+	if err != nil {
+		return nil, fmt.Errorf("reading Time Machine preferences: %w", err)
+	}
+
+	var prefs preferences
+	if _, err := plist.Unmarshal(raw, &prefs); err != nil {
+		return nil, fmt.Errorf("unmarshalling Time Machine preferences: %w", err)
+	}
+
+	return &prefs, nil
+}
+
+// users returns the usernames with home directories under /Users.
+func (c *collector) users() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(c.rootDir, "Users"))
+	if err != nil {
+		return nil, fmt.Errorf("reading user directories: %w", err)
+	}
+
+	var users []string
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "Shared" || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		users = append(users, e.Name())
+	}
+
+	return users, nil
+}
+
+// exclusions returns the fixed exclusions from prefs, and the sticky exclusions found by
+// Spotlight, sorted by path.
+func exclusions(prefs *preferences, stickyPaths []string) []exclusion {
+	seen := make(map[string]bool)
+	var results []exclusion
+
+	add := func(path, exclusionType string) {
+		path = strings.TrimSpace(path)
+		if path == "" || seen[exclusionType+path] {
+			return
+		}
+		seen[exclusionType+path] = true
+		results = append(results, exclusion{path: path, exclusionType: exclusionType})
+	}
+
+	if prefs != nil {
+		for _, path := range prefs.SkipPaths {
+			add(path, exclusionTypeFixed)
+		}
+		for _, path := range prefs.ExcludeByPath {
+			add(path, exclusionTypeFixed)
+		}
+	}
+	for _, path := range stickyPaths {
+		add(path, exclusionTypeSticky)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].path < results[j].path
+	})
+
+	return results
+}
+
+// parseMdfindOutput returns the paths mdfind printed, one per line.
+func parseMdfindOutput(output []byte) []string {
+	var paths []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// coverageFor reports whether each of the user's key directories is backed up to each
+// destination. With no destinations, nothing is covered, but the directories are still reported.
+func coverageFor(prefs *preferences, exclusions []exclusion, username string) []coverage {
+	home := filepath.Join("/Users", username)
+
+	var destinations []*destination
+	if prefs != nil {
+		for i := range prefs.Destinations {
+			destinations = append(destinations, &prefs.Destinations[i])
+		}
+	}
+	if len(destinations) == 0 {
+		destinations = []*destination{nil}
+	}
+
+	var results []coverage
+	for _, dir := range keyDirectories {
+		path := filepath.Join(home, dir)
+		excludedBy := excludingPath(exclusions, path, home)
+
+		for _, d := range destinations {
+			results = append(results, coverage{
+				username:    username,
+				path:        path,
+				excludedBy:  excludedBy,
+				destination: d,
+			})
+		}
+	}
+
+	return results
+}
+
+// excludingPath returns the exclusion that excludes path -- one for path itself or any
+// directory containing it -- or the empty string if path isn't excluded. Exclusions starting
+// with ~ are relative to home.
+func excludingPath(exclusions []exclusion, path, home string) string {
+	for _, e := range exclusions {
+		excluded := e.path
+		if excluded == "~" || strings.HasPrefix(excluded, "~/") {
+			excluded = home + strings.TrimPrefix(excluded, "~")
+		}
+		excluded = strings.TrimSuffix(excluded, "/")
+
+		if path == excluded || strings.HasPrefix(path, excluded+"/") || excluded == "" {
+			return e.path
+		}
+	}
+	return ""
+}
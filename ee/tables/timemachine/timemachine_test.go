@@ -0,0 +1,86 @@
+package timemachine
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{rootDir: filepath.Join("testdata", "root")}
+
+	users, err := c.users()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"alice", "bob"}, users)
+
+	prefs, err := c.readPreferences()
+	require.NoError(t, err)
+	require.True(t, prefs.AutoBackup)
+	require.Len(t, prefs.Destinations, 2)
+
+	local, network := prefs.Destinations[0], prefs.Destinations[1]
+	require.Equal(t, "local", local.kind())
+	require.Equal(t, time.Date(2024, 5, 13, 22, 10, 4, 0, time.UTC), local.lastBackup().UTC())
+	require.Equal(t, "network", network.kind())
+	require.True(t, network.lastBackup().IsZero())
+
+	stickyPaths := parseMdfindOutput([]byte("/Users/alice/Pictures\n/Users/alice/Library/Caches/big.cache\n\n"))
+	excl := exclusions(prefs, stickyPaths)
+	require.Equal(t, []exclusion{
+		{path: "/Users/alice/Library/Caches/big.cache", exclusionType: exclusionTypeSticky},
+		{path: "/Users/alice/Pictures", exclusionType: exclusionTypeSticky},
+		{path: "/Users/bob/Documents", exclusionType: exclusionTypeFixed},
+		{path: "~/Downloads", exclusionType: exclusionTypeFixed},
+	}, excl)
+
+	// Each key directory is reported once per destination
+	aliceCoverage := coverageFor(prefs, excl, "alice")
+	require.Len(t, aliceCoverage, len(keyDirectories)*2)
+	excludedBy := make(map[string]string)
+	for _, c := range aliceCoverage {
+		excludedBy[c.path] = c.excludedBy
+	}
+	require.Equal(t, map[string]string{
+		"/Users/alice":           "",
+		"/Users/alice/Desktop":   "",
+		"/Users/alice/Documents": "",
+		"/Users/alice/Pictures":  "/Users/alice/Pictures",
+	}, excludedBy)
+
+	bobCoverage := coverageFor(prefs, excl, "bob")
+	for _, c := range bobCoverage {
+		if c.path == "/Users/bob/Documents" {
+			require.Equal(t, "/Users/bob/Documents", c.excludedBy)
+		} else {
+			require.Empty(t, c.excludedBy, c.path)
+		}
+	}
+
+	// Without any destinations, the directories are still reported
+	noDestinations := coverageFor(&preferences{}, nil, "alice")
+	require.Len(t, noDestinations, len(keyDirectories))
+	require.Nil(t, noDestinations[0].destination)
+}
+
+func TestExcludingPath(t *testing.T) {
+	t.Parallel()
+
+	excl := []exclusion{
+		{path: "~/Downloads", exclusionType: exclusionTypeFixed},
+		{path: "/Users/alice/Documents/Archive", exclusionType: exclusionTypeSticky},
+		{path: "/Volumes/External/", exclusionType: exclusionTypeFixed},
+	}
+
+	require.Equal(t, "~/Downloads", excludingPath(excl, "/Users/alice/Downloads", "/Users/alice"))
+	require.Equal(t, "~/Downloads", excludingPath(excl, "/Users/alice/Downloads/installer.dmg", "/Users/alice"))
+	require.Equal(t, "/Volumes/External/", excludingPath(excl, "/Volumes/External/photos", "/Users/alice"))
+	// Excluding something within a directory doesn't exclude the directory itself
+	require.Empty(t, excludingPath(excl, "/Users/alice/Documents", "/Users/alice"))
+	require.Empty(t, excludingPath(excl, "/Users/alice/DownloadsOld", "/Users/alice"))
+
+	require.Equal(t, "/", excludingPath([]exclusion{{path: "/"}}, "/Users/alice", "/Users/alice"))
+}
@@ -0,0 +1,134 @@
+package tufinfo
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/tuf"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+const updateLibraryTableName = "kolide_update_library"
+
+// UpdateLibraryTable lists every binary version in the update library, including custom
+// builds, with whether it's verified and whether it's running -- so that the server can see
+// how far updates have propagated, and spot hosts that are stuck.
+func UpdateLibraryTable(flags types.Flags, slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("binary"),
+		table.TextColumn("version"),
+		table.IntegerColumn("custom_build"),
+		table.TextColumn("path"),
+		table.TextColumn("verification_status"),
+		table.TextColumn("verification_error"),
+		table.BigIntColumn("size"),
+		table.BigIntColumn("added_time"),
+		table.IntegerColumn("running"),
+	}
+
+	t := &updateLibraryTable{
+		flags:   flags,
+		slogger: slogger.With("table", updateLibraryTableName),
+	}
+
+	return table.NewPlugin(updateLibraryTableName, columns, t.generate)
+}
+
+type updateLibraryTable struct {
+	flags   types.Flags
+	slogger *slog.Logger
+}
+
+func (t *updateLibraryTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	updateDirectory := t.flags.UpdateDirectory()
+	if updateDirectory == "" {
+		updateDirectory = tuf.DefaultLibraryDirectory(t.flags.RootDirectory())
+	}
+
+	entries, err := tuf.LibraryContents(ctx, updateDirectory, tuf.LocalTufDirectory(t.flags.RootDirectory()))
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not check all of update library",
+			"err", err,
+		)
+	}
+
+	running := runningExecutables(ctx)
+
+	results := make([]map[string]string, 0, len(entries))
+	for _, e := range entries {
+		results = append(results, map[string]string{
+			"binary":              e.Binary,
+			"version":             e.Version,
+			"custom_build":        boolToIntString(e.CustomBuild),
+			"path":                e.ExecutablePath,
+			"verification_status": e.VerificationStatus,
+			"verification_error":  e.VerificationError,
+			"size":                strconv.FormatInt(e.Size, 10),
+			"added_time":          strconv.FormatInt(e.ModTime, 10),
+			"running":             boolToIntString(running[normalizePath(e.ExecutablePath)]),
+		})
+	}
+
+	return results, nil
+}
+
+// runningExecutables returns the paths of the launcher and osqueryd executables running now,
+// including this one.
+func runningExecutables(ctx context.Context) map[string]bool {
+	running := make(map[string]bool)
+
+	if self, err := os.Executable(); err == nil {
+		running[normalizePath(self)] = true
+	}
+
+	processes, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return running
+	}
+
+	for _, p := range processes {
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSuffix(strings.ToLower(name), ".exe") {
+		case "launcher", "osqueryd":
+		default:
+			continue
+		}
+
+		exe, err := p.ExeWithContext(ctx)
+		if err != nil || exe == "" {
+			continue
+		}
+		running[normalizePath(exe)] = true
+	}
+
+	return running
+}
+
+func normalizePath(p string) string {
+	p = filepath.Clean(p)
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		p = resolved
+	}
+	if runtime.GOOS == "windows" {
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
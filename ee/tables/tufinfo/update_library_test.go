@@ -0,0 +1,44 @@
+package tufinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/ee/tables/tabletest"
+	"github.com/kolide/launcher/ee/tuf"
+	tufci "github.com/kolide/launcher/ee/tuf/ci"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateLibraryTable(t *testing.T) {
+	t.Parallel()
+
+	testRootDir := t.TempDir()
+	v := randomSemver()
+	tufci.SeedLocalTufRepo(t, v, testRootDir)
+
+	// A corrupted version, which doesn't need executing to check
+	corruptedDir := filepath.Join(tuf.DefaultLibraryDirectory(testRootDir), "osqueryd", v)
+	require.NoError(t, os.MkdirAll(corruptedDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(corruptedDir, "README"), []byte("not osqueryd"), 0644))
+
+	mockFlags := mocks.NewFlags(t)
+	mockFlags.On("RootDirectory").Return(testRootDir)
+	mockFlags.On("UpdateDirectory").Return("")
+
+	rows := tabletest.Generate(t, UpdateLibraryTable(mockFlags, multislogger.NewNopLogger()), tabletest.NewQueryContext().Build())
+	require.Len(t, rows, 1)
+
+	row := rows[0]
+	require.Equal(t, "osqueryd", row["binary"])
+	require.Equal(t, v, row["version"])
+	require.Equal(t, "0", row["custom_build"])
+	require.Equal(t, tuf.VerificationStatusInvalidExecutable, row["verification_status"])
+	require.NotEmpty(t, row["verification_error"])
+	require.Equal(t, "12", row["size"])
+	require.NotEqual(t, "0", row["added_time"])
+	require.Equal(t, "0", row["running"])
+}
@@ -0,0 +1,139 @@
+//go:build darwin
+// +build darwin
+
+package ulimit
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/unix"
+	"howett.net/plist"
+)
+
+// launchDaemonsDir has the plists for system launch daemons, including launcher's own
+const launchDaemonsDir = "/Library/LaunchDaemons"
+
+// kernelKeys are the sysctl keys reported as kernel limits
+var kernelKeys = []string{
+	"kern.ipc.somaxconn",
+	"kern.maxfiles",
+	"kern.maxfilesperproc",
+	"kern.maxproc",
+	"kern.maxprocperuid",
+	"kern.maxvnodes",
+	"kern.num_files",
+}
+
+type launchDaemon struct {
+	Label              string           `plist:"Label"`
+	SoftResourceLimits map[string]int64 `plist:"SoftResourceLimits"`
+	HardResourceLimits map[string]int64 `plist:"HardResourceLimits"`
+}
+
+// platformLimits returns the limits launchd applies, and the kernel's. Unlike on Linux, other
+// processes' limits aren't readable, so osqueryd's aren't reported.
+func (t *Table) platformLimits(ctx context.Context, _ table.QueryContext) []limit {
+	var limits []limit
+	limits = append(limits, t.serviceLimits(ctx)...)
+	limits = append(limits, t.serviceManagerLimits(ctx)...)
+	limits = append(limits, t.kernelLimits(ctx)...)
+	return limits
+}
+
+// serviceLimits returns the limits launch daemons are configured with.
+func (t *Table) serviceLimits(ctx context.Context) []limit {
+	paths, err := filepath.Glob(filepath.Join(launchDaemonsDir, "*.plist"))
+	if err != nil {
+		return nil
+	}
+
+	var limits []limit
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read launch daemon plist",
+				"path", path,
+				"err", err,
+			)
+			continue
+		}
+
+		var daemon launchDaemon
+		if _, err := plist.Unmarshal(contents, &daemon); err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not unmarshal launch daemon plist",
+				"path", path,
+				"err", err,
+			)
+			continue
+		}
+
+		daemonLimits := launchDaemonLimits(daemon.SoftResourceLimits, daemon.HardResourceLimits)
+		if len(daemonLimits) == 0 {
+			continue
+		}
+		limits = append(limits, limitRows(scopeService, daemon.Label, "", daemonLimits)...)
+	}
+
+	return limits
+}
+
+// serviceManagerLimits returns the limits launchd applies to the processes it starts.
+func (t *Table) serviceManagerLimits(ctx context.Context) []limit {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.Launchctl, []string{"limit"}, &stdout, &stderr); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get launchd limits",
+			"stderr", stderr.String(),
+			"err", err,
+		)
+		return nil
+	}
+
+	return limitRows(scopeServiceManager, "launchd", "", parseLaunchctlLimit(stdout.String()))
+}
+
+// kernelLimits returns the kernel-wide limits from sysctl.
+func (t *Table) kernelLimits(ctx context.Context) []limit {
+	limits := make([]limit, 0, len(kernelKeys))
+	for _, key := range kernelKeys {
+		value, err := unix.SysctlUint32(key)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not read kernel limit",
+				"key", key,
+				"err", err,
+			)
+			continue
+		}
+		limits = append(limits, limit{
+			scope:    scopeKernel,
+			target:   "sysctl",
+			resource: key,
+			value:    strconv.FormatUint(uint64(value), 10),
+		})
+	}
+	return limits
+}
+
+// openFileCount returns the number of files the given process has open. Only launcher's own
+// are visible, through /dev/fd.
+func openFileCount(pid int) int {
+	if pid != os.Getpid() {
+		return -1
+	}
+	entries, err := os.ReadDir("/dev/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,178 @@
+//go:build linux
+// +build linux
+
+package ulimit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// allowedTargetCharacters are those allowed in systemd unit names
+const allowedTargetCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.@:\\"
+
+// kernelKeys are the /proc/sys keys reported as kernel limits
+var kernelKeys = []string{
+	"fs.aio-max-nr",
+	"fs.file-max",
+	"fs.file-nr",
+	"fs.inotify.max_user_instances",
+	"fs.inotify.max_user_watches",
+	"fs.nr_open",
+	"kernel.pid_max",
+	"kernel.threads-max",
+	"net.core.somaxconn",
+	"vm.max_map_count",
+}
+
+func (t *Table) platformLimits(ctx context.Context, queryContext table.QueryContext) []limit {
+	var limits []limit
+	limits = append(limits, t.osquerydLimits(ctx)...)
+	limits = append(limits, t.serviceLimits(ctx, queryContext)...)
+	limits = append(limits, t.serviceManagerLimits(ctx)...)
+	limits = append(limits, t.kernelLimits(ctx)...)
+	return limits
+}
+
+// osquerydLimits returns the limits of the running osqueryd processes.
+func (t *Table) osquerydLimits(ctx context.Context) []limit {
+	pidDirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil
+	}
+
+	var limits []limit
+	for _, pidDir := range pidDirs {
+		comm, err := os.ReadFile(filepath.Join(pidDir, "comm"))
+		if err != nil || strings.TrimSpace(string(comm)) != "osqueryd" {
+			continue
+		}
+
+		contents, err := os.ReadFile(filepath.Join(pidDir, "limits"))
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read osqueryd limits",
+				"path", pidDir,
+				"err", err,
+			)
+			continue
+		}
+
+		pid := filepath.Base(pidDir)
+		rows := limitRows(scopeProcess, "osqueryd", pid, parseProcLimits(string(contents)))
+		if pidNum, err := strconv.Atoi(pid); err == nil {
+			addOpenFiles(rows, openFileCount(pidNum))
+		}
+		limits = append(limits, rows...)
+	}
+
+	return limits
+}
+
+// serviceLimits returns the limits of launcher's own systemd unit, and of any units named in a
+// constraint on target.
+func (t *Table) serviceLimits(ctx context.Context, queryContext table.QueryContext) []limit {
+	var units []string
+	if unit := launcherUnit(); unit != "" {
+		units = append(units, unit)
+	}
+	for _, target := range tablehelpers.GetConstraints(queryContext, "target", tablehelpers.WithAllowedCharacters(allowedTargetCharacters)) {
+		if !slices.Contains(units, target) {
+			units = append(units, target)
+		}
+	}
+
+	var limits []limit
+	for _, unit := range units {
+		output, err := t.systemctlShow(ctx, unit)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not get unit limits",
+				"unit", unit,
+				"err", err,
+			)
+			continue
+		}
+		// systemctl show reports default properties for units that don't exist
+		if !strings.Contains(output, "\nLoadState=loaded\n") {
+			continue
+		}
+		limits = append(limits, limitRows(scopeService, unit, "", parseSystemctlLimits(output, "Limit"))...)
+	}
+
+	return limits
+}
+
+// serviceManagerLimits returns the default limits systemd applies to units.
+func (t *Table) serviceManagerLimits(ctx context.Context) []limit {
+	output, err := t.systemctlShow(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get systemd default limits",
+			"err", err,
+		)
+		return nil
+	}
+
+	return limitRows(scopeServiceManager, "systemd", "", parseSystemctlLimits(output, "DefaultLimit"))
+}
+
+func (t *Table) systemctlShow(ctx context.Context, units ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.Systemctl, append([]string{"show"}, units...), &stdout, &stderr); err != nil {
+		return "", fmt.Errorf("running systemctl show: %s: %w", stderr.String(), err)
+	}
+	// Leading newline, so properties can be matched as whole lines
+	return "\n" + stdout.String(), nil
+}
+
+// kernelLimits returns the kernel-wide limits from /proc/sys.
+func (t *Table) kernelLimits(ctx context.Context) []limit {
+	limits := make([]limit, 0, len(kernelKeys))
+	for _, key := range kernelKeys {
+		contents, err := os.ReadFile(filepath.Join("/proc/sys", strings.ReplaceAll(key, ".", "/")))
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not read kernel limit",
+				"key", key,
+				"err", err,
+			)
+			continue
+		}
+		limits = append(limits, limit{
+			scope:    scopeKernel,
+			target:   "sysctl",
+			resource: key,
+			value:    strings.Join(strings.Fields(string(contents)), " "),
+		})
+	}
+	return limits
+}
+
+// launcherUnit returns the systemd unit launcher runs under, if any.
+func launcherUnit() string {
+	contents, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	return parseCgroupUnit(string(contents))
+}
+
+func openFileCount(pid int) int {
+	entries, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(pid), "fd"))
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,97 @@
+//go:build !windows
+// +build !windows
+
+package ulimit
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/unix"
+)
+
+// selfLimits are the limits we report for launcher's own process
+var selfLimits = map[string]int{
+	"cpu":     unix.RLIMIT_CPU,
+	"fsize":   unix.RLIMIT_FSIZE,
+	"data":    unix.RLIMIT_DATA,
+	"stack":   unix.RLIMIT_STACK,
+	"core":    unix.RLIMIT_CORE,
+	"nofile":  unix.RLIMIT_NOFILE,
+	"nproc":   unix.RLIMIT_NPROC,
+	"memlock": unix.RLIMIT_MEMLOCK,
+	"as":      unix.RLIMIT_AS,
+}
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("scope"),
+		table.TextColumn("target"),
+		table.BigIntColumn("pid"),
+		table.TextColumn("resource"),
+		table.TextColumn("soft_limit"),
+		table.TextColumn("hard_limit"),
+		table.TextColumn("value"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	limits := t.selfLimits(ctx)
+	limits = append(limits, t.platformLimits(ctx, queryContext)...)
+
+	results := make([]map[string]string, 0, len(limits))
+	for _, l := range limits {
+		results = append(results, l.row())
+	}
+	return results, nil
+}
+
+// selfLimits returns launcher's own limits, with its open file count.
+func (t *Table) selfLimits(ctx context.Context) []limit {
+	limits := make(map[string][2]string, len(selfLimits))
+	for resource, rlimitResource := range selfLimits {
+		var rlimit unix.Rlimit
+		if err := unix.Getrlimit(rlimitResource, &rlimit); err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not get resource limit",
+				"resource", resource,
+				"err", err,
+			)
+			continue
+		}
+		limits[resource] = [2]string{
+			formatRlimit(uint64(rlimit.Cur), unix.RLIM_INFINITY),
+			formatRlimit(uint64(rlimit.Max), unix.RLIM_INFINITY),
+		}
+	}
+
+	pid := os.Getpid()
+	rows := limitRows(scopeProcess, "launcher", strconv.Itoa(pid), limits)
+	addOpenFiles(rows, openFileCount(pid))
+	return rows
+}
+
+// addOpenFiles sets the value of the nofile row, if there is one, to the number of open files.
+func addOpenFiles(rows []limit, openFiles int) {
+	if openFiles < 0 {
+		return
+	}
+	for i := range rows {
+		if rows[i].resource == "nofile" {
+			rows[i].value = strconv.Itoa(openFiles)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Package ulimit provides a table of the resource limits that apply to launcher and osquery --
+// their own process limits, the limits their service manager imposes on services, and the
+// kernel-wide limits behind them -- so that agents running out of file descriptors or processes
+// can be debugged.
+package ulimit
+
+import (
+	"bufio"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const tableName = "kolide_ulimit"
+
+// Scopes of the limits in the table
+const (
+	scopeProcess        = "process"         // limits in effect for a running process
+	scopeService        = "service"         // limits a service is configured to run with
+	scopeServiceManager = "service_manager" // defaults the service manager applies to services
+	scopeKernel         = "kernel"          // kernel-wide limits and usage
+)
+
+const unlimited = "unlimited"
+
+// limit is a row of the table.
+type limit struct {
+	scope    string
+	target   string
+	pid      string
+	resource string
+	soft     string
+	hard     string
+	value    string
+}
+
+func (l limit) row() map[string]string {
+	return map[string]string{
+		"scope":      l.scope,
+		"target":     l.target,
+		"pid":        l.pid,
+		"resource":   l.resource,
+		"soft_limit": l.soft,
+		"hard_limit": l.hard,
+		"value":      l.value,
+	}
+}
+
+// procLimitNames maps the limit descriptions in /proc/<pid>/limits to resource names.
+var procLimitNames = map[string]string{
+	"Max cpu time":          "cpu",
+	"Max file size":         "fsize",
+	"Max data size":         "data",
+	"Max stack size":        "stack",
+	"Max core file size":    "core",
+	"Max resident set":      "rss",
+	"Max processes":         "nproc",
+	"Max open files":        "nofile",
+	"Max locked memory":     "memlock",
+	"Max address space":     "as",
+	"Max file locks":        "locks",
+	"Max pending signals":   "sigpending",
+	"Max msgqueue size":     "msgqueue",
+	"Max nice priority":     "nice",
+	"Max realtime priority": "rtprio",
+	"Max realtime timeout":  "rttime",
+}
+
+// parseProcLimits parses /proc/<pid>/limits into resource -> soft and hard limits, e.g.
+//
+//	Limit                     Soft Limit           Hard Limit           Units
+//	Max open files            1024                 524288               files
+func parseProcLimits(contents string) map[string][2]string {
+	limits := make(map[string][2]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		for description, resource := range procLimitNames {
+			rest, found := strings.CutPrefix(line, description)
+			if !found {
+				continue
+			}
+			fields := strings.Fields(rest)
+			if len(fields) < 2 {
+				continue
+			}
+			limits[resource] = [2]string{fields[0], fields[1]}
+		}
+	}
+
+	return limits
+}
+
+// systemdLimitNames maps the suffixes of systemd's Limit* properties to resource names.
+var systemdLimitNames = map[string]string{
+	"CPU":        "cpu",
+	"FSIZE":      "fsize",
+	"DATA":       "data",
+	"STACK":      "stack",
+	"CORE":       "core",
+	"RSS":        "rss",
+	"NOFILE":     "nofile",
+	"AS":         "as",
+	"NPROC":      "nproc",
+	"MEMLOCK":    "memlock",
+	"LOCKS":      "locks",
+	"SIGPENDING": "sigpending",
+	"MSGQUEUE":   "msgqueue",
+	"NICE":       "nice",
+	"RTPRIO":     "rtprio",
+	"RTTIME":     "rttime",
+}
+
+// parseSystemctlLimits parses `systemctl show` output into resource -> soft and hard limits.
+// Limits are properties named with the given prefix -- Limit for units, DefaultLimit for the
+// manager -- where e.g. LimitNOFILE is the hard limit and LimitNOFILESoft the soft limit.
+func parseSystemctlLimits(output string, prefix string) map[string][2]string {
+	limits := make(map[string][2]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+		name, found := strings.CutPrefix(key, prefix)
+		if !found {
+			continue
+		}
+
+		soft := false
+		if trimmed, isSoft := strings.CutSuffix(name, "Soft"); isSoft {
+			name = trimmed
+			soft = true
+		}
+		resource, known := systemdLimitNames[name]
+		if !known {
+			continue
+		}
+
+		if value == "infinity" {
+			value = unlimited
+		}
+		l := limits[resource]
+		if soft {
+			l[0] = value
+		} else {
+			l[1] = value
+		}
+		limits[resource] = l
+	}
+
+	return limits
+}
+
+// launchdLimitNames maps the resource names used by `launchctl limit`, and the keys of
+// SoftResourceLimits and HardResourceLimits in launchd plists, to resource names.
+var launchdLimitNames = map[string]string{
+	"cpu":               "cpu",
+	"filesize":          "fsize",
+	"data":              "data",
+	"stack":             "stack",
+	"core":              "core",
+	"rss":               "rss",
+	"memlock":           "memlock",
+	"maxproc":           "nproc",
+	"maxfiles":          "nofile",
+	"CPU":               "cpu",
+	"FileSize":          "fsize",
+	"Data":              "data",
+	"Stack":             "stack",
+	"Core":              "core",
+	"ResidentSetSize":   "rss",
+	"MemoryLock":        "memlock",
+	"NumberOfProcesses": "nproc",
+	"NumberOfFiles":     "nofile",
+}
+
+// parseLaunchctlLimit parses `launchctl limit` output into resource -> soft and hard limits, e.g.
+//
+//	maxfiles    256            unlimited
+func parseLaunchctlLimit(output string) map[string][2]string {
+	limits := make(map[string][2]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		resource, known := launchdLimitNames[fields[0]]
+		if !known {
+			continue
+		}
+		limits[resource] = [2]string{fields[1], fields[2]}
+	}
+
+	return limits
+}
+
+// launchDaemonLimits converts a launch daemon's SoftResourceLimits and HardResourceLimits
+// into resource -> soft and hard limits.
+func launchDaemonLimits(soft, hard map[string]int64) map[string][2]string {
+	limits := make(map[string][2]string)
+	for key, value := range soft {
+		if resource, known := launchdLimitNames[key]; known {
+			l := limits[resource]
+			l[0] = strconv.FormatInt(value, 10)
+			limits[resource] = l
+		}
+	}
+	for key, value := range hard {
+		if resource, known := launchdLimitNames[key]; known {
+			l := limits[resource]
+			l[1] = strconv.FormatInt(value, 10)
+			limits[resource] = l
+		}
+	}
+	return limits
+}
+
+// formatRlimit formats a limit from getrlimit.
+func formatRlimit(value uint64, infinity uint64) string {
+	if value == infinity {
+		return unlimited
+	}
+	return strconv.FormatUint(value, 10)
+}
+
+// limitRows turns resource -> soft and hard limits into rows.
+func limitRows(scope, target, pid string, limits map[string][2]string) []limit {
+	resources := make([]string, 0, len(limits))
+	for resource := range limits {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	rows := make([]limit, 0, len(limits))
+	for _, resource := range resources {
+		l := limits[resource]
+		rows = append(rows, limit{
+			scope:    scope,
+			target:   target,
+			pid:      pid,
+			resource: resource,
+			soft:     l[0],
+			hard:     l[1],
+		})
+	}
+	return rows
+}
+
+// parseCgroupUnit returns the systemd service from /proc/<pid>/cgroup, e.g.
+//
+//	0::/system.slice/launcher.kolide-k2.service
+func parseCgroupUnit(contents string) string {
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, component := range strings.Split(parts[2], "/") {
+			if strings.HasSuffix(component, ".service") {
+				return component
+			}
+		}
+	}
+	return ""
+}
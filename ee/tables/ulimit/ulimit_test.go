@@ -0,0 +1,103 @@
+package ulimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcLimits(t *testing.T) {
+	t.Parallel()
+
+	contents := `Limit                     Soft Limit           Hard Limit           Units
+Max cpu time              unlimited            unlimited            seconds
+Max file size             unlimited            unlimited            bytes
+Max processes             63405                63405                processes
+Max open files            1024                 524288               files
+Max locked memory         8388608              8388608              bytes
+Max realtime timeout      unlimited            unlimited            us
+`
+	require.Equal(t, map[string][2]string{
+		"cpu":     {"unlimited", "unlimited"},
+		"fsize":   {"unlimited", "unlimited"},
+		"nproc":   {"63405", "63405"},
+		"nofile":  {"1024", "524288"},
+		"memlock": {"8388608", "8388608"},
+		"rttime":  {"unlimited", "unlimited"},
+	}, parseProcLimits(contents))
+}
+
+func TestParseSystemctlLimits(t *testing.T) {
+	t.Parallel()
+
+	output := `Type=simple
+LimitNOFILE=524288
+LimitNOFILESoft=1024
+LimitNPROC=infinity
+LimitNPROCSoft=infinity
+LimitFOO=1
+DefaultLimitNOFILE=524288
+MemoryLimit=infinity
+`
+	require.Equal(t, map[string][2]string{
+		"nofile": {"1024", "524288"},
+		"nproc":  {"unlimited", "unlimited"},
+	}, parseSystemctlLimits(output, "Limit"))
+
+	require.Equal(t, map[string][2]string{
+		"nofile": {"", "524288"},
+	}, parseSystemctlLimits(output, "DefaultLimit"))
+}
+
+func TestParseLaunchctlLimit(t *testing.T) {
+	t.Parallel()
+
+	output := `	cpu         unlimited      unlimited
+	filesize    unlimited      unlimited
+	maxproc     2666           4000
+	maxfiles    256            unlimited
+`
+	require.Equal(t, map[string][2]string{
+		"cpu":    {"unlimited", "unlimited"},
+		"fsize":  {"unlimited", "unlimited"},
+		"nproc":  {"2666", "4000"},
+		"nofile": {"256", "unlimited"},
+	}, parseLaunchctlLimit(output))
+}
+
+func TestLaunchDaemonLimits(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, map[string][2]string{
+		"nofile": {"4096", "8192"},
+		"nproc":  {"", "1000"},
+	}, launchDaemonLimits(
+		map[string]int64{"NumberOfFiles": 4096, "Unknown": 1},
+		map[string]int64{"NumberOfFiles": 8192, "NumberOfProcesses": 1000},
+	))
+	require.Empty(t, launchDaemonLimits(nil, nil))
+}
+
+func TestParseCgroupUnit(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "launcher.kolide-k2.service", parseCgroupUnit("0::/system.slice/launcher.kolide-k2.service\n"))
+	require.Equal(t, "launcher.kolide-k2.service", parseCgroupUnit("12:pids:/system.slice/launcher.kolide-k2.service\n1:name=systemd:/system.slice/launcher.kolide-k2.service\n"))
+	require.Equal(t, "", parseCgroupUnit("0::/user.slice/user-1000.slice/session-2.scope\n"))
+}
+
+func TestLimitRows(t *testing.T) {
+	t.Parallel()
+
+	rows := limitRows(scopeProcess, "launcher", "42", map[string][2]string{
+		"nproc":  {"100", "200"},
+		"nofile": {"1024", "4096"},
+	})
+	require.Equal(t, []limit{
+		{scope: scopeProcess, target: "launcher", pid: "42", resource: "nofile", soft: "1024", hard: "4096"},
+		{scope: scopeProcess, target: "launcher", pid: "42", resource: "nproc", soft: "100", hard: "200"},
+	}, rows)
+
+	require.Equal(t, unlimited, formatRlimit(^uint64(0), ^uint64(0)))
+	require.Equal(t, "1024", formatRlimit(1024, ^uint64(0)))
+}
@@ -0,0 +1,376 @@
+package virtualizationguests
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// bundleFormat describes how a hypervisor stores VMs in users' home directories.
+type bundleFormat struct {
+	dirs []string // the directories, relative to a home directory, that hold VMs
+	ext  string   // the extension of each VM's directory; empty if every directory is a VM
+	read func(bundle string) (guest, error)
+}
+
+// bundleFormats are the formats to look for on each platform.
+var bundleFormats = map[string][]bundleFormat{
+	"darwin": {
+		{
+			dirs: []string{"Parallels", filepath.Join("Documents", "Parallels")},
+			ext:  ".pvm",
+			read: readParallelsBundle,
+		},
+		{
+			dirs: []string{"Virtual Machines.localized", "Virtual Machines", filepath.Join("Documents", "Virtual Machines.localized")},
+			ext:  ".vmwarevm",
+			read: vmwareBundleReader(managerVmwareFusion),
+		},
+		{
+			dirs: []string{filepath.Join("Library", "Containers", "com.utmapp.UTM", "Data", "Documents")},
+			ext:  ".utm",
+			read: readUtmBundle,
+		},
+		{
+			dirs: []string{filepath.Join(".tart", "vms")},
+			read: readTartBundle,
+		},
+	},
+	"windows": {
+		{
+			dirs: []string{filepath.Join("Documents", "Virtual Machines")},
+			read: vmwareBundleReader(managerVmwareWorkstation),
+		},
+	},
+	"linux": {
+		{
+			dirs: []string{"vmware"},
+			read: vmwareBundleReader(managerVmwareWorkstation),
+		},
+	},
+}
+
+type homeDir struct {
+	username string
+	path     string
+}
+
+// collector finds VMs relative to rootDir, which is / (or the system drive, on Windows)
+// outside of tests.
+type collector struct {
+	rootDir string
+	goos    string
+}
+
+func defaultRootDir() string {
+	if runtime.GOOS != "windows" {
+		return "/"
+	}
+	if systemDrive := os.Getenv("SystemDrive"); systemDrive != "" {
+		return systemDrive + `\`
+	}
+	return `C:\`
+}
+
+// guests returns all the VMs we can find from the filesystem. It returns the VMs it could read
+// along with any errors from the ones it couldn't.
+func (c *collector) guests() ([]guest, error) {
+	var guests []guest
+	var errs []error
+
+	homes, err := c.homeDirs()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, home := range homes {
+		for _, format := range bundleFormats[c.goos] {
+			for _, dir := range format.dirs {
+				found, err := readBundles(filepath.Join(home.path, dir), format)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				for _, g := range found {
+					g.username = home.username
+					guests = append(guests, g)
+				}
+			}
+		}
+	}
+
+	if c.goos == "linux" {
+		found, err := c.libvirtGuests(homes)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		guests = append(guests, found...)
+	}
+
+	return guests, errors.Join(errs...)
+}
+
+// homeDirs returns the users' home directories. On macOS, /Users/Shared is included, with no
+// username, since it's a common place to keep VMs used by everyone.
+func (c *collector) homeDirs() ([]homeDir, error) {
+	usersDir := filepath.Join(c.rootDir, "Users")
+	if c.goos == "linux" {
+		usersDir = filepath.Join(c.rootDir, "home")
+	}
+
+	entries, err := os.ReadDir(usersDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading home directories: %w", err)
+	}
+
+	var homes []homeDir
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		switch {
+		case c.goos == "darwin" && name == "Shared":
+			homes = append(homes, homeDir{path: filepath.Join(usersDir, name)})
+		case c.goos == "windows" && slices.Contains([]string{"All Users", "Default", "Default User", "Public"}, name):
+			continue
+		default:
+			homes = append(homes, homeDir{username: name, path: filepath.Join(usersDir, name)})
+		}
+	}
+
+	if c.goos == "linux" {
+		homes = append(homes, homeDir{username: "root", path: filepath.Join(c.rootDir, "root")})
+	}
+
+	return homes, nil
+}
+
+// readBundles reads the VMs in dir. A missing dir just means there are none.
+func readBundles(dir string, format bundleFormat) ([]guest, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+
+	var guests []guest
+	var errs []error
+	for _, e := range entries {
+		if !e.IsDir() || (format.ext != "" && filepath.Ext(e.Name()) != format.ext) {
+			continue
+		}
+
+		g, err := format.read(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		guests = append(guests, g)
+	}
+
+	return guests, errors.Join(errs...)
+}
+
+// bundleName is the name of a VM bundle, without its extension.
+func bundleName(bundle string) string {
+	return strings.TrimSuffix(filepath.Base(bundle), filepath.Ext(bundle))
+}
+
+type parallelsConfig struct {
+	Identification struct {
+		VmUuid string `xml:"VmUuid"`
+		VmName string `xml:"VmName"`
+	} `xml:"Identification"`
+}
+
+// readParallelsBundle reads a Parallels Desktop .pvm bundle. Parallels keeps the VM's memory in
+// a .sav file while it's suspended.
+func readParallelsBundle(bundle string) (guest, error) {
+	raw, err := os.ReadFile(filepath.Join(bundle, "config.pvs"))
+	if err != nil {
+		return guest{}, fmt.Errorf("reading Parallels config: %w", err)
+	}
+
+	var config parallelsConfig
+	if err := xml.Unmarshal(raw, &config); err != nil {
+		return guest{}, fmt.Errorf("unmarshalling Parallels config %s: %w", bundle, err)
+	}
+
+	g := guest{
+		hypervisor: hypervisorParallels,
+		manager:    managerParallelsDesktop,
+		name:       config.Identification.VmName,
+		uuid:       strings.ToLower(strings.Trim(config.Identification.VmUuid, "{}")),
+		path:       bundle,
+		state:      stateStopped,
+		isProcess:  argsContainPath(bundle),
+	}
+	if g.name == "" {
+		g.name = bundleName(bundle)
+	}
+	if saved, _ := filepath.Glob(filepath.Join(bundle, "*.sav")); len(saved) > 0 {
+		g.state = stateSuspended
+	}
+
+	return g, nil
+}
+
+// vmwareBundleReader returns a reader for VMware VMs, which are a directory holding a .vmx
+// file -- a .vmwarevm bundle for Fusion, or any directory for Workstation.
+func vmwareBundleReader(manager string) func(string) (guest, error) {
+	return func(bundle string) (guest, error) {
+		vmxPaths, _ := filepath.Glob(filepath.Join(bundle, "*.vmx"))
+		if len(vmxPaths) == 0 {
+			return guest{}, fmt.Errorf("no .vmx file in %s", bundle)
+		}
+		vmxPath := vmxPaths[0]
+
+		vmx, err := readVmx(vmxPath)
+		if err != nil {
+			return guest{}, err
+		}
+
+		g := guest{
+			hypervisor: hypervisorVmware,
+			manager:    manager,
+			name:       vmx["displayname"],
+			uuid:       formatVmwareUuid(vmx["uuid.bios"]),
+			path:       vmxPath,
+			state:      stateStopped,
+			guestOS:    vmx["guestos"],
+			isProcess:  argsContainPath(vmxPath),
+		}
+		if g.name == "" {
+			g.name = bundleName(bundle)
+		}
+
+		// VMware holds a .lck directory beside the .vmx while the VM is powered on, and writes
+		// the VM's state to a .vmss file when suspending it
+		if _, err := os.Stat(vmxPath + ".lck"); err == nil {
+			g.state = stateRunning
+		} else if suspended, _ := filepath.Glob(filepath.Join(bundle, "*.vmss")); len(suspended) > 0 {
+			g.state = stateSuspended
+		}
+
+		return g, nil
+	}
+}
+
+// readVmx reads the settings in a .vmx file, which has a `key = "value"` setting per line.
+// Keys are case-insensitive, and are lowercased.
+func readVmx(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening vmx: %w", err)
+	}
+	defer f.Close()
+
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found || strings.HasPrefix(strings.TrimSpace(key), "#") {
+			continue
+		}
+		settings[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading vmx %s: %w", path, err)
+	}
+
+	return settings, nil
+}
+
+// formatVmwareUuid formats VMware's uuid.bios, which looks like
+// `56 4d 12 34 56 78 9a bc-de f0 12 34 56 78 9a bc`, as a standard UUID.
+func formatVmwareUuid(raw string) string {
+	hex := strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(raw))
+	if len(hex) != 32 {
+		return hex
+	}
+	return strings.Join([]string{hex[0:8], hex[8:12], hex[12:16], hex[16:20], hex[20:32]}, "-")
+}
+
+type utmConfig struct {
+	Backend     string `plist:"Backend"`
+	Information struct {
+		Name string `plist:"Name"`
+		UUID string `plist:"UUID"`
+	} `plist:"Information"`
+}
+
+// readUtmBundle reads a UTM .utm bundle. UTM runs VMs with either QEMU or Apple's
+// Virtualization.framework; only the QEMU ones have a process we can identify.
+func readUtmBundle(bundle string) (guest, error) {
+	raw, err := os.ReadFile(filepath.Join(bundle, "config.plist"))
+	if err != nil {
+		return guest{}, fmt.Errorf("reading UTM config: %w", err)
+	}
+
+	var config utmConfig
+	if _, err := plist.Unmarshal(raw, &config); err != nil {
+		return guest{}, fmt.Errorf("unmarshalling UTM config %s: %w", bundle, err)
+	}
+
+	g := guest{
+		hypervisor: hypervisorQemu,
+		manager:    managerUtm,
+		name:       config.Information.Name,
+		uuid:       strings.ToLower(config.Information.UUID),
+		path:       bundle,
+		state:      stateStopped,
+		isProcess:  argsContainPath(bundle),
+	}
+	if g.name == "" {
+		g.name = bundleName(bundle)
+	}
+	if config.Backend == "Apple" {
+		g.hypervisor = hypervisorVirtualizationFramework
+		g.state = stateUnknown
+		g.isProcess = nil
+	}
+
+	return g, nil
+}
+
+type tartConfig struct {
+	OS string `json:"os"`
+}
+
+// readTartBundle reads a Tart VM, which is a directory named for the VM under ~/.tart/vms.
+// A running Tart VM is a `tart run <name>` process.
+func readTartBundle(bundle string) (guest, error) {
+	raw, err := os.ReadFile(filepath.Join(bundle, "config.json"))
+	if err != nil {
+		return guest{}, fmt.Errorf("reading Tart config: %w", err)
+	}
+
+	var config tartConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return guest{}, fmt.Errorf("unmarshalling Tart config %s: %w", bundle, err)
+	}
+
+	name := filepath.Base(bundle)
+	return guest{
+		hypervisor: hypervisorVirtualizationFramework,
+		manager:    managerTart,
+		name:       name,
+		path:       bundle,
+		state:      stateStopped,
+		guestOS:    config.OS,
+		isProcess: func(cmdline []string) bool {
+			return processName(cmdline) == "tart" && slices.Contains(cmdline, "run") && slices.Contains(cmdline, name)
+		},
+	}, nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package virtualizationguests
+
+import (
+	"context"
+	"log/slog"
+)
+
+// hypervGuests returns nothing -- Hyper-V is only on Windows.
+func hypervGuests(_ context.Context, _ *slog.Logger) ([]guest, error) {
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+//go:build windows
+// +build windows
+
+package virtualizationguests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+// Get-VM fails when the Hyper-V PowerShell module isn't installed, which is the case whenever
+// Hyper-V isn't enabled -- we check for it first, so that isn't an error.
+const hypervScript = `if (-not (Get-Command Get-VM -ErrorAction SilentlyContinue)) { '[]'; exit }
+ConvertTo-Json -Compress -InputObject @(Get-VM | ForEach-Object { [pscustomobject]@{name=$_.Name; id=[string]$_.Id; state=[string]$_.State; path=$_.Path} })`
+
+type hypervVm struct {
+	Name  string `json:"name"`
+	ID    string `json:"id"`
+	State string `json:"state"`
+	Path  string `json:"path"`
+}
+
+// hypervGuests lists the Hyper-V VMs with Get-VM.
+func hypervGuests(ctx context.Context, slogger *slog.Logger) ([]guest, error) {
+	out, err := tablehelpers.RunSimple(ctx, slogger, 30, allowedcmd.Powershell, []string{"-NoProfile", "-NonInteractive", "-Command", hypervScript})
+	if err != nil {
+		return nil, fmt.Errorf("running Get-VM: %w", err)
+	}
+
+	return parseHypervOutput(out)
+}
+
+func parseHypervOutput(out []byte) ([]guest, error) {
+	var vms []hypervVm
+	if err := json.Unmarshal(out, &vms); err != nil {
+		return nil, fmt.Errorf("unmarshalling Get-VM output `%s`: %w", string(out), err)
+	}
+
+	guests := make([]guest, 0, len(vms))
+	for _, vm := range vms {
+		guests = append(guests, guest{
+			hypervisor: hypervisorHyperv,
+			manager:    managerHyperv,
+			name:       vm.Name,
+			uuid:       strings.ToLower(vm.ID),
+			path:       vm.Path,
+			state:      hypervState(vm.State),
+		})
+	}
+
+	return guests, nil
+}
+
+// hypervState maps Hyper-V's VMState to our states.
+func hypervState(state string) string {
+	switch state {
+	case "Running":
+		return stateRunning
+	case "Off":
+		return stateStopped
+	case "Saved":
+		return stateSuspended
+	case "Paused":
+		return statePaused
+	default:
+		// Starting, Stopping, Saving, and the Off/Running/Saved/Paused "Critical" variants
+		return stateUnknown
+	}
+}
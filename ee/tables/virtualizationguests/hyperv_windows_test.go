@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+package virtualizationguests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHypervOutput(t *testing.T) {
+	t.Parallel()
+
+	out := []byte(`[{"name":"Dev Box","id":"7D0E3F5C-2A4B-4C6D-8E9F-0A1B2C3D4E5F","state":"Running","path":"C:\\ProgramData\\Microsoft\\Windows\\Hyper-V"},{"name":"Old","id":"11111111-2222-3333-4444-555555555555","state":"Saved","path":"D:\\VMs"}]`)
+
+	guests, err := parseHypervOutput(out)
+	require.NoError(t, err)
+	require.Len(t, guests, 2)
+
+	require.Equal(t, "Dev Box", guests[0].name)
+	require.Equal(t, "7d0e3f5c-2a4b-4c6d-8e9f-0a1b2c3d4e5f", guests[0].uuid)
+	require.Equal(t, stateRunning, guests[0].state)
+	require.Equal(t, hypervisorHyperv, guests[0].hypervisor)
+	require.Equal(t, stateSuspended, guests[1].state)
+
+	guests, err = parseHypervOutput([]byte("[]"))
+	require.NoError(t, err)
+	require.Empty(t, guests)
+
+	_, err = parseHypervOutput([]byte("Get-VM : You do not have the required permission"))
+	require.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package virtualizationguests
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// libvirtDomain is the subset of a libvirt domain definition we report on.
+type libvirtDomain struct {
+	Type     string `xml:"type,attr"`
+	Name     string `xml:"name"`
+	UUID     string `xml:"uuid"`
+	Metadata struct {
+		Libosinfo struct {
+			OS struct {
+				ID string `xml:"id,attr"`
+			} `xml:"os"`
+		} `xml:"libosinfo"`
+	} `xml:"metadata"`
+}
+
+// libvirtStatus is the status file libvirtd keeps for each active system domain.
+type libvirtStatus struct {
+	State string `xml:"state,attr"`
+}
+
+// libvirtGuests returns the domains defined with libvirt's QEMU driver: system domains in
+// /etc/libvirt/qemu, and each user's session domains in ~/.config/libvirt/qemu.
+func (c *collector) libvirtGuests(homes []homeDir) ([]guest, error) {
+	var guests []guest
+	var errs []error
+
+	systemGuests, err := readLibvirtDomains(filepath.Join(c.rootDir, "etc", "libvirt", "qemu"), "")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, g := range systemGuests {
+		g.state = libvirtState(filepath.Join(c.rootDir, "run", "libvirt", "qemu", g.name+".xml"))
+		guests = append(guests, g)
+	}
+
+	// Session domains' status lives in the user's runtime directory, which needs a session to
+	// find -- so we rely on the running processes instead
+	for _, home := range homes {
+		sessionGuests, err := readLibvirtDomains(filepath.Join(home.path, ".config", "libvirt", "qemu"), home.username)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		guests = append(guests, sessionGuests...)
+	}
+
+	return guests, errors.Join(errs...)
+}
+
+// readLibvirtDomains reads the domain definitions in dir. A missing dir just means there are none.
+func readLibvirtDomains(dir string, username string) ([]guest, error) {
+	definitions, err := filepath.Glob(filepath.Join(dir, "*.xml"))
+	if err != nil {
+		return nil, fmt.Errorf("listing libvirt domains in %s: %w", dir, err)
+	}
+
+	var guests []guest
+	var errs []error
+	for _, definition := range definitions {
+		raw, err := os.ReadFile(definition)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading libvirt domain: %w", err))
+			continue
+		}
+
+		var domain libvirtDomain
+		if err := xml.Unmarshal(raw, &domain); err != nil {
+			errs = append(errs, fmt.Errorf("unmarshalling libvirt domain %s: %w", definition, err))
+			continue
+		}
+
+		name := domain.Name
+		guests = append(guests, guest{
+			hypervisor: domain.Type,
+			manager:    managerLibvirt,
+			name:       name,
+			uuid:       strings.ToLower(domain.UUID),
+			path:       definition,
+			state:      stateStopped,
+			guestOS:    domain.Metadata.Libosinfo.OS.ID,
+			username:   username,
+			// libvirt names the QEMU process with `-name guest=<name>,...`
+			isProcess: func(cmdline []string) bool {
+				if !isQemuProcess(cmdline) {
+					return false
+				}
+				for _, arg := range cmdline {
+					if arg == "guest="+name || strings.HasPrefix(arg, "guest="+name+",") {
+						return true
+					}
+				}
+				return false
+			},
+		})
+	}
+
+	return guests, errors.Join(errs...)
+}
+
+// libvirtState returns the state recorded in a system domain's status file. The status file
+// only exists while the domain is active.
+func libvirtState(statusPath string) string {
+	raw, err := os.ReadFile(statusPath)
+	if err != nil {
+		return stateStopped
+	}
+
+	var status libvirtStatus
+	if err := xml.Unmarshal(raw, &status); err != nil {
+		return stateUnknown
+	}
+
+	switch status.State {
+	case "running":
+		return stateRunning
+	case "paused":
+		return statePaused
+	case "pmsuspended":
+		return stateSuspended
+	case "shutoff", "crashed":
+		return stateStopped
+	default:
+		return stateUnknown
+	}
+}
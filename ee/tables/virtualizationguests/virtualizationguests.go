@@ -0,0 +1,246 @@
+// Package virtualizationguests provides the kolide_virtualization_guests table, which lists the
+// virtual machines on a device and whether they're running, across the common desktop
+// hypervisors: Parallels Desktop, VMware Fusion, UTM and Tart (Virtualization.framework or QEMU)
+// on macOS; Hyper-V and VMware Workstation on Windows; and libvirt and bare QEMU on Linux.
+//
+// VMs are found by the files the hypervisors keep them in, so we see them whether or not
+// they're running. Running state comes from the hypervisors' lock and status files where they
+// have them, and otherwise from the running processes.
+package virtualizationguests
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+const tableName = "kolide_virtualization_guests"
+
+// Guest states
+const (
+	stateRunning   = "running"
+	stateStopped   = "stopped"
+	stateSuspended = "suspended"
+	statePaused    = "paused"
+	stateUnknown   = "unknown"
+)
+
+// Hypervisors
+const (
+	hypervisorParallels               = "parallels"
+	hypervisorVmware                  = "vmware"
+	hypervisorVirtualizationFramework = "virtualization_framework"
+	hypervisorQemu                    = "qemu"
+	hypervisorHyperv                  = "hyperv"
+)
+
+// Managers -- the application that manages the VM, which isn't always the hypervisor itself
+const (
+	managerParallelsDesktop  = "parallels_desktop"
+	managerVmwareFusion      = "vmware_fusion"
+	managerVmwareWorkstation = "vmware_workstation"
+	managerUtm               = "utm"
+	managerTart              = "tart"
+	managerLibvirt           = "libvirt"
+	managerHyperv            = "hyperv"
+	managerQemu              = "qemu"
+)
+
+type guest struct {
+	hypervisor string
+	manager    string
+	name       string
+	uuid       string
+	path       string
+	state      string
+	guestOS    string
+	username   string
+
+	// isProcess reports whether the process with the given command line is running this
+	// guest, for guests whose files alone don't tell us. May be nil.
+	isProcess func(cmdline []string) bool
+}
+
+type processInfo struct {
+	cmdline  []string
+	username string
+}
+
+type Table struct {
+	slogger   *slog.Logger
+	collector *collector
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("hypervisor"),
+		table.TextColumn("manager"),
+		table.TextColumn("name"),
+		table.TextColumn("uuid"),
+		table.TextColumn("path"),
+		table.TextColumn("state"),
+		table.TextColumn("guest_os"),
+		table.TextColumn("username"),
+	}
+
+	t := &Table{
+		slogger:   slogger.With("table", tableName),
+		collector: &collector{rootDir: defaultRootDir(), goos: runtime.GOOS},
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	// Errors finding one hypervisor's VMs shouldn't stop us reporting on the others
+	guests, err := t.collector.guests()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read all virtual machines",
+			"err", err,
+		)
+	}
+
+	hypervGuests, err := hypervGuests(ctx, t.slogger)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list Hyper-V virtual machines",
+			"err", err,
+		)
+	}
+	guests = append(guests, hypervGuests...)
+
+	procs, err := runningProcesses(ctx)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list processes",
+			"err", err,
+		)
+	} else {
+		guests = applyProcesses(guests, procs)
+	}
+
+	results := make([]map[string]string, 0, len(guests))
+	for _, g := range guests {
+		results = append(results, map[string]string{
+			"hypervisor": g.hypervisor,
+			"manager":    g.manager,
+			"name":       g.name,
+			"uuid":       g.uuid,
+			"path":       g.path,
+			"state":      g.state,
+			"guest_os":   g.guestOS,
+			"username":   g.username,
+		})
+	}
+
+	return results, nil
+}
+
+// runningProcesses returns the command lines of all running processes. We only look up the
+// owner of QEMU processes, since those are the only ones we might report on directly.
+func runningProcesses(ctx context.Context) ([]processInfo, error) {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]processInfo, 0, len(procs))
+	for _, p := range procs {
+		cmdline, err := p.CmdlineSliceWithContext(ctx)
+		if err != nil || len(cmdline) == 0 {
+			// The process may have exited, or we may not be allowed to see it
+			continue
+		}
+
+		info := processInfo{cmdline: cmdline}
+		if isQemuProcess(cmdline) {
+			info.username, _ = p.UsernameWithContext(ctx)
+		}
+		results = append(results, info)
+	}
+
+	return results, nil
+}
+
+// applyProcesses marks the guests that have a running process as running, and adds any QEMU
+// processes that aren't running one of the known guests.
+func applyProcesses(guests []guest, procs []processInfo) []guest {
+	knownGuests := len(guests)
+
+	for _, p := range procs {
+		matched := false
+		for i := range guests[:knownGuests] {
+			if guests[i].isProcess == nil || !guests[i].isProcess(p.cmdline) {
+				continue
+			}
+			matched = true
+
+			// A paused or suspended guest may still have a process; the files know better
+			if guests[i].state == stateStopped || guests[i].state == stateUnknown {
+				guests[i].state = stateRunning
+			}
+		}
+
+		if !matched && isQemuProcess(p.cmdline) {
+			guests = append(guests, qemuProcessGuest(p))
+		}
+	}
+
+	return guests
+}
+
+func isQemuProcess(cmdline []string) bool {
+	return len(cmdline) > 0 && strings.HasPrefix(processName(cmdline), "qemu-system-")
+}
+
+// qemuProcessGuest describes a VM run by QEMU directly, from its command line.
+func qemuProcessGuest(p processInfo) guest {
+	g := guest{
+		hypervisor: hypervisorQemu,
+		manager:    managerQemu,
+		state:      stateRunning,
+		username:   p.username,
+	}
+
+	for i := 1; i < len(p.cmdline)-1; i++ {
+		switch p.cmdline[i] {
+		case "-name":
+			// e.g. `-name guest=debian,debug-threads=on` or `-name debian`
+			name, _, _ := strings.Cut(p.cmdline[i+1], ",")
+			g.name = strings.TrimPrefix(name, "guest=")
+		case "-uuid":
+			g.uuid = strings.ToLower(p.cmdline[i+1])
+		}
+	}
+
+	return g
+}
+
+// processName returns the name of the executable in cmdline, without any .exe suffix.
+func processName(cmdline []string) string {
+	// The command line may come from Windows, so handle either separator
+	name := cmdline[0]
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// argsContainPath returns an isProcess func that matches processes with path in an argument.
+func argsContainPath(path string) func([]string) bool {
+	path = strings.ToLower(filepath.Clean(path))
+	return func(cmdline []string) bool {
+		for _, arg := range cmdline[1:] {
+			if strings.Contains(strings.ToLower(arg), path) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,212 @@
+package virtualizationguests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// guestsByName indexes guests by name, dropping isProcess so they can be compared.
+func guestsByName(t *testing.T, guests []guest) map[string]guest {
+	byName := make(map[string]guest)
+	for _, g := range guests {
+		require.NotContains(t, byName, g.name, "duplicate guest")
+		g.isProcess = nil
+		byName[g.name] = g
+	}
+	return byName
+}
+
+func TestCollector_Darwin(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join("testdata", "darwin")
+	c := &collector{rootDir: root, goos: "darwin"}
+
+	guests, err := c.guests()
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]guest{
+		"Windows 11": {
+			hypervisor: hypervisorParallels,
+			manager:    managerParallelsDesktop,
+			name:       "Windows 11",
+			uuid:       "a1b2c3d4-e5f6-4789-9abc-def012345678",
+			path:       filepath.Join(root, "Users", "alice", "Parallels", "Windows 11.pvm"),
+			state:      stateSuspended,
+			username:   "alice",
+		},
+		"Kali": {
+			hypervisor: hypervisorParallels,
+			manager:    managerParallelsDesktop,
+			name:       "Kali",
+			uuid:       "0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9",
+			path:       filepath.Join(root, "Users", "Shared", "Parallels", "Kali.pvm"),
+			state:      stateStopped,
+		},
+		"Ubuntu 22.04": {
+			hypervisor: hypervisorVmware,
+			manager:    managerVmwareFusion,
+			name:       "Ubuntu 22.04",
+			uuid:       "564d8a3b-1c2d-3e4f-5061-728394a5b6c7",
+			path:       filepath.Join(root, "Users", "alice", "Virtual Machines.localized", "Ubuntu 22.04.vmwarevm", "Ubuntu 22.04.vmx"),
+			state:      stateRunning,
+			guestOS:    "ubuntu-64",
+			username:   "alice",
+		},
+		"Fedora": {
+			hypervisor: hypervisorQemu,
+			manager:    managerUtm,
+			name:       "Fedora",
+			uuid:       "3c4d5e6f-7081-4293-a4b5-c6d7e8f90a1b",
+			path:       filepath.Join(root, "Users", "bob", "Library", "Containers", "com.utmapp.UTM", "Data", "Documents", "Fedora.utm"),
+			state:      stateStopped,
+			username:   "bob",
+		},
+		"macOS Sequoia": {
+			hypervisor: hypervisorVirtualizationFramework,
+			manager:    managerUtm,
+			name:       "macOS Sequoia",
+			uuid:       "9e8d7c6b-5a49-4837-a261-504f3e2d1c0b",
+			path:       filepath.Join(root, "Users", "bob", "Library", "Containers", "com.utmapp.UTM", "Data", "Documents", "macOS.utm"),
+			state:      stateUnknown,
+			username:   "bob",
+		},
+		"sonoma": {
+			hypervisor: hypervisorVirtualizationFramework,
+			manager:    managerTart,
+			name:       "sonoma",
+			path:       filepath.Join(root, "Users", "bob", ".tart", "vms", "sonoma"),
+			state:      stateStopped,
+			guestOS:    "darwin",
+			username:   "bob",
+		},
+	}, guestsByName(t, guests))
+
+	// Running processes mark the stopped guests running
+	guests = applyProcesses(guests, []processInfo{
+		{cmdline: []string{"/Applications/Parallels Desktop.app/Contents/MacOS/prl_vm_app", "--openvm", filepath.Join(root, "Users", "Shared", "Parallels", "Kali.pvm")}},
+		{cmdline: []string{"/opt/homebrew/bin/tart", "run", "sonoma"}},
+		{cmdline: []string{"/usr/libexec/UserEventAgent"}},
+	})
+	byName := guestsByName(t, guests)
+	require.Len(t, byName, 6)
+	require.Equal(t, stateRunning, byName["Kali"].state)
+	require.Equal(t, stateRunning, byName["sonoma"].state)
+	require.Equal(t, stateStopped, byName["Fedora"].state)
+	require.Equal(t, stateSuspended, byName["Windows 11"].state)
+}
+
+func TestCollector_Windows(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join("testdata", "windows")
+	c := &collector{rootDir: root, goos: "windows"}
+
+	guests, err := c.guests()
+	require.NoError(t, err)
+
+	// The Public profile isn't a user, so its VMs aren't reported
+	require.Equal(t, map[string]guest{
+		"Windows 10": {
+			hypervisor: hypervisorVmware,
+			manager:    managerVmwareWorkstation,
+			name:       "Windows 10",
+			uuid:       "564d0011-2233-4455-6677-8899aabbccdd",
+			path:       filepath.Join(root, "Users", "carol", "Documents", "Virtual Machines", "Windows 10", "Windows 10.vmx"),
+			state:      stateSuspended,
+			guestOS:    "windows9-64",
+			username:   "carol",
+		},
+	}, guestsByName(t, guests))
+}
+
+func TestCollector_Linux(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join("testdata", "linux")
+	c := &collector{rootDir: root, goos: "linux"}
+
+	guests, err := c.guests()
+	require.NoError(t, err)
+
+	byName := guestsByName(t, guests)
+	require.Equal(t, map[string]guest{
+		"debian12": {
+			hypervisor: "kvm",
+			manager:    managerLibvirt,
+			name:       "debian12",
+			uuid:       "5f4e3d2c-1b0a-4998-8776-655443322110",
+			path:       filepath.Join(root, "etc", "libvirt", "qemu", "debian12.xml"),
+			state:      stateRunning,
+			guestOS:    "http://debian.org/debian/12",
+		},
+		"win11": {
+			hypervisor: "kvm",
+			manager:    managerLibvirt,
+			name:       "win11",
+			uuid:       "aa11bb22-cc33-4d44-8e55-ff6677889900",
+			path:       filepath.Join(root, "etc", "libvirt", "qemu", "win11.xml"),
+			state:      statePaused,
+			guestOS:    "http://microsoft.com/win/11",
+		},
+		"alpine": {
+			hypervisor: "qemu",
+			manager:    managerLibvirt,
+			name:       "alpine",
+			uuid:       "01234567-89ab-4cde-8f01-23456789abcd",
+			path:       filepath.Join(root, "etc", "libvirt", "qemu", "alpine.xml"),
+			state:      stateStopped,
+		},
+		"arch": {
+			hypervisor: "kvm",
+			manager:    managerLibvirt,
+			name:       "arch",
+			uuid:       "fedcba98-7654-4321-8fed-cba987654321",
+			path:       filepath.Join(root, "home", "dave", ".config", "libvirt", "qemu", "arch.xml"),
+			state:      stateStopped,
+			username:   "dave",
+		},
+	}, byName)
+
+	guests = applyProcesses(guests, []processInfo{
+		// libvirt's processes for its own domains aren't reported again
+		{cmdline: []string{"/usr/bin/qemu-system-x86_64", "-name", "guest=debian12,debug-threads=on", "-S"}, username: "libvirt-qemu"},
+		{cmdline: []string{"/usr/bin/qemu-system-x86_64", "-name", "guest=win11,debug-threads=on", "-S"}, username: "libvirt-qemu"},
+		{cmdline: []string{"/usr/bin/qemu-system-x86_64", "-name", "guest=arch,debug-threads=on"}, username: "dave"},
+		// but QEMU run directly is
+		{cmdline: []string{"qemu-system-aarch64", "-machine", "virt", "-name", "scratch", "-uuid", "ABCDEF01-2345-4678-9ABC-DEF012345678"}, username: "erin"},
+		{cmdline: []string{"/usr/bin/qemu-img", "convert", "-name", "notavm"}},
+	})
+
+	byName = guestsByName(t, guests)
+	require.Len(t, byName, 5)
+	require.Equal(t, stateRunning, byName["arch"].state)
+	require.Equal(t, statePaused, byName["win11"].state)
+	require.Equal(t, stateStopped, byName["alpine"].state)
+	require.Equal(t, guest{
+		hypervisor: hypervisorQemu,
+		manager:    managerQemu,
+		name:       "scratch",
+		uuid:       "abcdef01-2345-4678-9abc-def012345678",
+		state:      stateRunning,
+		username:   "erin",
+	}, byName["scratch"])
+}
+
+func TestFormatVmwareUuid(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "564d8a3b-1c2d-3e4f-5061-728394a5b6c7", formatVmwareUuid("56 4d 8a 3b 1c 2d 3e 4f-50 61 72 83 94 a5 b6 c7"))
+	require.Equal(t, "", formatVmwareUuid(""))
+	require.Equal(t, "564d8a", formatVmwareUuid("56 4d 8a"))
+}
+
+func TestProcessName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "vmware-vmx", processName([]string{`C:\Program Files (x86)\VMware\VMware Workstation\x64\vmware-vmx.exe`, "-s"}))
+	require.Equal(t, "qemu-system-x86_64", processName([]string{"/usr/bin/qemu-system-x86_64"}))
+	require.Equal(t, "tart", processName([]string{"tart"}))
+}
@@ -0,0 +1,120 @@
+package vpn
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// openvpnManagementTimeout bounds the exchange with a management interface. OpenVPN serves one
+// management client at a time, so we may wait behind another one, e.g. a GUI.
+const openvpnManagementTimeout = 3 * time.Second
+
+// errManagementUnavailable is returned when nothing is listening at the management interface,
+// i.e. OpenVPN isn't running the tunnel.
+var errManagementUnavailable = errors.New("management interface unavailable")
+
+// openvpnManagement is the management interface an OpenVPN config declares.
+type openvpnManagement struct {
+	network     string
+	address     string
+	hasPassword bool
+}
+
+// parseOpenvpnManagement finds the management directive in an OpenVPN config:
+// `management <ip> <port> [password file]` or `management <socket path> unix [password file]`.
+func parseOpenvpnManagement(config string) (openvpnManagement, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "management" {
+			continue
+		}
+
+		m := openvpnManagement{
+			network:     "tcp",
+			address:     net.JoinHostPort(fields[1], fields[2]),
+			hasPassword: len(fields) > 3,
+		}
+		if fields[2] == "unix" {
+			m.network = "unix"
+			m.address = fields[1]
+		}
+		return m, true
+	}
+
+	return openvpnManagement{}, false
+}
+
+// local reports whether the management interface is only reachable from this device. We
+// don't connect to any other.
+func (m openvpnManagement) local() bool {
+	if m.network == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(m.address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// queryOpenvpnState asks the management interface for the tunnel's state, returning the lines
+// of the response.
+func queryOpenvpnState(ctx context.Context, m openvpnManagement) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, openvpnManagementTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, m.network, m.address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: connecting to %s: %w", errManagementUnavailable, m.address, err)
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("setting deadline: %w", err)
+	}
+
+	if _, err := conn.Write([]byte("state\n")); err != nil {
+		return nil, fmt.Errorf("sending state command: %w", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "END":
+			// Leave the interface free for its other clients
+			_, _ = conn.Write([]byte("quit\n"))
+			return lines, nil
+		case strings.HasPrefix(line, "ERROR"):
+			return nil, fmt.Errorf("management interface returned %s", line)
+		case strings.HasPrefix(line, ">"):
+			// Real-time notifications, including the greeting, aren't part of the response
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading state response: %w", err)
+	}
+
+	return nil, errors.New("management interface closed the connection before responding")
+}
+
+// openvpnTunnelName names a tunnel for its config file, as OpenVPN's service units do.
+func openvpnTunnelName(configPath string) string {
+	return strings.TrimSuffix(filepath.Base(configPath), filepath.Ext(configPath))
+}
@@ -0,0 +1,180 @@
+package vpn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const statusTableName = "kolide_vpn_status"
+
+type StatusTable struct {
+	slogger *slog.Logger
+}
+
+// StatusTablePlugin reports the VPN tunnels on this device, one row per tunnel, with the state
+// each client reports normalized to connected, connecting, disconnecting, disconnected, or
+// unknown.
+func StatusTablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("client"),
+		table.TextColumn("name"),
+		table.TextColumn("interface"),
+		table.TextColumn("state"),
+		table.TextColumn("raw_state"),
+		table.IntegerColumn("connected"),
+		table.TextColumn("server"),
+		table.TextColumn("tunnel_address"),
+		table.BigIntColumn("since"),
+		table.TextColumn("source"),
+	}
+
+	t := &StatusTable{
+		slogger: slogger.With("table", statusTableName),
+	}
+
+	return table.NewPlugin(statusTableName, columns, t.generate)
+}
+
+func (t *StatusTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var tunnels []tunnel
+	tunnels = append(tunnels, t.wireguardTunnels(ctx)...)
+	tunnels = append(tunnels, t.openvpnTunnels(ctx)...)
+	tunnels = append(tunnels, t.ciscoTunnels(ctx)...)
+	tunnels = append(tunnels, t.platformTunnels(ctx)...)
+
+	results := make([]map[string]string, 0, len(tunnels))
+	for _, tun := range tunnels {
+		results = append(results, tun.row())
+	}
+	return results, nil
+}
+
+func (t *StatusTable) wireguardTunnels(ctx context.Context) []tunnel {
+	interfaces, err := wireguardInterfaces(ctx, t.slogger)
+	if errors.Is(err, allowedcmd.ErrCommandNotFound) {
+		return nil
+	}
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get wireguard interfaces",
+			"err", err,
+		)
+		return nil
+	}
+
+	now := time.Now()
+	tunnels := make([]tunnel, 0, len(interfaces))
+	for _, iface := range interfaces {
+		tunnels = append(tunnels, wireguardTunnel(iface, now))
+	}
+	return tunnels
+}
+
+// openvpnTunnels returns the state of the tunnels in OpenVPN's configs, asking each one's
+// management interface. Tunnels whose configs don't declare one can't be reported.
+func (t *StatusTable) openvpnTunnels(ctx context.Context) []tunnel {
+	var tunnels []tunnel
+	for _, pattern := range openvpnConfigPatterns() {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+
+		for _, path := range paths {
+			config, err := os.ReadFile(path)
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not read openvpn config",
+					"path", path,
+					"err", err,
+				)
+				continue
+			}
+
+			management, ok := parseOpenvpnManagement(string(config))
+			if !ok {
+				continue
+			}
+			if !management.local() {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"skipping openvpn management interface that is not local",
+					"path", path,
+					"address", management.address,
+				)
+				continue
+			}
+
+			tunnels = append(tunnels, t.openvpnTunnel(ctx, path, management))
+		}
+	}
+
+	return tunnels
+}
+
+func (t *StatusTable) openvpnTunnel(ctx context.Context, configPath string, management openvpnManagement) tunnel {
+	unknown := tunnel{
+		client: clientOpenvpn,
+		name:   openvpnTunnelName(configPath),
+		state:  stateUnknown,
+		source: configPath,
+	}
+
+	// We don't read the password, so we can't ask for the state
+	if management.hasPassword {
+		return unknown
+	}
+
+	lines, err := queryOpenvpnState(ctx, management)
+	if errors.Is(err, errManagementUnavailable) {
+		unknown.state = stateDisconnected
+		return unknown
+	}
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not query openvpn management interface",
+			"path", configPath,
+			"err", err,
+		)
+		return unknown
+	}
+
+	tun, ok := parseOpenvpnState(lines)
+	if !ok {
+		return unknown
+	}
+	tun.name = unknown.name
+	tun.source = configPath
+	return tun
+}
+
+func (t *StatusTable) ciscoTunnels(ctx context.Context) []tunnel {
+	var stdout, stderr bytes.Buffer
+	err := tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.CiscoVpn, []string{"state"}, &stdout, &stderr)
+	if errors.Is(err, allowedcmd.ErrCommandNotFound) {
+		return nil
+	}
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get cisco secure client state",
+			"stderr", stderr.String(),
+			"err", err,
+		)
+		return nil
+	}
+
+	tun, ok := parseCiscoState(stdout.String())
+	if !ok {
+		return nil
+	}
+	tun.source = "vpn"
+	return []tunnel{tun}
+}
@@ -0,0 +1,37 @@
+//go:build darwin
+// +build darwin
+
+package vpn
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+func openvpnConfigPatterns() []string {
+	return []string{
+		"/opt/homebrew/etc/openvpn/*.conf",
+		"/usr/local/etc/openvpn/*.conf",
+	}
+}
+
+// platformTunnels returns the VPN services macOS knows about. This includes GlobalProtect and
+// Cisco Secure Client, which are built as network extensions, and WireGuard's app, which
+// doesn't ship wg.
+func (t *StatusTable) platformTunnels(ctx context.Context) []tunnel {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.Scutil, []string{"--nc", "list"}, &stdout, &stderr); err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list network connection services",
+			"stderr", stderr.String(),
+			"err", err,
+		)
+		return nil
+	}
+
+	return parseScutilNcList(stdout.String())
+}
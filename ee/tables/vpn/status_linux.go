@@ -0,0 +1,59 @@
+//go:build linux
+// +build linux
+
+package vpn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+)
+
+func openvpnConfigPatterns() []string {
+	return []string{
+		"/etc/openvpn/*.conf",
+		"/etc/openvpn/client/*.conf",
+		"/etc/openvpn/server/*.conf",
+	}
+}
+
+// platformTunnels returns the tunnels NetworkManager manages, and GlobalProtect's.
+func (t *StatusTable) platformTunnels(ctx context.Context) []tunnel {
+	var tunnels []tunnel
+
+	var stdout, stderr bytes.Buffer
+	err := tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.Nmcli, []string{"-t", "-f", "NAME,TYPE,DEVICE,STATE", "connection", "show", "--active"}, &stdout, &stderr)
+	switch {
+	case err == nil:
+		tunnels = append(tunnels, parseNmcliActiveConnections(stdout.String())...)
+	case !errors.Is(err, allowedcmd.ErrCommandNotFound):
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get networkmanager connections",
+			"stderr", stderr.String(),
+			"err", err,
+		)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	err = tablehelpers.Run(ctx, t.slogger, 10, allowedcmd.Globalprotect, []string{"show", "--status"}, &stdout, &stderr)
+	switch {
+	case err == nil:
+		if tun, ok := parseGlobalprotectStatus(stdout.String()); ok {
+			tun.source = "globalprotect"
+			tunnels = append(tunnels, tun)
+		}
+	case !errors.Is(err, allowedcmd.ErrCommandNotFound):
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get globalprotect status",
+			"stderr", stderr.String(),
+			"err", err,
+		)
+	}
+
+	return tunnels
+}
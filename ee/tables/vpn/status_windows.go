@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package vpn
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+func openvpnConfigPatterns() []string {
+	return []string{
+		filepath.Join(os.Getenv("PROGRAMFILES"), "OpenVPN", "config", "*.ovpn"),
+		filepath.Join(os.Getenv("PROGRAMFILES"), "OpenVPN", "config-auto", "*.ovpn"),
+	}
+}
+
+// platformTunnels returns nothing on Windows: the clients we report there are queried on
+// every platform.
+func (t *StatusTable) platformTunnels(_ context.Context) []tunnel {
+	return nil
+}
@@ -0,0 +1,387 @@
+// Package vpn provides tables reporting the VPN tunnels on this device: kolide_wireguard, with
+// WireGuard's interfaces and peers, and kolide_vpn_status, which normalizes the state of
+// WireGuard, OpenVPN, and the common enterprise clients into one row per tunnel, so that
+// conditional access can check tunnel state from the endpoint.
+package vpn
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Normalized tunnel states
+const (
+	stateConnected     = "connected"
+	stateConnecting    = "connecting"
+	stateDisconnecting = "disconnecting"
+	stateDisconnected  = "disconnected"
+	stateUnknown       = "unknown"
+)
+
+// Client names, for the client column
+const (
+	clientWireguard      = "wireguard"
+	clientOpenvpn        = "openvpn"
+	clientCisco          = "cisco_secure_client"
+	clientGlobalprotect  = "globalprotect"
+	clientNetworkManager = "networkmanager"
+)
+
+// wireguardHandshakeTimeout is how long a WireGuard session lasts without a new handshake --
+// WireGuard's reject-after-time. Peers exchange handshakes every two minutes while there's
+// traffic, so a peer without a handshake in this long has no working session.
+const wireguardHandshakeTimeout = 180 * time.Second
+
+// stateAliases maps the states clients report, lowercased, to the normalized states.
+var stateAliases = map[string]string{
+	"connected":     stateConnected,
+	"activated":     stateConnected,
+	"connecting":    stateConnecting,
+	"activating":    stateConnecting,
+	"reconnecting":  stateConnecting,
+	"wait":          stateConnecting,
+	"auth":          stateConnecting,
+	"auth_pending":  stateConnecting,
+	"get_config":    stateConnecting,
+	"assign_ip":     stateConnecting,
+	"add_routes":    stateConnecting,
+	"resolve":       stateConnecting,
+	"tcp_connect":   stateConnecting,
+	"disconnecting": stateDisconnecting,
+	"deactivating":  stateDisconnecting,
+	"exiting":       stateDisconnecting,
+	"disconnected":  stateDisconnected,
+	"deactivated":   stateDisconnected,
+}
+
+// normalizeState maps the state a client reports to one of the normalized states.
+func normalizeState(rawState string) string {
+	if state, ok := stateAliases[strings.ToLower(strings.TrimSpace(rawState))]; ok {
+		return state
+	}
+	return stateUnknown
+}
+
+// tunnel is a VPN tunnel, normalized across clients.
+type tunnel struct {
+	client   string
+	name     string
+	iface    string
+	state    string
+	rawState string
+	server   string
+	address  string
+	since    time.Time
+	source   string
+}
+
+func (t tunnel) row() map[string]string {
+	connected := "0"
+	if t.state == stateConnected {
+		connected = "1"
+	}
+
+	since := ""
+	if !t.since.IsZero() {
+		since = strconv.FormatInt(t.since.Unix(), 10)
+	}
+
+	return map[string]string{
+		"client":         t.client,
+		"name":           t.name,
+		"interface":      t.iface,
+		"state":          t.state,
+		"raw_state":      t.rawState,
+		"connected":      connected,
+		"server":         t.server,
+		"tunnel_address": t.address,
+		"since":          since,
+		"source":         t.source,
+	}
+}
+
+// wireguardInterface is a WireGuard interface, with its peers. Private and preshared keys are
+// never kept.
+type wireguardInterface struct {
+	name       string
+	publicKey  string
+	listenPort string
+	fwmark     string
+	peers      []wireguardPeer
+}
+
+type wireguardPeer struct {
+	publicKey           string
+	endpoint            string
+	allowedIps          string
+	latestHandshake     time.Time
+	rxBytes             string
+	txBytes             string
+	persistentKeepalive string
+}
+
+// connected reports whether the peer has a working session.
+func (p wireguardPeer) connected(now time.Time) bool {
+	return !p.latestHandshake.IsZero() && now.Sub(p.latestHandshake) < wireguardHandshakeTimeout
+}
+
+// parseWgDump parses the output of `wg show all dump`: a tab-separated line for each interface,
+// followed by one for each of its peers.
+func parseWgDump(output string) []wireguardInterface {
+	var interfaces []wireguardInterface
+	byName := make(map[string]int)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		switch len(fields) {
+		case 5:
+			// interface, private key, public key, listen port, fwmark
+			byName[fields[0]] = len(interfaces)
+			interfaces = append(interfaces, wireguardInterface{
+				name:       fields[0],
+				publicKey:  fields[2],
+				listenPort: fields[3],
+				fwmark:     noneToEmpty(fields[4]),
+			})
+		case 9:
+			// interface, public key, preshared key, endpoint, allowed ips, latest handshake,
+			// transfer rx, transfer tx, persistent keepalive
+			i, ok := byName[fields[0]]
+			if !ok {
+				continue
+			}
+			peer := wireguardPeer{
+				publicKey:           fields[1],
+				endpoint:            noneToEmpty(fields[3]),
+				allowedIps:          noneToEmpty(fields[4]),
+				rxBytes:             fields[6],
+				txBytes:             fields[7],
+				persistentKeepalive: noneToEmpty(fields[8]),
+			}
+			if handshake, err := strconv.ParseInt(fields[5], 10, 64); err == nil && handshake > 0 {
+				peer.latestHandshake = time.Unix(handshake, 0)
+			}
+			interfaces[i].peers = append(interfaces[i].peers, peer)
+		}
+	}
+
+	return interfaces
+}
+
+// noneToEmpty drops the placeholders wg prints for unset values.
+func noneToEmpty(value string) string {
+	if value == "(none)" || value == "off" {
+		return ""
+	}
+	return value
+}
+
+// wireguardTunnel summarizes a WireGuard interface as a tunnel: it's connected if any peer has
+// a working session, and its server is the endpoint of the peer with the latest handshake.
+func wireguardTunnel(iface wireguardInterface, now time.Time) tunnel {
+	t := tunnel{
+		client: clientWireguard,
+		name:   iface.name,
+		iface:  iface.name,
+		state:  stateDisconnected,
+		source: "wg",
+	}
+
+	var latest time.Time
+	for _, peer := range iface.peers {
+		if peer.latestHandshake.After(latest) {
+			latest = peer.latestHandshake
+			t.server = peer.endpoint
+		}
+		if peer.connected(now) {
+			t.state = stateConnected
+		}
+	}
+
+	return t
+}
+
+// parseOpenvpnState parses the response to the OpenVPN management interface's `state`
+// command: a comma-separated line with the time of the last state change, the state, a
+// description, the tunnel address, and the server's address.
+func parseOpenvpnState(lines []string) (tunnel, bool) {
+	// The response ends with the current state
+	for i := len(lines) - 1; i >= 0; i -= 1 {
+		fields := strings.Split(lines[i], ",")
+		if len(fields) < 5 {
+			continue
+		}
+
+		t := tunnel{
+			client:   clientOpenvpn,
+			state:    normalizeState(fields[1]),
+			rawState: fields[1],
+			address:  fields[3],
+			server:   fields[4],
+		}
+		if changed, err := strconv.ParseInt(fields[0], 10, 64); err == nil && changed > 0 {
+			t.since = time.Unix(changed, 0)
+		}
+		return t, true
+	}
+
+	return tunnel{}, false
+}
+
+var (
+	ciscoStatePattern     = regexp.MustCompile(`>>\s*state:\s*(.+?)\s*$`)
+	ciscoConnectedPattern = regexp.MustCompile(`>>\s*notice:\s*Connected to (.+?)\.?\s*$`)
+)
+
+// parseCiscoState parses the output of the Cisco Secure Client (formerly AnyConnect) CLI's
+// `state` command, which reports the state several times as it attaches to the VPN agent;
+// the last report is current.
+func parseCiscoState(output string) (tunnel, bool) {
+	t := tunnel{client: clientCisco, name: "Cisco Secure Client"}
+	found := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := ciscoStatePattern.FindStringSubmatch(line); match != nil {
+			t.rawState = match[1]
+			found = true
+		}
+		if match := ciscoConnectedPattern.FindStringSubmatch(line); match != nil {
+			t.server = match[1]
+		}
+	}
+
+	t.state = normalizeState(t.rawState)
+	if t.state != stateConnected {
+		t.server = ""
+	}
+	return t, found
+}
+
+// parseGlobalprotectStatus parses the output of the GlobalProtect CLI's `show --status`
+// command: lines of the form `GlobalProtect status: Connected`, and, when connected, the
+// gateway.
+func parseGlobalprotectStatus(output string) (tunnel, bool) {
+	t := tunnel{client: clientGlobalprotect, name: "GlobalProtect"}
+	found := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasSuffix(key, "status") && !found:
+			t.rawState = value
+			found = true
+		case strings.HasSuffix(key, "gateway") && t.server == "":
+			t.server = value
+		}
+	}
+
+	t.state = normalizeState(t.rawState)
+	return t, found
+}
+
+// parseNmcliActiveConnections parses the output of
+// `nmcli -t -f NAME,TYPE,DEVICE,STATE connection show --active`, returning the VPN and
+// WireGuard connections NetworkManager manages.
+func parseNmcliActiveConnections(output string) []tunnel {
+	var tunnels []tunnel
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := splitNmcliTerse(scanner.Text())
+		if len(fields) != 4 || (fields[1] != "vpn" && fields[1] != "wireguard") {
+			continue
+		}
+		tunnels = append(tunnels, tunnel{
+			client:   clientNetworkManager,
+			name:     fields[0],
+			iface:    fields[2],
+			state:    normalizeState(fields[3]),
+			rawState: fields[3],
+			source:   "nmcli",
+		})
+	}
+
+	return tunnels
+}
+
+// splitNmcliTerse splits a line of nmcli's terse output into its fields, which are separated
+// by colons, with colons and backslashes in values escaped by a backslash.
+func splitNmcliTerse(line string) []string {
+	var fields []string
+	var field strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	return append(fields, field.String())
+}
+
+var scutilServicePattern = regexp.MustCompile(`^\*?\s*\(([^)]+)\)\s+[0-9A-Fa-f-]{36}\s+.*?"(.*)"\s+\[([^\]]*)\]\s*$`)
+
+// parseScutilNcList parses the output of `scutil --nc list`, which lists the VPN services in
+// the current network set -- including the enterprise clients built as network extensions,
+// such as GlobalProtect and Cisco Secure Client -- with their states.
+func parseScutilNcList(output string) []tunnel {
+	var tunnels []tunnel
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		match := scutilServicePattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		tunnels = append(tunnels, tunnel{
+			client:   scutilClient(match[3]),
+			name:     match[2],
+			state:    normalizeState(match[1]),
+			rawState: match[1],
+			source:   "scutil",
+		})
+	}
+
+	return tunnels
+}
+
+// scutilClient names the client from a service's type, e.g. VPN/com.paloaltonetworks.GlobalProtect.client
+// or PPP/L2TP, using our client names for the clients we know.
+func scutilClient(serviceType string) string {
+	lowered := strings.ToLower(serviceType)
+	switch {
+	case strings.Contains(lowered, "paloaltonetworks"):
+		return clientGlobalprotect
+	case strings.Contains(lowered, "cisco"):
+		return clientCisco
+	case strings.Contains(lowered, "wireguard"):
+		return clientWireguard
+	case strings.Contains(lowered, "openvpn"):
+		return clientOpenvpn
+	}
+
+	if _, provider, found := strings.Cut(serviceType, "/"); found {
+		return provider
+	}
+	return serviceType
+}
@@ -0,0 +1,262 @@
+package vpn
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWgDump(t *testing.T) {
+	t.Parallel()
+
+	dump := "wg0\tcHJpdmF0ZQ==\tcHVibGlj\t51820\toff\n" +
+		"wg0\tcGVlcjE=\t(none)\t203.0.113.5:51820\t10.0.0.0/24,10.1.0.0/16\t1700000000\t1024\t2048\t25\n" +
+		"wg0\tcGVlcjI=\tcHNr\t(none)\t(none)\t0\t0\t0\toff\n" +
+		"wg1\tcHJpdmF0ZTI=\tcHVibGljMg==\t51821\t0xca6c\n"
+
+	interfaces := parseWgDump(dump)
+	require.Equal(t, []wireguardInterface{
+		{
+			name:       "wg0",
+			publicKey:  "cHVibGlj",
+			listenPort: "51820",
+			peers: []wireguardPeer{
+				{
+					publicKey:           "cGVlcjE=",
+					endpoint:            "203.0.113.5:51820",
+					allowedIps:          "10.0.0.0/24,10.1.0.0/16",
+					latestHandshake:     time.Unix(1700000000, 0),
+					rxBytes:             "1024",
+					txBytes:             "2048",
+					persistentKeepalive: "25",
+				},
+				{
+					publicKey: "cGVlcjI=",
+					rxBytes:   "0",
+					txBytes:   "0",
+				},
+			},
+		},
+		{
+			name:       "wg1",
+			publicKey:  "cHVibGljMg==",
+			listenPort: "51821",
+			fwmark:     "0xca6c",
+		},
+	}, interfaces)
+
+	// Keys that must stay secret never make it out of the parser
+	require.NotContains(t, wireguardRow(interfaces[0], interfaces[0].peers[1], time.Now()), "cHNr")
+
+	connected := wireguardTunnel(interfaces[0], time.Unix(1700000000, 0).Add(time.Minute))
+	require.Equal(t, stateConnected, connected.state)
+	require.Equal(t, "203.0.113.5:51820", connected.server)
+
+	stale := wireguardTunnel(interfaces[0], time.Unix(1700000000, 0).Add(10*time.Minute))
+	require.Equal(t, stateDisconnected, stale.state)
+
+	require.Equal(t, stateDisconnected, wireguardTunnel(interfaces[1], time.Now()).state)
+}
+
+func TestParseOpenvpnManagement(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testCaseName string
+		config       string
+		expected     openvpnManagement
+		expectFound  bool
+		expectLocal  bool
+	}{
+		{
+			testCaseName: "tcp",
+			config:       "client\ndev tun\n# management 0.0.0.0 1\nmanagement 127.0.0.1 7505\n",
+			expected:     openvpnManagement{network: "tcp", address: "127.0.0.1:7505"},
+			expectFound:  true,
+			expectLocal:  true,
+		},
+		{
+			testCaseName: "unix socket with password",
+			config:       "management /run/openvpn/work.sock unix /etc/openvpn/mgmt.pw\n",
+			expected:     openvpnManagement{network: "unix", address: "/run/openvpn/work.sock", hasPassword: true},
+			expectFound:  true,
+			expectLocal:  true,
+		},
+		{
+			testCaseName: "remote",
+			config:       "management 0.0.0.0 7505\n",
+			expected:     openvpnManagement{network: "tcp", address: "0.0.0.0:7505"},
+			expectFound:  true,
+			expectLocal:  false,
+		},
+		{
+			testCaseName: "none",
+			config:       "client\nremote vpn.example.com 1194\n",
+			expectFound:  false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			management, found := parseOpenvpnManagement(tt.config)
+			require.Equal(t, tt.expectFound, found)
+			require.Equal(t, tt.expected, management)
+			if found {
+				require.Equal(t, tt.expectLocal, management.local())
+			}
+		})
+	}
+}
+
+func TestQueryOpenvpnState(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Serve one client the way OpenVPN does: a greeting, then the state history on request
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte(">INFO:OpenVPN Management Interface Version 5 -- type 'help' for more info\r\n"))
+		reader := bufio.NewReader(conn)
+		if command, _ := reader.ReadString('\n'); strings.TrimSpace(command) != "state" {
+			return
+		}
+		conn.Write([]byte("1700000000,CONNECTED,SUCCESS,10.8.0.6,203.0.113.5,1194,,\r\nEND\r\n"))
+		reader.ReadString('\n')
+	}()
+
+	lines, err := queryOpenvpnState(context.TODO(), openvpnManagement{network: "tcp", address: listener.Addr().String()})
+	require.NoError(t, err)
+
+	tun, ok := parseOpenvpnState(lines)
+	require.True(t, ok)
+	require.Equal(t, tunnel{
+		client:   clientOpenvpn,
+		state:    stateConnected,
+		rawState: "CONNECTED",
+		address:  "10.8.0.6",
+		server:   "203.0.113.5",
+		since:    time.Unix(1700000000, 0),
+	}, tun)
+}
+
+func TestQueryOpenvpnState_notRunning(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	_, err = queryOpenvpnState(context.TODO(), openvpnManagement{network: "tcp", address: address})
+	require.ErrorIs(t, err, errManagementUnavailable)
+}
+
+func TestParseCiscoState(t *testing.T) {
+	t.Parallel()
+
+	output := `Cisco Secure Client (version 5.0.01242) .
+
+Copyright (c) 2004 - 2022 Cisco Systems, Inc.  All Rights Reserved.
+
+
+  >> state: Connected
+  >> state: Connected
+  >> notice: Connected to vpn.example.com.
+  >> registered with local VPN subsystem.
+  >> state: Connected
+VPN>`
+
+	tun, ok := parseCiscoState(output)
+	require.True(t, ok)
+	require.Equal(t, stateConnected, tun.state)
+	require.Equal(t, "Connected", tun.rawState)
+	require.Equal(t, "vpn.example.com", tun.server)
+
+	tun, ok = parseCiscoState("  >> state: Disconnected\n  >> notice: Ready to connect.\n")
+	require.True(t, ok)
+	require.Equal(t, stateDisconnected, tun.state)
+	require.Equal(t, "", tun.server)
+
+	_, ok = parseCiscoState("error: could not attach to the VPN agent\n")
+	require.False(t, ok)
+}
+
+func TestParseGlobalprotectStatus(t *testing.T) {
+	t.Parallel()
+
+	tun, ok := parseGlobalprotectStatus("GlobalProtect status: Connected\nGateway: gw.example.com\n")
+	require.True(t, ok)
+	require.Equal(t, stateConnected, tun.state)
+	require.Equal(t, "gw.example.com", tun.server)
+
+	_, ok = parseGlobalprotectStatus("Error: GlobalProtect service is not running\n")
+	require.False(t, ok)
+}
+
+func TestParseNmcliActiveConnections(t *testing.T) {
+	t.Parallel()
+
+	output := `Wired connection 1:802-3-ethernet:enp0s31f6:activated
+Work\: Office VPN:vpn:wlp2s0:activated
+home-wg:wireguard:home-wg:activating
+`
+	require.Equal(t, []tunnel{
+		{client: clientNetworkManager, name: "Work: Office VPN", iface: "wlp2s0", state: stateConnected, rawState: "activated", source: "nmcli"},
+		{client: clientNetworkManager, name: "home-wg", iface: "home-wg", state: stateConnecting, rawState: "activating", source: "nmcli"},
+	}, parseNmcliActiveConnections(output))
+}
+
+func TestParseScutilNcList(t *testing.T) {
+	t.Parallel()
+
+	output := `Available network connection services in the current set (*=enabled):
+* (Connected)      4B9D2D5D-9AE1-4B3E-8A3D-3B5A5C1B4E0F VPN (com.paloaltonetworks.GlobalProtect.client) "GlobalProtect"                 [VPN/com.paloaltonetworks.GlobalProtect.client]
+* (Disconnected)   0F3A6E29-1C44-4F0B-9B6D-2E1B6A7C5D3E VPN (com.wireguard.macos) "Home"                                           [VPN/com.wireguard.macos]
+  (Disconnected)   8C1B2A3D-4E5F-4A6B-8C7D-9E0F1A2B3C4D PPP --> L2TP       "Legacy L2TP"                                          [PPP/L2TP]
+`
+	require.Equal(t, []tunnel{
+		{client: clientGlobalprotect, name: "GlobalProtect", state: stateConnected, rawState: "Connected", source: "scutil"},
+		{client: clientWireguard, name: "Home", state: stateDisconnected, rawState: "Disconnected", source: "scutil"},
+		{client: "L2TP", name: "Legacy L2TP", state: stateDisconnected, rawState: "Disconnected", source: "scutil"},
+	}, parseScutilNcList(output))
+}
+
+func TestTunnelRow(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, map[string]string{
+		"client":         clientOpenvpn,
+		"name":           "work",
+		"interface":      "",
+		"state":          stateConnected,
+		"raw_state":      "CONNECTED",
+		"connected":      "1",
+		"server":         "203.0.113.5",
+		"tunnel_address": "10.8.0.6",
+		"since":          "1700000000",
+		"source":         "/etc/openvpn/client/work.conf",
+	}, tunnel{
+		client:   clientOpenvpn,
+		name:     "work",
+		state:    stateConnected,
+		rawState: "CONNECTED",
+		server:   "203.0.113.5",
+		address:  "10.8.0.6",
+		since:    time.Unix(1700000000, 0),
+		source:   "/etc/openvpn/client/work.conf",
+	}.row())
+	require.Equal(t, "work", openvpnTunnelName("/etc/openvpn/client/work.conf"))
+}
@@ -0,0 +1,108 @@
+package vpn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+const wireguardTableName = "kolide_wireguard"
+
+type WireguardTable struct {
+	slogger *slog.Logger
+}
+
+// WireguardTablePlugin reports WireGuard's interfaces and peers, one row per peer. Interfaces
+// without peers have a row with empty peer columns.
+func WireguardTablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("interface"),
+		table.TextColumn("public_key"),
+		table.IntegerColumn("listen_port"),
+		table.TextColumn("fwmark"),
+		table.TextColumn("peer_public_key"),
+		table.TextColumn("endpoint"),
+		table.TextColumn("allowed_ips"),
+		table.BigIntColumn("latest_handshake"),
+		table.BigIntColumn("rx_bytes"),
+		table.BigIntColumn("tx_bytes"),
+		table.IntegerColumn("persistent_keepalive"),
+		table.IntegerColumn("connected"),
+	}
+
+	t := &WireguardTable{
+		slogger: slogger.With("table", wireguardTableName),
+	}
+
+	return table.NewPlugin(wireguardTableName, columns, t.generate)
+}
+
+func (t *WireguardTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	interfaces, err := wireguardInterfaces(ctx, t.slogger)
+	if errors.Is(err, allowedcmd.ErrCommandNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get wireguard interfaces",
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	now := time.Now()
+	results := make([]map[string]string, 0)
+	for _, iface := range interfaces {
+		if len(iface.peers) == 0 {
+			results = append(results, wireguardRow(iface, wireguardPeer{}, now))
+			continue
+		}
+		for _, peer := range iface.peers {
+			results = append(results, wireguardRow(iface, peer, now))
+		}
+	}
+
+	return results, nil
+}
+
+func wireguardRow(iface wireguardInterface, peer wireguardPeer, now time.Time) map[string]string {
+	row := map[string]string{
+		"interface":            iface.name,
+		"public_key":           iface.publicKey,
+		"listen_port":          iface.listenPort,
+		"fwmark":               iface.fwmark,
+		"peer_public_key":      peer.publicKey,
+		"endpoint":             peer.endpoint,
+		"allowed_ips":          peer.allowedIps,
+		"latest_handshake":     "",
+		"rx_bytes":             peer.rxBytes,
+		"tx_bytes":             peer.txBytes,
+		"persistent_keepalive": peer.persistentKeepalive,
+		"connected":            "0",
+	}
+	if !peer.latestHandshake.IsZero() {
+		row["latest_handshake"] = strconv.FormatInt(peer.latestHandshake.Unix(), 10)
+	}
+	if peer.connected(now) {
+		row["connected"] = "1"
+	}
+	return row
+}
+
+// wireguardInterfaces returns the WireGuard interfaces, as reported by wg. wg needs root to
+// read them.
+func wireguardInterfaces(ctx context.Context, slogger *slog.Logger) ([]wireguardInterface, error) {
+	var stdout, stderr bytes.Buffer
+	if err := tablehelpers.Run(ctx, slogger, 10, allowedcmd.Wg, []string{"show", "all", "dump"}, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("running wg: %s: %w", stderr.String(), err)
+	}
+	return parseWgDump(stdout.String()), nil
+}
@@ -0,0 +1,82 @@
+// Package windowsfirewall provides a table of the Windows Firewall's settings for each network
+// profile, from the firewall's COM API. The firewall rules alone can't say whether the
+// firewall is on for the networks the device is connected to.
+package windowsfirewall
+
+import (
+	"strconv"
+	"strings"
+)
+
+const tableName = "kolide_windows_firewall_profiles"
+
+// Profile types, from NET_FW_PROFILE_TYPE2, see
+// https://learn.microsoft.com/en-us/windows/win32/api/icftypes/ne-icftypes-net_fw_profile_type2
+const (
+	profileTypeDomain  int32 = 0x1
+	profileTypePrivate int32 = 0x2
+	profileTypePublic  int32 = 0x4
+)
+
+var profileNames = map[int32]string{
+	profileTypeDomain:  "domain",
+	profileTypePrivate: "private",
+	profileTypePublic:  "public",
+}
+
+// profileTypes are the profiles we report on, in order.
+var profileTypes = []int32{profileTypeDomain, profileTypePrivate, profileTypePublic}
+
+// Actions, from NET_FW_ACTION, see
+// https://learn.microsoft.com/en-us/windows/win32/api/icftypes/ne-icftypes-net_fw_action
+var actionNames = map[int32]string{
+	0: "block",
+	1: "allow",
+}
+
+// profile is the firewall's settings for a profile, as read from INetFwPolicy2, see
+// https://learn.microsoft.com/en-us/windows/win32/api/netfw/nn-netfw-inetfwpolicy2
+type profile struct {
+	profileType              int32
+	active                   bool // whether a connected network uses the profile
+	enabled                  bool
+	defaultInboundAction     int32
+	defaultOutboundAction    int32
+	blockAllInbound          bool // whether inbound traffic is blocked regardless of the rules
+	notificationsDisabled    bool // whether users aren't told when an application is blocked
+	unicastResponsesDisabled bool // whether unicast responses to multicast and broadcast are blocked
+	excludedInterfaces       []string
+}
+
+func (p profile) row() map[string]string {
+	return map[string]string{
+		"profile":                    profileNames[p.profileType],
+		"active":                     boolToIntString(p.active),
+		"enabled":                    boolToIntString(p.enabled),
+		"default_inbound_action":     actionName(p.defaultInboundAction),
+		"default_outbound_action":    actionName(p.defaultOutboundAction),
+		"block_all_inbound":          boolToIntString(p.blockAllInbound),
+		"notifications_disabled":     boolToIntString(p.notificationsDisabled),
+		"unicast_responses_disabled": boolToIntString(p.unicastResponsesDisabled),
+		"excluded_interfaces":        strings.Join(p.excludedInterfaces, ","),
+	}
+}
+
+// isActive reports whether the profile type is among the current profile types, a bitmask.
+func isActive(profileType int32, currentProfileTypes int32) bool {
+	return currentProfileTypes&profileType != 0
+}
+
+func actionName(action int32) string {
+	if name, ok := actionNames[action]; ok {
+		return name
+	}
+	return strconv.Itoa(int(action))
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,83 @@
+package windowsfirewall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileRow(t *testing.T) {
+	t.Parallel()
+
+	// A device connected to its domain network
+	const currentProfileTypes = profileTypeDomain
+
+	for _, tt := range []struct {
+		name     string
+		profile  profile
+		expected map[string]string
+	}{
+		{
+			name: "domain",
+			profile: profile{
+				profileType:           profileTypeDomain,
+				enabled:               true,
+				defaultInboundAction:  0,
+				defaultOutboundAction: 1,
+				excludedInterfaces:    []string{"{8A3F9A0E-6C36-4C1B-9C55-2B0E8D1C2F11}", "{1F6A3D2B-0C4E-4B7A-8E5D-3A9C7B1E0D22}"},
+			},
+			expected: map[string]string{
+				"profile":                    "domain",
+				"active":                     "1",
+				"enabled":                    "1",
+				"default_inbound_action":     "block",
+				"default_outbound_action":    "allow",
+				"block_all_inbound":          "0",
+				"notifications_disabled":     "0",
+				"unicast_responses_disabled": "0",
+				"excluded_interfaces":        "{8A3F9A0E-6C36-4C1B-9C55-2B0E8D1C2F11},{1F6A3D2B-0C4E-4B7A-8E5D-3A9C7B1E0D22}",
+			},
+		},
+		{
+			name: "public",
+			profile: profile{
+				profileType:              profileTypePublic,
+				enabled:                  false,
+				defaultInboundAction:     1,
+				defaultOutboundAction:    7,
+				blockAllInbound:          true,
+				notificationsDisabled:    true,
+				unicastResponsesDisabled: true,
+			},
+			expected: map[string]string{
+				"profile":                    "public",
+				"active":                     "0",
+				"enabled":                    "0",
+				"default_inbound_action":     "allow",
+				"default_outbound_action":    "7",
+				"block_all_inbound":          "1",
+				"notifications_disabled":     "1",
+				"unicast_responses_disabled": "1",
+				"excluded_interfaces":        "",
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.profile.active = isActive(tt.profile.profileType, currentProfileTypes)
+			require.Equal(t, tt.expected, tt.profile.row())
+		})
+	}
+}
+
+func TestIsActive(t *testing.T) {
+	t.Parallel()
+
+	// Connected to both a private and a public network
+	current := profileTypePrivate | profileTypePublic
+	require.False(t, isActive(profileTypeDomain, current))
+	require.True(t, isActive(profileTypePrivate, current))
+	require.True(t, isActive(profileTypePublic, current))
+}
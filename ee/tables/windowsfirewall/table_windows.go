@@ -0,0 +1,140 @@
+//go:build windows
+// +build windows
+
+package windowsfirewall
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+	"github.com/kolide/launcher/pkg/windows/oleconv"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/scjalliance/comshim"
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("profile"),
+		table.IntegerColumn("active"),
+		table.IntegerColumn("enabled"),
+		table.TextColumn("default_inbound_action"),
+		table.TextColumn("default_outbound_action"),
+		table.IntegerColumn("block_all_inbound"),
+		table.IntegerColumn("notifications_disabled"),
+		table.IntegerColumn("unicast_responses_disabled"),
+		table.TextColumn("excluded_interfaces"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	comshim.Add(1)
+	defer comshim.Done()
+
+	policy, err := newFirewallPolicy()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get firewall policy",
+			"err", err,
+		)
+		return nil, nil
+	}
+	defer policy.Release()
+
+	currentProfileTypes, err := oleconv.ToInt32Err(oleutil.GetProperty(policy, "CurrentProfileTypes"))
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get current firewall profiles",
+			"err", err,
+		)
+	}
+
+	results := make([]map[string]string, 0, len(profileTypes))
+	for _, profileType := range profileTypes {
+		p, err := readProfile(policy, profileType)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read firewall profile",
+				"profile", profileNames[profileType],
+				"err", err,
+			)
+			continue
+		}
+		p.active = isActive(profileType, currentProfileTypes)
+
+		results = append(results, p.row())
+	}
+
+	return results, nil
+}
+
+// newFirewallPolicy creates the HNetCfg.FwPolicy2 object, implementing INetFwPolicy2. The
+// caller must release it.
+func newFirewallPolicy() (*ole.IDispatch, error) {
+	unknown, err := oleutil.CreateObject("HNetCfg.FwPolicy2")
+	if err != nil {
+		return nil, fmt.Errorf("create HNetCfg.FwPolicy2: %w", err)
+	}
+	defer unknown.Release()
+
+	disp, err := unknown.QueryInterface(ole.IID_IDispatch)
+	if err != nil {
+		return nil, fmt.Errorf("IID_IDispatch: %w", err)
+	}
+
+	return disp, nil
+}
+
+// readProfile reads the policy's settings for the profile type. Each is an indexed property,
+// taking the profile type.
+func readProfile(policy *ole.IDispatch, profileType int32) (profile, error) {
+	p := profile{profileType: profileType}
+
+	var err error
+	if p.enabled, err = oleconv.ToBoolErr(oleutil.GetProperty(policy, "FirewallEnabled", profileType)); err != nil {
+		return p, fmt.Errorf("FirewallEnabled: %w", err)
+	}
+	if p.defaultInboundAction, err = oleconv.ToInt32Err(oleutil.GetProperty(policy, "DefaultInboundAction", profileType)); err != nil {
+		return p, fmt.Errorf("DefaultInboundAction: %w", err)
+	}
+	if p.defaultOutboundAction, err = oleconv.ToInt32Err(oleutil.GetProperty(policy, "DefaultOutboundAction", profileType)); err != nil {
+		return p, fmt.Errorf("DefaultOutboundAction: %w", err)
+	}
+	if p.blockAllInbound, err = oleconv.ToBoolErr(oleutil.GetProperty(policy, "BlockAllInboundTraffic", profileType)); err != nil {
+		return p, fmt.Errorf("BlockAllInboundTraffic: %w", err)
+	}
+	if p.notificationsDisabled, err = oleconv.ToBoolErr(oleutil.GetProperty(policy, "NotificationsDisabled", profileType)); err != nil {
+		return p, fmt.Errorf("NotificationsDisabled: %w", err)
+	}
+	if p.unicastResponsesDisabled, err = oleconv.ToBoolErr(oleutil.GetProperty(policy, "UnicastResponsesToMulticastBroadcastDisabled", profileType)); err != nil {
+		return p, fmt.Errorf("UnicastResponsesToMulticastBroadcastDisabled: %w", err)
+	}
+
+	excluded, err := oleutil.GetProperty(policy, "ExcludedInterfaces", profileType)
+	if err != nil {
+		return p, fmt.Errorf("ExcludedInterfaces: %w", err)
+	}
+	defer excluded.Clear()
+	// Unset when no interfaces are excluded
+	if array := excluded.ToArray(); array != nil {
+		for _, v := range array.ToValueArray() {
+			if name, ok := v.(string); ok {
+				p.excludedInterfaces = append(p.excludedInterfaces, name)
+			}
+		}
+	}
+
+	return p, nil
+}
@@ -0,0 +1,388 @@
+// Package windowspersistence provides the kolide_windows_persistence table, which brings the
+// common ways programs start themselves at logon into one normalized view: Run and RunOnce
+// registry keys, for the machine and for every user, including users who aren't logged in;
+// startup folders; and scheduled tasks with logon triggers. Each entry's target binary is
+// annotated with its signer.
+package windowspersistence
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Sources of persistence
+const (
+	sourceRunKey        = "run_key"
+	sourceRunOnceKey    = "run_once_key"
+	sourceStartupFolder = "startup_folder"
+	sourceScheduledTask = "scheduled_task"
+)
+
+// Signature statuses
+const (
+	signatureValid       = "valid"
+	signatureInvalid     = "invalid"
+	signatureUnsigned    = "unsigned"
+	signatureUnknown     = "unknown"
+	signatureUnsupported = "unsupported"
+)
+
+type entry struct {
+	username         string
+	sid              string
+	source           string
+	location         string // the registry key, startup folder, or task
+	name             string // the registry value, file, or task name
+	command          string // the command as configured
+	path             string // the target binary
+	arguments        string
+	enabled          string // "1", "0", or empty if unknown
+	fromUnloadedHive bool   // the user wasn't logged in, so launcher loaded their hive to read it
+}
+
+type signature struct {
+	status string
+	signer string
+}
+
+func (e entry) row(sig signature) map[string]string {
+	fromUnloadedHive := "0"
+	if e.fromUnloadedHive {
+		fromUnloadedHive = "1"
+	}
+
+	return map[string]string{
+		"username":           e.username,
+		"sid":                e.sid,
+		"source":             e.source,
+		"location":           e.location,
+		"name":               e.name,
+		"command":            e.command,
+		"path":               e.path,
+		"arguments":          e.arguments,
+		"enabled":            e.enabled,
+		"from_unloaded_hive": fromUnloadedHive,
+		"signature_status":   sig.status,
+		"signer":             sig.signer,
+	}
+}
+
+// executableExtensions are the extensions splitCommand looks for to find the end of an unquoted
+// path that contains spaces.
+var executableExtensions = []string{".exe", ".com", ".bat", ".cmd", ".dll", ".ps1", ".vbs", ".js", ".scr"}
+
+// splitCommand splits a command line into the binary's path and its arguments. Windows allows
+// unquoted paths with spaces, e.g. `C:\Program Files\Example\example.exe --background`, which
+// Windows resolves by trying each space in turn; we look for the end of the executable's name.
+func splitCommand(command string) (string, string) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return "", ""
+	}
+
+	if strings.HasPrefix(command, `"`) {
+		path, args, _ := strings.Cut(command[1:], `"`)
+		return path, strings.TrimSpace(args)
+	}
+
+	lower := strings.ToLower(command)
+	end := -1
+	for _, ext := range executableExtensions {
+		for offset := 0; ; {
+			idx := strings.Index(lower[offset:], ext)
+			if idx < 0 {
+				break
+			}
+			idx += offset + len(ext)
+			if idx == len(lower) || lower[idx] == ' ' {
+				if end < 0 || idx < end {
+					end = idx
+				}
+				break
+			}
+			offset = idx
+		}
+	}
+
+	if end < 0 {
+		path, args, _ := strings.Cut(command, " ")
+		return path, strings.TrimSpace(args)
+	}
+
+	return command[:end], strings.TrimSpace(command[end:])
+}
+
+// startupApprovedEnabled interprets a StartupApproved value, which Task Manager and Settings
+// write when a user turns a Run key entry or startup folder item off or back on. The low bit
+// of the first byte is set when it's disabled. Without a value, the item is enabled.
+func startupApprovedEnabled(value []byte) string {
+	if len(value) == 0 {
+		return "1"
+	}
+	if value[0]&1 == 1 {
+		return "0"
+	}
+	return "1"
+}
+
+// Shell link flags, see https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-shllink/ae350202-3ba9-4790-9e9e-98935f4ee5af
+const (
+	linkHasTargetIdList = 1 << 0
+	linkHasLinkInfo     = 1 << 1
+	linkHasName         = 1 << 2
+	linkHasRelativePath = 1 << 3
+	linkHasWorkingDir   = 1 << 4
+	linkHasArguments    = 1 << 5
+	linkHasIconLocation = 1 << 6
+	linkIsUnicode       = 1 << 7
+
+	linkHeaderSize           = 0x4c
+	linkInfoHasLocalBasePath = 1 << 0
+)
+
+// parseShortcut returns the target and arguments of a shell link (.lnk) file.
+func parseShortcut(data []byte) (string, string, error) {
+	if len(data) < linkHeaderSize || binary.LittleEndian.Uint32(data) != linkHeaderSize {
+		return "", "", errors.New("not a shell link")
+	}
+	flags := binary.LittleEndian.Uint32(data[20:])
+	offset := linkHeaderSize
+
+	if flags&linkHasTargetIdList != 0 {
+		if len(data) < offset+2 {
+			return "", "", errors.New("truncated target id list")
+		}
+		offset += 2 + int(binary.LittleEndian.Uint16(data[offset:]))
+	}
+
+	var target string
+	if flags&linkHasLinkInfo != 0 {
+		if len(data) < offset+28 {
+			return "", "", errors.New("truncated link info")
+		}
+		info := data[offset:]
+		infoSize := int(binary.LittleEndian.Uint32(info))
+		if infoSize < 28 || len(info) < infoSize {
+			return "", "", errors.New("invalid link info size")
+		}
+		info = info[:infoSize]
+
+		headerSize := binary.LittleEndian.Uint32(info[4:])
+		infoFlags := binary.LittleEndian.Uint32(info[8:])
+		if infoFlags&linkInfoHasLocalBasePath != 0 {
+			if headerSize >= 0x24 && len(info) >= 0x24 {
+				target = utf16String(info, int(binary.LittleEndian.Uint32(info[28:]))) +
+					utf16String(info, int(binary.LittleEndian.Uint32(info[32:])))
+			}
+			if target == "" {
+				target = ansiString(info, int(binary.LittleEndian.Uint32(info[16:]))) +
+					ansiString(info, int(binary.LittleEndian.Uint32(info[24:])))
+			}
+		}
+		offset += infoSize
+	}
+
+	var relativePath, arguments string
+	for _, s := range []struct {
+		flag uint32
+		dest *string
+	}{
+		{linkHasName, nil},
+		{linkHasRelativePath, &relativePath},
+		{linkHasWorkingDir, nil},
+		{linkHasArguments, &arguments},
+		{linkHasIconLocation, nil},
+	} {
+		if flags&s.flag == 0 {
+			continue
+		}
+		value, next, err := stringData(data, offset, flags&linkIsUnicode != 0)
+		if err != nil {
+			return "", "", err
+		}
+		offset = next
+		if s.dest != nil {
+			*s.dest = value
+		}
+	}
+
+	// Links to things that aren't files, like control panel items, have neither
+	if target == "" {
+		target = relativePath
+	}
+
+	return target, arguments, nil
+}
+
+// stringData reads one of a shell link's counted strings at offset, returning the string and
+// the offset after it.
+func stringData(data []byte, offset int, isUnicode bool) (string, int, error) {
+	if len(data) < offset+2 {
+		return "", 0, errors.New("truncated string data")
+	}
+	count := int(binary.LittleEndian.Uint16(data[offset:]))
+	offset += 2
+
+	if !isUnicode {
+		if len(data) < offset+count {
+			return "", 0, errors.New("truncated string data")
+		}
+		return string(data[offset : offset+count]), offset + count, nil
+	}
+
+	if len(data) < offset+2*count {
+		return "", 0, errors.New("truncated string data")
+	}
+	chars := make([]uint16, count)
+	for i := range chars {
+		chars[i] = binary.LittleEndian.Uint16(data[offset+2*i:])
+	}
+	return string(utf16.Decode(chars)), offset + 2*count, nil
+}
+
+// ansiString reads a NUL-terminated string at offset. An offset of zero means there's none.
+func ansiString(data []byte, offset int) string {
+	if offset <= 0 || offset >= len(data) {
+		return ""
+	}
+	s, _, _ := bytes.Cut(data[offset:], []byte{0})
+	return string(s)
+}
+
+// utf16String reads a NUL-terminated UTF-16 string at offset. An offset of zero means there's none.
+func utf16String(data []byte, offset int) string {
+	if offset <= 0 || offset >= len(data) {
+		return ""
+	}
+	var chars []uint16
+	for i := offset; i+1 < len(data); i += 2 {
+		c := binary.LittleEndian.Uint16(data[i:])
+		if c == 0 {
+			break
+		}
+		chars = append(chars, c)
+	}
+	return string(utf16.Decode(chars))
+}
+
+// taskXml is the part of a scheduled task definition we need, see
+// https://learn.microsoft.com/en-us/windows/win32/taskschd/task-scheduler-schema
+type taskXml struct {
+	Triggers struct {
+		LogonTriggers []struct {
+			Enabled string `xml:"Enabled"`
+			UserId  string `xml:"UserId"`
+		} `xml:"LogonTrigger"`
+	} `xml:"Triggers"`
+	Settings struct {
+		Enabled string `xml:"Enabled"`
+	} `xml:"Settings"`
+	Actions struct {
+		Exec []struct {
+			Command   string `xml:"Command"`
+			Arguments string `xml:"Arguments"`
+		} `xml:"Exec"`
+	} `xml:"Actions"`
+}
+
+// taskAction is a command a scheduled task runs at logon.
+type taskAction struct {
+	user      string // the user whose logon triggers the task; empty for any user
+	command   string
+	arguments string
+	enabled   bool
+}
+
+// parseTask returns the commands the task runs at logon, if it has an enabled logon trigger.
+// Task definitions on disk are usually UTF-16.
+func parseTask(data []byte) ([]taskAction, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte{0xff, 0xfe}) || bytes.HasPrefix(data, []byte{0xfe, 0xff}) {
+		r = transform.NewReader(r, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder())
+	}
+
+	dec := xml.NewDecoder(r)
+	// The declaration still says UTF-16, but it's been decoded by now
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var task taskXml
+	if err := dec.Decode(&task); err != nil {
+		return nil, fmt.Errorf("decoding task: %w", err)
+	}
+
+	var actions []taskAction
+	for _, trigger := range task.Triggers.LogonTriggers {
+		if strings.EqualFold(strings.TrimSpace(trigger.Enabled), "false") {
+			continue
+		}
+		for _, exec := range task.Actions.Exec {
+			actions = append(actions, taskAction{
+				user:      strings.TrimSpace(trigger.UserId),
+				command:   strings.TrimSpace(exec.Command),
+				arguments: strings.TrimSpace(exec.Arguments),
+				enabled:   !strings.EqualFold(strings.TrimSpace(task.Settings.Enabled), "false"),
+			})
+		}
+	}
+
+	return actions, nil
+}
+
+// commonName extracts the CN from a certificate subject, e.g. `CN=Microsoft Corporation, O=...`.
+// Values containing commas are quoted, e.g. `CN="Example, Inc.", O=...`.
+func commonName(subject string) string {
+	for rest := subject; rest != ""; {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		if key == "CN" {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
+
+// authenticodeSignature is the output of Get-AuthenticodeSignature for one file.
+type authenticodeSignature struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	Subject string `json:"subject"`
+}
+
+func parseAuthenticodeSignature(authenticode authenticodeSignature) signature {
+	sig := signature{signer: commonName(authenticode.Subject)}
+
+	switch authenticode.Status {
+	case "Valid":
+		sig.status = signatureValid
+	case "NotSigned":
+		sig.status = signatureUnsigned
+	case "NotSupportedFileFormat":
+		sig.status = signatureUnsupported
+	case "":
+		sig.status = signatureUnknown
+	default:
+		// HashMismatch, NotTrusted, UnknownError, and so on
+		sig.status = signatureInvalid
+	}
+
+	return sig
+}
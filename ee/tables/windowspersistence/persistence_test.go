@@ -0,0 +1,223 @@
+package windowspersistence
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommand(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		command      string
+		expectedPath string
+		expectedArgs string
+	}{
+		{command: `"C:\Program Files\Example\example.exe" --background`, expectedPath: `C:\Program Files\Example\example.exe`, expectedArgs: "--background"},
+		{command: `"C:\Program Files\Example\example.exe"`, expectedPath: `C:\Program Files\Example\example.exe`},
+		{command: `C:\Program Files\Example\example.exe --background`, expectedPath: `C:\Program Files\Example\example.exe`, expectedArgs: "--background"},
+		{command: `C:\Program Files\Example.exec\run.EXE /s`, expectedPath: `C:\Program Files\Example.exec\run.EXE`, expectedArgs: "/s"},
+		{command: `C:\Windows\system32\rundll32.exe C:\Tools\helper.dll,Start`, expectedPath: `C:\Windows\system32\rundll32.exe`, expectedArgs: `C:\Tools\helper.dll,Start`},
+		{command: `ctfmon /n`, expectedPath: "ctfmon", expectedArgs: "/n"},
+		{command: "  ", expectedPath: "", expectedArgs: ""},
+	} {
+		tt := tt
+		t.Run(tt.command, func(t *testing.T) {
+			t.Parallel()
+
+			path, args := splitCommand(tt.command)
+			require.Equal(t, tt.expectedPath, path)
+			require.Equal(t, tt.expectedArgs, args)
+		})
+	}
+}
+
+func TestStartupApprovedEnabled(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "1", startupApprovedEnabled(nil))
+	require.Equal(t, "1", startupApprovedEnabled([]byte{0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}))
+	require.Equal(t, "1", startupApprovedEnabled([]byte{0x06, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}))
+	require.Equal(t, "0", startupApprovedEnabled([]byte{0x03, 0, 0, 0, 0x12, 0x34, 0, 0, 0, 0, 0, 0}))
+}
+
+// buildShortcut returns a minimal shell link with a local base path and unicode arguments
+func buildShortcut(t *testing.T, basePath, suffix, arguments string) []byte {
+	var buf bytes.Buffer
+
+	header := make([]byte, linkHeaderSize)
+	binary.LittleEndian.PutUint32(header, linkHeaderSize)
+	binary.LittleEndian.PutUint32(header[20:], linkHasTargetIdList|linkHasLinkInfo|linkHasArguments|linkIsUnicode)
+	buf.Write(header)
+
+	// An empty target id list
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint16(2)))
+	buf.Write([]byte{0, 0})
+
+	const infoHeaderSize = 0x1c
+	strs := append([]byte(basePath), 0)
+	suffixOffset := infoHeaderSize + len(strs)
+	strs = append(strs, append([]byte(suffix), 0)...)
+
+	info := make([]byte, infoHeaderSize)
+	binary.LittleEndian.PutUint32(info, uint32(infoHeaderSize+len(strs)))
+	binary.LittleEndian.PutUint32(info[4:], infoHeaderSize)
+	binary.LittleEndian.PutUint32(info[8:], linkInfoHasLocalBasePath)
+	binary.LittleEndian.PutUint32(info[16:], infoHeaderSize)
+	binary.LittleEndian.PutUint32(info[24:], uint32(suffixOffset))
+	buf.Write(info)
+	buf.Write(strs)
+
+	chars := utf16.Encode([]rune(arguments))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint16(len(chars))))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, chars))
+
+	return buf.Bytes()
+}
+
+func TestParseShortcut(t *testing.T) {
+	t.Parallel()
+
+	target, args, err := parseShortcut(buildShortcut(t, `C:\Program Files\Example\`, "example.exe", "--minimized"))
+	require.NoError(t, err)
+	require.Equal(t, `C:\Program Files\Example\example.exe`, target)
+	require.Equal(t, "--minimized", args)
+
+	_, _, err = parseShortcut([]byte("[InternetShortcut]\r\nURL=https://example.com\r\n"))
+	require.Error(t, err)
+
+	truncated := buildShortcut(t, `C:\`, "example.exe", "--minimized")
+	_, _, err = parseShortcut(truncated[:len(truncated)-4])
+	require.Error(t, err)
+}
+
+const taskDefinition = `<?xml version="1.0" encoding="UTF-16"?>
+<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <Triggers>
+    <LogonTrigger>
+      <Enabled>true</Enabled>
+      <UserId>EXAMPLE\alice</UserId>
+    </LogonTrigger>
+    <LogonTrigger>
+      <Enabled>false</Enabled>
+    </LogonTrigger>
+    <TimeTrigger>
+      <StartBoundary>2024-01-01T00:00:00</StartBoundary>
+    </TimeTrigger>
+  </Triggers>
+  <Settings>
+    <Enabled>false</Enabled>
+  </Settings>
+  <Actions Context="Author">
+    <Exec>
+      <Command>"%LOCALAPPDATA%\Example\updater.exe"</Command>
+      <Arguments>/silent</Arguments>
+    </Exec>
+  </Actions>
+</Task>`
+
+func TestParseTask(t *testing.T) {
+	t.Parallel()
+
+	// Task definitions are written as UTF-16 with a byte order mark
+	var utf16Data bytes.Buffer
+	utf16Data.Write([]byte{0xff, 0xfe})
+	require.NoError(t, binary.Write(&utf16Data, binary.LittleEndian, utf16.Encode([]rune(taskDefinition))))
+
+	actions, err := parseTask(utf16Data.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, []taskAction{
+		{user: `EXAMPLE\alice`, command: `"%LOCALAPPDATA%\Example\updater.exe"`, arguments: "/silent", enabled: false},
+	}, actions)
+
+	// Tasks without logon triggers don't start at logon
+	actions, err = parseTask([]byte(`<Task><Triggers><BootTrigger/></Triggers><Actions><Exec><Command>example.exe</Command></Exec></Actions></Task>`))
+	require.NoError(t, err)
+	require.Empty(t, actions)
+
+	_, err = parseTask([]byte("not a task"))
+	require.Error(t, err)
+}
+
+func TestParseAuthenticodeSignature(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		in       authenticodeSignature
+		expected signature
+	}{
+		{
+			name:     "valid",
+			in:       authenticodeSignature{Status: "Valid", Subject: "CN=Microsoft Corporation, O=Microsoft Corporation, L=Redmond, S=Washington, C=US"},
+			expected: signature{status: signatureValid, signer: "Microsoft Corporation"},
+		},
+		{
+			name:     "quoted signer",
+			in:       authenticodeSignature{Status: "Valid", Subject: `O="Example, Inc.", CN="Example, Inc.", C=US`},
+			expected: signature{status: signatureValid, signer: "Example, Inc."},
+		},
+		{
+			name:     "unsigned",
+			in:       authenticodeSignature{Status: "NotSigned"},
+			expected: signature{status: signatureUnsigned},
+		},
+		{
+			name:     "hash mismatch",
+			in:       authenticodeSignature{Status: "HashMismatch", Subject: "CN=Example"},
+			expected: signature{status: signatureInvalid, signer: "Example"},
+		},
+		{
+			name:     "script",
+			in:       authenticodeSignature{Status: "NotSupportedFileFormat"},
+			expected: signature{status: signatureUnsupported},
+		},
+		{
+			name:     "missing",
+			expected: signature{status: signatureUnknown},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, parseAuthenticodeSignature(tt.in))
+		})
+	}
+}
+
+func TestEntryRow(t *testing.T) {
+	t.Parallel()
+
+	e := entry{
+		username:         "alice",
+		sid:              "S-1-5-21-1-2-3-1001",
+		source:           sourceRunKey,
+		location:         `HKEY_USERS\S-1-5-21-1-2-3-1001\Software\Microsoft\Windows\CurrentVersion\Run`,
+		name:             "Example",
+		command:          `"C:\Example\example.exe" --tray`,
+		path:             `C:\Example\example.exe`,
+		arguments:        "--tray",
+		enabled:          "1",
+		fromUnloadedHive: true,
+	}
+
+	require.Equal(t, map[string]string{
+		"username":           "alice",
+		"sid":                "S-1-5-21-1-2-3-1001",
+		"source":             "run_key",
+		"location":           `HKEY_USERS\S-1-5-21-1-2-3-1001\Software\Microsoft\Windows\CurrentVersion\Run`,
+		"name":               "Example",
+		"command":            `"C:\Example\example.exe" --tray`,
+		"path":               `C:\Example\example.exe`,
+		"arguments":          "--tray",
+		"enabled":            "1",
+		"from_unloaded_hive": "1",
+		"signature_status":   "valid",
+		"signer":             "Example",
+	}, e.row(signature{status: signatureValid, signer: "Example"}))
+}
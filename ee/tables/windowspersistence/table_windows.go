@@ -0,0 +1,659 @@
+//go:build windows
+// +build windows
+
+package windowspersistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	tableName = "kolide_windows_persistence"
+
+	profileListKey     = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList`
+	startupApprovedKey = `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved`
+
+	// hiveMountPrefix is where we load the hives of users who aren't logged in, under HKEY_USERS
+	hiveMountPrefix = "kolide_persistence_"
+)
+
+// runKeys are the Run keys we check, in both HKEY_LOCAL_MACHINE and each user's hive. approved
+// is the StartupApproved subkey recording whether the user turned an entry off, if any.
+var runKeys = []struct {
+	path     string
+	source   string
+	approved string
+}{
+	{path: `Software\Microsoft\Windows\CurrentVersion\Run`, source: sourceRunKey, approved: "Run"},
+	{path: `Software\Microsoft\Windows\CurrentVersion\RunOnce`, source: sourceRunOnceKey},
+	{path: `Software\WOW6432Node\Microsoft\Windows\CurrentVersion\Run`, source: sourceRunKey, approved: "Run32"},
+	{path: `Software\WOW6432Node\Microsoft\Windows\CurrentVersion\RunOnce`, source: sourceRunOnceKey},
+	{path: `Software\Microsoft\Windows\CurrentVersion\Policies\Explorer\Run`, source: sourceRunKey},
+}
+
+var (
+	advapi32          = windows.NewLazySystemDLL("advapi32.dll")
+	procRegLoadKeyW   = advapi32.NewProc("RegLoadKeyW")
+	procRegUnLoadKeyW = advapi32.NewProc("RegUnLoadKeyW")
+)
+
+type Table struct {
+	slogger *slog.Logger
+}
+
+// user is a user with a profile on this device.
+type user struct {
+	name   string
+	sid    string
+	home   string
+	loaded bool // their hive is loaded, because they're logged in
+}
+
+func TablePlugin(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("sid"),
+		table.TextColumn("source"),
+		table.TextColumn("location"),
+		table.TextColumn("name"),
+		table.TextColumn("command"),
+		table.TextColumn("path"),
+		table.TextColumn("arguments"),
+		table.IntegerColumn("enabled"),
+		table.IntegerColumn("from_unloaded_hive"),
+		table.TextColumn("signature_status"),
+		table.TextColumn("signer"),
+	}
+
+	t := &Table{
+		slogger: slogger.With("table", tableName),
+	}
+
+	return table.NewPlugin(tableName, columns, t.generate)
+}
+
+func (t *Table) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	users, err := profileUsers()
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not list user profiles, only returning machine-wide persistence",
+			"err", err,
+		)
+	}
+
+	entries := t.machineRunKeys(ctx)
+	entries = append(entries, t.userRunKeys(ctx, users)...)
+	entries = append(entries, t.startupFolders(ctx, users)...)
+	entries = append(entries, t.scheduledTasks(ctx)...)
+
+	signatures := t.signatures(ctx, entries)
+
+	results := make([]map[string]string, 0, len(entries))
+	for _, e := range entries {
+		sig, ok := signatures[strings.ToLower(e.path)]
+		if !ok {
+			sig = signature{status: signatureUnknown}
+		}
+		results = append(results, e.row(sig))
+	}
+
+	return results, nil
+}
+
+func (t *Table) machineRunKeys(ctx context.Context) []entry {
+	var entries []entry
+	for _, rk := range runKeys {
+		entries = append(entries, t.readRunKey(ctx, registry.LOCAL_MACHINE, "", `HKEY_LOCAL_MACHINE\`, rk.path, rk.source, rk.approved, user{}, func(s string) string { return expandForUser(s, "") })...)
+	}
+	return entries
+}
+
+// userRunKeys reads each user's Run keys. The hives of users who aren't logged in are loaded
+// for just as long as it takes to read them; a user logging in meanwhile would otherwise get
+// a temporary profile.
+func (t *Table) userRunKeys(ctx context.Context, users []user) []entry {
+	var entries []entry
+
+	var unloaded []user
+	for _, u := range users {
+		if !u.loaded {
+			unloaded = append(unloaded, u)
+			continue
+		}
+		entries = append(entries, t.readUserRunKeys(ctx, u.sid+`\`, u)...)
+	}
+
+	if len(unloaded) == 0 {
+		return entries
+	}
+
+	err := withHivePrivileges(func() {
+		for _, u := range unloaded {
+			mount := hiveMountPrefix + u.sid
+			if err := loadHive(mount, filepath.Join(u.home, "NTUSER.DAT")); err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not load user hive",
+					"sid", u.sid,
+					"err", err,
+				)
+				continue
+			}
+
+			entries = append(entries, t.readUserRunKeys(ctx, mount+`\`, u)...)
+
+			if err := unloadHive(mount); err != nil {
+				t.slogger.Log(ctx, slog.LevelWarn,
+					"could not unload user hive",
+					"sid", u.sid,
+					"err", err,
+				)
+			}
+		}
+	})
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not enable privileges to load user hives, skipping users who are not logged in",
+			"err", err,
+		)
+	}
+
+	return entries
+}
+
+func (t *Table) readUserRunKeys(ctx context.Context, prefix string, u user) []entry {
+	expand := func(s string) string {
+		return expandForUser(s, u.home)
+	}
+
+	var entries []entry
+	for _, rk := range runKeys {
+		entries = append(entries, t.readRunKey(ctx, registry.USERS, prefix, `HKEY_USERS\`+u.sid+`\`, rk.path, rk.source, rk.approved, u, expand)...)
+	}
+	for i := range entries {
+		entries[i].fromUnloadedHive = !u.loaded
+	}
+	return entries
+}
+
+// readRunKey reads the entries in a Run key. prefix is the key's path under root, and
+// displayPrefix how it's shown.
+func (t *Table) readRunKey(ctx context.Context, root registry.Key, prefix, displayPrefix, path, source, approved string, u user, expand func(string) string) []entry {
+	key, err := registry.OpenKey(root, prefix+path, registry.QUERY_VALUE)
+	if err != nil {
+		if !errors.Is(err, registry.ErrNotExist) {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not open run key",
+				"key", displayPrefix+path,
+				"err", err,
+			)
+		}
+		return nil
+	}
+	defer key.Close()
+
+	names, err := key.ReadValueNames(0)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not read run key values",
+			"key", displayPrefix+path,
+			"err", err,
+		)
+		return nil
+	}
+	sort.Strings(names)
+
+	var approvedValues registry.Key
+	if approved != "" {
+		if k, err := registry.OpenKey(root, prefix+startupApprovedKey+`\`+approved, registry.QUERY_VALUE); err == nil {
+			approvedValues = k
+			defer approvedValues.Close()
+		}
+	}
+
+	var entries []entry
+	for _, name := range names {
+		command, _, err := key.GetStringValue(name)
+		if err != nil || command == "" {
+			continue
+		}
+
+		e := entry{
+			username: u.name,
+			sid:      u.sid,
+			source:   source,
+			location: displayPrefix + path,
+			name:     name,
+			command:  command,
+			enabled:  "1",
+		}
+		e.path, e.arguments = splitCommand(expand(command))
+		if approvedValues != 0 {
+			value, _, _ := approvedValues.GetBinaryValue(name)
+			e.enabled = startupApprovedEnabled(value)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+// startupFolders lists the items in the all-users startup folder, and each user's.
+func (t *Table) startupFolders(ctx context.Context, users []user) []entry {
+	var entries []entry
+
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	machineApproved, err := registry.OpenKey(registry.LOCAL_MACHINE, startupApprovedKey+`\StartupFolder`, registry.QUERY_VALUE)
+	if err != nil {
+		machineApproved = 0
+	}
+	entries = append(entries, t.readStartupFolder(ctx, filepath.Join(programData, `Microsoft\Windows\Start Menu\Programs\Startup`), user{}, machineApproved)...)
+	if machineApproved != 0 {
+		machineApproved.Close()
+	}
+
+	for _, u := range users {
+		// The StartupApproved values are only readable for users who are logged in
+		var approved registry.Key
+		if u.loaded {
+			if k, err := registry.OpenKey(registry.USERS, u.sid+`\`+startupApprovedKey+`\StartupFolder`, registry.QUERY_VALUE); err == nil {
+				approved = k
+			}
+		}
+		entries = append(entries, t.readStartupFolder(ctx, filepath.Join(u.home, `AppData\Roaming\Microsoft\Windows\Start Menu\Programs\Startup`), u, approved)...)
+		if approved != 0 {
+			approved.Close()
+		}
+	}
+
+	return entries
+}
+
+// readStartupFolder lists the items in a startup folder. approved is the StartupApproved key for
+// the folder, if it could be opened.
+func (t *Table) readStartupFolder(ctx context.Context, dir string, u user, approved registry.Key) []entry {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read startup folder",
+				"dir", dir,
+				"err", err,
+			)
+		}
+		return nil
+	}
+
+	var entries []entry
+	for _, item := range items {
+		if item.IsDir() || strings.EqualFold(item.Name(), "desktop.ini") {
+			continue
+		}
+
+		itemPath := filepath.Join(dir, item.Name())
+		e := entry{
+			username: u.name,
+			sid:      u.sid,
+			source:   sourceStartupFolder,
+			location: dir,
+			name:     item.Name(),
+			command:  itemPath,
+			path:     itemPath,
+			enabled:  "1",
+		}
+
+		if strings.EqualFold(filepath.Ext(item.Name()), ".lnk") {
+			data, err := os.ReadFile(itemPath)
+			if err == nil {
+				var target string
+				target, e.arguments, err = parseShortcut(data)
+				if err == nil && target != "" {
+					e.path = expandForUser(target, u.home)
+					e.command = strings.TrimSpace(target + " " + e.arguments)
+				}
+			}
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not read startup folder shortcut",
+					"path", itemPath,
+					"err", err,
+				)
+			}
+		}
+
+		if approved != 0 {
+			value, _, _ := approved.GetBinaryValue(item.Name())
+			e.enabled = startupApprovedEnabled(value)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+// scheduledTasks returns the actions of scheduled tasks with logon triggers, from the task
+// definitions on disk.
+func (t *Table) scheduledTasks(ctx context.Context) []entry {
+	tasksDir := filepath.Join(os.Getenv("SystemRoot"), "System32", "Tasks")
+	if os.Getenv("SystemRoot") == "" {
+		tasksDir = `C:\Windows\System32\Tasks`
+	}
+
+	var entries []entry
+	_ = filepath.WalkDir(tasksDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		actions, err := parseTask(data)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelDebug,
+				"could not parse scheduled task",
+				"path", path,
+				"err", err,
+			)
+			return nil
+		}
+
+		taskName := `\` + strings.TrimPrefix(strings.TrimPrefix(path, tasksDir), `\`)
+		for _, a := range actions {
+			e := entry{
+				source:   sourceScheduledTask,
+				location: taskName,
+				name:     filepath.Base(path),
+				command:  strings.TrimSpace(a.command + " " + a.arguments),
+				enabled:  "0",
+			}
+			if a.enabled {
+				e.enabled = "1"
+			}
+			if strings.HasPrefix(a.user, "S-1-") {
+				e.sid = a.user
+				e.username, _ = accountName(a.user)
+			} else {
+				e.username = a.user
+			}
+
+			// The command is only the program; its arguments are separate
+			e.path = strings.Trim(expandForUser(a.command, ""), `"`)
+			e.arguments = a.arguments
+
+			entries = append(entries, e)
+		}
+		return nil
+	})
+
+	return entries
+}
+
+// signatures checks the Authenticode signatures of the entries' target binaries, in one
+// Get-AuthenticodeSignature call. The results are keyed by the lowercased path.
+func (t *Table) signatures(ctx context.Context, entries []entry) map[string]signature {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, e := range entries {
+		if e.path == "" || seen[strings.ToLower(e.path)] {
+			continue
+		}
+		if _, err := os.Stat(e.path); err != nil {
+			continue
+		}
+		seen[strings.ToLower(e.path)] = true
+		paths = append(paths, e.path)
+	}
+
+	signatures := make(map[string]signature)
+	if len(paths) == 0 {
+		return signatures
+	}
+
+	// Single quotes are escaped by doubling them in a single-quoted powershell string
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = "'" + strings.ReplaceAll(p, "'", "''") + "'"
+	}
+	script := fmt.Sprintf(
+		`@(Get-AuthenticodeSignature -LiteralPath %s | ForEach-Object { [pscustomobject]@{path=[string]$_.Path; status=[string]$_.Status; subject=[string]$_.SignerCertificate.Subject} }) | ConvertTo-Json -Compress`,
+		strings.Join(quoted, ","),
+	)
+
+	out, err := tablehelpers.RunSimple(ctx, t.slogger, 60, allowedcmd.Powershell, []string{"-NoProfile", "-NonInteractive", "-Command", script})
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"could not get authenticode signatures",
+			"err", err,
+		)
+		return signatures
+	}
+
+	// ConvertTo-Json unwraps single-element arrays
+	var results []authenticodeSignature
+	if err := json.Unmarshal(out, &results); err != nil {
+		var single authenticodeSignature
+		if err := json.Unmarshal(out, &single); err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not unmarshal authenticode signatures",
+				"err", err,
+			)
+			return signatures
+		}
+		results = []authenticodeSignature{single}
+	}
+
+	for _, r := range results {
+		signatures[strings.ToLower(r.Path)] = parseAuthenticodeSignature(r)
+	}
+
+	return signatures
+}
+
+// profileUsers returns the local and domain users with profiles on this device.
+func profileUsers() ([]user, error) {
+	profiles, err := registry.OpenKey(registry.LOCAL_MACHINE, profileListKey, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil, fmt.Errorf("opening profile list: %w", err)
+	}
+	defer profiles.Close()
+
+	sids, err := profiles.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("listing profiles: %w", err)
+	}
+	sort.Strings(sids)
+
+	var users []user
+	for _, sid := range sids {
+		// Only local and domain user accounts
+		if !strings.HasPrefix(sid, "S-1-5-21-") {
+			continue
+		}
+
+		home, err := profilePath(sid)
+		if err != nil {
+			continue
+		}
+
+		name, _ := accountName(sid)
+
+		loaded := false
+		if k, err := registry.OpenKey(registry.USERS, sid, registry.QUERY_VALUE); err == nil {
+			k.Close()
+			loaded = true
+		}
+
+		users = append(users, user{name: name, sid: sid, home: home, loaded: loaded})
+	}
+
+	return users, nil
+}
+
+// profilePath returns the user's profile directory.
+func profilePath(sid string) (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, profileListKey+`\`+sid, registry.QUERY_VALUE)
+	if err != nil {
+		return "", fmt.Errorf("opening profile list key: %w", err)
+	}
+	defer key.Close()
+
+	path, _, err := key.GetStringValue("ProfileImagePath")
+	if err != nil {
+		return "", fmt.Errorf("reading profile image path: %w", err)
+	}
+
+	expanded, err := registry.ExpandString(path)
+	if err != nil {
+		return path, nil
+	}
+	return expanded, nil
+}
+
+func accountName(sid string) (string, error) {
+	s, err := windows.StringToSid(sid)
+	if err != nil {
+		return "", fmt.Errorf("parsing sid: %w", err)
+	}
+
+	account, _, _, err := s.LookupAccount("")
+	if err != nil {
+		return "", fmt.Errorf("looking up account: %w", err)
+	}
+
+	return account, nil
+}
+
+// expandForUser expands environment variables in s, with the user's profile directories in
+// place of launcher's own. Without a home directory, launcher's environment is used.
+func expandForUser(s string, home string) string {
+	if home != "" {
+		for _, v := range []struct {
+			name string
+			path string
+		}{
+			{"%USERPROFILE%", home},
+			{"%APPDATA%", filepath.Join(home, "AppData", "Roaming")},
+			{"%LOCALAPPDATA%", filepath.Join(home, "AppData", "Local")},
+		} {
+			s = replaceFold(s, v.name, v.path)
+		}
+	}
+
+	expanded, err := registry.ExpandString(s)
+	if err != nil {
+		return s
+	}
+	return expanded
+}
+
+func replaceFold(s, old, replacement string) string {
+	idx := strings.Index(strings.ToLower(s), strings.ToLower(old))
+	if idx < 0 {
+		return s
+	}
+	return s[:idx] + replacement + s[idx+len(old):]
+}
+
+// withHivePrivileges runs f with the backup and restore privileges RegLoadKey requires enabled.
+// They're enabled on an impersonation token for the current thread only, rather than for all
+// of launcher.
+func withHivePrivileges(f func()) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := windows.ImpersonateSelf(windows.SecurityImpersonation); err != nil {
+		return fmt.Errorf("impersonating self: %w", err)
+	}
+	defer windows.RevertToSelf()
+
+	thread, err := windows.GetCurrentThread()
+	if err != nil {
+		return fmt.Errorf("getting current thread: %w", err)
+	}
+
+	var token windows.Token
+	if err := windows.OpenThreadToken(thread, windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, false, &token); err != nil {
+		return fmt.Errorf("opening thread token: %w", err)
+	}
+	defer token.Close()
+
+	for _, name := range []string{"SeBackupPrivilege", "SeRestorePrivilege"} {
+		if err := enablePrivilege(token, name); err != nil {
+			return err
+		}
+	}
+
+	f()
+	return nil
+}
+
+func enablePrivilege(token windows.Token, name string) error {
+	var luid windows.LUID
+	if err := windows.LookupPrivilegeValue(nil, windows.StringToUTF16Ptr(name), &luid); err != nil {
+		return fmt.Errorf("looking up %s: %w", name, err)
+	}
+
+	privileges := windows.Tokenprivileges{
+		PrivilegeCount: 1,
+		Privileges: [1]windows.LUIDAndAttributes{
+			{Luid: luid, Attributes: windows.SE_PRIVILEGE_ENABLED},
+		},
+	}
+	if err := windows.AdjustTokenPrivileges(token, false, &privileges, 0, nil, nil); err != nil {
+		return fmt.Errorf("enabling %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func loadHive(mount, path string) error {
+	mountPtr, err := windows.UTF16PtrFromString(mount)
+	if err != nil {
+		return err
+	}
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	if ret, _, _ := procRegLoadKeyW.Call(uintptr(registry.USERS), uintptr(unsafe.Pointer(mountPtr)), uintptr(unsafe.Pointer(pathPtr))); ret != 0 {
+		return fmt.Errorf("loading %s: %w", path, windows.Errno(ret))
+	}
+	return nil
+}
+
+func unloadHive(mount string) error {
+	mountPtr, err := windows.UTF16PtrFromString(mount)
+	if err != nil {
+		return err
+	}
+
+	if ret, _, _ := procRegUnLoadKeyW.Call(uintptr(registry.USERS), uintptr(unsafe.Pointer(mountPtr))); ret != 0 {
+		return fmt.Errorf("unloading %s: %w", mount, windows.Errno(ret))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Package tableschema publishes the schema of the tables launcher registers with osquery to the
+// control server, so that the server's query builder works from the tables launcher actually
+// has rather than a hand-maintained list. The schema is published when it differs from the
+// last one published -- generally, after launcher updates, or when the Kolide ATC tables change.
+package tableschema
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolide/kit/version"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/osquery/table"
+	osquery "github.com/osquery/osquery-go"
+)
+
+const (
+	// SchemaMethod is the control server message method the schema is sent with
+	SchemaMethod = "table_schema"
+
+	// publishedHashKey is the key in the control store for the hash of the last published schema
+	publishedHashKey = "table_schema_hash"
+
+	// initialDelay gives the control service time to authenticate before we first publish
+	initialDelay = 1 * time.Minute
+
+	// retryInterval is how long to wait before trying again when publishing fails
+	retryInterval = 15 * time.Minute
+)
+
+type messenger interface {
+	SendMessage(method string, params interface{}) error
+}
+
+// schemaMessage is the message sent to the control server.
+type schemaMessage struct {
+	Hash            string `json:"hash"`
+	LauncherVersion string `json:"launcher_version"`
+	table.Schema
+}
+
+type Publisher struct {
+	slogger       *slog.Logger
+	store         types.GetterSetter
+	messenger     messenger
+	tables        func() []osquery.OsqueryPlugin
+	initialDelay  time.Duration
+	retryInterval time.Duration
+	checkRequests chan struct{}
+	interrupt     chan struct{}
+	interrupted   atomic.Bool
+}
+
+func New(k types.Knapsack, messenger messenger) *Publisher {
+	slogger := k.Slogger().With("component", "table_schema_publisher")
+
+	return &Publisher{
+		slogger:   slogger,
+		store:     k.ControlStore(),
+		messenger: messenger,
+		tables: func() []osquery.OsqueryPlugin {
+			return table.RegisteredTables(k, types.DefaultRegistrationID, slogger, k.OsquerydPath())
+		},
+		initialDelay:  initialDelay,
+		retryInterval: retryInterval,
+		checkRequests: make(chan struct{}, 1),
+		interrupt:     make(chan struct{}, 1),
+	}
+}
+
+func (p *Publisher) Execute() error {
+	// Nothing to retry until we've tried once
+	var retry <-chan time.Time
+
+	initial := time.After(p.initialDelay)
+	for {
+		select {
+		case <-initial:
+		case <-p.checkRequests:
+		case <-retry:
+		case <-p.interrupt:
+			p.slogger.Log(context.TODO(), slog.LevelDebug,
+				"received external interrupt, stopping",
+			)
+			return nil
+		}
+
+		retry = nil
+		if err := p.publishIfChanged(); err != nil {
+			p.slogger.Log(context.TODO(), slog.LevelWarn,
+				"could not publish table schema, will retry",
+				"err", err,
+			)
+			retry = time.After(p.retryInterval)
+		}
+	}
+}
+
+func (p *Publisher) Interrupt(_ error) {
+	// Only perform shutdown tasks on first call to interrupt -- no need to repeat on potential extra calls.
+	if p.interrupted.Load() {
+		return
+	}
+	p.interrupted.Store(true)
+
+	p.interrupt <- struct{}{}
+}
+
+// Ping satisfies the control.subscriber interface -- the publisher subscribes to changes to
+// the katc_config subsystem, since they change the Kolide ATC tables.
+func (p *Publisher) Ping() {
+	select {
+	case p.checkRequests <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+}
+
+// publishIfChanged sends the current schema to the control server, unless it's the schema that
+// was last sent.
+func (p *Publisher) publishIfChanged() error {
+	schema := table.BuildSchema(p.tables())
+	hash, err := schema.Hash()
+	if err != nil {
+		return err
+	}
+
+	publishedHash, err := p.store.Get([]byte(publishedHashKey))
+	if err != nil {
+		return fmt.Errorf("reading last published schema hash: %w", err)
+	}
+	if string(publishedHash) == hash {
+		return nil
+	}
+
+	if err := p.messenger.SendMessage(SchemaMethod, schemaMessage{
+		Hash:            hash,
+		LauncherVersion: version.Version().Version,
+		Schema:          schema,
+	}); err != nil {
+		return fmt.Errorf("sending schema: %w", err)
+	}
+
+	if err := p.store.Set([]byte(publishedHashKey), []byte(hash)); err != nil {
+		return fmt.Errorf("storing published schema hash: %w", err)
+	}
+
+	p.slogger.Log(context.TODO(), slog.LevelInfo,
+		"published table schema",
+		"hash", hash,
+		"tables", len(schema.Tables),
+	)
+
+	return nil
+}
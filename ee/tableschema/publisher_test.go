@@ -0,0 +1,139 @@
+package tableschema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	osquery "github.com/osquery/osquery-go"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+type testMessenger struct {
+	lock     sync.Mutex
+	err      error
+	messages []json.RawMessage
+}
+
+func (m *testMessenger) SendMessage(method string, params interface{}) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if method != SchemaMethod {
+		return errors.New("unexpected method " + method)
+	}
+	if m.err != nil {
+		return m.err
+	}
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	m.messages = append(m.messages, raw)
+	return nil
+}
+
+func (m *testMessenger) sent() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.messages)
+}
+
+func testTable(name string, columns ...string) osquery.OsqueryPlugin {
+	definitions := make([]table.ColumnDefinition, 0, len(columns))
+	for _, c := range columns {
+		definitions = append(definitions, table.TextColumn(c))
+	}
+	return table.NewPlugin(name, definitions, func(context.Context, table.QueryContext) ([]map[string]string, error) {
+		return nil, nil
+	})
+}
+
+func testPublisher(messenger messenger, tables *[]osquery.OsqueryPlugin) *Publisher {
+	return &Publisher{
+		slogger:       multislogger.NewNopLogger(),
+		store:         inmemory.NewStore(),
+		messenger:     messenger,
+		tables:        func() []osquery.OsqueryPlugin { return *tables },
+		initialDelay:  0,
+		retryInterval: 10 * time.Millisecond,
+		checkRequests: make(chan struct{}, 1),
+		interrupt:     make(chan struct{}, 1),
+	}
+}
+
+func TestPublishIfChanged(t *testing.T) {
+	t.Parallel()
+
+	tables := []osquery.OsqueryPlugin{testTable("kolide_b", "x"), testTable("kolide_a", "y", "z")}
+	messenger := &testMessenger{}
+	p := testPublisher(messenger, &tables)
+
+	require.NoError(t, p.publishIfChanged())
+	require.Equal(t, 1, messenger.sent())
+
+	var message struct {
+		Hash   string `json:"hash"`
+		Tables []struct {
+			Name    string `json:"name"`
+			Columns []struct {
+				Name string `json:"name"`
+				Type string `json:"type"`
+			} `json:"columns"`
+		} `json:"tables"`
+	}
+	require.NoError(t, json.Unmarshal(messenger.messages[0], &message))
+	require.NotEmpty(t, message.Hash)
+	require.Len(t, message.Tables, 2)
+	require.Equal(t, "kolide_a", message.Tables[0].Name)
+	require.Equal(t, "z", message.Tables[0].Columns[1].Name)
+	require.Equal(t, "TEXT", message.Tables[0].Columns[1].Type)
+
+	// Unchanged, so not published again
+	require.NoError(t, p.publishIfChanged())
+	require.Equal(t, 1, messenger.sent())
+
+	// Failures aren't recorded as published
+	tables = append(tables, testTable("kolide_c", "w"))
+	messenger.err = errors.New("control server unavailable")
+	require.Error(t, p.publishIfChanged())
+	messenger.err = nil
+	require.NoError(t, p.publishIfChanged())
+	require.Equal(t, 2, messenger.sent())
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	tables := []osquery.OsqueryPlugin{testTable("kolide_a", "x")}
+	messenger := &testMessenger{err: errors.New("not authenticated yet")}
+	p := testPublisher(messenger, &tables)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Execute()
+	}()
+
+	// The initial attempt fails, and is retried
+	time.Sleep(50 * time.Millisecond)
+	messenger.lock.Lock()
+	messenger.err = nil
+	messenger.lock.Unlock()
+	require.Eventually(t, func() bool { return messenger.sent() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	p.Interrupt(nil)
+	p.Interrupt(nil)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Error("publisher did not exit after interrupt")
+	}
+}
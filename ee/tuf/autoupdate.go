@@ -18,6 +18,7 @@ import (
 	"runtime"
 	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,6 +26,8 @@ import (
 	"github.com/kolide/kit/version"
 	"github.com/kolide/launcher/ee/agent/flags/keys"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/gowrapper"
+	"github.com/kolide/launcher/ee/maintenancewindow"
 	"github.com/kolide/launcher/pkg/traces"
 	client "github.com/theupdateframework/go-tuf/client"
 	filejsonstore "github.com/theupdateframework/go-tuf/client/filejsonstore"
@@ -76,30 +79,49 @@ type (
 type librarian interface {
 	Available(binary autoupdatableBinary, targetFilename string) bool
 	AddToLibrary(binary autoupdatableBinary, currentVersion string, targetFilename string, targetMetadata data.TargetFileMeta) error
-	TidyLibrary(binary autoupdatableBinary, currentVersion string)
+	AddCustomBuild(binary autoupdatableBinary, downloadUrl string, sha256 string) error
+	TidyLibrary(binary autoupdatableBinary, currentVersion string, pinnedVersions []string)
+	TidyCustomBuilds(binary autoupdatableBinary, selectedSha256s []string)
 }
 
 type querier interface {
 	Query(query string) ([]map[string]string, error)
 }
 
+// osqueryInstances runs an osquery instance for each registration. The osquery runner fulfills this interface.
+type osqueryInstances interface {
+	OsquerydPath(registrationId string) string
+	RestartInstance(ctx context.Context, registrationId string) error
+}
+
 type TufAutoupdater struct {
-	metadataClient         *client.Client
-	libraryManager         librarian
-	osquerier              querier // used to query for current running osquery version
-	osquerierRetryInterval time.Duration
-	knapsack               types.Knapsack
-	store                  types.KVStore // stores autoupdater errors for kolide_tuf_autoupdater_errors table
-	updateChannel          string
-	pinnedVersions         map[autoupdatableBinary]string        // maps the binaries to their pinned versions
-	pinnedVersionGetters   map[autoupdatableBinary]func() string // maps the binaries to the knapsack function to retrieve updated pinned versions
-	initialDelayEnd        time.Time
-	updateLock             *sync.Mutex
-	interrupt              chan struct{}
-	interrupted            atomic.Bool
-	signalRestart          chan error
-	slogger                *slog.Logger
-	restartFuncs           map[autoupdatableBinary]func(context.Context) error
+	metadataClient          *client.Client
+	libraryManager          librarian
+	osquerier               querier // used to query for current running osquery version
+	osquerierRetryInterval  time.Duration
+	knapsack                types.Knapsack
+	store                   types.KVStore // stores autoupdater errors for kolide_tuf_autoupdater_errors table
+	updateChannel           string
+	pinnedVersions          map[autoupdatableBinary]string        // maps the binaries to their pinned versions
+	pinnedVersionGetters    map[autoupdatableBinary]func() string // maps the binaries to the knapsack function to retrieve updated pinned versions
+	initialDelayEnd         time.Time
+	updateLock              *sync.Mutex
+	interrupt               chan struct{}
+	interrupted             atomic.Bool
+	signalRestart           chan error
+	slogger                 *slog.Logger
+	restartFuncs            map[autoupdatableBinary]func(context.Context) error
+	pendingRestarts         map[autoupdatableBinary]pendingRestart // updated binaries awaiting the maintenance window to restart; protected by updateLock
+	osqueryInstances        osqueryInstances                       // restarts individual instances to run the osqueryd selected for their registration
+	registrationSelections  map[string]OsquerydSelection           // the osqueryd selected for each registration, as of the last check; protected by updateLock
+	pendingInstanceRestarts map[string]pendingRestart              // registrations whose instance awaits the maintenance window to restart; protected by updateLock
+}
+
+// pendingRestart is a restart, to run a newly-downloaded version of a binary, that has not
+// happened yet because we're outside the maintenance window.
+type pendingRestart struct {
+	version string
+	since   time.Time
 }
 
 type TufAutoupdaterOption func(*TufAutoupdater)
@@ -113,6 +135,14 @@ func WithOsqueryRestart(restart func(context.Context) error) TufAutoupdaterOptio
 	}
 }
 
+// WithOsqueryInstances lets the autoupdater manage the osqueryd selected for individual registrations:
+// it downloads each selection, and restarts only the affected registration's instance to run it.
+func WithOsqueryInstances(instances osqueryInstances) TufAutoupdaterOption {
+	return func(ta *TufAutoupdater) {
+		ta.osqueryInstances = instances
+	}
+}
+
 func NewTufAutoupdater(ctx context.Context, k types.Knapsack, metadataHttpClient *http.Client, mirrorHttpClient *http.Client,
 	osquerier querier, opts ...TufAutoupdaterOption) (*TufAutoupdater, error) {
 	ctx, span := traces.StartSpan(ctx)
@@ -132,12 +162,15 @@ func NewTufAutoupdater(ctx context.Context, k types.Knapsack, metadataHttpClient
 			binaryLauncher: func() string { return k.PinnedLauncherVersion() },
 			binaryOsqueryd: func() string { return k.PinnedOsquerydVersion() },
 		},
-		initialDelayEnd:        time.Now().Add(k.AutoupdateInitialDelay()),
-		updateLock:             &sync.Mutex{},
-		osquerier:              osquerier,
-		osquerierRetryInterval: 30 * time.Second,
-		slogger:                k.Slogger().With("component", "tuf_autoupdater"),
-		restartFuncs:           make(map[autoupdatableBinary]func(context.Context) error),
+		initialDelayEnd:         time.Now().Add(k.AutoupdateInitialDelay()),
+		updateLock:              &sync.Mutex{},
+		osquerier:               osquerier,
+		osquerierRetryInterval:  30 * time.Second,
+		slogger:                 k.Slogger().With("component", "tuf_autoupdater"),
+		restartFuncs:            make(map[autoupdatableBinary]func(context.Context) error),
+		pendingRestarts:         make(map[autoupdatableBinary]pendingRestart),
+		registrationSelections:  make(map[string]OsquerydSelection),
+		pendingInstanceRestarts: make(map[string]pendingRestart),
 	}
 
 	for _, opt := range opts {
@@ -217,7 +250,8 @@ func DefaultLibraryDirectory(rootDirectory string) string {
 
 // Execute is the TufAutoupdater run loop. It periodically checks to see if a new release
 // has been published; less frequently, it removes old/outdated TUF errors from the bucket
-// we store them in.
+// we store them in. In between, it performs any restarts after updates that were waiting
+// for the maintenance window.
 func (ta *TufAutoupdater) Execute() (err error) {
 	// Delay startup, if initial delay is set
 	select {
@@ -243,6 +277,8 @@ func (ta *TufAutoupdater) Execute() (err error) {
 	defer checkTicker.Stop()
 	cleanupTicker := time.NewTicker(12 * time.Hour)
 	defer cleanupTicker.Stop()
+	pendingRestartTicker := time.NewTicker(1 * time.Minute)
+	defer pendingRestartTicker.Stop()
 
 	for {
 		if err := ta.checkForUpdate(context.TODO(), binaries); err != nil {
@@ -253,21 +289,29 @@ func (ta *TufAutoupdater) Execute() (err error) {
 			)
 		}
 
-		select {
-		case <-checkTicker.C:
-			continue
-		case <-cleanupTicker.C:
-			ta.cleanUpOldErrors()
-		case <-ta.interrupt:
-			ta.slogger.Log(context.TODO(), slog.LevelDebug,
-				"received external interrupt, stopping",
-			)
-			return nil
-		case signalRestartErr := <-ta.signalRestart:
-			ta.slogger.Log(context.TODO(), slog.LevelDebug,
-				"received interrupt to restart launcher after update, stopping",
-			)
-			return signalRestartErr
+	waitForNextCheck:
+		for {
+			select {
+			case <-checkTicker.C:
+				break waitForNextCheck
+			case <-cleanupTicker.C:
+				ta.cleanUpOldErrors()
+				break waitForNextCheck
+			case <-pendingRestartTicker.C:
+				ta.updateLock.Lock()
+				ta.performPendingRestarts(context.TODO())
+				ta.updateLock.Unlock()
+			case <-ta.interrupt:
+				ta.slogger.Log(context.TODO(), slog.LevelDebug,
+					"received external interrupt, stopping",
+				)
+				return nil
+			case signalRestartErr := <-ta.signalRestart:
+				ta.slogger.Log(context.TODO(), slog.LevelDebug,
+					"received interrupt to restart launcher after update, stopping",
+				)
+				return signalRestartErr
+			}
 		}
 	}
 }
@@ -405,9 +449,46 @@ func (ta *TufAutoupdater) FlagsChanged(ctx context.Context, flagKeys ...keys.Fla
 	}
 }
 
+// Ping satisfies the control.subscriber interface -- the autoupdater subscribes to changes to the
+// osqueryd selected for individual registrations, so that it can download newly-selected builds
+// and restart the affected instances.
+func (ta *TufAutoupdater) Ping() {
+	ctx, span := traces.StartSpan(context.TODO())
+	defer span.End()
+
+	// We'll check for the selections as soon as the initial delay is over
+	if ta.osqueryInstances == nil || time.Now().Before(ta.initialDelayEnd) {
+		return
+	}
+
+	// Downloads can take a while, so don't hold up the control service
+	gowrapper.Go(ctx, ta.slogger, func() {
+		if err := ta.checkForUpdate(ctx, []autoupdatableBinary{binaryOsqueryd}); err != nil {
+			ta.storeError(err)
+			ta.slogger.Log(ctx, slog.LevelError,
+				"error checking for update after osqueryd selection changed",
+				"err", err,
+			)
+		}
+	})
+}
+
 // tidyLibrary gets the current running version for each binary (so that the current version is not removed)
 // and then asks the update library manager to tidy the update library.
 func (ta *TufAutoupdater) tidyLibrary() {
+	// Keep the builds selected for registrations, too
+	var pinnedOsquerydVersions, customOsquerydBuilds []string
+	if ta.osqueryInstances != nil {
+		for _, selection := range ta.osquerydSelections(context.TODO()) {
+			if selection.custom() {
+				customOsquerydBuilds = append(customOsquerydBuilds, selection.Sha256)
+			} else {
+				pinnedOsquerydVersions = append(pinnedOsquerydVersions, selection.PinnedVersion)
+			}
+		}
+		ta.libraryManager.TidyCustomBuilds(binaryOsqueryd, customOsquerydBuilds)
+	}
+
 	for _, binary := range binaries {
 		// Get the current running version to preserve it when tidying the available updates
 		currentVersion, err := ta.currentRunningVersion(binary)
@@ -420,7 +501,11 @@ func (ta *TufAutoupdater) tidyLibrary() {
 			continue
 		}
 
-		ta.libraryManager.TidyLibrary(binary, currentVersion)
+		var pinnedVersions []string
+		if binary == binaryOsqueryd {
+			pinnedVersions = pinnedOsquerydVersions
+		}
+		ta.libraryManager.TidyLibrary(binary, currentVersion, pinnedVersions)
 	}
 }
 
@@ -435,6 +520,12 @@ func (ta *TufAutoupdater) currentRunningVersion(binary autoupdatableBinary) (str
 		}
 		return launcherVersion, nil
 	case binaryOsqueryd:
+		// When the default registration runs an osqueryd selected for it, querying it says nothing
+		// about the osqueryd the installation autoupdates to -- go by the other instances instead
+		if version, ok := ta.unselectedOsquerydVersion(); ok {
+			return version, nil
+		}
+
 		// first verify that the osqueryd binary exists. if it doesn't, there's no reason to wait
 		// for initialization below
 		if ta.knapsack != nil {
@@ -528,52 +619,257 @@ func (ta *TufAutoupdater) checkForUpdate(ctx context.Context, binariesToCheck []
 
 	}
 
+	// Download the osqueryd selected for individual registrations, and queue restarts for
+	// the instances that should run a different one
+	if slices.Contains(binariesToCheck, binaryOsqueryd) && ta.osqueryInstances != nil {
+		if err := ta.updateRegistrationOsqueryd(ctx, targets); err != nil {
+			updateErrors = append(updateErrors, err)
+		}
+	}
+
 	// If an update failed, save the error
 	if len(updateErrors) > 0 {
 		return fmt.Errorf("could not download updates: %+v", updateErrors)
 	}
 
-	// If launcher was updated, we want to exit and reload
-	if updatedVersion, ok := updatesDownloaded[binaryLauncher]; ok {
-		// Only reload if we're not using a localdev path
-		if ta.knapsack.LocalDevelopmentPath() == "" {
+	// Queue up restarts to run the new versions: launcher exits and reloads, and for non-launcher
+	// binaries (i.e. osqueryd), we call any reload functions we have saved.
+	now := time.Now()
+	for binary, newBinaryVersion := range updatesDownloaded {
+		// Only reload launcher if we're not using a localdev path
+		if binary == binaryLauncher && ta.knapsack.LocalDevelopmentPath() != "" {
+			continue
+		}
+		if _, ok := ta.restartFuncs[binary]; binary != binaryLauncher && !ok {
+			continue
+		}
+
+		// If we were already waiting to restart for an earlier update, keep waiting from then,
+		// so that frequent releases can't push the restart past the maintenance window deadline
+		pending, alreadyPending := ta.pendingRestarts[binary]
+		if !alreadyPending {
+			pending.since = now
+		}
+		pending.version = newBinaryVersion
+		ta.pendingRestarts[binary] = pending
+
+		if restartAt := maintenancewindow.NextOpportunity(ta.knapsack, pending.since, now); restartAt.After(now) {
 			ta.slogger.Log(ctx, slog.LevelInfo,
-				"launcher updated -- exiting to load new version",
-				"new_binary_version", updatedVersion,
+				"deferring restart after update until maintenance window",
+				"binary", binary,
+				"new_binary_version", newBinaryVersion,
+				"maintenance_window", ta.knapsack.MaintenanceWindow(),
+				"restart_at", restartAt.Format(time.RFC3339),
 			)
-			ta.signalRestart <- NewLauncherReloadNeededErr(updatedVersion)
-			return nil
 		}
 	}
 
-	// For non-launcher binaries (i.e. osqueryd), call any reload functions we have saved
-	for binary, newBinaryVersion := range updatesDownloaded {
-		if binary == binaryLauncher {
+	ta.performPendingRestarts(ctx)
+
+	return nil
+}
+
+// performPendingRestarts runs the restarts after updates that the maintenance window now permits.
+// The caller must hold updateLock.
+func (ta *TufAutoupdater) performPendingRestarts(ctx context.Context) {
+	now := time.Now()
+
+	// If launcher was updated, we want to exit and reload -- which restarts osqueryd too
+	if pending, ok := ta.pendingRestarts[binaryLauncher]; ok && maintenancewindow.Permits(ctx, ta.knapsack, pending.since, now) {
+		ta.slogger.Log(ctx, slog.LevelInfo,
+			"launcher updated -- exiting to load new version",
+			"new_binary_version", pending.version,
+		)
+		clear(ta.pendingRestarts)
+		ta.signalRestart <- NewLauncherReloadNeededErr(pending.version)
+		return
+	}
+
+	for binary, pending := range ta.pendingRestarts {
+		if binary == binaryLauncher || !maintenancewindow.Permits(ctx, ta.knapsack, pending.since, now) {
 			continue
 		}
+		delete(ta.pendingRestarts, binary)
 
-		if restart, ok := ta.restartFuncs[binary]; ok {
-			if err := restart(ctx); err != nil {
-				ta.slogger.Log(ctx, slog.LevelWarn,
-					"failed to restart binary after update",
-					"binary", binary,
-					"new_binary_version", newBinaryVersion,
-					"err", err,
-				)
-				continue
+		if err := ta.restartFuncs[binary](ctx); err != nil {
+			ta.slogger.Log(ctx, slog.LevelWarn,
+				"failed to restart binary after update",
+				"binary", binary,
+				"new_binary_version", pending.version,
+				"err", err,
+			)
+			continue
+		}
+
+		ta.slogger.Log(ctx, slog.LevelInfo,
+			"restarted binary after update",
+			"binary", binary,
+			"new_binary_version", pending.version,
+		)
+
+		// Restarting osqueryd restarted every instance, each running the osqueryd selected for it
+		if binary == binaryOsqueryd {
+			clear(ta.pendingInstanceRestarts)
+		}
+	}
+
+	for registrationId, pending := range ta.pendingInstanceRestarts {
+		if !maintenancewindow.Permits(ctx, ta.knapsack, pending.since, now) {
+			continue
+		}
+		delete(ta.pendingInstanceRestarts, registrationId)
+
+		if err := ta.osqueryInstances.RestartInstance(ctx, registrationId); err != nil {
+			ta.slogger.Log(ctx, slog.LevelWarn,
+				"failed to restart osquery instance to run the osqueryd selected for its registration",
+				"registration_id", registrationId,
+				"osqueryd_selection", pending.version,
+				"err", err,
+			)
+			continue
+		}
+
+		ta.slogger.Log(ctx, slog.LevelInfo,
+			"restarted osquery instance to run the osqueryd selected for its registration",
+			"registration_id", registrationId,
+			"osqueryd_selection", pending.version,
+		)
+	}
+}
+
+// osquerydSelections returns the osqueryd selected for each registration that has a selection.
+func (ta *TufAutoupdater) osquerydSelections(ctx context.Context) map[string]OsquerydSelection {
+	selections := make(map[string]OsquerydSelection)
+	for _, registrationId := range ta.knapsack.RegistrationIDs() {
+		selection, err := osquerydSelection(ta.knapsack.OsquerydSelectionStore(), registrationId)
+		if err != nil {
+			ta.slogger.Log(ctx, slog.LevelWarn,
+				"could not get osqueryd selection for registration, ignoring it",
+				"registration_id", registrationId,
+				"err", err,
+			)
+			continue
+		}
+		if selection != nil {
+			selections[registrationId] = *selection
+		}
+	}
+	return selections
+}
+
+// updateRegistrationOsqueryd downloads the osqueryd selected for each registration that has a
+// selection. It then queues a restart for each instance that isn't running the osqueryd its
+// registration should, including those whose selection was removed. The caller must hold updateLock.
+func (ta *TufAutoupdater) updateRegistrationOsqueryd(ctx context.Context, targets data.TargetFiles) error {
+	ctx, span := traces.StartSpan(ctx)
+	defer span.End()
+
+	selections := ta.osquerydSelections(ctx)
+
+	downloadErrors := make([]error, 0)
+	for registrationId, selection := range selections {
+		var err error
+		if selection.custom() {
+			err = ta.libraryManager.AddCustomBuild(binaryOsqueryd, selection.DownloadUrl, selection.Sha256)
+		} else {
+			var target string
+			var targetMetadata data.TargetFileMeta
+			target, targetMetadata, err = findTargetByVersion(ctx, binaryOsqueryd, targets, selection.PinnedVersion)
+			if err == nil {
+				err = ta.libraryManager.AddToLibrary(binaryOsqueryd, "", target, targetMetadata)
 			}
+		}
+		if err != nil {
+			downloadErrors = append(downloadErrors, fmt.Errorf("could not download %s selected for registration %s: %w", selection, registrationId, err))
+		}
+	}
+
+	now := time.Now()
+	for _, registrationId := range ta.knapsack.RegistrationIDs() {
+		selection, selected := selections[registrationId]
+		_, wasSelected := ta.registrationSelections[registrationId]
+		if !selected && !wasSelected {
+			// Restarts to run the latest osqueryd are handled with the installation's updates
+			continue
+		}
+
+		runningPath := ta.osqueryInstances.OsquerydPath(registrationId)
+		selectedPath := ta.knapsack.RegistrationOsquerydPath(ctx, registrationId)
+		if runningPath == "" || runningPath == selectedPath {
+			delete(ta.pendingInstanceRestarts, registrationId)
+			continue
+		}
 
+		description := "latest version"
+		if selected {
+			description = selection.String()
+		}
+
+		pending, alreadyPending := ta.pendingInstanceRestarts[registrationId]
+		if !alreadyPending {
+			pending.since = now
+		}
+		pending.version = description
+		ta.pendingInstanceRestarts[registrationId] = pending
+
+		if restartAt := maintenancewindow.NextOpportunity(ta.knapsack, pending.since, now); restartAt.After(now) {
 			ta.slogger.Log(ctx, slog.LevelInfo,
-				"restarted binary after update",
-				"binary", binary,
-				"new_binary_version", newBinaryVersion,
+				"deferring osquery instance restart until maintenance window",
+				"registration_id", registrationId,
+				"osqueryd_selection", description,
+				"maintenance_window", ta.knapsack.MaintenanceWindow(),
+				"restart_at", restartAt.Format(time.RFC3339),
 			)
 		}
 	}
+	ta.registrationSelections = selections
 
+	if len(downloadErrors) > 0 {
+		return fmt.Errorf("could not download osqueryd selected for registrations: %+v", downloadErrors)
+	}
 	return nil
 }
 
+// unselectedOsquerydVersion returns the version of osqueryd that the instances without a
+// selection of their own are running, when the default registration has a selection. If no
+// instance runs a known version from the library, the version is empty.
+func (ta *TufAutoupdater) unselectedOsquerydVersion() (string, bool) {
+	if ta.osqueryInstances == nil || ta.knapsack == nil {
+		return "", false
+	}
+
+	selections := ta.osquerydSelections(context.TODO())
+	if _, selected := selections[types.DefaultRegistrationID]; !selected {
+		return "", false
+	}
+
+	updateDirectory := ta.knapsack.UpdateDirectory()
+	if updateDirectory == "" {
+		updateDirectory = DefaultLibraryDirectory(ta.knapsack.RootDirectory())
+	}
+
+	for _, registrationId := range ta.knapsack.RegistrationIDs() {
+		if _, selected := selections[registrationId]; selected {
+			continue
+		}
+		if version := libraryVersion(binaryOsqueryd, updateDirectory, ta.osqueryInstances.OsquerydPath(registrationId)); version != "" {
+			return version, true
+		}
+	}
+
+	return "", true
+}
+
+// libraryVersion returns the version of the binary at executablePath, if it's in the update library.
+func libraryVersion(binary autoupdatableBinary, baseUpdateDirectory string, executablePath string) string {
+	relativePath, err := filepath.Rel(updatesDirectory(binary, baseUpdateDirectory), executablePath)
+	if err != nil || relativePath == "." || strings.HasPrefix(relativePath, "..") {
+		return ""
+	}
+	version, _, _ := strings.Cut(filepath.ToSlash(relativePath), "/")
+	return version
+}
+
 // downloadUpdate will download a new release for the given binary, if available from TUF
 // and not already downloaded.
 func (ta *TufAutoupdater) downloadUpdate(binary autoupdatableBinary, targets data.TargetFiles) (string, error) {
@@ -644,10 +940,10 @@ func findTargetByVersion(ctx context.Context, binary autoupdatableBinary, target
 	_, span := traces.StartSpan(ctx)
 	defer span.End()
 
-	targetNameForVersion := path.Join(string(binary), runtime.GOOS, PlatformArch(), fmt.Sprintf("%s-%s.tar.gz", binary, binaryVersion))
+	wantedTargetName := targetNameForVersion(binary, binaryVersion)
 
 	for targetName, target := range targets {
-		if targetName != targetNameForVersion {
+		if targetName != wantedTargetName {
 			continue
 		}
 
@@ -93,6 +93,7 @@ func TestExecute_launcherUpdate(t *testing.T) {
 	mockKnapsack.On("MirrorServerURL").Return("https://example.com")
 	mockKnapsack.On("LocalDevelopmentPath").Return("")
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath)
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 	mockQuerier := newMockQuerier(t)
@@ -123,7 +124,7 @@ func TestExecute_launcherUpdate(t *testing.T) {
 	currentLauncherVersion := "" // cannot determine using version package in test
 	currentOsqueryVersion := "1.1.1"
 	mockQuerier.On("Query", mock.Anything).Return([]map[string]string{{"version": currentOsqueryVersion}}, nil)
-	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything).Return().Once()
+	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything, mock.Anything).Return().Once()
 
 	// Expect that we attempt to update the library
 	mockLibraryManager.On("Available", binaryOsqueryd, fmt.Sprintf("osqueryd-%s.tar.gz", testReleaseVersion)).Return(false).Once()
@@ -193,6 +194,7 @@ func TestExecute_osquerydUpdate(t *testing.T) {
 	mockKnapsack.On("UpdateDirectory").Return("")
 	mockKnapsack.On("MirrorServerURL").Return("https://example.com")
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 	mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath)
 	mockQuerier := newMockQuerier(t)
@@ -220,7 +222,7 @@ func TestExecute_osquerydUpdate(t *testing.T) {
 	autoupdater.libraryManager = mockLibraryManager
 	currentOsqueryVersion := "1.1.1"
 	mockQuerier.On("Query", mock.Anything).Return([]map[string]string{{"version": currentOsqueryVersion}}, nil)
-	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything).Return().Once()
+	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything, mock.Anything).Return().Once()
 
 	// Expect that we attempt to update the library
 	mockLibraryManager.On("Available", binaryOsqueryd, fmt.Sprintf("osqueryd-%s.tar.gz", testReleaseVersion)).Return(false)
@@ -276,6 +278,7 @@ func TestExecute_downgrade(t *testing.T) {
 	mockKnapsack.On("UpdateDirectory").Return("")
 	mockKnapsack.On("MirrorServerURL").Return("https://example.com")
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 	mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath)
 	mockQuerier := newMockQuerier(t)
@@ -301,7 +304,7 @@ func TestExecute_downgrade(t *testing.T) {
 	autoupdater.libraryManager = mockLibraryManager
 	currentOsqueryVersion := "4.0.0"
 	mockQuerier.On("Query", mock.Anything).Return([]map[string]string{{"version": currentOsqueryVersion}}, nil)
-	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything).Return().Once()
+	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything, mock.Anything).Return().Once()
 
 	// Expect that we do not attempt to update the library (i.e. the osquery update was previously downloaded)
 	mockLibraryManager.On("Available", binaryOsqueryd, fmt.Sprintf("osqueryd-%s.tar.gz", testReleaseVersion)).Return(true)
@@ -446,7 +449,7 @@ func TestExecute_inModernStandby(t *testing.T) {
 	mockLibraryManager := NewMocklibrarian(t)
 	autoupdater.libraryManager = mockLibraryManager
 	mockQuerier.On("Query", mock.Anything).Return([]map[string]string{{"version": "5.6.7"}}, nil)
-	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything).Return().Once()
+	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything, mock.Anything).Return().Once()
 
 	// Let the autoupdater run for less than the initial delay
 	go autoupdater.Execute()
@@ -494,6 +497,7 @@ func TestInterrupt_Multiple(t *testing.T) {
 	mockKnapsack.On("PinnedLauncherVersion").Return("")
 	mockKnapsack.On("PinnedOsquerydVersion").Return("")
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 	mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath)
 	mockQuerier := newMockQuerier(t)
@@ -506,7 +510,7 @@ func TestInterrupt_Multiple(t *testing.T) {
 	// Set up normal library and querier interactions
 	mockLibraryManager := NewMocklibrarian(t)
 	autoupdater.libraryManager = mockLibraryManager
-	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything).Return().Once()
+	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything, mock.Anything).Return().Once()
 	mockQuerier.On("Query", mock.Anything).Return([]map[string]string{{"version": "1.1.1"}}, nil)
 
 	// Let the autoupdater run for a bit
@@ -631,6 +635,7 @@ func TestDo(t *testing.T) {
 			mockQuerier := newMockQuerier(t)
 			mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
 			mockKnapsack.On("InModernStandby").Return(false)
+			mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 			mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 			mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath).Maybe()
 
@@ -704,6 +709,7 @@ func TestDo_HandlesSimultaneousUpdates(t *testing.T) {
 	mockKnapsack.On("MirrorServerURL").Return("https://example.com")
 	mockKnapsack.On("LocalDevelopmentPath").Return("")
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockQuerier := newMockQuerier(t)
 	mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
@@ -725,7 +731,7 @@ func TestDo_HandlesSimultaneousUpdates(t *testing.T) {
 	autoupdater.libraryManager = mockLibraryManager
 	currentOsqueryVersion := "1.1.1"
 	mockQuerier.On("Query", mock.Anything).Return([]map[string]string{{"version": currentOsqueryVersion}}, nil)
-	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything).Return().Once()
+	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything, mock.Anything).Return().Once()
 
 	// Expect that we attempt to update the library, only for the selected binary/binaries
 	autoupdater.libraryManager = mockLibraryManager
@@ -791,6 +797,7 @@ func TestDo_WillNotExecuteDuringInitialDelay(t *testing.T) {
 	mockQuerier := newMockQuerier(t)
 	mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 	mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath)
 
@@ -810,7 +817,7 @@ func TestDo_WillNotExecuteDuringInitialDelay(t *testing.T) {
 	autoupdater.libraryManager = mockLibraryManager
 	currentOsqueryVersion := "1.1.1"
 	mockQuerier.On("Query", mock.Anything).Return([]map[string]string{{"version": currentOsqueryVersion}}, nil)
-	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything).Return().Once()
+	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything, mock.Anything).Return().Once()
 	autoupdater.libraryManager = mockLibraryManager
 	for _, b := range binaries {
 		mockLibraryManager.On("Available", b, fmt.Sprintf("%s-%s.tar.gz", string(b), testReleaseVersion)).Return(true)
@@ -870,6 +877,7 @@ func TestFlagsChanged_UpdateChannelChanged(t *testing.T) {
 	mockKnapsack.On("PinnedLauncherVersion").Return("")
 	mockKnapsack.On("PinnedOsquerydVersion").Return("")
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 	mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath)
 
@@ -937,6 +945,7 @@ func TestFlagsChanged_PinnedVersionChanged(t *testing.T) {
 	mockKnapsack.On("UpdateChannel").Return("nightly")
 	mockKnapsack.On("PinnedLauncherVersion").Return("")
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 	mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath)
 
@@ -1131,6 +1140,7 @@ func Test_storeError(t *testing.T) {
 	mockKnapsack.On("PinnedLauncherVersion").Return("")
 	mockKnapsack.On("PinnedOsquerydVersion").Return("")
 	mockKnapsack.On("InModernStandby").Return(false)
+	mockKnapsack.On("MaintenanceWindow").Return("").Maybe()
 	mockKnapsack.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel, keys.PinnedLauncherVersion, keys.PinnedOsquerydVersion).Return()
 	mockKnapsack.On("LatestOsquerydPath", mock.Anything).Return(fakeOsqBinaryPath)
 	mockQuerier := newMockQuerier(t)
@@ -1144,7 +1154,7 @@ func Test_storeError(t *testing.T) {
 
 	// We only expect TidyLibrary to run for osqueryd, since we can't get the current running version
 	// for launcher in tests
-	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything).Return().Once()
+	mockLibraryManager.On("TidyLibrary", binaryOsqueryd, mock.Anything, mock.Anything).Return().Once()
 
 	// Start the autoupdater going
 	go autoupdater.Execute()
@@ -1217,6 +1227,59 @@ func Test_cleanUpOldErrors(t *testing.T) {
 	require.Equal(t, 1, keyCount, "cleanup routine did not clean up correct number of old errors")
 }
 
+func Test_performPendingRestarts_maintenanceWindow(t *testing.T) {
+	t.Parallel()
+
+	// A maintenance window that is hours away
+	windowStart := time.Now().Add(6 * time.Hour)
+	windowEnd := windowStart.Add(time.Minute)
+	maintenanceWindow := fmt.Sprintf("%02d:%02d-%02d:%02d", windowStart.Hour(), windowStart.Minute(), windowEnd.Hour(), windowEnd.Minute())
+
+	mockKnapsack := typesmocks.NewKnapsack(t)
+	mockKnapsack.On("MaintenanceWindow").Return(maintenanceWindow)
+	mockKnapsack.On("MaintenanceWindowDeadline").Return(72 * time.Hour)
+
+	osqueryRestarts := 0
+	autoupdater := &TufAutoupdater{
+		knapsack:      mockKnapsack,
+		slogger:       multislogger.NewNopLogger(),
+		signalRestart: make(chan error, 1),
+		restartFuncs: map[autoupdatableBinary]func(context.Context) error{
+			binaryOsqueryd: func(context.Context) error {
+				osqueryRestarts += 1
+				return nil
+			},
+		},
+		pendingRestarts: map[autoupdatableBinary]pendingRestart{
+			binaryOsqueryd: {version: "5.12.0", since: time.Now()},
+		},
+	}
+
+	// Outside the window, osqueryd should not be restarted yet
+	autoupdater.performPendingRestarts(context.TODO())
+	require.Equal(t, 0, osqueryRestarts)
+	require.Contains(t, autoupdater.pendingRestarts, binaryOsqueryd)
+
+	// Once the deadline has passed, osqueryd should be restarted anyway
+	autoupdater.pendingRestarts[binaryOsqueryd] = pendingRestart{version: "5.12.0", since: time.Now().Add(-73 * time.Hour)}
+	autoupdater.performPendingRestarts(context.TODO())
+	require.Equal(t, 1, osqueryRestarts)
+	require.Empty(t, autoupdater.pendingRestarts)
+
+	// A launcher restart should wait as well
+	autoupdater.pendingRestarts[binaryLauncher] = pendingRestart{version: "1.2.3", since: time.Now()}
+	autoupdater.performPendingRestarts(context.TODO())
+	require.Len(t, autoupdater.signalRestart, 0)
+
+	// Past the deadline, launcher restarts -- which takes care of any pending osqueryd restart too
+	autoupdater.pendingRestarts[binaryLauncher] = pendingRestart{version: "1.2.3", since: time.Now().Add(-73 * time.Hour)}
+	autoupdater.pendingRestarts[binaryOsqueryd] = pendingRestart{version: "5.12.1", since: time.Now()}
+	autoupdater.performPendingRestarts(context.TODO())
+	require.Len(t, autoupdater.signalRestart, 1)
+	require.Empty(t, autoupdater.pendingRestarts)
+	require.Equal(t, 1, osqueryRestarts)
+}
+
 func setupStorage(t *testing.T) types.KVStore {
 	s, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.AutoupdateErrorsStore.String())
 	require.NoError(t, err)
@@ -0,0 +1,159 @@
+package tuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/Masterminds/semver"
+	"github.com/kolide/launcher/pkg/traces"
+)
+
+// Verification statuses for the binaries in the update library
+const (
+	// VerificationStatusVerified means the executable runs, and its version is a target in our
+	// signed TUF metadata -- or, for a custom build, that it runs, since custom builds are
+	// only added to the library once their hash has been checked.
+	VerificationStatusVerified = "verified"
+	// VerificationStatusNotInMetadata means the executable runs, but our TUF metadata has no
+	// target for its version.
+	VerificationStatusNotInMetadata = "not_in_tuf_metadata"
+	// VerificationStatusInvalidVersion means the library directory isn't named for a version.
+	VerificationStatusInvalidVersion = "invalid_version"
+	// VerificationStatusInvalidExecutable means the executable is missing or doesn't run; the
+	// autoupdater removes these versions when it tidies the library.
+	VerificationStatusInvalidExecutable = "invalid_executable"
+)
+
+// LibraryEntry is a version of a binary in the update library, or a custom build.
+type LibraryEntry struct {
+	Binary             string
+	Version            string // the SHA-256 hash, for custom builds
+	CustomBuild        bool
+	Directory          string
+	ExecutablePath     string
+	VerificationStatus string
+	VerificationError  string
+	Size               int64 // the size of everything in Directory
+	ModTime            int64 // when the version was added to the library, as a Unix timestamp
+}
+
+// LibraryContents lists every binary version in the update library at baseUpdateDirectory,
+// including custom builds, checking each the way the autoupdater does. Versions are checked
+// against the TUF metadata in tufRepositoryLocation; if it can't be read, no version is
+// verified, and the error is returned alongside the entries.
+func LibraryContents(ctx context.Context, baseUpdateDirectory string, tufRepositoryLocation string) ([]LibraryEntry, error) {
+	ctx, span := traces.StartSpan(ctx)
+	defer span.End()
+
+	var errs []error
+
+	knownTargets := make(map[string]bool)
+	if metadataClient, err := readOnlyTufMetadataClient(tufRepositoryLocation); err != nil {
+		errs = append(errs, fmt.Errorf("initializing TUF client: %w", err))
+	} else if targets, err := metadataClient.Targets(); err != nil {
+		errs = append(errs, fmt.Errorf("getting targets: %w", err))
+	} else {
+		for targetName := range targets {
+			knownTargets[targetName] = true
+		}
+	}
+
+	var entries []LibraryEntry
+	for _, binary := range binaries {
+		versionDirs, err := filepath.Glob(filepath.Join(updatesDirectory(binary, baseUpdateDirectory), "*"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing %s versions: %w", binary, err))
+		}
+		for _, versionDir := range versionDirs {
+			entry := newLibraryEntry(ctx, binary, versionDir)
+			entry.Version = filepath.Base(versionDir)
+
+			switch {
+			case entry.VerificationStatus != "":
+				// Already invalid
+			case !isSemver(entry.Version):
+				entry.VerificationStatus = VerificationStatusInvalidVersion
+			case !knownTargets[targetNameForVersion(binary, entry.Version)]:
+				entry.VerificationStatus = VerificationStatusNotInMetadata
+			default:
+				entry.VerificationStatus = VerificationStatusVerified
+			}
+
+			entries = append(entries, entry)
+		}
+
+		customBuildDirs, err := filepath.Glob(filepath.Join(customBuildsDirectory(binary, baseUpdateDirectory), "*"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing %s custom builds: %w", binary, err))
+		}
+		for _, buildDir := range customBuildDirs {
+			entry := newLibraryEntry(ctx, binary, buildDir)
+			entry.Version = filepath.Base(buildDir)
+			entry.CustomBuild = true
+			if entry.VerificationStatus == "" {
+				entry.VerificationStatus = VerificationStatusVerified
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, errors.Join(errs...)
+}
+
+// newLibraryEntry describes the version directory, with the verification status set only if
+// its executable is invalid.
+func newLibraryEntry(ctx context.Context, binary autoupdatableBinary, dir string) LibraryEntry {
+	entry := LibraryEntry{
+		Binary:         string(binary),
+		Directory:      dir,
+		ExecutablePath: executableLocation(dir, binary),
+	}
+
+	if info, err := os.Stat(dir); err == nil {
+		entry.ModTime = info.ModTime().Unix()
+	}
+	entry.Size = directorySize(dir)
+
+	if err := CheckExecutable(ctx, entry.ExecutablePath, "--version"); err != nil {
+		entry.VerificationStatus = VerificationStatusInvalidExecutable
+		entry.VerificationError = err.Error()
+	}
+
+	return entry
+}
+
+// targetNameForVersion returns the name of the TUF target for the given version of the binary
+// on this platform.
+func targetNameForVersion(binary autoupdatableBinary, version string) string {
+	return path.Join(string(binary), runtime.GOOS, PlatformArch(), fmt.Sprintf("%s-%s.tar.gz", binary, version))
+}
+
+func isSemver(version string) bool {
+	_, err := semver.NewVersion(version)
+	return err == nil
+}
+
+// directorySize totals the sizes of the regular files under dir, ignoring anything it can't read.
+func directorySize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,64 @@
+package tuf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tufci "github.com/kolide/launcher/ee/tuf/ci"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibraryContents(t *testing.T) {
+	t.Parallel()
+
+	testRootDir := t.TempDir()
+	testReleaseVersion := "1.2.3"
+	tufci.SeedLocalTufRepo(t, testReleaseVersion, testRootDir)
+	baseDir := filepath.Join(testRootDir, "updates")
+
+	// A verified version, a version TUF doesn't know about, a corrupted version, and a
+	// directory that isn't a version
+	for _, version := range []string{testReleaseVersion, "0.9.9"} {
+		executablePath := executableLocation(filepath.Join(updatesDirectory(binaryOsqueryd, baseDir), version), binaryOsqueryd)
+		tufci.CopyBinary(t, executablePath)
+		require.NoError(t, os.Chmod(executablePath, 0755))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(updatesDirectory(binaryOsqueryd, baseDir), "1.0.0"), 0755))
+	invalidVersionExecutable := executableLocation(filepath.Join(updatesDirectory(binaryLauncher, baseDir), "not-a-version"), binaryLauncher)
+	tufci.CopyBinary(t, invalidVersionExecutable)
+	require.NoError(t, os.Chmod(invalidVersionExecutable, 0755))
+
+	// A custom build
+	customBuildExecutablePath := customBuildExecutable(binaryOsqueryd, baseDir, "ABCDEF")
+	tufci.CopyBinary(t, customBuildExecutablePath)
+	require.NoError(t, os.Chmod(customBuildExecutablePath, 0755))
+
+	entries, err := LibraryContents(context.TODO(), baseDir, LocalTufDirectory(testRootDir))
+	require.NoError(t, err)
+
+	statuses := make(map[string]string)
+	for _, e := range entries {
+		statuses[e.Binary+" "+e.Version] = e.VerificationStatus
+		require.Equal(t, executableLocation(e.Directory, autoupdatableBinary(e.Binary)), e.ExecutablePath)
+		require.NotZero(t, e.ModTime)
+		require.Equal(t, e.Version == "abcdef", e.CustomBuild)
+	}
+	require.Equal(t, map[string]string{
+		"osqueryd " + testReleaseVersion: VerificationStatusVerified,
+		"osqueryd 0.9.9":                 VerificationStatusNotInMetadata,
+		"osqueryd 1.0.0":                 VerificationStatusInvalidExecutable,
+		"launcher not-a-version":         VerificationStatusInvalidVersion,
+		"osqueryd abcdef":                VerificationStatusVerified,
+	}, statuses)
+
+	// Without TUF metadata, nothing in the library is verified
+	entries, err = LibraryContents(context.TODO(), baseDir, filepath.Join(t.TempDir(), "tuf"))
+	require.Error(t, err)
+	for _, e := range entries {
+		if e.Binary == string(binaryOsqueryd) && e.Version == testReleaseVersion {
+			require.Equal(t, VerificationStatusNotInMetadata, e.VerificationStatus)
+		}
+	}
+}
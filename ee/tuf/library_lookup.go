@@ -13,7 +13,6 @@ import (
 	"github.com/kolide/launcher/ee/agent/startupsettings"
 	"github.com/kolide/launcher/pkg/launcher"
 	"github.com/kolide/launcher/pkg/traces"
-	"github.com/peterbourgon/ff/v3"
 	"github.com/spf13/pflag"
 )
 
@@ -178,7 +177,7 @@ func getAutoupdateConfigFromFile(configFilePath string) (*autoupdateConfig, erro
 	defer cfgFileHandle.Close()
 
 	cfg := &autoupdateConfig{}
-	if err := ff.PlainParser(cfgFileHandle, func(name, value string) error {
+	if err := launcher.ConfigFileParser(cfgFileHandle, func(name, value string) error {
 		switch name {
 		case "root_directory":
 			cfg.rootDirectory = value
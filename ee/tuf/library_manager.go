@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/fs"
@@ -14,6 +16,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -175,6 +178,114 @@ func (ulm *updateLibraryManager) stageAndVerifyUpdate(binary autoupdatableBinary
 	return stagedUpdatePath, nil
 }
 
+// AddCustomBuild adds the customer-hosted build at downloadUrl to the custom builds library for the
+// given binary, downloading it and verifying it against the given SHA-256 hash if it's not already there.
+func (ulm *updateLibraryManager) AddCustomBuild(binary autoupdatableBinary, downloadUrl string, sha256 string) error {
+	// Acquire lock for modifying the library
+	ulm.lock.Lock(binary)
+	defer ulm.lock.Unlock(binary)
+
+	sha256 = strings.ToLower(sha256)
+	if CheckExecutable(context.TODO(), customBuildExecutable(binary, ulm.baseDir, sha256), "--version") == nil {
+		return nil
+	}
+
+	stagingDir, err := ulm.tempDir(binary, "staged-custom-build")
+	if err != nil {
+		return fmt.Errorf("could not create temporary directory for downloading custom build: %w", err)
+	}
+	// Remove the download and anything left of the extracted build, regardless of success
+	defer func() {
+		if err := backoff.WaitFor(func() error {
+			return os.RemoveAll(stagingDir)
+		}, 500*time.Millisecond, 100*time.Millisecond); err != nil {
+			ulm.slogger.Log(context.TODO(), slog.LevelWarn,
+				"could not remove temp staging directory",
+				"directory", stagingDir,
+				"err", err,
+			)
+		}
+	}()
+
+	archivePath := filepath.Join(stagingDir, fmt.Sprintf("%s.tar.gz", binary))
+	if err := ulm.downloadCustomBuild(downloadUrl, sha256, archivePath); err != nil {
+		return fmt.Errorf("could not download custom build: %w", err)
+	}
+
+	extractedDir := filepath.Join(stagingDir, sha256)
+	if err := os.MkdirAll(extractedDir, 0755); err != nil {
+		return fmt.Errorf("could not create directory for untarring custom build: %w", err)
+	}
+	if err := untar(extractedDir, archivePath); err != nil {
+		return fmt.Errorf("could not untar custom build to %s: %w", extractedDir, err)
+	}
+	if err := os.Chmod(executableLocation(extractedDir, binary), 0755); err != nil {
+		return fmt.Errorf("could not set +x permissions on executable: %w", err)
+	}
+	if err := patchExecutable(executableLocation(extractedDir, binary)); err != nil {
+		return fmt.Errorf("could not patch executable: %w", err)
+	}
+	if err := backoff.WaitFor(func() error {
+		return CheckExecutable(context.TODO(), executableLocation(extractedDir, binary), "--version")
+	}, 45*time.Second, 15*time.Second); err != nil {
+		return fmt.Errorf("could not verify executable after retries: %w", err)
+	}
+
+	// All good! Shelve it in the custom builds library under its hash.
+	if err := os.MkdirAll(customBuildsDirectory(binary, ulm.baseDir), 0755); err != nil {
+		return fmt.Errorf("could not make custom builds directory for %s: %w", binary, err)
+	}
+	newBuildDirectory := filepath.Join(customBuildsDirectory(binary, ulm.baseDir), sha256)
+	if err := os.RemoveAll(newBuildDirectory); err != nil {
+		return fmt.Errorf("could not remove invalid custom build at %s: %w", newBuildDirectory, err)
+	}
+	if err := backoff.WaitFor(func() error {
+		return os.Rename(extractedDir, newBuildDirectory)
+	}, 6*time.Second, 2*time.Second); err != nil {
+		return fmt.Errorf("could not move custom build from %s to %s after retries: %w", extractedDir, newBuildDirectory, err)
+	}
+
+	return nil
+}
+
+// downloadCustomBuild downloads the archive at downloadUrl to destination, verifying it against
+// the expected SHA-256 hash.
+func (ulm *updateLibraryManager) downloadCustomBuild(downloadUrl string, expectedSha256 string, destination string) error {
+	resp, err := ulm.mirrorClient.Get(downloadUrl)
+	if err != nil {
+		return fmt.Errorf("could not make request to download %s: %w", downloadUrl, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d downloading %s", resp.StatusCode, downloadUrl)
+	}
+
+	out, err := os.OpenFile(destination, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0655)
+	if err != nil {
+		return fmt.Errorf("could not create file at %s: %w", destination, err)
+	}
+
+	// Read at most one byte more than we allow, to tell when the download is too large
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(out, hasher), io.LimitReader(resp.Body, maxCustomBuildSize+1))
+	if closeErr := out.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write download to %s: %w", destination, err)
+	}
+	if written > maxCustomBuildSize {
+		return fmt.Errorf("download from %s is larger than the maximum of %d bytes", downloadUrl, maxCustomBuildSize)
+	}
+
+	if actualSha256 := hex.EncodeToString(hasher.Sum(nil)); actualSha256 != expectedSha256 {
+		return fmt.Errorf("verification failed for download from %s: expected sha256 %s, got %s", downloadUrl, expectedSha256, actualSha256)
+	}
+
+	return nil
+}
+
 // tempDir creates a directory inside of the updates directory. It is the caller's responsibility to remove
 // the directory when it is no longer needed.
 func (ulm *updateLibraryManager) tempDir(binary autoupdatableBinary, pattern string) (string, error) {
@@ -349,9 +460,10 @@ func (ulm *updateLibraryManager) removeUpdate(binary autoupdatableBinary, binary
 }
 
 // TidyLibrary reviews all updates in the library for the binary and removes any old versions
-// that are no longer needed. It will always preserve the current running binary, and then the
-// two most recent valid versions. It will remove versions it cannot validate.
-func (ulm *updateLibraryManager) TidyLibrary(binary autoupdatableBinary, currentVersion string) {
+// that are no longer needed. It will always preserve the current running binary and any versions
+// pinned for individual registrations, and then the two most recent valid versions. It will remove
+// versions it cannot validate.
+func (ulm *updateLibraryManager) TidyLibrary(binary autoupdatableBinary, currentVersion string, pinnedVersions []string) {
 	// Acquire lock for modifying the library
 	ulm.lock.Lock(binary)
 	defer ulm.lock.Unlock(binary)
@@ -391,8 +503,8 @@ func (ulm *updateLibraryManager) TidyLibrary(binary autoupdatableBinary, current
 	// Loop through, looking at the most recent versions first, and remove all once we hit nonCurrentlyRunningVersionsKept valid executables
 	nonCurrentlyRunningVersionsKept := 0
 	for i := len(versionsInLibrary) - 1; i >= 0; i -= 1 {
-		// Always keep the current running executable
-		if versionsInLibrary[i] == currentVersion {
+		// Always keep the current running executable, and the versions registrations are pinned to
+		if versionsInLibrary[i] == currentVersion || slices.Contains(pinnedVersions, versionsInLibrary[i]) {
 			continue
 		}
 
@@ -407,6 +519,44 @@ func (ulm *updateLibraryManager) TidyLibrary(binary autoupdatableBinary, current
 	}
 }
 
+// TidyCustomBuilds removes the custom builds of the given binary that are no longer selected
+// for any registration.
+func (ulm *updateLibraryManager) TidyCustomBuilds(binary autoupdatableBinary, selectedSha256s []string) {
+	// Acquire lock for modifying the library
+	ulm.lock.Lock(binary)
+	defer ulm.lock.Unlock(binary)
+
+	builds, err := filepath.Glob(filepath.Join(customBuildsDirectory(binary, ulm.baseDir), "*"))
+	if err != nil {
+		ulm.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not glob for custom builds to tidy",
+			"err", err,
+		)
+		return
+	}
+
+	for _, build := range builds {
+		if slices.ContainsFunc(selectedSha256s, func(selected string) bool {
+			return strings.EqualFold(selected, filepath.Base(build))
+		}) {
+			continue
+		}
+
+		if err := os.RemoveAll(build); err != nil {
+			ulm.slogger.Log(context.TODO(), slog.LevelWarn,
+				"could not remove custom build",
+				"directory", build,
+				"err", err,
+			)
+			continue
+		}
+		ulm.slogger.Log(context.TODO(), slog.LevelDebug,
+			"removed custom build",
+			"directory", build,
+		)
+	}
+}
+
 // sortedVersionsInLibrary looks through the update library for the given binary to validate and sort all
 // available versions. It returns a sorted list of the valid versions, a list of invalid versions, and
 // an error only when unable to glob for versions.
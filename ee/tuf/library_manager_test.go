@@ -442,6 +442,7 @@ func TestTidyLibrary(t *testing.T) {
 		testCaseName              string
 		existingVersions          map[string]bool // maps versions to whether they're executable
 		currentlyRunningVersion   string
+		pinnedVersions            []string
 		expectedPreservedVersions []string
 		expectedRemovedVersions   []string
 	}{
@@ -567,6 +568,27 @@ func TestTidyLibrary(t *testing.T) {
 				"1.0.0",
 			},
 		},
+		{
+			testCaseName: "more than 3 versions, registration pinned to old version",
+			existingVersions: map[string]bool{
+				"1.0.3":  true,
+				"1.0.1":  true,
+				"1.0.0":  true,
+				"0.13.6": true,
+				"0.12.4": true,
+			},
+			currentlyRunningVersion: "1.0.3",
+			pinnedVersions:          []string{"0.12.4"},
+			expectedPreservedVersions: []string{
+				"1.0.3",
+				"1.0.1",
+				"1.0.0",
+				"0.12.4",
+			},
+			expectedRemovedVersions: []string{
+				"0.13.6",
+			},
+		},
 		{
 			testCaseName: "fewer than 3 versions",
 			existingVersions: map[string]bool{
@@ -626,7 +648,7 @@ func TestTidyLibrary(t *testing.T) {
 				require.Equal(t, len(tt.existingVersions), len(updateMatches))
 
 				// Tidy the library
-				testLibraryManager.TidyLibrary(binary, tt.currentlyRunningVersion)
+				testLibraryManager.TidyLibrary(binary, tt.currentlyRunningVersion, tt.pinnedVersions)
 
 				// Confirm that the versions we expect are still there
 				for _, expectedPreservedVersion := range tt.expectedPreservedVersions {
@@ -12,6 +12,20 @@ type Mocklibrarian struct {
 	mock.Mock
 }
 
+// AddCustomBuild provides a mock function with given fields: binary, downloadUrl, sha256
+func (_m *Mocklibrarian) AddCustomBuild(binary autoupdatableBinary, downloadUrl string, sha256 string) error {
+	ret := _m.Called(binary, downloadUrl, sha256)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(autoupdatableBinary, string, string) error); ok {
+		r0 = rf(binary, downloadUrl, sha256)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // AddToLibrary provides a mock function with given fields: binary, currentVersion, targetFilename, targetMetadata
 func (_m *Mocklibrarian) AddToLibrary(binary autoupdatableBinary, currentVersion string, targetFilename string, targetMetadata data.TargetFileMeta) error {
 	ret := _m.Called(binary, currentVersion, targetFilename, targetMetadata)
@@ -54,9 +68,14 @@ func (_m *Mocklibrarian) Close() error {
 	return r0
 }
 
-// TidyLibrary provides a mock function with given fields: binary, currentVersion
-func (_m *Mocklibrarian) TidyLibrary(binary autoupdatableBinary, currentVersion string) {
-	_m.Called(binary, currentVersion)
+// TidyCustomBuilds provides a mock function with given fields: binary, selectedSha256s
+func (_m *Mocklibrarian) TidyCustomBuilds(binary autoupdatableBinary, selectedSha256s []string) {
+	_m.Called(binary, selectedSha256s)
+}
+
+// TidyLibrary provides a mock function with given fields: binary, currentVersion, pinnedVersions
+func (_m *Mocklibrarian) TidyLibrary(binary autoupdatableBinary, currentVersion string, pinnedVersions []string) {
+	_m.Called(binary, currentVersion, pinnedVersions)
 }
 
 type mockConstructorTestingTNewMocklibrarian interface {
@@ -0,0 +1,151 @@
+package tuf
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/traces"
+)
+
+// OsquerydSelectionSubsystemName is the control server subsystem that selects, for individual
+// registrations, an osqueryd other than the one the installation autoupdates to. This lets one
+// tenant on a multi-registration installation pilot an osquery release without affecting the others.
+const OsquerydSelectionSubsystemName = "osqueryd_selection"
+
+// maxCustomBuildSize bounds the download of a customer-hosted build
+const maxCustomBuildSize = 512 * 1024 * 1024
+
+// OsquerydSelection is the osqueryd selected for a registration. The control server sends a
+// selection per registration ID; the selections are kept in the osqueryd selection store,
+// as JSON, under the registration ID.
+type OsquerydSelection struct {
+	// PinnedVersion selects a release from our TUF repository, as the pinned_osqueryd_version
+	// flag does for the whole installation.
+	PinnedVersion string `json:"pinned_version,omitempty"`
+	// DownloadUrl selects a customer-hosted build instead: a .tar.gz archive laid out like
+	// our osqueryd releases. It takes precedence over PinnedVersion.
+	DownloadUrl string `json:"download_url,omitempty"`
+	// Sha256 is the hex-encoded SHA-256 hash of the archive at DownloadUrl. Builds that don't
+	// match it are never run.
+	Sha256 string `json:"sha256,omitempty"`
+}
+
+// custom reports whether the selection is a customer-hosted build.
+func (s OsquerydSelection) custom() bool {
+	return s.DownloadUrl != ""
+}
+
+func (s OsquerydSelection) validate() error {
+	if !s.custom() {
+		if s.PinnedVersion == "" {
+			return errors.New("selection has neither pinned_version nor download_url")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(s.DownloadUrl)
+	if err != nil {
+		return fmt.Errorf("parsing download_url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("download_url must use https, got %q", u.Scheme)
+	}
+
+	hash, err := hex.DecodeString(s.Sha256)
+	if err != nil || len(hash) != 32 {
+		return fmt.Errorf("sha256 %q is not a hex-encoded SHA-256 hash", s.Sha256)
+	}
+
+	return nil
+}
+
+// String describes the selection for logs.
+func (s OsquerydSelection) String() string {
+	if s.custom() {
+		return fmt.Sprintf("custom build %s", strings.ToLower(s.Sha256))
+	}
+	return fmt.Sprintf("version %s", s.PinnedVersion)
+}
+
+// osquerydSelection returns the osqueryd selected for the given registration, or nil if the
+// registration runs the one the installation autoupdates to.
+func osquerydSelection(store types.Getter, registrationId string) (*OsquerydSelection, error) {
+	if store == nil {
+		return nil, nil
+	}
+
+	raw, err := store.Get([]byte(registrationId))
+	if err != nil {
+		return nil, fmt.Errorf("getting osqueryd selection for %s: %w", registrationId, err)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var selection OsquerydSelection
+	if err := json.Unmarshal(raw, &selection); err != nil {
+		return nil, fmt.Errorf("unmarshalling osqueryd selection for %s: %w", registrationId, err)
+	}
+	if err := selection.validate(); err != nil {
+		return nil, fmt.Errorf("invalid osqueryd selection for %s: %w", registrationId, err)
+	}
+
+	return &selection, nil
+}
+
+// CheckOutRegistrationOsqueryd returns the path to, and version of, the osqueryd selected for the
+// given registration. It returns nil if the registration has no selection. Until the selection is
+// downloaded, a pinned version falls back to the latest osqueryd, as CheckOutLatest does, and a
+// custom build returns an error; either way, the autoupdater restarts the registration's instance
+// once the download completes.
+func CheckOutRegistrationOsqueryd(ctx context.Context, store types.Getter, registrationId string, rootDirectory string,
+	updateDirectory string, channel string, slogger *slog.Logger) (*BinaryUpdateInfo, error) {
+	ctx, span := traces.StartSpan(ctx, "registration_id", registrationId)
+	defer span.End()
+
+	selection, err := osquerydSelection(store, registrationId)
+	if err != nil {
+		return nil, err
+	}
+	if selection == nil {
+		return nil, nil
+	}
+
+	if updateDirectory == "" {
+		updateDirectory = DefaultLibraryDirectory(rootDirectory)
+	}
+
+	if !selection.custom() {
+		return CheckOutLatest(ctx, binaryOsqueryd, rootDirectory, updateDirectory, selection.PinnedVersion, channel, slogger.With("registration_id", registrationId))
+	}
+
+	executablePath := customBuildExecutable(binaryOsqueryd, updateDirectory, selection.Sha256)
+	if err := CheckExecutable(ctx, executablePath, "--version"); err != nil {
+		traces.SetError(span, err)
+		return nil, fmt.Errorf("selected %s is not yet downloaded, or is corrupted: %w", selection, err)
+	}
+
+	// Custom builds are known by their hash, not their version
+	return &BinaryUpdateInfo{
+		Path: executablePath,
+	}, nil
+}
+
+// customBuildsDirectory returns the library location for customer-hosted builds of the given
+// binary. It's kept apart from our releases, so that custom builds are never selected by version.
+func customBuildsDirectory(binary autoupdatableBinary, baseUpdateDirectory string) string {
+	return filepath.Join(baseUpdateDirectory, fmt.Sprintf("%s-custom", binary))
+}
+
+// customBuildExecutable returns the path to the executable for the custom build with the given hash.
+func customBuildExecutable(binary autoupdatableBinary, baseUpdateDirectory string, sha256 string) string {
+	return executableLocation(filepath.Join(customBuildsDirectory(binary, baseUpdateDirectory), strings.ToLower(sha256)), binary)
+}
@@ -0,0 +1,179 @@
+package tuf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOsquerydSelection_validate(t *testing.T) {
+	t.Parallel()
+
+	validSha256 := strings.Repeat("ab", 32)
+
+	for _, tt := range []struct {
+		testCaseName string
+		selection    OsquerydSelection
+		expectValid  bool
+	}{
+		{
+			testCaseName: "pinned version",
+			selection:    OsquerydSelection{PinnedVersion: "5.11.0"},
+			expectValid:  true,
+		},
+		{
+			testCaseName: "custom build",
+			selection:    OsquerydSelection{DownloadUrl: "https://example.com/osqueryd.tar.gz", Sha256: validSha256},
+			expectValid:  true,
+		},
+		{
+			testCaseName: "custom build with uppercase hash",
+			selection:    OsquerydSelection{DownloadUrl: "https://example.com/osqueryd.tar.gz", Sha256: strings.ToUpper(validSha256)},
+			expectValid:  true,
+		},
+		{
+			testCaseName: "empty",
+			selection:    OsquerydSelection{},
+			expectValid:  false,
+		},
+		{
+			testCaseName: "custom build over http",
+			selection:    OsquerydSelection{DownloadUrl: "http://example.com/osqueryd.tar.gz", Sha256: validSha256},
+			expectValid:  false,
+		},
+		{
+			testCaseName: "custom build without hash",
+			selection:    OsquerydSelection{DownloadUrl: "https://example.com/osqueryd.tar.gz"},
+			expectValid:  false,
+		},
+		{
+			testCaseName: "custom build with short hash",
+			selection:    OsquerydSelection{DownloadUrl: "https://example.com/osqueryd.tar.gz", Sha256: "abcd"},
+			expectValid:  false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			if tt.expectValid {
+				require.NoError(t, tt.selection.validate())
+			} else {
+				require.Error(t, tt.selection.validate())
+			}
+		})
+	}
+}
+
+func Test_osquerydSelection(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+
+	// No store, or no selection for the registration, means no selection
+	selection, err := osquerydSelection(nil, "default")
+	require.NoError(t, err)
+	require.Nil(t, selection)
+	selection, err = osquerydSelection(store, "default")
+	require.NoError(t, err)
+	require.Nil(t, selection)
+
+	raw, err := json.Marshal(OsquerydSelection{PinnedVersion: "5.11.0"})
+	require.NoError(t, err)
+	require.NoError(t, store.Set([]byte("tenant2"), raw))
+	require.NoError(t, store.Set([]byte("tenant3"), []byte(`{"download_url":"http://example.com/osqueryd.tar.gz"}`)))
+	require.NoError(t, store.Set([]byte("tenant4"), []byte(`not json`)))
+
+	selection, err = osquerydSelection(store, "tenant2")
+	require.NoError(t, err)
+	require.Equal(t, &OsquerydSelection{PinnedVersion: "5.11.0"}, selection)
+
+	_, err = osquerydSelection(store, "tenant3")
+	require.Error(t, err, "invalid selection should not be returned")
+
+	_, err = osquerydSelection(store, "tenant4")
+	require.Error(t, err, "malformed selection should not be returned")
+}
+
+func TestCheckOutRegistrationOsqueryd_customBuildNotDownloaded(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	raw, err := json.Marshal(OsquerydSelection{DownloadUrl: "https://example.com/osqueryd.tar.gz", Sha256: strings.Repeat("ab", 32)})
+	require.NoError(t, err)
+	require.NoError(t, store.Set([]byte("tenant2"), raw))
+
+	// The registration without a selection gets nothing
+	info, err := CheckOutRegistrationOsqueryd(context.TODO(), store, "default", t.TempDir(), "", "stable", multislogger.NewNopLogger())
+	require.NoError(t, err)
+	require.Nil(t, info)
+
+	// The registration whose custom build hasn't been downloaded gets an error
+	_, err = CheckOutRegistrationOsqueryd(context.TODO(), store, "tenant2", t.TempDir(), "", "stable", multislogger.NewNopLogger())
+	require.Error(t, err)
+}
+
+func TestAddCustomBuild(t *testing.T) {
+	t.Parallel()
+
+	tarball, err := os.ReadFile(filepath.Join("ci", "testdata", fmt.Sprintf("%s_%s.tar.gz", runtime.GOOS, binaryOsqueryd)))
+	require.NoError(t, err)
+	hash := sha256.Sum256(tarball)
+	tarballSha256 := hex.EncodeToString(hash[:])
+
+	testMirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer testMirror.Close()
+
+	testBaseDir := t.TempDir()
+	testLibraryManager := &updateLibraryManager{
+		mirrorClient: http.DefaultClient,
+		slogger:      multislogger.NewNopLogger(),
+		baseDir:      testBaseDir,
+		lock:         newLibraryLock(),
+	}
+
+	// A build that doesn't match its hash is never added
+	require.Error(t, testLibraryManager.AddCustomBuild(binaryOsqueryd, testMirror.URL, strings.Repeat("ab", 32)))
+	_, err = os.Stat(customBuildExecutable(binaryOsqueryd, testBaseDir, strings.Repeat("ab", 32)))
+	require.True(t, os.IsNotExist(err), "build with mismatched hash should not have been added")
+
+	// A build that does is shelved under its hash
+	require.NoError(t, testLibraryManager.AddCustomBuild(binaryOsqueryd, testMirror.URL, strings.ToUpper(tarballSha256)))
+	executablePath := customBuildExecutable(binaryOsqueryd, testBaseDir, tarballSha256)
+	require.NoError(t, CheckExecutable(context.TODO(), executablePath, "--version"))
+	require.Equal(t, "", libraryVersion(binaryOsqueryd, testBaseDir, executablePath), "custom build should not be mistaken for a release")
+
+	// Tidying keeps the selected build and removes the others
+	unselectedBuild := filepath.Join(customBuildsDirectory(binaryOsqueryd, testBaseDir), strings.Repeat("cd", 32))
+	require.NoError(t, os.MkdirAll(unselectedBuild, 0755))
+	testLibraryManager.TidyCustomBuilds(binaryOsqueryd, []string{tarballSha256})
+	_, err = os.Stat(executablePath)
+	require.NoError(t, err, "selected build should have been kept")
+	_, err = os.Stat(unselectedBuild)
+	require.True(t, os.IsNotExist(err), "unselected build should have been removed")
+}
+
+func Test_libraryVersion(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+
+	require.Equal(t, "5.11.0", libraryVersion(binaryOsqueryd, baseDir, executableLocation(filepath.Join(updatesDirectory(binaryOsqueryd, baseDir), "5.11.0"), binaryOsqueryd)))
+	require.Equal(t, "", libraryVersion(binaryOsqueryd, baseDir, filepath.Join("usr", "local", "bin", "osqueryd")))
+	require.Equal(t, "", libraryVersion(binaryOsqueryd, baseDir, ""))
+}
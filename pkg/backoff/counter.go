@@ -1,6 +1,8 @@
 package backoff
 
 import (
+	"math"
+	"math/rand"
 	"time"
 )
 
@@ -38,3 +40,35 @@ func NewMultiplicativeDurationCounter(baseDuration, maxDuration time.Duration) *
 		},
 	}
 }
+
+// NewExponentialDurationCounter creates a new durationCounter that doubles the interval each time.
+// Count is incremented each time Next() is called and returns the base interval multiplied by 2^(count-1).
+// If the result is greater than the maxDuration, maxDuration is returned.
+func NewExponentialDurationCounter(baseDuration, maxDuration time.Duration) *durationCounter {
+	return &durationCounter{
+		baseInterval: baseDuration,
+		maxInterval:  maxDuration,
+		calcNext: func(count int, baseInterval time.Duration) time.Duration {
+			interval := baseInterval
+			for i := 1; i < count; i++ {
+				// Stop doubling before we overflow -- we're well past any reasonable max interval
+				if interval > math.MaxInt64/2 {
+					return math.MaxInt64
+				}
+				interval *= 2
+			}
+			return interval
+		},
+	}
+}
+
+// Jitter returns a random duration between half of d and d. Clients backing off at the
+// same time, e.g. a fleet of devices that all lost connectivity together, use it to
+// spread out their retries instead of retrying in lockstep.
+func Jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return d - half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -72,3 +72,43 @@ func TestMultiplicativeCounter(t *testing.T) {
 		})
 	}
 }
+
+func TestExponentialCounter(t *testing.T) {
+	t.Parallel()
+
+	ec := NewExponentialDurationCounter(15*time.Second, 5*time.Minute)
+	expected := []time.Duration{
+		15 * time.Second, // 15s
+		30 * time.Second, // 30s
+		time.Minute,      // 1m
+		2 * time.Minute,  // 2m
+		4 * time.Minute,  // 4m
+		5 * time.Minute,  // capped at max interval
+		5 * time.Minute,  // capped at max interval
+	}
+
+	for _, e := range expected {
+		require.Equal(t, e, ec.Next())
+	}
+
+	ec.Reset()
+	require.Equal(t, 15*time.Second, ec.Next())
+
+	// Many failures should not overflow
+	for i := 0; i < 100; i++ {
+		ec.Next()
+	}
+	require.Equal(t, 5*time.Minute, ec.Next())
+}
+
+func TestJitter(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 100; i++ {
+		jittered := Jitter(time.Minute)
+		require.GreaterOrEqual(t, jittered, 30*time.Second)
+		require.LessOrEqual(t, jittered, time.Minute)
+	}
+
+	require.Equal(t, time.Duration(0), Jitter(0))
+}
@@ -0,0 +1,161 @@
+package launcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/peterbourgon/ff/v3"
+)
+
+const (
+	// includeDirective names another config file to parse in place, e.g. `include /etc/kolide-k2/site.flags`
+	includeDirective = "include"
+
+	// maxIncludeDepth bounds nested includes
+	maxIncludeDepth = 8
+)
+
+var envVarNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ConfigFileParser parses launcher's config file. It extends ff.PlainParser with:
+//
+//   - an `include <path>` directive, which parses another config file in place. Relative paths
+//     are relative to the including file. Later values override earlier ones, so a host-specific
+//     file can include a site-wide base, then override it. An include that doesn't exist is
+//     skipped with a warning, so that a missing override file doesn't prevent launcher from
+//     starting. Include cycles, includes nested too deep, and includes that can't be read are
+//     errors.
+//   - interpolation of environment variables in values, as ${VAR}, or ${VAR:-default} to use
+//     default when VAR is unset or empty. An unset variable without a default is replaced with
+//     the empty string. $${ is a literal ${.
+func ConfigFileParser(r io.Reader, set func(name, value string) error) error {
+	p := &configFileParser{set: set, slogger: slog.Default()}
+
+	// ff hands us the opened config file, which lets us resolve relative includes
+	var path string
+	if f, ok := r.(*os.File); ok {
+		path = f.Name()
+	}
+
+	return p.parse(r, path, 0)
+}
+
+type configFileParser struct {
+	set     func(name, value string) error
+	slogger *slog.Logger
+
+	// including is the stack of files currently being parsed, to detect include cycles
+	including []string
+}
+
+func (p *configFileParser) parse(r io.Reader, path string, depth int) error {
+	if path != "" {
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+		p.including = append(p.including, path)
+		defer func() {
+			p.including = p.including[:len(p.including)-1]
+		}()
+	}
+
+	return ff.PlainParser(r, func(name, value string) error {
+		value = interpolateEnv(value)
+
+		if name != includeDirective {
+			return p.set(name, value)
+		}
+
+		includePath := value
+		if !filepath.IsAbs(includePath) && path != "" {
+			includePath = filepath.Join(filepath.Dir(path), includePath)
+		}
+
+		return p.include(includePath, depth+1)
+	})
+}
+
+func (p *configFileParser) include(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("including %s: includes nested more than %d deep", path, maxIncludeDepth)
+	}
+
+	if absPath, err := filepath.Abs(path); err == nil {
+		for _, including := range p.including {
+			if including == absPath {
+				return fmt.Errorf("including %s: include cycle", path)
+			}
+		}
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		p.slogger.Log(context.TODO(), slog.LevelWarn,
+			"skipping config file include that does not exist",
+			"path", path,
+		)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("including %s: %w", path, err)
+	}
+	defer f.Close()
+
+	return p.parse(f, path, depth)
+}
+
+// interpolateEnv replaces ${VAR} and ${VAR:-default} in value with the environment variable's
+// value. Anything that isn't a well-formed reference is left as-is.
+func interpolateEnv(value string) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			sb.WriteString(value)
+			return sb.String()
+		}
+
+		// $${ escapes a literal ${
+		if start > 0 && value[start-1] == '$' {
+			sb.WriteString(value[:start-1])
+			sb.WriteString("${")
+			value = value[start+2:]
+			continue
+		}
+
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			sb.WriteString(value)
+			return sb.String()
+		}
+		end += start
+
+		name, defaultValue, hasDefault := strings.Cut(value[start+2:end], ":-")
+		if !envVarNameRegexp.MatchString(name) {
+			// Not a reference -- leave it alone
+			sb.WriteString(value[:end+1])
+			value = value[end+1:]
+			continue
+		}
+
+		sb.WriteString(value[:start])
+		if envValue := os.Getenv(name); envValue != "" || !hasDefault {
+			sb.WriteString(envValue)
+		} else {
+			sb.WriteString(defaultValue)
+		}
+		value = value[end+1:]
+	}
+}
@@ -0,0 +1,135 @@
+package launcher
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/kolide/launcher/pkg/threadsafebuffer"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_interpolateEnv(t *testing.T) { // nolint:paralleltest
+	t.Setenv("KOLIDE_TEST_SET", "example.com")
+	t.Setenv("KOLIDE_TEST_EMPTY", "")
+
+	for _, tt := range []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "no references", value: "k2device.kolide.com", expected: "k2device.kolide.com"},
+		{name: "set", value: "https://${KOLIDE_TEST_SET}/path", expected: "https://example.com/path"},
+		{name: "unset", value: "a${KOLIDE_TEST_UNSET}b", expected: "ab"},
+		{name: "default when unset", value: "${KOLIDE_TEST_UNSET:-fallback}", expected: "fallback"},
+		{name: "default when empty", value: "${KOLIDE_TEST_EMPTY:-fallback}", expected: "fallback"},
+		{name: "default ignored when set", value: "${KOLIDE_TEST_SET:-fallback}", expected: "example.com"},
+		{name: "multiple", value: "${KOLIDE_TEST_SET}:${KOLIDE_TEST_UNSET:-8080}", expected: "example.com:8080"},
+		{name: "escaped", value: "$${KOLIDE_TEST_SET}", expected: "${KOLIDE_TEST_SET}"},
+		{name: "unterminated", value: "${KOLIDE_TEST_SET", expected: "${KOLIDE_TEST_SET"},
+		{name: "invalid name", value: "${not a var}${KOLIDE_TEST_SET}", expected: "${not a var}example.com"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, interpolateEnv(tt.value))
+		})
+	}
+}
+
+func TestConfigFileParser_Includes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "conf.d", "base.flags"), "hostname base.example.com", "transport jsonrpc", "include nested.flags")
+	writeConfigFile(t, filepath.Join(dir, "conf.d", "nested.flags"), "update_channel beta")
+	mainPath := filepath.Join(dir, "launcher.flags")
+	writeConfigFile(t, mainPath, "include conf.d/base.flags", "include does-not-exist.flags", "hostname override.example.com")
+
+	var logBytes threadsafebuffer.ThreadSafeBuffer
+	slogger := slog.New(slog.NewTextHandler(&logBytes, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	got, err := parseConfigFile(t, mainPath, slogger)
+	require.NoError(t, err)
+	require.Equal(t, [][2]string{
+		{"hostname", "base.example.com"},
+		{"transport", "jsonrpc"},
+		{"update_channel", "beta"},
+		{"hostname", "override.example.com"},
+	}, got)
+
+	// The missing include is skipped, with a warning naming it
+	require.Contains(t, logBytes.String(), "level=WARN")
+	require.Contains(t, logBytes.String(), filepath.Join(dir, "does-not-exist.flags"))
+}
+
+func TestConfigFileParser_IncludeCycle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	aPath := filepath.Join(dir, "a.flags")
+	writeConfigFile(t, aPath, "hostname a.example.com", "include b.flags")
+	writeConfigFile(t, filepath.Join(dir, "b.flags"), "transport jsonrpc", "include a.flags")
+
+	_, err := parseConfigFile(t, aPath, nil)
+	require.ErrorContains(t, err, "include cycle")
+}
+
+func TestConfigFileParser_IncludeDepth(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for i := 0; i <= maxIncludeDepth; i++ {
+		writeConfigFile(t, filepath.Join(dir, fmt.Sprintf("%d.flags", i)), fmt.Sprintf("include %d.flags", i+1))
+	}
+	writeConfigFile(t, filepath.Join(dir, fmt.Sprintf("%d.flags", maxIncludeDepth+1)), "hostname deep.example.com")
+
+	_, err := parseConfigFile(t, filepath.Join(dir, "0.flags"), nil)
+	require.ErrorContains(t, err, "nested more than")
+}
+
+func TestOptionsFromFileWithIncludeAndEnv(t *testing.T) { // nolint:paralleltest
+	os.Clearenv()
+	t.Setenv("KOLIDE_TEST_HOSTNAME", "env.example.com")
+
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "base.flags"), "hostname ${KOLIDE_TEST_HOSTNAME}", "update_channel beta")
+	mainPath := filepath.Join(dir, "launcher.flags")
+	writeConfigFile(t, mainPath, "include base.flags", "transport ${KOLIDE_TEST_TRANSPORT:-grpc}", "osqueryd_path "+windowsAddExe("/dev/null"))
+
+	opts, err := ParseOptions("", []string{"-config", mainPath})
+	require.NoError(t, err)
+	require.Equal(t, "env.example.com", opts.KolideServerURL)
+	require.Equal(t, "beta", string(opts.UpdateChannel))
+	require.Equal(t, "grpc", opts.Transport)
+}
+
+func writeConfigFile(t *testing.T, path string, lines ...string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+}
+
+func parseConfigFile(t *testing.T, path string, slogger *slog.Logger) ([][2]string, error) {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	if slogger == nil {
+		slogger = multislogger.NewNopLogger()
+	}
+
+	var got [][2]string
+	p := &configFileParser{
+		set: func(name, value string) error {
+			got = append(got, [2]string{name, value})
+			return nil
+		},
+		slogger: slogger,
+	}
+	err = p.parse(f, path, 0)
+
+	return got, err
+}
@@ -30,6 +30,9 @@ type Options struct {
 	// EnrollSecretPath contains the path to a file containing the enroll
 	// secret.
 	EnrollSecretPath string
+	// HardwareKeyProof sends proof of possession of the hardware-backed key
+	// (TPM or Secure Enclave) along with the enroll secret.
+	HardwareKeyProof bool
 	// RootDirectory is the directory that should be used as the osquery
 	// root directory (database files, pidfile, etc.).
 	RootDirectory string
@@ -43,6 +46,12 @@ type Options struct {
 	// RootPEM is the path to the pem file containing the certificate
 	// chain, if necessary for verification.
 	RootPEM string
+	// MTLSClientCert is the path to a PEM file containing a client certificate to present
+	// to the Kolide and control servers, for mutual TLS with a proxy or relay. Its private
+	// key is one of the agent's keys, selected by MTLSClientKey.
+	MTLSClientCert string
+	// MTLSClientKey is which agent key the client certificate belongs to: "local" or "hardware"
+	MTLSClientKey string
 	// LoggingInterval is the interval at which logs should be flushed to
 	// the server.
 	LoggingInterval time.Duration
@@ -80,6 +89,12 @@ type Options struct {
 	AutoupdateInitialDelay time.Duration
 	// UpdateDirectory is the location of the update libraries for osqueryd and launcher
 	UpdateDirectory string
+	// MaintenanceWindow is the schedule, in local time, during which launcher may restart
+	// after updates and show notifications, e.g. "mon-fri 01:00-05:00". Empty means any time.
+	MaintenanceWindow string
+	// MaintenanceWindowDeadline is the longest launcher will defer a restart or notification
+	// while waiting for the maintenance window.
+	MaintenanceWindowDeadline time.Duration
 
 	// Debug enables debug logging.
 	Debug bool
@@ -95,6 +110,19 @@ type Options struct {
 	WatchdogMemoryLimitMB int
 	// WatchdogUtilizationLimitPercent sets the CPU utilization limit on osquery processes
 	WatchdogUtilizationLimitPercent int
+	// OsqueryHandoverEnabled allows a newly-updated launcher to adopt the running
+	// osqueryd process, rather than restarting it.
+	OsqueryHandoverEnabled bool
+	// ExportPlatformLogs mirrors launcher logs and osquery status logs into the
+	// platform's logging system (unified log, journald, or Event Log).
+	ExportPlatformLogs bool
+
+	// DistributedQueryDenylist is a list of regular expressions; distributed
+	// queries matching any of them are denied by policy instead of being run.
+	DistributedQueryDenylist []string
+	// LogRedactionClasses are the classes of sensitive value (emails, ips,
+	// serial_numbers, tokens) masked in launcher logs and flares.
+	LogRedactionClasses []string
 
 	// OsqueryFlags defines additional flags to pass to osquery (possibly
 	// overriding Launcher defaults)
@@ -127,6 +155,10 @@ type Options struct {
 	// ConfigFilePath is the config file options were parsed from, if provided
 	ConfigFilePath string
 
+	// HostRoot is where the host's filesystem is mounted, when launcher runs in a container
+	// to monitor the host it runs on. Only supported on Linux.
+	HostRoot string
+
 	// LocalDevelopmentPath is the path to a local build of launcher to test against, rather than finding the latest version in the library
 	LocalDevelopmentPath string
 
@@ -169,6 +201,12 @@ const (
 	DefaultMirror        = "https://dl.kolide.co"
 )
 
+// Agent keys that a client certificate for mutual TLS may belong to
+const (
+	MTLSClientKeyLocal    = "local"
+	MTLSClientKeyHardware = "hardware"
+)
+
 // Adapted from
 // https://stackoverflow.com/questions/28322997/how-to-get-a-list-of-values-into-a-flag-in-golang/28323276#28323276
 type ArrayFlags []string
@@ -203,6 +241,7 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		flControlRequestInterval          = flagset.Duration("control_request_interval", 60*time.Second, "The interval at which the control server requests will be made")
 		flEnrollSecret                    = flagset.String("enroll_secret", "", "The enroll secret that is used in your environment")
 		flEnrollSecretPath                = flagset.String("enroll_secret_path", "", "Optionally, the path to your enrollment secret")
+		flHardwareKeyProof                = flagset.Bool("hardware_key_proof", false, "Send proof of possession of the hardware-backed key when enrolling")
 		flInitialRunner                   = flagset.Bool("with_initial_runner", false, "Run differential queries from config ahead of scheduled interval.")
 		flKolideServerURL                 = flagset.String("hostname", "", "The hostname of the gRPC server")
 		flKolideHosted                    = flagset.Bool("kolide_hosted", false, "Use Kolide SaaS settings for defaults")
@@ -214,11 +253,17 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		flWatchdogDelaySec                = flagset.Int("watchdog_delay_sec", 120, "How many seconds to delay running watchdog after osquery startup")
 		flWatchdogMemoryLimitMB           = flagset.Int("watchdog_memory_limit_mb", 600, "osquery memory utilization limit in MB")
 		flWatchdogUtilizationLimitPercent = flagset.Int("watchdog_utilization_limit_percent", 50, "osquery CPU utilization limit in percent")
+		flOsqueryHandoverEnabled          = flagset.Bool("osquery_handover_enabled", false, "Keep osqueryd running across launcher autoupdate restarts")
 		flRootDirectory                   = flagset.String("root_directory", DefaultRootDirectoryPath, "The location of the local database, pidfiles, etc.")
 		flRootPEM                         = flagset.String("root_pem", "", "Path to PEM file including root certificates to verify against")
+		flMTLSClientCert                  = flagset.String("mtls_client_cert", "", "Path to PEM file containing a client certificate, for the agent key selected by mtls_client_key, to present for mutual TLS")
+		flMTLSClientKey                   = flagset.String("mtls_client_key", MTLSClientKeyLocal, "Which agent key the mtls_client_cert certificate belongs to: local or hardware")
+		flHostRoot                        = flagset.String("host_root", "", "Where the host's filesystem is mounted, when running in a container to monitor the host (Linux only)")
 		flVersion                         = flagset.Bool("version", false, "Print Launcher version and exit")
 		flLogMaxBytesPerBatch             = flagset.Int("log_max_bytes_per_batch", 0, "Maximum size of a batch of logs. Recommend leaving unset, and launcher will determine")
 		flOsqueryFlags                    ArrayFlags // set below with flagset.Var
+		flDistributedQueryDenylist        ArrayFlags // set below with flagset.Var
+		flLogRedactionClasses             ArrayFlags // set below with flagset.Var
 		flCompactDbMaxTx                  = flagset.Int64("compactdb-max-tx", 65536, "Maximum transaction size used when compacting the internal DB")
 		flConfigFilePath                  = flagset.String("config", DefaultConfigFilePath, "config file to parse options from (optional)")
 		flExportTraces                    = flagset.Bool("export_traces", false, "Whether to export traces")
@@ -226,6 +271,7 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		flLogIngestServerURL              = flagset.String("log_ingest_url", "", "Where to export logs")
 		flTraceIngestServerURL            = flagset.String("trace_ingest_url", "", "Where to export traces")
 		flDisableIngestTLS                = flagset.Bool("disable_trace_ingest_tls", false, "Disable TLS for observability ingest server communication")
+		flExportPlatformLogs              = flagset.Bool("export_platform_logs", false, "Mirror launcher and osquery status logs to the unified log, journald, or the Event Log")
 
 		// Autoupdate options
 		flAutoupdate             = flagset.Bool("autoupdate", DefaultAutoupdate, "Whether or not the osquery autoupdater is enabled (default: false)")
@@ -235,6 +281,8 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		flUpdateChannel          = flagset.String("update_channel", "stable", "The channel to pull updates from (options: stable, beta, nightly)")
 		flAutoupdateInitialDelay = flagset.Duration("autoupdater_initial_delay", 1*time.Hour, "Initial autoupdater subprocess delay")
 		flUpdateDirectory        = flagset.String("update_directory", "", "Local directory to hold updates for osqueryd and launcher")
+		flMaintenanceWindow      = flagset.String("maintenance_window", "", "When restarts after updates and notifications may happen, in local time, e.g. \"mon-fri 01:00-05:00\" (default: any time)")
+		flMaintenanceDeadline    = flagset.Duration("maintenance_window_deadline", 72*time.Hour, "The longest to defer restarts and notifications while waiting for the maintenance window")
 
 		// Development & Debugging options
 		flDebug                = flagset.Bool("debug", false, "Whether or not debug logging is enabled (default: false)")
@@ -262,13 +310,15 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 	)
 
 	flagset.Var(&flOsqueryFlags, "osquery_flag", "Flags to pass to osquery (possibly overriding Launcher defaults)")
+	flagset.Var(&flDistributedQueryDenylist, "distributed_query_denylist", "Regular expression matching distributed queries that must never be run (may be repeated)")
+	flagset.Var(&flLogRedactionClasses, "log_redaction_class", "Class of sensitive value to mask in logs and flares: emails, ips, serial_numbers, or tokens (may be repeated)")
 
 	// Deprecated array
 	flagset.Var(&ArrayFlags{}, "autoloaded_extension", "DEPRECATED")
 
 	ffOpts := []ff.Option{
 		ff.WithConfigFileFlag("config"),
-		ff.WithConfigFileParser(ff.PlainParser),
+		ff.WithConfigFileParser(ConfigFileParser),
 	}
 
 	// Windows doesn't really support environmental variables in quite
@@ -315,6 +365,16 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		return nil, errors.New("both enroll_secret and enroll_secret_path were defined")
 	}
 
+	if err := validateHostRoot(*flHostRoot); err != nil {
+		return nil, err
+	}
+
+	switch *flMTLSClientKey {
+	case MTLSClientKeyLocal, MTLSClientKeyHardware:
+	default:
+		return nil, fmt.Errorf("unknown mtls_client_key %s, expected %s or %s", *flMTLSClientKey, MTLSClientKeyLocal, MTLSClientKeyHardware)
+	}
+
 	var updateChannel UpdateChannel
 	switch *flUpdateChannel {
 	case "", "stable":
@@ -376,6 +436,8 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		Autoupdate:                      *flAutoupdate,
 		AutoupdateInterval:              *flAutoupdateInterval,
 		AutoupdateInitialDelay:          *flAutoupdateInitialDelay,
+		MaintenanceWindow:               *flMaintenanceWindow,
+		MaintenanceWindowDeadline:       *flMaintenanceDeadline,
 		CertPins:                        certPins,
 		CompactDbMaxTx:                  *flCompactDbMaxTx,
 		ConfigFilePath:                  *flConfigFilePath,
@@ -391,6 +453,8 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		WatchdogEnabled:                 *flWatchdogEnabled,
 		EnrollSecret:                    *flEnrollSecret,
 		EnrollSecretPath:                *flEnrollSecretPath,
+		HostRoot:                        *flHostRoot,
+		HardwareKeyProof:                *flHardwareKeyProof,
 		ExportTraces:                    *flExportTraces,
 		LogIngestServerURL:              *flLogIngestServerURL,
 		LocalDevelopmentPath:            *flLocalDevelopmentPath,
@@ -406,11 +470,15 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		MirrorServerURL:                 *flMirrorURL,
 		TufServerURL:                    *flTufServerURL,
 		OsqueryFlags:                    flOsqueryFlags,
+		DistributedQueryDenylist:        flDistributedQueryDenylist,
+		LogRedactionClasses:             flLogRedactionClasses,
 		OsqueryVerbose:                  *flOsqueryVerbose,
 		OsquerydPath:                    osquerydPath,
 		OsqueryHealthcheckStartupDelay:  *flOsqueryHealthcheckStartupDelay,
 		RootDirectory:                   *flRootDirectory,
 		RootPEM:                         *flRootPEM,
+		MTLSClientCert:                  *flMTLSClientCert,
+		MTLSClientKey:                   *flMTLSClientKey,
 		TraceSamplingRate:               *flTraceSamplingRate,
 		Transport:                       *flTransport,
 		UpdateChannel:                   updateChannel,
@@ -418,11 +486,39 @@ func ParseOptions(subcommandName string, args []string) (*Options, error) {
 		WatchdogDelaySec:                *flWatchdogDelaySec,
 		WatchdogMemoryLimitMB:           *flWatchdogMemoryLimitMB,
 		WatchdogUtilizationLimitPercent: *flWatchdogUtilizationLimitPercent,
+		OsqueryHandoverEnabled:          *flOsqueryHandoverEnabled,
+		ExportPlatformLogs:              *flExportPlatformLogs,
 	}
 
 	return opts, nil
 }
 
+// validateHostRoot checks that the host root, if set, is an existing directory on a
+// platform that supports it.
+func validateHostRoot(hostRoot string) error {
+	if hostRoot == "" {
+		return nil
+	}
+
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("host_root is not supported on %s", runtime.GOOS)
+	}
+
+	if !filepath.IsAbs(hostRoot) {
+		return fmt.Errorf("host_root %s must be an absolute path", hostRoot)
+	}
+
+	info, err := os.Stat(hostRoot)
+	if err != nil {
+		return fmt.Errorf("checking host_root: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("host_root %s is not a directory", hostRoot)
+	}
+
+	return nil
+}
+
 func shortUsage(flagset *flag.FlagSet) {
 	launcherFlags := map[string]string{}
 	flagAggregator := func(f *flag.Flag) {
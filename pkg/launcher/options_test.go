@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -243,6 +244,7 @@ func getArgsAndResponse() (map[string]string, *Options) {
 	opts := &Options{
 		AutoupdateInitialDelay:          1 * time.Hour,
 		AutoupdateInterval:              48 * time.Hour,
+		MaintenanceWindowDeadline:       72 * time.Hour,
 		CompactDbMaxTx:                  int64(65536),
 		Control:                         false,
 		ControlServerURL:                "",
@@ -265,6 +267,7 @@ func getArgsAndResponse() (map[string]string, *Options) {
 		WatchdogMemoryLimitMB:           600,
 		WatchdogUtilizationLimitPercent: 50,
 		Identifier:                      DefaultLauncherIdentifier,
+		MTLSClientKey:                   MTLSClientKeyLocal,
 	}
 
 	return args, opts
@@ -313,6 +316,26 @@ func TestSanitizeUpdateChannel(t *testing.T) {
 	}
 }
 
+func Test_validateHostRoot(t *testing.T) {
+	t.Parallel()
+
+	hostRoot := t.TempDir()
+	notADir := filepath.Join(hostRoot, "file")
+	require.NoError(t, os.WriteFile(notADir, nil, 0644))
+
+	require.NoError(t, validateHostRoot(""), "unset host root should be valid")
+
+	if runtime.GOOS != "linux" {
+		require.Error(t, validateHostRoot(hostRoot), "host root is only supported on Linux")
+		return
+	}
+
+	require.NoError(t, validateHostRoot(hostRoot))
+	require.Error(t, validateHostRoot("hostfs"), "relative host root should be invalid")
+	require.Error(t, validateHostRoot(filepath.Join(hostRoot, "does-not-exist")), "missing host root should be invalid")
+	require.Error(t, validateHostRoot(notADir), "host root that is not a directory should be invalid")
+}
+
 // windowsAddExe appends ".exe" to the input string when running on Windows
 func windowsAddExe(in string) string {
 	if runtime.GOOS == "windows" {
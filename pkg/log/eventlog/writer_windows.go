@@ -53,17 +53,23 @@ func (w *Writer) Close() error {
 }
 
 func (w *Writer) Write(p []byte) (n int, err error) {
-	ptr, err := syscall.UTF16PtrFromString(string(p))
-	if err != nil {
-		return 0, err
-	}
-	ss := []*uint16{ptr}
 	// always report as Info. Launcher logs as either info or debug, but the event log does not
 	// appear to have a debug level.
-	err = windows.ReportEvent(w.handle, windows.EVENTLOG_INFORMATION_TYPE, 0, 1, 0, 1, 0, &ss[0], nil)
+	err = w.WriteEvent(windows.EVENTLOG_INFORMATION_TYPE, 0, string(p))
 	return len(p), err
 }
 
+// WriteEvent reports msg to the event log with the given event type (e.g. windows.EVENTLOG_ERROR_TYPE)
+// and category.
+func (w *Writer) WriteEvent(eventType uint16, category uint16, msg string) error {
+	ptr, err := syscall.UTF16PtrFromString(msg)
+	if err != nil {
+		return err
+	}
+	ss := []*uint16{ptr}
+	return windows.ReportEvent(w.handle, eventType, category, 1, 0, 1, 0, &ss[0], nil)
+}
+
 func isAlreadyExists(err error) bool {
 	if err == nil {
 		return false
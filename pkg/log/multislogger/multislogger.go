@@ -4,7 +4,9 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"sync/atomic"
 
+	"github.com/kolide/launcher/pkg/log/redact"
 	slogmulti "github.com/samber/slog-multi"
 )
 
@@ -34,6 +36,7 @@ var ctxValueKeysToAdd = []contextKey{
 type MultiSlogger struct {
 	*slog.Logger
 	handlers []slog.Handler
+	redactor atomic.Pointer[redact.Redactor]
 }
 
 // New creates a new multislogger if no handlers are passed in, it will
@@ -65,10 +68,17 @@ func (m *MultiSlogger) AddHandler(handler ...slog.Handler) {
 		slogmulti.
 			Pipe(slogmulti.NewHandleInlineMiddleware(utcTimeMiddleware)).
 			Pipe(slogmulti.NewHandleInlineMiddleware(ctxValuesMiddleWare)).
+			Pipe(func(next slog.Handler) slog.Handler { return redact.NewHandler(next, m.redactor.Load) }).
 			Handler(slogmulti.Fanout(m.handlers...)),
 	)
 }
 
+// SetRedactor sets the redactor that masks sensitive values in logs before they reach any
+// handler. It applies to loggers already in use; a nil redactor turns redaction off.
+func (m *MultiSlogger) SetRedactor(r *redact.Redactor) {
+	m.redactor.Store(r)
+}
+
 func utcTimeMiddleware(ctx context.Context, record slog.Record, next func(context.Context, slog.Record) error) error {
 	record.Time = record.Time.UTC()
 	return next(ctx, record)
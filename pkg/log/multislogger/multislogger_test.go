@@ -10,6 +10,7 @@ import (
 	"log/slog"
 
 	"github.com/kolide/kit/ulid"
+	"github.com/kolide/launcher/pkg/log/redact"
 	"github.com/stretchr/testify/require"
 )
 
@@ -63,6 +64,20 @@ func TestMultiSlogger(t *testing.T) {
 	require.Contains(t, shipperBuf.String(), "info_with_interesting_ctx_value", "should now be in shipper log since it's new handler was set to debug level")
 	requireContainsAttribute(t, &shipperBuf, SpanIdKey.String(), spanId)
 	clearBufsFn()
+
+	// redaction applies to every handler, and to loggers already in use
+	withUser := multislogger.Logger.With("user", "alice@example.com")
+	redactor, errs := redact.New([]string{redact.ClassEmails})
+	require.Empty(t, errs)
+	multislogger.SetRedactor(redactor)
+	withUser.Log(context.TODO(), slog.LevelInfo, "enrolled bob@example.com")
+
+	for _, buf := range []*bytes.Buffer{&debugLogBuf, &shipperBuf} {
+		require.Contains(t, buf.String(), "enrolled [REDACTED:emails]")
+		require.NotContains(t, buf.String(), "example.com")
+		requireContainsAttribute(t, buf, "user", "[REDACTED:emails]")
+	}
+	clearBufsFn()
 }
 
 func requireContainsAttribute(t *testing.T, r io.Reader, key, value string) {
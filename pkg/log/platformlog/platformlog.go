@@ -0,0 +1,99 @@
+// Package platformlog provides a slog handler that mirrors logs into the platform's own logging
+// system -- the unified log on macOS, journald on Linux, and the Event Log on Windows -- so that
+// collectors already watching those pick up launcher's logs without tailing our files.
+//
+// Each record is written as a single JSON object, categorized by what produced it.
+package platformlog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Category identifies the source of the logs written through a Handler.
+type Category string
+
+const (
+	CategoryLauncher Category = "launcher"
+	CategoryOsquery  Category = "osquery"
+)
+
+// identifier is how the category's logs are tagged in syslog and journald
+func (c Category) identifier() string {
+	if c == CategoryLauncher {
+		return "launcher"
+	}
+	return "launcher-" + string(c)
+}
+
+// sink writes formatted records to the platform's logging system
+type sink interface {
+	write(level slog.Level, msg string) error
+	Close() error
+}
+
+// Handler is a slog.Handler that writes to the platform's logging system. It must be closed
+// when no longer in use.
+type Handler struct {
+	inner slog.Handler
+	w     *sinkWriter
+}
+
+// sinkWriter is the destination for the inner JSON handler. Since that only sees bytes, the
+// record's level is set on the writer before each record is handled.
+type sinkWriter struct {
+	sync.Mutex
+	sink  sink
+	level slog.Level
+}
+
+func (w *sinkWriter) Write(p []byte) (int, error) {
+	return len(p), w.sink.write(w.level, strings.TrimSuffix(string(p), "\n"))
+}
+
+// NewHandler opens the platform's logging system for logs of the given category, at or
+// above the given level.
+func NewHandler(category Category, level slog.Leveler) (*Handler, error) {
+	s, err := newSink(category)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHandler(s, level), nil
+}
+
+func newHandler(s sink, level slog.Leveler) *Handler {
+	w := &sinkWriter{sink: s}
+	return &Handler{
+		inner: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}),
+		w:     w,
+	}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	h.w.Lock()
+	defer h.w.Unlock()
+
+	h.w.level = r.Level
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{inner: h.inner.WithAttrs(attrs), w: h.w}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name), w: h.w}
+}
+
+// Close closes the connection to the platform's logging system, for this handler and any
+// derived from it.
+func (h *Handler) Close() error {
+	return h.w.sink.Close()
+}
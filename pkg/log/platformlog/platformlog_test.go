@@ -0,0 +1,64 @@
+package platformlog
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSink struct {
+	levels   []slog.Level
+	messages []string
+	closed   bool
+}
+
+func (s *testSink) write(level slog.Level, msg string) error {
+	s.levels = append(s.levels, level)
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	s := &testSink{}
+	h := newHandler(s, slog.LevelInfo)
+	slogger := slog.New(h).With("component", "test")
+
+	slogger.Log(context.TODO(), slog.LevelDebug, "below level")
+	slogger.Log(context.TODO(), slog.LevelInfo, "info message", "count", 2)
+	slogger.WithGroup("group").Log(context.TODO(), slog.LevelError, "error message", "err", "boom")
+
+	require.Equal(t, []slog.Level{slog.LevelInfo, slog.LevelError}, s.levels)
+	require.Len(t, s.messages, 2)
+
+	var info map[string]any
+	require.NoError(t, json.Unmarshal([]byte(s.messages[0]), &info))
+	require.Equal(t, "info message", info["msg"])
+	require.Equal(t, "test", info["component"])
+	require.Equal(t, float64(2), info["count"])
+	require.NotContains(t, s.messages[0], "\n")
+
+	var errLog map[string]any
+	require.NoError(t, json.Unmarshal([]byte(s.messages[1]), &errLog))
+	require.Equal(t, "error message", errLog["msg"])
+	require.Equal(t, map[string]any{"err": "boom"}, errLog["group"])
+
+	require.NoError(t, h.Close())
+	require.True(t, s.closed)
+}
+
+func TestCategoryIdentifier(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "launcher", CategoryLauncher.identifier())
+	require.Equal(t, "launcher-osquery", CategoryOsquery.identifier())
+}
@@ -0,0 +1,10 @@
+//go:build darwin
+// +build darwin
+
+package platformlog
+
+// newSink connects to syslog, which macOS routes into the unified log, tagged with the
+// category's identifier as the process.
+func newSink(category Category) (sink, error) {
+	return newSyslogSink(category)
+}
@@ -0,0 +1,82 @@
+//go:build linux
+// +build linux
+
+package platformlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const journalSocket = "/run/systemd/journal/socket"
+
+// newSink connects to journald if it's running, falling back to syslog otherwise.
+func newSink(category Category) (sink, error) {
+	if _, err := os.Stat(journalSocket); err != nil {
+		return newSyslogSink(category)
+	}
+
+	return newJournalSink(category, journalSocket)
+}
+
+// journalSink writes to journald using its native protocol, so that the category and
+// priority are kept as fields. See https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+type journalSink struct {
+	conn     *net.UnixConn
+	category Category
+}
+
+func newJournalSink(category Category, socketPath string) (*journalSink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to journald: %w", err)
+	}
+
+	return &journalSink{conn: conn, category: category}, nil
+}
+
+func (j *journalSink) write(level slog.Level, msg string) error {
+	if _, err := j.conn.Write(journalEntry(j.category, level, msg)); err != nil {
+		return fmt.Errorf("writing to journald: %w", err)
+	}
+	return nil
+}
+
+func (j *journalSink) Close() error {
+	return j.conn.Close()
+}
+
+// journalEntry serializes a log message as a journald native protocol datagram
+func journalEntry(category Category, level slog.Level, msg string) []byte {
+	fields := [][2]string{
+		{"MESSAGE", msg},
+		{"PRIORITY", strconv.Itoa(int(syslogPriority(level)))},
+		{"SYSLOG_IDENTIFIER", category.identifier()},
+		{"LAUNCHER_CATEGORY", string(category)},
+	}
+
+	var buf bytes.Buffer
+	for _, field := range fields {
+		buf.WriteString(field[0])
+		if !strings.Contains(field[1], "\n") {
+			buf.WriteByte('=')
+			buf.WriteString(field[1])
+			buf.WriteByte('\n')
+			continue
+		}
+
+		// Values containing newlines are written as a little-endian length, followed by the value
+		buf.WriteByte('\n')
+		binary.Write(&buf, binary.LittleEndian, uint64(len(field[1])))
+		buf.WriteString(field[1])
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
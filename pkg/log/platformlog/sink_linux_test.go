@@ -0,0 +1,47 @@
+//go:build linux
+// +build linux
+
+package platformlog
+
+import (
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalEntry(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t,
+		"MESSAGE={\"msg\":\"hello\"}\nPRIORITY=4\nSYSLOG_IDENTIFIER=launcher-osquery\nLAUNCHER_CATEGORY=osquery\n",
+		string(journalEntry(CategoryOsquery, slog.LevelWarn, `{"msg":"hello"}`)),
+	)
+
+	require.Equal(t,
+		"MESSAGE\n\x04\x00\x00\x00\x00\x00\x00\x00a\nb\n\nPRIORITY=7\nSYSLOG_IDENTIFIER=launcher\nLAUNCHER_CATEGORY=launcher\n",
+		string(journalEntry(CategoryLauncher, slog.LevelDebug, "a\nb\n")),
+	)
+}
+
+func TestJournalSink(t *testing.T) {
+	t.Parallel()
+
+	socketPath := filepath.Join(t.TempDir(), "journal.sock")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s, err := newJournalSink(CategoryLauncher, socketPath)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.write(slog.LevelError, "something failed"))
+
+	buf := make([]byte, 1024)
+	n, _, err := listener.ReadFromUnix(buf)
+	require.NoError(t, err)
+	require.Equal(t, string(journalEntry(CategoryLauncher, slog.LevelError, "something failed")), string(buf[:n]))
+}
@@ -0,0 +1,67 @@
+//go:build windows
+// +build windows
+
+package platformlog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/kolide/launcher/pkg/log/eventlog"
+	"golang.org/x/sys/windows"
+)
+
+// eventSource is shared with the system logger, so it's already registered on most installs
+const eventSource = "launcher"
+
+// eventCategories are reported as the event's category, since the source has no category
+// message file to name them
+var eventCategories = map[Category]uint16{
+	CategoryLauncher: 1,
+	CategoryOsquery:  2,
+}
+
+// eventLogSink writes to the Windows Event Log. Registering the event source requires
+// administrator privileges.
+type eventLogSink struct {
+	w        *eventlog.Writer
+	category uint16
+}
+
+func newSink(category Category) (sink, error) {
+	if !windows.GetCurrentProcessToken().IsElevated() {
+		return nil, errors.New("writing to the event log requires elevated permissions")
+	}
+
+	w, err := eventlog.NewWriter(eventSource)
+	if err == nil && w == nil {
+		// NewWriter returns no writer when it has only just installed the event source;
+		// now that it exists, try again.
+		w, err = eventlog.NewWriter(eventSource)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening event log: %w", err)
+	}
+	if w == nil {
+		return nil, errors.New("could not open event log")
+	}
+
+	return &eventLogSink{w: w, category: eventCategories[category]}, nil
+}
+
+func (e *eventLogSink) write(level slog.Level, msg string) error {
+	eventType := uint16(windows.EVENTLOG_INFORMATION_TYPE)
+	switch {
+	case level >= slog.LevelError:
+		eventType = windows.EVENTLOG_ERROR_TYPE
+	case level >= slog.LevelWarn:
+		eventType = windows.EVENTLOG_WARNING_TYPE
+	}
+
+	return e.w.WriteEvent(eventType, e.category, msg)
+}
+
+func (e *eventLogSink) Close() error {
+	return e.w.Close()
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+// +build !windows
+
+package platformlog
+
+import (
+	"fmt"
+	"log/slog"
+	"log/syslog"
+)
+
+// syslogSink writes to the local syslog daemon. On macOS, that's the unified log.
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+func newSyslogSink(category Category) (*syslogSink, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, category.identifier())
+	if err != nil {
+		return nil, fmt.Errorf("connecting to syslog: %w", err)
+	}
+
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) write(level slog.Level, msg string) error {
+	switch syslogPriority(level) {
+	case syslog.LOG_ERR:
+		return s.w.Err(msg)
+	case syslog.LOG_WARNING:
+		return s.w.Warning(msg)
+	case syslog.LOG_INFO:
+		return s.w.Info(msg)
+	default:
+		return s.w.Debug(msg)
+	}
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
+
+// syslogPriority maps slog levels onto syslog severities, which journald shares.
+func syslogPriority(level slog.Level) syslog.Priority {
+	switch {
+	case level >= slog.LevelError:
+		return syslog.LOG_ERR
+	case level >= slog.LevelWarn:
+		return syslog.LOG_WARNING
+	case level >= slog.LevelInfo:
+		return syslog.LOG_INFO
+	default:
+		return syslog.LOG_DEBUG
+	}
+}
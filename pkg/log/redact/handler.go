@@ -0,0 +1,112 @@
+package redact
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Handler masks log records, and the attributes loggers add with With, before passing them to
+// the next handler. The Redactor can change while loggers are in use, so it's looked up for
+// each record; attributes added with With are masked for the current Redactor when the logger
+// next handles a record.
+type Handler struct {
+	next    slog.Handler
+	current func() *Redactor
+
+	// ops are the attributes and groups added with WithAttrs and WithGroup, in order
+	ops     []handlerOp
+	derived atomic.Pointer[derivedHandler]
+}
+
+type handlerOp struct {
+	group string
+	attrs []slog.Attr
+}
+
+// derivedHandler is next with ops applied, masked for redactor.
+type derivedHandler struct {
+	redactor *Redactor
+	handler  slog.Handler
+}
+
+// NewHandler returns a Handler that masks records for the Redactor current returns.
+func NewHandler(next slog.Handler, current func() *Redactor) *Handler {
+	return &Handler{
+		next:    next,
+		current: current,
+	}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	r := h.current()
+	next := h.handlerFor(r)
+	if r == nil {
+		return next.Handle(ctx, record)
+	}
+
+	redacted := slog.NewRecord(record.Time, record.Level, r.String(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(r.Attr(a))
+		return true
+	})
+
+	return next.Handle(ctx, redacted)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(handlerOp{attrs: attrs})
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(handlerOp{group: name})
+}
+
+func (h *Handler) with(op handlerOp) *Handler {
+	ops := make([]handlerOp, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &Handler{
+		next:    h.next,
+		current: h.current,
+		ops:     append(ops, op),
+	}
+}
+
+// handlerFor returns next with the handler's attributes and groups applied, masked for r. It's
+// rebuilt only when r changes.
+func (h *Handler) handlerFor(r *Redactor) slog.Handler {
+	if len(h.ops) == 0 {
+		return h.next
+	}
+
+	if d := h.derived.Load(); d != nil && d.redactor == r {
+		return d.handler
+	}
+
+	handler := h.next
+	for _, op := range h.ops {
+		if op.group != "" {
+			handler = handler.WithGroup(op.group)
+			continue
+		}
+
+		attrs := make([]slog.Attr, len(op.attrs))
+		for i, a := range op.attrs {
+			attrs[i] = r.Attr(a)
+		}
+		handler = handler.WithAttrs(attrs)
+	}
+
+	h.derived.Store(&derivedHandler{redactor: r, handler: handler})
+	return handler
+}
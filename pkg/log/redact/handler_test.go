@@ -0,0 +1,51 @@
+package redact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	var current atomic.Pointer[Redactor]
+	var out bytes.Buffer
+	slogger := slog.New(NewHandler(slog.NewJSONHandler(&out, nil), current.Load))
+
+	lastRecord := func() map[string]any {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+		out.Reset()
+		return record
+	}
+
+	// Added before redaction is configured, so they must be masked when they're used
+	withAttrs := slogger.With("user", "alice@example.com").WithGroup("request").With("node_key", "abc123")
+
+	// Without a redactor, nothing is masked
+	withAttrs.Log(context.TODO(), slog.LevelInfo, "from 192.0.2.10", "email", "bob@example.com")
+	require.Equal(t, "from 192.0.2.10", lastRecord()["msg"])
+
+	r, errs := New([]string{ClassEmails, ClassIPs, ClassTokens})
+	require.Empty(t, errs)
+	current.Store(r)
+
+	withAttrs.Log(context.TODO(), slog.LevelInfo, "from 192.0.2.10", "email", "bob@example.com")
+	record := lastRecord()
+	require.Equal(t, "from [REDACTED:ips]", record["msg"])
+	require.Equal(t, "[REDACTED:emails]", record["user"])
+	require.Equal(t, map[string]any{"node_key": "[REDACTED:tokens]", "email": "[REDACTED:emails]"}, record["request"])
+
+	// Turning redaction off applies to loggers already in use
+	current.Store(nil)
+	withAttrs.Log(context.TODO(), slog.LevelInfo, "from 192.0.2.10")
+	record = lastRecord()
+	require.Equal(t, "from 192.0.2.10", record["msg"])
+	require.Equal(t, "alice@example.com", record["user"])
+}
@@ -0,0 +1,250 @@
+// Package redact masks potentially-sensitive values -- email addresses, IP addresses, serial
+// numbers, and secrets -- in launcher's logs and flares, before they leave the device. Which
+// classes of value are masked is configurable, so that each organization can choose what its
+// privacy review requires.
+package redact
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	ClassEmails        = "emails"
+	ClassIPs           = "ips"
+	ClassSerialNumbers = "serial_numbers"
+	ClassTokens        = "tokens"
+)
+
+// pattern finds values of a class in text. If group is set, only that submatch is masked,
+// leaving the context that identified it, e.g. the key of a key/value pair. If valid is set,
+// matches it rejects are left alone.
+type pattern struct {
+	re    *regexp.Regexp
+	group int
+	valid func(string) bool
+}
+
+// class is a kind of sensitive value.
+type class struct {
+	name        string
+	replacement string
+	patterns    []pattern
+
+	// keys matches the keys of log attributes whose values are always of this class, whatever
+	// they look like.
+	keys *regexp.Regexp
+}
+
+var classes = map[string]*class{
+	ClassEmails: {
+		name: ClassEmails,
+		patterns: []pattern{
+			{re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)},
+		},
+	},
+	ClassIPs: {
+		name: ClassIPs,
+		patterns: []pattern{
+			// IPv6 first, so that IPv4-mapped addresses are masked whole
+			{
+				re:    regexp.MustCompile(`(?:[0-9A-Fa-f]{0,4}:){2,7}(?:(?:\d{1,3}\.){3}\d{1,3}|[0-9A-Fa-f]{1,4})?(?:%[0-9A-Za-z_.\-]+)?`),
+				valid: maskableAddr,
+			},
+			{
+				re:    regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+				valid: maskableAddr,
+			},
+		},
+	},
+	ClassSerialNumbers: {
+		name: ClassSerialNumbers,
+		patterns: []pattern{
+			// Serial numbers have no common format, so we only recognize them by what labels them,
+			// e.g. `serial_number=C02XK0AAJG5J`, `"hardware_serial":"C02XK0AAJG5J"`, or
+			// `Serial Number (system): C02XK0AAJG5J`.
+			{
+				re:    regexp.MustCompile(`(?i)serial(?:[ _\-]?(?:number|num|no))?(?:\s*\([^)]*\))?["']?\s*[:=]\s*["']?([A-Za-z0-9][A-Za-z0-9\-]{3,})`),
+				group: 1,
+			},
+		},
+		keys: regexp.MustCompile(`(?i)serial`),
+	},
+	ClassTokens: {
+		name: ClassTokens,
+		patterns: []pattern{
+			// JWTs
+			{re: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)},
+			// Authorization headers
+			{re: regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+([A-Za-z0-9\-._~+/]+=*)`), group: 1},
+			// Values labelled as secrets, e.g. `password=hunter2` or `"node_key":"abc123"`
+			{
+				re:    regexp.MustCompile(`(?i)[\w\-]*(?:token|secret|password|passwd|api[_\-]?key|node_key|private_key)[\w\-]*["']?\s*[:=]\s*["']?([^\s"',&;]+)`),
+				group: 1,
+			},
+		},
+		keys: regexp.MustCompile(`(?i)token|secret|password|passwd|api[_\-]?key|node_key|private_key|authorization`),
+	},
+}
+
+func init() {
+	for _, c := range classes {
+		c.replacement = fmt.Sprintf("[REDACTED:%s]", c.name)
+	}
+}
+
+// Classes returns the names of the classes of value that can be redacted.
+func Classes() []string {
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// maskableAddr reports whether s is an IP address worth masking. Loopback and unspecified
+// addresses identify nothing, and are useful when debugging.
+func maskableAddr(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return false
+	}
+	return !addr.IsLoopback() && !addr.IsUnspecified()
+}
+
+// Redactor masks the values of its classes. A nil Redactor masks nothing.
+type Redactor struct {
+	classes []*class
+}
+
+// New returns a Redactor for the named classes, or nil if there are none. Unknown classes are
+// returned as errors, and skipped.
+func New(classNames []string) (*Redactor, []error) {
+	r := &Redactor{}
+	var errs []error
+
+	for _, name := range classNames {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		c, ok := classes[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown redaction class %q, expected one of %s", name, strings.Join(Classes(), ", ")))
+			continue
+		}
+		if !slices.Contains(r.classes, c) {
+			r.classes = append(r.classes, c)
+		}
+	}
+
+	if len(r.classes) == 0 {
+		return nil, errs
+	}
+
+	// Apply classes in a fixed order, so that configuration order doesn't change the output
+	slices.SortFunc(r.classes, func(a, b *class) int { return strings.Compare(a.name, b.name) })
+
+	return r, errs
+}
+
+// Classes returns the names of the classes r masks.
+func (r *Redactor) Classes() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, len(r.classes))
+	for i, c := range r.classes {
+		names[i] = c.name
+	}
+	return names
+}
+
+// String returns s with the values of r's classes masked.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, c := range r.classes {
+		for _, p := range c.patterns {
+			s = p.replaceAll(s, c.replacement)
+		}
+	}
+	return s
+}
+
+// Bytes returns b with the values of r's classes masked.
+func (r *Redactor) Bytes(b []byte) []byte {
+	if r == nil {
+		return b
+	}
+	return []byte(r.String(string(b)))
+}
+
+// Attr returns a with the values of r's classes masked. Attributes whose keys say they hold a
+// class's values, like `serial_number` or `enroll_secret`, are masked whole. Groups are masked
+// recursively; other values are masked as they would be formatted.
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	if r == nil {
+		return a
+	}
+
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		for _, c := range r.classes {
+			if c.keys != nil && c.keys.MatchString(a.Key) {
+				return slog.String(a.Key, c.replacement)
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.String(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]any, len(group))
+		for i, ga := range group {
+			redacted[i] = r.Attr(ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny:
+		// Only replace values that need masking, so that the rest keep their structure when the
+		// handler formats them.
+		formatted := fmt.Sprintf("%+v", v.Any())
+		if redacted := r.String(formatted); redacted != formatted {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+func (p pattern) replaceAll(s string, replacement string) string {
+	matches := p.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*p.group], m[2*p.group+1]
+		if start < 0 || (p.valid != nil && !p.valid(s[start:end])) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(replacement)
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	r, errs := New(nil)
+	require.Nil(t, r)
+	require.Empty(t, errs)
+
+	r, errs = New([]string{"tokens", " IPs ", "", "tokens", "phone_numbers"})
+	require.Equal(t, []string{ClassIPs, ClassTokens}, r.Classes())
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), "phone_numbers")
+
+	r, errs = New([]string{"phone_numbers"})
+	require.Nil(t, r, "a redactor without classes should be nil")
+	require.Len(t, errs, 1)
+
+	// A nil redactor masks nothing
+	require.Equal(t, "alice@example.com", r.String("alice@example.com"))
+	require.Nil(t, r.Classes())
+}
+
+func TestString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		class    string
+		input    string
+		expected string
+	}{
+		{
+			name:     "email",
+			class:    ClassEmails,
+			input:    `enrolled alice.smith+test@corp.example.co.uk and bob@example.com`,
+			expected: `enrolled [REDACTED:emails] and [REDACTED:emails]`,
+		},
+		{
+			name:     "not an email",
+			class:    ClassEmails,
+			input:    `pkg@v1.2.3 user@localhost`,
+			expected: `pkg@v1.2.3 user@localhost`,
+		},
+		{
+			name:     "ipv4",
+			class:    ClassIPs,
+			input:    `dial tcp 192.0.2.10:443: connect refused, via 10.0.0.1`,
+			expected: `dial tcp [REDACTED:ips]:443: connect refused, via [REDACTED:ips]`,
+		},
+		{
+			name:     "ipv6",
+			class:    ClassIPs,
+			input:    `dial tcp [2001:db8::10]:443 from fe80::1%en0 and ::ffff:192.0.2.1`,
+			expected: `dial tcp [[REDACTED:ips]]:443 from [REDACTED:ips] and [REDACTED:ips]`,
+		},
+		{
+			name:     "not ips",
+			class:    ClassIPs,
+			input:    `at 09:00:00.000 from 127.0.0.1 and ::1, version 1.2.3, mac aa:bb:cc:dd:ee:ff, bind 0.0.0.0, 999.1.1.1`,
+			expected: `at 09:00:00.000 from 127.0.0.1 and ::1, version 1.2.3, mac aa:bb:cc:dd:ee:ff, bind 0.0.0.0, 999.1.1.1`,
+		},
+		{
+			name:     "serial numbers",
+			class:    ClassSerialNumbers,
+			input:    `serial_number=C02XK0AAJG5J {"hardware_serial":"VMware-564d3a"} Serial Number (system): FVFXC2ABHV29 serial no: ab12`,
+			expected: `serial_number=[REDACTED:serial_numbers] {"hardware_serial":"[REDACTED:serial_numbers]"} Serial Number (system): [REDACTED:serial_numbers] serial no: [REDACTED:serial_numbers]`,
+		},
+		{
+			name:     "not serial numbers",
+			class:    ClassSerialNumbers,
+			input:    `serial port ready, serialized 12 rows, serial_number=`,
+			expected: `serial port ready, serialized 12 rows, serial_number=`,
+		},
+		{
+			name:     "tokens",
+			class:    ClassTokens,
+			input:    `Authorization: Bearer abc.DEF-123/xyz== token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig_-1 url ?enroll_secret=s3cr3t&x=1 {"node_key":"nk1","password": "hunter2"}`,
+			expected: `Authorization: Bearer [REDACTED:tokens] token [REDACTED:tokens] url ?enroll_secret=[REDACTED:tokens]&x=1 {"node_key":"[REDACTED:tokens]","password": "[REDACTED:tokens]"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, errs := New([]string{tt.class})
+			require.Empty(t, errs)
+			require.Equal(t, tt.expected, r.String(tt.input))
+
+			// Masking is idempotent
+			require.Equal(t, tt.expected, r.String(tt.expected))
+		})
+	}
+}
+
+func TestAttr(t *testing.T) {
+	t.Parallel()
+
+	r, errs := New(Classes())
+	require.Empty(t, errs)
+
+	require.Equal(t, slog.String("msg", "from [REDACTED:ips]"), r.Attr(slog.String("msg", "from 192.0.2.10")))
+
+	// Keys that say what a value is mask it whole, whatever it looks like
+	require.Equal(t, slog.String("enroll_secret", "[REDACTED:tokens]"), r.Attr(slog.String("enroll_secret", "abc")))
+	require.Equal(t, slog.String("SerialNumber", "[REDACTED:serial_numbers]"), r.Attr(slog.Int("SerialNumber", 12345)))
+
+	// Other values are left as they are, unless they need masking
+	require.Equal(t, slog.Int("count", 3), r.Attr(slog.Int("count", 3)))
+	require.Equal(t, slog.Any("tables", []string{"a", "b"}), r.Attr(slog.Any("tables", []string{"a", "b"})))
+	require.Equal(t, slog.String("err", "user [REDACTED:emails] not found"), r.Attr(slog.Any("err", errors.New("user bob@example.com not found"))))
+	require.Equal(t,
+		slog.String("hosts", "[[REDACTED:ips] [REDACTED:ips]]"),
+		r.Attr(slog.Any("hosts", []string{"192.0.2.1", "2001:db8::1"})),
+	)
+
+	// Groups are masked recursively
+	require.Equal(t,
+		slog.Group("device", slog.String("owner", "[REDACTED:emails]"), slog.String("serial", "[REDACTED:serial_numbers]"), slog.Bool("enrolled", true)),
+		r.Attr(slog.Group("device", slog.String("owner", "alice@example.com"), slog.String("serial", "C02XK0AAJG5J"), slog.Bool("enrolled", true))),
+	)
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	r, errs := New([]string{ClassEmails})
+	require.Empty(t, errs)
+
+	var out bytes.Buffer
+	w := NewWriter(&out, r)
+
+	// Values split between writes are still masked
+	for _, chunk := range []string{"first alice@exa", "mple.com\nsecond bob@", "example.com"} {
+		n, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+		require.Equal(t, len(chunk), n)
+	}
+	require.Equal(t, "first [REDACTED:emails]\n", out.String(), "incomplete lines should be buffered")
+
+	require.NoError(t, w.Flush())
+	require.Equal(t, "first [REDACTED:emails]\nsecond [REDACTED:emails]", out.String())
+
+	// A nil redactor writes through
+	out.Reset()
+	w = NewWriter(&out, nil)
+	_, err := w.Write([]byte("alice@example.com"))
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.com", out.String())
+	require.NoError(t, w.Flush())
+}
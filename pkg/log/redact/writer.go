@@ -0,0 +1,60 @@
+package redact
+
+import (
+	"bytes"
+	"io"
+)
+
+// maxLineBytes bounds how much of a line Writer buffers. Longer lines are masked in pieces,
+// which may miss values split between them.
+const maxLineBytes = 1024 * 1024
+
+// Writer masks what's written to it a line at a time, so that values aren't split between
+// writes, before writing it to the underlying writer. Flush writes any incomplete last line.
+type Writer struct {
+	w        io.Writer
+	redactor *Redactor
+	buf      []byte
+}
+
+// NewWriter returns a Writer that masks what's written to it with r, then writes it to w.
+func NewWriter(w io.Writer, r *Redactor) *Writer {
+	return &Writer{
+		w:        w,
+		redactor: r,
+	}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.redactor == nil {
+		return w.w.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+
+	end := bytes.LastIndexByte(w.buf, '\n') + 1
+	if end == 0 && len(w.buf) >= maxLineBytes {
+		end = len(w.buf)
+	}
+	if end == 0 {
+		return len(p), nil
+	}
+
+	if _, err := w.w.Write(w.redactor.Bytes(w.buf[:end])); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf[:0], w.buf[end:]...)
+
+	return len(p), nil
+}
+
+// Flush masks and writes anything buffered.
+func (w *Writer) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := w.w.Write(w.redactor.Bytes(w.buf))
+	w.buf = w.buf[:0]
+	return err
+}
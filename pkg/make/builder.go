@@ -40,6 +40,7 @@ type Builder struct {
 	race               bool
 	stampVersion       bool
 	fakedata           bool
+	minimal            bool
 	notStripped        bool
 	cgo                bool
 	githubActionOutput bool
@@ -104,6 +105,13 @@ func WithFakeData() Option {
 	}
 }
 
+// WithMinimal builds the minimal profile, which excludes the desktop UI and the autoupdater.
+func WithMinimal() Option {
+	return func(b *Builder) {
+		b.minimal = true
+	}
+}
+
 func WithGithubActionOutput() Option {
 	return func(b *Builder) {
 		b.githubActionOutput = true
@@ -286,8 +294,15 @@ func (b *Builder) BuildCmd(src, appName string) func(context.Context) error {
 			baseArgs = append(baseArgs, "-race")
 		}
 
+		var tags []string
 		if b.fakedata {
-			baseArgs = append(baseArgs, "-tags", "fakeserial")
+			tags = append(tags, "fakeserial")
+		}
+		if b.minimal {
+			tags = append(tags, "minimal")
+		}
+		if len(tags) > 0 {
+			baseArgs = append(baseArgs, "-tags", strings.Join(tags, ","))
 		}
 
 		var ldFlags []string
@@ -322,6 +337,9 @@ func (b *Builder) BuildCmd(src, appName string) func(context.Context) error {
 			if b.fakedata {
 				v = fmt.Sprintf("%s-fakedata", v)
 			}
+			if b.minimal {
+				v = fmt.Sprintf("%s-minimal", v)
+			}
 
 			branch, err := b.execOut(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD")
 			if err != nil {
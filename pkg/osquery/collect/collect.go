@@ -0,0 +1,271 @@
+// Package collect runs a set of named queries against a transient osqueryd process, with
+// launcher's tables loaded, and returns the results. It does not enroll, and leaves nothing
+// running afterwards -- it is meant for CI and imaging validation, where a persistent agent
+// is not wanted.
+package collect
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/kolide/kit/fsutil"
+	"github.com/kolide/kit/ulid"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/gowrapper"
+	"github.com/kolide/launcher/pkg/augeas"
+	osqueryRuntime "github.com/kolide/launcher/pkg/osquery/runtime"
+	"github.com/kolide/launcher/pkg/osquery/table"
+	osquery "github.com/osquery/osquery-go"
+)
+
+const (
+	extensionName = "com.kolide.launcher_collect"
+
+	// socketWaitTime is how long we wait for osqueryd to create its extension socket
+	socketWaitTime = 30 * time.Second
+)
+
+// Queries is the input to collect: a map from query name to SQL.
+type Queries struct {
+	Queries map[string]string `json:"queries"`
+}
+
+// Results is the output of collect: the rows returned by each query, and the error
+// for any query that failed.
+type Results struct {
+	Results map[string][]map[string]string `json:"results"`
+	Errors  map[string]string              `json:"errors,omitempty"`
+}
+
+// ReadQueries reads named queries, e.g.
+// `{"queries": {"hostname": "select hostname from system_info"}}`.
+func ReadQueries(r io.Reader) (map[string]string, error) {
+	var queries Queries
+	if err := json.NewDecoder(r).Decode(&queries); err != nil {
+		return nil, fmt.Errorf("decoding queries: %w", err)
+	}
+
+	if len(queries.Queries) == 0 {
+		return nil, errors.New("no queries provided")
+	}
+
+	return queries.Queries, nil
+}
+
+// WriteResults writes the results as indented JSON.
+func WriteResults(w io.Writer, results *Results) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(results); err != nil {
+		return fmt.Errorf("encoding results: %w", err)
+	}
+
+	return nil
+}
+
+// WriteCSV writes rows as CSV. osquery doesn't return columns in any particular order, so the
+// header lists every column in any row, in alphabetical order.
+func WriteCSV(w io.Writer, rows []map[string]string) error {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(columns); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = row[column]
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return fmt.Errorf("writing row: %w", err)
+		}
+	}
+	csvWriter.Flush()
+
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("flushing csv: %w", err)
+	}
+	return nil
+}
+
+// Run starts osqueryd in rootDir, runs the given named queries, and shuts osqueryd down again.
+// A query that fails does not stop the others from running; its error is recorded in the results.
+func Run(ctx context.Context, knapsack types.Knapsack, rootDir string, queries map[string]string) (*Results, error) {
+	slogger := knapsack.Slogger().With("component", "collect")
+
+	if err := os.MkdirAll(rootDir, fsutil.DirMode); err != nil {
+		return nil, fmt.Errorf("creating root dir for collect mode: %w", err)
+	}
+
+	// We need a shorter ulid to avoid running into socket path length issues.
+	socketId := ulid.New()
+	socketPath := osqueryRuntime.SocketPath(rootDir, socketId[len(socketId)-4:])
+
+	osqueryFlags, err := buildOsqueryFlags(rootDir, socketPath, knapsack.OsqueryFlags())
+	if err != nil {
+		return nil, fmt.Errorf("building osqueryd flags: %w", err)
+	}
+
+	proc, err := os.StartProcess(knapsack.OsquerydPath(), append([]string{knapsack.OsquerydPath()}, osqueryFlags...), &os.ProcAttr{
+		Files: []*os.File{nil, nil, os.Stderr},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting osqueryd: %w", err)
+	}
+	defer func() {
+		if err := proc.Kill(); err != nil {
+			slogger.Log(ctx, slog.LevelDebug,
+				"could not kill osqueryd",
+				"err", err,
+			)
+		}
+		_, _ = proc.Wait()
+	}()
+
+	// osquery.NewClient waits for osqueryd to create the socket
+	client, err := osquery.NewClient(socketPath, socketWaitTime, osquery.MaxWaitTime(socketWaitTime))
+	if err != nil {
+		return nil, fmt.Errorf("creating osquery client: %w", err)
+	}
+	defer client.Close()
+
+	extensionServer, err := osquery.NewExtensionManagerServer(
+		extensionName,
+		socketPath,
+		osquery.ServerTimeout(socketWaitTime),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating extension manager server: %w", err)
+	}
+	extensionServer.RegisterPlugin(table.PlatformTables(knapsack, types.DefaultRegistrationID, slogger, knapsack.OsquerydPath())...)
+
+	// Start blocks while serving, so run it in the background, and wait for the extension
+	// to be registered before running any queries.
+	gowrapper.Go(ctx, slogger, func() {
+		if err := extensionServer.Start(); err != nil {
+			slogger.Log(ctx, slog.LevelDebug,
+				"extension manager server exited",
+				"err", err,
+			)
+		}
+	})
+	defer extensionServer.Shutdown(context.Background())
+
+	if err := waitForExtension(ctx, client); err != nil {
+		return nil, fmt.Errorf("waiting for extension to register: %w", err)
+	}
+
+	results := &Results{
+		Results: make(map[string][]map[string]string),
+		Errors:  make(map[string]string),
+	}
+	for name, query := range queries {
+		rows, err := client.QueryRowsContext(ctx, query)
+		if err != nil {
+			slogger.Log(ctx, slog.LevelInfo,
+				"query failed",
+				"query_name", name,
+				"err", err,
+			)
+			results.Errors[name] = err.Error()
+			continue
+		}
+		if rows == nil {
+			rows = []map[string]string{}
+		}
+		results.Results[name] = rows
+	}
+
+	return results, nil
+}
+
+// waitForExtension waits until our extension is registered with osqueryd, so that its tables
+// are available.
+func waitForExtension(ctx context.Context, client *osquery.ExtensionManagerClient) error {
+	ctx, cancel := context.WithTimeout(ctx, socketWaitTime)
+	defer cancel()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		extensions, err := client.ExtensionsContext(ctx)
+		if err == nil {
+			for _, extension := range extensions {
+				if extension.Name == extensionName {
+					return nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("extension %s not registered: %w", extensionName, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func buildOsqueryFlags(rootDir, socketPath string, osqueryFlags []string) ([]string, error) {
+	// osqueryd requires a config; we don't want any scheduled queries, so give it an empty one.
+	configPath := filepath.Join(rootDir, "osquery.conf")
+	if err := os.WriteFile(configPath, []byte("{}"), 0600); err != nil {
+		return nil, fmt.Errorf("writing empty config: %w", err)
+	}
+
+	flags := []string{
+		"--config_plugin=filesystem",
+		fmt.Sprintf("--config_path=%s", configPath),
+		fmt.Sprintf("--pidfile=%s", filepath.Join(rootDir, "osquery.pid")),
+		fmt.Sprintf("--database_path=%s", filepath.Join(rootDir, "osquery.db")),
+		"--disable_database",
+		"--disable_logging",
+		"--disable_events",
+		"--disable_distributed",
+		"--disable_watchdog",
+		"--utc",
+		"--disable_extensions=false",
+		"--extensions_timeout=20",
+		fmt.Sprintf("--extensions_socket=%s", socketPath),
+	}
+
+	// only install augeas lenses on non-windows platforms
+	if runtime.GOOS != "windows" {
+		augeasLensesPath := filepath.Join(rootDir, "augeas-lenses")
+		if err := os.MkdirAll(augeasLensesPath, fsutil.DirMode); err != nil {
+			return nil, fmt.Errorf("creating augeas lens dir: %w", err)
+		}
+		if err := augeas.InstallLenses(augeasLensesPath); err != nil {
+			return nil, fmt.Errorf("installing augeas lenses: %w", err)
+		}
+		flags = append(flags, fmt.Sprintf("--augeas_lenses=%s", augeasLensesPath))
+	}
+
+	// Caller-provided flags go last, so that they take precedence
+	for _, flag := range osqueryFlags {
+		flags = append(flags, fmt.Sprintf("--%s", flag))
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,81 @@
+package collect
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadQueries(t *testing.T) {
+	t.Parallel()
+
+	queries, err := ReadQueries(strings.NewReader(`{"queries": {"time": "select * from time", "info": "select * from osquery_info"}}`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"time": "select * from time", "info": "select * from osquery_info"}, queries)
+
+	_, err = ReadQueries(strings.NewReader(`{"queries": {}}`))
+	require.Error(t, err, "no queries should be an error")
+
+	_, err = ReadQueries(strings.NewReader(`{"time": "select * from time"}`))
+	require.Error(t, err, "queries must be nested under the queries key")
+
+	_, err = ReadQueries(strings.NewReader(`["select * from time"]`))
+	require.Error(t, err, "queries must be named")
+}
+
+func TestWriteResults(t *testing.T) {
+	t.Parallel()
+
+	results := &Results{
+		Results: map[string][]map[string]string{
+			"info":  {{"version": "5.12.1"}},
+			"empty": {},
+		},
+		Errors: map[string]string{
+			"missing": "no such table: not_a_table",
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteResults(&buf, results))
+
+	var decoded Results
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, results, &decoded)
+}
+
+func TestWriteCSV(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, []map[string]string{
+		{"name": "Google Docs", "identifier": "ghbmnnjooekpmoecnnnilnnbdlolhkhi"},
+		{"name": "Quoted, \"name\"", "identifier": "abc", "version": "1.0"},
+	}))
+	require.Equal(t, "identifier,name,version\nghbmnnjooekpmoecnnnilnnbdlolhkhi,Google Docs,\nabc,\"Quoted, \"\"name\"\"\",1.0\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteCSV(&buf, nil))
+	require.Equal(t, "\n", buf.String())
+}
+
+func TestBuildOsqueryFlags(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	socketPath := filepath.Join(rootDir, "osquery.sock")
+
+	flags, err := buildOsqueryFlags(rootDir, socketPath, []string{"verbose", "disable_events=false"})
+	require.NoError(t, err)
+
+	require.Contains(t, flags, "--extensions_socket="+socketPath)
+	require.Contains(t, flags, "--disable_database")
+	require.FileExists(t, filepath.Join(rootDir, "osquery.conf"))
+
+	// Caller-provided flags come last, so they take precedence
+	require.Equal(t, []string{"--verbose", "--disable_events=false"}, flags[len(flags)-2:])
+}
@@ -0,0 +1,464 @@
+// Package configmerge merges osquery config fragments from several sources -- the osquery
+// server for each registration, and the config launcher adds itself, like FIM paths -- into a
+// single config. osquery would merge them itself, but the last source it reads wins any
+// conflict. Here, the merge is deterministic: fragments are given in precedence order, some
+// options are resolved by rule (e.g. the shortest interval any source asks for), and every
+// conflict is reported so the control server can tell when sources disagree.
+package configmerge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/kolide/launcher/ee/gowrapper"
+)
+
+// Rules for resolving conflicting values
+const (
+	RulePrecedence = "precedence" // the value from the highest precedence source
+	RuleMin        = "min"        // the smallest value
+	RuleMax        = "max"        // the largest value
+	RuleAnyTrue    = "any_true"   // true if any source sets it true
+	RuleAllTrue    = "all_true"   // true only if every source that sets it sets it true
+)
+
+// optionRules are the rules for options that aren't resolved by precedence.
+var optionRules = map[string]string{
+	// Check in as often as any source needs to
+	"config_refresh":             RuleMin,
+	"config_accelerated_refresh": RuleMin,
+	"distributed_interval":       RuleMin,
+	"logger_tls_period":          RuleMin,
+	// Don't let one source's tight limits kill queries another source needs
+	"watchdog_memory_limit":      RuleMax,
+	"watchdog_utilization_limit": RuleMax,
+	"watchdog_delay":             RuleMax,
+	// Events are collected if any source needs them
+	"enable_file_events": RuleAnyTrue,
+	"disable_events":     RuleAllTrue,
+}
+
+// Sections of the config whose entries are merged by name, with conflicting entries resolved
+// by precedence.
+var namedSections = []string{"schedule", "packs", "auto_table_construction"}
+
+// Sections of the config whose lists are combined, keyed by category.
+var unionSections = []string{"file_paths", "exclude_paths", "decorators"}
+
+// Fragment is the osquery config from one source.
+type Fragment struct {
+	Source string
+	Config string
+}
+
+// Conflict is a config key that more than one source set, to different values.
+type Conflict struct {
+	Section string   `json:"section"` // empty for top-level keys
+	Key     string   `json:"key"`
+	Rule    string   `json:"rule"`
+	Winner  string   `json:"winner"`  // the source whose value was used
+	Sources []string `json:"sources"` // every source that set the key, in precedence order
+}
+
+// Report is the result of merging the config for one registration.
+type Report struct {
+	RegistrationID string     `json:"registration_id"`
+	Sources        []string   `json:"sources"`
+	Conflicts      []Conflict `json:"conflicts"`
+}
+
+// Merge merges the fragments, which are in precedence order, highest first. Fragments that
+// aren't valid JSON are skipped, and reported as errors. A single fragment is returned as-is.
+func Merge(fragments []Fragment) (string, []Conflict, []error) {
+	var errs []error
+	parsed := make([]parsedFragment, 0, len(fragments))
+	for _, f := range fragments {
+		cfg, err := decode(f.Config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("decoding config from %s: %w", f.Source, err))
+			continue
+		}
+		parsed = append(parsed, parsedFragment{source: f.Source, raw: f.Config, cfg: cfg})
+	}
+
+	switch len(parsed) {
+	case 0:
+		return "{}", nil, errs
+	case 1:
+		return parsed[0].raw, nil, errs
+	}
+
+	m := &merger{merged: make(map[string]any)}
+	for _, key := range topLevelKeys(parsed) {
+		switch {
+		case key == "options":
+			m.mergeOptions(parsed)
+		case contains(namedSections, key):
+			m.mergeNamed(key, parsed)
+		case contains(unionSections, key):
+			m.mergeUnion(key, parsed)
+		default:
+			m.mergeValue(key, RulePrecedence, valuesOf(parsed, func(cfg map[string]any) (any, bool) {
+				v, ok := cfg[key]
+				return v, ok
+			}))
+		}
+	}
+
+	out, err := json.Marshal(m.merged)
+	if err != nil {
+		return "", nil, append(errs, fmt.Errorf("marshalling merged config: %w", err))
+	}
+
+	sort.Slice(m.conflicts, func(i, j int) bool {
+		if m.conflicts[i].Section != m.conflicts[j].Section {
+			return m.conflicts[i].Section < m.conflicts[j].Section
+		}
+		return m.conflicts[i].Key < m.conflicts[j].Key
+	})
+
+	return string(out), m.conflicts, errs
+}
+
+type parsedFragment struct {
+	source string
+	raw    string
+	cfg    map[string]any
+}
+
+// sourcedValue is a value, and the source that set it.
+type sourcedValue struct {
+	source string
+	value  any
+}
+
+type merger struct {
+	merged    map[string]any
+	conflicts []Conflict
+}
+
+func (m *merger) mergeOptions(fragments []parsedFragment) {
+	options := make(map[string]any)
+	m.merged["options"] = options
+
+	for _, key := range sectionKeys("options", fragments) {
+		rule, ok := optionRules[key]
+		if !ok {
+			rule = RulePrecedence
+		}
+
+		values := valuesOf(fragments, func(cfg map[string]any) (any, bool) {
+			section, _ := cfg["options"].(map[string]any)
+			v, ok := section[key]
+			return v, ok
+		})
+		if v, ok := m.resolve("options", key, rule, values); ok {
+			options[key] = v
+		}
+	}
+}
+
+// mergeNamed merges sections of named entries, like the schedule.
+func (m *merger) mergeNamed(section string, fragments []parsedFragment) {
+	merged := make(map[string]any)
+	m.merged[section] = merged
+
+	for _, key := range sectionKeys(section, fragments) {
+		values := valuesOf(fragments, func(cfg map[string]any) (any, bool) {
+			s, _ := cfg[section].(map[string]any)
+			v, ok := s[key]
+			return v, ok
+		})
+		if v, ok := m.resolve(section, key, RulePrecedence, values); ok {
+			merged[key] = v
+		}
+	}
+}
+
+// mergeUnion combines sections of lists keyed by category, like file_paths. Categories may
+// nest, as decorators' intervals do.
+func (m *merger) mergeUnion(section string, fragments []parsedFragment) {
+	merged := make(map[string]any)
+	m.merged[section] = merged
+
+	for _, key := range sectionKeys(section, fragments) {
+		values := valuesOf(fragments, func(cfg map[string]any) (any, bool) {
+			s, _ := cfg[section].(map[string]any)
+			v, ok := s[key]
+			return v, ok
+		})
+
+		if combined, ok := union(values); ok {
+			merged[key] = combined
+			continue
+		}
+
+		// Anything that can't be combined is resolved by precedence instead
+		if v, ok := m.resolve(section, key, RulePrecedence, values); ok {
+			merged[key] = v
+		}
+	}
+}
+
+// union combines lists, without duplicates, or maps of lists. It returns false if the values
+// aren't all lists, or all maps of lists.
+func union(values []sourcedValue) (any, bool) {
+	switch values[0].value.(type) {
+	case []any:
+		combined := make([]any, 0)
+		for _, v := range values {
+			list, ok := v.value.([]any)
+			if !ok {
+				return nil, false
+			}
+			for _, item := range list {
+				if !containsValue(combined, item) {
+					combined = append(combined, item)
+				}
+			}
+		}
+		return combined, true
+	case map[string]any:
+		byKey := make(map[string][]sourcedValue)
+		for _, v := range values {
+			entries, ok := v.value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			for k, entry := range entries {
+				byKey[k] = append(byKey[k], sourcedValue{source: v.source, value: entry})
+			}
+		}
+		combined := make(map[string]any, len(byKey))
+		for k, entries := range byKey {
+			c, ok := union(entries)
+			if !ok {
+				return nil, false
+			}
+			combined[k] = c
+		}
+		return combined, true
+	}
+	return nil, false
+}
+
+func (m *merger) mergeValue(key, rule string, values []sourcedValue) {
+	if v, ok := m.resolve("", key, rule, values); ok {
+		m.merged[key] = v
+	}
+}
+
+// resolve picks the value for a key from the values the sources set, recording a conflict if
+// they disagree.
+func (m *merger) resolve(section, key, rule string, values []sourcedValue) (any, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	winner := values[0]
+	agree := true
+	for _, v := range values[1:] {
+		if !reflect.DeepEqual(v.value, winner.value) {
+			agree = false
+			break
+		}
+	}
+	if agree {
+		return winner.value, true
+	}
+
+	switch rule {
+	case RuleMin, RuleMax:
+		if w, ok := pickNumber(values, rule == RuleMin); ok {
+			winner = w
+		} else {
+			rule = RulePrecedence
+		}
+	case RuleAnyTrue, RuleAllTrue:
+		if w, ok := pickBool(values, rule == RuleAnyTrue); ok {
+			winner = w
+		} else {
+			rule = RulePrecedence
+		}
+	}
+
+	sources := make([]string, 0, len(values))
+	for _, v := range values {
+		sources = append(sources, v.source)
+	}
+	m.conflicts = append(m.conflicts, Conflict{
+		Section: section,
+		Key:     key,
+		Rule:    rule,
+		Winner:  winner.source,
+		Sources: sources,
+	})
+
+	return winner.value, true
+}
+
+// pickNumber returns the smallest or largest value. Ties go to the higher precedence source.
+// It returns false if any value isn't a number.
+func pickNumber(values []sourcedValue, smallest bool) (sourcedValue, bool) {
+	var best sourcedValue
+	var bestN float64
+	for i, v := range values {
+		n, ok := toNumber(v.value)
+		if !ok {
+			return sourcedValue{}, false
+		}
+		if i == 0 || (smallest && n < bestN) || (!smallest && n > bestN) {
+			best, bestN = v, n
+		}
+	}
+	return best, true
+}
+
+// pickBool returns the first true value if any should win, or the first false value if all
+// must agree. It returns false if any value isn't a boolean.
+func pickBool(values []sourcedValue, anyTrue bool) (sourcedValue, bool) {
+	picked := -1
+	for i, v := range values {
+		b, ok := toBool(v.value)
+		if !ok {
+			return sourcedValue{}, false
+		}
+		if b == anyTrue && picked < 0 {
+			picked = i
+		}
+	}
+	if picked < 0 {
+		return values[0], true
+	}
+	return values[picked], true
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toBool(v any) (bool, bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		parsed, err := strconv.ParseBool(b)
+		return parsed, err == nil
+	}
+	return false, false
+}
+
+func decode(config string) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(config)))
+	dec.UseNumber()
+
+	var cfg map[string]any
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = make(map[string]any)
+	}
+	return cfg, nil
+}
+
+func valuesOf(fragments []parsedFragment, get func(map[string]any) (any, bool)) []sourcedValue {
+	var values []sourcedValue
+	for _, f := range fragments {
+		if v, ok := get(f.cfg); ok {
+			values = append(values, sourcedValue{source: f.source, value: v})
+		}
+	}
+	return values
+}
+
+func topLevelKeys(fragments []parsedFragment) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, f := range fragments {
+		for k := range f.cfg {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sectionKeys(section string, fragments []parsedFragment) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, f := range fragments {
+		s, _ := f.cfg[section].(map[string]any)
+		for k := range s {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(list []any, v any) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+var subscribers = struct {
+	sync.Mutex
+	funcs []func(Report)
+}{}
+
+// Subscribe registers f to be called with each new conflict report. f is called in its own
+// goroutine.
+func Subscribe(f func(Report)) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	subscribers.funcs = append(subscribers.funcs, f)
+}
+
+// Publish sends the report to subscribers.
+func Publish(ctx context.Context, slogger *slog.Logger, report Report) {
+	subscribers.Lock()
+	funcs := make([]func(Report), len(subscribers.funcs))
+	copy(funcs, subscribers.funcs)
+	subscribers.Unlock()
+
+	for _, f := range funcs {
+		gowrapper.Go(ctx, slogger, func() {
+			f(report)
+		})
+	}
+}
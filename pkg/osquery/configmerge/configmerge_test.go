@@ -0,0 +1,150 @@
+package configmerge
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	fragments := []Fragment{
+		{
+			Source: "default",
+			Config: `{
+				"options": {"distributed_interval": 60, "config_refresh": 300, "verbose": false, "disable_events": true, "watchdog_memory_limit": 350},
+				"schedule": {
+					"time": {"query": "select * from time", "interval": 60},
+					"users": {"query": "select * from users", "interval": 3600}
+				},
+				"packs": {"shared": {"queries": {"os": {"query": "select * from os_version", "interval": 60}}}},
+				"decorators": {"load": ["select uuid from system_info"], "interval": {"3600": ["select version from osquery_info"]}},
+				"node_invalid": false
+			}`,
+		},
+		{
+			Source: "tenant_b",
+			Config: `{
+				"options": {"distributed_interval": 10, "config_refresh": "600", "verbose": true, "disable_events": false, "watchdog_memory_limit": 500},
+				"schedule": {
+					"time": {"query": "select * from time", "interval": 60},
+					"users": {"query": "select username from users", "interval": 60},
+					"processes": {"query": "select * from processes", "interval": 600}
+				},
+				"auto_table_construction": {"chrome_history": {"path": "/Users/%/Library/Application Support/Google/Chrome/Default/History"}},
+				"decorators": {"load": ["select uuid from system_info", "select hostname from system_info"], "interval": {"60": ["select 1"]}},
+				"file_paths": {"etc": ["/etc/%%"]},
+				"node_invalid": true
+			}`,
+		},
+		{
+			Source: "kolide_fim",
+			Config: `{"file_paths": {"etc": ["/etc/%%", "/etc/ssh/%%"], "homes": ["/home/%/.ssh/%%"]}, "exclude_paths": {"etc": ["/etc/mtab"]}}`,
+		},
+	}
+
+	merged, conflicts, errs := Merge(fragments)
+	require.Empty(t, errs)
+
+	var cfg map[string]any
+	require.NoError(t, json.Unmarshal([]byte(merged), &cfg))
+
+	require.Equal(t, map[string]any{
+		"distributed_interval":  float64(10), // min
+		"config_refresh":        float64(300),
+		"verbose":               false, // precedence
+		"disable_events":        false, // all_true
+		"watchdog_memory_limit": float64(500),
+	}, cfg["options"])
+
+	schedule := cfg["schedule"].(map[string]any)
+	require.Len(t, schedule, 3)
+	require.Equal(t, "select * from users", schedule["users"].(map[string]any)["query"], "higher precedence source should win")
+	require.Contains(t, schedule, "processes")
+	require.Contains(t, cfg["packs"], "shared")
+	require.Contains(t, cfg["auto_table_construction"], "chrome_history")
+
+	require.Equal(t, map[string]any{
+		"etc":   []any{"/etc/%%", "/etc/ssh/%%"},
+		"homes": []any{"/home/%/.ssh/%%"},
+	}, cfg["file_paths"])
+	require.Equal(t, map[string]any{"etc": []any{"/etc/mtab"}}, cfg["exclude_paths"])
+	require.Equal(t, map[string]any{
+		"load": []any{"select uuid from system_info", "select hostname from system_info"},
+		"interval": map[string]any{
+			"60":   []any{"select 1"},
+			"3600": []any{"select version from osquery_info"},
+		},
+	}, cfg["decorators"])
+	require.Equal(t, false, cfg["node_invalid"])
+
+	require.Equal(t, []Conflict{
+		{Section: "", Key: "node_invalid", Rule: RulePrecedence, Winner: "default", Sources: []string{"default", "tenant_b"}},
+		{Section: "options", Key: "config_refresh", Rule: RuleMin, Winner: "default", Sources: []string{"default", "tenant_b"}},
+		{Section: "options", Key: "disable_events", Rule: RuleAllTrue, Winner: "tenant_b", Sources: []string{"default", "tenant_b"}},
+		{Section: "options", Key: "distributed_interval", Rule: RuleMin, Winner: "tenant_b", Sources: []string{"default", "tenant_b"}},
+		{Section: "options", Key: "verbose", Rule: RulePrecedence, Winner: "default", Sources: []string{"default", "tenant_b"}},
+		{Section: "options", Key: "watchdog_memory_limit", Rule: RuleMax, Winner: "tenant_b", Sources: []string{"default", "tenant_b"}},
+		{Section: "schedule", Key: "users", Rule: RulePrecedence, Winner: "default", Sources: []string{"default", "tenant_b"}},
+	}, conflicts)
+
+	// The merge is deterministic
+	for i := 0; i < 10; i++ {
+		again, _, _ := Merge(fragments)
+		require.Equal(t, merged, again)
+	}
+}
+
+func TestMerge_RuleFallsBackToPrecedence(t *testing.T) {
+	t.Parallel()
+
+	merged, conflicts, errs := Merge([]Fragment{
+		{Source: "a", Config: `{"options": {"distributed_interval": "soon"}}`},
+		{Source: "b", Config: `{"options": {"distributed_interval": 10}}`},
+	})
+	require.Empty(t, errs)
+	require.JSONEq(t, `{"options": {"distributed_interval": "soon"}}`, merged)
+	require.Equal(t, []Conflict{
+		{Section: "options", Key: "distributed_interval", Rule: RulePrecedence, Winner: "a", Sources: []string{"a", "b"}},
+	}, conflicts)
+}
+
+func TestMerge_InvalidAndSingleFragments(t *testing.T) {
+	t.Parallel()
+
+	// A single fragment is passed through untouched
+	single := `{"schedule":{"time":{"query":"select * from time","interval":60}},  "options":{}}`
+	merged, conflicts, errs := Merge([]Fragment{{Source: "default", Config: single}, {Source: "broken", Config: "not json"}})
+	require.Len(t, errs, 1)
+	require.Empty(t, conflicts)
+	require.Equal(t, single, merged)
+
+	merged, conflicts, errs = Merge(nil)
+	require.Empty(t, errs)
+	require.Empty(t, conflicts)
+	require.Equal(t, "{}", merged)
+}
+
+func TestPublish(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan Report, 1)
+	Subscribe(func(r Report) {
+		received <- r
+	})
+
+	report := Report{RegistrationID: "default", Sources: []string{"default", "kolide_fim"}}
+	Publish(context.TODO(), multislogger.NewNopLogger(), report)
+
+	select {
+	case r := <-received:
+		require.Equal(t, report, r)
+	case <-time.After(5 * time.Second):
+		t.Fatal("report was not published")
+	}
+}
@@ -0,0 +1,223 @@
+package osquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/consent"
+	"github.com/osquery/osquery-go/plugin/distributed"
+)
+
+// consentDeniedMessage is reported for distributed queries that were blocked because they use
+// tables the user hasn't consented to.
+const consentDeniedMessage = "blocked pending data collection consent"
+
+// consentUnknownPattern is logged as the table for queries blocked because the consent policy
+// couldn't be loaded, so the gated tables aren't known.
+const consentUnknownPattern = "any table (data collection consent unknown)"
+
+// packFilePattern is logged as the table for packs blocked because they're read from a file,
+// whose queries we can't check.
+const packFilePattern = "any table (pack file)"
+
+// newConsentDenylist returns a denylist matching queries that use any of the given tables.
+func newConsentDenylist(tables []string) *queryDenylist {
+	d := &queryDenylist{}
+	for _, table := range tables {
+		d.patterns = append(d.patterns, denylistPattern{
+			raw: table,
+			re:  regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(table) + `\b`),
+		})
+	}
+	return d
+}
+
+// consentDenylist returns a denylist matching the queries that must be blocked until the user
+// consents to data collection, or nil if there are none. It fails closed: if the consent
+// policy can't be loaded, it matches every query.
+func consentDenylist(ctx context.Context, slogger *slog.Logger, policyStore, statusStore types.Getter) *queryDenylist {
+	blocked, err := consent.BlockedTables(policyStore, statusStore)
+	if err != nil {
+		slogger.Log(ctx, slog.LevelWarn,
+			"could not load data collection consent policy, blocking all queries",
+			"err", err,
+		)
+		return &queryDenylist{
+			patterns: []denylistPattern{{raw: consentUnknownPattern, re: regexp.MustCompile(`^`)}},
+		}
+	}
+
+	if len(blocked) == 0 {
+		return nil
+	}
+	return newConsentDenylist(blocked)
+}
+
+// GateConsentQueries removes the queries that must be blocked until the user consents to data
+// collection from queries, for running queries outside of osquery's config and distributed
+// queries. It returns the names of the removed queries, mapped to the table blocking them.
+func GateConsentQueries(ctx context.Context, slogger *slog.Logger, policyStore, statusStore types.Getter, queries map[string]string) map[string]string {
+	d := consentDenylist(ctx, slogger, policyStore, statusStore)
+	if d == nil {
+		return nil
+	}
+
+	removed := make(map[string]string)
+	for name, query := range queries {
+		if pattern, ok := d.match(query); ok {
+			delete(queries, name)
+			removed[name] = pattern
+		}
+	}
+	return removed
+}
+
+func (e *Extension) consentDenylist(ctx context.Context) *queryDenylist {
+	return consentDenylist(ctx, e.slogger, e.knapsack.ControlStore(), e.knapsack.PersistentHostDataStore())
+}
+
+// gateQueries removes distributed queries that use tables the user hasn't consented to, and
+// reports them to the server as denied.
+func (e *Extension) gateQueries(ctx context.Context, queries *distributed.GetQueriesResult) {
+	d := e.consentDenylist(ctx)
+	if d == nil {
+		return
+	}
+
+	denied := d.filter(queries)
+	if len(denied) == 0 {
+		return
+	}
+
+	for _, d := range denied {
+		e.slogger.Log(ctx, slog.LevelInfo,
+			"distributed query blocked pending data collection consent",
+			"query_name", d.name,
+			"table", d.pattern,
+		)
+	}
+
+	e.queryRetries.drop(e.registrationId, deniedNames(denied))
+	if err := e.writeResultsWithReenroll(ctx, deniedResults(denied, consentDeniedMessage), true); err != nil {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"could not report distributed queries blocked pending data collection consent",
+			"denied_count", len(denied),
+			"err", err,
+		)
+	}
+}
+
+// gateConfig removes scheduled queries that use tables the user hasn't consented to from the
+// osquery config. The config is returned as-is if nothing is blocked. If it can't be parsed,
+// we can't tell which queries to remove, so none of it is used.
+func (e *Extension) gateConfig(ctx context.Context, config string) string {
+	d := e.consentDenylist(ctx)
+	if d == nil {
+		return config
+	}
+
+	gated, denied, err := gateConfig(config, d)
+	if err != nil {
+		e.slogger.Log(ctx, slog.LevelError,
+			"could not parse config to remove scheduled queries blocked pending data collection consent, dropping its schedule and packs",
+			"err", err,
+		)
+		return "{}"
+	}
+
+	for _, d := range denied {
+		e.slogger.Log(ctx, slog.LevelInfo,
+			"scheduled query blocked pending data collection consent",
+			"query_name", d.name,
+			"table", d.pattern,
+		)
+	}
+
+	return gated
+}
+
+// gateConfig removes the scheduled queries matching d from config, both in the schedule and
+// in packs. Packs whose discovery queries match are removed entirely, since without the
+// discovery queries the pack would run unconditionally. So are packs read from files, since
+// we can't check their queries.
+func gateConfig(config string, d *queryDenylist) (string, []deniedQuery, error) {
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return "", nil, fmt.Errorf("unmarshalling config: %w", err)
+	}
+
+	var denied []deniedQuery
+
+	if schedule, ok := cfg["schedule"].(map[string]any); ok {
+		denied = append(denied, gateScheduledQueries(schedule, d, "")...)
+	}
+
+	if packs, ok := cfg["packs"].(map[string]any); ok {
+		for packName, rawPack := range packs {
+			pack, ok := rawPack.(map[string]any)
+			if !ok {
+				delete(packs, packName)
+				denied = append(denied, deniedQuery{name: "pack:" + packName, pattern: packFilePattern})
+				continue
+			}
+
+			if discovery, ok := pack["discovery"].([]any); ok {
+				if pattern, matched := matchAny(d, discovery); matched {
+					delete(packs, packName)
+					denied = append(denied, deniedQuery{name: "pack:" + packName, pattern: pattern})
+					continue
+				}
+			}
+
+			if queries, ok := pack["queries"].(map[string]any); ok {
+				denied = append(denied, gateScheduledQueries(queries, d, "pack:"+packName+":")...)
+			}
+		}
+	}
+
+	if len(denied) == 0 {
+		return config, nil, nil
+	}
+
+	gated, err := json.Marshal(cfg)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshalling config: %w", err)
+	}
+
+	return string(gated), denied, nil
+}
+
+// gateScheduledQueries removes the queries matching d from a schedule or pack's queries.
+func gateScheduledQueries(queries map[string]any, d *queryDenylist, namePrefix string) []deniedQuery {
+	var denied []deniedQuery
+	for name, rawQuery := range queries {
+		query, ok := rawQuery.(map[string]any)
+		if !ok {
+			continue
+		}
+		sql, ok := query["query"].(string)
+		if !ok {
+			continue
+		}
+		if pattern, matched := d.match(sql); matched {
+			delete(queries, name)
+			denied = append(denied, deniedQuery{name: namePrefix + name, pattern: pattern})
+		}
+	}
+	return denied
+}
+
+func matchAny(d *queryDenylist, queries []any) (string, bool) {
+	for _, q := range queries {
+		if sql, ok := q.(string); ok {
+			if pattern, matched := d.match(sql); matched {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
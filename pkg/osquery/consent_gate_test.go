@@ -0,0 +1,231 @@
+package osquery
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kolide/kit/ulid"
+	"github.com/kolide/launcher/ee/agent/storage"
+	storageci "github.com/kolide/launcher/ee/agent/storage/ci"
+	"github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/ee/consent"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	settingsstoremock "github.com/kolide/launcher/pkg/osquery/mocks"
+	"github.com/kolide/launcher/pkg/service/mock"
+	"github.com/osquery/osquery-go/plugin/distributed"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateConfig(t *testing.T) {
+	t.Parallel()
+
+	config := `{
+		"options": {"distributed_interval": 60},
+		"schedule": {
+			"history": {"query": "select * from shell_history", "interval": 3600},
+			"history_backup": {"query": "select * from shell_history_backup", "interval": 3600},
+			"time": {"query": "select * from time", "interval": 60}
+		},
+		"packs": {
+			"browsing": {
+				"discovery": ["select 1 from Kolide_Chrome_History limit 1"],
+				"queries": {"users": {"query": "select * from users", "interval": 60}}
+			},
+			"mixed": {
+				"queries": {
+					"history": {"query": "select count(*) from shell_history", "interval": 60},
+					"os": {"query": "select * from os_version", "interval": 60}
+				}
+			},
+			"external": "/etc/osquery/packs/external.conf"
+		}
+	}`
+
+	gated, denied, err := gateConfig(config, newConsentDenylist([]string{"kolide_chrome_history", "shell_history"}))
+	require.NoError(t, err)
+
+	deniedNames := make([]string, 0, len(denied))
+	for _, d := range denied {
+		deniedNames = append(deniedNames, d.name)
+	}
+	require.ElementsMatch(t, []string{"history", "pack:browsing", "pack:mixed:history", "pack:external"}, deniedNames)
+
+	var cfg struct {
+		Options  map[string]any             `json:"options"`
+		Schedule map[string]any             `json:"schedule"`
+		Packs    map[string]json.RawMessage `json:"packs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(gated), &cfg))
+	require.Contains(t, cfg.Options, "distributed_interval")
+	require.Contains(t, cfg.Schedule, "time")
+	require.Contains(t, cfg.Schedule, "history_backup", "table names should only match whole words")
+	require.NotContains(t, cfg.Schedule, "history")
+	require.NotContains(t, cfg.Packs, "browsing")
+	require.NotContains(t, cfg.Packs, "external", "pack files can't be checked")
+	require.Contains(t, string(cfg.Packs["mixed"]), "os_version")
+	require.NotContains(t, string(cfg.Packs["mixed"]), "shell_history")
+
+	// Nothing to remove: the config is passed through untouched
+	config = `{"schedule": {"time": {"query": "select * from time", "interval": 60}}}`
+	unchanged, denied, err := gateConfig(config, newConsentDenylist([]string{"keychain_items"}))
+	require.NoError(t, err)
+	require.Empty(t, denied)
+	require.Equal(t, config, unchanged)
+
+	_, _, err = gateConfig("not json", newConsentDenylist([]string{"shell_history"}))
+	require.Error(t, err)
+}
+
+func TestExtensionGetQueriesConsentGate(t *testing.T) {
+	t.Parallel()
+
+	var publishedResults []distributed.Result
+	m := &mock.KolideService{
+		RequestQueriesFunc: func(ctx context.Context, nodeKey string) (*distributed.GetQueriesResult, bool, error) {
+			return &distributed.GetQueriesResult{
+				Queries: map[string]string{
+					"time":    "select * from time",
+					"history": "select command from shell_history",
+				},
+			}, false, nil
+		},
+		PublishResultsFunc: func(ctx context.Context, nodeKey string, results []distributed.Result) (string, string, bool, error) {
+			publishedResults = results
+			return "", "", false, nil
+		},
+	}
+
+	k := mocks.NewKnapsack(t)
+	k.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("DistributedQueryDenylist").Return([]string{})
+	k.On("ControlStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ControlStore.String()))
+	k.On("PersistentHostDataStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.PersistentHostDataStore.String()))
+
+	tracker := consent.New(k, nil)
+	require.NoError(t, tracker.Update(strings.NewReader(`{"enabled":true,"policy_version":"1","gated_tables":["shell_history"]}`)))
+
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
+	require.Nil(t, err)
+
+	// Pending consent: the query is blocked and reported
+	queries, err := e.GetQueries(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"time": "select * from time"}, queries.Queries)
+	require.Len(t, publishedResults, 1)
+	require.Equal(t, "history", publishedResults[0].QueryName)
+	require.Equal(t, consentDeniedMessage, publishedResults[0].Message)
+
+	// Once the user consents, the query runs
+	require.NoError(t, tracker.SendMessage(consent.AcknowledgeMethod, map[string]any{"policy_version": "1", "accepted": true}))
+	publishedResults = nil
+	queries, err = e.GetQueries(context.Background())
+	require.NoError(t, err)
+	require.Len(t, queries.Queries, 2)
+	require.Empty(t, publishedResults)
+}
+
+func TestExtensionConsentGateUnknownPolicy(t *testing.T) {
+	t.Parallel()
+
+	var publishedResults []distributed.Result
+	m := &mock.KolideService{
+		RequestQueriesFunc: func(ctx context.Context, nodeKey string) (*distributed.GetQueriesResult, bool, error) {
+			return &distributed.GetQueriesResult{
+				Queries: map[string]string{"time": "select * from time"},
+			}, false, nil
+		},
+		PublishResultsFunc: func(ctx context.Context, nodeKey string, results []distributed.Result) (string, string, bool, error) {
+			publishedResults = results
+			return "", "", false, nil
+		},
+	}
+
+	controlStore, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.ControlStore.String())
+	require.NoError(t, err)
+	require.NoError(t, controlStore.Set([]byte("data_collection_consent_policy"), []byte("not json")))
+
+	k := mocks.NewKnapsack(t)
+	k.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("DistributedQueryDenylist").Return([]string{})
+	k.On("ControlStore").Return(controlStore)
+	k.On("PersistentHostDataStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.PersistentHostDataStore.String()))
+
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
+	require.Nil(t, err)
+
+	// The gated tables aren't known, so every query is blocked
+	queries, err := e.GetQueries(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, queries.Queries)
+	require.Len(t, publishedResults, 1)
+	require.Equal(t, consentDeniedMessage, publishedResults[0].Message)
+
+	gated := e.gateConfig(context.Background(), `{"options": {"verbose": true}, "schedule": {"time": {"query": "select * from time", "interval": 60}}}`)
+	var cfg map[string]any
+	require.NoError(t, json.Unmarshal([]byte(gated), &cfg))
+	require.Empty(t, cfg["schedule"])
+
+	// A config that can't be parsed is dropped entirely
+	require.Equal(t, "{}", e.gateConfig(context.Background(), "not json"))
+
+	removed := GateConsentQueries(context.Background(), multislogger.NewNopLogger(), controlStore, k.PersistentHostDataStore(), map[string]string{"time": "select * from time"})
+	require.Contains(t, removed, "time")
+}
+
+func TestExtensionGetQueriesConsentGateRetries(t *testing.T) {
+	t.Parallel()
+
+	var publishedResults []distributed.Result
+	m := &mock.KolideService{
+		RequestQueriesFunc: func(ctx context.Context, nodeKey string) (*distributed.GetQueriesResult, bool, error) {
+			return &distributed.GetQueriesResult{
+				Queries: map[string]string{"history": "select command from shell_history"},
+			}, false, nil
+		},
+		PublishResultsFunc: func(ctx context.Context, nodeKey string, results []distributed.Result) (string, string, bool, error) {
+			publishedResults = results
+			return "", "", false, nil
+		},
+	}
+
+	k := mocks.NewKnapsack(t)
+	k.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("DistributedQueryDenylist").Return([]string{})
+	k.On("ControlStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ControlStore.String()))
+	k.On("PersistentHostDataStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.PersistentHostDataStore.String()))
+
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
+	require.Nil(t, err)
+
+	// The query fails transiently, and is queued for retry
+	_, err = e.GetQueries(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, e.WriteResults(context.Background(), []distributed.Result{
+		{QueryName: "history", Status: 1, Message: "Extension call failed: broken pipe"},
+	}))
+	require.False(t, m.PublishResultsFuncInvoked)
+
+	// Privacy mode is turned on before the retry is handed out: the retry is blocked too
+	tracker := consent.New(k, nil)
+	require.NoError(t, tracker.Update(strings.NewReader(`{"enabled":true,"policy_version":"1","gated_tables":["shell_history"]}`)))
+
+	m.RequestQueriesFunc = func(ctx context.Context, nodeKey string) (*distributed.GetQueriesResult, bool, error) {
+		return &distributed.GetQueriesResult{}, false, nil
+	}
+	queries, err := e.GetQueries(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, queries.Queries)
+	require.Len(t, publishedResults, 1)
+	require.Equal(t, "history", publishedResults[0].QueryName)
+	require.Equal(t, consentDeniedMessage, publishedResults[0].Message)
+
+	// The blocked retry is no longer queued, so its original failure won't be reported later
+	e.queryRetries.lock.Lock()
+	defer e.queryRetries.lock.Unlock()
+	require.NotContains(t, e.queryRetries.queued, queryRetryKey{e.registrationId, "history"})
+}
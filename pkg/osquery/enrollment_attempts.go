@@ -0,0 +1,159 @@
+package osquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/backoff"
+)
+
+const (
+	// Intervals between enrollment attempts after a failure. Devices imaged at the same time
+	// tend to attempt enrollment at the same time, so the interval grows exponentially, and is
+	// jittered to spread out the fleet's retries.
+	enrollRetryBaseInterval = 15 * time.Second
+	enrollRetryMaxInterval  = 30 * time.Minute
+
+	// maxEnrollmentAttempts is the number of enrollment attempts kept in the history
+	maxEnrollmentAttempts = 100
+)
+
+// Classes of enrollment errors, recorded in the enrollment attempt history
+const (
+	enrollErrorClassEnrollSecret     = "enroll_secret"
+	enrollErrorClassHostIdentifier   = "host_identifier"
+	enrollErrorClassEnrollDetails    = "enrollment_details"
+	enrollErrorClassHardwareKeyProof = "hardware_key_proof"
+	enrollErrorClassDeviceDisabled   = "device_disabled"
+	enrollErrorClassTransport        = "transport"
+	enrollErrorClassInvalid          = "invalid"
+	enrollErrorClassStorage          = "storage"
+)
+
+var errEnrollmentBackoff = errors.New("waiting to retry enrollment")
+
+// EnrollmentAttempt is a record of an attempt to enroll, kept in the enrollment attempts store.
+type EnrollmentAttempt struct {
+	Time                int64  `json:"time"` // unix seconds
+	RegistrationId      string `json:"registration_id"`
+	Success             bool   `json:"success"`
+	ErrorClass          string `json:"error_class,omitempty"`
+	Error               string `json:"error,omitempty"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	NextAttempt         int64  `json:"next_attempt,omitempty"` // unix seconds; unset after a success
+}
+
+// enrollmentRetryState tracks consecutive failed enrollment attempts, to decide when to try
+// again. It is only used while holding the extension's enrollMutex.
+type enrollmentRetryState struct {
+	intervals interface {
+		Next() time.Duration
+		Reset()
+	}
+	consecutiveFailures int
+	nextAttempt         time.Time
+}
+
+func newEnrollmentRetryState() *enrollmentRetryState {
+	return &enrollmentRetryState{
+		intervals: backoff.NewExponentialDurationCounter(enrollRetryBaseInterval, enrollRetryMaxInterval),
+	}
+}
+
+// ready reports whether enough time has passed since the last failure to try enrolling again.
+func (s *enrollmentRetryState) ready(now time.Time) bool {
+	return !now.Before(s.nextAttempt)
+}
+
+// failed records a failed attempt, and returns when the next attempt may be made.
+func (s *enrollmentRetryState) failed(now time.Time) time.Time {
+	s.consecutiveFailures += 1
+	s.nextAttempt = now.Add(backoff.Jitter(s.intervals.Next()))
+	return s.nextAttempt
+}
+
+// succeeded resets the backoff after a successful attempt.
+func (s *enrollmentRetryState) succeeded() {
+	s.intervals.Reset()
+	s.consecutiveFailures = 0
+	s.nextAttempt = time.Time{}
+}
+
+// enrollmentAttemptKey returns the store key for an attempt made at the given time. Keys sort
+// in the order the attempts were made.
+func enrollmentAttemptKey(t time.Time) []byte {
+	return []byte(fmt.Sprintf("%020d", t.UnixNano()))
+}
+
+// recordEnrollmentAttempt adds the attempt to the history, dropping the oldest attempts
+// beyond maxEnrollmentAttempts.
+func recordEnrollmentAttempt(store types.GetterSetterDeleterIterator, t time.Time, attempt EnrollmentAttempt) error {
+	attemptRaw, err := json.Marshal(attempt)
+	if err != nil {
+		return fmt.Errorf("marshalling enrollment attempt: %w", err)
+	}
+
+	if err := store.Set(enrollmentAttemptKey(t), attemptRaw); err != nil {
+		return fmt.Errorf("storing enrollment attempt: %w", err)
+	}
+
+	var keys []string
+	if err := store.ForEach(func(k, _ []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}); err != nil {
+		return fmt.Errorf("iterating over enrollment attempts: %w", err)
+	}
+
+	if len(keys) <= maxEnrollmentAttempts {
+		return nil
+	}
+
+	sort.Strings(keys)
+	toDelete := make([][]byte, 0, len(keys)-maxEnrollmentAttempts)
+	for _, k := range keys[:len(keys)-maxEnrollmentAttempts] {
+		toDelete = append(toDelete, []byte(k))
+	}
+
+	if err := store.Delete(toDelete...); err != nil {
+		return fmt.Errorf("pruning enrollment attempts: %w", err)
+	}
+
+	return nil
+}
+
+// EnrollmentAttempts returns the recorded enrollment attempts, oldest first.
+func EnrollmentAttempts(store types.Iterator) ([]EnrollmentAttempt, error) {
+	type keyedAttempt struct {
+		key     string
+		attempt EnrollmentAttempt
+	}
+
+	var keyedAttempts []keyedAttempt
+	if err := store.ForEach(func(k, v []byte) error {
+		var attempt EnrollmentAttempt
+		if err := json.Unmarshal(v, &attempt); err != nil {
+			// Skip anything we can't read, rather than failing the whole history
+			return nil
+		}
+		keyedAttempts = append(keyedAttempts, keyedAttempt{key: string(k), attempt: attempt})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("iterating over enrollment attempts: %w", err)
+	}
+
+	sort.Slice(keyedAttempts, func(i, j int) bool {
+		return keyedAttempts[i].key < keyedAttempts[j].key
+	})
+
+	attempts := make([]EnrollmentAttempt, 0, len(keyedAttempts))
+	for _, ka := range keyedAttempts {
+		attempts = append(attempts, ka.attempt)
+	}
+
+	return attempts, nil
+}
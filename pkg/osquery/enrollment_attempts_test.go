@@ -0,0 +1,62 @@
+package osquery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollmentRetryState(t *testing.T) {
+	t.Parallel()
+
+	s := newEnrollmentRetryState()
+	now := time.Now()
+	require.True(t, s.ready(now), "should be ready before any failures")
+
+	var lastInterval time.Duration
+	for i := 1; i <= 20; i++ {
+		nextAttempt := s.failed(now)
+		interval := nextAttempt.Sub(now)
+
+		require.Equal(t, i, s.consecutiveFailures)
+		require.False(t, s.ready(now), "should not be ready right after a failure")
+		require.True(t, s.ready(nextAttempt), "should be ready at the next attempt")
+		require.GreaterOrEqual(t, interval, enrollRetryBaseInterval/2)
+		require.LessOrEqual(t, interval, enrollRetryMaxInterval)
+
+		// With jitter, the interval can shrink from one failure to the next, but never to less
+		// than half of the previous one
+		require.GreaterOrEqual(t, interval, lastInterval/2)
+		lastInterval = interval
+	}
+
+	require.GreaterOrEqual(t, lastInterval, enrollRetryMaxInterval/2, "interval should reach the max")
+
+	s.succeeded()
+	require.Equal(t, 0, s.consecutiveFailures)
+	require.True(t, s.ready(now))
+	require.LessOrEqual(t, s.failed(now).Sub(now), enrollRetryBaseInterval, "backoff should restart after a success")
+}
+
+func Test_recordEnrollmentAttempt(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	start := time.Now()
+
+	for i := 0; i < maxEnrollmentAttempts+10; i++ {
+		require.NoError(t, recordEnrollmentAttempt(store, start.Add(time.Duration(i)*time.Second), EnrollmentAttempt{
+			Time:                start.Add(time.Duration(i) * time.Second).Unix(),
+			ErrorClass:          enrollErrorClassTransport,
+			ConsecutiveFailures: i + 1,
+		}))
+	}
+
+	attempts, err := EnrollmentAttempts(store)
+	require.NoError(t, err)
+	require.Len(t, attempts, maxEnrollmentAttempts, "oldest attempts should be pruned")
+	require.Equal(t, 11, attempts[0].ConsecutiveFailures, "should keep the most recent attempts, oldest first")
+	require.Equal(t, maxEnrollmentAttempts+10, attempts[len(attempts)-1].ConsecutiveFailures)
+}
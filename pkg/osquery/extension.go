@@ -10,16 +10,25 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/agent/keys"
 	"github.com/kolide/launcher/ee/agent/storage"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/fim"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
 	"github.com/kolide/launcher/ee/uninstall"
 	"github.com/kolide/launcher/pkg/backoff"
+	"github.com/kolide/launcher/pkg/osquery/configmerge"
+	"github.com/kolide/launcher/pkg/osquery/queryaccounting"
 	"github.com/kolide/launcher/pkg/osquery/runtime/history"
+	"github.com/kolide/launcher/pkg/osquery/watchdogevents"
 	"github.com/kolide/launcher/pkg/service"
 	"github.com/kolide/launcher/pkg/traces"
 	"github.com/osquery/osquery-go/plugin/distributed"
@@ -42,15 +51,24 @@ type settingsStoreWriter interface {
 type Extension struct {
 	NodeKey             string
 	Opts                ExtensionOpts
+	optsLock            sync.RWMutex
+	optsChanged         chan struct{}
 	registrationId      string
 	knapsack            types.Knapsack
 	serviceClient       service.KolideService
 	settingsWriter      settingsStoreWriter
 	enrollMutex         sync.Mutex
+	enrollmentRetry     *enrollmentRetryState
 	done                chan struct{}
 	interrupted         atomic.Bool
 	slogger             *slog.Logger
 	logPublicationState *logPublicationState
+	queryAccounting     *queryaccounting.Tracker
+	queryRetries        *queryRetryQueue
+	watchdogEvents      *watchdogevents.Recorder
+	statusLogMirror     *statusLogMirror
+	configConflictsLock sync.Mutex
+	lastConfigConflicts string // the conflicts from the last config merge, as last published
 }
 
 const (
@@ -60,6 +78,10 @@ const (
 	nodeKeyKey = "nodeKey"
 	// DB key for last retrieved config
 	configKey = "config"
+	// DB key for the time of the last successful config request
+	lastConfigCheckinKey = "lastConfigCheckin"
+	// Name of the config source for the FIM config from the control server
+	fimConfigName = "kolide_fim"
 	// DB keys for the rsa keys
 	privateKeyKey = "privateKey"
 
@@ -116,6 +138,30 @@ type ExtensionOpts struct {
 	// RunDifferentialQueriesImmediately allows the client to execute a new query the first time it sees it,
 	// bypassing the scheduler.
 	RunDifferentialQueriesImmediately bool
+	// DeduplicateSnapshotResults collapses identical consecutive snapshot results
+	// for the same query, within a batch, into one annotated with a repeat count.
+	DeduplicateSnapshotResults bool
+	// ExportPlatformLogs mirrors osquery status logs into the platform's logging
+	// system. It's read when the extension is created.
+	ExportPlatformLogs bool
+	// WatchdogEvents, if set, is handed osquery's status logs to record watchdog kills, and
+	// the distributed queries pending at the time. It's read when the extension is created.
+	WatchdogEvents *watchdogevents.Recorder
+}
+
+// setDefaults fills in defaults for any unset options.
+func (opts *ExtensionOpts) setDefaults() {
+	if opts.MaxBytesPerBatch == 0 {
+		opts.MaxBytesPerBatch = defaultMaxBytesPerBatch
+	}
+
+	if opts.LoggingInterval == 0 {
+		opts.LoggingInterval = defaultLoggingInterval
+	}
+
+	if opts.MaxBufferedLogs == 0 {
+		opts.MaxBufferedLogs = defaultMaxBufferedLogs
+	}
 }
 
 type iterationTerminatedError struct{}
@@ -133,17 +179,7 @@ func NewExtension(ctx context.Context, client service.KolideService, settingsWri
 
 	slogger := k.Slogger().With("component", "osquery_extension", "registration_id", registrationId)
 
-	if opts.MaxBytesPerBatch == 0 {
-		opts.MaxBytesPerBatch = defaultMaxBytesPerBatch
-	}
-
-	if opts.LoggingInterval == 0 {
-		opts.LoggingInterval = defaultLoggingInterval
-	}
-
-	if opts.MaxBufferedLogs == 0 {
-		opts.MaxBufferedLogs = defaultMaxBufferedLogs
-	}
+	opts.setDefaults()
 
 	configStore := k.ConfigStore()
 
@@ -164,6 +200,20 @@ func NewExtension(ctx context.Context, client service.KolideService, settingsWri
 		)
 	}
 
+	var mirror *statusLogMirror
+	if opts.ExportPlatformLogs {
+		mirror, err = newStatusLogMirror(registrationId)
+		if err != nil {
+			slogger.Log(ctx, slog.LevelWarn,
+				"could not export osquery status logs to platform logging system",
+				"err", err,
+			)
+		}
+	}
+
+	queryAccounting := queryaccounting.NewTracker()
+	opts.WatchdogEvents.SetPendingQueriesFunc(queryAccounting.Pending)
+
 	return &Extension{
 		slogger:             slogger,
 		serviceClient:       client,
@@ -172,14 +222,20 @@ func NewExtension(ctx context.Context, client service.KolideService, settingsWri
 		knapsack:            k,
 		NodeKey:             nodekey,
 		Opts:                opts,
+		optsChanged:         make(chan struct{}, 1),
 		done:                make(chan struct{}),
 		logPublicationState: NewLogPublicationState(opts.MaxBytesPerBatch),
+		enrollmentRetry:     newEnrollmentRetryState(),
+		queryAccounting:     queryAccounting,
+		queryRetries:        distributedQueryRetries,
+		statusLogMirror:     mirror,
+		watchdogEvents:      opts.WatchdogEvents,
 	}, nil
 }
 
 func (e *Extension) Execute() error {
 	// Process logs until shutdown
-	ticker := time.NewTicker(e.Opts.LoggingInterval)
+	ticker := time.NewTicker(e.currentOpts().LoggingInterval)
 	defer ticker.Stop()
 	for {
 		e.writeAndPurgeLogs()
@@ -193,10 +249,57 @@ func (e *Extension) Execute() error {
 			return nil
 		case <-ticker.C:
 			// Resume loop
+		case <-e.optsChanged:
+			// Apply the new options here, so that they don't change in the middle of a batch
+			opts := e.currentOpts()
+			ticker.Reset(opts.LoggingInterval)
+			e.logPublicationState.SetMaxBytesPerBatch(opts.MaxBytesPerBatch)
 		}
 	}
 }
 
+// Reconfigure updates the log batching and buffering options of the running extension.
+// Unset options revert to their defaults.
+func (e *Extension) Reconfigure(opts ExtensionOpts) {
+	opts.setDefaults()
+
+	e.optsLock.Lock()
+	changed := e.Opts.MaxBytesPerBatch != opts.MaxBytesPerBatch ||
+		e.Opts.LoggingInterval != opts.LoggingInterval ||
+		e.Opts.MaxBufferedLogs != opts.MaxBufferedLogs ||
+		e.Opts.DeduplicateSnapshotResults != opts.DeduplicateSnapshotResults
+	e.Opts.MaxBytesPerBatch = opts.MaxBytesPerBatch
+	e.Opts.LoggingInterval = opts.LoggingInterval
+	e.Opts.MaxBufferedLogs = opts.MaxBufferedLogs
+	e.Opts.DeduplicateSnapshotResults = opts.DeduplicateSnapshotResults
+	e.optsLock.Unlock()
+
+	if !changed {
+		return
+	}
+
+	e.slogger.Log(context.TODO(), slog.LevelInfo,
+		"reconfigured osquery extension",
+		"max_bytes_per_batch", opts.MaxBytesPerBatch,
+		"logging_interval", opts.LoggingInterval.String(),
+		"max_buffered_logs", opts.MaxBufferedLogs,
+		"deduplicate_snapshot_results", opts.DeduplicateSnapshotResults,
+	)
+
+	// Notify Execute without blocking -- a pending notification will pick up these options too
+	select {
+	case e.optsChanged <- struct{}{}:
+	default:
+	}
+}
+
+// currentOpts returns a copy of the extension's options, which may be changed by Reconfigure.
+func (e *Extension) currentOpts() ExtensionOpts {
+	e.optsLock.RLock()
+	defer e.optsLock.RUnlock()
+	return e.Opts
+}
+
 // Shutdown should be called to cleanup the resources and goroutines associated
 // with this extension.
 func (e *Extension) Shutdown(_ error) {
@@ -207,6 +310,13 @@ func (e *Extension) Shutdown(_ error) {
 	e.interrupted.Store(true)
 
 	close(e.done)
+
+	if err := e.statusLogMirror.close(); err != nil {
+		e.slogger.Log(context.TODO(), slog.LevelDebug,
+			"could not close platform log for osquery status logs",
+			"err", err,
+		)
+	}
 }
 
 // getHostIdentifier returns the UUID identifier associated with this host. If
@@ -346,6 +456,25 @@ func NodeKey(getter types.Getter, registrationId string) (string, error) {
 	}
 }
 
+// LastConfigCheckin returns the time of the last successful config request from the storage
+// layer, or the zero time if there has not been one.
+func LastConfigCheckin(getter types.Getter, registrationId string) (time.Time, error) {
+	lastCheckin, err := getter.Get(storage.KeyByIdentifier([]byte(lastConfigCheckinKey), storage.IdentifierTypeRegistration, []byte(registrationId)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error getting last config checkin: %w", err)
+	}
+	if lastCheckin == nil {
+		return time.Time{}, nil
+	}
+
+	lastCheckinUnix, err := strconv.ParseInt(string(lastCheckin), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing last config checkin %s: %w", string(lastCheckin), err)
+	}
+
+	return time.Unix(lastCheckinUnix, 0), nil
+}
+
 // Config returns the device config from the storage layer
 func Config(getter types.Getter, registrationId string) (string, error) {
 	key, err := getter.Get(storage.KeyByIdentifier([]byte(configKey), storage.IdentifierTypeRegistration, []byte(registrationId)))
@@ -407,23 +536,54 @@ func (e *Extension) Enroll(ctx context.Context) (string, bool, error) {
 			"found stored node key, skipping enrollment",
 		)
 		span.AddEvent("found_stored_node_key")
+		e.checkProvenKey(ctx)
 		e.NodeKey = key
 		return e.NodeKey, false, nil
 	}
 
+	// After a failure, wait out the backoff, so that a fleet of devices failing to enroll
+	// together doesn't retry together
+	if !e.enrollmentRetry.ready(time.Now()) {
+		span.AddEvent("enrollment_backoff")
+		return "", true, fmt.Errorf("%w: next attempt at %s", errEnrollmentBackoff, e.enrollmentRetry.nextAttempt.UTC().Format(time.RFC3339))
+	}
+
 	e.slogger.Log(ctx, slog.LevelInfo,
 		"no node key found, starting enrollment",
 	)
 	span.AddEvent("starting_enrollment")
 
+	keyString, errorClass, err := e.requestNodeKey(ctx)
+	e.recordEnrollmentAttempt(ctx, errorClass, err)
+	if err != nil {
+		traces.SetError(span, err)
+		return "", true, err
+	}
+
+	e.NodeKey = keyString
+
+	e.slogger.Log(ctx, slog.LevelInfo,
+		"completed enrollment",
+	)
+	span.AddEvent("completed_enrollment")
+
+	return e.NodeKey, false, nil
+}
+
+// requestNodeKey enrolls with the server, and stores the new node key. On failure, it
+// returns the class of the error for the enrollment attempt history.
+func (e *Extension) requestNodeKey(ctx context.Context) (string, string, error) {
+	ctx, span := traces.StartSpan(ctx)
+	defer span.End()
+
 	enrollSecret, err := e.knapsack.ReadEnrollSecret()
 	if err != nil {
-		return "", true, fmt.Errorf("could not read enroll secret: %w", err)
+		return "", enrollErrorClassEnrollSecret, fmt.Errorf("could not read enroll secret: %w", err)
 	}
 
 	identifier, err := e.getHostIdentifier()
 	if err != nil {
-		return "", true, fmt.Errorf("generating UUID: %w", err)
+		return "", enrollErrorClassHostIdentifier, fmt.Errorf("generating UUID: %w", err)
 	}
 
 	// We used to see the enrollment details fail, but now that we're running as an exec,
@@ -446,7 +606,7 @@ func (e *Extension) Enroll(ctx context.Context) (string, bool, error) {
 			return err
 		}, 30*time.Second, 5*time.Second); err != nil {
 			if os.Getenv("LAUNCHER_DEBUG_ENROLL_DETAILS_REQUIRED") == "true" {
-				return "", true, fmt.Errorf("query osq enrollment details: %w", err)
+				return "", enrollErrorClassEnrollDetails, fmt.Errorf("query osq enrollment details: %w", err)
 			}
 
 			e.slogger.Log(ctx, slog.LevelError,
@@ -458,6 +618,21 @@ func (e *Extension) Enroll(ctx context.Context) (string, bool, error) {
 			span.AddEvent("got_enrollment_details")
 		}
 	}
+
+	var keyProof *keys.KeyProof
+	if e.knapsack.HardwareKeyProof() {
+		keyProof, err = keys.NewKeyProof(agent.HardwareKeys(), identifier, e.registrationId, enrollSecret)
+		if err != nil {
+			return "", enrollErrorClassHardwareKeyProof, fmt.Errorf("creating hardware key proof: %w", err)
+		}
+
+		enrollDetails.LauncherHardwareKeyProof, err = keyProof.Encode()
+		if err != nil {
+			return "", enrollErrorClassHardwareKeyProof, fmt.Errorf("encoding hardware key proof: %w", err)
+		}
+		span.AddEvent("created_hardware_key_proof")
+	}
+
 	// If no cached node key, enroll for new node key
 	// note that we set invalid two ways. Via the return, _or_ via isNodeInvaliderr
 	keyString, invalid, err := e.serviceClient.RequestEnrollment(ctx, enrollSecret, identifier, enrollDetails)
@@ -468,13 +643,13 @@ func (e *Extension) Enroll(ctx context.Context) (string, bool, error) {
 		// the uninstall call above will cause launcher to uninstall and exit
 		// so we are returning the err here just incase something somehow
 		// goes wrong with the uninstall
-		return "", true, fmt.Errorf("device disabled, should have uninstalled: %w", err)
+		return "", enrollErrorClassDeviceDisabled, fmt.Errorf("device disabled, should have uninstalled: %w", err)
 
 	case isNodeInvalidErr(err):
 		invalid = true
 
 	case err != nil:
-		return "", true, fmt.Errorf("transport error getting queries: %w", err)
+		return "", enrollErrorClassTransport, fmt.Errorf("transport error getting queries: %w", err)
 
 	default: // pass through no error
 	}
@@ -483,25 +658,90 @@ func (e *Extension) Enroll(ctx context.Context) (string, bool, error) {
 		if err == nil {
 			err = errors.New("no further error")
 		}
-		err = fmt.Errorf("enrollment invalid: %w", err)
-		traces.SetError(span, err)
-		return "", true, err
+		return "", enrollErrorClassInvalid, fmt.Errorf("enrollment invalid: %w", err)
 	}
 
 	// Save newly acquired node key if successful
 	err = e.knapsack.ConfigStore().Set(storage.KeyByIdentifier([]byte(nodeKeyKey), storage.IdentifierTypeRegistration, []byte(e.registrationId)), []byte(keyString))
 	if err != nil {
-		return "", true, fmt.Errorf("saving node key: %w", err)
+		return "", enrollErrorClassStorage, fmt.Errorf("saving node key: %w", err)
 	}
 
-	e.NodeKey = keyString
+	if keyProof != nil {
+		if err := keys.StoreProvenKey(e.knapsack.ConfigStore(), e.registrationId, keyProof); err != nil {
+			e.slogger.Log(ctx, slog.LevelWarn,
+				"could not store proven hardware key",
+				"err", err,
+			)
+		}
+	}
 
-	e.slogger.Log(ctx, slog.LevelInfo,
-		"completed enrollment",
-	)
-	span.AddEvent("completed_enrollment")
+	return keyString, "", nil
+}
 
-	return e.NodeKey, false, nil
+// recordEnrollmentAttempt updates the enrollment backoff after an attempt, and adds the
+// attempt to the history in the enrollment attempts store.
+func (e *Extension) recordEnrollmentAttempt(ctx context.Context, errorClass string, enrollErr error) {
+	now := time.Now()
+	attempt := EnrollmentAttempt{
+		Time:           now.Unix(),
+		RegistrationId: e.registrationId,
+		Success:        enrollErr == nil,
+	}
+
+	if enrollErr == nil {
+		e.enrollmentRetry.succeeded()
+	} else {
+		nextAttempt := e.enrollmentRetry.failed(now)
+		attempt.ErrorClass = errorClass
+		attempt.Error = enrollErr.Error()
+		attempt.ConsecutiveFailures = e.enrollmentRetry.consecutiveFailures
+		attempt.NextAttempt = nextAttempt.Unix()
+
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"enrollment failed, backing off before retrying",
+			"error_class", errorClass,
+			"consecutive_failures", attempt.ConsecutiveFailures,
+			"next_attempt", nextAttempt.UTC().Format(time.RFC3339),
+			"err", enrollErr,
+		)
+	}
+
+	store := e.knapsack.EnrollmentAttemptsStore()
+	if store == nil {
+		return
+	}
+	if err := recordEnrollmentAttempt(store, now, attempt); err != nil {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"could not record enrollment attempt",
+			"err", err,
+		)
+	}
+}
+
+// checkProvenKey warns if the hardware key no longer matches the one proven at
+// enrollment time -- for example, if the TPM was cleared. The server will be unable to
+// verify requests signed by the new key against this enrollment.
+func (e *Extension) checkProvenKey(ctx context.Context) {
+	if !e.knapsack.HardwareKeyProof() {
+		return
+	}
+
+	matches, err := keys.ProvenKeyMatches(e.knapsack.ConfigStore(), e.registrationId, agent.HardwareKeys())
+	if err != nil {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"could not check proven hardware key",
+			"err", err,
+		)
+		return
+	}
+
+	if !matches {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"hardware key has changed since enrollment",
+			"hardware_key_source", agent.HardwareKeys().Type(),
+		)
+	}
 }
 
 func (e *Extension) enrolled() bool {
@@ -554,6 +794,12 @@ func (e *Extension) GenerateConfigs(ctx context.Context) (map[string]string, err
 				"err", err,
 			)
 		}
+		if err := e.knapsack.ConfigStore().Set(storage.KeyByIdentifier([]byte(lastConfigCheckinKey), storage.IdentifierTypeRegistration, []byte(e.registrationId)), []byte(strconv.FormatInt(time.Now().Unix(), 10))); err != nil {
+			e.slogger.Log(ctx, slog.LevelError,
+				"writing last config checkin to config store",
+				"err", err,
+			)
+		}
 		if err := e.settingsWriter.WriteSettings(); err != nil {
 			e.slogger.Log(ctx, slog.LevelError,
 				"writing config to startup settings",
@@ -562,7 +808,101 @@ func (e *Extension) GenerateConfigs(ctx context.Context) (map[string]string, err
 		}
 	}
 
-	return map[string]string{"config": config}, nil
+	// Scheduled queries using tables the user hasn't consented to are removed here, rather than
+	// from the stored config, so that they run once the user consents
+	fragments := []configmerge.Fragment{{Source: e.registrationId, Config: e.gateConfig(ctx, config)}}
+
+	// The FIM paths configured via the control server are added alongside the config from
+	// the osquery server, which takes precedence
+	if fimConfig := e.fimConfig(ctx); fimConfig != "" {
+		fragments = append(fragments, configmerge.Fragment{Source: fimConfigName, Config: fimConfig})
+	}
+
+	return map[string]string{"config": e.mergeConfigs(ctx, fragments)}, nil
+}
+
+// mergeConfigs merges the config fragments into a single config, rather than leaving osquery
+// to merge them, so that conflicts are resolved deterministically. Conflicts are published
+// when they change.
+func (e *Extension) mergeConfigs(ctx context.Context, fragments []configmerge.Fragment) string {
+	merged, conflicts, errs := configmerge.Merge(fragments)
+	for _, err := range errs {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"could not merge osquery config fragment",
+			"err", err,
+		)
+	}
+
+	var reportable string
+	if len(conflicts) > 0 {
+		conflictsRaw, err := json.Marshal(conflicts)
+		if err != nil {
+			e.slogger.Log(ctx, slog.LevelWarn,
+				"could not marshal osquery config conflicts",
+				"err", err,
+			)
+			return merged
+		}
+		reportable = string(conflictsRaw)
+	}
+
+	e.configConflictsLock.Lock()
+	changed := reportable != e.lastConfigConflicts
+	e.lastConfigConflicts = reportable
+	e.configConflictsLock.Unlock()
+
+	if changed {
+		sources := make([]string, 0, len(fragments))
+		for _, f := range fragments {
+			sources = append(sources, f.Source)
+		}
+
+		e.slogger.Log(ctx, slog.LevelInfo,
+			"osquery config conflicts changed",
+			"sources", sources,
+			"conflict_count", len(conflicts),
+		)
+		configmerge.Publish(ctx, e.slogger, configmerge.Report{
+			RegistrationID: e.registrationId,
+			Sources:        sources,
+			Conflicts:      conflicts,
+		})
+	}
+
+	return merged
+}
+
+// fimConfig returns the osquery config for the FIM spec sent by the control server, or
+// an empty string if FIM is not configured.
+func (e *Extension) fimConfig(ctx context.Context) string {
+	store := e.knapsack.FimConfigStore()
+	if store == nil {
+		return ""
+	}
+
+	effective, err := fim.LoadEffective(store, runtime.GOOS)
+	if err != nil {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"could not load FIM config",
+			"err", err,
+		)
+		return ""
+	}
+
+	if !effective.Enabled() {
+		return ""
+	}
+
+	fimConfig, err := effective.OsqueryConfig()
+	if err != nil {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"could not generate FIM config",
+			"err", err,
+		)
+		return ""
+	}
+
+	return fimConfig
 }
 
 // TODO: https://github.com/kolide/launcher/issues/366
@@ -715,21 +1055,28 @@ func storeForLogType(s types.Stores, typ logger.LogType) (types.KVStore, error)
 // logs over the maximum count will be purged to avoid unbounded growth of the
 // buffers.
 func (e *Extension) writeAndPurgeLogs() {
-	for _, typ := range []logger.LogType{logger.LogTypeStatus, logger.LogTypeString} {
-		originalBatchState := e.logPublicationState.CurrentValues()
-		// Write logs
-		err := e.writeBufferedLogsForType(typ)
-		if err != nil {
-			e.slogger.Log(context.TODO(), slog.LevelInfo,
-				"sending logs",
-				"type", typ.String(),
-				"attempted_publication_state", originalBatchState,
-				"err", err,
-			)
+	logTypes := []logger.LogType{logger.LogTypeStatus, logger.LogTypeString}
+
+	// When there are only a few logs of each type, send them together in one request
+	if !e.writeCoalescedLogs(logTypes) {
+		for _, typ := range logTypes {
+			originalBatchState := e.logPublicationState.CurrentValues()
+			// Write logs
+			err := e.writeBufferedLogsForType(typ)
+			if err != nil {
+				e.slogger.Log(context.TODO(), slog.LevelInfo,
+					"sending logs",
+					"type", typ.String(),
+					"attempted_publication_state", originalBatchState,
+					"err", err,
+				)
+			}
 		}
+	}
 
+	for _, typ := range logTypes {
 		// Purge overflow
-		err = e.purgeBufferedLogsForType(typ)
+		err := e.purgeBufferedLogsForType(typ)
 		if err != nil {
 			e.slogger.Log(context.TODO(), slog.LevelInfo,
 				"purging logs",
@@ -740,20 +1087,72 @@ func (e *Extension) writeAndPurgeLogs() {
 	}
 }
 
+// bufferedLogBatch is a batch of logs read from the buffer for a single log type, ready to send.
+type bufferedLogBatch struct {
+	typ          logger.LogType
+	store        types.KVStore
+	logs         []string
+	logIDs       [][]byte
+	totalBytes   int
+	bufferFilled bool // true if there were more logs in the buffer than fit in the batch
+}
+
 // writeBufferedLogs flushes the log buffers, writing up to
 // Opts.MaxBytesPerBatch bytes worth of logs in one run. If the logs write
 // successfully, they will be deleted from the buffer.
 func (e *Extension) writeBufferedLogsForType(typ logger.LogType) error {
-	store, err := storeForLogType(e.knapsack, typ)
+	batch, err := e.readBufferedLogsForType(typ)
 	if err != nil {
 		return err
 	}
 
+	if len(batch.logs) == 0 {
+		// Nothing to send
+		return nil
+	}
+
+	// inform the publication state tracking whether this batch should be used to
+	// determine the appropriate limit
+	e.logPublicationState.BeginBatch(time.Now(), batch.bufferFilled)
+	publicationCtx := context.WithValue(context.Background(),
+		service.PublicationCtxKey,
+		e.logPublicationState.CurrentValues(),
+	)
+	err = e.writeLogsWithReenroll(publicationCtx, typ, batch.logs, true)
+	if err != nil {
+		return fmt.Errorf("writing logs: %w", err)
+	}
+
+	// Delete logs that were successfully sent
+	err = batch.store.Delete(batch.logIDs...)
+
+	if err != nil {
+		return fmt.Errorf("deleting sent logs: %w", err)
+	}
+
+	return nil
+}
+
+// readBufferedLogsForType reads up to Opts.MaxBytesPerBatch bytes worth of logs of the given
+// type from the buffer.
+func (e *Extension) readBufferedLogsForType(typ logger.LogType) (*bufferedLogBatch, error) {
+	store, err := storeForLogType(e.knapsack, typ)
+	if err != nil {
+		return nil, err
+	}
+
 	// Collect up logs to be sent
-	var logs []string
-	var logIDs [][]byte
-	bufferFilled := false
-	totalBytes := 0
+	batch := &bufferedLogBatch{
+		typ:   typ,
+		store: store,
+	}
+
+	// Snapshot results are sent as string logs
+	var dedup *snapshotDeduplicator
+	if typ == logger.LogTypeString && e.currentOpts().DeduplicateSnapshotResults {
+		dedup = newSnapshotDeduplicator()
+	}
+
 	err = store.ForEach(func(k, v []byte) error {
 		// A somewhat cumbersome if block...
 		//
@@ -769,15 +1168,19 @@ func (e *Extension) writeBufferedLogsForType(typ logger.LogType) error {
 				"dropped log",
 				"log_id", k,
 				"size", len(v),
-				"limit", e.Opts.MaxBytesPerBatch,
+				"limit", e.currentOpts().MaxBytesPerBatch,
 				"loghead", string(v)[0:logheadSize],
 			)
-		} else if e.logPublicationState.ExceedsCurrentBatchThreshold(totalBytes + len(v)) {
+		} else if e.logPublicationState.ExceedsCurrentBatchThreshold(batch.totalBytes + len(v)) {
 			// Buffer is filled. Break the loop and come back later.
 			return iterationTerminatedError{}
+		} else if repeat, extraBytes := dedup.isRepeat(v, len(batch.logs)); repeat {
+			// Counted against an earlier log in the batch, so there's nothing to send --
+			// but it's deleted along with the rest of the batch.
+			batch.totalBytes += extraBytes
 		} else {
-			logs = append(logs, string(v))
-			totalBytes += len(v)
+			batch.logs = append(batch.logs, string(v))
+			batch.totalBytes += len(v)
 		}
 
 		// Note the logID for deletion. We do this by
@@ -789,41 +1192,119 @@ func (e *Extension) writeBufferedLogsForType(typ logger.LogType) error {
 		// the server.
 		logID := make([]byte, len(k))
 		copy(logID, k)
-		logIDs = append(logIDs, logID)
+		batch.logIDs = append(batch.logIDs, logID)
 		return nil
 	})
 
 	if err != nil && errors.Is(err, iterationTerminatedError{}) {
-		bufferFilled = true
+		batch.bufferFilled = true
 	} else if err != nil {
-		return fmt.Errorf("reading buffered logs: %w", err)
+		return nil, fmt.Errorf("reading buffered logs: %w", err)
 	}
 
-	if len(logs) == 0 {
-		// Nothing to send
-		return nil
+	if dedup != nil {
+		dedup.annotate(batch.logs)
 	}
 
-	// inform the publication state tracking whether this batch should be used to
-	// determine the appropriate limit
-	e.logPublicationState.BeginBatch(time.Now(), bufferFilled)
+	return batch, nil
+}
+
+// writeCoalescedLogs sends the buffered logs of the given types in a single request, if the
+// service client can batch requests and all the logs fit within a single batch. It returns
+// false, having sent nothing, if the logs should be sent separately instead.
+func (e *Extension) writeCoalescedLogs(logTypes []logger.LogType) bool {
+	publisher, ok := e.serviceClient.(service.LogBatchPublisher)
+	if !ok {
+		return false
+	}
+
+	var batches []*bufferedLogBatch
+	totalBytes := 0
+	for _, typ := range logTypes {
+		batch, err := e.readBufferedLogsForType(typ)
+		if err != nil || batch.bufferFilled {
+			return false
+		}
+		if len(batch.logs) == 0 {
+			continue
+		}
+		batches = append(batches, batch)
+		totalBytes += batch.totalBytes
+	}
+
+	if len(batches) < 2 || e.logPublicationState.ExceedsCurrentBatchThreshold(totalBytes) {
+		return false
+	}
+
+	serviceBatches := make([]service.LogBatch, len(batches))
+	var allLogs []string
+	for i, batch := range batches {
+		serviceBatches[i] = service.LogBatch{LogType: batch.typ, Logs: batch.logs}
+		allLogs = append(allLogs, batch.logs...)
+	}
+
+	// grab a reference to the existing nodekey to prevent data races with any re-enrollments
+	e.enrollMutex.Lock()
+	nodeKey := e.NodeKey
+	e.enrollMutex.Unlock()
+
+	e.logPublicationState.BeginBatch(time.Now(), false)
 	publicationCtx := context.WithValue(context.Background(),
 		service.PublicationCtxKey,
 		e.logPublicationState.CurrentValues(),
 	)
-	err = e.writeLogsWithReenroll(publicationCtx, typ, logs, true)
-	if err != nil {
-		return fmt.Errorf("writing logs: %w", err)
-	}
+	results := publisher.PublishLogBatches(publicationCtx, nodeKey, serviceBatches)
+
+	allSent := true
+	var invalid []*bufferedLogBatch
+	for i, result := range results {
+		if errors.Is(result.Err, service.ErrDeviceDisabled{}) {
+			uninstall.Uninstall(publicationCtx, e.knapsack, true)
+			// the uninstall call above will cause launcher to uninstall and exit
+			return true
+		}
 
-	// Delete logs that were successfully sent
-	err = store.Delete(logIDs...)
+		if result.NodeInvalid || isNodeInvalidErr(result.Err) {
+			allSent = false
+			invalid = append(invalid, batches[i])
+			continue
+		}
 
-	if err != nil {
-		return fmt.Errorf("deleting sent logs: %w", err)
+		if result.Err != nil {
+			// The logs stay buffered, to be sent next time
+			allSent = false
+			e.slogger.Log(context.TODO(), slog.LevelInfo,
+				"sending logs",
+				"type", batches[i].typ.String(),
+				"coalesced", true,
+				"err", result.Err,
+			)
+			continue
+		}
+
+		// Delete logs that were successfully sent
+		if err := batches[i].store.Delete(batches[i].logIDs...); err != nil {
+			e.slogger.Log(context.TODO(), slog.LevelInfo,
+				"deleting sent logs",
+				"type", batches[i].typ.String(),
+				"err", err,
+			)
+		}
 	}
+	e.logPublicationState.EndBatch(allLogs, allSent)
 
-	return nil
+	// Logs rejected for an invalid node key are retried on their own, which handles reenrollment
+	for _, batch := range invalid {
+		if err := e.writeBufferedLogsForType(batch.typ); err != nil {
+			e.slogger.Log(context.TODO(), slog.LevelInfo,
+				"sending logs",
+				"type", batch.typ.String(),
+				"err", err,
+			)
+		}
+	}
+
+	return true
 }
 
 // Helper to allow for a single attempt at re-enrollment
@@ -887,7 +1368,7 @@ func (e *Extension) purgeBufferedLogsForType(typ logger.LogType) error {
 		return err
 	}
 
-	deleteCount := totalCount - e.Opts.MaxBufferedLogs
+	deleteCount := totalCount - e.currentOpts().MaxBufferedLogs
 	if deleteCount <= 0 { // Limit not exceeded
 		return nil
 	}
@@ -930,18 +1411,103 @@ func (e *Extension) LogString(ctx context.Context, typ logger.LogType, logText s
 		return fmt.Errorf("unknown log type: %w", err)
 	}
 
+	if typ == logger.LogTypeStatus {
+		e.statusLogMirror.log(ctx, logText)
+		e.observeWatchdogEvents(ctx, logText)
+	}
+
 	// Buffer the log for sending later in a batch
 	// note that AppendValues guarantees these logs are inserted with
 	// sequential keys for ordered retrieval later
 	return store.AppendValues([]byte(logText))
 }
 
+// observeWatchdogEvents checks the given status log for osquery's watchdog killing its worker.
+func (e *Extension) observeWatchdogEvents(ctx context.Context, logText string) {
+	if e.watchdogEvents == nil {
+		return
+	}
+
+	var status osqueryStatusLine
+	if err := json.Unmarshal([]byte(logText), &status); err != nil {
+		return
+	}
+
+	e.watchdogEvents.Observe(ctx, status.Message, time.Now())
+}
+
 // GetQueries will request the distributed queries to execute from the server.
+// Queries queued for retry after failing transiently are added.
+// Any queries denied by policy, or using tables the user hasn't consented to, are removed,
+// and reported to the server as denied.
 func (e *Extension) GetQueries(ctx context.Context) (*distributed.GetQueriesResult, error) {
 	ctx, span := traces.StartSpan(ctx)
 	defer span.End()
 
-	return e.getQueriesWithReenroll(ctx, true)
+	queries, err := e.getQueriesWithReenroll(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retries are added first, so that they're checked against the denylist and consent too
+	e.retryQueries(ctx, queries)
+	e.denyQueries(ctx, queries)
+	e.gateQueries(ctx, queries)
+	e.queryAccounting.Start(queries, time.Now())
+	tablehelpers.SetInteractiveQueriesPending(e.registrationId, len(e.queryAccounting.Pending()) > 0)
+
+	return queries, nil
+}
+
+// denyQueries removes distributed queries matching the denylist, audit logs them, and reports
+// them to the server as denied by policy.
+func (e *Extension) denyQueries(ctx context.Context, queries *distributed.GetQueriesResult) {
+	denylist, errs := newQueryDenylist(e.knapsack.DistributedQueryDenylist())
+	for _, err := range errs {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"invalid distributed query denylist pattern",
+			"err", err,
+		)
+	}
+
+	denied := denylist.filter(queries)
+	if len(denied) == 0 {
+		return
+	}
+
+	for _, d := range denied {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"distributed query denied by policy",
+			"query_name", d.name,
+			"pattern", d.pattern,
+		)
+	}
+
+	e.queryRetries.drop(e.registrationId, deniedNames(denied))
+	if err := e.writeResultsWithReenroll(ctx, deniedResults(denied, deniedQueryMessage), true); err != nil {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"could not report denied distributed queries",
+			"denied_count", len(denied),
+			"err", err,
+		)
+	}
+}
+
+// retryQueries adds the distributed queries queued for retry to queries. Queued retries that
+// didn't run within the retry window are reported to the server with their original failure.
+func (e *Extension) retryQueries(ctx context.Context, queries *distributed.GetQueriesResult) {
+	expired := e.queryRetries.prepare(e.registrationId, queries, time.Now())
+	if len(expired) == 0 {
+		return
+	}
+
+	if err := e.writeResultsWithReenroll(ctx, expired, true); err != nil {
+		e.slogger.Log(ctx, slog.LevelWarn,
+			"could not report failed distributed queries after retry window expired",
+			"failed_count", len(expired),
+			"err", err,
+		)
+	}
 }
 
 // Helper to allow for a single attempt at re-enrollment
@@ -1000,12 +1566,53 @@ func (e *Extension) getQueriesWithReenroll(ctx context.Context, reenroll bool) (
 }
 
 // WriteResults will publish results of the executed distributed queries back
-// to the server.
+// to the server, along with the accounting of their execution. Queries that failed
+// because osquery was briefly unavailable are held back and queued for retry.
 func (e *Extension) WriteResults(ctx context.Context, results []distributed.Result) error {
 	ctx, span := traces.StartSpan(ctx)
 	defer span.End()
 
-	return e.writeResultsWithReenroll(ctx, results, true)
+	finished := time.Now()
+	accounting := e.queryAccounting.Finish(results, finished)
+	recordQuerySpans(ctx, accounting, finished)
+	tablehelpers.SetInteractiveQueriesPending(e.registrationId, len(e.queryAccounting.Pending()) > 0)
+	ctx = context.WithValue(ctx, service.QueryAccountingCtxKey, accounting)
+
+	toReport := e.queryRetries.filter(e.registrationId, results, time.Now())
+	if retried := len(results) - len(toReport); retried > 0 {
+		e.slogger.Log(ctx, slog.LevelInfo,
+			"queued distributed queries for retry after transient failure",
+			"retry_count", retried,
+		)
+		if len(toReport) == 0 {
+			return nil
+		}
+	}
+
+	return e.writeResultsWithReenroll(ctx, toReport, true)
+}
+
+// recordQuerySpans traces each distributed query's execution, from when it was handed to osquery
+// until its results came back. Queries that weren't handed out by GetQueries have no start, and
+// aren't traced.
+func recordQuerySpans(ctx context.Context, accounting []queryaccounting.Entry, finished time.Time) {
+	for _, entry := range accounting {
+		if entry.StartTime.IsZero() {
+			continue
+		}
+
+		var err error
+		if entry.Status != 0 {
+			err = fmt.Errorf("query failed with status %d: %s", entry.Status, entry.Error)
+		}
+
+		traces.RecordSpan(ctx, "distributed_query", entry.StartTime, finished, err,
+			"query_name", entry.QueryName,
+			"row_count", entry.RowCount,
+			"status", entry.Status,
+			"osquery_wall_time_ms", entry.OsqueryWallTimeMs,
+		)
+	}
 }
 
 // Helper to allow for a single attempt at re-enrollment
@@ -11,6 +11,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"sync"
 	"testing"
@@ -26,6 +28,7 @@ import (
 	"github.com/kolide/launcher/ee/agent/types/mocks"
 	"github.com/kolide/launcher/pkg/log/multislogger"
 	settingsstoremock "github.com/kolide/launcher/pkg/osquery/mocks"
+	"github.com/kolide/launcher/pkg/osquery/queryaccounting"
 	"github.com/kolide/launcher/pkg/service"
 	"github.com/kolide/launcher/pkg/service/mock"
 	"github.com/osquery/osquery-go/plugin/distributed"
@@ -60,7 +63,13 @@ func makeKnapsack(t *testing.T, db *bbolt.DB) types.Knapsack {
 	m.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
 	m.On("Slogger").Return(multislogger.NewNopLogger())
 	m.On("ReadEnrollSecret").Maybe().Return("enroll_secret", nil)
+	m.On("HardwareKeyProof").Maybe().Return(false)
 	m.On("RootDirectory").Maybe().Return("whatever")
+	m.On("DistributedQueryDenylist").Maybe().Return([]string{})
+	m.On("FimConfigStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.FimConfigStore.String()))
+	m.On("EnrollmentAttemptsStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.EnrollmentAttemptsStore.String()))
+	m.On("ControlStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ControlStore.String()))
+	m.On("PersistentHostDataStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.PersistentHostDataStore.String()))
 	return m
 }
 
@@ -71,6 +80,7 @@ func TestNewExtensionEmptyEnrollSecret(t *testing.T) {
 	m.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
 	m.On("Slogger").Return(multislogger.NewNopLogger())
 	m.On("ReadEnrollSecret").Maybe().Return("", errors.New("test"))
+	m.On("HardwareKeyProof").Maybe().Return(false)
 
 	// We should be able to make an extension despite an empty enroll secret
 	e, err := NewExtension(context.TODO(), &mock.KolideService{}, settingsstoremock.NewSettingsStoreWriter(t), m, ulid.New(), ExtensionOpts{})
@@ -182,6 +192,91 @@ func TestExtensionEnrollTransportError(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestExtensionEnrollBackoff(t *testing.T) {
+	t.Parallel()
+
+	m := &mock.KolideService{
+		RequestEnrollmentFunc: func(ctx context.Context, enrollSecret, hostIdentifier string, details service.EnrollmentDetails) (string, bool, error) {
+			return "", false, errors.New("transport")
+		},
+	}
+
+	db, cleanup := makeTempDB(t)
+	defer cleanup()
+	k := makeKnapsack(t, db)
+
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, types.DefaultRegistrationID, ExtensionOpts{})
+	require.NoError(t, err)
+
+	// The first attempt fails
+	_, invalid, err := e.Enroll(context.Background())
+	require.True(t, m.RequestEnrollmentFuncInvoked)
+	require.True(t, invalid)
+	require.Error(t, err)
+
+	// Retrying right away should not reach the server
+	m.RequestEnrollmentFuncInvoked = false
+	_, invalid, err = e.Enroll(context.Background())
+	require.False(t, m.RequestEnrollmentFuncInvoked)
+	require.True(t, invalid)
+	require.ErrorIs(t, err, errEnrollmentBackoff)
+
+	// Once the backoff has passed, we retry, and succeed
+	e.enrollmentRetry.nextAttempt = time.Now().Add(-1 * time.Second)
+	m.RequestEnrollmentFunc = func(ctx context.Context, enrollSecret, hostIdentifier string, details service.EnrollmentDetails) (string, bool, error) {
+		return "node_key", false, nil
+	}
+	key, invalid, err := e.Enroll(context.Background())
+	require.True(t, m.RequestEnrollmentFuncInvoked)
+	require.False(t, invalid)
+	require.NoError(t, err)
+	require.Equal(t, "node_key", key)
+	require.True(t, e.enrollmentRetry.ready(time.Now()), "backoff should be reset after success")
+
+	// Both attempts that reached the server are in the history
+	attempts, err := EnrollmentAttempts(k.EnrollmentAttemptsStore())
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+
+	require.False(t, attempts[0].Success)
+	require.Equal(t, types.DefaultRegistrationID, attempts[0].RegistrationId)
+	require.Equal(t, enrollErrorClassTransport, attempts[0].ErrorClass)
+	require.Equal(t, 1, attempts[0].ConsecutiveFailures)
+	require.Greater(t, attempts[0].NextAttempt, attempts[0].Time)
+
+	require.True(t, attempts[1].Success)
+	require.Empty(t, attempts[1].ErrorClass)
+	require.Zero(t, attempts[1].NextAttempt)
+}
+
+func TestExtensionEnrollHardwareKeyProofWithoutHardwareKey(t *testing.T) {
+	m := &mock.KolideService{
+		RequestEnrollmentFunc: func(ctx context.Context, enrollSecret, hostIdentifier string, details service.EnrollmentDetails) (string, bool, error) {
+			return "node_key", false, nil
+		},
+	}
+
+	k := mocks.NewKnapsack(t)
+	k.On("OsquerydPath").Maybe().Return("")
+	k.On("LatestOsquerydPath", testifymock.Anything).Maybe().Return("")
+	k.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("ReadEnrollSecret").Maybe().Return("enroll_secret", nil)
+	k.On("HardwareKeyProof").Return(true)
+	k.On("EnrollmentAttemptsStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.EnrollmentAttemptsStore.String()))
+
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, types.DefaultRegistrationID, ExtensionOpts{})
+	require.Nil(t, err)
+
+	// Without a hardware key there is nothing to prove possession of, so we should fail
+	// before ever requesting enrollment.
+	key, invalid, err := e.Enroll(context.Background())
+	assert.False(t, m.RequestEnrollmentFuncInvoked)
+	assert.Equal(t, "", key)
+	assert.True(t, invalid)
+	assert.ErrorContains(t, err, "hardware key")
+}
+
 func TestExtensionEnrollSecretInvalid(t *testing.T) {
 
 	m := &mock.KolideService{
@@ -221,6 +316,8 @@ func TestExtensionEnroll(t *testing.T) {
 	k.On("Slogger").Return(multislogger.NewNopLogger())
 	expectedEnrollSecret := "foo_secret"
 	k.On("ReadEnrollSecret").Maybe().Return(expectedEnrollSecret, nil)
+	k.On("HardwareKeyProof").Maybe().Return(false)
+	k.On("EnrollmentAttemptsStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.EnrollmentAttemptsStore.String()))
 
 	e, err := NewExtension(context.TODO(), m, s, k, types.DefaultRegistrationID, ExtensionOpts{})
 	require.Nil(t, err)
@@ -298,7 +395,8 @@ func TestExtensionGenerateConfigsCaching(t *testing.T) {
 	k := makeKnapsack(t, db)
 	s := settingsstoremock.NewSettingsStoreWriter(t)
 	s.On("WriteSettings").Return(nil)
-	e, err := NewExtension(context.TODO(), m, s, k, ulid.New(), ExtensionOpts{})
+	registrationId := ulid.New()
+	e, err := NewExtension(context.TODO(), m, s, k, registrationId, ExtensionOpts{})
 	require.Nil(t, err)
 
 	configs, err := e.GenerateConfigs(context.Background())
@@ -306,6 +404,11 @@ func TestExtensionGenerateConfigsCaching(t *testing.T) {
 	assert.Equal(t, map[string]string{"config": configVal}, configs)
 	assert.Nil(t, err)
 
+	// The successful request should be recorded
+	lastCheckin, err := LastConfigCheckin(k.ConfigStore(), registrationId)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), lastCheckin, time.Minute)
+
 	// Now have requesting the config fail, and expect to get the same
 	// config anyway (through the cache).
 	m.RequestConfigFuncInvoked = false
@@ -361,6 +464,8 @@ func TestGenerateConfigs_CannotEnrollYet(t *testing.T) {
 	k.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
 	k.On("Slogger").Return(multislogger.NewNopLogger())
 	k.On("ReadEnrollSecret").Maybe().Return("", errors.New("test"))
+	k.On("HardwareKeyProof").Maybe().Return(false)
+	k.On("EnrollmentAttemptsStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.EnrollmentAttemptsStore.String()))
 
 	e, err := NewExtension(context.TODO(), s, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
 	require.Nil(t, err)
@@ -402,6 +507,43 @@ func TestExtensionGenerateConfigs(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestExtensionGenerateConfigs_WithFim(t *testing.T) {
+
+	configVal := `{"foo":"bar","options":{"distributed_interval":5,"verbose":true}}`
+	m := &mock.KolideService{
+		RequestConfigFunc: func(ctx context.Context, nodeKey string) (string, bool, error) {
+			return configVal, false, nil
+		},
+	}
+	db, cleanup := makeTempDB(t)
+	defer cleanup()
+	k := makeKnapsack(t, db)
+	fimPath := "/etc/%%"
+	if runtime.GOOS == "windows" {
+		fimPath = "C:/Windows/%%"
+	}
+	require.NoError(t, k.FimConfigStore().Set([]byte("spec"), []byte(fmt.Sprintf(`{"categories":{"system":{"paths":[%q]}}}`, fimPath))))
+	s := settingsstoremock.NewSettingsStoreWriter(t)
+	s.On("WriteSettings").Return(nil)
+	e, err := NewExtension(context.TODO(), m, s, k, ulid.New(), ExtensionOpts{})
+	require.Nil(t, err)
+
+	configs, err := e.GenerateConfigs(context.Background())
+	require.NoError(t, err)
+
+	// The FIM config is merged into the config from the server
+	require.Len(t, configs, 1)
+	var merged struct {
+		Foo       string              `json:"foo"`
+		Options   map[string]any      `json:"options"`
+		FilePaths map[string][]string `json:"file_paths"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(configs["config"]), &merged))
+	require.Equal(t, "bar", merged.Foo)
+	require.Equal(t, map[string]any{"distributed_interval": float64(5), "verbose": true}, merged.Options)
+	require.Equal(t, []string{filepath.FromSlash(fimPath)}, merged.FilePaths["system"])
+}
+
 func TestExtensionWriteLogsTransportError(t *testing.T) {
 
 	m := &mock.KolideService{
@@ -539,6 +681,8 @@ func TestExtensionWriteBufferedLogsEmpty(t *testing.T) {
 	k.On("Slogger").Return(multislogger.NewNopLogger()).Maybe()
 	k.On("StatusLogsStore").Return(statusLogsStore)
 	k.On("ReadEnrollSecret").Maybe().Return("enroll_secret", nil)
+	k.On("HardwareKeyProof").Maybe().Return(false)
+	k.On("EnrollmentAttemptsStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.EnrollmentAttemptsStore.String()))
 
 	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
 	require.Nil(t, err)
@@ -579,6 +723,8 @@ func TestExtensionWriteBufferedLogs(t *testing.T) {
 	k.On("StatusLogsStore").Return(statusLogsStore)
 	k.On("ResultLogsStore").Return(resultLogsStore)
 	k.On("ReadEnrollSecret").Maybe().Return("enroll_secret", nil)
+	k.On("HardwareKeyProof").Maybe().Return(false)
+	k.On("EnrollmentAttemptsStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.EnrollmentAttemptsStore.String()))
 
 	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
 	require.Nil(t, err)
@@ -620,6 +766,71 @@ func TestExtensionWriteBufferedLogs(t *testing.T) {
 	assert.Nil(t, gotResultLogs)
 }
 
+// batchingKolideService is a mock service client that can publish log batches
+type batchingKolideService struct {
+	*mock.KolideService
+	batches [][]service.LogBatch
+}
+
+func (s *batchingKolideService) PublishLogBatches(ctx context.Context, nodeKey string, batches []service.LogBatch) []service.LogBatchResult {
+	s.batches = append(s.batches, batches)
+	return make([]service.LogBatchResult, len(batches))
+}
+
+func TestExtensionWriteCoalescedLogs(t *testing.T) {
+	t.Parallel()
+
+	var gotStatusLogs []string
+	m := &batchingKolideService{
+		KolideService: &mock.KolideService{
+			PublishLogsFunc: func(ctx context.Context, nodeKey string, logType logger.LogType, logs []string) (string, string, bool, error) {
+				require.Equal(t, logger.LogTypeStatus, logType, "only lone status logs should be published separately")
+				gotStatusLogs = logs
+				return "", "", false, nil
+			},
+		},
+	}
+
+	statusLogsStore, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.StatusLogsStore.String())
+	require.NoError(t, err)
+	resultLogsStore, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.ResultLogsStore.String())
+	require.NoError(t, err)
+
+	k := mocks.NewKnapsack(t)
+	k.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
+	k.On("Slogger").Return(multislogger.NewNopLogger()).Maybe()
+	k.On("StatusLogsStore").Return(statusLogsStore)
+	k.On("ResultLogsStore").Return(resultLogsStore)
+
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
+	require.NoError(t, err)
+
+	e.LogString(context.Background(), logger.LogTypeStatus, "status foo")
+	e.LogString(context.Background(), logger.LogTypeString, "result foo")
+	e.LogString(context.Background(), logger.LogTypeString, "result bar")
+
+	// Both types are sent together
+	e.writeAndPurgeLogs()
+	require.Equal(t, [][]service.LogBatch{
+		{
+			{LogType: logger.LogTypeStatus, Logs: []string{"status foo"}},
+			{LogType: logger.LogTypeString, Logs: []string{"result foo", "result bar"}},
+		},
+	}, m.batches)
+	require.False(t, m.PublishLogsFuncInvoked)
+
+	// and were removed from the buffers
+	e.writeAndPurgeLogs()
+	require.Len(t, m.batches, 1)
+	require.False(t, m.PublishLogsFuncInvoked)
+
+	// With logs of only one type, there's nothing to coalesce
+	e.LogString(context.Background(), logger.LogTypeStatus, "status bar")
+	e.writeAndPurgeLogs()
+	require.Len(t, m.batches, 1)
+	require.Equal(t, []string{"status bar"}, gotStatusLogs)
+}
+
 func TestExtensionWriteBufferedLogsEnrollmentInvalid(t *testing.T) {
 	// Test for https://github.com/kolide/launcher/issues/219 in which a
 	// call to writeBufferedLogsForType with an invalid node key causes a
@@ -648,6 +859,8 @@ func TestExtensionWriteBufferedLogsEnrollmentInvalid(t *testing.T) {
 	k.On("LatestOsquerydPath", testifymock.Anything).Maybe().Return("")
 	k.On("Slogger").Return(multislogger.NewNopLogger())
 	k.On("ReadEnrollSecret").Maybe().Return("enroll_secret", nil)
+	k.On("HardwareKeyProof").Maybe().Return(false)
+	k.On("EnrollmentAttemptsStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.EnrollmentAttemptsStore.String()))
 
 	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
 	require.Nil(t, err)
@@ -813,6 +1026,75 @@ func TestExtensionWriteBufferedLogsDropsBigLog(t *testing.T) {
 	require.Equal(t, 0, finalLogCount, "no more queued logs")
 }
 
+func TestExtensionWriteBufferedLogsDeduplicatesSnapshots(t *testing.T) {
+	var gotResultLogs []string
+	m := &mock.KolideService{
+		PublishLogsFunc: func(ctx context.Context, nodeKey string, logType logger.LogType, logs []string) (string, string, bool, error) {
+			gotResultLogs = logs
+			return "", "", false, nil
+		},
+	}
+
+	resultLogsStore, err := storageci.NewStore(t, multislogger.NewNopLogger(), storage.ResultLogsStore.String())
+	require.NoError(t, err)
+
+	k := mocks.NewKnapsack(t)
+	k.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("ResultLogsStore").Return(resultLogsStore)
+
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{
+		DeduplicateSnapshotResults: true,
+	})
+	require.Nil(t, err)
+
+	snapshot := func(name string, rows string, decorations string, unixTime int) string {
+		return fmt.Sprintf(`{"action":"snapshot","decorations":%s,"name":"%s","snapshot":%s,"unixTime":%d}`, decorations, name, rows, unixTime)
+	}
+	hostA := `{"hostname":"a"}`
+	hostB := `{"hostname":"b"}`
+
+	logs := []string{
+		snapshot("users", `[{"uid":"501"}]`, hostA, 100),
+		snapshot("apps", `[{"name":"Safari"}]`, hostA, 100),
+		snapshot("users", `[{"uid":"501"}]`, hostA, 200), // repeats the first log
+		"not a snapshot",
+		snapshot("users", `[{"uid":"501"}]`, hostA, 300),    // repeats the first log
+		snapshot("apps", `[{"name":"Safari"}]`, hostB, 300), // different decorations
+		snapshot("users", `[{"uid":"502"}]`, hostA, 400),    // different rows
+		snapshot("users", `[{"uid":"501"}]`, hostA, 500),    // not consecutive with the first log
+		snapshot("users", `[{"uid":"501"}]`, hostA, 600),    // repeats the previous log
+	}
+	for _, l := range logs {
+		e.LogString(context.Background(), logger.LogTypeString, l)
+	}
+
+	require.NoError(t, e.writeBufferedLogsForType(logger.LogTypeString))
+	require.Equal(t, 6, len(gotResultLogs))
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(gotResultLogs[0]), &first))
+	require.Equal(t, "users", first["name"])
+	require.Equal(t, float64(3), first[repeatCountField])
+	require.Equal(t, float64(300), first[lastUnixTimeField])
+	require.Equal(t, float64(100), first["unixTime"])
+
+	require.Equal(t, logs[1], gotResultLogs[1])
+	require.Equal(t, logs[3], gotResultLogs[2])
+	require.Equal(t, logs[5], gotResultLogs[3])
+	require.Equal(t, logs[6], gotResultLogs[4])
+
+	var last map[string]any
+	require.NoError(t, json.Unmarshal([]byte(gotResultLogs[5]), &last))
+	require.Equal(t, float64(2), last[repeatCountField])
+	require.Equal(t, float64(600), last[lastUnixTimeField])
+
+	// The collapsed logs are deleted along with the rest of the batch
+	finalLogCount, err := e.knapsack.ResultLogsStore().Count()
+	require.NoError(t, err)
+	require.Equal(t, 0, finalLogCount, "no more queued logs")
+}
+
 func TestExtensionWriteLogsLoop(t *testing.T) {
 	var gotStatusLogs, gotResultLogs []string
 	var logLock sync.Mutex
@@ -1040,6 +1322,8 @@ func TestExtensionGetQueriesEnrollmentInvalid(t *testing.T) {
 	k.On("LatestOsquerydPath", testifymock.Anything).Maybe().Return("")
 	k.On("Slogger").Return(multislogger.NewNopLogger())
 	k.On("ReadEnrollSecret").Return("enroll_secret", nil)
+	k.On("HardwareKeyProof").Maybe().Return(false)
+	k.On("EnrollmentAttemptsStore").Maybe().Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.EnrollmentAttemptsStore.String()))
 
 	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
 	require.Nil(t, err)
@@ -1078,6 +1362,53 @@ func TestExtensionGetQueries(t *testing.T) {
 	assert.Equal(t, expectedQueries, queries.Queries)
 }
 
+func TestExtensionGetQueriesDenylist(t *testing.T) {
+	var publishedResults []distributed.Result
+	m := &mock.KolideService{
+		RequestQueriesFunc: func(ctx context.Context, nodeKey string) (*distributed.GetQueriesResult, bool, error) {
+			return &distributed.GetQueriesResult{
+				Queries: map[string]string{
+					"time":          "select * from time",
+					"shell_history": "SELECT command FROM Shell_History",
+					"discovered":    "select * from users",
+				},
+				Discovery: map[string]string{
+					"discovered": "select 1 from keychain_items limit 1",
+				},
+			}, false, nil
+		},
+		PublishResultsFunc: func(ctx context.Context, nodeKey string, results []distributed.Result) (string, string, bool, error) {
+			publishedResults = results
+			return "", "", false, nil
+		},
+	}
+
+	k := mocks.NewKnapsack(t)
+	k.On("ConfigStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ConfigStore.String()))
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("DistributedQueryDenylist").Return([]string{"shell_history", "keychain_items"})
+	k.On("ControlStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.ControlStore.String()))
+	k.On("PersistentHostDataStore").Return(storageci.NewStore(t, multislogger.NewNopLogger(), storage.PersistentHostDataStore.String()))
+
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
+	require.Nil(t, err)
+
+	queries, err := e.GetQueries(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"time": "select * from time"}, queries.Queries)
+	assert.Empty(t, queries.Discovery)
+
+	// The denied queries should be reported to the server
+	require.True(t, m.PublishResultsFuncInvoked)
+	require.Len(t, publishedResults, 2)
+	for _, result := range publishedResults {
+		assert.Contains(t, []string{"shell_history", "discovered"}, result.QueryName)
+		assert.Equal(t, deniedQueryStatus, result.Status)
+		assert.Equal(t, "denied by policy", result.Message)
+		assert.Empty(t, result.Rows)
+	}
+}
+
 func TestExtensionWriteResultsTransportError(t *testing.T) {
 
 	m := &mock.KolideService{
@@ -1126,9 +1457,11 @@ func TestExtensionWriteResultsEnrollmentInvalid(t *testing.T) {
 func TestExtensionWriteResults(t *testing.T) {
 
 	var gotResults []distributed.Result
+	var gotAccounting []queryaccounting.Entry
 	m := &mock.KolideService{
 		PublishResultsFunc: func(ctx context.Context, nodeKey string, results []distributed.Result) (string, string, bool, error) {
 			gotResults = results
+			gotAccounting, _ = ctx.Value(service.QueryAccountingCtxKey).([]queryaccounting.Entry)
 			return "", "", false, nil
 		},
 	}
@@ -1146,10 +1479,62 @@ func TestExtensionWriteResults(t *testing.T) {
 		},
 	}
 
+	e.queryAccounting.Start(&distributed.GetQueriesResult{Queries: map[string]string{"foobar": "select 1"}}, time.Now())
+
 	err = e.WriteResults(context.Background(), expectedResults)
 	assert.True(t, m.PublishResultsFuncInvoked)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedResults, gotResults)
+
+	// The accounting for the results is sent along with them
+	require.Len(t, gotAccounting, 1)
+	require.Equal(t, "foobar", gotAccounting[0].QueryName)
+	require.Equal(t, 1, gotAccounting[0].RowCount)
+	require.False(t, gotAccounting[0].StartTime.IsZero())
+}
+
+func TestExtensionWriteResultsRetriesTransientFailures(t *testing.T) {
+
+	var publishedResults [][]distributed.Result
+	m := &mock.KolideService{
+		RequestQueriesFunc: func(ctx context.Context, nodeKey string) (*distributed.GetQueriesResult, bool, error) {
+			return &distributed.GetQueriesResult{
+				Queries: map[string]string{"munemo": "select * from kolide_munemo"},
+			}, false, nil
+		},
+		PublishResultsFunc: func(ctx context.Context, nodeKey string, results []distributed.Result) (string, string, bool, error) {
+			publishedResults = append(publishedResults, results)
+			return "", "", false, nil
+		},
+	}
+	db, cleanup := makeTempDB(t)
+	defer cleanup()
+	k := makeKnapsack(t, db)
+	e, err := NewExtension(context.TODO(), m, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{})
+	require.Nil(t, err)
+
+	_, err = e.GetQueries(context.Background())
+	require.NoError(t, err)
+
+	// osquery restarted while running the query -- the failure is held back for a retry
+	require.NoError(t, e.WriteResults(context.Background(), []distributed.Result{
+		{QueryName: "munemo", Status: 1, Message: "Extension call failed: broken pipe"},
+	}))
+	require.False(t, m.PublishResultsFuncInvoked)
+
+	// The server has no new queries, but the retry is handed out
+	m.RequestQueriesFunc = func(ctx context.Context, nodeKey string) (*distributed.GetQueriesResult, bool, error) {
+		return &distributed.GetQueriesResult{}, false, nil
+	}
+	queries, err := e.GetQueries(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"munemo": "select * from kolide_munemo"}, queries.Queries)
+
+	retried := []distributed.Result{
+		{QueryName: "munemo", Status: 0, Rows: []map[string]string{{"munemo": "abc"}}},
+	}
+	require.NoError(t, e.WriteResults(context.Background(), retried))
+	require.Equal(t, [][]distributed.Result{retried}, publishedResults)
 }
 
 func TestSetupLauncherKeys(t *testing.T) {
@@ -1420,3 +1805,33 @@ func Test_setVerbose_MalformedConfig(t *testing.T) {
 
 	require.Equal(t, malformedCfg, modifiedCfg)
 }
+
+func TestExtensionReconfigure(t *testing.T) {
+	db, cleanup := makeTempDB(t)
+	defer cleanup()
+	k := makeKnapsack(t, db)
+
+	e, err := NewExtension(context.TODO(), &mock.KolideService{}, settingsstoremock.NewSettingsStoreWriter(t), k, ulid.New(), ExtensionOpts{
+		MaxBytesPerBatch: 100,
+		LoggingInterval:  time.Minute,
+		MaxBufferedLogs:  10,
+	})
+	require.NoError(t, err)
+
+	// Unchanged options should not notify Execute
+	e.Reconfigure(ExtensionOpts{MaxBytesPerBatch: 100, LoggingInterval: time.Minute, MaxBufferedLogs: 10})
+	require.Equal(t, 0, len(e.optsChanged))
+
+	e.Reconfigure(ExtensionOpts{MaxBytesPerBatch: 200, LoggingInterval: 30 * time.Second, MaxBufferedLogs: 20})
+	require.Equal(t, 1, len(e.optsChanged))
+	require.Equal(t, 200, e.currentOpts().MaxBytesPerBatch)
+	require.Equal(t, 30*time.Second, e.currentOpts().LoggingInterval)
+	require.Equal(t, 20, e.currentOpts().MaxBufferedLogs)
+
+	// Unset options revert to defaults, and notifications don't pile up
+	e.Reconfigure(ExtensionOpts{})
+	require.Equal(t, 1, len(e.optsChanged))
+	require.Equal(t, defaultMaxBytesPerBatch, e.currentOpts().MaxBytesPerBatch)
+	require.Equal(t, defaultLoggingInterval, e.currentOpts().LoggingInterval)
+	require.Equal(t, defaultMaxBufferedLogs, e.currentOpts().MaxBufferedLogs)
+}
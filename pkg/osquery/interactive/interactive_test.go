@@ -125,6 +125,7 @@ func TestProc(t *testing.T) {
 			store, err := storageci.NewStore(t, slogger, storage.KatcConfigStore.String())
 			require.NoError(t, err)
 			mockSack.On("KatcConfigStore").Return(store)
+			mockSack.On("ListeningServicesStore").Return(nil)
 
 			// Make sure the process starts in a timely fashion
 			var proc *os.Process
@@ -82,6 +82,18 @@ func (lps *logPublicationState) EndBatch(logs []string, successful bool) {
 	lps.reduceBatchThreshold()
 }
 
+// SetMaxBytesPerBatch updates the fixed upper limit for batch size. If publication has been
+// healthy -- i.e. we're at the old limit -- the new limit applies immediately; otherwise, the
+// current limit is only lowered if necessary, and will grow towards the new limit as batches succeed.
+func (lps *logPublicationState) SetMaxBytesPerBatch(maxBytesPerBatch int) {
+	if lps.currentMaxBytesPerBatch >= lps.maxBytesPerBatch {
+		lps.currentMaxBytesPerBatch = maxBytesPerBatch
+	} else {
+		lps.currentMaxBytesPerBatch = minInt(lps.currentMaxBytesPerBatch, maxBytesPerBatch)
+	}
+	lps.maxBytesPerBatch = maxBytesPerBatch
+}
+
 func (lps *logPublicationState) ExceedsCurrentBatchThreshold(amountBytes int) bool {
 	return amountBytes > lps.currentMaxBytesPerBatch
 }
@@ -129,3 +129,24 @@ func TestExtensionLogPublicationIgnoresNonTimeoutErrors(t *testing.T) {
 		assert.False(t, e.logPublicationState.currentBatchBufferFilled)
 	}
 }
+
+func TestLogPublicationStateSetMaxBytesPerBatch(t *testing.T) {
+	// At the limit, a new limit applies immediately, in either direction
+	lps := NewLogPublicationState(minBytesPerBatch * 4)
+	lps.SetMaxBytesPerBatch(minBytesPerBatch * 6)
+	assert.Equal(t, minBytesPerBatch*6, lps.currentMaxBytesPerBatch)
+	lps.SetMaxBytesPerBatch(minBytesPerBatch * 2)
+	assert.Equal(t, minBytesPerBatch*2, lps.currentMaxBytesPerBatch)
+
+	// After reducing for failures, a higher limit must be earned back
+	lps = NewLogPublicationState(minBytesPerBatch * 4)
+	lps.reduceBatchThreshold()
+	reduced := lps.currentMaxBytesPerBatch
+	lps.SetMaxBytesPerBatch(minBytesPerBatch * 6)
+	assert.Equal(t, reduced, lps.currentMaxBytesPerBatch)
+	assert.Equal(t, minBytesPerBatch*6, lps.maxBytesPerBatch)
+
+	// But a lower limit still applies immediately
+	lps.SetMaxBytesPerBatch(minBytesPerBatch)
+	assert.Equal(t, minBytesPerBatch, lps.currentMaxBytesPerBatch)
+}
@@ -0,0 +1,111 @@
+package osquery
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/osquery/osquery-go/plugin/distributed"
+)
+
+// deniedQueryStatus is the status reported for distributed queries that were denied by policy.
+// osquery uses a non-zero status to indicate that a query did not run successfully.
+const deniedQueryStatus = 1
+
+// deniedQueryMessage is reported for distributed queries that were denied by the denylist.
+const deniedQueryMessage = "denied by policy"
+
+// queryDenylist holds the compiled patterns for distributed queries that must not be run.
+type queryDenylist struct {
+	patterns []denylistPattern
+}
+
+type denylistPattern struct {
+	raw string
+	re  *regexp.Regexp
+}
+
+// deniedQuery is a distributed query that was removed by the denylist.
+type deniedQuery struct {
+	name    string
+	pattern string
+}
+
+// newQueryDenylist compiles the given patterns as case-insensitive regular expressions.
+// A pattern that is not a valid regular expression is matched as a literal string instead,
+// so that a typo never causes a query to be allowed.
+func newQueryDenylist(patterns []string) (*queryDenylist, []error) {
+	d := &queryDenylist{}
+	var errs []error
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("compiling denylist pattern %q, matching it literally instead: %w", pattern, err))
+			re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern))
+		}
+		d.patterns = append(d.patterns, denylistPattern{raw: pattern, re: re})
+	}
+
+	return d, errs
+}
+
+// match returns the first pattern matching the query, if any.
+func (d *queryDenylist) match(query string) (string, bool) {
+	for _, p := range d.patterns {
+		if p.re.MatchString(query) {
+			return p.raw, true
+		}
+	}
+
+	return "", false
+}
+
+// filter removes any denied queries, and their discovery queries, from the given distributed
+// queries. A query is also denied if its discovery query matches the denylist, since osquery
+// would still run the discovery query.
+func (d *queryDenylist) filter(queries *distributed.GetQueriesResult) []deniedQuery {
+	if queries == nil || len(d.patterns) == 0 {
+		return nil
+	}
+
+	var denied []deniedQuery
+	for name, query := range queries.Queries {
+		pattern, matched := d.match(query)
+		if discovery, ok := queries.Discovery[name]; ok && !matched {
+			pattern, matched = d.match(discovery)
+		}
+		if !matched {
+			continue
+		}
+
+		delete(queries.Queries, name)
+		delete(queries.Discovery, name)
+		denied = append(denied, deniedQuery{name: name, pattern: pattern})
+	}
+
+	return denied
+}
+
+// deniedResults builds the results reported to the server for the denied queries, with the
+// given reason as their message.
+func deniedResults(denied []deniedQuery, message string) []distributed.Result {
+	results := make([]distributed.Result, len(denied))
+	for i, d := range denied {
+		results[i] = distributed.Result{
+			QueryName: d.name,
+			Status:    deniedQueryStatus,
+			Rows:      []map[string]string{},
+			Message:   message,
+		}
+	}
+
+	return results
+}
+
+func deniedNames(denied []deniedQuery) []string {
+	names := make([]string, len(denied))
+	for i, d := range denied {
+		names[i] = d.name
+	}
+	return names
+}
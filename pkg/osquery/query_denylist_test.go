@@ -0,0 +1,54 @@
+package osquery
+
+import (
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/distributed"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryDenylist(t *testing.T) {
+	t.Parallel()
+
+	denylist, errs := newQueryDenylist([]string{`\bshell_history\b`, `keychain_(items|acls)`, `invalid(`})
+	require.Len(t, errs, 1, "invalid pattern should be reported")
+
+	for _, tt := range []struct {
+		query        string
+		expectDenied bool
+	}{
+		{query: "select * from shell_history", expectDenied: true},
+		{query: "SELECT * FROM SHELL_HISTORY", expectDenied: true},
+		{query: "select * from shell_history_backup"},
+		{query: "select label from keychain_items", expectDenied: true},
+		{query: "select * from keychain_acls", expectDenied: true},
+		{query: "select 'invalid(' as x", expectDenied: true},
+		{query: "select * from time"},
+	} {
+		_, denied := denylist.match(tt.query)
+		require.Equal(t, tt.expectDenied, denied, tt.query)
+	}
+}
+
+func TestQueryDenylist_Filter(t *testing.T) {
+	t.Parallel()
+
+	emptyDenylist, errs := newQueryDenylist(nil)
+	require.Empty(t, errs)
+	queries := &distributed.GetQueriesResult{Queries: map[string]string{"history": "select * from shell_history"}}
+	require.Empty(t, emptyDenylist.filter(queries))
+	require.Len(t, queries.Queries, 1)
+
+	denylist, errs := newQueryDenylist([]string{"shell_history"})
+	require.Empty(t, errs)
+	require.Empty(t, denylist.filter(nil))
+
+	denied := denylist.filter(queries)
+	require.Equal(t, []deniedQuery{{name: "history", pattern: "shell_history"}}, denied)
+	require.Empty(t, queries.Queries)
+
+	results := deniedResults(denied, deniedQueryMessage)
+	require.Len(t, results, 1)
+	require.Equal(t, "history", results[0].QueryName)
+	require.Equal(t, deniedQueryStatus, results[0].Status)
+}
@@ -0,0 +1,180 @@
+package osquery
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/distributed"
+)
+
+const (
+	// maxQueryRetries is how many times a distributed query that failed transiently is re-run
+	maxQueryRetries = 1
+
+	// queryRetryWindow is how long a queued retry waits for osquery to ask for queries again. If
+	// osquery doesn't come back within the window, the original failure is reported instead, so
+	// the server isn't left waiting.
+	queryRetryWindow = 2 * time.Minute
+
+	// maxRememberedQueryAge is how long we keep the SQL of distributed queries handed to osquery,
+	// in case they need to be retried
+	maxRememberedQueryAge = 1 * time.Hour
+)
+
+// transientQueryErrors are fragments of the errors osquery reports for a distributed query when it
+// lost its connection to an extension, as happens while launcher restarts osquery, or its
+// extensions haven't registered their tables yet.
+var transientQueryErrors = []string{
+	"broken pipe",
+	"connection reset",
+	"extension socket",
+	"could not connect to extension",
+	"extension call failed",
+	"timed out waiting for extension",
+	"no such table: kolide_",
+}
+
+// isTransientQueryFailure reports whether result failed in a way that's worth retrying.
+func isTransientQueryFailure(result distributed.Result) bool {
+	if result.Status == 0 {
+		return false
+	}
+
+	message := strings.ToLower(result.Message)
+	for _, fragment := range transientQueryErrors {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// distributedQueryRetries is shared by all extensions, since the osquery restarts that cause
+// transient failures also replace the extension.
+var distributedQueryRetries = newQueryRetryQueue()
+
+// queryRetryQueue holds distributed queries that failed transiently, until they're handed to
+// osquery again.
+type queryRetryQueue struct {
+	lock       sync.Mutex
+	remembered map[queryRetryKey]rememberedQuery
+	queued     map[queryRetryKey]*queuedRetry
+}
+
+type queryRetryKey struct {
+	registrationId string
+	queryName      string
+}
+
+type rememberedQuery struct {
+	sql    string
+	seenAt time.Time
+}
+
+type queuedRetry struct {
+	sql       string
+	attempts  int
+	failure   distributed.Result // reported if the retry never runs
+	queuedAt  time.Time
+	handedOut bool
+}
+
+func newQueryRetryQueue() *queryRetryQueue {
+	return &queryRetryQueue{
+		remembered: make(map[queryRetryKey]rememberedQuery),
+		queued:     make(map[queryRetryKey]*queuedRetry),
+	}
+}
+
+// prepare is called with the queries about to be handed to osquery. It remembers their SQL, in
+// case they need to be retried, and adds any queued retries to them. It returns the failures of
+// any retries that weren't handed out within the retry window, which should be reported as-is.
+func (q *queryRetryQueue) prepare(registrationId string, queries *distributed.GetQueriesResult, now time.Time) []distributed.Result {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for key, remembered := range q.remembered {
+		if now.Sub(remembered.seenAt) > maxRememberedQueryAge {
+			delete(q.remembered, key)
+		}
+	}
+	if queries == nil {
+		return nil
+	}
+	for name, sql := range queries.Queries {
+		q.remembered[queryRetryKey{registrationId, name}] = rememberedQuery{sql: sql, seenAt: now}
+	}
+
+	var expired []distributed.Result
+	for key, retry := range q.queued {
+		if key.registrationId != registrationId {
+			continue
+		}
+
+		switch {
+		case now.Sub(retry.queuedAt) > queryRetryWindow:
+			// Too late to retry, or the retry's results never came back
+			expired = append(expired, retry.failure)
+			delete(q.queued, key)
+		case retry.handedOut:
+			// Still waiting on the results
+		default:
+			if _, alreadyQueried := queries.Queries[key.queryName]; alreadyQueried {
+				// The server sent the query again itself
+				continue
+			}
+			if queries.Queries == nil {
+				queries.Queries = make(map[string]string)
+			}
+			queries.Queries[key.queryName] = retry.sql
+			retry.handedOut = true
+		}
+	}
+
+	return expired
+}
+
+// drop removes the given queries from the retry queue, as when a retry handed out by prepare is
+// then denied, so that its original failure isn't also reported when the retry window expires.
+func (q *queryRetryQueue) drop(registrationId string, queryNames []string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, name := range queryNames {
+		delete(q.queued, queryRetryKey{registrationId, name})
+	}
+}
+
+// filter is called with the results osquery returns. It queues the queries that failed
+// transiently for retry, and returns the results that should be reported to the server now.
+func (q *queryRetryQueue) filter(registrationId string, results []distributed.Result, now time.Time) []distributed.Result {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	toReport := make([]distributed.Result, 0, len(results))
+	for _, result := range results {
+		key := queryRetryKey{registrationId, result.QueryName}
+
+		attempts := 0
+		if retry, ok := q.queued[key]; ok {
+			attempts = retry.attempts
+			delete(q.queued, key)
+		}
+
+		remembered, known := q.remembered[key]
+		if !known || attempts >= maxQueryRetries || !isTransientQueryFailure(result) {
+			toReport = append(toReport, result)
+			continue
+		}
+
+		q.queued[key] = &queuedRetry{
+			sql:      remembered.sql,
+			attempts: attempts + 1,
+			failure:  result,
+			queuedAt: now,
+		}
+	}
+
+	return toReport
+}
@@ -0,0 +1,95 @@
+package osquery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/distributed"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransientQueryFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		result          distributed.Result
+		expectTransient bool
+	}{
+		{result: distributed.Result{Status: 1, Message: "Extension call failed: Connection reset by peer"}, expectTransient: true},
+		{result: distributed.Result{Status: 1, Message: "write: broken pipe"}, expectTransient: true},
+		{result: distributed.Result{Status: 1, Message: "no such table: kolide_munemo"}, expectTransient: true},
+		{result: distributed.Result{Status: 1, Message: "no such table: bogus"}},
+		{result: distributed.Result{Status: 1, Message: "near \"selec\": syntax error"}},
+		{result: distributed.Result{Status: 0, Message: "connection reset"}},
+	} {
+		require.Equal(t, tt.expectTransient, isTransientQueryFailure(tt.result), tt.result.Message)
+	}
+}
+
+func TestQueryRetryQueue(t *testing.T) {
+	t.Parallel()
+
+	const registrationId = "default"
+	transientFailure := distributed.Result{QueryName: "munemo", Status: 1, Message: "Extension call failed: broken pipe"}
+	now := time.Now()
+
+	q := newQueryRetryQueue()
+	q.prepare(registrationId, &distributed.GetQueriesResult{
+		Queries: map[string]string{
+			"munemo": "select * from kolide_munemo",
+			"time":   "select * from time",
+		},
+	}, now)
+
+	// The transient failure is held back, other results are reported
+	syntaxError := distributed.Result{QueryName: "time", Status: 1, Message: "syntax error"}
+	toReport := q.filter(registrationId, []distributed.Result{transientFailure, syntaxError}, now)
+	require.Equal(t, []distributed.Result{syntaxError}, toReport)
+
+	// Other registrations don't see the retry
+	otherQueries := &distributed.GetQueriesResult{}
+	require.Empty(t, q.prepare("other", otherQueries, now))
+	require.Empty(t, otherQueries.Queries)
+
+	// The next check-in includes the retry, just once
+	queries := &distributed.GetQueriesResult{}
+	require.Empty(t, q.prepare(registrationId, queries, now.Add(10*time.Second)))
+	require.Equal(t, map[string]string{"munemo": "select * from kolide_munemo"}, queries.Queries)
+
+	queries = &distributed.GetQueriesResult{}
+	require.Empty(t, q.prepare(registrationId, queries, now.Add(20*time.Second)))
+	require.Empty(t, queries.Queries)
+
+	// If the retry fails too, it's reported
+	toReport = q.filter(registrationId, []distributed.Result{transientFailure}, now.Add(30*time.Second))
+	require.Equal(t, []distributed.Result{transientFailure}, toReport)
+	require.Empty(t, q.queued)
+}
+
+func TestQueryRetryQueue_Expired(t *testing.T) {
+	t.Parallel()
+
+	const registrationId = "default"
+	transientFailure := distributed.Result{QueryName: "munemo", Status: 1, Message: "connection reset by peer"}
+	now := time.Now()
+
+	q := newQueryRetryQueue()
+	q.prepare(registrationId, &distributed.GetQueriesResult{Queries: map[string]string{"munemo": "select * from kolide_munemo"}}, now)
+	require.Empty(t, q.filter(registrationId, []distributed.Result{transientFailure}, now))
+
+	// osquery didn't ask for queries again within the window, so the original failure is reported
+	queries := &distributed.GetQueriesResult{}
+	expired := q.prepare(registrationId, queries, now.Add(queryRetryWindow+time.Second))
+	require.Equal(t, []distributed.Result{transientFailure}, expired)
+	require.Empty(t, queries.Queries)
+	require.Empty(t, q.queued)
+}
+
+func TestQueryRetryQueue_UnknownQuery(t *testing.T) {
+	t.Parallel()
+
+	// Without the query's SQL, there's nothing to retry
+	q := newQueryRetryQueue()
+	failure := distributed.Result{QueryName: "munemo", Status: 1, Message: "broken pipe"}
+	require.Equal(t, []distributed.Result{failure}, q.filter("default", []distributed.Result{failure}, time.Now()))
+}
@@ -0,0 +1,150 @@
+// Package queryaccounting tracks how distributed queries execute -- how long they take, how many
+// rows they return, and whether they fail -- so that expensive queries can be identified. osquery's
+// own schedule stats only cover scheduled queries.
+package queryaccounting
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/distributed"
+)
+
+const (
+	// maxEntries is how many of the most recent entries are kept for the kolide_query_accounting table
+	maxEntries = 500
+
+	// maxPending is how long we wait for the results of a query handed to osquery, before forgetting it
+	maxPending = 1 * time.Hour
+)
+
+var recent = &history{}
+
+// Entry is the accounting for a single execution of a distributed query.
+type Entry struct {
+	QueryName  string    `json:"query_name"`
+	StartTime  time.Time `json:"start_time"`  // when the query was handed to osquery
+	DurationMs int64     `json:"duration_ms"` // from StartTime until the results were returned
+	RowCount   int       `json:"row_count"`
+	Status     int       `json:"status"`
+	Error      string    `json:"error,omitempty"`
+
+	// osquery's own stats, when it reports them
+	OsqueryWallTimeMs int64 `json:"osquery_wall_time_ms,omitempty"`
+	OsqueryUserTime   int64 `json:"osquery_user_time,omitempty"`
+	OsquerySystemTime int64 `json:"osquery_system_time,omitempty"`
+	OsqueryMemory     int64 `json:"osquery_memory,omitempty"`
+}
+
+type history struct {
+	sync.Mutex
+	entries []Entry
+}
+
+func (h *history) add(entries ...Entry) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.entries = append(h.entries, entries...)
+	if len(h.entries) > maxEntries {
+		h.entries = h.entries[len(h.entries)-maxEntries:]
+	}
+}
+
+// Recent returns the accounting for the most recently completed distributed queries, oldest first.
+func Recent() []Entry {
+	recent.Lock()
+	defer recent.Unlock()
+
+	entries := make([]Entry, len(recent.entries))
+	copy(entries, recent.entries)
+	return entries
+}
+
+// Tracker notes when distributed queries are handed to osquery, so that their execution can be
+// accounted for when their results come back.
+type Tracker struct {
+	sync.Mutex
+	pending map[string]time.Time // query name -> when it was handed to osquery
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		pending: make(map[string]time.Time),
+	}
+}
+
+// Start notes that the given queries were handed to osquery at the given time.
+func (t *Tracker) Start(queries *distributed.GetQueriesResult, at time.Time) {
+	if queries == nil {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	// Forget queries whose results never came back
+	for name, started := range t.pending {
+		if at.Sub(started) > maxPending {
+			delete(t.pending, name)
+		}
+	}
+
+	for name := range queries.Queries {
+		t.pending[name] = at
+	}
+}
+
+// Finish accounts for the given results, returned at the given time, and records them for
+// the kolide_query_accounting table. Results for queries that weren't started through this
+// tracker have no duration.
+func (t *Tracker) Finish(results []distributed.Result, at time.Time) []Entry {
+	t.Lock()
+	entries := make([]Entry, 0, len(results))
+	for _, result := range results {
+		entry := Entry{
+			QueryName: result.QueryName,
+			RowCount:  len(result.Rows),
+			Status:    result.Status,
+		}
+
+		if started, ok := t.pending[result.QueryName]; ok {
+			entry.StartTime = started
+			entry.DurationMs = at.Sub(started).Milliseconds()
+			delete(t.pending, result.QueryName)
+		}
+
+		if result.Status != 0 {
+			entry.Error = result.Message
+		}
+
+		if stats := result.QueryStats; stats != nil {
+			entry.OsqueryWallTimeMs = int64(stats.WallTimeMs)
+			entry.OsqueryUserTime = int64(stats.UserTime)
+			entry.OsquerySystemTime = int64(stats.SystemTime)
+			entry.OsqueryMemory = int64(stats.Memory)
+		}
+
+		entries = append(entries, entry)
+	}
+	t.Unlock()
+
+	recent.add(entries...)
+
+	return entries
+}
+
+// Pending returns the names of the queries that have been handed to osquery, but whose results
+// haven't come back yet.
+func (t *Tracker) Pending() []string {
+	t.Lock()
+	defer t.Unlock()
+
+	names := make([]string, 0, len(t.pending))
+	for name := range t.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
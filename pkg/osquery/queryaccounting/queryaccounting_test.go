@@ -0,0 +1,97 @@
+package queryaccounting
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/distributed"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewTracker()
+	start := time.Date(2024, 5, 14, 9, 0, 0, 0, time.UTC)
+
+	tracker.Start(&distributed.GetQueriesResult{
+		Queries: map[string]string{
+			"kolide_accounting_ok":     "select * from users",
+			"kolide_accounting_failed": "select * from nonexistent",
+		},
+	}, start)
+	require.Equal(t, []string{"kolide_accounting_failed", "kolide_accounting_ok"}, tracker.Pending())
+
+	entries := tracker.Finish([]distributed.Result{
+		{
+			QueryName:  "kolide_accounting_ok",
+			Rows:       []map[string]string{{"uid": "501"}, {"uid": "502"}},
+			QueryStats: &distributed.Stats{WallTimeMs: 12, UserTime: 8, SystemTime: 3, Memory: 4096},
+		},
+		{
+			QueryName: "kolide_accounting_failed",
+			Status:    1,
+			Message:   "no such table: nonexistent",
+		},
+		{
+			QueryName: "kolide_accounting_unknown",
+		},
+	}, start.Add(1500*time.Millisecond))
+
+	require.Equal(t, []Entry{
+		{
+			QueryName:         "kolide_accounting_ok",
+			StartTime:         start,
+			DurationMs:        1500,
+			RowCount:          2,
+			OsqueryWallTimeMs: 12,
+			OsqueryUserTime:   8,
+			OsquerySystemTime: 3,
+			OsqueryMemory:     4096,
+		},
+		{
+			QueryName:  "kolide_accounting_failed",
+			StartTime:  start,
+			DurationMs: 1500,
+			Status:     1,
+			Error:      "no such table: nonexistent",
+		},
+		{
+			QueryName: "kolide_accounting_unknown",
+		},
+	}, entries)
+
+	// Finished queries are no longer pending, and are in the recent history
+	require.Empty(t, tracker.Pending())
+	require.Subset(t, Recent(), entries)
+}
+
+func TestTracker_forgetsAbandonedQueries(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewTracker()
+	start := time.Now()
+
+	tracker.Start(&distributed.GetQueriesResult{Queries: map[string]string{"abandoned": "select 1"}}, start)
+	tracker.Start(&distributed.GetQueriesResult{Queries: map[string]string{"current": "select 1"}}, start.Add(maxPending+time.Minute))
+
+	require.Contains(t, tracker.pending, "current")
+	require.NotContains(t, tracker.pending, "abandoned")
+
+	// A nil result, as when there are no queries, is fine
+	tracker.Start(nil, start)
+}
+
+func TestHistory_bounded(t *testing.T) {
+	t.Parallel()
+
+	h := &history{}
+	for i := 0; i < maxEntries+10; i++ {
+		h.add(Entry{QueryName: fmt.Sprintf("query_%d", i)})
+	}
+
+	require.Len(t, h.entries, maxEntries)
+	require.Equal(t, "query_10", h.entries[0].QueryName)
+	require.Equal(t, fmt.Sprintf("query_%d", maxEntries+9), h.entries[maxEntries-1].QueryName)
+}
@@ -0,0 +1,147 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kolide/launcher/ee/gowrapper"
+	"github.com/kolide/launcher/pkg/traces"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// How frequently we should run the canary queries against osqueryd
+	canaryInterval = 60 * time.Second
+
+	// How long a single canary query may take before we consider it failed. A healthy osqueryd
+	// answers these in milliseconds.
+	canaryLatencyThreshold = 30 * time.Second
+
+	// How many consecutive rounds of canaries may fail before we restart the instance
+	maxConsecutiveCanaryFailures = 3
+)
+
+// canaryQuery is a query that a healthy osqueryd should always answer, quickly and with at least one row.
+type canaryQuery struct {
+	name  string
+	query string
+}
+
+// canaryQueries exercise osqueryd's query path, which can wedge even while its extension
+// socket still answers pings. The first touches only osqueryd; the second round-trips
+// through our extension, too.
+var canaryQueries = []canaryQuery{
+	{
+		name:  "trivial",
+		query: "select 1 from time",
+	},
+	{
+		name:  "extension_table",
+		query: "select run_id from kolide_launcher_osquery_instance_history limit 1",
+	},
+}
+
+// queryCanary periodically runs the canary queries, tracking their latency, and reports
+// when they have failed too many times in a row.
+type queryCanary struct {
+	slogger             *slog.Logger
+	query               func(query string) ([]map[string]string, error)
+	queries             []canaryQuery
+	latencyThreshold    time.Duration
+	maxFailures         int
+	consecutiveFailures int
+}
+
+func newQueryCanary(slogger *slog.Logger, query func(query string) ([]map[string]string, error)) *queryCanary {
+	return &queryCanary{
+		slogger:          slogger.With("component", "query_canary"),
+		query:            query,
+		queries:          canaryQueries,
+		latencyThreshold: canaryLatencyThreshold,
+		maxFailures:      maxConsecutiveCanaryFailures,
+	}
+}
+
+// check runs each canary query once. It returns an error once the canaries have failed on
+// `maxFailures` consecutive checks; a single passing check clears earlier failures.
+func (c *queryCanary) check(ctx context.Context) error {
+	ctx, span := traces.StartSpan(ctx)
+	defer span.End()
+
+	var failures []error
+	for _, q := range c.queries {
+		latency, err := c.run(ctx, q)
+		span.SetAttributes(attribute.Int64(fmt.Sprintf("launcher.canary.%s.latency_ms", q.name), latency.Milliseconds()))
+
+		if err != nil {
+			failures = append(failures, fmt.Errorf("canary %s: %w", q.name, err))
+			continue
+		}
+
+		c.slogger.Log(ctx, slog.LevelDebug,
+			"canary query succeeded",
+			"canary", q.name,
+			"latency", latency.String(),
+		)
+	}
+
+	if len(failures) == 0 {
+		if c.consecutiveFailures > 0 {
+			c.slogger.Log(ctx, slog.LevelInfo,
+				"canary queries passed after previous failures -- clearing error",
+				"previous_failures", c.consecutiveFailures,
+			)
+		}
+		c.consecutiveFailures = 0
+		return nil
+	}
+
+	c.consecutiveFailures += 1
+	err := errors.Join(failures...)
+	traces.SetError(span, err)
+
+	if c.consecutiveFailures >= c.maxFailures {
+		return fmt.Errorf("canary queries failed on %d consecutive checks: %w", c.consecutiveFailures, err)
+	}
+
+	c.slogger.Log(ctx, slog.LevelWarn,
+		"canary queries failed, will retry",
+		"consecutive_failures", c.consecutiveFailures,
+		"max_failures", c.maxFailures,
+		"err", err,
+	)
+
+	return nil
+}
+
+// run runs the canary query, returning how long it took. It gives up on the query once it
+// has exceeded the latency threshold, since a wedged osqueryd may never answer.
+func (c *queryCanary) run(ctx context.Context, q canaryQuery) (time.Duration, error) {
+	resultsChan := make(chan error, 1)
+	start := time.Now()
+
+	gowrapper.Go(ctx, c.slogger, func() {
+		results, err := c.query(q.query)
+		if err != nil {
+			resultsChan <- fmt.Errorf("querying: %w", err)
+			return
+		}
+		if len(results) == 0 {
+			resultsChan <- errors.New("query returned no rows")
+			return
+		}
+		resultsChan <- nil
+	})
+
+	select {
+	case err := <-resultsChan:
+		return time.Since(start), err
+	case <-time.After(c.latencyThreshold):
+		return time.Since(start), fmt.Errorf("query exceeded latency threshold of %s", c.latencyThreshold.String())
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCanary(t *testing.T) {
+	t.Parallel()
+
+	var failing atomic.Bool
+	canary := newQueryCanary(multislogger.NewNopLogger(), func(query string) ([]map[string]string, error) {
+		if failing.Load() {
+			return nil, errors.New("test error")
+		}
+		return []map[string]string{{"1": "1"}}, nil
+	})
+
+	require.NoError(t, canary.check(context.TODO()))
+
+	// Failures are tolerated until there have been too many in a row
+	failing.Store(true)
+	for i := 1; i < maxConsecutiveCanaryFailures; i++ {
+		require.NoError(t, canary.check(context.TODO()))
+	}
+
+	// A passing check clears earlier failures
+	failing.Store(false)
+	require.NoError(t, canary.check(context.TODO()))
+	require.Equal(t, 0, canary.consecutiveFailures)
+
+	failing.Store(true)
+	for i := 1; i < maxConsecutiveCanaryFailures; i++ {
+		require.NoError(t, canary.check(context.TODO()))
+	}
+	require.Error(t, canary.check(context.TODO()))
+}
+
+func TestQueryCanary_NoRows(t *testing.T) {
+	t.Parallel()
+
+	canary := newQueryCanary(multislogger.NewNopLogger(), func(query string) ([]map[string]string, error) {
+		return nil, nil
+	})
+	canary.maxFailures = 1
+
+	require.ErrorContains(t, canary.check(context.TODO()), "no rows")
+}
+
+func TestQueryCanary_ExceedsLatencyThreshold(t *testing.T) {
+	t.Parallel()
+
+	// Simulate a wedged osqueryd that never answers
+	wedged := make(chan struct{})
+	t.Cleanup(func() { close(wedged) })
+
+	canary := newQueryCanary(multislogger.NewNopLogger(), func(query string) ([]map[string]string, error) {
+		<-wedged
+		return nil, nil
+	})
+	canary.maxFailures = 1
+	canary.latencyThreshold = 100 * time.Millisecond
+
+	start := time.Now()
+	require.ErrorContains(t, canary.check(context.TODO()), "exceeded latency threshold")
+	require.Less(t, time.Since(start), time.Duration(len(canaryQueries))*time.Second, "check should not wait on a wedged osqueryd")
+}
@@ -0,0 +1,228 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolide/launcher/ee/gowrapper"
+)
+
+// When launcher restarts itself after an autoupdate, it can hand its running osqueryd
+// processes over to the new launcher process, rather than restarting them. This avoids
+// gaps in event tables. Because launcher restarts via exec, the new launcher process
+// keeps our PID, so osqueryd remains its child. The outgoing instance writes a handover
+// record describing osqueryd, then shuts down everything except osqueryd. The incoming
+// instance reads the record, adopts osqueryd, and re-registers the extension.
+
+// handoverMaxAge is how old a handover record can be before we consider it stale.
+const handoverMaxAge = 2 * time.Minute
+
+// handoverRecord describes a running osqueryd process that is being handed over to
+// a new launcher process.
+type handoverRecord struct {
+	RegistrationId      string    `json:"registration_id"`
+	RunId               string    `json:"run_id"`
+	LauncherPid         int       `json:"launcher_pid"`
+	OsquerydPid         int       `json:"osqueryd_pid"`
+	OsquerydPath        string    `json:"osqueryd_path"`
+	ExtensionSocketPath string    `json:"extension_socket_path"`
+	PidfilePath         string    `json:"pidfile_path"`
+	StdoutFd            uintptr   `json:"stdout_fd"`
+	StderrFd            uintptr   `json:"stderr_fd"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+func handoverRecordPath(rootDirectory string, registrationId string) string {
+	return filepath.Join(rootDirectory, fmt.Sprintf("osquery-handover-%s.json", registrationId))
+}
+
+// writeHandoverRecord atomically writes the handover record for the given registration ID.
+func writeHandoverRecord(rootDirectory string, record *handoverRecord) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshalling handover record: %w", err)
+	}
+
+	recordPath := handoverRecordPath(rootDirectory, record.RegistrationId)
+	tmpPath := recordPath + ".tmp"
+	if err := os.WriteFile(tmpPath, recordBytes, 0600); err != nil {
+		return fmt.Errorf("writing handover record: %w", err)
+	}
+	if err := os.Rename(tmpPath, recordPath); err != nil {
+		return fmt.Errorf("renaming handover record: %w", err)
+	}
+
+	return nil
+}
+
+// takeHandoverRecord reads the handover record for the given registration ID, if one exists,
+// and removes it so that it cannot be used twice. It returns nil, nil if there is no record.
+func takeHandoverRecord(rootDirectory string, registrationId string) (*handoverRecord, error) {
+	recordPath := handoverRecordPath(rootDirectory, registrationId)
+	recordBytes, err := os.ReadFile(recordPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading handover record: %w", err)
+	}
+
+	if err := os.Remove(recordPath); err != nil {
+		return nil, fmt.Errorf("removing handover record: %w", err)
+	}
+
+	var record handoverRecord
+	if err := json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, fmt.Errorf("unmarshalling handover record: %w", err)
+	}
+
+	return &record, nil
+}
+
+// validateHandoverRecord confirms that the osqueryd process described by the record was handed
+// over to this launcher process, recently, and is still running.
+func validateHandoverRecord(record *handoverRecord) error {
+	if record.LauncherPid != os.Getpid() {
+		return fmt.Errorf("handover record is for launcher pid %d, but we are %d", record.LauncherPid, os.Getpid())
+	}
+
+	if age := time.Since(record.Timestamp); age > handoverMaxAge {
+		return fmt.Errorf("handover record is stale: written %s ago", age.String())
+	}
+
+	if !pidfileMatches(record) {
+		return fmt.Errorf("pidfile %s does not contain osqueryd pid %d", record.PidfilePath, record.OsquerydPid)
+	}
+
+	if !processAlive(record.OsquerydPid) {
+		return fmt.Errorf("osqueryd process %d is not running", record.OsquerydPid)
+	}
+
+	if _, err := os.Stat(record.ExtensionSocketPath); err != nil {
+		return fmt.Errorf("checking extension socket: %w", err)
+	}
+
+	return nil
+}
+
+// pidfileMatches checks that osqueryd's pidfile still refers to the handed-over process, so that
+// we don't adopt or kill an unrelated process that has reused the PID.
+func pidfileMatches(record *handoverRecord) bool {
+	pidfileContents, err := os.ReadFile(record.PidfilePath)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(pidfileContents)) == strconv.Itoa(record.OsquerydPid)
+}
+
+// copyOsquerydOutput copies osqueryd's output from the read end of a pipe to the given log adapter,
+// until osqueryd closes the pipe.
+func (i *OsqueryInstance) copyOsquerydOutput(ctx context.Context, r *os.File, w io.Writer) {
+	gowrapper.Go(ctx, i.slogger, func() {
+		defer r.Close()
+
+		if _, err := io.Copy(w, r); err != nil && !errors.Is(err, os.ErrClosed) {
+			i.slogger.Log(ctx, slog.LevelDebug,
+				"error copying osqueryd output",
+				"pipe", r.Name(),
+				"err", err,
+			)
+		}
+	})
+}
+
+// pipeOsquerydOutput replaces osqueryd's stdout and stderr log adapters with pipes, and copies
+// from the pipes to the adapters. Unlike the pipes that exec.Cmd would create for us, these can
+// be handed over to the next launcher process. It returns the write ends of the pipes, which
+// the caller must close once osqueryd has started.
+func (i *OsqueryInstance) pipeOsquerydOutput(ctx context.Context) ([]*os.File, error) {
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+	}
+
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		stdoutReader.Close()
+		stdoutWriter.Close()
+		return nil, fmt.Errorf("creating stderr pipe: %w", err)
+	}
+
+	i.copyOsquerydOutput(ctx, stdoutReader, i.cmd.Stdout)
+	i.copyOsquerydOutput(ctx, stderrReader, i.cmd.Stderr)
+	i.cmd.Stdout = stdoutWriter
+	i.cmd.Stderr = stderrWriter
+	i.outputPipes = []*os.File{stdoutReader, stderrReader}
+
+	return []*os.File{stdoutWriter, stderrWriter}, nil
+}
+
+// adoptOsquerydProcess takes over the osqueryd process described by the handover record,
+// resuming logging of its output.
+func (i *OsqueryInstance) adoptOsquerydProcess(ctx context.Context, record *handoverRecord) error {
+	process, err := os.FindProcess(record.OsquerydPid)
+	if err != nil {
+		return fmt.Errorf("finding osqueryd process %d: %w", record.OsquerydPid, err)
+	}
+
+	// The process was started by the previous launcher process, so we only have a partial
+	// command for it -- enough to wait on it and kill it.
+	i.cmd = &exec.Cmd{
+		Path:    record.OsquerydPath,
+		Args:    []string{record.OsquerydPath},
+		Process: process,
+	}
+
+	// If we can't read osqueryd's output, it will be killed by SIGPIPE the next time it
+	// writes, so don't adopt it.
+	stdoutReader, err := inheritedFile(record.StdoutFd, "osqueryd-stdout")
+	if err != nil {
+		return fmt.Errorf("inheriting osqueryd stdout: %w", err)
+	}
+	stderrReader, err := inheritedFile(record.StderrFd, "osqueryd-stderr")
+	if err != nil {
+		stdoutReader.Close()
+		return fmt.Errorf("inheriting osqueryd stderr: %w", err)
+	}
+
+	i.copyOsquerydOutput(ctx, stdoutReader, i.newOsqueryLogAdapter("stdout", slog.LevelDebug))
+	i.copyOsquerydOutput(ctx, stderrReader, i.newOsqueryLogAdapter("stderr", slog.LevelInfo))
+	i.outputPipes = []*os.File{stdoutReader, stderrReader}
+
+	i.slogger.Log(ctx, slog.LevelInfo,
+		"adopted osqueryd process from previous launcher process",
+		"osqueryd_pid", record.OsquerydPid,
+		"previous_instance_run_id", record.RunId,
+	)
+
+	return nil
+}
+
+// killStaleOsquerydProcess kills an osqueryd process that was handed over, but that we
+// can't adopt.
+func killStaleOsquerydProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("finding process %d: %w", pid, err)
+	}
+
+	if err := killProcessGroup(&exec.Cmd{Process: process}); err != nil {
+		return fmt.Errorf("killing process %d: %w", pid, err)
+	}
+
+	// If the process was our child, reap it. If it wasn't, there's nothing to do.
+	_, _ = process.Wait()
+
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+// +build !windows
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// handoverSupported is true on platforms where launcher restarts via exec, keeping its PID.
+const handoverSupported = true
+
+// allowInheritance clears close-on-exec on the given file, so that it remains open in the
+// new launcher process after exec. It returns the file descriptor.
+func allowInheritance(f *os.File) (uintptr, error) {
+	rawConn, err := f.SyscallConn()
+	if err != nil {
+		return 0, fmt.Errorf("getting raw conn for %s: %w", f.Name(), err)
+	}
+
+	var fd uintptr
+	var fcntlErr error
+	if err := rawConn.Control(func(rawFd uintptr) {
+		fd = rawFd
+		_, fcntlErr = unix.FcntlInt(rawFd, unix.F_SETFD, 0)
+	}); err != nil {
+		return 0, fmt.Errorf("accessing fd for %s: %w", f.Name(), err)
+	}
+	if fcntlErr != nil {
+		return 0, fmt.Errorf("clearing close-on-exec for %s: %w", f.Name(), fcntlErr)
+	}
+
+	return fd, nil
+}
+
+// inheritedFile returns the file inherited from the previous launcher process at the given fd.
+func inheritedFile(fd uintptr, name string) (*os.File, error) {
+	if _, err := unix.FcntlInt(fd, unix.F_GETFD, 0); err != nil {
+		return nil, fmt.Errorf("checking inherited fd %d: %w", fd, err)
+	}
+
+	// Set close-on-exec again, so that we don't leak this fd to other children
+	syscall.CloseOnExec(int(fd))
+
+	return os.NewFile(fd, name), nil
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
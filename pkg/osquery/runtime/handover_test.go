@@ -0,0 +1,142 @@
+//go:build !windows
+// +build !windows
+
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestHandoverRecord_WriteAndTake(t *testing.T) {
+	t.Parallel()
+
+	rootDirectory := t.TempDir()
+
+	// No record yet
+	record, err := takeHandoverRecord(rootDirectory, types.DefaultRegistrationID)
+	require.NoError(t, err)
+	require.Nil(t, record)
+
+	expected := &handoverRecord{
+		RegistrationId:      types.DefaultRegistrationID,
+		RunId:               "test-run-id",
+		LauncherPid:         os.Getpid(),
+		OsquerydPid:         12345,
+		OsquerydPath:        "/usr/local/bin/osqueryd",
+		ExtensionSocketPath: filepath.Join(rootDirectory, "osquery.sock"),
+		PidfilePath:         filepath.Join(rootDirectory, "osquery.pid"),
+		StdoutFd:            7,
+		StderrFd:            9,
+		Timestamp:           time.Now().UTC().Truncate(time.Second),
+	}
+	require.NoError(t, writeHandoverRecord(rootDirectory, expected))
+
+	record, err = takeHandoverRecord(rootDirectory, types.DefaultRegistrationID)
+	require.NoError(t, err)
+	require.Equal(t, expected, record)
+
+	// The record can only be taken once
+	require.NoFileExists(t, handoverRecordPath(rootDirectory, types.DefaultRegistrationID))
+	record, err = takeHandoverRecord(rootDirectory, types.DefaultRegistrationID)
+	require.NoError(t, err)
+	require.Nil(t, record)
+}
+
+func TestValidateHandoverRecord(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name        string
+		modify      func(r *handoverRecord)
+		expectValid bool
+	}{
+		{
+			name:        "valid",
+			modify:      func(r *handoverRecord) {},
+			expectValid: true,
+		},
+		{
+			name:   "different launcher process",
+			modify: func(r *handoverRecord) { r.LauncherPid = os.Getpid() + 1 },
+		},
+		{
+			name:   "stale",
+			modify: func(r *handoverRecord) { r.Timestamp = time.Now().Add(-2 * handoverMaxAge) },
+		},
+		{
+			name:   "pidfile mismatch",
+			modify: func(r *handoverRecord) { r.OsquerydPid = os.Getppid() },
+		},
+		{
+			name:   "missing socket",
+			modify: func(r *handoverRecord) { r.ExtensionSocketPath = r.ExtensionSocketPath + ".missing" },
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rootDirectory := t.TempDir()
+
+			// Use our own process to stand in for a running osqueryd
+			record := &handoverRecord{
+				RegistrationId:      types.DefaultRegistrationID,
+				LauncherPid:         os.Getpid(),
+				OsquerydPid:         os.Getpid(),
+				ExtensionSocketPath: filepath.Join(rootDirectory, "osquery.sock"),
+				PidfilePath:         filepath.Join(rootDirectory, "osquery.pid"),
+				Timestamp:           time.Now(),
+			}
+			require.NoError(t, os.WriteFile(record.PidfilePath, []byte(strconv.Itoa(os.Getpid())+"\n"), 0600))
+			require.NoError(t, os.WriteFile(record.ExtensionSocketPath, nil, 0600))
+
+			tt.modify(record)
+
+			err := validateHandoverRecord(record)
+			if tt.expectValid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestAllowInheritance(t *testing.T) {
+	t.Parallel()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	fd, err := allowInheritance(r)
+	require.NoError(t, err)
+
+	flags, err := unix.FcntlInt(fd, unix.F_GETFD, 0)
+	require.NoError(t, err)
+	require.Zero(t, flags&unix.FD_CLOEXEC, "close-on-exec should be cleared")
+
+	// Simulate inheriting the fd with a duplicate, so that only one file owns each fd
+	dupFd, err := unix.Dup(int(fd))
+	require.NoError(t, err)
+	inherited, err := inheritedFile(uintptr(dupFd), "inherited")
+	require.NoError(t, err)
+	defer inherited.Close()
+
+	flags, err = unix.FcntlInt(uintptr(dupFd), unix.F_GETFD, 0)
+	require.NoError(t, err)
+	require.NotZero(t, flags&unix.FD_CLOEXEC, "close-on-exec should be set once inherited")
+
+	// Not a valid fd
+	_, err = inheritedFile(uintptr(99999), "invalid")
+	require.Error(t, err)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package runtime
+
+import (
+	"errors"
+	"os"
+)
+
+// handoverSupported is false on Windows: launcher restarts by starting a new process,
+// so osqueryd cannot remain its child.
+const handoverSupported = false
+
+var errHandoverUnsupported = errors.New("osquery handover is not supported on windows")
+
+func allowInheritance(_ *os.File) (uintptr, error) {
+	return 0, errHandoverUnsupported
+}
+
+func inheritedFile(_ uintptr, _ string) (*os.File, error) {
+	return nil, errHandoverUnsupported
+}
+
+func processAlive(_ int) bool {
+	return false
+}
@@ -3,6 +3,8 @@ package history
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/kolide/launcher/ee/agent/types"
 )
 
 const (
@@ -10,24 +12,50 @@ const (
 )
 
 func (h *History) load() error {
-	var instancesBytes []byte
+	instances, err := loadInstances(h.store)
+	if err != nil {
+		return err
+	}
 
-	instancesBytes, err := h.store.Get([]byte(osqueryHistoryInstanceKey))
+	if instances != nil {
+		h.instances = instances
+	}
+	return nil
+}
+
+// LoadInstances reads the osquery instance history directly from store, oldest first, without
+// touching the current history. It is intended for reading the history recorded by another
+// launcher process, e.g. from a read-only copy of its database.
+func LoadInstances(store types.Getter) ([]Instance, error) {
+	instances, err := loadInstances(store)
 	if err != nil {
-		return fmt.Errorf("error reading osquery_instance_history from db: %w", err)
+		return nil, err
 	}
 
-	var instances []*Instance
+	results := make([]Instance, len(instances))
+	for i, v := range instances {
+		results[i] = *v
+	}
+
+	return results, nil
+}
+
+func loadInstances(store types.Getter) ([]*Instance, error) {
+	instancesBytes, err := store.Get([]byte(osqueryHistoryInstanceKey))
+	if err != nil {
+		return nil, fmt.Errorf("error reading osquery_instance_history from db: %w", err)
+	}
 
 	if instancesBytes == nil {
-		return nil
+		return nil, nil
 	}
+
+	var instances []*Instance
 	if err := json.Unmarshal(instancesBytes, &instances); err != nil {
-		return fmt.Errorf("error unmarshalling osquery_instance_history: %w", err)
+		return nil, fmt.Errorf("error unmarshalling osquery_instance_history: %w", err)
 	}
 
-	h.instances = instances
-	return nil
+	return instances, nil
 }
 
 func (h *History) save() error {
@@ -13,18 +13,22 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/kolide/kit/ulid"
 	"github.com/kolide/launcher/ee/agent/types"
 	"github.com/kolide/launcher/ee/errgroup"
+	"github.com/kolide/launcher/ee/fim"
 	"github.com/kolide/launcher/ee/gowrapper"
 	kolidelog "github.com/kolide/launcher/ee/log/osquerylogs"
 	"github.com/kolide/launcher/pkg/backoff"
 	launcherosq "github.com/kolide/launcher/pkg/osquery"
 	"github.com/kolide/launcher/pkg/osquery/runtime/history"
+	"github.com/kolide/launcher/pkg/osquery/snapshotdiff"
 	"github.com/kolide/launcher/pkg/osquery/table"
+	"github.com/kolide/launcher/pkg/osquery/watchdogevents"
 	"github.com/kolide/launcher/pkg/service"
 	"github.com/kolide/launcher/pkg/traces"
 	"github.com/osquery/osquery-go"
@@ -119,6 +123,11 @@ type OsqueryInstance struct {
 	extensionManagerClient  *osquery.ExtensionManagerClient
 	stats                   *history.Instance
 	startFunc               func(cmd *exec.Cmd) error
+	paths                   *osqueryFilePaths
+	outputPipes             []*os.File    // read ends of osqueryd's stdout and stderr, kept so they can be handed over
+	launched                atomic.Bool   // set once Launch or Adopt completes successfully
+	handingOver             chan struct{} // closed when osqueryd is being handed over to a new launcher process
+	watchdogEvents          *watchdogevents.Recorder
 }
 
 // Healthy will check to determine whether or not the osquery process that is
@@ -210,6 +219,8 @@ func newInstance(registrationId string, knapsack types.Knapsack, serviceClient s
 		serviceClient:  serviceClient,
 		settingsWriter: settingsWriter,
 		runId:          runId,
+		handingOver:    make(chan struct{}),
+		watchdogEvents: watchdogevents.NewRecorder(knapsack.Slogger().With("registration_id", registrationId)),
 	}
 
 	for _, opt := range opts {
@@ -233,6 +244,65 @@ func (i *OsqueryInstance) BeginShutdown() {
 	i.errgroup.Shutdown()
 }
 
+// BeginHandover records the running osqueryd process so that the next launcher process
+// can adopt it, then shuts down the rest of the instance, leaving osqueryd running.
+// It should only be called immediately before launcher execs its replacement.
+func (i *OsqueryInstance) BeginHandover() error {
+	if !handoverSupported {
+		return errors.New("osquery handover is not supported on this platform")
+	}
+
+	if !i.launched.Load() || len(i.outputPipes) != 2 {
+		return errors.New("instance has not launched osqueryd, cannot hand it over")
+	}
+
+	stdoutFd, err := allowInheritance(i.outputPipes[0])
+	if err != nil {
+		return fmt.Errorf("preparing osqueryd stdout for handover: %w", err)
+	}
+	stderrFd, err := allowInheritance(i.outputPipes[1])
+	if err != nil {
+		return fmt.Errorf("preparing osqueryd stderr for handover: %w", err)
+	}
+
+	record := &handoverRecord{
+		RegistrationId:      i.registrationId,
+		RunId:               i.runId,
+		LauncherPid:         os.Getpid(),
+		OsquerydPid:         i.cmd.Process.Pid,
+		OsquerydPath:        i.cmd.Path,
+		ExtensionSocketPath: i.paths.extensionSocketPath,
+		PidfilePath:         i.paths.pidfilePath,
+		StdoutFd:            stdoutFd,
+		StderrFd:            stderrFd,
+		Timestamp:           time.Now().UTC(),
+	}
+	if err := writeHandoverRecord(i.knapsack.RootDirectory(), record); err != nil {
+		return fmt.Errorf("writing handover record: %w", err)
+	}
+
+	i.slogger.Log(context.TODO(), slog.LevelInfo,
+		"handing over osqueryd to new launcher process",
+		"osqueryd_pid", record.OsquerydPid,
+	)
+
+	close(i.handingOver)
+	i.errgroup.Shutdown()
+
+	return nil
+}
+
+// isHandingOver returns true if osqueryd is being handed over to a new launcher process,
+// in which case we should leave it and its files alone during shutdown.
+func (i *OsqueryInstance) isHandingOver() bool {
+	select {
+	case <-i.handingOver:
+		return true
+	default:
+		return false
+	}
+}
+
 // WaitShutdown waits for the instance's errgroup routines to exit, then returns the
 // initial error. It should be called after either `Exited` has returned, or after
 // the instance has been asked to shut down via call to `BeginShutdown`.
@@ -254,6 +324,15 @@ func (i *OsqueryInstance) WaitShutdown(ctx context.Context) error {
 	return exitErr
 }
 
+// osquerydPath returns the path to the osqueryd binary the instance runs, or an empty string
+// if it hasn't created its osqueryd command yet.
+func (i *OsqueryInstance) osquerydPath() string {
+	if i.cmd == nil {
+		return ""
+	}
+	return i.cmd.Path
+}
+
 // Exited returns a channel to monitor for signal that instance has shut itself down
 func (i *OsqueryInstance) Exited() <-chan struct{} {
 	return i.errgroup.Exited()
@@ -262,6 +341,20 @@ func (i *OsqueryInstance) Exited() <-chan struct{} {
 // Launch starts the osquery instance and its components. It will run until one of its
 // components becomes unhealthy, or until it is asked to shutdown via `BeginShutdown`.
 func (i *OsqueryInstance) Launch() error {
+	return i.launch(nil)
+}
+
+// Adopt takes over the osqueryd process handed over by the previous launcher process,
+// rather than starting a new one, and then starts the instance's other components.
+// It runs until one of its components becomes unhealthy, or until it is asked to
+// shutdown via `BeginShutdown`.
+func (i *OsqueryInstance) Adopt(record *handoverRecord) error {
+	return i.launch(record)
+}
+
+// launch starts the osquery instance and its components. If record is non-nil, it adopts
+// the osqueryd process described by the record; otherwise, it starts a new one.
+func (i *OsqueryInstance) launch(record *handoverRecord) error {
 	ctx, span := traces.StartSpan(context.Background())
 	defer span.End()
 
@@ -279,10 +372,17 @@ func (i *OsqueryInstance) Launch() error {
 		return fmt.Errorf("could not calculate osquery file paths: %w", err)
 	}
 
+	// An adopted osqueryd process is already using the previous instance's pidfile and socket
+	if record != nil {
+		paths.pidfilePath = record.PidfilePath
+		paths.extensionSocketPath = record.ExtensionSocketPath
+	}
+	i.paths = paths
+
 	// Register as many of our shutdown functions ahead of time as we can, so that we can make sure
 	// we fully clean up after any partially-launched erroring instances.
 	i.errgroup.AddShutdownGoroutine(ctx, "kill_osquery_process", func() error {
-		if i.cmd.Process == nil {
+		if i.cmd == nil || i.cmd.Process == nil || i.isHandingOver() {
 			return nil
 		}
 
@@ -302,6 +402,10 @@ func (i *OsqueryInstance) Launch() error {
 	})
 	// Clean up PID file on shutdown
 	i.errgroup.AddShutdownGoroutine(ctx, "remove_pid_file", func() error {
+		if i.isHandingOver() {
+			return nil
+		}
+
 		// We do a couple retries -- on Windows, the PID file may still be in use
 		// and therefore unable to be removed.
 		if err := backoff.WaitFor(func() error {
@@ -317,6 +421,10 @@ func (i *OsqueryInstance) Launch() error {
 
 	// Clean up socket file on shutdown
 	i.errgroup.AddShutdownGoroutine(ctx, "remove_socket_file", func() error {
+		if i.isHandingOver() {
+			return nil
+		}
+
 		// We do a couple retries -- on Windows, the socket file may still be in use
 		// and therefore unable to be removed.
 		if err := backoff.WaitFor(func() error {
@@ -343,36 +451,46 @@ func (i *OsqueryInstance) Launch() error {
 		}
 	}
 
-	// The knapsack will retrieve the correct version of osqueryd from the download library if available.
-	// If not available, it will fall back to the configured installed version of osqueryd.
-	currentOsquerydBinaryPath := i.knapsack.LatestOsquerydPath(ctx)
-	span.AddEvent("got_osqueryd_binary_path", trace.WithAttributes(attribute.String("path", currentOsquerydBinaryPath)))
-
-	// Now that we have accepted options from the caller and/or determined what
-	// they should be due to them not being set, we are ready to create and start
-	// the *exec.Cmd instance that will run osqueryd.
-	i.cmd, err = i.createOsquerydCommand(currentOsquerydBinaryPath, paths)
-	if err != nil {
-		traces.SetError(span, fmt.Errorf("couldn't create osqueryd command: %w", err))
-		return fmt.Errorf("couldn't create osqueryd command: %w", err)
-	}
+	var currentOsquerydBinaryPath string
+	if record != nil {
+		currentOsquerydBinaryPath = record.OsquerydPath
+		if err := i.adoptOsquerydProcess(ctx, record); err != nil {
+			traces.SetError(span, fmt.Errorf("adopting osqueryd process: %w", err))
+			return fmt.Errorf("adopting osqueryd process: %w", err)
+		}
+	} else {
+		// The knapsack will retrieve the osqueryd selected for this registration, or else the correct
+		// version of osqueryd from the download library if available. If not available, it will fall
+		// back to the configured installed version of osqueryd.
+		currentOsquerydBinaryPath = i.knapsack.RegistrationOsquerydPath(ctx, i.registrationId)
+		span.AddEvent("got_osqueryd_binary_path", trace.WithAttributes(attribute.String("path", currentOsquerydBinaryPath)))
+
+		// Now that we have accepted options from the caller and/or determined what
+		// they should be due to them not being set, we are ready to create and start
+		// the *exec.Cmd instance that will run osqueryd.
+		i.cmd, err = i.createOsquerydCommand(currentOsquerydBinaryPath, paths)
+		if err != nil {
+			traces.SetError(span, fmt.Errorf("couldn't create osqueryd command: %w", err))
+			return fmt.Errorf("couldn't create osqueryd command: %w", err)
+		}
 
-	// Assign a PGID that matches the PID. This lets us kill the entire process group later.
-	i.cmd.SysProcAttr = setpgid()
+		// Assign a PGID that matches the PID. This lets us kill the entire process group later.
+		i.cmd.SysProcAttr = setpgid()
 
-	// remove any socket already at the extension socket path to ensure
-	// that it's not left over from a previous instance
-	if err := os.RemoveAll(paths.extensionSocketPath); err != nil {
-		i.slogger.Log(ctx, slog.LevelWarn,
-			"error removing osquery extension socket",
-			"path", paths.extensionSocketPath,
-			"err", err,
-		)
-	}
+		// remove any socket already at the extension socket path to ensure
+		// that it's not left over from a previous instance
+		if err := os.RemoveAll(paths.extensionSocketPath); err != nil {
+			i.slogger.Log(ctx, slog.LevelWarn,
+				"error removing osquery extension socket",
+				"path", paths.extensionSocketPath,
+				"err", err,
+			)
+		}
 
-	// Launch osquery process (async)
-	if err := i.startOsquerydProcess(ctx, paths); err != nil {
-		return fmt.Errorf("starting osqueryd process: %w", err)
+		// Launch osquery process (async)
+		if err := i.startOsquerydProcess(ctx, paths); err != nil {
+			return fmt.Errorf("starting osqueryd process: %w", err)
+		}
 	}
 
 	stats, err := history.NewInstance(i.registrationId, i.runId)
@@ -388,7 +506,19 @@ func (i *OsqueryInstance) Launch() error {
 	// successfully started. ("successful" is independent of exit
 	// code. eg: this runs if we could exec. Failure to exec is above.)
 	i.errgroup.StartGoroutine(ctx, "monitor_osquery_process", func() error {
-		err := i.cmd.Wait()
+		waitErr := make(chan error, 1)
+		gowrapper.Go(ctx, i.slogger, func() {
+			waitErr <- i.cmd.Wait()
+		})
+
+		var err error
+		select {
+		case err = <-waitErr:
+		case <-i.handingOver:
+			// osqueryd is staying up for the next launcher process, so there's nothing more to monitor
+			return nil
+		}
+
 		switch {
 		case err == nil, isExitOk(err):
 			i.slogger.Log(ctx, slog.LevelInfo,
@@ -423,8 +553,7 @@ func (i *OsqueryInstance) Launch() error {
 		distributed.NewPlugin(KolideSaasExtensionName, i.saasExtension.GetQueries, i.saasExtension.WriteResults),
 		osquerylogger.NewPlugin(KolideSaasExtensionName, i.saasExtension.LogString),
 	}
-	kolideSaasPlugins = append(kolideSaasPlugins, table.PlatformTables(i.knapsack, i.registrationId, i.knapsack.Slogger().With("component", "platform_tables"), currentOsquerydBinaryPath)...)
-	kolideSaasPlugins = append(kolideSaasPlugins, table.LauncherTables(i.knapsack)...)
+	kolideSaasPlugins = append(kolideSaasPlugins, table.RegisteredTables(i.knapsack, i.registrationId, i.knapsack.Slogger().With("component", "platform_tables"), currentOsquerydBinaryPath)...)
 
 	if err := i.StartOsqueryExtensionManagerServer(KolideSaasExtensionName, paths.extensionSocketPath, i.extensionManagerClient, kolideSaasPlugins); err != nil {
 		i.slogger.Log(ctx, slog.LevelInfo,
@@ -460,6 +589,38 @@ func (i *OsqueryInstance) Launch() error {
 		return nil
 	})
 
+	// Canary queries on interval -- osqueryd can stop answering queries while still answering pings
+	canary := newQueryCanary(i.slogger, i.Query)
+	i.errgroup.StartRepeatedGoroutine(ctx, "query_canary", canaryInterval, i.knapsack.OsqueryHealthcheckStartupDelay(), func() error {
+		// As with healthchecks, don't force unnecessary restarts while the device is sleeping
+		if i.knapsack != nil && i.knapsack.InModernStandby() {
+			return nil
+		}
+
+		if err := canary.check(ctx); err != nil {
+			return fmt.Errorf("query canary failed: %w", err)
+		}
+
+		return nil
+	})
+
+	// Snapshot selected tables on interval, emitting differential results for changes
+	if hostIdentifier, err := launcherosq.IdentifierFromDB(i.knapsack.ConfigStore(), i.registrationId); err != nil {
+		i.slogger.Log(ctx, slog.LevelWarn,
+			"could not get host identifier, not starting snapshot diffs",
+			"err", err,
+		)
+	} else {
+		snapshotDiffEngine := snapshotdiff.New(i.knapsack, i.registrationId, hostIdentifier, i, i.saasExtension)
+		i.errgroup.StartRepeatedGoroutine(ctx, "snapshot_diff", i.knapsack.SnapshotDiffInterval(), i.knapsack.OsqueryHealthcheckStartupDelay(), func() error {
+			// Failures are logged by the engine; they should never stop the instance
+			snapshotDiffEngine.Run(ctx)
+			return nil
+		})
+	}
+
+	i.launched.Store(true)
+
 	return nil
 }
 
@@ -475,6 +636,20 @@ func (i *OsqueryInstance) startOsquerydProcess(ctx context.Context, paths *osque
 		"args", strings.Join(i.cmd.Args, " "),
 	)
 
+	// Where handover is supported, pipe osqueryd's output ourselves, so that the pipes can be
+	// inherited by the next launcher process. The write ends belong to osqueryd once it starts.
+	if handoverSupported {
+		outputWriters, err := i.pipeOsquerydOutput(ctx)
+		if err != nil {
+			return fmt.Errorf("creating pipes for osqueryd output: %w", err)
+		}
+		defer func() {
+			for _, w := range outputWriters {
+				w.Close()
+			}
+		}()
+	}
+
 	if err := i.startFunc(i.cmd); err != nil {
 		// Failure here is indicative of a failure to exec. A missing
 		// binary? Bad permissions? TODO: Consider catching errors in the
@@ -557,15 +732,14 @@ func (i *OsqueryInstance) healthcheckWithRetries(ctx context.Context, maxHealthC
 	return nil
 }
 
-// startKolideSaasExtension creates the Kolide SaaS extension, which provides configuration,
-// distributed queries, and a log destination for the osquery process.
-func (i *OsqueryInstance) startKolideSaasExtension(ctx context.Context) error {
-	ctx, span := traces.StartSpan(ctx)
-	defer span.End()
-
-	// create the osquery extension
+// extensionOpts builds the options for the Kolide SaaS extension from the current flag values.
+func (i *OsqueryInstance) extensionOpts(ctx context.Context) launcherosq.ExtensionOpts {
 	extOpts := launcherosq.ExtensionOpts{
-		LoggingInterval: i.knapsack.LoggingInterval(),
+		LoggingInterval:            i.knapsack.LoggingInterval(),
+		MaxBufferedLogs:            i.knapsack.MaxBufferedLogs(),
+		DeduplicateSnapshotResults: i.knapsack.DeduplicateSnapshotResults(),
+		ExportPlatformLogs:         i.knapsack.ExportPlatformLogs(),
+		WatchdogEvents:             i.watchdogEvents,
 	}
 
 	// Setting MaxBytesPerBatch is a tradeoff. If it's too low, we
@@ -591,9 +765,29 @@ func (i *OsqueryInstance) startKolideSaasExtension(ctx context.Context) error {
 		extOpts.MaxBytesPerBatch = 5 << 20
 	}
 
+	return extOpts
+}
+
+// reconfigureExtension applies the current flag values to the running Kolide SaaS extension,
+// without restarting osquery.
+func (i *OsqueryInstance) reconfigureExtension(ctx context.Context) {
+	// If the instance hasn't finished launching, it will pick up the current values when it does
+	if !i.launched.Load() || i.saasExtension == nil {
+		return
+	}
+
+	i.saasExtension.Reconfigure(i.extensionOpts(ctx))
+}
+
+// startKolideSaasExtension creates the Kolide SaaS extension, which provides configuration,
+// distributed queries, and a log destination for the osquery process.
+func (i *OsqueryInstance) startKolideSaasExtension(ctx context.Context) error {
+	ctx, span := traces.StartSpan(ctx)
+	defer span.End()
+
 	// Create the extension
 	var err error
-	i.saasExtension, err = launcherosq.NewExtension(ctx, i.serviceClient, i.settingsWriter, i.knapsack, i.registrationId, extOpts)
+	i.saasExtension, err = launcherosq.NewExtension(ctx, i.serviceClient, i.settingsWriter, i.knapsack, i.registrationId, i.extensionOpts(ctx))
 	if err != nil {
 		return fmt.Errorf("creating new extension: %w", err)
 	}
@@ -678,6 +872,36 @@ func calculateOsqueryPaths(rootDirectory string, registrationId string, runId st
 	return osqueryFilePaths, nil
 }
 
+// fimFlags returns the flags needed to enable the FIM configuration sent by the control
+// server, if any. Problems with the configuration are logged here, once per osquery launch.
+func (i *OsqueryInstance) fimFlags() []string {
+	store := i.knapsack.FimConfigStore()
+	if store == nil {
+		return nil
+	}
+
+	effective, err := fim.LoadEffective(store, runtime.GOOS)
+	if err != nil {
+		i.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not load FIM config",
+			"err", err,
+		)
+		return nil
+	}
+
+	for _, problem := range effective.Problems {
+		i.slogger.Log(context.TODO(), slog.LevelWarn,
+			"could not apply part of FIM config",
+			"category", problem.Category,
+			"kind", problem.Kind,
+			"value", problem.Value,
+			"problem", problem.Message,
+		)
+	}
+
+	return effective.Flags
+}
+
 // createOsquerydCommand uses osqueryOptions to return an *exec.Cmd
 // which will launch a properly configured osqueryd process.
 func (i *OsqueryInstance) createOsquerydCommand(osquerydBinary string, paths *osqueryFilePaths) (*exec.Cmd, error) {
@@ -724,26 +948,9 @@ func (i *OsqueryInstance) createOsquerydCommand(osquerydBinary string, paths *os
 	}
 
 	cmd.Args = append(cmd.Args, platformArgs()...)
-	cmd.Stdout = kolidelog.NewOsqueryLogAdapter(
-		i.knapsack.Slogger().With(
-			"component", "osquery",
-			"osqlevel", "stdout",
-			"registration_id", i.registrationId,
-			"instance_run_id", i.runId,
-		),
-		i.knapsack.RootDirectory(),
-		kolidelog.WithLevel(slog.LevelDebug),
-	)
-	cmd.Stderr = kolidelog.NewOsqueryLogAdapter(
-		i.knapsack.Slogger().With(
-			"component", "osquery",
-			"osqlevel", "stderr",
-			"registration_id", i.registrationId,
-			"instance_run_id", i.runId,
-		),
-		i.knapsack.RootDirectory(),
-		kolidelog.WithLevel(slog.LevelInfo),
-	)
+	cmd.Args = append(cmd.Args, i.fimFlags()...)
+	cmd.Stdout = i.newOsqueryLogAdapter("stdout", slog.LevelDebug)
+	cmd.Stderr = i.newOsqueryLogAdapter("stderr", slog.LevelInfo)
 
 	// Apply user-provided flags last so that they can override other flags set
 	// by Launcher (besides the flags below)
@@ -792,6 +999,23 @@ func (i *OsqueryInstance) createOsquerydCommand(osquerydBinary string, paths *os
 	return cmd, nil
 }
 
+// newOsqueryLogAdapter returns a writer that logs osqueryd's output from the given stream.
+func (i *OsqueryInstance) newOsqueryLogAdapter(osqlevel string, level slog.Level) io.Writer {
+	return kolidelog.NewOsqueryLogAdapter(
+		i.knapsack.Slogger().With(
+			"component", "osquery",
+			"osqlevel", osqlevel,
+			"registration_id", i.registrationId,
+			"instance_run_id", i.runId,
+		),
+		i.knapsack.RootDirectory(),
+		kolidelog.WithLevel(level),
+		kolidelog.WithObserver(func(msg string) {
+			i.watchdogEvents.Observe(context.TODO(), msg, time.Now())
+		}),
+	)
+}
+
 // StartOsqueryClient will create and return a new osquery client with a connection
 // over the socket at the provided path. It will retry for up to 10 seconds to create
 // the connection in the event of a failure.
@@ -66,6 +66,7 @@ func TestCreateOsqueryCommand(t *testing.T) {
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("Slogger").Return(multislogger.NewNopLogger())
 	k.On("RootDirectory").Return("")
+	k.On("FimConfigStore").Return(nil)
 
 	i := newInstance(types.DefaultRegistrationID, k, mockServiceClient(t), settingsstoremock.NewSettingsStoreWriter(t))
 
@@ -87,6 +88,7 @@ func TestCreateOsqueryCommandWithFlags(t *testing.T) {
 	k.On("OsqueryVerbose").Return(true)
 	k.On("Slogger").Return(multislogger.NewNopLogger())
 	k.On("RootDirectory").Return("")
+	k.On("FimConfigStore").Return(nil)
 
 	i := newInstance(types.DefaultRegistrationID, k, mockServiceClient(t), settingsstoremock.NewSettingsStoreWriter(t))
 
@@ -121,6 +123,7 @@ func TestCreateOsqueryCommand_SetsEnabledWatchdogSettingsAppropriately(t *testin
 	k.On("OsqueryVerbose").Return(true)
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("RootDirectory").Return("")
+	k.On("FimConfigStore").Return(nil)
 
 	i := newInstance(types.DefaultRegistrationID, k, mockServiceClient(t), settingsstoremock.NewSettingsStoreWriter(t))
 
@@ -171,6 +174,7 @@ func TestCreateOsqueryCommand_SetsDisabledWatchdogSettingsAppropriately(t *testi
 	k.On("OsqueryVerbose").Return(true)
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("RootDirectory").Return("")
+	k.On("FimConfigStore").Return(nil)
 
 	i := newInstance(types.DefaultRegistrationID, k, mockServiceClient(t), settingsstoremock.NewSettingsStoreWriter(t))
 
@@ -258,11 +262,16 @@ func TestLaunch(t *testing.T) {
 	k.On("RootDirectory").Return(rootDirectory)
 	k.On("LoggingInterval").Return(1 * time.Second)
 	k.On("LogMaxBytesPerBatch").Return(500)
+	k.On("MaxBufferedLogs").Return(0)
+	k.On("DeduplicateSnapshotResults").Return(false)
+	k.On("ExportPlatformLogs").Return(false)
 	k.On("Transport").Return("jsonrpc")
 	setUpMockStores(t, k)
 	k.On("ReadEnrollSecret").Return("", nil)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("OsqueryHealthcheckStartupDelay").Return(10 * time.Second)
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel).Maybe()
 	k.On("RegisterChangeObserver", mock.Anything, keys.PinnedLauncherVersion).Maybe()
 	k.On("RegisterChangeObserver", mock.Anything, keys.PinnedOsquerydVersion).Maybe()
@@ -11,6 +11,7 @@ import (
 
 	"github.com/kolide/launcher/ee/agent/flags/keys"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/tuf"
 	"github.com/kolide/launcher/pkg/service"
 	"github.com/kolide/launcher/pkg/traces"
 	"golang.org/x/sync/errgroup"
@@ -20,6 +21,10 @@ const (
 	launchRetryDelay = 10 * time.Second
 )
 
+// extensionOptionKeys are the flags backing the Kolide SaaS extension's log batching and
+// buffering options, which the running extension can pick up without restarting osquery.
+var extensionOptionKeys = []keys.FlagKey{keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults}
+
 // settingsStoreWriter writes to our startup settings store
 type settingsStoreWriter interface {
 	WriteSettings() error
@@ -54,6 +59,9 @@ func New(k types.Knapsack, serviceClient service.KolideService, settingsWriter s
 		keys.WatchdogEnabled, keys.WatchdogMemoryLimitMB, keys.WatchdogUtilizationLimitPercent, keys.WatchdogDelaySec,
 	)
 
+	// These can be applied to the running extensions, without a restart
+	k.RegisterChangeObserver(runner, extensionOptionKeys...)
+
 	return runner
 }
 
@@ -147,6 +155,9 @@ func (r *Runner) launchInstanceWithRetries(ctx context.Context, registrationId s
 	ctx, span := traces.StartSpan(ctx)
 	defer span.End()
 
+	// If the previous launcher process handed over its osqueryd process, try to adopt it
+	record := r.handoverRecord(ctx, registrationId)
+
 	for {
 		// Add the instance to our instances map right away, so that if we receive a shutdown
 		// request during launch, we can shut down the instance.
@@ -154,7 +165,13 @@ func (r *Runner) launchInstanceWithRetries(ctx context.Context, registrationId s
 		instance := newInstance(registrationId, r.knapsack, r.serviceClient, r.settingsWriter, r.opts...)
 		r.instances[registrationId] = instance
 		r.instanceLock.Unlock()
-		err := instance.Launch()
+
+		var err error
+		if record != nil {
+			err = instance.Adopt(record)
+		} else {
+			err = instance.Launch()
+		}
 
 		// Success!
 		if err == nil {
@@ -171,6 +188,7 @@ func (r *Runner) launchInstanceWithRetries(ctx context.Context, registrationId s
 			"could not launch instance, will retry after delay",
 			"err", err,
 			"registration_id", registrationId,
+			"adopting", record != nil,
 		)
 		instance.BeginShutdown()
 		if err := instance.WaitShutdown(ctx); err != context.Canceled && err != nil {
@@ -181,6 +199,13 @@ func (r *Runner) launchInstanceWithRetries(ctx context.Context, registrationId s
 			)
 		}
 
+		// If we failed to adopt the handed-over osqueryd process, shutting down the instance
+		// killed it -- launch a new one right away.
+		if record != nil {
+			record = nil
+			continue
+		}
+
 		select {
 		case <-r.shutdown:
 			return nil, fmt.Errorf("runner received shutdown, halting before successfully launching instance for %s", registrationId)
@@ -204,7 +229,62 @@ func (r *Runner) Query(query string) ([]map[string]string, error) {
 	return instance.Query(query)
 }
 
-func (r *Runner) Interrupt(_ error) {
+// handoverRecord returns the valid handover record for the given registration ID, if the
+// previous launcher process left one. If the record is not valid, but refers to an osqueryd
+// process that is still running, it kills that process so that it isn't orphaned.
+func (r *Runner) handoverRecord(ctx context.Context, registrationId string) *handoverRecord {
+	record, err := takeHandoverRecord(r.knapsack.RootDirectory(), registrationId)
+	if err != nil {
+		r.slogger.Log(ctx, slog.LevelWarn,
+			"could not read osquery handover record",
+			"err", err,
+			"registration_id", registrationId,
+		)
+		return nil
+	}
+	if record == nil {
+		return nil
+	}
+
+	if err := validateHandoverRecord(record); err != nil {
+		r.slogger.Log(ctx, slog.LevelWarn,
+			"osquery handover record is not valid, will launch new osqueryd process",
+			"err", err,
+			"registration_id", registrationId,
+			"osqueryd_pid", record.OsquerydPid,
+		)
+
+		if pidfileMatches(record) && processAlive(record.OsquerydPid) {
+			if err := killStaleOsquerydProcess(record.OsquerydPid); err != nil {
+				r.slogger.Log(ctx, slog.LevelWarn,
+					"could not kill osqueryd process from invalid handover",
+					"err", err,
+					"registration_id", registrationId,
+					"osqueryd_pid", record.OsquerydPid,
+				)
+			}
+		}
+
+		return nil
+	}
+
+	return record
+}
+
+// Interrupt shuts down the runner. If launcher is exiting to reload after an autoupdate and
+// osquery handover is enabled, it hands over the running osqueryd processes to the new launcher
+// process instead of stopping them.
+func (r *Runner) Interrupt(interruptErr error) {
+	if tuf.IsLauncherReloadNeededErr(interruptErr) && r.knapsack.OsqueryHandoverEnabled() && handoverSupported {
+		if err := r.Handover(); err != nil {
+			r.slogger.Log(context.TODO(), slog.LevelWarn,
+				"could not hand over osquery instances on interrupt",
+				"err", err,
+			)
+		}
+		return
+	}
+
 	if err := r.Shutdown(); err != nil {
 		r.slogger.Log(context.TODO(), slog.LevelWarn,
 			"could not shut down runner on interrupt",
@@ -227,15 +307,38 @@ func (r *Runner) Shutdown() error {
 	r.interrupted.Store(true)
 	close(r.shutdown)
 
-	if err := r.triggerShutdownForInstances(ctx); err != nil {
+	if err := r.triggerShutdownForInstances(ctx, false); err != nil {
 		return fmt.Errorf("triggering shutdown for instances during runner shutdown: %w", err)
 	}
 
 	return nil
 }
 
-// triggerShutdownForInstances asks all instances in `r.instances` to shut down.
-func (r *Runner) triggerShutdownForInstances(ctx context.Context) error {
+// Handover permanently stops the runner, like `Shutdown`, but leaves each instance's osqueryd
+// process running for the next launcher process to adopt. Instances that cannot be handed over
+// are shut down as usual.
+func (r *Runner) Handover() error {
+	ctx, span := traces.StartSpan(context.TODO())
+	defer span.End()
+
+	if r.interrupted.Load() {
+		// Already shut down, nothing else to do
+		return nil
+	}
+
+	r.interrupted.Store(true)
+	close(r.shutdown)
+
+	if err := r.triggerShutdownForInstances(ctx, true); err != nil {
+		return fmt.Errorf("triggering handover for instances: %w", err)
+	}
+
+	return nil
+}
+
+// triggerShutdownForInstances asks all instances in `r.instances` to shut down. If handover
+// is true, it asks them to hand over their osqueryd processes instead, where possible.
+func (r *Runner) triggerShutdownForInstances(ctx context.Context, handover bool) error {
 	ctx, span := traces.StartSpan(ctx)
 	defer span.End()
 
@@ -248,7 +351,16 @@ func (r *Runner) triggerShutdownForInstances(ctx context.Context) error {
 		id := registrationId
 		i := instance
 		shutdownWg.Go(func() error {
-			i.BeginShutdown()
+			if !handover {
+				i.BeginShutdown()
+			} else if err := i.BeginHandover(); err != nil {
+				r.slogger.Log(ctx, slog.LevelWarn,
+					"could not hand over osquery instance, shutting it down instead",
+					"err", err,
+					"registration_id", id,
+				)
+				i.BeginShutdown()
+			}
 			if err := i.WaitShutdown(ctx); err != context.Canceled && err != nil {
 				return fmt.Errorf("shutting down instance %s: %w", id, err)
 			}
@@ -270,6 +382,15 @@ func (r *Runner) FlagsChanged(ctx context.Context, flagKeys ...keys.FlagKey) {
 	ctx, span := traces.StartSpan(ctx)
 	defer span.End()
 
+	if len(keys.Intersection(flagKeys, extensionOptionKeys)) > 0 {
+		r.reconfigureExtensions(ctx)
+
+		// If nothing else changed, no restart is needed
+		if len(flagKeys) == len(keys.Intersection(flagKeys, extensionOptionKeys)) {
+			return
+		}
+	}
+
 	r.slogger.Log(ctx, slog.LevelDebug,
 		"control server flags changed, restarting instance to apply",
 		"flags", fmt.Sprintf("%+v", flagKeys),
@@ -283,19 +404,29 @@ func (r *Runner) FlagsChanged(ctx context.Context, flagKeys ...keys.FlagKey) {
 	}
 }
 
+// reconfigureExtensions applies the current extension option flags to each running instance's extension.
+func (r *Runner) reconfigureExtensions(ctx context.Context) {
+	r.instanceLock.Lock()
+	defer r.instanceLock.Unlock()
+
+	for _, instance := range r.instances {
+		instance.reconfigureExtension(ctx)
+	}
+}
+
 // Ping satisfies the control.subscriber interface -- the runner subscribes to changes to
-// the katc_config subsystem.
+// the katc_config and fim_config subsystems.
 func (r *Runner) Ping() {
 	ctx, span := traces.StartSpan(context.TODO())
 	defer span.End()
 
 	r.slogger.Log(ctx, slog.LevelDebug,
-		"KATC configuration changed, restarting instance to apply",
+		"KATC or FIM configuration changed, restarting instance to apply",
 	)
 
 	if err := r.Restart(ctx); err != nil {
 		r.slogger.Log(ctx, slog.LevelError,
-			"could not restart osquery instance after KATC configuration changed",
+			"could not restart osquery instance after KATC or FIM configuration changed",
 			"err", err,
 		)
 	}
@@ -312,13 +443,79 @@ func (r *Runner) Restart(ctx context.Context) error {
 	)
 
 	// Shut down the instances -- this will trigger a restart in each `runInstance`.
-	if err := r.triggerShutdownForInstances(ctx); err != nil {
+	if err := r.triggerShutdownForInstances(ctx, false); err != nil {
 		return fmt.Errorf("triggering shutdown for instances during runner restart: %w", err)
 	}
 
 	return nil
 }
 
+// RestartInstance shuts down the instance for the given registration, which its worker then
+// relaunches -- to run the osqueryd selected for the registration, without disturbing the
+// other registrations' instances.
+func (r *Runner) RestartInstance(ctx context.Context, registrationId string) error {
+	ctx, span := traces.StartSpan(ctx)
+	defer span.End()
+
+	r.instanceLock.Lock()
+	defer r.instanceLock.Unlock()
+
+	instance, ok := r.instances[registrationId]
+	if !ok {
+		return fmt.Errorf("no instance exists for %s, cannot restart", registrationId)
+	}
+
+	instance.BeginShutdown()
+	if err := instance.WaitShutdown(ctx); err != context.Canceled && err != nil {
+		return fmt.Errorf("shutting down instance %s for restart: %w", registrationId, err)
+	}
+
+	return nil
+}
+
+// ResetDatabase shuts down the instance for the given registration, and calls reset before its
+// worker relaunches it, so that reset can safely replace the instance's database. The worker
+// relaunches the instance even if reset fails.
+func (r *Runner) ResetDatabase(ctx context.Context, registrationId string, reset func() error) error {
+	ctx, span := traces.StartSpan(ctx)
+	defer span.End()
+
+	// Holding the lock keeps the worker from relaunching the instance until reset is done
+	r.instanceLock.Lock()
+	defer r.instanceLock.Unlock()
+
+	instance, ok := r.instances[registrationId]
+	if !ok {
+		return fmt.Errorf("no instance exists for %s, cannot reset database", registrationId)
+	}
+
+	instance.BeginShutdown()
+	if err := instance.WaitShutdown(ctx); err != context.Canceled && err != nil {
+		return fmt.Errorf("shutting down instance %s for database reset: %w", registrationId, err)
+	}
+
+	r.slogger.Log(ctx, slog.LevelInfo,
+		"resetting osquery database while instance is down",
+		"registration_id", registrationId,
+	)
+
+	return reset()
+}
+
+// OsquerydPath returns the path to the osqueryd binary that the instance for the given
+// registration is running, or an empty string if it isn't running one.
+func (r *Runner) OsquerydPath(registrationId string) string {
+	r.instanceLock.Lock()
+	defer r.instanceLock.Unlock()
+
+	instance, ok := r.instances[registrationId]
+	if !ok {
+		return ""
+	}
+
+	return instance.osquerydPath()
+}
+
 // Healthy checks the health of the instance and returns an error describing
 // any problem.
 func (r *Runner) Healthy() error {
@@ -46,15 +46,21 @@ func TestOsquerySlowStart(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryVerbose").Return(true).Maybe()
 	k.On("OsqueryFlags").Return([]string{}).Maybe()
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -104,15 +110,21 @@ func TestExtensionSocketPath(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryVerbose").Return(true).Maybe()
 	k.On("OsqueryFlags").Return([]string{}).Maybe()
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -134,15 +134,21 @@ func TestBadBinaryPath(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return("") // bad binary path
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return("") // bad binary path
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryVerbose").Return(true)
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("RegisterChangeObserver", mock.Anything, keys.UpdateChannel).Maybe()
@@ -178,15 +184,21 @@ func TestWithOsqueryFlags(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryFlags").Return([]string{"verbose=false"})
 	k.On("OsqueryVerbose").Return(false)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -218,18 +230,24 @@ func TestFlagsChanged(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false).Once() // WatchdogEnabled should initially return false
 	k.On("WatchdogMemoryLimitMB").Return(150)
 	k.On("WatchdogUtilizationLimitPercent").Return(20)
 	k.On("WatchdogDelaySec").Return(120)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryFlags").Return([]string{"verbose=false"})
 	k.On("OsqueryVerbose").Return(false)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -425,15 +443,21 @@ func TestSimplePath(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("OsqueryVerbose").Return(true)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -472,15 +496,21 @@ func TestMultipleInstances(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID, extraRegistrationId})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("OsqueryVerbose").Return(true)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -542,15 +572,21 @@ func TestRunnerHandlesImmediateShutdownWithMultipleInstances(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("OsqueryVerbose").Return(true)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -604,15 +640,21 @@ func TestMultipleShutdowns(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("OsqueryVerbose").Return(true)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -647,15 +689,21 @@ func TestOsqueryDies(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(false)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults)
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("RootDirectory").Return(rootDirectory)
 	k.On("OsqueryFlags").Return([]string{})
 	k.On("OsqueryVerbose").Return(true)
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -700,8 +748,11 @@ func TestNotStarted(t *testing.T) {
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults).Return()
 	k.On("Slogger").Return(multislogger.NewNopLogger())
 	runner := New(k, mockServiceClient(t), settingsstoremock.NewSettingsStoreWriter(t))
 
@@ -781,6 +832,41 @@ func TestRestart(t *testing.T) {
 	waitShutdown(t, runner, logBytes)
 }
 
+func TestResetDatabase(t *testing.T) {
+	t.Parallel()
+	runner, logBytes := setupOsqueryInstanceForTests(t)
+	ensureShutdownOnCleanup(t, runner, logBytes)
+
+	previousInstance := runner.instances[types.DefaultRegistrationID]
+	dbPath := previousInstance.paths.databasePath
+	archivePath := dbPath + ".archived"
+
+	require.NoError(t, runner.ResetDatabase(context.TODO(), types.DefaultRegistrationID, func() error {
+		// The instance must be down, and not yet replaced, while we reset
+		require.NotEmpty(t, previousInstance.stats.ExitTime)
+		require.Equal(t, previousInstance, runner.instances[types.DefaultRegistrationID])
+		return os.Rename(dbPath, archivePath)
+	}))
+	waitHealthy(t, runner, logBytes)
+
+	require.NotEqual(t, previousInstance, runner.instances[types.DefaultRegistrationID])
+	require.DirExists(t, archivePath)
+	require.DirExists(t, dbPath, "relaunched instance should create a fresh database")
+
+	// A failed reset still relaunches the instance
+	require.Error(t, runner.ResetDatabase(context.TODO(), types.DefaultRegistrationID, func() error {
+		return errors.New("test error")
+	}))
+	waitHealthy(t, runner, logBytes)
+
+	require.Error(t, runner.ResetDatabase(context.TODO(), "not-a-registration", func() error {
+		t.Fatal("reset should not be called for unknown registration")
+		return nil
+	}))
+
+	waitShutdown(t, runner, logBytes)
+}
+
 // sets up an osquery instance with a running extension to be used in tests.
 func setupOsqueryInstanceForTests(t *testing.T) (runner *Runner, logBytes *threadsafebuffer.ThreadSafeBuffer) {
 	rootDirectory := testRootDirectory(t)
@@ -790,18 +876,24 @@ func setupOsqueryInstanceForTests(t *testing.T) (runner *Runner, logBytes *threa
 	k := typesMocks.NewKnapsack(t)
 	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID})
 	k.On("OsqueryHealthcheckStartupDelay").Return(0 * time.Second).Maybe()
+	k.On("SnapshotDiffTables").Return([]string{}).Maybe()
+	k.On("SnapshotDiffInterval").Return(1 * time.Hour).Maybe()
 	k.On("WatchdogEnabled").Return(true)
 	k.On("WatchdogMemoryLimitMB").Return(150)
 	k.On("WatchdogUtilizationLimitPercent").Return(20)
 	k.On("WatchdogDelaySec").Return(120)
 	k.On("RegisterChangeObserver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	k.On("RegisterChangeObserver", mock.Anything, keys.LoggingInterval, keys.LogMaxBytesPerBatch, keys.MaxBufferedLogs, keys.DeduplicateSnapshotResults).Maybe()
 	k.On("Slogger").Return(slogger)
-	k.On("LatestOsquerydPath", mock.Anything).Return(testOsqueryBinary)
+	k.On("RegistrationOsquerydPath", mock.Anything, mock.Anything).Return(testOsqueryBinary)
 	k.On("RootDirectory").Return(rootDirectory).Maybe()
 	k.On("OsqueryFlags").Return([]string{}).Maybe()
 	k.On("OsqueryVerbose").Return(true).Maybe()
 	k.On("LoggingInterval").Return(5 * time.Minute).Maybe()
 	k.On("LogMaxBytesPerBatch").Return(0).Maybe()
+	k.On("MaxBufferedLogs").Return(0).Maybe()
+	k.On("DeduplicateSnapshotResults").Return(false).Maybe()
+	k.On("ExportPlatformLogs").Return(false).Maybe()
 	k.On("Transport").Return("jsonrpc").Maybe()
 	k.On("ReadEnrollSecret").Return("", nil).Maybe()
 	k.On("InModernStandby").Return(false).Maybe()
@@ -837,6 +929,14 @@ func setUpMockStores(t *testing.T, k *typesMocks.Knapsack) {
 	k.On("AutoupdateErrorsStore").Return(inmemory.NewStore()).Maybe()
 	k.On("StatusLogsStore").Return(inmemory.NewStore()).Maybe()
 	k.On("ResultLogsStore").Return(inmemory.NewStore()).Maybe()
+	k.On("SnapshotDiffStore").Return(inmemory.NewStore()).Maybe()
+	k.On("FimConfigStore").Return(inmemory.NewStore()).Maybe()
+	k.On("ListeningServicesStore").Return(inmemory.NewStore()).Maybe()
+	k.On("HostsFileWatchStore").Return(inmemory.NewStore()).Maybe()
+	k.On("EnrollmentAttemptsStore").Return(inmemory.NewStore()).Maybe()
+	k.On("ControlActionHistoryStore").Return(inmemory.NewStore()).Maybe()
+	k.On("NetworkChangeEventsStore").Return(inmemory.NewStore()).Maybe()
+	k.On("ControlSigningKeysStore").Return(inmemory.NewStore()).Maybe()
 	k.On("BboltDB").Return(storageci.SetupDB(t)).Maybe()
 }
 
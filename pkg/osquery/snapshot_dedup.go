@@ -0,0 +1,114 @@
+package osquery
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"strconv"
+)
+
+const (
+	// Fields added to a snapshot result log that stands in for identical results after it
+	repeatCountField  = "kolide_repeat_count"
+	lastUnixTimeField = "kolide_last_unix_time"
+
+	// Room for the fields above, which we add after the batch's size has been tallied
+	dedupedSnapshotOverhead = 64
+)
+
+// snapshotDeduplicator collapses identical consecutive snapshot results for the same query, within
+// a batch of result logs, into the first of them. That log is annotated with the number of results
+// it stands for, and the time of the last one, so that stable queries don't cost an upload per run.
+// Results are identical if their rows and decorations are; the time they ran doesn't matter.
+type snapshotDeduplicator struct {
+	latest   map[string]*dedupedSnapshot // query name -> the query's most recent snapshot in the batch
+	repeated []*dedupedSnapshot          // snapshots in the batch that were followed by identical ones
+}
+
+type dedupedSnapshot struct {
+	index        int               // index of the log in the batch
+	digest       [sha256.Size]byte // hash of the snapshot's rows and decorations
+	repeats      int               // number of identical snapshots that followed
+	lastUnixTime json.RawMessage   // when the last of them ran
+}
+
+// snapshotLog is the part of osquery's snapshot result log that determines its content
+type snapshotLog struct {
+	Action      string          `json:"action"`
+	Name        string          `json:"name"`
+	Snapshot    json.RawMessage `json:"snapshot"`
+	Decorations json.RawMessage `json:"decorations"`
+	UnixTime    json.RawMessage `json:"unixTime"`
+}
+
+func newSnapshotDeduplicator() *snapshotDeduplicator {
+	return &snapshotDeduplicator{
+		latest: make(map[string]*dedupedSnapshot),
+	}
+}
+
+// isRepeat reports whether log, which would be at index in the batch, repeats the previous snapshot
+// result for the same query in the batch -- in which case it's counted, and needn't be sent. The
+// second return value is the number of bytes the batch grows by to record the repeat. A nil
+// deduplicator treats every log as new.
+func (d *snapshotDeduplicator) isRepeat(log []byte, index int) (bool, int) {
+	if d == nil {
+		return false, 0
+	}
+
+	// Avoid decoding logs that can't be snapshots
+	if !bytes.Contains(log, []byte(`"snapshot"`)) {
+		return false, 0
+	}
+
+	var snapshot snapshotLog
+	if err := json.Unmarshal(log, &snapshot); err != nil || snapshot.Action != "snapshot" || snapshot.Name == "" {
+		return false, 0
+	}
+
+	h := sha256.New()
+	h.Write(snapshot.Snapshot)
+	h.Write([]byte{0})
+	h.Write(snapshot.Decorations)
+	var digest [sha256.Size]byte
+	copy(digest[:], h.Sum(nil))
+
+	previous, ok := d.latest[snapshot.Name]
+	if ok && previous.digest == digest {
+		previous.repeats += 1
+		previous.lastUnixTime = snapshot.UnixTime
+		if previous.repeats == 1 {
+			d.repeated = append(d.repeated, previous)
+			return true, dedupedSnapshotOverhead
+		}
+		return true, 0
+	}
+
+	d.latest[snapshot.Name] = &dedupedSnapshot{
+		index:  index,
+		digest: digest,
+	}
+
+	return false, 0
+}
+
+// annotate adds the repeat count to the logs in the batch that stand in for identical results.
+func (d *snapshotDeduplicator) annotate(logs []string) {
+	for _, snapshot := range d.repeated {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(logs[snapshot.index]), &fields); err != nil {
+			continue
+		}
+
+		fields[repeatCountField] = json.RawMessage(strconv.Itoa(snapshot.repeats + 1))
+		if len(snapshot.lastUnixTime) > 0 {
+			fields[lastUnixTimeField] = snapshot.lastUnixTime
+		}
+
+		annotated, err := json.Marshal(fields)
+		if err != nil {
+			continue
+		}
+		logs[snapshot.index] = string(annotated)
+	}
+}
@@ -0,0 +1,286 @@
+// Package snapshotdiff provides event semantics for tables that osquery cannot make evented.
+// It periodically snapshots the configured tables, compares each snapshot to the previous one,
+// and emits differential result logs -- one per added or removed row -- in the same format
+// osquery uses for scheduled queries.
+package snapshotdiff
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/kolide/launcher/ee/agent/storage"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/osquery/osquery-go/plugin/logger"
+)
+
+const (
+	actionAdded   = "added"
+	actionRemoved = "removed"
+
+	// osquery's calendarTime format
+	calendarTimeLayout = "Mon Jan _2 15:04:05 2006 MST"
+)
+
+// Only plain table names are allowed, since we build the query from them
+var tableNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+type querier interface {
+	Query(query string) ([]map[string]string, error)
+}
+
+type logWriter interface {
+	LogString(ctx context.Context, typ logger.LogType, logText string) error
+}
+
+type Engine struct {
+	slogger        *slog.Logger
+	knapsack       types.Knapsack
+	store          types.KVStore
+	registrationId string
+	hostIdentifier string
+	querier        querier
+	logWriter      logWriter
+}
+
+// snapshot is the previous snapshot of a table, as stored between runs.
+type snapshot struct {
+	Counter int                          `json:"counter"`
+	Rows    map[string]map[string]string `json:"rows"`
+}
+
+// resultLog is a single differential result, matching osquery's event format.
+type resultLog struct {
+	Name           string            `json:"name"`
+	HostIdentifier string            `json:"hostIdentifier"`
+	CalendarTime   string            `json:"calendarTime"`
+	UnixTime       int64             `json:"unixTime"`
+	Epoch          int               `json:"epoch"`
+	Counter        int               `json:"counter"`
+	Numerics       bool              `json:"numerics"`
+	Columns        map[string]string `json:"columns"`
+	Action         string            `json:"action"`
+}
+
+func New(k types.Knapsack, registrationId string, hostIdentifier string, q querier, w logWriter) *Engine {
+	return &Engine{
+		slogger:        k.Slogger().With("component", "snapshot_diff", "registration_id", registrationId),
+		knapsack:       k,
+		store:          k.SnapshotDiffStore(),
+		registrationId: registrationId,
+		hostIdentifier: hostIdentifier,
+		querier:        q,
+		logWriter:      w,
+	}
+}
+
+// Run snapshots each configured table once, emitting result logs for any changes since the
+// previous snapshot. Errors are logged rather than returned, so that a single failing table
+// doesn't stop the others.
+func (e *Engine) Run(ctx context.Context) {
+	tables := e.knapsack.SnapshotDiffTables()
+
+	for _, tableName := range tables {
+		if err := e.snapshotTable(ctx, tableName, time.Now()); err != nil {
+			e.slogger.Log(ctx, slog.LevelInfo,
+				"could not snapshot table",
+				"table", tableName,
+				"err", err,
+			)
+		}
+	}
+
+	if err := e.pruneSnapshots(tables); err != nil {
+		e.slogger.Log(ctx, slog.LevelInfo,
+			"could not prune snapshots for tables no longer configured",
+			"err", err,
+		)
+	}
+}
+
+func (e *Engine) snapshotTable(ctx context.Context, tableName string, now time.Time) error {
+	if !tableNameRegexp.MatchString(tableName) {
+		return fmt.Errorf("invalid table name %q", tableName)
+	}
+
+	rows, err := e.querier.Query(fmt.Sprintf("SELECT * FROM %s", tableName))
+	if err != nil {
+		return fmt.Errorf("querying table: %w", err)
+	}
+
+	previous, err := e.previousSnapshot(tableName)
+	if err != nil {
+		return fmt.Errorf("reading previous snapshot: %w", err)
+	}
+
+	current := &snapshot{
+		Counter: previous.Counter,
+		Rows:    make(map[string]map[string]string, len(rows)),
+	}
+	for _, row := range rows {
+		hash, err := rowHash(row)
+		if err != nil {
+			return fmt.Errorf("hashing row: %w", err)
+		}
+		current.Rows[hash] = row
+	}
+
+	added, removed := diff(previous.Rows, current.Rows)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	// As with osquery, the first run has counter 0, and emits every row as added
+	if previous.Rows != nil {
+		current.Counter += 1
+	}
+
+	for _, change := range []struct {
+		action string
+		rows   []map[string]string
+	}{
+		{actionRemoved, removed},
+		{actionAdded, added},
+	} {
+		for _, row := range change.rows {
+			if err := e.writeResultLog(ctx, tableName, change.action, current.Counter, row, now); err != nil {
+				// Don't save the snapshot, so that we retry next time
+				return fmt.Errorf("writing result log: %w", err)
+			}
+		}
+	}
+
+	e.slogger.Log(ctx, slog.LevelDebug,
+		"emitted snapshot diff",
+		"table", tableName,
+		"added", len(added),
+		"removed", len(removed),
+	)
+
+	return e.saveSnapshot(tableName, current)
+}
+
+func (e *Engine) writeResultLog(ctx context.Context, tableName string, action string, counter int, row map[string]string, now time.Time) error {
+	logBytes, err := json.Marshal(resultLog{
+		Name:           fmt.Sprintf("snapshot_diff_%s", tableName),
+		HostIdentifier: e.hostIdentifier,
+		CalendarTime:   now.UTC().Format(calendarTimeLayout),
+		UnixTime:       now.Unix(),
+		Epoch:          0,
+		Counter:        counter,
+		Numerics:       false,
+		Columns:        row,
+		Action:         action,
+	})
+	if err != nil {
+		return fmt.Errorf("marshalling result log: %w", err)
+	}
+
+	return e.logWriter.LogString(ctx, logger.LogTypeString, string(logBytes))
+}
+
+func (e *Engine) snapshotKey(tableName string) []byte {
+	return storage.KeyByIdentifier([]byte(tableName), storage.IdentifierTypeRegistration, []byte(e.registrationId))
+}
+
+// previousSnapshot returns the previous snapshot for the table. If there isn't one, the
+// returned snapshot has nil rows.
+func (e *Engine) previousSnapshot(tableName string) (*snapshot, error) {
+	snapshotBytes, err := e.store.Get(e.snapshotKey(tableName))
+	if err != nil {
+		return nil, fmt.Errorf("getting snapshot from store: %w", err)
+	}
+
+	var s snapshot
+	if len(snapshotBytes) == 0 {
+		return &s, nil
+	}
+
+	if err := json.Unmarshal(snapshotBytes, &s); err != nil {
+		return nil, fmt.Errorf("unmarshalling snapshot: %w", err)
+	}
+
+	return &s, nil
+}
+
+func (e *Engine) saveSnapshot(tableName string, s *snapshot) error {
+	snapshotBytes, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshalling snapshot: %w", err)
+	}
+
+	if err := e.store.Set(e.snapshotKey(tableName), snapshotBytes); err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// pruneSnapshots removes stored snapshots for this registration's tables that are no longer configured.
+func (e *Engine) pruneSnapshots(tables []string) error {
+	configured := make(map[string]struct{}, len(tables))
+	for _, tableName := range tables {
+		configured[tableName] = struct{}{}
+	}
+
+	var staleKeys [][]byte
+	if err := e.store.ForEach(func(k []byte, _ []byte) error {
+		tableName, _, registrationId := storage.SplitKey(k)
+		if string(registrationId) != e.registrationId {
+			return nil
+		}
+		if _, ok := configured[string(tableName)]; !ok {
+			staleKeys = append(staleKeys, k)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("iterating over snapshots: %w", err)
+	}
+
+	if len(staleKeys) == 0 {
+		return nil
+	}
+
+	return e.store.Delete(staleKeys...)
+}
+
+// rowHash identifies a row by its contents. json.Marshal sorts map keys, so this is stable.
+func rowHash(row map[string]string) (string, error) {
+	rowBytes, err := json.Marshal(row)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(rowBytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// diff returns the rows in current but not previous, and the rows in previous but not current,
+// each in a stable order.
+func diff(previous, current map[string]map[string]string) (added, removed []map[string]string) {
+	return missingFrom(previous, current), missingFrom(current, previous)
+}
+
+// missingFrom returns the rows in b that are not in a, ordered by hash.
+func missingFrom(a, b map[string]map[string]string) []map[string]string {
+	var hashes []string
+	for hash := range b {
+		if _, ok := a[hash]; !ok {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Strings(hashes)
+
+	rows := make([]map[string]string, len(hashes))
+	for i, hash := range hashes {
+		rows[i] = b[hash]
+	}
+
+	return rows
+}
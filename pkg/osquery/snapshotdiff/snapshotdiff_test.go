@@ -0,0 +1,147 @@
+package snapshotdiff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	typesmocks "github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/osquery/osquery-go/plugin/logger"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQuerier struct {
+	results map[string][]map[string]string
+}
+
+func (f *fakeQuerier) Query(query string) ([]map[string]string, error) {
+	results, ok := f.results[query]
+	if !ok {
+		return nil, errors.New("no such table")
+	}
+	return results, nil
+}
+
+type fakeLogWriter struct {
+	logs []resultLog
+	err  error
+}
+
+func (f *fakeLogWriter) LogString(_ context.Context, typ logger.LogType, logText string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if typ != logger.LogTypeString {
+		return errors.New("unexpected log type")
+	}
+
+	var r resultLog
+	if err := json.Unmarshal([]byte(logText), &r); err != nil {
+		return err
+	}
+	f.logs = append(f.logs, r)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	k := typesmocks.NewKnapsack(t)
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("SnapshotDiffStore").Return(store)
+	k.On("SnapshotDiffTables").Return([]string{"kolide_chrome_extensions", "not_a_table", "bad; table"})
+
+	extA := map[string]string{"identifier": "a", "name": "Extension A"}
+	extB := map[string]string{"identifier": "b", "name": "Extension B"}
+	extC := map[string]string{"identifier": "c", "name": "Extension C"}
+
+	q := &fakeQuerier{results: map[string][]map[string]string{
+		"SELECT * FROM kolide_chrome_extensions": {extA, extB},
+	}}
+	w := &fakeLogWriter{}
+
+	e := New(k, "test_registration", "test_host", q, w)
+
+	// First run: all rows are added, with counter 0
+	e.Run(context.Background())
+	require.Equal(t, 2, len(w.logs))
+	for _, l := range w.logs {
+		require.Equal(t, "snapshot_diff_kolide_chrome_extensions", l.Name)
+		require.Equal(t, "test_host", l.HostIdentifier)
+		require.Equal(t, actionAdded, l.Action)
+		require.Equal(t, 0, l.Counter)
+	}
+
+	// No changes: nothing emitted
+	w.logs = nil
+	e.Run(context.Background())
+	require.Equal(t, 0, len(w.logs))
+
+	// One row removed, one added
+	q.results["SELECT * FROM kolide_chrome_extensions"] = []map[string]string{extA, extC}
+	e.Run(context.Background())
+	require.Equal(t, 2, len(w.logs))
+	require.Equal(t, actionRemoved, w.logs[0].Action)
+	require.Equal(t, extB, w.logs[0].Columns)
+	require.Equal(t, 1, w.logs[0].Counter)
+	require.Equal(t, actionAdded, w.logs[1].Action)
+	require.Equal(t, extC, w.logs[1].Columns)
+	require.Equal(t, 1, w.logs[1].Counter)
+}
+
+func TestRun_LogWriteFailureRetries(t *testing.T) {
+	t.Parallel()
+
+	k := typesmocks.NewKnapsack(t)
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("SnapshotDiffStore").Return(inmemory.NewStore())
+	k.On("SnapshotDiffTables").Return([]string{"certificates"})
+
+	q := &fakeQuerier{results: map[string][]map[string]string{
+		"SELECT * FROM certificates": {{"sha1": "abc"}},
+	}}
+	w := &fakeLogWriter{err: errors.New("test error")}
+
+	e := New(k, "test_registration", "test_host", q, w)
+
+	// The snapshot shouldn't be saved when logs can't be written
+	e.Run(context.Background())
+	require.Equal(t, 0, len(w.logs))
+
+	// So the rows are emitted on the next successful run
+	w.err = nil
+	e.Run(context.Background())
+	require.Equal(t, 1, len(w.logs))
+	require.Equal(t, actionAdded, w.logs[0].Action)
+}
+
+func TestRun_PrunesUnconfiguredTables(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	k := typesmocks.NewKnapsack(t)
+	k.On("Slogger").Return(multislogger.NewNopLogger())
+	k.On("SnapshotDiffStore").Return(store)
+	k.On("SnapshotDiffTables").Return([]string{"certificates"}).Once()
+	k.On("SnapshotDiffTables").Return([]string{}).Once()
+
+	q := &fakeQuerier{results: map[string][]map[string]string{
+		"SELECT * FROM certificates": {{"sha1": "abc"}},
+	}}
+
+	e := New(k, "test_registration", "test_host", q, &fakeLogWriter{})
+
+	e.Run(context.Background())
+	v, err := store.Get(e.snapshotKey("certificates"))
+	require.NoError(t, err)
+	require.NotEmpty(t, v)
+
+	e.Run(context.Background())
+	v, err = store.Get(e.snapshotKey("certificates"))
+	require.NoError(t, err)
+	require.Empty(t, v)
+}
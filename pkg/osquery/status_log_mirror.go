@@ -0,0 +1,75 @@
+package osquery
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/kolide/launcher/pkg/log/platformlog"
+)
+
+// statusLogMirror writes osquery's status logs to the platform's logging system, alongside
+// buffering them to send to the server.
+type statusLogMirror struct {
+	handler *platformlog.Handler
+	slogger *slog.Logger
+}
+
+// osqueryStatusLine is the format osquery hands status logs to logger plugins in
+type osqueryStatusLine struct {
+	Severity int    `json:"s"`
+	Filename string `json:"f"`
+	Line     int    `json:"i"`
+	Message  string `json:"m"`
+}
+
+func newStatusLogMirror(registrationId string) (*statusLogMirror, error) {
+	handler, err := platformlog.NewHandler(platformlog.CategoryOsquery, slog.LevelInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statusLogMirror{
+		handler: handler,
+		slogger: slog.New(handler).With("registration_id", registrationId),
+	}, nil
+}
+
+// log writes the status log, translating osquery's severity to a log level. Logs we can't
+// parse are written as is. A nil mirror does nothing.
+func (m *statusLogMirror) log(ctx context.Context, logText string) {
+	if m == nil {
+		return
+	}
+
+	var status osqueryStatusLine
+	if err := json.Unmarshal([]byte(logText), &status); err != nil {
+		m.slogger.Log(ctx, slog.LevelInfo, logText)
+		return
+	}
+
+	m.slogger.Log(ctx, statusLogLevel(status.Severity), status.Message,
+		"filename", status.Filename,
+		"line", status.Line,
+	)
+}
+
+func (m *statusLogMirror) close() error {
+	if m == nil {
+		return nil
+	}
+	return m.handler.Close()
+}
+
+// statusLogLevel maps glog severities, which osquery uses, to log levels
+func statusLogLevel(severity int) slog.Level {
+	switch severity {
+	case 0:
+		return slog.LevelInfo
+	case 1:
+		return slog.LevelWarn
+	default:
+		// ERROR and FATAL
+		return slog.LevelError
+	}
+}
@@ -0,0 +1,54 @@
+package osquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusLogMirror(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	m := &statusLogMirror{
+		slogger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+
+	m.log(context.TODO(), `{"s":1,"f":"events.cpp","i":863,"m":"Event publisher failed setup","h":"host","c":"Mon Jan  1 00:00:00 2024 UTC","u":1704067200}`)
+	m.log(context.TODO(), `not json`)
+
+	var logs []map[string]any
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var l map[string]any
+		require.NoError(t, decoder.Decode(&l))
+		logs = append(logs, l)
+	}
+	require.Len(t, logs, 2)
+
+	require.Equal(t, "WARN", logs[0]["level"])
+	require.Equal(t, "Event publisher failed setup", logs[0]["msg"])
+	require.Equal(t, "events.cpp", logs[0]["filename"])
+	require.Equal(t, float64(863), logs[0]["line"])
+
+	require.Equal(t, "INFO", logs[1]["level"])
+	require.Equal(t, "not json", logs[1]["msg"])
+
+	// A nil mirror, when platform logs aren't exported, does nothing
+	var nilMirror *statusLogMirror
+	nilMirror.log(context.TODO(), "ignored")
+	require.NoError(t, nilMirror.close())
+}
+
+func TestStatusLogLevel(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, slog.LevelInfo, statusLogLevel(0))
+	require.Equal(t, slog.LevelWarn, statusLogLevel(1))
+	require.Equal(t, slog.LevelError, statusLogLevel(2))
+	require.Equal(t, slog.LevelError, statusLogLevel(3))
+}
@@ -0,0 +1,445 @@
+package table
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/ee/hostroot"
+	"github.com/kolide/launcher/ee/keyidentifier"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+	p12 "software.sslmate.com/src/go-pkcs12"
+)
+
+// certificateKeySystemPaths are where kolide_certificate_private_keys looks for keys and
+// certificates outside users' home directories, when the query doesn't name paths.
+var certificateKeySystemPaths = map[string][]string{
+	"linux": {
+		"/etc/ssl/private/*",
+		"/etc/ssl/certs/*",
+		"/etc/pki/tls/private/*",
+		"/etc/pki/tls/certs/*",
+		"/etc/ssh/ssh_host_*",
+	},
+	"darwin": {
+		"/etc/ssh/ssh_host_*",
+	},
+	"windows": {
+		`C:\ProgramData\ssh\ssh_host_*`,
+	},
+}
+
+// certificateKeyUserPaths are where kolide_certificate_private_keys looks in each user's home
+// directory. Client certificates are often downloaded as PKCS#12 bundles, and left there.
+var certificateKeyUserPaths = []string{
+	".ssh/*",
+	".certs/*",
+	"Downloads/*.p12",
+	"Downloads/*.pfx",
+	"Desktop/*.p12",
+	"Desktop/*.pfx",
+}
+
+// maxCertificateKeyFileSize skips files too large to be keys or certificates
+const maxCertificateKeyFileSize = 1024 * 1024
+
+type CertificatePrivateKeysTable struct {
+	slogger    *slog.Logger
+	kIdentifer *keyidentifier.KeyIdentifier
+}
+
+// certificateKeyFile is a file we look for keys and certificates in
+type certificateKeyFile struct {
+	path string
+	user string
+	mode os.FileMode
+}
+
+type foundPrivateKey struct {
+	file                certificateKeyFile
+	format              string
+	keyType             string
+	bits                int
+	encrypted           *bool
+	legacyPemEncryption bool
+	fingerprint         string
+}
+
+type foundCertificate struct {
+	path        string
+	certType    string
+	subject     string
+	issuer      string
+	serial      string
+	notAfter    int64
+	fingerprint string
+}
+
+func CertificatePrivateKeys(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("path"),
+		table.TextColumn("user"),
+		table.TextColumn("format"),
+		table.TextColumn("type"),
+		table.IntegerColumn("bits"),
+		table.IntegerColumn("encrypted"),
+		table.TextColumn("fingerprint_sha256"),
+		table.TextColumn("mode"),
+		table.IntegerColumn("exportable"),
+		table.TextColumn("weaknesses"),
+		table.IntegerColumn("has_certificate"),
+		table.TextColumn("certificate_path"),
+		table.TextColumn("certificate_type"),
+		table.TextColumn("certificate_subject"),
+		table.TextColumn("certificate_issuer"),
+		table.TextColumn("certificate_serial"),
+		table.BigIntColumn("certificate_not_after"),
+	}
+
+	// we don't want the logging in osquery, so don't instantiate WithSlogger()
+	kIdentifer, err := keyidentifier.New()
+	if err != nil {
+		slogger.Log(context.TODO(), slog.LevelInfo,
+			"failed to create keyidentifier",
+			"err", err,
+		)
+		return nil
+	}
+
+	t := &CertificatePrivateKeysTable{
+		slogger:    slogger.With("table", "kolide_certificate_private_keys"),
+		kIdentifer: kIdentifer,
+	}
+
+	return table.NewPlugin("kolide_certificate_private_keys", columns, t.generate)
+}
+
+func (t *CertificatePrivateKeysTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	var keys []foundPrivateKey
+	certificates := make(map[string][]foundCertificate)
+	for _, file := range t.files(ctx, queryContext) {
+		fileKeys, fileCertificates := t.identify(ctx, file)
+		keys = append(keys, fileKeys...)
+		for _, cert := range fileCertificates {
+			certificates[cert.fingerprint] = append(certificates[cert.fingerprint], cert)
+		}
+	}
+
+	for _, key := range keys {
+		res := privateKeyRow(key)
+
+		// Keys we couldn't get the public half of, such as encrypted ones, can't be matched
+		matches := certificates[key.fingerprint]
+		if key.fingerprint == "" || len(matches) == 0 {
+			res["has_certificate"] = "0"
+			results = append(results, res)
+			continue
+		}
+
+		for _, cert := range matches {
+			certRes := make(map[string]string, len(res)+6)
+			for k, v := range res {
+				certRes[k] = v
+			}
+			certRes["has_certificate"] = "1"
+			certRes["certificate_path"] = cert.path
+			certRes["certificate_type"] = cert.certType
+			certRes["certificate_subject"] = cert.subject
+			certRes["certificate_issuer"] = cert.issuer
+			certRes["certificate_serial"] = cert.serial
+			if cert.notAfter != 0 {
+				certRes["certificate_not_after"] = strconv.FormatInt(cert.notAfter, 10)
+			}
+			results = append(results, certRes)
+		}
+	}
+
+	return results, nil
+}
+
+// files returns the files to look for keys and certificates in: those matching the query's paths,
+// or else the well-known locations. Each file is returned once, however many links lead to it.
+func (t *CertificatePrivateKeysTable) files(ctx context.Context, queryContext table.QueryContext) []certificateKeyFile {
+	var candidates []userFileInfo
+
+	if requestedPaths := tablehelpers.GetConstraints(queryContext, "path"); len(requestedPaths) > 0 {
+		for _, requestedPath := range requestedPaths {
+			// We take globs in via the sql %, but glob needs *. So convert.
+			candidates = append(candidates, t.glob(ctx, strings.ReplaceAll(requestedPath, `%`, `*`))...)
+		}
+	} else {
+		for _, pattern := range certificateKeySystemPaths[runtime.GOOS] {
+			candidates = append(candidates, t.glob(ctx, hostroot.Path(pattern))...)
+		}
+		for _, pattern := range certificateKeyUserPaths {
+			userFiles, err := findFileInUserDirs(pattern, t.slogger)
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"error finding paths in user directories",
+					"path", pattern,
+					"err", err,
+				)
+				continue
+			}
+			candidates = append(candidates, userFiles...)
+		}
+	}
+
+	seen := make(map[string]bool, len(candidates))
+	var files []certificateKeyFile
+	for _, candidate := range candidates {
+		resolved, err := filepath.EvalSymlinks(candidate.path)
+		if err != nil || seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+
+		stat, err := os.Stat(resolved)
+		if err != nil || !stat.Mode().IsRegular() || stat.Size() > maxCertificateKeyFileSize {
+			continue
+		}
+
+		files = append(files, certificateKeyFile{
+			path: candidate.path,
+			user: candidate.user,
+			mode: stat.Mode().Perm(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files
+}
+
+func (t *CertificatePrivateKeysTable) glob(ctx context.Context, pattern string) []userFileInfo {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelInfo,
+			"bad file glob",
+			"pattern", pattern,
+			"err", err,
+		)
+		return nil
+	}
+
+	found := make([]userFileInfo, len(paths))
+	for i, path := range paths {
+		found[i] = userFileInfo{path: path}
+	}
+	return found
+}
+
+// identify returns the private keys and certificates in the file. Files can hold several, as
+// PEM bundles and PKCS#12 files do.
+func (t *CertificatePrivateKeysTable) identify(ctx context.Context, file certificateKeyFile) ([]foundPrivateKey, []foundCertificate) {
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		t.slogger.Log(ctx, slog.LevelDebug,
+			"could not read file",
+			"path", file.path,
+			"err", err,
+		)
+		return nil, nil
+	}
+
+	var keys []foundPrivateKey
+	var certificates []foundCertificate
+
+	rest := data
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		switch {
+		case block.Type == "CERTIFICATE":
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				certificates = append(certificates, x509Certificate(file.path, cert))
+			}
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			ki, err := t.kIdentifer.Identify(pem.EncodeToMemory(block))
+			if err != nil {
+				continue
+			}
+			key := identifiedPrivateKey(file, ki)
+			key.legacyPemEncryption = strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED")
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 || len(certificates) > 0 {
+		return keys, certificates
+	}
+
+	// The formats keyidentifier knows that aren't PEM, like ssh certificates and PuTTY keys
+	if ki, err := t.kIdentifer.Identify(data); err == nil {
+		if ki.Certificate != nil {
+			return nil, []foundCertificate{sshCertificate(file.path, ki)}
+		}
+		return []foundPrivateKey{identifiedPrivateKey(file, ki)}, nil
+	}
+
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return nil, []foundCertificate{x509Certificate(file.path, cert)}
+	}
+
+	return pkcs12Contents(file, data)
+}
+
+// pkcs12Contents returns the key and certificates in a PKCS#12 file. We can only look inside
+// files without a password. Those with one are reported as an encrypted key.
+func pkcs12Contents(file certificateKeyFile, data []byte) ([]foundPrivateKey, []foundCertificate) {
+	privateKey, cert, caCerts, err := p12.DecodeChain(data, "")
+	if errors.Is(err, p12.ErrIncorrectPassword) {
+		return []foundPrivateKey{{file: file, format: "pkcs12", encrypted: boolPtr(true)}}, nil
+	}
+	if err != nil {
+		return nil, nil
+	}
+
+	var keys []foundPrivateKey
+	if signer, ok := privateKey.(crypto.Signer); ok {
+		key := foundPrivateKey{
+			file:        file,
+			format:      "pkcs12",
+			encrypted:   boolPtr(false),
+			fingerprint: keyidentifier.FingerprintSHA256(signer.Public()),
+		}
+		switch assertedKey := privateKey.(type) {
+		case *rsa.PrivateKey:
+			key.keyType = "rsa"
+			key.bits = assertedKey.PublicKey.Size() * 8
+		case *ecdsa.PrivateKey:
+			key.keyType = "ecdsa"
+			key.bits = assertedKey.PublicKey.Curve.Params().BitSize
+		case ed25519.PrivateKey:
+			key.keyType = "ed25519"
+			key.bits = ed25519.PublicKeySize * 8
+		}
+		keys = append(keys, key)
+	}
+
+	var certificates []foundCertificate
+	for _, c := range append([]*x509.Certificate{cert}, caCerts...) {
+		if c != nil {
+			certificates = append(certificates, x509Certificate(file.path, c))
+		}
+	}
+	return keys, certificates
+}
+
+func identifiedPrivateKey(file certificateKeyFile, ki *keyidentifier.KeyInfo) foundPrivateKey {
+	return foundPrivateKey{
+		file:        file,
+		format:      ki.Format,
+		keyType:     ki.Type,
+		bits:        ki.Bits,
+		encrypted:   ki.Encrypted,
+		fingerprint: ki.FingerprintSHA256,
+	}
+}
+
+func x509Certificate(path string, cert *x509.Certificate) foundCertificate {
+	return foundCertificate{
+		path:        path,
+		certType:    "x509",
+		subject:     cert.Subject.String(),
+		issuer:      cert.Issuer.String(),
+		serial:      cert.SerialNumber.String(),
+		notAfter:    cert.NotAfter.Unix(),
+		fingerprint: keyidentifier.FingerprintSHA256(cert.PublicKey),
+	}
+}
+
+func sshCertificate(path string, ki *keyidentifier.KeyInfo) foundCertificate {
+	cert := foundCertificate{
+		path:        path,
+		certType:    "ssh",
+		subject:     ki.Certificate.KeyId,
+		issuer:      ki.Certificate.CAFingerprintSHA256,
+		serial:      strconv.FormatUint(ki.Certificate.Serial, 10),
+		fingerprint: ki.FingerprintSHA256,
+	}
+	if !ki.Certificate.ValidBefore.IsZero() {
+		cert.notAfter = ki.Certificate.ValidBefore.Unix()
+	}
+	return cert
+}
+
+func privateKeyRow(key foundPrivateKey) map[string]string {
+	res := map[string]string{
+		"path":               key.file.path,
+		"user":               key.file.user,
+		"format":             key.format,
+		"type":               key.keyType,
+		"fingerprint_sha256": key.fingerprint,
+		"mode":               key.file.mode.String(),
+		"weaknesses":         strings.Join(privateKeyWeaknesses(key), ","),
+	}
+
+	if key.bits != 0 {
+		res["bits"] = strconv.Itoa(key.bits)
+	}
+
+	// A key that isn't encrypted is usable by anyone who can copy the file
+	if key.encrypted != nil {
+		res["encrypted"] = strconv.Itoa(btoi(*key.encrypted))
+		res["exportable"] = strconv.Itoa(btoi(!*key.encrypted))
+	}
+
+	return res
+}
+
+// privateKeyWeaknesses lists what makes the key easier to steal, or to use once stolen
+func privateKeyWeaknesses(key foundPrivateKey) []string {
+	var weaknesses []string
+
+	if key.encrypted != nil && !*key.encrypted {
+		weaknesses = append(weaknesses, "unencrypted")
+	}
+
+	// Traditional PEM encryption derives the key with a single round of MD5, so the passphrase
+	// is cheap to guess
+	if key.legacyPemEncryption {
+		weaknesses = append(weaknesses, "legacy_pem_encryption")
+	}
+
+	switch keyType := strings.ToLower(key.keyType); {
+	case keyType == "rsa1", keyType == "dsa", keyType == "ssh-dss":
+		weaknesses = append(weaknesses, "weak_key")
+	case strings.Contains(keyType, "rsa") && key.bits > 0 && key.bits < 2048:
+		weaknesses = append(weaknesses, "weak_key")
+	}
+
+	// Windows permissions are ACLs, which the mode doesn't describe
+	if runtime.GOOS != "windows" {
+		if key.file.mode&0004 != 0 {
+			weaknesses = append(weaknesses, "world_readable")
+		}
+		if key.file.mode&0040 != 0 {
+			weaknesses = append(weaknesses, "group_readable")
+		}
+	}
+
+	return weaknesses
+}
+
+// boolPtr makes a pointer from a boolean, for keys whose encryption may be unknown
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,196 @@
+package table
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/ee/keyidentifier"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	p12 "software.sslmate.com/src/go-pkcs12"
+)
+
+func TestCertificatePrivateKeys(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	// A server key, and its certificate in another file
+	serverKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	serverCert := selfSignedCertificate(t, "server.example.com", serverKey, &serverKey.PublicKey)
+	writePem(t, filepath.Join(dir, "server.key"), 0644, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(serverKey)})
+	writePem(t, filepath.Join(dir, "server.crt"), 0644, &pem.Block{Type: "CERTIFICATE", Bytes: serverCert.Raw})
+
+	// A client certificate bundle, without a password, and another with one
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientCert := selfSignedCertificate(t, "alice", clientKey, &clientKey.PublicKey)
+	unprotected, err := p12.Encode(rand.Reader, clientKey, clientCert, nil, "")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "client.p12"), unprotected, 0600))
+	protected, err := p12.Encode(rand.Reader, clientKey, clientCert, nil, "hunter2")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "protected.pfx"), protected, 0600))
+
+	// A key with traditional PEM encryption, and no certificate
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	weakBlock, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(weakKey), []byte("hunter2"), x509.PEMCipherAES256) // nolint:staticcheck
+	require.NoError(t, err)
+	writePem(t, filepath.Join(dir, "weak.key"), 0600, weakBlock)
+
+	// An ssh key, and its certificate
+	_, sshKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshBlock, err := ssh.MarshalPrivateKey(sshKey, "")
+	require.NoError(t, err)
+	writePem(t, filepath.Join(dir, "id_ed25519"), 0600, sshBlock)
+	writeSshCertificate(t, filepath.Join(dir, "id_ed25519-cert.pub"), sshKey)
+
+	certificatePrivateKeysTable := &CertificatePrivateKeysTable{
+		slogger:    multislogger.NewNopLogger(),
+		kIdentifer: mustKeyIdentifier(t),
+	}
+	results, err := certificatePrivateKeysTable.generate(context.TODO(), tablehelpers.MockQueryContext(map[string][]string{
+		"path": {filepath.Join(dir, "%")},
+	}))
+	require.NoError(t, err)
+
+	rows := make(map[string]map[string]string)
+	for _, row := range results {
+		rows[filepath.Base(row["path"])] = row
+	}
+	require.Len(t, rows, 5)
+
+	require.Equal(t, "1", rows["server.key"]["has_certificate"])
+	require.Equal(t, filepath.Join(dir, "server.crt"), rows["server.key"]["certificate_path"])
+	require.Equal(t, "x509", rows["server.key"]["certificate_type"])
+	require.Equal(t, "CN=server.example.com", rows["server.key"]["certificate_subject"])
+	require.Equal(t, "1", rows["server.key"]["exportable"])
+	if runtime.GOOS == "windows" {
+		require.Equal(t, "unencrypted", rows["server.key"]["weaknesses"])
+	} else {
+		require.Equal(t, "unencrypted,world_readable,group_readable", rows["server.key"]["weaknesses"])
+	}
+
+	require.Equal(t, "pkcs12", rows["client.p12"]["format"])
+	require.Equal(t, "ecdsa", rows["client.p12"]["type"])
+	require.Equal(t, "1", rows["client.p12"]["has_certificate"])
+	require.Equal(t, "CN=alice", rows["client.p12"]["certificate_subject"])
+	require.Equal(t, "1", rows["client.p12"]["exportable"])
+	require.Equal(t, "unencrypted", rows["client.p12"]["weaknesses"])
+
+	require.Equal(t, "pkcs12", rows["protected.pfx"]["format"])
+	require.Equal(t, "1", rows["protected.pfx"]["encrypted"])
+	require.Equal(t, "0", rows["protected.pfx"]["exportable"])
+	require.Equal(t, "0", rows["protected.pfx"]["has_certificate"])
+	require.Equal(t, "", rows["protected.pfx"]["weaknesses"])
+
+	require.Equal(t, "1", rows["weak.key"]["encrypted"])
+	require.Equal(t, "0", rows["weak.key"]["has_certificate"])
+	require.Equal(t, "legacy_pem_encryption", rows["weak.key"]["weaknesses"])
+
+	require.Equal(t, "1", rows["id_ed25519"]["has_certificate"])
+	require.Equal(t, "ssh", rows["id_ed25519"]["certificate_type"])
+	require.Equal(t, "alice-laptop", rows["id_ed25519"]["certificate_subject"])
+	require.Equal(t, "unencrypted", rows["id_ed25519"]["weaknesses"])
+}
+
+func TestPrivateKeyWeaknesses(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		testCaseName string
+		key          foundPrivateKey
+		expected     []string
+	}{
+		{
+			testCaseName: "small rsa",
+			key:          foundPrivateKey{keyType: "ssh-rsa", bits: 1024, encrypted: boolPtr(true), file: certificateKeyFile{mode: 0600}},
+			expected:     []string{"weak_key"},
+		},
+		{
+			testCaseName: "rsa of unknown size",
+			key:          foundPrivateKey{keyType: "ssh-rsa", encrypted: boolPtr(true), file: certificateKeyFile{mode: 0600}},
+			expected:     nil,
+		},
+		{
+			testCaseName: "dsa",
+			key:          foundPrivateKey{keyType: "ssh-dss", bits: 1024, encrypted: boolPtr(false), file: certificateKeyFile{mode: 0600}},
+			expected:     []string{"unencrypted", "weak_key"},
+		},
+		{
+			testCaseName: "unknown encryption",
+			key:          foundPrivateKey{keyType: "ecdsa", file: certificateKeyFile{mode: 0600}},
+			expected:     nil,
+		},
+	} {
+		tt := tt
+		t.Run(tt.testCaseName, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.expected, privateKeyWeaknesses(tt.key))
+		})
+	}
+}
+
+func mustKeyIdentifier(t *testing.T) *keyidentifier.KeyIdentifier {
+	kIdentifier, err := keyidentifier.New()
+	require.NoError(t, err)
+	return kIdentifier
+}
+
+func selfSignedCertificate(t *testing.T, commonName string, key any, pub any) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func writePem(t *testing.T, path string, mode os.FileMode, block *pem.Block) {
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), mode))
+	// WriteFile's mode is subject to the umask
+	require.NoError(t, os.Chmod(path, mode))
+}
+
+func writeSshCertificate(t *testing.T, path string, key ed25519.PrivateKey) {
+	pub, err := ssh.NewPublicKey(key.Public())
+	require.NoError(t, err)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	require.NoError(t, err)
+
+	cert := &ssh.Certificate{
+		Key:         pub,
+		CertType:    ssh.UserCert,
+		KeyId:       "alice-laptop",
+		ValidBefore: ssh.CertTimeInfinity,
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, caSigner))
+	require.NoError(t, os.WriteFile(path, ssh.MarshalAuthorizedKey(cert), 0644))
+}
@@ -0,0 +1,180 @@
+package table
+
+// tableDescriptions are the descriptions reported in the table schema. Columns and platforms are
+// read from the registered tables themselves; only the prose lives here.
+var tableDescriptions = map[string]string{
+	"kolide_apfs_list":                         "APFS containers and volumes, from diskutil apfs list.",
+	"kolide_apfs_users":                        "Users that can unlock each APFS volume, from diskutil apfs listUsers.",
+	"kolide_app_icons":                         "Icons for installed macOS applications.",
+	"kolide_apple_silicon_security_policy":     "Boot security policy on Apple silicon Macs, from bputil.",
+	"kolide_apt_upgradeable":                   "Packages with upgrades available from apt.",
+	"kolide_battery_health":                    "Battery charge, capacity, and health.",
+	"kolide_brew_outdated":                     "Outdated Homebrew packages.",
+	"kolide_brew_upgradeable":                  "Homebrew packages with upgrades available.",
+	"kolide_carbonblack_repcli_status":         "Carbon Black Cloud sensor status, from repcli.",
+	"kolide_certificate_private_keys":          "Private keys on disk, the certificates they match, and how well they're protected.",
+	"kolide_chrome_login_data_emails":          "Email addresses saved in Chrome's login data, by profile.",
+	"kolide_chrome_login_keychain":             "Deprecated, use kolide_chrome_login_data_emails.",
+	"kolide_chrome_user_profiles":              "Chrome user profiles.",
+	"kolide_connectivity_probes":               "Results of launcher's connectivity checks against Kolide's endpoints.",
+	"kolide_control_action_history":            "Actions taken on this device by the control server.",
+	"kolide_control_flags":                     "Agent flags set by the control server.",
+	"kolide_cron_at_anacron":                   "Jobs scheduled by cron, anacron, at, launchd calendar intervals, and periodic, with when each next runs.",
+	"kolide_cryptoinfo":                        "Certificates and keys parsed from files.",
+	"kolide_cryptsetup_status":                 "Status of LUKS encrypted devices, from cryptsetup.",
+	"kolide_data_collection_consent":           "The end user's data collection consent under privacy mode, and the tables kept out of queries until they consent.",
+	"kolide_desktop_capability":                "Whether this device can run launcher desktop, or is headless, and why.",
+	"kolide_desktop_ipc_connections":           "Connections between launcher and its desktop processes.",
+	"kolide_desktop_procs":                     "Launcher desktop processes, by user.",
+	"kolide_dev_table_tooling":                 "Runs a small set of allowed diagnostic commands.",
+	"kolide_dhcp_leases":                       "Current DHCP leases by interface, with the options the DHCP server sent, including DNS servers and WPAD.",
+	"kolide_disk_smart_info":                   "SMART health data for attached disks.",
+	"kolide_diskutil_list":                     "Disks and partitions, from diskutil list.",
+	"kolide_display_and_idle_settings":         "Display sleep, screen lock timeout, and password-after-sleep settings, normalized across platforms.",
+	"kolide_dnf_updateinfo":                    "Security and bugfix advisories available from dnf.",
+	"kolide_dnf_upgradeable":                   "Packages with upgrades available from dnf.",
+	"kolide_dpkg_version_info":                 "Installed dpkg package versions.",
+	"kolide_dropbox":                           "Dropbox accounts each user is signed in to, where they sync, and which of the user's folders they back up.",
+	"kolide_dsim_default_associations":         "Default application associations, from DISM.",
+	"kolide_dsregcmd":                          "Device registration and join status, from dsregcmd.",
+	"kolide_enrollment_attempts":               "History of launcher's attempts to enroll.",
+	"kolide_entra_join_status":                 "Microsoft Entra join status and tenant.",
+	"kolide_etc_resolvers":                     "DNS resolvers configured in /etc/resolver.",
+	"kolide_falcon_kernel_check":               "Whether the running kernel is supported by CrowdStrike Falcon.",
+	"kolide_falconctl_options":                 "CrowdStrike Falcon sensor options, from falconctl.",
+	"kolide_falconctl_stats":                   "CrowdStrike Falcon sensor stats, from falconctl.",
+	"kolide_falconctl_systags":                 "CrowdStrike Falcon sensor grouping tags, from falconctl.",
+	"kolide_filevault":                         "FileVault status, from fdesetup.",
+	"kolide_fim_config":                        "File integrity monitoring configuration sent by the control server.",
+	"kolide_firefox_preferences":               "Preferences from Firefox profiles.",
+	"kolide_firmwarepasswd":                    "Firmware password settings on Intel Macs.",
+	"kolide_flatpak_upgradeable":               "Flatpak applications with upgrades available.",
+	"kolide_fscrypt_info":                      "fscrypt encryption status for directories.",
+	"kolide_gdrive_sync_config":                "Google Drive for desktop sync configuration.",
+	"kolide_gdrive_sync_history":               "Google Drive for desktop sync history.",
+	"kolide_google_drive":                      "Google Drive accounts each user is signed in to, where they sync, and which of the user's folders they back up.",
+	"kolide_gsettings":                         "GNOME settings, by user.",
+	"kolide_gsettings_metadata":                "Descriptions and types of GNOME settings.",
+	"kolide_hardware_security":                 "TPM and Secure Enclave availability and status.",
+	"kolide_hosts_file_watch":                  "Changes to the hosts file.",
+	"kolide_ini":                               "Parses INI files into key-value rows.",
+	"kolide_intune_status":                     "Microsoft Intune enrollment and policy status.",
+	"kolide_ios_paired_devices":                "iPhones and iPads paired with, backed up to, or synced with this Mac, and whether their local backups are encrypted.",
+	"kolide_ioreg":                             "macOS I/O Kit registry, from ioreg.",
+	"kolide_jamf_status":                       "Jamf Pro enrollment status.",
+	"kolide_journald":                          "Entries from the systemd journal.",
+	"kolide_json":                              "Parses JSON files into key-value rows.",
+	"kolide_jsonl":                             "Parses JSON Lines files into key-value rows.",
+	"kolide_jwt":                               "Claims from JSON Web Tokens in files.",
+	"kolide_kerberos_tickets":                  "Tickets in users' Kerberos credential caches, with their flags and expirations.",
+	"kolide_keychain_acls":                     "Access control lists for macOS keychain items.",
+	"kolide_keychain_items":                    "Items in macOS keychains, without their secrets.",
+	"kolide_keyinfo":                           "Type and encryption of private key files.",
+	"kolide_launchagents_per_user":             "LaunchAgents in every local user's home directory, including logged-out users.",
+	"kolide_launcher_autoupdate_config":        "Launcher's autoupdate configuration.",
+	"kolide_launcher_config":                   "Launcher's configuration.",
+	"kolide_launcher_db_info":                  "Statistics for launcher's database.",
+	"kolide_launcher_info":                     "Launcher version, identity, and runtime information.",
+	"kolide_launcher_osquery_instance_history": "History of the osquery instances launcher has run.",
+	"kolide_launcher_processes":                "Launcher's running processes.",
+	"kolide_linux_pam_config":                  "Each PAM service's effective module stack, with risky modules flagged and whether account lockout and password quality checks are configured.",
+	"kolide_listening_services":                "Processes listening on network ports, and when they were first seen.",
+	"kolide_login_banner":                      "The banner or legal notice shown before login, from each place it's configured.",
+	"kolide_login_window_settings":             "macOS login window settings.",
+	"kolide_loginwindow_users":                 "Last login and unlock times for each user, and how they authenticated.",
+	"kolide_lsa_protection":                    "LSASS protection, Credential Guard, and NTLM restrictions.",
+	"kolide_lsblk":                             "Block devices, from lsblk.",
+	"kolide_macho_info":                        "Architecture and signing information for Mach-O binaries.",
+	"kolide_macos_analytics_and_siri_settings": "Diagnostics submission, Siri, dictation, and personalized ads settings for each user, and where each is set.",
+	"kolide_macos_available_products":          "Software updates available from Apple.",
+	"kolide_macos_gatekeeper_assessment":       "Gatekeeper's assessment of apps at the given paths, and the rule that decided it.",
+	"kolide_macos_gatekeeper_overrides":        "Gatekeeper policy rules, including those added to allow apps Gatekeeper would block.",
+	"kolide_macos_recommended_updates":         "Software updates recommended by Apple.",
+	"kolide_macos_software_update":             "macOS automatic software update settings.",
+	"kolide_macos_tcc_permissions":             "Privacy permissions granted to applications (TCC).",
+	"kolide_mdm_info":                          "MDM enrollment information, from profiles.",
+	"kolide_mdmclient":                         "MDM client information, from mdmclient.",
+	"kolide_munki_installs":                    "Items installed by Munki.",
+	"kolide_munki_report":                      "Munki's last run report.",
+	"kolide_network_change_events":             "Changes to network interfaces, addresses, and default routes.",
+	"kolide_network_shares_mounted":            "Network filesystems (SMB, NFS, AFP, and WebDAV) currently mounted, with where the credentials used to mount them came from.",
+	"kolide_nftables":                          "nftables firewall rules.",
+	"kolide_nix_upgradeable":                   "Nix packages with upgrades available.",
+	"kolide_nmcli_wifi":                        "Wi-Fi networks visible to NetworkManager.",
+	"kolide_ntfs_ads":                          "NTFS alternate data streams on files, including download origins from Zone.Identifier.",
+	"kolide_onedrive":                          "OneDrive accounts each user is signed in to, where they sync, and which of the user's folders they back up.",
+	"kolide_onepassword_accounts":              "1Password accounts configured on the device.",
+	"kolide_os_hardening":                      "Operating system hardening settings.",
+	"kolide_osquery_watchdog_events":           "osquery watchdog kills of queries and workers.",
+	"kolide_pacman_group":                      "pacman package groups.",
+	"kolide_pacman_upgradeable":                "Packages with upgrades available from pacman.",
+	"kolide_pacman_version_info":               "Installed pacman package versions.",
+	"kolide_plist":                             "Parses property list files into key-value rows.",
+	"kolide_powermetrics":                      "Power and thermal metrics, from powermetrics.",
+	"kolide_powershell_history":                "PowerShell command history, by user.",
+	"kolide_profiles":                          "Configuration profiles installed on the Mac.",
+	"kolide_program_icons":                     "Icons for installed Windows programs.",
+	"kolide_pwpolicy":                          "macOS password policy, from pwpolicy.",
+	"kolide_quarantine_events":                 "Files downloaded and quarantined by macOS.",
+	"kolide_query_accounting":                  "Resource usage of osquery queries.",
+	"kolide_remotectl":                         "Devices and services known to remotectl, from remotectl dumpstate.",
+	"kolide_restart_required":                  "Restarts this device is waiting on, and the state of reminding the user about them.",
+	"kolide_rpm_version_info":                  "Installed RPM package versions.",
+	"kolide_screenlock":                        "Screen lock settings, by user.",
+	"kolide_secedit":                           "Windows security policy, from secedit.",
+	"kolide_secureboot":                        "Secure Boot status.",
+	"kolide_server_data":                       "Data about this device provided by the Kolide server.",
+	"kolide_shell_history":                     "Shell command history, by user.",
+	"kolide_slack_app_config":                  "Slack app configuration.",
+	"kolide_slack_config":                      "Slack workspaces signed in to.",
+	"kolide_snap_installed":                    "Installed snap packages.",
+	"kolide_snap_upgradeable":                  "Snap packages with upgrades available.",
+	"kolide_socketfilterfw":                    "macOS application firewall settings.",
+	"kolide_socketfilterfw_apps":               "Applications allowed or blocked by the macOS application firewall.",
+	"kolide_software_update_settings":          "macOS software update settings.",
+	"kolide_softwareupdate":                    "Software updates available from softwareupdate.",
+	"kolide_softwareupdate_scan":               "Software updates found by a softwareupdate scan.",
+	"kolide_spotlight":                         "Files found with a Spotlight query.",
+	"kolide_ssh_keys":                          "SSH keys in users' home directories, and whether they're encrypted.",
+	"kolide_storage_retention":                 "Retention policy for each of launcher's stores, and how much has been purged.",
+	"kolide_system_profiler":                   "Hardware and software information, from system_profiler.",
+	"kolide_system_proxy_settings":             "Effective proxy configuration: per-protocol proxies, PAC URLs, auto-discovery, and bypass lists.",
+	"kolide_teams_config":                      "Microsoft Teams configuration.",
+	"kolide_time_machine_backup_coverage":      "Whether users' home directories are backed up by Time Machine.",
+	"kolide_time_machine_exclusions":           "Paths excluded from Time Machine backups.",
+	"kolide_tmutil_destinationinfo":            "Time Machine backup destinations.",
+	"kolide_touchid_system_config":             "Touch ID hardware and system settings.",
+	"kolide_touchid_user_config":               "Touch ID settings and enrolled fingerprints, by user.",
+	"kolide_tuf_autoupdater_errors":            "Errors encountered by launcher's autoupdater.",
+	"kolide_tuf_release_version":               "Launcher and osqueryd versions selected by the autoupdater.",
+	"kolide_ulimit":                            "Resource limits for launcher, osquery, services, and the kernel, with current usage.",
+	"kolide_unified_device_identity":           "Device identifiers from each source, reconciled into one identity.",
+	"kolide_update_library":                    "Every binary version in launcher's update library, with its verification status, size, when it was added, and whether it's running.",
+	"kolide_user_avatars":                      "Users' account pictures.",
+	"kolide_virtualization_guests":             "Virtual machines defined on this device, and whether they're running.",
+	"kolide_vpn_status":                        "VPN tunnels from WireGuard, OpenVPN, and enterprise clients, with normalized connection state.",
+	"kolide_wifi_networks":                     "Wi-Fi networks visible to Windows.",
+	"kolide_windows_firewall_profiles":         "Windows Firewall settings for the domain, private, and public profiles, and which are active.",
+	"kolide_windows_persistence":               "Programs started at logon by Run and RunOnce keys, startup folders, and scheduled tasks, for the machine and every user, with each program's signer.",
+	"kolide_windows_services_acl":              "Who may start, stop, or reconfigure each Windows service.",
+	"kolide_windows_update_history":            "History of Windows Update installs.",
+	"kolide_windows_updates":                   "Updates available from Windows Update.",
+	"kolide_winget_upgradeable":                "Packages with upgrades available from winget.",
+	"kolide_wireguard":                         "WireGuard interfaces and peers, with latest handshakes and transfer counts.",
+	"kolide_wmi":                               "Results of WMI queries.",
+	"kolide_wsone_uem_status_dependency":       "Workspace ONE UEM dependency status.",
+	"kolide_wsone_uem_status_enroll":           "Workspace ONE UEM enrollment status.",
+	"kolide_wsone_uem_status_profile":          "Workspace ONE UEM profile status.",
+	"kolide_xfconf":                            "Xfce settings, by user.",
+	"kolide_xml":                               "Parses XML files into key-value rows.",
+	"kolide_xrdb":                              "X resources, by user.",
+	"kolide_zerotier_info":                     "ZeroTier node information.",
+	"kolide_zerotier_networks":                 "ZeroTier networks joined.",
+	"kolide_zerotier_peers":                    "ZeroTier peers.",
+	"kolide_zfs_properties":                    "ZFS dataset properties.",
+	"kolide_zoom_config":                       "Zoom configuration.",
+	"kolide_zpool_properties":                  "ZFS pool properties.",
+	"kolide_zypper_upgradeable_packages":       "Packages with upgrades available from zypper.",
+	"kolide_zypper_upgradeable_patches":        "Patches available from zypper.",
+	"launcher_gc_info":                         "Launcher's Go garbage collector statistics.",
+}
@@ -0,0 +1,101 @@
+package table
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/kolide/launcher/ee/agent"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/osquery"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// DeviceIdentityTable returns the identifiers launcher uses for this device, in one place, so
+// that server records can be tied back to it. There's a row per registration; the device-wide
+// identifiers are repeated in each.
+func DeviceIdentityTable(k types.Knapsack) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("registration_id"),
+		table.TextColumn("host_identifier"),
+		table.TextColumn("munemo"),
+		table.TextColumn("server_munemo"),
+		table.TextColumn("device_id"),
+		table.TextColumn("hardware_uuid"),
+		table.TextColumn("hardware_serial"),
+		table.TextColumn("local_key"),
+		table.TextColumn("hardware_key"),
+		table.TextColumn("hardware_key_source"),
+		table.TextColumn("public_key"),
+		table.TextColumn("fingerprint"),
+	}
+	return table.NewPlugin("kolide_unified_device_identity", columns, generateDeviceIdentity(k))
+}
+
+func generateDeviceIdentity(k types.Knapsack) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		device := map[string]string{}
+
+		// The munemo from the enroll secret, and the one the server reports, should match --
+		// but a mismatch is exactly what we'd want to see during a migration
+		if munemo, err := agent.CurrentMunemo(k); err == nil {
+			device["munemo"] = munemo
+		}
+		if store := k.ServerProvidedDataStore(); store != nil {
+			if munemo, err := store.Get([]byte("munemo")); err == nil {
+				device["server_munemo"] = string(munemo)
+			}
+			if deviceId, err := store.Get([]byte("device_id")); err == nil {
+				device["device_id"] = string(deviceId)
+			}
+		}
+
+		// The hardware identifiers as last collected, rather than as they are now
+		if store := k.PersistentHostDataStore(); store != nil {
+			if serial, hardwareUuid, err := agent.RecordedHardwareIdentifiers(store); err == nil {
+				device["hardware_serial"] = serial
+				device["hardware_uuid"] = hardwareUuid
+			}
+		}
+
+		if agent.LocalDbKeys() != nil && agent.LocalDbKeys().Public() != nil {
+			if localKeyDer, err := x509.MarshalPKIXPublicKey(agent.LocalDbKeys().Public()); err == nil {
+				device["local_key"] = base64.StdEncoding.EncodeToString(localKeyDer)
+			}
+		}
+
+		hardwareKey, err := hardwareKeyJson()
+		if err != nil {
+			return nil, err
+		}
+		if hardwareKey != "" {
+			device["hardware_key"] = hardwareKey
+			device["hardware_key_source"] = agent.HardwareKeys().Type()
+		}
+
+		if publicKey, fingerprint, err := osquery.PublicRSAKeyFromDB(k.ConfigStore()); err == nil {
+			device["public_key"] = publicKey
+			device["fingerprint"] = fingerprint
+		}
+
+		results := make([]map[string]string, 0)
+		for _, registrationId := range k.RegistrationIDs() {
+			hostIdentifier, err := osquery.IdentifierFromDB(k.ConfigStore(), registrationId)
+			if err != nil {
+				return nil, fmt.Errorf("getting host identifier for registration %s: %w", registrationId, err)
+			}
+
+			row := map[string]string{
+				"registration_id": registrationId,
+				"host_identifier": hostIdentifier,
+			}
+			for column, value := range device {
+				row[column] = value
+			}
+			results = append(results, row)
+		}
+
+		return results, nil
+	}
+}
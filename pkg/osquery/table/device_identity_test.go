@@ -0,0 +1,56 @@
+package table
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/osquery"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDeviceIdentity(t *testing.T) {
+	t.Parallel()
+
+	secretJwt := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"organization": "enrollmunemo"})
+	enrollSecret, err := secretJwt.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	configStore := inmemory.NewStore()
+	serverDataStore := inmemory.NewStore()
+	require.NoError(t, serverDataStore.Set([]byte("munemo"), []byte("servermunemo")))
+	require.NoError(t, serverDataStore.Set([]byte("device_id"), []byte("12345")))
+	hostDataStore := inmemory.NewStore()
+	require.NoError(t, hostDataStore.Set([]byte("serial"), []byte("C02ABC123")))
+	require.NoError(t, hostDataStore.Set([]byte("hardware_uuid"), []byte("8A4F2C1E-0000-0000-0000-000000000000")))
+
+	k := mocks.NewKnapsack(t)
+	k.On("EnrollSecret").Return(enrollSecret)
+	k.On("ServerProvidedDataStore").Return(serverDataStore)
+	k.On("PersistentHostDataStore").Return(hostDataStore)
+	k.On("ConfigStore").Return(configStore)
+	k.On("RegistrationIDs").Return([]string{types.DefaultRegistrationID, "other"})
+
+	rows, err := generateDeviceIdentity(k)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	for i, registrationId := range []string{types.DefaultRegistrationID, "other"} {
+		// The host identifier is the one used to enroll this registration
+		hostIdentifier, err := osquery.IdentifierFromDB(configStore, registrationId)
+		require.NoError(t, err)
+
+		require.Equal(t, registrationId, rows[i]["registration_id"])
+		require.Equal(t, hostIdentifier, rows[i]["host_identifier"])
+		require.Equal(t, "enrollmunemo", rows[i]["munemo"])
+		require.Equal(t, "servermunemo", rows[i]["server_munemo"])
+		require.Equal(t, "12345", rows[i]["device_id"])
+		require.Equal(t, "C02ABC123", rows[i]["hardware_serial"])
+		require.Equal(t, "8A4F2C1E-0000-0000-0000-000000000000", rows[i]["hardware_uuid"])
+	}
+	require.NotEqual(t, rows[0]["host_identifier"], rows[1]["host_identifier"])
+}
@@ -0,0 +1,54 @@
+package table
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/pkg/osquery"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// EnrollmentAttemptsTable reports launcher's recent enrollment attempts, including the class of
+// error for failed attempts and when launcher will next retry.
+func EnrollmentAttemptsTable(store types.Iterator) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.BigIntColumn("time"),
+		table.TextColumn("registration_id"),
+		table.IntegerColumn("success"),
+		table.TextColumn("error_class"),
+		table.TextColumn("error"),
+		table.IntegerColumn("consecutive_failures"),
+		table.BigIntColumn("next_attempt"),
+	}
+	return table.NewPlugin("kolide_enrollment_attempts", columns, generateEnrollmentAttempts(store))
+}
+
+func generateEnrollmentAttempts(store types.Iterator) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		results := make([]map[string]string, 0)
+
+		if store == nil {
+			return results, nil
+		}
+
+		attempts, err := osquery.EnrollmentAttempts(store)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, attempt := range attempts {
+			results = append(results, map[string]string{
+				"time":                 strconv.FormatInt(attempt.Time, 10),
+				"registration_id":      attempt.RegistrationId,
+				"success":              strconv.Itoa(btoi(attempt.Success)),
+				"error_class":          attempt.ErrorClass,
+				"error":                attempt.Error,
+				"consecutive_failures": strconv.Itoa(attempt.ConsecutiveFailures),
+				"next_attempt":         strconv.FormatInt(attempt.NextAttempt, 10),
+			})
+		}
+
+		return results, nil
+	}
+}
@@ -0,0 +1,46 @@
+package table
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/storage/inmemory"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateEnrollmentAttempts(t *testing.T) {
+	t.Parallel()
+
+	store := inmemory.NewStore()
+	require.NoError(t, store.Set([]byte("00000000000000000002"), []byte(`{"time":1700000100,"registration_id":"default","success":true,"consecutive_failures":0}`)))
+	require.NoError(t, store.Set([]byte("00000000000000000001"), []byte(`{"time":1700000000,"registration_id":"default","success":false,"error_class":"transport","error":"connection refused","consecutive_failures":1,"next_attempt":1700000015}`)))
+
+	rows, err := generateEnrollmentAttempts(store)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{
+			"time":                 "1700000000",
+			"registration_id":      "default",
+			"success":              "0",
+			"error_class":          "transport",
+			"error":                "connection refused",
+			"consecutive_failures": "1",
+			"next_attempt":         "1700000015",
+		},
+		{
+			"time":                 "1700000100",
+			"registration_id":      "default",
+			"success":              "1",
+			"error_class":          "",
+			"error":                "",
+			"consecutive_failures": "0",
+			"next_attempt":         "0",
+		},
+	}, rows)
+
+	// No store, no rows
+	rows, err = generateEnrollmentAttempts(nil)(context.TODO(), tablehelpers.MockQueryContext(nil))
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
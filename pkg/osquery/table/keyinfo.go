@@ -2,9 +2,11 @@ package table
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 	"strconv"
+	"strings"
 
 	"github.com/kolide/launcher/ee/keyidentifier"
 	"github.com/osquery/osquery-go/plugin/table"
@@ -25,6 +27,7 @@ func KeyInfo(slogger *slog.Logger) *table.Plugin {
 		table.TextColumn("fingerprint_sha256"),
 		table.TextColumn("fingerprint_md5"),
 	}
+	columns = append(columns, certificateColumns()...)
 
 	// we don't want the logging in osquery, so don't instantiate WithSlogger()
 	kIdentifer, err := keyidentifier.New()
@@ -83,9 +86,56 @@ func (t *KeyInfoTable) generate(ctx context.Context, queryContext table.QueryCon
 			res["fingerprint_md5"] = ki.FingerprintMD5
 		}
 
+		addCertificateColumns(res, ki.Certificate)
+
 		results = append(results, res)
 	}
 
 	return results, nil
 
 }
+
+// certificateColumns are the columns describing ssh certificates, for the tables that report on keys
+func certificateColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("certificate_type"),
+		table.TextColumn("certificate_key_id"),
+		table.TextColumn("certificate_serial"),
+		table.TextColumn("certificate_principals"),
+		table.BigIntColumn("certificate_valid_after"),
+		table.BigIntColumn("certificate_valid_before"),
+		table.TextColumn("certificate_ca_type"),
+		table.TextColumn("certificate_ca_fingerprint_sha256"),
+		table.TextColumn("certificate_critical_options"),
+		table.TextColumn("certificate_extensions"),
+	}
+}
+
+// addCertificateColumns fills in the certificateColumns for cert, if the key is an ssh certificate
+func addCertificateColumns(res map[string]string, cert *keyidentifier.CertificateInfo) {
+	if cert == nil {
+		return
+	}
+
+	res["certificate_type"] = cert.Type
+	res["certificate_key_id"] = cert.KeyId
+	// uint64, so it may not fit in an osquery BIGINT
+	res["certificate_serial"] = strconv.FormatUint(cert.Serial, 10)
+	res["certificate_principals"] = strings.Join(cert.Principals, ",")
+	res["certificate_ca_type"] = cert.CAType
+	res["certificate_ca_fingerprint_sha256"] = cert.CAFingerprintSHA256
+	res["certificate_extensions"] = strings.Join(cert.Extensions, ",")
+
+	// Unbounded validity periods are left empty
+	if !cert.ValidAfter.IsZero() {
+		res["certificate_valid_after"] = strconv.FormatInt(cert.ValidAfter.Unix(), 10)
+	}
+	if !cert.ValidBefore.IsZero() {
+		res["certificate_valid_before"] = strconv.FormatInt(cert.ValidBefore.Unix(), 10)
+	}
+
+	// Option values may themselves contain commas (e.g. source-address), so these are JSON
+	if criticalOptions, err := json.Marshal(cert.CriticalOptions); err == nil && len(cert.CriticalOptions) > 0 {
+		res["certificate_critical_options"] = string(criticalOptions)
+	}
+}
@@ -0,0 +1,43 @@
+package table
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolide/launcher/ee/keyidentifier"
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyInfo_SshCertificate(t *testing.T) {
+	t.Parallel()
+
+	kIdentifier, err := keyidentifier.New()
+	require.NoError(t, err)
+	keyInfoTable := &KeyInfoTable{
+		slogger:    multislogger.NewNopLogger(),
+		kIdentifer: kIdentifier,
+	}
+
+	certPath := filepath.Join("..", "..", "..", "ee", "keyidentifier", "testdata", "certs", "user_ecdsa-cert.pub")
+	results, err := keyInfoTable.generate(context.TODO(), tablehelpers.MockQueryContext(map[string][]string{
+		"path": {certPath},
+	}))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	require.Equal(t, "ecdsa-sha2-nistp384", results[0]["type"])
+	require.Equal(t, "384", results[0]["bits"])
+	require.Equal(t, "user", results[0]["certificate_type"])
+	require.Equal(t, "alice-laptop", results[0]["certificate_key_id"])
+	require.Equal(t, "42", results[0]["certificate_serial"])
+	require.Equal(t, "alice,deploy", results[0]["certificate_principals"])
+	require.Equal(t, "1704067200", results[0]["certificate_valid_after"])
+	require.Equal(t, "2051222400", results[0]["certificate_valid_before"])
+	require.Equal(t, "ssh-ed25519", results[0]["certificate_ca_type"])
+	require.Equal(t, "5jQu3YJKsncWSMSisaz+ILC6vnk43u06Nu/3KMV2/7U", results[0]["certificate_ca_fingerprint_sha256"])
+	require.JSONEq(t, `{"force-command":"/usr/bin/true","source-address":"10.0.0.0/8,192.168.0.0/16"}`, results[0]["certificate_critical_options"])
+	require.Equal(t, "permit-X11-forwarding,permit-agent-forwarding,permit-pty,permit-user-rc", results[0]["certificate_extensions"])
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/kolide/kit/version"
 	"github.com/kolide/launcher/ee/agent"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/buildprofile"
 	"github.com/kolide/launcher/pkg/osquery"
 	"github.com/kolide/launcher/pkg/osquery/runtime/history"
 	"github.com/osquery/osquery-go/plugin/table"
@@ -34,6 +35,7 @@ func LauncherInfoTable(configStore types.GetterSetter, LauncherHistoryStore type
 		table.TextColumn("identifier"),
 		table.TextColumn("osquery_instance_id"),
 		table.TextColumn("uptime"),
+		table.TextColumn("build_profile"),
 
 		// Signing key info
 		table.TextColumn("signing_key"),
@@ -98,6 +100,7 @@ func generateLauncherInfoTable(configStore types.GetterSetter, LauncherHistorySt
 				"fingerprint":         fingerprint,
 				"public_key":          publicKey,
 				"uptime":              uptime,
+				"build_profile":       buildprofile.Name,
 			},
 		}
 
@@ -114,38 +117,48 @@ func generateLauncherInfoTable(configStore types.GetterSetter, LauncherHistorySt
 			results[0]["local_key"] = base64.StdEncoding.EncodeToString(localKeyDer)
 		}
 
-		// we might not always have hardware keys so check first
-		if agent.HardwareKeys().Public() == nil {
-			return results, nil
+		hardwareKey, err := hardwareKeyJson()
+		if err != nil {
+			return nil, err
 		}
-
-		if runtime.GOOS == "darwin" && agent.HardwareKeys() != nil && agent.HardwareKeys().Public() != nil {
-			jsonBytes, err := json.Marshal(agent.HardwareKeys())
-			if err != nil {
-				return nil, fmt.Errorf("marshalling hardware keys: %w", err)
-			}
-			results[0]["hardware_key"] = string(jsonBytes)
+		if hardwareKey != "" {
+			results[0]["hardware_key"] = hardwareKey
 			results[0]["hardware_key_source"] = agent.HardwareKeys().Type()
-
-			return results, nil
 		}
 
-		if hardwareKeyDer, err := x509.MarshalPKIXPublicKey(agent.HardwareKeys().Public()); err == nil {
-			// on non-darwin we'll only have 1 key for the entire machine, but we want to keep format consistent with darwin
-			// so just return a map with 0 as the uid
-			jsonBytes, err := json.Marshal(map[string]string{
-				"0": base64.StdEncoding.EncodeToString(hardwareKeyDer),
-			})
+		return results, nil
+	}
+}
 
-			if err != nil {
-				return nil, fmt.Errorf("marshalling hardware keys: %w", err)
-			}
+// hardwareKeyJson returns the public hardware keys as JSON, or an empty string if there are none.
+func hardwareKeyJson() (string, error) {
+	// we might not always have hardware keys so check first
+	if agent.HardwareKeys() == nil || agent.HardwareKeys().Public() == nil {
+		return "", nil
+	}
 
-			// der is a binary format, so convert to b64
-			results[0]["hardware_key"] = string(jsonBytes)
-			results[0]["hardware_key_source"] = agent.HardwareKeys().Type()
+	if runtime.GOOS == "darwin" {
+		jsonBytes, err := json.Marshal(agent.HardwareKeys())
+		if err != nil {
+			return "", fmt.Errorf("marshalling hardware keys: %w", err)
 		}
+		return string(jsonBytes), nil
+	}
 
-		return results, nil
+	hardwareKeyDer, err := x509.MarshalPKIXPublicKey(agent.HardwareKeys().Public())
+	if err != nil {
+		return "", nil
+	}
+
+	// on non-darwin we'll only have 1 key for the entire machine, but we want to keep format consistent with darwin
+	// so just return a map with 0 as the uid
+	jsonBytes, err := json.Marshal(map[string]string{
+		// der is a binary format, so convert to b64
+		"0": base64.StdEncoding.EncodeToString(hardwareKeyDer),
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshalling hardware keys: %w", err)
 	}
+
+	return string(jsonBytes), nil
 }
@@ -8,27 +8,48 @@ import (
 
 	"github.com/knightsc/system_policy/osquery/table/kextpolicy"
 	"github.com/knightsc/system_policy/osquery/table/legacyexec"
+	"github.com/kolide/launcher/ee/agent/types"
 	"github.com/kolide/launcher/ee/allowedcmd"
 	"github.com/kolide/launcher/ee/tables/airport"
+	"github.com/kolide/launcher/ee/tables/analyticssettings"
 	appicons "github.com/kolide/launcher/ee/tables/app-icons"
 	"github.com/kolide/launcher/ee/tables/apple_silicon_security_policy"
+	"github.com/kolide/launcher/ee/tables/batteryhealth"
 	"github.com/kolide/launcher/ee/tables/dataflattentable"
+	"github.com/kolide/launcher/ee/tables/dhcpleases"
+	"github.com/kolide/launcher/ee/tables/displayidle"
+	"github.com/kolide/launcher/ee/tables/entrajoin"
 	"github.com/kolide/launcher/ee/tables/execparsers/remotectl"
 	"github.com/kolide/launcher/ee/tables/execparsers/repcli"
+	"github.com/kolide/launcher/ee/tables/execparsers/scutil_dns"
 	"github.com/kolide/launcher/ee/tables/execparsers/socketfilterfw"
 	"github.com/kolide/launcher/ee/tables/execparsers/softwareupdate"
 	"github.com/kolide/launcher/ee/tables/filevault"
 	"github.com/kolide/launcher/ee/tables/firmwarepasswd"
+	"github.com/kolide/launcher/ee/tables/gatekeeper"
 	"github.com/kolide/launcher/ee/tables/homebrew"
+	"github.com/kolide/launcher/ee/tables/intune"
 	"github.com/kolide/launcher/ee/tables/ioreg"
+	"github.com/kolide/launcher/ee/tables/iosdevices"
+	"github.com/kolide/launcher/ee/tables/jamf"
+	"github.com/kolide/launcher/ee/tables/launchagents"
+	"github.com/kolide/launcher/ee/tables/loginwindow"
 	"github.com/kolide/launcher/ee/tables/macos_software_update"
 	"github.com/kolide/launcher/ee/tables/mdmclient"
 	"github.com/kolide/launcher/ee/tables/munki"
+	"github.com/kolide/launcher/ee/tables/oshardening"
 	"github.com/kolide/launcher/ee/tables/osquery_user_exec_table"
 	"github.com/kolide/launcher/ee/tables/profiles"
 	"github.com/kolide/launcher/ee/tables/pwpolicy"
+	"github.com/kolide/launcher/ee/tables/quarantineevents"
+	"github.com/kolide/launcher/ee/tables/scheduledjobs"
 	"github.com/kolide/launcher/ee/tables/spotlight"
 	"github.com/kolide/launcher/ee/tables/systemprofiler"
+	"github.com/kolide/launcher/ee/tables/systemproxy"
+	"github.com/kolide/launcher/ee/tables/tcc"
+	"github.com/kolide/launcher/ee/tables/timemachine"
+	"github.com/kolide/launcher/ee/tables/ulimit"
+	"github.com/kolide/launcher/ee/tables/vpn"
 	"github.com/kolide/launcher/ee/tables/zfs"
 	_ "github.com/mattn/go-sqlite3"
 	osquery "github.com/osquery/osquery-go"
@@ -41,7 +62,7 @@ const (
 	screenlockQuery    = "select enabled, grace_period from screenlock"
 )
 
-func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath string) []osquery.OsqueryPlugin {
+func platformSpecificTables(k types.Knapsack, slogger *slog.Logger, currentOsquerydBinaryPath string) []osquery.OsqueryPlugin {
 	munki := munki.New()
 
 	// This table uses undocumented APIs, There is some discussion at the
@@ -84,6 +105,7 @@ func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath stri
 		keychainItemsTable,
 		appicons.AppIcons(),
 		brew_upgradeable.TablePlugin(slogger),
+		brew_upgradeable.OutdatedTablePlugin(slogger),
 		ChromeLoginKeychainInfo(slogger),
 		firmwarepasswd.TablePlugin(slogger),
 		GDriveSyncConfig(slogger),
@@ -92,6 +114,7 @@ func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath stri
 		macos_software_update.MacOSUpdate(),
 		macos_software_update.RecommendedUpdates(slogger),
 		macos_software_update.AvailableProducts(slogger),
+		macos_software_update.SoftwareUpdateSettings(slogger),
 		MachoInfo(),
 		spotlight.TablePlugin(slogger),
 		TouchIDUserConfig(slogger),
@@ -102,8 +125,30 @@ func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath stri
 		airport.TablePlugin(slogger),
 		kextpolicy.TablePlugin(),
 		filevault.TablePlugin(slogger),
+		gatekeeper.OverridesTablePlugin(slogger),
+		gatekeeper.AssessmentTablePlugin(slogger),
+		loginwindow.TablePlugin(slogger),
+		tcc.TablePlugin(slogger),
+		quarantineevents.TablePlugin(slogger),
+		launchagents.TablePlugin(slogger),
+		scheduledjobs.TablePlugin(slogger),
+		entrajoin.TablePlugin(slogger),
+		oshardening.TablePlugin(slogger),
+		batteryhealth.TablePlugin(slogger),
+		displayidle.TablePlugin(slogger),
+		systemproxy.TablePlugin(slogger),
+		dhcpleases.TablePlugin(slogger),
+		ulimit.TablePlugin(slogger),
+		vpn.WireguardTablePlugin(slogger),
+		vpn.StatusTablePlugin(slogger),
+		timemachine.ExclusionsTablePlugin(slogger),
+		timemachine.CoverageTablePlugin(slogger),
 		mdmclient.TablePlugin(slogger),
+		jamf.TablePlugin(slogger),
+		intune.TablePlugin(slogger),
 		apple_silicon_security_policy.TablePlugin(slogger),
+		analyticssettings.TablePlugin(slogger),
+		iosdevices.TablePlugin(slogger),
 		legacyexec.TablePlugin(),
 		dataflattentable.TablePluginExec(slogger,
 			"kolide_diskutil_list", dataflattentable.PlistType, allowedcmd.Diskutil, []string{"list", "-plist"}),
@@ -129,6 +174,7 @@ func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath stri
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_softwareupdate", softwareupdate.Parser, allowedcmd.Softwareupdate, []string{`--list`, `--no-scan`}, dataflattentable.WithIncludeStderr()),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_softwareupdate_scan", softwareupdate.Parser, allowedcmd.Softwareupdate, []string{`--list`}, dataflattentable.WithIncludeStderr()),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_carbonblack_repcli_status", repcli.Parser, allowedcmd.Repcli, []string{"status"}, dataflattentable.WithIncludeStderr()),
+		dataflattentable.NewExecAndParseTable(slogger, "kolide_etc_resolvers", scutil_dns.Parser, allowedcmd.Scutil, []string{"--dns"}),
 		zfs.ZfsPropertiesPlugin(slogger),
 		zfs.ZpoolPropertiesPlugin(slogger),
 	}
@@ -6,39 +6,54 @@ package table
 import (
 	"log/slog"
 
+	"github.com/kolide/launcher/ee/agent/types"
 	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/batteryhealth"
 	"github.com/kolide/launcher/ee/tables/crowdstrike/falcon_kernel_check"
 	"github.com/kolide/launcher/ee/tables/crowdstrike/falconctl"
 	"github.com/kolide/launcher/ee/tables/cryptsetup"
 	"github.com/kolide/launcher/ee/tables/dataflattentable"
+	"github.com/kolide/launcher/ee/tables/dhcpleases"
+	"github.com/kolide/launcher/ee/tables/displayidle"
 	"github.com/kolide/launcher/ee/tables/execparsers/apt"
 	"github.com/kolide/launcher/ee/tables/execparsers/data_table"
 	"github.com/kolide/launcher/ee/tables/execparsers/dnf"
+	"github.com/kolide/launcher/ee/tables/execparsers/dnf_updateinfo"
 	"github.com/kolide/launcher/ee/tables/execparsers/dpkg"
 	"github.com/kolide/launcher/ee/tables/execparsers/flatpak/remote_ls/upgradeable"
 	pacman_group "github.com/kolide/launcher/ee/tables/execparsers/pacman/group"
 	pacman_info "github.com/kolide/launcher/ee/tables/execparsers/pacman/info"
 	pacman_upgradeable "github.com/kolide/launcher/ee/tables/execparsers/pacman/upgradeable"
 	"github.com/kolide/launcher/ee/tables/execparsers/repcli"
+	"github.com/kolide/launcher/ee/tables/execparsers/resolvectl"
 	"github.com/kolide/launcher/ee/tables/execparsers/rpm"
 	"github.com/kolide/launcher/ee/tables/execparsers/simple_array"
 	"github.com/kolide/launcher/ee/tables/fscrypt_info"
 	"github.com/kolide/launcher/ee/tables/gsettings"
 	"github.com/kolide/launcher/ee/tables/homebrew"
+	"github.com/kolide/launcher/ee/tables/journald"
 	nix_env_upgradeable "github.com/kolide/launcher/ee/tables/nix_env/upgradeable"
+	"github.com/kolide/launcher/ee/tables/oshardening"
+	"github.com/kolide/launcher/ee/tables/pamconfig"
+	"github.com/kolide/launcher/ee/tables/scheduledjobs"
 	"github.com/kolide/launcher/ee/tables/secureboot"
+	"github.com/kolide/launcher/ee/tables/systemproxy"
+	"github.com/kolide/launcher/ee/tables/ulimit"
+	"github.com/kolide/launcher/ee/tables/vpn"
 	"github.com/kolide/launcher/ee/tables/xfconf"
 	"github.com/kolide/launcher/ee/tables/xrdb"
 	"github.com/kolide/launcher/ee/tables/zfs"
 	osquery "github.com/osquery/osquery-go"
 )
 
-func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath string) []osquery.OsqueryPlugin {
+func platformSpecificTables(k types.Knapsack, slogger *slog.Logger, currentOsquerydBinaryPath string) []osquery.OsqueryPlugin {
 	return []osquery.OsqueryPlugin{
 		brew_upgradeable.TablePlugin(slogger),
+		brew_upgradeable.OutdatedTablePlugin(slogger),
 		cryptsetup.TablePlugin(slogger),
 		gsettings.Settings(slogger),
 		gsettings.Metadata(slogger),
+		journald.TablePlugin(slogger, k.JournaldCursorStore()),
 		nix_env_upgradeable.TablePlugin(slogger),
 		secureboot.TablePlugin(slogger),
 		xrdb.TablePlugin(slogger),
@@ -46,6 +61,16 @@ func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath stri
 		falcon_kernel_check.TablePlugin(slogger),
 		falconctl.NewFalconctlOptionTable(slogger),
 		xfconf.TablePlugin(slogger),
+		oshardening.TablePlugin(slogger),
+		batteryhealth.TablePlugin(slogger),
+		displayidle.TablePlugin(slogger),
+		systemproxy.TablePlugin(slogger),
+		dhcpleases.TablePlugin(slogger),
+		ulimit.TablePlugin(slogger),
+		vpn.WireguardTablePlugin(slogger),
+		vpn.StatusTablePlugin(slogger),
+		pamconfig.TablePlugin(slogger),
+		scheduledjobs.TablePlugin(slogger),
 
 		dataflattentable.TablePluginExec(slogger,
 			"kolide_nmcli_wifi", dataflattentable.KeyValueType,
@@ -61,6 +86,7 @@ func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath stri
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_falconctl_systags", simple_array.New("systags"), allowedcmd.Falconctl, []string{"-g", "--systags"}),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_apt_upgradeable", apt.Parser, allowedcmd.Apt, []string{"list", "--upgradeable"}, dataflattentable.WithIncludeStderr()),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_dnf_upgradeable", dnf.Parser, allowedcmd.Dnf, []string{"check-update"}, dataflattentable.WithIncludeStderr()),
+		dataflattentable.NewExecAndParseTable(slogger, "kolide_dnf_updateinfo", dnf_updateinfo.Parser, allowedcmd.Dnf, []string{"-q", "updateinfo", "list", "--updates"}, dataflattentable.WithTimeoutSeconds(60)),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_dpkg_version_info", dpkg.Parser, allowedcmd.Dpkg, []string{"-p"}, dataflattentable.WithIncludeStderr()),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_flatpak_upgradeable", flatpak_upgradeable.Parser, allowedcmd.Flatpak, []string{"remote-ls", "--updates"}, dataflattentable.WithIncludeStderr()),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_pacman_group", pacman_group.Parser, allowedcmd.Pacman, []string{"-Qg"}, dataflattentable.WithIncludeStderr()),
@@ -70,6 +96,7 @@ func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath stri
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_snap_installed", data_table.NewParser(), allowedcmd.Snap, []string{"list"}, dataflattentable.WithIncludeStderr()),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_snap_upgradeable", data_table.NewParser(), allowedcmd.Snap, []string{"refresh", "--list"}, dataflattentable.WithIncludeStderr()),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_carbonblack_repcli_status", repcli.Parser, allowedcmd.Repcli, []string{"status"}, dataflattentable.WithIncludeStderr()),
+		dataflattentable.NewExecAndParseTable(slogger, "kolide_etc_resolvers", resolvectl.Parser, allowedcmd.Resolvectl, []string{"status", "--no-pager"}),
 		dataflattentable.TablePluginExec(slogger, "kolide_zypper_upgradeable_packages", dataflattentable.XmlType, allowedcmd.Zypper, []string{"-x", "lu"}),
 		dataflattentable.TablePluginExec(slogger, "kolide_zypper_upgradeable_patches", dataflattentable.XmlType, allowedcmd.Zypper, []string{"-x", "lp"}),
 		dataflattentable.TablePluginExec(slogger, "kolide_nftables", dataflattentable.JsonType, allowedcmd.Nftables, []string{"-jat", "list", "ruleset"}), // -j (json) -a (show object handles) -t (terse, omit set contents)
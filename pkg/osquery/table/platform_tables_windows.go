@@ -6,26 +6,56 @@ package table
 import (
 	"log/slog"
 
+	"github.com/kolide/launcher/ee/agent/types"
 	"github.com/kolide/launcher/ee/allowedcmd"
+	"github.com/kolide/launcher/ee/tables/batteryhealth"
 	"github.com/kolide/launcher/ee/tables/dataflattentable"
+	"github.com/kolide/launcher/ee/tables/dhcpleases"
+	"github.com/kolide/launcher/ee/tables/displayidle"
 	"github.com/kolide/launcher/ee/tables/dsim_default_associations"
+	"github.com/kolide/launcher/ee/tables/entrajoin"
 	"github.com/kolide/launcher/ee/tables/execparsers/dsregcmd"
+	"github.com/kolide/launcher/ee/tables/execparsers/winget"
+	"github.com/kolide/launcher/ee/tables/intune"
+	"github.com/kolide/launcher/ee/tables/lsaprotection"
+	"github.com/kolide/launcher/ee/tables/ntfsads"
+	"github.com/kolide/launcher/ee/tables/oshardening"
 	"github.com/kolide/launcher/ee/tables/secedit"
+	"github.com/kolide/launcher/ee/tables/servicesacl"
+	"github.com/kolide/launcher/ee/tables/systemproxy"
+	"github.com/kolide/launcher/ee/tables/vpn"
 	"github.com/kolide/launcher/ee/tables/wifi_networks"
+	"github.com/kolide/launcher/ee/tables/windowsfirewall"
+	"github.com/kolide/launcher/ee/tables/windowspersistence"
 	"github.com/kolide/launcher/ee/tables/windowsupdatetable"
 	"github.com/kolide/launcher/ee/tables/wmitable"
 	osquery "github.com/osquery/osquery-go"
 )
 
-func platformSpecificTables(slogger *slog.Logger, currentOsquerydBinaryPath string) []osquery.OsqueryPlugin {
+func platformSpecificTables(k types.Knapsack, slogger *slog.Logger, currentOsquerydBinaryPath string) []osquery.OsqueryPlugin {
 	return []osquery.OsqueryPlugin{
 		ProgramIcons(),
 		dsim_default_associations.TablePlugin(slogger),
+		intune.TablePlugin(slogger),
+		lsaprotection.TablePlugin(slogger),
+		ntfsads.TablePlugin(slogger),
+		oshardening.TablePlugin(slogger),
+		batteryhealth.TablePlugin(slogger),
+		displayidle.TablePlugin(slogger),
+		systemproxy.TablePlugin(slogger),
+		dhcpleases.TablePlugin(slogger),
+		vpn.WireguardTablePlugin(slogger),
+		vpn.StatusTablePlugin(slogger),
 		secedit.TablePlugin(slogger),
+		servicesacl.TablePlugin(slogger),
 		wifi_networks.TablePlugin(slogger),
 		windowsupdatetable.TablePlugin(windowsupdatetable.UpdatesTable, slogger),
 		windowsupdatetable.TablePlugin(windowsupdatetable.HistoryTable, slogger),
 		wmitable.TablePlugin(slogger),
 		dataflattentable.NewExecAndParseTable(slogger, "kolide_dsregcmd", dsregcmd.Parser, allowedcmd.Dsregcmd, []string{`/status`}),
+		entrajoin.TablePlugin(slogger),
+		windowsfirewall.TablePlugin(slogger),
+		windowspersistence.TablePlugin(slogger),
+		dataflattentable.NewExecAndParseTable(slogger, "kolide_winget_upgradeable", winget.Parser, allowedcmd.Winget, []string{"upgrade", "--include-unknown", "--accept-source-agreements", "--disable-interactivity"}, dataflattentable.WithTimeoutSeconds(60)),
 	}
 }
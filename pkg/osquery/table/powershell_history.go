@@ -0,0 +1,65 @@
+package table
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+type powershellHistoryTable struct {
+	slogger *slog.Logger
+}
+
+// PowershellHistory returns the PSReadLine command history for each user. PSReadLine keeps
+// a history file per host application, e.g. ConsoleHost_history.txt for the console.
+func PowershellHistory(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.BigIntColumn("uid"),
+		table.TextColumn("host"),
+		table.IntegerColumn("line"),
+		table.TextColumn("command"),
+		table.TextColumn("history_file"),
+	}
+
+	t := &powershellHistoryTable{
+		slogger: slogger.With("table", "kolide_powershell_history"),
+	}
+
+	return table.NewPlugin("kolide_powershell_history", columns, t.generate)
+}
+
+func (t *powershellHistoryTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	for _, file := range findUserHistoryFiles(ctx, t.slogger, queryContext, psReadLineHistoryPattern()) {
+		entries, err := readHistoryFile(file.path, parsePowershellHistory)
+		if err != nil {
+			t.slogger.Log(ctx, slog.LevelInfo,
+				"could not read powershell history",
+				"path", file.path,
+				"err", err,
+			)
+			continue
+		}
+
+		uid := uidForUsername(file.user)
+		host := strings.TrimSuffix(filepath.Base(file.path), "_history.txt")
+		for i, entry := range entries {
+			results = append(results, map[string]string{
+				"username":     file.user,
+				"uid":          uid,
+				"host":         host,
+				"line":         strconv.Itoa(i + 1),
+				"command":      entry.command,
+				"history_file": file.path,
+			})
+		}
+	}
+
+	return results, nil
+}
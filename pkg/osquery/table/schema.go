@@ -0,0 +1,86 @@
+package table
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sort"
+
+	"github.com/kolide/launcher/ee/agent/types"
+	osquery "github.com/osquery/osquery-go"
+)
+
+// Schema describes the tables launcher registers with osquery on this platform.
+type Schema struct {
+	Tables []TableSchema `json:"tables"`
+}
+
+// TableSchema describes a single table. Each launcher build only knows the tables for its own
+// platform, so Platforms is always the current platform; the control server merges schemas
+// reported from each platform.
+type TableSchema struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Platforms   []string       `json:"platforms"`
+	Columns     []ColumnSchema `json:"columns"`
+}
+
+type ColumnSchema struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// RegisteredTables returns all the tables launcher registers with osquery -- the platform tables,
+// including Kolide ATC tables for the given registration, and the launcher tables. Calls to each
+// are traced under the table's name.
+func RegisteredTables(k types.Knapsack, registrationId string, slogger *slog.Logger, currentOsquerydBinaryPath string) []osquery.OsqueryPlugin {
+	tables := PlatformTables(k, registrationId, slogger, currentOsquerydBinaryPath)
+	return withTracing(append(tables, LauncherTables(k)...))
+}
+
+// BuildSchema returns the schema of the table plugins in plugins. Other plugins are ignored.
+func BuildSchema(plugins []osquery.OsqueryPlugin) Schema {
+	tables := make([]TableSchema, 0, len(plugins))
+	for _, plugin := range plugins {
+		if plugin.RegistryName() != "table" {
+			continue
+		}
+
+		// Table plugins describe their columns as routes
+		var columns []ColumnSchema
+		for _, route := range plugin.Routes() {
+			if route["id"] != "column" {
+				continue
+			}
+			columns = append(columns, ColumnSchema{
+				Name: route["name"],
+				Type: route["type"],
+			})
+		}
+
+		tables = append(tables, TableSchema{
+			Name:        plugin.Name(),
+			Description: tableDescriptions[plugin.Name()],
+			Platforms:   []string{runtime.GOOS},
+			Columns:     columns,
+		})
+	}
+
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	return Schema{Tables: tables}
+}
+
+// Hash returns a hash of the schema, for noticing when it has changed.
+func (s Schema) Hash() (string, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("marshalling schema: %w", err)
+	}
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
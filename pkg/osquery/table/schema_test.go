@@ -0,0 +1,52 @@
+package table
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	osquery "github.com/osquery/osquery-go"
+	"github.com/osquery/osquery-go/plugin/distributed"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSchema(t *testing.T) {
+	t.Parallel()
+
+	generate := func(context.Context, table.QueryContext) ([]map[string]string, error) { return nil, nil }
+
+	schema := BuildSchema([]osquery.OsqueryPlugin{
+		table.NewPlugin("kolide_virtualization_guests", []table.ColumnDefinition{
+			table.TextColumn("name"),
+			table.IntegerColumn("pid"),
+		}, generate),
+		table.NewPlugin("kolide_undocumented", []table.ColumnDefinition{
+			table.BigIntColumn("size"),
+		}, generate),
+		// Not a table, so not part of the schema
+		distributed.NewPlugin("kolide", nil, nil),
+	})
+
+	require.Equal(t, Schema{Tables: []TableSchema{
+		{
+			Name:      "kolide_undocumented",
+			Platforms: []string{runtime.GOOS},
+			Columns:   []ColumnSchema{{Name: "size", Type: "BIGINT"}},
+		},
+		{
+			Name:        "kolide_virtualization_guests",
+			Description: tableDescriptions["kolide_virtualization_guests"],
+			Platforms:   []string{runtime.GOOS},
+			Columns:     []ColumnSchema{{Name: "name", Type: "TEXT"}, {Name: "pid", Type: "INTEGER"}},
+		},
+	}}, schema)
+
+	hash, err := schema.Hash()
+	require.NoError(t, err)
+
+	schema.Tables[0].Columns[0].Type = "TEXT"
+	changedHash, err := schema.Hash()
+	require.NoError(t, err)
+	require.NotEqual(t, hash, changedHash)
+}
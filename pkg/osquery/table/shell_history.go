@@ -0,0 +1,364 @@
+package table
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/kolide/launcher/ee/tables/tablehelpers"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// historyEntry is a single command from a shell history file. time is 0 when the history
+// format doesn't record timestamps.
+type historyEntry struct {
+	time    int64
+	command string
+}
+
+type historyParser func(io.Reader) ([]historyEntry, error)
+
+type shellHistorySource struct {
+	shell   string
+	pattern string
+	parse   historyParser
+}
+
+// psReadLineHistoryDirs is where PSReadLine keeps per-host history files, e.g. ConsoleHost_history.txt
+var psReadLineHistoryDirs = map[string]string{
+	"windows": "AppData/Roaming/Microsoft/Windows/PowerShell/PSReadLine",
+}
+
+// pwsh on macOS and linux follows XDG
+var psReadLineHistoryDirDefault = ".local/share/powershell/PSReadLine"
+
+func psReadLineHistoryPattern() string {
+	dir, ok := psReadLineHistoryDirs[runtime.GOOS]
+	if !ok {
+		dir = psReadLineHistoryDirDefault
+	}
+	return dir + "/*_history.txt"
+}
+
+func shellHistorySources() []shellHistorySource {
+	sources := []shellHistorySource{
+		{shell: "powershell", pattern: psReadLineHistoryPattern(), parse: parsePowershellHistory},
+	}
+
+	if runtime.GOOS == "windows" {
+		return sources
+	}
+
+	return append(sources,
+		shellHistorySource{shell: "bash", pattern: ".bash_history", parse: parseBashHistory},
+		shellHistorySource{shell: "zsh", pattern: ".zsh_history", parse: parseZshHistory},
+		shellHistorySource{shell: "zsh", pattern: ".zsh_sessions/*.history", parse: parseZshHistory},
+		shellHistorySource{shell: "sh", pattern: ".sh_history", parse: parseBashHistory},
+		shellHistorySource{shell: "fish", pattern: ".local/share/fish/fish_history", parse: parseFishHistory},
+	)
+}
+
+type shellHistoryTable struct {
+	slogger *slog.Logger
+}
+
+// ShellHistory normalizes shell histories across platforms and shells, including PSReadLine
+// history on Windows, which osquery's shell_history table does not support.
+func ShellHistory(slogger *slog.Logger) *table.Plugin {
+	columns := []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.BigIntColumn("uid"),
+		table.TextColumn("shell"),
+		table.BigIntColumn("time"),
+		table.TextColumn("command"),
+		table.TextColumn("history_file"),
+	}
+
+	t := &shellHistoryTable{
+		slogger: slogger.With("table", "kolide_shell_history"),
+	}
+
+	return table.NewPlugin("kolide_shell_history", columns, t.generate)
+}
+
+func (t *shellHistoryTable) generate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+
+	for _, source := range shellHistorySources() {
+		for _, file := range findUserHistoryFiles(ctx, t.slogger, queryContext, source.pattern) {
+			entries, err := readHistoryFile(file.path, source.parse)
+			if err != nil {
+				t.slogger.Log(ctx, slog.LevelInfo,
+					"could not read shell history",
+					"path", file.path,
+					"err", err,
+				)
+				continue
+			}
+
+			uid := uidForUsername(file.user)
+			for _, entry := range entries {
+				row := map[string]string{
+					"username":     file.user,
+					"uid":          uid,
+					"shell":        source.shell,
+					"time":         "",
+					"command":      entry.command,
+					"history_file": file.path,
+				}
+				if entry.time != 0 {
+					row["time"] = strconv.FormatInt(entry.time, 10)
+				}
+				results = append(results, row)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// findUserHistoryFiles finds history files matching pattern in users' home directories,
+// restricted to the usernames in the query's constraints, if any.
+func findUserHistoryFiles(ctx context.Context, slogger *slog.Logger, queryContext table.QueryContext, pattern string) []userFileInfo {
+	var opts [][]FindFileOpt
+	for _, username := range tablehelpers.GetConstraints(queryContext, "username") {
+		opts = append(opts, []FindFileOpt{WithUsername(username)})
+	}
+	if len(opts) == 0 {
+		opts = [][]FindFileOpt{nil}
+	}
+
+	var files []userFileInfo
+	for _, o := range opts {
+		found, err := findFileInUserDirs(pattern, slogger, o...)
+		if err != nil {
+			slogger.Log(ctx, slog.LevelInfo,
+				"error finding history files",
+				"pattern", pattern,
+				"err", err,
+			)
+			continue
+		}
+		files = append(files, found...)
+	}
+
+	return files
+}
+
+func readHistoryFile(path string, parse historyParser) ([]historyEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	return parse(f)
+}
+
+// uidForUsername returns the user's uid. On Windows, this is the RID -- the last component of
+// the user's SID -- matching osquery's users table. It returns an empty string if the user
+// can't be found, e.g. for a home directory that doesn't match the username.
+func uidForUsername(username string) string {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return ""
+	}
+
+	if runtime.GOOS == "windows" {
+		return u.Uid[strings.LastIndex(u.Uid, "-")+1:]
+	}
+
+	return u.Uid
+}
+
+// newHistoryScanner returns a line scanner that tolerates long commands.
+func newHistoryScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return scanner
+}
+
+// parseBashHistory parses bash (and sh) history. When HISTTIMEFORMAT is set, bash precedes
+// each command with a comment line holding its timestamp, e.g. `#1700000000`.
+func parseBashHistory(r io.Reader) ([]historyEntry, error) {
+	var entries []historyEntry
+	var ts int64
+
+	scanner := newHistoryScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "#") {
+			if parsed, err := strconv.ParseInt(line[1:], 10, 64); err == nil {
+				ts = parsed
+				continue
+			}
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		entries = append(entries, historyEntry{time: ts, command: line})
+		ts = 0
+	}
+
+	return entries, scanner.Err()
+}
+
+// parseZshHistory parses zsh history. With EXTENDED_HISTORY, lines look like
+// `: 1700000000:0;command`. Multi-line commands have each embedded newline escaped with a
+// trailing backslash.
+func parseZshHistory(r io.Reader) ([]historyEntry, error) {
+	var entries []historyEntry
+	var current []string
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		entries = append(entries, parseZshEntry(strings.Join(current, "\n")))
+		current = nil
+	}
+
+	scanner := newHistoryScanner(r)
+	for scanner.Scan() {
+		line := unmetafyZsh(scanner.Text())
+
+		if strings.HasSuffix(line, `\`) {
+			current = append(current, strings.TrimSuffix(line, `\`))
+			continue
+		}
+
+		current = append(current, line)
+		flush()
+	}
+	flush()
+
+	return entries, scanner.Err()
+}
+
+func parseZshEntry(entry string) historyEntry {
+	if !strings.HasPrefix(entry, ": ") {
+		return historyEntry{command: entry}
+	}
+
+	meta, command, found := strings.Cut(entry[2:], ";")
+	if !found {
+		return historyEntry{command: entry}
+	}
+
+	tsStr, _, _ := strings.Cut(meta, ":")
+	ts, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return historyEntry{command: entry}
+	}
+
+	return historyEntry{time: ts, command: command}
+}
+
+// unmetafyZsh reverses zsh's "metafication" of history files, where some bytes are written as
+// 0x83 followed by the byte XOR 32.
+func unmetafyZsh(line string) string {
+	const meta = 0x83
+	if strings.IndexByte(line, meta) == -1 {
+		return line
+	}
+
+	out := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		if line[i] == meta && i+1 < len(line) {
+			i++
+			out = append(out, line[i]^32)
+			continue
+		}
+		out = append(out, line[i])
+	}
+
+	return string(out)
+}
+
+// parseFishHistory parses fish's YAML-like history format, where each entry starts with a
+// `- cmd: <command>` line, followed by indented fields including `when: <timestamp>`.
+func parseFishHistory(r io.Reader) ([]historyEntry, error) {
+	var entries []historyEntry
+
+	scanner := newHistoryScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if command, found := strings.CutPrefix(line, "- cmd: "); found {
+			entries = append(entries, historyEntry{command: unescapeFish(command)})
+			continue
+		}
+
+		if whenStr, found := strings.CutPrefix(line, "  when: "); found && len(entries) > 0 {
+			if ts, err := strconv.ParseInt(whenStr, 10, 64); err == nil {
+				entries[len(entries)-1].time = ts
+			}
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// unescapeFish reverses fish's escaping of newlines and backslashes in history commands.
+func unescapeFish(command string) string {
+	var sb strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] == '\\' && i+1 < len(command) {
+			switch command[i+1] {
+			case 'n':
+				sb.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				sb.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(command[i])
+	}
+
+	return sb.String()
+}
+
+// parsePowershellHistory parses PSReadLine history, which has no timestamps. Multi-line
+// commands have each line but the last terminated with a backtick.
+func parsePowershellHistory(r io.Reader) ([]historyEntry, error) {
+	var entries []historyEntry
+	var current []string
+
+	scanner := newHistoryScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if strings.HasSuffix(line, "`") {
+			current = append(current, strings.TrimSuffix(line, "`"))
+			continue
+		}
+
+		current = append(current, line)
+		command := strings.Join(current, "\n")
+		current = nil
+
+		if strings.TrimSpace(command) == "" {
+			continue
+		}
+		entries = append(entries, historyEntry{command: command})
+	}
+
+	if len(current) > 0 {
+		entries = append(entries, historyEntry{command: strings.Join(current, "\n")})
+	}
+
+	return entries, scanner.Err()
+}
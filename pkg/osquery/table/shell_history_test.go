@@ -0,0 +1,86 @@
+package table
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShellHistory(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		parse    historyParser
+		input    string
+		expected []historyEntry
+	}{
+		{
+			name:  "bash without timestamps",
+			parse: parseBashHistory,
+			input: "ls -la\n\ncd /tmp\n# not a timestamp\n",
+			expected: []historyEntry{
+				{command: "ls -la"},
+				{command: "cd /tmp"},
+				{command: "# not a timestamp"},
+			},
+		},
+		{
+			name:  "bash with timestamps",
+			parse: parseBashHistory,
+			input: "#1700000000\nls -la\n#1700000100\ncd /tmp\nwhoami\n",
+			expected: []historyEntry{
+				{time: 1700000000, command: "ls -la"},
+				{time: 1700000100, command: "cd /tmp"},
+				{command: "whoami"},
+			},
+		},
+		{
+			name:  "zsh extended history",
+			parse: parseZshHistory,
+			input: ": 1700000000:0;ls -la\n: 1700000100:5;for i in 1 2; do\\\necho $i\\\ndone\nplain command\n",
+			expected: []historyEntry{
+				{time: 1700000000, command: "ls -la"},
+				{time: 1700000100, command: "for i in 1 2; do\necho $i\ndone"},
+				{command: "plain command"},
+			},
+		},
+		{
+			name:  "zsh metafied",
+			parse: parseZshHistory,
+			input: ": 1700000000:0;echo \x83\xa3\n",
+			expected: []historyEntry{
+				{time: 1700000000, command: "echo \x83"},
+			},
+		},
+		{
+			name:  "fish",
+			parse: parseFishHistory,
+			input: "- cmd: ls -la\n  when: 1700000000\n  paths:\n    - -la\n- cmd: echo a\\nb \\\\ c\n  when: 1700000100\n",
+			expected: []historyEntry{
+				{time: 1700000000, command: "ls -la"},
+				{time: 1700000100, command: "echo a\nb \\ c"},
+			},
+		},
+		{
+			name:  "powershell",
+			parse: parsePowershellHistory,
+			input: "Get-Process\r\nGet-ChildItem `\r\n  -Recurse\r\n\r\nInvoke-WebRequest https://example.com `\r\n",
+			expected: []historyEntry{
+				{command: "Get-Process"},
+				{command: "Get-ChildItem \n  -Recurse"},
+				{command: "Invoke-WebRequest https://example.com "},
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			entries, err := tt.parse(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, entries)
+		})
+	}
+}
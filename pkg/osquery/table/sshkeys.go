@@ -36,6 +36,7 @@ func SshKeys(slogger *slog.Logger) *table.Plugin {
 		table.TextColumn("fingerprint_sha256"),
 		table.TextColumn("fingerprint_md5"),
 	}
+	columns = append(columns, certificateColumns()...)
 
 	// we don't want the logging in osquery, so don't instantiate WithSlogger()
 	kIdentifer, err := keyidentifier.New()
@@ -107,6 +108,8 @@ func (t *SshKeysTable) generate(ctx context.Context, queryContext table.QueryCon
 				res["fingerprint_md5"] = ki.FingerprintMD5
 			}
 
+			addCertificateColumns(res, ki.Certificate)
+
 			results = append(results, res)
 		}
 	}
@@ -12,16 +12,39 @@ import (
 	"github.com/kolide/launcher/ee/agent/types"
 	"github.com/kolide/launcher/ee/allowedcmd"
 	"github.com/kolide/launcher/ee/katc"
+	"github.com/kolide/launcher/ee/tables/appconfig"
+	"github.com/kolide/launcher/ee/tables/cloudsync"
+	"github.com/kolide/launcher/ee/tables/connectivity_probes"
+	"github.com/kolide/launcher/ee/tables/consentstatus"
+	"github.com/kolide/launcher/ee/tables/controlactionhistory"
 	"github.com/kolide/launcher/ee/tables/cryptoinfotable"
 	"github.com/kolide/launcher/ee/tables/dataflattentable"
+	"github.com/kolide/launcher/ee/tables/desktopcapability"
+	"github.com/kolide/launcher/ee/tables/desktopipc"
 	"github.com/kolide/launcher/ee/tables/desktopprocs"
 	"github.com/kolide/launcher/ee/tables/dev_table_tooling"
+	"github.com/kolide/launcher/ee/tables/disksmart"
+	"github.com/kolide/launcher/ee/tables/fimconfig"
 	"github.com/kolide/launcher/ee/tables/firefox_preferences"
+	"github.com/kolide/launcher/ee/tables/hardwaresecurity"
+	"github.com/kolide/launcher/ee/tables/hostsfilewatch"
 	"github.com/kolide/launcher/ee/tables/jwt"
+	"github.com/kolide/launcher/ee/tables/kerberos"
+	"github.com/kolide/launcher/ee/tables/lastlogin"
 	"github.com/kolide/launcher/ee/tables/launcher_db"
+	"github.com/kolide/launcher/ee/tables/launcher_processes"
+	"github.com/kolide/launcher/ee/tables/listeningservices"
+	"github.com/kolide/launcher/ee/tables/loginbanner"
+	"github.com/kolide/launcher/ee/tables/networkchangeevents"
+	"github.com/kolide/launcher/ee/tables/networkshares"
 	"github.com/kolide/launcher/ee/tables/osquery_instance_history"
+	"github.com/kolide/launcher/ee/tables/osquery_watchdog_events"
+	"github.com/kolide/launcher/ee/tables/query_accounting"
+	"github.com/kolide/launcher/ee/tables/restartstatus"
+	"github.com/kolide/launcher/ee/tables/storage_retention"
 	"github.com/kolide/launcher/ee/tables/tdebug"
 	"github.com/kolide/launcher/ee/tables/tufinfo"
+	"github.com/kolide/launcher/ee/tables/virtualizationguests"
 
 	osquery "github.com/osquery/osquery-go"
 )
@@ -33,13 +56,29 @@ func LauncherTables(k types.Knapsack) []osquery.OsqueryPlugin {
 		LauncherConfigTable(k.ConfigStore(), k),
 		LauncherDbInfo(k.BboltDB()),
 		LauncherInfoTable(k.ConfigStore(), k.LauncherHistoryStore()),
+		DeviceIdentityTable(k),
 		launcher_db.TablePlugin("kolide_server_data", k.ServerProvidedDataStore()),
 		launcher_db.TablePlugin("kolide_control_flags", k.AgentFlagsStore()),
 		LauncherAutoupdateConfigTable(k),
 		osquery_instance_history.TablePlugin(),
+		query_accounting.TablePlugin(),
+		osquery_watchdog_events.TablePlugin(),
+		storage_retention.TablePlugin(),
+		connectivity_probes.TablePlugin(),
 		tufinfo.TufReleaseVersionTable(k),
+		tufinfo.UpdateLibraryTable(k, k.Slogger()),
 		launcher_db.TablePlugin("kolide_tuf_autoupdater_errors", k.AutoupdateErrorsStore()),
 		desktopprocs.TablePlugin(),
+		desktopcapability.TablePlugin(),
+		launcher_processes.TablePlugin(),
+		desktopipc.TablePlugin(),
+		fimconfig.TablePlugin(k.FimConfigStore()),
+		hostsfilewatch.TablePlugin(k.HostsFileWatchStore()),
+		EnrollmentAttemptsTable(k.EnrollmentAttemptsStore()),
+		controlactionhistory.TablePlugin(k.ControlActionHistoryStore()),
+		networkchangeevents.TablePlugin(k.NetworkChangeEventsStore()),
+		restartstatus.TablePlugin(k.Slogger(), k.PersistentHostDataStore()),
+		consentstatus.TablePlugin(k.Slogger(), k.ControlStore(), k.PersistentHostDataStore()),
 	}
 }
 
@@ -47,16 +86,27 @@ func LauncherTables(k types.Knapsack) []osquery.OsqueryPlugin {
 func PlatformTables(k types.Knapsack, registrationId string, slogger *slog.Logger, currentOsquerydBinaryPath string) []osquery.OsqueryPlugin {
 	// Common tables to all platforms
 	tables := []osquery.OsqueryPlugin{
+		CertificatePrivateKeys(slogger),
 		ChromeLoginDataEmails(slogger),
 		ChromeUserProfiles(slogger),
 		KeyInfo(slogger),
 		OnePasswordAccounts(slogger),
+		PowershellHistory(slogger),
+		ShellHistory(slogger),
 		SlackConfig(slogger),
 		SshKeys(slogger),
 		cryptoinfotable.TablePlugin(slogger),
 		dev_table_tooling.TablePlugin(slogger),
+		disksmart.TablePlugin(slogger),
 		firefox_preferences.TablePlugin(slogger),
+		hardwaresecurity.TablePlugin(slogger),
 		jwt.TablePlugin(slogger),
+		kerberos.TablePlugin(slogger),
+		lastlogin.TablePlugin(slogger),
+		listeningservices.TablePlugin(slogger, listeningServicesStore(k)),
+		loginbanner.TablePlugin(slogger),
+		networkshares.TablePlugin(slogger),
+		virtualizationguests.TablePlugin(slogger),
 		dataflattentable.TablePluginExec(slogger,
 			"kolide_zerotier_info", dataflattentable.JsonType, allowedcmd.ZerotierCli, []string{"info"}),
 		dataflattentable.TablePluginExec(slogger,
@@ -69,8 +119,14 @@ func PlatformTables(k types.Knapsack, registrationId string, slogger *slog.Logge
 	// The dataflatten tables
 	tables = append(tables, dataflattentable.AllTablePlugins(slogger)...)
 
+	// The conferencing and chat app configuration tables
+	tables = append(tables, appconfig.TablePlugins(slogger)...)
+
+	// The OneDrive, Dropbox, and Google Drive sync client tables
+	tables = append(tables, cloudsync.TablePlugins(slogger)...)
+
 	// add in the platform specific ones (as denoted by build tags)
-	tables = append(tables, platformSpecificTables(slogger, currentOsquerydBinaryPath)...)
+	tables = append(tables, platformSpecificTables(k, slogger, currentOsquerydBinaryPath)...)
 
 	// Add in the Kolide custom ATC tables
 	tables = append(tables, kolideCustomAtcTables(k, registrationId, slogger)...)
@@ -78,6 +134,16 @@ func PlatformTables(k types.Knapsack, registrationId string, slogger *slog.Logge
 	return tables
 }
 
+// listeningServicesStore returns the store kolide_listening_services uses to track when
+// services were first seen, if available.
+func listeningServicesStore(k types.Knapsack) types.GetterSetterDeleterIterator {
+	if k == nil || k.ListeningServicesStore() == nil {
+		return nil
+	}
+
+	return k.ListeningServicesStore()
+}
+
 // kolideCustomAtcTables retrieves Kolide ATC config from the appropriate data store(s),
 // then constructs the tables.
 func kolideCustomAtcTables(k types.Knapsack, registrationId string, slogger *slog.Logger) []osquery.OsqueryPlugin {
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+
+	"github.com/kolide/launcher/ee/hostroot"
 )
 
 type findFile struct {
@@ -56,6 +58,7 @@ func findFileInUserDirs(pattern string, slogger *slog.Logger, opts ...FindFileOp
 	if ff.username == "" {
 		for _, possibleHome := range homedirRoots {
 
+			possibleHome = hostroot.Path(possibleHome)
 			userDirs, err := os.ReadDir(possibleHome)
 			if err != nil {
 				// This possibleHome doesn't exist. Move on
@@ -90,7 +93,7 @@ func findFileInUserDirs(pattern string, slogger *slog.Logger, opts ...FindFileOp
 
 	// We have a username. Future normal path here
 	for _, possibleHome := range homedirRoots {
-		userPathPattern := filepath.Join(possibleHome, ff.username, pattern)
+		userPathPattern := filepath.Join(hostroot.Path(possibleHome), ff.username, pattern)
 		fullPaths, err := filepath.Glob(userPathPattern)
 		if err != nil {
 			// skipping ErrBadPattern
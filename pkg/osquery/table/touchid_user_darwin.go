@@ -56,8 +56,9 @@ func (t *touchIDUserConfigTable) generate(ctx context.Context, queryContext tabl
 			continue
 		}
 
-		// Get the user's TouchID config
-		configOutput, err := tablehelpers.RunSimple(ctx, t.slogger, 10, allowedcmd.Bioutil, []string{"-r"}, tablehelpers.WithUid(u.Uid))
+		// Get the user's TouchID config. bioutil reports on the login session it runs in, so it
+		// needs to run in the user's context rather than just as the user.
+		configOutput, err := tablehelpers.RunSimple(ctx, t.slogger, 10, allowedcmd.Bioutil, []string{"-r"}, tablehelpers.WithUserContext(u.Uid))
 		if err != nil {
 			t.slogger.Log(ctx, slog.LevelInfo,
 				"could not run bioutil -r",
@@ -87,7 +88,7 @@ func (t *touchIDUserConfigTable) generate(ctx context.Context, queryContext tabl
 		}
 
 		// Grab the fingerprint count
-		countOut, err := tablehelpers.RunSimple(ctx, t.slogger, 10, allowedcmd.Bioutil, []string{"-c"}, tablehelpers.WithUid(u.Uid))
+		countOut, err := tablehelpers.RunSimple(ctx, t.slogger, 10, allowedcmd.Bioutil, []string{"-c"}, tablehelpers.WithUserContext(u.Uid))
 		if err != nil {
 			t.slogger.Log(ctx, slog.LevelDebug,
 				"could not run bioutil -c",
@@ -0,0 +1,43 @@
+package table
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kolide/launcher/pkg/traces"
+	osquery "github.com/osquery/osquery-go"
+	osquerygen "github.com/osquery/osquery-go/gen/osquery"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedTable wraps a table plugin so that each call to it is traced under the table's name.
+// osquery-go traces calls too, but doesn't say which table they were for.
+type tracedTable struct {
+	osquery.OsqueryPlugin
+}
+
+// withTracing wraps the table plugins in plugins. Other plugins are returned as they are.
+func withTracing(plugins []osquery.OsqueryPlugin) []osquery.OsqueryPlugin {
+	traced := make([]osquery.OsqueryPlugin, len(plugins))
+	for i, plugin := range plugins {
+		if plugin.RegistryName() != "table" {
+			traced[i] = plugin
+			continue
+		}
+		traced[i] = &tracedTable{OsqueryPlugin: plugin}
+	}
+	return traced
+}
+
+func (t *tracedTable) Call(ctx context.Context, request osquerygen.ExtensionPluginRequest) osquerygen.ExtensionResponse {
+	ctx, span := traces.StartNamedSpan(ctx, "table/"+t.Name(), "table_name", t.Name(), "action", request["action"])
+	defer span.End()
+
+	response := t.OsqueryPlugin.Call(ctx, request)
+	if response.Status != nil && response.Status.Code != 0 {
+		traces.SetError(span, errors.New(response.Status.Message))
+	}
+	span.SetAttributes(attribute.Int("launcher.table.row_count", len(response.Response)))
+
+	return response
+}
@@ -0,0 +1,36 @@
+package table
+
+import (
+	"context"
+	"testing"
+
+	osquery "github.com/osquery/osquery-go"
+	"github.com/osquery/osquery-go/plugin/config"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTracing(t *testing.T) {
+	t.Parallel()
+
+	tablePlugin := table.NewPlugin("kolide_example", []table.ColumnDefinition{table.TextColumn("name")},
+		func(_ context.Context, _ table.QueryContext) ([]map[string]string, error) {
+			return []map[string]string{{"name": "a"}, {"name": "b"}}, nil
+		},
+	)
+	configPlugin := config.NewPlugin("kolide_config", func(_ context.Context) (map[string]string, error) {
+		return nil, nil
+	})
+
+	traced := withTracing([]osquery.OsqueryPlugin{tablePlugin, configPlugin})
+	require.Len(t, traced, 2)
+	require.IsType(t, &tracedTable{}, traced[0])
+	require.Equal(t, configPlugin, traced[1])
+
+	// The wrapped table behaves as the table does
+	require.Equal(t, "kolide_example", traced[0].Name())
+	require.Equal(t, tablePlugin.Routes(), traced[0].Routes())
+	response := traced[0].Call(context.TODO(), map[string]string{"action": "generate", "context": "{}"})
+	require.Equal(t, int32(0), response.Status.Code)
+	require.Len(t, response.Response, 2)
+}
@@ -0,0 +1,236 @@
+// Package watchdogevents records when osquery's watchdog kills its worker process for exceeding
+// its CPU or memory limits, along with the queries that were likely responsible. osquery denylists
+// the offending scheduled query on its own, but only notes that it did so in its status logs.
+package watchdogevents
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolide/kit/ulid"
+	"github.com/kolide/launcher/ee/gowrapper"
+)
+
+const (
+	// maxEvents is how many of the most recent events are kept for the kolide_osquery_watchdog_events table
+	maxEvents = 100
+
+	// attributionWindow is how long after a kill we'll attribute a failed scheduled query to it.
+	// osquery reports the query once the restarted worker's scheduler runs.
+	attributionWindow = 10 * time.Minute
+)
+
+const (
+	LimitMemory = "memory"
+	LimitCpu    = "cpu"
+)
+
+var (
+	// e.g. `osqueryd worker (12345) stopping: Memory limits exceeded: 412532736`
+	killRegex = regexp.MustCompile(`osqueryd worker \((\d+)\) stopping: (.*)`)
+	// e.g. `Scheduled query may have failed: pack:kolide_log_pipeline:processes`
+	failedQueryRegex = regexp.MustCompile(`Scheduled query may have failed: ([^\s"]+)`)
+)
+
+var recent = &history{}
+
+// Event is a single kill of osquery's worker process by its watchdog.
+type Event struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	WorkerPid int       `json:"worker_pid"`
+	Limit     string    `json:"limit"` // LimitMemory or LimitCpu, if the watchdog said which
+	Message   string    `json:"message"`
+
+	// ScheduledQuery is the scheduled query osquery blamed for the kill, if it reported one.
+	// It's filled in after the event is first recorded, once the restarted worker reports it.
+	ScheduledQuery string `json:"scheduled_query,omitempty"`
+	// PendingDistributedQueries are the distributed queries osquery was running when its worker
+	// was killed; osquery doesn't report on these, but any of them may be responsible.
+	PendingDistributedQueries []string `json:"pending_distributed_queries,omitempty"`
+}
+
+type history struct {
+	sync.Mutex
+	events      []Event
+	subscribers []func(Event)
+}
+
+func (h *history) add(event Event) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	// The kill may be reported by both osqueryd's stderr and its status logs
+	for _, existing := range h.events {
+		if existing.WorkerPid == event.WorkerPid {
+			return false
+		}
+	}
+
+	h.events = append(h.events, event)
+	if len(h.events) > maxEvents {
+		h.events = h.events[len(h.events)-maxEvents:]
+	}
+	return true
+}
+
+// attribute blames the query on the most recent kill, if it was recent enough and hasn't
+// been attributed to a query already.
+func (h *history) attribute(query string, at time.Time) (Event, bool) {
+	h.Lock()
+	defer h.Unlock()
+
+	if len(h.events) == 0 {
+		return Event{}, false
+	}
+
+	last := &h.events[len(h.events)-1]
+	if last.ScheduledQuery != "" || at.Sub(last.Time) > attributionWindow {
+		return Event{}, false
+	}
+
+	last.ScheduledQuery = query
+	return *last, true
+}
+
+// Recent returns the most recent watchdog events, oldest first.
+func Recent() []Event {
+	recent.Lock()
+	defer recent.Unlock()
+
+	events := make([]Event, len(recent.events))
+	copy(events, recent.events)
+	return events
+}
+
+// Subscribe registers f to be called with each new watchdog event, and again when a scheduled
+// query is attributed to it. f is called in its own goroutine.
+func Subscribe(f func(Event)) {
+	recent.Lock()
+	defer recent.Unlock()
+
+	recent.subscribers = append(recent.subscribers, f)
+}
+
+func notify(ctx context.Context, slogger *slog.Logger, event Event) {
+	recent.Lock()
+	subscribers := make([]func(Event), len(recent.subscribers))
+	copy(subscribers, recent.subscribers)
+	recent.Unlock()
+
+	for _, f := range subscribers {
+		gowrapper.Go(ctx, slogger, func() {
+			f(event)
+		})
+	}
+}
+
+// Recorder parses osquery's output for watchdog kills.
+type Recorder struct {
+	slogger *slog.Logger
+
+	lock           sync.Mutex
+	pendingQueries func() []string
+}
+
+func NewRecorder(slogger *slog.Logger) *Recorder {
+	return &Recorder{
+		slogger: slogger.With("component", "osquery_watchdog_events"),
+	}
+}
+
+// SetPendingQueriesFunc sets the function used to look up which distributed queries osquery
+// was running when its worker was killed.
+func (r *Recorder) SetPendingQueriesFunc(f func() []string) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.pendingQueries = f
+}
+
+func (r *Recorder) pendingDistributedQueries() []string {
+	r.lock.Lock()
+	f := r.pendingQueries
+	r.lock.Unlock()
+
+	if f == nil {
+		return nil
+	}
+	return f()
+}
+
+// Observe checks the given osquery output, which may contain several lines, for watchdog kills
+// and the queries osquery blames for them.
+func (r *Recorder) Observe(ctx context.Context, output string, at time.Time) {
+	if r == nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		r.observeLine(ctx, line, at)
+	}
+}
+
+func (r *Recorder) observeLine(ctx context.Context, line string, at time.Time) {
+	if matches := killRegex.FindStringSubmatch(line); matches != nil {
+		pid, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return
+		}
+
+		event := Event{
+			ID:                        ulid.New(),
+			Time:                      at,
+			WorkerPid:                 pid,
+			Limit:                     limitFromMessage(matches[2]),
+			Message:                   strings.TrimSpace(matches[2]),
+			PendingDistributedQueries: r.pendingDistributedQueries(),
+		}
+		if !recent.add(event) {
+			return
+		}
+
+		r.slogger.Log(ctx, slog.LevelWarn,
+			"osquery watchdog killed worker",
+			"worker_pid", event.WorkerPid,
+			"limit", event.Limit,
+			"message", event.Message,
+			"pending_distributed_queries", event.PendingDistributedQueries,
+		)
+		notify(ctx, r.slogger, event)
+		return
+	}
+
+	if matches := failedQueryRegex.FindStringSubmatch(line); matches != nil {
+		event, ok := recent.attribute(matches[1], at)
+		if !ok {
+			return
+		}
+
+		r.slogger.Log(ctx, slog.LevelWarn,
+			"osquery blamed scheduled query for watchdog kill",
+			"worker_pid", event.WorkerPid,
+			"scheduled_query", event.ScheduledQuery,
+		)
+		notify(ctx, r.slogger, event)
+	}
+}
+
+func limitFromMessage(msg string) string {
+	switch {
+	case strings.Contains(msg, "Memory limits exceeded"):
+		return LimitMemory
+	case strings.Contains(msg, "CPU utilization limit exceeded"):
+		return LimitCpu
+	default:
+		return ""
+	}
+}
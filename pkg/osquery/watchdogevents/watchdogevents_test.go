@@ -0,0 +1,83 @@
+package watchdogevents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/stretchr/testify/require"
+)
+
+// These tests share the package's recent history, so they don't run in parallel.
+
+func TestObserve(t *testing.T) {
+	recent = &history{}
+
+	r := NewRecorder(multislogger.NewNopLogger())
+	r.SetPendingQueriesFunc(func() []string { return []string{"kolide_distributed_1"} })
+
+	killedAt := time.Now()
+	r.Observe(context.TODO(), "I1018 09:00:00.000000 1234 watcher.cpp:321] Created and monitoring extension child (5678)", killedAt)
+	r.Observe(context.TODO(), "W1018 09:00:01.000000 1234 watcher.cpp:415] osqueryd worker (5678) stopping: Memory limits exceeded: 412532736", killedAt)
+	// The same kill, reported again through the status logs, isn't recorded twice
+	r.Observe(context.TODO(), "osqueryd worker (5678) stopping: Memory limits exceeded: 412532736", killedAt.Add(time.Second))
+
+	events := Recent()
+	require.Len(t, events, 1)
+	require.NotEmpty(t, events[0].ID)
+	require.Equal(t, 5678, events[0].WorkerPid)
+	require.Equal(t, LimitMemory, events[0].Limit)
+	require.Equal(t, "Memory limits exceeded: 412532736", events[0].Message)
+	require.Equal(t, []string{"kolide_distributed_1"}, events[0].PendingDistributedQueries)
+	require.Empty(t, events[0].ScheduledQuery)
+
+	// Once the restarted worker blames a scheduled query, it's attributed to the kill -- but only once
+	r.Observe(context.TODO(), "W1018 09:00:05.000000 9012 scheduler.cpp:102] Scheduled query may have failed: pack:kolide_log_pipeline:processes", killedAt.Add(5*time.Second))
+	r.Observe(context.TODO(), "Scheduled query may have failed: pack:kolide_log_pipeline:users", killedAt.Add(6*time.Second))
+
+	events = Recent()
+	require.Len(t, events, 1)
+	require.Equal(t, "pack:kolide_log_pipeline:processes", events[0].ScheduledQuery)
+}
+
+func TestObserve_cpuLimit(t *testing.T) {
+	recent = &history{}
+
+	r := NewRecorder(multislogger.NewNopLogger())
+	killedAt := time.Now()
+	r.Observe(context.TODO(), "osqueryd worker (4321) stopping: Maximum sustainable CPU utilization limit exceeded: 12\nsome other output", killedAt)
+
+	events := Recent()
+	require.Len(t, events, 1)
+	require.Equal(t, LimitCpu, events[0].Limit)
+	require.Nil(t, events[0].PendingDistributedQueries)
+
+	// A failed query reported long after the kill isn't attributed to it
+	r.Observe(context.TODO(), "Scheduled query may have failed: pack:kolide_log_pipeline:processes", killedAt.Add(attributionWindow+time.Minute))
+	require.Empty(t, Recent()[0].ScheduledQuery)
+}
+
+func TestSubscribe(t *testing.T) {
+	recent = &history{}
+
+	received := make(chan Event, 2)
+	Subscribe(func(e Event) { received <- e })
+
+	r := NewRecorder(multislogger.NewNopLogger())
+	r.Observe(context.TODO(), "osqueryd worker (2468) stopping: Memory limits exceeded: 1", time.Now())
+	r.Observe(context.TODO(), "Scheduled query may have failed: kolide_expensive", time.Now())
+
+	// Subscribers are notified in their own goroutines, so the notifications may arrive in any order
+	var queries []string
+	for range 2 {
+		select {
+		case e := <-received:
+			require.Equal(t, 2468, e.WorkerPid)
+			queries = append(queries, e.ScheduledQuery)
+		case <-time.After(5 * time.Second):
+			t.Fatal("subscriber was not notified")
+		}
+	}
+	require.ElementsMatch(t, []string{"", "kolide_expensive"}, queries)
+}
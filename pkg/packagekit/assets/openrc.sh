@@ -0,0 +1,19 @@
+#!/sbin/openrc-run
+
+name="{{.Common.Identifier}}"
+description="{{.Common.Description}}"
+
+command="{{.Common.Path}}"
+command_args="{{ StringsJoin .Common.Flags " " }}"
+command_background=true
+pidfile="/run/${RC_SVCNAME}.pid"
+output_log="/var/log/${RC_SVCNAME}.log"
+error_log="/var/log/${RC_SVCNAME}.log"
+{{- range $key, $value := .Common.Environment }}
+export {{$key}}={{$value}}
+{{- end }}
+
+depend() {
+    need net
+    after firewall
+}
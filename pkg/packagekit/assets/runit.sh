@@ -0,0 +1,11 @@
+#!/bin/sh
+
+# runit service for {{.Common.Identifier}}. runsv expects this
+# script to exec the daemon in the foreground.
+exec 2>&1
+{{- range $key, $value := .Common.Environment }}
+{{$key}}={{$value}}
+export {{$key}}
+{{- end }}
+
+exec "{{.Common.Path}}" {{ StringsJoin .Common.Flags " \\\n    " }}
@@ -1,5 +1,7 @@
 package packagekit
 
+import "time"
+
 // PackageOptions is the superset of all packaging options. Not all
 // packages will support all options.
 type PackageOptions struct {
@@ -12,6 +14,8 @@ type PackageOptions struct {
 	VersionNum int    // package version in numeric format. used to create comparable windows registry keys
 	FlagFile   string // Path to the flagfile for configuration
 
+	SourceDateEpoch time.Time // Fixed timestamp for reproducible builds. If zero, builds aren't reproducible.
+
 	DisableService bool // Whether to install a system service in a disabled state
 
 	AppleNotarizeAccountId   string   // The 10 character apple account id
@@ -96,6 +96,10 @@ func PackageFPM(ctx context.Context, w io.Writer, po *PackageOptions, fpmOpts ..
 		return err
 	}
 
+	if err := normalizeRoot(po); err != nil {
+		return err
+	}
+
 	outputFilename := fmt.Sprintf("%s-%s.%s", po.Name, po.Version, f.outputType)
 
 	outputPathDir, err := os.MkdirTemp("", "packaging-fpm-output")
@@ -104,6 +108,48 @@ func PackageFPM(ctx context.Context, w io.Writer, po *PackageOptions, fpmOpts ..
 	}
 	defer os.RemoveAll(outputPathDir)
 
+	cmd := exec.CommandContext(ctx, "docker", append(dockerArgs(po, outputPathDir), fpmCommand(po, f, outputFilename)...)...) //nolint:forbidigo // Fine to use exec.CommandContext outside of launcher proper
+
+	stderr := new(bytes.Buffer)
+	stdout := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	cmd.Stdout = stdout
+
+	level.Debug(logger).Log(
+		"msg", "Running fpm",
+		"cmd", strings.Join(cmd.Args, " "),
+	)
+
+	if err := cmd.Run(); err != nil {
+		level.Error(logger).Log(
+			"msg", "Error running fpm",
+			"err", err,
+			"cmd", strings.Join(cmd.Args, " "),
+			"stderr", stderr,
+			"stdout", stdout,
+		)
+		return fmt.Errorf("creating fpm package: %s: %w", stderr, err)
+	}
+	level.Debug(logger).Log("msg", "fpm exited cleanly")
+
+	outputFH, err := os.Open(filepath.Join(outputPathDir, outputFilename))
+	if err != nil {
+		return fmt.Errorf("opening resultant output file: %w", err)
+	}
+	defer outputFH.Close()
+
+	level.Debug(logger).Log("msg", "Copying fpm built to remote filehandle")
+	if _, err := io.Copy(w, outputFH); err != nil {
+		return fmt.Errorf("copying output: %w", err)
+	}
+
+	SetInContext(ctx, ContextLauncherVersionKey, po.Version)
+
+	return nil
+}
+
+// fpmCommand returns the fpm command line, as run in the fpm container.
+func fpmCommand(po *PackageOptions, f fpmOptions, outputFilename string) []string {
 	fpmCommand := []string{
 		"fpm",
 		"-s", "dir",
@@ -134,51 +180,44 @@ func PackageFPM(ctx context.Context, w io.Writer, po *PackageOptions, fpmOpts ..
 		fpmCommand = append(fpmCommand, "--before-remove", filepath.Join("/pkgscripts", "prerm"))
 	}
 
-	dockerArgs := []string{
+	// For reproducible builds, fpm clamps file times to SOURCE_DATE_EPOCH, and uses it as the
+	// build time. Ownership and rpm's build host would otherwise come from the build machine.
+	if po.reproducible() {
+		fpmCommand = append(fpmCommand, "--source-date-epoch-default", po.sourceDateEpoch())
+
+		switch f.outputType {
+		case Deb:
+			fpmCommand = append(fpmCommand, "--deb-user", "root", "--deb-group", "root")
+		case RPM:
+			fpmCommand = append(fpmCommand,
+				"--rpm-user", "root",
+				"--rpm-group", "root",
+				"--rpm-rpmbuild-define", "_buildhost reproducible",
+				"--rpm-rpmbuild-define", "use_source_date_epoch_as_buildtime 1",
+				"--rpm-rpmbuild-define", "clamp_mtime_to_source_date_epoch 1",
+			)
+		}
+	}
+
+	return fpmCommand
+}
+
+// dockerArgs returns the arguments to run the fpm container, with the package root, scripts,
+// and output directories mounted.
+func dockerArgs(po *PackageOptions, outputPathDir string) []string {
+	args := []string{
 		"run", "--rm",
 		"-v", fmt.Sprintf("%s:/pkgsrc", po.Root),
 		"-v", fmt.Sprintf("%s:/pkgscripts", po.Scripts),
 		"-v", fmt.Sprintf("%s:/out", outputPathDir),
-		"--entrypoint", "", // override this, to ensure more compatibility with the plain command line
-		"kolide/fpm:latest",
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", append(dockerArgs, fpmCommand...)...) //nolint:forbidigo // Fine to use exec.CommandContext outside of launcher proper
-
-	stderr := new(bytes.Buffer)
-	stdout := new(bytes.Buffer)
-	cmd.Stderr = stderr
-	cmd.Stdout = stdout
-
-	level.Debug(logger).Log(
-		"msg", "Running fpm",
-		"cmd", strings.Join(cmd.Args, " "),
-	)
-
-	if err := cmd.Run(); err != nil {
-		level.Error(logger).Log(
-			"msg", "Error running fpm",
-			"err", err,
-			"cmd", strings.Join(cmd.Args, " "),
-			"stderr", stderr,
-			"stdout", stdout,
-		)
-		return fmt.Errorf("creating fpm package: %s: %w", stderr, err)
+	if po.reproducible() {
+		args = append(args, "-e", "SOURCE_DATE_EPOCH="+po.sourceDateEpoch())
 	}
-	level.Debug(logger).Log("msg", "fpm exited cleanly")
 
-	outputFH, err := os.Open(filepath.Join(outputPathDir, outputFilename))
-	if err != nil {
-		return fmt.Errorf("opening resultant output file: %w", err)
-	}
-	defer outputFH.Close()
-
-	level.Debug(logger).Log("msg", "Copying fpm built to remote filehandle")
-	if _, err := io.Copy(w, outputFH); err != nil {
-		return fmt.Errorf("copying output: %w", err)
-	}
-
-	SetInContext(ctx, ContextLauncherVersionKey, po.Version)
-
-	return nil
+	return append(args,
+		"--entrypoint", "", // override this, to ensure more compatibility with the plain command line
+		"kolide/fpm:latest",
+	)
 }
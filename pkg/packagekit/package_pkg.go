@@ -30,6 +30,10 @@ func PackagePkg(ctx context.Context, w io.Writer, po *PackageOptions, arch strin
 		return err
 	}
 
+	if err := normalizeRoot(po); err != nil {
+		return err
+	}
+
 	outputPathDir, err := os.MkdirTemp("", "packaging-pkg-output")
 	if err != nil {
 		return fmt.Errorf("making TempDir: %w", err)
@@ -0,0 +1,28 @@
+package packagekit
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opencensus.io/trace"
+)
+
+// PackageTar writes the package root to w as a tar archive. Unlike the other Linux packages,
+// it's built here rather than by fpm, so that it's reproducible when SourceDateEpoch is set.
+func PackageTar(ctx context.Context, w io.Writer, po *PackageOptions) error {
+	ctx, span := trace.StartSpan(ctx, "packagekit.PackageTar")
+	defer span.End()
+
+	if err := isDirectory(po.Root); err != nil {
+		return err
+	}
+
+	if err := writeTar(w, po.Root, po.SourceDateEpoch); err != nil {
+		return fmt.Errorf("writing tar: %w", err)
+	}
+
+	SetInContext(ctx, ContextLauncherVersionKey, po.Version)
+
+	return nil
+}
@@ -53,6 +53,10 @@ func PackageWixMSI(ctx context.Context, w io.Writer, po *PackageOptions, include
 		return err
 	}
 
+	if err := normalizeRoot(po); err != nil {
+		return err
+	}
+
 	// populate VersionNum if it isn't already set by the caller. we'll
 	// store this in the registry on install to give a comparable field
 	// for intune to drive upgrade behavior from
@@ -68,26 +72,38 @@ func PackageWixMSI(ctx context.Context, w io.Writer, po *PackageOptions, include
 	// how versions and builds are calculated. See
 	// https://www.firegiant.com/wix/tutorial/upgrades-and-modularization/
 	// for opinionated background
-	guidNonce, err := uuid.NewRandom()
+	//
+	// For reproducible builds, the nonce is instead a digest of the package's contents, so that
+	// rebuilding the same files produces the same codes, but any change still triggers the Major
+	// Upgrade flow.
+	guidNonce, err := msiGuidNonce(po)
 	if err != nil {
-		return fmt.Errorf("generating uuid as guid nonce: %w", err)
-
+		return fmt.Errorf("generating guid nonce: %w", err)
 	}
 	extraGuidIdentifiers := []string{
 		po.Version,
 		runtime.GOARCH,
-		guidNonce.String(),
+		guidNonce,
+	}
+
+	// The package code identifies this particular MSI file. Without reproducible builds, wix
+	// generates a random one.
+	packageCode := "*"
+	if po.reproducible() {
+		packageCode = generateMicrosoftProductCode("launcher_package"+po.Identifier, extraGuidIdentifiers...)
 	}
 
 	var templateData = struct {
 		Opts            *PackageOptions
 		UpgradeCode     string
 		ProductCode     string
+		PackageCode     string
 		PermissionsGUID string
 	}{
 		Opts:        po,
 		UpgradeCode: generateMicrosoftProductCode("launcher" + po.Identifier),
 		ProductCode: generateMicrosoftProductCode("launcher"+po.Identifier, extraGuidIdentifiers...),
+		PackageCode: packageCode,
 		// our permissions component does not meet the criteria to have it's GUID automatically generated - but we should
 		// ensure it is unique for each build so we regenerate here alongside the product and upgrade codes
 		PermissionsGUID: generateMicrosoftProductCode("launcher_root_dir_permissions"+po.Identifier, extraGuidIdentifiers...),
@@ -113,6 +129,10 @@ func PackageWixMSI(ctx context.Context, w io.Writer, po *PackageOptions, include
 		wixArgs = append(wixArgs, wix.WithWix(po.WixPath))
 	}
 
+	if po.reproducible() {
+		wixArgs = append(wixArgs, wix.WithStableGuids("launcher"+po.Identifier+guidNonce))
+	}
+
 	{
 		// Regardless of whether or not there's a UI in the MSI, we
 		// still want the icon file to be included.
@@ -192,6 +212,24 @@ func PackageWixMSI(ctx context.Context, w io.Writer, po *PackageOptions, include
 	return nil
 }
 
+// msiGuidNonce returns the nonce that makes each MSI build's product code unique. It's random,
+// unless the build is reproducible.
+func msiGuidNonce(po *PackageOptions) (string, error) {
+	if po.reproducible() {
+		digest, err := treeDigest(po.Root)
+		if err != nil {
+			return "", fmt.Errorf("computing digest of package root: %w", err)
+		}
+		return digest, nil
+	}
+
+	guidNonce, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("generating uuid as guid nonce: %w", err)
+	}
+	return guidNonce.String(), nil
+}
+
 // getSigntoolPath attempts to look up the location of signtool so that
 // we do not have to rely on a hard-coded signtool location that will change
 // when we upgrade to a new version of signtool.
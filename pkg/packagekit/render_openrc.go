@@ -0,0 +1,38 @@
+package packagekit
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"go.opencensus.io/trace"
+)
+
+//go:embed assets/openrc.sh
+var openrcTemplate []byte
+
+// RenderOpenRC renders an OpenRC service script, suitable for
+// /etc/init.d on distros that use OpenRC (eg: Alpine, Gentoo)
+func RenderOpenRC(ctx context.Context, w io.Writer, initOptions *InitOptions) error {
+	_, span := trace.StartSpan(ctx, "packagekit.RenderOpenRC")
+	defer span.End()
+
+	var data = struct {
+		Common InitOptions
+	}{
+		Common: *initOptions,
+	}
+
+	funcsMap := template.FuncMap{
+		"StringsJoin": strings.Join,
+	}
+
+	t, err := template.New("openrc").Funcs(funcsMap).Parse(string(openrcTemplate))
+	if err != nil {
+		return fmt.Errorf("not able to parse openrc template: %w", err)
+	}
+	return t.ExecuteTemplate(w, "openrc", data)
+}
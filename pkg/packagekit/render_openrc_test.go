@@ -0,0 +1,46 @@
+package packagekit
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderOpenRCEmpty(t *testing.T) {
+	t.Parallel()
+
+	expectedOutputStrings := []string{
+		`#!/sbin/openrc-run`,
+		`name="empty"`,
+	}
+
+	var output bytes.Buffer
+	err := RenderOpenRC(context.TODO(), &output, emptyInitOptions())
+	require.NoError(t, err)
+
+	for _, s := range expectedOutputStrings {
+		require.Contains(t, output.String(), s)
+	}
+
+}
+
+func TestRenderOpenRCComplex(t *testing.T) {
+	t.Parallel()
+
+	expectedOutputStrings := []string{
+		`command="/usr/local/kolide-app/bin/launcher"`,
+		`export KOLIDE_LAUNCHER_OSQUERYD_PATH=/usr/local/kolide-app/bin/osqueryd`,
+		`command_args="--autoupdate --with_initial_runner"`,
+	}
+
+	var output bytes.Buffer
+	err := RenderOpenRC(context.TODO(), &output, complexInitOptions())
+	require.NoError(t, err)
+
+	for _, s := range expectedOutputStrings {
+		require.Contains(t, output.String(), s)
+	}
+
+}
@@ -0,0 +1,39 @@
+package packagekit
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"go.opencensus.io/trace"
+)
+
+//go:embed assets/runit.sh
+var runitTemplate []byte
+
+// RenderRunit renders the `run` script for a runit service
+// directory. The service directory itself is expected to be linked
+// into the runsvdir by the postinstall script.
+func RenderRunit(ctx context.Context, w io.Writer, initOptions *InitOptions) error {
+	_, span := trace.StartSpan(ctx, "packagekit.RenderRunit")
+	defer span.End()
+
+	var data = struct {
+		Common InitOptions
+	}{
+		Common: *initOptions,
+	}
+
+	funcsMap := template.FuncMap{
+		"StringsJoin": strings.Join,
+	}
+
+	t, err := template.New("runit").Funcs(funcsMap).Parse(string(runitTemplate))
+	if err != nil {
+		return fmt.Errorf("not able to parse runit template: %w", err)
+	}
+	return t.ExecuteTemplate(w, "runit", data)
+}
@@ -0,0 +1,46 @@
+package packagekit
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderRunitEmpty(t *testing.T) {
+	t.Parallel()
+
+	expectedOutputStrings := []string{
+		`#!/bin/sh`,
+		`exec "/dev/null"`,
+	}
+
+	var output bytes.Buffer
+	err := RenderRunit(context.TODO(), &output, emptyInitOptions())
+	require.NoError(t, err)
+
+	for _, s := range expectedOutputStrings {
+		require.Contains(t, output.String(), s)
+	}
+
+}
+
+func TestRenderRunitComplex(t *testing.T) {
+	t.Parallel()
+
+	expectedOutputStrings := []string{
+		`exec "/usr/local/kolide-app/bin/launcher"`,
+		`export KOLIDE_LAUNCHER_OSQUERYD_PATH`,
+		`--with_initial_runner`,
+	}
+
+	var output bytes.Buffer
+	err := RenderRunit(context.TODO(), &output, complexInitOptions())
+	require.NoError(t, err)
+
+	for _, s := range expectedOutputStrings {
+		require.Contains(t, output.String(), s)
+	}
+
+}
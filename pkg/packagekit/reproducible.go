@@ -0,0 +1,194 @@
+package packagekit
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// reproducible reports whether the package should be built reproducibly. Given the same package
+// root, options, and SourceDateEpoch, the packagers then aim to produce the same bytes: file
+// times are set to SourceDateEpoch, archives list their files in sorted order and owned by root,
+// and identifiers that would otherwise be random are derived from the package's contents. See
+// https://reproducible-builds.org/docs/.
+//
+// How far this goes depends on the format, as most are built by external tools:
+//
+//   - tar is built here, and is byte-reproducible.
+//   - deb, rpm, and pacman are byte-reproducible, given the same fpm image, as fpm and rpmbuild
+//     honor SOURCE_DATE_EPOCH.
+//   - msi gets stable product, package, and component codes, but light.exe records the build
+//     time in the summary information.
+//   - pkg gets stable file times, but pkgbuild records the build time in the archive's table of
+//     contents, and signatures aren't reproducible.
+func (po *PackageOptions) reproducible() bool {
+	return !po.SourceDateEpoch.IsZero()
+}
+
+// sourceDateEpoch returns SourceDateEpoch in the SOURCE_DATE_EPOCH format, seconds since the
+// Unix epoch.
+func (po *PackageOptions) sourceDateEpoch() string {
+	return strconv.FormatInt(po.SourceDateEpoch.Unix(), 10)
+}
+
+// normalizeRoot prepares the package root for a reproducible build, if requested.
+func normalizeRoot(po *PackageOptions) error {
+	if !po.reproducible() {
+		return nil
+	}
+	if err := NormalizeTimes(po.Root, po.SourceDateEpoch); err != nil {
+		return fmt.Errorf("normalizing times in package root: %w", err)
+	}
+	return nil
+}
+
+// NormalizeTimes sets the access and modification times of the files and directories under
+// root to t, so that packaging tools that copy them record the same times on every build.
+// Symlinks are left alone, as their times can't be set portably.
+func NormalizeTimes(root string, t time.Time) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		if err := os.Chtimes(path, t, t); err != nil {
+			return fmt.Errorf("setting times on %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// treeDigest returns a hex-encoded sha256 digest of the files under root: their paths, types,
+// permissions, symlink targets, and contents, in sorted order. Times and owners aren't
+// included, so it's the same for the same files on any build machine.
+func treeDigest(root string) (string, error) {
+	h := sha256.New()
+
+	if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", path, err)
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("relative path for %s: %w", path, err)
+		}
+
+		fmt.Fprintf(h, "%s\x00%o\x00", filepath.ToSlash(rel), info.Mode()&(fs.ModeType|fs.ModePerm))
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("reading link %s: %w", path, err)
+			}
+			fmt.Fprintf(h, "%s\x00", filepath.ToSlash(target))
+		case info.Mode().IsRegular():
+			fh, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("opening %s: %w", path, err)
+			}
+			defer fh.Close()
+
+			fmt.Fprintf(h, "%d\x00", info.Size())
+			if _, err := io.Copy(h, fh); err != nil {
+				return fmt.Errorf("reading %s: %w", path, err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeTar writes the files under root to w as a tar archive. Entries are in sorted order and
+// owned by root. If mtime is set, every entry has that time, and group and world write
+// permissions -- which depend on the build machine's umask -- are dropped, so that the archive
+// is reproducible.
+func writeTar(w io.Writer, root string, mtime time.Time) error {
+	tw := tar.NewWriter(w)
+
+	if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", path, err)
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return fmt.Errorf("reading link %s: %w", path, err)
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("making tar header for %s: %w", path, err)
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("relative path for %s: %w", path, err)
+		}
+		hdr.Name = "./" + filepath.ToSlash(rel)
+		if rel == "." {
+			hdr.Name = "./"
+		} else if d.IsDir() {
+			hdr.Name += "/"
+		}
+
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "root", "root"
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		if !mtime.IsZero() {
+			hdr.ModTime = mtime
+			hdr.Mode &^= 0o022
+		}
+		hdr.ModTime = hdr.ModTime.Truncate(time.Second)
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("writing tar header for %s: %w", path, err)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		fh, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", path, err)
+		}
+		defer fh.Close()
+
+		if _, err := io.Copy(tw, fh); err != nil {
+			return fmt.Errorf("writing %s to tar: %w", path, err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
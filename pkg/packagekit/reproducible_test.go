@@ -0,0 +1,140 @@
+package packagekit
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupReproducibleRoot(t *testing.T) string {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "usr", "local", "bin"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc", "kolide-k2"), 0775))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "usr", "local", "bin", "launcher"), []byte("launcher binary"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "etc", "kolide-k2", "launcher.flags"), []byte("hostname example.com\n"), 0664))
+	require.NoError(t, os.Symlink("launcher", filepath.Join(root, "usr", "local", "bin", "launcher-link")))
+
+	return root
+}
+
+func TestPackageTarReproducible(t *testing.T) {
+	t.Parallel()
+
+	epoch := time.Unix(1700000000, 0).UTC()
+
+	build := func() []byte {
+		po := &PackageOptions{
+			Name:            "launcher",
+			Identifier:      "kolide-k2",
+			Version:         "1.2.3",
+			Root:            setupReproducibleRoot(t),
+			SourceDateEpoch: epoch,
+		}
+
+		var out bytes.Buffer
+		require.NoError(t, PackageTar(context.TODO(), &out, po))
+		return out.Bytes()
+	}
+
+	first := build()
+	// Make sure the second build's files have different times
+	time.Sleep(1100 * time.Millisecond)
+	second := build()
+	require.Equal(t, first, second, "tar output should be identical across builds")
+
+	tr := tar.NewReader(bytes.NewReader(first))
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		names = append(names, hdr.Name)
+		require.True(t, hdr.ModTime.Equal(epoch), "%s has mtime %s", hdr.Name, hdr.ModTime)
+		require.Equal(t, 0, hdr.Uid)
+		require.Equal(t, "root", hdr.Uname)
+		require.Zero(t, hdr.Mode&0o022, "%s should not be group or world writable", hdr.Name)
+	}
+
+	require.Equal(t, []string{
+		"./",
+		"./etc/",
+		"./etc/kolide-k2/",
+		"./etc/kolide-k2/launcher.flags",
+		"./usr/",
+		"./usr/local/",
+		"./usr/local/bin/",
+		"./usr/local/bin/launcher",
+		"./usr/local/bin/launcher-link",
+	}, names)
+}
+
+func TestNormalizeTimes(t *testing.T) {
+	t.Parallel()
+
+	root := setupReproducibleRoot(t)
+	epoch := time.Unix(1700000000, 0)
+
+	require.NoError(t, NormalizeTimes(root, epoch))
+
+	for _, p := range []string{".", "usr/local/bin", "usr/local/bin/launcher", "etc/kolide-k2/launcher.flags"} {
+		info, err := os.Stat(filepath.Join(root, p))
+		require.NoError(t, err)
+		require.True(t, info.ModTime().Equal(epoch), "%s has mtime %s", p, info.ModTime())
+	}
+}
+
+func TestTreeDigest(t *testing.T) {
+	t.Parallel()
+
+	first, err := treeDigest(setupReproducibleRoot(t))
+	require.NoError(t, err)
+
+	second, err := treeDigest(setupReproducibleRoot(t))
+	require.NoError(t, err)
+	require.Equal(t, first, second, "same files should have the same digest")
+
+	changed := setupReproducibleRoot(t)
+	require.NoError(t, os.WriteFile(filepath.Join(changed, "usr", "local", "bin", "launcher"), []byte("new launcher binary"), 0755))
+	third, err := treeDigest(changed)
+	require.NoError(t, err)
+	require.NotEqual(t, first, third, "changed contents should change the digest")
+}
+
+func TestFpmCommandReproducible(t *testing.T) {
+	t.Parallel()
+
+	po := &PackageOptions{
+		Name:       "launcher",
+		Identifier: "kolide-k2",
+		Version:    "1.2.3",
+		Root:       t.TempDir(),
+		Scripts:    t.TempDir(),
+	}
+
+	f := fpmOptions{outputType: Deb, arch: "amd64"}
+	require.NotContains(t, fpmCommand(po, f, "out.deb"), "--source-date-epoch-default")
+	require.NotContains(t, dockerArgs(po, "/tmp/out"), "SOURCE_DATE_EPOCH=1700000000")
+
+	po.SourceDateEpoch = time.Unix(1700000000, 0)
+
+	debCommand := fpmCommand(po, f, "out.deb")
+	require.Contains(t, debCommand, "--source-date-epoch-default")
+	require.Contains(t, debCommand, "1700000000")
+	require.Contains(t, debCommand, "--deb-user")
+	require.Contains(t, dockerArgs(po, "/tmp/out"), "SOURCE_DATE_EPOCH=1700000000")
+
+	rpmCommand := fpmCommand(po, fpmOptions{outputType: RPM, arch: "amd64"}, "out.rpm")
+	require.Contains(t, rpmCommand, "--rpm-rpmbuild-define")
+	require.NotContains(t, rpmCommand, "--deb-user")
+}
@@ -8,10 +8,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 
 	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
 	"github.com/kolide/kit/fsutil"
 	"github.com/kolide/kit/ulid"
 	"github.com/kolide/launcher/pkg/contexts/ctxlog"
@@ -31,6 +33,7 @@ type wixTool struct {
 	ui              bool       // whether or not to include a ui
 	extraFiles      []extraFile
 	identifier      string // the package identifier used for directory path creation (e.g. kolide-k2)
+	guidNamespace   string // if set, GUIDs and ids are derived from this, rather than random
 
 	execCC func(context.Context, string, ...string) *exec.Cmd // Allows test overrides
 }
@@ -104,6 +107,15 @@ func SkipCleanup() WixOpt {
 	}
 }
 
+// WithStableGuids derives the GUIDs and ids that would otherwise be random -- those of the
+// components heat generates, and of services -- from namespace, so that building the same
+// files produces the same MSI.
+func WithStableGuids(namespace string) WixOpt {
+	return func(wo *wixTool) {
+		wo.guidNamespace = namespace
+	}
+}
+
 // New takes a packageRoot of files, and a wxsContent of xml wix
 // configuration, and will return a struct with methods for building
 // packages with.
@@ -191,6 +203,10 @@ func (wo *wixTool) Package(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("running heat: %w", err)
 	}
 
+	if err := wo.stabilizeGuids(); err != nil {
+		return "", fmt.Errorf("stabilizing guids: %w", err)
+	}
+
 	if err := wo.addServices(ctx); err != nil {
 		return "", fmt.Errorf("adding services: %w", err)
 	}
@@ -288,7 +304,7 @@ func (wo *wixTool) addServices(ctx context.Context) error {
 				}
 
 				// make sure elements are not duplicated in any service
-				serviceId := fmt.Sprintf("%s%s", baseSvcName, ulid.New())
+				serviceId := fmt.Sprintf("%s%s", baseSvcName, wo.uniqueId(string(currentArchSpecificBinDir)+line))
 				service.serviceControl.Id = serviceId
 				service.serviceInstall.Id = serviceId
 				service.serviceInstall.ServiceConfig.Id = serviceId
@@ -328,6 +344,57 @@ func (wo *wixTool) addServices(ctx context.Context) error {
 	return nil
 }
 
+// componentGuidRegex matches a component's Guid attribute, capturing the component's Id. heat
+// writes the Id first.
+var componentGuidRegex = regexp.MustCompile(`(<Component\s[^>]*?Id="([^"]+)"[^>]*?\sGuid=")[^"]*(")`)
+
+// stabilizeGuids replaces the random component GUIDs heat generates with ones derived from the
+// component ids, which heat derives from the files' paths. It's a no-op unless a GUID namespace
+// is set.
+func (wo *wixTool) stabilizeGuids() error {
+	if wo.guidNamespace == "" {
+		return nil
+	}
+
+	for _, wxsFile := range []string{"AppFiles.wxs", "AppData.wxs"} {
+		wxsPath := filepath.Join(wo.buildDir, wxsFile)
+		content, err := os.ReadFile(wxsPath)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", wxsFile, err)
+		}
+
+		if err := os.WriteFile(wxsPath, stableComponentGuids(content, wo.guidNamespace), 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", wxsFile, err)
+		}
+	}
+
+	return nil
+}
+
+// stableComponentGuids replaces each component's GUID in the wxs content with one derived from
+// namespace and the component's Id.
+func stableComponentGuids(wxs []byte, namespace string) []byte {
+	return componentGuidRegex.ReplaceAllFunc(wxs, func(match []byte) []byte {
+		groups := componentGuidRegex.FindSubmatch(match)
+		guid := "{" + stableGuid(namespace, string(groups[2])) + "}"
+		return append(append(append([]byte{}, groups[1]...), guid...), groups[3]...)
+	})
+}
+
+// uniqueId returns a suffix to keep an element's id unique. It's random, unless a GUID
+// namespace is set, in which case it's derived from seed.
+func (wo *wixTool) uniqueId(seed string) string {
+	if wo.guidNamespace == "" {
+		return ulid.New()
+	}
+	return strings.ReplaceAll(stableGuid(wo.guidNamespace, seed), "-", "")
+}
+
+func stableGuid(namespace, name string) string {
+	space := uuid.NewSHA1(uuid.Nil, []byte(namespace))
+	return strings.ToUpper(uuid.NewSHA1(space, []byte(name)).String())
+}
+
 // setupDataDir handles the windows data directory setup by pre-creating any files
 // that we want to ensure are cleaned up on uninstall.
 // this is handled before the other heat/candle/light calls because we must issue
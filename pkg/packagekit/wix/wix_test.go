@@ -98,3 +98,25 @@ func setupPackageRoot(packageRoot string) error {
 	}
 	return nil
 }
+
+func TestStableComponentGuids(t *testing.T) {
+	t.Parallel()
+
+	wxs := []byte(`<Wix>
+  <Component Id="cmp1A2B" Directory="dir1" Guid="{11111111-1111-1111-1111-111111111111}">
+    <File Id="fil1A2B" KeyPath="yes" Source="SourceDir\launcher.exe" />
+  </Component>
+  <Component Id="cmp3C4D" Guid="{22222222-2222-2222-2222-222222222222}" Directory="dir1">
+    <File Id="fil3C4D" KeyPath="yes" Source="SourceDir\osqueryd.exe" />
+  </Component>
+</Wix>`)
+
+	first := stableComponentGuids(wxs, "launcher-kolide-k2")
+	require.NotContains(t, string(first), "11111111-1111-1111-1111-111111111111")
+	require.NotContains(t, string(first), "22222222-2222-2222-2222-222222222222")
+	require.Contains(t, string(first), `Guid="{`+stableGuid("launcher-kolide-k2", "cmp1A2B")+`}"`)
+	require.Contains(t, string(first), `Guid="{`+stableGuid("launcher-kolide-k2", "cmp3C4D")+`}"`)
+
+	require.Equal(t, first, stableComponentGuids(wxs, "launcher-kolide-k2"), "guids should be stable")
+	require.NotEqual(t, first, stableComponentGuids(wxs, "launcher-other"), "guids should depend on the namespace")
+}
@@ -0,0 +1,16 @@
+#!/bin/sh
+
+if [ ! -z "{{.InfoFilename}}" ]; then
+    cat <<EOF > "{{.InfoFilename}}"
+{{.InfoJson}}
+EOF
+fi
+
+# tar packages have no configure step, so rather than checking $1,
+# we only check that the service script is present.
+if [ -e "{{.Path}}" ]; then
+    chmod 755 "{{.Path}}"
+    rc-update add "launcher-{{.Identifier}}" default >/dev/null
+    set -e
+    rc-service "launcher-{{.Identifier}}" restart
+fi
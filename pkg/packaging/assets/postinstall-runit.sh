@@ -0,0 +1,24 @@
+#!/bin/sh
+
+if [ ! -z "{{.InfoFilename}}" ]; then
+    cat <<EOF > "{{.InfoFilename}}"
+{{.InfoJson}}
+EOF
+fi
+
+# Distros don't agree on where runsvdir looks for services, so link
+# into the first one that exists. runsvdir notices new links within a
+# few seconds, so a fresh install needs no explicit start.
+if [ -e "{{.Path}}" ]; then
+    chmod 755 "{{.Path}}"
+    for svdir in /var/service /etc/service /etc/runit/runsvdir/default; do
+        if [ -d "$svdir" ]; then
+            if [ ! -e "$svdir/launcher-{{.Identifier}}" ]; then
+                ln -s "{{ StringsTrimSuffix .Path "/run" }}" "$svdir/launcher-{{.Identifier}}"
+            else
+                sv restart "launcher-{{.Identifier}}" || true
+            fi
+            break
+        fi
+    done
+fi
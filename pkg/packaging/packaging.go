@@ -12,8 +12,10 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/kolide/kit/fsutil"
 	"github.com/kolide/launcher/pkg/launcher"
@@ -60,6 +62,7 @@ type PackageOptions struct {
 	MSIUI             bool
 	WixSkipCleanup    bool
 	DisableService    bool
+	SourceDateEpoch   time.Time // Fixed timestamp for reproducible builds. If zero, builds aren't reproducible.
 
 	AppleNotarizeAccountId   string   // The 10 character apple account id
 	AppleNotarizeAppPassword string   // app password for notarization service
@@ -187,8 +190,14 @@ func (p *PackageOptions) Build(ctx context.Context, packageWriter io.Writer, tar
 			return fmt.Errorf("failed to write %s to flagfile: %w", k, err)
 		}
 	}
-	for k, v := range launcherMapFlags {
-		if _, err := flagFile.WriteString(fmt.Sprintf("%s %s\n", k, v)); err != nil {
+	// Sort the map flags, so the flagfile is the same from build to build
+	mapFlagKeys := make([]string, 0, len(launcherMapFlags))
+	for k := range launcherMapFlags {
+		mapFlagKeys = append(mapFlagKeys, k)
+	}
+	sort.Strings(mapFlagKeys)
+	for _, k := range mapFlagKeys {
+		if _, err := flagFile.WriteString(fmt.Sprintf("%s %s\n", k, launcherMapFlags[k])); err != nil {
 			return fmt.Errorf("failed to write %s to flagfile: %w", k, err)
 		}
 	}
@@ -329,6 +338,7 @@ func (p *PackageOptions) Build(ctx context.Context, packageWriter io.Writer, tar
 		WixUI:                    p.MSIUI,
 		WixSkipCleanup:           p.WixSkipCleanup,
 		DisableService:           p.DisableService,
+		SourceDateEpoch:          p.SourceDateEpoch,
 	}
 
 	if err := p.makePackage(ctx); err != nil {
@@ -435,7 +445,7 @@ func (p *PackageOptions) makePackage(ctx context.Context) error {
 		}
 
 	case p.target.Package == Tar:
-		if err := packagekit.PackageFPM(ctx, p.packageWriter, p.packagekitops, packagekit.AsTar(), packagekit.WithReplaces(oldPackageNames), packagekit.WithArch(string(p.target.Arch))); err != nil {
+		if err := packagekit.PackageTar(ctx, p.packageWriter, p.packagekitops); err != nil {
 			return fmt.Errorf("packaging, target %s: %w", p.target.String(), err)
 		}
 	case p.target.Package == Pacman:
@@ -584,6 +594,16 @@ func (p *PackageOptions) setupInit(ctx context.Context) error {
 		dir = "/etc/init.d"
 		file = fmt.Sprintf("%s-launcher", p.Identifier)
 		renderFunc = packagekit.RenderInit
+	case p.target.Platform == Linux && p.target.Init == OpenRC:
+		dir = "/etc/init.d"
+		file = fmt.Sprintf("launcher-%s", p.Identifier)
+		renderFunc = packagekit.RenderOpenRC
+	case p.target.Platform == Linux && p.target.Init == Runit:
+		// runit supervises a service directory, not a single file. We
+		// only need the run script, runsv creates the rest.
+		dir = filepath.Join("/etc/sv", fmt.Sprintf("launcher-%s", p.Identifier))
+		file = "run"
+		renderFunc = packagekit.RenderRunit
 	case p.target.Platform == Windows && p.target.Init == WindowsService:
 		// Do nothing, this is handled in the packaging step.
 		return nil
@@ -621,6 +641,10 @@ func (p *PackageOptions) setupPrerm(ctx context.Context) error {
 	switch {
 	case p.target.Platform == Linux && p.target.Init == Systemd:
 		prermTemplate = prermSystemdTemplate()
+	case p.target.Platform == Linux && p.target.Init == OpenRC:
+		prermTemplate = prermOpenRCTemplate()
+	case p.target.Platform == Linux && p.target.Init == Runit:
+		prermTemplate = prermRunitTemplate()
 	default:
 		// If we don't match in the case statement, log that we're ignoring
 		// the setup, and move on. Don't throw an error.
@@ -674,6 +698,10 @@ func (p *PackageOptions) setupPostinst(ctx context.Context) error {
 		postinstTemplateName = "postinstall-upstart.sh"
 	case p.target.Platform == Linux && p.target.Init == Init:
 		postinstTemplateName = "postinstall-init.sh"
+	case p.target.Platform == Linux && p.target.Init == OpenRC:
+		postinstTemplateName = "postinstall-openrc.sh"
+	case p.target.Platform == Linux && p.target.Init == Runit:
+		postinstTemplateName = "postinstall-runit.sh"
 	default:
 		// If we don't match in the case statement, log that we're ignoring
 		// the setup, and move on. Don't throw an error.
@@ -757,6 +785,34 @@ if [ "$1" = remove -o "$1" = "0" ] ; then
 fi`
 }
 
+// prermOpenRCTemplate returns a template suitable for stopping and
+// removing launcher from the OpenRC default runlevel. Like the
+// systemd one, it handles both dpkg and rpm args.
+func prermOpenRCTemplate() string {
+	return `#!/bin/sh
+set -e
+if [ "$1" = remove -o "$1" = "0" ] ; then
+  rc-service launcher-{{.Identifier}} stop || true
+  rc-update del launcher-{{.Identifier}} default || true
+fi`
+}
+
+// prermRunitTemplate returns a template suitable for stopping
+// launcher and unlinking its service directory from whichever
+// runsvdir it was linked into.
+func prermRunitTemplate() string {
+	return `#!/bin/sh
+set -e
+if [ "$1" = remove -o "$1" = "0" ] ; then
+  sv stop launcher-{{.Identifier}} || true
+  for svdir in /var/service /etc/service /etc/runit/runsvdir/default; do
+    if [ -L "$svdir/launcher-{{.Identifier}}" ]; then
+      rm -f "$svdir/launcher-{{.Identifier}}"
+    fi
+  done
+fi`
+}
+
 func (p *PackageOptions) setupDirectories() error {
 	switch p.target.Platform {
 	case Linux, Darwin:
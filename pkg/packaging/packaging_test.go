@@ -275,6 +275,16 @@ func testedTargets() []Target {
 			Init:     NoInit,
 			Package:  Deb,
 		},
+		{
+			Platform: Linux,
+			Init:     OpenRC,
+			Package:  Tar,
+		},
+		{
+			Platform: Linux,
+			Init:     Runit,
+			Package:  Deb,
+		},
 	}
 }
 
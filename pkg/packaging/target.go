@@ -25,9 +25,11 @@ const (
 	WindowsService   InitFlavor = "service"
 	NoInit           InitFlavor = "none"
 	UpstartAmazonAMI InitFlavor = "upstart_amazon_ami"
+	OpenRC           InitFlavor = "openrc"
+	Runit            InitFlavor = "runit"
 )
 
-var knownInitFlavors = [...]InitFlavor{LaunchD, Systemd, Init, Upstart, WindowsService, NoInit, UpstartAmazonAMI}
+var knownInitFlavors = [...]InitFlavor{LaunchD, Systemd, Init, Upstart, WindowsService, NoInit, UpstartAmazonAMI, OpenRC, Runit}
 
 type PlatformFlavor string
 
@@ -35,6 +35,14 @@ func TestInitFromString(t *testing.T) {
 			in:  "none",
 			out: NoInit,
 		},
+		{
+			in:  "openrc",
+			out: OpenRC,
+		},
+		{
+			in:  "runit",
+			out: Runit,
+		},
 	}
 
 	// Test error case
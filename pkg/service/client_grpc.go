@@ -16,6 +16,7 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/ipstack"
 	pb "github.com/kolide/launcher/pkg/pb/launcher"
 )
 
@@ -127,7 +128,7 @@ func DialGRPC(
 
 	grpcOpts = append(grpcOpts, opts...)
 
-	conn, err := grpc.DialContext(grpcCtx, k.KolideServerURL(), grpcOpts...)
+	conn, err := grpc.DialContext(grpcCtx, ipstack.NormalizeHostPort(k.KolideServerURL()), grpcOpts...)
 	return conn, err
 }
 
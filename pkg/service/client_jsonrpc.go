@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-kit/kit/transport/http/jsonrpc"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/ipstack"
 )
 
 // forceNoChunkedEncoding forces the connection not to use chunked
@@ -55,25 +56,22 @@ func NewJSONRPCClient(
 ) KolideService {
 	serviceURL := &url.URL{
 		Scheme: "https",
-		Host:   k.KolideServerURL(),
+		Host:   ipstack.NormalizeHostPort(k.KolideServerURL()),
 	}
 
 	if k.InsecureTransportTLS() {
 		serviceURL.Scheme = "http"
 	}
 
-	httpClient := &http.Client{
-		Timeout: time.Second * 30,
-		Transport: &http.Transport{
-			DisableKeepAlives: true,
-		},
+	transport := &http.Transport{
+		DisableKeepAlives: true,
 	}
 	if !k.InsecureTransportTLS() {
-		tlsConfig := makeTLSConfig(k, rootPool)
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig:   tlsConfig,
-			DisableKeepAlives: true,
-		}
+		transport.TLSClientConfig = makeTLSConfig(k, rootPool)
+	}
+	httpClient := &http.Client{
+		Timeout:   time.Second * 30,
+		Transport: newCompressingTransport(transport),
 	}
 
 	commonOpts := []jsonrpc.ClientOption{
@@ -121,10 +119,13 @@ func NewJSONRPCClient(
 		append(commonOpts, jsonrpc.ClientResponseDecoder(decodeJSONRPCHealthCheckResponse))...,
 	).Endpoint()
 
+	publishLogBatchesEndpoint := newJSONRPCBatchClient(serviceURL, httpClient, "PublishLogs", decodeJSONRPCPublishLogsResponse).Endpoint()
+
 	var client KolideService = Endpoints{
 		RequestEnrollmentEndpoint: requestEnrollmentEndpoint,
 		RequestConfigEndpoint:     requestConfigEndpoint,
 		PublishLogsEndpoint:       publishLogsEndpoint,
+		PublishLogBatchesEndpoint: publishLogBatchesEndpoint,
 		RequestQueriesEndpoint:    requestQueriesEndpoint,
 		PublishResultsEndpoint:    publishResultsEndpoint,
 		CheckHealthEndpoint:       checkHealthEndpoint,
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// minCompressBytes is the smallest request body worth compressing
+const minCompressBytes = 1024
+
+// requestCodings are the content codings we can compress request bodies with, in order of preference
+var requestCodings = []struct {
+	name     string
+	compress func(io.Writer) (io.WriteCloser, error)
+}{
+	{
+		name:     "zstd",
+		compress: func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+	},
+	{
+		name:     "gzip",
+		compress: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+	},
+}
+
+// compressingTransport is an http.RoundTripper that compresses request bodies, once the server
+// has said it accepts compressed requests. Following RFC 7694, the server lists the content
+// codings it accepts in the Accept-Encoding header of its responses; until it does, requests are
+// sent uncompressed. (Compressed responses are handled by http.Transport itself.)
+type compressingTransport struct {
+	next http.RoundTripper
+
+	lock   sync.Mutex
+	coding int // index into requestCodings, or -1 if the server hasn't accepted any
+}
+
+func newCompressingTransport(next http.RoundTripper) *compressingTransport {
+	return &compressingTransport{
+		next:   next,
+		coding: -1,
+	}
+}
+
+func (t *compressingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	coding := t.currentCoding()
+	if coding < 0 || req.Body == nil || req.ContentLength < minCompressBytes || req.Header.Get("Content-Encoding") != "" {
+		resp, err := t.next.RoundTrip(req)
+		if err == nil {
+			t.negotiate(resp.Header)
+		}
+		return resp, err
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+
+	compressedReq, err := compressRequest(req, body, requestCodings[coding].name, requestCodings[coding].compress)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(compressedReq)
+	if err != nil {
+		return nil, err
+	}
+	t.negotiate(resp.Header)
+
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, nil
+	}
+
+	// The server no longer accepts this coding -- stop compressing, and resend the request as it was
+	resp.Body.Close()
+	t.reject(coding)
+
+	uncompressedReq := req.Clone(req.Context())
+	uncompressedReq.Body = io.NopCloser(bytes.NewReader(body))
+	uncompressedReq.ContentLength = int64(len(body))
+	return t.next.RoundTrip(uncompressedReq)
+}
+
+func (t *compressingTransport) currentCoding() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.coding
+}
+
+// negotiate picks the coding to use from the Accept-Encoding header of a response, if it has one.
+func (t *compressingTransport) negotiate(header http.Header) {
+	accepted := header.Values("Accept-Encoding")
+	if len(accepted) == 0 {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.coding = preferredCoding(accepted)
+}
+
+// reject stops using the given coding, unless a newer response has already picked another.
+func (t *compressingTransport) reject(coding int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.coding == coding {
+		t.coding = -1
+	}
+}
+
+// preferredCoding returns our most preferred coding among those listed in the given
+// Accept-Encoding header values, or -1 if none are.
+func preferredCoding(acceptEncoding []string) int {
+	accepted := make(map[string]bool)
+	for _, value := range acceptEncoding {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			// A weight of zero means the coding is not acceptable
+			if _, q, found := strings.Cut(params, "q="); found {
+				if weight, err := strconv.ParseFloat(strings.TrimSpace(q), 64); err == nil && weight == 0 {
+					continue
+				}
+			}
+			accepted[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+
+	for i, coding := range requestCodings {
+		if accepted[coding.name] {
+			return i
+		}
+	}
+	return -1
+}
+
+// compressRequest returns a copy of req with the given body compressed.
+func compressRequest(req *http.Request, body []byte, coding string, compress func(io.Writer) (io.WriteCloser, error)) (*http.Request, error) {
+	var compressed bytes.Buffer
+	w, err := compress(&compressed)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s encoder: %w", coding, err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, fmt.Errorf("compressing request body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("compressing request body: %w", err)
+	}
+
+	compressedReq := req.Clone(req.Context())
+	compressedReq.Body = io.NopCloser(&compressed)
+	compressedReq.ContentLength = int64(compressed.Len())
+	compressedReq.Header.Set("Content-Encoding", coding)
+
+	return compressedReq, nil
+}
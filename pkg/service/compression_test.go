@@ -0,0 +1,110 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferredCoding(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		acceptEncoding []string
+		expected       string
+	}{
+		{acceptEncoding: []string{"gzip"}, expected: "gzip"},
+		{acceptEncoding: []string{"br, GZIP;q=0.5"}, expected: "gzip"},
+		{acceptEncoding: []string{"identity", "gzip"}, expected: "gzip"},
+		{acceptEncoding: []string{"zstd"}, expected: "zstd"},
+		{acceptEncoding: []string{"gzip, zstd"}, expected: "zstd"},
+		{acceptEncoding: []string{"gzip", "zstd;q=0"}, expected: "gzip"},
+		{acceptEncoding: []string{"gzip;q=0"}, expected: ""},
+		{acceptEncoding: []string{"br"}, expected: ""},
+		{acceptEncoding: []string{""}, expected: ""},
+	} {
+		var got string
+		if coding := preferredCoding(tt.acceptEncoding); coding >= 0 {
+			got = requestCodings[coding].name
+		}
+		require.Equal(t, tt.expected, got, tt.acceptEncoding)
+	}
+}
+
+func TestCompressingTransport(t *testing.T) {
+	t.Parallel()
+
+	for _, coding := range []string{"gzip", "zstd"} {
+		coding := coding
+		t.Run(coding, func(t *testing.T) {
+			t.Parallel()
+			testCompressingTransport(t, coding)
+		})
+	}
+}
+
+// testCompressingTransport runs requests against a server that accepts only the given coding.
+func testCompressingTransport(t *testing.T, coding string) {
+	largeBody := strings.Repeat("osquery result log ", 200)
+
+	var receivedEncodings []string
+	accept := true
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedEncodings = append(receivedEncodings, r.Header.Get("Content-Encoding"))
+
+		if r.Header.Get("Content-Encoding") == coding && !accept {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var body io.Reader = r.Body
+		switch r.Header.Get("Content-Encoding") {
+		case "gzip":
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		case "zstd":
+			zr, err := zstd.NewReader(r.Body)
+			require.NoError(t, err)
+			defer zr.Close()
+			body = zr
+		}
+		received, err := io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, largeBody, string(received))
+
+		if accept {
+			w.Header().Set("Accept-Encoding", coding)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	client := &http.Client{Transport: newCompressingTransport(http.DefaultTransport)}
+	post := func() {
+		resp, err := client.Post(testServer.URL, "application/json", bytes.NewBufferString(largeBody))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// Until the server says it accepts the coding, requests aren't compressed
+	post()
+	post()
+	require.Equal(t, []string{"", coding}, receivedEncodings)
+
+	// If the server stops accepting the coding, the request is resent uncompressed, and later
+	// requests aren't compressed
+	accept = false
+	receivedEncodings = nil
+	post()
+	post()
+	require.Equal(t, []string{coding, "", ""}, receivedEncodings)
+}
@@ -110,7 +110,7 @@ func TestSwappingCert(t *testing.T) { // nolint:paralleltest
 	knapsack.On("InsecureTransportTLS").Return(false)
 	knapsack.On("InsecureTLS").Return(false)
 	knapsack.On("CertPins").Return([][]byte{})
-	knapsack.On("Transport").Return("grpc")
+	knapsack.On("MTLSClientCert").Return("")
 
 	slogger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 	knapsack.On("Slogger").Return(slogger)
@@ -162,7 +162,7 @@ func TestCertRemainsBad(t *testing.T) { // nolint:paralleltest
 	knapsack.On("InsecureTransportTLS").Return(false)
 	knapsack.On("InsecureTLS").Return(false)
 	knapsack.On("CertPins").Return([][]byte{})
-	knapsack.On("Transport").Return("grpc")
+	knapsack.On("MTLSClientCert").Return("")
 
 	slogger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 	knapsack.On("Slogger").Return(slogger)
@@ -239,7 +239,7 @@ func TestCertPinning(t *testing.T) { // nolint:paralleltest
 			knapsack.On("InsecureTransportTLS").Return(false)
 			knapsack.On("InsecureTLS").Return(false)
 			knapsack.On("CertPins").Return(certPins)
-			knapsack.On("Transport").Return("grpc")
+			knapsack.On("MTLSClientCert").Return("")
 
 			slogger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 			knapsack.On("Slogger").Return(slogger)
@@ -312,7 +312,7 @@ func TestRootCAs(t *testing.T) { // nolint:paralleltest
 			knapsack.On("InsecureTransportTLS").Return(false)
 			knapsack.On("InsecureTLS").Return(false)
 			knapsack.On("CertPins").Return([][]byte{})
-			knapsack.On("Transport").Return("grpc")
+			knapsack.On("MTLSClientCert").Return("")
 
 			slogger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 			knapsack.On("Slogger").Return(slogger)
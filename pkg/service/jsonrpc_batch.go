@@ -0,0 +1,163 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport/http/jsonrpc"
+)
+
+// batchRetryInterval is how long we wait before trying batch requests again, after the server
+// has rejected one
+const batchRetryInterval = 1 * time.Hour
+
+// errBatchingUnsupported is returned by a batch endpoint when the server doesn't accept batch
+// requests, and the requests should be sent individually instead.
+var errBatchingUnsupported = errors.New("server does not support batch requests")
+
+// batchedResponse is the decoded response to one of the requests in a batch.
+type batchedResponse struct {
+	Response interface{}
+	Err      error
+}
+
+// jsonRPCBatchClient sends several calls of the same method in a single JSON-RPC batch request.
+// go-kit's JSON-RPC client only sends single calls.
+type jsonRPCBatchClient struct {
+	tgt    *url.URL
+	client *http.Client
+	method string
+	dec    jsonrpc.DecodeResponseFunc
+
+	lock             sync.Mutex
+	nextID           int
+	unsupportedUntil time.Time
+}
+
+func newJSONRPCBatchClient(tgt *url.URL, client *http.Client, method string, dec jsonrpc.DecodeResponseFunc) *jsonRPCBatchClient {
+	return &jsonRPCBatchClient{
+		tgt:    tgt,
+		client: client,
+		method: method,
+		dec:    dec,
+	}
+}
+
+type batchRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+	ID      int         `json:"id"`
+}
+
+// Endpoint returns an endpoint that takes a []interface{} of the method's params, and returns a
+// []batchedResponse in the same order.
+func (c *jsonRPCBatchClient) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		params := request.([]interface{})
+
+		if !c.batchingAllowed() {
+			return nil, errBatchingUnsupported
+		}
+
+		rpcReqs := make([]batchRequest, len(params))
+		ids := c.reserveIDs(len(params))
+		for i, p := range params {
+			rpcReqs[i] = batchRequest{
+				JSONRPC: jsonrpc.Version,
+				Method:  c.method,
+				Params:  p,
+				ID:      ids + i,
+			}
+		}
+
+		body, err := json.Marshal(rpcReqs)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling batch request: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tgt.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating batch request: %w", err)
+		}
+		req.Header.Set("Content-Type", jsonrpc.ContentType)
+		ctx = forceNoChunkedEncoding(ctx, req)
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading batch response: %w", err)
+		}
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, fmt.Errorf("batch request returned status %d", resp.StatusCode)
+		}
+
+		// A server that doesn't understand batches rejects the request, or answers it with a
+		// single error response rather than an array of responses
+		var rpcResps []jsonrpc.Response
+		if resp.StatusCode != http.StatusOK || json.Unmarshal(respBody, &rpcResps) != nil {
+			c.disableBatching()
+			return nil, errBatchingUnsupported
+		}
+
+		byID := make(map[int]jsonrpc.Response, len(rpcResps))
+		for _, rpcResp := range rpcResps {
+			if rpcResp.ID == nil {
+				continue
+			}
+			if id, err := rpcResp.ID.Int(); err == nil {
+				byID[id] = rpcResp
+			}
+		}
+
+		responses := make([]batchedResponse, len(rpcReqs))
+		for i, rpcReq := range rpcReqs {
+			rpcResp, ok := byID[rpcReq.ID]
+			if !ok {
+				responses[i].Err = fmt.Errorf("no response to request %d in batch", rpcReq.ID)
+				continue
+			}
+			responses[i].Response, responses[i].Err = c.dec(ctx, rpcResp)
+		}
+
+		return responses, nil
+	}
+}
+
+func (c *jsonRPCBatchClient) reserveIDs(count int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	first := c.nextID
+	c.nextID += count
+	return first
+}
+
+func (c *jsonRPCBatchClient) batchingAllowed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return time.Now().After(c.unsupportedUntil)
+}
+
+func (c *jsonRPCBatchClient) disableBatching() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.unsupportedUntil = time.Now().Add(batchRetryInterval)
+}
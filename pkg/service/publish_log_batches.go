@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kolide/kit/contexts/uuid"
+	"github.com/osquery/osquery-go/plugin/logger"
+)
+
+// LogBatch is a batch of logs of a single type, as would be sent with PublishLogs.
+type LogBatch struct {
+	LogType logger.LogType
+	Logs    []string
+}
+
+// LogBatchResult is the result of publishing a single LogBatch, as PublishLogs would return it.
+type LogBatchResult struct {
+	Message     string
+	ErrorCode   string
+	NodeInvalid bool
+	Err         error
+}
+
+// LogBatchPublisher is implemented by clients that can publish several batches of logs at
+// once. Where the transport and server support it, the batches are sent in a single request;
+// otherwise, they're sent one after another.
+type LogBatchPublisher interface {
+	PublishLogBatches(ctx context.Context, nodeKey string, batches []LogBatch) []LogBatchResult
+}
+
+// publishLogBatches publishes the batches with svc, in a single request if it can.
+func publishLogBatches(ctx context.Context, svc KolideService, nodeKey string, batches []LogBatch) []LogBatchResult {
+	if publisher, ok := svc.(LogBatchPublisher); ok {
+		return publisher.PublishLogBatches(ctx, nodeKey, batches)
+	}
+	return publishLogBatchesSeparately(ctx, svc, nodeKey, batches)
+}
+
+func publishLogBatchesSeparately(ctx context.Context, svc KolideService, nodeKey string, batches []LogBatch) []LogBatchResult {
+	results := make([]LogBatchResult, len(batches))
+	for i, batch := range batches {
+		results[i].Message, results[i].ErrorCode, results[i].NodeInvalid, results[i].Err = svc.PublishLogs(ctx, nodeKey, batch.LogType, batch.Logs)
+	}
+	return results
+}
+
+// PublishLogBatches implements LogBatchPublisher
+func (e Endpoints) PublishLogBatches(ctx context.Context, nodeKey string, batches []LogBatch) []LogBatchResult {
+	if e.PublishLogBatchesEndpoint == nil || len(batches) < 2 {
+		return publishLogBatchesSeparately(ctx, e, nodeKey, batches)
+	}
+
+	newCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	requests := make([]interface{}, len(batches))
+	for i, batch := range batches {
+		requests[i] = logCollection{NodeKey: nodeKey, LogType: batch.LogType, Logs: batch.Logs}
+	}
+
+	response, err := e.PublishLogBatchesEndpoint(newCtx, requests)
+	if errors.Is(err, errBatchingUnsupported) {
+		return publishLogBatchesSeparately(ctx, e, nodeKey, batches)
+	}
+
+	results := make([]LogBatchResult, len(batches))
+	if err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	for i, batchResponse := range response.([]batchedResponse) {
+		if batchResponse.Err != nil {
+			results[i].Err = batchResponse.Err
+			continue
+		}
+
+		resp := batchResponse.Response.(publishLogsResponse)
+		if resp.DisableDevice {
+			results[i].Err = ErrDeviceDisabled{}
+			continue
+		}
+
+		results[i] = LogBatchResult{
+			Message:     resp.Message,
+			ErrorCode:   resp.ErrorCode,
+			NodeInvalid: resp.NodeInvalid,
+			Err:         resp.Err,
+		}
+	}
+
+	return results
+}
+
+func (mw logmw) PublishLogBatches(ctx context.Context, nodeKey string, batches []LogBatch) (results []LogBatchResult) {
+	defer func(begin time.Time) {
+		uuid, _ := uuid.FromContext(ctx)
+
+		logCount := 0
+		for _, batch := range batches {
+			logCount += len(batch.Logs)
+		}
+
+		var errs []error
+		for i, result := range results {
+			if result.Err != nil {
+				errs = append(errs, fmt.Errorf("publishing %s logs: %w", batches[i].LogType, result.Err))
+			}
+		}
+		err := errors.Join(errs...)
+
+		message := "success"
+		if err != nil {
+			message = "failure"
+		}
+
+		pubStateVals, ok := ctx.Value(PublicationCtxKey).(map[string]int)
+		if !ok {
+			pubStateVals = make(map[string]int)
+		}
+
+		mw.knapsack.Slogger().Log(ctx, levelForError(err), message, // nolint:sloglint // it's fine to not have a constant or literal here
+			"method", "PublishLogBatches",
+			"uuid", uuid,
+			"batch_count", len(batches),
+			"log_count", logCount,
+			"err", err,
+			"took", time.Since(begin),
+			"publication_state", pubStateVals,
+		)
+	}(time.Now())
+
+	return publishLogBatches(ctx, mw.next, nodeKey, batches)
+}
+
+func (mw uuidmw) PublishLogBatches(ctx context.Context, nodeKey string, batches []LogBatch) []LogBatchResult {
+	ctx = uuid.NewContext(ctx, uuid.NewForRequest())
+	return publishLogBatches(ctx, mw.next, nodeKey, batches)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/transport/http/jsonrpc"
+	"github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/kolide/launcher/pkg/log/multislogger"
+	"github.com/osquery/osquery-go/plugin/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishLogBatches(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name             string
+		serverBatches    bool
+		expectedRequests int
+	}{
+		{
+			name:             "server supports batches",
+			serverBatches:    true,
+			expectedRequests: 1,
+		},
+		{
+			name:          "server does not support batches",
+			serverBatches: false,
+			// the rejected batch, then each batch separately
+			expectedRequests: 3,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var lock sync.Mutex
+			requests := 0
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				requests++
+				lock.Unlock()
+
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				if !bytes.HasPrefix(body, []byte("[")) {
+					var req jsonrpc.Request
+					require.NoError(t, json.Unmarshal(body, &req))
+					respJson, err := json.Marshal(jsonrpc.Response{Result: []byte(`{"message": "single"}`), ID: req.ID})
+					require.NoError(t, err)
+					w.Write(respJson)
+					return
+				}
+
+				if !tt.serverBatches {
+					// Like go-kit's server, answer a batch with a single parse error
+					respJson, err := json.Marshal(jsonrpc.Response{Error: &jsonrpc.Error{Code: jsonrpc.ParseError, Message: "parse error"}})
+					require.NoError(t, err)
+					w.Write(respJson)
+					return
+				}
+
+				var reqs []jsonrpc.Request
+				require.NoError(t, json.Unmarshal(body, &reqs))
+				require.Len(t, reqs, 2)
+
+				// Respond out of order, with the second batch rejected for an invalid node key
+				resps := []jsonrpc.Response{
+					{Result: []byte(`{"node_invalid": true}`), ID: reqs[1].ID},
+					{Result: []byte(`{"message": "batched"}`), ID: reqs[0].ID},
+				}
+				respJson, err := json.Marshal(resps)
+				require.NoError(t, err)
+				w.Write(respJson)
+			}))
+			defer testServer.Close()
+
+			u, err := url.Parse(testServer.URL)
+			require.NoError(t, err)
+
+			mockKnapsack := mocks.NewKnapsack(t)
+			mockKnapsack.On("KolideServerURL").Return(u.Host)
+			mockKnapsack.On("InsecureTransportTLS").Return(true)
+			mockKnapsack.On("Slogger").Return(multislogger.NewNopLogger())
+
+			client := NewJSONRPCClient(mockKnapsack, nil)
+			publisher, ok := client.(LogBatchPublisher)
+			require.True(t, ok, "JSON-RPC client should publish log batches")
+
+			results := publisher.PublishLogBatches(context.TODO(), "node_key", []LogBatch{
+				{LogType: logger.LogTypeStatus, Logs: []string{"status"}},
+				{LogType: logger.LogTypeString, Logs: []string{"result"}},
+			})
+			require.Len(t, results, 2)
+			for _, result := range results {
+				require.NoError(t, result.Err)
+			}
+
+			if tt.serverBatches {
+				require.Equal(t, "batched", results[0].Message)
+				require.True(t, results[1].NodeInvalid)
+			} else {
+				require.Equal(t, "single", results[0].Message)
+				require.Equal(t, "single", results[1].Message)
+
+				// Batching isn't attempted again right away
+				publisher.PublishLogBatches(context.TODO(), "node_key", []LogBatch{
+					{LogType: logger.LogTypeStatus, Logs: []string{"status"}},
+					{LogType: logger.LogTypeString, Logs: []string{"result"}},
+				})
+				tt.expectedRequests += 2
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			require.Equal(t, tt.expectedRequests, requests)
+		})
+	}
+}
@@ -19,6 +19,9 @@ type contextKey string
 const (
 	// PublicationCtxKey is used to set the relevant thresholds in context for reporting when logs are published
 	PublicationCtxKey contextKey = "log_publication_state"
+
+	// QueryAccountingCtxKey is used to set the execution accounting to send along with distributed query results
+	QueryAccountingCtxKey contextKey = "query_accounting"
 )
 
 type logCollection struct {
@@ -10,14 +10,16 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/transport/http/jsonrpc"
 	"github.com/kolide/kit/contexts/uuid"
+	"github.com/kolide/launcher/pkg/osquery/queryaccounting"
 	pb "github.com/kolide/launcher/pkg/pb/launcher"
 	"github.com/kolide/launcher/pkg/traces"
 	"github.com/osquery/osquery-go/plugin/distributed"
 )
 
 type resultCollection struct {
-	NodeKey string `json:"node_key"`
-	Results []distributed.Result
+	NodeKey    string `json:"node_key"`
+	Results    []distributed.Result
+	Accounting []queryaccounting.Entry `json:"accounting,omitempty"`
 }
 
 type publishResultsResponse struct {
@@ -170,7 +172,10 @@ func (e Endpoints) PublishResults(ctx context.Context, nodeKey string, results [
 	newCtx, cancel := context.WithTimeout(ctx, requestTimeout)
 	defer cancel()
 
-	request := resultCollection{NodeKey: nodeKey, Results: results}
+	// Attach the execution accounting for these results, if any. The gRPC transport doesn't carry it.
+	accounting, _ := ctx.Value(QueryAccountingCtxKey).([]queryaccounting.Entry)
+
+	request := resultCollection{NodeKey: nodeKey, Results: results, Accounting: accounting}
 	response, err := e.PublishResultsEndpoint(newCtx, request)
 	if err != nil {
 		return "", "", false, err
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
 	"testing"
 
+	"github.com/kolide/launcher/pkg/osquery/queryaccounting"
+	"github.com/osquery/osquery-go/plugin/distributed"
 	"github.com/stretchr/testify/require"
 )
 
@@ -47,3 +50,29 @@ func Test_TrivialTruncate(t *testing.T) {
 	}
 
 }
+
+func TestPublishResults_Accounting(t *testing.T) {
+	t.Parallel()
+
+	var gotRequest resultCollection
+	e := Endpoints{
+		PublishResultsEndpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+			gotRequest = request.(resultCollection)
+			return publishResultsResponse{}, nil
+		},
+	}
+
+	results := []distributed.Result{{QueryName: "foobar"}}
+	accounting := []queryaccounting.Entry{{QueryName: "foobar", DurationMs: 250}}
+
+	// Without accounting in the context, none is sent
+	_, _, _, err := e.PublishResults(context.TODO(), "node_key", results)
+	require.NoError(t, err)
+	require.Equal(t, results, gotRequest.Results)
+	require.Nil(t, gotRequest.Accounting)
+
+	ctx := context.WithValue(context.TODO(), QueryAccountingCtxKey, accounting)
+	_, _, _, err = e.PublishResults(ctx, "node_key", results)
+	require.NoError(t, err)
+	require.Equal(t, accounting, gotRequest.Accounting)
+}
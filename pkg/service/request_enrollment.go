@@ -38,6 +38,11 @@ type EnrollmentDetails struct {
 	GOOS                      string `json:"goos"`
 	GOARCH                    string `json:"goarch"`
 	HardwareUUID              string `json:"hardware_uuid"`
+
+	// LauncherHardwareKeyProof is only set when hardware key proof is enabled. It is a
+	// base64 encoded, JSON encoded keys.KeyProof, signed by the hardware key, and bound to this
+	// request's host identifier and enroll secret; see keys.KeyProofVerifier.
+	LauncherHardwareKeyProof string `json:"launcher_hardware_key_proof,omitempty"`
 }
 
 type enrollmentResponse struct {
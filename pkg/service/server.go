@@ -34,6 +34,7 @@ type Endpoints struct {
 	RequestEnrollmentEndpoint endpoint.Endpoint
 	RequestConfigEndpoint     endpoint.Endpoint
 	PublishLogsEndpoint       endpoint.Endpoint
+	PublishLogBatchesEndpoint endpoint.Endpoint // optional; only the JSON-RPC client can batch requests
 	RequestQueriesEndpoint    endpoint.Endpoint
 	PublishResultsEndpoint    endpoint.Endpoint
 	CheckHealthEndpoint       endpoint.Endpoint
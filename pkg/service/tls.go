@@ -8,34 +8,25 @@ import (
 	"crypto/x509"
 	"errors"
 	"log/slog"
-	"net/url"
 
+	"github.com/kolide/launcher/ee/agent"
 	"github.com/kolide/launcher/ee/agent/types"
+	"github.com/kolide/launcher/ee/ipstack"
 )
 
 func makeTLSConfig(k types.Knapsack, rootPool *x509.CertPool) *tls.Config {
 
-	hostname := k.KolideServerURL()
-	if k.Transport() == "grpc" {
-		// gRPC doesn't use the port for TLS verification. So we strip it here.
-		u, err := url.Parse(k.KolideServerURL())
-		if err != nil {
-			k.Slogger().Log(context.TODO(), slog.LevelError,
-				"failed to parse server URL",
-				"err", err,
-			)
-			return nil
-		}
-		hostname = u.Hostname()
-	}
-
 	conf := &tls.Config{
-		ServerName:         hostname,
+		// Certificates are verified against the hostname, or IP address, without the port
+		ServerName:         ipstack.Hostname(k.KolideServerURL()),
 		InsecureSkipVerify: k.InsecureTLS(),
 		RootCAs:            rootPool,
 		MinVersion:         tls.VersionTLS12,
 	}
 
+	// Present a client certificate, if configured, for mutual TLS
+	conf.GetClientCertificate = agent.ClientCertificateFunc(k)
+
 	if len(k.CertPins()) > 0 {
 		conf.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
 			for _, chain := range verifiedChains {
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/kolide/launcher/ee/agent/types/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeTLSConfig_ServerName(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		serverURL  string
+		serverName string
+	}{
+		{serverURL: "k2device.kolide.com", serverName: "k2device.kolide.com"},
+		{serverURL: "k2device.kolide.com:443", serverName: "k2device.kolide.com"},
+		{serverURL: "192.0.2.10:8443", serverName: "192.0.2.10"},
+		{serverURL: "[2001:db8::10]:443", serverName: "2001:db8::10"},
+		{serverURL: "2001:db8::10", serverName: "2001:db8::10"},
+	} {
+		tt := tt
+		t.Run(tt.serverURL, func(t *testing.T) {
+			t.Parallel()
+
+			k := mocks.NewKnapsack(t)
+			k.On("KolideServerURL").Return(tt.serverURL)
+			k.On("InsecureTLS").Return(false)
+			k.On("MTLSClientCert").Return("")
+			k.On("CertPins").Return(nil)
+
+			conf := makeTLSConfig(k, nil)
+			require.Equal(t, tt.serverName, conf.ServerName)
+		})
+	}
+}
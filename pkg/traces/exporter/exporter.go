@@ -45,6 +45,7 @@ type TraceExporter struct {
 	provider                  *sdktrace.TracerProvider
 	providerLock              sync.Mutex
 	bufSpanProcessor          *bufspanprocessor.BufSpanProcessor
+	retainingExporter         *retainingExporter
 	knapsack                  types.Knapsack
 	osqueryClient             querier
 	slogger                   *slog.Logger
@@ -299,8 +300,11 @@ func (t *TraceExporter) setNewGlobalProvider(rebuildExporter bool) {
 		return
 	}
 
+	// hold on to spans that fail to export, to retry them with the next batch
+	t.retainingExporter = newRetainingExporter(exporter, maxRetainedSpans, t.retainingExporter)
+
 	// create child processor with new exporter and set it on the bufspanprocessor
-	batchSpanProcessor := sdktrace.NewBatchSpanProcessor(exporter, sdktrace.WithBatchTimeout(t.batchTimeout))
+	batchSpanProcessor := sdktrace.NewBatchSpanProcessor(t.retainingExporter, sdktrace.WithBatchTimeout(t.batchTimeout))
 	t.bufSpanProcessor.SetChildProcessor(batchSpanProcessor)
 
 	// set ingest url after successfully setting up new child processor
@@ -0,0 +1,71 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// maxRetainedSpans bounds how many spans we hold on to while the ingest server is unreachable
+const maxRetainedSpans = 2000
+
+// retainingExporter holds on to spans it couldn't export -- e.g. while the device is offline, or
+// the ingest server is unavailable -- and retries them with the next batch. Past maxSpans, the
+// oldest are dropped.
+type retainingExporter struct {
+	sdktrace.SpanExporter
+	maxSpans int
+	retained []sdktrace.ReadOnlySpan
+	lock     sync.Mutex
+}
+
+// newRetainingExporter wraps exporter. Spans retained by previous, the exporter it replaces, are
+// carried over so that changing the ingest server doesn't lose them.
+func newRetainingExporter(exporter sdktrace.SpanExporter, maxSpans int, previous *retainingExporter) *retainingExporter {
+	r := &retainingExporter{
+		SpanExporter: exporter,
+		maxSpans:     maxSpans,
+	}
+
+	if previous != nil {
+		previous.lock.Lock()
+		r.retained = previous.retained
+		previous.retained = nil
+		previous.lock.Unlock()
+	}
+
+	return r
+}
+
+func (r *retainingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	r.lock.Lock()
+	toExport := make([]sdktrace.ReadOnlySpan, 0, len(r.retained)+len(spans))
+	toExport = append(toExport, r.retained...)
+	toExport = append(toExport, spans...)
+	r.retained = nil
+	r.lock.Unlock()
+
+	err := r.SpanExporter.ExportSpans(ctx, toExport)
+	if err == nil {
+		return nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.retained = append(toExport, r.retained...)
+	if len(r.retained) > r.maxSpans {
+		r.retained = r.retained[len(r.retained)-r.maxSpans:]
+	}
+
+	return err
+}
+
+// retainedCount returns how many spans are waiting to be retried
+func (r *retainingExporter) retainedCount() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return len(r.retained)
+}
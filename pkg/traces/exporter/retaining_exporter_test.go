@@ -0,0 +1,77 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeSpanExporter struct {
+	err      error
+	exported []string
+}
+
+func (f *fakeSpanExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, span := range spans {
+		f.exported = append(f.exported, span.Name())
+	}
+	return nil
+}
+
+func (f *fakeSpanExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func testSpans(names ...string) []sdktrace.ReadOnlySpan {
+	stubs := make(tracetest.SpanStubs, len(names))
+	for i, name := range names {
+		stubs[i] = tracetest.SpanStub{Name: name}
+	}
+	return stubs.Snapshots()
+}
+
+func TestRetainingExporter(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeSpanExporter{err: errors.New("ingest server unavailable")}
+	r := newRetainingExporter(fake, 3, nil)
+
+	// Failed exports are retained, up to the limit, dropping the oldest
+	require.Error(t, r.ExportSpans(context.TODO(), testSpans("a", "b")))
+	require.Equal(t, 2, r.retainedCount())
+	require.Error(t, r.ExportSpans(context.TODO(), testSpans("c", "d")))
+	require.Equal(t, 3, r.retainedCount())
+
+	// Once the ingest server is back, retained spans go out first
+	fake.err = nil
+	require.NoError(t, r.ExportSpans(context.TODO(), testSpans("e")))
+	require.Equal(t, []string{"b", "c", "d", "e"}, fake.exported)
+	require.Equal(t, 0, r.retainedCount())
+}
+
+func TestRetainingExporter_carriedOver(t *testing.T) {
+	t.Parallel()
+
+	previous := newRetainingExporter(&fakeSpanExporter{err: errors.New("ingest server unavailable")}, maxRetainedSpans, nil)
+	spans := make([]string, 10)
+	for i := range spans {
+		spans[i] = fmt.Sprintf("span-%d", i)
+	}
+	require.Error(t, previous.ExportSpans(context.TODO(), testSpans(spans...)))
+
+	replacement := &fakeSpanExporter{}
+	r := newRetainingExporter(replacement, maxRetainedSpans, previous)
+	require.Equal(t, 0, previous.retainedCount())
+	require.Equal(t, 10, r.retainedCount())
+
+	require.NoError(t, r.ExportSpans(context.TODO(), nil))
+	require.Equal(t, spans, replacement.exported)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/kolide/launcher/pkg/log/multislogger"
 	"go.opentelemetry.io/otel"
@@ -27,7 +28,7 @@ const (
 // ending the span. `keyVals` should be a list of pairs, where the first in the pair is a
 // string representing the attribute key and the second in the pair is the attribute value.
 func StartHttpRequestSpan(r *http.Request, keyVals ...interface{}) (*http.Request, trace.Span) {
-	ctx, span := startSpanWithExtractedAttributes(r.Context(), keyVals...)
+	ctx, span := startSpanWithExtractedAttributes(r.Context(), "", nil, keyVals...)
 	return r.WithContext(ctx), span
 }
 
@@ -36,15 +37,33 @@ func StartHttpRequestSpan(r *http.Request, keyVals ...interface{}) (*http.Reques
 // ending the span. `keyVals` should be a list of pairs, where the first in the pair is a
 // string representing the attribute key and the second in the pair is the attribute value.
 func StartSpan(ctx context.Context, keyVals ...interface{}) (context.Context, trace.Span) {
-	return startSpanWithExtractedAttributes(ctx, keyVals...)
+	return startSpanWithExtractedAttributes(ctx, "", nil, keyVals...)
 }
 
-// startSpanWithExtractedAttributes is the internal implementation of StartSpan and StartHttpRequestSpan
-// with runtime.Caller(2) so that the caller of the wrapper function is used.
-func startSpanWithExtractedAttributes(ctx context.Context, keyVals ...interface{}) (context.Context, trace.Span) {
-	spanName := defaultSpanName
+// StartNamedSpan is StartSpan, for callers whose function name doesn't describe the work -- e.g.
+// a wrapper around many different tables. The span is named spanName instead.
+func StartNamedSpan(ctx context.Context, spanName string, keyVals ...interface{}) (context.Context, trace.Span) {
+	return startSpanWithExtractedAttributes(ctx, spanName, nil, keyVals...)
+}
 
-	opts := make([]trace.SpanStartOption, 0)
+// RecordSpan records a span named spanName for work that has already finished, e.g. a distributed
+// query, whose start we only learn of when its results arrive. If err is not nil, it's set on the span.
+func RecordSpan(ctx context.Context, spanName string, start time.Time, end time.Time, err error, keyVals ...interface{}) {
+	_, span := startSpanWithExtractedAttributes(ctx, spanName, []trace.SpanStartOption{trace.WithTimestamp(start)}, keyVals...)
+	if err != nil {
+		SetError(span, err)
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// startSpanWithExtractedAttributes is the internal implementation of StartSpan and its variants,
+// with runtime.Caller(2) so that the caller of the wrapper function is used. The span is named for
+// the caller, unless spanName is set.
+func startSpanWithExtractedAttributes(ctx context.Context, spanName string, opts []trace.SpanStartOption, keyVals ...interface{}) (context.Context, trace.Span) {
+	namedForCaller := spanName == ""
+	if namedForCaller {
+		spanName = defaultSpanName
+	}
 
 	// Extract information about the caller to set some standard attributes (code.filepath,
 	// code.lineno, code.function) and to set more specific span and attribute names.
@@ -60,7 +79,9 @@ func startSpanWithExtractedAttributes(ctx context.Context, keyVals ...interface{
 
 		// Extract the calling function name and use it to set code.function and the span name.
 		if f := runtime.FuncForPC(programCounter); f != nil {
-			spanName = filepath.Base(f.Name())
+			if namedForCaller {
+				spanName = filepath.Base(f.Name())
+			}
 			opts = append(opts, trace.WithAttributes(semconv.CodeFunction(f.Name())))
 		}
 	}
@@ -1,11 +1,17 @@
 package traces
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func Test_buildAttributes(t *testing.T) {
@@ -129,3 +135,27 @@ func Test_buildAttributes(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordSpan(t *testing.T) { //nolint:paralleltest
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := StartNamedSpan(context.TODO(), "table/kolide_example", "table_name", "kolide_example")
+	start := time.Now().Add(-time.Minute)
+	end := start.Add(10 * time.Second)
+	RecordSpan(ctx, "distributed_query", start, end, errors.New("query failed"), "query_name", "example")
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "distributed_query", spans[0].Name())
+	require.Equal(t, start, spans[0].StartTime())
+	require.Equal(t, end, spans[0].EndTime())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Contains(t, spans[0].Attributes(), attribute.String("launcher.traces.query_name", "example"))
+
+	require.Equal(t, "table/kolide_example", spans[1].Name())
+	require.Contains(t, spans[1].Attributes(), attribute.String("launcher.traces.table_name", "kolide_example"))
+}